@@ -2,32 +2,56 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/backup"
+	"github.com/alexmacdonald/simple-ipass/internal/config"
+	"github.com/alexmacdonald/simple-ipass/internal/costs"
 	"github.com/alexmacdonald/simple-ipass/internal/db"
 	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/export"
 	"github.com/alexmacdonald/simple-ipass/internal/handlers"
 	"github.com/alexmacdonald/simple-ipass/internal/logger"
 	"github.com/alexmacdonald/simple-ipass/internal/middleware"
-	"github.com/gorilla/mux"
-	"github.com/rs/cors"
+	"github.com/alexmacdonald/simple-ipass/internal/notify"
+	"github.com/alexmacdonald/simple-ipass/internal/retention"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/alexmacdonald/simple-ipass/internal/version"
 )
 
 func main() {
 	// Initialize structured logger (ELK-ready!)
 	appLogger := logger.NewLogger("ipaas-api")
+	build := version.Get()
 	appLogger.Info("Starting GoFlow API Server...", map[string]interface{}{
-		"version": "0.4.0",
-		"env":     getEnv("ENVIRONMENT", "development"),
+		"git_commit": build.GitCommit,
+		"build_date": build.BuildDate,
+		"go_version": build.GoVersion,
+		"env":        getEnv("ENVIRONMENT", "development"),
+	})
+
+	// ROLE=readonly runs a warm standby: it serves reads from the primary's database file
+	// and runs no scheduler, workers or background jobs (see server.RoleReadOnly)
+	role := getEnv("ROLE", server.RolePrimary)
+	if err := server.ValidateRole(role); err != nil {
+		log.Fatalf("Invalid role: %v", err)
+	}
+	readOnly := role == server.RoleReadOnly
+	appLogger.Info("Instance role", map[string]interface{}{
+		"role":        role,
+		"primary_url": getEnv("PRIMARY_URL", ""),
 	})
 
 	// Initialize database with retry logic for Docker/production environments
-	database, err := initializeDatabaseWithRetry(appLogger, 10, 2*time.Second)
+	database, err := initializeDatabaseWithRetry(appLogger, readOnly, 10, 2*time.Second)
 	if err != nil {
 		appLogger.Error("Failed to initialize database after retries", map[string]interface{}{
 			"error": err.Error(),
@@ -38,95 +62,195 @@ func main() {
 
 	appLogger.Info("Database initialized successfully", nil)
 
-	// Initialize executor with logger
-	executor := engine.NewExecutor(database, appLogger)
+	// Move credentials onto their tenant's current data key: legacy single-key rows, and
+	// rows left behind by an interrupted master (ENCRYPTION_KEY_PREVIOUS) or tenant key rotation
+	// A read-only instance leaves that to the primary
+	if !readOnly {
+		reencrypted, skipped, err := database.ReencryptCredentials()
+		if err != nil {
+			appLogger.Error("Failed to re-encrypt credentials", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else if reencrypted > 0 || skipped > 0 {
+			appLogger.Info("Re-encrypted credentials", map[string]interface{}{
+				"reencrypted": reencrypted,
+				"skipped":     skipped,
+			})
+		}
+	}
+
+	// Load per-connector unit costs (COST_TABLE_JSON / COST_TABLE_FILE)
+	costTable, err := costs.LoadTable()
+	if err != nil {
+		appLogger.Error("Failed to load cost table", map[string]interface{}{
+			"error": err.Error(),
+		})
+		log.Fatalf("Failed to load cost table: %v", err)
+	}
+
+	// Share one pooled outbound HTTP transport across connectors (OUTBOUND_* settings)
+	httpClients, err := loadHTTPClients()
+	if err != nil {
+		appLogger.Error("Failed to configure outbound HTTP clients", map[string]interface{}{
+			"error": err.Error(),
+		})
+		log.Fatalf("Failed to configure outbound HTTP clients: %v", err)
+	}
+	connectors.SetHTTPClients(httpClients)
+
+	// Load runtime-tunable settings (env, then the optional CONFIG_FILE)
+	configFile := os.Getenv("CONFIG_FILE")
+	settings, err := config.Load(configFile)
+	if err != nil {
+		appLogger.Error("Failed to load runtime config", map[string]interface{}{
+			"error": err.Error(),
+		})
+		log.Fatalf("Failed to load runtime config: %v", err)
+	}
+	runtimeConfig := config.NewManager(settings, database, appLogger)
+
+	// Initialize executor with logger (workers never start on a read-only instance)
+	newExecutor := engine.NewExecutor
+	if readOnly {
+		newExecutor = engine.NewStandbyExecutor
+	}
+	executor := newExecutor(database, appLogger)
+	executor.Metrics().WatchWriteRetries(database.WriteRetries)
+	executor.SetCostTable(costTable)
+	executor.ResizeWorkerPool(settings.WorkerPoolSize)
+	executor.SetBranchConcurrency(settings.BranchConcurrency)
+	executor.SetServiceLimits(settings.ServiceLimits)
+	executor.SetMaxTestingDelay(time.Duration(settings.MaxTestingDelay))
+	executor.SetWatchdogThresholds(getEnvDuration("WORKER_SOFT_TIMEOUT", engine.DefaultWorkerSoftTimeout),
+		getEnvDuration("WORKER_HARD_TIMEOUT", engine.DefaultWorkerHardTimeout))
 
 	// Initialize scheduler with logger (tenant-aware ready!)
 	scheduler := engine.NewScheduler(database, executor, appLogger)
-	scheduler.Start(60 * time.Second) // Check every 60 seconds
-	defer scheduler.Stop()
+	scheduler.SetLogAutoAckAge(getEnvDuration("LOG_AUTO_ACK_AFTER", 7*24*time.Hour))
+	scheduler.SetJitter(settings.SchedulerJitter)
+	scheduler.SetTenantConcurrency(settings.TenantConcurrency)
+	if !readOnly {
+		scheduler.Start(time.Duration(settings.SchedulerInterval))
+		defer scheduler.Stop()
+	}
 
-	// Setup router
-	router := mux.NewRouter()
+	// Verified database snapshots (see GET /api/admin/backups and devtool restore)
+	// SQLite only: a Postgres server is backed up with its own tools
+	backups := backup.NewManager(database, getEnv("BACKUP_DIR", "backups"), appLogger)
+	backups.SetSchedule(time.Duration(settings.BackupInterval), settings.BackupKeep, time.Duration(settings.BackupMaxAge))
+	if !readOnly && getEnv("DB_DRIVER", "sqlite") == "sqlite" {
+		backups.Start()
+		defer backups.Stop()
+	}
 
-	// Add request logging middleware (tracks all HTTP requests with status codes & timing)
-	router.Use(middleware.RequestLogger(appLogger))
+	// Per-tenant data retention (see GET /api/tenants/settings/retention)
+	retentionWorker := retention.NewWorker(database, appLogger)
+	retentionWorker.SetSchedule(time.Duration(settings.RetentionInterval), settings.RetentionBatchSize)
+	retentionWorker.SetDefaultLogsDays(settings.LogRetentionDays)
+	if !readOnly {
+		retentionWorker.Start()
+		defer retentionWorker.Stop()
+	}
 
-	// Public routes
-	authHandler := handlers.NewAuthHandler(database)
-	router.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST")
-	router.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST")
-	
-	// Dev mode endpoint (only enable in development)
-	if getEnv("ENVIRONMENT", "development") == "development" {
-		router.HandleFunc("/api/auth/dev-login", authHandler.DevLogin).Methods("POST")
-		appLogger.Info("Dev mode enabled - /api/auth/dev-login endpoint available", nil)
-	}
-
-	// Webhook handler (public but workflow-specific)
-	webhookHandler := handlers.NewWebhookHandler(database, executor)
-	router.HandleFunc("/api/webhooks/{id}", webhookHandler.TriggerWebhook).Methods("POST")
-
-	// Health check endpoint
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy","version":"0.2.0"}`))
-	}).Methods("GET")
-
-	// Protected routes with tenant-aware middleware
-	api := router.PathPrefix("/api").Subrouter()
-	api.Use(middleware.AuthMiddleware(appLogger)) // Now logs user_id AND tenant_id!
-
-	// Credentials routes
-	credentialsHandler := handlers.NewCredentialsHandler(database)
-	api.HandleFunc("/credentials", credentialsHandler.CreateCredential).Methods("POST")
-	api.HandleFunc("/credentials", credentialsHandler.GetCredentials).Methods("GET")
-
-	// Workflows routes
-	workflowsHandler := handlers.NewWorkflowsHandler(database, executor)
-	api.HandleFunc("/workflows", workflowsHandler.CreateWorkflow).Methods("POST")
-	api.HandleFunc("/workflows", workflowsHandler.GetWorkflows).Methods("GET")
-	api.HandleFunc("/workflows/dry-run", workflowsHandler.DryRunWorkflow).Methods("POST") // NEW: Dry run endpoint
-	api.HandleFunc("/workflows/{id}/toggle", workflowsHandler.ToggleWorkflow).Methods("PUT")
-	api.HandleFunc("/workflows/{id}", workflowsHandler.DeleteWorkflow).Methods("DELETE")
-
-	// Logs routes
-	logsHandler := handlers.NewLogsHandler(database)
-	api.HandleFunc("/logs", logsHandler.GetLogs).Methods("GET")
-
-	// Kong Gateway integration routes
-	kongHandler := handlers.NewKongHandler(database, getEnv("KONG_ADMIN_URL", "http://kong:8001"))
-	api.HandleFunc("/kong/services", kongHandler.CreateKongService).Methods("POST")
-	api.HandleFunc("/kong/services", kongHandler.ListKongServices).Methods("GET")
-	api.HandleFunc("/kong/services/{id}", kongHandler.DeleteKongService).Methods("DELETE")
-	api.HandleFunc("/kong/routes", kongHandler.CreateKongRoute).Methods("POST")
-	api.HandleFunc("/kong/plugins", kongHandler.AddKongPlugin).Methods("POST")
-	api.HandleFunc("/kong/templates", kongHandler.CreateUseCaseTemplate).Methods("POST")
+	// Self-service tenant data exports (see POST /api/tenants/export); links are signed
+	// with EXPORT_SIGNING_KEY, or the JWT secret when it isn't set
+	exports := export.NewManager(database, getEnv("EXPORT_DIR", "exports"),
+		[]byte(getEnv("EXPORT_SIGNING_KEY", string(middleware.GetJWTSecret()))), appLogger)
+	exports.SetLinkTTL(getEnvDuration("EXPORT_LINK_TTL", export.DefaultLinkTTL))
+	exports.SetRetention(retentionWorker)
+	if !readOnly {
+		exports.Start()
+		defer exports.Stop()
+	}
 
-	// PRODUCTION FIX: Use battle-tested CORS library instead of manual headers
-	corsHandler := cors.New(cors.Options{
-		AllowedOrigins: getAllowedOrigins(),
-		AllowedMethods: []string{
-			http.MethodGet,
-			http.MethodPost,
-			http.MethodPut,
-			http.MethodDelete,
-			http.MethodOptions,
-		},
-		AllowedHeaders: []string{
-			"Content-Type",
-			"Authorization",
-			"X-Requested-With",
-		},
-		ExposedHeaders: []string{
-			"Content-Length",
-			"Content-Type",
+	// Workflow change notifications to tenant members (see PUT /api/tenants/settings/notifications)
+	// Also delivers execution anomaly alerts (see PUT /api/tenants/settings/anomaly)
+	notifier := notify.NewNotifier(database, appLogger)
+	if !readOnly {
+		notifier.Start()
+		defer notifier.Stop()
+	}
+	executor.SetNotifier(notifier)
+
+	// Per-tenant API rate limits
+	// Tiers and overrides come from tenant_settings (PUT /api/admin/tenants/{id}/limits)
+	rateLimiter := middleware.NewRateLimiter(settings.RateLimitFree, settings.RateLimitPaid, settings.RateLimitBurst)
+	rateLimiter.UseTenantLimits(database, middleware.DefaultTenantLimitsTTL)
+
+	// Replay retried creates (IDEMPOTENCY_TTL, IDEMPOTENCY_MAX_ENTRIES)
+	idempotency := middleware.NewIdempotencyManager(getEnvDuration("IDEMPOTENCY_TTL", middleware.DefaultIdempotencyTTL),
+		getEnvInt("IDEMPOTENCY_MAX_ENTRIES", middleware.DefaultIdempotencyMaxEntries))
+
+	// Support impersonation: sessions are checked per request, optionally read-only
+	impersonation := middleware.NewImpersonationGuard(database, appLogger)
+	impersonation.SetReadOnly(settings.ImpersonationReadOnly)
+
+	// Apply settings changes in place (PUT /api/admin/config or SIGHUP) - no restart, no dropped work
+	runtimeConfig.Subscribe(func(s config.Settings) {
+		executor.ResizeWorkerPool(s.WorkerPoolSize)
+		executor.SetBranchConcurrency(s.BranchConcurrency)
+		executor.SetServiceLimits(s.ServiceLimits)
+		executor.SetMaxTestingDelay(time.Duration(s.MaxTestingDelay))
+		scheduler.SetInterval(time.Duration(s.SchedulerInterval))
+		scheduler.SetJitter(s.SchedulerJitter)
+		scheduler.SetTenantConcurrency(s.TenantConcurrency)
+		backups.SetSchedule(time.Duration(s.BackupInterval), s.BackupKeep, time.Duration(s.BackupMaxAge))
+		retentionWorker.SetSchedule(time.Duration(s.RetentionInterval), s.RetentionBatchSize)
+		retentionWorker.SetDefaultLogsDays(s.LogRetentionDays)
+		rateLimiter.SetLimits(s.RateLimitFree, s.RateLimitPaid, s.RateLimitBurst)
+		impersonation.SetReadOnly(s.ImpersonationReadOnly)
+	})
+
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			if err := runtimeConfig.Reload(configFile); err != nil {
+				appLogger.Error("Config reload failed, keeping current settings", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+	}()
+
+	// Setup router (routes live in internal/server so tests and the client SDK share them)
+	router := server.NewRouter(server.Config{
+		Store:        database,
+		Executor:     executor,
+		Logger:       appLogger,
+		CostTable:    costTable,
+		KongAdminURL: getEnv("KONG_ADMIN_URL", "http://kong:8001"),
+		KongAdmin: handlers.KongAdminConfig{
+			Token:       getEnv("KONG_ADMIN_TOKEN", ""),
+			TokenHeader: getEnv("KONG_ADMIN_TOKEN_HEADER", "Kong-Admin-Token"),
+			Timeout:     getEnvDuration("KONG_ADMIN_TIMEOUT", handlers.DefaultKongTimeout),
+			MaxRetries:  getEnvInt("KONG_ADMIN_RETRIES", handlers.DefaultKongRetries),
 		},
-		AllowCredentials: true,
-		MaxAge:           300, // 5 minutes
-		Debug:            getEnv("ENVIRONMENT", "development") == "development",
-	}).Handler(router)
+		DevLogin:     handlers.DevLoginEnabled(), // ENVIRONMENT=development and DEV_LOGIN_ENABLED=true
+
+		WebhookWaitTimeout: getEnvDuration("WEBHOOK_WAIT_TIMEOUT", handlers.DefaultWebhookWaitTimeout),
+
+		RuntimeConfig: runtimeConfig,
+		RateLimiter:   rateLimiter,
+		Idempotency:   idempotency,
+		AdminEmails:   parseCSV(getEnv("ADMIN_EMAILS", "")),
+		Impersonation: impersonation,
+		Backups:       backups,
+		Exports:       exports,
+		Notifier:      notifier,
+		Retention:     retentionWorker,
+
+		Role:       role,
+		PrimaryURL: getEnv("PRIMARY_URL", ""),
+	})
+
+	// PRODUCTION FIX: Use battle-tested CORS library instead of manual headers
+	// Origins, headers, credentials and max age come from the runtime config (reloadable)
+	corsMiddleware := middleware.NewCORS(settings.CORS.Options(), getEnv("ENVIRONMENT", "development") == "development")
+	runtimeConfig.Subscribe(func(s config.Settings) {
+		corsMiddleware.SetOptions(s.CORS.Options())
+	})
+	corsHandler := corsMiddleware.Handler(router)
 
 	// PRODUCTION FIX: Create HTTP server with proper timeouts
 	port := getEnv("PORT", "8080")
@@ -187,7 +311,10 @@ func main() {
 	})
 
 	// Graceful shutdown with timeout
+	// The HTTP server and the executor each get part of it, leaving the rest to close the database
 	shutdownTimeout := 30 * time.Second
+	httpShutdownTimeout := 10 * time.Second
+	executorShutdownTimeout := 15 * time.Second
 	ctx, cancel := context.WithTimeout(shutdownCtx, shutdownTimeout)
 	defer cancel()
 
@@ -196,17 +323,33 @@ func main() {
 	})
 
 	// Stop scheduler first
-	scheduler.Stop()
-	appLogger.Info("Scheduler stopped", nil)
+	if !readOnly {
+		scheduler.Stop()
+		appLogger.Info("Scheduler stopped", nil)
+	}
 
 	// Shutdown HTTP server
-	if err := srv.Shutdown(ctx); err != nil {
+	httpCtx, httpCancel := context.WithTimeout(ctx, httpShutdownTimeout)
+	defer httpCancel()
+	if err := srv.Shutdown(httpCtx); err != nil {
 		appLogger.Error("Server shutdown error", map[string]interface{}{
 			"error": err.Error(),
 		})
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Let queued and running workflows finish and write their logs before the database
+	// closes; the webhooks the server accepted last are among them
+	executorCtx, executorCancel := context.WithTimeout(ctx, executorShutdownTimeout)
+	defer executorCancel()
+	if err := executor.Shutdown(executorCtx); err != nil {
+		appLogger.Error("Executor shutdown error", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		appLogger.Info("Executor stopped", nil)
+	}
+
 	// Close database
 	database.Close()
 	appLogger.Info("Database closed", nil)
@@ -214,33 +357,6 @@ func main() {
 	appLogger.Info("Graceful shutdown complete", nil)
 }
 
-// getAllowedOrigins returns CORS allowed origins based on environment
-func getAllowedOrigins() []string {
-	env := getEnv("ENVIRONMENT", "development")
-	
-	if env == "production" {
-		// Production: Only allow specific domains
-		allowedOrigins := getEnv("CORS_ALLOWED_ORIGINS", "")
-		if allowedOrigins != "" {
-			// Parse comma-separated list
-			return parseCSV(allowedOrigins)
-		}
-		// Default production origins
-		return []string{
-			"https://app.ipaas.com",
-			"https://dashboard.ipaas.com",
-		}
-	}
-	
-	// Development: Allow localhost and common dev ports
-	return []string{
-		"http://localhost:3000",
-		"http://localhost:3001",
-		"http://localhost:8080",
-		"http://127.0.0.1:3000",
-	}
-}
-
 // parseCSV splits a comma-separated string into a slice
 func parseCSV(s string) []string {
 	if s == "" {
@@ -306,20 +422,106 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvDuration parses a duration environment variable (e.g., "168h") with a default fallback
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvInt parses an integer environment variable with a default fallback
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// loadHTTPClients builds the connectors' shared HTTP client provider from the environment
+// OUTBOUND_PROXY_URL, OUTBOUND_CA_BUNDLE (a PEM file), OUTBOUND_MAX_IDLE_CONNS,
+// OUTBOUND_MAX_IDLE_CONNS_PER_HOST, OUTBOUND_TIMEOUT and OUTBOUND_CONNECTOR_TIMEOUTS
+// ("salesforce=60s,soap=45s"). Without a proxy URL, HTTP_PROXY/HTTPS_PROXY apply
+func loadHTTPClients() (*connectors.HTTPClientProvider, error) {
+	config := connectors.HTTPClientConfig{
+		ProxyURL:       getEnv("OUTBOUND_PROXY_URL", ""),
+		DefaultTimeout: getEnvDuration("OUTBOUND_TIMEOUT", connectors.DefaultConnectorTimeout),
+	}
+	if path := getEnv("OUTBOUND_CA_BUNDLE", ""); path != "" {
+		bundle, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading OUTBOUND_CA_BUNDLE: %w", err)
+		}
+		config.CABundle = string(bundle)
+	}
+	for key, target := range map[string]*int{
+		"OUTBOUND_MAX_IDLE_CONNS":          &config.MaxIdleConns,
+		"OUTBOUND_MAX_IDLE_CONNS_PER_HOST": &config.MaxIdleConnsPerHost,
+	} {
+		if value := getEnv(key, ""); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%s must be a positive number", key)
+			}
+			*target = n
+		}
+	}
+	timeouts, err := connectors.ParseConnectorTimeouts(getEnv("OUTBOUND_CONNECTOR_TIMEOUTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("OUTBOUND_CONNECTOR_TIMEOUTS: %w", err)
+	}
+	config.ConnectorTimeouts = timeouts
+	return connectors.NewHTTPClientProvider(config)
+}
+
 // initializeDatabaseWithRetry attempts to initialize the database with exponential backoff
 // This is critical for Docker environments where the DB container might not be ready immediately
-func initializeDatabaseWithRetry(logger *logger.Logger, maxRetries int, initialDelay time.Duration) (*db.Database, error) {
+// DB_DRIVER picks SQLite (DB_PATH, the default) or Postgres (DATABASE_URL). A read-only
+// SQLite instance opens the primary's file without touching its schema; with Postgres
+// every replica connects to the same server and the idempotent schema setup is shared
+func initializeDatabaseWithRetry(logger *logger.Logger, readOnly bool, maxRetries int, initialDelay time.Duration) (*db.Database, error) {
+	driver := getEnv("DB_DRIVER", "sqlite")
 	dbPath := getEnv("DB_PATH", "ipaas.db")
+	busyTimeout := getEnvDuration("SQLITE_BUSY_TIMEOUT", db.DefaultBusyTimeout)
 	delay := initialDelay
+	var open func(string) (*db.Database, error)
+	switch driver {
+	case "sqlite":
+		options := db.SQLiteOptions{
+			BusyTimeout: busyTimeout,
+			ReadOnly:    readOnly,
+		}
+		open = func(path string) (*db.Database, error) {
+			return db.OpenSQLite(path, options)
+		}
+	case "postgres":
+		dbPath = os.Getenv("DATABASE_URL")
+		if dbPath == "" {
+			return nil, fmt.Errorf("DATABASE_URL is required with DB_DRIVER=postgres")
+		}
+		open = db.NewPostgres
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q (use sqlite or postgres)", driver)
+	}
 
-	logger.Info("Initializing database with retry logic", map[string]interface{}{
-		"db_path":     dbPath,
+	fields := map[string]interface{}{
+		"driver":      driver,
+		"read_only":   readOnly,
 		"max_retries": maxRetries,
-	})
+	}
+	if driver == "sqlite" {
+		fields["db_path"] = dbPath // DATABASE_URL isn't logged: it carries the password
+		fields["busy_timeout"] = busyTimeout.String()
+	}
+	logger.Info("Initializing database with retry logic", fields)
 
 	var err error
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		database, err := db.New(dbPath)
+		database, err := open(dbPath)
 		if err == nil {
 			// Success! Test the connection with a simple query
 			pingErr := database.Ping()
@@ -0,0 +1,220 @@
+// Command devtool bundles developer utilities for working on GoFlow.
+//
+// Usage:
+//
+//	devtool generate-connector --openapi spec.yaml --name foo [--operations listFoos,getFoo]
+//	devtool restore --snapshot backups/snapshot-20240101T000000.000Z.db --out restored.db
+//	NEW_ENCRYPTION_KEY=... devtool rotate-encryption-key --db ipaas.db
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/backup"
+	"github.com/alexmacdonald/simple-ipass/internal/connectorgen"
+	"github.com/alexmacdonald/simple-ipass/internal/crypto"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "devtool:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches a subcommand
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		usage(out)
+		return errors.New("missing command")
+	}
+
+	switch args[0] {
+	case "generate-connector":
+		return generateConnector(args[1:], out)
+	case "restore":
+		return restore(args[1:], out)
+	case "rotate-encryption-key":
+		return rotateEncryptionKey(args[1:], out)
+	case "help", "-h", "--help":
+		usage(out)
+		return nil
+	default:
+		usage(out)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func usage(out io.Writer) {
+	fmt.Fprintln(out, `Usage: devtool <command> [flags]
+
+Commands:
+  generate-connector     Scaffold a REST connector and its tests from an OpenAPI 3 spec
+  restore                Restore a database snapshot into a new file and verify it against its manifest
+  rotate-encryption-key  Re-encrypt every credential from ENCRYPTION_KEY to NEW_ENCRYPTION_KEY`)
+}
+
+// generateConnector writes a connector skeleton generated from an OpenAPI spec
+func generateConnector(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("generate-connector", flag.ContinueOnError)
+	flags.SetOutput(out)
+	specPath := flags.String("openapi", "", "Path to the OpenAPI 3 spec (YAML or JSON)")
+	name := flags.String("name", "", "Connector name in snake_case (e.g. pet_store); also the credential service name")
+	operations := flags.String("operations", "", "Comma-separated operationIds to include (default: all)")
+	baseURL := flags.String("base-url", "", "Override the spec's server URL")
+	actionType := flags.String("action", "", "Workflow action type (default: <name>_call)")
+	outDir := flags.String("out", filepath.Join("internal", "engine", "connectors"), "Output directory")
+	importPath := flags.String("import", connectorgen.DefaultImportPath, "Import path of the output directory (used by the generated test)")
+	force := flags.Bool("force", false, "Overwrite existing files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *specPath == "" || *name == "" {
+		flags.Usage()
+		return errors.New("--openapi and --name are required")
+	}
+
+	spec, err := connectorgen.LoadSpec(*specPath)
+	if err != nil {
+		return err
+	}
+
+	opts := connectorgen.Options{
+		Name:       *name,
+		BaseURL:    *baseURL,
+		ActionType: *actionType,
+		ImportPath: *importPath,
+	}
+	for _, id := range strings.Split(*operations, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			opts.Operations = append(opts.Operations, id)
+		}
+	}
+
+	files, err := connectorgen.Generate(spec, opts)
+	if err != nil {
+		return err
+	}
+
+	// Check every file first so a refused overwrite writes nothing
+	if !*force {
+		for _, file := range files {
+			path := filepath.Join(*outDir, file.Name)
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+			}
+		}
+	}
+	for _, file := range files {
+		path := filepath.Join(*outDir, file.Name)
+		if err := os.WriteFile(path, file.Content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Fprintln(out, "wrote", path)
+	}
+
+	action := opts.ActionType
+	if action == "" {
+		action = *name + "_call"
+	}
+	fmt.Fprintf(out, `
+Next steps:
+  1. Review the generated config fields and result data
+  2. Run: go test ./%s/...
+  3. Register %q in the executor's connector registry
+     (builtinConnectors in engine/connector_registry.go)
+     and add its config fields to models.WorkflowConfig
+`, filepath.ToSlash(*outDir), action)
+	return nil
+}
+
+// restore copies a backup snapshot to a new database file and checks it against the manifest
+// Point DB_PATH at the restored file (with the API stopped) to use it
+func restore(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(out)
+	snapshot := flags.String("snapshot", "", "Path to the snapshot (its .json manifest must sit next to it)")
+	target := flags.String("out", "", "Path of the database file to create (must not exist)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *snapshot == "" || *target == "" {
+		flags.Usage()
+		return errors.New("--snapshot and --out are required")
+	}
+
+	manifest, err := backup.Restore(*snapshot, *target)
+	if err != nil {
+		return err
+	}
+
+	var rows int64
+	for _, count := range manifest.Tables {
+		rows += count
+	}
+	fmt.Fprintf(out, "restored %s (taken %s) to %s: integrity ok, %d tables and %d rows match the manifest\n",
+		manifest.Snapshot, manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), *target, len(manifest.Tables), rows)
+	return nil
+}
+
+// rotateEncryptionKey re-wraps every credential under a new master key
+// The keys come from the environment so they stay out of shell history: ENCRYPTION_KEY is
+// the key in use, NEW_ENCRYPTION_KEY the one to move to. Each batch commits on its own, so
+// an interrupted run is resumed by running it again; swapping the two keys rolls back
+func rotateEncryptionKey(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("rotate-encryption-key", flag.ContinueOnError)
+	flags.SetOutput(out)
+	dbPath := flags.String("db", "ipaas.db", "Path of the SQLite database")
+	databaseURL := flags.String("database-url", "", "Postgres connection URL (instead of --db)")
+	batchSize := flags.Int("batch", 100, "Credentials re-encrypted per transaction")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	oldKey, newKey := os.Getenv("ENCRYPTION_KEY"), os.Getenv("NEW_ENCRYPTION_KEY")
+	if _, err := crypto.ParseKey(oldKey); err != nil {
+		return fmt.Errorf("ENCRYPTION_KEY: %w", err)
+	}
+	if _, err := crypto.ParseKey(newKey); err != nil {
+		return fmt.Errorf("NEW_ENCRYPTION_KEY: %w", err)
+	}
+	if oldKey == newKey {
+		return errors.New("NEW_ENCRYPTION_KEY is the key already in use")
+	}
+
+	// Seal under the new key; rows still under the old one decrypt with it as the previous key
+	os.Setenv("ENCRYPTION_KEY_PREVIOUS", oldKey)
+	os.Setenv("ENCRYPTION_KEY", newKey)
+
+	var database *db.Database
+	var err error
+	if *databaseURL != "" {
+		database, err = db.NewPostgres(*databaseURL)
+	} else {
+		database, err = db.New(*dbPath)
+	}
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	reencrypted, skipped, err := database.ReencryptCredentialsInBatches(*batchSize, func(p db.ReencryptProgress) {
+		fmt.Fprintf(out, "%d/%d credentials: %d re-encrypted, %d skipped\n", p.Done, p.Total, p.Reencrypted, p.Skipped)
+	})
+	if err != nil {
+		return fmt.Errorf("re-encryption stopped after %d credentials (run again to resume): %w", reencrypted+skipped, err)
+	}
+
+	fmt.Fprintf(out, "done: %d re-encrypted under key %s, %d skipped (could not be decrypted)\n",
+		reencrypted, crypto.MasterKeyID(crypto.GetEncryptionKey()), skipped)
+	fmt.Fprintln(out, "Set ENCRYPTION_KEY to the new key and ENCRYPTION_KEY_PREVIOUS to the old one and restart;")
+	fmt.Fprintln(out, "the API re-wraps anything written meanwhile at startup. Drop ENCRYPTION_KEY_PREVIOUS after that.")
+	return nil
+}
@@ -6,7 +6,7 @@ import Image from 'next/image'
 import { useRouter } from 'next/navigation'
 import ProtectedLayout from '@/components/ProtectedRoute'
 import { ErrorBoundary } from '@/components/ErrorBoundary'
-import { clearToken } from '@/lib/api'
+import { auth } from '@/lib/api'
 import { Button } from '@/components/ui/button'
 
 export default function DashboardLayout({
@@ -26,8 +26,8 @@ export default function DashboardLayout({
     else setActiveTab('dashboard')
   }, [])
 
-  const handleLogout = () => {
-    clearToken()
+  const handleLogout = async () => {
+    await auth.logout()
     router.push('/login')
   }
 
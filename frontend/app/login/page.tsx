@@ -30,7 +30,7 @@ export default function LoginPage() {
       
       if (response.token || response.data?.token) {
         const token = response.token || response.data.token
-        setToken(token)
+        setToken(token, response.refresh_token || response.data?.refresh_token)
         router.push('/dashboard')
       } else {
         setError('Login failed. Please check your credentials.')
@@ -49,10 +49,10 @@ export default function LoginPage() {
     setLoading(true)
 
     try {
-      const response = await api.post('/auth/dev-login')
+      const response = await api.post('/auth/dev-login', { email: 'dev@goflow.local' })
       
       if (response.token) {
-        setToken(response.token)
+        setToken(response.token, response.refresh_token)
         router.push('/dashboard')
       } else {
         setError('Dev login failed.')
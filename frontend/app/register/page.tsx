@@ -40,7 +40,7 @@ export default function RegisterPage() {
       
       if (response.token || response.data?.token) {
         const token = response.token || response.data.token
-        setToken(token)
+        setToken(token, response.refresh_token || response.data?.refresh_token)
         router.push('/dashboard')
       } else {
         setError('Registration failed. Please try again.')
@@ -9,18 +9,62 @@ const getToken = (): string | null => {
   return null;
 };
 
-// Set auth token in localStorage
-export const setToken = (token: string): void => {
+// Get the refresh token that renews the short-lived access token
+const getRefreshToken = (): string | null => {
+  if (typeof window !== 'undefined') {
+    return localStorage.getItem('refresh_token');
+  }
+  return null;
+};
+
+// Set auth token (and the refresh token issued with it) in localStorage
+export const setToken = (token: string, refreshToken?: string): void => {
   if (typeof window !== 'undefined') {
     localStorage.setItem('token', token);
+    if (refreshToken) {
+      localStorage.setItem('refresh_token', refreshToken);
+    }
   }
 };
 
-// Clear auth token
+// Clear auth and refresh tokens
 export const clearToken = (): void => {
   if (typeof window !== 'undefined') {
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+  }
+};
+
+// Exchange the refresh token for a new token pair
+// Concurrent 401s share one refresh, since each refresh token works only once
+let refreshing: Promise<boolean> | null = null;
+const refreshSession = (): Promise<boolean> => {
+  if (refreshing) {
+    return refreshing;
+  }
+  const refreshToken = getRefreshToken();
+  if (!refreshToken) {
+    return Promise.resolve(false);
   }
+  const pending = fetch(`${API_BASE_URL}/auth/refresh`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ refresh_token: refreshToken }),
+  })
+    .then(async (response) => {
+      if (!response.ok) {
+        return false;
+      }
+      const data = await response.json();
+      setToken(data.token, data.refresh_token);
+      return true;
+    })
+    .catch(() => false)
+    .finally(() => {
+      refreshing = null;
+    });
+  refreshing = pending;
+  return pending;
 };
 
 // Check if user is authenticated
@@ -30,7 +74,8 @@ export const isAuthenticated = (): boolean => {
 
 // API client with JWT injection
 // TODO: MULTI-TENANT - Add tenant_id to headers
-async function apiClient(endpoint: string, options: RequestInit = {}) {
+// An expired access token is refreshed once and the request retried
+async function apiClient(endpoint: string, options: RequestInit = {}, retried = false): Promise<Response> {
   const token = getToken();
   
   const headers: Record<string, string> = {
@@ -47,6 +92,12 @@ async function apiClient(endpoint: string, options: RequestInit = {}) {
     headers,
   });
 
+  if (response.status === 401 && token && !retried && !endpoint.startsWith('/auth/')) {
+    if (await refreshSession()) {
+      return apiClient(endpoint, options, true);
+    }
+  }
+
   if (response.status === 401) {
     clearToken();
     if (typeof window !== 'undefined') {
@@ -95,6 +146,23 @@ export const auth = {
     
     return data;
   },
+
+  // Revoke this device's refresh token; the tokens are cleared even if that fails
+  logout: async () => {
+    const refreshToken = getRefreshToken();
+    if (refreshToken) {
+      try {
+        await fetch(`${API_BASE_URL}/auth/logout`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+      } catch (err) {
+        console.error('Logout error:', err);
+      }
+    }
+    clearToken();
+  },
 };
 
 // Credentials API
@@ -0,0 +1,202 @@
+// Package anomaly spots workflows that got slower or flakier than their own history.
+//
+// It only does the math; the store keeps each workflow's baselines and the engine
+// decides what to do with a finding. Durations are tracked as two exponentially
+// weighted moving averages: a fast one over the last few executions and a slow
+// long-term baseline. Gradual drift moves the baseline along with it, so only a
+// sudden regression makes the fast average pull away. Failure rates are compared
+// hour by hour: the current hour alarms when it fails more often than any hour of
+// the past week did.
+package anomaly
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// Smoothing of the duration averages: the weight a new execution gets
+const (
+	RecentAlpha   = 0.2  // Roughly the last 10 executions
+	BaselineAlpha = 0.01 // Roughly the last 200 executions
+)
+
+// Defaults for thresholds a tenant didn't override
+const (
+	DefaultDurationFactor = 2.0 // Recent average twice the baseline
+	DefaultFailureRate    = 0.2 // An hour failing less than this never alarms
+	DefaultMinExecutions  = 5
+	DefaultMinSamples     = 20
+)
+
+// MinRegressionMS is how much slower than the baseline recent executions must be, at least,
+// so jitter in workflows that take a few milliseconds never alarms
+const MinRegressionMS = 100
+
+// MinHistoryHours is how many hours with executions the past week needs before
+// the current hour's failure rate is compared against it
+const MinHistoryHours = 6
+
+// HistoryWindow is how far back failure rates are compared
+const HistoryWindow = 7 * 24 * time.Hour
+
+// Finding kinds
+const (
+	KindDuration    = "duration_regression"
+	KindFailureRate = "failure_rate_regression"
+)
+
+// Thresholds decide when a workflow's latest executions count as a regression
+type Thresholds struct {
+	Disabled       bool    `json:"disabled"`
+	DurationFactor float64 `json:"duration_factor"`
+	FailureRate    float64 `json:"failure_rate"`
+	MinExecutions  int     `json:"min_executions"`
+	MinSamples     int64   `json:"min_samples"`
+}
+
+// ThresholdsFor applies a tenant's overrides to the defaults; nil means defaults
+func ThresholdsFor(settings *models.AnomalySettings) Thresholds {
+	t := Thresholds{
+		DurationFactor: DefaultDurationFactor,
+		FailureRate:    DefaultFailureRate,
+		MinExecutions:  DefaultMinExecutions,
+		MinSamples:     DefaultMinSamples,
+	}
+	if settings == nil {
+		return t
+	}
+	t.Disabled = settings.Disabled
+	if settings.DurationFactor > 0 {
+		t.DurationFactor = settings.DurationFactor
+	}
+	if settings.FailureRate > 0 {
+		t.FailureRate = settings.FailureRate
+	}
+	if settings.MinExecutions > 0 {
+		t.MinExecutions = settings.MinExecutions
+	}
+	if settings.MinSamples > 0 {
+		t.MinSamples = int64(settings.MinSamples)
+	}
+	return t
+}
+
+// Validate checks a tenant's overrides are usable
+func Validate(settings models.AnomalySettings) error {
+	if settings.DurationFactor != 0 && (settings.DurationFactor <= 1 || settings.DurationFactor > 100) {
+		return fmt.Errorf("duration_factor must be above 1 and at most 100")
+	}
+	if settings.FailureRate < 0 || settings.FailureRate > 1 {
+		return fmt.Errorf("failure_rate must be between 0 and 1")
+	}
+	if settings.MinExecutions < 0 || settings.MinSamples < 0 {
+		return fmt.Errorf("min_executions and min_samples can't be negative")
+	}
+	return nil
+}
+
+// Durations are a workflow's duration averages, in milliseconds
+type Durations struct {
+	Recent   float64
+	Baseline float64
+	Samples  int64
+}
+
+// Update folds one execution's duration into the averages
+// The first sample seeds both, so a new workflow starts with no regression
+func (d Durations) Update(durationMS float64) Durations {
+	if d.Samples == 0 {
+		return Durations{Recent: durationMS, Baseline: durationMS, Samples: 1}
+	}
+	return Durations{
+		Recent:   decay(d.Recent, durationMS, RecentAlpha),
+		Baseline: decay(d.Baseline, durationMS, BaselineAlpha),
+		Samples:  d.Samples + 1,
+	}
+}
+
+// decay moves an average towards a sample by alpha
+func decay(average, sample, alpha float64) float64 {
+	return average + alpha*(sample-average)
+}
+
+// Finding is a regression: what was observed and what it was compared against
+type Finding struct {
+	Kind     string  `json:"kind"`
+	Observed float64 `json:"observed"` // Recent duration (ms) or this hour's failure rate
+	Expected float64 `json:"expected"` // Baseline duration (ms) or the week's worst hourly failure rate
+}
+
+// CheckDuration reports a duration regression once the baseline has enough samples
+func CheckDuration(d Durations, t Thresholds) (Finding, bool) {
+	if t.Disabled || d.Samples < t.MinSamples || d.Baseline <= 0 {
+		return Finding{}, false
+	}
+	if d.Recent <= d.Baseline*t.DurationFactor || d.Recent-d.Baseline < MinRegressionMS {
+		return Finding{}, false
+	}
+	return Finding{Kind: KindDuration, Observed: d.Recent, Expected: d.Baseline}, true
+}
+
+// CheckFailureRate compares the hour containing now with the previous week
+// hours holds hour buckets in any order; buckets outside the week are ignored
+func CheckFailureRate(hours []models.StatHour, now time.Time, t Thresholds) (Finding, bool) {
+	if t.Disabled {
+		return Finding{}, false
+	}
+
+	currentHour := now.UTC().Truncate(time.Hour)
+	var current models.StatHour
+	worst := 0.0
+	history := 0
+	for _, hour := range hours {
+		start := hour.Hour.UTC()
+		switch {
+		case start.Equal(currentHour):
+			current = hour
+		case start.Before(currentHour) && !start.Before(currentHour.Add(-HistoryWindow)):
+			if executions(hour) == 0 {
+				continue
+			}
+			history++
+			if rate := FailureRate(hour); rate > worst {
+				worst = rate
+			}
+		}
+	}
+
+	if history < MinHistoryHours || executions(current) < t.MinExecutions {
+		return Finding{}, false
+	}
+	rate := FailureRate(current)
+	if rate < t.FailureRate || rate <= worst {
+		return Finding{}, false
+	}
+	return Finding{Kind: KindFailureRate, Observed: rate, Expected: worst}, true
+}
+
+// FailureRate is the share of an hour's executions that failed
+func FailureRate(hour models.StatHour) float64 {
+	if executions(hour) == 0 {
+		return 0
+	}
+	return float64(hour.Failures) / float64(executions(hour))
+}
+
+// executions counts an hour's executions
+func executions(hour models.StatHour) int {
+	return hour.Successes + hour.Failures
+}
+
+// Describe renders a finding for logs and notifications
+func Describe(f Finding) string {
+	switch f.Kind {
+	case KindDuration:
+		return fmt.Sprintf("recent executions average %.0fms against a %.0fms baseline (%.1fx)", f.Observed, f.Expected, f.Observed/f.Expected)
+	case KindFailureRate:
+		return fmt.Sprintf("%.0f%% of this hour's executions failed; the worst hour of the past week was %.0f%%", f.Observed*100, f.Expected*100)
+	}
+	return f.Kind
+}
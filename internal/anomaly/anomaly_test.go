@@ -0,0 +1,115 @@
+package anomaly_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/anomaly"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestDurationRegressionAlarmsOnSuddenJump feeds a steady workflow a sudden slowdown
+func TestDurationRegressionAlarmsOnSuddenJump(t *testing.T) {
+	thresholds := anomaly.ThresholdsFor(nil)
+
+	var d anomaly.Durations
+	for i := 0; i < 100; i++ {
+		d = d.Update(200)
+	}
+	if _, found := anomaly.CheckDuration(d, thresholds); found {
+		t.Fatalf("Expected a steady workflow to be fine, got %+v", d)
+	}
+
+	for i := 0; i < 10; i++ {
+		d = d.Update(1000)
+	}
+	finding, found := anomaly.CheckDuration(d, thresholds)
+	if !found || finding.Kind != anomaly.KindDuration {
+		t.Fatalf("Expected a duration regression, got %+v (%+v)", finding, d)
+	}
+	if finding.Expected >= 400 || finding.Observed <= 800 {
+		t.Errorf("Expected the baseline to lag the recent average, got %+v", finding)
+	}
+}
+
+// TestDurationGradualDriftDoesNotAlarm slows a workflow down slowly enough for the baseline to follow
+func TestDurationGradualDriftDoesNotAlarm(t *testing.T) {
+	thresholds := anomaly.ThresholdsFor(nil)
+
+	var d anomaly.Durations
+	duration := 200.0
+	for i := 0; i < 2000; i++ {
+		duration *= 1.001 // About 7x slower by the end
+		d = d.Update(duration)
+		if finding, found := anomaly.CheckDuration(d, thresholds); found {
+			t.Fatalf("Execution %d: expected gradual drift not to alarm, got %+v", i, finding)
+		}
+	}
+}
+
+// TestDurationNeedsMinSamples checks a young baseline is never trusted
+func TestDurationNeedsMinSamples(t *testing.T) {
+	thresholds := anomaly.ThresholdsFor(&models.AnomalySettings{MinSamples: 50})
+
+	d := anomaly.Durations{}.Update(10)
+	for i := 0; i < 10; i++ {
+		d = d.Update(5000)
+	}
+	if _, found := anomaly.CheckDuration(d, thresholds); found {
+		t.Errorf("Expected no finding with %d samples", d.Samples)
+	}
+}
+
+// week returns hourly buckets for the week before now, each with the given outcomes
+func week(now time.Time, successes, failures int) []models.StatHour {
+	var hours []models.StatHour
+	current := now.UTC().Truncate(time.Hour)
+	for i := 1; i <= 24*7; i++ {
+		hours = append(hours, models.StatHour{Hour: current.Add(-time.Duration(i) * time.Hour), Successes: successes, Failures: failures})
+	}
+	return hours
+}
+
+func TestFailureRateRegression(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)
+	thisHour := func(successes, failures int) models.StatHour {
+		return models.StatHour{Hour: now.Truncate(time.Hour), Successes: successes, Failures: failures}
+	}
+
+	tests := []struct {
+		name       string
+		hours      []models.StatHour
+		thresholds anomaly.Thresholds
+		want       bool
+	}{
+		{"worse than any hour of the week", append(week(now, 9, 1), thisHour(5, 5)), anomaly.ThresholdsFor(nil), true},
+		{"as bad as a past hour", append(append(week(now, 9, 1), models.StatHour{Hour: now.Add(-3 * time.Hour).Truncate(time.Hour), Failures: 10}), thisHour(5, 5)), anomaly.ThresholdsFor(nil), false},
+		{"too few executions this hour", append(week(now, 9, 1), thisHour(1, 3)), anomaly.ThresholdsFor(nil), false},
+		{"below the tenant's floor", append(week(now, 10, 0), thisHour(9, 1)), anomaly.ThresholdsFor(nil), false},
+		{"lower floor set by the tenant", append(week(now, 10, 0), thisHour(9, 1)), anomaly.ThresholdsFor(&models.AnomalySettings{FailureRate: 0.05}), true},
+		{"no history yet", []models.StatHour{thisHour(0, 10)}, anomaly.ThresholdsFor(nil), false},
+		{"disabled", append(week(now, 9, 1), thisHour(0, 10)), anomaly.ThresholdsFor(&models.AnomalySettings{Disabled: true}), false},
+	}
+	for _, tt := range tests {
+		finding, found := anomaly.CheckFailureRate(tt.hours, now, tt.thresholds)
+		if found != tt.want {
+			t.Errorf("%s: expected found=%t, got %+v", tt.name, tt.want, finding)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, settings := range []models.AnomalySettings{
+		{DurationFactor: 1},
+		{DurationFactor: 0.5},
+		{FailureRate: 1.5},
+		{MinExecutions: -1},
+	} {
+		if err := anomaly.Validate(settings); err == nil {
+			t.Errorf("Expected %+v to be rejected", settings)
+		}
+	}
+	if err := anomaly.Validate(models.AnomalySettings{DurationFactor: 3, FailureRate: 0.5, Notify: true}); err != nil {
+		t.Errorf("Expected valid settings to pass, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Package audit records who changed workflows, credentials and sign-ins, and when
+package audit
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// Store is the part of db.Store the recorder writes to
+type Store interface {
+	RecordAuditEvent(event *models.AuditEvent) error
+}
+
+// Recorder writes audit events for changes made through the API
+type Recorder struct {
+	store Store
+}
+
+// NewRecorder creates a recorder writing to the store
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{store: store}
+}
+
+// Record writes an event for a request. The actor, tenant and impersonator come from the
+// request's token unless the event sets them (sign-ins have no token yet); changes are
+// stored masked, so secrets in them never reach the audit trail
+func (r *Recorder) Record(req *http.Request, event models.AuditEvent, changes map[string]interface{}) error {
+	ctx := req.Context()
+	if event.ActorID == "" {
+		event.ActorID, _ = middleware.GetUserIDFromContext(ctx)
+	}
+	if event.TenantID == "" {
+		event.TenantID, _ = middleware.GetTenantIDFromContext(ctx)
+	}
+	if impersonatorID, sessionID, ok := middleware.GetImpersonationFromContext(ctx); ok {
+		event.ImpersonatorID, event.SessionID = impersonatorID, sessionID
+	}
+	event.Method, event.Path = req.Method, req.URL.Path
+	event.SourceIP = SourceIP(req)
+	if changes != nil {
+		if detail, err := json.Marshal(utils.MaskMap(changes)); err == nil {
+			event.Detail = string(detail)
+		}
+	}
+	return r.store.RecordAuditEvent(&event)
+}
+
+// Denied records a change refused with 403: action names what was attempted
+// It is filed under the resource's tenant, so its admins see attempts from outside too
+func (r *Recorder) Denied(req *http.Request, action, resourceType, resourceID, tenantID string) error {
+	return r.Record(req, models.AuditEvent{
+		Action:       models.AuditAccessDenied,
+		TenantID:     tenantID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		StatusCode:   http.StatusForbidden,
+	}, map[string]interface{}{"attempted": action})
+}
+
+// SourceIP is the address the request came from (the connection's, not forwarded headers,
+// which any caller can set)
+func SourceIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
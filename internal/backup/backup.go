@@ -0,0 +1,475 @@
+// Package backup takes verified snapshots of the SQLite database and restores them.
+//
+// Each snapshot is written with VACUUM INTO, checked with PRAGMA integrity_check,
+// and stored next to a JSON manifest holding its checksum and per-table row counts.
+// Restores refuse to overwrite an existing file and compare the restored copy
+// against the manifest before it is put in place.
+package backup
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// maxHistory bounds how many backup outcomes are kept for the admin listing
+const maxHistory = 20
+
+// snapshotPrefix and snapshotTimeFormat name snapshot files so they sort by age
+const (
+	snapshotPrefix     = "snapshot-"
+	snapshotTimeFormat = "20060102T150405.000Z"
+)
+
+// Source is a database that can copy itself to a new file
+type Source interface {
+	BackupTo(path string) error
+}
+
+// Manifest describes one snapshot; it is written next to it as <snapshot>.json
+type Manifest struct {
+	Snapshot  string           `json:"snapshot"` // File name of the snapshot, relative to the manifest
+	CreatedAt time.Time        `json:"created_at"`
+	SizeBytes int64            `json:"size_bytes"`
+	SHA256    string           `json:"sha256"`
+	Tables    map[string]int64 `json:"tables"` // Row count of every table at backup time
+}
+
+// Outcome records one backup attempt
+type Outcome struct {
+	StartedAt  time.Time `json:"started_at"`
+	Status     string    `json:"status"` // 'success' or 'failed'
+	Snapshot   string    `json:"snapshot,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Pruned     int       `json:"pruned"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Manager takes snapshots on a schedule and prunes old ones
+type Manager struct {
+	source Source
+	dir    string
+	log    *logger.Logger
+
+	runMu sync.Mutex // One backup at a time
+
+	mu       sync.Mutex
+	interval time.Duration // 0 disables scheduled backups
+	keep     int           // Snapshots kept by count (0 = no limit)
+	maxAge   time.Duration // Snapshots older than this are pruned (0 = no limit)
+	history  []Outcome     // Most recent last
+	changed  chan struct{}
+	done     chan struct{}
+}
+
+// NewManager creates a manager writing snapshots of source to dir
+func NewManager(source Source, dir string, log *logger.Logger) *Manager {
+	return &Manager{
+		source:  source,
+		dir:     dir,
+		log:     log,
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// Dir returns the directory snapshots are written to
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// SetSchedule changes the backup interval and retention at runtime
+// An interval of 0 pauses scheduled backups; the change applies from the next wait
+func (m *Manager) SetSchedule(interval time.Duration, keep int, maxAge time.Duration) {
+	m.mu.Lock()
+	changed := interval != m.interval
+	m.interval, m.keep, m.maxAge = interval, keep, maxAge
+	m.mu.Unlock()
+
+	if changed {
+		m.log.Info("Backup schedule changed", map[string]interface{}{
+			"interval": interval.String(),
+			"keep":     keep,
+			"max_age":  maxAge.String(),
+		})
+		select {
+		case m.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start runs scheduled backups until Stop is called
+func (m *Manager) Start() {
+	m.log.Info("Backup manager started", map[string]interface{}{
+		"dir":      m.dir,
+		"interval": m.currentInterval().String(),
+	})
+
+	go func() {
+		for {
+			// A nil channel never fires, so a zero interval just waits for a change
+			var tick <-chan time.Time
+			var timer *time.Timer
+			if interval := m.currentInterval(); interval > 0 {
+				timer = time.NewTimer(interval)
+				tick = timer.C
+			}
+			select {
+			case <-tick:
+				m.Run()
+			case <-m.changed:
+			case <-m.done:
+				m.log.Info("Backup manager stopped", nil)
+				return
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// Stop stops scheduled backups; a backup already running finishes
+func (m *Manager) Stop() {
+	close(m.done)
+}
+
+func (m *Manager) currentInterval() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.interval
+}
+
+// Run takes, verifies and records one snapshot, then prunes old ones
+func (m *Manager) Run() Outcome {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	start := time.Now()
+	outcome := Outcome{StartedAt: start, Status: "success"}
+	manifest, err := m.snapshot(start)
+	if err == nil {
+		outcome.Snapshot = manifest.Snapshot
+		outcome.Pruned, err = m.prune(start)
+	}
+	outcome.DurationMS = time.Since(start).Milliseconds()
+
+	if err != nil {
+		outcome.Status = "failed"
+		outcome.Error = err.Error()
+		m.log.Error("Database backup failed", map[string]interface{}{
+			"dir":         m.dir,
+			"snapshot":    outcome.Snapshot,
+			"duration_ms": outcome.DurationMS,
+			"error":       err.Error(),
+		})
+	} else {
+		m.log.Info("Database backup complete", map[string]interface{}{
+			"snapshot":    manifest.Snapshot,
+			"size_bytes":  manifest.SizeBytes,
+			"tables":      len(manifest.Tables),
+			"pruned":      outcome.Pruned,
+			"duration_ms": outcome.DurationMS,
+		})
+	}
+
+	m.mu.Lock()
+	m.history = append(m.history, outcome)
+	if len(m.history) > maxHistory {
+		m.history = m.history[len(m.history)-maxHistory:]
+	}
+	m.mu.Unlock()
+	return outcome
+}
+
+// History returns recent backup outcomes, newest first
+func (m *Manager) History() []Outcome {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history := make([]Outcome, len(m.history))
+	for i, outcome := range m.history {
+		history[len(m.history)-1-i] = outcome
+	}
+	return history
+}
+
+// snapshot writes and verifies one snapshot and its manifest
+// Nothing is left behind under the final name unless the snapshot verified
+func (m *Manager) snapshot(at time.Time) (*Manifest, error) {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := snapshotPrefix + at.UTC().Format(snapshotTimeFormat) + ".db"
+	path := filepath.Join(m.dir, name)
+	partial := path + ".partial"
+	os.Remove(partial)
+	defer os.Remove(partial)
+
+	if err := m.source.BackupTo(partial); err != nil {
+		return nil, err
+	}
+	tables, err := Verify(partial)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot failed verification: %w", err)
+	}
+	sum, size, err := checksum(partial)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return nil, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	manifest := &Manifest{
+		Snapshot:  name,
+		CreatedAt: at.UTC(),
+		SizeBytes: size,
+		SHA256:    sum,
+		Tables:    tables,
+	}
+	if err := writeManifest(manifestPath(path), manifest); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// List returns the snapshots in the backup directory, newest first
+// Snapshots without a readable manifest are skipped
+func (m *Manager) List() ([]Manifest, error) {
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := []Manifest{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		manifest, err := ReadManifest(filepath.Join(m.dir, strings.TrimSuffix(name, ".json")+".db"))
+		if err != nil {
+			continue
+		}
+		manifests = append(manifests, *manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].CreatedAt.After(manifests[j].CreatedAt) })
+	return manifests, nil
+}
+
+// prune deletes snapshots beyond the retention limits, always keeping the newest
+func (m *Manager) prune(now time.Time) (int, error) {
+	m.mu.Lock()
+	keep, maxAge := m.keep, m.maxAge
+	m.mu.Unlock()
+
+	manifests, err := m.List()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for i, manifest := range manifests {
+		if i == 0 {
+			continue
+		}
+		tooMany := keep > 0 && i >= keep
+		tooOld := maxAge > 0 && now.Sub(manifest.CreatedAt) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		path := filepath.Join(m.dir, manifest.Snapshot)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return pruned, fmt.Errorf("failed to prune %s: %w", manifest.Snapshot, err)
+		}
+		os.Remove(manifestPath(path))
+		pruned++
+	}
+	return pruned, nil
+}
+
+// Verify opens a database file read-only, runs PRAGMA integrity_check and
+// returns the row count of every table
+func Verify(path string) (map[string]int64, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("integrity check failed: %w", err)
+	}
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	rows.Close()
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+
+	rows, err = conn.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	tables := make(map[string]int64, len(names))
+	for _, name := range names {
+		var count int64
+		if err := conn.QueryRow(`SELECT COUNT(*) FROM "` + strings.ReplaceAll(name, `"`, `""`) + `"`).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", name, err)
+		}
+		tables[name] = count
+	}
+	return tables, nil
+}
+
+// Restore copies a snapshot to target (which must not exist) and checks the copy
+// against the snapshot's manifest: checksum, integrity and the row count of every table
+func Restore(snapshotPath, target string) (*Manifest, error) {
+	manifest, err := ReadManifest(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(target); err == nil {
+		return nil, fmt.Errorf("%s already exists; restore into a new file", target)
+	}
+
+	sum, _, err := checksum(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	if sum != manifest.SHA256 {
+		return nil, fmt.Errorf("snapshot checksum %s does not match the manifest (%s)", sum, manifest.SHA256)
+	}
+
+	partial := target + ".partial"
+	defer os.Remove(partial)
+	if err := copyFile(snapshotPath, partial); err != nil {
+		return nil, err
+	}
+	tables, err := Verify(partial)
+	if err != nil {
+		return nil, fmt.Errorf("restored copy failed verification: %w", err)
+	}
+	for table, want := range manifest.Tables {
+		if got, ok := tables[table]; !ok || got != want {
+			return nil, fmt.Errorf("table %s has %d rows, the manifest recorded %d", table, got, want)
+		}
+	}
+	if len(tables) != len(manifest.Tables) {
+		return nil, fmt.Errorf("restored copy has %d tables, the manifest recorded %d", len(tables), len(manifest.Tables))
+	}
+
+	if err := os.Rename(partial, target); err != nil {
+		return nil, fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+	return manifest, nil
+}
+
+// ReadManifest reads the manifest written next to a snapshot
+func ReadManifest(snapshotPath string) (*Manifest, error) {
+	raw, err := os.ReadFile(manifestPath(snapshotPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// manifestPath returns where a snapshot's manifest is kept
+func manifestPath(snapshotPath string) string {
+	return strings.TrimSuffix(snapshotPath, ".db") + ".json"
+}
+
+// writeManifest writes a manifest atomically
+func writeManifest(path string, manifest *Manifest) error {
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".partial", raw, 0600); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return os.Rename(path+".partial", path)
+}
+
+// checksum returns the SHA-256 and size of a file
+func checksum(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// copyFile copies src to a new file at dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
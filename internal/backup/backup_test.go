@@ -0,0 +1,130 @@
+package backup_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/backup"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// failingSource simulates a backup that can't be written
+type failingSource struct{}
+
+func (failingSource) BackupTo(path string) error { return errors.New("disk full") }
+
+// TestBackupAndRestore takes verified snapshots, prunes by count and restores one
+func TestBackupAndRestore(t *testing.T) {
+	database := dbtest.New(t)
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		user, _ := database.CreateUser(email, "hashed")
+		database.CreateWorkflow(user.ID, "Flow", "webhook", "slack_message", `{}`)
+	}
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	manager := backup.NewManager(database, dir, logger.NewLogger("test"))
+	manager.SetSchedule(0, 2, 0)
+
+	var outcomes []backup.Outcome
+	for i := 0; i < 3; i++ {
+		outcomes = append(outcomes, manager.Run())
+		time.Sleep(5 * time.Millisecond) // Snapshot names have millisecond resolution
+	}
+	for i, outcome := range outcomes {
+		if outcome.Status != "success" || outcome.Snapshot == "" {
+			t.Fatalf("Backup %d failed: %+v", i+1, outcome)
+		}
+	}
+	if outcomes[2].Pruned != 1 {
+		t.Errorf("Expected the third backup to prune the oldest, got %+v", outcomes[2])
+	}
+
+	snapshots, err := manager.List()
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots kept, got %+v (%v)", snapshots, err)
+	}
+	latest := snapshots[0]
+	if latest.Snapshot != outcomes[2].Snapshot || latest.Tables["users"] != 2 || latest.Tables["workflows"] != 2 || latest.SHA256 == "" {
+		t.Errorf("Unexpected manifest: %+v", latest)
+	}
+	if history := manager.History(); len(history) != 3 || history[0].Snapshot != latest.Snapshot {
+		t.Errorf("Expected the history newest first, got %+v", history)
+	}
+
+	snapshotPath := filepath.Join(dir, latest.Snapshot)
+	target := filepath.Join(t.TempDir(), "restored.db")
+	manifest, err := backup.Restore(snapshotPath, target)
+	if err != nil || manifest.Snapshot != latest.Snapshot {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	tables, err := backup.Verify(target)
+	if err != nil || tables["users"] != 2 {
+		t.Errorf("Expected the restored database to verify, got %v (%v)", tables, err)
+	}
+
+	if _, err := backup.Restore(snapshotPath, target); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an existing target to be refused, got %v", err)
+	}
+}
+
+// TestRestoreRejectsMismatches refuses snapshots that don't match their manifest
+func TestRestoreRejectsMismatches(t *testing.T) {
+	database := dbtest.New(t)
+	database.CreateUser("a@example.com", "hashed")
+
+	dir := t.TempDir()
+	manager := backup.NewManager(database, dir, logger.NewLogger("test"))
+	outcome := manager.Run()
+	if outcome.Status != "success" {
+		t.Fatalf("Backup failed: %+v", outcome)
+	}
+	snapshotPath := filepath.Join(dir, outcome.Snapshot)
+	manifestPath := strings.TrimSuffix(snapshotPath, ".db") + ".json"
+
+	// A manifest whose row counts disagree with the snapshot
+	manifest, _ := backup.ReadManifest(snapshotPath)
+	original, _ := os.ReadFile(manifestPath)
+	manifest.Tables["users"] = 5
+	tampered, _ := json.Marshal(manifest)
+	os.WriteFile(manifestPath, tampered, 0600)
+
+	target := filepath.Join(t.TempDir(), "restored.db")
+	if _, err := backup.Restore(snapshotPath, target); err == nil || !strings.Contains(err.Error(), "table users has 1 rows") {
+		t.Errorf("Expected a row count mismatch, got %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing restored after a failed check, got %v", err)
+	}
+
+	// A snapshot changed after the manifest was written
+	os.WriteFile(manifestPath, original, 0600)
+	file, _ := os.OpenFile(snapshotPath, os.O_WRONLY|os.O_APPEND, 0600)
+	file.Write([]byte("corruption"))
+	file.Close()
+	if _, err := backup.Restore(snapshotPath, target); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+}
+
+// TestFailedBackupRecorded keeps failures in the history without leaving files behind
+func TestFailedBackupRecorded(t *testing.T) {
+	dir := t.TempDir()
+	manager := backup.NewManager(failingSource{}, dir, logger.NewLogger("test"))
+
+	outcome := manager.Run()
+	if outcome.Status != "failed" || !strings.Contains(outcome.Error, "disk full") {
+		t.Fatalf("Expected a failed outcome, got %+v", outcome)
+	}
+	if history := manager.History(); len(history) != 1 || history[0].Status != "failed" {
+		t.Errorf("Expected the failure in the history, got %+v", history)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files after a failed backup, got %d", len(entries))
+	}
+}
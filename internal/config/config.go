@@ -0,0 +1,288 @@
+// Package config holds the runtime-tunable settings of the API server.
+//
+// Settings are loaded from the environment (and an optional JSON file named by
+// CONFIG_FILE) at boot. They can be changed without a restart through
+// PUT /api/admin/config or by sending SIGHUP, which re-reads the environment
+// and the file. Components subscribe to the Manager and apply new values in place.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Settings are the values that can change while the server is running
+type Settings struct {
+	WorkerPoolSize    int                `json:"worker_pool_size"`         // Concurrent workflow executions
+	BranchConcurrency float64            `json:"branch_concurrency"`       // Parallel chain branches running at once, as a multiple of worker_pool_size
+	RateLimitFree     float64            `json:"rate_limit_free"`          // API requests/sec per free-tier tenant
+	RateLimitPaid     float64            `json:"rate_limit_paid"`          // API requests/sec per paid-tier tenant
+	RateLimitBurst    int                `json:"rate_limit_burst"`         // Burst capacity of each tenant limiter
+	SchedulerInterval Duration           `json:"scheduler_interval"`       // How often scheduled workflows are checked
+	SchedulerJitter   bool               `json:"scheduler_jitter"`         // Spread each tick's due workflows over the interval
+	TenantConcurrency int                `json:"tenant_concurrency"`       // Jobs a tenant may have queued or running before its scheduled runs wait a tick (0 = no cap)
+	MaxTestingDelay   Duration           `json:"max_testing_delay"`        // Longest delay a testing action may simulate
+	ServiceLimits     map[string]float64 `json:"service_limits,omitempty"` // Outbound calls/sec per action type (e.g. "slack_message": 1)
+
+	ImpersonationReadOnly bool `json:"impersonation_read_only"` // Block mutating requests made while impersonating a user
+
+	BackupInterval Duration `json:"backup_interval"` // How often the database is snapshotted (0 = off)
+	BackupKeep     int      `json:"backup_keep"`     // Snapshots kept (0 = no count limit)
+	BackupMaxAge   Duration `json:"backup_max_age"`  // Snapshots older than this are pruned (0 = no age limit)
+
+	RetentionInterval  Duration `json:"retention_interval"`   // How often tenant retention policies are applied (0 = off)
+	RetentionBatchSize int      `json:"retention_batch_size"` // Rows removed per tenant and data class before moving to the next tenant
+	LogRetentionDays   int      `json:"log_retention_days"`   // Days logs are kept without a tenant override: the free tier's period, the least for paid tiers
+
+	CORS CORSSettings `json:"cors"` // Browser origins allowed to call the API
+}
+
+// Defaults returns the settings used when nothing is configured
+func Defaults() Settings {
+	return Settings{
+		WorkerPoolSize:     10,
+		BranchConcurrency:  1,
+		RateLimitFree:      5,
+		RateLimitPaid:      50,
+		RateLimitBurst:     10,
+		SchedulerInterval:  Duration(60 * time.Second),
+		MaxTestingDelay:    Duration(10 * time.Second),
+		CORS:               defaultCORS(),
+		BackupInterval:     Duration(24 * time.Hour),
+		BackupKeep:         7,
+		RetentionInterval:  Duration(time.Hour),
+		RetentionBatchSize: 500,
+		LogRetentionDays:   30,
+	}
+}
+
+// Validate rejects settings that would stall or overload the server
+func (s Settings) Validate() error {
+	if s.WorkerPoolSize < 1 || s.WorkerPoolSize > 1000 {
+		return errors.New("worker_pool_size must be between 1 and 1000")
+	}
+	if s.BranchConcurrency <= 0 || s.BranchConcurrency > 10 {
+		return errors.New("branch_concurrency must be greater than 0 and at most 10")
+	}
+	if s.RateLimitFree <= 0 || s.RateLimitPaid <= 0 {
+		return errors.New("rate limits must be positive")
+	}
+	if s.RateLimitBurst < 1 {
+		return errors.New("rate_limit_burst must be at least 1")
+	}
+	if time.Duration(s.SchedulerInterval) < time.Second {
+		return errors.New("scheduler_interval must be at least 1s")
+	}
+	if s.TenantConcurrency < 0 || s.TenantConcurrency > 1000 {
+		return errors.New("tenant_concurrency must be between 0 (no cap) and 1000")
+	}
+	if time.Duration(s.MaxTestingDelay) < 0 || time.Duration(s.MaxTestingDelay) > 5*time.Minute {
+		return errors.New("max_testing_delay must be between 0s and 5m")
+	}
+	for service, limit := range s.ServiceLimits {
+		if limit <= 0 {
+			return fmt.Errorf("service_limits.%s must be positive (omit it to remove the limit)", service)
+		}
+	}
+	if interval := time.Duration(s.BackupInterval); interval < 0 || (interval > 0 && interval < time.Minute) {
+		return errors.New("backup_interval must be 0 (off) or at least 1m")
+	}
+	if s.BackupKeep < 0 || time.Duration(s.BackupMaxAge) < 0 {
+		return errors.New("backup_keep and backup_max_age must not be negative")
+	}
+	if interval := time.Duration(s.RetentionInterval); interval < 0 || (interval > 0 && interval < time.Minute) {
+		return errors.New("retention_interval must be 0 (off) or at least 1m")
+	}
+	if s.RetentionBatchSize < 1 || s.RetentionBatchSize > 100000 {
+		return errors.New("retention_batch_size must be between 1 and 100000")
+	}
+	if s.LogRetentionDays < 1 || s.LogRetentionDays > 3650 {
+		return errors.New("log_retention_days must be between 1 and 3650")
+	}
+	return s.CORS.Validate()
+}
+
+// clone copies the settings so callers can't mutate the manager's map
+func (s Settings) clone() Settings {
+	if s.ServiceLimits != nil {
+		limits := make(map[string]float64, len(s.ServiceLimits))
+		for service, limit := range s.ServiceLimits {
+			limits[service] = limit
+		}
+		s.ServiceLimits = limits
+	}
+	s.CORS.AllowedOrigins = append([]string(nil), s.CORS.AllowedOrigins...)
+	s.CORS.AllowedHeaders = append([]string(nil), s.CORS.AllowedHeaders...)
+	s.CORS.ExposedHeaders = append([]string(nil), s.CORS.ExposedHeaders...)
+	return s
+}
+
+// Load reads settings from the environment, then overlays the JSON file at path (if set)
+func Load(path string) (Settings, error) {
+	settings, err := FromEnv()
+	if err != nil {
+		return settings, err
+	}
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return settings, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return settings, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	return settings, settings.Validate()
+}
+
+// FromEnv reads settings from environment variables over the defaults
+// WORKER_POOL_SIZE, BRANCH_CONCURRENCY, RATE_LIMIT_FREE, RATE_LIMIT_PAID, RATE_LIMIT_BURST,
+// SCHEDULER_INTERVAL (e.g. "60s"), SCHEDULER_JITTER (true/false), TENANT_CONCURRENCY, MAX_TESTING_DELAY (e.g. "10s"),
+// SERVICE_RATE_LIMITS (JSON: {"slack_message": 1}), IMPERSONATION_READ_ONLY (true/false),
+// BACKUP_INTERVAL, BACKUP_KEEP, BACKUP_MAX_AGE, RETENTION_INTERVAL, RETENTION_BATCH_SIZE,
+// LOG_RETENTION_DAYS and the CORS_* variables (see corsFromEnv)
+func FromEnv() (Settings, error) {
+	settings := Defaults()
+
+	if err := envInt("WORKER_POOL_SIZE", &settings.WorkerPoolSize); err != nil {
+		return settings, err
+	}
+	if err := envFloat("BRANCH_CONCURRENCY", &settings.BranchConcurrency); err != nil {
+		return settings, err
+	}
+	if err := envFloat("RATE_LIMIT_FREE", &settings.RateLimitFree); err != nil {
+		return settings, err
+	}
+	if err := envFloat("RATE_LIMIT_PAID", &settings.RateLimitPaid); err != nil {
+		return settings, err
+	}
+	if err := envInt("RATE_LIMIT_BURST", &settings.RateLimitBurst); err != nil {
+		return settings, err
+	}
+	if value := os.Getenv("SCHEDULER_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid SCHEDULER_INTERVAL: %w", err)
+		}
+		settings.SchedulerInterval = Duration(d)
+	}
+	if err := envBool("SCHEDULER_JITTER", &settings.SchedulerJitter); err != nil {
+		return settings, err
+	}
+	if err := envInt("TENANT_CONCURRENCY", &settings.TenantConcurrency); err != nil {
+		return settings, err
+	}
+	if value := os.Getenv("MAX_TESTING_DELAY"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return settings, fmt.Errorf("invalid MAX_TESTING_DELAY: %w", err)
+		}
+		settings.MaxTestingDelay = Duration(d)
+	}
+	if value := os.Getenv("SERVICE_RATE_LIMITS"); value != "" {
+		if err := json.Unmarshal([]byte(value), &settings.ServiceLimits); err != nil {
+			return settings, fmt.Errorf("invalid SERVICE_RATE_LIMITS: %w", err)
+		}
+	}
+	if err := envBool("IMPERSONATION_READ_ONLY", &settings.ImpersonationReadOnly); err != nil {
+		return settings, err
+	}
+	if err := envDuration("BACKUP_INTERVAL", &settings.BackupInterval); err != nil {
+		return settings, err
+	}
+	if err := envDuration("BACKUP_MAX_AGE", &settings.BackupMaxAge); err != nil {
+		return settings, err
+	}
+	if err := envInt("BACKUP_KEEP", &settings.BackupKeep); err != nil {
+		return settings, err
+	}
+	if err := envDuration("RETENTION_INTERVAL", &settings.RetentionInterval); err != nil {
+		return settings, err
+	}
+	if err := envInt("RETENTION_BATCH_SIZE", &settings.RetentionBatchSize); err != nil {
+		return settings, err
+	}
+	if err := envInt("LOG_RETENTION_DAYS", &settings.LogRetentionDays); err != nil {
+		return settings, err
+	}
+	if err := corsFromEnv(&settings.CORS); err != nil {
+		return settings, err
+	}
+
+	return settings, nil
+}
+
+func envInt(key string, dst *int) error {
+	if value := os.Getenv(key); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = n
+	}
+	return nil
+}
+
+func envFloat(key string, dst *float64) error {
+	if value := os.Getenv(key); value != "" {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = f
+	}
+	return nil
+}
+
+func envBool(key string, dst *bool) error {
+	if value := os.Getenv(key); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = b
+	}
+	return nil
+}
+
+func envDuration(key string, dst *Duration) error {
+	if value := os.Getenv(key); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = Duration(d)
+	}
+	return nil
+}
+
+// Duration is a time.Duration written as a string ("90s") in JSON
+type Duration time.Duration
+
+// MarshalJSON writes the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON accepts a duration string ("90s") or a number of seconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", text)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return errors.New("duration must be a string like \"60s\" or a number of seconds")
+	}
+	*d = Duration(time.Duration(seconds * float64(time.Second)))
+	return nil
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/cors"
+)
+
+// CORSSettings control which browser origins may call the API
+type CORSSettings struct {
+	AllowedOrigins   []string `json:"allowed_origins"`   // "https://app.example.com", "https://*.example.com" or "*"
+	AllowedHeaders   []string `json:"allowed_headers"`   // Request headers browsers may send
+	ExposedHeaders   []string `json:"exposed_headers"`   // Response headers scripts may read
+	AllowCredentials bool     `json:"allow_credentials"` // Allow cookies and Authorization on cross-origin requests
+	MaxAge           Duration `json:"max_age"`           // How long browsers may cache a preflight answer
+}
+
+// devOrigins are allowed by default outside production
+var devOrigins = []string{
+	"http://localhost:3000",
+	"http://localhost:3001",
+	"http://localhost:8080",
+	"http://127.0.0.1:3000",
+}
+
+// defaultCORS returns the CORS settings used when nothing is configured
+func defaultCORS() CORSSettings {
+	return CORSSettings{
+		AllowedOrigins:   append([]string{}, devOrigins...),
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With"},
+		ExposedHeaders:   []string{"Content-Length", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           Duration(5 * time.Minute),
+	}
+}
+
+// corsFromEnv overlays CORS_ALLOWED_ORIGINS, CORS_ALLOWED_HEADERS, CORS_EXPOSED_HEADERS (comma-separated),
+// CORS_ALLOW_CREDENTIALS (true/false) and CORS_MAX_AGE (e.g. "5m")
+// In production (ENVIRONMENT=production) no origin is allowed unless configured
+func corsFromEnv(c *CORSSettings) error {
+	if os.Getenv("ENVIRONMENT") == "production" {
+		c.AllowedOrigins = nil
+	}
+	if value, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		c.AllowedOrigins = splitList(value)
+	}
+	if value, ok := os.LookupEnv("CORS_ALLOWED_HEADERS"); ok {
+		c.AllowedHeaders = splitList(value)
+	}
+	if value, ok := os.LookupEnv("CORS_EXPOSED_HEADERS"); ok {
+		c.ExposedHeaders = splitList(value)
+	}
+	if err := envBool("CORS_ALLOW_CREDENTIALS", &c.AllowCredentials); err != nil {
+		return err
+	}
+	if value := os.Getenv("CORS_MAX_AGE"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid CORS_MAX_AGE: %w", err)
+		}
+		c.MaxAge = Duration(d)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate rejects origin patterns the matcher can't honour and unsafe combinations
+func (c CORSSettings) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return errors.New("cors.allowed_origins must not contain \"*\" when allow_credentials is enabled")
+			}
+			continue
+		}
+		if _, err := parseOrigin(origin); err != nil {
+			return fmt.Errorf("cors.allowed_origins: %w", err)
+		}
+	}
+	for _, header := range append(append([]string{}, c.AllowedHeaders...), c.ExposedHeaders...) {
+		if header == "" || strings.ContainsAny(header, " \t:,") {
+			return fmt.Errorf("cors: invalid header name %q", header)
+		}
+	}
+	if time.Duration(c.MaxAge) < 0 || time.Duration(c.MaxAge) > 24*time.Hour {
+		return errors.New("cors.max_age must be between 0s and 24h")
+	}
+	return nil
+}
+
+// origin is a parsed origin or origin pattern
+type origin struct {
+	scheme string
+	host   string // Lower case; "*.example.com" for a subdomain wildcard
+	port   string // Always set, defaulted from the scheme
+}
+
+// parseOrigin parses "scheme://host[:port]", where host may start with "*."
+func parseOrigin(value string) (origin, error) {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return origin{}, fmt.Errorf("%q is not an http(s) origin", value)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return origin{}, fmt.Errorf("%q must not have a path, query or credentials", value)
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	host = strings.ToLower(host)
+	if strings.Contains(host, "*") {
+		// "*.example.com" only: never part of a label, never a whole registrable domain
+		domain, ok := strings.CutPrefix(host, "*.")
+		if !ok || strings.Contains(domain, "*") || !strings.Contains(domain, ".") || net.ParseIP(domain) != nil {
+			return origin{}, fmt.Errorf("%q: a wildcard may only replace the first label of a domain like https://*.example.com", value)
+		}
+	}
+	return origin{scheme: u.Scheme, host: host, port: port}, nil
+}
+
+// matches reports whether a concrete origin is covered by the pattern
+// A wildcard matches any subdomain depth but never the bare domain itself
+func (pattern origin) matches(o origin) bool {
+	if pattern.scheme != o.scheme || pattern.port != o.port {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(pattern.host, "*"); ok {
+		return strings.HasSuffix(o.host, suffix) && len(o.host) > len(suffix)
+	}
+	return pattern.host == o.host
+}
+
+// AllowOrigin reports whether a request's Origin header is allowed
+// Scheme, host and port must all match; default ports are implied
+func (c CORSSettings) AllowOrigin(value string) bool {
+	requested, err := parseOrigin(value)
+	if err != nil || strings.Contains(requested.host, "*") {
+		return false
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+		pattern, err := parseOrigin(allowed)
+		if err == nil && pattern.matches(requested) {
+			return true
+		}
+	}
+	return false
+}
+
+// Options converts the settings for the CORS middleware
+func (c CORSSettings) Options() cors.Options {
+	return cors.Options{
+		AllowOriginFunc: c.AllowOrigin,
+		AllowedMethods: []string{
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodDelete,
+			http.MethodOptions,
+		},
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           int(time.Duration(c.MaxAge).Seconds()),
+	}
+}
@@ -0,0 +1,146 @@
+package config_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/config"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+)
+
+// TestCORSAllowOrigin covers ports, schemes and subdomain wildcards
+func TestCORSAllowOrigin(t *testing.T) {
+	settings := config.CORSSettings{AllowedOrigins: []string{
+		"https://app.example.com",
+		"http://localhost:3000",
+		"https://*.customer-portal.com",
+		"https://*.staging.example.org:8443",
+	}}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"https://app.example.com:443", true},
+		{"https://app.example.com:8443", false},
+		{"http://app.example.com", false},
+		{"https://evil-app.example.com", false},
+		{"https://app.example.com.evil.com", false},
+		{"http://localhost:3000", true},
+		{"http://localhost:3001", false},
+		{"http://localhost", false},
+		{"https://localhost:3000", false},
+		{"https://acme.customer-portal.com", true},
+		{"https://eu.acme.customer-portal.com", true},
+		{"https://customer-portal.com", false},
+		{"https://evilcustomer-portal.com", false},
+		{"http://acme.customer-portal.com", false},
+		{"https://acme.customer-portal.com:8443", false},
+		{"https://a.staging.example.org:8443", true},
+		{"https://a.staging.example.org", false},
+		{"https://*.customer-portal.com", false},
+		{"null", false},
+		{"", false},
+		{"https://app.example.com/path", false},
+	}
+	for _, tt := range tests {
+		if got := settings.AllowOrigin(tt.origin); got != tt.want {
+			t.Errorf("AllowOrigin(%q): expected %v, got %v", tt.origin, tt.want, got)
+		}
+	}
+
+	if !(config.CORSSettings{AllowedOrigins: []string{"*"}}).AllowOrigin("https://anything.example") {
+		t.Error("Expected \"*\" to allow any origin")
+	}
+}
+
+// TestCORSValidation rejects unsafe and malformed settings
+func TestCORSValidation(t *testing.T) {
+	valid := config.Defaults()
+	valid.CORS.AllowedOrigins = []string{"https://*.customer-portal.com", "http://localhost:3000"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected valid settings, got %v", err)
+	}
+
+	tests := map[string]func(c *config.CORSSettings){
+		"wildcard with credentials": func(c *config.CORSSettings) { c.AllowedOrigins = []string{"*"} },
+		"no scheme":                 func(c *config.CORSSettings) { c.AllowedOrigins = []string{"app.example.com"} },
+		"path":                      func(c *config.CORSSettings) { c.AllowedOrigins = []string{"https://app.example.com/app"} },
+		"wildcard inside label":     func(c *config.CORSSettings) { c.AllowedOrigins = []string{"https://app-*.example.com"} },
+		"wildcard top-level domain": func(c *config.CORSSettings) { c.AllowedOrigins = []string{"https://*.com"} },
+		"bad header":                func(c *config.CORSSettings) { c.AllowedHeaders = []string{"X-Tenant ID"} },
+		"negative max age":          func(c *config.CORSSettings) { c.MaxAge = -1 },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			settings := config.Defaults()
+			mutate(&settings.CORS)
+			if err := settings.Validate(); err == nil {
+				t.Errorf("Expected %s to be rejected", name)
+			}
+		})
+	}
+
+	// "*" is fine without credentials
+	open := config.Defaults()
+	open.CORS.AllowedOrigins = []string{"*"}
+	open.CORS.AllowCredentials = false
+	if err := open.Validate(); err != nil {
+		t.Errorf("Expected \"*\" without credentials to be valid, got %v", err)
+	}
+}
+
+// TestCORSReloadedAtRuntime applies new CORS settings through the manager without a restart
+func TestCORSReloadedAtRuntime(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com")
+	t.Setenv("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-Tenant-ID")
+
+	settings, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	manager := config.NewManager(settings, db.NewMockStore(), logger.NewLogger("test"))
+	cors := middleware.NewCORS(settings.CORS.Options(), false)
+	manager.Subscribe(func(s config.Settings) { cors.SetOptions(s.CORS.Options()) })
+
+	handler := cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/api/workflows", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "X-Tenant-ID")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := preflight("https://app.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		!strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), "X-Tenant-Id") {
+		t.Errorf("Expected the configured origin and header to be allowed, got %v", rec.Header())
+	}
+	if rec := preflight("https://acme.customer-portal.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected an unlisted origin to be refused, got %v", rec.Header())
+	}
+
+	if _, err := manager.Apply([]byte(`{"cors": {"allowed_origins": ["*"]}}`), "admin_1", config.SourceAPI); !errors.Is(err, config.ErrInvalid) {
+		t.Errorf("Expected \"*\" with credentials to be rejected, got %v", err)
+	}
+	if _, err := manager.Apply([]byte(`{"cors": {"allowed_origins": ["https://*.customer-portal.com"], "max_age": "10m"}}`), "admin_1", config.SourceAPI); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	rec := preflight("https://acme.customer-portal.com")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://acme.customer-portal.com" || rec.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("Expected the new settings to apply immediately, got %v", rec.Header())
+	}
+	if current := manager.Current().CORS; !current.AllowCredentials || len(current.AllowedHeaders) != 3 {
+		t.Errorf("Expected omitted CORS fields to keep their value, got %+v", current)
+	}
+}
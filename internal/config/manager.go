@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// Change sources recorded in the audit trail
+const (
+	SourceAPI    = "api"
+	SourceSIGHUP = "sighup"
+)
+
+// SystemActor is the audited actor of changes not made by a user
+const SystemActor = "system"
+
+// ErrInvalid wraps errors caused by the submitted settings rather than the server
+var ErrInvalid = errors.New("invalid config")
+
+// Manager owns the effective settings and notifies subscribers when they change
+type Manager struct {
+	store db.Store // Interface, not concrete type!
+	log   *logger.Logger
+
+	applyMu     sync.Mutex // Serializes updates so subscribers see them in order
+	mu          sync.RWMutex
+	current     Settings
+	subscribers []func(Settings)
+}
+
+// NewManager creates a manager with the boot-time settings
+func NewManager(initial Settings, store db.Store, log *logger.Logger) *Manager {
+	return &Manager{
+		store:   store,
+		log:     log,
+		current: initial.clone(),
+	}
+}
+
+// Current returns a copy of the effective settings
+func (m *Manager) Current() Settings {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current.clone()
+}
+
+// Subscribe registers fn to be called with the new settings after every change
+// Subscribers run synchronously, in registration order, and must not call Apply or Replace
+func (m *Manager) Subscribe(fn func(Settings)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Apply merges a partial JSON document into the current settings
+// Fields that are omitted keep their value; service_limits is replaced as a whole
+func (m *Manager) Apply(patch []byte, actor, source string) (Settings, error) {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	next := m.Current()
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return next, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if _, ok := fields["service_limits"]; ok {
+		next.ServiceLimits = nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&next); err != nil {
+		return m.Current(), fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return next, m.update(next, actor, source)
+}
+
+// Replace swaps in a complete set of settings (used by SIGHUP reloads)
+func (m *Manager) Replace(next Settings, actor, source string) error {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	return m.update(next.clone(), actor, source)
+}
+
+// Reload re-reads the environment and the optional config file
+func (m *Manager) Reload(path string) error {
+	settings, err := Load(path)
+	if err != nil {
+		return err
+	}
+	return m.Replace(settings, SystemActor, SourceSIGHUP)
+}
+
+// update validates, audits and publishes new settings; callers hold applyMu
+func (m *Manager) update(next Settings, actor, source string) error {
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	previous := m.Current()
+	changes := diffSettings(previous, next)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Audit before applying so a change is never live without a record
+	masked, err := json.Marshal(utils.MaskMap(changes))
+	if err != nil {
+		return fmt.Errorf("failed to encode config change: %w", err)
+	}
+	if err := m.store.RecordConfigChange(&models.ConfigChange{
+		Actor:   actor,
+		Source:  source,
+		Changes: string(masked),
+	}); err != nil {
+		return fmt.Errorf("failed to audit config change: %w", err)
+	}
+
+	m.mu.Lock()
+	m.current = next
+	subscribers := append([]func(Settings){}, m.subscribers...)
+	m.mu.Unlock()
+
+	m.log.Info("Runtime config updated", map[string]interface{}{
+		"actor":   actor,
+		"source":  source,
+		"changes": string(masked),
+	})
+
+	for _, fn := range subscribers {
+		fn(next.clone())
+	}
+	return nil
+}
+
+// Masked returns the effective settings as generic JSON with secrets masked
+func (m *Manager) Masked() map[string]interface{} {
+	return utils.MaskMap(settingsMap(m.Current()))
+}
+
+// diffSettings lists the settings that differ as {"field": {"before": ..., "after": ...}}
+func diffSettings(before, after Settings) map[string]interface{} {
+	old := settingsMap(before)
+	updated := settingsMap(after)
+
+	changes := make(map[string]interface{})
+	for key, value := range updated {
+		if !reflect.DeepEqual(old[key], value) {
+			changes[key] = map[string]interface{}{"before": old[key], "after": value}
+		}
+	}
+	for key, value := range old {
+		if _, ok := updated[key]; !ok {
+			changes[key] = map[string]interface{}{"before": value, "after": nil}
+		}
+	}
+	return changes
+}
+
+// settingsMap converts settings to generic JSON data
+func settingsMap(s Settings) map[string]interface{} {
+	raw, _ := json.Marshal(s)
+	var out map[string]interface{}
+	json.Unmarshal(raw, &out)
+	return out
+}
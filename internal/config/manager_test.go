@@ -0,0 +1,157 @@
+package config_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/config"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestApplyNotifiesSubscribersAndAudits applies a partial update and a rejected one
+func TestApplyNotifiesSubscribersAndAudits(t *testing.T) {
+	mockStore := db.NewMockStore()
+	manager := config.NewManager(config.Defaults(), mockStore, logger.NewLogger("test"))
+
+	var applied []config.Settings
+	manager.Subscribe(func(s config.Settings) { applied = append(applied, s) })
+
+	next, err := manager.Apply([]byte(`{"worker_pool_size": 20, "scheduler_interval": "30s", "service_limits": {"slack_message": 1}}`), "admin_1", config.SourceAPI)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if next.WorkerPoolSize != 20 || time.Duration(next.SchedulerInterval) != 30*time.Second || next.RateLimitFree != 5 {
+		t.Errorf("Expected a merged update, got %+v", next)
+	}
+	if len(applied) != 1 || applied[0].ServiceLimits["slack_message"] != 1 {
+		t.Fatalf("Expected subscribers to see the change once, got %+v", applied)
+	}
+
+	if len(mockStore.ConfigChanges) != 1 {
+		t.Fatalf("Expected 1 audited change, got %d", len(mockStore.ConfigChanges))
+	}
+	change := mockStore.ConfigChanges[0]
+	var fields map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(change.Changes), &fields); err != nil {
+		t.Fatalf("Audited changes are not JSON: %v", err)
+	}
+	if change.Actor != "admin_1" || change.Source != config.SourceAPI || fields["worker_pool_size"]["before"] != float64(10) || fields["worker_pool_size"]["after"] != float64(20) {
+		t.Errorf("Unexpected audit record: %+v", change)
+	}
+	if _, ok := fields["rate_limit_free"]; ok {
+		t.Errorf("Unchanged settings must not be audited: %s", change.Changes)
+	}
+
+	// Invalid and unknown settings are rejected without applying or auditing anything
+	for _, patch := range []string{`{"worker_pool_size": 0}`, `{"scheduler_intervall": "5s"}`, `{"service_limits": {"slack_message": -1}}`} {
+		if _, err := manager.Apply([]byte(patch), "admin_1", config.SourceAPI); !errors.Is(err, config.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for %s, got %v", patch, err)
+		}
+	}
+	// A no-op update isn't a change
+	if _, err := manager.Apply([]byte(`{"worker_pool_size": 20}`), "admin_1", config.SourceAPI); err != nil {
+		t.Errorf("No-op apply failed: %v", err)
+	}
+	if len(applied) != 1 || len(mockStore.ConfigChanges) != 1 || manager.Current().WorkerPoolSize != 20 {
+		t.Errorf("Expected rejected and no-op updates to change nothing")
+	}
+}
+
+// TestReloadReadsConfigFile simulates a SIGHUP after the config file was edited
+func TestReloadReadsConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goflow.json")
+	os.WriteFile(path, []byte(`{"worker_pool_size": 4}`), 0600)
+	t.Setenv("RATE_LIMIT_PAID", "80")
+	t.Setenv("LOG_RETENTION_DAYS", "14")
+
+	settings, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if settings.WorkerPoolSize != 4 || settings.RateLimitPaid != 80 || settings.LogRetentionDays != 14 {
+		t.Fatalf("Expected file over env over defaults, got %+v", settings)
+	}
+
+	mockStore := db.NewMockStore()
+	manager := config.NewManager(settings, mockStore, logger.NewLogger("test"))
+
+	os.WriteFile(path, []byte(`{"worker_pool_size": 8}`), 0600)
+	if err := manager.Reload(path); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if manager.Current().WorkerPoolSize != 8 || mockStore.ConfigChanges[0].Source != config.SourceSIGHUP {
+		t.Errorf("Expected reload to apply the file, got %+v", manager.Current())
+	}
+
+	// A broken file keeps the current settings
+	os.WriteFile(path, []byte(`{"worker_pool_size": `), 0600)
+	if err := manager.Reload(path); err == nil || manager.Current().WorkerPoolSize != 8 {
+		t.Errorf("Expected a broken file to be rejected, got %v", err)
+	}
+}
+
+// TestAdminConfigEndpoints checks admin gating and the GET/PUT round trip
+func TestAdminConfigEndpoints(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	manager := config.NewManager(config.Defaults(), mockStore, testLogger)
+
+	admin, _ := mockStore.CreateUser("ops@example.com", "hashed")
+	member, _ := mockStore.CreateUser("member@example.com", "hashed")
+
+	router := server.NewRouter(server.Config{
+		Store:         mockStore,
+		Logger:        testLogger,
+		RuntimeConfig: manager,
+		AdminEmails:   []string{"OPS@example.com"},
+	})
+
+	request := func(method, userID, body string) *httptest.ResponseRecorder {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"user_id":   userID,
+			"tenant_id": models.DefaultTenantID(userID),
+			"exp":       time.Now().Add(time.Hour).Unix(),
+		}).SignedString(middleware.GetJWTSecret())
+		req := httptest.NewRequest(method, "/api/admin/config", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request("GET", member.ID, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for non-admin, got %d", rec.Code)
+	}
+	if rec := request("PUT", member.ID, `{"worker_pool_size": 2}`); rec.Code != http.StatusForbidden || manager.Current().WorkerPoolSize != 10 {
+		t.Errorf("Expected non-admin update to be refused, got %d", rec.Code)
+	}
+
+	rec := request("PUT", admin.ID, `{"worker_pool_size": 2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected admin update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := request("PUT", admin.ID, `{"worker_pool_size": "lots"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid value, got %d", rec.Code)
+	}
+
+	var effective map[string]interface{}
+	json.NewDecoder(request("GET", admin.ID, "").Body).Decode(&effective)
+	if effective["worker_pool_size"] != float64(2) || effective["scheduler_interval"] != "1m0s" {
+		t.Errorf("Unexpected effective config: %v", effective)
+	}
+	if len(mockStore.ConfigChanges) != 1 || mockStore.ConfigChanges[0].Actor != admin.ID {
+		t.Errorf("Expected the admin's change to be audited, got %+v", mockStore.ConfigChanges)
+	}
+}
@@ -0,0 +1,810 @@
+package connectorgen
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// DefaultImportPath is the package generated connectors are written to
+const DefaultImportPath = "github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+	"zero":  zeroCheck,
+	"check": requiredCheck,
+	"set":   setParam,
+}).ParseFS(templateFS, "templates/*.tmpl"))
+
+var validName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Options controls what is generated
+type Options struct {
+	Name       string   // Connector name in snake_case (e.g., "pet_store"); also the credential service name
+	Operations []string // operationIds to include (default: every operation in the spec)
+	BaseURL    string   // Overrides the spec's first server URL
+	ActionType string   // Workflow action type (default: Name + "_call")
+	ImportPath string   // Import path of the output package, used by the test file (default: DefaultImportPath)
+}
+
+// File is a generated source file
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// connector is the template model for one generated connector
+type connector struct {
+	Name        string
+	Type        string // Go type of the connector (e.g., "PetStoreConnector")
+	Config      string // Go type of its config (e.g., "PetStoreConfig")
+	Prefix      string // Exported identifier prefix (e.g., "PetStore")
+	Request     string // Unexported prepared-request type
+	Label       string // Human-readable name used in messages
+	ActionType  string
+	ImportPath  string
+	BaseURL     string
+	Auth        *auth
+	Fields      []*field
+	Operations  []*operation
+	UsesStrconv bool
+}
+
+// auth describes how the generated connector sends its credential
+type auth struct {
+	Kind string // "header", "query", "bearer" or "basic"
+	Name string // Header or query parameter name
+	Hint string // What to paste in as the credential
+}
+
+// field is one config struct field shared by the operations that use it
+type field struct {
+	GoName  string
+	JSON    string
+	GoType  string
+	Comment string
+}
+
+// param is one request input of an operation
+type param struct {
+	Field    *field
+	Wire     string // Name on the wire
+	In       string // "path", "query", "header" or "body"
+	Required bool
+	Sample   interface{} // Value used by the generated tests
+}
+
+// operation is the template model of one API call
+type operation struct {
+	ID       string
+	Method   string
+	Path     string
+	Summary  string
+	PathExpr string // Go expression building the path from the config
+	Params   []*param
+	HasBody  bool
+	Response string // Sample JSON response for the test fixture
+
+	// Test fixture expectations
+	SampleConfig  string
+	SamplePath    string
+	SampleQuery   string
+	SampleBody    string
+	SampleHeader  map[string]string
+	MissingParam  *param // A required parameter the error test leaves out
+	MissingConfig string // Sample config without MissingParam
+}
+
+// Generate renders the connector and its test file
+func Generate(spec *Spec, opts Options) ([]File, error) {
+	if !validName.MatchString(opts.Name) {
+		return nil, fmt.Errorf("invalid connector name %q (use snake_case, e.g. pet_store)", opts.Name)
+	}
+	if opts.ActionType == "" {
+		opts.ActionType = opts.Name + "_call"
+	}
+	if opts.ImportPath == "" {
+		opts.ImportPath = DefaultImportPath
+	}
+
+	baseURL := opts.BaseURL
+	if baseURL == "" && len(spec.Servers) > 0 {
+		baseURL = spec.Servers[0].URL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("spec has no absolute server URL; pass a base URL")
+	}
+
+	prefix := goName(opts.Name)
+	label := messageLabel(strings.TrimSpace(spec.Info.Title))
+	if label == "" {
+		label = prefix
+	}
+
+	c := &connector{
+		Name:       opts.Name,
+		Type:       prefix + "Connector",
+		Config:     prefix + "Config",
+		Prefix:     prefix,
+		Request:    lowerFirst(prefix) + "Request",
+		Label:      label,
+		ActionType: opts.ActionType,
+		ImportPath: opts.ImportPath,
+		BaseURL:    baseURL,
+	}
+
+	selected, err := selectOperations(spec, opts.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]*field{"Operation": {GoName: "Operation", JSON: "operation", GoType: "string"}}
+	for _, ref := range selected {
+		op, err := c.buildOperation(spec, ref, fields)
+		if err != nil {
+			return nil, err
+		}
+		c.Operations = append(c.Operations, op)
+		if scheme := spec.securityFor(ref.Operation); scheme != nil && c.Auth == nil {
+			c.Auth = authFor(scheme)
+		}
+	}
+
+	ids := make([]string, len(c.Operations))
+	for i, op := range c.Operations {
+		ids[i] = op.ID
+	}
+	fields["Operation"].Comment = fmt.Sprintf("%s (default: %s)", strings.Join(ids, ", "), ids[0])
+	c.Fields = sortedFields(fields)
+
+	for _, op := range c.Operations {
+		op.PathExpr = pathExpr(op)
+		op.SampleConfig = sampleConfig(c, op, nil)
+		for _, p := range op.Params {
+			if p.Field.GoType != "string" && p.Field.GoType != "interface{}" && p.In != "body" {
+				c.UsesStrconv = true
+			}
+			if p.Required && op.MissingParam == nil && zeroCheck(p) != "" {
+				op.MissingParam = p
+			}
+		}
+		if op.MissingParam != nil {
+			op.MissingConfig = sampleConfig(c, op, op.MissingParam)
+		}
+		if err := sampleRequest(op); err != nil {
+			return nil, err
+		}
+	}
+
+	var files []File
+	for _, t := range []struct{ template, name string }{
+		{"connector.go.tmpl", opts.Name + ".go"},
+		{"connector_test.go.tmpl", opts.Name + "_test.go"},
+	} {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, t.template, c); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", t.name, err)
+		}
+		formatted, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("generated %s does not parse: %w", t.name, err)
+		}
+		files = append(files, File{Name: t.name, Content: formatted})
+	}
+	return files, nil
+}
+
+// selectOperations picks operations by operationId, keeping the requested order
+func selectOperations(spec *Spec, ids []string) ([]operationRef, error) {
+	all := spec.operations()
+	if len(ids) == 0 {
+		return all, nil
+	}
+
+	byID := make(map[string]operationRef, len(all))
+	available := make([]string, 0, len(all))
+	for _, ref := range all {
+		id := operationID(ref)
+		byID[id] = ref
+		available = append(available, id)
+	}
+
+	selected := make([]operationRef, 0, len(ids))
+	for _, id := range ids {
+		ref, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown operation %q (available: %s)", id, strings.Join(available, ", "))
+		}
+		selected = append(selected, ref)
+	}
+	return selected, nil
+}
+
+// operationID returns the spec's operationId or derives one from the method and path
+func operationID(ref operationRef) string {
+	if ref.Operation.OperationID != "" {
+		return ref.Operation.OperationID
+	}
+	return lowerFirst(goName(strings.ToLower(ref.Method) + " " + ref.Path))
+}
+
+// buildOperation collects an operation's parameters into shared config fields
+func (c *connector) buildOperation(spec *Spec, ref operationRef, fields map[string]*field) (*operation, error) {
+	op := &operation{
+		ID:      operationID(ref),
+		Method:  ref.Method,
+		Path:    ref.Path,
+		Summary: firstLine(ref.Operation.Summary),
+	}
+	if op.Summary == "" {
+		op.Summary = firstLine(ref.Operation.Description)
+	}
+
+	// Operation parameters override path-level ones with the same name and location
+	seen := make(map[string]bool)
+	var params []*Parameter
+	for _, list := range [][]*Parameter{ref.Operation.Parameters, ref.Shared} {
+		for _, raw := range list {
+			p, err := spec.resolveParameter(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op.ID, err)
+			}
+			key := p.In + ":" + p.Name
+			if seen[key] || p.In == "cookie" {
+				continue
+			}
+			seen[key] = true
+			params = append(params, p)
+		}
+	}
+
+	for _, p := range params {
+		schema, err := spec.resolveSchema(p.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op.ID, err)
+		}
+		description := p.Description
+		if description == "" && schema != nil {
+			description = schema.Description
+		}
+		op.Params = append(op.Params, &param{
+			Field:    addField(fields, p.Name, paramType(schema), describe(description, schema, p.In, p.Required || p.In == "path")),
+			Wire:     p.Name,
+			In:       p.In,
+			Required: p.Required || p.In == "path",
+			Sample:   sampleValue(p.Name, schema),
+		})
+	}
+
+	// JSON request bodies become one config field per top-level property
+	body, err := spec.resolveRequestBody(ref.Operation.RequestBody)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op.ID, err)
+	}
+	if body != nil {
+		if media, ok := body.Content["application/json"]; ok {
+			schema, err := spec.resolveSchema(media.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op.ID, err)
+			}
+			if schema != nil && len(schema.Properties) > 0 {
+				op.HasBody = true
+				required := make(map[string]bool, len(schema.Required))
+				for _, name := range schema.Required {
+					required[name] = true
+				}
+				names := make([]string, 0, len(schema.Properties))
+				for name := range schema.Properties {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					property, err := spec.resolveSchema(schema.Properties[name])
+					if err != nil {
+						return nil, fmt.Errorf("%s: %w", op.ID, err)
+					}
+					description := ""
+					if property != nil {
+						description = property.Description
+					}
+					op.Params = append(op.Params, &param{
+						Field:    addField(fields, name, bodyType(property), describe(description, property, "body", required[name])),
+						Wire:     name,
+						In:       "body",
+						Required: required[name],
+						Sample:   sampleValue(name, property),
+					})
+				}
+			}
+		}
+	}
+
+	op.Response = sampleResponse(ref.Operation)
+	return op, nil
+}
+
+// addField returns the config field for a parameter, sharing it between operations
+// Parameters with the same name but different types fall back to a string field
+func addField(fields map[string]*field, wire, goType, comment string) *field {
+	name := goName(wire)
+	if name == "Operation" {
+		name = "OperationParam"
+	}
+	if existing, ok := fields[name]; ok {
+		if existing.GoType != goType {
+			existing.GoType = "string"
+		}
+		return existing
+	}
+	f := &field{GoName: name, JSON: snakeName(wire), GoType: goType, Comment: comment}
+	fields[name] = f
+	return f
+}
+
+// sortedFields lists config fields with Operation first, then alphabetically
+func sortedFields(fields map[string]*field) []*field {
+	list := make([]*field, 0, len(fields))
+	for name, f := range fields {
+		if name != "Operation" {
+			list = append(list, f)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].GoName < list[j].GoName })
+	return append([]*field{fields["Operation"]}, list...)
+}
+
+// authFor maps a security scheme to the generated credential handling
+func authFor(scheme *SecurityScheme) *auth {
+	switch {
+	case scheme.Type == "apiKey" && scheme.In == "header":
+		return &auth{Kind: "header", Name: scheme.Name, Hint: fmt.Sprintf("API key (sent in the %s header)", scheme.Name)}
+	case scheme.Type == "apiKey":
+		return &auth{Kind: "query", Name: scheme.Name, Hint: fmt.Sprintf("API key (sent as the %s query parameter)", scheme.Name)}
+	case strings.EqualFold(scheme.Scheme, "bearer"):
+		return &auth{Kind: "bearer", Hint: "Bearer token"}
+	default:
+		return &auth{Kind: "basic", Hint: "username:password"}
+	}
+}
+
+// paramType picks the Go type of a path, query or header parameter
+func paramType(schema *Schema) string {
+	if schema == nil {
+		return "string"
+	}
+	switch schema.Type {
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]string"
+	default:
+		return "string"
+	}
+}
+
+// bodyType picks the Go type of a JSON body property
+func bodyType(schema *Schema) string {
+	if schema != nil && (schema.Type == "object" || (schema.Type == "array" && (schema.Items == nil || schema.Items.Type != "string"))) {
+		return "interface{}"
+	}
+	return paramType(schema)
+}
+
+// describe builds the config field comment
+func describe(description string, schema *Schema, in string, required bool) string {
+	comment := firstLine(description)
+	if schema != nil && len(schema.Enum) > 0 {
+		values := make([]string, len(schema.Enum))
+		for i, v := range schema.Enum {
+			values[i] = fmt.Sprintf("%v", v)
+		}
+		comment = strings.TrimSpace(comment + " One of: " + strings.Join(values, ", "))
+	}
+	where := in
+	if required {
+		where += ", required"
+	}
+	if comment == "" {
+		return "(" + where + ")"
+	}
+	return comment + " (" + where + ")"
+}
+
+// sampleValue picks a deterministic value for the generated tests
+func sampleValue(name string, schema *Schema) interface{} {
+	goType := paramType(schema)
+	if schema != nil && schema.Type == "object" {
+		return map[string]interface{}{"key": "value"}
+	}
+	if schema != nil && len(schema.Enum) > 0 && goType == "string" {
+		return fmt.Sprintf("%v", schema.Enum[0])
+	}
+	switch goType {
+	case "int":
+		return 2
+	case "float64":
+		return 1.5
+	case "bool":
+		return true
+	case "[]string":
+		if schema != nil && schema.Items != nil && schema.Items.Type != "string" && schema.Items.Type != "" {
+			return []interface{}{map[string]interface{}{"key": "value"}}
+		}
+		return []string{"a", "b"}
+	default:
+		if schema != nil {
+			if example, ok := schema.Example.(string); ok && example != "" {
+				return example
+			}
+		}
+		return "sample-" + strings.ReplaceAll(snakeName(name), "_", "-")
+	}
+}
+
+// sampleResponse uses the first documented JSON example, or a small object
+func sampleResponse(op *Operation) string {
+	for _, status := range []string{"200", "201", "202"} {
+		response, ok := op.Responses[status]
+		if !ok || response == nil {
+			continue
+		}
+		if media, ok := response.Content["application/json"]; ok && media != nil && media.Example != nil {
+			if raw, err := json.Marshal(media.Example); err == nil {
+				return string(raw)
+			}
+		}
+	}
+	return `{"ok":true}`
+}
+
+// sampleConfig renders the config literal used by the test for an operation
+func sampleConfig(c *connector, op *operation, skip *param) string {
+	parts := []string{fmt.Sprintf("Operation: %q", op.ID)}
+	for _, p := range op.Params {
+		if p == skip {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", p.Field.GoName, goLiteral(p.Field.GoType, p.Sample)))
+	}
+	return fmt.Sprintf("connectors.%s{%s}", c.Config, strings.Join(parts, ", "))
+}
+
+// sampleRequest computes what the test server should receive for the sample config
+func sampleRequest(op *operation) error {
+	path := op.Path
+	query := url.Values{}
+	body := map[string]interface{}{}
+	op.SampleHeader = map[string]string{}
+
+	for _, p := range op.Params {
+		value := p.Sample
+		if p.Field.GoType == "string" {
+			value = wireString(value)
+		}
+		switch p.In {
+		case "path":
+			// The test server compares the decoded path
+			path = strings.ReplaceAll(path, "{"+p.Wire+"}", wireString(value))
+		case "query":
+			if list, ok := value.([]string); ok {
+				for _, item := range list {
+					query.Add(p.Wire, item)
+				}
+			} else {
+				query.Set(p.Wire, wireString(value))
+			}
+		case "header":
+			op.SampleHeader[p.Wire] = wireString(value)
+		case "body":
+			body[p.Wire] = value
+		}
+	}
+
+	op.SamplePath = path
+	op.SampleQuery = query.Encode()
+	if op.HasBody {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("%s: failed to encode sample body: %w", op.ID, err)
+		}
+		op.SampleBody = string(raw)
+	}
+	return nil
+}
+
+// pathExpr builds the Go expression for an operation's path
+func pathExpr(op *operation) string {
+	byName := make(map[string]*param)
+	for _, p := range op.Params {
+		if p.In == "path" {
+			byName[p.Wire] = p
+		}
+	}
+
+	var parts []string
+	rest := op.Path
+	for {
+		open := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if open < 0 || end < open {
+			break
+		}
+		if open > 0 {
+			parts = append(parts, strconv.Quote(rest[:open]))
+		}
+		if p, ok := byName[rest[open+1:end]]; ok {
+			parts = append(parts, "url.PathEscape("+formatExpr(p.Field.GoType, "config."+p.Field.GoName)+")")
+		} else {
+			parts = append(parts, strconv.Quote(rest[open:end+1]))
+		}
+		rest = rest[end+1:]
+	}
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(rest))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// formatExpr converts a config value to its string form on the wire
+func formatExpr(goType, expr string) string {
+	switch goType {
+	case "int":
+		return "strconv.Itoa(" + expr + ")"
+	case "float64":
+		return "strconv.FormatFloat(" + expr + ", 'f', -1, 64)"
+	case "bool":
+		return "strconv.FormatBool(" + expr + ")"
+	case "[]string":
+		return `strings.Join(` + expr + `, ",")`
+	default:
+		return expr
+	}
+}
+
+// setParam renders the code that puts a parameter on the request
+func setParam(p *param) string {
+	value := "config." + p.Field.GoName
+	var set string
+	switch p.In {
+	case "path":
+		return "" // Part of the path expression
+	case "query":
+		if p.Field.GoType == "[]string" {
+			set = fmt.Sprintf("for _, value := range %s {\nquery.Add(%q, value)\n}", value, p.Wire)
+		} else {
+			set = fmt.Sprintf("query.Set(%q, %s)", p.Wire, formatExpr(p.Field.GoType, value))
+		}
+	case "header":
+		set = fmt.Sprintf("header.Set(%q, %s)", p.Wire, formatExpr(p.Field.GoType, value))
+	case "body":
+		set = fmt.Sprintf("payload[%q] = %s", p.Wire, value)
+	}
+
+	if p.Required {
+		return set
+	}
+	switch {
+	case p.Field.GoType == "bool":
+		return fmt.Sprintf("if %s {\n%s\n}", value, set)
+	case p.Field.GoType == "[]string" && p.In == "query":
+		return set
+	default:
+		return fmt.Sprintf("if %s {\n%s\n}", nonZeroCheck(p), set)
+	}
+}
+
+// requiredCheck renders the missing-value error of a required parameter
+func requiredCheck(c *connector, op *operation, p *param) string {
+	check := zeroCheck(p)
+	if !p.Required || check == "" {
+		return ""
+	}
+	return fmt.Sprintf("if %s {\nreturn nil, fmt.Errorf(\"%s %s requires '%s'\")\n}", check, c.Label, op.ID, p.Field.JSON)
+}
+
+// zeroCheck returns the condition under which a field counts as unset ("" when it can't tell)
+func zeroCheck(p *param) string {
+	expr := "config." + p.Field.GoName
+	switch p.Field.GoType {
+	case "string":
+		return expr + ` == ""`
+	case "[]string":
+		return "len(" + expr + ") == 0"
+	case "interface{}":
+		return expr + " == nil"
+	case "int", "float64":
+		if p.In == "path" {
+			return ""
+		}
+		return expr + " == 0"
+	case "bool":
+		return ""
+	}
+	return ""
+}
+
+// nonZeroCheck returns the condition under which an optional field is sent
+func nonZeroCheck(p *param) string {
+	expr := "config." + p.Field.GoName
+	switch p.Field.GoType {
+	case "string":
+		return expr + ` != ""`
+	case "[]string":
+		return "len(" + expr + ") > 0"
+	case "interface{}":
+		return expr + " != nil"
+	case "bool":
+		return expr
+	default:
+		return expr + " != 0"
+	}
+}
+
+// messageLabel makes the API title safe inside generated string literals, format strings and comments
+func messageLabel(label string) string {
+	return strings.NewReplacer(`"`, `'`, `\`, "", "%", "", "\n", " ", "`", "'").Replace(label)
+}
+
+// goLiteral renders a sample value as a Go literal of the field's type
+func goLiteral(goType string, value interface{}) string {
+	switch goType {
+	case "string":
+		return strconv.Quote(wireString(value))
+	case "[]string":
+		if list, ok := value.([]string); ok {
+			quoted := make([]string, len(list))
+			for i, item := range list {
+				quoted[i] = strconv.Quote(item)
+			}
+			return "[]string{" + strings.Join(quoted, ", ") + "}"
+		}
+		return `[]string{"a"}`
+	case "interface{}":
+		return goValueLiteral(value)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// goValueLiteral renders generic JSON-like data as a Go literal
+func goValueLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = strconv.Quote(key) + ": " + goValueLiteral(v[key])
+		}
+		return "map[string]interface{}{" + strings.Join(parts, ", ") + "}"
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = goValueLiteral(item)
+		}
+		return "[]interface{}{" + strings.Join(parts, ", ") + "}"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// wireString formats a sample scalar the way the generated code sends it
+func wireString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// commonInitialisms are written in upper case in Go identifiers
+var commonInitialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true,
+	"SKU": true, "SQL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// words splits snake_case, kebab-case, camelCase and "X-Header[name]" into words
+func words(s string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return result
+}
+
+// goName converts an API name to an exported Go identifier ("pet_id" -> "PetID")
+func goName(s string) string {
+	var b strings.Builder
+	for _, word := range words(s) {
+		upper := strings.ToUpper(word)
+		if commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+	}
+	name := b.String()
+	if name == "" {
+		return "Param"
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		name = "N" + name
+	}
+	return name
+}
+
+// snakeName converts an API name to the snake_case JSON config key ("petId" -> "pet_id")
+func snakeName(s string) string {
+	list := words(s)
+	for i, word := range list {
+		list[i] = strings.ToLower(word)
+	}
+	return strings.Join(list, "_")
+}
+
+func lowerFirst(s string) string {
+	for initialism := range commonInitialisms {
+		if strings.HasPrefix(s, initialism) && (len(s) == len(initialism) || unicode.IsUpper(rune(s[len(initialism)]))) {
+			return strings.ToLower(initialism) + s[len(initialism):]
+		}
+	}
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
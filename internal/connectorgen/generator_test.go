@@ -0,0 +1,156 @@
+package connectorgen_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/connectorgen"
+)
+
+const modulePath = "github.com/alexmacdonald/simple-ipass"
+
+// TestGeneratedConnectorsCompileAndPass generates connectors for both sample specs
+// into a copy of the connectors package, then vets and runs their generated tests
+func TestGeneratedConnectorsCompileAndPass(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Compiles generated code with the go tool")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available")
+	}
+
+	// Directories starting with "_" are ignored by ./... so a leftover copy can't break the build
+	dir, err := os.MkdirTemp(".", "_gen")
+	if err != nil {
+		t.Fatalf("Failed to create package dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	copyConnectorsPackage(t, dir)
+
+	importPath := modulePath + "/internal/connectorgen/" + filepath.Base(dir)
+	for _, sample := range []struct {
+		spec string
+		name string
+	}{
+		{"testdata/petstore.yaml", "pet_store"},
+		{"testdata/quotes.json", "quotes"},
+	} {
+		spec, err := connectorgen.LoadSpec(sample.spec)
+		if err != nil {
+			t.Fatalf("LoadSpec(%s) failed: %v", sample.spec, err)
+		}
+		files, err := connectorgen.Generate(spec, connectorgen.Options{Name: sample.name, ImportPath: importPath})
+		if err != nil {
+			t.Fatalf("Generate(%s) failed: %v", sample.spec, err)
+		}
+		for _, file := range files {
+			if err := os.WriteFile(filepath.Join(dir, file.Name), file.Content, 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", file.Name, err)
+			}
+		}
+	}
+
+	for _, args := range [][]string{
+		{"vet", "./" + filepath.Base(dir)},
+		{"test", "-count=1", "-run", "TestPetStore|TestQuotes", "./" + filepath.Base(dir)},
+	} {
+		cmd := exec.Command(goTool, args...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s failed: %v\n%s", args[0], err, output)
+		}
+	}
+}
+
+// copyConnectorsPackage copies the hand-written connectors (without their tests) into dir
+func copyConnectorsPackage(t *testing.T, dir string) {
+	t.Helper()
+	sources, err := filepath.Glob(filepath.Join("..", "engine", "connectors", "*.go"))
+	if err != nil || len(sources) == 0 {
+		t.Fatalf("Failed to find connectors package: %v", err)
+	}
+	for _, source := range sources {
+		if strings.HasSuffix(source, "_test.go") {
+			continue
+		}
+		content, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", source, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(source)), content, 0644); err != nil {
+			t.Fatalf("Failed to copy %s: %v", source, err)
+		}
+	}
+}
+
+// TestGenerateSelectedOperations checks operation selection, auth and naming
+func TestGenerateSelectedOperations(t *testing.T) {
+	spec, err := connectorgen.LoadSpec("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatalf("LoadSpec failed: %v", err)
+	}
+
+	files, err := connectorgen.Generate(spec, connectorgen.Options{
+		Name:       "pet_store",
+		Operations: []string{"showPetById"},
+		BaseURL:    "https://staging.petstore.example.com/",
+		ActionType: "pet_lookup",
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Name != "pet_store.go" || files[1].Name != "pet_store_test.go" {
+		t.Fatalf("Unexpected files: %+v", files)
+	}
+
+	source := string(files[0].Content)
+	for _, want := range []string{
+		`const PetStoreActionType = "pet_lookup"`,
+		"type PetStoreConnector struct",
+		"PetID     string `json:\"pet_id,omitempty\"`",
+		`baseURL = "https://staging.petstore.example.com"`,
+		`req.Header.Set("X-API-Key", c.APIKey)`,
+		"RegisterCredentialRequirements(PetStoreActionType",
+		"func (c *PetStoreConnector) DryRunPetStore(config PetStoreConfig) Result",
+	} {
+		if !strings.Contains(source, want) {
+			t.Errorf("Generated connector is missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Limit", "createPet", "strconv"} {
+		if strings.Contains(source, unwanted) {
+			t.Errorf("Generated connector should not contain %q", unwanted)
+		}
+	}
+}
+
+// TestGenerateRejectsBadInput covers invalid names, unknown operations and specs
+func TestGenerateRejectsBadInput(t *testing.T) {
+	spec, err := connectorgen.LoadSpec("testdata/quotes.json")
+	if err != nil {
+		t.Fatalf("LoadSpec failed: %v", err)
+	}
+
+	if _, err := connectorgen.Generate(spec, connectorgen.Options{Name: "Quotes-API"}); err == nil {
+		t.Error("Expected a non snake_case name to be rejected")
+	}
+	_, err = connectorgen.Generate(spec, connectorgen.Options{Name: "quotes", Operations: []string{"deleteQuote"}})
+	if err == nil || !strings.Contains(err.Error(), "getQuotesRandom") || !strings.Contains(err.Error(), "update_quote") {
+		t.Errorf("Expected the unknown operation error to list the available ones, got %v", err)
+	}
+
+	if _, err := connectorgen.ParseSpec([]byte(`{"swagger": "2.0", "paths": {"/a": {}}}`)); err == nil {
+		t.Error("Expected Swagger 2.0 specs to be rejected")
+	}
+	noServer := []byte(`{"openapi": "3.0.0", "paths": {"/a": {"get": {"operationId": "a"}}}}`)
+	spec, err = connectorgen.ParseSpec(noServer)
+	if err != nil {
+		t.Fatalf("ParseSpec failed: %v", err)
+	}
+	if _, err := connectorgen.Generate(spec, connectorgen.Options{Name: "a"}); err == nil {
+		t.Error("Expected a spec without a server URL to need --base-url")
+	}
+}
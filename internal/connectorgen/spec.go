@@ -0,0 +1,246 @@
+// Package connectorgen scaffolds REST connectors from OpenAPI 3 specs.
+//
+// It reads the subset of OpenAPI that matters for a connector skeleton:
+// servers, operations with their parameters and JSON request bodies, and
+// security schemes. The output is a connector file and a test file in the
+// style of the hand-written connectors in internal/engine/connectors; they are
+// a starting point to review and edit, not a finished integration.
+package connectorgen
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the part of an OpenAPI 3 document the generator uses
+type Spec struct {
+	OpenAPI    string                `yaml:"openapi"`
+	Info       Info                  `yaml:"info"`
+	Servers    []Server              `yaml:"servers"`
+	Paths      map[string]*PathItem  `yaml:"paths"`
+	Components Components            `yaml:"components"`
+	Security   []map[string][]string `yaml:"security"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Version     string `yaml:"version"`
+}
+
+// Server is a base URL the API is served from
+type Server struct {
+	URL string `yaml:"url"`
+}
+
+// PathItem holds the operations of one path
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"` // Shared by every operation on the path
+	Get        *Operation   `yaml:"get"`
+	Post       *Operation   `yaml:"post"`
+	Put        *Operation   `yaml:"put"`
+	Patch      *Operation   `yaml:"patch"`
+	Delete     *Operation   `yaml:"delete"`
+}
+
+// Operation is a single API call
+type Operation struct {
+	OperationID string                 `yaml:"operationId"`
+	Summary     string                 `yaml:"summary"`
+	Description string                 `yaml:"description"`
+	Parameters  []*Parameter           `yaml:"parameters"`
+	RequestBody *RequestBody           `yaml:"requestBody"`
+	Responses   map[string]*Response   `yaml:"responses"`
+	Security    *[]map[string][]string `yaml:"security"` // nil inherits the spec's security, empty disables it
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"` // "path", "query", "header" or "cookie"
+	Description string  `yaml:"description"`
+	Required    bool    `yaml:"required"`
+	Schema      *Schema `yaml:"schema"`
+}
+
+// RequestBody describes the body of POST/PUT/PATCH operations
+type RequestBody struct {
+	Ref      string                `yaml:"$ref"`
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+// Response describes one response status
+type Response struct {
+	Description string                `yaml:"description"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+// MediaType is the schema (and example) of a body
+type MediaType struct {
+	Schema  *Schema     `yaml:"schema"`
+	Example interface{} `yaml:"example"`
+}
+
+// Schema is the subset of JSON Schema used to pick Go types
+type Schema struct {
+	Ref         string             `yaml:"$ref"`
+	Type        string             `yaml:"type"`
+	Format      string             `yaml:"format"`
+	Description string             `yaml:"description"`
+	Enum        []interface{}      `yaml:"enum"`
+	Default     interface{}        `yaml:"default"`
+	Example     interface{}        `yaml:"example"`
+	Items       *Schema            `yaml:"items"`
+	Properties  map[string]*Schema `yaml:"properties"`
+	Required    []string           `yaml:"required"`
+}
+
+// Components holds reusable definitions referenced with $ref
+type Components struct {
+	Schemas         map[string]*Schema         `yaml:"schemas"`
+	Parameters      map[string]*Parameter      `yaml:"parameters"`
+	RequestBodies   map[string]*RequestBody    `yaml:"requestBodies"`
+	SecuritySchemes map[string]*SecurityScheme `yaml:"securitySchemes"`
+}
+
+// SecurityScheme describes how the API authenticates
+type SecurityScheme struct {
+	Type   string `yaml:"type"`   // "apiKey" or "http"
+	Name   string `yaml:"name"`   // Header or query parameter name (apiKey)
+	In     string `yaml:"in"`     // "header", "query" or "cookie" (apiKey)
+	Scheme string `yaml:"scheme"` // "bearer" or "basic" (http)
+}
+
+// LoadSpec reads an OpenAPI document in YAML or JSON
+func LoadSpec(path string) (*Spec, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	return ParseSpec(raw)
+}
+
+// ParseSpec parses an OpenAPI document (JSON is valid YAML)
+func ParseSpec(raw []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported spec version %q (only OpenAPI 3.x)", spec.OpenAPI)
+	}
+	if len(spec.Paths) == 0 {
+		return nil, fmt.Errorf("spec has no paths")
+	}
+	return &spec, nil
+}
+
+// operationRef is an operation with the path and method it lives at
+type operationRef struct {
+	Path      string
+	Method    string
+	Operation *Operation
+	Shared    []*Parameter
+}
+
+// operations lists every operation in a stable order (path, then method)
+func (s *Spec) operations() []operationRef {
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var ops []operationRef
+	for _, path := range paths {
+		item := s.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, m := range []struct {
+			method string
+			op     *Operation
+		}{{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch}, {"DELETE", item.Delete}} {
+			if m.op != nil {
+				ops = append(ops, operationRef{Path: path, Method: m.method, Operation: m.op, Shared: item.Parameters})
+			}
+		}
+	}
+	return ops
+}
+
+// resolveParameter follows a #/components/parameters reference
+func (s *Spec) resolveParameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+	if resolved, ok := s.Components.Parameters[name]; ok && name != p.Ref {
+		return s.resolveParameter(resolved)
+	}
+	return nil, fmt.Errorf("unresolved parameter reference %q", p.Ref)
+}
+
+// resolveSchema follows #/components/schemas references
+func (s *Spec) resolveSchema(schema *Schema) (*Schema, error) {
+	for depth := 0; schema != nil && schema.Ref != ""; depth++ {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := s.Components.Schemas[name]
+		if !ok || name == schema.Ref || depth > 10 {
+			return nil, fmt.Errorf("unresolved schema reference %q", schema.Ref)
+		}
+		schema = resolved
+	}
+	return schema, nil
+}
+
+// resolveRequestBody follows a #/components/requestBodies reference
+func (s *Spec) resolveRequestBody(body *RequestBody) (*RequestBody, error) {
+	if body == nil || body.Ref == "" {
+		return body, nil
+	}
+	name := strings.TrimPrefix(body.Ref, "#/components/requestBodies/")
+	if resolved, ok := s.Components.RequestBodies[name]; ok && name != body.Ref {
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("unresolved request body reference %q", body.Ref)
+}
+
+// securityFor picks the scheme an operation authenticates with (nil for public APIs)
+func (s *Spec) securityFor(op *Operation) *SecurityScheme {
+	requirements := s.Security
+	if op.Security != nil {
+		requirements = *op.Security
+	}
+	for _, requirement := range requirements {
+		names := make([]string, 0, len(requirement))
+		for name := range requirement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if scheme, ok := s.Components.SecuritySchemes[name]; ok && supportedScheme(scheme) {
+				return scheme
+			}
+		}
+	}
+	return nil
+}
+
+// supportedScheme reports whether the generator can emit auth code for a scheme
+func supportedScheme(scheme *SecurityScheme) bool {
+	switch scheme.Type {
+	case "apiKey":
+		return scheme.Name != "" && (scheme.In == "header" || scheme.In == "query")
+	case "http":
+		return strings.EqualFold(scheme.Scheme, "bearer") || strings.EqualFold(scheme.Scheme, "basic")
+	}
+	return false
+}
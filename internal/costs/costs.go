@@ -0,0 +1,162 @@
+package costs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// ServiceCost is the configured price for one connector (keyed by action type)
+type ServiceCost struct {
+	UnitCost    float64            `json:"unit_cost"`              // Price per unit
+	Unit        string             `json:"unit"`                   // 'call' or 'segment' (SMS)
+	PrefixCosts map[string]float64 `json:"prefix_costs,omitempty"` // Per-unit price by destination prefix (e.g., "+44")
+}
+
+// Table holds unit costs for every billable connector
+type Table struct {
+	Currency string                 `json:"currency"`
+	Services map[string]ServiceCost `json:"services"`
+}
+
+// DefaultTable returns list prices for the connectors that cost us money
+// Everything not listed is treated as free
+func DefaultTable() *Table {
+	return &Table{
+		Currency: "USD",
+		Services: map[string]ServiceCost{
+			"twilio_sms": {
+				UnitCost: 0.0079, // US/Canada outbound per segment
+				Unit:     "segment",
+				PrefixCosts: map[string]float64{
+					"+44": 0.0400, // UK
+					"+49": 0.0850, // Germany
+					"+61": 0.0515, // Australia
+					"+91": 0.0083, // India
+				},
+			},
+		},
+	}
+}
+
+// LoadTable reads the cost table from COST_TABLE_JSON (inline JSON) or
+// COST_TABLE_FILE (path to a JSON file), falling back to DefaultTable
+func LoadTable() (*Table, error) {
+	raw := os.Getenv("COST_TABLE_JSON")
+	if raw == "" {
+		if path := os.Getenv("COST_TABLE_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read cost table file: %w", err)
+			}
+			raw = string(data)
+		}
+	}
+	if raw == "" {
+		return DefaultTable(), nil
+	}
+
+	table := &Table{}
+	if err := json.Unmarshal([]byte(raw), table); err != nil {
+		return nil, fmt.Errorf("failed to parse cost table: %w", err)
+	}
+	if table.Currency == "" {
+		table.Currency = "USD"
+	}
+	return table, nil
+}
+
+// Estimate returns the estimated cost of a single connector call
+// Only successful calls are billed by providers, so failures cost nothing
+func (t *Table) Estimate(actionType string, result connectors.Result) float64 {
+	if t == nil {
+		return 0
+	}
+
+	service, ok := t.Services[actionType]
+	if !ok {
+		return 0
+	}
+
+	// Multi-recipient sends bill every delivered message, even when others failed
+	if service.Unit == "segment" && result.Data["recipients"] != nil {
+		total := 0.0
+		for _, recipient := range recipientsFromData(result.Data) {
+			if recipient["status"] == "success" {
+				total += segmentCost(service, recipient)
+			}
+		}
+		return total
+	}
+
+	if result.Status != "success" {
+		return 0
+	}
+	if service.Unit != "segment" {
+		return service.UnitCost
+	}
+	return segmentCost(service, result.Data)
+}
+
+// segmentCost prices one message by its segment count and destination
+func segmentCost(service ServiceCost, data map[string]interface{}) float64 {
+	segments := intFromData(data, "segments")
+	if segments == 0 {
+		segments = 1
+	}
+	to, _ := data["to"].(string)
+	return float64(segments) * service.unitCostFor(to)
+}
+
+// recipientsFromData reads per-recipient results (typed live, generic after a JSON round-trip)
+func recipientsFromData(data map[string]interface{}) []map[string]interface{} {
+	switch v := data["recipients"].(type) {
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		recipients := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if recipient, ok := item.(map[string]interface{}); ok {
+				recipients = append(recipients, recipient)
+			}
+		}
+		return recipients
+	default:
+		return nil
+	}
+}
+
+// unitCostFor picks the longest matching destination prefix price
+func (s ServiceCost) unitCostFor(destination string) float64 {
+	cost := s.UnitCost
+	longest := 0
+	for prefix, prefixCost := range s.PrefixCosts {
+		if strings.HasPrefix(destination, prefix) && len(prefix) > longest {
+			cost = prefixCost
+			longest = len(prefix)
+		}
+	}
+	return cost
+}
+
+// intFromData reads a numeric field from Result.Data (ints survive JSON round-trips as float64)
+func intFromData(data map[string]interface{}, key string) int {
+	switch v := data[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// Month returns the usage rollup period for a timestamp
+// Periods are calendar months in UTC so every tenant shares the same boundaries
+func Month(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
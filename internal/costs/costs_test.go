@@ -0,0 +1,113 @@
+package costs_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/costs"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestMonthBoundaries verifies rollup periods are UTC calendar months
+func TestMonthBoundaries(t *testing.T) {
+	berlin := time.FixedZone("CET", 60*60)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected string
+	}{
+		{"last second of January", time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC), "2024-01"},
+		{"first second of February", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "2024-02"},
+		{"leap day", time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), "2024-02"},
+		{"year rollover", time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC), "2024-12"},
+		{"new year", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "2025-01"},
+		{"local midnight still previous UTC month", time.Date(2024, 3, 1, 0, 30, 0, 0, berlin), "2024-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := costs.Month(tt.at); got != tt.expected {
+				t.Errorf("Month(%s) = %s, want %s", tt.at, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestEstimateTwilio verifies per-segment pricing with destination prefixes
+func TestEstimateTwilio(t *testing.T) {
+	table := costs.DefaultTable()
+
+	us := connectors.Result{Status: "success", Data: map[string]interface{}{"to": "+15551234567", "segments": 2}}
+	if got := table.Estimate("twilio_sms", us); math.Abs(got-0.0158) > 1e-9 {
+		t.Errorf("US 2-segment cost = %f, want 0.0158", got)
+	}
+
+	uk := connectors.Result{Status: "success", Data: map[string]interface{}{"to": "+447700900123", "segments": 1}}
+	if got := table.Estimate("twilio_sms", uk); math.Abs(got-0.04) > 1e-9 {
+		t.Errorf("UK 1-segment cost = %f, want 0.04", got)
+	}
+
+	failed := connectors.Result{Status: "failed", Data: map[string]interface{}{"to": "+15551234567", "segments": 1}}
+	if got := table.Estimate("twilio_sms", failed); got != 0 {
+		t.Errorf("Failed SMS cost = %f, want 0", got)
+	}
+
+	// Partially delivered multi-recipient sends bill the delivered messages
+	multi := connectors.Result{Status: "failed", Data: map[string]interface{}{"recipients": []interface{}{
+		map[string]interface{}{"to": "+15551234567", "status": "success", "segments": float64(1)},
+		map[string]interface{}{"to": "+447700900123", "status": "success", "segments": float64(1)},
+		map[string]interface{}{"to": "+447700900999", "status": "failed", "segments": float64(1)},
+	}}}
+	if got := table.Estimate("twilio_sms", multi); math.Abs(got-0.0479) > 1e-9 {
+		t.Errorf("Multi-recipient cost = %f, want 0.0479", got)
+	}
+
+	if got := table.Estimate("swapi_fetch", connectors.Result{Status: "success"}); got != 0 {
+		t.Errorf("Free connector cost = %f, want 0", got)
+	}
+}
+
+// TestMonthlyRollup verifies costs recorded either side of a month boundary
+// land in separate usage rows
+func TestMonthlyRollup(t *testing.T) {
+	database := dbtest.New(t)
+
+	tenantID := "tenant_rollup"
+	endOfJanuary := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+	startOfFebruary := endOfJanuary.Add(time.Second)
+
+	records := []struct {
+		at   time.Time
+		cost float64
+	}{
+		{endOfJanuary, 0.0079},
+		{endOfJanuary, 0.0158},
+		{startOfFebruary, 0.04},
+	}
+	for _, rec := range records {
+		if err := database.RecordWorkflowCost(tenantID, "wf_1", costs.Month(rec.at), rec.cost, 0); err != nil {
+			t.Fatalf("RecordWorkflowCost failed: %v", err)
+		}
+	}
+	// Another tenant's spend must not leak into the report
+	database.RecordWorkflowCost("tenant_other", "wf_2", "2024-01", 1.0, 0)
+
+	january, err := database.GetWorkflowCosts(tenantID, "2024-01")
+	if err != nil {
+		t.Fatalf("GetWorkflowCosts failed: %v", err)
+	}
+	if len(january) != 1 || january[0].Executions != 2 || math.Abs(january[0].Cost-0.0237) > 1e-9 {
+		t.Errorf("January rollup = %+v, want 1 row with 2 executions costing 0.0237", january)
+	}
+
+	february, err := database.GetWorkflowCosts(tenantID, "2024-02")
+	if err != nil {
+		t.Fatalf("GetWorkflowCosts failed: %v", err)
+	}
+	if len(february) != 1 || february[0].Executions != 1 || math.Abs(february[0].Cost-0.04) > 1e-9 {
+		t.Errorf("February rollup = %+v, want 1 row with 1 execution costing 0.04", february)
+	}
+}
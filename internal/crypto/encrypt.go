@@ -6,19 +6,18 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 // GetEncryptionKey retrieves or generates the encryption key
 // In production, this should come from a secure key management service
 func GetEncryptionKey() []byte {
 	// Check environment variable first
-	if key := os.Getenv("ENCRYPTION_KEY"); key != "" {
-		decoded, err := base64.StdEncoding.DecodeString(key)
-		if err == nil && len(decoded) == 32 {
-			return decoded
-		}
+	if key := envKey("ENCRYPTION_KEY"); key != nil {
+		return key
 	}
 	
 	// For POC: Use a fixed key (DO NOT DO THIS IN PRODUCTION)
@@ -27,44 +26,131 @@ func GetEncryptionKey() []byte {
 	return key
 }
 
+// envKey decodes a base64 32-byte key from an environment variable, or returns nil
+func envKey(name string) []byte {
+	if key := os.Getenv(name); key != "" {
+		if decoded, err := ParseKey(key); err == nil {
+			return decoded
+		}
+	}
+	return nil
+}
+
+// ParseKey decodes a base64 master key, which must be 32 bytes
+// (generate one with: openssl rand -base64 32)
+func ParseKey(encoded string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(decoded) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes, base64 encoded")
+	}
+	return decoded, nil
+}
+
+// boundPrefix marks ciphertexts sealed with additional authenticated data
+// Unprefixed values are legacy ciphertexts written before AAD binding
+const boundPrefix = "v2:"
+
+// ErrContextMismatch means a bound ciphertext failed authentication for the
+// context it was read with - typically because it was copied from another row
+var ErrContextMismatch = errors.New("ciphertext does not match its context")
+
 // Encrypt encrypts plain text using AES-GCM
 func Encrypt(plaintext string) (string, error) {
-	key := GetEncryptionKey()
-	
-	block, err := aes.NewCipher(key)
+	ciphertext, err := seal(plaintext, nil)
 	if err != nil {
 		return "", err
 	}
-	
-	aesGCM, err := cipher.NewGCM(block)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts cipher text using AES-GCM
+func Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
 		return "", err
 	}
-	
-	nonce := make([]byte, aesGCM.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+	return open(data, nil)
+}
+
+// EncryptWithAAD encrypts plain text bound to aad (e.g. the owning row's identity)
+// The same aad must be supplied to decrypt, so the value can't be moved elsewhere
+func EncryptWithAAD(plaintext string, aad []byte) (string, error) {
+	ciphertext, err := seal(plaintext, aad)
+	if err != nil {
 		return "", err
 	}
-	
-	ciphertext := aesGCM.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return boundPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Decrypt decrypts cipher text using AES-GCM
-func Decrypt(ciphertext string) (string, error) {
-	key := GetEncryptionKey()
-	
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
+// DecryptWithAAD decrypts a value written by EncryptWithAAD with the same aad
+// Legacy unbound ciphertexts still decrypt (use IsBound to find rows to upgrade);
+// a bound ciphertext opened with different aad returns ErrContextMismatch
+func DecryptWithAAD(ciphertext string, aad []byte) (string, error) {
+	if !IsBound(ciphertext) {
+		return Decrypt(ciphertext)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, boundPrefix))
 	if err != nil {
 		return "", err
 	}
-	
+	plaintext, err := open(data, aad)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrContextMismatch, err)
+	}
+	return plaintext, nil
+}
+
+// IsBound reports whether a ciphertext was sealed with additional authenticated data
+// (directly under the master key, or under a tenant key)
+func IsBound(ciphertext string) bool {
+	return strings.HasPrefix(ciphertext, boundPrefix) || strings.HasPrefix(ciphertext, tenantPrefix)
+}
+
+// newGCM creates the AES-GCM cipher for a key
+func newGCM(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext under the current master key
+func seal(plaintext string, aad []byte) ([]byte, error) {
+	return sealWith(GetEncryptionKey(), plaintext, aad)
+}
+
+// sealWith returns nonce || ciphertext under key
+func sealWith(key []byte, plaintext string, aad []byte) ([]byte, error) {
+	aesGCM, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
 	}
 	
-	aesGCM, err := cipher.NewGCM(block)
+	return aesGCM.Seal(nonce, nonce, []byte(plaintext), aad), nil
+}
+
+// open reverses seal, trying the previous master key too while a rotation is in progress
+func open(data, aad []byte) (string, error) {
+	var plaintext string
+	var err error
+	for _, key := range masterKeys() {
+		if plaintext, err = openWith(key, data, aad); err == nil {
+			return plaintext, nil
+		}
+	}
+	return "", err
+}
+
+// openWith reverses sealWith
+func openWith(key, data, aad []byte) (string, error) {
+	aesGCM, err := newGCM(key)
 	if err != nil {
 		return "", err
 	}
@@ -75,11 +161,10 @@ func Decrypt(ciphertext string) (string, error) {
 	}
 	
 	nonce, encryptedData := data[:nonceSize], data[nonceSize:]
-	plaintext, err := aesGCM.Open(nil, nonce, encryptedData, nil)
+	plaintext, err := aesGCM.Open(nil, nonce, encryptedData, aad)
 	if err != nil {
 		return "", err
 	}
 	
 	return string(plaintext), nil
 }
-
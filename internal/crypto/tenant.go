@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// tenantPrefix marks ciphertexts sealed with a tenant's derived data key:
+// "v3:<master key ID>:<base64>". The tenant key version is stored with the row
+const tenantPrefix = "v3:"
+
+// tenantKeySalt separates tenant data keys from any other use of the master key
+var tenantKeySalt = []byte("goflow tenant data key")
+
+// ErrTenantMismatch means a row was read on behalf of a tenant that doesn't own it
+var ErrTenantMismatch = errors.New("ciphertext belongs to another tenant")
+
+// PreviousEncryptionKey returns the master key being rotated away from, if any
+// Set ENCRYPTION_KEY_PREVIOUS to the old key while rows are re-wrapped under the new one
+func PreviousEncryptionKey() []byte {
+	return envKey("ENCRYPTION_KEY_PREVIOUS")
+}
+
+// masterKeys returns the current master key, then the previous one if set
+func masterKeys() [][]byte {
+	keys := [][]byte{GetEncryptionKey()}
+	if previous := PreviousEncryptionKey(); previous != nil && !bytes.Equal(previous, keys[0]) {
+		keys = append(keys, previous)
+	}
+	return keys
+}
+
+// MasterKeyID identifies a master key without revealing it
+func MasterKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// CurrentTenantPrefix is the prefix of every value sealed under the current master key
+// Values without it (legacy, or from before a master rotation) need re-wrapping
+func CurrentTenantPrefix() string {
+	return tenantPrefix + MasterKeyID(GetEncryptionKey()) + ":"
+}
+
+// DeriveTenantKey derives a tenant's data key from a master key
+// HKDF-SHA256 with the tenant ID and key version as info, so every tenant (and every
+// rotation of a tenant's key) gets an independent key without storing any of them
+func DeriveTenantKey(master []byte, tenantID string, version int) ([]byte, error) {
+	if tenantID == "" {
+		return nil, errors.New("tenant ID is required")
+	}
+	info := []byte("tenant\x00" + tenantID + "\x00" + strconv.Itoa(version))
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, tenantKeySalt, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncryptForTenant encrypts plain text with the tenant's data key, bound to aad
+func EncryptForTenant(plaintext, tenantID string, version int, aad []byte) (string, error) {
+	master := GetEncryptionKey()
+	key, err := DeriveTenantKey(master, tenantID, version)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := sealWith(key, plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+	return tenantPrefix + MasterKeyID(master) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptForTenant decrypts a value written by EncryptForTenant for the same tenant,
+// key version and aad. Values from before tenant keys (see DecryptWithAAD) still decrypt;
+// anything sealed for another tenant or row returns ErrContextMismatch
+func DecryptForTenant(ciphertext, tenantID string, version int, aad []byte) (string, error) {
+	if !strings.HasPrefix(ciphertext, tenantPrefix) {
+		return DecryptWithAAD(ciphertext, aad)
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(ciphertext, tenantPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed tenant ciphertext")
+	}
+	var master []byte
+	for _, key := range masterKeys() {
+		if MasterKeyID(key) == keyID {
+			master = key
+			break
+		}
+	}
+	if master == nil {
+		return "", fmt.Errorf("ciphertext was sealed with unknown master key %s", keyID)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	key, err := DeriveTenantKey(master, tenantID, version)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openWith(key, data, aad)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrContextMismatch, err)
+	}
+	return plaintext, nil
+}
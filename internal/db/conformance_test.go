@@ -0,0 +1,388 @@
+package db_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+)
+
+// conformanceStores returns the stores the conformance tests run against: SQLite and the
+// mock always, and Postgres when TEST_DATABASE_URL is set
+func conformanceStores(t *testing.T) map[string]db.Store {
+	t.Helper()
+	stores := map[string]db.Store{
+		"sqlite": dbtest.New(t),
+		"mock":   db.NewMockStore(),
+	}
+	if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
+		stores["postgres"] = newPostgresTestDatabase(t, dsn)
+	}
+	return stores
+}
+
+// newPostgresTestDatabase opens the Postgres database at dsn in a fresh schema, dropped
+// when the test ends, so tests never see each other's rows
+func newPostgresTestDatabase(t *testing.T, dsn string) *db.Database {
+	t.Helper()
+	raw, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open TEST_DATABASE_URL: %v", err)
+	}
+	schema := fmt.Sprintf("goflow_test_%d", time.Now().UnixNano())
+	if _, err := raw.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		raw.Close()
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		raw.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		raw.Close()
+	})
+
+	// pq passes unknown parameters on as run-time settings, in URLs and key=value strings alike
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	database, err := db.NewPostgres(dsn)
+	if err != nil {
+		t.Fatalf("Failed to open Postgres store: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// TestStoreErrorConformance runs the same failing calls against every store
+// and expects each to report the same error kind
+func TestStoreErrorConformance(t *testing.T) {
+	for name, s := range conformanceStores(t) {
+		t.Run(name, func(t *testing.T) {
+			user, err := s.CreateUser("conformance@example.com", "hashed")
+			if err != nil {
+				t.Fatalf("Failed to create user: %v", err)
+			}
+			workflow, err := s.CreateWorkflow(user.ID, "Conformance", "webhook", "testing", `{}`)
+			if err != nil {
+				t.Fatalf("Failed to create workflow: %v", err)
+			}
+			fixture := func() *models.WorkflowFixture {
+				return &models.WorkflowFixture{WorkflowID: workflow.ID, Name: "order", Payload: []byte(`{}`), Source: "manual"}
+			}
+			if err := s.CreateWorkflowFixture(fixture(), 10); err != nil {
+				t.Fatalf("Failed to create fixture: %v", err)
+			}
+			domain := func(tenantID string) *models.TenantDomain {
+				return &models.TenantDomain{TenantID: tenantID, Domain: "hooks.example.com", VerificationToken: "token", Status: models.DomainPending}
+			}
+			if err := s.CreateTenantDomain(domain("tenant_a")); err != nil {
+				t.Fatalf("Failed to create domain: %v", err)
+			}
+			letter, err := s.CreateDeadLetter(workflow.ID, "queue full", `{}`)
+			if err != nil || s.ResolveDeadLetter(letter.ID, time.Now()) != nil {
+				t.Fatalf("Failed to create and resolve a dead letter: %v", err)
+			}
+
+			calls := []struct {
+				name string
+				kind error
+				call func() error
+			}{
+				{"GetUserByID", store.ErrNotFound, func() error { _, err := s.GetUserByID("missing"); return err }},
+				{"GetUserByEmail", store.ErrNotFound, func() error { _, err := s.GetUserByEmail("missing@example.com"); return err }},
+				{"UpdateUserPassword", store.ErrNotFound, func() error { _, err := s.UpdateUserPassword("missing", "hashed"); return err }},
+				{"ResetPassword", store.ErrNotFound, func() error { _, err := s.ResetPassword("missing", "hashed", time.Now()); return err }},
+				{"GetWorkflowByID", store.ErrNotFound, func() error { _, err := s.GetWorkflowByID("missing"); return err }},
+				{"UpdateWorkflow", store.ErrNotFound, func() error { return s.UpdateWorkflow(&models.Workflow{ID: "missing", Name: "x"}) }},
+				{"UpdateWorkflowActive", store.ErrNotFound, func() error { return s.UpdateWorkflowActive("missing", true) }},
+				{"GetActiveWorkflow", store.ErrNotFound, func() error { _, err := s.GetActiveWorkflow("missing"); return err }},
+				{"UpdateWorkflowLastExecuted", store.ErrNotFound, func() error { return s.UpdateWorkflowLastExecuted("missing", time.Now(), nil) }},
+				{"DeleteWorkflow", store.ErrNotFound, func() error { return s.DeleteWorkflow("missing") }},
+				{"GetLogByID", store.ErrNotFound, func() error { _, err := s.GetLogByID("missing"); return err }},
+				{"AcknowledgeLog", store.ErrNotFound, func() error { return s.AcknowledgeLog("missing", user.ID, time.Now()) }},
+				{"GetLatestExecution", store.ErrNotFound, func() error { _, err := s.GetLatestExecution(workflow.ID); return err }},
+				{"GetExecutionByID", store.ErrNotFound, func() error { _, err := s.GetExecutionByID("missing"); return err }},
+				{"GetWorkflowFixture", store.ErrNotFound, func() error { _, err := s.GetWorkflowFixture(workflow.ID, "missing"); return err }},
+				{"DeleteWorkflowFixture", store.ErrNotFound, func() error { return s.DeleteWorkflowFixture(workflow.ID, "missing") }},
+				{"GetWebhookRequest", store.ErrNotFound, func() error { _, err := s.GetWebhookRequest(workflow.ID, "missing"); return err }},
+				{"SetWorkflowDebugRequests", store.ErrNotFound, func() error { return s.SetWorkflowDebugRequests("missing", nil, 10) }},
+				{"GetImpersonationSession", store.ErrNotFound, func() error { _, err := s.GetImpersonationSession("missing"); return err }},
+				{"RevokeImpersonationSession", store.ErrNotFound, func() error { return s.RevokeImpersonationSession("missing", user.ID, time.Now()) }},
+				{"CreateUser duplicate", store.ErrConflict, func() error { _, err := s.CreateUser("conformance@example.com", "hashed"); return err }},
+				{"CreateWorkflowFixture duplicate", store.ErrConflict, func() error { return s.CreateWorkflowFixture(fixture(), 10) }},
+				{"GetTenantByID", store.ErrNotFound, func() error { _, err := s.GetTenantByID("missing"); return err }},
+				{"CreateTenant duplicate", store.ErrConflict, func() error { _, err := s.CreateTenant(user.TenantID, "x"); return err }},
+				{"GetTenantInvite", store.ErrNotFound, func() error { _, err := s.GetTenantInvite("missing"); return err }},
+				{"RevokeTenantInvite", store.ErrNotFound, func() error { return s.RevokeTenantInvite(user.TenantID, "missing", time.Now()) }},
+				{"DeleteCredential", store.ErrNotFound, func() error { return s.DeleteCredential(user.TenantID, "missing") }},
+				{"AcceptTenantInvite", store.ErrNotFound, func() error { _, err := s.AcceptTenantInvite("missing", "hashed", time.Now()); return err }},
+				{"CreateUserInTenant missing", store.ErrNotFound, func() error { _, err := s.CreateUserInTenant("missing", "new@example.com", "hashed"); return err }},
+				{"GetTenantDomain", store.ErrNotFound, func() error { _, err := s.GetTenantDomain("tenant_a", "missing"); return err }},
+				{"GetTenantDomainByName", store.ErrNotFound, func() error { _, err := s.GetTenantDomainByName("missing.example.com"); return err }},
+				{"UpdateTenantDomain", store.ErrNotFound, func() error { return s.UpdateTenantDomain(&models.TenantDomain{ID: "missing", TenantID: "tenant_a"}) }},
+				{"DeleteTenantDomain", store.ErrNotFound, func() error { return s.DeleteTenantDomain("tenant_a", "missing") }},
+				{"CreateTenantDomain taken", store.ErrConflict, func() error { return s.CreateTenantDomain(domain("tenant_b")) }},
+				{"GetDeadLetter", store.ErrNotFound, func() error { _, err := s.GetDeadLetter("missing"); return err }},
+				{"ResolveDeadLetter", store.ErrNotFound, func() error { return s.ResolveDeadLetter("missing", time.Now()) }},
+				{"ResolveDeadLetter twice", store.ErrConflict, func() error { return s.ResolveDeadLetter(letter.ID, time.Now()) }},
+			}
+			for _, c := range calls {
+				err := c.call()
+				if !errors.Is(err, c.kind) {
+					t.Errorf("%s: expected %v, got %v", c.name, c.kind, err)
+				}
+			}
+
+			// The more specific sentinels still match alongside their kind
+			if err := s.CreateWorkflowFixture(fixture(), 10); !errors.Is(err, db.ErrFixtureExists) {
+				t.Errorf("Expected ErrFixtureExists, got %v", err)
+			}
+			if err := s.CreateTenantDomain(domain("tenant_b")); !errors.Is(err, db.ErrDomainTaken) {
+				t.Errorf("Expected ErrDomainTaken, got %v", err)
+			}
+		})
+	}
+}
+
+// TestStoreErrorUnavailable checks a closed database reports store.ErrUnavailable
+// rather than a driver message callers would have to match
+func TestStoreErrorUnavailable(t *testing.T) {
+	database := dbtest.New(t)
+	database.Close()
+
+	_, err := database.GetUserByID("anyone")
+	if !store.IsUnavailable(err) {
+		t.Errorf("Expected store.ErrUnavailable from a read, got %v", err)
+	}
+	if err := database.UpdateWorkflowActive("anything", true); !store.IsUnavailable(err) {
+		t.Errorf("Expected store.ErrUnavailable from a write, got %v", err)
+	}
+	if store.IsNotFound(err) {
+		t.Error("An unavailable store must not look like a missing record")
+	}
+}
+
+// TestStoreConformance runs the same calls against every store and expects the same
+// results, covering the queries whose SQL differs most between dialects: upserts,
+// boolean filters, rolling windows, row-value cursors and rowid ordering
+func TestStoreConformance(t *testing.T) {
+	for name, s := range conformanceStores(t) {
+		t.Run(name, func(t *testing.T) {
+			user, err := s.CreateUser("behaviour@example.com", "hashed")
+			if err != nil {
+				t.Fatalf("Failed to create user: %v", err)
+			}
+			scheduled, _ := s.CreateWorkflow(user.ID, "Nightly", "schedule", "testing", `{}`)
+			paused, _ := s.CreateWorkflow(user.ID, "Paused", "schedule", "testing", `{}`)
+			if err := s.UpdateWorkflowActive(paused.ID, false); err != nil {
+				t.Fatalf("Failed to pause workflow: %v", err)
+			}
+
+			hook, _ := s.CreateWorkflow(user.ID, "Hook", "webhook", "testing", `{}`)
+			later, _ := s.CreateWorkflow(user.ID, "Later", "schedule", "testing", `{}`)
+			ran := time.Now()
+			next := ran.Add(time.Hour)
+			s.UpdateWorkflowLastExecuted(later.ID, ran, &next)
+
+			due, err := s.GetDueWorkflows(time.Now())
+			if err != nil || len(due) != 1 || due[0].ID != scheduled.ID || due[0].NextRunAt == nil {
+				t.Errorf("Expected only the active workflow that's due, got %v, %v", due, err)
+			}
+			if due, _ := s.GetDueWorkflows(next); len(due) != 2 || due[0].ID != scheduled.ID {
+				t.Errorf("Expected both active schedules due in an hour, most overdue first, got %v", due)
+			}
+			if _, err := s.GetActiveWorkflow(paused.ID); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("Expected the paused workflow not found as active, got %v", err)
+			}
+			if got, err := s.GetActiveWorkflow(scheduled.ID); err != nil || got.ID != scheduled.ID {
+				t.Errorf("Expected the active workflow, got %v, %v", got, err)
+			}
+			s.DeleteWorkflow(hook.ID)
+			s.DeleteWorkflow(later.ID)
+
+			first, err := s.ListWorkflows(user.ID, models.PageRequest{Limit: 1})
+			if err != nil || len(first) != 1 {
+				t.Fatalf("Expected a first page of one, got %v, %v", first, err)
+			}
+			second, err := s.ListWorkflows(user.ID, models.PageRequest{Limit: 1, Cursor: &models.Cursor{Time: first[0].CreatedAt, ID: first[0].ID}})
+			if err != nil || len(second) != 1 || second[0].ID == first[0].ID {
+				t.Errorf("Expected the cursor to return the other workflow, got %v, %v", second, err)
+			}
+
+			now := time.Now()
+			s.RecordWorkflowExecution(scheduled.ID, "success", 100*time.Millisecond, "", now)
+			s.RecordWorkflowExecution(scheduled.ID, "failed", 300*time.Millisecond, "boom", now)
+			if err := s.RecordWorkflowExecution(scheduled.ID, models.StatusSuppressed, 0, "", now); err != nil {
+				t.Fatalf("Failed to record executions: %v", err)
+			}
+			workflow, err := s.GetWorkflowByID(scheduled.ID)
+			if err != nil {
+				t.Fatalf("Failed to get workflow: %v", err)
+			}
+			stats := workflow.Stats
+			if stats.TotalExecutions != 2 || stats.ConsecutiveFailures != 1 || stats.SuppressedExecutions != 1 ||
+				stats.Successes24h != 1 || stats.Successes7d != 1 || stats.LastError != "boom" || stats.AvgDurationMS != 200 {
+				t.Errorf("Unexpected stats: %+v", stats)
+			}
+
+			// A failure alert goes out once per cooldown until the workflow recovers
+			if failures, due, err := s.ClaimFailureAlert(scheduled.ID, 1, time.Hour, now); err != nil || !due || failures != 1 {
+				t.Errorf("Expected an alert due after 1 failure, got %d, %v, %v", failures, due, err)
+			}
+			if _, due, _ := s.ClaimFailureAlert(scheduled.ID, 1, time.Hour, now.Add(30*time.Minute)); due {
+				t.Error("Expected no second alert within the cooldown")
+			}
+			if _, due, _ := s.ClaimFailureAlert(scheduled.ID, 1, time.Hour, now.Add(61*time.Minute)); !due {
+				t.Error("Expected another alert after the cooldown")
+			}
+			if _, due, _ := s.ClaimFailureAlert(scheduled.ID, 2, 0, now.Add(2*time.Hour)); due {
+				t.Error("Expected no alert below the threshold")
+			}
+			if recovered, err := s.ClearFailureAlert(scheduled.ID); err != nil || !recovered {
+				t.Errorf("Expected the alert cleared, got %v, %v", recovered, err)
+			}
+			if recovered, _ := s.ClearFailureAlert(scheduled.ID); recovered {
+				t.Error("Expected nothing left to clear")
+			}
+
+			tenantID := models.DefaultTenantID(user.ID)
+			s.RecordWorkflowCost(tenantID, scheduled.ID, "2026-10", 0.25, 10)
+			if err := s.RecordWorkflowCost(tenantID, scheduled.ID, "2026-10", 0.5, 5); err != nil {
+				t.Fatalf("Failed to record cost: %v", err)
+			}
+			costs, err := s.GetWorkflowCosts(tenantID, "2026-10")
+			if err != nil || len(costs) != 1 || costs[0].Executions != 2 || costs[0].Cost != 0.75 || costs[0].DelayMS != 15 {
+				t.Errorf("Expected one rollup of 2 executions, got %+v, %v", costs, err)
+			}
+
+			seen, err := s.MarkWebhookEventSeen(scheduled.ID, "evt_1", now, now.Add(time.Hour))
+			if err != nil || !seen {
+				t.Errorf("Expected a new event, got %v, %v", seen, err)
+			}
+			if seen, _ := s.MarkWebhookEventSeen(scheduled.ID, "evt_1", now.Add(time.Minute), now.Add(time.Hour)); seen {
+				t.Error("Expected a duplicate event within its TTL")
+			}
+			if err := s.ForgetWebhookEvent(scheduled.ID, "evt_1"); err != nil {
+				t.Errorf("Failed to forget event: %v", err)
+			}
+			if seen, _ := s.MarkWebhookEventSeen(scheduled.ID, "evt_1", now.Add(2*time.Minute), now.Add(time.Hour)); !seen {
+				t.Error("Expected a forgotten event to be new again")
+			}
+
+			var captured []string
+			for i := 0; i < 3; i++ {
+				request := &models.WebhookRequest{WorkflowID: scheduled.ID, ReceivedAt: now.Add(time.Duration(i) * time.Second),
+					Method: "POST", Verdict: "executed", BodyTruncated: i == 2}
+				if err := s.CaptureWebhookRequest(request, 2); err != nil {
+					t.Fatalf("Failed to capture request: %v", err)
+				}
+				captured = append(captured, request.ID)
+			}
+			requests, err := s.ListWebhookRequests(scheduled.ID)
+			if err != nil || len(requests) != 2 || requests[0].ID != captured[2] || requests[1].ID != captured[1] || !requests[0].BodyTruncated {
+				t.Errorf("Expected the two newest requests, newest first, got %+v, %v", requests, err)
+			}
+
+			var saved []string
+			for i := 0; i < 3; i++ {
+				payload := &models.WebhookPayload{WorkflowID: scheduled.ID, ReceivedAt: now.Add(time.Duration(i) * time.Second),
+					Payload: json.RawMessage(fmt.Sprintf(`{"n":%d}`, i))}
+				if err := s.SaveWebhookPayload(payload, 2); err != nil {
+					t.Fatalf("Failed to save payload: %v", err)
+				}
+				saved = append(saved, payload.ID)
+			}
+			payloads, err := s.ListWebhookPayloads(scheduled.ID)
+			if err != nil || len(payloads) != 2 || payloads[0].ID != saved[2] || payloads[1].ID != saved[1] || string(payloads[0].Payload) != `{"n":2}` {
+				t.Errorf("Expected the two newest payloads, newest first, got %+v, %v", payloads, err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := s.CreateExecution(&models.Execution{WorkflowID: scheduled.ID, Status: "success", TriggerSource: "schedule", CatchUp: i == 1}); err != nil {
+					t.Fatalf("Failed to create execution: %v", err)
+				}
+			}
+			// Clearing a workflow's logs goes oldest first, at most limit at a time
+			for i := 0; i < 5; i++ {
+				s.CreateLogEntry(&models.Log{WorkflowID: scheduled.ID, Status: "success", Message: fmt.Sprint(i), ExecutedAt: now.Add(time.Duration(i-10) * time.Minute)})
+			}
+			s.CreateLogEntry(&models.Log{WorkflowID: paused.ID, Status: "success", ExecutedAt: now.Add(-time.Hour)})
+			if deleted, err := s.DeleteWorkflowLogs(scheduled.ID, 2); err != nil || deleted != 2 {
+				t.Errorf("Expected a batch of 2 deleted, got %d, %v", deleted, err)
+			}
+			logs, _ := s.GetLogsByWorkflowID(scheduled.ID)
+			if len(logs) != 3 {
+				t.Errorf("Expected 3 logs kept, got %d", len(logs))
+			}
+			for _, log := range logs {
+				if log.Message == "0" || log.Message == "1" {
+					t.Errorf("Expected the oldest logs deleted first, %s is left", log.Message)
+				}
+			}
+			if deleted, _ := s.DeleteWorkflowLogs(scheduled.ID, 10); deleted != 3 {
+				t.Errorf("Expected the last 3 deleted, got %d", deleted)
+			}
+			if logs, _ := s.GetLogsByWorkflowID(paused.ID); len(logs) != 1 {
+				t.Errorf("Expected the other workflow's log kept, got %d", len(logs))
+			}
+
+			// Refresh tokens rotate once, end with the password and are pruned when closed
+			s.CreateRefreshToken(&models.RefreshToken{UserID: user.ID, TokenHash: "first", ExpiresAt: now.Add(time.Hour)})
+			if rotated, err := s.RotateRefreshToken("first", &models.RefreshToken{TokenHash: "second", ExpiresAt: now.Add(time.Hour)}, now); err != nil || rotated.UserID != user.ID {
+				t.Errorf("Expected the token rotated, got %+v, %v", rotated, err)
+			}
+			if _, err := s.RotateRefreshToken("first", &models.RefreshToken{TokenHash: "third", ExpiresAt: now.Add(time.Hour)}, now); !errors.Is(err, db.ErrRefreshTokenClosed) {
+				t.Errorf("Expected a used token closed, got %v", err)
+			}
+			if _, err := s.RotateRefreshToken("unknown", &models.RefreshToken{TokenHash: "fourth"}, now); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("Expected an unknown token not found, got %v", err)
+			}
+			s.UpdateUserPassword(user.ID, "rehashed")
+			if _, err := s.RotateRefreshToken("second", &models.RefreshToken{TokenHash: "fifth", ExpiresAt: now.Add(time.Hour)}, now); !errors.Is(err, db.ErrRefreshTokenClosed) {
+				t.Errorf("Expected a token from before the password change closed, got %v", err)
+			}
+			if revoked, err := s.RevokeRefreshToken("second", true, now); err != nil || revoked.RevokedAt == nil {
+				t.Errorf("Expected the token revoked, got %+v, %v", revoked, err)
+			}
+			if pruned, err := s.PruneRefreshTokens(now.Add(time.Second)); err != nil || pruned != 2 {
+				t.Errorf("Expected both closed tokens pruned, got %d, %v", pruned, err)
+			}
+
+			var exported int
+			err = s.EachExecution(user.ID, now.Add(-time.Hour), func(*models.Execution) error { exported++; return nil })
+			if err != nil || exported != 2 {
+				t.Errorf("Expected 2 exported executions, got %d, %v", exported, err)
+			}
+		})
+	}
+}
+
+// TestRebind covers the placeholder rewriting used for Postgres
+func TestRebind(t *testing.T) {
+	cases := map[string]string{
+		`SELECT 1`: `SELECT 1`,
+		`SELECT * FROM t WHERE a = ? AND b IN (?, ?)`: `SELECT * FROM t WHERE a = $1 AND b IN ($2, $3)`,
+		`SELECT '?', "col?" FROM t WHERE a = ?`:       `SELECT '?', "col?" FROM t WHERE a = $1`,
+		`SELECT 'it''s ?' WHERE a = ?`:                `SELECT 'it''s ?' WHERE a = $1`,
+		"SELECT a -- why?\nFROM t WHERE a = ?":        "SELECT a -- why?\nFROM t WHERE a = $1",
+		`UPDATE t SET a = a - ? WHERE b = ?`:          `UPDATE t SET a = a - $1 WHERE b = $2`,
+	}
+	for query, want := range cases {
+		if got := db.Rebind(query); got != want {
+			t.Errorf("Rebind(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
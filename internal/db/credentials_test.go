@@ -0,0 +1,285 @@
+package db_test
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/crypto"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+)
+
+// openCredentialDatabase opens a test database plus a raw connection for tampering with rows
+func openCredentialDatabase(t *testing.T) (*db.Database, *sql.DB) {
+	t.Helper()
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	database := dbtest.Open(t, path)
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open raw connection: %v", err)
+	}
+	t.Cleanup(func() { raw.Close() })
+	return database, raw
+}
+
+// TestCredentialCiphertextBoundToRow proves a ciphertext moved to another row fails to decrypt
+func TestCredentialCiphertextBoundToRow(t *testing.T) {
+	database, raw := openCredentialDatabase(t)
+
+	victim, _ := database.CreateUser("victim@example.com", "hashed")
+	attacker, _ := database.CreateUser("attacker@example.com", "hashed")
+	victimSlack, err := database.CreateCredential(victim.ID, "slack", "https://hooks.slack.com/services/VICTIM")
+	if err != nil {
+		t.Fatalf("CreateCredential failed: %v", err)
+	}
+	database.CreateCredential(victim.ID, "discord", "https://discord.com/api/webhooks/VICTIM")
+	database.CreateCredential(attacker.ID, "slack", "https://hooks.slack.com/services/ATTACKER")
+
+	if !crypto.IsBound(victimSlack.EncryptedKey) {
+		t.Fatalf("Expected new credentials to be bound, got %q", victimSlack.EncryptedKey)
+	}
+	cred, err := database.GetCredentialByUserAndService(victim.ID, "slack")
+	if err != nil || cred.DecryptedKey != "https://hooks.slack.com/services/VICTIM" {
+		t.Fatalf("Expected the owner to decrypt, got %v (%v)", cred, err)
+	}
+
+	tests := []struct {
+		name    string
+		userID  string
+		service string
+	}{
+		{"another user's row", attacker.ID, "slack"},
+		{"another service's row", victim.ID, "discord"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := raw.Exec(`UPDATE credentials SET encrypted_key = ? WHERE user_id = ? AND service_name = ?`,
+				victimSlack.EncryptedKey, tt.userID, tt.service); err != nil {
+				t.Fatalf("Failed to tamper with row: %v", err)
+			}
+			cred, err := database.GetCredentialByUserAndService(tt.userID, tt.service)
+			if !errors.Is(err, crypto.ErrContextMismatch) {
+				t.Fatalf("Expected ErrContextMismatch, got %v (%+v)", err, cred)
+			}
+		})
+	}
+}
+
+// TestReencryptLegacyCredentials upgrades unbound ciphertexts in place
+func TestReencryptLegacyCredentials(t *testing.T) {
+	database, raw := openCredentialDatabase(t)
+
+	user, _ := database.CreateUser("legacy@example.com", "hashed")
+	legacy, err := crypto.Encrypt("legacy-api-key")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO credentials (id, user_id, service_name, encrypted_key) VALUES ('cred_legacy', ?, 'newsapi', ?)`, user.ID, legacy); err != nil {
+		t.Fatalf("Failed to insert legacy row: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO credentials (id, user_id, service_name, encrypted_key) VALUES ('cred_corrupt', ?, 'catapi', 'not-a-ciphertext')`, user.ID); err != nil {
+		t.Fatalf("Failed to insert corrupt row: %v", err)
+	}
+
+	// Legacy rows keep working until they are re-encrypted
+	if cred, err := database.GetCredentialByUserAndService(user.ID, "newsapi"); err != nil || cred.DecryptedKey != "legacy-api-key" {
+		t.Fatalf("Expected legacy row to decrypt, got %v (%v)", cred, err)
+	}
+
+	reencrypted, skipped, err := database.ReencryptCredentials()
+	if err != nil || reencrypted != 1 || skipped != 1 {
+		t.Fatalf("Expected 1 re-encrypted and 1 skipped, got %d/%d (%v)", reencrypted, skipped, err)
+	}
+
+	var stored string
+	raw.QueryRow(`SELECT encrypted_key FROM credentials WHERE id = 'cred_legacy'`).Scan(&stored)
+	if !crypto.IsBound(stored) {
+		t.Errorf("Expected the legacy row to be bound, got %q", stored)
+	}
+	if cred, err := database.GetCredentialByUserAndService(user.ID, "newsapi"); err != nil || cred.DecryptedKey != "legacy-api-key" {
+		t.Errorf("Expected re-encrypted row to decrypt, got %v (%v)", cred, err)
+	}
+
+	// Idempotent: nothing left to upgrade except the row that can't be decrypted
+	if reencrypted, skipped, err := database.ReencryptCredentials(); err != nil || reencrypted != 0 || skipped != 1 {
+		t.Errorf("Expected a second pass to do nothing, got %d/%d (%v)", reencrypted, skipped, err)
+	}
+}
+
+// TestCredentialTenantIsolation proves one tenant's ciphertext can't be read as another's
+func TestCredentialTenantIsolation(t *testing.T) {
+	database, raw := openCredentialDatabase(t)
+
+	victim, _ := database.CreateUser("victim@example.com", "hashed")
+	attacker, _ := database.CreateUser("attacker@example.com", "hashed")
+	victimSlack, _ := database.CreateCredential(victim.ID, "slack", "https://hooks.slack.com/services/VICTIM")
+	database.CreateCredential(attacker.ID, "slack", "https://hooks.slack.com/services/ATTACKER")
+
+	if victimSlack.TenantID != "tenant_"+victim.ID || victimSlack.KeyVersion != 1 {
+		t.Fatalf("Expected the default tenant at version 1, got %q/%d", victimSlack.TenantID, victimSlack.KeyVersion)
+	}
+	if _, err := database.GetCredentialForTenant("tenant_"+attacker.ID, victim.ID, "slack"); !errors.Is(err, crypto.ErrTenantMismatch) {
+		t.Errorf("Expected ErrTenantMismatch for another tenant, got %v", err)
+	}
+
+	// A ciphertext copied into the attacker's row can't be opened with the attacker's tenant key
+	if _, err := raw.Exec(`UPDATE credentials SET encrypted_key = ? WHERE user_id = ?`, victimSlack.EncryptedKey, attacker.ID); err != nil {
+		t.Fatalf("Failed to tamper with row: %v", err)
+	}
+	if _, err := database.GetCredentialByUserAndService(attacker.ID, "slack"); !errors.Is(err, crypto.ErrContextMismatch) {
+		t.Errorf("Expected ErrContextMismatch for a copied ciphertext, got %v", err)
+	}
+
+	// Relabelling the victim's row with the attacker's tenant derives the wrong key
+	if _, err := raw.Exec(`UPDATE credentials SET tenant_id = ? WHERE id = ?`, "tenant_"+attacker.ID, victimSlack.ID); err != nil {
+		t.Fatalf("Failed to tamper with row: %v", err)
+	}
+	if _, err := database.GetCredentialForTenant("tenant_"+attacker.ID, victim.ID, "slack"); !errors.Is(err, crypto.ErrContextMismatch) {
+		t.Errorf("Expected ErrContextMismatch for a relabelled row, got %v", err)
+	}
+}
+
+// TestRotateTenantKey re-wraps only the rotated tenant's credentials
+func TestRotateTenantKey(t *testing.T) {
+	database, raw := openCredentialDatabase(t)
+
+	alice, _ := database.CreateUser("alice@example.com", "hashed")
+	bob, _ := database.CreateUser("bob@example.com", "hashed")
+	aliceSlack, _ := database.CreateCredential(alice.ID, "slack", "alice-webhook")
+	database.CreateCredential(alice.ID, "discord", "alice-discord")
+	database.CreateCredential(bob.ID, "slack", "bob-webhook")
+
+	version, reencrypted, skipped, err := database.RotateTenantKey("tenant_" + alice.ID)
+	if err != nil || version != 2 || reencrypted != 2 || skipped != 0 {
+		t.Fatalf("Expected version 2 with 2 re-encrypted, got %d, %d/%d (%v)", version, reencrypted, skipped, err)
+	}
+
+	var stored string
+	raw.QueryRow(`SELECT encrypted_key FROM credentials WHERE id = ?`, aliceSlack.ID).Scan(&stored)
+	if stored == aliceSlack.EncryptedKey {
+		t.Error("Expected the rotated row to have a new ciphertext")
+	}
+	for _, tt := range []struct {
+		userID, service, want string
+		version               int
+	}{
+		{alice.ID, "slack", "alice-webhook", 2},
+		{alice.ID, "discord", "alice-discord", 2},
+		{bob.ID, "slack", "bob-webhook", 1},
+	} {
+		cred, err := database.GetCredentialByUserAndService(tt.userID, tt.service)
+		if err != nil || cred.DecryptedKey != tt.want || cred.KeyVersion != tt.version {
+			t.Errorf("Expected %s at version %d, got %+v (%v)", tt.want, tt.version, cred, err)
+		}
+	}
+
+	// New credentials use the tenant's current version
+	if cred, err := database.CreateCredential(alice.ID, "twilio", "alice-twilio"); err != nil || cred.KeyVersion != 2 {
+		t.Errorf("Expected a new credential at version 2, got %+v (%v)", cred, err)
+	}
+}
+
+// TestMasterKeyRotation re-wraps everything under a new master key
+func TestMasterKeyRotation(t *testing.T) {
+	database, _ := openCredentialDatabase(t)
+
+	user, _ := database.CreateUser("rotate@example.com", "hashed")
+	database.CreateCredential(user.ID, "slack", "https://hooks.slack.com/services/ROTATE")
+
+	t.Setenv("ENCRYPTION_KEY_PREVIOUS", os.Getenv("ENCRYPTION_KEY"))
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+
+	// Rows sealed under the previous key stay readable during the rotation
+	if cred, err := database.GetCredentialByUserAndService(user.ID, "slack"); err != nil || cred.DecryptedKey != "https://hooks.slack.com/services/ROTATE" {
+		t.Fatalf("Expected the old row to decrypt with the previous key, got %v (%v)", cred, err)
+	}
+	if reencrypted, skipped, err := database.ReencryptCredentials(); err != nil || reencrypted != 1 || skipped != 0 {
+		t.Fatalf("Expected 1 re-encrypted, got %d/%d (%v)", reencrypted, skipped, err)
+	}
+
+	// Once everything is re-wrapped the previous key can be retired
+	t.Setenv("ENCRYPTION_KEY_PREVIOUS", "")
+	if cred, err := database.GetCredentialByUserAndService(user.ID, "slack"); err != nil || cred.DecryptedKey != "https://hooks.slack.com/services/ROTATE" {
+		t.Errorf("Expected the row to decrypt with only the new key, got %v (%v)", cred, err)
+	}
+}
+
+// TestMasterKeyRotationInBatches rotates several credentials a batch at a time, resumes a
+// partly rotated table and checks only the new key opens them afterwards
+func TestMasterKeyRotationInBatches(t *testing.T) {
+	database, raw := openCredentialDatabase(t)
+
+	alice, _ := database.CreateUser("alice@example.com", "hashed")
+	bob, _ := database.CreateUser("bob@example.com", "hashed")
+	want := map[string]string{}
+	var original []string
+	for _, c := range []struct{ userID, service, key string }{
+		{alice.ID, "slack", "alice-slack"},
+		{alice.ID, "discord", "alice-discord"},
+		{alice.ID, "newsapi", "alice-news"},
+		{bob.ID, "slack", "bob-slack"},
+		{bob.ID, "twilio", "bob-twilio"},
+	} {
+		cred, err := database.CreateCredential(c.userID, c.service, c.key)
+		if err != nil {
+			t.Fatalf("CreateCredential failed: %v", err)
+		}
+		want[cred.ID] = c.key
+		original = append(original, cred.ID+"|"+cred.EncryptedKey)
+	}
+
+	oldKey := os.Getenv("ENCRYPTION_KEY")
+	newKey := base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+	t.Setenv("ENCRYPTION_KEY_PREVIOUS", oldKey)
+	t.Setenv("ENCRYPTION_KEY", newKey)
+
+	var reports []db.ReencryptProgress
+	reencrypted, skipped, err := database.ReencryptCredentialsInBatches(2, func(p db.ReencryptProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil || reencrypted != 5 || skipped != 0 {
+		t.Fatalf("Expected 5 re-encrypted, got %d/%d (%v)", reencrypted, skipped, err)
+	}
+	if len(reports) != 3 || reports[0].Done != 2 || reports[2].Done != 5 || reports[2].Total != 5 || reports[2].Reencrypted != 5 {
+		t.Errorf("Expected progress after each of 3 batches, got %+v", reports)
+	}
+
+	// A rotation interrupted halfway leaves rows on the old key; running it again finishes them
+	id, stale, _ := strings.Cut(original[3], "|")
+	if _, err := raw.Exec(`UPDATE credentials SET encrypted_key = ? WHERE id = ?`, stale, id); err != nil {
+		t.Fatalf("Failed to restore an old-key row: %v", err)
+	}
+	if reencrypted, _, err := database.ReencryptCredentialsInBatches(2, nil); err != nil || reencrypted != 1 {
+		t.Errorf("Expected the resumed rotation to re-encrypt 1 row, got %d (%v)", reencrypted, err)
+	}
+
+	readAll := func() (opened int) {
+		for _, userID := range []string{alice.ID, bob.ID} {
+			credentials, _ := database.GetCredentialsByUserID(userID)
+			for _, c := range credentials {
+				cred, err := database.GetCredentialByUserAndService(userID, c.ServiceName)
+				if err == nil && cred.DecryptedKey == want[cred.ID] {
+					opened++
+				}
+			}
+		}
+		return opened
+	}
+
+	// With the previous key retired, everything opens under the new key and nothing under the old
+	t.Setenv("ENCRYPTION_KEY_PREVIOUS", "")
+	if opened := readAll(); opened != 5 {
+		t.Errorf("Expected all 5 credentials to decrypt with the new key, got %d", opened)
+	}
+	t.Setenv("ENCRYPTION_KEY", oldKey)
+	if opened := readAll(); opened != 0 {
+		t.Errorf("Expected no credential to decrypt with the old key, got %d", opened)
+	}
+}
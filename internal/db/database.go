@@ -2,50 +2,127 @@ package db
 
 import (
 	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/alexmacdonald/simple-ipass/internal/anomaly"
 	"github.com/alexmacdonald/simple-ipass/internal/models"
 	"github.com/alexmacdonald/simple-ipass/internal/crypto"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
 	"github.com/google/uuid"
 )
 
 type Database struct {
-	conn *sql.DB
+	conn         *sql.DB
+	dialect      string // dialectSQLite or dialectPostgres
+	writeRetries uint64 // Writes retried after SQLITE_BUSY or a Postgres lock failure (read atomically)
+}
+
+// schemaSQL is the SQLite schema for fresh installs, built into the binary so the server
+// runs from any working directory
+//
+//go:embed schema.sql
+var schemaSQL string
+
+// DefaultBusyTimeout is how long SQLite waits on a lock before returning SQLITE_BUSY
+const DefaultBusyTimeout = 5 * time.Second
+
+// SQLiteOptions tunes how a SQLite database is opened
+type SQLiteOptions struct {
+	BusyTimeout time.Duration // Wait on a lock before SQLITE_BUSY; DefaultBusyTimeout when zero
+	ReadOnly    bool          // Open an existing database for reading only (see NewReadOnly)
 }
 
 // New creates a new database connection and initializes schema
 func New(dbPath string) (*Database, error) {
-	conn, err := sql.Open("sqlite3", dbPath)
+	return OpenSQLite(dbPath, SQLiteOptions{})
+}
+
+// NewReadOnly opens an existing database for reading only, for a read-only (standby) instance
+func NewReadOnly(dbPath string) (*Database, error) {
+	return OpenSQLite(dbPath, SQLiteOptions{ReadOnly: true})
+}
+
+// OpenSQLite opens the SQLite database at dbPath, creating and migrating its schema
+// unless it is opened read-only
+func OpenSQLite(dbPath string, opts SQLiteOptions) (*Database, error) {
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = DefaultBusyTimeout
+	}
+	if opts.ReadOnly {
+		return openReadOnly(dbPath, opts.BusyTimeout)
+	}
+
+	conn, err := sql.Open("sqlite3", sqliteDSN(dbPath, opts.BusyTimeout))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Enable foreign keys
-	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
+	// SQLite allows a single writer at a time; one connection serializes
+	// access inside this process instead of contending on the file lock
+	conn.SetMaxOpenConns(1)
 
-	db := &Database{conn: conn}
+	db := &Database{conn: conn, dialect: dialectSQLite}
 
 	// Initialize schema
 	if err := db.initSchema(); err != nil {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	// Upgrade databases created by older versions
+	if err := db.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
 	return db, nil
 }
 
-// initSchema creates tables from schema.sql
-func (db *Database) initSchema() error {
-	schema, err := os.ReadFile("schema.sql")
+// openReadOnly opens an existing database without creating or migrating the schema:
+// the primary owns it, and any write fails.
+// Readers may use several connections since nothing here contends for the write lock
+func openReadOnly(dbPath string, busyTimeout time.Duration) (*Database, error) {
+	dsn := fmt.Sprintf("file:%s?mode=ro&_query_only=true&_busy_timeout=%d&_foreign_keys=on", dbPath, busyTimeout.Milliseconds())
+	conn, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		return fmt.Errorf("failed to read schema.sql: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if _, err := db.conn.Exec(string(schema)); err != nil {
+	// Fail at startup, not on the first dashboard request, when the primary hasn't created the schema
+	var tables int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'workflows'`).Scan(&tables); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open database read-only: %w", err)
+	}
+	if tables == 0 {
+		conn.Close()
+		return nil, fmt.Errorf("database %s has no schema yet; start the primary first", dbPath)
+	}
+
+	return &Database{conn: conn, dialect: dialectSQLite}, nil
+}
+
+// sqliteDSN appends connection pragmas to the database path
+// WAL lets other handles on the file (a read-only standby instance, another
+// process) read while this one writes; within one handle the single connection still
+// serializes reads behind writes. busy_timeout makes SQLite wait for a lock instead of
+// failing immediately with "database is locked", and foreign keys are enabled on every
+// connection the pool opens
+func sqliteDSN(dbPath string, busyTimeout time.Duration) string {
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_journal_mode=WAL&_busy_timeout=%d&_foreign_keys=on", dbPath, separator, busyTimeout.Milliseconds())
+}
+
+// initSchema creates tables from the embedded schema.sql
+func (db *Database) initSchema() error {
+	if _, err := db.conn.Exec(schemaSQL); err != nil {
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
 
@@ -57,25 +134,116 @@ func (db *Database) Close() error {
 	return db.conn.Close()
 }
 
+// BackupTo writes a consistent copy of the live database to path (which must not exist)
+// VACUUM INTO reads inside one transaction, so writers are never blocked for long and
+// the copy never contains half of a write
+// Postgres databases are backed up with the server's own tools (pg_dump, base backups)
+func (db *Database) BackupTo(path string) error {
+	if db.dialect == dialectPostgres {
+		return errors.New("file backups are only supported for SQLite; back up Postgres with pg_dump")
+	}
+	if _, err := db.conn.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
 // Ping verifies the database connection is alive and working
 // Returns an error if the database is not accessible
 func (db *Database) Ping() error {
 	return db.conn.Ping()
 }
 
+// --- Tenant Repository ---
+
+// CreateTenant creates a tenant other users can then be created in
+// Returns a conflict error when the ID is taken
+func (db *Database) CreateTenant(id, name string) (*models.Tenant, error) {
+	tenant := &models.Tenant{
+		ID:        id,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+
+	query := `INSERT INTO tenants (id, name, created_at) VALUES (?, ?, ?)`
+	if _, err := db.execWrite(query, tenant.ID, tenant.Name, tenant.CreatedAt); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// GetTenantByID retrieves a tenant by ID
+func (db *Database) GetTenantByID(id string) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	query := `SELECT id, name, created_at FROM tenants WHERE id = ?`
+	if err := db.conn.QueryRow(query, id).Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt); err != nil {
+		return nil, classify(err)
+	}
+	return tenant, nil
+}
+
+// ListTenantUserIDs lists the IDs of a tenant's users, oldest first
+func (db *Database) ListTenantUserIDs(tenantID string) ([]string, error) {
+	rows, err := db.conn.Query(`SELECT id FROM users WHERE tenant_id = ? ORDER BY created_at, id`, tenantID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// userTenantID returns the tenant a user belongs to
+// Falls back to the user's default tenant for IDs with no user row, as data written
+// before the tenants table was
+func (db *Database) userTenantID(userID string) (string, error) {
+	var tenantID sql.NullString
+	err := db.conn.QueryRow(`SELECT tenant_id FROM users WHERE id = ?`, userID).Scan(&tenantID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", classify(err)
+	}
+	if !tenantID.Valid || tenantID.String == "" {
+		return models.DefaultTenantID(userID), nil
+	}
+	return tenantID.String, nil
+}
+
 // --- User Repository ---
 
-// CreateUser creates a new user
+const userColumns = `id, email, password_hash, created_at, COALESCE(tenant_id, ''), COALESCE(role, 'member'), token_version`
+
+func scanUser(row interface{ Scan(...interface{}) error }, user *models.User) error {
+	return row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.TenantID, &user.Role, &user.TokenVersion)
+}
+
+// CreateUser creates a new user as the admin of a tenant of their own, named after the email
 func (db *Database) CreateUser(email, passwordHash string) (*models.User, error) {
 	user := &models.User{
 		ID:           uuid.New().String(),
 		Email:        email,
 		PasswordHash: passwordHash,
 		CreatedAt:    time.Now(),
+		Role:         models.RoleAdmin,
 	}
+	user.TenantID = models.DefaultTenantID(user.ID)
 
-	query := `INSERT INTO users (id, email, password_hash, created_at) VALUES (?, ?, ?, ?)`
-	_, err := db.conn.Exec(query, user.ID, user.Email, user.PasswordHash, user.CreatedAt)
+	err := db.writeTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO tenants (id, name, created_at) VALUES (?, ?, ?)`,
+			user.TenantID, email, user.CreatedAt); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO users (id, email, password_hash, created_at, tenant_id, role) VALUES (?, ?, ?, ?, ?, ?)`,
+			user.ID, user.Email, user.PasswordHash, user.CreatedAt, user.TenantID, user.Role)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -83,13 +251,35 @@ func (db *Database) CreateUser(email, passwordHash string) (*models.User, error)
 	return user, nil
 }
 
+// CreateUserInTenant creates a new member of an existing tenant, sharing its workflows
+// and credentials; returns a not-found error when the tenant doesn't exist
+func (db *Database) CreateUserInTenant(tenantID, email, passwordHash string) (*models.User, error) {
+	if _, err := db.GetTenantByID(tenantID); err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        email,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now(),
+		TenantID:     tenantID,
+		Role:         models.RoleMember,
+	}
+
+	query := `INSERT INTO users (id, email, password_hash, created_at, tenant_id, role) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.execWrite(query, user.ID, user.Email, user.PasswordHash, user.CreatedAt, user.TenantID, user.Role); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 // GetUserByEmail retrieves a user by email
 func (db *Database) GetUserByEmail(email string) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, email, password_hash, created_at FROM users WHERE email = ?`
-	err := db.conn.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
-	if err != nil {
-		return nil, err
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ?`
+	if err := scanUser(db.conn.QueryRow(query, email), user); err != nil {
+		return nil, classify(err)
 	}
 	return user, nil
 }
@@ -97,20 +287,112 @@ func (db *Database) GetUserByEmail(email string) (*models.User, error) {
 // GetUserByID retrieves a user by ID
 func (db *Database) GetUserByID(id string) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, email, password_hash, created_at FROM users WHERE id = ?`
-	err := db.conn.QueryRow(query, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+	if err := scanUser(db.conn.QueryRow(query, id), user); err != nil {
+		return nil, classify(err)
+	}
+	return user, nil
+}
+
+// UpdateUserPassword sets a user's password hash and bumps their token version,
+// so every token issued before the change is refused from then on
+func (db *Database) UpdateUserPassword(userID, passwordHash string) (*models.User, error) {
+	result, err := db.execWrite(`UPDATE users SET password_hash = ?, token_version = token_version + 1 WHERE id = ?`, passwordHash, userID)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetUserByID(userID)
 }
 
 // --- Credentials Repository ---
-// TODO: MULTI-TENANT - Change user_id filter to tenant_id
 
-// CreateCredential creates a new credential
+// credentialAAD binds a credential ciphertext to the row that owns it, so a value
+// copied into another user's or service's row fails to decrypt
+func credentialAAD(userID, serviceName string) []byte {
+	return []byte("credential\x00" + userID + "\x00" + serviceName)
+}
+
+// credentialColumns are the columns scanned by scanCredential
+const credentialColumns = `id, user_id, tenant_id, service_name, encrypted_key, key_version, created_at, environment`
+
+// scanCredential scans a row selected with credentialColumns
+// Rows from before tenant keys belong to their owner's default tenant
+func scanCredential(row interface{ Scan(...interface{}) error }, cred *models.Credential) error {
+	var tenantID sql.NullString
+	if err := row.Scan(&cred.ID, &cred.UserID, &tenantID, &cred.ServiceName, &cred.EncryptedKey, &cred.KeyVersion, &cred.CreatedAt, &cred.Environment); err != nil {
+		return classify(err)
+	}
+	cred.TenantID = tenantID.String
+	if cred.TenantID == "" {
+		cred.TenantID = models.DefaultTenantID(cred.UserID)
+	}
+	return nil
+}
+
+// tenantKeyVersion returns the current version of a tenant's data key
+func (db *Database) tenantKeyVersion(tenantID string) (int, error) {
+	var version int
+	err := db.conn.QueryRow(`SELECT version FROM tenant_keys WHERE tenant_id = ?`, tenantID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 1, nil
+	}
+	return version, classify(err)
+}
+
+// CreateCredential creates a new live credential, encrypted with its tenant's data key
 func (db *Database) CreateCredential(userID, serviceName, apiKey string) (*models.Credential, error) {
-	encryptedKey, err := crypto.Encrypt(apiKey)
+	return db.CreateCredentialInEnvironment(userID, serviceName, apiKey, models.EnvironmentLive)
+}
+
+// CreateCredentialInEnvironment creates a new credential for the live or test environment
+func (db *Database) CreateCredentialInEnvironment(userID, serviceName, apiKey, environment string) (*models.Credential, error) {
+	tenantID, err := db.userTenantID(userID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := db.tenantKeyVersion(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant key version: %w", err)
+	}
+	encryptedKey, err := crypto.EncryptForTenant(apiKey, tenantID, version, credentialAAD(userID, serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+
+	cred := &models.Credential{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		ServiceName:  serviceName,
+		EncryptedKey: encryptedKey,
+		CreatedAt:    time.Now(),
+		TenantID:     tenantID,
+		KeyVersion:   version,
+		Environment:  environment,
+	}
+
+	query := `INSERT INTO credentials (id, user_id, tenant_id, service_name, encrypted_key, key_version, created_at, environment) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = db.execWrite(query, cred.ID, cred.UserID, cred.TenantID, cred.ServiceName, cred.EncryptedKey, cred.KeyVersion, cred.CreatedAt, cred.Environment)
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	return cred, nil
+}
+
+// ReplaceCredential stores a credential in place of any the user already has for the service
+func (db *Database) ReplaceCredential(userID, serviceName, apiKey string) (*models.Credential, error) {
+	tenantID, err := db.userTenantID(userID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := db.tenantKeyVersion(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant key version: %w", err)
+	}
+	encryptedKey, err := crypto.EncryptForTenant(apiKey, tenantID, version, credentialAAD(userID, serviceName))
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt key: %w", err)
 	}
@@ -121,10 +403,19 @@ func (db *Database) CreateCredential(userID, serviceName, apiKey string) (*model
 		ServiceName:  serviceName,
 		EncryptedKey: encryptedKey,
 		CreatedAt:    time.Now(),
+		TenantID:     tenantID,
+		KeyVersion:   version,
+		Environment:  models.EnvironmentLive,
 	}
 
-	query := `INSERT INTO credentials (id, user_id, service_name, encrypted_key, created_at) VALUES (?, ?, ?, ?, ?)`
-	_, err = db.conn.Exec(query, cred.ID, cred.UserID, cred.ServiceName, cred.EncryptedKey, cred.CreatedAt)
+	err = db.writeTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM credentials WHERE user_id = ? AND service_name = ?`, userID, serviceName); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO credentials (id, user_id, tenant_id, service_name, encrypted_key, key_version, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			cred.ID, cred.UserID, cred.TenantID, cred.ServiceName, cred.EncryptedKey, cred.KeyVersion, cred.CreatedAt)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -132,21 +423,84 @@ func (db *Database) CreateCredential(userID, serviceName, apiKey string) (*model
 	return cred, nil
 }
 
+// UpdateCredential re-encrypts a credential's value in place (e.g. a refreshed OAuth token)
+// The row keeps its ID, environment and creation time; it is sealed with its tenant's current key
+func (db *Database) UpdateCredential(credentialID, apiKey string) error {
+	return db.writeTx(func(tx *sql.Tx) error {
+		var userID, serviceName string
+		var tenantID sql.NullString
+		err := tx.QueryRow(`SELECT user_id, tenant_id, service_name FROM credentials WHERE id = ?`, credentialID).Scan(&userID, &tenantID, &serviceName)
+		if err != nil {
+			return classify(err)
+		}
+		tenant := tenantID.String
+		if tenant == "" {
+			tenant = models.DefaultTenantID(userID)
+		}
+
+		version := 1
+		err = tx.QueryRow(`SELECT version FROM tenant_keys WHERE tenant_id = ?`, tenant).Scan(&version)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return classify(err)
+		}
+		encryptedKey, err := crypto.EncryptForTenant(apiKey, tenant, version, credentialAAD(userID, serviceName))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt key: %w", err)
+		}
+
+		_, err = tx.Exec(`UPDATE credentials SET encrypted_key = ?, key_version = ?, tenant_id = ? WHERE id = ?`,
+			encryptedKey, version, tenant, credentialID)
+		return classify(err)
+	})
+}
+
+// DeleteCredential removes one of a tenant's credentials
+func (db *Database) DeleteCredential(tenantID, credentialID string) error {
+	result, err := db.execWrite(`DELETE FROM credentials WHERE id = ? AND tenant_id = ?`, credentialID, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // GetCredentialsByUserID retrieves all credentials for a user
 func (db *Database) GetCredentialsByUserID(userID string) ([]models.Credential, error) {
-	query := `SELECT id, user_id, service_name, encrypted_key, created_at FROM credentials WHERE user_id = ?`
+	query := `SELECT ` + credentialColumns + ` FROM credentials WHERE user_id = ?`
 	rows, err := db.conn.Query(query, userID)
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 	defer rows.Close()
 
 	var credentials []models.Credential
 	for rows.Next() {
 		var cred models.Credential
-		err := rows.Scan(&cred.ID, &cred.UserID, &cred.ServiceName, &cred.EncryptedKey, &cred.CreatedAt)
-		if err != nil {
-			return nil, err
+		if err := scanCredential(rows, &cred); err != nil {
+			return nil, classify(err)
+		}
+		credentials = append(credentials, cred)
+	}
+
+	return credentials, nil
+}
+
+// GetCredentialsByTenantID retrieves the credentials of every user in a tenant
+func (db *Database) GetCredentialsByTenantID(tenantID string) ([]models.Credential, error) {
+	query := `SELECT ` + credentialColumns + ` FROM credentials WHERE tenant_id = ? ORDER BY created_at, id`
+	rows, err := db.conn.Query(query, tenantID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var credentials []models.Credential
+	for rows.Next() {
+		var cred models.Credential
+		if err := scanCredential(rows, &cred); err != nil {
+			return nil, classify(err)
 		}
 		credentials = append(credentials, cred)
 	}
@@ -157,14 +511,42 @@ func (db *Database) GetCredentialsByUserID(userID string) ([]models.Credential,
 // GetCredentialByUserAndService retrieves a specific credential
 func (db *Database) GetCredentialByUserAndService(userID, serviceName string) (*models.Credential, error) {
 	cred := &models.Credential{}
-	query := `SELECT id, user_id, service_name, encrypted_key, created_at FROM credentials WHERE user_id = ? AND service_name = ?`
-	err := db.conn.QueryRow(query, userID, serviceName).Scan(&cred.ID, &cred.UserID, &cred.ServiceName, &cred.EncryptedKey, &cred.CreatedAt)
+	query := `SELECT ` + credentialColumns + ` FROM credentials WHERE user_id = ? AND service_name = ?`
+	if err := scanCredential(db.conn.QueryRow(query, userID, serviceName), cred); err != nil {
+		return nil, classify(err)
+	}
+
+	// Decrypt the key (wraps crypto.ErrContextMismatch if it was moved from another row or tenant)
+	decryptedKey, err := crypto.DecryptForTenant(cred.EncryptedKey, cred.TenantID, cred.KeyVersion, credentialAAD(cred.UserID, cred.ServiceName))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decrypt key: %w", err)
+	}
+	cred.DecryptedKey = decryptedKey
+
+	return cred, nil
+}
+
+// GetCredentialForTenant retrieves a credential on behalf of a tenant, preferring the live one
+// Fails closed with crypto.ErrTenantMismatch, without decrypting, when the row belongs to another tenant
+func (db *Database) GetCredentialForTenant(tenantID, userID, serviceName string) (*models.Credential, error) {
+	return db.GetCredentialInEnvironment(tenantID, userID, serviceName, models.EnvironmentLive)
+}
+
+// GetCredentialInEnvironment retrieves a credential on behalf of a tenant, preferring the given
+// environment; when the user only has the other environment's, that one is returned
+// (callers compare cred.Environment to decide whether it may be used)
+func (db *Database) GetCredentialInEnvironment(tenantID, userID, serviceName, environment string) (*models.Credential, error) {
+	cred := &models.Credential{}
+	query := `SELECT ` + credentialColumns + ` FROM credentials WHERE user_id = ? AND service_name = ?
+	          ORDER BY environment = ? DESC, created_at DESC LIMIT 1`
+	if err := scanCredential(db.conn.QueryRow(query, userID, serviceName, environment), cred); err != nil {
+		return nil, classify(err)
+	}
+	if cred.TenantID != tenantID {
+		return nil, fmt.Errorf("credential %s: %w", cred.ID, crypto.ErrTenantMismatch)
 	}
 
-	// Decrypt the key
-	decryptedKey, err := crypto.Decrypt(cred.EncryptedKey)
+	decryptedKey, err := crypto.DecryptForTenant(cred.EncryptedKey, cred.TenantID, cred.KeyVersion, credentialAAD(cred.UserID, cred.ServiceName))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt key: %w", err)
 	}
@@ -173,14 +555,140 @@ func (db *Database) GetCredentialByUserAndService(userID, serviceName string) (*
 	return cred, nil
 }
 
+// ReencryptCredentials re-wraps every credential not yet sealed with its tenant's current
+// data key under the current master key: legacy single-key rows, rows from before a master
+// key rotation (set ENCRYPTION_KEY_PREVIOUS to the old key) and rows from before a tenant
+// key rotation. Run at startup, so an interrupted rotation resumes; rows that fail to
+// decrypt are left untouched and counted as skipped
+func (db *Database) ReencryptCredentials() (reencrypted, skipped int, err error) {
+	return db.reencryptCredentials(defaultReencryptBatch, nil, ``)
+}
+
+// defaultReencryptBatch is how many credentials are re-wrapped per transaction
+const defaultReencryptBatch = 100
+
+// ReencryptProgress reports how far a re-encryption has got after each batch
+type ReencryptProgress struct {
+	Done        int // Stale rows handled so far
+	Total       int // Stale rows found when the pass started
+	Reencrypted int
+	Skipped     int
+}
+
+// ReencryptCredentialsInBatches is ReencryptCredentials committing batchSize rows per
+// transaction and reporting progress after each. An interrupted pass loses at most the
+// batch in flight; running it again picks up the rows still on the old keys
+func (db *Database) ReencryptCredentialsInBatches(batchSize int, progress func(ReencryptProgress)) (reencrypted, skipped int, err error) {
+	return db.reencryptCredentials(batchSize, progress, ``)
+}
+
+// RotateTenantKey moves a tenant to a new data key version and re-wraps its credentials
+func (db *Database) RotateTenantKey(tenantID string) (version, reencrypted, skipped int, err error) {
+	current, err := db.tenantKeyVersion(tenantID)
+	if err != nil {
+		return 0, 0, 0, classify(err)
+	}
+	version = current + 1
+	if _, err := db.execWrite(`INSERT INTO tenant_keys (tenant_id, version, rotated_at) VALUES (?, ?, ?)
+	          ON CONFLICT (tenant_id) DO UPDATE SET version = excluded.version, rotated_at = excluded.rotated_at`,
+		tenantID, version, time.Now()); err != nil {
+		return 0, 0, 0, classify(err)
+	}
+
+	reencrypted, skipped, err = db.reencryptCredentials(defaultReencryptBatch, nil, ` AND COALESCE(c.tenant_id, 'tenant_' || c.user_id) = ?`, tenantID)
+	return version, reencrypted, skipped, classify(err)
+}
+
+// reencryptCredentials re-wraps the stale credentials matching an extra condition on c,
+// batchSize rows per transaction
+func (db *Database) reencryptCredentials(batchSize int, progress func(ReencryptProgress), condition string, args ...interface{}) (reencrypted, skipped int, err error) {
+	if batchSize < 1 {
+		batchSize = defaultReencryptBatch
+	}
+	query := `SELECT c.id, c.user_id, c.tenant_id, c.service_name, c.encrypted_key, c.key_version, c.created_at,
+	                 COALESCE(k.version, 1)
+	          FROM credentials c
+	          LEFT JOIN tenant_keys k ON k.tenant_id = COALESCE(c.tenant_id, 'tenant_' || c.user_id)
+	          WHERE (c.encrypted_key NOT LIKE ? OR c.tenant_id IS NULL OR c.key_version != COALESCE(k.version, 1))` + condition
+	rows, err := db.conn.Query(query, append([]interface{}{crypto.CurrentTenantPrefix() + "%"}, args...)...)
+	if err != nil {
+		return 0, 0, classify(err)
+	}
+
+	// Collect first: the single connection can't serve updates while rows are open
+	type staleCredential struct {
+		models.Credential
+		targetVersion int
+	}
+	var stale []staleCredential
+	for rows.Next() {
+		var cred staleCredential
+		var tenantID sql.NullString
+		if err := rows.Scan(&cred.ID, &cred.UserID, &tenantID, &cred.ServiceName, &cred.EncryptedKey, &cred.KeyVersion, &cred.CreatedAt, &cred.targetVersion); err != nil {
+			rows.Close()
+			return 0, 0, classify(err)
+		}
+		cred.TenantID = tenantID.String
+		if cred.TenantID == "" {
+			cred.TenantID = models.DefaultTenantID(cred.UserID)
+		}
+		stale = append(stale, cred)
+	}
+	rows.Close()
+
+	for start := 0; start < len(stale); start += batchSize {
+		batch := stale[start:min(start+batchSize, len(stale))]
+		var batchReencrypted, batchSkipped int
+		err := db.writeTx(func(tx *sql.Tx) error {
+			batchReencrypted, batchSkipped = 0, 0
+			for _, cred := range batch {
+				aad := credentialAAD(cred.UserID, cred.ServiceName)
+				plaintext, err := crypto.DecryptForTenant(cred.EncryptedKey, cred.TenantID, cred.KeyVersion, aad)
+				if err != nil {
+					batchSkipped++
+					continue
+				}
+				sealed, err := crypto.EncryptForTenant(plaintext, cred.TenantID, cred.targetVersion, aad)
+				if err != nil {
+					return fmt.Errorf("failed to encrypt key: %w", err)
+				}
+				// Compare-and-swap so a concurrent update of the row isn't overwritten
+				result, err := tx.Exec(`UPDATE credentials SET encrypted_key = ?, key_version = ?, tenant_id = ? WHERE id = ? AND encrypted_key = ?`,
+					sealed, cred.targetVersion, cred.TenantID, cred.ID, cred.EncryptedKey)
+				if err != nil {
+					return err
+				}
+				if n, _ := result.RowsAffected(); n == 1 {
+					batchReencrypted++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return reencrypted, skipped, err
+		}
+		reencrypted += batchReencrypted
+		skipped += batchSkipped
+		if progress != nil {
+			progress(ReencryptProgress{Done: start + len(batch), Total: len(stale), Reencrypted: reencrypted, Skipped: skipped})
+		}
+	}
+	return reencrypted, skipped, nil
+}
+
 // --- Workflows Repository ---
-// TODO: MULTI-TENANT - Change user_id filter to tenant_id
+// Workflows belong to their creator's tenant, whose users all see them
 
 // CreateWorkflow creates a new workflow with optional action chain
 func (db *Database) CreateWorkflow(userID, name, triggerType, actionType, configJSON string) (*models.Workflow, error) {
+	tenantID, err := db.userTenantID(userID)
+	if err != nil {
+		return nil, err
+	}
 	workflow := &models.Workflow{
 		ID:          uuid.New().String(),
 		UserID:      userID,
+		TenantID:    tenantID,
 		Name:        name,
 		TriggerType: triggerType,
 		ActionType:  actionType,
@@ -189,11 +697,12 @@ func (db *Database) CreateWorkflow(userID, name, triggerType, actionType, config
 		IsActive:    true,
 		CreatedAt:   time.Now(),
 	}
+	workflow.NextRunAt = workflow.NextRunAfter(nil)
 
-	query := `INSERT INTO workflows (id, user_id, name, trigger_type, action_type, config_json, action_chain, is_active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := db.conn.Exec(query, workflow.ID, workflow.UserID, workflow.Name, workflow.TriggerType, workflow.ActionType, workflow.ConfigJSON, workflow.ActionChain, workflow.IsActive, workflow.CreatedAt)
+	query := `INSERT INTO workflows (id, user_id, tenant_id, name, trigger_type, action_type, config_json, action_chain, is_active, next_run_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = db.execWrite(query, workflow.ID, workflow.UserID, workflow.TenantID, workflow.Name, workflow.TriggerType, workflow.ActionType, workflow.ConfigJSON, workflow.ActionChain, workflow.IsActive, workflow.NextRunAt, workflow.CreatedAt)
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 
 	return workflow, nil
@@ -206,9 +715,14 @@ func (db *Database) CreateWorkflowWithChain(userID, name, triggerType, actionTyp
 
 // CreateWorkflowComplete creates a new workflow with all fields including parameters
 func (db *Database) CreateWorkflowComplete(userID, name, triggerType, actionType, configJSON, actionChain, parameters string) (*models.Workflow, error) {
+	tenantID, err := db.userTenantID(userID)
+	if err != nil {
+		return nil, err
+	}
 	workflow := &models.Workflow{
 		ID:          uuid.New().String(),
 		UserID:      userID,
+		TenantID:    tenantID,
 		Name:        name,
 		TriggerType: triggerType,
 		ActionType:  actionType,
@@ -218,189 +732,605 @@ func (db *Database) CreateWorkflowComplete(userID, name, triggerType, actionType
 		IsActive:    true,
 		CreatedAt:   time.Now(),
 	}
+	workflow.NextRunAt = workflow.NextRunAfter(nil)
 
-	query := `INSERT INTO workflows (id, user_id, name, trigger_type, action_type, config_json, action_chain, parameters, is_active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := db.conn.Exec(query, workflow.ID, workflow.UserID, workflow.Name, workflow.TriggerType, workflow.ActionType, workflow.ConfigJSON, workflow.ActionChain, workflow.Parameters, workflow.IsActive, workflow.CreatedAt)
+	query := `INSERT INTO workflows (id, user_id, tenant_id, name, trigger_type, action_type, config_json, action_chain, parameters, is_active, next_run_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = db.execWrite(query, workflow.ID, workflow.UserID, workflow.TenantID, workflow.Name, workflow.TriggerType, workflow.ActionType, workflow.ConfigJSON, workflow.ActionChain, workflow.Parameters, workflow.IsActive, workflow.NextRunAt, workflow.CreatedAt)
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 
 	return workflow, nil
 }
 
-// GetWorkflowsByUserID retrieves all workflows for a user
-func (db *Database) GetWorkflowsByUserID(userID string) ([]models.Workflow, error) {
-	query := `SELECT id, user_id, name, trigger_type, action_type, config_json, action_chain, parameters, is_active, last_executed_at, created_at FROM workflows WHERE user_id = ? ORDER BY created_at DESC`
-	rows, err := db.conn.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// workflowColumns are the columns scanned by scanWorkflow, selected FROM workflowTables
+// Rolling success counts come from the hourly buckets; the 24h window is hour-granular.
+// The two window starts are bound first (see workflowWindowArgs)
+const workflowColumns = `w.id, w.user_id, w.tenant_id, w.name, w.trigger_type, w.action_type, w.config_json, w.action_chain, w.parameters, w.is_active, w.last_executed_at, w.next_run_at, w.created_at,
+	w.debug_requests_until, w.debug_requests_limit, w.paused_until,
+	s.total_executions, s.consecutive_failures, s.timed_executions, s.total_duration_ms, s.last_error, s.suppressed_executions,
+	(SELECT COALESCE(SUM(b.successes), 0) FROM workflow_stat_buckets b WHERE b.workflow_id = w.id AND b.hour >= ?),
+	(SELECT COALESCE(SUM(b.successes), 0) FROM workflow_stat_buckets b WHERE b.workflow_id = w.id AND b.hour >= ?)`
 
-	var workflows []models.Workflow
-	for rows.Next() {
-		var w models.Workflow
-		var lastExecutedAt sql.NullTime
-		var actionChain sql.NullString
-		var parameters sql.NullString
-		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.TriggerType, &w.ActionType, &w.ConfigJSON, &actionChain, &parameters, &w.IsActive, &lastExecutedAt, &w.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		if lastExecutedAt.Valid {
-			w.LastExecutedAt = &lastExecutedAt.Time
-		}
-		if actionChain.Valid {
-			w.ActionChain = actionChain.String
-		}
-		if parameters.Valid {
-			w.Parameters = parameters.String
-		}
-		workflows = append(workflows, w)
-	}
+// workflowWindowArgs returns the bucket keys starting the 24h and 7d success windows,
+// computed here rather than in SQL so both dialects agree with statsHour
+func workflowWindowArgs(args ...interface{}) []interface{} {
+	now := time.Now()
+	return append([]interface{}{statsHour(now.Add(-23 * time.Hour)), statsHour(now.Add(-167 * time.Hour))}, args...)
+}
 
-	return workflows, nil
+// workflowTables joins each workflow with its (optional) stats row
+const workflowTables = `workflows w LEFT JOIN workflow_stats s ON s.workflow_id = w.id`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-// GetWorkflowByID retrieves a workflow by ID
-func (db *Database) GetWorkflowByID(workflowID string) (*models.Workflow, error) {
+// scanWorkflow scans a row selected with workflowColumns
+func scanWorkflow(row rowScanner) (*models.Workflow, error) {
 	w := &models.Workflow{}
-	var lastExecutedAt sql.NullTime
+	var lastExecutedAt, nextRunAt sql.NullTime
 	var actionChain sql.NullString
 	var parameters sql.NullString
-	query := `SELECT id, user_id, name, trigger_type, action_type, config_json, action_chain, parameters, is_active, last_executed_at, created_at FROM workflows WHERE id = ?`
-	err := db.conn.QueryRow(query, workflowID).Scan(&w.ID, &w.UserID, &w.Name, &w.TriggerType, &w.ActionType, &w.ConfigJSON, &actionChain, &parameters, &w.IsActive, &lastExecutedAt, &w.CreatedAt)
+	var totalExecutions, consecutiveFailures, timedExecutions, totalDurationMS, suppressed sql.NullInt64
+	var lastError sql.NullString
+	var successes24h, successes7d int
+	var debugUntil sql.NullTime
+	var debugLimit sql.NullInt64
+	var pausedUntil sql.NullTime
+	var tenantID sql.NullString
+	err := row.Scan(&w.ID, &w.UserID, &tenantID, &w.Name, &w.TriggerType, &w.ActionType, &w.ConfigJSON, &actionChain, &parameters, &w.IsActive, &lastExecutedAt, &nextRunAt, &w.CreatedAt,
+		&debugUntil, &debugLimit, &pausedUntil,
+		&totalExecutions, &consecutiveFailures, &timedExecutions, &totalDurationMS, &lastError, &suppressed, &successes24h, &successes7d)
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
+	}
+	w.TenantID = tenantID.String
+	if w.TenantID == "" {
+		w.TenantID = models.DefaultTenantID(w.UserID)
 	}
 	if lastExecutedAt.Valid {
 		w.LastExecutedAt = &lastExecutedAt.Time
 	}
+	if nextRunAt.Valid {
+		w.NextRunAt = &nextRunAt.Time
+	}
 	if actionChain.Valid {
 		w.ActionChain = actionChain.String
 	}
 	if parameters.Valid {
 		w.Parameters = parameters.String
 	}
-	return w, nil
-}
-
-// UpdateWorkflowActive toggles workflow active status
-func (db *Database) UpdateWorkflowActive(workflowID string, isActive bool) error {
-	query := `UPDATE workflows SET is_active = ? WHERE id = ?`
-	_, err := db.conn.Exec(query, isActive, workflowID)
-	return err
-}
-
-// UpdateWorkflowLastExecuted updates the last execution time
-func (db *Database) UpdateWorkflowLastExecuted(workflowID string, executedAt time.Time) error {
-	query := `UPDATE workflows SET last_executed_at = ? WHERE id = ?`
-	_, err := db.conn.Exec(query, executedAt, workflowID)
-	return err
-}
+	if debugUntil.Valid {
+		w.DebugRequestsUntil = &debugUntil.Time
+		w.DebugRequestsLimit = int(debugLimit.Int64)
+	}
+	if pausedUntil.Valid {
+		w.PausedUntil = &pausedUntil.Time
+	}
 
-// DeleteWorkflow deletes a workflow
-func (db *Database) DeleteWorkflow(workflowID string) error {
-	query := `DELETE FROM workflows WHERE id = ?`
-	_, err := db.conn.Exec(query, workflowID)
-	return err
+	// Never-executed workflows still get zeroed stats so clients can render them uniformly
+	w.Stats = &models.WorkflowStats{
+		TotalExecutions:     totalExecutions.Int64,
+		ConsecutiveFailures: int(consecutiveFailures.Int64),
+		Successes24h:        successes24h,
+		Successes7d:         successes7d,
+		LastError:           lastError.String,
+		SuppressedExecutions: suppressed.Int64,
+	}
+	if timedExecutions.Int64 > 0 {
+		w.Stats.AvgDurationMS = float64(totalDurationMS.Int64) / float64(timedExecutions.Int64)
+	}
+	return w, nil
 }
 
-// GetActiveScheduledWorkflows retrieves all active scheduled workflows
-func (db *Database) GetActiveScheduledWorkflows() ([]models.Workflow, error) {
-	query := `SELECT id, user_id, name, trigger_type, action_type, config_json, action_chain, parameters, is_active, last_executed_at, created_at 
-	          FROM workflows WHERE trigger_type = 'schedule' AND is_active = 1`
-	rows, err := db.conn.Query(query)
+// queryWorkflows runs a query selecting workflowColumns and scans every row
+func (db *Database) queryWorkflows(query string, args ...interface{}) ([]models.Workflow, error) {
+	rows, err := db.conn.Query(query, workflowWindowArgs(args...)...)
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 	defer rows.Close()
 
 	var workflows []models.Workflow
 	for rows.Next() {
-		var w models.Workflow
-		var lastExecutedAt sql.NullTime
-		var actionChain sql.NullString
-		var parameters sql.NullString
-		err := rows.Scan(&w.ID, &w.UserID, &w.Name, &w.TriggerType, &w.ActionType, &w.ConfigJSON, &actionChain, &parameters, &w.IsActive, &lastExecutedAt, &w.CreatedAt)
+		w, err := scanWorkflow(rows)
 		if err != nil {
-			return nil, err
-		}
-		if lastExecutedAt.Valid {
-			w.LastExecutedAt = &lastExecutedAt.Time
-		}
-		if actionChain.Valid {
-			w.ActionChain = actionChain.String
-		}
-		if parameters.Valid {
-			w.Parameters = parameters.String
+			return nil, classify(err)
 		}
-		workflows = append(workflows, w)
+		workflows = append(workflows, *w)
 	}
 
 	return workflows, nil
 }
 
-// --- Logs Repository ---
-// TODO: MULTI-TENANT - Join with workflows to filter by tenant_id
+// GetWorkflowsByUserID retrieves all workflows for a user
+func (db *Database) GetWorkflowsByUserID(userID string) ([]models.Workflow, error) {
+	query := `SELECT ` + workflowColumns + ` FROM ` + workflowTables + ` WHERE w.user_id = ? ORDER BY w.created_at DESC`
+	return db.queryWorkflows(query, userID)
+}
 
-// CreateLog creates a new execution log
-func (db *Database) CreateLog(workflowID, status, message string) error {
-	log := &models.Log{
-		ID:         uuid.New().String(),
-		WorkflowID: workflowID,
-		Status:     status,
-		Message:    message,
-		ExecutedAt: time.Now(),
+// GetWorkflowsByTenantID retrieves the workflows of every user in a tenant
+func (db *Database) GetWorkflowsByTenantID(tenantID string) ([]models.Workflow, error) {
+	query := `SELECT ` + workflowColumns + ` FROM ` + workflowTables + ` WHERE w.tenant_id = ? ORDER BY w.created_at DESC`
+	return db.queryWorkflows(query, tenantID)
+}
+
+// ListWorkflows retrieves one page of a user's workflows, newest first
+// Keyset pagination on (created_at, id) keeps pages stable while workflows are added;
+// Offset is only honoured when no cursor is given (deprecated)
+func (db *Database) ListWorkflows(userID string, page models.PageRequest) ([]models.Workflow, error) {
+	return db.listWorkflows(`w.user_id = ?`, userID, page)
+}
+
+// ListTenantWorkflows retrieves one page of a tenant's workflows, paged like ListWorkflows
+func (db *Database) ListTenantWorkflows(tenantID string, page models.PageRequest) ([]models.Workflow, error) {
+	return db.listWorkflows(`w.tenant_id = ?`, tenantID, page)
+}
+
+// listWorkflows retrieves one page of the workflows matching owner
+func (db *Database) listWorkflows(owner, ownerID string, page models.PageRequest) ([]models.Workflow, error) {
+	query := `SELECT ` + workflowColumns + ` FROM ` + workflowTables + ` WHERE ` + owner
+	args := []interface{}{ownerID}
+
+	if page.Cursor != nil {
+		query += ` AND (w.created_at, w.id) < (?, ?)`
+		args = append(args, page.Cursor.Time, page.Cursor.ID)
+	}
+	query += ` ORDER BY w.created_at DESC, w.id DESC LIMIT ?`
+	args = append(args, page.Limit)
+	if page.Cursor == nil && page.Offset > 0 {
+		query += ` OFFSET ?`
+		args = append(args, page.Offset)
 	}
 
-	query := `INSERT INTO logs (id, workflow_id, status, message, executed_at) VALUES (?, ?, ?, ?, ?)`
-	_, err := db.conn.Exec(query, log.ID, log.WorkflowID, log.Status, log.Message, log.ExecutedAt)
-	return err
+	return db.queryWorkflows(query, args...)
 }
 
-// GetLogsByUserID retrieves all logs for a user's workflows
-func (db *Database) GetLogsByUserID(userID string) ([]models.WorkflowLog, error) {
-	query := `SELECT l.id, l.workflow_id, l.status, l.message, l.executed_at, w.name 
-	          FROM logs l 
-	          JOIN workflows w ON l.workflow_id = w.id 
-	          WHERE w.user_id = ? 
-	          ORDER BY l.executed_at DESC 
-	          LIMIT 100`
-	rows, err := db.conn.Query(query, userID)
+// GetWorkflowByID retrieves a workflow by ID
+func (db *Database) GetWorkflowByID(workflowID string) (*models.Workflow, error) {
+	query := `SELECT ` + workflowColumns + ` FROM ` + workflowTables + ` WHERE w.id = ?`
+	workflow, err := scanWorkflow(db.conn.QueryRow(query, workflowWindowArgs(workflowID)...))
+	if err != nil {
+		return nil, classify(err)
+	}
+	return workflow, nil
+}
+
+// UpdateWorkflowActive toggles workflow active status
+func (db *Database) UpdateWorkflowActive(workflowID string, isActive bool) error {
+	query := `UPDATE workflows SET is_active = ? WHERE id = ?`
+	result, err := db.execWrite(query, isActive, workflowID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateWorkflow saves a workflow's definition in place
+// The ID, active state, execution history and stats are left alone, so webhook URLs
+// and Kong services pointing at the workflow keep working. The next run is recomputed
+// from the last one, so a changed trigger or interval applies straight away
+func (db *Database) UpdateWorkflow(workflow *models.Workflow) error {
+	rescheduled := *workflow
+	rescheduled.NextRunAt = nil
+	workflow.NextRunAt = rescheduled.NextRunAfter(workflow.LastExecutedAt)
+
+	query := `UPDATE workflows SET name = ?, trigger_type = ?, action_type = ?, config_json = ?, action_chain = ?, next_run_at = ? WHERE id = ?`
+	result, err := db.execWrite(query, workflow.Name, workflow.TriggerType, workflow.ActionType, workflow.ConfigJSON, workflow.ActionChain, workflow.NextRunAt, workflow.ID)
+	if err != nil {
+		return classify(err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateWorkflowLastExecuted updates the last execution time and when the workflow runs next
+// (nil for workflows the scheduler doesn't run)
+func (db *Database) UpdateWorkflowLastExecuted(workflowID string, executedAt time.Time, nextRunAt *time.Time) error {
+	query := `UPDATE workflows SET last_executed_at = ?, next_run_at = ? WHERE id = ?`
+	result, err := db.execWrite(query, executedAt, nextRunAt, workflowID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWorkflow deletes a workflow
+func (db *Database) DeleteWorkflow(workflowID string) error {
+	query := `DELETE FROM workflows WHERE id = ?`
+	result, err := db.execWrite(query, workflowID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetWorkflowsActive enables or disables workflows in one transaction and returns the IDs
+// that no longer exist (the others are still updated)
+func (db *Database) SetWorkflowsActive(workflowIDs []string, isActive bool) ([]string, error) {
+	return db.eachWorkflowTx(workflowIDs, `UPDATE workflows SET is_active = ? WHERE id = ?`, isActive)
+}
+
+// DeleteWorkflows deletes workflows in one transaction and returns the IDs that no longer
+// existed; their logs, stats and other rows go with them (ON DELETE CASCADE)
+func (db *Database) DeleteWorkflows(workflowIDs []string) ([]string, error) {
+	return db.eachWorkflowTx(workflowIDs, `DELETE FROM workflows WHERE id = ?`)
+}
+
+// eachWorkflowTx runs query once per workflow ID (the last argument) in one transaction,
+// and returns the IDs it affected no row for
+func (db *Database) eachWorkflowTx(workflowIDs []string, query string, args ...interface{}) ([]string, error) {
+	var missing []string
+	err := db.writeTx(func(tx *sql.Tx) error {
+		missing = nil
+		for _, workflowID := range workflowIDs {
+			result, err := tx.Exec(query, append(args, workflowID)...)
+			if err != nil {
+				return err
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				missing = append(missing, workflowID)
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return missing, nil
+}
+
+// GetDueWorkflows retrieves the active workflows whose next run is at or before now,
+// most overdue first. Only schedule workflows have a next run (idx_workflows_next_run_at)
+// A workflow paused past now isn't due; once its pause ends it is again, with no update needed
+func (db *Database) GetDueWorkflows(now time.Time) ([]models.Workflow, error) {
+	query := `SELECT ` + workflowColumns + ` FROM ` + workflowTables + ` WHERE w.next_run_at <= ? AND w.is_active
+	          AND (w.paused_until IS NULL OR w.paused_until <= ?) ORDER BY w.next_run_at`
+	return db.queryWorkflows(query, now, now)
+}
+
+// GetActiveWorkflow retrieves a workflow by ID unless it was deleted or disabled
+func (db *Database) GetActiveWorkflow(workflowID string) (*models.Workflow, error) {
+	query := `SELECT ` + workflowColumns + ` FROM ` + workflowTables + ` WHERE w.id = ? AND w.is_active`
+	workflow, err := scanWorkflow(db.conn.QueryRow(query, workflowWindowArgs(workflowID)...))
+	if err != nil {
+		return nil, classify(err)
+	}
+	return workflow, nil
+}
+
+// --- Workflow Stats Repository ---
+
+// maxStatsErrorLength caps the last error shown in list views
+const maxStatsErrorLength = 200
+
+// statsErrorMessage masks secrets in an error message and truncates it for display
+func statsErrorMessage(message string) string {
+	masked := utils.Mask(message)
+	if len(masked) > maxStatsErrorLength {
+		masked = masked[:maxStatsErrorLength-3] + "..."
+	}
+	return masked
+}
+
+// statsHour returns the bucket key for the rolling success windows
+func statsHour(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15")
+}
+
+// RecordWorkflowExecution updates a workflow's execution counters
+// All counter changes happen in SQL (col = col + 1) inside one transaction,
+// so concurrent executions of the same workflow never lose updates
+// Suppressed executions (outbound throttle) only count as such: they neither
+// succeed nor fail, so they leave the other counters alone
+func (db *Database) RecordWorkflowExecution(workflowID, status string, duration time.Duration, errorMessage string, at time.Time) error {
+	if status == models.StatusSuppressed {
+		_, err := db.execWrite(`INSERT INTO workflow_stats (workflow_id, suppressed_executions, updated_at) VALUES (?, 1, ?)
+		          ON CONFLICT(workflow_id) DO UPDATE SET suppressed_executions = workflow_stats.suppressed_executions + 1, updated_at = excluded.updated_at`,
+			workflowID, at)
+		return classify(err)
+	}
+
+	success := status == "success"
+	var lastError interface{}
+	if !success && errorMessage != "" {
+		lastError = statsErrorMessage(errorMessage)
+	}
+	durationMS := duration.Milliseconds()
+
+	return db.writeTx(func(tx *sql.Tx) error {
+		// The duration averages decay in Go (package anomaly), so they are read inside the transaction
+		var durations anomaly.Durations
+		err := tx.QueryRow(`SELECT recent_duration_ms, baseline_duration_ms, timed_executions FROM workflow_stats WHERE workflow_id = ?`, workflowID).
+			Scan(&durations.Recent, &durations.Baseline, &durations.Samples)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return classify(err)
+		}
+		durations = durations.Update(float64(durationMS))
+
+		_, err = tx.Exec(`INSERT INTO workflow_stats (workflow_id, total_executions, consecutive_failures, timed_executions, total_duration_ms, last_error, recent_duration_ms, baseline_duration_ms, updated_at)
+		          VALUES (?, 1, ?, 1, ?, ?, ?, ?, ?)
+		          ON CONFLICT(workflow_id) DO UPDATE SET
+		              total_executions = workflow_stats.total_executions + 1,
+		              consecutive_failures = CASE WHEN ? THEN 0 ELSE workflow_stats.consecutive_failures + 1 END,
+		              timed_executions = workflow_stats.timed_executions + 1,
+		              total_duration_ms = workflow_stats.total_duration_ms + excluded.total_duration_ms,
+		              last_error = COALESCE(excluded.last_error, workflow_stats.last_error),
+		              recent_duration_ms = excluded.recent_duration_ms,
+		              baseline_duration_ms = excluded.baseline_duration_ms,
+		              updated_at = excluded.updated_at`,
+			workflowID, boolToInt(!success), durationMS, lastError, durations.Recent, durations.Baseline, at, success)
+		if err != nil {
+			return classify(err)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO workflow_stat_buckets (workflow_id, hour, successes, failures) VALUES (?, ?, ?, ?)
+		          ON CONFLICT(workflow_id, hour) DO UPDATE SET successes = workflow_stat_buckets.successes + excluded.successes,
+		              failures = workflow_stat_buckets.failures + excluded.failures`,
+			workflowID, statsHour(at), boolToInt(success), boolToInt(!success)); err != nil {
+			return classify(err)
+		}
+
+		// Buckets older than the widest window are never read again
+		_, err = tx.Exec(`DELETE FROM workflow_stat_buckets WHERE workflow_id = ? AND hour < ?`,
+			workflowID, statsHour(at.Add(-7*24*time.Hour)))
+		return classify(err)
+	})
+}
+
+// GetWorkflowBaseline returns a workflow's duration averages and its hourly outcomes since a time
+// A workflow that never ran has a zero baseline
+func (db *Database) GetWorkflowBaseline(workflowID string, since time.Time) (*models.WorkflowBaseline, error) {
+	baseline := &models.WorkflowBaseline{}
+	err := db.conn.QueryRow(`SELECT recent_duration_ms, baseline_duration_ms, timed_executions FROM workflow_stats WHERE workflow_id = ?`, workflowID).
+		Scan(&baseline.RecentDurationMS, &baseline.BaselineDurationMS, &baseline.TimedExecutions)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, classify(err)
+	}
+
+	rows, err := db.conn.Query(`SELECT hour, successes, failures FROM workflow_stat_buckets WHERE workflow_id = ? AND hour >= ? ORDER BY hour`,
+		workflowID, statsHour(since))
+	if err != nil {
+		return nil, classify(err)
+	}
 	defer rows.Close()
 
-	var logs []models.WorkflowLog
 	for rows.Next() {
-		var log models.WorkflowLog
-		err := rows.Scan(&log.ID, &log.WorkflowID, &log.Status, &log.Message, &log.ExecutedAt, &log.WorkflowName)
+		var hour string
+		var bucket models.StatHour
+		if err := rows.Scan(&hour, &bucket.Successes, &bucket.Failures); err != nil {
+			return nil, classify(err)
+		}
+		if bucket.Hour, err = time.Parse("2006-01-02T15", hour); err != nil {
+			return nil, fmt.Errorf("invalid stats hour %q: %w", hour, err)
+		}
+		baseline.Hours = append(baseline.Hours, bucket)
+	}
+	return baseline, classify(rows.Err())
+}
+
+// ClaimFailureAlert returns a workflow's consecutive failures and whether a failure alert is
+// due: at least threshold failures in a row and no alert since the last recovery, or none
+// within the cooldown. A due alert is marked sent by the same statement, so concurrent runs
+// of the workflow send it once
+func (db *Database) ClaimFailureAlert(workflowID string, threshold int, cooldown time.Duration, now time.Time) (int, bool, error) {
+	result, err := db.execWrite(`UPDATE workflow_stats SET failure_alerted_at = ?
+	          WHERE workflow_id = ? AND consecutive_failures >= ? AND (failure_alerted_at IS NULL OR failure_alerted_at <= ?)`,
+		now, workflowID, threshold, now.Add(-cooldown))
+	if err != nil {
+		return 0, false, classify(err)
+	}
+	n, _ := result.RowsAffected()
+
+	var failures int
+	err = db.conn.QueryRow(`SELECT consecutive_failures FROM workflow_stats WHERE workflow_id = ?`, workflowID).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, classify(err)
+	}
+	return failures, n == 1, nil
+}
+
+// ClearFailureAlert ends a workflow's failure alert once it recovers, and reports whether
+// one had been sent (so the all clear goes out once)
+func (db *Database) ClearFailureAlert(workflowID string) (bool, error) {
+	result, err := db.execWrite(`UPDATE workflow_stats SET failure_alerted_at = NULL WHERE workflow_id = ? AND failure_alerted_at IS NOT NULL`, workflowID)
+	if err != nil {
+		return false, classify(err)
+	}
+	n, _ := result.RowsAffected()
+	return n == 1, nil
+}
+
+// boolToInt converts a bool for integer columns
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// --- Logs Repository ---
+
+// CreateLog creates a new execution log
+func (db *Database) CreateLog(workflowID, status, message string) error {
+	return db.CreateLogEntry(&models.Log{WorkflowID: workflowID, Status: status, Message: message})
+}
+
+// CreateLogEntry stores a log entry including its error code, filling in ID and ExecutedAt
+func (db *Database) CreateLogEntry(log *models.Log) error {
+	log.ID = uuid.New().String()
+	if log.ExecutedAt.IsZero() {
+		log.ExecutedAt = time.Now()
+	}
+
+	var errorCode, errorParams, requestID, durationMS interface{}
+	if log.DurationMS != nil {
+		durationMS = *log.DurationMS
+	}
+	if log.ErrorCode != "" {
+		errorCode = log.ErrorCode
+	}
+	if log.RequestID != "" {
+		requestID = log.RequestID
+	}
+	if len(log.ErrorParams) > 0 {
+		encoded, err := json.Marshal(log.ErrorParams)
 		if err != nil {
-			return nil, err
+			return classify(err)
 		}
-		logs = append(logs, log)
+		errorParams = string(encoded)
 	}
 
-	return logs, nil
+	query := `INSERT INTO logs (id, workflow_id, status, message, executed_at, error_code, error_params, request_id, duration_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.execWrite(query, log.ID, log.WorkflowID, log.Status, log.Message, log.ExecutedAt, errorCode, errorParams, requestID, durationMS)
+	return classify(err)
+}
+
+// logColumns are the columns scanned by scanLog, prefixed with the logs alias "l"
+const logColumns = `l.id, l.workflow_id, l.status, l.message, l.executed_at, l.acknowledged_by, l.acknowledged_at, l.error_code, l.error_params, l.request_id, l.duration_ms`
+
+// scanLog scans a row selected with logColumns (plus any trailing destinations)
+func scanLog(rows *sql.Rows, log *models.Log, extra ...interface{}) error {
+	var acknowledgedBy sql.NullString
+	var acknowledgedAt sql.NullTime
+	var errorCode, errorParams, requestID sql.NullString
+	var durationMS sql.NullInt64
+	dest := append([]interface{}{&log.ID, &log.WorkflowID, &log.Status, &log.Message, &log.ExecutedAt, &acknowledgedBy, &acknowledgedAt, &errorCode, &errorParams, &requestID, &durationMS}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return classify(err)
+	}
+	if acknowledgedBy.Valid {
+		log.AcknowledgedBy = acknowledgedBy.String
+	}
+	if acknowledgedAt.Valid {
+		log.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	log.ErrorCode = errorCode.String
+	log.RequestID = requestID.String
+	if durationMS.Valid {
+		log.DurationMS = &durationMS.Int64
+	}
+	if errorParams.Valid {
+		// A malformed value only loses the placeholders, never the log entry
+		json.Unmarshal([]byte(errorParams.String), &log.ErrorParams)
+	}
+	return nil
+}
+
+// GetLogsByUserID retrieves all logs for a user's workflows
+func (db *Database) GetLogsByUserID(userID string) ([]models.WorkflowLog, error) {
+	return db.QueryLogs(userID, models.LogFilter{Limit: 100})
+}
+
+// GetLogsByTenantID retrieves the latest logs of every workflow in a tenant
+func (db *Database) GetLogsByTenantID(tenantID string) ([]models.WorkflowLog, error) {
+	return db.QueryLogs("", models.LogFilter{TenantID: tenantID, Limit: 100})
 }
 
 // GetLogsByWorkflowID retrieves logs for a specific workflow
 func (db *Database) GetLogsByWorkflowID(workflowID string) ([]models.Log, error) {
-	query := `SELECT id, workflow_id, status, message, executed_at FROM logs WHERE workflow_id = ? ORDER BY executed_at DESC LIMIT 50`
+	query := `SELECT ` + logColumns + ` FROM logs l WHERE l.workflow_id = ? ORDER BY l.executed_at DESC LIMIT 50`
 	rows, err := db.conn.Query(query, workflowID)
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 	defer rows.Close()
 
 	var logs []models.Log
 	for rows.Next() {
 		var log models.Log
-		err := rows.Scan(&log.ID, &log.WorkflowID, &log.Status, &log.Message, &log.ExecutedAt)
-		if err != nil {
-			return nil, err
+		if err := scanLog(rows, &log); err != nil {
+			return nil, classify(err)
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, nil
+}
+
+// logFilterClause builds the WHERE clause shared by log queries and bulk updates
+// Always scoped to the user's workflows, or the tenant's when filter.TenantID is set, via the w alias
+func logFilterClause(userID string, filter models.LogFilter) (string, []interface{}) {
+	clauses := []string{"w.user_id = ?"}
+	args := []interface{}{userID}
+	if filter.TenantID != "" {
+		clauses[0], args[0] = "w.tenant_id = ?", filter.TenantID
+	}
+
+	if filter.WorkflowID != "" {
+		clauses = append(clauses, "l.workflow_id = ?")
+		args = append(args, filter.WorkflowID)
+	}
+	if filter.Status != "" {
+		clauses = append(clauses, "l.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Unacknowledged {
+		clauses = append(clauses, "l.acknowledged_at IS NULL")
+	}
+	if filter.From != nil {
+		clauses = append(clauses, "l.executed_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		clauses = append(clauses, "l.executed_at <= ?")
+		args = append(args, *filter.To)
+	}
+	if filter.Cursor != nil {
+		clauses = append(clauses, "(l.executed_at, l.id) < (?, ?)")
+		args = append(args, filter.Cursor.Time, filter.Cursor.ID)
+	}
+
+	return strings.Join(clauses, " AND "), args
+}
+
+// QueryLogs retrieves a user's logs matching a filter, newest first
+// Keyset pagination on (executed_at, id) via filter.Cursor; Offset is deprecated
+func (db *Database) QueryLogs(userID string, filter models.LogFilter) ([]models.WorkflowLog, error) {
+	where, args := logFilterClause(userID, filter)
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `SELECT ` + logColumns + `, w.name
+	          FROM logs l
+	          JOIN workflows w ON l.workflow_id = w.id
+	          WHERE ` + where + `
+	          ORDER BY l.executed_at DESC, l.id DESC
+	          LIMIT ?`
+	args = append(args, limit)
+	if filter.Cursor == nil && filter.Offset > 0 {
+		query += ` OFFSET ?`
+		args = append(args, filter.Offset)
+	}
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var logs []models.WorkflowLog
+	for rows.Next() {
+		var log models.WorkflowLog
+		if err := scanLog(rows, &log.Log, &log.WorkflowName); err != nil {
+			return nil, classify(err)
 		}
 		logs = append(logs, log)
 	}
@@ -408,3 +1338,1889 @@ func (db *Database) GetLogsByWorkflowID(workflowID string) ([]models.Log, error)
 	return logs, nil
 }
 
+// GetLogsFiltered returns one page of a user's logs like QueryLogs, plus how many logs
+// match the filter across all pages (the cursor and offset don't reduce the total)
+func (db *Database) GetLogsFiltered(userID string, filter models.LogFilter) ([]models.WorkflowLog, int, error) {
+	logs, err := db.QueryLogs(userID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter.Cursor = nil
+	where, args := logFilterClause(userID, filter)
+	var total int
+	query := `SELECT COUNT(*) FROM logs l JOIN workflows w ON l.workflow_id = w.id WHERE ` + where
+	if err := db.conn.QueryRow(query, args...).Scan(&total); err != nil {
+		return nil, 0, classify(err)
+	}
+	return logs, total, nil
+}
+
+// GetLogByID retrieves a single log entry
+func (db *Database) GetLogByID(logID string) (*models.Log, error) {
+	query := `SELECT ` + logColumns + ` FROM logs l WHERE l.id = ?`
+	rows, err := db.conn.Query(query, logID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, classify(err)
+		}
+		return nil, ErrNotFound
+	}
+
+	log := &models.Log{}
+	if err := scanLog(rows, log); err != nil {
+		return nil, classify(err)
+	}
+	return log, nil
+}
+
+// AcknowledgeLog marks a log entry as triaged by a user
+// Re-acknowledging keeps the original acknowledger and time
+func (db *Database) AcknowledgeLog(logID, acknowledgedBy string, at time.Time) error {
+	query := `UPDATE logs SET acknowledged_by = ?, acknowledged_at = ? WHERE id = ? AND acknowledged_at IS NULL`
+	result, err := db.execWrite(query, acknowledgedBy, at, logID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return nil
+	}
+
+	// Nothing updated: either already acknowledged or no such entry
+	var exists int
+	err = db.conn.QueryRow(`SELECT 1 FROM logs WHERE id = ?`, logID).Scan(&exists)
+	return classify(err)
+}
+
+// AcknowledgeLogs bulk-acknowledges a user's unacknowledged logs matching a filter
+// Returns the number of entries acknowledged
+func (db *Database) AcknowledgeLogs(userID string, filter models.LogFilter, acknowledgedBy string, at time.Time) (int64, error) {
+	filter.Unacknowledged = true
+	where, args := logFilterClause(userID, filter)
+
+	query := `UPDATE logs SET acknowledged_by = ?, acknowledged_at = ?
+	          WHERE id IN (SELECT l.id FROM logs l JOIN workflows w ON l.workflow_id = w.id WHERE ` + where + `)`
+	result, err := db.execWrite(query, append([]interface{}{acknowledgedBy, at}, args...)...)
+	if err != nil {
+		return 0, classify(err)
+	}
+	return result.RowsAffected()
+}
+
+// AutoAcknowledgeLogs acknowledges every entry executed before a cutoff
+// Keeps the unacknowledged list focused on recent problems
+func (db *Database) AutoAcknowledgeLogs(olderThan time.Time) (int64, error) {
+	query := `UPDATE logs SET acknowledged_by = ?, acknowledged_at = ? WHERE acknowledged_at IS NULL AND executed_at < ?`
+	result, err := db.execWrite(query, models.AutoAcknowledgedBy, time.Now(), olderThan)
+	if err != nil {
+		return 0, classify(err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteWorkflowLogs deletes up to limit of a workflow's log entries, oldest first
+// Clearing a long history in batches keeps each write lock short
+func (db *Database) DeleteWorkflowLogs(workflowID string, limit int) (int64, error) {
+	query := `DELETE FROM logs WHERE id IN (
+	          SELECT id FROM logs WHERE workflow_id = ? ORDER BY executed_at LIMIT ?)`
+	result, err := db.execWrite(query, workflowID, limit)
+	if err != nil {
+		return 0, classify(err)
+	}
+	return result.RowsAffected()
+}
+
+// --- Run Stats Repository ---
+
+// heldBackStatuses are logged runs that neither succeeded nor failed: the workflow held them back
+var heldBackStatuses = map[string]bool{models.StatusSuppressed: true, models.StatusSkippedOverlap: true}
+
+// failedRunCondition matches the logs (alias l) of failed runs
+const failedRunCondition = `l.status <> 'success' AND l.status NOT IN ('` + models.StatusSuppressed + `', '` + models.StatusSkippedOverlap + `')`
+
+// runTally accumulates one workflow's (or a tenant's) run counts by status
+type runTally struct {
+	stats       models.RunStats
+	durationSum int64
+	durations   int64 // Runs with a logged duration
+}
+
+func newRunTally(since time.Time) *runTally {
+	return &runTally{stats: models.RunStats{Since: since, ByStatus: map[string]int64{}}}
+}
+
+// add counts runs of one status, durations the number of them with a logged duration
+func (t *runTally) add(status string, count, durationSum, durations int64) {
+	t.stats.TotalRuns += count
+	t.stats.ByStatus[status] += count
+	switch {
+	case status == "success":
+		t.stats.Successes += count
+	case !heldBackStatuses[status]:
+		t.stats.Failures += count
+	}
+	t.durationSum += durationSum
+	t.durations += durations
+}
+
+// result fills in the success rate and average duration
+func (t *runTally) result() models.RunStats {
+	stats := t.stats
+	if decided := stats.Successes + stats.Failures; decided > 0 {
+		stats.SuccessRate = float64(stats.Successes) / float64(decided)
+	}
+	if t.durations > 0 {
+		avg := float64(t.durationSum) / float64(t.durations)
+		stats.AvgDurationMS = &avg
+	}
+	return stats
+}
+
+// statDays lists every UTC day from since to now, oldest first, with the counts found for it
+func statDays(since, now time.Time, counts map[string]*models.StatDay) []models.StatDay {
+	var days []models.StatDay
+	for day := since.UTC().Truncate(24 * time.Hour); !day.After(now.UTC()); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if found, ok := counts[date]; ok {
+			days = append(days, *found)
+		} else {
+			days = append(days, models.StatDay{Date: date})
+		}
+	}
+	return days
+}
+
+// countDay adds runs of one status to a day's counts
+func countDay(counts map[string]*models.StatDay, date, status string, count int64) {
+	day, ok := counts[date]
+	if !ok {
+		day = &models.StatDay{Date: date}
+		counts[date] = day
+	}
+	day.Total += count
+	switch {
+	case status == "success":
+		day.Successes += count
+	case !heldBackStatuses[status]:
+		day.Failures += count
+	}
+}
+
+// medianOf returns the median of sorted values (nil for none)
+func medianOf(sorted []int64) *float64 {
+	if len(sorted) == 0 {
+		return nil
+	}
+	mid := len(sorted) / 2
+	median := float64(sorted[mid])
+	if len(sorted)%2 == 0 {
+		median = float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+	return &median
+}
+
+// runScope selects the logs (alias l) a stats query aggregates
+type runScope struct {
+	from string // FROM and WHERE clauses; conditions are appended with AND
+	arg  interface{}
+}
+
+// args returns the scope's argument followed by more
+func (s runScope) args(more ...interface{}) []interface{} {
+	return append([]interface{}{s.arg}, more...)
+}
+
+// runWorkflow is a workflow with the times of its latest run and latest success
+type runWorkflow struct {
+	id, name             string
+	lastRun, lastSuccess sql.NullTime
+}
+
+// GetWorkflowRunStats aggregates a workflow's logged runs since a time
+// The window's counts come from aggregate queries on the (workflow_id, executed_at) index
+func (db *Database) GetWorkflowRunStats(workflowID string, since time.Time) (*models.RunStats, error) {
+	workflows, err := db.runWorkflows(`w.id = ?`, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	if len(workflows) == 0 {
+		return nil, ErrNotFound
+	}
+	scope := runScope{from: `FROM logs l WHERE l.workflow_id = ?`, arg: workflowID}
+
+	tallies, err := db.runTallies(scope, since)
+	if err != nil {
+		return nil, err
+	}
+	tally, ok := tallies[workflowID]
+	if !ok {
+		tally = newRunTally(since)
+	}
+	stats := tally.result()
+	if err := db.fillRunWorkflow(&stats, workflows[0]); err != nil {
+		return nil, err
+	}
+	if stats.MedianDurationMS, err = db.medianRunDuration(scope, since, tally.durations); err != nil {
+		return nil, err
+	}
+	if stats.Daily, err = db.dailyRuns(scope, since); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetRunStatsOverview aggregates the logged runs of every workflow in a tenant since a time
+func (db *Database) GetRunStatsOverview(tenantID string, since time.Time) (*models.RunStatsOverview, error) {
+	workflows, err := db.runWorkflows(`w.tenant_id = ?`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	scope := runScope{from: `FROM logs l JOIN workflows w ON w.id = l.workflow_id WHERE w.tenant_id = ?`, arg: tenantID}
+
+	tallies, err := db.runTallies(scope, since)
+	if err != nil {
+		return nil, err
+	}
+	overview := &models.RunStatsOverview{WorkflowCount: len(workflows), Workflows: []models.RunStats{}}
+	total := newRunTally(since)
+	for _, workflow := range workflows {
+		tally, ok := tallies[workflow.id]
+		if !ok {
+			tally = newRunTally(since)
+		}
+		for status, count := range tally.stats.ByStatus {
+			total.add(status, count, 0, 0)
+		}
+		total.durationSum += tally.durationSum
+		total.durations += tally.durations
+
+		stats := tally.result()
+		if err := db.fillRunWorkflow(&stats, workflow); err != nil {
+			return nil, err
+		}
+		overview.Workflows = append(overview.Workflows, stats)
+	}
+
+	overview.RunStats = total.result()
+	for _, stats := range overview.Workflows {
+		if stats.FailureStreak > 0 {
+			overview.FailingWorkflows++
+		}
+		if stats.FailureStreak > overview.FailureStreak {
+			overview.FailureStreak = stats.FailureStreak
+		}
+		if stats.LastExecutedAt != nil && (overview.LastExecutedAt == nil || stats.LastExecutedAt.After(*overview.LastExecutedAt)) {
+			overview.LastExecutedAt = stats.LastExecutedAt
+		}
+	}
+	if overview.MedianDurationMS, err = db.medianRunDuration(scope, since, total.durations); err != nil {
+		return nil, err
+	}
+	if overview.Daily, err = db.dailyRuns(scope, since); err != nil {
+		return nil, err
+	}
+	return overview, nil
+}
+
+// runWorkflows lists the workflows matching a condition (alias w) with their latest run and success
+func (db *Database) runWorkflows(condition string, arg interface{}) ([]runWorkflow, error) {
+	rows, err := db.conn.Query(`SELECT w.id, w.name,
+	              (SELECT l.executed_at FROM logs l WHERE l.workflow_id = w.id ORDER BY l.executed_at DESC LIMIT 1),
+	              (SELECT l.executed_at FROM logs l WHERE l.workflow_id = w.id AND l.status = 'success' ORDER BY l.executed_at DESC LIMIT 1)
+	          FROM workflows w WHERE `+condition+` ORDER BY w.created_at, w.id`, arg)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var workflows []runWorkflow
+	for rows.Next() {
+		var workflow runWorkflow
+		if err := rows.Scan(&workflow.id, &workflow.name, &workflow.lastRun, &workflow.lastSuccess); err != nil {
+			return nil, classify(err)
+		}
+		workflows = append(workflows, workflow)
+	}
+	return workflows, classify(rows.Err())
+}
+
+// fillRunWorkflow sets a workflow's name, latest run and failure streak on its stats
+// The streak counts failed runs after the latest success, so it needs no query while the latest run succeeded
+func (db *Database) fillRunWorkflow(stats *models.RunStats, workflow runWorkflow) error {
+	stats.WorkflowID = workflow.id
+	stats.WorkflowName = workflow.name
+	if !workflow.lastRun.Valid {
+		return nil
+	}
+	lastRun := workflow.lastRun.Time
+	stats.LastExecutedAt = &lastRun
+	if workflow.lastSuccess.Valid && !workflow.lastSuccess.Time.Before(lastRun) {
+		return nil
+	}
+
+	query := `SELECT COUNT(*) FROM logs l WHERE l.workflow_id = ? AND ` + failedRunCondition
+	args := []interface{}{workflow.id}
+	if workflow.lastSuccess.Valid {
+		query += ` AND l.executed_at > ?`
+		args = append(args, workflow.lastSuccess.Time)
+	}
+	return classify(db.conn.QueryRow(query, args...).Scan(&stats.FailureStreak))
+}
+
+// runTallies counts the scope's runs since a time, by workflow and status
+func (db *Database) runTallies(scope runScope, since time.Time) (map[string]*runTally, error) {
+	rows, err := db.conn.Query(`SELECT l.workflow_id, l.status, COUNT(*), COALESCE(SUM(l.duration_ms), 0), COUNT(l.duration_ms) `+
+		scope.from+` AND l.executed_at >= ? GROUP BY l.workflow_id, l.status`, scope.args(since)...)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	tallies := make(map[string]*runTally)
+	for rows.Next() {
+		var workflowID, status string
+		var count, durationSum, durations int64
+		if err := rows.Scan(&workflowID, &status, &count, &durationSum, &durations); err != nil {
+			return nil, classify(err)
+		}
+		tally, ok := tallies[workflowID]
+		if !ok {
+			tally = newRunTally(since)
+			tallies[workflowID] = tally
+		}
+		tally.add(status, count, durationSum, durations)
+	}
+	return tallies, classify(rows.Err())
+}
+
+// medianRunDuration reads the middle one or two of the scope's count logged durations since a time
+// Neither dialect shares a median aggregate, so the rows are found by position in duration order
+func (db *Database) medianRunDuration(scope runScope, since time.Time, count int64) (*float64, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	limit := 2 - count%2
+	rows, err := db.conn.Query(`SELECT l.duration_ms `+scope.from+` AND l.executed_at >= ? AND l.duration_ms IS NOT NULL
+	          ORDER BY l.duration_ms LIMIT ? OFFSET ?`, scope.args(since, limit, (count-1)/2)...)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var middle []int64
+	for rows.Next() {
+		var duration int64
+		if err := rows.Scan(&duration); err != nil {
+			return nil, classify(err)
+		}
+		middle = append(middle, duration)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classify(err)
+	}
+	return medianOf(middle), nil
+}
+
+// dailyRuns counts the scope's runs per UTC day since a time, including days without runs
+func (db *Database) dailyRuns(scope runScope, since time.Time) ([]models.StatDay, error) {
+	day := `date(l.executed_at)` // SQLite converts the stored offset to UTC
+	if db.dialect == dialectPostgres {
+		day = `to_char(l.executed_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')`
+	}
+	rows, err := db.conn.Query(`SELECT `+day+`, l.status, COUNT(*) `+scope.from+` AND l.executed_at >= ? GROUP BY 1, 2`, scope.args(since)...)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]*models.StatDay)
+	for rows.Next() {
+		var date, status string
+		var count int64
+		if err := rows.Scan(&date, &status, &count); err != nil {
+			return nil, classify(err)
+		}
+		countDay(counts, date, status, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classify(err)
+	}
+	return statDays(since, time.Now(), counts), nil
+}
+
+// --- Executions Repository ---
+
+// CreateExecution stores the trace of a workflow run
+func (db *Database) CreateExecution(execution *models.Execution) error {
+	if execution.ID == "" {
+		execution.ID = uuid.New().String()
+	}
+	if execution.ExecutedAt.IsZero() {
+		execution.ExecutedAt = time.Now()
+	}
+
+	query := `INSERT INTO executions (id, workflow_id, status, message, trigger_source, trigger_payload, result_data, duration_ms, executed_at, version, instance_id, catch_up)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.execWrite(query, execution.ID, execution.WorkflowID, execution.Status, execution.Message, execution.TriggerSource,
+		execution.TriggerPayload, execution.ResultData, execution.DurationMS, execution.ExecutedAt, execution.Version, execution.InstanceID, execution.CatchUp)
+	return classify(err)
+}
+
+// GetLatestExecution retrieves the most recent real (non dry-run) execution of a workflow
+// Returns ErrNotFound if the workflow has never run
+func (db *Database) GetLatestExecution(workflowID string) (*models.Execution, error) {
+	query := `SELECT id, workflow_id, status, message, trigger_source, trigger_payload, result_data, duration_ms, executed_at, version, instance_id, catch_up
+	          FROM executions
+	          WHERE workflow_id = ? AND trigger_source != 'dry-run'
+	          ORDER BY executed_at DESC, id DESC
+	          LIMIT 1`
+
+	execution := &models.Execution{}
+	var message, payload, resultData, buildVersion, instanceID sql.NullString
+	err := db.conn.QueryRow(query, workflowID).Scan(&execution.ID, &execution.WorkflowID, &execution.Status, &message,
+		&execution.TriggerSource, &payload, &resultData, &execution.DurationMS, &execution.ExecutedAt, &buildVersion, &instanceID, &execution.CatchUp)
+	if err != nil {
+		return nil, classify(err)
+	}
+	execution.Message = message.String
+	execution.TriggerPayload = payload.String
+	execution.ResultData = resultData.String
+	execution.Version = buildVersion.String
+	execution.InstanceID = instanceID.String
+	return execution, nil
+}
+
+// GetExecutionByID retrieves a single execution trace
+func (db *Database) GetExecutionByID(executionID string) (*models.Execution, error) {
+	query := `SELECT id, workflow_id, status, message, trigger_source, trigger_payload, result_data, duration_ms, executed_at, version, instance_id, catch_up
+	          FROM executions
+	          WHERE id = ?`
+
+	execution := &models.Execution{}
+	var message, payload, resultData, buildVersion, instanceID sql.NullString
+	err := db.conn.QueryRow(query, executionID).Scan(&execution.ID, &execution.WorkflowID, &execution.Status, &message,
+		&execution.TriggerSource, &payload, &resultData, &execution.DurationMS, &execution.ExecutedAt, &buildVersion, &instanceID, &execution.CatchUp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, classify(err)
+	}
+	execution.Message = message.String
+	execution.TriggerPayload = payload.String
+	execution.ResultData = resultData.String
+	execution.Version = buildVersion.String
+	execution.InstanceID = instanceID.String
+	return execution, nil
+}
+
+// ListExecutions retrieves one page of a workflow's executions, newest first
+// Keyset pagination on (executed_at, id) via page.Cursor; Offset is deprecated
+func (db *Database) ListExecutions(workflowID string, page models.PageRequest) ([]models.Execution, error) {
+	query := `SELECT id, workflow_id, status, message, trigger_source, trigger_payload, result_data, duration_ms, executed_at, version, instance_id, catch_up
+	          FROM executions
+	          WHERE workflow_id = ?`
+	args := []interface{}{workflowID}
+
+	if page.Cursor != nil {
+		query += ` AND (executed_at, id) < (?, ?)`
+		args = append(args, page.Cursor.Time, page.Cursor.ID)
+	}
+	query += ` ORDER BY executed_at DESC, id DESC LIMIT ?`
+	args = append(args, page.Limit)
+	if page.Cursor == nil && page.Offset > 0 {
+		query += ` OFFSET ?`
+		args = append(args, page.Offset)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var executions []models.Execution
+	for rows.Next() {
+		var execution models.Execution
+		var message, payload, resultData, buildVersion, instanceID sql.NullString
+		if err := rows.Scan(&execution.ID, &execution.WorkflowID, &execution.Status, &message, &execution.TriggerSource,
+			&payload, &resultData, &execution.DurationMS, &execution.ExecutedAt, &buildVersion, &instanceID, &execution.CatchUp); err != nil {
+			return nil, classify(err)
+		}
+		execution.Message = message.String
+		execution.TriggerPayload = payload.String
+		execution.ResultData = resultData.String
+		execution.Version = buildVersion.String
+		execution.InstanceID = instanceID.String
+		executions = append(executions, execution)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classify(err)
+	}
+	return executions, nil
+}
+
+// exportPageSize is how many rows EachExecution and EachAuditEvent read per query
+// Each page is released before fn runs, so a long export never holds the connection
+const exportPageSize = 500
+
+// EachExecution calls fn for every execution of a user's workflows since a time, oldest first
+// Rows are read a page at a time so memory stays flat however long the history is
+func (db *Database) EachExecution(userID string, since time.Time, fn func(*models.Execution) error) error {
+	query := `SELECT e.rowid, e.id, e.workflow_id, e.status, e.message, e.trigger_source, e.trigger_payload, e.result_data,
+	                 e.duration_ms, e.executed_at, e.version, e.instance_id, e.catch_up
+	          FROM executions e
+	          JOIN workflows w ON e.workflow_id = w.id
+	          WHERE w.user_id = ? AND e.executed_at >= ? AND e.rowid > ?
+	          ORDER BY e.rowid
+	          LIMIT ?`
+
+	var after int64
+	for {
+		rows, err := db.conn.Query(query, userID, since, after, exportPageSize)
+		if err != nil {
+			return classify(err)
+		}
+		var page []models.Execution
+		for rows.Next() {
+			var execution models.Execution
+			var message, payload, resultData, buildVersion, instanceID sql.NullString
+			if err := rows.Scan(&after, &execution.ID, &execution.WorkflowID, &execution.Status, &message, &execution.TriggerSource,
+				&payload, &resultData, &execution.DurationMS, &execution.ExecutedAt, &buildVersion, &instanceID, &execution.CatchUp); err != nil {
+				rows.Close()
+				return classify(err)
+			}
+			execution.Message = message.String
+			execution.TriggerPayload = payload.String
+			execution.ResultData = resultData.String
+			execution.Version = buildVersion.String
+			execution.InstanceID = instanceID.String
+			page = append(page, execution)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return classify(err)
+		}
+
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return classify(err)
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// --- Workflow Fixtures Repository ---
+
+// CreateWorkflowFixture stores a named sample payload for a workflow
+// Returns ErrFixtureExists for a duplicate name and ErrFixtureLimit once the
+// workflow already has limit fixtures; both are checked in the insert's transaction
+func (db *Database) CreateWorkflowFixture(fixture *models.WorkflowFixture, limit int) error {
+	if fixture.ID == "" {
+		fixture.ID = uuid.New().String()
+	}
+	if fixture.CreatedAt.IsZero() {
+		fixture.CreatedAt = time.Now()
+	}
+
+	return db.writeTx(func(tx *sql.Tx) error {
+		var count, duplicates int
+		err := tx.QueryRow(`SELECT COUNT(*), COALESCE(SUM(CASE WHEN name = ? THEN 1 ELSE 0 END), 0) FROM workflow_fixtures WHERE workflow_id = ?`,
+			fixture.Name, fixture.WorkflowID).Scan(&count, &duplicates)
+		if err != nil {
+			return classify(err)
+		}
+		if duplicates > 0 {
+			return ErrFixtureExists
+		}
+		if count >= limit {
+			return ErrFixtureLimit
+		}
+
+		_, err = tx.Exec(`INSERT INTO workflow_fixtures (id, workflow_id, name, payload, source, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			fixture.ID, fixture.WorkflowID, fixture.Name, string(fixture.Payload), fixture.Source, fixture.CreatedAt)
+		return classify(err)
+	})
+}
+
+// ListWorkflowFixtures returns a workflow's fixtures sorted by name
+func (db *Database) ListWorkflowFixtures(workflowID string) ([]models.WorkflowFixture, error) {
+	rows, err := db.conn.Query(`SELECT id, workflow_id, name, payload, source, created_at FROM workflow_fixtures WHERE workflow_id = ? ORDER BY name`, workflowID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	fixtures := []models.WorkflowFixture{}
+	for rows.Next() {
+		var fixture models.WorkflowFixture
+		var payload string
+		if err := rows.Scan(&fixture.ID, &fixture.WorkflowID, &fixture.Name, &payload, &fixture.Source, &fixture.CreatedAt); err != nil {
+			return nil, classify(err)
+		}
+		fixture.Payload = []byte(payload)
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, rows.Err()
+}
+
+// GetWorkflowFixture retrieves a fixture by name
+func (db *Database) GetWorkflowFixture(workflowID, name string) (*models.WorkflowFixture, error) {
+	fixture := &models.WorkflowFixture{}
+	var payload string
+	err := db.conn.QueryRow(`SELECT id, workflow_id, name, payload, source, created_at FROM workflow_fixtures WHERE workflow_id = ? AND name = ?`,
+		workflowID, name).Scan(&fixture.ID, &fixture.WorkflowID, &fixture.Name, &payload, &fixture.Source, &fixture.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, classify(err)
+	}
+	fixture.Payload = []byte(payload)
+	return fixture, nil
+}
+
+// DeleteWorkflowFixture removes a fixture by name
+func (db *Database) DeleteWorkflowFixture(workflowID, name string) error {
+	result, err := db.execWrite(`DELETE FROM workflow_fixtures WHERE workflow_id = ? AND name = ?`, workflowID, name)
+	if err != nil {
+		return classify(err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetWorkflowPausedUntil pauses a workflow until a time (nil resumes it now)
+func (db *Database) SetWorkflowPausedUntil(workflowID string, until *time.Time) error {
+	var untilValue interface{}
+	if until != nil {
+		untilValue = *until
+	}
+	result, err := db.execWrite(`UPDATE workflows SET paused_until = ? WHERE id = ?`, untilValue, workflowID)
+	if err != nil {
+		return classify(err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// --- Webhook Requests Repository ---
+
+// SetWorkflowDebugRequests turns webhook request capture on until a time (nil turns it off)
+// limit is how many captured requests the workflow keeps
+func (db *Database) SetWorkflowDebugRequests(workflowID string, until *time.Time, limit int) error {
+	var untilValue interface{}
+	if until != nil {
+		untilValue = *until
+	}
+	result, err := db.execWrite(`UPDATE workflows SET debug_requests_until = ?, debug_requests_limit = ? WHERE id = ?`, untilValue, limit, workflowID)
+	if err != nil {
+		return classify(err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CaptureWebhookRequest stores a captured request and drops the workflow's oldest
+// beyond keep, in one transaction so the buffer never grows past its size
+func (db *Database) CaptureWebhookRequest(request *models.WebhookRequest, keep int) error {
+	if request.ID == "" {
+		request.ID = uuid.New().String()
+	}
+	if request.ReceivedAt.IsZero() {
+		request.ReceivedAt = time.Now()
+	}
+	headers, err := json.Marshal(request.Headers)
+	if err != nil {
+		return classify(err)
+	}
+
+	return db.writeTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO webhook_requests (id, workflow_id, received_at, method, headers, body, body_truncated, verdict, detail)
+		                   VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			request.ID, request.WorkflowID, request.ReceivedAt, request.Method, string(headers), request.Body,
+			request.BodyTruncated, request.Verdict, request.Detail)
+		if err != nil {
+			return classify(err)
+		}
+		_, err = tx.Exec(`DELETE FROM webhook_requests WHERE workflow_id = ? AND id NOT IN (
+		                      SELECT id FROM webhook_requests WHERE workflow_id = ? ORDER BY received_at DESC, rowid DESC LIMIT ?)`,
+			request.WorkflowID, request.WorkflowID, keep)
+		return classify(err)
+	})
+}
+
+// webhookRequestColumns are the columns scanned by scanWebhookRequest
+const webhookRequestColumns = `id, workflow_id, received_at, method, headers, body, body_truncated, verdict, detail`
+
+// scanWebhookRequest scans a row selected with webhookRequestColumns
+func scanWebhookRequest(row rowScanner) (*models.WebhookRequest, error) {
+	request := &models.WebhookRequest{}
+	var headers, body, detail sql.NullString
+	err := row.Scan(&request.ID, &request.WorkflowID, &request.ReceivedAt, &request.Method, &headers, &body,
+		&request.BodyTruncated, &request.Verdict, &detail)
+	if err != nil {
+		return nil, classify(err)
+	}
+	request.Body = body.String
+	request.Detail = detail.String
+	if headers.String != "" {
+		if err := json.Unmarshal([]byte(headers.String), &request.Headers); err != nil {
+			return nil, classify(err)
+		}
+	}
+	return request, nil
+}
+
+// ListWebhookRequests retrieves a workflow's captured requests, newest first
+func (db *Database) ListWebhookRequests(workflowID string) ([]models.WebhookRequest, error) {
+	query := `SELECT ` + webhookRequestColumns + ` FROM webhook_requests WHERE workflow_id = ? ORDER BY received_at DESC, rowid DESC`
+	rows, err := db.conn.Query(query, workflowID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var requests []models.WebhookRequest
+	for rows.Next() {
+		request, err := scanWebhookRequest(rows)
+		if err != nil {
+			return nil, classify(err)
+		}
+		requests = append(requests, *request)
+	}
+	return requests, rows.Err()
+}
+
+// GetWebhookRequest retrieves one captured request of a workflow
+func (db *Database) GetWebhookRequest(workflowID, requestID string) (*models.WebhookRequest, error) {
+	query := `SELECT ` + webhookRequestColumns + ` FROM webhook_requests WHERE workflow_id = ? AND id = ?`
+	request, err := scanWebhookRequest(db.conn.QueryRow(query, workflowID, requestID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return request, classify(err)
+}
+
+// SaveWebhookPayload stores a webhook payload and drops the workflow's oldest beyond keep
+func (db *Database) SaveWebhookPayload(payload *models.WebhookPayload, keep int) error {
+	if payload.ID == "" {
+		payload.ID = uuid.New().String()
+	}
+	if payload.ReceivedAt.IsZero() {
+		payload.ReceivedAt = time.Now()
+	}
+
+	return db.writeTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO webhook_payloads (id, workflow_id, payload, received_at) VALUES (?, ?, ?, ?)`,
+			payload.ID, payload.WorkflowID, string(payload.Payload), payload.ReceivedAt)
+		if err != nil {
+			return classify(err)
+		}
+		_, err = tx.Exec(`DELETE FROM webhook_payloads WHERE workflow_id = ? AND id NOT IN (
+		                      SELECT id FROM webhook_payloads WHERE workflow_id = ? ORDER BY received_at DESC, rowid DESC LIMIT ?)`,
+			payload.WorkflowID, payload.WorkflowID, keep)
+		return classify(err)
+	})
+}
+
+// ListWebhookPayloads retrieves a workflow's recent webhook payloads, newest first
+func (db *Database) ListWebhookPayloads(workflowID string) ([]models.WebhookPayload, error) {
+	rows, err := db.conn.Query(`SELECT id, workflow_id, payload, received_at FROM webhook_payloads
+	                            WHERE workflow_id = ? ORDER BY received_at DESC, rowid DESC`, workflowID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var payloads []models.WebhookPayload
+	for rows.Next() {
+		var payload models.WebhookPayload
+		var raw string
+		if err := rows.Scan(&payload.ID, &payload.WorkflowID, &raw, &payload.ReceivedAt); err != nil {
+			return nil, classify(err)
+		}
+		payload.Payload = json.RawMessage(raw)
+		payloads = append(payloads, payload)
+	}
+	return payloads, rows.Err()
+}
+
+// --- Dead Letters Repository ---
+
+// CreateDeadLetter records a run the worker pool dropped, so it can be retried
+func (db *Database) CreateDeadLetter(workflowID, reason, payload string) (*models.DeadLetter, error) {
+	letter := &models.DeadLetter{
+		ID:         uuid.New().String(),
+		WorkflowID: workflowID,
+		Reason:     reason,
+		Payload:    payload,
+		CreatedAt:  time.Now(),
+	}
+	_, err := db.execWrite(`INSERT INTO dead_letters (id, workflow_id, reason, payload, created_at) VALUES (?, ?, ?, ?, ?)`,
+		letter.ID, letter.WorkflowID, letter.Reason, letter.Payload, letter.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// deadLetterColumns are the columns scanned by scanDeadLetter
+const deadLetterColumns = `d.id, d.workflow_id, d.reason, d.payload, d.created_at, d.resolved_at`
+
+// scanDeadLetter scans a row selected with deadLetterColumns
+func scanDeadLetter(row rowScanner) (*models.DeadLetter, error) {
+	letter := &models.DeadLetter{}
+	var payload sql.NullString
+	var resolvedAt sql.NullTime
+	if err := row.Scan(&letter.ID, &letter.WorkflowID, &letter.Reason, &payload, &letter.CreatedAt, &resolvedAt); err != nil {
+		return nil, classify(err)
+	}
+	letter.Payload = payload.String
+	if resolvedAt.Valid {
+		letter.ResolvedAt = &resolvedAt.Time
+	}
+	return letter, nil
+}
+
+// ListDeadLetters retrieves the dead letters of a user's workflows, newest first
+func (db *Database) ListDeadLetters(userID string) ([]models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters d JOIN workflows w ON w.id = d.workflow_id
+	          WHERE w.user_id = ? ORDER BY d.created_at DESC, d.id DESC`
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var letters []models.DeadLetter
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, *letter)
+	}
+	return letters, classify(rows.Err())
+}
+
+// GetDeadLetter retrieves one dead letter
+func (db *Database) GetDeadLetter(id string) (*models.DeadLetter, error) {
+	return scanDeadLetter(db.conn.QueryRow(`SELECT `+deadLetterColumns+` FROM dead_letters d WHERE d.id = ?`, id))
+}
+
+// ResolveDeadLetter marks a dead letter retried
+// Only the first call succeeds, so two concurrent retries can't both resubmit the run
+func (db *Database) ResolveDeadLetter(id string, at time.Time) error {
+	result, err := db.execWrite(`UPDATE dead_letters SET resolved_at = ? WHERE id = ? AND resolved_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+	if _, err := db.GetDeadLetter(id); err != nil {
+		return err
+	}
+	return ErrDeadLetterResolved
+}
+
+// --- Webhook Events Repository ---
+
+// MarkWebhookEventSeen records a provider event ID for a workflow
+// Returns true if the event is new (or its previous record has expired) and
+// false for a duplicate; the single upsert makes racing deliveries agree
+func (db *Database) MarkWebhookEventSeen(workflowID, eventID string, receivedAt, expiresAt time.Time) (bool, error) {
+	query := `INSERT INTO webhook_events (workflow_id, event_id, received_at, expires_at) VALUES (?, ?, ?, ?)
+	          ON CONFLICT(workflow_id, event_id) DO UPDATE SET
+	              received_at = excluded.received_at,
+	              expires_at = excluded.expires_at
+	          WHERE webhook_events.expires_at <= excluded.received_at`
+	result, err := db.execWrite(query, workflowID, eventID, receivedAt, expiresAt)
+	if err != nil {
+		return false, classify(err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, classify(err)
+	}
+	return rows > 0, nil
+}
+
+// ForgetWebhookEvent deletes a recorded event ID, so a redelivery of the event runs it
+func (db *Database) ForgetWebhookEvent(workflowID, eventID string) error {
+	_, err := db.execWrite(`DELETE FROM webhook_events WHERE workflow_id = ? AND event_id = ?`, workflowID, eventID)
+	return classify(err)
+}
+
+// PruneWebhookEvents deletes event IDs that expired before the given time
+func (db *Database) PruneWebhookEvents(before time.Time) (int64, error) {
+	result, err := db.execWrite(`DELETE FROM webhook_events WHERE expires_at <= ?`, before)
+	if err != nil {
+		return 0, classify(err)
+	}
+	return result.RowsAffected()
+}
+
+// --- Config Change Repository ---
+
+// RecordConfigChange stores an audited runtime settings update
+func (db *Database) RecordConfigChange(change *models.ConfigChange) error {
+	if change.ID == "" {
+		change.ID = uuid.New().String()
+	}
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now()
+	}
+
+	query := `INSERT INTO config_changes (id, actor, source, changes, changed_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.execWrite(query, change.ID, change.Actor, change.Source, change.Changes, change.ChangedAt)
+	return classify(err)
+}
+
+// ListConfigChanges retrieves the most recent runtime settings updates, newest first
+func (db *Database) ListConfigChanges(limit int) ([]models.ConfigChange, error) {
+	query := `SELECT id, actor, source, changes, changed_at FROM config_changes
+	          ORDER BY changed_at DESC, id DESC LIMIT ?`
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var changes []models.ConfigChange
+	for rows.Next() {
+		var c models.ConfigChange
+		if err := rows.Scan(&c.ID, &c.Actor, &c.Source, &c.Changes, &c.ChangedAt); err != nil {
+			return nil, classify(err)
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// --- Impersonation & Audit Repository ---
+
+// CreateImpersonationSession stores a new impersonation session
+func (db *Database) CreateImpersonationSession(session *models.ImpersonationSession) error {
+	if session.ID == "" {
+		session.ID = uuid.New().String()
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO impersonation_sessions (id, admin_id, target_user_id, reason, created_at, expires_at)
+	          VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := db.execWrite(query, session.ID, session.AdminID, session.TargetUserID, session.Reason, session.CreatedAt, session.ExpiresAt)
+	return classify(err)
+}
+
+// GetImpersonationSession retrieves a session by ID, revoked or not
+func (db *Database) GetImpersonationSession(sessionID string) (*models.ImpersonationSession, error) {
+	query := `SELECT id, admin_id, target_user_id, reason, created_at, expires_at, revoked_at, COALESCE(revoked_by, '')
+	          FROM impersonation_sessions WHERE id = ?`
+	session, err := scanImpersonationSession(db.conn.QueryRow(query, sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return session, classify(err)
+}
+
+// ListActiveImpersonationSessions retrieves sessions that are neither revoked nor expired, newest first
+func (db *Database) ListActiveImpersonationSessions(now time.Time) ([]models.ImpersonationSession, error) {
+	query := `SELECT id, admin_id, target_user_id, reason, created_at, expires_at, revoked_at, COALESCE(revoked_by, '')
+	          FROM impersonation_sessions
+	          WHERE revoked_at IS NULL AND expires_at > ?
+	          ORDER BY created_at DESC`
+	rows, err := db.conn.Query(query, now)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	sessions := []models.ImpersonationSession{}
+	for rows.Next() {
+		session, err := scanImpersonationSession(rows)
+		if err != nil {
+			return nil, classify(err)
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeImpersonationSession ends a session; tokens issued for it stop working at once
+// Returns ErrNotFound if the session doesn't exist or was already revoked
+func (db *Database) RevokeImpersonationSession(sessionID, revokedBy string, at time.Time) error {
+	result, err := db.execWrite(`UPDATE impersonation_sessions SET revoked_at = ?, revoked_by = ? WHERE id = ? AND revoked_at IS NULL`,
+		at, revokedBy, sessionID)
+	if err != nil {
+		return classify(err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanImpersonationSession reads one impersonation_sessions row
+func scanImpersonationSession(row interface{ Scan(...interface{}) error }) (*models.ImpersonationSession, error) {
+	session := &models.ImpersonationSession{}
+	var revokedAt sql.NullTime
+	if err := row.Scan(&session.ID, &session.AdminID, &session.TargetUserID, &session.Reason,
+		&session.CreatedAt, &session.ExpiresAt, &revokedAt, &session.RevokedBy); err != nil {
+		return nil, classify(err)
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
+
+// RecordAuditEvent appends an entry to the audit trail
+func (db *Database) RecordAuditEvent(event *models.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO audit_events (id, actor_id, impersonator_id, session_id, action, method, path, status_code, detail,
+	                                    tenant_id, resource_type, resource_id, source_ip, created_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.execWrite(query, event.ID, event.ActorID, event.ImpersonatorID, event.SessionID, event.Action,
+		event.Method, event.Path, event.StatusCode, event.Detail,
+		event.TenantID, event.ResourceType, event.ResourceID, event.SourceIP, event.CreatedAt)
+	return classify(err)
+}
+
+// auditEventColumns is the column list scanAuditEvent expects
+const auditEventColumns = `id, actor_id, COALESCE(impersonator_id, ''), COALESCE(session_id, ''), action,
+	COALESCE(method, ''), COALESCE(path, ''), COALESCE(status_code, 0), COALESCE(detail, ''),
+	COALESCE(tenant_id, ''), COALESCE(resource_type, ''), COALESCE(resource_id, ''), COALESCE(source_ip, ''), created_at`
+
+// scanAuditEvent scans a row selected with auditEventColumns, after any leading columns in dest
+func scanAuditEvent(row rowScanner, e *models.AuditEvent, dest ...interface{}) error {
+	return row.Scan(append(dest, &e.ID, &e.ActorID, &e.ImpersonatorID, &e.SessionID, &e.Action,
+		&e.Method, &e.Path, &e.StatusCode, &e.Detail,
+		&e.TenantID, &e.ResourceType, &e.ResourceID, &e.SourceIP, &e.CreatedAt)...)
+}
+
+// ListAuditEvents retrieves the most recent audit events, newest first
+func (db *Database) ListAuditEvents(limit int) ([]models.AuditEvent, error) {
+	query := `SELECT ` + auditEventColumns + `
+	          FROM audit_events
+	          ORDER BY created_at DESC, rowid DESC LIMIT ?`
+	return db.queryAuditEvents(query, limit)
+}
+
+// ListTenantAuditEvents retrieves a tenant's audit events, newest first, narrowed by the filter
+func (db *Database) ListTenantAuditEvents(tenantID string, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	query := `SELECT ` + auditEventColumns + `
+	          FROM audit_events
+	          WHERE tenant_id = ?`
+	args := []interface{}{tenantID}
+	if filter.ResourceType != "" {
+		query += ` AND resource_type = ?`
+		args = append(args, filter.ResourceType)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, filter.Until)
+	}
+	query += ` ORDER BY created_at DESC, rowid DESC LIMIT ?`
+	return db.queryAuditEvents(query, append(args, filter.Limit)...)
+}
+
+// queryAuditEvents runs a query selecting auditEventColumns
+func (db *Database) queryAuditEvents(query string, args ...interface{}) ([]models.AuditEvent, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := scanAuditEvent(rows, &e); err != nil {
+			return nil, classify(err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// EachAuditEvent calls fn for every audit event performed as a user, oldest first, a page at a time
+func (db *Database) EachAuditEvent(actorID string, fn func(*models.AuditEvent) error) error {
+	query := `SELECT rowid, ` + auditEventColumns + `
+	          FROM audit_events
+	          WHERE actor_id = ? AND rowid > ?
+	          ORDER BY rowid
+	          LIMIT ?`
+
+	var after int64
+	for {
+		rows, err := db.conn.Query(query, actorID, after, exportPageSize)
+		if err != nil {
+			return classify(err)
+		}
+		var page []models.AuditEvent
+		for rows.Next() {
+			var e models.AuditEvent
+			if err := scanAuditEvent(rows, &e, &after); err != nil {
+				rows.Close()
+				return classify(err)
+			}
+			page = append(page, e)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return classify(err)
+		}
+
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return classify(err)
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// --- Usage Repository ---
+
+// RecordWorkflowCost adds one execution, its estimated cost and any simulated delay to the monthly rollup
+func (db *Database) RecordWorkflowCost(tenantID, workflowID, month string, cost float64, delayMS int64) error {
+	query := `INSERT INTO usage (tenant_id, workflow_id, month, executions, cost, delay_ms, updated_at)
+	          VALUES (?, ?, ?, 1, ?, ?, ?)
+	          ON CONFLICT (tenant_id, workflow_id, month)
+	          DO UPDATE SET executions = usage.executions + 1, cost = usage.cost + excluded.cost,
+	                        delay_ms = usage.delay_ms + excluded.delay_ms, updated_at = excluded.updated_at`
+	_, err := db.execWrite(query, tenantID, workflowID, month, cost, delayMS, time.Now())
+	return classify(err)
+}
+
+// GetWorkflowCosts retrieves a tenant's per-workflow spend for a month, most expensive first
+func (db *Database) GetWorkflowCosts(tenantID, month string) ([]models.WorkflowCost, error) {
+	query := `SELECT u.workflow_id, COALESCE(w.name, ''), u.month, u.executions, u.cost, u.delay_ms
+	          FROM usage u
+	          LEFT JOIN workflows w ON u.workflow_id = w.id
+	          WHERE u.tenant_id = ? AND u.month = ?
+	          ORDER BY u.cost DESC`
+	rows, err := db.conn.Query(query, tenantID, month)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var costs []models.WorkflowCost
+	for rows.Next() {
+		var c models.WorkflowCost
+		if err := rows.Scan(&c.WorkflowID, &c.WorkflowName, &c.Month, &c.Executions, &c.Cost, &c.DelayMS); err != nil {
+			return nil, classify(err)
+		}
+		costs = append(costs, c)
+	}
+
+	return costs, nil
+}
+
+// EachWorkflowCost calls fn for every monthly usage rollup of a tenant, oldest month first
+// A tenant has one row per workflow and month, so the rows are read in one query
+func (db *Database) EachWorkflowCost(tenantID string, fn func(*models.WorkflowCost) error) error {
+	query := `SELECT u.workflow_id, COALESCE(w.name, ''), u.month, u.executions, u.cost, u.delay_ms
+	          FROM usage u
+	          LEFT JOIN workflows w ON u.workflow_id = w.id
+	          WHERE u.tenant_id = ?
+	          ORDER BY u.month, u.workflow_id`
+	rows, err := db.conn.Query(query, tenantID)
+	if err != nil {
+		return classify(err)
+	}
+
+	var costs []models.WorkflowCost
+	for rows.Next() {
+		var c models.WorkflowCost
+		if err := rows.Scan(&c.WorkflowID, &c.WorkflowName, &c.Month, &c.Executions, &c.Cost, &c.DelayMS); err != nil {
+			rows.Close()
+			return classify(err)
+		}
+		costs = append(costs, c)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return classify(err)
+	}
+
+	for i := range costs {
+		if err := fn(&costs[i]); err != nil {
+			return classify(err)
+		}
+	}
+	return nil
+}
+
+// --- Tenant Settings Repository ---
+
+// GetTenantSettings retrieves a tenant's settings
+// A tenant that never saved any is on the free tier with no overrides
+func (db *Database) GetTenantSettings(tenantID string) (*models.TenantSettings, error) {
+	settings := &models.TenantSettings{TenantID: tenantID, Tier: models.TierFree}
+	var retention, notifications, canaries, maskedFields, anomalySettings, credentialPolicy, lastPurge sql.NullString
+	var updatedAt sql.NullTime
+	err := db.conn.QueryRow(`SELECT tier, retention, notifications, canaries, masked_fields, anomaly, credential_policy, last_purge, updated_at FROM tenant_settings WHERE tenant_id = ?`, tenantID).
+		Scan(&settings.Tier, &retention, &notifications, &canaries, &maskedFields, &anomalySettings, &credentialPolicy, &lastPurge, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, classify(err)
+	}
+	settings.UpdatedAt = updatedAt.Time
+	settings.CredentialPolicy = credentialPolicy.String
+	if retention.String != "" {
+		settings.Retention = &models.RetentionPolicy{}
+		if err := json.Unmarshal([]byte(retention.String), settings.Retention); err != nil {
+			return nil, fmt.Errorf("invalid retention settings for %s: %w", tenantID, err)
+		}
+	}
+	if notifications.String != "" {
+		settings.Notifications = &models.ChangeNotifications{}
+		if err := json.Unmarshal([]byte(notifications.String), settings.Notifications); err != nil {
+			return nil, fmt.Errorf("invalid notification settings for %s: %w", tenantID, err)
+		}
+	}
+	if canaries.String != "" {
+		if err := json.Unmarshal([]byte(canaries.String), &settings.Canaries); err != nil {
+			return nil, fmt.Errorf("invalid connector canaries for %s: %w", tenantID, err)
+		}
+	}
+	if maskedFields.String != "" {
+		if err := json.Unmarshal([]byte(maskedFields.String), &settings.MaskedFields); err != nil {
+			return nil, fmt.Errorf("invalid masked fields for %s: %w", tenantID, err)
+		}
+	}
+	if anomalySettings.String != "" {
+		settings.Anomaly = &models.AnomalySettings{}
+		if err := json.Unmarshal([]byte(anomalySettings.String), settings.Anomaly); err != nil {
+			return nil, fmt.Errorf("invalid anomaly settings for %s: %w", tenantID, err)
+		}
+	}
+	if lastPurge.String != "" {
+		settings.LastPurge = &models.RetentionRun{}
+		if err := json.Unmarshal([]byte(lastPurge.String), settings.LastPurge); err != nil {
+			return nil, fmt.Errorf("invalid retention run for %s: %w", tenantID, err)
+		}
+	}
+	return settings, nil
+}
+
+// SaveTenantSettings stores a tenant's tier and retention overrides
+// The last purge summary is left alone; RecordRetentionRun owns it
+func (db *Database) SaveTenantSettings(settings *models.TenantSettings) error {
+	if settings.Tier == "" {
+		settings.Tier = models.TierFree
+	}
+	settings.UpdatedAt = time.Now()
+
+	var retention interface{}
+	if settings.Retention != nil {
+		encoded, err := json.Marshal(settings.Retention)
+		if err != nil {
+			return classify(err)
+		}
+		retention = string(encoded)
+	}
+	var notifications interface{}
+	if settings.Notifications != nil {
+		encoded, err := json.Marshal(settings.Notifications)
+		if err != nil {
+			return classify(err)
+		}
+		notifications = string(encoded)
+	}
+	var canaries interface{}
+	if len(settings.Canaries) > 0 {
+		encoded, err := json.Marshal(settings.Canaries)
+		if err != nil {
+			return classify(err)
+		}
+		canaries = string(encoded)
+	}
+	var maskedFields interface{}
+	if len(settings.MaskedFields) > 0 {
+		encoded, err := json.Marshal(settings.MaskedFields)
+		if err != nil {
+			return classify(err)
+		}
+		maskedFields = string(encoded)
+	}
+	var anomalySettings interface{}
+	if settings.Anomaly != nil {
+		encoded, err := json.Marshal(settings.Anomaly)
+		if err != nil {
+			return classify(err)
+		}
+		anomalySettings = string(encoded)
+	}
+	var credentialPolicy interface{}
+	if settings.CredentialPolicy != "" {
+		credentialPolicy = settings.CredentialPolicy
+	}
+	_, err := db.execWrite(`INSERT INTO tenant_settings (tenant_id, tier, retention, notifications, canaries, masked_fields, anomaly, credential_policy, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	          ON CONFLICT (tenant_id) DO UPDATE SET tier = excluded.tier, retention = excluded.retention,
+	              notifications = excluded.notifications, canaries = excluded.canaries,
+	              masked_fields = excluded.masked_fields, anomaly = excluded.anomaly,
+	              credential_policy = excluded.credential_policy, updated_at = excluded.updated_at`,
+		settings.TenantID, settings.Tier, retention, notifications, canaries, maskedFields, anomalySettings, credentialPolicy, settings.UpdatedAt)
+	return classify(err)
+}
+
+// RecordRetentionRun stores the summary of a tenant's latest retention pass
+func (db *Database) RecordRetentionRun(run *models.RetentionRun) error {
+	encoded, err := json.Marshal(run)
+	if err != nil {
+		return classify(err)
+	}
+	_, err = db.execWrite(`INSERT INTO tenant_settings (tenant_id, tier, last_purge, updated_at) VALUES (?, ?, ?, ?)
+	          ON CONFLICT (tenant_id) DO UPDATE SET last_purge = excluded.last_purge`,
+		run.TenantID, models.TierFree, string(encoded), time.Now())
+	return classify(err)
+}
+
+// GetTenantLimits returns a tenant's tier and API rate limit overrides
+func (db *Database) GetTenantLimits(tenantID string) (*models.TenantLimits, error) {
+	limits := &models.TenantLimits{TenantID: tenantID, Tier: models.TierFree}
+	err := db.conn.QueryRow(`SELECT tier, COALESCE(requests_per_second, 0), COALESCE(burst, 0) FROM tenant_settings WHERE tenant_id = ?`, tenantID).
+		Scan(&limits.Tier, &limits.RequestsPerSecond, &limits.Burst)
+	if errors.Is(err, sql.ErrNoRows) {
+		return limits, nil
+	}
+	if err != nil {
+		return nil, classify(err)
+	}
+	return limits, nil
+}
+
+// SetTenantLimits stores a tenant's tier and API rate limit overrides (0 = none)
+func (db *Database) SetTenantLimits(limits *models.TenantLimits) error {
+	if limits.Tier == "" {
+		limits.Tier = models.TierFree
+	}
+	var requestsPerSecond, burst interface{}
+	if limits.RequestsPerSecond > 0 {
+		requestsPerSecond = limits.RequestsPerSecond
+	}
+	if limits.Burst > 0 {
+		burst = limits.Burst
+	}
+	_, err := db.execWrite(`INSERT INTO tenant_settings (tenant_id, tier, requests_per_second, burst, updated_at) VALUES (?, ?, ?, ?, ?)
+	          ON CONFLICT (tenant_id) DO UPDATE SET tier = excluded.tier, requests_per_second = excluded.requests_per_second,
+	              burst = excluded.burst, updated_at = excluded.updated_at`,
+		limits.TenantID, limits.Tier, requestsPerSecond, burst, time.Now())
+	return classify(err)
+}
+
+// ListTenantIDs returns every tenant, in a stable order
+func (db *Database) ListTenantIDs() ([]string, error) {
+	rows, err := db.conn.Query(`SELECT id FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	var tenantIDs []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, classify(err)
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	return tenantIDs, rows.Err()
+}
+
+// tenantWorkflows selects the IDs of a tenant's workflows
+const tenantWorkflows = `SELECT id FROM workflows WHERE tenant_id = ?`
+
+// purgeQueries remove (or, for payloads, clear) up to a limit of a tenant's rows older than a time
+var purgeQueries = map[string]string{
+	models.RetentionLogs: `DELETE FROM logs WHERE id IN (
+	    SELECT id FROM logs WHERE workflow_id IN (` + tenantWorkflows + `) AND executed_at < ? LIMIT ?)`,
+	models.RetentionPayloads: `UPDATE executions SET trigger_payload = '', result_data = '' WHERE id IN (
+	    SELECT id FROM executions WHERE workflow_id IN (` + tenantWorkflows + `) AND executed_at < ?
+	    AND (trigger_payload != '' OR result_data != '') LIMIT ?)`,
+	models.RetentionExecutions: `DELETE FROM executions WHERE id IN (
+	    SELECT id FROM executions WHERE workflow_id IN (` + tenantWorkflows + `) AND executed_at < ? LIMIT ?)`,
+	models.RetentionFixtures: `DELETE FROM workflow_fixtures WHERE id IN (
+	    SELECT id FROM workflow_fixtures WHERE workflow_id IN (` + tenantWorkflows + `) AND created_at < ? LIMIT ?)`,
+	models.RetentionDebugRequests: `DELETE FROM webhook_requests WHERE id IN (
+	    SELECT id FROM webhook_requests WHERE workflow_id IN (` + tenantWorkflows + `) AND received_at < ? LIMIT ?)`,
+}
+
+// PurgeTenantData removes at most limit rows of one data class older than before
+// Payloads are cleared from their executions rather than deleted; returns the rows affected
+func (db *Database) PurgeTenantData(tenantID, dataClass string, before time.Time, limit int) (int64, error) {
+	query, ok := purgeQueries[dataClass]
+	if !ok {
+		return 0, fmt.Errorf("unknown data class %q", dataClass)
+	}
+	result, err := db.execWrite(query, tenantID, before, limit)
+	if err != nil {
+		return 0, classify(err)
+	}
+	return result.RowsAffected()
+}
+
+// --- Tenant Domains Repository ---
+
+const tenantDomainColumns = `id, tenant_id, domain, verification_token, status, COALESCE(kong_service_id, ''),
+	COALESCE(kong_route_id, ''), COALESCE(last_error, ''), checked_at, verified_at, created_at`
+
+// CreateTenantDomain registers a domain for a tenant
+// The domain column is unique, so two tenants racing for one domain can't both get it
+func (db *Database) CreateTenantDomain(domain *models.TenantDomain) error {
+	if domain.ID == "" {
+		domain.ID = uuid.New().String()
+	}
+	if domain.CreatedAt.IsZero() {
+		domain.CreatedAt = time.Now()
+	}
+	_, err := db.execWrite(`INSERT INTO tenant_domains (id, tenant_id, domain, verification_token, status, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		domain.ID, domain.TenantID, domain.Domain, domain.VerificationToken, domain.Status, domain.CreatedAt)
+	if isConstraintError(err) {
+		return ErrDomainTaken
+	}
+	return classify(err)
+}
+
+// ListTenantDomains returns a tenant's domains, oldest first
+func (db *Database) ListTenantDomains(tenantID string) ([]models.TenantDomain, error) {
+	rows, err := db.conn.Query(`SELECT `+tenantDomainColumns+` FROM tenant_domains WHERE tenant_id = ? ORDER BY created_at`, tenantID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	domains := []models.TenantDomain{}
+	for rows.Next() {
+		domain, err := scanTenantDomain(rows)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, *domain)
+	}
+	return domains, classify(rows.Err())
+}
+
+// GetTenantDomain retrieves one of a tenant's domains
+func (db *Database) GetTenantDomain(tenantID, domainID string) (*models.TenantDomain, error) {
+	domain, err := scanTenantDomain(db.conn.QueryRow(`SELECT `+tenantDomainColumns+` FROM tenant_domains WHERE id = ? AND tenant_id = ?`, domainID, tenantID))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return domain, err
+}
+
+// GetTenantDomainByName retrieves a domain whichever tenant registered it
+func (db *Database) GetTenantDomainByName(name string) (*models.TenantDomain, error) {
+	domain, err := scanTenantDomain(db.conn.QueryRow(`SELECT `+tenantDomainColumns+` FROM tenant_domains WHERE domain = ?`, name))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return domain, err
+}
+
+// UpdateTenantDomain stores a domain's status, Kong objects and latest verification check
+func (db *Database) UpdateTenantDomain(domain *models.TenantDomain) error {
+	result, err := db.execWrite(`UPDATE tenant_domains SET status = ?, kong_service_id = ?, kong_route_id = ?, last_error = ?, checked_at = ?, verified_at = ?
+	          WHERE id = ? AND tenant_id = ?`,
+		domain.Status, domain.KongServiceID, domain.KongRouteID, domain.LastError, domain.CheckedAt, domain.VerifiedAt, domain.ID, domain.TenantID)
+	if err != nil {
+		return classify(err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteTenantDomain removes a tenant's domain, freeing it for registration again
+func (db *Database) DeleteTenantDomain(tenantID, domainID string) error {
+	result, err := db.execWrite(`DELETE FROM tenant_domains WHERE id = ? AND tenant_id = ?`, domainID, tenantID)
+	if err != nil {
+		return classify(err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanTenantDomain(row interface{ Scan(...interface{}) error }) (*models.TenantDomain, error) {
+	domain := &models.TenantDomain{}
+	var checkedAt, verifiedAt sql.NullTime
+	if err := row.Scan(&domain.ID, &domain.TenantID, &domain.Domain, &domain.VerificationToken, &domain.Status,
+		&domain.KongServiceID, &domain.KongRouteID, &domain.LastError, &checkedAt, &verifiedAt, &domain.CreatedAt); err != nil {
+		return nil, classify(err)
+	}
+	if checkedAt.Valid {
+		domain.CheckedAt = &checkedAt.Time
+	}
+	if verifiedAt.Valid {
+		domain.VerifiedAt = &verifiedAt.Time
+	}
+	return domain, nil
+}
+
+// --- Kong Resources Repository ---
+
+// CreateKongResource records a Kong object created for a workflow
+func (db *Database) CreateKongResource(resource *models.KongResource) error {
+	if resource.ID == "" {
+		resource.ID = uuid.New().String()
+	}
+	if resource.CreatedAt.IsZero() {
+		resource.CreatedAt = time.Now()
+	}
+	_, err := db.execWrite(`INSERT INTO kong_resources (id, workflow_id, tenant_id, kind, kong_id, name, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		resource.ID, resource.WorkflowID, resource.TenantID, resource.Kind, resource.KongID, resource.Name, resource.CreatedAt)
+	return classify(err)
+}
+
+// ListKongResources returns the Kong objects recorded for a tenant's workflow, or for all
+// its workflows when workflowID is empty, oldest first
+func (db *Database) ListKongResources(tenantID, workflowID string) ([]models.KongResource, error) {
+	query := `SELECT id, workflow_id, tenant_id, kind, kong_id, COALESCE(name, ''), created_at FROM kong_resources WHERE tenant_id = ?`
+	args := []interface{}{tenantID}
+	if workflowID != "" {
+		query += ` AND workflow_id = ?`
+		args = append(args, workflowID)
+	}
+	rows, err := db.conn.Query(query+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	resources := []models.KongResource{}
+	for rows.Next() {
+		var resource models.KongResource
+		if err := rows.Scan(&resource.ID, &resource.WorkflowID, &resource.TenantID, &resource.Kind,
+			&resource.KongID, &resource.Name, &resource.CreatedAt); err != nil {
+			return nil, classify(err)
+		}
+		resources = append(resources, resource)
+	}
+	return resources, classify(rows.Err())
+}
+
+// DeleteKongResource removes a tenant's record of a Kong object
+func (db *Database) DeleteKongResource(tenantID, resourceID string) error {
+	result, err := db.execWrite(`DELETE FROM kong_resources WHERE id = ? AND tenant_id = ?`, resourceID, tenantID)
+	if err != nil {
+		return classify(err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// --- Tenant Invites Repository ---
+
+const tenantInviteColumns = `id, tenant_id, email, invited_by, status, created_at, expires_at,
+	accepted_at, COALESCE(accepted_by, ''), revoked_at`
+
+// CreateTenantInvite stores a pending invite into a tenant
+func (db *Database) CreateTenantInvite(invite *models.TenantInvite) error {
+	if invite.ID == "" {
+		invite.ID = uuid.New().String()
+	}
+	if invite.CreatedAt.IsZero() {
+		invite.CreatedAt = time.Now()
+	}
+	invite.Status = models.InvitePending
+
+	_, err := db.execWrite(`INSERT INTO tenant_invites (id, tenant_id, email, invited_by, status, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		invite.ID, invite.TenantID, invite.Email, invite.InvitedBy, invite.Status, invite.CreatedAt, invite.ExpiresAt)
+	return err
+}
+
+// ListTenantInvites returns a tenant's invites, newest first
+func (db *Database) ListTenantInvites(tenantID string) ([]models.TenantInvite, error) {
+	rows, err := db.conn.Query(`SELECT `+tenantInviteColumns+` FROM tenant_invites WHERE tenant_id = ? ORDER BY created_at DESC`, tenantID)
+	if err != nil {
+		return nil, classify(err)
+	}
+	defer rows.Close()
+
+	invites := []models.TenantInvite{}
+	for rows.Next() {
+		invite, err := scanTenantInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+	return invites, classify(rows.Err())
+}
+
+// GetTenantInvite retrieves an invite by ID, whatever its status
+func (db *Database) GetTenantInvite(inviteID string) (*models.TenantInvite, error) {
+	return scanTenantInvite(db.conn.QueryRow(`SELECT `+tenantInviteColumns+` FROM tenant_invites WHERE id = ?`, inviteID))
+}
+
+// RevokeTenantInvite withdraws a pending invite so its token can't be used
+// Returns ErrInviteClosed if it was already accepted or revoked
+func (db *Database) RevokeTenantInvite(tenantID, inviteID string, at time.Time) error {
+	result, err := db.execWrite(`UPDATE tenant_invites SET status = ?, revoked_at = ? WHERE id = ? AND tenant_id = ? AND status = ?`,
+		models.InviteRevoked, at, inviteID, tenantID, models.InvitePending)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+	invite, err := db.GetTenantInvite(inviteID)
+	if err != nil {
+		return err
+	}
+	if invite.TenantID != tenantID {
+		return ErrNotFound
+	}
+	return ErrInviteClosed
+}
+
+// AcceptTenantInvite creates the invited user as a member of the invite's tenant and uses the invite up
+// Both happen in one transaction, so of two racing sign-ups with one token only the
+// first succeeds; the other gets ErrInviteClosed, as does an expired invite
+func (db *Database) AcceptTenantInvite(inviteID, passwordHash string, at time.Time) (*models.User, error) {
+	invite, err := db.GetTenantInvite(inviteID)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        invite.Email,
+		PasswordHash: passwordHash,
+		CreatedAt:    at,
+		TenantID:     invite.TenantID,
+		Role:         models.RoleMember,
+	}
+	err = db.writeTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE tenant_invites SET status = ?, accepted_at = ?, accepted_by = ? WHERE id = ? AND status = ? AND expires_at > ?`,
+			models.InviteAccepted, at, user.ID, inviteID, models.InvitePending, at)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrInviteClosed
+		}
+		_, err = tx.Exec(`INSERT INTO users (id, email, password_hash, created_at, tenant_id, role) VALUES (?, ?, ?, ?, ?, ?)`,
+			user.ID, user.Email, user.PasswordHash, user.CreatedAt, user.TenantID, user.Role)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func scanTenantInvite(row interface{ Scan(...interface{}) error }) (*models.TenantInvite, error) {
+	invite := &models.TenantInvite{}
+	var acceptedAt, revokedAt sql.NullTime
+	if err := row.Scan(&invite.ID, &invite.TenantID, &invite.Email, &invite.InvitedBy, &invite.Status, &invite.CreatedAt,
+		&invite.ExpiresAt, &acceptedAt, &invite.AcceptedBy, &revokedAt); err != nil {
+		return nil, classify(err)
+	}
+	if acceptedAt.Valid {
+		invite.AcceptedAt = &acceptedAt.Time
+	}
+	if revokedAt.Valid {
+		invite.RevokedAt = &revokedAt.Time
+	}
+	return invite, nil
+}
+
+// --- Password Resets Repository ---
+
+// CreatePasswordReset stores a reset by the hash of its token
+func (db *Database) CreatePasswordReset(reset *models.PasswordReset) error {
+	if reset.ID == "" {
+		reset.ID = uuid.New().String()
+	}
+	if reset.CreatedAt.IsZero() {
+		reset.CreatedAt = time.Now()
+	}
+
+	_, err := db.execWrite(`INSERT INTO password_resets (id, user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		reset.ID, reset.UserID, reset.TokenHash, reset.CreatedAt, reset.ExpiresAt)
+	return err
+}
+
+// ResetPassword uses up the reset with this token hash and sets its user's password
+// Both happen in one transaction, so of two racing resets with one token only the
+// first succeeds; the other gets ErrResetClosed, as does an expired reset.
+// The user's other open resets are used up too.
+func (db *Database) ResetPassword(tokenHash, passwordHash string, at time.Time) (*models.User, error) {
+	var userID string
+	if err := db.conn.QueryRow(`SELECT user_id FROM password_resets WHERE token_hash = ?`, tokenHash).Scan(&userID); err != nil {
+		return nil, classify(err)
+	}
+
+	err := db.writeTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE password_resets SET used_at = ? WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`,
+			at, tokenHash, at)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrResetClosed
+		}
+		if _, err := tx.Exec(`UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, at, userID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE users SET password_hash = ?, token_version = token_version + 1 WHERE id = ?`, passwordHash, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db.GetUserByID(userID)
+}
+
+// --- Refresh Tokens Repository ---
+
+// CreateRefreshToken stores a refresh token by the hash of its token
+func (db *Database) CreateRefreshToken(token *models.RefreshToken) error {
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	_, err := db.execWrite(`INSERT INTO refresh_tokens (id, user_id, token_hash, token_version, user_agent, ip_address, created_at, expires_at)
+	                        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, token.UserID, token.TokenHash, token.TokenVersion, token.UserAgent, token.IPAddress, token.CreatedAt, token.ExpiresAt)
+	return classify(err)
+}
+
+// RotateRefreshToken revokes the token with this hash and stores next in its place
+// Both happen in one transaction, so of two racing refreshes with one token only the
+// first gets a new token; the other gets ErrRefreshTokenClosed, as does a revoked or
+// expired token and one issued before its user's last password change.
+// next takes the user and token version of the token it replaces.
+func (db *Database) RotateRefreshToken(tokenHash string, next *models.RefreshToken, at time.Time) (*models.RefreshToken, error) {
+	current, err := db.getRefreshToken(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if next.ID == "" {
+		next.ID = uuid.New().String()
+	}
+	if next.CreatedAt.IsZero() {
+		next.CreatedAt = at
+	}
+	next.UserID, next.TokenVersion = current.UserID, current.TokenVersion
+
+	err = db.writeTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = ?
+		                        WHERE token_hash = ? AND revoked_at IS NULL AND expires_at > ?
+		                          AND token_version = (SELECT token_version FROM users WHERE id = refresh_tokens.user_id)`,
+			at, tokenHash, at)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrRefreshTokenClosed
+		}
+		_, err = tx.Exec(`INSERT INTO refresh_tokens (id, user_id, token_hash, token_version, user_agent, ip_address, created_at, expires_at)
+		                  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			next.ID, next.UserID, next.TokenHash, next.TokenVersion, next.UserAgent, next.IPAddress, next.CreatedAt, next.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	current.RevokedAt = &at
+	return current, nil
+}
+
+// RevokeRefreshToken revokes the token with this hash, or with allSessions every open
+// token of its user; revoking an already revoked token is not an error
+func (db *Database) RevokeRefreshToken(tokenHash string, allSessions bool, at time.Time) (*models.RefreshToken, error) {
+	token, err := db.getRefreshToken(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	query, arg := `UPDATE refresh_tokens SET revoked_at = ? WHERE token_hash = ? AND revoked_at IS NULL`, token.TokenHash
+	if allSessions {
+		query, arg = `UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, token.UserID
+	}
+	if _, err := db.execWrite(query, at, arg); err != nil {
+		return nil, classify(err)
+	}
+	if token.RevokedAt == nil {
+		token.RevokedAt = &at
+	}
+	return token, nil
+}
+
+// PruneRefreshTokens deletes tokens that expired or were revoked before the given time
+// Every refresh revokes a row, so they would otherwise pile up for each signed-in device
+func (db *Database) PruneRefreshTokens(before time.Time) (int64, error) {
+	result, err := db.execWrite(`DELETE FROM refresh_tokens WHERE expires_at <= ? OR revoked_at <= ?`, before, before)
+	if err != nil {
+		return 0, classify(err)
+	}
+	return result.RowsAffected()
+}
+
+// getRefreshToken reads the refresh token with this hash
+func (db *Database) getRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	var userAgent, ipAddress sql.NullString
+	var revokedAt sql.NullTime
+	err := db.conn.QueryRow(`SELECT id, user_id, token_hash, token_version, user_agent, ip_address, created_at, expires_at, revoked_at
+	                         FROM refresh_tokens WHERE token_hash = ?`, tokenHash).
+		Scan(&token.ID, &token.UserID, &token.TokenHash, &token.TokenVersion, &userAgent, &ipAddress, &token.CreatedAt, &token.ExpiresAt, &revokedAt)
+	if err != nil {
+		return nil, classify(err)
+	}
+	token.UserAgent, token.IPAddress = userAgent.String, ipAddress.String
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return token, nil
+}
@@ -0,0 +1,28 @@
+// Package dbtest opens real SQLite databases for tests
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+)
+
+// New opens a fresh SQLite database in a temp directory, closed when the test ends
+func New(t testing.TB) *db.Database {
+	t.Helper()
+	return Open(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+// Open opens (creating if needed) the SQLite database at path, closed when the test
+// ends; tests reopen the same path to check what survives a restart
+func Open(t testing.TB, path string) *db.Database {
+	t.Helper()
+
+	database, err := db.New(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Common errors
+// Both stores return errors carrying a store kind (store.ErrNotFound, ...) so callers
+// can use errors.Is or the store.Is* helpers whichever implementation they run against
+var (
+	ErrNotFound      = store.ErrNotFound
+	ErrFixtureExists = &StoreError{Code: "fixture_exists", Message: "A fixture with this name already exists", Kind: store.ErrConflict}
+	ErrFixtureLimit  = &StoreError{Code: "fixture_limit", Message: "Workflow fixture limit reached", Kind: store.ErrConflict}
+	ErrDomainTaken   = &StoreError{Code: "domain_taken", Message: "This domain is already registered", Kind: store.ErrConflict}
+
+	ErrDeadLetterResolved = &StoreError{Code: "dead_letter_resolved", Message: "This dead letter was already retried", Kind: store.ErrConflict}
+	ErrResetClosed        = &StoreError{Code: "password_reset_closed", Message: "This password reset was already used or has expired", Kind: store.ErrConflict}
+	ErrRefreshTokenClosed = &StoreError{Code: "refresh_token_closed", Message: "This refresh token was already used, revoked or has expired", Kind: store.ErrConflict}
+	ErrInviteClosed       = &StoreError{Code: "invite_closed", Message: "This invite was already accepted, revoked or has expired", Kind: store.ErrConflict}
+)
+
+// StoreError represents a database error
+type StoreError struct {
+	Code    string
+	Message string
+	Kind    error // One of the store kinds, matched by errors.Is
+}
+
+func (e *StoreError) Error() string {
+	return e.Message
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Kind
+}
+
+// classify tags a driver error with its store kind
+// Errors that already carry a kind, and errors that aren't the store's fault
+// (decryption failures, bad input), pass through unchanged
+func classify(err error) error {
+	if err == nil || store.Classified(err) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return store.Wrap(store.ErrNotFound, err)
+	case isBusyError(err):
+		return store.Wrap(store.ErrLocked, err)
+	case isConstraintError(err):
+		return store.Wrap(store.ErrConflict, err)
+	case errors.Is(err, sql.ErrConnDone) || strings.Contains(err.Error(), "sql: database is closed"):
+		return store.Wrap(store.ErrUnavailable, err)
+	}
+	return err
+}
+
+// isConstraintError reports whether err is a UNIQUE or other constraint violation
+func isConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrConstraint
+	}
+	return isPostgresConstraintError(err)
+}
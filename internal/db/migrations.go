@@ -0,0 +1,225 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// migrations upgrade databases created before a column existed in schema.sql
+// schema.sql always describes the latest shape for fresh installs; every
+// statement here must be safe to re-run against either kind of database
+// (duplicate column errors are ignored, indexes use IF NOT EXISTS)
+var migrations = []string{
+	// Log acknowledgment (triage of failures)
+	`ALTER TABLE logs ADD COLUMN acknowledged_by TEXT`,
+	`ALTER TABLE logs ADD COLUMN acknowledged_at DATETIME`,
+	`CREATE INDEX IF NOT EXISTS idx_logs_acknowledged_at ON logs(acknowledged_at)`,
+
+	// Keyset pagination: composite indexes matching the (timestamp, id) cursor order
+	`CREATE INDEX IF NOT EXISTS idx_logs_executed_at_id ON logs(executed_at, id)`,
+	`CREATE INDEX IF NOT EXISTS idx_workflows_user_created_id ON workflows(user_id, created_at, id)`,
+
+	// Execution stamping: which build and instance ran each execution
+	`ALTER TABLE executions ADD COLUMN version TEXT`,
+	`ALTER TABLE executions ADD COLUMN instance_id TEXT`,
+
+	// Testing connector delays counted against tenant usage
+	`ALTER TABLE usage ADD COLUMN delay_ms INTEGER NOT NULL DEFAULT 0`,
+
+	// Error codes so failure messages can be translated when displayed
+	`ALTER TABLE logs ADD COLUMN error_code TEXT`,
+	`ALTER TABLE logs ADD COLUMN error_params TEXT`,
+
+	// Tenant-scoped credential encryption; ReencryptCredentials moves existing rows to tenant keys
+	`ALTER TABLE credentials ADD COLUMN tenant_id TEXT`,
+	`ALTER TABLE credentials ADD COLUMN key_version INTEGER NOT NULL DEFAULT 0`,
+	`UPDATE credentials SET tenant_id = 'tenant_' || user_id WHERE tenant_id IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_credentials_tenant_id ON credentials(tenant_id)`,
+
+	// Webhook request capture for debugging (the webhook_requests table is created by schema.sql)
+	`ALTER TABLE workflows ADD COLUMN debug_requests_until DATETIME`,
+	`ALTER TABLE workflows ADD COLUMN debug_requests_limit INTEGER`,
+
+	// Workflow change notifications
+	`ALTER TABLE tenant_settings ADD COLUMN notifications TEXT`,
+
+	// Connector version canaries
+	`ALTER TABLE tenant_settings ADD COLUMN canaries TEXT`,
+
+	// Tenant payload masking policy
+	`ALTER TABLE tenant_settings ADD COLUMN masked_fields TEXT`,
+
+	// Scheduled runs caught up after downtime
+	`ALTER TABLE executions ADD COLUMN catch_up INTEGER NOT NULL DEFAULT 0`,
+
+	// Outbound notification throttle
+	`ALTER TABLE workflow_stats ADD COLUMN suppressed_executions INTEGER NOT NULL DEFAULT 0`,
+
+	// Execution anomaly detection baselines
+	`ALTER TABLE workflow_stats ADD COLUMN recent_duration_ms REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE workflow_stats ADD COLUMN baseline_duration_ms REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE workflow_stat_buckets ADD COLUMN failures INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tenant_settings ADD COLUMN anomaly TEXT`,
+
+	// Credential environments (test vs. live)
+	`ALTER TABLE credentials ADD COLUMN environment TEXT NOT NULL DEFAULT 'live'`,
+	`ALTER TABLE tenant_settings ADD COLUMN credential_policy TEXT`,
+	`UPDATE workflow_stats SET recent_duration_ms = total_duration_ms * 1.0 / timed_executions,
+	     baseline_duration_ms = total_duration_ms * 1.0 / timed_executions
+	 WHERE baseline_duration_ms = 0 AND timed_executions > 0`,
+
+	// Tenants: every existing user becomes the only member of a tenant of their own,
+	// with the ID credentials were already encrypted under (the tenants table is created by schema.sql)
+	`ALTER TABLE users ADD COLUMN tenant_id TEXT REFERENCES tenants(id)`,
+	`ALTER TABLE workflows ADD COLUMN tenant_id TEXT`,
+	`INSERT OR IGNORE INTO tenants (id, name, created_at) SELECT 'tenant_' || id, email, created_at FROM users WHERE tenant_id IS NULL`,
+	`UPDATE users SET tenant_id = 'tenant_' || id WHERE tenant_id IS NULL`,
+	`UPDATE workflows SET tenant_id = (SELECT u.tenant_id FROM users u WHERE u.id = workflows.user_id) WHERE tenant_id IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_workflows_tenant_created_id ON workflows(tenant_id, created_at, id)`,
+
+	// Tenant roles: whoever's sign-up created the tenant administers it
+	`ALTER TABLE users ADD COLUMN role TEXT`,
+	`UPDATE users SET role = CASE WHEN tenant_id = 'tenant_' || id THEN 'admin' ELSE 'member' END WHERE role IS NULL`,
+
+	// Scheduler due times; backfillNextRuns fills them in for existing schedule workflows
+	`ALTER TABLE workflows ADD COLUMN next_run_at DATETIME`,
+	`CREATE INDEX IF NOT EXISTS idx_workflows_next_run_at ON workflows(next_run_at)`,
+
+	// Audit trail of workflow, credential and sign-in changes per tenant
+	`ALTER TABLE audit_events ADD COLUMN tenant_id TEXT`,
+	`ALTER TABLE audit_events ADD COLUMN resource_type TEXT`,
+	`ALTER TABLE audit_events ADD COLUMN resource_id TEXT`,
+	`ALTER TABLE audit_events ADD COLUMN source_ip TEXT`,
+	`CREATE INDEX IF NOT EXISTS idx_audit_events_tenant_created ON audit_events(tenant_id, created_at)`,
+
+	// Per-tenant API rate limit overrides
+	`ALTER TABLE tenant_settings ADD COLUMN requests_per_second REAL`,
+	`ALTER TABLE tenant_settings ADD COLUMN burst INTEGER`,
+
+	// Workflows paused until a time
+	`ALTER TABLE workflows ADD COLUMN paused_until DATETIME`,
+
+	// The API request that triggered each logged run
+	`ALTER TABLE logs ADD COLUMN request_id TEXT`,
+
+	// Password changes end existing sessions (the password_resets table is created by schema.sql)
+	`ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0`,
+
+	// Run durations and the per-workflow index behind the stats endpoints
+	`ALTER TABLE logs ADD COLUMN duration_ms INTEGER`,
+	`CREATE INDEX IF NOT EXISTS idx_logs_workflow_executed_at ON logs(workflow_id, executed_at)`,
+
+	// Failure alerts
+	`ALTER TABLE workflow_stats ADD COLUMN failure_alerted_at DATETIME`,
+
+	// Workflow stats backfill from existing logs, for workflows that have no stats row yet
+	// Buckets first: the second statement creates the stats rows that mark a workflow as backfilled
+	`INSERT OR IGNORE INTO workflow_stat_buckets (workflow_id, hour, successes)
+	 SELECT workflow_id, strftime('%Y-%m-%dT%H', executed_at), COUNT(*)
+	 FROM logs
+	 WHERE status = 'success'
+	   AND executed_at >= datetime('now', '-7 days')
+	   AND workflow_id NOT IN (SELECT workflow_id FROM workflow_stats)
+	 GROUP BY 1, 2`,
+	`INSERT OR IGNORE INTO workflow_stats (workflow_id, total_executions, consecutive_failures)
+	 SELECT l.workflow_id,
+	        COUNT(*),
+	        SUM(CASE WHEN l.status = 'failed' AND l.executed_at > COALESCE(
+	            (SELECT MAX(ls.executed_at) FROM logs ls WHERE ls.workflow_id = l.workflow_id AND ls.status = 'success'), '')
+	            THEN 1 ELSE 0 END)
+	 FROM logs l
+	 JOIN workflows w ON w.id = l.workflow_id
+	 WHERE l.workflow_id NOT IN (SELECT workflow_id FROM workflow_stats)
+	 GROUP BY l.workflow_id`,
+}
+
+// migrate applies column additions and indexes for new columns
+func (db *Database) migrate() error {
+	for _, statement := range migrations {
+		if _, err := db.conn.Exec(statement); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("migration %q failed: %w", statement, err)
+		}
+	}
+	if err := db.backfillLastErrors(); err != nil {
+		return err
+	}
+	return db.backfillNextRuns()
+}
+
+// backfillLastErrors copies the latest failure message into stats rows that have none
+// Done in Go rather than SQL so the message goes through the same masking as live updates
+func (db *Database) backfillLastErrors() error {
+	rows, err := db.conn.Query(`SELECT s.workflow_id,
+	       (SELECT lf.message FROM logs lf WHERE lf.workflow_id = s.workflow_id AND lf.status = 'failed'
+	        ORDER BY lf.executed_at DESC LIMIT 1)
+	FROM workflow_stats s
+	WHERE s.last_error IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to read stats for backfill: %w", err)
+	}
+
+	// Collect first: the single connection can't serve updates while rows are open
+	lastErrors := make(map[string]string)
+	for rows.Next() {
+		var workflowID string
+		var message sql.NullString
+		if err := rows.Scan(&workflowID, &message); err != nil {
+			rows.Close()
+			return err
+		}
+		if message.Valid {
+			lastErrors[workflowID] = message.String
+		}
+	}
+	rows.Close()
+
+	for workflowID, message := range lastErrors {
+		if _, err := db.conn.Exec(`UPDATE workflow_stats SET last_error = ? WHERE workflow_id = ? AND last_error IS NULL`,
+			statsErrorMessage(message), workflowID); err != nil {
+			return fmt.Errorf("failed to backfill last error: %w", err)
+		}
+	}
+	return nil
+}
+
+// backfillNextRuns computes next_run_at for schedule workflows saved before it existed
+// Done in Go so the due time comes from the same interval parsing as live updates
+func (db *Database) backfillNextRuns() error {
+	rows, err := db.conn.Query(`SELECT id, trigger_type, config_json, last_executed_at, created_at
+	FROM workflows
+	WHERE trigger_type = 'schedule' AND next_run_at IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to read workflows for backfill: %w", err)
+	}
+
+	// Collect first: the single connection can't serve updates while rows are open
+	var workflows []models.Workflow
+	for rows.Next() {
+		var workflow models.Workflow
+		var lastExecutedAt sql.NullTime
+		if err := rows.Scan(&workflow.ID, &workflow.TriggerType, &workflow.ConfigJSON, &lastExecutedAt, &workflow.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if lastExecutedAt.Valid {
+			workflow.LastExecutedAt = &lastExecutedAt.Time
+		}
+		workflows = append(workflows, workflow)
+	}
+	rows.Close()
+
+	for _, workflow := range workflows {
+		next := workflow.NextRunAfter(workflow.LastExecutedAt)
+		if next == nil {
+			continue // Unparseable config; saving a fixed one sets it
+		}
+		if _, err := db.conn.Exec(`UPDATE workflows SET next_run_at = ? WHERE id = ? AND next_run_at IS NULL`, *next, workflow.ID); err != nil {
+			return fmt.Errorf("failed to backfill next run: %w", err)
+		}
+	}
+	return nil
+}
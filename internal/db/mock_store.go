@@ -1,43 +1,152 @@
 package db
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/crypto"
 	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
 )
 
 // MockStore is a mock implementation of Store for testing
 // This allows E2E tests to run without touching the filesystem
+// Its methods are safe for concurrent use, as the engine's workers call them from many
+// goroutines; tests seed and inspect the fields directly only while nothing else runs
 type MockStore struct {
-	Users       map[string]*models.User
-	Credentials map[string]*models.Credential
-	Workflows   map[string]*models.Workflow
-	Logs        []models.Log
+	mu sync.RWMutex // Guards the fields below; the unexported helpers expect it held
+
+	Tenants        map[string]*models.Tenant
+	Users          map[string]*models.User
+	Credentials    map[string]*models.Credential
+	Workflows      map[string]*models.Workflow
+	Logs           []models.Log
+	Executions     []models.Execution
+	Usage          map[string]*models.WorkflowCost // Keyed by tenant|workflow|month
+	WebhookEvents  map[string]time.Time            // Expiry keyed by workflow|event
+	ConfigChanges  []models.ConfigChange
+	Fixtures       []models.WorkflowFixture
+	WebhookRequests []models.WebhookRequest // Oldest first
+	WebhookPayloads []models.WebhookPayload // Oldest first
+	Impersonations map[string]*models.ImpersonationSession
+	AuditEvents    []models.AuditEvent
+	TenantKeys     map[string]int // Current data key version by tenant
+	TenantSettings map[string]*models.TenantSettings
+	TenantLimits   map[string]models.TenantLimits // Rate limit overrides; the tier lives in TenantSettings
+	TenantDomains  []models.TenantDomain
+	DeadLetters    []models.DeadLetter // Oldest first
+	TenantInvites  []models.TenantInvite // Oldest first
+	PasswordResets []models.PasswordReset
+	RefreshTokens  []models.RefreshToken
+	KongResources  []models.KongResource // Oldest first
+	FailureAlerts  map[string]time.Time  // When each failing workflow's last alert was sent
 }
 
 // NewMockStore creates a new in-memory mock store
 func NewMockStore() *MockStore {
 	return &MockStore{
-		Users:       make(map[string]*models.User),
-		Credentials: make(map[string]*models.Credential),
-		Workflows:   make(map[string]*models.Workflow),
-		Logs:        make([]models.Log, 0),
+		Tenants:        make(map[string]*models.Tenant),
+		Users:          make(map[string]*models.User),
+		Credentials:    make(map[string]*models.Credential),
+		Workflows:      make(map[string]*models.Workflow),
+		Logs:           make([]models.Log, 0),
+		Usage:          make(map[string]*models.WorkflowCost),
+		WebhookEvents:  make(map[string]time.Time),
+		Impersonations: make(map[string]*models.ImpersonationSession),
+		TenantKeys:     make(map[string]int),
+		TenantSettings: make(map[string]*models.TenantSettings),
+		TenantLimits:   make(map[string]models.TenantLimits),
+		FailureAlerts:  make(map[string]time.Time),
+	}
+}
+
+// Tenant operations
+func (m *MockStore) CreateTenant(id, name string) (*models.Tenant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Tenants[id]; ok {
+		return nil, fmt.Errorf("tenant %s: %w", id, store.ErrConflict)
+	}
+	tenant := &models.Tenant{ID: id, Name: name, CreatedAt: time.Now()}
+	m.Tenants[id] = tenant
+	return tenant, nil
+}
+
+func (m *MockStore) GetTenantByID(id string) (*models.Tenant, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if tenant, ok := m.Tenants[id]; ok {
+		return tenant, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) ListTenantUserIDs(tenantID string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var userIDs []string
+	for _, user := range m.Users {
+		if user.TenantID == tenantID {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+// userTenantID returns the user's tenant, or their default one for an unknown user
+func (m *MockStore) userTenantID(userID string) string {
+	if user, ok := m.Users[userID]; ok && user.TenantID != "" {
+		return user.TenantID
 	}
+	return models.DefaultTenantID(userID)
 }
 
 // User operations
 func (m *MockStore) CreateUser(email, passwordHash string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, err := m.newUser(models.DefaultTenantID("mock_user_"+email), models.RoleAdmin, email, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	m.Tenants[user.TenantID] = &models.Tenant{ID: user.TenantID, Name: email, CreatedAt: user.CreatedAt}
+	return user, nil
+}
+
+func (m *MockStore) CreateUserInTenant(tenantID, email, passwordHash string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Tenants[tenantID]; !ok {
+		return nil, ErrNotFound
+	}
+	return m.newUser(tenantID, models.RoleMember, email, passwordHash)
+}
+
+func (m *MockStore) newUser(tenantID, role, email, passwordHash string) (*models.User, error) {
+	for _, existing := range m.Users {
+		if existing.Email == email {
+			return nil, fmt.Errorf("user %s: %w", email, store.ErrConflict)
+		}
+	}
 	user := &models.User{
 		ID:           "mock_user_" + email,
 		Email:        email,
 		PasswordHash: passwordHash,
 		CreatedAt:    time.Now(),
+		TenantID:     tenantID,
+		Role:         role,
 	}
 	m.Users[user.ID] = user
 	return user, nil
 }
 
 func (m *MockStore) GetUserByEmail(email string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for _, user := range m.Users {
 		if user.Email == email {
 			return user, nil
@@ -47,26 +156,214 @@ func (m *MockStore) GetUserByEmail(email string) (*models.User, error) {
 }
 
 func (m *MockStore) GetUserByID(id string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if user, ok := m.Users[id]; ok {
 		return user, nil
 	}
 	return nil, ErrNotFound
 }
 
+func (m *MockStore) UpdateUserPassword(userID, passwordHash string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updateUserPassword(userID, passwordHash)
+}
+
+func (m *MockStore) updateUserPassword(userID, passwordHash string) (*models.User, error) {
+	user, ok := m.Users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.PasswordHash = passwordHash
+	user.TokenVersion++
+	return user, nil
+}
+
+// Password resets
+func (m *MockStore) CreatePasswordReset(reset *models.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if reset.ID == "" {
+		reset.ID = fmt.Sprintf("mock_reset_%d", len(m.PasswordResets)+1)
+	}
+	if reset.CreatedAt.IsZero() {
+		reset.CreatedAt = time.Now()
+	}
+	m.PasswordResets = append(m.PasswordResets, *reset)
+	return nil
+}
+
+func (m *MockStore) ResetPassword(tokenHash, passwordHash string, at time.Time) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.PasswordResets {
+		reset := &m.PasswordResets[i]
+		if reset.TokenHash != tokenHash {
+			continue
+		}
+		if reset.UsedAt != nil || !at.Before(reset.ExpiresAt) {
+			return nil, ErrResetClosed
+		}
+		for j := range m.PasswordResets {
+			if other := &m.PasswordResets[j]; other.UserID == reset.UserID && other.UsedAt == nil {
+				other.UsedAt = &at
+			}
+		}
+		return m.updateUserPassword(reset.UserID, passwordHash)
+	}
+	return nil, ErrNotFound
+}
+
+// Refresh tokens
+func (m *MockStore) CreateRefreshToken(token *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createRefreshToken(token)
+}
+
+func (m *MockStore) createRefreshToken(token *models.RefreshToken) error {
+	if token.ID == "" {
+		token.ID = fmt.Sprintf("mock_refresh_%d", len(m.RefreshTokens)+1)
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	m.RefreshTokens = append(m.RefreshTokens, *token)
+	return nil
+}
+
+func (m *MockStore) RotateRefreshToken(tokenHash string, next *models.RefreshToken, at time.Time) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current := m.refreshToken(tokenHash)
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	user, ok := m.Users[current.UserID]
+	if current.RevokedAt != nil || !at.Before(current.ExpiresAt) || !ok || user.TokenVersion != current.TokenVersion {
+		return nil, ErrRefreshTokenClosed
+	}
+	current.RevokedAt = &at
+	revoked := *current
+
+	next.UserID, next.TokenVersion = current.UserID, current.TokenVersion
+	if next.CreatedAt.IsZero() {
+		next.CreatedAt = at
+	}
+	m.createRefreshToken(next)
+	return &revoked, nil
+}
+
+func (m *MockStore) RevokeRefreshToken(tokenHash string, allSessions bool, at time.Time) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token := m.refreshToken(tokenHash)
+	if token == nil {
+		return nil, ErrNotFound
+	}
+	for i := range m.RefreshTokens {
+		other := &m.RefreshTokens[i]
+		if other.RevokedAt == nil && (other == token || allSessions && other.UserID == token.UserID) {
+			other.RevokedAt = &at
+		}
+	}
+	revoked := *token
+	return &revoked, nil
+}
+
+func (m *MockStore) PruneRefreshTokens(before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pruned int64
+	kept := m.RefreshTokens[:0]
+	for _, token := range m.RefreshTokens {
+		if !token.ExpiresAt.After(before) || token.RevokedAt != nil && !token.RevokedAt.After(before) {
+			pruned++
+			continue
+		}
+		kept = append(kept, token)
+	}
+	m.RefreshTokens = kept
+	return pruned, nil
+}
+
+// refreshToken finds a refresh token by hash; the pointer is into RefreshTokens
+func (m *MockStore) refreshToken(tokenHash string) *models.RefreshToken {
+	for i := range m.RefreshTokens {
+		if m.RefreshTokens[i].TokenHash == tokenHash {
+			return &m.RefreshTokens[i]
+		}
+	}
+	return nil
+}
+
 // Credential operations
 func (m *MockStore) CreateCredential(userID, serviceName, apiKey string) (*models.Credential, error) {
+	return m.CreateCredentialInEnvironment(userID, serviceName, apiKey, models.EnvironmentLive)
+}
+
+func (m *MockStore) CreateCredentialInEnvironment(userID, serviceName, apiKey, environment string) (*models.Credential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createCredential(userID, serviceName, apiKey, environment)
+}
+
+func (m *MockStore) createCredential(userID, serviceName, apiKey, environment string) (*models.Credential, error) {
+	id := "mock_cred_" + serviceName
+	if environment != models.EnvironmentLive {
+		id += "_" + environment
+	}
 	cred := &models.Credential{
-		ID:           "mock_cred_" + serviceName,
+		ID:           id,
 		UserID:       userID,
 		ServiceName:  serviceName,
 		EncryptedKey: "encrypted_" + apiKey, // Mock encryption
 		CreatedAt:    time.Now(),
+		TenantID:     m.userTenantID(userID),
+		KeyVersion:   1,
+		Environment:  environment,
 	}
 	m.Credentials[cred.ID] = cred
 	return cred, nil
 }
 
+func (m *MockStore) ReplaceCredential(userID, serviceName, apiKey string) (*models.Credential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, cred := range m.Credentials {
+		if cred.UserID == userID && cred.ServiceName == serviceName {
+			delete(m.Credentials, id)
+		}
+	}
+	return m.createCredential(userID, serviceName, apiKey, models.EnvironmentLive)
+}
+
+func (m *MockStore) UpdateCredential(credentialID, apiKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cred, ok := m.Credentials[credentialID]
+	if !ok {
+		return ErrNotFound
+	}
+	cred.EncryptedKey = "encrypted_" + apiKey // Mock encryption
+	return nil
+}
+
+func (m *MockStore) DeleteCredential(tenantID, credentialID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cred, ok := m.Credentials[credentialID]
+	if !ok || cred.TenantID != tenantID {
+		return ErrNotFound
+	}
+	delete(m.Credentials, credentialID)
+	return nil
+}
+
 func (m *MockStore) GetCredentialsByUserID(userID string) ([]models.Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var creds []models.Credential
 	for _, cred := range m.Credentials {
 		if cred.UserID == userID {
@@ -76,7 +373,21 @@ func (m *MockStore) GetCredentialsByUserID(userID string) ([]models.Credential,
 	return creds, nil
 }
 
+func (m *MockStore) GetCredentialsByTenantID(tenantID string) ([]models.Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var creds []models.Credential
+	for _, cred := range m.Credentials {
+		if cred.TenantID == tenantID {
+			creds = append(creds, *cred)
+		}
+	}
+	return creds, nil
+}
+
 func (m *MockStore) GetCredentialByUserAndService(userID, serviceName string) (*models.Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for _, cred := range m.Credentials {
 		if cred.UserID == userID && cred.ServiceName == serviceName {
 			// Mock decryption
@@ -87,11 +398,68 @@ func (m *MockStore) GetCredentialByUserAndService(userID, serviceName string) (*
 	return nil, ErrNotFound
 }
 
+func (m *MockStore) GetCredentialForTenant(tenantID, userID, serviceName string) (*models.Credential, error) {
+	return m.GetCredentialInEnvironment(tenantID, userID, serviceName, models.EnvironmentLive)
+}
+
+func (m *MockStore) GetCredentialInEnvironment(tenantID, userID, serviceName, environment string) (*models.Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var cred *models.Credential
+	for _, candidate := range m.Credentials {
+		if candidate.UserID == userID && candidate.ServiceName == serviceName &&
+			(cred == nil || candidate.Environment == environment) {
+			cred = candidate
+		}
+	}
+	if cred == nil {
+		return nil, ErrNotFound
+	}
+	cred.DecryptedKey = "mock_webhook_url" // Mock decryption
+	if cred.TenantID != tenantID {
+		return nil, fmt.Errorf("credential %s: %w", cred.ID, crypto.ErrTenantMismatch)
+	}
+	return cred, nil
+}
+
+func (m *MockStore) ReencryptCredentials() (int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return 0, 0, nil // Mock values aren't really encrypted
+}
+
+func (m *MockStore) RotateTenantKey(tenantID string) (int, int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	version := m.TenantKeys[tenantID]
+	if version == 0 {
+		version = 1
+	}
+	version++
+	m.TenantKeys[tenantID] = version
+
+	reencrypted := 0
+	for _, cred := range m.Credentials {
+		if cred.TenantID == tenantID {
+			cred.KeyVersion = version
+			reencrypted++
+		}
+	}
+	return version, reencrypted, 0, nil
+}
+
 // Workflow operations
 func (m *MockStore) CreateWorkflow(userID, name, triggerType, actionType, configJSON string) (*models.Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createWorkflow(userID, name, triggerType, actionType, configJSON)
+}
+
+func (m *MockStore) createWorkflow(userID, name, triggerType, actionType, configJSON string) (*models.Workflow, error) {
 	workflow := &models.Workflow{
 		ID:          "mock_wf_" + name,
 		UserID:      userID,
+		TenantID:    m.userTenantID(userID),
 		Name:        name,
 		TriggerType: triggerType,
 		ActionType:  actionType,
@@ -99,11 +467,29 @@ func (m *MockStore) CreateWorkflow(userID, name, triggerType, actionType, config
 		IsActive:    true,
 		CreatedAt:   time.Now(),
 	}
+	workflow.NextRunAt = workflow.NextRunAfter(nil)
 	m.Workflows[workflow.ID] = workflow
 	return workflow, nil
 }
 
+func (m *MockStore) CreateWorkflowWithChain(userID, name, triggerType, actionType, configJSON, actionChain string) (*models.Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	workflow, err := m.createWorkflow(userID, name, triggerType, actionType, configJSON)
+	if err != nil {
+		return nil, err
+	}
+	workflow.ActionChain = actionChain
+	return workflow, nil
+}
+
 func (m *MockStore) GetWorkflowsByUserID(userID string) ([]models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.workflowsByUserID(userID)
+}
+
+func (m *MockStore) workflowsByUserID(userID string) ([]models.Workflow, error) {
 	var workflows []models.Workflow
 	for _, wf := range m.Workflows {
 		if wf.UserID == userID {
@@ -113,14 +499,101 @@ func (m *MockStore) GetWorkflowsByUserID(userID string) ([]models.Workflow, erro
 	return workflows, nil
 }
 
+func (m *MockStore) GetWorkflowsByTenantID(tenantID string) ([]models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.workflowsByTenantID(tenantID)
+}
+
+func (m *MockStore) workflowsByTenantID(tenantID string) ([]models.Workflow, error) {
+	var workflows []models.Workflow
+	for _, wf := range m.Workflows {
+		if wf.TenantID == tenantID {
+			workflows = append(workflows, *wf)
+		}
+	}
+	return workflows, nil
+}
+
+func (m *MockStore) ListWorkflows(userID string, page models.PageRequest) ([]models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	workflows, _ := m.workflowsByUserID(userID)
+	return mockWorkflowPage(workflows, page), nil
+}
+
+func (m *MockStore) ListTenantWorkflows(tenantID string, page models.PageRequest) ([]models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	workflows, _ := m.workflowsByTenantID(tenantID)
+	return mockWorkflowPage(workflows, page), nil
+}
+
+// mockWorkflowPage sorts workflows newest first and returns the requested page
+func mockWorkflowPage(workflows []models.Workflow, page models.PageRequest) []models.Workflow {
+	sort.Slice(workflows, func(i, j int) bool {
+		return cursorAfter(workflows[i].CreatedAt, workflows[i].ID, workflows[j].CreatedAt, workflows[j].ID)
+	})
+
+	var pageWorkflows []models.Workflow
+	skipped := 0
+	for _, wf := range workflows {
+		if page.Cursor != nil && !cursorAfter(page.Cursor.Time, page.Cursor.ID, wf.CreatedAt, wf.ID) {
+			continue
+		}
+		if page.Cursor == nil && skipped < page.Offset {
+			skipped++
+			continue
+		}
+		pageWorkflows = append(pageWorkflows, wf)
+		if page.Limit > 0 && len(pageWorkflows) >= page.Limit {
+			break
+		}
+	}
+	return pageWorkflows
+}
+
+// cursorAfter reports whether (t1, id1) sorts after (t2, id2), matching the SQL row-value comparison
+func cursorAfter(t1 time.Time, id1 string, t2 time.Time, id2 string) bool {
+	if !t1.Equal(t2) {
+		return t1.After(t2)
+	}
+	return id1 > id2
+}
+
 func (m *MockStore) GetWorkflowByID(workflowID string) (*models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if wf, ok := m.Workflows[workflowID]; ok {
-		return wf, nil
+		copied := *wf // Runs read it while workers update the stored one
+		return &copied, nil
 	}
 	return nil, ErrNotFound
 }
 
+func (m *MockStore) UpdateWorkflow(workflow *models.Workflow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if wf, ok := m.Workflows[workflow.ID]; ok {
+		wf.Name = workflow.Name
+		wf.TriggerType = workflow.TriggerType
+		wf.ActionType = workflow.ActionType
+		wf.ConfigJSON = workflow.ConfigJSON
+		wf.ActionChain = workflow.ActionChain
+		wf.NextRunAt = nil
+		wf.NextRunAt = wf.NextRunAfter(wf.LastExecutedAt)
+		return nil
+	}
+	return ErrNotFound
+}
+
 func (m *MockStore) UpdateWorkflowActive(workflowID string, isActive bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updateWorkflowActive(workflowID, isActive)
+}
+
+func (m *MockStore) updateWorkflowActive(workflowID string, isActive bool) error {
 	if wf, ok := m.Workflows[workflowID]; ok {
 		wf.IsActive = isActive
 		return nil
@@ -128,43 +601,270 @@ func (m *MockStore) UpdateWorkflowActive(workflowID string, isActive bool) error
 	return ErrNotFound
 }
 
-func (m *MockStore) UpdateWorkflowLastExecuted(workflowID string, executedAt time.Time) error {
+func (m *MockStore) UpdateWorkflowLastExecuted(workflowID string, executedAt time.Time, nextRunAt *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if wf, ok := m.Workflows[workflowID]; ok {
 		wf.LastExecutedAt = &executedAt
+		wf.NextRunAt = nextRunAt
 		return nil
 	}
 	return ErrNotFound
 }
 
 func (m *MockStore) DeleteWorkflow(workflowID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.deleteWorkflow(workflowID)
+}
+
+func (m *MockStore) deleteWorkflow(workflowID string) error {
+	if _, ok := m.Workflows[workflowID]; !ok {
+		return ErrNotFound
+	}
 	delete(m.Workflows, workflowID)
 	return nil
 }
 
-func (m *MockStore) GetActiveScheduledWorkflows() ([]models.Workflow, error) {
+func (m *MockStore) SetWorkflowsActive(workflowIDs []string, isActive bool) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var missing []string
+	for _, workflowID := range workflowIDs {
+		if err := m.updateWorkflowActive(workflowID, isActive); err != nil {
+			missing = append(missing, workflowID)
+		}
+	}
+	return missing, nil
+}
+
+func (m *MockStore) DeleteWorkflows(workflowIDs []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var missing []string
+	for _, workflowID := range workflowIDs {
+		if err := m.deleteWorkflow(workflowID); err != nil {
+			missing = append(missing, workflowID)
+		}
+	}
+	return missing, nil
+}
+
+func (m *MockStore) GetDueWorkflows(now time.Time) ([]models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var workflows []models.Workflow
 	for _, wf := range m.Workflows {
-		if wf.TriggerType == "schedule" && wf.IsActive {
+		if wf.NextRunAt != nil && !wf.NextRunAt.After(now) && wf.IsActive && !wf.PausedAt(now) {
 			workflows = append(workflows, *wf)
 		}
 	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].NextRunAt.Before(*workflows[j].NextRunAt) })
 	return workflows, nil
 }
 
+func (m *MockStore) GetActiveWorkflow(workflowID string) (*models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if wf, ok := m.Workflows[workflowID]; ok && wf.IsActive {
+		copied := *wf // Runs read it while workers update the stored one
+		return &copied, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) RecordWorkflowExecution(workflowID, status string, duration time.Duration, errorMessage string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf, ok := m.Workflows[workflowID]
+	if !ok {
+		return ErrNotFound
+	}
+	if wf.Stats == nil {
+		wf.Stats = &models.WorkflowStats{}
+	}
+	stats := wf.Stats
+	if status == models.StatusSuppressed {
+		stats.SuppressedExecutions++
+		return nil
+	}
+	totalMS := stats.AvgDurationMS*float64(stats.TotalExecutions) + float64(duration.Milliseconds())
+	stats.TotalExecutions++
+	stats.AvgDurationMS = totalMS / float64(stats.TotalExecutions)
+	if status == "success" {
+		stats.ConsecutiveFailures = 0
+		// Rolling windows aren't tracked by the mock; every recorded success counts as recent
+		stats.Successes24h++
+		stats.Successes7d++
+	} else {
+		stats.ConsecutiveFailures++
+		if errorMessage != "" {
+			stats.LastError = statsErrorMessage(errorMessage)
+		}
+	}
+	return nil
+}
+
+func (m *MockStore) ClaimFailureAlert(workflowID string, threshold int, cooldown time.Duration, now time.Time) (int, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf, ok := m.Workflows[workflowID]
+	if !ok || wf.Stats == nil {
+		return 0, false, nil
+	}
+	failures := wf.Stats.ConsecutiveFailures
+	if failures < threshold {
+		return failures, false, nil
+	}
+	if last, alerted := m.FailureAlerts[workflowID]; alerted && now.Sub(last) < cooldown {
+		return failures, false, nil
+	}
+	m.FailureAlerts[workflowID] = now
+	return failures, true, nil
+}
+
+func (m *MockStore) ClearFailureAlert(workflowID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, alerted := m.FailureAlerts[workflowID]
+	delete(m.FailureAlerts, workflowID)
+	return alerted, nil
+}
+
+func (m *MockStore) GetWorkflowBaseline(workflowID string, since time.Time) (*models.WorkflowBaseline, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.Workflows[workflowID]; !ok {
+		return nil, ErrNotFound
+	}
+	return &models.WorkflowBaseline{}, nil // Baselines aren't tracked by the mock
+}
+
+func (m *MockStore) GetWorkflowRunStats(workflowID string, since time.Time) (*models.RunStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wf, ok := m.Workflows[workflowID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	stats := m.runStats(wf, since, true)
+	return &stats, nil
+}
+
+func (m *MockStore) GetRunStatsOverview(tenantID string, since time.Time) (*models.RunStatsOverview, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var workflows []*models.Workflow
+	for _, wf := range m.Workflows {
+		if wf.TenantID == tenantID {
+			workflows = append(workflows, wf)
+		}
+	}
+	sort.Slice(workflows, func(i, j int) bool {
+		if !workflows[i].CreatedAt.Equal(workflows[j].CreatedAt) {
+			return workflows[i].CreatedAt.Before(workflows[j].CreatedAt)
+		}
+		return workflows[i].ID < workflows[j].ID
+	})
+
+	overview := &models.RunStatsOverview{WorkflowCount: len(workflows), Workflows: []models.RunStats{}}
+	inTenant := make(map[string]bool, len(workflows))
+	for _, wf := range workflows {
+		inTenant[wf.ID] = true
+		stats := m.runStats(wf, since, false)
+		overview.Workflows = append(overview.Workflows, stats)
+		if stats.FailureStreak > 0 {
+			overview.FailingWorkflows++
+		}
+		if stats.FailureStreak > overview.FailureStreak {
+			overview.FailureStreak = stats.FailureStreak
+		}
+		if stats.LastExecutedAt != nil && (overview.LastExecutedAt == nil || stats.LastExecutedAt.After(*overview.LastExecutedAt)) {
+			overview.LastExecutedAt = stats.LastExecutedAt
+		}
+	}
+
+	streak, last := overview.FailureStreak, overview.LastExecutedAt
+	overview.RunStats = m.tallyRuns(since, func(log models.Log) bool { return inTenant[log.WorkflowID] })
+	overview.FailureStreak, overview.LastExecutedAt = streak, last
+	return overview, nil
+}
+
+// runStats aggregates one workflow's logs like Database.GetWorkflowRunStats
+// Medians and daily counts are only filled in with detail, as for the overview's workflows
+func (m *MockStore) runStats(wf *models.Workflow, since time.Time, detail bool) models.RunStats {
+	stats := m.tallyRuns(since, func(log models.Log) bool { return log.WorkflowID == wf.ID })
+	if !detail {
+		stats.MedianDurationMS, stats.Daily = nil, nil
+	}
+	stats.WorkflowID, stats.WorkflowName = wf.ID, wf.Name
+
+	// Latest run and the failures after the latest success, before the window too
+	var lastSuccess time.Time
+	for _, log := range m.Logs {
+		if log.WorkflowID != wf.ID {
+			continue
+		}
+		if stats.LastExecutedAt == nil || log.ExecutedAt.After(*stats.LastExecutedAt) {
+			executedAt := log.ExecutedAt
+			stats.LastExecutedAt = &executedAt
+		}
+		if log.Status == "success" && log.ExecutedAt.After(lastSuccess) {
+			lastSuccess = log.ExecutedAt
+		}
+	}
+	for _, log := range m.Logs {
+		if log.WorkflowID == wf.ID && log.Status != "success" && !heldBackStatuses[log.Status] && log.ExecutedAt.After(lastSuccess) {
+			stats.FailureStreak++
+		}
+	}
+	return stats
+}
+
+// tallyRuns counts the matching logs since a time, with their median duration and daily counts
+func (m *MockStore) tallyRuns(since time.Time, match func(models.Log) bool) models.RunStats {
+	tally := newRunTally(since)
+	counts := make(map[string]*models.StatDay)
+	var durations []int64
+	for _, log := range m.Logs {
+		if !match(log) || log.ExecutedAt.Before(since) {
+			continue
+		}
+		var durationSum, timed int64
+		if log.DurationMS != nil {
+			durationSum, timed = *log.DurationMS, 1
+			durations = append(durations, *log.DurationMS)
+		}
+		tally.add(log.Status, 1, durationSum, timed)
+		countDay(counts, log.ExecutedAt.UTC().Format("2006-01-02"), log.Status, 1)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	stats := tally.result()
+	stats.MedianDurationMS = medianOf(durations)
+	stats.Daily = statDays(since, time.Now(), counts)
+	return stats
+}
+
 // Log operations
 func (m *MockStore) CreateLog(workflowID, status, message string) error {
-	log := models.Log{
-		ID:         "mock_log_" + workflowID,
-		WorkflowID: workflowID,
-		Status:     status,
-		Message:    message,
-		ExecutedAt: time.Now(),
+	return m.CreateLogEntry(&models.Log{WorkflowID: workflowID, Status: status, Message: message})
+}
+
+func (m *MockStore) CreateLogEntry(log *models.Log) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log.ID = fmt.Sprintf("mock_log_%s_%d", log.WorkflowID, len(m.Logs))
+	if log.ExecutedAt.IsZero() {
+		log.ExecutedAt = time.Now()
 	}
-	m.Logs = append(m.Logs, log)
+	m.Logs = append(m.Logs, *log)
 	return nil
 }
 
 func (m *MockStore) GetLogsByUserID(userID string) ([]models.WorkflowLog, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var logs []models.WorkflowLog
 	for _, log := range m.Logs {
 		// Find workflow to get user_id
@@ -180,7 +880,13 @@ func (m *MockStore) GetLogsByUserID(userID string) ([]models.WorkflowLog, error)
 	return logs, nil
 }
 
+func (m *MockStore) GetLogsByTenantID(tenantID string) ([]models.WorkflowLog, error) {
+	return m.QueryLogs("", models.LogFilter{TenantID: tenantID, Limit: 100})
+}
+
 func (m *MockStore) GetLogsByWorkflowID(workflowID string) ([]models.Log, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	var logs []models.Log
 	for _, log := range m.Logs {
 		if log.WorkflowID == workflowID {
@@ -190,24 +896,1053 @@ func (m *MockStore) GetLogsByWorkflowID(workflowID string) ([]models.Log, error)
 	return logs, nil
 }
 
-// Lifecycle
-func (m *MockStore) Close() error {
-	// No-op for in-memory mock
-	return nil
+func (m *MockStore) QueryLogs(userID string, filter models.LogFilter) ([]models.WorkflowLog, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.queryLogs(userID, filter)
 }
 
-// Common errors
-var (
-	ErrNotFound = &StoreError{Code: "not_found", Message: "Resource not found"}
-)
+func (m *MockStore) queryLogs(userID string, filter models.LogFilter) ([]models.WorkflowLog, error) {
+	var logs []models.WorkflowLog
+	skipped := 0
+	for i := len(m.Logs) - 1; i >= 0; i-- { // Newest first
+		log := m.Logs[i]
+		wf, ok := m.Workflows[log.WorkflowID]
+		if !ok || !mockLogScope(wf, userID, filter) || !mockLogMatches(log, filter) {
+			continue
+		}
+		if filter.Cursor == nil && skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		logs = append(logs, models.WorkflowLog{Log: log, WorkflowName: wf.Name})
+		if filter.Limit > 0 && len(logs) >= filter.Limit {
+			break
+		}
+	}
+	return logs, nil
+}
+
+func (m *MockStore) GetLogsFiltered(userID string, filter models.LogFilter) ([]models.WorkflowLog, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	logs, _ := m.queryLogs(userID, filter)
+	filter.Cursor = nil
+	total := 0
+	for _, log := range m.Logs {
+		if wf, ok := m.Workflows[log.WorkflowID]; ok && mockLogScope(wf, userID, filter) && mockLogMatches(log, filter) {
+			total++
+		}
+	}
+	return logs, total, nil
+}
+
+func (m *MockStore) GetLogByID(logID string) (*models.Log, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := range m.Logs {
+		if m.Logs[i].ID == logID {
+			log := m.Logs[i]
+			return &log, nil
+		}
+	}
+	return nil, ErrNotFound
+}
 
-// StoreError represents a database error
-type StoreError struct {
-	Code    string
-	Message string
+func (m *MockStore) AcknowledgeLog(logID, acknowledgedBy string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.Logs {
+		if m.Logs[i].ID == logID {
+			if m.Logs[i].AcknowledgedAt == nil {
+				m.Logs[i].AcknowledgedBy = acknowledgedBy
+				m.Logs[i].AcknowledgedAt = &at
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
 }
 
-func (e *StoreError) Error() string {
-	return e.Message
+func (m *MockStore) AcknowledgeLogs(userID string, filter models.LogFilter, acknowledgedBy string, at time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	filter.Unacknowledged = true
+	var count int64
+	for i := range m.Logs {
+		wf, ok := m.Workflows[m.Logs[i].WorkflowID]
+		if !ok || !mockLogScope(wf, userID, filter) || !mockLogMatches(m.Logs[i], filter) {
+			continue
+		}
+		m.Logs[i].AcknowledgedBy = acknowledgedBy
+		m.Logs[i].AcknowledgedAt = &at
+		count++
+	}
+	return count, nil
 }
 
+func (m *MockStore) AutoAcknowledgeLogs(olderThan time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var count int64
+	for i := range m.Logs {
+		if m.Logs[i].AcknowledgedAt == nil && m.Logs[i].ExecutedAt.Before(olderThan) {
+			m.Logs[i].AcknowledgedBy = models.AutoAcknowledgedBy
+			m.Logs[i].AcknowledgedAt = &now
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteWorkflowLogs deletes up to limit of a workflow's log entries, oldest first
+func (m *MockStore) DeleteWorkflowLogs(workflowID string, limit int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matching []time.Time
+	for _, log := range m.Logs {
+		if log.WorkflowID == workflowID {
+			matching = append(matching, log.ExecutedAt)
+		}
+	}
+	if len(matching) > limit {
+		sort.Slice(matching, func(i, j int) bool { return matching[i].Before(matching[j]) })
+		matching = matching[:limit]
+	}
+	cutoff := len(matching)
+
+	var deleted int64
+	kept := m.Logs[:0]
+	for _, log := range m.Logs {
+		if log.WorkflowID == workflowID && int(deleted) < cutoff && !log.ExecutedAt.After(matching[cutoff-1]) {
+			deleted++
+			continue
+		}
+		kept = append(kept, log)
+	}
+	m.Logs = kept
+	return deleted, nil
+}
+
+// mockLogScope reports whether a workflow's logs are visible to the user, or to the filter's tenant
+func mockLogScope(wf *models.Workflow, userID string, filter models.LogFilter) bool {
+	if filter.TenantID != "" {
+		return wf.TenantID == filter.TenantID
+	}
+	return wf.UserID == userID
+}
+
+// mockLogMatches applies a LogFilter to a single log entry
+func mockLogMatches(log models.Log, filter models.LogFilter) bool {
+	if filter.WorkflowID != "" && log.WorkflowID != filter.WorkflowID {
+		return false
+	}
+	if filter.Status != "" && log.Status != filter.Status {
+		return false
+	}
+	if filter.Unacknowledged && log.AcknowledgedAt != nil {
+		return false
+	}
+	if filter.From != nil && log.ExecutedAt.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && log.ExecutedAt.After(*filter.To) {
+		return false
+	}
+	if filter.Cursor != nil && !cursorAfter(filter.Cursor.Time, filter.Cursor.ID, log.ExecutedAt, log.ID) {
+		return false
+	}
+	return true
+}
+
+// Execution operations
+func (m *MockStore) CreateExecution(execution *models.Execution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if execution.ID == "" {
+		execution.ID = fmt.Sprintf("mock_exec_%s_%d", execution.WorkflowID, len(m.Executions))
+	}
+	if execution.ExecutedAt.IsZero() {
+		execution.ExecutedAt = time.Now()
+	}
+	m.Executions = append(m.Executions, *execution)
+	return nil
+}
+
+func (m *MockStore) GetLatestExecution(workflowID string) (*models.Execution, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.Executions) - 1; i >= 0; i-- {
+		if m.Executions[i].WorkflowID == workflowID && m.Executions[i].TriggerSource != "dry-run" {
+			execution := m.Executions[i]
+			return &execution, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) GetExecutionByID(executionID string) (*models.Execution, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, execution := range m.Executions {
+		if execution.ID == executionID {
+			return &execution, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) ListExecutions(workflowID string, page models.PageRequest) ([]models.Execution, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var executions []models.Execution
+	for _, execution := range m.Executions {
+		if execution.WorkflowID == workflowID {
+			executions = append(executions, execution)
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		return cursorAfter(executions[i].ExecutedAt, executions[i].ID, executions[j].ExecutedAt, executions[j].ID)
+	})
+
+	var pageExecutions []models.Execution
+	skipped := 0
+	for _, execution := range executions {
+		if page.Cursor != nil && !cursorAfter(page.Cursor.Time, page.Cursor.ID, execution.ExecutedAt, execution.ID) {
+			continue
+		}
+		if page.Cursor == nil && skipped < page.Offset {
+			skipped++
+			continue
+		}
+		pageExecutions = append(pageExecutions, execution)
+		if page.Limit > 0 && len(pageExecutions) >= page.Limit {
+			break
+		}
+	}
+	return pageExecutions, nil
+}
+
+func (m *MockStore) EachExecution(userID string, since time.Time, fn func(*models.Execution) error) error {
+	// fn runs without the lock held so it may call back into the store
+	m.mu.RLock()
+	var executions []models.Execution
+	for _, execution := range m.Executions {
+		wf, ok := m.Workflows[execution.WorkflowID]
+		if !ok || wf.UserID != userID || execution.ExecutedAt.Before(since) {
+			continue
+		}
+		executions = append(executions, execution)
+	}
+	m.mu.RUnlock()
+
+	for i := range executions {
+		if err := fn(&executions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Webhook request capture
+func (m *MockStore) SetWorkflowDebugRequests(workflowID string, until *time.Time, limit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf, ok := m.Workflows[workflowID]
+	if !ok {
+		return ErrNotFound
+	}
+	wf.DebugRequestsUntil = until
+	wf.DebugRequestsLimit = limit
+	return nil
+}
+
+func (m *MockStore) SetWorkflowPausedUntil(workflowID string, until *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf, ok := m.Workflows[workflowID]
+	if !ok {
+		return ErrNotFound
+	}
+	wf.PausedUntil = until
+	return nil
+}
+
+func (m *MockStore) CaptureWebhookRequest(request *models.WebhookRequest, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if request.ID == "" {
+		request.ID = fmt.Sprintf("mock_webhook_request_%d", len(m.WebhookRequests)+1)
+	}
+	if request.ReceivedAt.IsZero() {
+		request.ReceivedAt = time.Now()
+	}
+	m.WebhookRequests = append(m.WebhookRequests, *request)
+
+	// Drop the workflow's oldest beyond keep
+	count := 0
+	for i := len(m.WebhookRequests) - 1; i >= 0; i-- {
+		if m.WebhookRequests[i].WorkflowID != request.WorkflowID {
+			continue
+		}
+		count++
+		if count > keep {
+			m.WebhookRequests = append(m.WebhookRequests[:i], m.WebhookRequests[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (m *MockStore) ListWebhookRequests(workflowID string) ([]models.WebhookRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var requests []models.WebhookRequest
+	for i := len(m.WebhookRequests) - 1; i >= 0; i-- {
+		if m.WebhookRequests[i].WorkflowID == workflowID {
+			requests = append(requests, m.WebhookRequests[i])
+		}
+	}
+	return requests, nil
+}
+
+func (m *MockStore) GetWebhookRequest(workflowID, requestID string) (*models.WebhookRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, request := range m.WebhookRequests {
+		if request.WorkflowID == workflowID && request.ID == requestID {
+			return &request, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) SaveWebhookPayload(payload *models.WebhookPayload, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if payload.ID == "" {
+		payload.ID = fmt.Sprintf("mock_webhook_payload_%d", len(m.WebhookPayloads)+1)
+	}
+	if payload.ReceivedAt.IsZero() {
+		payload.ReceivedAt = time.Now()
+	}
+	m.WebhookPayloads = append(m.WebhookPayloads, *payload)
+
+	// Drop the workflow's oldest beyond keep
+	count := 0
+	for i := len(m.WebhookPayloads) - 1; i >= 0; i-- {
+		if m.WebhookPayloads[i].WorkflowID != payload.WorkflowID {
+			continue
+		}
+		count++
+		if count > keep {
+			m.WebhookPayloads = append(m.WebhookPayloads[:i], m.WebhookPayloads[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (m *MockStore) ListWebhookPayloads(workflowID string) ([]models.WebhookPayload, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var payloads []models.WebhookPayload
+	for i := len(m.WebhookPayloads) - 1; i >= 0; i-- {
+		if m.WebhookPayloads[i].WorkflowID == workflowID {
+			payloads = append(payloads, m.WebhookPayloads[i])
+		}
+	}
+	return payloads, nil
+}
+
+// Workflow fixtures
+func (m *MockStore) CreateWorkflowFixture(fixture *models.WorkflowFixture, limit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, existing := range m.Fixtures {
+		if existing.WorkflowID != fixture.WorkflowID {
+			continue
+		}
+		if existing.Name == fixture.Name {
+			return ErrFixtureExists
+		}
+		count++
+	}
+	if count >= limit {
+		return ErrFixtureLimit
+	}
+	if fixture.ID == "" {
+		fixture.ID = fmt.Sprintf("mock_fixture_%d", len(m.Fixtures))
+	}
+	if fixture.CreatedAt.IsZero() {
+		fixture.CreatedAt = time.Now()
+	}
+	m.Fixtures = append(m.Fixtures, *fixture)
+	return nil
+}
+
+func (m *MockStore) ListWorkflowFixtures(workflowID string) ([]models.WorkflowFixture, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	fixtures := []models.WorkflowFixture{}
+	for _, fixture := range m.Fixtures {
+		if fixture.WorkflowID == workflowID {
+			fixtures = append(fixtures, fixture)
+		}
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
+
+func (m *MockStore) GetWorkflowFixture(workflowID, name string) (*models.WorkflowFixture, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, fixture := range m.Fixtures {
+		if fixture.WorkflowID == workflowID && fixture.Name == name {
+			return &fixture, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) DeleteWorkflowFixture(workflowID, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, fixture := range m.Fixtures {
+		if fixture.WorkflowID == workflowID && fixture.Name == name {
+			m.Fixtures = append(m.Fixtures[:i], m.Fixtures[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Dead letters
+func (m *MockStore) CreateDeadLetter(workflowID, reason, payload string) (*models.DeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	letter := models.DeadLetter{
+		ID:         fmt.Sprintf("mock_dead_letter_%d", len(m.DeadLetters)+1),
+		WorkflowID: workflowID,
+		Reason:     reason,
+		Payload:    payload,
+		CreatedAt:  time.Now(),
+	}
+	m.DeadLetters = append(m.DeadLetters, letter)
+	return &letter, nil
+}
+
+func (m *MockStore) ListDeadLetters(userID string) ([]models.DeadLetter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var letters []models.DeadLetter
+	for i := len(m.DeadLetters) - 1; i >= 0; i-- {
+		if wf, ok := m.Workflows[m.DeadLetters[i].WorkflowID]; ok && wf.UserID == userID {
+			letters = append(letters, m.DeadLetters[i])
+		}
+	}
+	return letters, nil
+}
+
+func (m *MockStore) GetDeadLetter(id string) (*models.DeadLetter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, letter := range m.DeadLetters {
+		if letter.ID == id {
+			return &letter, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) ResolveDeadLetter(id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.DeadLetters {
+		if m.DeadLetters[i].ID != id {
+			continue
+		}
+		if m.DeadLetters[i].ResolvedAt != nil {
+			return ErrDeadLetterResolved
+		}
+		m.DeadLetters[i].ResolvedAt = &at
+		return nil
+	}
+	return ErrNotFound
+}
+
+// Webhook event dedupe
+func (m *MockStore) MarkWebhookEventSeen(workflowID, eventID string, receivedAt, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := workflowID + "|" + eventID
+	if expiry, ok := m.WebhookEvents[key]; ok && expiry.After(receivedAt) {
+		return false, nil
+	}
+	m.WebhookEvents[key] = expiresAt
+	return true, nil
+}
+
+func (m *MockStore) ForgetWebhookEvent(workflowID, eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.WebhookEvents, workflowID+"|"+eventID)
+	return nil
+}
+
+func (m *MockStore) PruneWebhookEvents(before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pruned int64
+	for key, expiry := range m.WebhookEvents {
+		if !expiry.After(before) {
+			delete(m.WebhookEvents, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// Runtime config audit
+func (m *MockStore) RecordConfigChange(change *models.ConfigChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if change.ID == "" {
+		change.ID = fmt.Sprintf("mock_config_change_%d", len(m.ConfigChanges)+1)
+	}
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now()
+	}
+	m.ConfigChanges = append(m.ConfigChanges, *change)
+	return nil
+}
+
+func (m *MockStore) ListConfigChanges(limit int) ([]models.ConfigChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var changes []models.ConfigChange
+	for i := len(m.ConfigChanges) - 1; i >= 0 && len(changes) < limit; i-- {
+		changes = append(changes, m.ConfigChanges[i])
+	}
+	return changes, nil
+}
+
+// Impersonation sessions and audit trail
+func (m *MockStore) CreateImpersonationSession(session *models.ImpersonationSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session.ID == "" {
+		session.ID = fmt.Sprintf("mock_impersonation_%d", len(m.Impersonations)+1)
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	stored := *session
+	m.Impersonations[session.ID] = &stored
+	return nil
+}
+
+func (m *MockStore) GetImpersonationSession(sessionID string) (*models.ImpersonationSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.Impersonations[sessionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *MockStore) ListActiveImpersonationSessions(now time.Time) ([]models.ImpersonationSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := []models.ImpersonationSession{}
+	for _, session := range m.Impersonations {
+		if session.Active(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (m *MockStore) RevokeImpersonationSession(sessionID, revokedBy string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.Impersonations[sessionID]
+	if !ok || session.RevokedAt != nil {
+		return ErrNotFound
+	}
+	session.RevokedAt = &at
+	session.RevokedBy = revokedBy
+	return nil
+}
+
+func (m *MockStore) RecordAuditEvent(event *models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if event.ID == "" {
+		event.ID = fmt.Sprintf("mock_audit_%d", len(m.AuditEvents)+1)
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	m.AuditEvents = append(m.AuditEvents, *event)
+	return nil
+}
+
+func (m *MockStore) ListAuditEvents(limit int) ([]models.AuditEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var events []models.AuditEvent
+	for i := len(m.AuditEvents) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, m.AuditEvents[i])
+	}
+	return events, nil
+}
+
+func (m *MockStore) ListTenantAuditEvents(tenantID string, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var events []models.AuditEvent
+	for i := len(m.AuditEvents) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		event := m.AuditEvents[i]
+		if event.TenantID != tenantID || (filter.ResourceType != "" && event.ResourceType != filter.ResourceType) ||
+			(!filter.Since.IsZero() && event.CreatedAt.Before(filter.Since)) ||
+			(!filter.Until.IsZero() && !event.CreatedAt.Before(filter.Until)) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (m *MockStore) EachAuditEvent(actorID string, fn func(*models.AuditEvent) error) error {
+	m.mu.RLock()
+	var events []models.AuditEvent
+	for _, event := range m.AuditEvents {
+		if event.ActorID == actorID {
+			events = append(events, event)
+		}
+	}
+	m.mu.RUnlock()
+
+	for i := range events {
+		if err := fn(&events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage operations
+func (m *MockStore) RecordWorkflowCost(tenantID, workflowID, month string, cost float64, delayMS int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := tenantID + "|" + workflowID + "|" + month
+	entry, ok := m.Usage[key]
+	if !ok {
+		entry = &models.WorkflowCost{WorkflowID: workflowID, Month: month}
+		m.Usage[key] = entry
+	}
+	entry.Executions++
+	entry.Cost += cost
+	entry.DelayMS += delayMS
+	return nil
+}
+
+func (m *MockStore) GetWorkflowCosts(tenantID, month string) ([]models.WorkflowCost, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var costs []models.WorkflowCost
+	for key, entry := range m.Usage {
+		if strings.HasPrefix(key, tenantID+"|") && entry.Month == month {
+			c := *entry
+			if wf, ok := m.Workflows[c.WorkflowID]; ok {
+				c.WorkflowName = wf.Name
+			}
+			costs = append(costs, c)
+		}
+	}
+	return costs, nil
+}
+
+func (m *MockStore) EachWorkflowCost(tenantID string, fn func(*models.WorkflowCost) error) error {
+	m.mu.RLock()
+	var costs []models.WorkflowCost
+	for key, entry := range m.Usage {
+		if strings.HasPrefix(key, tenantID+"|") {
+			c := *entry
+			if wf, ok := m.Workflows[c.WorkflowID]; ok {
+				c.WorkflowName = wf.Name
+			}
+			costs = append(costs, c)
+		}
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if costs[i].Month != costs[j].Month {
+			return costs[i].Month < costs[j].Month
+		}
+		return costs[i].WorkflowID < costs[j].WorkflowID
+	})
+	m.mu.RUnlock()
+
+	for i := range costs {
+		if err := fn(&costs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tenant settings and data retention
+func (m *MockStore) GetTenantSettings(tenantID string) (*models.TenantSettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if settings, ok := m.TenantSettings[tenantID]; ok {
+		copied := *settings
+		return &copied, nil
+	}
+	return &models.TenantSettings{TenantID: tenantID, Tier: models.TierFree}, nil
+}
+
+func (m *MockStore) SaveTenantSettings(settings *models.TenantSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if settings.Tier == "" {
+		settings.Tier = models.TierFree
+	}
+	settings.UpdatedAt = time.Now()
+	saved := *settings
+	if existing, ok := m.TenantSettings[settings.TenantID]; ok {
+		saved.LastPurge = existing.LastPurge
+	} else {
+		saved.LastPurge = nil
+	}
+	m.TenantSettings[settings.TenantID] = &saved
+	return nil
+}
+
+func (m *MockStore) RecordRetentionRun(run *models.RetentionRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	settings, ok := m.TenantSettings[run.TenantID]
+	if !ok {
+		settings = &models.TenantSettings{TenantID: run.TenantID, Tier: models.TierFree, UpdatedAt: time.Now()}
+		m.TenantSettings[run.TenantID] = settings
+	}
+	recorded := *run
+	settings.LastPurge = &recorded
+	return nil
+}
+
+func (m *MockStore) GetTenantLimits(tenantID string) (*models.TenantLimits, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	limits := m.TenantLimits[tenantID]
+	limits.TenantID, limits.Tier = tenantID, models.TierFree
+	if settings, ok := m.TenantSettings[tenantID]; ok {
+		limits.Tier = settings.Tier
+	}
+	return &limits, nil
+}
+
+func (m *MockStore) SetTenantLimits(limits *models.TenantLimits) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limits.Tier == "" {
+		limits.Tier = models.TierFree
+	}
+	settings, ok := m.TenantSettings[limits.TenantID]
+	if !ok {
+		settings = &models.TenantSettings{TenantID: limits.TenantID}
+		m.TenantSettings[limits.TenantID] = settings
+	}
+	settings.Tier = limits.Tier
+	settings.UpdatedAt = time.Now()
+	m.TenantLimits[limits.TenantID] = *limits
+	return nil
+}
+
+func (m *MockStore) ListTenantIDs() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tenantIDs []string
+	for id := range m.Tenants {
+		tenantIDs = append(tenantIDs, id)
+	}
+	sort.Strings(tenantIDs)
+	return tenantIDs, nil
+}
+
+func (m *MockStore) PurgeTenantData(tenantID, dataClass string, before time.Time, limit int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	owned := func(workflowID string) bool {
+		wf, ok := m.Workflows[workflowID]
+		return ok && wf.TenantID == tenantID
+	}
+
+	var removed int64
+	switch dataClass {
+	case models.RetentionLogs:
+		kept := m.Logs[:0]
+		for _, log := range m.Logs {
+			if removed < int64(limit) && owned(log.WorkflowID) && log.ExecutedAt.Before(before) {
+				removed++
+				continue
+			}
+			kept = append(kept, log)
+		}
+		m.Logs = kept
+	case models.RetentionPayloads:
+		for i := range m.Executions {
+			e := &m.Executions[i]
+			if removed < int64(limit) && owned(e.WorkflowID) && e.ExecutedAt.Before(before) && (e.TriggerPayload != "" || e.ResultData != "") {
+				e.TriggerPayload, e.ResultData = "", ""
+				removed++
+			}
+		}
+	case models.RetentionExecutions:
+		kept := m.Executions[:0]
+		for _, e := range m.Executions {
+			if removed < int64(limit) && owned(e.WorkflowID) && e.ExecutedAt.Before(before) {
+				removed++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		m.Executions = kept
+	case models.RetentionDebugRequests:
+		kept := m.WebhookRequests[:0]
+		for _, r := range m.WebhookRequests {
+			if removed < int64(limit) && owned(r.WorkflowID) && r.ReceivedAt.Before(before) {
+				removed++
+				continue
+			}
+			kept = append(kept, r)
+		}
+		m.WebhookRequests = kept
+	case models.RetentionFixtures:
+		kept := m.Fixtures[:0]
+		for _, f := range m.Fixtures {
+			if removed < int64(limit) && owned(f.WorkflowID) && f.CreatedAt.Before(before) {
+				removed++
+				continue
+			}
+			kept = append(kept, f)
+		}
+		m.Fixtures = kept
+	default:
+		return 0, fmt.Errorf("unknown data class %q", dataClass)
+	}
+	return removed, nil
+}
+
+// Tenant webhook domains
+func (m *MockStore) CreateTenantDomain(domain *models.TenantDomain) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.TenantDomains {
+		if existing.Domain == domain.Domain {
+			return ErrDomainTaken
+		}
+	}
+	if domain.ID == "" {
+		domain.ID = fmt.Sprintf("mock_domain_%d", len(m.TenantDomains))
+	}
+	if domain.CreatedAt.IsZero() {
+		domain.CreatedAt = time.Now()
+	}
+	m.TenantDomains = append(m.TenantDomains, *domain)
+	return nil
+}
+
+func (m *MockStore) ListTenantDomains(tenantID string) ([]models.TenantDomain, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	domains := []models.TenantDomain{}
+	for _, domain := range m.TenantDomains {
+		if domain.TenantID == tenantID {
+			domains = append(domains, domain)
+		}
+	}
+	return domains, nil
+}
+
+func (m *MockStore) GetTenantDomain(tenantID, domainID string) (*models.TenantDomain, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, domain := range m.TenantDomains {
+		if domain.ID == domainID && domain.TenantID == tenantID {
+			found := domain
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) GetTenantDomainByName(name string) (*models.TenantDomain, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, domain := range m.TenantDomains {
+		if domain.Domain == name {
+			found := domain
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) UpdateTenantDomain(domain *models.TenantDomain) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.TenantDomains {
+		if existing.ID == domain.ID && existing.TenantID == domain.TenantID {
+			m.TenantDomains[i].Status = domain.Status
+			m.TenantDomains[i].KongServiceID = domain.KongServiceID
+			m.TenantDomains[i].KongRouteID = domain.KongRouteID
+			m.TenantDomains[i].LastError = domain.LastError
+			m.TenantDomains[i].CheckedAt = domain.CheckedAt
+			m.TenantDomains[i].VerifiedAt = domain.VerifiedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MockStore) DeleteTenantDomain(tenantID, domainID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, domain := range m.TenantDomains {
+		if domain.ID == domainID && domain.TenantID == tenantID {
+			m.TenantDomains = append(m.TenantDomains[:i], m.TenantDomains[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Kong resources
+func (m *MockStore) CreateKongResource(resource *models.KongResource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if resource.ID == "" {
+		resource.ID = fmt.Sprintf("mock_kong_%d", len(m.KongResources))
+	}
+	if resource.CreatedAt.IsZero() {
+		resource.CreatedAt = time.Now()
+	}
+	m.KongResources = append(m.KongResources, *resource)
+	return nil
+}
+
+func (m *MockStore) ListKongResources(tenantID, workflowID string) ([]models.KongResource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	resources := []models.KongResource{}
+	for _, resource := range m.KongResources {
+		if resource.TenantID == tenantID && (workflowID == "" || resource.WorkflowID == workflowID) {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (m *MockStore) DeleteKongResource(tenantID, resourceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, resource := range m.KongResources {
+		if resource.ID == resourceID && resource.TenantID == tenantID {
+			m.KongResources = append(m.KongResources[:i], m.KongResources[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Tenant invites
+func (m *MockStore) CreateTenantInvite(invite *models.TenantInvite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if invite.ID == "" {
+		invite.ID = fmt.Sprintf("mock_invite_%d", len(m.TenantInvites)+1)
+	}
+	if invite.CreatedAt.IsZero() {
+		invite.CreatedAt = time.Now()
+	}
+	invite.Status = models.InvitePending
+	m.TenantInvites = append(m.TenantInvites, *invite)
+	return nil
+}
+
+func (m *MockStore) ListTenantInvites(tenantID string) ([]models.TenantInvite, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	invites := []models.TenantInvite{}
+	for i := len(m.TenantInvites) - 1; i >= 0; i-- {
+		if m.TenantInvites[i].TenantID == tenantID {
+			invites = append(invites, m.TenantInvites[i])
+		}
+	}
+	return invites, nil
+}
+
+func (m *MockStore) GetTenantInvite(inviteID string) (*models.TenantInvite, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, invite := range m.TenantInvites {
+		if invite.ID == inviteID {
+			found := invite
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockStore) RevokeTenantInvite(tenantID, inviteID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.TenantInvites {
+		invite := &m.TenantInvites[i]
+		if invite.ID != inviteID || invite.TenantID != tenantID {
+			continue
+		}
+		if invite.Status != models.InvitePending {
+			return ErrInviteClosed
+		}
+		invite.Status = models.InviteRevoked
+		invite.RevokedAt = &at
+		return nil
+	}
+	return ErrNotFound
+}
+
+func (m *MockStore) AcceptTenantInvite(inviteID, passwordHash string, at time.Time) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.TenantInvites {
+		invite := &m.TenantInvites[i]
+		if invite.ID != inviteID {
+			continue
+		}
+		if invite.Status != models.InvitePending || !at.Before(invite.ExpiresAt) {
+			return nil, ErrInviteClosed
+		}
+		user, err := m.newUser(invite.TenantID, models.RoleMember, invite.Email, passwordHash)
+		if err != nil {
+			return nil, err
+		}
+		invite.Status = models.InviteAccepted
+		invite.AcceptedAt = &at
+		invite.AcceptedBy = user.ID
+		return user, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Lifecycle
+func (m *MockStore) Close() error {
+	// No-op for in-memory mock
+	return nil
+}
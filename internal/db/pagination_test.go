@@ -0,0 +1,121 @@
+package db_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// roundTripCursor passes a cursor through the opaque string clients see
+func roundTripCursor(t *testing.T, ts time.Time, id string) *models.Cursor {
+	t.Helper()
+	cursor, err := handlers.DecodeCursor(handlers.EncodeCursor(ts, id))
+	if err != nil {
+		t.Fatalf("Failed to decode cursor: %v", err)
+	}
+	return cursor
+}
+
+// TestLogCursorPaginationWithConcurrentInserts proves that logs written between
+// page fetches neither shift rows onto the next page nor repeat earlier ones
+func TestLogCursorPaginationWithConcurrentInserts(t *testing.T) {
+	database := dbtest.New(t)
+
+	user, err := database.CreateUser("pages@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	workflow, err := database.CreateWorkflow(user.ID, "Paged", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	const existing = 25
+	for i := 0; i < existing; i++ {
+		if err := database.CreateLog(workflow.ID, "success", fmt.Sprintf("log %d", i)); err != nil {
+			t.Fatalf("Failed to create log: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var cursor *models.Cursor
+	for page := 0; ; page++ {
+		logs, err := database.QueryLogs(user.ID, models.LogFilter{Limit: 10, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("QueryLogs failed: %v", err)
+		}
+		if len(logs) == 0 {
+			break
+		}
+		for _, log := range logs {
+			if seen[log.ID] {
+				t.Fatalf("Log %s returned twice", log.ID)
+			}
+			seen[log.ID] = true
+		}
+
+		// New activity between fetches lands before the first page, not in the middle
+		for i := 0; i < 3; i++ {
+			if err := database.CreateLog(workflow.ID, "success", fmt.Sprintf("new log %d.%d", page, i)); err != nil {
+				t.Fatalf("Failed to create log: %v", err)
+			}
+		}
+
+		last := logs[len(logs)-1]
+		cursor = roundTripCursor(t, last.ExecutedAt, last.ID)
+	}
+
+	if len(seen) != existing {
+		t.Errorf("Expected %d logs across pages, got %d", existing, len(seen))
+	}
+}
+
+// TestWorkflowCursorPaginationWithConcurrentInserts is the same guarantee for workflows
+func TestWorkflowCursorPaginationWithConcurrentInserts(t *testing.T) {
+	database := dbtest.New(t)
+
+	user, err := database.CreateUser("workflow-pages@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	const existing = 12
+	for i := 0; i < existing; i++ {
+		if _, err := database.CreateWorkflow(user.ID, fmt.Sprintf("Workflow %d", i), "webhook", "testing", `{}`); err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var cursor *models.Cursor
+	for page := 0; ; page++ {
+		workflows, err := database.ListWorkflows(user.ID, models.PageRequest{Limit: 5, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListWorkflows failed: %v", err)
+		}
+		if len(workflows) == 0 {
+			break
+		}
+		for _, workflow := range workflows {
+			if seen[workflow.ID] {
+				t.Fatalf("Workflow %s returned twice", workflow.ID)
+			}
+			seen[workflow.ID] = true
+		}
+
+		if _, err := database.CreateWorkflow(user.ID, fmt.Sprintf("New workflow %d", page), "webhook", "testing", `{}`); err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+
+		last := workflows[len(workflows)-1]
+		cursor = roundTripCursor(t, last.CreatedAt, last.ID)
+	}
+
+	if len(seen) != existing {
+		t.Errorf("Expected %d workflows across pages, got %d", existing, len(seen))
+	}
+}
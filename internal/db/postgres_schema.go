@@ -0,0 +1,307 @@
+package db
+
+// postgresSchema is schema.sql translated for PostgreSQL; keep the two in step
+// Differences: TIMESTAMPTZ for DATETIME, BOOLEAN for flags, DOUBLE PRECISION for REAL,
+// BIGINT for millisecond totals, and a rowid BIGSERIAL column on the tables whose
+// queries page or order by SQLite's implicit rowid
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    email TEXT UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    tenant_id TEXT REFERENCES tenants(id),
+    role TEXT,
+    token_version INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS credentials (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_name TEXT NOT NULL,
+    encrypted_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    tenant_id TEXT,
+    key_version INTEGER NOT NULL DEFAULT 0,
+    environment TEXT NOT NULL DEFAULT 'live'
+);
+
+CREATE TABLE IF NOT EXISTS workflows (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    trigger_type TEXT NOT NULL,
+    action_type TEXT NOT NULL,
+    config_json TEXT NOT NULL,
+    action_chain TEXT,
+    parameters TEXT,
+    tenant_id TEXT,
+    is_active BOOLEAN DEFAULT TRUE,
+    last_executed_at TIMESTAMPTZ,
+    next_run_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    debug_requests_until TIMESTAMPTZ,
+    debug_requests_limit INTEGER,
+    paused_until TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS logs (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    message TEXT,
+    executed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_by TEXT,
+    acknowledged_at TIMESTAMPTZ,
+    error_code TEXT,
+    error_params TEXT,
+    request_id TEXT,
+    duration_ms BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS usage (
+    tenant_id TEXT NOT NULL,
+    workflow_id TEXT NOT NULL,
+    month TEXT NOT NULL,
+    executions INTEGER NOT NULL DEFAULT 0,
+    cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+    delay_ms BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, workflow_id, month)
+);
+
+CREATE TABLE IF NOT EXISTS workflow_stats (
+    workflow_id TEXT PRIMARY KEY REFERENCES workflows(id) ON DELETE CASCADE,
+    total_executions BIGINT NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    timed_executions BIGINT NOT NULL DEFAULT 0,
+    total_duration_ms BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    suppressed_executions BIGINT NOT NULL DEFAULT 0,
+    recent_duration_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    baseline_duration_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    failure_alerted_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workflow_stat_buckets (
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    hour TEXT NOT NULL,
+    successes INTEGER NOT NULL DEFAULT 0,
+    failures INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (workflow_id, hour)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_events (
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (workflow_id, event_id)
+);
+
+CREATE TABLE IF NOT EXISTS executions (
+    id TEXT PRIMARY KEY,
+    rowid BIGSERIAL,
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    message TEXT,
+    trigger_source TEXT NOT NULL,
+    trigger_payload TEXT,
+    result_data TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    executed_at TIMESTAMPTZ NOT NULL,
+    version TEXT,
+    instance_id TEXT,
+    catch_up BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS config_changes (
+    id TEXT PRIMARY KEY,
+    actor TEXT NOT NULL,
+    source TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS workflow_fixtures (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    source TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (workflow_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+    id TEXT PRIMARY KEY,
+    admin_id TEXT NOT NULL,
+    target_user_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    revoked_by TEXT
+);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id TEXT PRIMARY KEY,
+    rowid BIGSERIAL,
+    actor_id TEXT NOT NULL,
+    impersonator_id TEXT,
+    session_id TEXT,
+    action TEXT NOT NULL,
+    method TEXT,
+    path TEXT,
+    status_code INTEGER,
+    detail TEXT,
+    tenant_id TEXT,
+    resource_type TEXT,
+    resource_id TEXT,
+    source_ip TEXT,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tenant_keys (
+    tenant_id TEXT PRIMARY KEY,
+    version INTEGER NOT NULL,
+    rotated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id TEXT PRIMARY KEY,
+    tier TEXT NOT NULL DEFAULT 'free',
+    retention TEXT,
+    notifications TEXT,
+    canaries TEXT,
+    masked_fields TEXT,
+    anomaly TEXT,
+    credential_policy TEXT,
+    requests_per_second DOUBLE PRECISION,
+    burst INTEGER,
+    last_purge TEXT,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_requests (
+    id TEXT PRIMARY KEY,
+    rowid BIGSERIAL,
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    received_at TIMESTAMPTZ NOT NULL,
+    method TEXT NOT NULL,
+    headers TEXT,
+    body TEXT,
+    body_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    verdict TEXT NOT NULL,
+    detail TEXT
+);
+
+CREATE TABLE IF NOT EXISTS tenant_domains (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    domain TEXT NOT NULL UNIQUE,
+    verification_token TEXT NOT NULL,
+    status TEXT NOT NULL,
+    kong_service_id TEXT,
+    kong_route_id TEXT,
+    last_error TEXT,
+    checked_at TIMESTAMPTZ,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    payload TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS tenant_invites (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL REFERENCES tenants(id),
+    email TEXT NOT NULL,
+    invited_by TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    accepted_by TEXT,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_payloads (
+    id TEXT PRIMARY KEY,
+    rowid BIGSERIAL,
+    workflow_id TEXT NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    payload TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS kong_resources (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    tenant_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    kong_id TEXT NOT NULL,
+    name TEXT,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    token_version INTEGER NOT NULL,
+    user_agent TEXT,
+    ip_address TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_credentials_user_id ON credentials(user_id);
+CREATE INDEX IF NOT EXISTS idx_credentials_tenant_id ON credentials(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_workflows_user_id ON workflows(user_id);
+CREATE INDEX IF NOT EXISTS idx_workflows_trigger_type ON workflows(trigger_type);
+CREATE INDEX IF NOT EXISTS idx_workflows_user_created_id ON workflows(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_logs_workflow_id ON logs(workflow_id);
+CREATE INDEX IF NOT EXISTS idx_logs_executed_at ON logs(executed_at);
+CREATE INDEX IF NOT EXISTS idx_logs_workflow_executed_at ON logs(workflow_id, executed_at);
+CREATE INDEX IF NOT EXISTS idx_logs_executed_at_id ON logs(executed_at, id);
+CREATE INDEX IF NOT EXISTS idx_logs_acknowledged_at ON logs(acknowledged_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_expires_at ON webhook_events(expires_at);
+CREATE INDEX IF NOT EXISTS idx_executions_workflow_executed ON executions(workflow_id, executed_at);
+CREATE INDEX IF NOT EXISTS idx_executions_rowid ON executions(rowid);
+CREATE INDEX IF NOT EXISTS idx_config_changes_changed_at ON config_changes(changed_at);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_expires_at ON impersonation_sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_rowid ON audit_events(actor_id, rowid);
+CREATE INDEX IF NOT EXISTS idx_workflow_fixtures_created_at ON workflow_fixtures(created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_requests_workflow_received ON webhook_requests(workflow_id, received_at);
+CREATE INDEX IF NOT EXISTS idx_tenant_domains_tenant_id ON tenant_domains(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_workflow_created ON dead_letters(workflow_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_tenant_invites_tenant_created ON tenant_invites(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_payloads_workflow_received ON webhook_payloads(workflow_id, received_at);
+CREATE INDEX IF NOT EXISTS idx_kong_resources_tenant_workflow ON kong_resources(tenant_id, workflow_id);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+`
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// maxWriteRetries is how many times a write is retried after SQLITE_BUSY
+	maxWriteRetries = 5
+	// baseRetryDelay is the first backoff step; each retry doubles it
+	baseRetryDelay = 10 * time.Millisecond
+)
+
+// execWrite runs a write statement, retrying with jittered exponential backoff
+// when SQLite reports the database as busy or locked (e.g., another process
+// holds the write lock longer than busy_timeout)
+func (db *Database) execWrite(query string, args ...interface{}) (sql.Result, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := db.conn.Exec(query, args...)
+		if err == nil || !isBusyError(err) || attempt == maxWriteRetries {
+			return result, classify(err)
+		}
+
+		atomic.AddUint64(&db.writeRetries, 1)
+		time.Sleep(retryDelay(attempt))
+	}
+}
+
+// writeTx runs fn in a transaction, retrying the whole transaction on SQLITE_BUSY
+// fn may be called more than once, so it must only touch the database through tx
+func (db *Database) writeTx(fn func(tx *sql.Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		err := db.runTx(fn)
+		if err == nil || !isBusyError(err) || attempt == maxWriteRetries {
+			return classify(err)
+		}
+
+		atomic.AddUint64(&db.writeRetries, 1)
+		time.Sleep(retryDelay(attempt))
+	}
+}
+
+// runTx runs fn in a single transaction, rolling back on error
+func (db *Database) runTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isBusyError reports whether err is SQLITE_BUSY or SQLITE_LOCKED, or a Postgres
+// serialization, deadlock or lock timeout failure
+func isBusyError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return isPostgresBusyError(err)
+}
+
+// retryDelay returns the backoff for an attempt with up to 100% jitter
+// so competing writers don't retry in lockstep
+func retryDelay(attempt int) time.Duration {
+	backoff := baseRetryDelay << attempt
+	return backoff + time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// WriteRetries returns how many writes have been retried after SQLITE_BUSY
+// Served at /metrics as db_write_retries_total for monitoring lock contention
+func (db *Database) WriteRetries() uint64 {
+	return atomic.LoadUint64(&db.writeRetries)
+}
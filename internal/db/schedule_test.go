@@ -0,0 +1,43 @@
+package db_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+)
+
+// TestNextRunBackfill upgrades a database whose schedule workflows predate next_run_at
+func TestNextRunBackfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	database := dbtest.Open(t, path)
+	user, _ := database.CreateUser("schedules@example.com", "hashed")
+	ran, _ := database.CreateWorkflow(user.ID, "Ran", "schedule", "testing", `{"interval": 30}`)
+	fresh, _ := database.CreateWorkflow(user.ID, "Fresh", "schedule", "testing", `{}`)
+	hook, _ := database.CreateWorkflow(user.ID, "Hook", "webhook", "testing", `{}`)
+	lastRun := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	database.UpdateWorkflowLastExecuted(ran.ID, lastRun, nil)
+	database.Close()
+
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open raw connection: %v", err)
+	}
+	if _, err := raw.Exec(`UPDATE workflows SET next_run_at = NULL`); err != nil {
+		t.Fatalf("Failed to clear next runs: %v", err)
+	}
+	raw.Close()
+
+	database = dbtest.Open(t, path)
+	if got, _ := database.GetWorkflowByID(ran.ID); got.NextRunAt == nil || !got.NextRunAt.Equal(lastRun.Add(30*time.Minute)) {
+		t.Errorf("Expected the next run an interval after the last, got %v", got.NextRunAt)
+	}
+	if got, _ := database.GetWorkflowByID(fresh.ID); got.NextRunAt == nil || !got.NextRunAt.Equal(got.CreatedAt) {
+		t.Errorf("Expected a workflow that never ran to be due, got %v", got.NextRunAt)
+	}
+	if got, _ := database.GetWorkflowByID(hook.ID); got.NextRunAt != nil {
+		t.Errorf("Expected no next run for a webhook workflow, got %v", got.NextRunAt)
+	}
+}
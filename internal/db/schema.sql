@@ -0,0 +1,335 @@
+-- iPaaS SQLite Schema
+-- Multi-User design with path to Multi-Tenant migration
+
+-- 0. Tenants Table (The top-level entity; its users share workflows, credentials and logs)
+CREATE TABLE IF NOT EXISTS tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 1. Users Table
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    email TEXT UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    tenant_id TEXT REFERENCES tenants(id), -- A user who signs up alone gets a tenant of their own
+    role TEXT,                             -- 'admin' (created the tenant) or 'member'
+    token_version INTEGER NOT NULL DEFAULT 0 -- Bumped by a password change to end existing sessions
+);
+
+-- 2. Credentials Table (Encrypted API keys/Tokens)
+-- We separate this so you can manage sensitive data independently
+CREATE TABLE IF NOT EXISTS credentials (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    service_name TEXT NOT NULL, -- e.g., 'slack', 'discord', 'openweather'
+    encrypted_key TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    tenant_id TEXT,                         -- Owning tenant; its derived data key encrypts encrypted_key
+    key_version INTEGER NOT NULL DEFAULT 0, -- Tenant key version (0 = sealed directly with the master key)
+    environment TEXT NOT NULL DEFAULT 'live', -- 'live' or 'test'; workflows should use their own environment's
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 3. Workflows/Integrations Table
+CREATE TABLE IF NOT EXISTS workflows (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    trigger_type TEXT NOT NULL, -- 'webhook', 'schedule'
+    action_type TEXT NOT NULL,  -- 'slack_message', 'discord_post', 'weather_check' (primary action)
+    config_json TEXT NOT NULL,  -- Stores params like channel IDs or thresholds
+    action_chain TEXT,          -- JSON array of additional actions to execute sequentially
+    parameters TEXT,            -- JSON array of runtime parameters (NEW!)
+    tenant_id TEXT,             -- The creator's tenant; its users share the workflow
+    is_active BOOLEAN DEFAULT 1,
+    last_executed_at DATETIME,
+    next_run_at DATETIME,           -- When the scheduler runs it next (schedule triggers only)
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    debug_requests_until DATETIME,  -- Inbound webhook requests are captured until then
+    debug_requests_limit INTEGER,   -- How many captured requests are kept
+    paused_until DATETIME,          -- Treated as inactive until then (resumes by itself)
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 4. Execution Logs (Crucial for iPaaS visibility)
+CREATE TABLE IF NOT EXISTS logs (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    status TEXT NOT NULL, -- 'success', 'failed'
+    message TEXT,
+    executed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_by TEXT,     -- user_id who triaged this entry ('system:auto' for age-based)
+    acknowledged_at DATETIME, -- NULL until acknowledged
+    error_code TEXT,          -- Connector error code, translated for display
+    error_params TEXT,        -- JSON object of values for the translated message
+    request_id TEXT,          -- X-Request-ID of the API request that triggered the run (NULL for schedules)
+    duration_ms INTEGER,      -- How long the run took (NULL for entries from before durations were kept)
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 5. Usage Rollup (estimated connector spend per tenant, per workflow, per month)
+-- No foreign key to workflows: spend history must survive workflow deletion
+CREATE TABLE IF NOT EXISTS usage (
+    tenant_id TEXT NOT NULL,
+    workflow_id TEXT NOT NULL,
+    month TEXT NOT NULL, -- 'YYYY-MM' calendar month in UTC
+    executions INTEGER NOT NULL DEFAULT 0,
+    cost REAL NOT NULL DEFAULT 0,
+    delay_ms INTEGER NOT NULL DEFAULT 0, -- Time spent in simulated testing delays
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, workflow_id, month)
+);
+
+-- 6. Workflow Stats (counters maintained by the executor for list-view sparklines)
+CREATE TABLE IF NOT EXISTS workflow_stats (
+    workflow_id TEXT PRIMARY KEY,
+    total_executions INTEGER NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    timed_executions INTEGER NOT NULL DEFAULT 0,  -- Executions with a recorded duration (backfilled logs have none)
+    total_duration_ms INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,                              -- Masked and truncated
+    suppressed_executions INTEGER NOT NULL DEFAULT 0, -- Held back by the outbound notification throttle
+    recent_duration_ms REAL NOT NULL DEFAULT 0,   -- Fast-moving duration average (anomaly detection)
+    baseline_duration_ms REAL NOT NULL DEFAULT 0, -- Slow-moving long-term duration average
+    failure_alerted_at DATETIME,                  -- Last failure alert, until the workflow recovers
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 7. Hourly outcome counts backing the rolling 24h/7d windows and anomaly checks (pruned after 7 days)
+CREATE TABLE IF NOT EXISTS workflow_stat_buckets (
+    workflow_id TEXT NOT NULL,
+    hour TEXT NOT NULL, -- 'YYYY-MM-DDTHH' in UTC
+    successes INTEGER NOT NULL DEFAULT 0,
+    failures INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (workflow_id, hour),
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 8. Webhook Event Dedupe (provider event IDs already processed, per workflow)
+-- Rows expire after the workflow's dedupe TTL and are pruned by the scheduler
+CREATE TABLE IF NOT EXISTS webhook_events (
+    workflow_id TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    received_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (workflow_id, event_id),
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 9. Executions (masked trace of each run: payload in, step outputs out)
+CREATE TABLE IF NOT EXISTS executions (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    message TEXT,
+    trigger_source TEXT NOT NULL, -- 'webhook', 'schedule', 'dry-run'
+    trigger_payload TEXT,         -- Masked JSON
+    result_data TEXT,             -- Masked, truncated JSON
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    executed_at DATETIME NOT NULL,
+    version TEXT,                 -- Build that ran the execution
+    instance_id TEXT,             -- Host or INSTANCE_ID that ran it
+    catch_up INTEGER NOT NULL DEFAULT 0, -- 1 = a scheduled run missed during downtime, run at startup
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 10. Runtime Config Changes (audit trail of settings applied without a restart)
+CREATE TABLE IF NOT EXISTS config_changes (
+    id TEXT PRIMARY KEY,
+    actor TEXT NOT NULL,   -- User ID, or 'system' for SIGHUP reloads
+    source TEXT NOT NULL,  -- 'api' or 'sighup'
+    changes TEXT NOT NULL, -- Masked JSON: {"setting": {"before": ..., "after": ...}}
+    changed_at DATETIME NOT NULL
+);
+
+-- 11. Workflow Fixtures (named sample trigger payloads for repeatable dry runs)
+CREATE TABLE IF NOT EXISTS workflow_fixtures (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    payload TEXT NOT NULL,        -- Masked JSON
+    source TEXT NOT NULL,         -- 'manual' or 'execution'
+    created_at DATETIME NOT NULL,
+    UNIQUE (workflow_id, name),
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 12. Impersonation Sessions (support engineers acting as a user, revocable)
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+    id TEXT PRIMARY KEY,
+    admin_id TEXT NOT NULL,
+    target_user_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    revoked_by TEXT
+);
+
+-- 13. Audit Events (who did what, including actions taken while impersonating)
+CREATE TABLE IF NOT EXISTS audit_events (
+    id TEXT PRIMARY KEY,
+    actor_id TEXT NOT NULL,      -- User the action was performed as
+    impersonator_id TEXT,        -- Admin behind an impersonation session
+    session_id TEXT,
+    action TEXT NOT NULL,        -- e.g. 'impersonation.start', 'http.request'
+    method TEXT,
+    path TEXT,
+    status_code INTEGER,
+    detail TEXT,
+    tenant_id TEXT,              -- Tenant the action was performed in
+    resource_type TEXT,          -- 'workflow', 'credential' or 'user'
+    resource_id TEXT,
+    source_ip TEXT,
+    created_at DATETIME NOT NULL
+);
+
+-- 14. Tenant Keys (current version of each tenant's derived data key; absent = version 1)
+CREATE TABLE IF NOT EXISTS tenant_keys (
+    tenant_id TEXT PRIMARY KEY,
+    version INTEGER NOT NULL,
+    rotated_at DATETIME NOT NULL
+);
+
+-- 15. Tenant Settings (tier and per-tenant overrides; absent = free tier defaults)
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id TEXT PRIMARY KEY,
+    tier TEXT NOT NULL DEFAULT 'free', -- 'free', 'pro', 'enterprise'
+    retention TEXT,                    -- JSON retention overrides (days per data class)
+    notifications TEXT,                -- JSON workflow change notification settings
+    canaries TEXT,                     -- JSON connector version canaries keyed by action type
+    masked_fields TEXT,                -- JSON array of payload keys the tenant always redacts
+    anomaly TEXT,                      -- JSON anomaly detection threshold overrides
+    credential_policy TEXT,            -- 'warn' or 'enforce' credential environment mismatches
+    requests_per_second REAL,          -- API rate limit override (NULL = the tier's limit)
+    burst INTEGER,                     -- API burst override (NULL = the configured burst)
+    last_purge TEXT,                   -- JSON summary of the latest retention pass
+    updated_at DATETIME NOT NULL
+);
+
+-- 16. Webhook Requests (ring buffer of the last raw requests per workflow, while debugging is on)
+CREATE TABLE IF NOT EXISTS webhook_requests (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    received_at DATETIME NOT NULL,
+    method TEXT NOT NULL,
+    headers TEXT,                 -- JSON object, sensitive values masked
+    body TEXT,                    -- Masked, at most 64KB
+    body_truncated BOOLEAN NOT NULL DEFAULT 0,
+    verdict TEXT NOT NULL,        -- 'executed', 'rejected_validation', 'rejected_signature', 'duplicate', 'error'
+    detail TEXT,
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 17. Tenant Domains (custom webhook hostnames routed through Kong, verified by DNS TXT record)
+CREATE TABLE IF NOT EXISTS tenant_domains (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    domain TEXT NOT NULL UNIQUE,  -- One tenant per hostname
+    verification_token TEXT NOT NULL,
+    status TEXT NOT NULL,         -- 'pending' or 'verified'
+    kong_service_id TEXT,
+    kong_route_id TEXT,
+    last_error TEXT,
+    checked_at DATETIME,
+    verified_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+-- 18. Dead Letters (workflow runs dropped by a full worker queue, kept for retry)
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    payload TEXT,                 -- Trigger payload, unmasked so the retry matches the original run
+    created_at DATETIME NOT NULL,
+    resolved_at DATETIME,         -- Set when the run is resubmitted
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 19. Tenant Invites (single-use sign-ups into an existing tenant, revocable until accepted)
+CREATE TABLE IF NOT EXISTS tenant_invites (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    email TEXT NOT NULL,
+    invited_by TEXT NOT NULL,
+    status TEXT NOT NULL,         -- 'pending', 'accepted' or 'revoked'; pending past expires_at reads as 'expired'
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME,
+    accepted_by TEXT,             -- The user the invite created
+    revoked_at DATETIME,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+);
+
+-- 20. Webhook Payloads (the last few masked JSON bodies that ran each webhook workflow, for template testing)
+CREATE TABLE IF NOT EXISTS webhook_payloads (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    payload TEXT NOT NULL,        -- Masked JSON object
+    received_at DATETIME NOT NULL,
+    FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+-- 21. Kong Resources (Kong Gateway objects created for a workflow, deleted with it)
+-- No foreign key: a row outlives its workflow when Kong couldn't delete the object
+CREATE TABLE IF NOT EXISTS kong_resources (
+    id TEXT PRIMARY KEY,
+    workflow_id TEXT NOT NULL,
+    tenant_id TEXT NOT NULL,
+    kind TEXT NOT NULL,           -- 'service', 'route' or 'plugin'
+    kong_id TEXT NOT NULL,
+    name TEXT,
+    created_at DATETIME NOT NULL
+);
+
+-- 22. Password Resets (single-use, short-lived; only the token's hash is kept)
+CREATE TABLE IF NOT EXISTS password_resets (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL, -- hex SHA-256 of the token sent to the user
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 23. Refresh Tokens (one per signed-in device; single-use, rotated on every refresh; only the token's hash is kept)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL, -- hex SHA-256 of the token given to the client
+    token_version INTEGER NOT NULL,  -- users.token_version at sign-in; refreshing fails once it moves on
+    user_agent TEXT,
+    ip_address TEXT,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,             -- Refreshed or logged out
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_credentials_user_id ON credentials(user_id);
+CREATE INDEX IF NOT EXISTS idx_workflows_user_id ON workflows(user_id);
+CREATE INDEX IF NOT EXISTS idx_workflows_trigger_type ON workflows(trigger_type);
+CREATE INDEX IF NOT EXISTS idx_logs_workflow_id ON logs(workflow_id);
+CREATE INDEX IF NOT EXISTS idx_logs_executed_at ON logs(executed_at);
+CREATE INDEX IF NOT EXISTS idx_logs_workflow_executed_at ON logs(workflow_id, executed_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_expires_at ON webhook_events(expires_at);
+CREATE INDEX IF NOT EXISTS idx_executions_workflow_executed ON executions(workflow_id, executed_at);
+CREATE INDEX IF NOT EXISTS idx_config_changes_changed_at ON config_changes(changed_at);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_expires_at ON impersonation_sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_workflow_fixtures_created_at ON workflow_fixtures(created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_requests_workflow_received ON webhook_requests(workflow_id, received_at);
+CREATE INDEX IF NOT EXISTS idx_tenant_domains_tenant_id ON tenant_domains(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_workflow_created ON dead_letters(workflow_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_tenant_invites_tenant_created ON tenant_invites(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_payloads_workflow_received ON webhook_payloads(workflow_id, received_at);
+CREATE INDEX IF NOT EXISTS idx_kong_resources_tenant_workflow ON kong_resources(tenant_id, workflow_id);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
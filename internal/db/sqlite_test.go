@@ -0,0 +1,65 @@
+package db_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestConcurrentLogWrites hammers CreateLogEntry and UpdateWorkflowLastExecuted from 50
+// goroutines, half of them through a second handle on the same file as another process
+// would, and expects no "database is locked" errors and no lost rows
+func TestConcurrentLogWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.db")
+	primary := dbtest.Open(t, path)
+	other, err := db.OpenSQLite(path, db.SQLiteOptions{BusyTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to open a second handle: %v", err)
+	}
+	defer other.Close()
+
+	user, _ := primary.CreateUser("busy@example.com", "hashed")
+	workflow, err := primary.CreateWorkflow(user.ID, "Hot webhook", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	const goroutines, writes = 50, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*writes*2)
+	for g := 0; g < goroutines; g++ {
+		database := primary
+		if g%2 == 1 {
+			database = other
+		}
+		wg.Add(1)
+		go func(g int, database *db.Database) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				now := time.Now()
+				if err := database.CreateLogEntry(&models.Log{WorkflowID: workflow.ID, Status: "success", Message: fmt.Sprintf("%d-%d", g, i), ExecutedAt: now}); err != nil {
+					errs <- err
+				}
+				if err := database.UpdateWorkflowLastExecuted(workflow.ID, now, nil); err != nil {
+					errs <- err
+				}
+			}
+		}(g, database)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Write failed: %v", err)
+	}
+	_, total, err := primary.GetLogsFiltered(user.ID, models.LogFilter{WorkflowID: workflow.ID, Limit: 1})
+	if err != nil || total != goroutines*writes {
+		t.Errorf("Expected %d logs, got %d, %v", goroutines*writes, total, err)
+	}
+}
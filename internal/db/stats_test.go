@@ -0,0 +1,299 @@
+package db_test
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+)
+
+// TestWorkflowStatsConcurrentExecutions proves counters don't lose updates when
+// the same workflow finishes many executions at once
+func TestWorkflowStatsConcurrentExecutions(t *testing.T) {
+	database := dbtest.New(t)
+
+	user, err := database.CreateUser("stats@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	workflow, err := database.CreateWorkflow(user.ID, "Busy", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	record := func(n int, status, message string, duration time.Duration) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := database.RecordWorkflowExecution(workflow.ID, status, duration, message, time.Now()); err != nil {
+					t.Errorf("RecordWorkflowExecution failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	record(100, "success", "", 10*time.Millisecond)
+	record(30, "failed", "Slack returned 403 for https://hooks.slack.com/services/T000/B000/XXXXXXXXXXXX", 40*time.Millisecond)
+
+	reloaded, err := database.GetWorkflowByID(workflow.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload workflow: %v", err)
+	}
+	stats := reloaded.Stats
+	if stats == nil {
+		t.Fatal("Expected stats on workflow")
+	}
+
+	if stats.TotalExecutions != 130 {
+		t.Errorf("Expected 130 executions, got %d", stats.TotalExecutions)
+	}
+	if stats.ConsecutiveFailures != 30 {
+		t.Errorf("Expected 30 consecutive failures, got %d", stats.ConsecutiveFailures)
+	}
+	if stats.Successes24h != 100 || stats.Successes7d != 100 {
+		t.Errorf("Expected 100 recent successes, got 24h=%d 7d=%d", stats.Successes24h, stats.Successes7d)
+	}
+	wantAvg := float64(100*10+30*40) / 130
+	if stats.AvgDurationMS < wantAvg-0.01 || stats.AvgDurationMS > wantAvg+0.01 {
+		t.Errorf("Expected average duration %.2fms, got %.2fms", wantAvg, stats.AvgDurationMS)
+	}
+	if strings.Contains(stats.LastError, "hooks.slack.com/services") {
+		t.Errorf("Expected webhook URL to be masked, got %q", stats.LastError)
+	}
+
+	// A success resets the failure streak
+	record(1, "success", "", time.Millisecond)
+	reloaded, _ = database.GetWorkflowByID(workflow.ID)
+	if reloaded.Stats.ConsecutiveFailures != 0 {
+		t.Errorf("Expected failure streak reset, got %d", reloaded.Stats.ConsecutiveFailures)
+	}
+}
+
+// TestWorkflowStatsBackfill verifies stats are rebuilt from existing logs on startup
+func TestWorkflowStatsBackfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.db")
+	database := dbtest.Open(t, path)
+
+	user, err := database.CreateUser("backfill@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	workflow, err := database.CreateWorkflow(user.ID, "Old", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	// Logs written without stats, as by a version that predates them
+	for _, status := range []string{"success", "success", "failed", "success", "failed", "failed"} {
+		if err := database.CreateLog(workflow.ID, status, status+" run"); err != nil {
+			t.Fatalf("Failed to create log: %v", err)
+		}
+		time.Sleep(time.Millisecond) // Distinct executed_at for streak ordering
+	}
+
+	// Restarting runs the migrations, which backfill stats from the logs
+	database.Close()
+	reopened := dbtest.Open(t, path)
+	reloaded, err := reopened.GetWorkflowByID(workflow.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload workflow: %v", err)
+	}
+
+	stats := reloaded.Stats
+	if stats.TotalExecutions != 6 {
+		t.Errorf("Expected 6 backfilled executions, got %d", stats.TotalExecutions)
+	}
+	if stats.ConsecutiveFailures != 2 {
+		t.Errorf("Expected 2 consecutive failures, got %d", stats.ConsecutiveFailures)
+	}
+	if stats.Successes7d != 3 {
+		t.Errorf("Expected 3 successes in 7d, got %d", stats.Successes7d)
+	}
+	if stats.LastError != "failed run" {
+		t.Errorf("Expected last error 'failed run', got %q", stats.LastError)
+	}
+}
+
+// TestWorkflowBaseline checks hourly failure counts and the decayed duration averages
+func TestWorkflowBaseline(t *testing.T) {
+	database := dbtest.New(t)
+
+	user, _ := database.CreateUser("baseline@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Baseline", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	now := time.Now().UTC()
+	earlier := now.Add(-3 * time.Hour)
+	for i := 0; i < 4; i++ {
+		database.RecordWorkflowExecution(workflow.ID, "success", 100*time.Millisecond, "", earlier)
+	}
+	database.RecordWorkflowExecution(workflow.ID, "failed", 100*time.Millisecond, "boom", earlier)
+	database.RecordWorkflowExecution(workflow.ID, "failed", 1100*time.Millisecond, "boom", now)
+	database.RecordWorkflowExecution(workflow.ID, "timed_out_hard", 100*time.Millisecond, "stuck", now)
+
+	baseline, err := database.GetWorkflowBaseline(workflow.ID, now.Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetWorkflowBaseline failed: %v", err)
+	}
+	if len(baseline.Hours) != 2 {
+		t.Fatalf("Expected two hours with executions, got %+v", baseline.Hours)
+	}
+	if h := baseline.Hours[0]; h.Successes != 4 || h.Failures != 1 || !h.Hour.Equal(earlier.Truncate(time.Hour)) {
+		t.Errorf("Expected 4 successes and 1 failure in the earlier hour, got %+v", h)
+	}
+	if h := baseline.Hours[1]; h.Successes != 0 || h.Failures != 2 {
+		t.Errorf("Expected 2 failures this hour, got %+v", h)
+	}
+
+	// Five 100ms runs, one 1100ms run, one 100ms run: the recent average moves much further than the baseline
+	if baseline.TimedExecutions != 7 {
+		t.Errorf("Expected 7 timed executions, got %d", baseline.TimedExecutions)
+	}
+	if baseline.RecentDurationMS < 250 || baseline.BaselineDurationMS > 115 || baseline.BaselineDurationMS < 100 {
+		t.Errorf("Expected recent ≈ 260ms and baseline ≈ 109ms, got %.1f and %.1f", baseline.RecentDurationMS, baseline.BaselineDurationMS)
+	}
+
+	reloaded, _ := database.GetWorkflowByID(workflow.ID)
+	if reloaded.Stats.Successes24h != 4 {
+		t.Errorf("Expected failures not to count as successes, got %d", reloaded.Stats.Successes24h)
+	}
+}
+
+// TestRunStats aggregates generated logs over a window and checks every figure against
+// the same logs counted by hand
+func TestRunStats(t *testing.T) {
+	database := dbtest.New(t)
+
+	user, _ := database.CreateUser("runstats@example.com", "hashed")
+	busy, _ := database.CreateWorkflow(user.ID, "Busy", "webhook", "testing", `{}`)
+	quiet, _ := database.CreateWorkflow(user.ID, "Quiet", "webhook", "testing", `{}`)
+	outsider, _ := database.CreateUser("outsider@example.com", "hashed")
+	foreign, _ := database.CreateWorkflow(outsider.ID, "Foreign", "webhook", "testing", `{}`)
+
+	// Ten days of runs, every fifth failing, then a suppressed run and a streak of three failures
+	now := time.Now()
+	var logs []models.Log
+	for day := 0; day < 10; day++ {
+		for k := 0; k < 300; k++ {
+			status := "success"
+			if k%5 == 4 {
+				status = "failed"
+			}
+			duration := int64(k%97 + 1)
+			logs = append(logs, models.Log{WorkflowID: busy.ID, Status: status, DurationMS: &duration,
+				ExecutedAt: now.Add(-time.Duration(day)*24*time.Hour - time.Hour - time.Duration(k)*time.Second)})
+		}
+	}
+	logs = append(logs, models.Log{WorkflowID: busy.ID, Status: models.StatusSuppressed, ExecutedAt: now.Add(-4 * time.Minute)})
+	for i := 0; i < 3; i++ {
+		duration := int64(1000)
+		logs = append(logs, models.Log{WorkflowID: busy.ID, Status: "failed", DurationMS: &duration, ExecutedAt: now.Add(-time.Duration(3-i) * time.Minute)})
+	}
+	logs = append(logs, models.Log{WorkflowID: foreign.ID, Status: "success", ExecutedAt: now.Add(-time.Minute)})
+	for i := range logs {
+		if err := database.CreateLogEntry(&logs[i]); err != nil {
+			t.Fatalf("Failed to create log: %v", err)
+		}
+	}
+
+	// The expected figures, counted from the generated logs
+	since := now.Add(-7 * 24 * time.Hour)
+	want := models.RunStats{ByStatus: map[string]int64{}}
+	wantDays := map[string]models.StatDay{}
+	var durations []int64
+	for _, log := range logs {
+		if log.WorkflowID != busy.ID || log.ExecutedAt.Before(since) {
+			continue
+		}
+		want.TotalRuns++
+		want.ByStatus[log.Status]++
+		day := wantDays[log.ExecutedAt.UTC().Format("2006-01-02")]
+		day.Total++
+		switch log.Status {
+		case "success":
+			want.Successes++
+			day.Successes++
+		case "failed":
+			want.Failures++
+			day.Failures++
+		}
+		wantDays[log.ExecutedAt.UTC().Format("2006-01-02")] = day
+		if log.DurationMS != nil {
+			durations = append(durations, *log.DurationMS)
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var sum int64
+	for _, d := range durations {
+		sum += d
+	}
+	wantAvg := float64(sum) / float64(len(durations))
+	wantMedian := float64(durations[len(durations)/2-1]+durations[len(durations)/2]) / 2
+	if len(durations)%2 == 1 {
+		wantMedian = float64(durations[len(durations)/2])
+	}
+
+	stats, err := database.GetWorkflowRunStats(busy.ID, since)
+	if err != nil {
+		t.Fatalf("GetWorkflowRunStats failed: %v", err)
+	}
+	if stats.TotalRuns != want.TotalRuns || stats.Successes != want.Successes || stats.Failures != want.Failures {
+		t.Errorf("Expected %d runs (%d ok, %d failed), got %d (%d, %d)", want.TotalRuns, want.Successes, want.Failures, stats.TotalRuns, stats.Successes, stats.Failures)
+	}
+	if stats.ByStatus[models.StatusSuppressed] != 1 {
+		t.Errorf("Expected the suppressed run counted by status, got %v", stats.ByStatus)
+	}
+	if rate := float64(want.Successes) / float64(want.Successes+want.Failures); math.Abs(stats.SuccessRate-rate) > 1e-9 {
+		t.Errorf("Expected success rate %.4f, got %.4f", rate, stats.SuccessRate)
+	}
+	if stats.AvgDurationMS == nil || math.Abs(*stats.AvgDurationMS-wantAvg) > 1e-6 {
+		t.Errorf("Expected average duration %.3f, got %v", wantAvg, stats.AvgDurationMS)
+	}
+	if stats.MedianDurationMS == nil || *stats.MedianDurationMS != wantMedian {
+		t.Errorf("Expected median duration %.1f, got %v", wantMedian, stats.MedianDurationMS)
+	}
+	if stats.FailureStreak != 3 {
+		t.Errorf("Expected a failure streak of 3, got %d", stats.FailureStreak)
+	}
+	if stats.LastExecutedAt == nil || !stats.LastExecutedAt.Equal(logs[len(logs)-2].ExecutedAt) {
+		t.Errorf("Expected the last run at %v, got %v", logs[len(logs)-2].ExecutedAt, stats.LastExecutedAt)
+	}
+	if len(stats.Daily) < 7 || len(stats.Daily) > 8 {
+		t.Fatalf("Expected a day for each of the window's days, got %d", len(stats.Daily))
+	}
+	for _, day := range stats.Daily {
+		if expected := wantDays[day.Date]; day.Total != expected.Total || day.Successes != expected.Successes || day.Failures != expected.Failures {
+			t.Errorf("%s: expected %+v, got %+v", day.Date, expected, day)
+		}
+	}
+
+	overview, err := database.GetRunStatsOverview(user.TenantID, since)
+	if err != nil {
+		t.Fatalf("GetRunStatsOverview failed: %v", err)
+	}
+	if overview.WorkflowCount != 2 || len(overview.Workflows) != 2 || overview.Workflows[1].WorkflowID != quiet.ID || overview.Workflows[1].TotalRuns != 0 {
+		t.Fatalf("Expected both of the tenant's workflows, got %+v", overview.Workflows)
+	}
+	if overview.TotalRuns != want.TotalRuns || overview.FailingWorkflows != 1 || overview.FailureStreak != 3 {
+		t.Errorf("Expected the busy workflow's runs and streak only, got %d runs, %d failing, streak %d", overview.TotalRuns, overview.FailingWorkflows, overview.FailureStreak)
+	}
+	if overview.MedianDurationMS == nil || *overview.MedianDurationMS != wantMedian {
+		t.Errorf("Expected the overview's median %.1f, got %v", wantMedian, overview.MedianDurationMS)
+	}
+
+	if _, err := database.GetWorkflowRunStats("missing", since); !store.IsNotFound(err) {
+		t.Errorf("Expected not found for an unknown workflow, got %v", err)
+	}
+}
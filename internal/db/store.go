@@ -8,35 +8,171 @@ import (
 
 // Store defines the interface for data persistence
 // This allows for easy testing with mocks and potential database swaps
+// Implementations report failures with the kinds in package store (store.ErrNotFound,
+// store.ErrConflict, store.ErrLocked, store.ErrUnavailable); check them with errors.Is
 type Store interface {
+	// Tenant operations
+	CreateTenant(id, name string) (*models.Tenant, error)
+	GetTenantByID(id string) (*models.Tenant, error)
+	ListTenantUserIDs(tenantID string) ([]string, error)
+
 	// User operations
-	CreateUser(email, passwordHash string) (*models.User, error)
+	CreateUser(email, passwordHash string) (*models.User, error) // In a new tenant of their own
+	CreateUserInTenant(tenantID, email, passwordHash string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	GetUserByID(id string) (*models.User, error)
+	UpdateUserPassword(userID, passwordHash string) (*models.User, error) // Bumps the token version, so tokens issued before stop working
+
+	// Password resets
+	CreatePasswordReset(reset *models.PasswordReset) error
+	ResetPassword(tokenHash, passwordHash string, at time.Time) (*models.User, error) // Uses the reset up and sets the password as UpdateUserPassword does; ErrResetClosed unless unused and unexpired
+
+	// Refresh tokens (signed-in sessions)
+	CreateRefreshToken(token *models.RefreshToken) error
+	RotateRefreshToken(tokenHash string, next *models.RefreshToken, at time.Time) (*models.RefreshToken, error) // Revokes the token and stores next for its user; ErrRefreshTokenClosed if revoked, expired or from before a password change
+	RevokeRefreshToken(tokenHash string, allSessions bool, at time.Time) (*models.RefreshToken, error)          // With allSessions, every open token of its user
+	PruneRefreshTokens(before time.Time) (int64, error)                                                         // Deletes tokens expired or revoked before the time
 
 	// Credential operations
-	CreateCredential(userID, serviceName, apiKey string) (*models.Credential, error)
+	CreateCredential(userID, serviceName, apiKey string) (*models.Credential, error) // A live credential
+	CreateCredentialInEnvironment(userID, serviceName, apiKey, environment string) (*models.Credential, error)
+	ReplaceCredential(userID, serviceName, apiKey string) (*models.Credential, error) // Drops the user's other rows for the service
+	UpdateCredential(credentialID, apiKey string) error                                // Re-encrypts the value in place
+	DeleteCredential(tenantID, credentialID string) error
 	GetCredentialsByUserID(userID string) ([]models.Credential, error)
+	GetCredentialsByTenantID(tenantID string) ([]models.Credential, error)
 	GetCredentialByUserAndService(userID, serviceName string) (*models.Credential, error)
+	GetCredentialForTenant(tenantID, userID, serviceName string) (*models.Credential, error) // crypto.ErrTenantMismatch for another tenant's row
+	GetCredentialInEnvironment(tenantID, userID, serviceName, environment string) (*models.Credential, error) // Falls back to the other environment's credential
+	ReencryptCredentials() (reencrypted, skipped int, err error)
+	RotateTenantKey(tenantID string) (version, reencrypted, skipped int, err error)
 
 	// Workflow operations
 	CreateWorkflow(userID, name, triggerType, actionType, configJSON string) (*models.Workflow, error)
+	CreateWorkflowWithChain(userID, name, triggerType, actionType, configJSON, actionChain string) (*models.Workflow, error)
 	GetWorkflowsByUserID(userID string) ([]models.Workflow, error)
+	GetWorkflowsByTenantID(tenantID string) ([]models.Workflow, error)
+	ListWorkflows(userID string, page models.PageRequest) ([]models.Workflow, error)
+	ListTenantWorkflows(tenantID string, page models.PageRequest) ([]models.Workflow, error)
 	GetWorkflowByID(workflowID string) (*models.Workflow, error)
+	GetActiveWorkflow(workflowID string) (*models.Workflow, error) // ErrNotFound once deleted or disabled
+	UpdateWorkflow(workflow *models.Workflow) error // Name, trigger, action, config and chain; keeps ID, state and history
 	UpdateWorkflowActive(workflowID string, isActive bool) error
-	UpdateWorkflowLastExecuted(workflowID string, executedAt time.Time) error
+	UpdateWorkflowLastExecuted(workflowID string, executedAt time.Time, nextRunAt *time.Time) error
 	DeleteWorkflow(workflowID string) error
-	GetActiveScheduledWorkflows() ([]models.Workflow, error)
+	SetWorkflowsActive(workflowIDs []string, isActive bool) ([]string, error) // One transaction; returns the IDs not found
+	DeleteWorkflows(workflowIDs []string) ([]string, error)                   // One transaction; returns the IDs not found
+	GetDueWorkflows(now time.Time) ([]models.Workflow, error) // Active workflows with next_run_at <= now
+
+	RecordWorkflowExecution(workflowID, status string, duration time.Duration, errorMessage string, at time.Time) error
+	GetWorkflowBaseline(workflowID string, since time.Time) (*models.WorkflowBaseline, error) // Anomaly detection inputs
+	ClaimFailureAlert(workflowID string, threshold int, cooldown time.Duration, now time.Time) (int, bool, error) // Consecutive failures, and whether an alert is due (marked sent if so)
+	ClearFailureAlert(workflowID string) (bool, error) // Reports whether a failure alert was outstanding
+	GetWorkflowRunStats(workflowID string, since time.Time) (*models.RunStats, error)         // Aggregated from the logs
+	GetRunStatsOverview(tenantID string, since time.Time) (*models.RunStatsOverview, error)   // Every workflow of the tenant
 
 	// Log operations
 	CreateLog(workflowID, status, message string) error
+	CreateLogEntry(log *models.Log) error // Also stores the error code and params
 	GetLogsByUserID(userID string) ([]models.WorkflowLog, error)
+	GetLogsByTenantID(tenantID string) ([]models.WorkflowLog, error)
 	GetLogsByWorkflowID(workflowID string) ([]models.Log, error)
+	QueryLogs(userID string, filter models.LogFilter) ([]models.WorkflowLog, error) // filter.TenantID widens the scope to the tenant
+	GetLogsFiltered(userID string, filter models.LogFilter) ([]models.WorkflowLog, int, error) // Page plus the filter's total, ignoring paging
+	GetLogByID(logID string) (*models.Log, error)
+	AcknowledgeLog(logID, acknowledgedBy string, at time.Time) error
+	AcknowledgeLogs(userID string, filter models.LogFilter, acknowledgedBy string, at time.Time) (int64, error)
+	AutoAcknowledgeLogs(olderThan time.Time) (int64, error)
+	DeleteWorkflowLogs(workflowID string, limit int) (int64, error) // Oldest first; callers repeat until fewer than limit go
+
+	// Execution operations
+	CreateExecution(execution *models.Execution) error
+	GetLatestExecution(workflowID string) (*models.Execution, error)
+	GetExecutionByID(executionID string) (*models.Execution, error)
+	ListExecutions(workflowID string, page models.PageRequest) ([]models.Execution, error) // Newest first
+	EachExecution(userID string, since time.Time, fn func(*models.Execution) error) error // Oldest first, paged
+
+	// Workflow fixture operations (named sample payloads)
+	CreateWorkflowFixture(fixture *models.WorkflowFixture, limit int) error
+	ListWorkflowFixtures(workflowID string) ([]models.WorkflowFixture, error)
+	GetWorkflowFixture(workflowID, name string) (*models.WorkflowFixture, error)
+	DeleteWorkflowFixture(workflowID, name string) error
+
+	// Webhook request capture (debugging)
+	SetWorkflowDebugRequests(workflowID string, until *time.Time, limit int) error
+	SetWorkflowPausedUntil(workflowID string, until *time.Time) error // Nil resumes the workflow
+	CaptureWebhookRequest(request *models.WebhookRequest, keep int) error // Keeps the workflow's newest keep
+	ListWebhookRequests(workflowID string) ([]models.WebhookRequest, error)
+	GetWebhookRequest(workflowID, requestID string) (*models.WebhookRequest, error)
+
+	// Recent webhook payloads (template testing)
+	SaveWebhookPayload(payload *models.WebhookPayload, keep int) error // Keeps the workflow's newest keep
+	ListWebhookPayloads(workflowID string) ([]models.WebhookPayload, error)
+
+	// Dead letters (runs dropped by a full worker queue)
+	CreateDeadLetter(workflowID, reason, payload string) (*models.DeadLetter, error)
+	ListDeadLetters(userID string) ([]models.DeadLetter, error) // Newest first, resolved ones included
+	GetDeadLetter(id string) (*models.DeadLetter, error)
+	ResolveDeadLetter(id string, at time.Time) error // store.ErrConflict if already resolved
+
+	// Webhook event dedupe
+	MarkWebhookEventSeen(workflowID, eventID string, receivedAt, expiresAt time.Time) (bool, error)
+	ForgetWebhookEvent(workflowID, eventID string) error // Undoes MarkWebhookEventSeen for an event that could not be run
+	PruneWebhookEvents(before time.Time) (int64, error)
+
+	// Runtime config audit
+	RecordConfigChange(change *models.ConfigChange) error
+	ListConfigChanges(limit int) ([]models.ConfigChange, error)
+
+	// Impersonation sessions and audit trail
+	CreateImpersonationSession(session *models.ImpersonationSession) error
+	GetImpersonationSession(sessionID string) (*models.ImpersonationSession, error)
+	ListActiveImpersonationSessions(now time.Time) ([]models.ImpersonationSession, error)
+	RevokeImpersonationSession(sessionID, revokedBy string, at time.Time) error
+	RecordAuditEvent(event *models.AuditEvent) error
+	ListAuditEvents(limit int) ([]models.AuditEvent, error)
+	ListTenantAuditEvents(tenantID string, filter models.AuditFilter) ([]models.AuditEvent, error) // Newest first
+	EachAuditEvent(actorID string, fn func(*models.AuditEvent) error) error // Oldest first, paged
+
+	// Usage operations
+	RecordWorkflowCost(tenantID, workflowID, month string, cost float64, delayMS int64) error
+	GetWorkflowCosts(tenantID, month string) ([]models.WorkflowCost, error)
+	EachWorkflowCost(tenantID string, fn func(*models.WorkflowCost) error) error // Every month, oldest first
+
+	// Tenant settings and data retention
+	GetTenantSettings(tenantID string) (*models.TenantSettings, error)
+	SaveTenantSettings(settings *models.TenantSettings) error
+	RecordRetentionRun(run *models.RetentionRun) error
+	GetTenantLimits(tenantID string) (*models.TenantLimits, error) // Free tier without overrides when unset
+	SetTenantLimits(limits *models.TenantLimits) error             // Changes the tier too; other settings are left alone
+	ListTenantIDs() ([]string, error)
+	PurgeTenantData(tenantID, dataClass string, before time.Time, limit int) (int64, error)
+
+	// Tenant webhook domains
+	CreateTenantDomain(domain *models.TenantDomain) error // ErrDomainTaken when any tenant has the domain
+	ListTenantDomains(tenantID string) ([]models.TenantDomain, error)
+	GetTenantDomain(tenantID, domainID string) (*models.TenantDomain, error)
+	GetTenantDomainByName(domain string) (*models.TenantDomain, error)
+	UpdateTenantDomain(domain *models.TenantDomain) error // Status, Kong objects and the latest check
+	DeleteTenantDomain(tenantID, domainID string) error
+
+	// Kong objects created for workflows
+	CreateKongResource(resource *models.KongResource) error
+	ListKongResources(tenantID, workflowID string) ([]models.KongResource, error) // Empty workflowID = every workflow's, oldest first
+	DeleteKongResource(tenantID, resourceID string) error
+
+	// Tenant invites
+	CreateTenantInvite(invite *models.TenantInvite) error
+	ListTenantInvites(tenantID string) ([]models.TenantInvite, error) // Newest first, with the stored status
+	GetTenantInvite(inviteID string) (*models.TenantInvite, error)
+	RevokeTenantInvite(tenantID, inviteID string, at time.Time) error                    // ErrInviteClosed unless pending
+	AcceptTenantInvite(inviteID, passwordHash string, at time.Time) (*models.User, error) // Creates the user in the invite's tenant; ErrInviteClosed unless pending
 
 	// Lifecycle
 	Close() error
 }
 
-// Ensure Database implements Store interface
+// Ensure Database and MockStore implement Store interface
 var _ Store = (*Database)(nil)
+var _ Store = (*MockStore)(nil)
 
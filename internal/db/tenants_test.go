@@ -0,0 +1,99 @@
+package db_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestTenantScopedQueries checks the tenant variants return every member's rows and
+// nothing from other tenants
+func TestTenantScopedQueries(t *testing.T) {
+	for name, s := range conformanceStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.CreateTenant("tenant_acme", "Acme"); err != nil {
+				t.Fatalf("Failed to create tenant: %v", err)
+			}
+			alice, err := s.CreateUserInTenant("tenant_acme", "alice@acme.com", "hashed")
+			if err != nil || alice.TenantID != "tenant_acme" || alice.Role != models.RoleMember {
+				t.Fatalf("Expected alice in the tenant, got %+v, %v", alice, err)
+			}
+			bob, _ := s.CreateUserInTenant("tenant_acme", "bob@acme.com", "hashed")
+			outsider, _ := s.CreateUser("outsider@example.com", "hashed")
+			if outsider.TenantID != models.DefaultTenantID(outsider.ID) || outsider.Role != models.RoleAdmin {
+				t.Errorf("Expected a sign-up to administer its own tenant, got %q, %q", outsider.TenantID, outsider.Role)
+			}
+
+			shared, _ := s.CreateWorkflow(alice.ID, "Alerts", "webhook", "testing", `{}`)
+			s.CreateWorkflow(bob.ID, "Reports", "webhook", "testing", `{}`)
+			s.CreateWorkflow(outsider.ID, "Private", "webhook", "testing", `{}`)
+			s.CreateCredential(alice.ID, "slack", "https://hooks.slack.com/services/ACME")
+			s.CreateCredential(outsider.ID, "discord", "https://discord.com/api/webhooks/OUTSIDER")
+			s.CreateLog(shared.ID, "success", "Sent")
+
+			members, err := s.ListTenantUserIDs("tenant_acme")
+			if err != nil || len(members) != 2 || members[0] == members[1] ||
+				(members[0] != alice.ID && members[0] != bob.ID) || (members[1] != alice.ID && members[1] != bob.ID) {
+				t.Errorf("Expected alice and bob as members, got %v, %v", members, err)
+			}
+			workflows, err := s.GetWorkflowsByTenantID("tenant_acme")
+			if err != nil || len(workflows) != 2 {
+				t.Errorf("Expected both members' workflows, got %v, %v", workflows, err)
+			}
+			page, err := s.ListTenantWorkflows("tenant_acme", models.PageRequest{Limit: 1})
+			if err != nil || len(page) != 1 || page[0].TenantID != "tenant_acme" {
+				t.Errorf("Expected a page of one tenant workflow, got %v, %v", page, err)
+			}
+			credentials, err := s.GetCredentialsByTenantID("tenant_acme")
+			if err != nil || len(credentials) != 1 || credentials[0].UserID != alice.ID {
+				t.Errorf("Expected alice's credential only, got %v, %v", credentials, err)
+			}
+			logs, err := s.GetLogsByTenantID("tenant_acme")
+			if err != nil || len(logs) != 1 {
+				t.Errorf("Expected the shared workflow's log, got %v, %v", logs, err)
+			}
+			if logs, _ := s.GetLogsByTenantID(outsider.TenantID); len(logs) != 0 {
+				t.Errorf("Expected no logs for the outsider's tenant, got %v", logs)
+			}
+		})
+	}
+}
+
+// TestTenantBackfill upgrades a database whose users and workflows predate tenants
+func TestTenantBackfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	database := dbtest.Open(t, path)
+	user, _ := database.CreateUser("legacy@example.com", "hashed")
+	workflow, _ := database.CreateWorkflow(user.ID, "Legacy", "webhook", "testing", `{}`)
+	database.Close()
+
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open raw connection: %v", err)
+	}
+	for _, statement := range []string{
+		`UPDATE users SET tenant_id = NULL, role = NULL`,
+		`UPDATE workflows SET tenant_id = NULL`,
+		`DELETE FROM tenants`,
+	} {
+		if _, err := raw.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	raw.Close()
+
+	database = dbtest.Open(t, path)
+	want := models.DefaultTenantID(user.ID)
+	if tenant, err := database.GetTenantByID(want); err != nil || tenant.Name != user.Email {
+		t.Errorf("Expected a tenant named after the user, got %+v, %v", tenant, err)
+	}
+	if got, _ := database.GetUserByID(user.ID); got.TenantID != want || got.Role != models.RoleAdmin {
+		t.Errorf("Expected the user backfilled as admin of %s, got %q, %q", want, got.TenantID, got.Role)
+	}
+	if got, _ := database.GetWorkflowByID(workflow.ID); got.TenantID != want {
+		t.Errorf("Expected the workflow backfilled into %s, got %q", want, got.TenantID)
+	}
+}
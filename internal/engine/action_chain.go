@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// maxChainSteps bounds how many steps an action chain may declare
+const maxChainSteps = 10
+
+// ValidateActionChain rejects chains the executor can't run: more than maxChainSteps steps,
+// action types whose connector isn't chainable, configs their connector rejects, and
+// use_data_from values other than "previous"
+// Parallel steps are checked branch by branch; their shape is left to ValidateParallelSteps
+func (e *Executor) ValidateActionChain(actionChainJSON string) error {
+	if actionChainJSON == "" {
+		return nil
+	}
+	var chain []models.ChainedAction
+	if err := json.Unmarshal([]byte(actionChainJSON), &chain); err != nil {
+		return fmt.Errorf("Invalid action_chain format: %v", err)
+	}
+	if len(chain) > maxChainSteps {
+		return fmt.Errorf("action_chain may have at most %d steps (got %d)", maxChainSteps, len(chain))
+	}
+
+	for i, action := range chain {
+		if len(action.Parallel) == 0 {
+			if err := e.validateChainedAction(action); err != nil {
+				return fmt.Errorf("action_chain step %d: %v", i+1, err)
+			}
+			continue
+		}
+		for j, branch := range action.Parallel {
+			if branch.ActionType == "" {
+				continue // Reported by ValidateParallelSteps
+			}
+			if err := e.validateChainedAction(branch); err != nil {
+				return fmt.Errorf("action_chain step %d, branch %d: %v", i+1, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateChainedAction checks one chain step's action type, config and data source
+func (e *Executor) validateChainedAction(action models.ChainedAction) error {
+	if connector, ok := e.connectors.Lookup(action.ActionType); !ok || !connector.Chainable {
+		return fmt.Errorf("unsupported action_type %q", action.ActionType)
+	}
+	configBytes, _ := json.Marshal(action.Config)
+	if _, err := e.connectors.Decode(action.ActionType, configBytes); err != nil {
+		return err
+	}
+	if action.UseDataFrom != "" && action.UseDataFrom != "previous" {
+		return fmt.Errorf("use_data_from must be empty or \"previous\", got %q", action.UseDataFrom)
+	}
+	return nil
+}
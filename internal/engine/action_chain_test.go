@@ -0,0 +1,103 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// routeHost sends requests for one host to a test server, and everything else on as usual
+type routeHost struct {
+	host   string
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (rt routeHost) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == rt.host {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host = rt.target.Scheme, rt.target.Host
+	}
+	return rt.next.RoundTrip(req)
+}
+
+// routeConnectorHost points connector calls for one host at a test server until the test ends
+func routeConnectorHost(t *testing.T, host, serverURL string) {
+	t.Helper()
+	target, _ := url.Parse(serverURL)
+	provider, err := connectors.NewHTTPClientProvider(connectors.HTTPClientConfig{
+		Transport: routeHost{host: host, target: target, next: http.DefaultTransport},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build HTTP clients: %v", err)
+	}
+	original := connectors.HTTPClients()
+	connectors.SetHTTPClients(provider)
+	t.Cleanup(func() { connectors.SetHTTPClients(original) })
+}
+
+// TestChainFetchThenMessage runs a news_fetch step followed by Slack messages that use the
+// fetched articles, checking the articles are still there after the first message
+func TestChainFetchThenMessage(t *testing.T) {
+	news := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "golang" || r.URL.Query().Get("apiKey") != "news-key" {
+			t.Errorf("Unexpected News API request %s", r.URL)
+		}
+		io.WriteString(w, `{"status": "ok", "totalResults": 2, "articles": [{"title": "Go 1.30 released"}, {"title": "Generics in practice"}]}`)
+	}))
+	defer news.Close()
+	routeConnectorHost(t, "newsapi.org", news.URL)
+
+	var mu sync.Mutex
+	var messages []string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		messages = append(messages, body.Text)
+		mu.Unlock()
+	}))
+	defer slack.Close()
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("chain@example.com", "hashed")
+	database.CreateCredential(user.ID, "newsapi", "news-key")
+	database.CreateCredential(user.ID, "slack", slack.URL)
+
+	workflow, err := database.CreateWorkflowWithChain(user.ID, "Headlines", "schedule", "testing", `{}`, `[
+		{"action_type": "news_fetch", "config": {"news_query": "golang"}},
+		{"action_type": "slack_message", "config": {"slack_message": "Top story: {{articles.0.title}}"}, "use_data_from": "previous"},
+		{"action_type": "slack_message", "config": {"slack_message": "{{count}} articles fetched"}, "use_data_from": "previous"}
+	]`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+
+	execution, err := database.GetLatestExecution(workflow.ID)
+	if err != nil {
+		t.Fatalf("Expected an execution record: %v", err)
+	}
+	if execution.Status != "success" {
+		t.Fatalf("Expected the chain to succeed, got %s: %s", execution.Status, execution.Message)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) != 2 || messages[0] != "Top story: Go 1.30 released" || messages[1] != "2 articles fetched" {
+		t.Errorf("Expected both messages rendered from the fetched articles, got %q", messages)
+	}
+}
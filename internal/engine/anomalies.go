@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/anomaly"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/notify"
+)
+
+// How often one workflow's anomalies are reported while they last
+const (
+	anomalyLogInterval    = time.Hour      // Warn events, per kind
+	anomalyNotifyInterval = 24 * time.Hour // Tenant notifications, any kind
+)
+
+// anomalyReports remembers when each workflow's anomalies were last reported
+type anomalyReports struct {
+	mu       sync.Mutex
+	logged   map[string]time.Time // Keyed by workflow ID and finding kind
+	notified map[string]time.Time // Keyed by workflow ID
+}
+
+func newAnomalyReports() *anomalyReports {
+	return &anomalyReports{logged: make(map[string]time.Time), notified: make(map[string]time.Time)}
+}
+
+// due reports whether key was last reported at least interval ago, and marks it reported if so
+func (r *anomalyReports) due(reported map[string]time.Time, key string, interval time.Duration, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := reported[key]; ok && now.Sub(last) < interval {
+		return false
+	}
+	reported[key] = now
+	return true
+}
+
+// SetNotifier sends anomaly alerts to tenants that asked for them through notifier
+func (e *Executor) SetNotifier(notifier *notify.Notifier) {
+	e.notifier = notifier
+}
+
+// checkAnomalies compares a workflow's latest executions with its history after they were recorded
+// Findings are logged as warn events and, when the tenant opted in, sent to its members
+func (e *Executor) checkAnomalies(workflow models.Workflow, tenantID string, now time.Time) {
+	settings, err := e.store.GetTenantSettings(tenantID)
+	if err != nil {
+		return
+	}
+	thresholds := anomaly.ThresholdsFor(settings.Anomaly)
+	if thresholds.Disabled {
+		return
+	}
+
+	baseline, err := e.store.GetWorkflowBaseline(workflow.ID, now.Add(-anomaly.HistoryWindow))
+	if err != nil {
+		return
+	}
+
+	var findings []anomaly.Finding
+	durations := anomaly.Durations{Recent: baseline.RecentDurationMS, Baseline: baseline.BaselineDurationMS, Samples: baseline.TimedExecutions}
+	if finding, found := anomaly.CheckDuration(durations, thresholds); found {
+		findings = append(findings, finding)
+	}
+	if finding, found := anomaly.CheckFailureRate(baseline.Hours, now, thresholds); found {
+		findings = append(findings, finding)
+	}
+
+	for _, finding := range findings {
+		if !e.anomalies.due(e.anomalies.logged, workflow.ID+"|"+finding.Kind, anomalyLogInterval, now) {
+			continue
+		}
+		e.log.WorkflowLog(
+			logger.LevelWarn,
+			"Workflow anomaly detected",
+			workflow.ID,
+			workflow.UserID,
+			tenantID,
+			map[string]interface{}{
+				"kind":     finding.Kind,
+				"observed": finding.Observed,
+				"expected": finding.Expected,
+				"detail":   anomaly.Describe(finding),
+			},
+		)
+
+		if settings.Anomaly != nil && settings.Anomaly.Notify && e.notifier != nil &&
+			e.anomalies.due(e.anomalies.notified, workflow.ID, anomalyNotifyInterval, now) {
+			message := fmt.Sprintf("GoFlow: workflow %q looks unhealthy: %s", workflow.Name, anomaly.Describe(finding))
+			go e.notifier.Alert(tenantID, message)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// EvaluateAssertions checks each assertion against result data
+// Paths are resolved with gjson, the same resolver the template engine uses,
+// so "items.0.name", "items.#" (length) and "items.#.name" (all names) work
+// Returns the per-assertion report and whether every assertion passed
+func EvaluateAssertions(data map[string]interface{}, assertions []models.Assertion) ([]models.AssertionResult, bool) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		dataJSON = []byte("{}")
+	}
+
+	results := make([]models.AssertionResult, 0, len(assertions))
+	allPassed := true
+	for _, assertion := range assertions {
+		result := evaluateAssertion(string(dataJSON), assertion)
+		if !result.Passed {
+			allPassed = false
+		}
+		results = append(results, result)
+	}
+
+	return results, allPassed
+}
+
+// evaluateAssertion checks a single assertion against JSON data
+func evaluateAssertion(dataJSON string, assertion models.Assertion) models.AssertionResult {
+	value := gjson.Get(dataJSON, assertion.Path)
+	result := models.AssertionResult{
+		Path:     assertion.Path,
+		Operator: assertion.Operator,
+		Expected: assertion.Value,
+	}
+	if value.Exists() {
+		result.Actual = value.Value()
+	}
+
+	fail := func(format string, args ...interface{}) models.AssertionResult {
+		result.Message = fmt.Sprintf("%s: "+format, append([]interface{}{assertion.Path}, args...)...)
+		return result
+	}
+	pass := func() models.AssertionResult {
+		result.Passed = true
+		result.Message = fmt.Sprintf("%s: %s check passed", assertion.Path, assertion.Operator)
+		return result
+	}
+
+	if assertion.Operator == "exists" {
+		want := true
+		if b, ok := assertion.Value.(bool); ok {
+			want = b
+		}
+		if value.Exists() != want {
+			if want {
+				return fail("expected path to exist")
+			}
+			return fail("expected path to be absent, got %s", value.Raw)
+		}
+		return pass()
+	}
+
+	if !value.Exists() {
+		return fail("path not found in response")
+	}
+
+	switch assertion.Operator {
+	case "eq":
+		if !valuesEqual(value, assertion.Value) {
+			return fail("expected %v, got %s", formatExpected(assertion.Value), value.Raw)
+		}
+	case "ne":
+		if valuesEqual(value, assertion.Value) {
+			return fail("expected anything but %v", formatExpected(assertion.Value))
+		}
+	case "gt", "lt":
+		actual, ok := numericValue(value)
+		if !ok {
+			return fail("expected a number, got %s", value.Raw)
+		}
+		expected, ok := toFloat(assertion.Value)
+		if !ok {
+			return fail("assertion value %v is not a number", formatExpected(assertion.Value))
+		}
+		if assertion.Operator == "gt" && !(actual > expected) {
+			return fail("expected > %v, got %v", expected, actual)
+		}
+		if assertion.Operator == "lt" && !(actual < expected) {
+			return fail("expected < %v, got %v", expected, actual)
+		}
+	case "contains":
+		if !valueContains(value, assertion.Value) {
+			return fail("expected to contain %v, got %s", formatExpected(assertion.Value), value.Raw)
+		}
+	default:
+		return fail("unknown operator %q (use eq, ne, gt, lt, contains, exists)", assertion.Operator)
+	}
+
+	return pass()
+}
+
+// valuesEqual compares a JSON value with an expected value from config
+func valuesEqual(value gjson.Result, expected interface{}) bool {
+	switch want := expected.(type) {
+	case nil:
+		return value.Type == gjson.Null
+	case bool:
+		return (value.Type == gjson.True || value.Type == gjson.False) && value.Bool() == want
+	case float64, int:
+		actual, ok := numericValue(value)
+		expectedNum, _ := toFloat(want)
+		return ok && actual == expectedNum
+	case string:
+		return value.String() == want
+	default:
+		// Objects/arrays: compare canonical JSON
+		wantJSON, err := json.Marshal(want)
+		if err != nil {
+			return false
+		}
+		return gjson.Parse(string(wantJSON)).String() == gjson.Parse(value.Raw).String()
+	}
+}
+
+// valueContains checks array membership or substring match
+func valueContains(value gjson.Result, expected interface{}) bool {
+	if value.IsArray() {
+		for _, item := range value.Array() {
+			if valuesEqual(item, expected) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(value.String(), fmt.Sprint(expected))
+}
+
+// numericValue reads a number, accepting numeric strings ("42") from loosely typed APIs
+func numericValue(value gjson.Result) (float64, bool) {
+	switch value.Type {
+	case gjson.Number:
+		return value.Num, true
+	case gjson.String:
+		f, err := strconv.ParseFloat(value.Str, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// toFloat converts a config value to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// formatExpected renders an expected value for assertion messages
+func formatExpected(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
+
+// applyAssertions evaluates an action's assertions and fails the result if any don't hold
+// Per-assertion pass/fail is recorded in Data["assertions"] for the execution trace
+func applyAssertions(result *connectors.Result, assertions []models.Assertion) {
+	if len(assertions) == 0 || result.Status != "success" {
+		return
+	}
+
+	report, allPassed := EvaluateAssertions(result.Data, assertions)
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+	result.Data["assertions"] = report
+
+	if allPassed {
+		result.Message = fmt.Sprintf("%s | Assertions: %d/%d passed", result.Message, len(report), len(report))
+		return
+	}
+
+	var failures []string
+	for _, r := range report {
+		if !r.Passed {
+			failures = append(failures, r.Message)
+		}
+	}
+	result.Status = "failed"
+	result.Message = fmt.Sprintf("Assertions failed (%d/%d): %s", len(failures), len(report), strings.Join(failures, "; "))
+	result.ErrorCode = connectors.ErrCodeAssertionFailed
+	result.ErrorParams = map[string]string{"failed": strconv.Itoa(len(failures)), "total": strconv.Itoa(len(report))}
+}
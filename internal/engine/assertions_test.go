@@ -0,0 +1,105 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestEvaluateAssertions covers each operator, array paths, and missing paths
+func TestEvaluateAssertions(t *testing.T) {
+	data := map[string]interface{}{
+		"status":  "ok",
+		"latency": 120.0,
+		"version": "42",
+		"healthy": true,
+		"error":   nil,
+		"articles": []interface{}{
+			map[string]interface{}{"title": "Go 1.22 released", "source": "golang"},
+			map[string]interface{}{"title": "SQLite tips", "source": "blog"},
+		},
+		"tags": []interface{}{"prod", "eu-west"},
+	}
+
+	tests := []struct {
+		name      string
+		assertion models.Assertion
+		passed    bool
+	}{
+		{"eq string", models.Assertion{Path: "status", Operator: "eq", Value: "ok"}, true},
+		{"eq string mismatch", models.Assertion{Path: "status", Operator: "eq", Value: "degraded"}, false},
+		{"eq number", models.Assertion{Path: "latency", Operator: "eq", Value: 120.0}, true},
+		{"eq bool", models.Assertion{Path: "healthy", Operator: "eq", Value: true}, true},
+		{"eq null", models.Assertion{Path: "error", Operator: "eq", Value: nil}, true},
+		{"ne", models.Assertion{Path: "status", Operator: "ne", Value: "down"}, true},
+		{"gt", models.Assertion{Path: "latency", Operator: "gt", Value: 100.0}, true},
+		{"lt fails", models.Assertion{Path: "latency", Operator: "lt", Value: 100.0}, false},
+		{"gt numeric string", models.Assertion{Path: "version", Operator: "gt", Value: 41.0}, true},
+		{"gt non-number", models.Assertion{Path: "status", Operator: "gt", Value: 1.0}, false},
+		{"contains substring", models.Assertion{Path: "articles.0.title", Operator: "contains", Value: "Go 1.22"}, true},
+		{"contains array element", models.Assertion{Path: "tags", Operator: "contains", Value: "prod"}, true},
+		{"contains array element missing", models.Assertion{Path: "tags", Operator: "contains", Value: "staging"}, false},
+		{"array index", models.Assertion{Path: "articles.1.source", Operator: "eq", Value: "blog"}, true},
+		{"array length", models.Assertion{Path: "articles.#", Operator: "gt", Value: 1.0}, true},
+		{"array projection contains", models.Assertion{Path: "articles.#.source", Operator: "contains", Value: "golang"}, true},
+		{"array index out of range", models.Assertion{Path: "articles.5.title", Operator: "exists"}, false},
+		{"exists", models.Assertion{Path: "status", Operator: "exists"}, true},
+		{"exists false on missing", models.Assertion{Path: "missing", Operator: "exists", Value: false}, true},
+		{"missing path fails eq", models.Assertion{Path: "missing.deep.path", Operator: "eq", Value: "x"}, false},
+		{"missing path fails ne", models.Assertion{Path: "missing", Operator: "ne", Value: "x"}, false},
+		{"unknown operator", models.Assertion{Path: "status", Operator: "matches", Value: "ok"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, allPassed := engine.EvaluateAssertions(data, []models.Assertion{tt.assertion})
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %d", len(results))
+			}
+			if results[0].Passed != tt.passed || allPassed != tt.passed {
+				t.Errorf("Passed = %v, want %v (%s)", results[0].Passed, tt.passed, results[0].Message)
+			}
+		})
+	}
+}
+
+// TestAssertionFailureFailsExecution verifies a failing assertion marks the run failed
+func TestAssertionFailureFailsExecution(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	workflow := models.Workflow{
+		ID:          "wf_monitor",
+		UserID:      "user_monitor",
+		Name:        "Synthetic monitor",
+		TriggerType: "schedule",
+		ActionType:  "testing",
+		ConfigJSON: `{
+			"testing_response_json": "{\"status\": \"degraded\", \"checks\": [1, 2]}",
+			"assertions": [
+				{"path": "status", "operator": "eq", "value": "ok"},
+				{"path": "checks.#", "operator": "eq", "value": 2}
+			]
+		}`,
+	}
+
+	result := executor.DryRun(workflow, workflow.UserID, "tenant_"+workflow.UserID)
+	if result.Status != "failed" {
+		t.Fatalf("Expected failed status, got %s: %s", result.Status, result.Message)
+	}
+	if !strings.Contains(result.Message, `expected "ok", got "degraded"`) {
+		t.Errorf("Expected readable assertion report, got %q", result.Message)
+	}
+
+	report, ok := result.Data["assertions"].([]models.AssertionResult)
+	if !ok || len(report) != 2 {
+		t.Fatalf("Expected 2 assertion results in data, got %v", result.Data["assertions"])
+	}
+	if report[0].Passed || !report[1].Passed {
+		t.Errorf("Expected [fail, pass], got [%v, %v]", report[0].Passed, report[1].Passed)
+	}
+}
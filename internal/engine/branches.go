@@ -0,0 +1,249 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// DefaultBranchConcurrencyFactor sizes the shared branch slots relative to the worker pool
+// 1 means parallel branches never make more outbound calls at once than there are workers
+const DefaultBranchConcurrencyFactor = 1.0
+
+// maxParallelBranches bounds how many branches one parallel step may declare
+const maxParallelBranches = 10
+
+// branchLimiter is a semaphore shared by the parallel branches of every running workflow
+// Branches that find it full queue in arrival order; resizing never interrupts a branch
+type branchLimiter struct {
+	mu      sync.Mutex
+	workers int
+	factor  float64
+	limit   int
+	active  int
+	waiters []chan struct{} // Closed when the waiter is handed a slot
+}
+
+func newBranchLimiter(workers int) *branchLimiter {
+	l := &branchLimiter{workers: workers, factor: DefaultBranchConcurrencyFactor}
+	l.limit = l.size()
+	return l
+}
+
+// size is the slot count for the current pool size and factor (at least 1)
+func (l *branchLimiter) size() int {
+	slots := int(math.Ceil(float64(l.workers) * l.factor))
+	if slots < 1 {
+		slots = 1
+	}
+	return slots
+}
+
+// resize follows a worker pool resize or a factor change (0 keeps the current value)
+func (l *branchLimiter) resize(workers int, factor float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if workers > 0 {
+		l.workers = workers
+	}
+	if factor > 0 {
+		l.factor = factor
+	}
+	l.limit = l.size()
+	l.grant()
+}
+
+// grant hands free slots to queued branches; callers hold mu
+func (l *branchLimiter) grant() {
+	for l.active < l.limit && len(l.waiters) > 0 {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+		l.active++
+	}
+}
+
+// acquire takes a slot, queueing until one frees up or ctx ends
+// Returns how long the branch waited
+func (l *branchLimiter) acquire(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	l.mu.Lock()
+	if l.active < l.limit && len(l.waiters) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return 0, nil
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, waiter := range l.waiters {
+			if waiter == ready {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				return time.Since(start), ctx.Err()
+			}
+		}
+		// Granted while giving up: hand the slot on
+		l.active--
+		l.grant()
+		return time.Since(start), ctx.Err()
+	}
+}
+
+// release returns a slot, handing it to the next queued branch if any
+func (l *branchLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.grant()
+}
+
+// stats reports the slot count, slots in use and queued branches
+func (l *branchLimiter) stats() (limit, active, queued int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit, l.active, len(l.waiters)
+}
+
+// SetBranchConcurrency sizes the shared parallel branch slots as factor × worker pool size
+func (e *Executor) SetBranchConcurrency(factor float64) {
+	e.branches.resize(0, factor)
+}
+
+// executeParallelStep runs a parallel step's branches at the same time, each holding one
+// of the shared branch slots while it runs. The step succeeds only if every branch does;
+// its data lists each branch's data so a following step can use it
+func (e *Executor) executeParallelStep(ctx context.Context, step int, action models.ChainedAction, userID, tenantID string, previousData map[string]interface{}, chainStart time.Time, budget time.Duration) connectors.Result {
+	start := time.Now()
+	e.log.Info("Executing parallel chain step", map[string]interface{}{
+		"chain_step": step,
+		"branches":   len(action.Parallel),
+		"user_id":    userID,
+		"tenant_id":  tenantID,
+	})
+
+	branches := make([]connectors.Result, len(action.Parallel))
+	var wg sync.WaitGroup
+	for i, branch := range action.Parallel {
+		wg.Add(1)
+		go func(i int, branch models.ChainedAction) {
+			defer wg.Done()
+			wait, err := e.branches.acquire(ctx)
+			if err != nil {
+				branches[i] = connectors.Result{
+					Status:    "cancelled",
+					Message:   fmt.Sprintf("Branch %d cancelled waiting for a branch slot: %v", i+1, err),
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+					WaitMS:    wait.Milliseconds(),
+				}
+				codeFailure(&branches[i], branch.ActionType)
+				return
+			}
+			defer e.branches.release()
+
+			branches[i] = e.runChainedAction(ctx, branch, userID, tenantID, previousData, chainStart, budget)
+			branches[i].WaitMS = wait.Milliseconds()
+		}(i, branch)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	var cost float64
+	data := make([]interface{}, len(branches))
+	for i, branch := range branches {
+		if branch.Status == "success" {
+			succeeded++
+		}
+		cost += branch.Cost
+		data[i] = branch.Data
+	}
+
+	result := connectors.Result{
+		Status:    "success",
+		Message:   fmt.Sprintf("%d of %d parallel branches succeeded", succeeded, len(branches)),
+		Data:      map[string]interface{}{"branches": data},
+		Duration:  time.Since(start).String(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Cost:      cost,
+		ElapsedMS: time.Since(start).Milliseconds(),
+		Branches:  branches,
+	}
+	if succeeded < len(branches) {
+		result.Status = "failed"
+		if ctx.Err() != nil {
+			result.Status = "cancelled"
+		}
+	}
+	if budget > 0 {
+		remaining := (budget - time.Since(chainStart)).Milliseconds()
+		if remaining < 0 {
+			remaining = 0
+		}
+		result.BudgetRemainingMS = &remaining
+	}
+	codeFailure(&result, "parallel")
+	return result
+}
+
+// labeledAction is a chained action with the name validation errors use for it
+type labeledAction struct {
+	label  string
+	action models.ChainedAction
+}
+
+// chainActions lists every action of a chain, parallel branches included
+func chainActions(chain []models.ChainedAction) []labeledAction {
+	var actions []labeledAction
+	for i, action := range chain {
+		if len(action.Parallel) == 0 {
+			actions = append(actions, labeledAction{fmt.Sprintf("action_chain step %d", i+1), action})
+			continue
+		}
+		for j, branch := range action.Parallel {
+			actions = append(actions, labeledAction{fmt.Sprintf("action_chain step %d, branch %d", i+1, j+1), branch})
+		}
+	}
+	return actions
+}
+
+// ValidateParallelSteps rejects parallel steps the executor can't run: branches must be
+// plain actions (no nested parallel steps), and a step may have at most 10 of them
+// Malformed JSON is left for the executor to report
+func ValidateParallelSteps(actionChainJSON string) error {
+	if actionChainJSON == "" {
+		return nil
+	}
+	var chain []models.ChainedAction
+	if err := json.Unmarshal([]byte(actionChainJSON), &chain); err != nil {
+		return nil
+	}
+	for i, action := range chain {
+		if len(action.Parallel) == 0 {
+			continue
+		}
+		if len(action.Parallel) > maxParallelBranches {
+			return fmt.Errorf("action_chain step %d: at most %d parallel branches are allowed (got %d)", i+1, maxParallelBranches, len(action.Parallel))
+		}
+		for j, branch := range action.Parallel {
+			if len(branch.Parallel) > 0 {
+				return fmt.Errorf("action_chain step %d, branch %d: parallel steps can't be nested", i+1, j+1)
+			}
+			if branch.ActionType == "" {
+				return fmt.Errorf("action_chain step %d, branch %d: action_type is required", i+1, j+1)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package engine_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestParallelBranchesBoundedConcurrency runs many parallel-branch workflows at once and
+// checks outbound calls never exceed the shared branch slots, while queued branches
+// report their wait
+func TestParallelBranchesBoundedConcurrency(t *testing.T) {
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	executor.ResizeWorkerPool(8)
+	executor.SetBranchConcurrency(0.5) // 4 branch slots
+
+	var inFlight, maxInFlight, calls int64
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt64(&inFlight, 1)
+		for {
+			seen := atomic.LoadInt64(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt64(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -1)
+		atomic.AddInt64(&calls, 1)
+	}))
+	defer slack.Close()
+
+	user, _ := database.CreateUser("branches@example.com", "hashed")
+	database.CreateCredential(user.ID, "slack", slack.URL)
+
+	const workflows, branches = 24, 5
+	branch := `{"action_type": "slack_message", "config": {"slack_message": "hi"}}`
+	chain := `[{"parallel": [` + strings.TrimSuffix(strings.Repeat(branch+",", branches), ",") + `]}]`
+
+	var ids []string
+	for i := 0; i < workflows; i++ {
+		workflow, err := database.CreateWorkflow(user.ID, "Fan out", "webhook", "testing", `{}`)
+		if err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+		workflow.ActionChain = chain
+		ids = append(ids, workflow.ID)
+		executor.ExecuteWorkflow(*workflow, "")
+	}
+
+	var waited bool
+	waitFor(t, "every workflow to finish", func() bool {
+		waited = false
+		for _, id := range ids {
+			execution, err := database.GetLatestExecution(id)
+			if err != nil {
+				return false
+			}
+			if execution.Status != "success" {
+				t.Fatalf("Expected every workflow to succeed, got %s: %s", execution.Status, execution.Message)
+			}
+			waited = waited || strings.Contains(execution.ResultData, `"wait_ms"`)
+		}
+		return true
+	})
+
+	if calls != workflows*branches {
+		t.Errorf("Expected %d outbound calls, got %d", workflows*branches, calls)
+	}
+	if maxInFlight > 4 {
+		t.Errorf("Expected at most 4 outbound calls at once, got %d", maxInFlight)
+	}
+	if maxInFlight < 2 {
+		t.Errorf("Expected branches to run in parallel, got at most %d at once", maxInFlight)
+	}
+	if !waited {
+		t.Error("Expected queued branches to report wait_ms in chain_results")
+	}
+	if status := executor.WorkerStatus(); status.BranchSlots != 4 || status.BranchActive != 0 || status.BranchQueued != 0 {
+		t.Errorf("Expected 4 idle branch slots, got %+v", status)
+	}
+}
+
+// TestParallelStepResults checks branch results, step status and data for a following step
+func TestParallelStepResults(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("parallel@example.com", "hashed")
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Parallel", "webhook", "testing", `{}`)
+	workflow.ActionChain = `[{"parallel": [
+			{"action_type": "testing", "config": {"testing_response_json": "{\"region\": \"eu\"}"}},
+			{"action_type": "testing", "config": {"testing_status_code": 503}}
+		]},
+		{"action_type": "testing", "use_data_from": "previous", "config": {"testing_response_json": "{\"first\": \"{{branches.0.region}}\"}"}}]`
+
+	result := executor.DryRun(*workflow, user.ID, "tenant_"+user.ID)
+	chainResults, ok := result.Data["chain_results"].([]connectors.Result)
+	if !ok || len(chainResults) != 2 {
+		t.Fatalf("Expected 2 chain results, got %v", result.Data["chain_results"])
+	}
+
+	parallel := chainResults[0]
+	if parallel.Status != "failed" || len(parallel.Branches) != 2 {
+		t.Fatalf("Expected a failed parallel step with 2 branches, got %+v", parallel)
+	}
+	if parallel.Branches[0].Status != "success" || parallel.Branches[1].ErrorCode != connectors.ErrCodeUpstreamHTTP {
+		t.Errorf("Expected branch 1 to succeed and branch 2 to fail upstream, got %+v", parallel.Branches)
+	}
+	if next := chainResults[1]; next.Data["first"] != "eu" {
+		t.Errorf("Expected the next step to read branch data, got %v", next.Data)
+	}
+}
+
+// TestValidateParallelSteps rejects nested and oversized parallel steps
+func TestValidateParallelSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		chain   string
+		wantErr string
+	}{
+		{"valid", `[{"parallel": [{"action_type": "slack_message"}, {"action_type": "discord_post"}]}]`, ""},
+		{"nested", `[{"parallel": [{"action_type": "testing", "parallel": [{"action_type": "testing"}]}]}]`, "can't be nested"},
+		{"missing action", `[{"action_type": "testing"}, {"parallel": [{"config": {}}]}]`, "step 2, branch 1: action_type is required"},
+		{"too many", `[{"parallel": [` + strings.TrimSuffix(strings.Repeat(`{"action_type": "testing"},`, 11), ",") + `]}]`, "at most 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.ValidateParallelSteps(tt.chain)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// Catch-up policies for scheduled runs missed while the server was down
+const (
+	CatchUpSkip     = "skip"     // Run at the next normal slot
+	CatchUpRunOnce  = "run_once" // One catch-up run at startup, however many were missed (the default)
+	CatchUpBackfill = "backfill" // Every missed run, oldest first, up to catch_up_max
+)
+
+// Backfill limits and the default spread of catch-up runs
+const (
+	DefaultCatchUpMax    = 10
+	MaxCatchUpMax        = 100
+	DefaultCatchUpJitter = 30 * time.Second
+)
+
+// catchUpKey marks a context whose execution is a catch-up run
+type catchUpKey struct{}
+
+// withCatchUp returns a context whose execution is recorded as a catch-up run
+func withCatchUp(ctx context.Context) context.Context {
+	return context.WithValue(ctx, catchUpKey{}, true)
+}
+
+// IsCatchUp reports whether execution is a scheduled run missed during downtime
+func IsCatchUp(ctx context.Context) bool {
+	catchUp, _ := ctx.Value(catchUpKey{}).(bool)
+	return catchUp
+}
+
+// ReconcileSummary is what startup reconciliation found and scheduled
+type ReconcileSummary struct {
+	Workflows   int            `json:"workflows"`     // Scheduled workflows that missed at least one run
+	Missed      int            `json:"missed"`        // Runs missed in total
+	Skipped     int            `json:"skipped"`       // Missed runs that won't be made up
+	CatchUpRuns int            `json:"catch_up_runs"` // Catch-up executions scheduled
+	Policies    map[string]int `json:"policies"`      // Workflows per catch-up policy
+}
+
+// ValidateCatchUp rejects catch-up settings the scheduler can't apply
+// Malformed JSON is left for the executor to report
+func ValidateCatchUp(configJSON string) error {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	switch config.CatchUp {
+	case "", CatchUpSkip, CatchUpRunOnce, CatchUpBackfill:
+	default:
+		return fmt.Errorf("catch_up must be %s, %s or %s", CatchUpSkip, CatchUpRunOnce, CatchUpBackfill)
+	}
+	if config.CatchUpMax < 0 || config.CatchUpMax > MaxCatchUpMax {
+		return fmt.Errorf("catch_up_max must be between 0 and %d", MaxCatchUpMax)
+	}
+	return nil
+}
+
+// MissedRuns returns the scheduled slots between the last run and now that never ran
+// Slots fall every interval after lastRun; one within grace of now isn't missed, the
+// next regular tick runs it. Only the latest limit slots are returned, oldest first,
+// alongside the total number missed
+func MissedRuns(lastRun time.Time, interval, grace time.Duration, now time.Time, limit int) ([]time.Time, int) {
+	if interval <= 0 {
+		return nil, 0
+	}
+	cutoff := now.Add(-grace)
+	if !cutoff.After(lastRun) {
+		return nil, 0
+	}
+	missed := int(cutoff.Sub(lastRun) / interval)
+	if missed == 0 {
+		return nil, 0
+	}
+	first := missed - limit + 1
+	if first < 1 {
+		first = 1
+	}
+	slots := make([]time.Time, 0, missed-first+1)
+	for k := first; k <= missed; k++ {
+		slots = append(slots, lastRun.Add(time.Duration(k)*interval))
+	}
+	return slots, missed
+}
+
+// SetCatchUpJitter spreads catch-up runs over up to this long after startup, so
+// workflows that all missed runs don't fire at once (0 runs them immediately)
+func (s *Scheduler) SetCatchUpJitter(jitter time.Duration) {
+	s.catchUpJitter = jitter
+}
+
+// Reconcile applies each scheduled workflow's catch-up policy to the runs it missed
+// while the server was down, and logs a summary
+// Every workflow's schedule moves to its latest missed slot first, so the regular tick
+// waits for the next slot instead of firing all overdue workflows at once
+func (s *Scheduler) Reconcile(now time.Time) ReconcileSummary {
+	summary := ReconcileSummary{Policies: make(map[string]int)}
+	s.mu.Lock()
+	grace := s.interval // A slot due within one tick is the regular tick's to run
+	s.mu.Unlock()
+
+	// Only workflows whose next run was due before the grace period can have missed one
+	workflows, err := s.store.GetDueWorkflows(now.Add(-grace))
+	if err != nil {
+		s.log.Error("Failed to fetch scheduled workflows for reconciliation", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return summary
+	}
+
+	for _, workflow := range workflows {
+		if workflow.LastExecutedAt == nil {
+			continue // Never ran, so nothing was missed; the first tick runs it
+		}
+		var config models.WorkflowConfig
+		if err := json.Unmarshal([]byte(workflow.ConfigJSON), &config); err != nil {
+			continue // The executor reports the bad config when it runs
+		}
+		interval, _ := workflow.ScheduleInterval()
+		policy := config.CatchUp
+		if policy == "" {
+			policy = CatchUpRunOnce
+		}
+		limit := 1
+		if policy == CatchUpBackfill {
+			limit = config.CatchUpMax
+			if limit <= 0 {
+				limit = DefaultCatchUpMax
+			}
+		}
+
+		slots, missed := MissedRuns(*workflow.LastExecutedAt, interval, grace, now, limit)
+		if missed == 0 {
+			continue
+		}
+		summary.Workflows++
+		summary.Missed += missed
+		summary.Policies[policy]++
+
+		latest := slots[len(slots)-1]
+		next := latest.Add(interval)
+		if err := s.store.UpdateWorkflowLastExecuted(workflow.ID, latest, &next); err != nil {
+			s.log.Warn("Failed to move schedule past missed runs", map[string]interface{}{
+				"workflow_id": workflow.ID,
+				"error":       err.Error(),
+			})
+			continue
+		}
+		if policy == CatchUpSkip {
+			summary.Skipped += missed
+			continue
+		}
+		summary.Skipped += missed - len(slots)
+		summary.CatchUpRuns += len(slots)
+		go s.catchUp(workflow, slots)
+	}
+
+	if summary.Workflows > 0 {
+		s.log.Info("Startup reconciliation", map[string]interface{}{
+			"workflows":     summary.Workflows,
+			"missed":        summary.Missed,
+			"skipped":       summary.Skipped,
+			"catch_up_runs": summary.CatchUpRuns,
+			"policies":      summary.Policies,
+			"jitter":        s.catchUpJitter.String(),
+		})
+	}
+	return summary
+}
+
+// catchUp runs a workflow once per missed slot, one after another, after a random delay
+func (s *Scheduler) catchUp(workflow models.Workflow, slots []time.Time) {
+	for _, slot := range slots {
+		if s.catchUpJitter > 0 {
+			select {
+			case <-time.After(time.Duration(rand.Int63n(int64(s.catchUpJitter)))):
+			case <-s.stopPending:
+				return
+			}
+		}
+
+		s.log.InfoWithContext(
+			"Running missed scheduled workflow",
+			workflow.UserID,
+			workflow.TenantID,
+			map[string]interface{}{
+				"workflow_id":   workflow.ID,
+				"workflow_name": workflow.Name,
+				"missed_slot":   slot.UTC().Format(time.RFC3339),
+			},
+		)
+		done := make(chan struct{})
+		s.executor.pool.Submit(WorkflowJob{
+			Workflow: workflow,
+			Executor: s.executor,
+			Run: func(ctx context.Context) {
+				defer close(done)
+				s.executor.ExecuteWorkflowWithContext(withCatchUp(ctx), workflow, "")
+			},
+		})
+		select {
+		case <-done:
+		case <-s.stopPending:
+			return
+		}
+	}
+}
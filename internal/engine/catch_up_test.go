@@ -0,0 +1,134 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestMissedRuns checks missed slots for outages of different lengths
+func TestMissedRuns(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	hour := time.Hour
+
+	cases := []struct {
+		name   string
+		outage time.Duration
+		limit  int
+		missed int
+		slots  int
+		grace  time.Duration
+		latest time.Duration // Latest returned slot, relative to the last run
+	}{
+		{"shorter than the interval", 20 * time.Minute, 1, 0, 0, 0, 0},
+		{"exactly one interval", hour, 1, 1, 1, 0, hour},
+		{"one interval, due within grace", hour, 1, 0, 0, time.Minute, 0},
+		{"three and a half hours", 3*hour + 30*time.Minute, 1, 3, 1, time.Minute, 3 * hour},
+		{"three and a half hours, backfill", 3*hour + 30*time.Minute, 10, 3, 3, time.Minute, 3 * hour},
+		{"two days, capped backfill", 48*hour + 10*time.Minute, 5, 48, 5, time.Minute, 48 * hour},
+	}
+	for _, c := range cases {
+		lastRun := now.Add(-c.outage)
+		slots, missed := engine.MissedRuns(lastRun, hour, c.grace, now, c.limit)
+		if missed != c.missed || len(slots) != c.slots {
+			t.Errorf("%s: expected %d missed and %d slots, got %d and %d", c.name, c.missed, c.slots, missed, len(slots))
+			continue
+		}
+		if len(slots) == 0 {
+			continue
+		}
+		if latest := slots[len(slots)-1]; !latest.Equal(lastRun.Add(c.latest)) {
+			t.Errorf("%s: expected the latest slot at %s, got %s", c.name, lastRun.Add(c.latest), latest)
+		}
+		for i := 1; i < len(slots); i++ {
+			if slots[i].Sub(slots[i-1]) != hour {
+				t.Errorf("%s: expected hourly slots oldest first, got %v", c.name, slots)
+			}
+		}
+	}
+}
+
+// TestReconcileCatchUpPolicies simulates outages against each policy and checks which
+// catch-up runs execute, that they're marked catch_up, and where schedules resume
+func TestReconcileCatchUpPolicies(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+	user, _ := database.CreateUser("catchup@example.com", "hashed")
+	now := time.Now()
+
+	type scenario struct {
+		config   string
+		outage   time.Duration // 0 = never ran
+		catchUps int
+	}
+	scenarios := map[string]scenario{
+		"skip":            {`{"interval": 60, "catch_up": "skip"}`, 3*time.Hour + 30*time.Minute, 0},
+		"run_once":        {`{"interval": 60, "catch_up": "run_once"}`, 3*time.Hour + 30*time.Minute, 1},
+		"default":         {`{"interval": 60}`, 3*time.Hour + 30*time.Minute, 1},
+		"backfill":        {`{"interval": 60, "catch_up": "backfill"}`, 3*time.Hour + 30*time.Minute, 3},
+		"capped backfill": {`{"interval": 60, "catch_up": "backfill", "catch_up_max": 2}`, 5*time.Hour + 30*time.Minute, 2},
+		"short outage":    {`{"interval": 60, "catch_up": "backfill"}`, 20 * time.Minute, 0},
+		"never ran":       {`{"interval": 60, "catch_up": "backfill"}`, 0, 0},
+	}
+	ids := make(map[string]string)
+	for name, sc := range scenarios {
+		workflow, err := database.CreateWorkflow(user.ID, name, "schedule", "testing", sc.config)
+		if err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+		if sc.outage > 0 {
+			lastRun := now.Add(-sc.outage)
+			next := lastRun.Add(time.Hour)
+			database.UpdateWorkflowLastExecuted(workflow.ID, lastRun, &next)
+		}
+		ids[name] = workflow.ID
+	}
+
+	scheduler := engine.NewScheduler(database, executor, testLogger)
+	scheduler.SetCatchUpJitter(0)
+	summary := scheduler.Reconcile(now)
+
+	if summary.Workflows != 5 || summary.Missed != 3+3+3+3+5 || summary.CatchUpRuns != 7 || summary.Skipped != 3+2+2+0+3 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if summary.Policies[engine.CatchUpSkip] != 1 || summary.Policies[engine.CatchUpRunOnce] != 2 || summary.Policies[engine.CatchUpBackfill] != 2 {
+		t.Errorf("Unexpected policy counts %+v", summary.Policies)
+	}
+
+	// Skipped workflows resume at their next normal slot
+	skipped, _ := database.GetWorkflowByID(ids["skip"])
+	if want := now.Add(-30 * time.Minute); skipped.LastExecutedAt == nil || skipped.LastExecutedAt.Sub(want).Abs() > time.Second {
+		t.Errorf("Expected the skipped schedule to move to its latest missed slot %s, got %v", want, skipped.LastExecutedAt)
+	}
+
+	catchUps := func() map[string]int {
+		counts := make(map[string]int)
+		database.EachExecution(user.ID, time.Time{}, func(execution *models.Execution) error {
+			if execution.CatchUp {
+				counts[execution.WorkflowID]++
+			}
+			return nil
+		})
+		return counts
+	}
+	waitFor(t, "catch-up runs to finish", func() bool {
+		counts, total := catchUps(), 0
+		for _, n := range counts {
+			total += n
+		}
+		return total >= summary.CatchUpRuns
+	})
+	time.Sleep(50 * time.Millisecond) // Nothing beyond the scheduled runs should follow
+
+	counts := catchUps()
+	for name, sc := range scenarios {
+		if counts[ids[name]] != sc.catchUps {
+			t.Errorf("%s: expected %d catch-up runs, got %d", name, sc.catchUps, counts[ids[name]])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// WorkflowTimeout is how long a queued workflow run may take in total, unless its
+// config sets timeout_seconds
+const WorkflowTimeout = 5 * time.Minute
+
+// StatusNotStarted marks chain steps skipped because the chain budget ran out
+const StatusNotStarted = "not_started"
+
+// executeChainStep runs one chained action within its time limit: the smaller of the
+// step's timeout_seconds and what is left of the chain budget (neither = the run's context)
+// A step stopped by its own limit fails with upstream_timeout while the run goes on
+func (e *Executor) executeChainStep(ctx context.Context, action models.ChainedAction, chainStart time.Time, budget time.Duration, run func(context.Context) connectors.Result) connectors.Result {
+	start := time.Now()
+	limit := time.Duration(action.TimeoutSeconds) * time.Second
+	if budget > 0 {
+		if remaining := budget - time.Since(chainStart); limit <= 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+
+	stepCtx := ctx
+	if limit > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+
+	result := run(stepCtx)
+	if result.Status == "cancelled" && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		service := notificationServices[action.ActionType]
+		if service == "" {
+			service = action.ActionType
+		}
+		timedOut := connectors.NewErrorResult(connectors.ErrCodeUpstreamTimeout, map[string]string{"service": service},
+			fmt.Sprintf("%s step stopped after its %s limit", action.ActionType, limit.Round(time.Millisecond)), start)
+		timedOut.DelayMS = result.DelayMS
+		result = timedOut
+	}
+
+	result.ElapsedMS = time.Since(start).Milliseconds()
+	if budget > 0 {
+		remaining := (budget - time.Since(chainStart)).Milliseconds()
+		if remaining < 0 {
+			remaining = 0
+		}
+		result.BudgetRemainingMS = &remaining
+	}
+	return result
+}
+
+// notStartedResult reports a chain step skipped because the budget was spent
+func notStartedResult(step int, actionType string, budget time.Duration) connectors.Result {
+	remaining := int64(0)
+	return connectors.Result{
+		Status:            StatusNotStarted,
+		Message:           fmt.Sprintf("Chain step %d (%s) not started: the %s chain budget is spent", step, actionType, budget),
+		Timestamp:         time.Now().UTC().Format(time.RFC3339),
+		BudgetRemainingMS: &remaining,
+	}
+}
+
+// ValidateChainTimeouts rejects step timeouts and budgets that can't all be honoured:
+// the summed step timeouts must fit in the chain budget, and both in the workflow timeout
+// (its timeout_seconds, or WorkflowTimeout)
+// Malformed JSON is left for the executor to report
+func (e *Executor) ValidateChainTimeouts(configJSON, actionChainJSON string) error {
+	workflowLimit := workflowTimeout(models.Workflow{ConfigJSON: configJSON}, WorkflowTimeout).limit
+	var budget time.Duration
+	if configJSON != "" {
+		var config models.WorkflowConfig
+		if err := json.Unmarshal([]byte(configJSON), &config); err == nil {
+			if config.BudgetSeconds < 0 {
+				return fmt.Errorf("budget_seconds must not be negative (got %d)", config.BudgetSeconds)
+			}
+			budget = time.Duration(config.BudgetSeconds) * time.Second
+		}
+	}
+	if budget > workflowLimit {
+		return fmt.Errorf("budget_seconds of %d exceeds the workflow timeout of %d seconds", int(budget.Seconds()), int(workflowLimit.Seconds()))
+	}
+
+	if actionChainJSON == "" {
+		return nil
+	}
+	var chain []models.ChainedAction
+	if err := json.Unmarshal([]byte(actionChainJSON), &chain); err != nil {
+		return nil
+	}
+
+	for _, step := range chainActions(chain) {
+		if step.action.TimeoutSeconds < 0 {
+			return fmt.Errorf("%s: timeout_seconds must not be negative (got %d)", step.label, step.action.TimeoutSeconds)
+		}
+	}
+
+	// Parallel branches run together, so a parallel step lasts as long as its slowest branch
+	var total time.Duration
+	for _, action := range chain {
+		timeout := action.TimeoutSeconds
+		for _, branch := range action.Parallel {
+			if branch.TimeoutSeconds > timeout {
+				timeout = branch.TimeoutSeconds
+			}
+		}
+		total += time.Duration(timeout) * time.Second
+	}
+	if budget > 0 && total > budget {
+		return fmt.Errorf("action_chain step timeouts add up to %d seconds, more than the budget_seconds of %d", int(total.Seconds()), int(budget.Seconds()))
+	}
+	if total > workflowLimit {
+		return fmt.Errorf("action_chain step timeouts add up to %d seconds, more than the workflow timeout of %d seconds", int(total.Seconds()), int(workflowLimit.Seconds()))
+	}
+	return nil
+}
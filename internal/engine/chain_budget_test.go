@@ -0,0 +1,101 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestChainBudgetExhaustedMidChain spends a 1s budget on two slow steps: the second
+// is cut short when the budget runs out and the third never starts
+func TestChainBudgetExhaustedMidChain(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("budget@example.com", "hashed")
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Budgeted", "webhook", "testing", `{"budget_seconds": 1}`)
+	workflow.ActionChain = `[{"action_type": "testing", "config": {"testing_delay": 600}},
+		{"action_type": "testing", "config": {"testing_delay": 600}},
+		{"action_type": "testing", "config": {}}]`
+
+	result := executor.DryRun(*workflow, user.ID, "tenant_"+user.ID)
+	chainResults, ok := result.Data["chain_results"].([]connectors.Result)
+	if !ok || len(chainResults) != 3 {
+		t.Fatalf("Expected 3 chain results, got %v", result.Data["chain_results"])
+	}
+
+	first, second, third := chainResults[0], chainResults[1], chainResults[2]
+	if first.Status != "success" || first.ElapsedMS < 600 || first.BudgetRemainingMS == nil || *first.BudgetRemainingMS > 400 {
+		t.Errorf("Expected the first step to finish within budget, got %+v", first)
+	}
+	if second.Status != "failed" || second.ErrorCode != connectors.ErrCodeUpstreamTimeout || second.ElapsedMS >= 600 {
+		t.Errorf("Expected the second step cut short by the budget, got %+v", second)
+	}
+	if second.BudgetRemainingMS == nil || *second.BudgetRemainingMS != 0 {
+		t.Errorf("Expected no budget left after the second step, got %v", second.BudgetRemainingMS)
+	}
+	if third.Status != engine.StatusNotStarted || !strings.Contains(third.Message, "not started") {
+		t.Errorf("Expected the third step not started, got %+v", third)
+	}
+}
+
+// TestChainStepTimeout stops a slow step at its own limit without a chain budget
+func TestChainStepTimeout(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("step@example.com", "hashed")
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Step Limit", "webhook", "testing", `{}`)
+	workflow.ActionChain = `[{"action_type": "testing", "timeout_seconds": 1, "config": {"testing_delay": 1500}},
+		{"action_type": "testing", "config": {"testing_delay": 20}}]`
+
+	result := executor.DryRun(*workflow, user.ID, "tenant_"+user.ID)
+	chainResults, ok := result.Data["chain_results"].([]connectors.Result)
+	if !ok || len(chainResults) != 2 {
+		t.Fatalf("Expected 2 chain results, got %v", result.Data["chain_results"])
+	}
+	if step := chainResults[0]; step.Status != "failed" || step.ErrorCode != connectors.ErrCodeUpstreamTimeout || step.ElapsedMS < 1000 || step.ElapsedMS >= 1500 {
+		t.Errorf("Expected the step stopped at its 1s limit, got %+v", step)
+	}
+	// Without a budget later steps run as before and no remaining budget is reported
+	if step := chainResults[1]; step.Status != "success" || step.BudgetRemainingMS != nil {
+		t.Errorf("Expected the next step to run normally, got %+v", step)
+	}
+}
+
+// TestValidateChainTimeouts rejects step timeouts that can't fit their budgets
+func TestValidateChainTimeouts(t *testing.T) {
+	executor := engine.NewExecutor(db.NewMockStore(), logger.NewLogger("test"))
+
+	tests := []struct {
+		name    string
+		config  string
+		chain   string
+		wantErr string
+	}{
+		{"no limits", `{}`, `[{"action_type": "testing"}]`, ""},
+		{"steps fit the budget", `{"budget_seconds": 30}`, `[{"action_type": "testing", "timeout_seconds": 10}, {"action_type": "testing", "timeout_seconds": 20}]`, ""},
+		{"steps exceed the budget", `{"budget_seconds": 20}`, `[{"action_type": "testing", "timeout_seconds": 10}, {"action_type": "testing", "timeout_seconds": 15}]`, "more than the budget_seconds of 20"},
+		{"steps exceed the workflow timeout", `{}`, `[{"action_type": "testing", "timeout_seconds": 200}, {"action_type": "testing", "timeout_seconds": 200}]`, "more than the workflow timeout"},
+		{"budget exceeds the workflow timeout", `{"budget_seconds": 600}`, ``, "exceeds the workflow timeout"},
+		{"negative step timeout", `{}`, `[{"action_type": "testing", "timeout_seconds": -1}]`, "step 1: timeout_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executor.ValidateChainTimeouts(tt.config, tt.chain)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
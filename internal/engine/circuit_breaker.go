@@ -1,8 +1,15 @@
 package engine
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
 )
 
 // CircuitBreakerState represents the state of a circuit breaker
@@ -42,10 +49,9 @@ func NewCircuitBreaker(maxFailures int, timeout time.Duration) *CircuitBreaker {
 }
 
 // Call executes a function with circuit breaker protection
+// The lock isn't held while fn runs, so concurrent calls through one breaker don't queue
 func (cb *CircuitBreaker) Call(fn func() error) error {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
-
 	// Check if circuit is open
 	if cb.state == StateOpen {
 		// Check if timeout has elapsed
@@ -55,16 +61,20 @@ func (cb *CircuitBreaker) Call(fn func() error) error {
 			cb.halfOpenAttempts = 0
 		} else {
 			// Circuit still open, reject immediately
+			cb.mu.Unlock()
 			return &CircuitBreakerError{
 				State:   StateOpen,
 				Message: "Circuit breaker is open, service unavailable",
 			}
 		}
 	}
+	cb.mu.Unlock()
 
 	// Execute the function
 	err := fn()
 
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
 	if err != nil {
 		// Record failure
 		cb.recordFailure()
@@ -121,6 +131,19 @@ func (cb *CircuitBreaker) GetFailures() int {
 	return cb.failures
 }
 
+// RetryAfter returns how long until an open circuit lets a call through again (0 if not open)
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	if cb.state != StateOpen {
+		return 0
+	}
+	if remaining := cb.timeout - time.Since(cb.lastFailureTime); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
 // Reset manually resets the circuit breaker (admin action)
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
@@ -192,7 +215,8 @@ func (m *CircuitBreakerManager) GetAllStates() map[string]CircuitBreakerState {
 }
 
 // ResetBreaker manually resets a specific circuit breaker
-func (m *CircuitBreakerManager) ResetBreaker(connectorKey string) {
+// Returns false if no breaker exists for the key
+func (m *CircuitBreakerManager) ResetBreaker(connectorKey string) bool {
 	m.mu.RLock()
 	breaker, exists := m.breakers[connectorKey]
 	m.mu.RUnlock()
@@ -200,5 +224,46 @@ func (m *CircuitBreakerManager) ResetBreaker(connectorKey string) {
 	if exists {
 		breaker.Reset()
 	}
+	return exists
 }
 
+// errFailedResult marks a connector result that should count against its breaker
+var errFailedResult = errors.New("connector call failed")
+
+// CircuitBreakers returns the executor's breakers, keyed by user and action type
+func (e *Executor) CircuitBreakers() *CircuitBreakerManager {
+	return e.breakers
+}
+
+// BreakerKey is the breaker a user's calls to an action type go through
+func BreakerKey(userID, actionType string) string {
+	return userID + ":" + actionType
+}
+
+// guarded runs a connector call through the user's breaker for the action type
+// A failed result counts against the breaker; while it is open, run isn't called at all
+// Dry runs bypass breakers: they neither trip one nor are stopped by one
+func (e *Executor) guarded(ctx context.Context, userID, actionType string, run func() connectors.Result) connectors.Result {
+	if isDryRun(ctx) {
+		return run()
+	}
+
+	breaker := e.breakers.GetBreaker(BreakerKey(userID, actionType))
+	var result connectors.Result
+	err := breaker.Call(func() error {
+		result = run()
+		if result.Status == "failed" {
+			return errFailedResult
+		}
+		return nil
+	})
+
+	var open *CircuitBreakerError
+	if errors.As(err, &open) {
+		retryAfter := strconv.Itoa(int(math.Ceil(breaker.RetryAfter().Seconds())))
+		return connectors.NewErrorResult(connectors.ErrCodeCircuitOpen,
+			map[string]string{"action": actionType, "retry_after": retryAfter},
+			fmt.Sprintf("circuit open for %s, retry after %ss", actionType, retryAfter), time.Now())
+	}
+	return result
+}
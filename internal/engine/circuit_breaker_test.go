@@ -0,0 +1,104 @@
+package engine_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// webhookStore is a mock store whose credentials decrypt to a real webhook URL
+type webhookStore struct {
+	*db.MockStore
+	url string
+}
+
+func (s *webhookStore) GetCredentialInEnvironment(tenantID, userID, serviceName, environment string) (*models.Credential, error) {
+	cred, err := s.MockStore.GetCredentialInEnvironment(tenantID, userID, serviceName, environment)
+	if cred != nil {
+		cred.DecryptedKey = s.url
+	}
+	return cred, err
+}
+
+// TestCircuitBreakerShortCircuits fails a Slack webhook five times in a row and checks the
+// sixth run is failed without a request, until the breaker is reset
+func TestCircuitBreakerShortCircuits(t *testing.T) {
+	var hits int32
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer slack.Close()
+
+	store := &webhookStore{MockStore: db.NewMockStore(), url: slack.URL}
+	executor := engine.NewExecutor(store, logger.NewLogger("test"))
+	user, _ := store.CreateUser("breaker@example.com", "hashed")
+	store.CreateCredential(user.ID, "slack", "https://hooks.slack.com/services/T000")
+	workflow, err := store.CreateWorkflow(user.ID, "Alerts", "webhook", "slack_message", `{"slack_message": "Disk full"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	other, _ := store.CreateUser("other@example.com", "hashed")
+	store.CreateCredential(other.ID, "slack:ops", "https://hooks.slack.com/services/T001") // Mock credential IDs are per service name
+	otherWorkflow, _ := store.CreateWorkflow(other.ID, "Theirs", "webhook", "slack_message", `{"credential": "slack:ops"}`)
+
+	latest := func(workflowID string) *models.Execution {
+		t.Helper()
+		execution, err := store.GetLatestExecution(workflowID)
+		if err != nil {
+			t.Fatalf("Expected an execution record: %v", err)
+		}
+		return execution
+	}
+
+	for i := 0; i < 5; i++ {
+		executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+	}
+	if atomic.LoadInt32(&hits) != 5 {
+		t.Fatalf("Expected 5 webhook requests, got %d", atomic.LoadInt32(&hits))
+	}
+	key := engine.BreakerKey(user.ID, "slack_message")
+	if state := executor.CircuitBreakers().GetAllStates()[key]; state != engine.StateOpen {
+		t.Fatalf("Expected the breaker to open after 5 failures, got %q", state)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+	if atomic.LoadInt32(&hits) != 5 {
+		t.Errorf("Expected the sixth run to make no request, got %d requests", atomic.LoadInt32(&hits))
+	}
+	if execution := latest(workflow.ID); execution.Status != "failed" || execution.Message != "circuit open for slack_message, retry after 60s" {
+		t.Errorf("Expected a circuit-open failure, got %s: %s", execution.Status, execution.Message)
+	}
+	logs, _ := store.GetLogsByWorkflowID(workflow.ID)
+	if len(logs) != 6 || logs[len(logs)-1].ErrorCode != connectors.ErrCodeCircuitOpen {
+		t.Errorf("Expected the last log to carry %s, got %+v", connectors.ErrCodeCircuitOpen, logs)
+	}
+
+	// Breakers are per user: another user's Slack calls still go out
+	executor.ExecuteWorkflowWithContext(context.Background(), *otherWorkflow, "")
+	if atomic.LoadInt32(&hits) != 6 {
+		t.Errorf("Expected another user's run to reach the webhook, got %d requests", atomic.LoadInt32(&hits))
+	}
+
+	// Dry runs neither trip nor respect an open breaker
+	if result := executor.DryRun(*workflow, user.ID, models.DefaultTenantID(user.ID)); strings.HasPrefix(result.Message, "circuit open") || atomic.LoadInt32(&hits) != 7 {
+		t.Errorf("Expected a dry run to bypass the breaker, got %q after %d requests", result.Message, atomic.LoadInt32(&hits))
+	}
+
+	if !executor.CircuitBreakers().ResetBreaker(key) {
+		t.Fatalf("Expected breaker %s to exist", key)
+	}
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+	if atomic.LoadInt32(&hits) != 8 {
+		t.Errorf("Expected a reset breaker to let the run through, got %d requests", atomic.LoadInt32(&hits))
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// headerNamePattern is an HTTP header field name (RFC 7230 token)
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// reservedConnectorHeaders are set by the HTTP client itself and can't be overridden
+var reservedConnectorHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+}
+
+// ValidateConnectorOptions rejects a connector_timeout_seconds outside 1-300 and
+// connector_headers that couldn't be sent
+// Malformed JSON is left for the executor to report
+func ValidateConnectorOptions(configJSON string) error {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	if timeout := time.Duration(config.ConnectorTimeoutSeconds) * time.Second; config.ConnectorTimeoutSeconds != 0 &&
+		(timeout < connectors.MinCallTimeout || timeout > connectors.MaxCallTimeout) {
+		return fmt.Errorf("connector_timeout_seconds: must be between %d and %d, got %d",
+			int(connectors.MinCallTimeout.Seconds()), int(connectors.MaxCallTimeout.Seconds()), config.ConnectorTimeoutSeconds)
+	}
+	for name, value := range config.ConnectorHeaders {
+		if !headerNamePattern.MatchString(name) {
+			return fmt.Errorf("connector_headers: %q is not a valid header name", name)
+		}
+		if reservedConnectorHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("connector_headers: %s is set by the HTTP client and can't be overridden", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("connector_headers: the value of %s must be on one line", name)
+		}
+	}
+	return nil
+}
+
+// connectorCallOptions are the outbound call overrides a workflow config sets
+func connectorCallOptions(config models.WorkflowConfig) connectors.CallOptions {
+	return connectors.CallOptions{
+		Timeout: time.Duration(config.ConnectorTimeoutSeconds) * time.Second,
+		Headers: config.ConnectorHeaders,
+	}
+}
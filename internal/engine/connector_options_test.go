@@ -0,0 +1,57 @@
+package engine_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestConnectorOptions checks connector_timeout_seconds and connector_headers are
+// validated, and that a run sends the workflow's headers
+func TestConnectorOptions(t *testing.T) {
+	for _, c := range []struct {
+		config string
+		reason string
+	}{
+		{`{"connector_timeout_seconds": 0}`, ""},
+		{`{"connector_timeout_seconds": 300, "connector_headers": {"X-Egress-Zone": "eu"}}`, ""},
+		{`{"connector_timeout_seconds": 301}`, "between 1 and 300"},
+		{`{"connector_timeout_seconds": -1}`, "between 1 and 300"},
+		{`{"connector_headers": {"Bad Name": "x"}}`, "not a valid header name"},
+		{`{"connector_headers": {"host": "internal"}}`, "can't be overridden"},
+		{`{"connector_headers": {"X-Note": "a\r\nX-Injected: 1"}}`, "on one line"},
+	} {
+		err := engine.ValidateConnectorOptions(c.config)
+		if (c.reason == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), c.reason)) {
+			t.Errorf("%s: expected %q, got %v", c.config, c.reason, err)
+		}
+	}
+
+	news := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Egress-Zone") != "eu" {
+			t.Errorf("Expected the workflow's header on the News API request, got %v", r.Header)
+		}
+		io.WriteString(w, `{"status": "ok", "totalResults": 0, "articles": []}`)
+	}))
+	defer news.Close()
+	routeConnectorHost(t, "newsapi.org", news.URL)
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("egress@example.com", "hashed")
+	database.CreateCredential(user.ID, "newsapi", "news-key")
+	workflow, _ := database.CreateWorkflow(user.ID, "Corporate news", "schedule", "news_fetch",
+		`{"news_query": "acme", "connector_timeout_seconds": 5, "connector_headers": {"X-Egress-Zone": "eu"}}`)
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+	if execution, err := database.GetLatestExecution(workflow.ID); err != nil || execution.Status != "success" {
+		t.Fatalf("Expected the run to succeed, got %+v (%v)", execution, err)
+	}
+}
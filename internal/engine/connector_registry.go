@@ -0,0 +1,287 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// Connectors returns the registry of action types this executor can run
+// Registering a connector makes it valid in workflows and chains and listed by GET /api/connectors
+func (e *Executor) Connectors() *connectors.Registry {
+	return e.connectors
+}
+
+// builtinConnectors are the connectors every executor starts with
+func (e *Executor) builtinConnectors() []connectors.Connector {
+	// notification runs the action with its fallbacks (see executeNotification)
+	notification := func(actionType string) func(context.Context, connectors.Call) connectors.Result {
+		return func(ctx context.Context, call connectors.Call) connectors.Result {
+			return e.executeNotification(ctx, actionType, call.UserID, call.TenantID, call.Config, call.Payload)
+		}
+	}
+	// fetch runs an action that doesn't read the trigger payload
+	fetch := func(run func(context.Context, string, string, models.WorkflowConfig) connectors.Result) func(context.Context, connectors.Call) connectors.Result {
+		return func(ctx context.Context, call connectors.Call) connectors.Result {
+			return run(ctx, call.UserID, call.TenantID, call.Config)
+		}
+	}
+	// templated runs an action that renders templates against the trigger payload
+	templated := func(run func(context.Context, string, string, models.WorkflowConfig, string) connectors.Result) func(context.Context, connectors.Call) connectors.Result {
+		return func(ctx context.Context, call connectors.Call) connectors.Result {
+			return run(ctx, call.UserID, call.TenantID, call.Config, call.Payload)
+		}
+	}
+
+	return []connectors.Connector{
+		{
+			ActionType: "slack_message", Label: "Slack message", Credential: "slack",
+			Fields: []connectors.ConfigField{
+				{Name: "slack_message", Description: "Message text (supports templates)"},
+				{Name: "slack_blocks", Description: "Block Kit array"},
+				{Name: "slack_channel", Description: "Channel; required with a bot token"},
+				{Name: "slack_thread_ts", Description: "Reply in this thread"},
+			},
+			Execute: notification("slack_message"),
+		},
+		{
+			ActionType: "discord_post", Label: "Discord post", Credential: "discord",
+			Fields: []connectors.ConfigField{
+				{Name: "discord_message", Description: "Message text (supports templates)"},
+				{Name: "discord_embed", Description: "Rich embed"},
+				{Name: "discord_username", Description: "Overrides the webhook's name"},
+				{Name: "discord_avatar_url", Description: "Overrides the webhook's avatar"},
+			},
+			Execute: notification("discord_post"),
+		},
+		{
+			ActionType: "twilio_sms", Label: "Twilio SMS", Credential: "twilio",
+			Fields: []connectors.ConfigField{
+				{Name: "twilio_to", Required: true, Description: "Recipient phone number(s)"},
+				{Name: "twilio_message", Required: true, Description: "Message text (supports templates)"},
+				{Name: "twilio_default_country_code", Description: "Country code for national numbers"},
+				{Name: "twilio_channel", Description: "sms (default) or whatsapp"},
+				{Name: "twilio_status_callback", Description: "Delivery status URL"},
+			},
+			Execute: notification("twilio_sms"),
+		},
+		{
+			ActionType: "testing", Label: "Testing",
+			Fields: []connectors.ConfigField{
+				{Name: "testing_response_json", Description: "JSON response to return"},
+				{Name: "testing_status_code", Description: "HTTP status code (default 200)"},
+				{Name: "testing_delay", Description: "Delay in milliseconds"},
+				{Name: "testing_headers", Description: "Response headers"},
+			},
+			Execute: notification("testing"),
+		},
+		{
+			ActionType: "news_fetch", Label: "News API", Credential: "newsapi",
+			Fields: []connectors.ConfigField{
+				{Name: "news_query", Description: "Search query"},
+				{Name: "news_country", Description: "Country code"},
+				{Name: "news_category", Description: "Category"},
+				{Name: "news_page_size", Description: "Number of articles (default 10)"},
+				{Name: "news_sources", Description: "Comma-separated source IDs"},
+			},
+			Execute: fetch(e.executeNewsAPIAction),
+		},
+		{
+			ActionType: "cat_fetch", Label: "The Cat API", Credential: "catapi", CredentialOptional: true,
+			Fields: []connectors.ConfigField{
+				{Name: "cat_limit", Description: "Number of images (default 1)"},
+				{Name: "cat_has_breeds", Description: "Only cats with breed info"},
+				{Name: "cat_breed_id", Description: "Breed"},
+				{Name: "cat_category", Description: "Category"},
+			},
+			Execute: fetch(e.executeCatAPIAction),
+		},
+		{
+			ActionType: "fakestore_fetch", Label: "Fake Store API",
+			Fields: []connectors.ConfigField{
+				{Name: "fakestore_endpoint", Description: "products, users or carts"},
+				{Name: "fakestore_limit", Description: "Number of items"},
+				{Name: "fakestore_category", Description: "Product category"},
+			},
+			Execute: fetch(e.executeFakeStoreAction),
+		},
+		{
+			ActionType: "weather_check", Label: "OpenWeather", Credential: "openweather",
+			Fields: []connectors.ConfigField{
+				{Name: "city", Description: "City (default London)"},
+			},
+			Execute: fetch(e.executeWeatherAction),
+		},
+		{
+			ActionType: "soap_call", Label: "SOAP call", Credential: "soap", CredentialOptional: true,
+			Fields: []connectors.ConfigField{
+				{Name: "soap_endpoint", Required: true, Description: "SOAP service URL"},
+				{Name: "soap_method", Required: true, Description: "Method name"},
+				{Name: "soap_action", Description: "SOAPAction header"},
+				{Name: "soap_namespace", Description: "XML namespace"},
+				{Name: "soap_parameters", Description: "Method parameters"},
+				{Name: "soap_version", Description: "1.1 (default) or 1.2"},
+				{Name: "soap_ws_security", Description: "Send a WS-Security UsernameToken"},
+			},
+			Execute: fetch(e.executeSOAPAction),
+		},
+		{
+			ActionType: "swapi_fetch", Label: "Star Wars API",
+			Fields: []connectors.ConfigField{
+				{Name: "swapi_resource", Description: "films, people, planets, species, vehicles or starships"},
+				{Name: "swapi_id", Description: "Resource ID"},
+				{Name: "swapi_search", Description: "Search query"},
+				{Name: "swapi_page", Description: "1-based page"},
+				{Name: "swapi_limit", Description: "Items per page"},
+				{Name: "swapi_fields", Description: "Keep only these keys"},
+			},
+			Execute: fetch(e.executeSWAPIAction),
+		},
+		{
+			ActionType: "salesforce", Label: "Salesforce", Credential: "salesforce",
+			Fields: []connectors.ConfigField{
+				{Name: "salesforce_operation", Required: true, Description: "query, create, get, update or delete"},
+				{Name: "salesforce_object", Description: "Object type (e.g. Account)"},
+				{Name: "salesforce_record_id", Description: "Record ID for get, update and delete"},
+				{Name: "salesforce_query", Description: "SOQL query"},
+				{Name: "salesforce_data", Description: "Fields for create and update"},
+			},
+			Execute: fetch(e.executeSalesforceAction),
+		},
+		{
+			ActionType: "http_request", Label: "HTTP request",
+			Fields: []connectors.ConfigField{
+				{Name: "http_url", Required: true, Description: "http(s) URL (supports templates)"},
+				{Name: "http_method", Description: "GET (default), HEAD, POST, PUT, PATCH or DELETE"},
+				{Name: "http_headers", Description: "Request headers"},
+				{Name: "http_query", Description: "Query string parameters"},
+				{Name: "http_body", Description: "Request body"},
+				{Name: "http_auth_credential", Description: "Credential for bearer or basic auth"},
+				{Name: "http_timeout", Description: "Seconds (default 30)"},
+			},
+			Execute: templated(e.executeHTTPAction),
+		},
+		{
+			ActionType: "email_send", Label: "Email", Credential: "smtp",
+			Fields: []connectors.ConfigField{
+				{Name: "email_to", Required: true, Description: "Recipient address(es)"},
+				{Name: "email_subject", Required: true, Description: "Subject (supports templates)"},
+				{Name: "email_body", Description: "Plain text body (supports templates)"},
+				{Name: "email_html_body", Description: "HTML body (supports templates)"},
+			},
+			Execute: templated(e.executeEmailAction),
+		},
+		{
+			ActionType: "github", Label: "GitHub", Credential: "github",
+			Fields: []connectors.ConfigField{
+				{Name: "github_operation", Required: true, Description: "create_issue, comment_issue, list_issues or get_repo"},
+				{Name: "github_owner", Required: true, Description: "User or organization"},
+				{Name: "github_repo", Required: true, Description: "Repository name"},
+				{Name: "github_issue_number", Description: "Issue to comment on"},
+				{Name: "github_title", Description: "New issue title (supports templates)"},
+				{Name: "github_body", Description: "Issue or comment body (supports templates)"},
+				{Name: "github_labels", Description: "Labels"},
+			},
+			Execute: templated(e.executeGitHubAction),
+		},
+		{
+			ActionType: "jira", Label: "Jira", Credential: "jira",
+			Fields: []connectors.ConfigField{
+				{Name: "jira_operation", Required: true, Description: "create_issue, add_comment, transition_issue or search"},
+				{Name: "jira_project_key", Description: "Project of a new issue"},
+				{Name: "jira_issue_type", Description: "Type of a new issue (default Task)"},
+				{Name: "jira_summary", Description: "New issue summary (supports templates)"},
+				{Name: "jira_description", Description: "Issue description or comment (supports templates)"},
+				{Name: "jira_issue_key", Description: "Issue to comment on or transition (supports templates)"},
+				{Name: "jira_transition", Description: "Transition name or ID"},
+				{Name: "jira_jql", Description: "JQL query to search with"},
+			},
+			Execute: templated(e.executeJiraAction),
+		},
+	}
+}
+
+// builtinValidators are the connector-specific checks of built-in configs
+var builtinValidators = map[string]func(actionType, configJSON string) error{
+	"slack_message": ValidateSlackMessage,
+	"twilio_sms":    ValidateTwilioMessage,
+	"soap_call":     ValidateSOAPCall,
+	"http_request":  ValidateHTTPRequest,
+}
+
+// newConnectorRegistry creates a registry holding the built-in connectors
+func (e *Executor) newConnectorRegistry() *connectors.Registry {
+	registry := connectors.NewRegistry()
+	for _, c := range e.builtinConnectors() {
+		c.Chainable = true
+		c.DecodeConfig = workflowConfigDecoder(c.ActionType, c.Fields, builtinValidators[c.ActionType])
+		if err := registry.Register(c); err != nil {
+			panic(err) // Built-in connectors are registered once, with distinct action types
+		}
+	}
+	return registry
+}
+
+// workflowConfigDecoder decodes a built-in connector's config into a models.WorkflowConfig,
+// requiring its required fields and running its validator, if any
+func workflowConfigDecoder(actionType string, fields []connectors.ConfigField, validate func(actionType, configJSON string) error) func([]byte) (interface{}, error) {
+	return func(raw []byte) (interface{}, error) {
+		var config models.WorkflowConfig
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("Invalid config_json: %v", err)
+		}
+		if err := checkRequiredFields(actionType, fields, raw); err != nil {
+			return nil, err
+		}
+		if validate != nil {
+			if err := validate(actionType, string(raw)); err != nil {
+				return nil, err
+			}
+		}
+		return config, nil
+	}
+}
+
+// checkRequiredFields rejects a config missing a required field, or leaving it empty
+func checkRequiredFields(actionType string, fields []connectors.ConfigField, raw []byte) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return fmt.Errorf("Invalid config_json: %v", err)
+	}
+	for _, field := range fields {
+		if !field.Required {
+			continue
+		}
+		switch value := values[field.Name].(type) {
+		case nil:
+		case string:
+			if strings.TrimSpace(value) != "" {
+				continue
+			}
+		case []interface{}:
+			if len(value) > 0 {
+				continue
+			}
+		default:
+			continue
+		}
+		return fmt.Errorf("%s is required for %s", field.Name, actionType)
+	}
+	return nil
+}
+
+// ValidateAction checks a workflow's action type and config against its connector
+func (e *Executor) ValidateAction(actionType, configJSON string) error {
+	if _, err := e.connectors.Decode(actionType, []byte(configJSON)); err != nil {
+		if errors.Is(err, connectors.ErrUnknownAction) {
+			return errors.New("Invalid action_type")
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// SelectConnectorVersion picks the connector version an execution runs
+// A workflow pin always wins; otherwise the tenant's canary for the action type takes
+// the workflows whose bucket falls under its percentage, and the rest run the current version
+func SelectConnectorVersion(actionType, pin, workflowID string, canaries map[string]models.ConnectorCanary) (string, error) {
+	versions := connectors.VersionsFor(actionType)
+	if pin != "" && pin != connectors.LatestVersion {
+		if !versions.Supports(pin) {
+			return "", fmt.Errorf("%s has no connector version %q (supported: %v)", actionType, pin, versions.Versions)
+		}
+		return pin, nil
+	}
+	if canary, ok := canaries[actionType]; ok && versions.Supports(canary.Version) && ConnectorBucket(workflowID) < canary.Percent {
+		return canary.Version, nil
+	}
+	return versions.Current, nil
+}
+
+// ConnectorBucket places a workflow in one of 100 canary buckets
+// The bucket depends only on the workflow ID, so raising a canary's percentage only ever
+// moves workflows onto the new version
+func ConnectorBucket(workflowID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(workflowID))
+	return int(h.Sum32() % 100)
+}
+
+// ValidateConnectorVersion rejects a pinned connector version the action type doesn't have
+// Malformed JSON is left for the executor to report
+func ValidateConnectorVersion(actionType, configJSON string) error {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	_, err := SelectConnectorVersion(actionType, config.ConnectorVersion, "", nil)
+	return err
+}
+
+// ValidateCanary rejects a canary whose version or percentage can't be applied
+func ValidateCanary(actionType string, canary models.ConnectorCanary) error {
+	versions := connectors.VersionsFor(actionType)
+	if !versions.Supports(canary.Version) {
+		return fmt.Errorf("%s has no connector version %q (supported: %v)", actionType, canary.Version, versions.Versions)
+	}
+	if canary.Percent < 0 || canary.Percent > 100 {
+		return fmt.Errorf("%s: percent must be between 0 and 100", actionType)
+	}
+	return nil
+}
+
+// ConnectorVersionStats counts executions of one action type on one connector version
+type ConnectorVersionStats struct {
+	ActionType string `json:"action_type"`
+	Version    string `json:"version"`
+	Executions int64  `json:"executions"`
+	Failures   int64  `json:"failures"`
+}
+
+// versionStats counts executions per action type and connector version
+// so a canary's failure rate can be compared with the current version's
+type versionStats struct {
+	mu     sync.Mutex
+	counts map[[2]string]*ConnectorVersionStats
+}
+
+func newVersionStats() *versionStats {
+	return &versionStats{counts: make(map[[2]string]*ConnectorVersionStats)}
+}
+
+// record counts one execution's outcome
+func (s *versionStats) record(actionType, version, status string) {
+	if version == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{actionType, version}
+	stats, ok := s.counts[key]
+	if !ok {
+		stats = &ConnectorVersionStats{ActionType: actionType, Version: version}
+		s.counts[key] = stats
+	}
+	stats.Executions++
+	if status == "failed" {
+		stats.Failures++
+	}
+}
+
+// snapshot returns the counts sorted by action type and version
+func (s *versionStats) snapshot() []ConnectorVersionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]ConnectorVersionStats, 0, len(s.counts))
+	for _, stats := range s.counts {
+		out = append(out, *stats)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ActionType != out[j].ActionType {
+			return out[i].ActionType < out[j].ActionType
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
+
+// ConnectorVersionStats returns execution and failure counts per connector version since startup
+func (e *Executor) ConnectorVersionStats() []ConnectorVersionStats {
+	return e.versions.snapshot()
+}
+
+// connectorVersion resolves the version a workflow's action runs
+// Tenant settings are only read for action types that have more than one version
+func (e *Executor) connectorVersion(workflow models.Workflow, tenantID string, config models.WorkflowConfig) (string, error) {
+	var canaries map[string]models.ConnectorCanary
+	if len(connectors.VersionsFor(workflow.ActionType).Versions) > 1 {
+		settings, err := e.store.GetTenantSettings(tenantID)
+		if err != nil {
+			// Without the canary the workflow still runs, on the current version
+			e.log.Warn("Failed to load connector canaries", map[string]interface{}{
+				"tenant_id": tenantID,
+				"error":     err.Error(),
+			})
+		} else {
+			canaries = settings.Canaries
+		}
+	}
+	return SelectConnectorVersion(workflow.ActionType, config.ConnectorVersion, workflow.ID, canaries)
+}
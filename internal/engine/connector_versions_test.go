@@ -0,0 +1,137 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestSelectConnectorVersionPrecedence checks a workflow pin beats the tenant's canary,
+// and the canary only applies to "latest" workflows
+func TestSelectConnectorVersionPrecedence(t *testing.T) {
+	all := map[string]models.ConnectorCanary{"salesforce": {Version: "v2", Percent: 100}}
+	none := map[string]models.ConnectorCanary{"salesforce": {Version: "v2", Percent: 0}}
+
+	cases := []struct {
+		name     string
+		pin      string
+		canaries map[string]models.ConnectorCanary
+		want     string
+	}{
+		{"no pin, no canary", "", nil, "v1"},
+		{"latest, no canary", "latest", nil, "v1"},
+		{"canary at 100%", "", all, "v2"},
+		{"canary at 0%", "latest", none, "v1"},
+		{"pin beats canary", "v1", all, "v1"},
+		{"pin without canary", "v2", none, "v2"},
+	}
+	for _, c := range cases {
+		got, err := engine.SelectConnectorVersion("salesforce", c.pin, "wf-1", c.canaries)
+		if err != nil || got != c.want {
+			t.Errorf("%s: expected %s, got %s (%v)", c.name, c.want, got, err)
+		}
+	}
+
+	if _, err := engine.SelectConnectorVersion("salesforce", "v9", "wf-1", nil); err == nil {
+		t.Error("Expected an unsupported pin to be rejected")
+	}
+	// Action types without declared versions run v1; a canary for a version they lack is ignored
+	if got, _ := engine.SelectConnectorVersion("slack_message", "", "wf-1", map[string]models.ConnectorCanary{"slack_message": {Version: "v2", Percent: 100}}); got != "v1" {
+		t.Errorf("Expected slack_message to stay on v1, got %s", got)
+	}
+}
+
+// TestConnectorBucketDeterministic checks buckets are stable per workflow ID, so the
+// same workflows take the canary on every execution, and roughly match the percentage
+func TestConnectorBucketDeterministic(t *testing.T) {
+	canaries := map[string]models.ConnectorCanary{"salesforce": {Version: "v2", Percent: 20}}
+
+	onCanary := 0
+	const workflows = 2000
+	for i := 0; i < workflows; i++ {
+		id := fmt.Sprintf("workflow-%d", i)
+		first, _ := engine.SelectConnectorVersion("salesforce", "", id, canaries)
+		for j := 0; j < 3; j++ {
+			if again, _ := engine.SelectConnectorVersion("salesforce", "", id, canaries); again != first {
+				t.Fatalf("%s moved from %s to %s between executions", id, first, again)
+			}
+		}
+		if first == "v2" {
+			onCanary++
+		}
+
+		// Raising the percentage only moves workflows onto the canary
+		wider := map[string]models.ConnectorCanary{"salesforce": {Version: "v2", Percent: 50}}
+		if widened, _ := engine.SelectConnectorVersion("salesforce", "", id, wider); first == "v2" && widened != "v2" {
+			t.Fatalf("%s left the canary when its percentage went up", id)
+		}
+	}
+
+	if share := float64(onCanary) / workflows; share < 0.15 || share > 0.25 {
+		t.Errorf("Expected about 20%% of workflows on the canary, got %.1f%%", share*100)
+	}
+}
+
+// TestConnectorVersionRecorded checks the executor calls the Salesforce API version of
+// the selected connector version and records that version in the trace and stats
+func TestConnectorVersionRecorded(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	salesforce := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"totalSize": 0, "done": true, "records": []}`))
+	}))
+	defer salesforce.Close()
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("versions@example.com", "hashed")
+	database.CreateCredential(user.ID, "salesforce", `{"instance_url": "`+salesforce.URL+`", "access_token": "token"}`)
+	database.SaveTenantSettings(&models.TenantSettings{
+		TenantID: models.DefaultTenantID(user.ID),
+		Canaries: map[string]models.ConnectorCanary{"salesforce": {Version: "v2", Percent: 100}},
+	})
+
+	for _, pin := range []string{"", "v1"} {
+		config, _ := json.Marshal(map[string]string{
+			"salesforce_operation": "query",
+			"salesforce_query":     "SELECT Id FROM Account",
+			"connector_version":    pin,
+		})
+		workflow, _ := database.CreateWorkflow(user.ID, "Accounts", "schedule", "salesforce", string(config))
+		executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+
+		want := map[string]string{"": "v2", "v1": "v1"}[pin]
+		execution, err := database.GetLatestExecution(workflow.ID)
+		if err != nil {
+			t.Fatalf("Expected an execution trace: %v", err)
+		}
+		if !strings.Contains(execution.ResultData, `"connector_version":"`+want+`"`) {
+			t.Errorf("pin %q: expected connector_version %s in the trace, got %s", pin, want, execution.ResultData)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 2 || !strings.Contains(paths[0], "/v60.0/") || !strings.Contains(paths[1], "/v59.0/") {
+		t.Errorf("Expected the canary to call v60.0 and the pinned workflow v59.0, got %v", paths)
+	}
+
+	stats := executor.ConnectorVersionStats()
+	if len(stats) != 2 || stats[0].Version != "v1" || stats[1].Version != "v2" || stats[0].Executions != 1 || stats[1].Executions != 1 {
+		t.Errorf("Expected one salesforce execution on each version, got %+v", stats)
+	}
+}
@@ -86,9 +86,7 @@ func (b *BoredAPIConnector) ExecuteWithContext(ctx context.Context, config Bored
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := HTTPClients().Client("boredapi", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -99,25 +97,25 @@ func (b *BoredAPIConnector) ExecuteWithContext(ctx context.Context, config Bored
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Bored API request failed: %v", err), start)
+		return NewRequestErrorResult("Bored", err, fmt.Sprintf("Bored API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read Bored API response: %v", err), start)
+		return NewInvalidResponseResult("Bored API", fmt.Sprintf("Failed to read Bored API response: %v", err), start)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Bored API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Bored API", resp.StatusCode, fmt.Sprintf("Bored API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	// Parse JSON response
 	var activityData map[string]interface{}
 	if err := json.Unmarshal(body, &activityData); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse Bored API response: %v", err), start)
+		return NewInvalidResponseResult("Bored API", fmt.Sprintf("Failed to parse Bored API response: %v", err), start)
 	}
 
 	// Extract activity for logging
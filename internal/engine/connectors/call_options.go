@@ -0,0 +1,61 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Bounds of a workflow's own connector timeout
+const (
+	MinCallTimeout = 1 * time.Second
+	MaxCallTimeout = 300 * time.Second
+)
+
+// CallOptions are a workflow's overrides for its connector's outbound requests
+type CallOptions struct {
+	Timeout time.Duration     // Replaces the connector's default timeout (0 = keep it)
+	Headers map[string]string // Added to each request, except headers the connector sets itself
+}
+
+type callOptionsKey struct{}
+
+// WithCallOptions applies options to the requests connectors send with ctx
+func WithCallOptions(ctx context.Context, options CallOptions) context.Context {
+	if options.Timeout <= 0 && len(options.Headers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, callOptionsKey{}, options)
+}
+
+// callOptions are the options set on ctx, if any
+func callOptions(ctx context.Context) CallOptions {
+	options, _ := ctx.Value(callOptionsKey{}).(CallOptions)
+	return options
+}
+
+// doCall sends a connector's request with the call options on its context
+// A timeout error names the limit that fired: the workflow's or the connector's default
+func doCall(client *http.Client, req *http.Request) (*http.Response, error) {
+	options := callOptions(req.Context())
+	for name, value := range options.Headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	limit := "the connector's default timeout"
+	if options.Timeout > 0 {
+		configured := *client
+		configured.Timeout = options.Timeout
+		client = &configured
+		limit = "the workflow's connector_timeout_seconds"
+	}
+
+	resp, err := client.Do(req)
+	if err != nil && isTimeout(err) && req.Context().Err() == nil {
+		return nil, fmt.Errorf("%s (%s) was exceeded: %w", limit, client.Timeout, err)
+	}
+	return resp, err
+}
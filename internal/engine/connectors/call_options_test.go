@@ -0,0 +1,66 @@
+package connectors_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestCallOptionsTimeout calls a News API that answers after 2.5s: a 2-second workflow
+// timeout fires and says so, while the connector's 10-second default lets it finish
+func TestCallOptionsTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2500 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		io.WriteString(w, `{"status": "ok", "totalResults": 0, "articles": []}`)
+	}))
+	defer srv.Close()
+	news := &connectors.NewsAPI{APIKey: "key", BaseURL: srv.URL}
+
+	start := time.Now()
+	ctx := connectors.WithCallOptions(context.Background(), connectors.CallOptions{Timeout: 2 * time.Second})
+	result := news.ExecuteWithContext(ctx, connectors.NewsConfig{Country: "us"})
+	if elapsed := time.Since(start); elapsed > 2400*time.Millisecond {
+		t.Errorf("Expected the configured timeout to fire after 2s, took %s", elapsed)
+	}
+	if result.Status != "failed" || result.ErrorCode != connectors.ErrCodeUpstreamTimeout ||
+		!strings.Contains(result.Message, "the workflow's connector_timeout_seconds (2s) was exceeded") {
+		t.Errorf("Expected a timeout naming connector_timeout_seconds, got %s (%s): %s", result.Status, result.ErrorCode, result.Message)
+	}
+
+	if result := news.ExecuteWithContext(context.Background(), connectors.NewsConfig{Country: "us"}); result.Status != "success" {
+		t.Errorf("Expected the default timeout to let the slow call finish, got %s: %s", result.Status, result.Message)
+	}
+}
+
+// TestCallOptionsHeaders adds a workflow's headers without replacing the connector's own
+func TestCallOptionsHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header
+		io.WriteString(w, `<?xml version="1.0"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><PingResponse/></soap:Body></soap:Envelope>`)
+	}))
+	defer srv.Close()
+
+	ctx := connectors.WithCallOptions(context.Background(), connectors.CallOptions{Headers: map[string]string{
+		"X-Egress-Zone": "eu-west",
+		"Content-Type":  "application/json",
+	}})
+	result := (&connectors.SOAPConnector{}).ExecuteWithContext(ctx, connectors.SOAPConfig{Endpoint: srv.URL, Method: "Ping"})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %s: %s", result.Status, result.Message)
+	}
+	header := <-received
+	if header.Get("X-Egress-Zone") != "eu-west" || header.Get("Content-Type") != "text/xml; charset=utf-8" {
+		t.Errorf("Expected the extra header added and the SOAP content type kept, got %v", header)
+	}
+}
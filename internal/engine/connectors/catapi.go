@@ -81,9 +81,7 @@ func (c *CatAPI) ExecuteWithContext(ctx context.Context, config CatConfig) Resul
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := HTTPClients().Client("catapi", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -94,19 +92,19 @@ func (c *CatAPI) ExecuteWithContext(ctx context.Context, config CatConfig) Resul
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Cat API request failed: %v", err), start)
+		return NewRequestErrorResult("Cat", err, fmt.Sprintf("Cat API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Check response status
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Cat API returned error status: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("Cat API", resp.StatusCode, fmt.Sprintf("Cat API returned error status: %d", resp.StatusCode), start)
 	}
 
 	// Parse response
 	var cats []CatImage
 	if err := json.NewDecoder(resp.Body).Decode(&cats); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse Cat API response: %v", err), start)
+		return NewInvalidResponseResult("Cat API", fmt.Sprintf("Failed to parse Cat API response: %v", err), start)
 	}
 
 	return NewSuccessResult("Cat images fetched successfully", map[string]interface{}{
@@ -5,10 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+// MaxDiscordEmbedFields is the most fields Discord accepts in one embed
+const MaxDiscordEmbedFields = 25
+
 // DiscordWebhook handles Discord webhook integrations
 type DiscordWebhook struct {
 	WebhookURL string
@@ -16,7 +21,56 @@ type DiscordWebhook struct {
 
 // DiscordMessage represents a Discord message payload
 type DiscordMessage struct {
-	Content string `json:"content"`
+	Content   string         `json:"content,omitempty"`
+	Username  string         `json:"username,omitempty"`   // Overrides the webhook's name
+	AvatarURL string         `json:"avatar_url,omitempty"` // Overrides the webhook's avatar
+	Embeds    []DiscordEmbed `json:"embeds,omitempty"`
+}
+
+// DiscordEmbed is a rich message block in Discord's wire format
+type DiscordEmbed struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Color       int                 `json:"color,omitempty"`
+	Fields      []DiscordEmbedField `json:"fields,omitempty"`
+	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"` // RFC3339
+}
+
+// DiscordEmbedField is a name/value pair in an embed
+type DiscordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// DiscordEmbedFooter is the small text under an embed
+type DiscordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// validate rejects messages Discord would answer with a bare "Invalid Form Body"
+func (m DiscordMessage) validate() error {
+	if m.Content == "" && len(m.Embeds) == 0 {
+		return fmt.Errorf("a Discord message needs content or an embed")
+	}
+	for _, embed := range m.Embeds {
+		if len(embed.Fields) > MaxDiscordEmbedFields {
+			return fmt.Errorf("Discord accepts at most %d embed fields, got %d", MaxDiscordEmbedFields, len(embed.Fields))
+		}
+		for i, field := range embed.Fields {
+			if field.Name == "" || field.Value == "" {
+				return fmt.Errorf("embed field %d needs a name and a value", i+1)
+			}
+		}
+		if embed.Timestamp != "" {
+			if _, err := time.Parse(time.RFC3339, embed.Timestamp); err != nil {
+				return fmt.Errorf("embed timestamp must be RFC3339, got %q", embed.Timestamp)
+			}
+		}
+	}
+	return nil
 }
 
 // Execute sends a message to Discord
@@ -26,6 +80,12 @@ func (d *DiscordWebhook) Execute(message string) Result {
 
 // ExecuteWithContext sends a message to Discord with context awareness
 func (d *DiscordWebhook) ExecuteWithContext(ctx context.Context, message string) Result {
+	return d.Send(ctx, DiscordMessage{Content: message})
+}
+
+// Send posts a message with its embeds and overrides
+// A 429 fails with rate_limited and the wait Discord asked for in RetryAfterMS
+func (d *DiscordWebhook) Send(ctx context.Context, message DiscordMessage) Result {
 	start := time.Now()
 
 	select {
@@ -34,8 +94,11 @@ func (d *DiscordWebhook) ExecuteWithContext(ctx context.Context, message string)
 	default:
 	}
 
-	payload := DiscordMessage{Content: message}
-	jsonData, err := json.Marshal(payload)
+	if err := message.validate(); err != nil {
+		return NewFailureResult(fmt.Sprintf("Invalid Discord message: %v", err), start)
+	}
+
+	jsonData, err := json.Marshal(message)
 	if err != nil {
 		return NewFailureResult(fmt.Sprintf("Failed to marshal Discord payload: %v", err), start)
 	}
@@ -46,9 +109,7 @@ func (d *DiscordWebhook) ExecuteWithContext(ctx context.Context, message string)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := HTTPClients().Client("discord", 0)
 	resp, err := client.Do(req)
 
 	select {
@@ -58,16 +119,36 @@ func (d *DiscordWebhook) ExecuteWithContext(ctx context.Context, message string)
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Discord webhook request failed: %v", err), start)
+		return NewRequestErrorResult("Discord", err, fmt.Sprintf("Discord webhook request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := discordRetryAfter(resp, body)
+		return NewRateLimitedResult("Discord", retryAfter, fmt.Sprintf("Discord rate limited the webhook; retry after %s", retryAfter), start)
+	}
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Discord returned error status: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("Discord", resp.StatusCode, fmt.Sprintf("Discord returned error status %d: %s", resp.StatusCode, body), start)
 	}
 
 	return NewSuccessResult("Discord message sent successfully", map[string]interface{}{
 		"status_code": resp.StatusCode,
-		"message":     message,
+		"message":     message.Content,
 	}, start)
 }
+
+// discordRetryAfter reads how long a 429 asks callers to wait: retry_after in the body
+// (seconds, possibly fractional), else the Retry-After header, else one second
+func discordRetryAfter(resp *http.Response, body []byte) time.Duration {
+	var limited struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &limited) == nil && limited.RetryAfter > 0 {
+		return time.Duration(limited.RetryAfter * float64(time.Second))
+	}
+	if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Second
+}
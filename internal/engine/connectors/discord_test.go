@@ -0,0 +1,93 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestDiscordSend posts an embed with overrides and reports Discord's rate limits and errors
+func TestDiscordSend(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusNoContent)
+		case "/limited":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"message": "You are being rate limited.", "retry_after": 2.5, "global": false}`)
+		case "/limited-header":
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"code": 50035, "message": "Invalid Form Body"}`)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	message := connectors.DiscordMessage{
+		Content:   "Order shipped",
+		Username:  "Shop Bot",
+		AvatarURL: "https://example.com/bot.png",
+		Embeds: []connectors.DiscordEmbed{{
+			Title:     "Order 42",
+			Color:     5793266,
+			Fields:    []connectors.DiscordEmbedField{{Name: "Total", Value: "$10", Inline: true}},
+			Footer:    &connectors.DiscordEmbedFooter{Text: "Shop"},
+			Timestamp: "2026-10-17T09:00:00Z",
+		}},
+	}
+
+	result := (&connectors.DiscordWebhook{WebhookURL: srv.URL + "/ok"}).Send(ctx, message)
+	if result.Status != "success" || got["username"] != "Shop Bot" || got["avatar_url"] != "https://example.com/bot.png" {
+		t.Fatalf("Expected the message sent with its overrides, got %+v with %v", result, got)
+	}
+	embed := got["embeds"].([]interface{})[0].(map[string]interface{})
+	if embed["title"] != "Order 42" || embed["color"] != float64(5793266) || embed["footer"].(map[string]interface{})["text"] != "Shop" ||
+		embed["timestamp"] != "2026-10-17T09:00:00Z" || embed["fields"].([]interface{})[0].(map[string]interface{})["inline"] != true {
+		t.Errorf("Expected the embed in Discord's format, got %v", embed)
+	}
+
+	for _, c := range []struct {
+		path string
+		wait time.Duration
+	}{
+		{"/limited", 2500 * time.Millisecond},
+		{"/limited-header", 3 * time.Second},
+	} {
+		result := (&connectors.DiscordWebhook{WebhookURL: srv.URL + c.path}).Send(ctx, message)
+		if result.Status != "failed" || result.ErrorCode != connectors.ErrCodeRateLimited ||
+			result.RetryAfterMS != c.wait.Milliseconds() || result.ErrorParams["retry_after"] != "3" {
+			t.Errorf("%s: expected rate_limited with a %s wait, got %+v", c.path, c.wait, result)
+		}
+	}
+
+	result = (&connectors.DiscordWebhook{WebhookURL: srv.URL + "/bad"}).Send(ctx, message)
+	if result.ErrorCode != connectors.ErrCodeUpstreamHTTP || !strings.Contains(result.Message, "Invalid Form Body") {
+		t.Errorf("Expected Discord's error body in the failure, got %+v", result)
+	}
+
+	invalid := message
+	invalid.Embeds = []connectors.DiscordEmbed{{Fields: []connectors.DiscordEmbedField{{Name: "Total"}}}}
+	if result := (&connectors.DiscordWebhook{WebhookURL: srv.URL + "/ok"}).Send(ctx, invalid); result.Status != "failed" || !strings.Contains(result.Message, "name and a value") {
+		t.Errorf("Expected a field without a value refused, got %+v", result)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if result := (&connectors.DiscordWebhook{WebhookURL: srv.URL + "/ok"}).Send(cancelled, message); result.Status != "cancelled" {
+		t.Errorf("Expected a cancelled context to stop the post, got %+v", result)
+	}
+}
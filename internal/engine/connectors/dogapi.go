@@ -77,9 +77,7 @@ func (d *DogAPIConnector) ExecuteWithContext(ctx context.Context, config DogAPIC
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := HTTPClients().Client("dogapi", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -90,25 +88,25 @@ func (d *DogAPIConnector) ExecuteWithContext(ctx context.Context, config DogAPIC
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Dog API request failed: %v", err), start)
+		return NewRequestErrorResult("Dog", err, fmt.Sprintf("Dog API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read Dog API response: %v", err), start)
+		return NewInvalidResponseResult("Dog API", fmt.Sprintf("Failed to read Dog API response: %v", err), start)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Dog API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Dog API", resp.StatusCode, fmt.Sprintf("Dog API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	// Parse JSON response
 	var dogData map[string]interface{}
 	if err := json.Unmarshal(body, &dogData); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse Dog API response: %v", err), start)
+		return NewInvalidResponseResult("Dog API", fmt.Sprintf("Failed to parse Dog API response: %v", err), start)
 	}
 
 	// Extract message for logging
@@ -0,0 +1,93 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error codes say why an action failed without exposing technical detail
+// User-facing surfaces translate them (see handlers.Messages); Result.Message
+// keeps the technical message for logs and administrators
+const (
+	ErrCodeActionFailed          = "action_failed"                   // {action}: no more specific code applies
+	ErrCodeCancelled             = "cancelled"                       // The run was cancelled or timed out
+	ErrCodeCredentialMissing     = "credential_missing"              // {service}: no usable credential is connected
+	ErrCodeCredentialEnvironment = "credential_environment_mismatch" // {service, workflow_environment, credential_environment}: only a credential for the other environment is connected
+	ErrCodeUpstreamHTTP          = "upstream_http_error"             // {service, status}: the API answered with an error status
+	ErrCodeAuthFailed            = "auth_failed"                     // {service, status}: the API rejected the credential (HTTP 401)
+	ErrCodeUpstreamUnreachable   = "upstream_unreachable"            // {service}: the API could not be reached
+	ErrCodeUpstreamTimeout       = "upstream_timeout"                // {service}: the API did not answer in time (it may still have acted)
+	ErrCodeRateLimited           = "rate_limited"                    // {service, retry_after}: the API asked for no more requests for retry_after seconds
+	ErrCodeInvalidResponse       = "invalid_response"                // {service}: the API's answer could not be read
+	ErrCodeAssertionFailed       = "assertion_failed"                // {failed, total}: output checks did not pass
+	ErrCodeTimedOutHard          = "timed_out_hard"                  // {duration}: the run hung past the watchdog's limit and was abandoned
+	ErrCodeCircuitOpen           = "circuit_open"                    // {action, retry_after}: recent calls kept failing, so the action was skipped
+	ErrCodeDeliveryFailed        = "delivery_failed"                 // {service, status, provider_code}: the message was accepted but never delivered
+)
+
+// NewErrorResult creates a failure result with an error code for user-facing surfaces
+// message is the technical detail
+func NewErrorResult(code string, params map[string]string, message string, start time.Time) Result {
+	result := NewFailureResult(message, start)
+	result.ErrorCode = code
+	result.ErrorParams = params
+	return result
+}
+
+// NewHTTPErrorResult reports an error status from a service's API
+// A 401 means the credential was refused, which retrying with it won't fix
+func NewHTTPErrorResult(service string, status int, message string, start time.Time) Result {
+	code := ErrCodeUpstreamHTTP
+	if status == http.StatusUnauthorized {
+		code = ErrCodeAuthFailed
+	}
+	return NewErrorResult(code, map[string]string{"service": service, "status": strconv.Itoa(status)}, message, start)
+}
+
+// NewRateLimitedResult reports a 429 from a service, with how long it asked callers to wait
+// RetryAfterMS carries the wait for retry policies; the params round it up to whole seconds
+func NewRateLimitedResult(service string, retryAfter time.Duration, message string, start time.Time) Result {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	result := NewErrorResult(ErrCodeRateLimited, map[string]string{"service": service, "retry_after": strconv.Itoa(seconds)}, message, start)
+	result.RetryAfterMS = retryAfter.Milliseconds()
+	return result
+}
+
+// NewRequestErrorResult reports a request to a service that never got an answer
+// Timeouts get their own code: unlike a refused connection, the service may have acted on the request
+func NewRequestErrorResult(service string, err error, message string, start time.Time) Result {
+	code := ErrCodeUpstreamUnreachable
+	if isTimeout(err) {
+		code = ErrCodeUpstreamTimeout
+	}
+	return NewErrorResult(code, map[string]string{"service": service}, message, start)
+}
+
+// isTimeout reports whether a request failed by running out of time
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// NewInvalidResponseResult reports an answer from a service that could not be read
+func NewInvalidResponseResult(service string, message string, start time.Time) Result {
+	return NewErrorResult(ErrCodeInvalidResponse, map[string]string{"service": service}, message, start)
+}
+
+// NewCredentialMissingResult reports that the user hasn't connected a service
+func NewCredentialMissingResult(service string, message string) Result {
+	return NewErrorResult(ErrCodeCredentialMissing, map[string]string{"service": service}, message, time.Now())
+}
+
+// NewCredentialEnvironmentResult reports that the only credential for a service belongs to the other environment
+func NewCredentialEnvironmentResult(service, workflowEnvironment, credentialEnvironment string, message string) Result {
+	return NewErrorResult(ErrCodeCredentialEnvironment, map[string]string{
+		"service":                service,
+		"workflow_environment":   workflowEnvironment,
+		"credential_environment": credentialEnvironment,
+	}, message, time.Now())
+}
@@ -0,0 +1,57 @@
+package connectors_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestConnectorErrorCodes checks failures carry a code and params alongside the technical message
+func TestConnectorErrorCodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/people":
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		default:
+			w.Write([]byte("<html>not json</html>"))
+		}
+	}))
+	unreachable := server.URL
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		code    string
+		params  map[string]string
+	}{
+		{"error status", server.URL, connectors.ErrCodeUpstreamHTTP, map[string]string{"service": "SWAPI", "status": "429"}},
+		{"unreadable body", server.URL + "/garbage", connectors.ErrCodeInvalidResponse, map[string]string{"service": "SWAPI"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &connectors.SWAPIConnector{BaseURL: tt.baseURL}
+			result := connector.ExecuteWithContext(context.Background(), connectors.SWAPIConfig{Resource: "people"})
+			if result.Status != "failed" || result.ErrorCode != tt.code {
+				t.Fatalf("Expected %s, got %s/%s: %s", tt.code, result.Status, result.ErrorCode, result.Message)
+			}
+			for key, want := range tt.params {
+				if result.ErrorParams[key] != want {
+					t.Errorf("Expected %s=%s, got %v", key, want, result.ErrorParams)
+				}
+			}
+			if result.Message == "" {
+				t.Error("Expected the technical message to be kept")
+			}
+		})
+	}
+
+	server.Close()
+	connector := &connectors.SWAPIConnector{BaseURL: unreachable}
+	if result := connector.ExecuteWithContext(context.Background(), connectors.SWAPIConfig{Resource: "people"}); result.ErrorCode != connectors.ErrCodeUpstreamUnreachable {
+		t.Errorf("Expected %s, got %s: %s", connectors.ErrCodeUpstreamUnreachable, result.ErrorCode, result.Message)
+	}
+}
@@ -72,10 +72,8 @@ func (f *FakeStoreAPI) ExecuteWithContext(ctx context.Context, config FakeStoreC
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("fakestore", 0)
+	resp, err := doCall(client, req)
 
 	// Check if context was cancelled during request
 	select {
@@ -85,13 +83,13 @@ func (f *FakeStoreAPI) ExecuteWithContext(ctx context.Context, config FakeStoreC
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Fake Store API request failed: %v", err), start)
+		return NewRequestErrorResult("Fake Store", err, fmt.Sprintf("Fake Store API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Check response status
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Fake Store API returned error status: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("Fake Store API", resp.StatusCode, fmt.Sprintf("Fake Store API returned error status: %d", resp.StatusCode), start)
 	}
 
 	// Parse response based on endpoint
@@ -99,14 +97,14 @@ func (f *FakeStoreAPI) ExecuteWithContext(ctx context.Context, config FakeStoreC
 	if config.Endpoint == "products" || config.Category != "" {
 		var products []Product
 		if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
-			return NewFailureResult(fmt.Sprintf("Failed to parse Fake Store API response: %v", err), start)
+			return NewInvalidResponseResult("Fake Store API", fmt.Sprintf("Failed to parse Fake Store API response: %v", err), start)
 		}
 		data = products
 	} else {
 		// Generic JSON parsing for other endpoints
 		var genericData interface{}
 		if err := json.NewDecoder(resp.Body).Decode(&genericData); err != nil {
-			return NewFailureResult(fmt.Sprintf("Failed to parse Fake Store API response: %v", err), start)
+			return NewInvalidResponseResult("Fake Store API", fmt.Sprintf("Failed to parse Fake Store API response: %v", err), start)
 		}
 		data = genericData
 	}
@@ -126,8 +124,8 @@ func (f *FakeStoreAPI) GetCategories(ctx context.Context) Result {
 		return NewFailureResult(fmt.Sprintf("Failed to create request: %v", err), start)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("fakestore", 0)
+	resp, err := doCall(client, req)
 	if err != nil {
 		return NewFailureResult(fmt.Sprintf("Request failed: %v", err), start)
 	}
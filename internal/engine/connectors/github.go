@@ -0,0 +1,253 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitHubConnector works with issues and repositories through the GitHub REST API
+// Reference: https://docs.github.com/en/rest
+type GitHubConnector struct {
+	Token   string // Personal access token
+	BaseURL string // Default: https://api.github.com
+}
+
+// GitHubConfig represents GitHub connector configuration
+type GitHubConfig struct {
+	Operation   string   `json:"operation"`    // create_issue, comment_issue, list_issues, get_repo
+	Owner       string   `json:"owner"`        // User or organization (e.g., "octocat")
+	Repo        string   `json:"repo"`         // Repository name (e.g., "hello-world")
+	IssueNumber int      `json:"issue_number"` // For comment_issue
+	Title       string   `json:"title"`        // For create_issue
+	Body        string   `json:"body"`         // Issue or comment body (markdown)
+	Labels      []string `json:"labels"`       // For create_issue; filters list_issues
+}
+
+// gitHubOperations are the supported operations; the bool marks those that change the repository
+var gitHubOperations = map[string]bool{
+	"create_issue":  true,
+	"comment_issue": true,
+	"list_issues":   false,
+	"get_repo":      false,
+}
+
+// GitHubOperationWrites reports whether an operation changes the repository, so it is
+// skipped in a sandbox
+func GitHubOperationWrites(operation string) bool {
+	return gitHubOperations[operation]
+}
+
+// ExecuteWithContext performs a GitHub operation
+// Results carry the API's rate limit headers under data.rate_limit
+func (g *GitHubConnector) ExecuteWithContext(ctx context.Context, config GitHubConfig) Result {
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return NewCancelledResult("Context cancelled before GitHub request: " + ctx.Err().Error())
+	default:
+	}
+
+	if _, ok := gitHubOperations[config.Operation]; !ok {
+		return NewFailureResult(fmt.Sprintf("Invalid GitHub operation: %s. Valid: create_issue, comment_issue, list_issues, get_repo", config.Operation), start)
+	}
+	if config.Owner == "" || config.Repo == "" {
+		return NewFailureResult("GitHub owner and repo are required", start)
+	}
+	if g.Token == "" {
+		return NewFailureResult("GitHub token is required", start)
+	}
+	repoPath := "/repos/" + url.PathEscape(config.Owner) + "/" + url.PathEscape(config.Repo)
+
+	switch config.Operation {
+	case "create_issue":
+		if config.Title == "" {
+			return NewFailureResult("GitHub issue title is required", start)
+		}
+		issue := map[string]interface{}{"title": config.Title, "body": config.Body}
+		if len(config.Labels) > 0 {
+			issue["labels"] = config.Labels
+		}
+		var created map[string]interface{}
+		rateLimit, result := g.call(ctx, "POST", repoPath+"/issues", issue, &created, start)
+		if result != nil {
+			return *result
+		}
+		return NewSuccessResult(fmt.Sprintf("GitHub issue #%v created in %s/%s", created["number"], config.Owner, config.Repo), map[string]interface{}{
+			"operation":  "create_issue",
+			"issue":      created,
+			"number":     created["number"],
+			"html_url":   created["html_url"],
+			"rate_limit": rateLimit,
+		}, start)
+
+	case "comment_issue":
+		if config.IssueNumber <= 0 {
+			return NewFailureResult("GitHub issue number is required", start)
+		}
+		if config.Body == "" {
+			return NewFailureResult("GitHub comment body is required", start)
+		}
+		var comment map[string]interface{}
+		rateLimit, result := g.call(ctx, "POST", fmt.Sprintf("%s/issues/%d/comments", repoPath, config.IssueNumber), map[string]string{"body": config.Body}, &comment, start)
+		if result != nil {
+			return *result
+		}
+		return NewSuccessResult(fmt.Sprintf("Commented on GitHub issue #%d in %s/%s", config.IssueNumber, config.Owner, config.Repo), map[string]interface{}{
+			"operation":    "comment_issue",
+			"comment":      comment,
+			"issue_number": config.IssueNumber,
+			"html_url":     comment["html_url"],
+			"rate_limit":   rateLimit,
+		}, start)
+
+	case "list_issues":
+		path := repoPath + "/issues?state=open&per_page=30"
+		if len(config.Labels) > 0 {
+			path += "&labels=" + url.QueryEscape(strings.Join(config.Labels, ","))
+		}
+		var issues []interface{}
+		rateLimit, result := g.call(ctx, "GET", path, nil, &issues, start)
+		if result != nil {
+			return *result
+		}
+		return NewSuccessResult(fmt.Sprintf("GitHub returned %d open issues for %s/%s", len(issues), config.Owner, config.Repo), map[string]interface{}{
+			"operation":  "list_issues",
+			"issues":     issues,
+			"count":      len(issues),
+			"rate_limit": rateLimit,
+		}, start)
+
+	default: // get_repo
+		var repo map[string]interface{}
+		rateLimit, result := g.call(ctx, "GET", repoPath, nil, &repo, start)
+		if result != nil {
+			return *result
+		}
+		return NewSuccessResult(fmt.Sprintf("GitHub repository %s/%s retrieved", config.Owner, config.Repo), map[string]interface{}{
+			"operation":  "get_repo",
+			"repo":       repo,
+			"html_url":   repo["html_url"],
+			"rate_limit": rateLimit,
+		}, start)
+	}
+}
+
+// call sends one API request and decodes the response into out
+// It returns the rate limit headers, and a result only when the call failed
+func (g *GitHubConnector) call(ctx context.Context, method, path string, body interface{}, out interface{}, start time.Time) (map[string]interface{}, *Result) {
+	baseURL := g.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			result := NewFailureResult(fmt.Sprintf("Failed to marshal GitHub request: %v", err), start)
+			return nil, &result
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(baseURL, "/")+path, reader)
+	if err != nil {
+		result := NewFailureResult(fmt.Sprintf("Failed to create GitHub request: %v", err), start)
+		return nil, &result
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "GoFlow")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := HTTPClients().Client("github", 0)
+	resp, err := client.Do(req)
+
+	select {
+	case <-ctx.Done():
+		result := NewCancelledResult("Context cancelled during GitHub request: " + ctx.Err().Error())
+		return nil, &result
+	default:
+	}
+
+	if err != nil {
+		result := NewRequestErrorResult("GitHub", err, fmt.Sprintf("GitHub request failed: %v", err), start)
+		return nil, &result
+	}
+	defer resp.Body.Close()
+	rateLimit := gitHubRateLimit(resp.Header)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result := NewInvalidResponseResult("GitHub", fmt.Sprintf("Failed to read GitHub response: %v", err), start)
+		return rateLimit, &result
+	}
+
+	if resp.StatusCode >= 400 {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &apiError)
+		message := fmt.Sprintf("GitHub returned HTTP error: %d - %s", resp.StatusCode, apiError.Message)
+		if rateLimit["remaining"] == 0 {
+			message += " (rate limit exhausted)"
+		}
+		result := NewHTTPErrorResult("GitHub", resp.StatusCode, message, start)
+		result.Data = map[string]interface{}{"status_code": resp.StatusCode, "rate_limit": rateLimit}
+		return rateLimit, &result
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		result := NewInvalidResponseResult("GitHub", fmt.Sprintf("Failed to parse GitHub response: %v", err), start)
+		return rateLimit, &result
+	}
+	return rateLimit, nil
+}
+
+// gitHubRateLimit reads the X-RateLimit-* headers (limit, remaining, used, reset, resource)
+// Numbers are returned as ints; headers the response lacks are left out
+func gitHubRateLimit(header http.Header) map[string]interface{} {
+	rateLimit := make(map[string]interface{})
+	for _, name := range []string{"limit", "remaining", "used", "reset"} {
+		if value, err := strconv.Atoi(header.Get("X-RateLimit-" + name)); err == nil {
+			rateLimit[name] = value
+		}
+	}
+	if resource := header.Get("X-RateLimit-Resource"); resource != "" {
+		rateLimit["resource"] = resource
+	}
+	return rateLimit
+}
+
+// DryRunGitHub simulates a GitHub call without actually making the request
+func (g *GitHubConnector) DryRunGitHub(config GitHubConfig) Result {
+	start := time.Now()
+
+	return NewSuccessResult("GitHub dry run completed", map[string]interface{}{
+		"operation":    config.Operation,
+		"owner":        config.Owner,
+		"repo":         config.Repo,
+		"issue_number": config.IssueNumber,
+		"title":        config.Title,
+		"labels":       config.Labels,
+		"note":         "This is a dry run - no actual GitHub call was made",
+		"example_operations": map[string]string{
+			"create_issue":  "Open an issue: {title: 'Deploy failed', labels: ['ops']}",
+			"comment_issue": "Comment on issue #42",
+			"list_issues":   "List open issues, optionally filtered by labels",
+			"get_repo":      "Retrieve the repository's details",
+		},
+	}, start)
+}
@@ -0,0 +1,103 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestGitHubConnector runs each operation against a fake API and checks the requests,
+// the issue fields surfaced for templates and the rate limit metadata
+func TestGitHubConnector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp_test" || r.Header.Get("Accept") != "application/vnd.github+json" {
+			t.Errorf("Unexpected headers on %s: %v", r.URL.Path, r.Header)
+		}
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4998")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		w.Header().Set("X-RateLimit-Resource", "core")
+		body, _ := io.ReadAll(r.Body)
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /repos/acme/api/issues":
+			var issue map[string]interface{}
+			json.Unmarshal(body, &issue)
+			if issue["title"] != "Deploy failed" || len(issue["labels"].([]interface{})) != 2 {
+				t.Errorf("Unexpected issue body %s", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"number": 42, "html_url": "https://github.com/acme/api/issues/42", "title": "Deploy failed"}`)
+		case "POST /repos/acme/api/issues/42/comments":
+			io.WriteString(w, `{"id": 7, "html_url": "https://github.com/acme/api/issues/42#issuecomment-7"}`)
+		case "GET /repos/acme/api/issues":
+			if r.URL.Query().Get("labels") != "bug,ops" {
+				t.Errorf("Expected a label filter, got %s", r.URL.RawQuery)
+			}
+			io.WriteString(w, `[{"number": 1}, {"number": 2}]`)
+		case "GET /repos/acme/api":
+			io.WriteString(w, `{"full_name": "acme/api", "html_url": "https://github.com/acme/api"}`)
+		default:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"message": "API rate limit exceeded"}`)
+		}
+	}))
+	defer srv.Close()
+
+	github := &connectors.GitHubConnector{Token: "ghp_test", BaseURL: srv.URL}
+	ctx := context.Background()
+	repo := connectors.GitHubConfig{Owner: "acme", Repo: "api"}
+
+	create := repo
+	create.Operation, create.Title, create.Labels = "create_issue", "Deploy failed", []string{"bug", "ops"}
+	result := github.ExecuteWithContext(ctx, create)
+	if result.Status != "success" || result.Data["html_url"] != "https://github.com/acme/api/issues/42" || result.Data["number"] != float64(42) {
+		t.Fatalf("Expected the created issue's number and URL, got %+v", result)
+	}
+	rateLimit := result.Data["rate_limit"].(map[string]interface{})
+	if rateLimit["limit"] != 5000 || rateLimit["remaining"] != 4998 || rateLimit["resource"] != "core" {
+		t.Errorf("Expected the rate limit headers, got %v", rateLimit)
+	}
+
+	comment := repo
+	comment.Operation, comment.IssueNumber, comment.Body = "comment_issue", 42, "Rolled back"
+	if result := github.ExecuteWithContext(ctx, comment); result.Status != "success" || !strings.HasSuffix(result.Data["html_url"].(string), "#issuecomment-7") {
+		t.Errorf("Expected the comment URL, got %+v", result)
+	}
+
+	list := repo
+	list.Operation, list.Labels = "list_issues", []string{"bug", "ops"}
+	if result := github.ExecuteWithContext(ctx, list); result.Status != "success" || result.Data["count"] != 2 {
+		t.Errorf("Expected 2 issues, got %+v", result)
+	}
+
+	get := repo
+	get.Operation = "get_repo"
+	if result := github.ExecuteWithContext(ctx, get); result.Status != "success" || result.Data["repo"].(map[string]interface{})["full_name"] != "acme/api" {
+		t.Errorf("Expected the repository, got %+v", result)
+	}
+
+	limited := connectors.GitHubConfig{Operation: "get_repo", Owner: "acme", Repo: "other"}
+	result = github.ExecuteWithContext(ctx, limited)
+	if result.Status != "failed" || result.ErrorCode != connectors.ErrCodeUpstreamHTTP || !strings.Contains(result.Message, "rate limit exhausted") {
+		t.Errorf("Expected an exhausted rate limit to be reported, got %+v", result)
+	}
+
+	for _, config := range []connectors.GitHubConfig{
+		{Operation: "delete_repo", Owner: "acme", Repo: "api"},
+		{Operation: "get_repo", Owner: "acme"},
+		{Operation: "create_issue", Owner: "acme", Repo: "api"},
+		{Operation: "comment_issue", Owner: "acme", Repo: "api", Body: "no number"},
+	} {
+		if result := github.ExecuteWithContext(ctx, config); result.Status != "failed" {
+			t.Errorf("%+v: expected a validation failure, got %+v", config, result)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package connectors
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultConnectorTimeout is the timeout of connectors without a default of their own
+const DefaultConnectorTimeout = 10 * time.Second
+
+// connectorTimeouts are the connectors' own default timeouts, for APIs known to be slow
+var connectorTimeouts = map[string]time.Duration{
+	"github":       30 * time.Second,
+	"http_request": DefaultHTTPTimeout,
+	"jira":         30 * time.Second,
+	"nasa":         15 * time.Second,
+	"salesforce":   30 * time.Second,
+	"soap":         30 * time.Second,
+	"twilio":       15 * time.Second,
+}
+
+// HTTPClientConfig configures the transport every connector's outbound calls share
+type HTTPClientConfig struct {
+	ProxyURL            string                   // Routes every call through this proxy; empty uses HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	CABundle            string                   // PEM CA certificates trusted in addition to the system roots
+	MaxIdleConns        int                      // Idle connections kept across all hosts (0 = 100)
+	MaxIdleConnsPerHost int                      // Idle connections kept per host (0 = 10)
+	IdleConnTimeout     time.Duration            // How long an idle connection is kept (0 = 90s)
+	DefaultTimeout      time.Duration            // Timeout of connectors without their own (0 = DefaultConnectorTimeout)
+	ConnectorTimeouts   map[string]time.Duration // Per connector timeouts, e.g. "salesforce": 60s, over the built-in ones
+	Transport           http.RoundTripper        // Replaces the pooled transport for calls without TLS settings (tests)
+}
+
+// HTTPClientProvider hands connectors HTTP clients that share one pooled transport, so
+// calls to the same API reuse connections and all go through the configured proxy and CAs
+type HTTPClientProvider struct {
+	transport      *http.Transport
+	roundTripper   http.RoundTripper
+	caBundle       string
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration
+}
+
+// NewHTTPClientProvider builds a provider from the config
+func NewHTTPClientProvider(config HTTPClientConfig) (*HTTPClientProvider, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 10
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+
+	if config.ProxyURL != "" {
+		proxy, err := url.Parse(config.ProxyURL)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", config.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if config.CABundle != "" {
+		tlsConfig, err := (&TLSSettings{CABundle: config.CABundle}).Config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	provider := &HTTPClientProvider{
+		transport:      transport,
+		roundTripper:   transport,
+		caBundle:       config.CABundle,
+		defaultTimeout: DefaultConnectorTimeout,
+		timeouts:       make(map[string]time.Duration),
+	}
+	if config.Transport != nil {
+		provider.roundTripper = config.Transport
+	}
+	if config.DefaultTimeout > 0 {
+		provider.defaultTimeout = config.DefaultTimeout
+	}
+	for connector, timeout := range connectorTimeouts {
+		provider.timeouts[connector] = timeout
+	}
+	for connector, timeout := range config.ConnectorTimeouts {
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout for %s must be positive", connector)
+		}
+		provider.timeouts[connector] = timeout
+	}
+	return provider, nil
+}
+
+// ParseConnectorTimeouts parses per connector timeouts written as "salesforce=60s,soap=45s"
+func ParseConnectorTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		connector, raw, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("connector timeout %q must look like name=30s", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("connector timeout %q: %w", entry, err)
+		}
+		timeouts[strings.TrimSpace(connector)] = timeout
+	}
+	return timeouts, nil
+}
+
+// Timeout returns the default timeout of a connector
+func (p *HTTPClientProvider) Timeout(connector string) time.Duration {
+	if timeout, ok := p.timeouts[connector]; ok {
+		return timeout
+	}
+	return p.defaultTimeout
+}
+
+// Client returns a client on the shared transport for a connector
+// A positive timeout overrides the connector's default for this call
+func (p *HTTPClientProvider) Client(connector string, timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = p.Timeout(connector)
+	}
+	return &http.Client{Timeout: timeout, Transport: p.roundTripper}
+}
+
+// ClientWithTLS is Client for calls that need TLS settings of their own (private CAs,
+// mutual TLS). Those get a transport of their own, still using the proxy and shared CAs
+func (p *HTTPClientProvider) ClientWithTLS(connector string, timeout time.Duration, settings *TLSSettings) (*http.Client, error) {
+	if settings == nil {
+		return p.Client(connector, timeout), nil
+	}
+	tlsConfig, err := settings.Config()
+	if err != nil {
+		return nil, err
+	}
+	if p.caBundle != "" {
+		if tlsConfig.RootCAs == nil {
+			if tlsConfig.RootCAs, err = x509.SystemCertPool(); err != nil || tlsConfig.RootCAs == nil {
+				tlsConfig.RootCAs = x509.NewCertPool()
+			}
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM([]byte(p.caBundle)) {
+			return nil, errors.New("shared CA bundle contains no valid PEM certificates")
+		}
+	}
+
+	client := p.Client(connector, timeout)
+	transport := p.transport.Clone()
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = transport
+	return client, nil
+}
+
+var (
+	httpClientsMu sync.RWMutex
+	httpClients   = mustHTTPClientProvider(HTTPClientConfig{})
+)
+
+// mustHTTPClientProvider builds the provider used until SetHTTPClients is called
+func mustHTTPClientProvider(config HTTPClientConfig) *HTTPClientProvider {
+	provider, err := NewHTTPClientProvider(config)
+	if err != nil {
+		panic(err)
+	}
+	return provider
+}
+
+// SetHTTPClients replaces the provider every connector gets its HTTP client from
+// Called once at startup; connectors use a default provider until then
+func SetHTTPClients(provider *HTTPClientProvider) {
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	httpClients = provider
+}
+
+// HTTPClients returns the provider connectors get their HTTP clients from
+func HTTPClients() *HTTPClientProvider {
+	httpClientsMu.RLock()
+	defer httpClientsMu.RUnlock()
+	return httpClients
+}
@@ -0,0 +1,113 @@
+package connectors_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestHTTPClientsReuseConnections runs a connector repeatedly against one server and
+// checks every call went over the same pooled TCP connection
+func TestHTTPClientsReuseConnections(t *testing.T) {
+	provider, err := connectors.NewHTTPClientProvider(connectors.HTTPClientConfig{})
+	if err != nil {
+		t.Fatalf("Failed to build provider: %v", err)
+	}
+	previous := connectors.HTTPClients()
+	connectors.SetHTTPClients(provider)
+	defer connectors.SetHTTPClients(previous)
+
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"name": "Luke Skywalker", "url": "https://swapi.info/api/people/1"}`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	swapi := &connectors.SWAPIConnector{BaseURL: srv.URL}
+	for i := 0; i < 5; i++ {
+		if result := swapi.ExecuteWithContext(context.Background(), connectors.SWAPIConfig{Resource: "people", ID: "1"}); result.Status != "success" {
+			t.Fatalf("Call %d failed: %s", i, result.Message)
+		}
+	}
+	if got := atomic.LoadInt32(&conns); got != 1 {
+		t.Errorf("Expected one TCP connection for five calls, got %d", got)
+	}
+}
+
+// TestHTTPClientTimeouts checks a per-call timeout beats the connector's, which beats the default
+func TestHTTPClientTimeouts(t *testing.T) {
+	timeouts, err := connectors.ParseConnectorTimeouts("salesforce=60s, swapi=5s")
+	if err != nil {
+		t.Fatalf("Failed to parse timeouts: %v", err)
+	}
+	provider, err := connectors.NewHTTPClientProvider(connectors.HTTPClientConfig{
+		DefaultTimeout:    20 * time.Second,
+		ConnectorTimeouts: timeouts,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build provider: %v", err)
+	}
+
+	for _, c := range []struct {
+		connector string
+		override  time.Duration
+		want      time.Duration
+	}{
+		{"salesforce", 0, 60 * time.Second},
+		{"swapi", 0, 5 * time.Second},
+		{"nasa", 0, 15 * time.Second}, // Built-in connector default
+		{"catapi", 0, 20 * time.Second},
+		{"salesforce", 2 * time.Second, 2 * time.Second},
+	} {
+		if got := provider.Client(c.connector, c.override).Timeout; got != c.want {
+			t.Errorf("%s with override %v: expected %v, got %v", c.connector, c.override, c.want, got)
+		}
+	}
+
+	for _, bad := range []string{"salesforce", "salesforce=soon"} {
+		if _, err := connectors.ParseConnectorTimeouts(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if _, err := connectors.NewHTTPClientProvider(connectors.HTTPClientConfig{ProxyURL: "not a url"}); err == nil {
+		t.Error("Expected an invalid proxy URL to be rejected")
+	}
+}
+
+// TestHTTPClientsUseProxy checks connector calls go through the configured proxy
+func TestHTTPClientsUseProxy(t *testing.T) {
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "catapi.example" {
+			atomic.AddInt32(&proxied, 1)
+		}
+		io.WriteString(w, `[]`)
+	}))
+	defer proxy.Close()
+
+	provider, err := connectors.NewHTTPClientProvider(connectors.HTTPClientConfig{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("Failed to build provider: %v", err)
+	}
+	resp, err := provider.Client("catapi", 0).Get("http://catapi.example/v1/images/search")
+	if err != nil {
+		t.Fatalf("Request through the proxy failed: %v", err)
+	}
+	resp.Body.Close()
+	if atomic.LoadInt32(&proxied) != 1 {
+		t.Error("Expected the request to go through the proxy")
+	}
+}
@@ -0,0 +1,280 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultHTTPTimeout applies when an HTTP request config sets no timeout
+	DefaultHTTPTimeout = 30 * time.Second
+	// MaxHTTPTimeout is the longest timeout an HTTP request config may ask for
+	MaxHTTPTimeout = 120 * time.Second
+
+	// maxHTTPResponseBytes caps how much of a response body is read and kept
+	maxHTTPResponseBytes = 1 << 20
+)
+
+// ErrBlockedDestination is returned for URLs that would reach the server itself or the
+// cloud metadata service (SSRF): loopback, link-local and unspecified addresses
+var ErrBlockedDestination = errors.New("destination address is not allowed")
+
+// HTTPAuth is the authorization a generic HTTP request sends
+// Loaded from a stored credential, never from the workflow config
+type HTTPAuth struct {
+	Username string `json:"username,omitempty"` // Basic auth
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"` // Bearer token
+}
+
+// ParseHTTPAuth decodes a credential value into HTTP authorization
+// The value is JSON with "token" (bearer) or "username" and "password" (basic);
+// anything that isn't a JSON object is taken as a bearer token
+func ParseHTTPAuth(raw string) (*HTTPAuth, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "{") {
+		if raw == "" {
+			return nil, errors.New("credential is empty")
+		}
+		return &HTTPAuth{Token: raw}, nil
+	}
+
+	var auth HTTPAuth
+	if err := json.Unmarshal([]byte(raw), &auth); err != nil {
+		return nil, fmt.Errorf("invalid auth credential JSON: %w", err)
+	}
+	switch {
+	case auth.Token != "" && auth.Username != "":
+		return nil, errors.New("auth credential must hold a token or a username, not both")
+	case auth.Token == "" && auth.Username == "":
+		return nil, errors.New("auth credential needs a token or a username and password")
+	}
+	return &auth, nil
+}
+
+// apply sets the Authorization header on a request
+func (a *HTTPAuth) apply(req *http.Request) {
+	if a == nil {
+		return
+	}
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+		return
+	}
+	req.SetBasicAuth(a.Username, a.Password)
+}
+
+// HTTPConfig describes a generic HTTP request
+type HTTPConfig struct {
+	Method  string            // Default GET, or POST when a body is given
+	URL     string            // http or https
+	Headers map[string]string // Sent as given; Content-Type defaults to application/json for object bodies
+	Query   map[string]string // Added to the URL's query string
+	Body    interface{}       // A string is sent as is; any other JSON value is encoded
+	Auth    *HTTPAuth         // Optional
+	Timeout time.Duration     // Default DefaultHTTPTimeout
+}
+
+// HTTPRequestConnector calls any HTTP API, so simple integrations don't need a connector of their own
+type HTTPRequestConnector struct {
+	TLS *TLSSettings // Optional private CA / mutual TLS settings
+
+	// DialContext replaces the guarded dialer (tests point it at a local server)
+	// Destinations are still checked by CheckHTTPURL before any connection is made
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// CheckHTTPURL rejects URLs a workflow may not call: schemes other than http(s), and
+// hosts that name the server itself or the metadata service
+// Hostnames are checked again after resolution, when the connection is made
+func CheckHTTPURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("URL scheme must be http or https, got %q", u.Scheme)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return nil, errors.New("URL has no host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || host == "metadata.google.internal" {
+		return nil, fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && blockedIP(ip) {
+		return nil, fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+	}
+	return u, nil
+}
+
+// blockedIP reports whether an address reaches the server itself or link-local services
+// (169.254.169.254 is the metadata endpoint of most clouds)
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// guardedDialer refuses connections to blocked addresses after DNS resolution, so a
+// hostname pointing at 127.0.0.1 (or a redirect to one) is stopped too
+var guardedDialer = &net.Dialer{
+	Timeout: 10 * time.Second,
+	Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+		}
+		return nil
+	},
+}
+
+// client builds the HTTP client for one request; a zero timeout is the connector default
+func (c *HTTPRequestConnector) client(timeout time.Duration) (*http.Client, error) {
+	client, err := HTTPClients().ClientWithTLS("http_request", timeout, c.TLS)
+	if err != nil {
+		return nil, err
+	}
+	// Work on a copy; the shared transport must keep its proxy
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+	transport.Proxy = nil // A proxy would make the connection, bypassing the dial check
+	transport.DialContext = guardedDialer.DialContext
+	if c.DialContext != nil {
+		transport.DialContext = c.DialContext
+	}
+	client.Transport = transport
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("stopped after 5 redirects")
+		}
+		_, err := CheckHTTPURL(req.URL.String())
+		return err
+	}
+	return client, nil
+}
+
+// ExecuteWithContext sends the request and reports the response's status code, headers,
+// body (parsed JSON, or the raw text) and size; error statuses fail with the response kept
+func (c *HTTPRequestConnector) ExecuteWithContext(ctx context.Context, config HTTPConfig) Result {
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return NewCancelledResult("Context cancelled before HTTP request: " + ctx.Err().Error())
+	default:
+	}
+
+	u, err := CheckHTTPURL(config.URL)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("HTTP request refused: %v", err), start)
+	}
+	query := u.Query()
+	for key, value := range config.Query {
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+
+	var body io.Reader
+	contentType := ""
+	switch b := config.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return NewFailureResult(fmt.Sprintf("Failed to encode HTTP body: %v", err), start)
+		}
+		body = bytes.NewReader(encoded)
+		contentType = "application/json"
+	}
+
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create HTTP request: %v", err), start)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range config.Headers {
+		req.Header.Set(key, value)
+	}
+	config.Auth.apply(req)
+
+	client, err := c.client(config.Timeout)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Invalid TLS settings: %v", err), start)
+	}
+	resp, err := client.Do(req)
+
+	select {
+	case <-ctx.Done():
+		return NewCancelledResult("Context cancelled during HTTP request: " + ctx.Err().Error())
+	default:
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrBlockedDestination) {
+			return NewFailureResult(fmt.Sprintf("HTTP request refused: %v", err), start)
+		}
+		return NewRequestErrorResult("HTTP", err, fmt.Sprintf("HTTP request failed: %v", err), start)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseBytes+1))
+	if err != nil {
+		return NewInvalidResponseResult("HTTP", fmt.Sprintf("Failed to read HTTP response: %v", err), start)
+	}
+	truncated := len(raw) > maxHTTPResponseBytes
+	if truncated {
+		raw = raw[:maxHTTPResponseBytes]
+	}
+
+	headers := make(map[string]interface{}, len(resp.Header))
+	for key, values := range resp.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		parsed = string(raw)
+	}
+	data := map[string]interface{}{
+		"status_code": resp.StatusCode,
+		"headers":     headers,
+		"body":        parsed,
+		"size":        len(raw),
+	}
+	if truncated {
+		data["truncated"] = true
+	}
+
+	if resp.StatusCode >= 400 {
+		result := NewHTTPErrorResult("HTTP", resp.StatusCode, fmt.Sprintf("%s %s returned HTTP %d", method, u.Host, resp.StatusCode), start)
+		result.Data = data
+		return result
+	}
+	return NewSuccessResult(fmt.Sprintf("%s %s returned HTTP %d", method, u.Host, resp.StatusCode), data, start)
+}
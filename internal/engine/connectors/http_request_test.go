@@ -0,0 +1,171 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// dialTo sends every connection to addr, so tests can use public-looking hostnames
+func dialTo(addr string, dials *int32) func(ctx context.Context, network, _ string) (net.Conn, error) {
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		atomic.AddInt32(dials, 1)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+}
+
+// TestHTTPRequestConnector sends requests through the generic connector and checks what
+// reaches the server and what the result reports
+func TestHTTPRequestConnector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/echo":
+			w.Header().Set("X-Request-Id", "req-1")
+			json.NewEncoder(w).Encode(map[string]string{
+				"method":        r.Method,
+				"authorization": r.Header.Get("Authorization"),
+				"content_type":  r.Header.Get("Content-Type"),
+				"custom":        r.Header.Get("X-Custom"),
+				"query":         r.URL.RawQuery,
+				"body":          string(body),
+			})
+		case "/text":
+			io.WriteString(w, "plain text")
+		case "/fail":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			io.WriteString(w, `{"error": "bad order"}`)
+		case "/redirect":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	var dials int32
+	connector := &connectors.HTTPRequestConnector{DialContext: dialTo(srv.Listener.Addr().String(), &dials)}
+	ctx := context.Background()
+
+	result := connector.ExecuteWithContext(ctx, connectors.HTTPConfig{
+		URL:     "http://api.example.com/echo?existing=1",
+		Headers: map[string]string{"X-Custom": "yes"},
+		Query:   map[string]string{"page": "2"},
+		Body:    map[string]interface{}{"order": 42},
+		Auth:    &connectors.HTTPAuth{Token: "secret-token"},
+	})
+	if result.Status != "success" || result.Data["status_code"] != http.StatusOK {
+		t.Fatalf("Expected a successful request, got %+v", result)
+	}
+	echo := result.Data["body"].(map[string]interface{})
+	if echo["method"] != "POST" || echo["authorization"] != "Bearer secret-token" || echo["custom"] != "yes" {
+		t.Errorf("Expected a POST with bearer auth and the custom header, got %v", echo)
+	}
+	if echo["content_type"] != "application/json" || echo["body"] != `{"order":42}` || echo["query"] != "existing=1&page=2" {
+		t.Errorf("Expected the JSON body and merged query, got %v", echo)
+	}
+	if result.Data["headers"].(map[string]interface{})["X-Request-Id"] != "req-1" || result.Data["size"].(int) == 0 {
+		t.Errorf("Expected response headers and size, got %v", result.Data)
+	}
+
+	result = connector.ExecuteWithContext(ctx, connectors.HTTPConfig{
+		Method: "put",
+		URL:    "http://api.example.com/echo",
+		Body:   "raw=1",
+		Auth:   &connectors.HTTPAuth{Username: "ada", Password: "pw"},
+	})
+	echo = result.Data["body"].(map[string]interface{})
+	if echo["method"] != "PUT" || echo["body"] != "raw=1" || !strings.HasPrefix(echo["authorization"].(string), "Basic ") {
+		t.Errorf("Expected a PUT with the raw body and basic auth, got %v", echo)
+	}
+
+	result = connector.ExecuteWithContext(ctx, connectors.HTTPConfig{URL: "http://api.example.com/text"})
+	if result.Data["body"] != "plain text" || result.Data["size"] != len("plain text") {
+		t.Errorf("Expected a non-JSON body as text, got %v", result.Data)
+	}
+
+	result = connector.ExecuteWithContext(ctx, connectors.HTTPConfig{URL: "http://api.example.com/fail"})
+	if result.Status != "failed" || result.ErrorCode != connectors.ErrCodeUpstreamHTTP || result.Data["status_code"] != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an error status to fail with the response kept, got %+v", result)
+	}
+
+	// Blocked destinations are refused before any connection is made
+	before := atomic.LoadInt32(&dials)
+	for _, url := range []string{
+		"http://localhost:8080/admin",
+		"http://127.0.0.1/",
+		"http://[::1]/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/",
+		"file:///etc/passwd",
+	} {
+		result := connector.ExecuteWithContext(ctx, connectors.HTTPConfig{URL: url})
+		if result.Status != "failed" || !strings.Contains(result.Message, "refused") {
+			t.Errorf("%s: expected the request to be refused, got %+v", url, result)
+		}
+	}
+	if atomic.LoadInt32(&dials) != before {
+		t.Errorf("Expected no connections to blocked destinations")
+	}
+
+	result = connector.ExecuteWithContext(ctx, connectors.HTTPConfig{URL: "http://api.example.com/redirect"})
+	if result.Status != "failed" || !strings.Contains(result.Message, "not allowed") {
+		t.Errorf("Expected a redirect to the metadata service to be refused, got %+v", result)
+	}
+}
+
+// TestHTTPRequestGuardedDialer checks a hostname that resolves to a loopback address is
+// refused at connection time, past the URL check
+func TestHTTPRequestGuardedDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request to reach the loopback server")
+	}))
+	defer srv.Close()
+
+	// The machine's own hostname usually resolves to loopback through /etc/hosts
+	hostname, _ := os.Hostname()
+	ips, err := net.LookupIP(hostname)
+	if err != nil || len(ips) == 0 || !ips[0].IsLoopback() {
+		t.Skipf("Hostname %q does not resolve to a loopback address", hostname)
+	}
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	result := (&connectors.HTTPRequestConnector{}).ExecuteWithContext(context.Background(), connectors.HTTPConfig{URL: "http://" + net.JoinHostPort(hostname, port) + "/"})
+	if result.Status != "failed" || !strings.Contains(result.Message, "not allowed") {
+		t.Errorf("Expected a loopback connection to be refused, got %+v", result)
+	}
+}
+
+// TestParseHTTPAuth covers the credential formats for HTTP auth
+func TestParseHTTPAuth(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    connectors.HTTPAuth
+		wantErr bool
+	}{
+		{raw: "sk_live_123", want: connectors.HTTPAuth{Token: "sk_live_123"}},
+		{raw: `{"token": "abc"}`, want: connectors.HTTPAuth{Token: "abc"}},
+		{raw: `{"username": "ada", "password": "pw"}`, want: connectors.HTTPAuth{Username: "ada", Password: "pw"}},
+		{raw: `{"token": "abc", "username": "ada"}`, wantErr: true},
+		{raw: `{}`, wantErr: true},
+		{raw: `{"token": `, wantErr: true},
+		{raw: "  ", wantErr: true},
+	}
+	for _, tt := range tests {
+		auth, err := connectors.ParseHTTPAuth(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.raw, tt.wantErr, err)
+			continue
+		}
+		if err == nil && *auth != tt.want {
+			t.Errorf("%q: expected %+v, got %+v", tt.raw, tt.want, *auth)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jira connector limits
+const (
+	jiraSearchLimit      = 50               // Issues returned by one search
+	jiraDefaultRateLimit = 10 * time.Second // Wait after a 429 without Retry-After
+)
+
+// JiraConnector works with issues through the Jira REST API (v2, which takes plain text
+// descriptions and is served by both Jira Cloud and Data Center)
+// Reference: https://developer.atlassian.com/cloud/jira/platform/rest/v2/
+type JiraConnector struct {
+	BaseURL  string // Site URL, e.g. https://acme.atlassian.net
+	Email    string // Basic auth with an API token (Jira Cloud)
+	APIToken string
+	Token    string // Bearer personal access token (Jira Data Center), instead of email and API token
+}
+
+// JiraConfig represents Jira connector configuration
+type JiraConfig struct {
+	Operation   string `json:"operation"`   // create_issue, add_comment, transition_issue, search
+	ProjectKey  string `json:"project_key"` // For create_issue (e.g., "OPS")
+	IssueType   string `json:"issue_type"`  // For create_issue (default "Task")
+	Summary     string `json:"summary"`     // For create_issue
+	Description string `json:"description"` // Issue description, or the comment for add_comment
+	IssueKey    string `json:"issue_key"`   // For add_comment and transition_issue (e.g., "OPS-42")
+	Transition  string `json:"transition"`  // Transition name or ID, for transition_issue
+	JQL         string `json:"jql"`         // For search
+}
+
+// jiraOperations are the supported operations; the bool marks those that change issues
+var jiraOperations = map[string]bool{
+	"create_issue":     true,
+	"add_comment":      true,
+	"transition_issue": true,
+	"search":           false,
+}
+
+// JiraOperationWrites reports whether an operation changes issues, so it is skipped in a sandbox
+func JiraOperationWrites(operation string) bool {
+	return jiraOperations[operation]
+}
+
+// ParseJiraCredential reads the "jira" credential: JSON with base_url and either email and
+// api_token (basic auth) or token (a bearer personal access token)
+func ParseJiraCredential(raw string) (*JiraConnector, error) {
+	var cred struct {
+		BaseURL  string `json:"base_url"`
+		Email    string `json:"email"`
+		APIToken string `json:"api_token"`
+		Token    string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(raw), &cred); err != nil {
+		return nil, fmt.Errorf("expected JSON with base_url and email and api_token, or token: %v", err)
+	}
+	parsed, err := url.Parse(cred.BaseURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, errors.New("base_url must be the site's http(s) URL")
+	}
+	switch {
+	case cred.Token != "" && (cred.Email != "" || cred.APIToken != ""):
+		return nil, errors.New("use email and api_token, or token, not both")
+	case cred.Token == "" && (cred.Email == "" || cred.APIToken == ""):
+		return nil, errors.New("email and api_token, or token, are required")
+	}
+	return &JiraConnector{
+		BaseURL:  strings.TrimRight(cred.BaseURL, "/"),
+		Email:    cred.Email,
+		APIToken: cred.APIToken,
+		Token:    cred.Token,
+	}, nil
+}
+
+// ExecuteWithContext performs a Jira operation
+// Issue results carry the issue key and its browse URL for later steps
+func (j *JiraConnector) ExecuteWithContext(ctx context.Context, config JiraConfig) Result {
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return NewCancelledResult("Context cancelled before Jira request: " + ctx.Err().Error())
+	default:
+	}
+
+	if _, ok := jiraOperations[config.Operation]; !ok {
+		return NewFailureResult(fmt.Sprintf("Invalid Jira operation: %s. Valid: create_issue, add_comment, transition_issue, search", config.Operation), start)
+	}
+	if j.BaseURL == "" || (j.Token == "" && (j.Email == "" || j.APIToken == "")) {
+		return NewFailureResult("Jira base URL and credentials are required", start)
+	}
+
+	switch config.Operation {
+	case "create_issue":
+		if config.ProjectKey == "" || config.Summary == "" {
+			return NewFailureResult("Jira project key and summary are required", start)
+		}
+		issueType := config.IssueType
+		if issueType == "" {
+			issueType = "Task"
+		}
+		fields := map[string]interface{}{
+			"project":   map[string]string{"key": config.ProjectKey},
+			"issuetype": map[string]string{"name": issueType},
+			"summary":   config.Summary,
+		}
+		if config.Description != "" {
+			fields["description"] = config.Description
+		}
+		var created struct {
+			ID  string `json:"id"`
+			Key string `json:"key"`
+		}
+		if result := j.call(ctx, "POST", "/rest/api/2/issue", map[string]interface{}{"fields": fields}, &created, start); result != nil {
+			return *result
+		}
+		return NewSuccessResult(fmt.Sprintf("Jira issue %s created in %s", created.Key, config.ProjectKey), map[string]interface{}{
+			"operation": "create_issue",
+			"issue_id":  created.ID,
+			"issue_key": created.Key,
+			"url":       j.browseURL(created.Key),
+		}, start)
+
+	case "add_comment":
+		if config.IssueKey == "" {
+			return NewFailureResult("Jira issue key is required", start)
+		}
+		if config.Description == "" {
+			return NewFailureResult("Jira comment (jira_description) is required", start)
+		}
+		var comment struct {
+			ID string `json:"id"`
+		}
+		if result := j.call(ctx, "POST", j.issuePath(config.IssueKey)+"/comment", map[string]string{"body": config.Description}, &comment, start); result != nil {
+			return *result
+		}
+		return NewSuccessResult(fmt.Sprintf("Commented on Jira issue %s", config.IssueKey), map[string]interface{}{
+			"operation":  "add_comment",
+			"issue_key":  config.IssueKey,
+			"comment_id": comment.ID,
+			"url":        j.browseURL(config.IssueKey) + "?focusedCommentId=" + url.QueryEscape(comment.ID),
+		}, start)
+
+	case "transition_issue":
+		if config.IssueKey == "" || config.Transition == "" {
+			return NewFailureResult("Jira issue key and transition are required", start)
+		}
+		var available struct {
+			Transitions []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+				To   struct {
+					Name string `json:"name"`
+				} `json:"to"`
+			} `json:"transitions"`
+		}
+		if result := j.call(ctx, "GET", j.issuePath(config.IssueKey)+"/transitions", nil, &available, start); result != nil {
+			return *result
+		}
+		var names []string
+		for _, transition := range available.Transitions {
+			if transition.ID != config.Transition && !strings.EqualFold(transition.Name, config.Transition) {
+				names = append(names, transition.Name)
+				continue
+			}
+			body := map[string]interface{}{"transition": map[string]string{"id": transition.ID}}
+			if result := j.call(ctx, "POST", j.issuePath(config.IssueKey)+"/transitions", body, nil, start); result != nil {
+				return *result
+			}
+			return NewSuccessResult(fmt.Sprintf("Jira issue %s moved to %s", config.IssueKey, transition.To.Name), map[string]interface{}{
+				"operation":     "transition_issue",
+				"issue_key":     config.IssueKey,
+				"transition_id": transition.ID,
+				"transition":    transition.Name,
+				"status":        transition.To.Name,
+				"url":           j.browseURL(config.IssueKey),
+			}, start)
+		}
+		return NewFailureResult(fmt.Sprintf("Jira issue %s has no transition %q; available: %s", config.IssueKey, config.Transition, strings.Join(names, ", ")), start)
+
+	default: // search
+		if config.JQL == "" {
+			return NewFailureResult("Jira JQL query is required", start)
+		}
+		query := url.Values{"jql": {config.JQL}, "maxResults": {strconv.Itoa(jiraSearchLimit)}}
+		var found struct {
+			Total  int                      `json:"total"`
+			Issues []map[string]interface{} `json:"issues"`
+		}
+		if result := j.call(ctx, "GET", "/rest/api/2/search?"+query.Encode(), nil, &found, start); result != nil {
+			return *result
+		}
+		keys := make([]string, 0, len(found.Issues))
+		for _, issue := range found.Issues {
+			if key, ok := issue["key"].(string); ok {
+				keys = append(keys, key)
+				issue["url"] = j.browseURL(key)
+			}
+		}
+		return NewSuccessResult(fmt.Sprintf("Jira search returned %d of %d issues", len(found.Issues), found.Total), map[string]interface{}{
+			"operation":  "search",
+			"issues":     found.Issues,
+			"issue_keys": keys,
+			"count":      len(found.Issues),
+			"total":      found.Total,
+		}, start)
+	}
+}
+
+// issuePath is the API path of an issue
+func (j *JiraConnector) issuePath(key string) string {
+	return "/rest/api/2/issue/" + url.PathEscape(key)
+}
+
+// browseURL is where people open an issue
+func (j *JiraConnector) browseURL(key string) string {
+	return j.BaseURL + "/browse/" + url.PathEscape(key)
+}
+
+// call sends one API request and decodes the response into out (nil skips the body)
+// It returns a result only when the call failed; Jira's own error messages are kept as is
+func (j *JiraConnector) call(ctx context.Context, method, path string, body interface{}, out interface{}, start time.Time) *Result {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			result := NewFailureResult(fmt.Sprintf("Failed to marshal Jira request: %v", err), start)
+			return &result
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, j.BaseURL+path, reader)
+	if err != nil {
+		result := NewFailureResult(fmt.Sprintf("Failed to create Jira request: %v", err), start)
+		return &result
+	}
+	req.Header.Set("Accept", "application/json")
+	if j.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+j.Token)
+	} else {
+		req.SetBasicAuth(j.Email, j.APIToken)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := HTTPClients().Client("jira", 0)
+	resp, err := client.Do(req)
+
+	select {
+	case <-ctx.Done():
+		result := NewCancelledResult("Context cancelled during Jira request: " + ctx.Err().Error())
+		return &result
+	default:
+	}
+
+	if err != nil {
+		result := NewRequestErrorResult("Jira", err, fmt.Sprintf("Jira request failed: %v", err), start)
+		return &result
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result := NewInvalidResponseResult("Jira", fmt.Sprintf("Failed to read Jira response: %v", err), start)
+		return &result
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := jiraDefaultRateLimit
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		result := NewRateLimitedResult("Jira", retryAfter, fmt.Sprintf("Jira rate limited the request; retry after %s", retryAfter), start)
+		return &result
+	}
+	if resp.StatusCode >= 400 {
+		result := jiraErrorResult(resp.StatusCode, respBody, start)
+		return &result
+	}
+
+	if out == nil || len(bytes.TrimSpace(respBody)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		result := NewInvalidResponseResult("Jira", fmt.Sprintf("Failed to parse Jira response: %v", err), start)
+		return &result
+	}
+	return nil
+}
+
+// jiraErrorResult reports an error status with Jira's messages verbatim: errorMessages,
+// then the per-field errors (e.g. "summary: You must specify a summary of the issue.")
+func jiraErrorResult(status int, body []byte, start time.Time) Result {
+	var apiError struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	json.Unmarshal(body, &apiError)
+
+	problems := append([]string(nil), apiError.ErrorMessages...)
+	fields := make([]string, 0, len(apiError.Errors))
+	for field := range apiError.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		problems = append(problems, field+": "+apiError.Errors[field])
+	}
+	detail := strings.Join(problems, "; ")
+	if detail == "" {
+		detail = strings.TrimSpace(string(body))
+	}
+
+	result := NewHTTPErrorResult("Jira", status, fmt.Sprintf("Jira returned HTTP error: %d - %s", status, detail), start)
+	result.Data = map[string]interface{}{"status_code": status}
+	if len(apiError.ErrorMessages) > 0 {
+		result.Data["error_messages"] = apiError.ErrorMessages
+	}
+	if len(apiError.Errors) > 0 {
+		result.Data["errors"] = apiError.Errors
+	}
+	return result
+}
+
+// DryRunJira simulates a Jira call without actually making the request
+func (j *JiraConnector) DryRunJira(config JiraConfig) Result {
+	start := time.Now()
+
+	return NewSuccessResult("Jira dry run completed", map[string]interface{}{
+		"operation":   config.Operation,
+		"project_key": config.ProjectKey,
+		"issue_type":  config.IssueType,
+		"summary":     config.Summary,
+		"issue_key":   config.IssueKey,
+		"transition":  config.Transition,
+		"note":        "This is a dry run - no actual Jira call was made",
+		"example_operations": map[string]string{
+			"create_issue":     "Open an issue: {project_key: 'OPS', summary: 'Deploy failed'}",
+			"add_comment":      "Comment on OPS-42",
+			"transition_issue": "Move OPS-42 through the 'Done' transition",
+			"search":           "Search with JQL: project = OPS AND status = 'In Progress'",
+		},
+	}, start)
+}
@@ -0,0 +1,147 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestJiraConnector runs each operation against a fake API and checks the requests, the
+// issue key and browse URL surfaced for later steps, and Jira's errors kept verbatim
+func TestJiraConnector(t *testing.T) {
+	var transitioned string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if email, token, ok := r.BasicAuth(); !ok || email != "ops@acme.com" || token != "jira-token" {
+			t.Errorf("Expected basic auth with the email and API token on %s", r.URL.Path)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /rest/api/2/issue":
+			fields := body["fields"].(map[string]interface{})
+			if fields["project"].(map[string]interface{})["key"] != "OPS" || fields["issuetype"].(map[string]interface{})["name"] != "Bug" || fields["description"] != "Checkout returns 500" {
+				t.Errorf("Unexpected issue fields %v", fields)
+			}
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"id": "10042", "key": "OPS-42", "self": "https://acme.atlassian.net/rest/api/2/issue/10042"}`)
+		case "POST /rest/api/2/issue/OPS-42/comment":
+			if body["body"] != "Rolled back" {
+				t.Errorf("Unexpected comment %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"id": "7"}`)
+		case "GET /rest/api/2/issue/OPS-42/transitions":
+			io.WriteString(w, `{"transitions": [{"id": "21", "name": "Start progress", "to": {"name": "In Progress"}}, {"id": "31", "name": "Done", "to": {"name": "Done"}}]}`)
+		case "POST /rest/api/2/issue/OPS-42/transitions":
+			transitioned = body["transition"].(map[string]interface{})["id"].(string)
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /rest/api/2/search":
+			if r.URL.Query().Get("jql") != "project = OPS AND status = Done" || r.URL.Query().Get("maxResults") != "50" {
+				t.Errorf("Unexpected search %s", r.URL.RawQuery)
+			}
+			io.WriteString(w, `{"total": 3, "issues": [{"key": "OPS-42", "fields": {"summary": "Checkout down"}}, {"key": "OPS-7"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"errorMessages": ["Issue does not exist or you do not have permission to see it."], "errors": {}}`)
+		}
+	}))
+	defer srv.Close()
+
+	jira := &connectors.JiraConnector{BaseURL: srv.URL, Email: "ops@acme.com", APIToken: "jira-token"}
+	ctx := context.Background()
+
+	create := connectors.JiraConfig{Operation: "create_issue", ProjectKey: "OPS", IssueType: "Bug", Summary: "Checkout down", Description: "Checkout returns 500"}
+	result := jira.ExecuteWithContext(ctx, create)
+	if result.Status != "success" || result.Data["issue_key"] != "OPS-42" || result.Data["url"] != srv.URL+"/browse/OPS-42" {
+		t.Fatalf("Expected the created issue's key and browse URL, got %+v", result)
+	}
+
+	comment := connectors.JiraConfig{Operation: "add_comment", IssueKey: "OPS-42", Description: "Rolled back"}
+	if result := jira.ExecuteWithContext(ctx, comment); result.Status != "success" || result.Data["comment_id"] != "7" {
+		t.Errorf("Expected the comment ID, got %+v", result)
+	}
+
+	transition := connectors.JiraConfig{Operation: "transition_issue", IssueKey: "OPS-42", Transition: "done"}
+	if result := jira.ExecuteWithContext(ctx, transition); result.Status != "success" || result.Data["status"] != "Done" || transitioned != "31" {
+		t.Errorf("Expected the Done transition (by name, any case), got %+v and %q", result, transitioned)
+	}
+	transition.Transition = "Reopen"
+	if result := jira.ExecuteWithContext(ctx, transition); result.Status != "failed" || !strings.Contains(result.Message, "available: Start progress, Done") {
+		t.Errorf("Expected the available transitions listed, got %+v", result)
+	}
+
+	search := connectors.JiraConfig{Operation: "search", JQL: "project = OPS AND status = Done"}
+	result = jira.ExecuteWithContext(ctx, search)
+	if result.Status != "success" || result.Data["count"] != 2 || result.Data["total"] != 3 || strings.Join(result.Data["issue_keys"].([]string), ",") != "OPS-42,OPS-7" {
+		t.Errorf("Expected 2 of 3 issues, got %+v", result)
+	}
+
+	// Errors come back as Jira wrote them
+	result = jira.ExecuteWithContext(ctx, connectors.JiraConfig{Operation: "add_comment", IssueKey: "OPS-1", Description: "Hello"})
+	if result.Status != "failed" || result.ErrorCode != connectors.ErrCodeUpstreamHTTP || !strings.Contains(result.Message, "404 - Issue does not exist or you do not have permission to see it.") {
+		t.Errorf("Expected Jira's error message, got %+v", result)
+	}
+
+	for _, config := range []connectors.JiraConfig{
+		{Operation: "delete_issue"},
+		{Operation: "create_issue", ProjectKey: "OPS"},
+		{Operation: "add_comment", IssueKey: "OPS-42"},
+		{Operation: "transition_issue", IssueKey: "OPS-42"},
+		{Operation: "search"},
+	} {
+		if result := jira.ExecuteWithContext(ctx, config); result.Status != "failed" {
+			t.Errorf("%+v: expected a validation failure, got %+v", config, result)
+		}
+	}
+}
+
+// TestJiraFieldErrors checks per-field errors are reported verbatim and kept in the data
+func TestJiraFieldErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat-123" {
+			t.Errorf("Expected the personal access token, got %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"errorMessages": [], "errors": {"summary": "You must specify a summary of the issue.", "issuetype": "Specify a valid issue type"}}`)
+	}))
+	defer srv.Close()
+
+	jira := &connectors.JiraConnector{BaseURL: srv.URL, Token: "pat-123"}
+	result := jira.ExecuteWithContext(context.Background(), connectors.JiraConfig{Operation: "create_issue", ProjectKey: "OPS", IssueType: "Bugg", Summary: "x"})
+	want := "Jira returned HTTP error: 400 - issuetype: Specify a valid issue type; summary: You must specify a summary of the issue."
+	if result.Status != "failed" || result.Message != want {
+		t.Errorf("Expected %q, got %q", want, result.Message)
+	}
+	if fieldErrors, _ := result.Data["errors"].(map[string]string); fieldErrors["issuetype"] != "Specify a valid issue type" {
+		t.Errorf("Expected the field errors in the data, got %v", result.Data)
+	}
+}
+
+// TestParseJiraCredential accepts email and API token or a personal access token
+func TestParseJiraCredential(t *testing.T) {
+	jira, err := connectors.ParseJiraCredential(`{"base_url": "https://acme.atlassian.net/", "email": "ops@acme.com", "api_token": "t"}`)
+	if err != nil || jira.BaseURL != "https://acme.atlassian.net" || jira.Email != "ops@acme.com" {
+		t.Errorf("Expected basic auth credentials, got %+v, %v", jira, err)
+	}
+	if jira, err := connectors.ParseJiraCredential(`{"base_url": "https://jira.acme.com", "token": "pat"}`); err != nil || jira.Token != "pat" {
+		t.Errorf("Expected a personal access token, got %+v, %v", jira, err)
+	}
+	for _, raw := range []string{
+		`not json`,
+		`{"email": "ops@acme.com", "api_token": "t"}`,
+		`{"base_url": "ftp://acme.com", "token": "pat"}`,
+		`{"base_url": "https://acme.atlassian.net", "email": "ops@acme.com"}`,
+		`{"base_url": "https://acme.atlassian.net", "email": "ops@acme.com", "api_token": "t", "token": "pat"}`,
+	} {
+		if _, err := connectors.ParseJiraCredential(raw); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
@@ -63,9 +63,7 @@ func (n *NASAAPIConnector) ExecuteWithContext(ctx context.Context, config NASAAP
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 15 * time.Second, // NASA API can be slower
-	}
+	client := HTTPClients().Client("nasa", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -76,25 +74,25 @@ func (n *NASAAPIConnector) ExecuteWithContext(ctx context.Context, config NASAAP
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("NASA API request failed: %v", err), start)
+		return NewRequestErrorResult("NASA", err, fmt.Sprintf("NASA API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read NASA API response: %v", err), start)
+		return NewInvalidResponseResult("NASA API", fmt.Sprintf("Failed to read NASA API response: %v", err), start)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("NASA API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("NASA API", resp.StatusCode, fmt.Sprintf("NASA API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	// Parse JSON response
 	var nasaData interface{}
 	if err := json.Unmarshal(body, &nasaData); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse NASA API response: %v", err), start)
+		return NewInvalidResponseResult("NASA API", fmt.Sprintf("Failed to parse NASA API response: %v", err), start)
 	}
 
 	// Extract title for APOD if available
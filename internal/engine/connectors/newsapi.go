@@ -5,21 +5,153 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // NewsAPI handles News API integrations
 // API Documentation: https://newsapi.org/docs
 type NewsAPI struct {
-	APIKey string
+	APIKey  string
+	BaseURL string // Default: https://newsapi.org
+}
+
+// baseURL returns the API root, without a trailing slash
+func (n *NewsAPI) baseURL() string {
+	if n.BaseURL == "" {
+		return "https://newsapi.org"
+	}
+	return strings.TrimRight(n.BaseURL, "/")
 }
 
 // NewsConfig represents News API query configuration
+// Query or any of From, To, Language, SortBy and SearchIn search /v2/everything; otherwise
+// the top headlines for Country and Category, or for Sources, are fetched
 type NewsConfig struct {
 	Query    string `json:"query"`     // Search query (e.g., "bitcoin")
-	Country  string `json:"country"`   // Country code (e.g., "us")
-	Category string `json:"category"`  // Category (e.g., "technology")
+	Country  string `json:"country"`   // Country code (e.g., "us"), top headlines only
+	Category string `json:"category"`  // Category (e.g., "technology"), top headlines only
 	PageSize int    `json:"page_size"` // Number of articles (default: 10)
+	From     string `json:"from"`      // Oldest article, ISO 8601 (e.g., "2026-10-16" or "2026-10-16T09:00:00Z")
+	To       string `json:"to"`        // Newest article, ISO 8601
+	Language string `json:"language"`  // Two-letter language code (e.g., "en")
+	Sources  string `json:"sources"`   // Comma-separated source IDs (e.g., "bbc-news,the-verge")
+	SortBy   string `json:"sort_by"`   // relevancy, popularity or publishedAt
+	SearchIn string `json:"search_in"` // Comma-separated fields the query must match: title, description, content
+}
+
+// News API values the everything endpoint accepts
+var (
+	newsSortOrders     = []string{"relevancy", "popularity", "publishedAt"}
+	newsSearchFields   = []string{"title", "description", "content"}
+	newsLanguages      = []string{"ar", "de", "en", "es", "fr", "he", "it", "nl", "no", "pt", "ru", "sv", "ud", "zh"}
+	newsDatePattern    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:\d{2})?)?$`)
+	newsSourcesPattern = regexp.MustCompile(`^[a-z0-9-]+(,[a-z0-9-]+)*$`)
+)
+
+// NewsHeadline is the compact form of an article, for templating into messages
+type NewsHeadline struct {
+	Title       string `json:"title"`
+	Source      string `json:"source"`
+	URL         string `json:"url"`
+	PublishedAt string `json:"publishedAt"`
+}
+
+// filtersEverything reports whether the config uses a filter only /v2/everything has
+func (c NewsConfig) filtersEverything() bool {
+	return c.From != "" || c.To != "" || c.Language != "" || c.SortBy != "" || c.SearchIn != ""
+}
+
+// searchesEverything reports whether the config needs /v2/everything rather than top headlines
+// A query on its own still searches everything, ignoring Country and Category as it always has
+func (c NewsConfig) searchesEverything() bool {
+	return c.Query != "" || c.filtersEverything()
+}
+
+// validate applies News API's rules up front, so a bad combination fails with a reason
+// instead of a bare 400 from the API
+func (c NewsConfig) validate() error {
+	if c.Sources != "" && (c.Country != "" || c.Category != "") {
+		return fmt.Errorf("news_sources can't be combined with news_country or news_category; News API only filters by one of them")
+	}
+	if c.Sources != "" && !newsSourcesPattern.MatchString(c.Sources) {
+		return fmt.Errorf("news_sources must be a comma-separated list of source IDs like \"bbc-news,the-verge\"")
+	}
+	if c.filtersEverything() && c.Query == "" && c.Sources == "" {
+		return fmt.Errorf("news_from, news_to, news_language, news_sort_by and news_search_in need news_query or news_sources")
+	}
+	if c.filtersEverything() && (c.Country != "" || c.Category != "") {
+		return fmt.Errorf("news_country and news_category only apply to top headlines; use news_query or news_sources with date, language and sort filters")
+	}
+	for name, value := range map[string]string{"news_from": c.From, "news_to": c.To} {
+		if value != "" && !newsDatePattern.MatchString(value) {
+			return fmt.Errorf("%s must be an ISO 8601 date or time, like \"2026-10-16\" or \"2026-10-16T09:00:00Z\"", name)
+		}
+	}
+	if c.Language != "" && !containsString(newsLanguages, c.Language) {
+		return fmt.Errorf("news_language %q isn't supported; use one of %s", c.Language, strings.Join(newsLanguages, ", "))
+	}
+	if c.SortBy != "" && !containsString(newsSortOrders, c.SortBy) {
+		return fmt.Errorf("news_sort_by must be one of %s", strings.Join(newsSortOrders, ", "))
+	}
+	if c.SearchIn != "" {
+		for _, field := range strings.Split(c.SearchIn, ",") {
+			if !containsString(newsSearchFields, strings.TrimSpace(field)) {
+				return fmt.Errorf("news_search_in must list fields from %s", strings.Join(newsSearchFields, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// requestURL is the News API URL for the config
+func (n *NewsAPI) requestURL(config NewsConfig) string {
+	params := url.Values{}
+	params.Set("apiKey", n.APIKey)
+	params.Set("pageSize", strconv.Itoa(config.PageSize))
+	if config.Query != "" {
+		params.Set("q", config.Query)
+	}
+	if config.Sources != "" {
+		params.Set("sources", config.Sources)
+	}
+
+	endpoint := "/v2/top-headlines"
+	if config.searchesEverything() {
+		endpoint = "/v2/everything"
+		for name, value := range map[string]string{
+			"from":     config.From,
+			"to":       config.To,
+			"language": config.Language,
+			"sortBy":   config.SortBy,
+			"searchIn": strings.ReplaceAll(config.SearchIn, " ", ""),
+		} {
+			if value != "" {
+				params.Set(name, value)
+			}
+		}
+	} else {
+		if config.Country != "" {
+			params.Set("country", config.Country)
+		}
+		if config.Category != "" {
+			params.Set("category", config.Category)
+		}
+	}
+	return n.baseURL() + endpoint + "?" + params.Encode()
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // NewsArticle represents a single news article
@@ -61,23 +193,10 @@ func (n *NewsAPI) ExecuteWithContext(ctx context.Context, config NewsConfig) Res
 		config.PageSize = 100 // News API limit
 	}
 
-	// Build API URL
-	var apiURL string
-	if config.Query != "" {
-		// Search everything
-		apiURL = fmt.Sprintf("https://newsapi.org/v2/everything?q=%s&pageSize=%d&apiKey=%s",
-			config.Query, config.PageSize, n.APIKey)
-	} else {
-		// Top headlines
-		apiURL = fmt.Sprintf("https://newsapi.org/v2/top-headlines?pageSize=%d&apiKey=%s",
-			config.PageSize, n.APIKey)
-		if config.Country != "" {
-			apiURL += "&country=" + config.Country
-		}
-		if config.Category != "" {
-			apiURL += "&category=" + config.Category
-		}
+	if err := config.validate(); err != nil {
+		return NewFailureResult(err.Error(), start)
 	}
+	apiURL := n.requestURL(config)
 
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
@@ -86,10 +205,8 @@ func (n *NewsAPI) ExecuteWithContext(ctx context.Context, config NewsConfig) Res
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("newsapi", 0)
+	resp, err := doCall(client, req)
 
 	// Check if context was cancelled during request
 	select {
@@ -99,28 +216,39 @@ func (n *NewsAPI) ExecuteWithContext(ctx context.Context, config NewsConfig) Res
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("News API request failed: %v", err), start)
+		return NewRequestErrorResult("News", err, fmt.Sprintf("News API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Check response status
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("News API returned error status: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("News API", resp.StatusCode, fmt.Sprintf("News API returned error status: %d", resp.StatusCode), start)
 	}
 
 	// Parse response
 	var newsResp NewsAPIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&newsResp); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse News API response: %v", err), start)
+		return NewInvalidResponseResult("News API", fmt.Sprintf("Failed to parse News API response: %v", err), start)
 	}
 
 	if newsResp.Status != "ok" {
 		return NewFailureResult(fmt.Sprintf("News API returned error status: %s", newsResp.Status), start)
 	}
 
+	headlines := make([]NewsHeadline, 0, len(newsResp.Articles))
+	for _, article := range newsResp.Articles {
+		headlines = append(headlines, NewsHeadline{
+			Title:       article.Title,
+			Source:      article.Source.Name,
+			URL:         article.URL,
+			PublishedAt: article.PublishedAt,
+		})
+	}
+
 	return NewSuccessResult("News articles fetched successfully", map[string]interface{}{
 		"total_results": newsResp.TotalResults,
 		"articles":      newsResp.Articles,
+		"headlines":     headlines,
 		"count":         len(newsResp.Articles),
 	}, start)
 }
@@ -0,0 +1,88 @@
+package connectors_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestNewsAPIQuery checks each config's exact endpoint and query string, and the compact
+// headlines returned alongside the articles
+func TestNewsAPIQuery(t *testing.T) {
+	requests := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.Path + "?" + r.URL.RawQuery
+		io.WriteString(w, `{"status": "ok", "totalResults": 1, "articles": [{
+			"source": {"id": "the-verge", "name": "The Verge"}, "author": "A. Writer",
+			"title": "Acme ships v2", "description": "Long text", "url": "https://example.com/acme",
+			"publishedAt": "2026-10-16T08:00:00Z"}]}`)
+	}))
+	defer srv.Close()
+	news := &connectors.NewsAPI{APIKey: "key", BaseURL: srv.URL}
+
+	for _, c := range []struct {
+		name   string
+		config connectors.NewsConfig
+		want   string
+	}{
+		{"top headlines", connectors.NewsConfig{Country: "us", Category: "technology"},
+			"/v2/top-headlines?apiKey=key&category=technology&country=us&pageSize=10"},
+		{"headlines from sources", connectors.NewsConfig{Sources: "bbc-news,the-verge", PageSize: 5},
+			"/v2/top-headlines?apiKey=key&pageSize=5&sources=bbc-news%2Cthe-verge"},
+		{"everything", connectors.NewsConfig{
+			Query: "Acme Corp", PageSize: 5, From: "2026-10-16T08:00:00Z", To: "2026-10-17",
+			Language: "en", SortBy: "popularity", SearchIn: "title, description",
+		}, "/v2/everything?apiKey=key&from=2026-10-16T08%3A00%3A00Z&language=en&pageSize=5&q=Acme+Corp" +
+			"&searchIn=title%2Cdescription&sortBy=popularity&to=2026-10-17"},
+		{"everything from sources", connectors.NewsConfig{Sources: "the-verge", SortBy: "publishedAt"},
+			"/v2/everything?apiKey=key&pageSize=10&sortBy=publishedAt&sources=the-verge"},
+	} {
+		result := news.ExecuteWithContext(context.Background(), c.config)
+		if result.Status != "success" {
+			t.Fatalf("%s: expected success, got %s: %s", c.name, result.Status, result.Message)
+		}
+		if got := <-requests; got != c.want {
+			t.Errorf("%s: unexpected request\n got %s\nwant %s", c.name, got, c.want)
+		}
+		headlines := result.Data["headlines"].([]connectors.NewsHeadline)
+		want := connectors.NewsHeadline{Title: "Acme ships v2", Source: "The Verge", URL: "https://example.com/acme", PublishedAt: "2026-10-16T08:00:00Z"}
+		if len(headlines) != 1 || headlines[0] != want {
+			t.Errorf("%s: unexpected headlines %+v", c.name, headlines)
+		}
+	}
+}
+
+// TestNewsAPIValidation refuses combinations News API would reject, without calling it
+func TestNewsAPIValidation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request, got %s", r.URL)
+	}))
+	defer srv.Close()
+	news := &connectors.NewsAPI{APIKey: "key", BaseURL: srv.URL}
+
+	for _, c := range []struct {
+		name   string
+		config connectors.NewsConfig
+		reason string
+	}{
+		{"sources with country", connectors.NewsConfig{Sources: "bbc-news", Country: "gb"}, "can't be combined"},
+		{"sources with category", connectors.NewsConfig{Sources: "bbc-news", Category: "business"}, "can't be combined"},
+		{"malformed sources", connectors.NewsConfig{Sources: "BBC News"}, "comma-separated list"},
+		{"filters without a query", connectors.NewsConfig{Language: "en"}, "need news_query or news_sources"},
+		{"country with filters", connectors.NewsConfig{Query: "acme", Country: "us", SortBy: "popularity"}, "only apply to top headlines"},
+		{"bad date", connectors.NewsConfig{Query: "acme", From: "yesterday"}, "news_from must be an ISO 8601"},
+		{"unknown language", connectors.NewsConfig{Query: "acme", Language: "xx"}, "isn't supported"},
+		{"unknown sort", connectors.NewsConfig{Query: "acme", SortBy: "newest"}, "news_sort_by must be one of"},
+		{"unknown field", connectors.NewsConfig{Query: "acme", SearchIn: "title,author"}, "news_search_in must list"},
+	} {
+		result := news.ExecuteWithContext(context.Background(), c.config)
+		if result.Status != "failed" || !strings.Contains(result.Message, c.reason) {
+			t.Errorf("%s: expected a failure mentioning %q, got %s: %s", c.name, c.reason, result.Status, result.Message)
+		}
+	}
+}
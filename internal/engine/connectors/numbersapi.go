@@ -54,9 +54,7 @@ func (n *NumbersAPIConnector) ExecuteWithContext(ctx context.Context, config Num
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := HTTPClients().Client("numbersapi", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -67,19 +65,19 @@ func (n *NumbersAPIConnector) ExecuteWithContext(ctx context.Context, config Num
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Numbers API request failed: %v", err), start)
+		return NewRequestErrorResult("Numbers", err, fmt.Sprintf("Numbers API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read Numbers API response: %v", err), start)
+		return NewInvalidResponseResult("Numbers API", fmt.Sprintf("Failed to read Numbers API response: %v", err), start)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Numbers API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Numbers API", resp.StatusCode, fmt.Sprintf("Numbers API returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	// Numbers API returns plain text or JSON
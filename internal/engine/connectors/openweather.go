@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
 // OpenWeatherAPI handles OpenWeather API integrations
 type OpenWeatherAPI struct {
-	APIKey string
+	APIKey  string
+	BaseURL string // Default: https://api.openweathermap.org
 }
 
 // WeatherData represents the OpenWeather API response
@@ -26,6 +28,14 @@ type WeatherData struct {
 	Name string `json:"name"`
 }
 
+// baseURL returns the API root, without a trailing slash
+func (w *OpenWeatherAPI) baseURL() string {
+	if w.BaseURL == "" {
+		return "https://api.openweathermap.org"
+	}
+	return strings.TrimRight(w.BaseURL, "/")
+}
+
 // FetchWeather retrieves weather data for a city
 func (w *OpenWeatherAPI) FetchWeather(city string) Result {
 	return w.FetchWeatherWithContext(context.Background(), city)
@@ -41,17 +51,15 @@ func (w *OpenWeatherAPI) FetchWeatherWithContext(ctx context.Context, city strin
 	default:
 	}
 
-	url := fmt.Sprintf("https://api.openweathermap.org/data/2.5/weather?q=%s&appid=%s&units=metric", city, w.APIKey)
+	url := fmt.Sprintf("%s/data/2.5/weather?q=%s&appid=%s&units=metric", w.baseURL(), city, w.APIKey)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return NewFailureResult(fmt.Sprintf("Failed to create weather request: %v", err), start)
 	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("openweather", 0)
+	resp, err := doCall(client, req)
 
 	select {
 	case <-ctx.Done():
@@ -60,12 +68,12 @@ func (w *OpenWeatherAPI) FetchWeatherWithContext(ctx context.Context, city strin
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("OpenWeather API request failed: %v", err), start)
+		return NewRequestErrorResult("OpenWeather", err, fmt.Sprintf("OpenWeather API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("OpenWeather returned error status: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("OpenWeather", resp.StatusCode, fmt.Sprintf("OpenWeather returned error status: %d", resp.StatusCode), start)
 	}
 
 	var weather WeatherData
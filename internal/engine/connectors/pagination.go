@@ -0,0 +1,77 @@
+package connectors
+
+import "fmt"
+
+// ListOptions control how much of a collection a connector places in Result.Data
+type ListOptions struct {
+	Page   int      `json:"page,omitempty"`   // 1-based page number (default 1)
+	Limit  int      `json:"limit,omitempty"`  // Items per page (0 = no limit)
+	Fields []string `json:"fields,omitempty"` // Keep only these keys of each item (empty = all)
+}
+
+// validate rejects negative page/limit values
+func (o ListOptions) validate() error {
+	if o.Page < 0 {
+		return fmt.Errorf("page must be 1 or more (got %d)", o.Page)
+	}
+	if o.Limit < 0 {
+		return fmt.Errorf("limit must not be negative (got %d)", o.Limit)
+	}
+	return nil
+}
+
+// paginate returns the requested page of a full list, for APIs that can't paginate server-side
+// Without a limit the whole list is a single page
+func (o ListOptions) paginate(items []interface{}) []interface{} {
+	if o.Limit == 0 {
+		return items
+	}
+	page := o.Page
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * o.Limit
+	if start >= len(items) {
+		return []interface{}{}
+	}
+	end := start + o.Limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// limit trims a page the API already selected to at most Limit items
+func (o ListOptions) limit(items []interface{}) []interface{} {
+	if o.Limit > 0 && len(items) > o.Limit {
+		return items[:o.Limit]
+	}
+	return items
+}
+
+// prune keeps only the selected keys of each object in items
+func (o ListOptions) prune(items []interface{}) []interface{} {
+	if len(o.Fields) == 0 {
+		return items
+	}
+	pruned := make([]interface{}, len(items))
+	for i, item := range items {
+		pruned[i] = o.pruneItem(item)
+	}
+	return pruned
+}
+
+// pruneItem keeps only the selected keys of an object; other values are returned as-is
+func (o ListOptions) pruneItem(item interface{}) interface{} {
+	object, ok := item.(map[string]interface{})
+	if !ok || len(o.Fields) == 0 {
+		return item
+	}
+	pruned := make(map[string]interface{}, len(o.Fields))
+	for _, field := range o.Fields {
+		if value, exists := object[field]; exists {
+			pruned[field] = value
+		}
+	}
+	return pruned
+}
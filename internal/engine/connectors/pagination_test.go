@@ -0,0 +1,167 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// resultJSON encodes result data the way templates and chained actions see it
+func resultJSON(t *testing.T, result connectors.Result) string {
+	t.Helper()
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %s: %s", result.Status, result.Message)
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatalf("Failed to encode result data: %v", err)
+	}
+	return string(data)
+}
+
+// people returns n SWAPI-style people
+func people(n int) []map[string]interface{} {
+	list := make([]map[string]interface{}, n)
+	for i := range list {
+		list[i] = map[string]interface{}{
+			"name":       fmt.Sprintf("Person %d", i+1),
+			"height":     "172",
+			"homeworld":  "https://swapi.info/api/planets/1",
+			"birth_year": "19BBY",
+		}
+	}
+	return list
+}
+
+// TestSWAPIClientSidePagination slices a full-collection response (swapi.info style)
+func TestSWAPIClientSidePagination(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		json.NewEncoder(w).Encode(people(82))
+	}))
+	defer server.Close()
+
+	connector := &connectors.SWAPIConnector{BaseURL: server.URL}
+	result := connector.ExecuteWithContext(context.Background(), connectors.SWAPIConfig{
+		Resource:    "people",
+		ListOptions: connectors.ListOptions{Page: 3, Limit: 5, Fields: []string{"name", "height"}},
+	})
+	data := resultJSON(t, result)
+
+	if requested != "/people?page=3" {
+		t.Errorf("Expected the page passed on, got %s", requested)
+	}
+	if got := utils.NewTemplateEngine().Render("showing {{returned_count}} of {{total_count}}: {{data.0.name}}", data); got != "showing 5 of 82: Person 11" {
+		t.Errorf("Unexpected render: %q", got)
+	}
+	if utils.ExtractValue(data, "data.0.homeworld") != "" || utils.ExtractValue(data, "data.0.height") != "172" {
+		t.Errorf("Expected items pruned to the selected fields, got %s", utils.ExtractValue(data, "data.0"))
+	}
+
+	// Past the end is an empty page, not an error
+	result = connector.ExecuteWithContext(context.Background(), connectors.SWAPIConfig{
+		Resource:    "people",
+		ListOptions: connectors.ListOptions{Page: 20, Limit: 5},
+	})
+	if result.Data["returned_count"] != 0 || result.Data["total_count"] != 82 {
+		t.Errorf("Expected an empty page of 82, got %v/%v", result.Data["returned_count"], result.Data["total_count"])
+	}
+}
+
+// TestSWAPIServerSidePagination keeps a paginated envelope (swapi.dev style), trimmed to the limit
+func TestSWAPIServerSidePagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "2" || r.URL.Query().Get("search") != "sky walker" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"count":   82,
+			"next":    "https://swapi.dev/api/people/?page=3",
+			"results": people(10),
+		})
+	}))
+	defer server.Close()
+
+	connector := &connectors.SWAPIConnector{BaseURL: server.URL}
+	result := connector.ExecuteWithContext(context.Background(), connectors.SWAPIConfig{
+		Resource:    "people",
+		Search:      "sky walker",
+		ListOptions: connectors.ListOptions{Page: 2, Limit: 3, Fields: []string{"name"}},
+	})
+	data := resultJSON(t, result)
+
+	if result.Data["total_count"] != 82 || result.Data["returned_count"] != 3 {
+		t.Errorf("Expected 3 of 82, got %v of %v", result.Data["returned_count"], result.Data["total_count"])
+	}
+	if got := utils.ExtractValue(data, "data.results.#"); got != "3" {
+		t.Errorf("Expected the page trimmed to 3 results, got %s", got)
+	}
+	if utils.ExtractValue(data, "data.next") == "" || utils.ExtractValue(data, "data.results.0.height") != "" {
+		t.Errorf("Expected the envelope kept and results pruned, got %s", utils.ExtractValue(data, "data"))
+	}
+
+	// A single resource is pruned but not paged
+	singleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(people(1)[0])
+	}))
+	defer singleServer.Close()
+	single := &connectors.SWAPIConnector{BaseURL: singleServer.URL}
+	result = single.ExecuteWithContext(context.Background(), connectors.SWAPIConfig{
+		Resource:    "people",
+		ID:          "1",
+		ListOptions: connectors.ListOptions{Fields: []string{"name"}},
+	})
+	if data := resultJSON(t, result); utils.ExtractValue(data, "data.name") != "Person 1" || utils.ExtractValue(data, "data.height") != "" {
+		t.Errorf("Unexpected single resource: %s", utils.ExtractValue(data, "data"))
+	}
+}
+
+// TestRESTCountriesPagination pages client-side and asks the API for only the selected fields
+func TestRESTCountriesPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/region/europe" || r.URL.Query().Get("fields") != "name,capital" {
+			t.Errorf("Unexpected request: %s", r.URL.RequestURI())
+		}
+		countries := make([]map[string]interface{}, 53)
+		for i := range countries {
+			countries[i] = map[string]interface{}{
+				"name":       map[string]string{"common": fmt.Sprintf("Country %d", i+1)},
+				"capital":    []string{fmt.Sprintf("Capital %d", i+1)},
+				"population": 1000 * (i + 1),
+			}
+		}
+		json.NewEncoder(w).Encode(countries)
+	}))
+	defer server.Close()
+
+	connector := &connectors.RESTCountriesConnector{BaseURL: server.URL}
+	result := connector.ExecuteWithContext(context.Background(), connectors.RESTCountriesConfig{
+		SearchType:  "region",
+		Query:       "europe",
+		ListOptions: connectors.ListOptions{Page: 11, Limit: 5, Fields: []string{"name", "capital"}},
+	})
+	data := resultJSON(t, result)
+
+	if got := utils.NewTemplateEngine().Render("{{countries.0.name.common}}-{{countries.2.name.common}} ({{returned_count}} of {{total_count}})", data); got != "Country 51-Country 53 (3 of 53)" {
+		t.Errorf("Unexpected render: %q", got)
+	}
+	if utils.ExtractValue(data, "countries.0.population") != "" {
+		t.Error("Expected unselected fields to be pruned client-side")
+	}
+
+	result = connector.ExecuteWithContext(context.Background(), connectors.RESTCountriesConfig{
+		SearchType:  "region",
+		Query:       "europe",
+		ListOptions: connectors.ListOptions{Limit: -1},
+	})
+	if result.Status != "failed" {
+		t.Errorf("Expected a negative limit to be rejected, got %s", result.Status)
+	}
+}
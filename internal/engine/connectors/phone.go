@@ -0,0 +1,135 @@
+package connectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// phoneFormatting are characters people type between digits; they are dropped
+const phoneFormatting = " -.()/\t"
+
+// NormalizePhoneNumber converts a typed phone number into E.164 ("+447700900123")
+// Accepts "+44 7700 900123", "0044 7700 900123", "(555) 123-4567" and national
+// numbers like "07700 900123" when defaultCountryCode is set ("44" or "+44").
+// Short codes (3-6 bare digits) are returned unchanged.
+func NormalizePhoneNumber(raw, defaultCountryCode string) (string, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return "", fmt.Errorf("invalid phone number %q: empty", raw)
+	}
+
+	international := strings.HasPrefix(value, "+")
+	var digits strings.Builder
+	for _, r := range strings.TrimPrefix(value, "+") {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(phoneFormatting, r):
+		default:
+			return "", fmt.Errorf("invalid phone number %q: unexpected character %q", raw, r)
+		}
+	}
+	number := digits.String()
+	if number == "" {
+		return "", fmt.Errorf("invalid phone number %q: no digits", raw)
+	}
+
+	countryCode := strings.TrimPrefix(strings.TrimSpace(defaultCountryCode), "+")
+
+	if !international {
+		switch {
+		case strings.HasPrefix(number, "00"):
+			// International dialing prefix used outside North America
+			international = true
+			number = number[2:]
+		case countryCode == "1" && strings.HasPrefix(number, "011") && len(number) > 11:
+			// North American international dialing prefix
+			international = true
+			number = number[3:]
+		case number == value && len(number) >= 3 && len(number) <= 6:
+			// Short codes go to Twilio as they are
+			return number, nil
+		}
+	}
+
+	if !international {
+		if countryCode == "" {
+			return "", fmt.Errorf("invalid phone number %q: no country code (start with + or set twilio_default_country_code)", raw)
+		}
+		if !isDigits(countryCode) || len(countryCode) > 3 || countryCode[0] == '0' {
+			return "", fmt.Errorf("invalid default country code %q", defaultCountryCode)
+		}
+
+		if countryCode == "1" {
+			// North American numbers are 10 digits, optionally written with the leading 1
+			if len(number) == 11 && number[0] == '1' {
+				number = number[1:]
+			}
+			if len(number) != 10 {
+				return "", fmt.Errorf("invalid phone number %q: US/Canada numbers have 10 digits", raw)
+			}
+		} else {
+			// Drop the national trunk prefix ("07700 900123" in the UK)
+			number = strings.TrimPrefix(number, "0")
+		}
+		number = countryCode + number
+	}
+
+	if number[0] == '0' {
+		return "", fmt.Errorf("invalid phone number %q: country codes never start with 0", raw)
+	}
+	if len(number) < 7 || len(number) > 15 {
+		return "", fmt.Errorf("invalid phone number %q: E.164 numbers have 7 to 15 digits", raw)
+	}
+	return "+" + number, nil
+}
+
+// SplitRecipients expands recipient values that hold several numbers:
+// comma or semicolon separated lists and JSON arrays (e.g. a rendered "{{customer.phones}}")
+func SplitRecipients(values []string) []string {
+	var recipients []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "[") {
+			var list []string
+			if err := json.Unmarshal([]byte(value), &list); err == nil {
+				recipients = append(recipients, SplitRecipients(list)...)
+				continue
+			}
+		}
+		for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+			if part = strings.TrimSpace(part); part != "" {
+				recipients = append(recipients, part)
+			}
+		}
+	}
+	return recipients
+}
+
+// NormalizeRecipients splits and normalizes recipients, dropping duplicates
+// The first value that can't be normalized fails the whole list so nothing is sent
+func NormalizeRecipients(values []string, defaultCountryCode string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, raw := range SplitRecipients(values) {
+		number, err := NormalizePhoneNumber(raw, defaultCountryCode)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[number] {
+			seen[number] = true
+			normalized = append(normalized, number)
+		}
+	}
+	return normalized, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -57,9 +57,7 @@ func (p *PokeAPIConnector) ExecuteWithContext(ctx context.Context, config PokeAP
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := HTTPClients().Client("pokeapi", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -70,25 +68,25 @@ func (p *PokeAPIConnector) ExecuteWithContext(ctx context.Context, config PokeAP
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("PokeAPI request failed: %v", err), start)
+		return NewRequestErrorResult("PokeAPI", err, fmt.Sprintf("PokeAPI request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read PokeAPI response: %v", err), start)
+		return NewInvalidResponseResult("PokeAPI", fmt.Sprintf("Failed to read PokeAPI response: %v", err), start)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("PokeAPI returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("PokeAPI", resp.StatusCode, fmt.Sprintf("PokeAPI returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	// Parse JSON response
 	var pokeData map[string]interface{}
 	if err := json.Unmarshal(body, &pokeData); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse PokeAPI response: %v", err), start)
+		return NewInvalidResponseResult("PokeAPI", fmt.Sprintf("Failed to parse PokeAPI response: %v", err), start)
 	}
 
 	// Extract name for logging
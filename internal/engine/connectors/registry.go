@@ -0,0 +1,147 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// ErrUnknownAction is returned for an action type no connector is registered for
+var ErrUnknownAction = errors.New("unknown action type")
+
+// ConfigField describes one config field a connector reads
+type ConfigField struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// Call is one run of a connector: who it runs for, its config and the trigger payload
+type Call struct {
+	UserID   string
+	TenantID string
+	Config   models.WorkflowConfig // The step's config, with the connector version resolved
+	Options  interface{}           // What the connector's DecodeConfig returned for the raw config
+	Payload  string                // Trigger payload (or the previous step's data) for templates
+}
+
+// Connector is everything the executor and the API need to know about an action type
+type Connector struct {
+	ActionType         string
+	Label              string
+	Credential         string // Service whose credential the connector uses ("" for none)
+	CredentialOptional bool   // The connector runs without the credential (e.g. with a lower quota)
+	Chainable          bool   // Can run as an action_chain step
+	Fields             []ConfigField
+
+	// DecodeConfig parses and checks the raw config; its error rejects the workflow at save
+	// time and fails the step at run time, so the two can't disagree (nil accepts any config)
+	DecodeConfig func(raw []byte) (interface{}, error)
+
+	// Execute runs the action; the executor has already applied its circuit breaker,
+	// rate limits and timeouts
+	Execute func(ctx context.Context, call Call) Result
+}
+
+// ConnectorInfo is a connector as listed by GET /api/connectors
+type ConnectorInfo struct {
+	ActionType         string        `json:"action_type"`
+	Label              string        `json:"label"`
+	Credential         string        `json:"credential,omitempty"`
+	CredentialOptional bool          `json:"credential_optional,omitempty"`
+	Chainable          bool          `json:"chainable"`
+	ConfigFields       []ConfigField `json:"config_fields"`
+}
+
+// Info describes the connector for the API
+func (c Connector) Info() ConnectorInfo {
+	fields := c.Fields
+	if fields == nil {
+		fields = []ConfigField{}
+	}
+	return ConnectorInfo{
+		ActionType:         c.ActionType,
+		Label:              c.Label,
+		Credential:         c.Credential,
+		CredentialOptional: c.CredentialOptional,
+		Chainable:          c.Chainable,
+		ConfigFields:       fields,
+	}
+}
+
+// Registry holds the connectors workflows can use, by action type
+// Adding a connector is one Register call: validation, dispatch and the listing follow it
+type Registry struct {
+	mu         sync.RWMutex
+	connectors map[string]Connector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{connectors: make(map[string]Connector)}
+}
+
+// Register adds a connector; an action type can only be registered once
+// Connectors with a credential also declare it for CredentialRequirementsFor
+func (r *Registry) Register(c Connector) error {
+	if c.ActionType == "" {
+		return errors.New("connector has no action type")
+	}
+	if c.Execute == nil {
+		return fmt.Errorf("connector %q has no Execute func", c.ActionType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.connectors[c.ActionType]; exists {
+		return fmt.Errorf("connector %q is already registered", c.ActionType)
+	}
+	r.connectors[c.ActionType] = c
+
+	if c.Credential != "" {
+		if _, declared := actionCredentials[c.ActionType]; !declared {
+			RegisterCredentialRequirements(c.ActionType, CredentialRequirement{Service: c.Credential, Optional: c.CredentialOptional})
+		}
+	}
+	return nil
+}
+
+// Lookup returns the connector registered for an action type
+func (r *Registry) Lookup(actionType string) (Connector, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.connectors[actionType]
+	return c, ok
+}
+
+// List returns every registered connector, sorted by action type
+func (r *Registry) List() []Connector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]Connector, 0, len(r.connectors))
+	for _, c := range r.connectors {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ActionType < list[j].ActionType })
+	return list
+}
+
+// Decode runs the action type's DecodeConfig on a raw config ("" decodes as "{}")
+// It returns ErrUnknownAction when no connector is registered for the action type
+func (r *Registry) Decode(actionType string, raw []byte) (interface{}, error) {
+	c, ok := r.Lookup(actionType)
+	if !ok {
+		return nil, ErrUnknownAction
+	}
+	if c.DecodeConfig == nil {
+		return nil, nil
+	}
+	if len(raw) == 0 {
+		raw = []byte("{}")
+	}
+	return c.DecodeConfig(raw)
+}
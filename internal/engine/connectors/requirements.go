@@ -0,0 +1,38 @@
+package connectors
+
+// CredentialRequirement describes a credential service an action type needs
+// before it can execute
+type CredentialRequirement struct {
+	Service  string `json:"service"`  // Credential service_name (e.g., 'slack')
+	Label    string `json:"label"`    // Human-readable service name
+	Optional bool   `json:"optional"` // Connector works without it (e.g., higher rate limits only)
+	Hint     string `json:"hint"`     // What the user needs to paste in as the credential
+}
+
+// actionCredentials maps each action type to the credential services it uses
+// Keep in sync with the credential lookups in the executor
+var actionCredentials = map[string][]CredentialRequirement{
+	"slack_message": {{Service: "slack", Label: "Slack", Hint: "Incoming webhook URL (https://hooks.slack.com/services/...) or bot token (xoxb-...)"}},
+	"discord_post":  {{Service: "discord", Label: "Discord", Hint: "Channel webhook URL (https://discord.com/api/webhooks/...)"}},
+	"twilio_sms":    {{Service: "twilio", Label: "Twilio", Hint: `JSON with account_sid, auth_token and from_number`}},
+	"weather_check": {{Service: "openweather", Label: "OpenWeather", Hint: "OpenWeather API key"}},
+	"news_fetch":    {{Service: "newsapi", Label: "News API", Hint: "NewsAPI.org API key"}},
+	"cat_fetch":     {{Service: "catapi", Label: "The Cat API", Optional: true, Hint: "Optional API key for higher rate limits"}},
+	"salesforce":    {{Service: "salesforce", Label: "Salesforce", Hint: `JSON with instance_url and access_token`}},
+	"github":        {{Service: "github", Label: "GitHub", Hint: "Personal access token with access to the repository's issues"}},
+	"jira":          {{Service: "jira", Label: "Jira", Hint: `JSON with base_url and email and api_token (Jira Cloud), or base_url and token (a Data Center personal access token)`}},
+	"email_send":    {{Service: "smtp", Label: "SMTP", Hint: `JSON with host, port, from, username, password and tls_mode (starttls, tls or none)`}},
+	"soap_call":     {{Service: "soap", Label: "SOAP", Optional: true, Hint: `JSON with username, password and password_type (text or digest), for services that need WS-Security`}},
+}
+
+// RegisterCredentialRequirements declares the credentials a connector's action type needs
+// Generated connectors call it from init() so the requirements checklist knows about them
+func RegisterCredentialRequirements(actionType string, requirements ...CredentialRequirement) {
+	actionCredentials[actionType] = requirements
+}
+
+// CredentialRequirementsFor returns the credential services needed by an action type
+// Returns nil for connectors that call public APIs (fakestore_fetch, swapi_fetch, testing)
+func CredentialRequirementsFor(actionType string) []CredentialRequirement {
+	return actionCredentials[actionType]
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -19,6 +20,9 @@ type RESTCountriesConnector struct {
 type RESTCountriesConfig struct {
 	SearchType string `json:"search_type"` // name, capital, currency, language, region, subregion
 	Query      string `json:"query"`       // Search query (e.g., "united", "euro", "asia")
+
+	// Page, limit and fields; the API has no pagination, so pages are sliced client-side
+	ListOptions
 }
 
 // ExecuteWithContext fetches country data from REST Countries API
@@ -42,15 +46,15 @@ func (r *RESTCountriesConnector) ExecuteWithContext(ctx context.Context, config
 		config.SearchType = "all"
 	}
 
+	if err := config.ListOptions.validate(); err != nil {
+		return NewFailureResult("Invalid REST Countries config: "+err.Error(), start)
+	}
+
 	// Build URL
-	var url string
-	if config.SearchType == "all" {
-		url = fmt.Sprintf("%s/all", r.BaseURL)
-	} else if config.Query != "" {
-		url = fmt.Sprintf("%s/%s/%s", r.BaseURL, config.SearchType, config.Query)
-	} else {
+	if config.SearchType != "all" && config.Query == "" {
 		return NewFailureResult("Query is required for search type: "+config.SearchType, start)
 	}
+	url := r.requestURL(config)
 
 	// Create HTTP request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -59,9 +63,7 @@ func (r *RESTCountriesConnector) ExecuteWithContext(ctx context.Context, config
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := HTTPClients().Client("restcountries", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -72,52 +74,73 @@ func (r *RESTCountriesConnector) ExecuteWithContext(ctx context.Context, config
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("REST Countries request failed: %v", err), start)
+		return NewRequestErrorResult("REST Countries", err, fmt.Sprintf("REST Countries request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read REST Countries response: %v", err), start)
+		return NewInvalidResponseResult("REST Countries", fmt.Sprintf("Failed to read REST Countries response: %v", err), start)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("REST Countries returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("REST Countries", resp.StatusCode, fmt.Sprintf("REST Countries returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	// Parse JSON response
 	var countriesData interface{}
 	if err := json.Unmarshal(body, &countriesData); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse REST Countries response: %v", err), start)
+		return NewInvalidResponseResult("REST Countries", fmt.Sprintf("Failed to parse REST Countries response: %v", err), start)
 	}
 
-	// Count results
-	resultCount := 0
-	if countries, ok := countriesData.([]interface{}); ok {
-		resultCount = len(countries)
+	// Normalize to a list
+	var countries []interface{}
+	if list, ok := countriesData.([]interface{}); ok {
+		countries = list
 	} else if countryMap, ok := countriesData.(map[string]interface{}); ok {
 		// Single country result
-		resultCount = 1
-		countriesData = []interface{}{countryMap}
+		countries = []interface{}{countryMap}
 	}
 
-	message := fmt.Sprintf("REST Countries data fetched: %d countries", resultCount)
+	// Select the requested page and fields
+	totalCount := len(countries)
+	countries = config.prune(config.paginate(countries))
+	resultCount := len(countries)
+
+	message := fmt.Sprintf("REST Countries data fetched: showing %d of %d countries", resultCount, totalCount)
 	if config.Query != "" {
-		message = fmt.Sprintf("REST Countries search '%s': %d countries", config.Query, resultCount)
+		message = fmt.Sprintf("REST Countries search '%s': showing %d of %d countries", config.Query, resultCount, totalCount)
 	}
 
 	return NewSuccessResult(message, map[string]interface{}{
 		"search_type":    config.SearchType,
 		"query":          config.Query,
 		"country_count":  resultCount,
-		"countries":      countriesData,
+		"countries":      countries,
+		"total_count":    totalCount,
+		"returned_count": resultCount,
+		"page":           config.Page,
+		"limit":          config.Limit,
 		"url":            url,
 		"api_info":       "REST Countries API - https://restcountries.com/",
 	}, start)
 }
 
+// requestURL builds the REST Countries URL; selected fields are also requested
+// server-side so the API sends less data
+func (r *RESTCountriesConnector) requestURL(config RESTCountriesConfig) string {
+	url := fmt.Sprintf("%s/%s", r.BaseURL, config.SearchType)
+	if config.SearchType != "all" {
+		url = fmt.Sprintf("%s/%s/%s", r.BaseURL, config.SearchType, config.Query)
+	}
+	if len(config.Fields) > 0 {
+		url += "?fields=" + strings.Join(config.Fields, ",")
+	}
+	return url
+}
+
 // SearchByName searches countries by name
 func (r *RESTCountriesConnector) SearchByName(ctx context.Context, name string) Result {
 	return r.ExecuteWithContext(ctx, RESTCountriesConfig{
@@ -157,17 +180,13 @@ func (r *RESTCountriesConnector) DryRunRESTCountries(config RESTCountriesConfig)
 		r.BaseURL = "https://restcountries.com/v3.1"
 	}
 
-	var url string
-	if config.SearchType == "all" {
-		url = fmt.Sprintf("%s/all", r.BaseURL)
-	} else {
-		url = fmt.Sprintf("%s/%s/%s", r.BaseURL, config.SearchType, config.Query)
-	}
-
 	return NewSuccessResult("REST Countries dry run completed", map[string]interface{}{
 		"search_type": config.SearchType,
 		"query":       config.Query,
-		"url":         url,
+		"page":        config.Page,
+		"limit":       config.Limit,
+		"fields":      config.Fields,
+		"url":         r.requestURL(config),
 		"api_info":    "REST Countries - https://restcountries.com/",
 		"note":        "This is a dry run - no actual REST Countries call was made",
 		"example_country": map[string]interface{}{
@@ -10,7 +10,25 @@ type Result struct {
 	Message   string                 `json:"message"`   // Human-readable message
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Duration  string                 `json:"duration,omitempty"`
-	Timestamp string                 `json:"timestamp"` // ISO8601 format
+	Timestamp string                 `json:"timestamp"`      // ISO8601 format
+	Cost      float64                `json:"cost,omitempty"`     // Estimated provider cost of this call
+	DelayMS   int64                  `json:"delay_ms,omitempty"` // Time spent in a simulated (testing) delay
+
+	ErrorCode   string            `json:"error_code,omitempty"`   // Why a failed action failed (see errors.go)
+	ErrorParams map[string]string `json:"error_params,omitempty"` // Values for the translated message
+	Detail      string            `json:"detail,omitempty"`       // Untranslated message, set by the API for administrators
+
+	FallbackLevel int `json:"fallback_level,omitempty"` // Which fallback target produced this result (0 = the primary)
+
+	RetryAfterMS int64 `json:"retry_after_ms,omitempty"` // How long a rate-limited API asked callers to wait
+
+	ConnectorVersion string `json:"connector_version,omitempty"` // Connector implementation version that ran the action
+
+	ElapsedMS         int64  `json:"elapsed_ms,omitempty"`          // Time a chain step took
+	BudgetRemainingMS *int64 `json:"budget_remaining_ms,omitempty"` // Chain budget left after the step (set when the chain has a budget)
+
+	WaitMS   int64    `json:"wait_ms,omitempty"`  // Time a parallel branch queued for a branch slot
+	Branches []Result `json:"branches,omitempty"` // Results of a parallel step's branches, in order
 }
 
 // NewSuccessResult creates a success result
@@ -40,5 +58,6 @@ func NewCancelledResult(message string) Result {
 		Status:    "cancelled",
 		Message:   message,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		ErrorCode: ErrCodeCancelled,
 	}
 }
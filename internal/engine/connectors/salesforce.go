@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -27,6 +28,8 @@ type SalesforceConfig struct {
 	Data         map[string]interface{} `json:"data"`          // Data for create/update operations
 	InstanceURL  string                 `json:"instance_url"`  // Override instance URL
 	AccessToken  string                 `json:"access_token"`  // Override access token
+	FlattenRecords bool                 `json:"flatten_records"` // Strip attributes and promote a single query record to data
+	RecordLimit    int                  `json:"record_limit"`    // Keep at most this many query records (0 = all)
 }
 
 // SalesforceAuthConfig represents OAuth2 authentication config
@@ -88,7 +91,7 @@ func (s *SalesforceConnector) ExecuteWithContext(ctx context.Context, config Sal
 	// Execute operation based on type
 	switch config.Operation {
 	case "query":
-		return s.executeQuery(ctx, instanceURL, accessToken, apiVersion, config.Query, start)
+		return s.executeQuery(ctx, instanceURL, accessToken, apiVersion, config, start)
 	case "create":
 		return s.executeCreate(ctx, instanceURL, accessToken, apiVersion, config.Object, config.Data, start)
 	case "get":
@@ -103,7 +106,8 @@ func (s *SalesforceConnector) ExecuteWithContext(ctx context.Context, config Sal
 }
 
 // executeQuery runs a SOQL query
-func (s *SalesforceConnector) executeQuery(ctx context.Context, instanceURL, accessToken, apiVersion, query string, start time.Time) Result {
+func (s *SalesforceConnector) executeQuery(ctx context.Context, instanceURL, accessToken, apiVersion string, config SalesforceConfig, start time.Time) Result {
+	query := config.Query
 	if query == "" {
 		return NewFailureResult("SOQL query is required", start)
 	}
@@ -119,8 +123,8 @@ func (s *SalesforceConnector) executeQuery(ctx context.Context, instanceURL, acc
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("salesforce", 0)
+	resp, err := doCall(client, req)
 
 	select {
 	case <-ctx.Done():
@@ -135,31 +139,114 @@ func (s *SalesforceConnector) executeQuery(ctx context.Context, instanceURL, acc
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
+		return NewInvalidResponseResult("Salesforce", fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
 	}
 
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Salesforce", resp.StatusCode, fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	var queryResult map[string]interface{}
 	if err := json.Unmarshal(body, &queryResult); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse Salesforce response: %v", err), start)
+		return NewInvalidResponseResult("Salesforce", fmt.Sprintf("Failed to parse Salesforce response: %v", err), start)
 	}
 
-	recordCount := 0
-	if records, ok := queryResult["records"].([]interface{}); ok {
-		recordCount = len(records)
+	records, _ := queryResult["records"].([]interface{})
+	totalSize := len(records)
+	if size, ok := queryResult["totalSize"].(float64); ok {
+		totalSize = int(size)
+	}
+	objectType := soqlObjectType(query)
+	if len(records) > 0 {
+		if recordType := salesforceRecordType(records[0]); recordType != "" {
+			objectType = recordType
+		}
+	}
+
+	// Applied client-side, so it also caps queries without a LIMIT clause
+	if config.RecordLimit > 0 && len(records) > config.RecordLimit {
+		records = records[:config.RecordLimit]
+		queryResult["records"] = records
+	}
+	recordCount := len(records)
+
+	var data interface{} = queryResult
+	if config.FlattenRecords {
+		data = FlattenSalesforceRecords(records)
 	}
 
 	return NewSuccessResult(fmt.Sprintf("Salesforce query returned %d records", recordCount), map[string]interface{}{
 		"operation":    "query",
 		"query":        query,
+		"object_type":  objectType,
 		"record_count": recordCount,
-		"data":         queryResult,
+		"total_size":   totalSize,
+		"data":         data,
 	}, start)
 }
 
+// FlattenSalesforceRecords strips the "attributes" metadata from query records
+// A single record is returned as a flat object so templates can use {{data.Name}}
+// instead of {{data.records.0.Name}}; other counts are returned as a plain list
+func FlattenSalesforceRecords(records []interface{}) interface{} {
+	flattened := make([]interface{}, len(records))
+	for i, record := range records {
+		flattened[i] = stripSalesforceAttributes(record)
+	}
+	if len(flattened) == 1 {
+		return flattened[0]
+	}
+	return flattened
+}
+
+// stripSalesforceAttributes removes attributes recursively, including on
+// relationship fields, and turns nested subquery results into record lists
+func stripSalesforceAttributes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if records, ok := v["records"].([]interface{}); ok {
+			if _, isSubquery := v["totalSize"]; isSubquery {
+				return stripSalesforceAttributes(records)
+			}
+		}
+		clean := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			if key == "attributes" {
+				continue
+			}
+			clean[key] = stripSalesforceAttributes(field)
+		}
+		return clean
+	case []interface{}:
+		clean := make([]interface{}, len(v))
+		for i, item := range v {
+			clean[i] = stripSalesforceAttributes(item)
+		}
+		return clean
+	default:
+		return value
+	}
+}
+
+// salesforceRecordType reads the sObject type from a record's attributes
+func salesforceRecordType(record interface{}) string {
+	fields, _ := record.(map[string]interface{})
+	attributes, _ := fields["attributes"].(map[string]interface{})
+	recordType, _ := attributes["type"].(string)
+	return recordType
+}
+
+// soqlObjectType extracts the object named in a SOQL FROM clause (used for empty results)
+func soqlObjectType(query string) string {
+	words := strings.Fields(query)
+	for i := 0; i < len(words)-1; i++ {
+		if strings.EqualFold(words[i], "FROM") {
+			return strings.TrimRight(words[i+1], ",)")
+		}
+	}
+	return ""
+}
+
 // executeCreate creates a new record
 func (s *SalesforceConnector) executeCreate(ctx context.Context, instanceURL, accessToken, apiVersion, object string, data map[string]interface{}, start time.Time) Result {
 	if object == "" {
@@ -184,8 +271,8 @@ func (s *SalesforceConnector) executeCreate(ctx context.Context, instanceURL, ac
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("salesforce", 0)
+	resp, err := doCall(client, req)
 
 	select {
 	case <-ctx.Done():
@@ -200,16 +287,16 @@ func (s *SalesforceConnector) executeCreate(ctx context.Context, instanceURL, ac
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
+		return NewInvalidResponseResult("Salesforce", fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
 	}
 
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Salesforce", resp.StatusCode, fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	var createResult map[string]interface{}
 	if err := json.Unmarshal(body, &createResult); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse Salesforce response: %v", err), start)
+		return NewInvalidResponseResult("Salesforce", fmt.Sprintf("Failed to parse Salesforce response: %v", err), start)
 	}
 
 	recordID := ""
@@ -218,10 +305,12 @@ func (s *SalesforceConnector) executeCreate(ctx context.Context, instanceURL, ac
 	}
 
 	return NewSuccessResult(fmt.Sprintf("Salesforce %s created successfully: %s", object, recordID), map[string]interface{}{
-		"operation": "create",
-		"object":    object,
-		"record_id": recordID,
-		"data":      createResult,
+		"operation":    "create",
+		"object":       object,
+		"object_type":  object,
+		"record_id":    recordID,
+		"record_count": 1,
+		"data":         createResult,
 	}, start)
 }
 
@@ -241,8 +330,8 @@ func (s *SalesforceConnector) executeGet(ctx context.Context, instanceURL, acces
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("salesforce", 0)
+	resp, err := doCall(client, req)
 
 	select {
 	case <-ctx.Done():
@@ -257,23 +346,25 @@ func (s *SalesforceConnector) executeGet(ctx context.Context, instanceURL, acces
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
+		return NewInvalidResponseResult("Salesforce", fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
 	}
 
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Salesforce", resp.StatusCode, fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	var record map[string]interface{}
 	if err := json.Unmarshal(body, &record); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse Salesforce response: %v", err), start)
+		return NewInvalidResponseResult("Salesforce", fmt.Sprintf("Failed to parse Salesforce response: %v", err), start)
 	}
 
 	return NewSuccessResult(fmt.Sprintf("Salesforce %s retrieved: %s", object, recordID), map[string]interface{}{
-		"operation": "get",
-		"object":    object,
-		"record_id": recordID,
-		"data":      record,
+		"operation":    "get",
+		"object":       object,
+		"object_type":  object,
+		"record_id":    recordID,
+		"record_count": 1,
+		"data":         record,
 	}, start)
 }
 
@@ -301,8 +392,8 @@ func (s *SalesforceConnector) executeUpdate(ctx context.Context, instanceURL, ac
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("salesforce", 0)
+	resp, err := doCall(client, req)
 
 	select {
 	case <-ctx.Done():
@@ -317,17 +408,19 @@ func (s *SalesforceConnector) executeUpdate(ctx context.Context, instanceURL, ac
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
+		return NewInvalidResponseResult("Salesforce", fmt.Sprintf("Failed to read Salesforce response: %v", err), start)
 	}
 
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Salesforce", resp.StatusCode, fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	return NewSuccessResult(fmt.Sprintf("Salesforce %s updated: %s", object, recordID), map[string]interface{}{
-		"operation": "update",
-		"object":    object,
-		"record_id": recordID,
+		"operation":    "update",
+		"object":       object,
+		"object_type":  object,
+		"record_id":    recordID,
+		"record_count": 1,
 	}, start)
 }
 
@@ -346,8 +439,8 @@ func (s *SalesforceConnector) executeDelete(ctx context.Context, instanceURL, ac
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("salesforce", 0)
+	resp, err := doCall(client, req)
 
 	select {
 	case <-ctx.Done():
@@ -362,13 +455,15 @@ func (s *SalesforceConnector) executeDelete(ctx context.Context, instanceURL, ac
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return NewFailureResult(fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("Salesforce", resp.StatusCode, fmt.Sprintf("Salesforce returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	return NewSuccessResult(fmt.Sprintf("Salesforce %s deleted: %s", object, recordID), map[string]interface{}{
-		"operation": "delete",
-		"object":    object,
-		"record_id": recordID,
+		"operation":    "delete",
+		"object":       object,
+		"object_type":  object,
+		"record_id":    recordID,
+		"record_count": 1,
 	}, start)
 }
 
@@ -396,8 +491,8 @@ func (s *SalesforceConnector) Authenticate(ctx context.Context, config Salesforc
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("salesforce", 0)
+	resp, err := doCall(client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -420,6 +515,14 @@ func (s *SalesforceConnector) Authenticate(ctx context.Context, config Salesforc
 	return &tokenResp, nil
 }
 
+// SalesforceSessionExpired reports whether a result failed because the access token
+// expired or was revoked (HTTP 401 with errorCode INVALID_SESSION_ID), so a fresh token
+// from Authenticate can be tried
+func SalesforceSessionExpired(result Result) bool {
+	return result.ErrorCode == ErrCodeAuthFailed &&
+		strings.Contains(result.Message, "INVALID_SESSION_ID")
+}
+
 // DryRunSalesforce simulates a Salesforce call without actually making the request
 func (s *SalesforceConnector) DryRunSalesforce(config SalesforceConfig) Result {
 	start := time.Now()
@@ -435,6 +538,8 @@ func (s *SalesforceConnector) DryRunSalesforce(config SalesforceConfig) Result {
 		"record_id":   config.RecordID,
 		"query":       config.Query,
 		"api_version": apiVersion,
+		"flatten_records": config.FlattenRecords,
+		"record_limit":    config.RecordLimit,
 		"note":        "This is a dry run - no actual Salesforce call was made",
 		"example_operations": map[string]string{
 			"query":  "SELECT Id, Name FROM Account LIMIT 10",
@@ -0,0 +1,152 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+const singleAccountResponse = `{
+	"totalSize": 1,
+	"done": true,
+	"records": [{
+		"attributes": {"type": "Account", "url": "/services/data/v59.0/sobjects/Account/001A"},
+		"Id": "001A",
+		"Name": "Acme Corp",
+		"Owner": {"attributes": {"type": "User", "url": "/services/data/v59.0/sobjects/User/005A"}, "Name": "Ada"},
+		"Contacts": {"totalSize": 1, "done": true, "records": [
+			{"attributes": {"type": "Contact", "url": "/services/data/v59.0/sobjects/Contact/003A"}, "Email": "ops@acme.com"}
+		]}
+	}]
+}`
+
+const multiAccountResponse = `{
+	"totalSize": 3,
+	"done": true,
+	"records": [
+		{"attributes": {"type": "Account", "url": "/a/1"}, "Id": "001A", "Name": "Acme Corp"},
+		{"attributes": {"type": "Account", "url": "/a/2"}, "Id": "001B", "Name": "Globex"},
+		{"attributes": {"type": "Account", "url": "/a/3"}, "Id": "001C", "Name": "Initech"}
+	]
+}`
+
+// querySalesforce runs a query against a fixture and returns the result as the
+// JSON a chained action receives with use_data_from: "previous"
+func querySalesforce(t *testing.T, response string, config connectors.SalesforceConfig) (connectors.Result, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v59.0/query" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected request: %s (auth %q)", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	connector := &connectors.SalesforceConnector{InstanceURL: server.URL, AccessToken: "token"}
+	config.Operation = "query"
+	result := connector.ExecuteWithContext(context.Background(), config)
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %s: %s", result.Status, result.Message)
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatalf("Failed to encode result data: %v", err)
+	}
+	return result, string(data)
+}
+
+// TestSalesforceFlattenSingleRecord promotes a single record to a flat data object
+func TestSalesforceFlattenSingleRecord(t *testing.T) {
+	result, data := querySalesforce(t, singleAccountResponse, connectors.SalesforceConfig{
+		Query:          "SELECT Id, Name, Owner.Name, (SELECT Email FROM Contacts) FROM Account WHERE Id = '001A'",
+		FlattenRecords: true,
+	})
+
+	if result.Data["record_count"] != 1 || result.Data["object_type"] != "Account" {
+		t.Errorf("Unexpected record_count/object_type: %v/%v", result.Data["record_count"], result.Data["object_type"])
+	}
+
+	engine := utils.NewTemplateEngine()
+	for template, want := range map[string]string{
+		"{{data.Name}}":                    "Acme Corp",
+		"{{data.Owner.Name}}":              "Ada",
+		"{{data.Contacts.0.Email}}":        "ops@acme.com",
+		"{{object_type}} {{record_count}}": "Account 1",
+	} {
+		if got := engine.Render(template, data); got != want {
+			t.Errorf("Render(%s) = %q, want %q", template, got, want)
+		}
+	}
+	for _, path := range []string{"data.attributes", "data.Owner.attributes", "data.Contacts.0.attributes", "data.records"} {
+		if utils.ExtractValue(data, path) != "" {
+			t.Errorf("Expected %s to be removed, got %s", path, utils.ExtractValue(data, path))
+		}
+	}
+}
+
+// TestSalesforceFlattenMultipleRecords returns a plain record list, capped by record_limit
+func TestSalesforceFlattenMultipleRecords(t *testing.T) {
+	result, data := querySalesforce(t, multiAccountResponse, connectors.SalesforceConfig{
+		Query:          "SELECT Id, Name FROM Account",
+		FlattenRecords: true,
+		RecordLimit:    2,
+	})
+
+	if result.Data["record_count"] != 2 || result.Data["total_size"] != 3 || result.Data["object_type"] != "Account" {
+		t.Errorf("Unexpected counts: %v", result.Data)
+	}
+
+	engine := utils.NewTemplateEngine()
+	if got := engine.Render("{{data.0.Name}}, {{data.1.Name}} ({{data.#}})", data); got != "Acme Corp, Globex (2)" {
+		t.Errorf("Unexpected render: %q", got)
+	}
+	if utils.ExtractValue(data, "data.0.attributes") != "" {
+		t.Error("Expected attributes to be removed")
+	}
+
+	// A limit of one promotes the remaining record like a single-record query
+	_, data = querySalesforce(t, multiAccountResponse, connectors.SalesforceConfig{
+		Query:          "SELECT Id, Name FROM Account",
+		FlattenRecords: true,
+		RecordLimit:    1,
+	})
+	if got := utils.ExtractValue(data, "data.Name"); got != "Acme Corp" {
+		t.Errorf("Expected the first record promoted, got %q", got)
+	}
+}
+
+// TestSalesforceQueryWithoutFlattening keeps the raw response shape for existing templates
+func TestSalesforceQueryWithoutFlattening(t *testing.T) {
+	result, data := querySalesforce(t, multiAccountResponse, connectors.SalesforceConfig{
+		Query:       "SELECT Id, Name FROM Account",
+		RecordLimit: 1,
+	})
+
+	if result.Data["record_count"] != 1 || result.Data["object_type"] != "Account" {
+		t.Errorf("Unexpected record_count/object_type: %v", result.Data)
+	}
+	if got := utils.ExtractValue(data, "data.records.0.Name"); got != "Acme Corp" {
+		t.Errorf("Expected the raw records path to work, got %q", got)
+	}
+	if got := utils.ExtractValue(data, "data.records.#"); got != "1" {
+		t.Errorf("Expected record_limit to trim the raw records, got %s", got)
+	}
+
+	// Empty results take the object type from the SOQL FROM clause
+	result, data = querySalesforce(t, `{"totalSize": 0, "done": true, "records": []}`, connectors.SalesforceConfig{
+		Query:          "SELECT Id FROM Opportunity WHERE Amount > 1000000",
+		FlattenRecords: true,
+	})
+	if result.Data["record_count"] != 0 || result.Data["object_type"] != "Opportunity" {
+		t.Errorf("Unexpected empty result: %v", result.Data)
+	}
+	if got := utils.ExtractValue(data, "data.#"); got != "0" {
+		t.Errorf("Expected an empty record list, got %s", got)
+	}
+}
@@ -5,18 +5,69 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
-// SlackWebhook handles Slack webhook integrations
+// MaxSlackBlocks is the most blocks Slack accepts in one message
+const MaxSlackBlocks = 50
+
+// slackBotTokenPrefix marks a bot token credential, posted through chat.postMessage
+// instead of an incoming webhook
+const slackBotTokenPrefix = "xoxb-"
+
+// SlackWebhook handles Slack integrations: an incoming webhook, or a bot token when
+// Token is set
 type SlackWebhook struct {
 	WebhookURL string
+	Token      string // Bot token (xoxb-...); posts with chat.postMessage
+	BaseURL    string // Web API base; defaults to https://slack.com/api (overridden in tests)
+}
+
+// NewSlackConnector creates a Slack connector for a stored credential: a bot token
+// or an incoming webhook URL
+func NewSlackConnector(credential string) *SlackWebhook {
+	if IsSlackBotToken(credential) {
+		return &SlackWebhook{Token: credential}
+	}
+	return &SlackWebhook{WebhookURL: credential}
+}
+
+// IsSlackBotToken reports whether a Slack credential is a bot token rather than a webhook URL
+func IsSlackBotToken(credential string) bool {
+	return strings.HasPrefix(credential, slackBotTokenPrefix)
 }
 
 // SlackMessage represents a Slack message payload
 type SlackMessage struct {
-	Text string `json:"text"`
+	Channel  string          `json:"channel,omitempty"` // Bot tokens only; a webhook posts to its own channel
+	Text     string          `json:"text"`              // The notification fallback when Blocks are set
+	Blocks   json.RawMessage `json:"blocks,omitempty"`  // Block Kit array, passed through as is
+	ThreadTS string          `json:"thread_ts,omitempty"`
+}
+
+// ValidateSlackBlocks checks blocks are a JSON array of at most MaxSlackBlocks objects,
+// each with a type
+// Slack would reject anything else, and its answer names no block
+func ValidateSlackBlocks(blocks json.RawMessage) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	var parsed []map[string]interface{}
+	if err := json.Unmarshal(blocks, &parsed); err != nil {
+		return fmt.Errorf("blocks must be a JSON array of objects")
+	}
+	if len(parsed) > MaxSlackBlocks {
+		return fmt.Errorf("Slack accepts at most %d blocks, got %d", MaxSlackBlocks, len(parsed))
+	}
+	for i, block := range parsed {
+		if kind, _ := block["type"].(string); kind == "" {
+			return fmt.Errorf("block %d has no type", i+1)
+		}
+	}
+	return nil
 }
 
 // Execute sends a message to Slack (legacy method - no context)
@@ -27,6 +78,12 @@ func (s *SlackWebhook) Execute(message string) Result {
 // ExecuteWithContext sends a message to Slack with context awareness
 // PRODUCTION: Respects cancellation and timeouts
 func (s *SlackWebhook) ExecuteWithContext(ctx context.Context, message string) Result {
+	return s.Send(ctx, SlackMessage{Text: message})
+}
+
+// Send posts a message, with blocks and in a thread when set
+// Slack's error body is returned verbatim in a failure's message
+func (s *SlackWebhook) Send(ctx context.Context, message SlackMessage) Result {
 	start := time.Now()
 
 	// Check if context is already cancelled
@@ -36,23 +93,34 @@ func (s *SlackWebhook) ExecuteWithContext(ctx context.Context, message string) R
 	default:
 	}
 
-	payload := SlackMessage{Text: message}
-	jsonData, err := json.Marshal(payload)
+	if err := ValidateSlackBlocks(message.Blocks); err != nil {
+		return NewFailureResult(fmt.Sprintf("Invalid Slack blocks: %v", err), start)
+	}
+	if s.Token != "" && message.Channel == "" {
+		return NewFailureResult("Slack bot tokens need a slack_channel to post to", start)
+	}
+
+	jsonData, err := json.Marshal(message)
 	if err != nil {
 		return NewFailureResult(fmt.Sprintf("Failed to marshal Slack payload: %v", err), start)
 	}
 
 	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, "POST", s.WebhookURL, bytes.NewBuffer(jsonData))
+	target := s.WebhookURL
+	if s.Token != "" {
+		target = s.apiURL("chat.postMessage")
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return NewFailureResult(fmt.Sprintf("Failed to create Slack request: %v", err), start)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
 
 	// Execute request with context awareness
-	client := &http.Client{
-		Timeout: 10 * time.Second, // Maximum 10 seconds per request
-	}
+	client := HTTPClients().Client("slack", 0)
 	resp, err := client.Do(req)
 
 	// Check if context was cancelled during request
@@ -63,16 +131,47 @@ func (s *SlackWebhook) ExecuteWithContext(ctx context.Context, message string) R
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Slack webhook request failed: %v", err), start)
+		return NewRequestErrorResult("Slack", err, fmt.Sprintf("Slack webhook request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
 
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Slack returned error status: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("Slack", resp.StatusCode, fmt.Sprintf("Slack returned error status %d: %s", resp.StatusCode, body), start)
 	}
 
-	return NewSuccessResult("Slack message sent successfully", map[string]interface{}{
+	data := map[string]interface{}{
 		"status_code": resp.StatusCode,
-		"message":     message,
-	}, start)
+		"message":     message.Text,
+	}
+	if message.ThreadTS != "" {
+		data["thread_ts"] = message.ThreadTS
+	}
+	if s.Token != "" {
+		// The Web API answers 200 and reports failures in the body
+		var answer struct {
+			OK      bool   `json:"ok"`
+			Channel string `json:"channel"`
+			TS      string `json:"ts"`
+		}
+		if err := json.Unmarshal(body, &answer); err != nil {
+			return NewInvalidResponseResult("Slack", fmt.Sprintf("Failed to parse Slack response: %v", err), start)
+		}
+		if !answer.OK {
+			return NewHTTPErrorResult("Slack", resp.StatusCode, fmt.Sprintf("Slack rejected the message: %s", body), start)
+		}
+		// ts lets a later chain step reply in the thread ("slack_thread_ts": "{{ts}}" with use_data_from "previous")
+		data["channel"], data["ts"] = answer.Channel, answer.TS
+	}
+
+	return NewSuccessResult("Slack message sent successfully", data, start)
+}
+
+// apiURL is a Slack Web API method's URL
+func (s *SlackWebhook) apiURL(method string) string {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = "https://slack.com/api"
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + method
 }
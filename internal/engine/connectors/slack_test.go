@@ -0,0 +1,103 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestSlackSend posts blocks through a webhook and a bot token, and returns Slack's
+// error body as it was sent
+func TestSlackSend(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		switch r.URL.Path {
+		case "/webhook":
+			io.WriteString(w, "ok")
+		case "/webhook/bad":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "invalid_blocks")
+		case "/api/chat.postMessage":
+			if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+				io.WriteString(w, `{"ok": false, "error": "invalid_auth"}`)
+				return
+			}
+			if got["channel"] != "C123" {
+				io.WriteString(w, `{"ok": false, "error": "channel_not_found"}`)
+				return
+			}
+			io.WriteString(w, `{"ok": true, "channel": "C123", "ts": "1700000000.000100"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	blocks := json.RawMessage(`[{"type": "section", "text": {"type": "mrkdwn", "text": "*Deploy* done"}}]`)
+	ctx := context.Background()
+
+	webhook := &connectors.SlackWebhook{WebhookURL: srv.URL + "/webhook"}
+	result := webhook.Send(ctx, connectors.SlackMessage{Text: "Deploy done", Blocks: blocks, ThreadTS: "1.2"})
+	if result.Status != "success" || got["thread_ts"] != "1.2" || len(got["blocks"].([]interface{})) != 1 {
+		t.Errorf("Expected the blocks posted in the thread, got %+v with %v", result, got)
+	}
+	if _, ok := got["channel"]; ok {
+		t.Errorf("Expected no channel sent to a webhook, got %v", got)
+	}
+
+	bad := &connectors.SlackWebhook{WebhookURL: srv.URL + "/webhook/bad"}
+	if result := bad.Send(ctx, connectors.SlackMessage{Blocks: blocks}); result.Status != "failed" ||
+		!strings.Contains(result.Message, "invalid_blocks") || result.ErrorCode != connectors.ErrCodeUpstreamHTTP {
+		t.Errorf("Expected Slack's error body in the failure, got %+v", result)
+	}
+
+	bot := connectors.NewSlackConnector("xoxb-test")
+	bot.BaseURL = srv.URL + "/api"
+	result = bot.Send(ctx, connectors.SlackMessage{Channel: "C123", Text: "Deploy done", Blocks: blocks})
+	if result.Status != "success" || result.Data["ts"] != "1700000000.000100" || result.Data["channel"] != "C123" {
+		t.Errorf("Expected the bot to post and return the message ts, got %+v", result)
+	}
+	result = bot.Send(ctx, connectors.SlackMessage{Channel: "C999", Text: "Deploy done"})
+	if result.Status != "failed" || !strings.Contains(result.Message, `"error": "channel_not_found"`) {
+		t.Errorf("Expected Slack's error body in the failure, got %+v", result)
+	}
+	if result := bot.Send(ctx, connectors.SlackMessage{Text: "Deploy done"}); result.Status != "failed" || !strings.Contains(result.Message, "slack_channel") {
+		t.Errorf("Expected a bot token without a channel refused, got %+v", result)
+	}
+}
+
+// TestValidateSlackBlocks rejects what Slack would, before anything is sent
+func TestValidateSlackBlocks(t *testing.T) {
+	many := make([]string, connectors.MaxSlackBlocks+1)
+	for i := range many {
+		many[i] = `{"type": "divider"}`
+	}
+
+	tests := []struct {
+		name, blocks, wantError string
+	}{
+		{"none", ``, ""},
+		{"fifty blocks", "[" + strings.Join(many[1:], ",") + "]", ""},
+		{"too many blocks", "[" + strings.Join(many, ",") + "]", fmt.Sprintf("at most %d blocks", connectors.MaxSlackBlocks)},
+		{"not an array", `{"type": "section"}`, "JSON array"},
+		{"block without a type", `[{"type": "divider"}, {"text": "hi"}]`, "block 2 has no type"},
+	}
+	for _, tt := range tests {
+		err := connectors.ValidateSlackBlocks(json.RawMessage(tt.blocks))
+		if tt.wantError == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantError != "" && (err == nil || !strings.Contains(err.Error(), tt.wantError)) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.wantError, err)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP TLS modes
+const (
+	SMTPTLSStartTLS = "starttls" // Upgrade a plain connection (default, port 587)
+	SMTPTLSImplicit = "tls"      // TLS from the first byte (port 465)
+	SMTPTLSNone     = "none"     // No encryption; servers other than localhost won't get the password
+)
+
+// maxEmailRecipients bounds the recipients of one email
+const maxEmailRecipients = 50
+
+// SMTPConnector sends email through an SMTP server
+type SMTPConnector struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`     // Default: 587, or 465 with TLS mode "tls"
+	TLSMode  string `json:"tls_mode"` // starttls (default), tls or none
+	Username string `json:"username"` // Optional: no AUTH without it
+	Password string `json:"password"`
+	From     string `json:"from"` // Sender address, e.g. "GoFlow <alerts@example.com>"
+
+	TLSConfig *tls.Config `json:"-"` // Overrides the default TLS settings (tests trust their own certificate)
+}
+
+// SMTPConfig is one email to send
+type SMTPConfig struct {
+	To       []string // Addresses; each entry may hold several, comma-separated
+	Subject  string
+	Body     string // Plain text
+	HTMLBody string // Optional HTML alternative
+}
+
+// ParseSMTPCredential reads the "smtp" credential: JSON with host, port, tls_mode, from,
+// username and password
+func ParseSMTPCredential(raw string) (*SMTPConnector, error) {
+	var s SMTPConnector
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return nil, fmt.Errorf("expected JSON with host, port, tls_mode, from, username and password: %v", err)
+	}
+	if s.Host == "" || s.From == "" {
+		return nil, errors.New("host and from are required")
+	}
+	switch s.TLSMode {
+	case "":
+		s.TLSMode = SMTPTLSStartTLS
+	case SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone:
+	default:
+		return nil, fmt.Errorf("tls_mode must be starttls, tls or none, got %q", s.TLSMode)
+	}
+	if _, err := mail.ParseAddress(s.From); err != nil {
+		return nil, fmt.Errorf("invalid from address: %v", err)
+	}
+	return &s, nil
+}
+
+// ParseEmailRecipients splits and validates recipient addresses, returning the bare addresses
+func ParseEmailRecipients(values []string) ([]string, error) {
+	var recipients []string
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		list, err := mail.ParseAddressList(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %v", value, err)
+		}
+		for _, addr := range list {
+			recipients = append(recipients, addr.Address)
+		}
+	}
+	if len(recipients) > maxEmailRecipients {
+		return nil, fmt.Errorf("at most %d recipients are allowed (got %d)", maxEmailRecipients, len(recipients))
+	}
+	return recipients, nil
+}
+
+// ExecuteWithContext sends one email to every recipient in a single SMTP transaction
+// The result reports the recipient count and the server's reply to the message
+func (s *SMTPConnector) ExecuteWithContext(ctx context.Context, config SMTPConfig) Result {
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return NewCancelledResult("Context cancelled before SMTP request: " + ctx.Err().Error())
+	default:
+	}
+
+	recipients, err := ParseEmailRecipients(config.To)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Email recipient rejected: %v", err), start)
+	}
+	if len(recipients) == 0 || (config.Body == "" && config.HTMLBody == "") {
+		return NewFailureResult("Email requires 'email_to' and 'email_body' or 'email_html_body'", start)
+	}
+	if strings.ContainsAny(config.Subject, "\r\n") {
+		return NewFailureResult("Email subject must be a single line", start)
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Invalid from address: %v", err), start)
+	}
+
+	message, err := buildEmail(from, recipients, config)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to build email: %v", err), start)
+	}
+
+	code, reply, err := s.send(ctx, from.Address, recipients, message)
+	if ctx.Err() != nil {
+		return NewCancelledResult("Context cancelled during SMTP request: " + ctx.Err().Error())
+	}
+	if err != nil {
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) {
+			result := NewFailureResult(fmt.Sprintf("SMTP server rejected the email: %d %s", protoErr.Code, protoErr.Msg), start)
+			result.Data = map[string]interface{}{"response_code": protoErr.Code}
+			return result
+		}
+		return NewRequestErrorResult("SMTP", err, fmt.Sprintf("SMTP request failed: %v", err), start)
+	}
+
+	return NewSuccessResult(fmt.Sprintf("Email sent to %d recipient(s)", len(recipients)), map[string]interface{}{
+		"recipients":    len(recipients),
+		"response_code": code,
+		"response":      reply,
+		"subject":       config.Subject,
+	}, start)
+}
+
+// send runs the SMTP transaction and returns the server's reply to the message data
+// Cancelling ctx closes the connection
+func (s *SMTPConnector) send(ctx context.Context, from string, recipients []string, message []byte) (int, string, error) {
+	port := s.Port
+	if port == 0 {
+		port = 587
+		if s.TLSMode == SMTPTLSImplicit {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	tlsConfig := s.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: s.Host, MinVersion: tls.VersionTLS12}
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if s.TLSMode == SMTPTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return 0, "", err
+	}
+	defer client.Close()
+
+	if s.TLSMode == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return 0, "", errors.New("server does not support STARTTLS (set tls_mode to \"none\" to send unencrypted)")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return 0, "", err
+		}
+	}
+	if s.Username != "" {
+		// PlainAuth refuses to send the password unencrypted to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return 0, "", err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return 0, "", err
+	}
+	for _, to := range recipients {
+		if err := client.Rcpt(to); err != nil {
+			return 0, "", err
+		}
+	}
+
+	// DATA goes through the text connection so the final reply code can be reported
+	id, err := client.Text.Cmd("DATA")
+	if err != nil {
+		return 0, "", err
+	}
+	client.Text.StartResponse(id)
+	_, _, err = client.Text.ReadResponse(354)
+	client.Text.EndResponse(id)
+	if err != nil {
+		return 0, "", err
+	}
+	w := client.Text.DotWriter()
+	if _, err := w.Write(message); err != nil {
+		return 0, "", err
+	}
+	if err := w.Close(); err != nil {
+		return 0, "", err
+	}
+	code, reply, err := client.Text.ReadResponse(250)
+	if err != nil {
+		return 0, "", err
+	}
+	client.Quit()
+	return code, reply, nil
+}
+
+// buildEmail renders the message headers and body, with an HTML alternative when set
+func buildEmail(from *mail.Address, recipients []string, config SMTPConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", config.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+
+	if config.HTMLBody == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, config.Body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", config.Body},
+		{"text/html; charset=utf-8", config.HTMLBody},
+	} {
+		if part.body == "" {
+			continue
+		}
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable encodes a body so long lines and non-ASCII text survive transport
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID returns a unique Message-ID in the sender's domain
+func messageID(from string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	domain := "goflow.local"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
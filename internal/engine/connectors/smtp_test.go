@@ -0,0 +1,193 @@
+package connectors_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// smtpStub is a minimal SMTP server that records what it receives
+// It advertises AUTH PLAIN but not STARTTLS, and rejects recipients containing "reject"
+type smtpStub struct {
+	listener net.Listener
+	mu       sync.Mutex
+	auth     string   // Decoded AUTH PLAIN response
+	rcpts    []string // RCPT TO arguments of the last transaction
+	data     string   // Message data of the last transaction
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stub := &smtpStub{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return stub
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			text.PrintfLine("250-stub\r\n250 AUTH PLAIN")
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			s.mu.Lock()
+			s.auth = string(decoded)
+			s.mu.Unlock()
+			if strings.HasSuffix(string(decoded), "\x00secret") {
+				text.PrintfLine("235 2.7.0 Authentication successful")
+			} else {
+				text.PrintfLine("535 5.7.8 Authentication failed")
+			}
+		case "MAIL":
+			s.mu.Lock()
+			s.rcpts = nil
+			s.mu.Unlock()
+			text.PrintfLine("250 2.1.0 Ok")
+		case "RCPT":
+			if strings.Contains(arg, "reject") {
+				text.PrintfLine("550 5.1.1 Mailbox unavailable")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, arg)
+			s.mu.Unlock()
+			text.PrintfLine("250 2.1.5 Ok")
+		case "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, _ := text.ReadDotBytes()
+			s.mu.Lock()
+			s.data = string(data)
+			s.mu.Unlock()
+			text.PrintfLine("250 2.0.0 Ok: queued as ABC123")
+		case "QUIT":
+			text.PrintfLine("221 2.0.0 Bye")
+			return
+		default:
+			text.PrintfLine("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+// TestSMTPConnector sends plain and HTML emails through a local stub and checks the
+// replies are reported
+func TestSMTPConnector(t *testing.T) {
+	stub := newSMTPStub(t)
+	ctx := context.Background()
+	connector := &connectors.SMTPConnector{
+		Host:     "127.0.0.1",
+		Port:     stub.port(),
+		TLSMode:  connectors.SMTPTLSNone,
+		Username: "alerts",
+		Password: "secret",
+		From:     "GoFlow <alerts@example.com>",
+	}
+
+	result := connector.ExecuteWithContext(ctx, connectors.SMTPConfig{
+		To:      []string{"ada@example.com, Grace <grace@example.com>"},
+		Subject: "Order #42 shipped",
+		Body:    "Your order is on its way.",
+	})
+	if result.Status != "success" || result.Data["recipients"] != 2 || result.Data["response_code"] != 250 {
+		t.Fatalf("Expected the email to be accepted for 2 recipients, got %+v", result)
+	}
+	if !strings.Contains(result.Data["response"].(string), "queued as ABC123") {
+		t.Errorf("Expected the server's reply, got %v", result.Data["response"])
+	}
+	stub.mu.Lock()
+	if stub.auth != "\x00alerts\x00secret" {
+		t.Errorf("Expected AUTH PLAIN with the credential, got %q", stub.auth)
+	}
+	if strings.Join(stub.rcpts, " ") != "TO:<ada@example.com> TO:<grace@example.com>" {
+		t.Errorf("Expected both recipients, got %v", stub.rcpts)
+	}
+	if !strings.Contains(stub.data, "Subject: Order #42 shipped") || !strings.Contains(stub.data, "text/plain") || !strings.Contains(stub.data, "Your order is on its way.") {
+		t.Errorf("Expected the subject and plain text body, got:\n%s", stub.data)
+	}
+	stub.mu.Unlock()
+
+	result = connector.ExecuteWithContext(ctx, connectors.SMTPConfig{
+		To:       []string{"ada@example.com"},
+		Subject:  "Résumé",
+		Body:     "Plain version",
+		HTMLBody: "<p>HTML version</p>",
+	})
+	if result.Status != "success" {
+		t.Fatalf("Expected the HTML email to be accepted, got %+v", result)
+	}
+	stub.mu.Lock()
+	if !strings.Contains(stub.data, "multipart/alternative") || !strings.Contains(stub.data, "<p>HTML version</p>") || !strings.Contains(stub.data, "Subject: =?utf-8?q?R=C3=A9sum=C3=A9?=") {
+		t.Errorf("Expected a multipart message with an encoded subject, got:\n%s", stub.data)
+	}
+	stub.mu.Unlock()
+
+	result = connector.ExecuteWithContext(ctx, connectors.SMTPConfig{To: []string{"reject@example.com"}, Body: "hi"})
+	if result.Status != "failed" || result.Data["response_code"] != 550 {
+		t.Errorf("Expected a rejected recipient to fail with 550, got %+v", result)
+	}
+
+	result = connector.ExecuteWithContext(ctx, connectors.SMTPConfig{To: []string{"ada@example.com"}, Subject: "a\r\nBcc: eve@example.com", Body: "hi"})
+	if result.Status != "failed" || !strings.Contains(result.Message, "single line") {
+		t.Errorf("Expected a header injection to be refused, got %+v", result)
+	}
+
+	wrongPassword := *connector
+	wrongPassword.Password = "guess"
+	if result := wrongPassword.ExecuteWithContext(ctx, connectors.SMTPConfig{To: []string{"ada@example.com"}, Body: "hi"}); result.Status != "failed" || result.Data["response_code"] != 535 {
+		t.Errorf("Expected a failed login to be reported, got %+v", result)
+	}
+
+	startTLS := *connector
+	startTLS.TLSMode = connectors.SMTPTLSStartTLS
+	if result := startTLS.ExecuteWithContext(ctx, connectors.SMTPConfig{To: []string{"ada@example.com"}, Body: "hi"}); result.Status != "failed" || !strings.Contains(result.Message, "STARTTLS") {
+		t.Errorf("Expected STARTTLS to be required, got %+v", result)
+	}
+}
+
+// TestParseSMTPCredential covers the smtp credential format
+func TestParseSMTPCredential(t *testing.T) {
+	smtp, err := connectors.ParseSMTPCredential(`{"host": "smtp.example.com", "from": "alerts@example.com", "username": "u", "password": "p"}`)
+	if err != nil || smtp.TLSMode != connectors.SMTPTLSStartTLS {
+		t.Errorf("Expected STARTTLS by default, got %+v, %v", smtp, err)
+	}
+	for _, raw := range []string{
+		`{"host": "smtp.example.com"}`,
+		`{"host": "smtp.example.com", "from": "not an address"}`,
+		`{"host": "smtp.example.com", "from": "a@example.com", "tls_mode": "ssl3"}`,
+		`smtp.example.com`,
+	} {
+		if _, err := connectors.ParseSMTPCredential(raw); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
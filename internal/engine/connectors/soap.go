@@ -3,18 +3,45 @@ package connectors
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// SOAP versions and their envelope namespaces
+const (
+	SOAP11 = "1.1"
+	SOAP12 = "1.2"
+
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// WS-Security UsernameToken namespaces and password types
+const (
+	wsseNamespace     = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	wsuNamespace      = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	wssPasswordText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	wssPasswordDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	wssBase64Binary   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
 // SOAPConnector handles SOAP to REST conversion
 // Allows modern REST clients to interact with legacy SOAP services
 type SOAPConnector struct {
 	SOAPEndpoint string
-	SOAPAction   string // Optional SOAP action header
+	SOAPAction   string       // Optional SOAP action header
+	TLS          *TLSSettings // Optional private CA / mutual TLS settings
 }
 
 // SOAPConfig represents SOAP connector configuration
@@ -25,6 +52,54 @@ type SOAPConfig struct {
 	Namespace  string                 `json:"namespace"`   // XML namespace
 	Parameters map[string]interface{} `json:"parameters"`  // Method parameters
 	Headers    map[string]string      `json:"headers"`     // Custom HTTP headers
+	Version    string                 `json:"version"`     // SOAP11 (default) or SOAP12
+	Security   *WSSecurity            `json:"-"`           // Optional WS-Security UsernameToken
+}
+
+// WSSecurity is a WS-Security UsernameToken, read from the "soap" credential
+type WSSecurity struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordType string `json:"password_type"` // "text" (default) or "digest"
+}
+
+// ParseSOAPCredential reads the "soap" credential: JSON with username, password and an
+// optional password_type
+func ParseSOAPCredential(raw string) (*WSSecurity, error) {
+	var security WSSecurity
+	if err := json.Unmarshal([]byte(raw), &security); err != nil {
+		return nil, fmt.Errorf("expected JSON with username, password and password_type: %v", err)
+	}
+	if security.Username == "" {
+		return nil, errors.New("username is required")
+	}
+	switch security.PasswordType {
+	case "":
+		security.PasswordType = "text"
+	case "text", "digest":
+	default:
+		return nil, fmt.Errorf("password_type must be text or digest, got %q", security.PasswordType)
+	}
+	return &security, nil
+}
+
+// header renders the wsse:Security header; a digest password is
+// Base64(SHA-1(nonce + created + password)) as the UsernameToken profile defines
+func (ws *WSSecurity) header(now time.Time, nonce []byte) string {
+	var b strings.Builder
+	b.WriteString(`<wsse:Security xmlns:wsse="` + wsseNamespace + `" xmlns:wsu="` + wsuNamespace + `" soap:mustUnderstand="1">`)
+	b.WriteString(`<wsse:UsernameToken><wsse:Username>` + escapeXML(ws.Username) + `</wsse:Username>`)
+	if ws.PasswordType == "digest" {
+		created := now.UTC().Format("2006-01-02T15:04:05.000Z")
+		digest := sha1.Sum(append(append(append([]byte{}, nonce...), created...), ws.Password...))
+		b.WriteString(`<wsse:Password Type="` + wssPasswordDigest + `">` + base64.StdEncoding.EncodeToString(digest[:]) + `</wsse:Password>`)
+		b.WriteString(`<wsse:Nonce EncodingType="` + wssBase64Binary + `">` + base64.StdEncoding.EncodeToString(nonce) + `</wsse:Nonce>`)
+		b.WriteString(`<wsu:Created>` + created + `</wsu:Created>`)
+	} else {
+		b.WriteString(`<wsse:Password Type="` + wssPasswordText + `">` + escapeXML(ws.Password) + `</wsse:Password>`)
+	}
+	b.WriteString(`</wsse:UsernameToken></wsse:Security>`)
+	return b.String()
 }
 
 // SOAPEnvelope represents a standard SOAP 1.1/1.2 envelope
@@ -71,10 +146,18 @@ func (s *SOAPConnector) ExecuteWithContext(ctx context.Context, config SOAPConfi
 		return NewFailureResult(fmt.Sprintf("Failed to create HTTP request: %v", err), start)
 	}
 
-	// Set SOAP headers
-	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
-	if config.Action != "" {
-		req.Header.Set("SOAPAction", config.Action)
+	// Set SOAP headers: 1.2 carries the action in the content type
+	if config.Version == SOAP12 {
+		contentType := "application/soap+xml; charset=utf-8"
+		if config.Action != "" {
+			contentType += fmt.Sprintf(`; action="%s"`, config.Action)
+		}
+		req.Header.Set("Content-Type", contentType)
+	} else {
+		req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+		if config.Action != "" {
+			req.Header.Set("SOAPAction", config.Action)
+		}
 	}
 
 	// Add custom headers
@@ -83,10 +166,11 @@ func (s *SOAPConnector) ExecuteWithContext(ctx context.Context, config SOAPConfi
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second, // SOAP services can be slow
+	client, err := HTTPClients().ClientWithTLS("soap", 0, s.TLS) // SOAP services can be slow
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Invalid TLS settings: %v", err), start)
 	}
-	resp, err := client.Do(req)
+	resp, err := doCall(client, req)
 
 	// Check if context was cancelled during request
 	select {
@@ -96,30 +180,28 @@ func (s *SOAPConnector) ExecuteWithContext(ctx context.Context, config SOAPConfi
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("SOAP request failed: %v", err), start)
+		return NewRequestErrorResult("SOAP", err, fmt.Sprintf("SOAP request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read SOAP response: %v", err), start)
+		return NewInvalidResponseResult("SOAP", fmt.Sprintf("Failed to read SOAP response: %v", err), start)
 	}
 
-	// Check for HTTP errors
+	// A fault fails the call whatever the status (1.1 services send them with 500, some with 200)
+	if fault := parseSOAPFault(body); fault != nil {
+		return NewFailureResult(fmt.Sprintf("SOAP Fault: %s - %s", fault.FaultCode, fault.FaultString), start)
+	}
 	if resp.StatusCode >= 400 {
-		// Try to parse SOAP fault
-		fault := parseSOAPFault(body)
-		if fault != nil {
-			return NewFailureResult(fmt.Sprintf("SOAP Fault: %s - %s", fault.FaultCode, fault.FaultString), start)
-		}
-		return NewFailureResult(fmt.Sprintf("SOAP returned HTTP error: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("SOAP", resp.StatusCode, fmt.Sprintf("SOAP returned HTTP error: %d", resp.StatusCode), start)
 	}
 
 	// Parse SOAP response
 	parsedResponse, err := parseSOAPResponse(body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse SOAP response: %v", err), start)
+		return NewInvalidResponseResult("SOAP", fmt.Sprintf("Failed to parse SOAP response: %v", err), start)
 	}
 
 	return NewSuccessResult("SOAP request completed successfully", map[string]interface{}{
@@ -131,59 +213,259 @@ func (s *SOAPConnector) ExecuteWithContext(ctx context.Context, config SOAPConfi
 
 // buildSOAPRequest creates a SOAP envelope from the config
 func buildSOAPRequest(config SOAPConfig) ([]byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return buildSOAPEnvelope(config, time.Now(), nonce)
+}
+
+// buildSOAPEnvelope renders the envelope for a SOAP version, with the WS-Security header
+// (stamped with now and nonce) when the config has one
+func buildSOAPEnvelope(config SOAPConfig, now time.Time, nonce []byte) ([]byte, error) {
+	namespace := soap11Namespace
+	switch config.Version {
+	case "", SOAP11:
+	case SOAP12:
+		namespace = soap12Namespace
+	default:
+		return nil, fmt.Errorf("version must be %s or %s, got %q", SOAP11, SOAP12, config.Version)
+	}
+	if !isXMLName(config.Method) {
+		return nil, fmt.Errorf("invalid method name %q", config.Method)
+	}
+
 	// Build the method call XML
-	var methodXML string
+	var method bytes.Buffer
 	if config.Namespace != "" {
-		methodXML = fmt.Sprintf(`<%s xmlns="%s">`, config.Method, config.Namespace)
+		fmt.Fprintf(&method, `<%s xmlns="%s">`, config.Method, escapeXML(config.Namespace))
 	} else {
-		methodXML = fmt.Sprintf(`<%s>`, config.Method)
+		fmt.Fprintf(&method, `<%s>`, config.Method)
 	}
 
-	// Add parameters
-	for key, value := range config.Parameters {
-		methodXML += fmt.Sprintf(`<%s>%v</%s>`, key, value, key)
+	// Add parameters; nested maps become nested elements and lists repeat the element
+	for _, key := range sortedKeys(config.Parameters) {
+		if err := writeXMLValue(&method, key, config.Parameters[key]); err != nil {
+			return nil, err
+		}
 	}
 
-	methodXML += fmt.Sprintf(`</%s>`, config.Method)
+	fmt.Fprintf(&method, `</%s>`, config.Method)
+
+	header := ""
+	if config.Security != nil {
+		header = "\n  <soap:Header>" + config.Security.header(now, nonce) + "</soap:Header>"
+	}
 
 	// Build SOAP envelope
 	envelope := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
-<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+<soap:Envelope xmlns:soap="%s">%s
   <soap:Body>
     %s
   </soap:Body>
-</soap:Envelope>`, methodXML)
+</soap:Envelope>`, namespace, header, method.String())
 
 	return []byte(envelope), nil
 }
 
-// parseSOAPResponse extracts data from SOAP response
+// writeXMLValue renders a JSON parameter value as the element name
+// In a map, "@name" keys become attributes and "#text" the element's text, mirroring
+// how responses are converted
+func writeXMLValue(buf *bytes.Buffer, name string, value interface{}) error {
+	if !isXMLName(name) {
+		return fmt.Errorf("invalid parameter name %q", name)
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := writeXMLValue(buf, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		buf.WriteString("<" + name)
+		keys := sortedKeys(v)
+		for _, key := range keys {
+			if strings.HasPrefix(key, "@") {
+				if !isXMLName(key[1:]) {
+					return fmt.Errorf("invalid attribute name %q", key)
+				}
+				buf.WriteString(" " + key[1:] + `="` + escapeXML(xmlText(v[key])) + `"`)
+			}
+		}
+		buf.WriteString(">")
+		if text, ok := v["#text"]; ok {
+			buf.WriteString(escapeXML(xmlText(text)))
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, "@") || key == "#text" {
+				continue
+			}
+			if err := writeXMLValue(buf, key, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</" + name + ">")
+		return nil
+	default:
+		buf.WriteString("<" + name + ">" + escapeXML(xmlText(v)) + "</" + name + ">")
+		return nil
+	}
+}
+
+// xmlText formats a scalar JSON value as element text; numbers never use exponents
+func xmlText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// escapeXML escapes text for element content and attribute values
+func escapeXML(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// isXMLName reports whether name can be used as an element or attribute name
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 0x7f:
+		case i > 0 && (r == '-' || r == '.' || (r >= '0' && r <= '9')):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns a map's keys in order, so the same parameters render the same XML
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseSOAPResponse converts the children of the SOAP body to JSON-style values
+// Elements are keyed by local name (namespace prefixes dropped); repeated elements become
+// arrays, attributes "@name" keys and text beside child elements or attributes "#text".
+// A leaf element is its text
 func parseSOAPResponse(body []byte) (map[string]interface{}, error) {
-	// Parse the SOAP envelope
-	var envelope struct {
-		XMLName xml.Name `xml:"Envelope"`
-		Body    struct {
-			Content string `xml:",innerxml"`
-		} `xml:"Body"`
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	inEnvelope := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, errors.New("no SOAP body in the response")
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case !inEnvelope && start.Name.Local == "Envelope":
+			inEnvelope = true
+		case !inEnvelope:
+			return nil, fmt.Errorf("expected a SOAP envelope, got <%s>", start.Name.Local)
+		case start.Name.Local == "Body":
+			content, err := decodeXMLElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			if parsed, ok := content.(map[string]interface{}); ok {
+				return parsed, nil
+			}
+			return map[string]interface{}{}, nil // Empty body
+		default:
+			if err := decoder.Skip(); err != nil { // The SOAP header
+				return nil, err
+			}
+		}
 	}
+}
 
-	if err := xml.Unmarshal(body, &envelope); err != nil {
-		return nil, err
+// decodeXMLElement converts the element opened by start, consuming it from the decoder
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		fields["@"+attr.Name.Local] = attr.Value
 	}
 
-	// Return the body content as a simple map
-	// In production, you'd want more sophisticated XML to JSON conversion
-	return map[string]interface{}{
-		"body": envelope.Body.Content,
-	}, nil
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := fields[name].(type) {
+			case nil:
+				fields[name] = child
+			case []interface{}:
+				fields[name] = append(existing, child)
+			default:
+				fields[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			trimmed := strings.TrimSpace(text.String())
+			if len(fields) == 0 {
+				return trimmed, nil
+			}
+			if trimmed != "" {
+				fields["#text"] = trimmed
+			}
+			return fields, nil
+		}
+	}
 }
 
-// parseSOAPFault tries to parse a SOAP fault from the response
+// parseSOAPFault tries to parse a SOAP 1.1 or 1.2 fault from the response
 func parseSOAPFault(body []byte) *SOAPFault {
 	var envelope struct {
 		XMLName xml.Name `xml:"Envelope"`
 		Body    struct {
-			Fault SOAPFault `xml:"Fault"`
+			Fault struct {
+				FaultCode   string `xml:"faultcode"`   // SOAP 1.1
+				FaultString string `xml:"faultstring"` // SOAP 1.1
+				Detail      string `xml:"detail"`      // SOAP 1.1
+				Code        struct {
+					Value string `xml:"Value"`
+				} `xml:"Code"` // SOAP 1.2
+				Reason struct {
+					Text string `xml:"Text"`
+				} `xml:"Reason"` // SOAP 1.2
+			} `xml:"Fault"`
 		} `xml:"Body"`
 	}
 
@@ -191,8 +473,12 @@ func parseSOAPFault(body []byte) *SOAPFault {
 		return nil
 	}
 
-	if envelope.Body.Fault.FaultCode != "" {
-		return &envelope.Body.Fault
+	fault := envelope.Body.Fault
+	if fault.FaultCode != "" {
+		return &SOAPFault{FaultCode: fault.FaultCode, FaultString: fault.FaultString, Detail: fault.Detail}
+	}
+	if fault.Code.Value != "" {
+		return &SOAPFault{FaultCode: fault.Code.Value, FaultString: fault.Reason.Text}
 	}
 
 	return nil
@@ -0,0 +1,239 @@
+package connectors_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// Canned envelopes from a calculator-style service (tempuri.org Add/AddResponse)
+const (
+	calculatorAddResponse11 = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <AddResponse xmlns="http://tempuri.org/">
+      <AddResult>5</AddResult>
+    </AddResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+	calculatorHistoryResponse12 = `<?xml version="1.0" encoding="utf-8"?>
+<soap12:Envelope xmlns:soap12="http://www.w3.org/2003/05/soap-envelope" xmlns:t="http://tempuri.org/">
+  <soap12:Header><t:Session>abc</t:Session></soap12:Header>
+  <soap12:Body>
+    <t:HistoryResponse>
+      <t:Calculation id="1" operator="add"><t:A>2</t:A><t:B>3</t:B><t:Result>5</t:Result></t:Calculation>
+      <t:Calculation id="2" operator="divide"><t:A>9</t:A><t:B>3</t:B><t:Result>3</t:Result></t:Calculation>
+      <t:Total unit="operations">2</t:Total>
+    </t:HistoryResponse>
+  </soap12:Body>
+</soap12:Envelope>`
+
+	calculatorFault12 = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+  <soap:Body>
+    <soap:Fault>
+      <soap:Code><soap:Value>soap:Sender</soap:Value></soap:Code>
+      <soap:Reason><soap:Text xml:lang="en">Attempted to divide by zero.</soap:Text></soap:Reason>
+    </soap:Fault>
+  </soap:Body>
+</soap:Envelope>`
+)
+
+// soapRequest is what a fake service received
+type soapRequest struct {
+	header http.Header
+	body   string
+}
+
+// newSOAPService answers every call with response and records the requests
+func newSOAPService(t *testing.T, status int, response string) (*httptest.Server, chan soapRequest) {
+	t.Helper()
+	received := make(chan soapRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- soapRequest{header: r.Header, body: string(body)}
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+// TestSOAPVersions checks each version's envelope namespace and content type
+func TestSOAPVersions(t *testing.T) {
+	for _, c := range []struct {
+		version, namespace, contentType, soapAction string
+	}{
+		{"", "http://schemas.xmlsoap.org/soap/envelope/", "text/xml; charset=utf-8", "http://tempuri.org/Add"},
+		{connectors.SOAP12, "http://www.w3.org/2003/05/soap-envelope", `application/soap+xml; charset=utf-8; action="http://tempuri.org/Add"`, ""},
+	} {
+		srv, received := newSOAPService(t, http.StatusOK, calculatorAddResponse11)
+		result := (&connectors.SOAPConnector{}).ExecuteWithContext(context.Background(), connectors.SOAPConfig{
+			Endpoint:   srv.URL,
+			Action:     "http://tempuri.org/Add",
+			Method:     "Add",
+			Namespace:  "http://tempuri.org/",
+			Parameters: map[string]interface{}{"intA": float64(2), "intB": float64(3)},
+			Version:    c.version,
+		})
+		if result.Status != "success" {
+			t.Fatalf("SOAP %q: expected success, got %s: %s", c.version, result.Status, result.Message)
+		}
+
+		req := <-received
+		if req.header.Get("Content-Type") != c.contentType || req.header.Get("SOAPAction") != c.soapAction {
+			t.Errorf("SOAP %q: unexpected headers %v", c.version, req.header)
+		}
+		if !strings.Contains(req.body, `xmlns:soap="`+c.namespace+`"`) ||
+			!strings.Contains(req.body, `<Add xmlns="http://tempuri.org/"><intA>2</intA><intB>3</intB></Add>`) {
+			t.Errorf("SOAP %q: unexpected envelope %s", c.version, req.body)
+		}
+		if strings.Contains(req.body, "soap:Header") {
+			t.Errorf("SOAP %q: expected no header without WS-Security, got %s", c.version, req.body)
+		}
+		if response := result.Data["response"].(map[string]interface{}); response["AddResponse"].(map[string]interface{})["AddResult"] != "5" {
+			t.Errorf("SOAP %q: expected AddResult 5, got %v", c.version, response)
+		}
+	}
+
+	result := (&connectors.SOAPConnector{}).ExecuteWithContext(context.Background(), connectors.SOAPConfig{
+		Endpoint: "http://127.0.0.1:1", Method: "Add", Version: "2.0",
+	})
+	if result.Status != "failed" || !strings.Contains(result.Message, "version") {
+		t.Errorf("Expected an unknown version refused, got %s: %s", result.Status, result.Message)
+	}
+}
+
+// TestSOAPWSSecurity checks the UsernameToken header for text and digest passwords
+func TestSOAPWSSecurity(t *testing.T) {
+	if _, err := connectors.ParseSOAPCredential(`{"username": "calc", "password": "s3cret", "password_type": "hashed"}`); err == nil {
+		t.Error("Expected an unknown password_type refused")
+	}
+	if _, err := connectors.ParseSOAPCredential(`not json`); err == nil {
+		t.Error("Expected a malformed credential refused")
+	}
+
+	for _, passwordType := range []string{"", "digest"} {
+		security, err := connectors.ParseSOAPCredential(`{"username": "calc", "password": "s3cret & more", "password_type": "` + passwordType + `"}`)
+		if err != nil {
+			t.Fatalf("Failed to parse credential: %v", err)
+		}
+		srv, received := newSOAPService(t, http.StatusOK, calculatorAddResponse11)
+		result := (&connectors.SOAPConnector{}).ExecuteWithContext(context.Background(), connectors.SOAPConfig{
+			Endpoint: srv.URL, Method: "Add", Namespace: "http://tempuri.org/", Security: security,
+		})
+		if result.Status != "success" {
+			t.Fatalf("Expected success, got %s: %s", result.Status, result.Message)
+		}
+
+		var envelope struct {
+			Header struct {
+				Security struct {
+					MustUnderstand string `xml:"mustUnderstand,attr"`
+					Token          struct {
+						Username string `xml:"Username"`
+						Password struct {
+							Type  string `xml:"Type,attr"`
+							Value string `xml:",chardata"`
+						} `xml:"Password"`
+						Nonce   string `xml:"Nonce"`
+						Created string `xml:"Created"`
+					} `xml:"UsernameToken"`
+				} `xml:"Security"`
+			} `xml:"Header"`
+		}
+		req := <-received
+		if err := xml.Unmarshal([]byte(req.body), &envelope); err != nil {
+			t.Fatalf("Sent an invalid envelope: %v\n%s", err, req.body)
+		}
+		token := envelope.Header.Security.Token
+		if token.Username != "calc" || envelope.Header.Security.MustUnderstand != "1" {
+			t.Errorf("Unexpected security header in %s", req.body)
+		}
+
+		if passwordType == "" {
+			if token.Password.Value != "s3cret & more" || !strings.HasSuffix(token.Password.Type, "#PasswordText") || token.Nonce != "" {
+				t.Errorf("Expected a plain text password, got %+v", token)
+			}
+			continue
+		}
+		nonce, _ := base64.StdEncoding.DecodeString(token.Nonce)
+		digest := sha1.Sum([]byte(string(nonce) + token.Created + "s3cret & more"))
+		if len(nonce) == 0 || token.Created == "" || !strings.HasSuffix(token.Password.Type, "#PasswordDigest") ||
+			token.Password.Value != base64.StdEncoding.EncodeToString(digest[:]) {
+			t.Errorf("Expected Base64(SHA-1(nonce + created + password)), got %+v", token)
+		}
+		if strings.Contains(req.body, "s3cret") {
+			t.Error("Expected the digest to keep the password out of the envelope")
+		}
+	}
+}
+
+// TestSOAPXMLConversion renders nested parameters and converts a nested response to JSON
+func TestSOAPXMLConversion(t *testing.T) {
+	srv, received := newSOAPService(t, http.StatusOK, calculatorHistoryResponse12)
+	result := (&connectors.SOAPConnector{}).ExecuteWithContext(context.Background(), connectors.SOAPConfig{
+		Endpoint:  srv.URL,
+		Method:    "History",
+		Namespace: "http://tempuri.org/",
+		Version:   connectors.SOAP12,
+		Parameters: map[string]interface{}{
+			"filter": map[string]interface{}{
+				"@mode":     "recent",
+				"operators": map[string]interface{}{"operator": []interface{}{"add", "divide"}},
+				"since":     "2026-10-01",
+				"limit":     float64(25),
+			},
+			"note": "a < b",
+		},
+	})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %s: %s", result.Status, result.Message)
+	}
+
+	req := <-received
+	expected := `<History xmlns="http://tempuri.org/"><filter mode="recent"><limit>25</limit>` +
+		`<operators><operator>add</operator><operator>divide</operator></operators><since>2026-10-01</since></filter>` +
+		`<note>a &lt; b</note></History>`
+	if !strings.Contains(req.body, expected) {
+		t.Errorf("Expected nested parameters\n%s\nin\n%s", expected, req.body)
+	}
+
+	converted, _ := json.Marshal(result.Data["response"])
+	want := `{"HistoryResponse":{"Calculation":[` +
+		`{"@id":"1","@operator":"add","A":"2","B":"3","Result":"5"},` +
+		`{"@id":"2","@operator":"divide","A":"9","B":"3","Result":"3"}],` +
+		`"Total":{"#text":"2","@unit":"operations"}}}`
+	if string(converted) != want {
+		t.Errorf("Unexpected conversion\n got %s\nwant %s", converted, want)
+	}
+
+	bad := (&connectors.SOAPConnector{}).ExecuteWithContext(context.Background(), connectors.SOAPConfig{
+		Endpoint: srv.URL, Method: "History", Parameters: map[string]interface{}{"not valid": "x"},
+	})
+	if bad.Status != "failed" || !strings.Contains(bad.Message, "invalid parameter name") {
+		t.Errorf("Expected an invalid element name refused, got %s: %s", bad.Status, bad.Message)
+	}
+}
+
+// TestSOAP12Fault surfaces a SOAP 1.2 fault's code and reason
+func TestSOAP12Fault(t *testing.T) {
+	srv, _ := newSOAPService(t, http.StatusInternalServerError, calculatorFault12)
+	result := (&connectors.SOAPConnector{}).ExecuteWithContext(context.Background(), connectors.SOAPConfig{
+		Endpoint: srv.URL, Method: "Divide", Version: connectors.SOAP12,
+		Parameters: map[string]interface{}{"intA": float64(1), "intB": float64(0)},
+	})
+	if result.Status != "failed" || result.Message != "SOAP Fault: soap:Sender - Attempted to divide by zero." {
+		t.Errorf("Expected the fault's code and reason, got %s: %s", result.Status, result.Message)
+	}
+}
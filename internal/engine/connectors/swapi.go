@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -20,6 +22,9 @@ type SWAPIConfig struct {
 	Resource string `json:"resource"` // films, people, planets, species, vehicles, starships
 	ID       string `json:"id"`       // Resource ID (e.g., "1" for first film)
 	Search   string `json:"search"`   // Search query
+
+	// Page, limit and fields for list and search responses
+	ListOptions
 }
 
 // SWAPIResponse represents a single SWAPI resource
@@ -63,19 +68,12 @@ func (s *SWAPIConnector) ExecuteWithContext(ctx context.Context, config SWAPICon
 		)
 	}
 
-	// Build URL
-	var url string
-	if config.ID != "" {
-		// Fetch specific resource by ID
-		url = fmt.Sprintf("%s/%s/%s", s.BaseURL, config.Resource, config.ID)
-	} else if config.Search != "" {
-		// Search resources
-		url = fmt.Sprintf("%s/%s?search=%s", s.BaseURL, config.Resource, config.Search)
-	} else {
-		// List all resources
-		url = fmt.Sprintf("%s/%s", s.BaseURL, config.Resource)
+	if err := config.ListOptions.validate(); err != nil {
+		return NewFailureResult("Invalid SWAPI config: "+err.Error(), start)
 	}
 
+	url := s.requestURL(config)
+
 	// Create HTTP request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -83,10 +81,8 @@ func (s *SWAPIConnector) ExecuteWithContext(ctx context.Context, config SWAPICon
 	}
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("swapi", 0)
+	resp, err := doCall(client, req)
 
 	// Check if context was cancelled during request
 	select {
@@ -96,40 +92,40 @@ func (s *SWAPIConnector) ExecuteWithContext(ctx context.Context, config SWAPICon
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("SWAPI request failed: %v", err), start)
+		return NewRequestErrorResult("SWAPI", err, fmt.Sprintf("SWAPI request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to read SWAPI response: %v", err), start)
+		return NewInvalidResponseResult("SWAPI", fmt.Sprintf("Failed to read SWAPI response: %v", err), start)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("SWAPI returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
+		return NewHTTPErrorResult("SWAPI", resp.StatusCode, fmt.Sprintf("SWAPI returned HTTP error: %d - %s", resp.StatusCode, string(body)), start)
 	}
 
 	// Parse JSON response
 	var swapiData interface{}
 	if err := json.Unmarshal(body, &swapiData); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse SWAPI response: %v", err), start)
+		return NewInvalidResponseResult("SWAPI", fmt.Sprintf("Failed to parse SWAPI response: %v", err), start)
 	}
 
+	// Select the requested page and fields, keeping the response shape
+	swapiData, totalCount, returnedCount := config.selectItems(swapiData)
+
 	// Extract name/title for logging
 	var resourceName string
-	if dataMap, ok := swapiData.(map[string]interface{}); ok {
+	if dataMap, ok := swapiData.(map[string]interface{}); ok && config.ID != "" {
 		if name, exists := dataMap["name"]; exists {
 			resourceName = fmt.Sprintf("%v", name)
 		} else if title, exists := dataMap["title"]; exists {
 			resourceName = fmt.Sprintf("%v", title)
-		} else if results, exists := dataMap["results"]; exists {
-			// It's a list response
-			if resultsList, ok := results.([]interface{}); ok {
-				resourceName = fmt.Sprintf("%d results", len(resultsList))
-			}
 		}
+	} else {
+		resourceName = fmt.Sprintf("showing %d of %d results", returnedCount, totalCount)
 	}
 
 	message := fmt.Sprintf("SWAPI data fetched successfully: %s", resourceName)
@@ -140,16 +136,70 @@ func (s *SWAPIConnector) ExecuteWithContext(ctx context.Context, config SWAPICon
 	}
 
 	return NewSuccessResult(message, map[string]interface{}{
-		"resource":  config.Resource,
-		"id":        config.ID,
-		"search":    config.Search,
-		"data":      swapiData,
-		"url":       url,
-		"api_info":  "Star Wars API - https://swapi.info/",
-		"cache_hit": resp.Header.Get("X-Cache") == "HIT",
+		"resource":       config.Resource,
+		"id":             config.ID,
+		"search":         config.Search,
+		"data":           swapiData,
+		"total_count":    totalCount,
+		"returned_count": returnedCount,
+		"page":           config.Page,
+		"limit":          config.Limit,
+		"url":            url,
+		"api_info":       "Star Wars API - https://swapi.info/",
+		"cache_hit":      resp.Header.Get("X-Cache") == "HIT",
 	}, start)
 }
 
+// requestURL builds the SWAPI URL; list and search requests pass the page on for
+// APIs that paginate server-side (swapi.dev), others ignore it
+func (s *SWAPIConnector) requestURL(config SWAPIConfig) string {
+	if config.ID != "" {
+		return fmt.Sprintf("%s/%s/%s", s.BaseURL, config.Resource, config.ID)
+	}
+
+	query := url.Values{}
+	if config.Search != "" {
+		query.Set("search", config.Search)
+	}
+	if config.Page > 1 {
+		query.Set("page", strconv.Itoa(config.Page))
+	}
+	if len(query) == 0 {
+		return fmt.Sprintf("%s/%s", s.BaseURL, config.Resource)
+	}
+	return fmt.Sprintf("%s/%s?%s", s.BaseURL, config.Resource, query.Encode())
+}
+
+// selectItems applies page/limit/fields to a SWAPI response and counts the items
+// A plain array (swapi.info) is the full collection and is paged client-side; an
+// envelope with "count" and "results" (swapi.dev) is already a page and is only trimmed
+func (config SWAPIConfig) selectItems(data interface{}) (interface{}, int, int) {
+	switch response := data.(type) {
+	case []interface{}:
+		items := config.prune(config.paginate(response))
+		return items, len(response), len(items)
+	case map[string]interface{}:
+		results, ok := response["results"].([]interface{})
+		if !ok {
+			// A single resource
+			return config.pruneItem(response), 1, 1
+		}
+		items := config.prune(config.limit(results))
+		total := len(results)
+		if count, ok := response["count"].(float64); ok {
+			total = int(count)
+		}
+		envelope := make(map[string]interface{}, len(response))
+		for key, value := range response {
+			envelope[key] = value
+		}
+		envelope["results"] = items
+		return envelope, total, len(items)
+	default:
+		return data, 0, 0
+	}
+}
+
 // GetFilm fetches a specific Star Wars film by ID
 func (s *SWAPIConnector) GetFilm(ctx context.Context, filmID string) Result {
 	return s.ExecuteWithContext(ctx, SWAPIConfig{
@@ -190,20 +240,14 @@ func (s *SWAPIConnector) DryRunSWAPI(config SWAPIConfig) Result {
 		s.BaseURL = "https://swapi.info/api"
 	}
 
-	var url string
-	if config.ID != "" {
-		url = fmt.Sprintf("%s/%s/%s", s.BaseURL, config.Resource, config.ID)
-	} else if config.Search != "" {
-		url = fmt.Sprintf("%s/%s?search=%s", s.BaseURL, config.Resource, config.Search)
-	} else {
-		url = fmt.Sprintf("%s/%s", s.BaseURL, config.Resource)
-	}
-
 	return NewSuccessResult("SWAPI dry run completed", map[string]interface{}{
 		"resource": config.Resource,
 		"id":       config.ID,
 		"search":   config.Search,
-		"url":      url,
+		"page":     config.Page,
+		"limit":    config.Limit,
+		"fields":   config.Fields,
+		"url":      s.requestURL(config),
 		"api_info": "Star Wars API - https://swapi.info/",
 		"note":     "This is a dry run - no actual SWAPI call was made",
 		"example_data": map[string]string{
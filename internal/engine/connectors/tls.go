@@ -0,0 +1,87 @@
+package connectors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSCredentialPrefix marks credentials that hold TLS settings instead of an API key
+// e.g. service_name "tls:partner-erp", referenced from config as "tls_credential": "tls:partner-erp"
+const TLSCredentialPrefix = "tls:"
+
+// TLSSettings are per-credential transport settings for private CAs and mutual TLS
+// Stored as the JSON body of an encrypted credential, so the client key is never in plaintext at rest
+type TLSSettings struct {
+	CABundle           string `json:"ca_bundle,omitempty"`            // PEM CA certificates trusted in addition to the system roots
+	ClientCert         string `json:"client_cert,omitempty"`          // PEM client certificate (mutual TLS)
+	ClientKey          string `json:"client_key,omitempty"`           // PEM private key for ClientCert
+	MinVersion         string `json:"min_version,omitempty"`          // "1.2" (default) or "1.3"
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // Development only: refused when ENVIRONMENT=production
+}
+
+// ErrInsecureTLSInProduction is returned when insecure_skip_verify is used in production
+var ErrInsecureTLSInProduction = errors.New("insecure_skip_verify is not allowed when ENVIRONMENT=production")
+
+// ParseTLSSettings decodes and validates TLS settings from a credential value
+func ParseTLSSettings(raw string) (*TLSSettings, error) {
+	var settings TLSSettings
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		return nil, fmt.Errorf("invalid TLS settings JSON: %w", err)
+	}
+	if _, err := settings.Config(); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Config builds a tls.Config from the settings
+func (s *TLSSettings) Config() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s == nil {
+		return config, nil
+	}
+
+	switch s.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported min_version %q (use 1.2 or 1.3)", s.MinVersion)
+	}
+
+	if s.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(s.CABundle)) {
+			return nil, errors.New("ca_bundle contains no valid PEM certificates")
+		}
+		config.RootCAs = pool
+	}
+
+	if s.ClientCert != "" || s.ClientKey != "" {
+		if s.ClientCert == "" || s.ClientKey == "" {
+			return nil, errors.New("client_cert and client_key must be provided together")
+		}
+		cert, err := tls.X509KeyPair([]byte(s.ClientCert), []byte(s.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if s.InsecureSkipVerify {
+		if strings.EqualFold(os.Getenv("ENVIRONMENT"), "production") {
+			return nil, ErrInsecureTLSInProduction
+		}
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
@@ -0,0 +1,191 @@
+package connectors_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+const soapOK = `<?xml version="1.0"?><soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><PingResponse><Result>pong</Result></PingResponse></soap:Body></soap:Envelope>`
+
+// testCA is a throwaway certificate authority for issuing server and client certs
+type testCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Private CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// issue signs a leaf certificate and returns it as PEM cert and key
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// newPrivateServer starts a SOAP stub with a certificate from the private CA
+func newPrivateServer(t *testing.T, ca *testCA, requireClientCert bool) *httptest.Server {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	serverCert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(soapOK))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	if requireClientCert {
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.cert)
+		server.TLS.ClientCAs = pool
+		server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func callSOAP(server *httptest.Server, settings *connectors.TLSSettings) connectors.Result {
+	soap := &connectors.SOAPConnector{TLS: settings}
+	return soap.ExecuteWithContext(context.Background(), connectors.SOAPConfig{
+		Endpoint:  server.URL,
+		Method:    "Ping",
+		Namespace: "http://example.com/ping",
+	})
+}
+
+// TestSOAPPrivateCA verifies a CA bundle lets connectors trust a private CA
+func TestSOAPPrivateCA(t *testing.T) {
+	ca := newTestCA(t)
+	server := newPrivateServer(t, ca, false)
+
+	if result := callSOAP(server, nil); result.Status != "failed" {
+		t.Fatalf("Expected default transport to reject the private CA, got %s", result.Status)
+	}
+
+	result := callSOAP(server, &connectors.TLSSettings{CABundle: ca.certPEM})
+	if result.Status != "success" {
+		t.Fatalf("Expected success with CA bundle, got %s: %s", result.Status, result.Message)
+	}
+}
+
+// TestSOAPMutualTLS verifies the client certificate is presented when the server requires one
+func TestSOAPMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	server := newPrivateServer(t, ca, true)
+
+	if result := callSOAP(server, &connectors.TLSSettings{CABundle: ca.certPEM}); result.Status != "failed" {
+		t.Fatalf("Expected handshake failure without a client certificate, got %s", result.Status)
+	}
+
+	clientCert, clientKey := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+	result := callSOAP(server, &connectors.TLSSettings{
+		CABundle:   ca.certPEM,
+		ClientCert: clientCert,
+		ClientKey:  clientKey,
+		MinVersion: "1.3",
+	})
+	if result.Status != "success" {
+		t.Fatalf("Expected success with client certificate, got %s: %s", result.Status, result.Message)
+	}
+}
+
+// TestInsecureSkipVerifyRefusedInProduction verifies the escape hatch only works outside production
+func TestInsecureSkipVerifyRefusedInProduction(t *testing.T) {
+	ca := newTestCA(t)
+	server := newPrivateServer(t, ca, false)
+	settings := &connectors.TLSSettings{InsecureSkipVerify: true}
+
+	t.Setenv("ENVIRONMENT", "development")
+	if result := callSOAP(server, settings); result.Status != "success" {
+		t.Fatalf("Expected insecure_skip_verify to work in development, got %s: %s", result.Status, result.Message)
+	}
+
+	t.Setenv("ENVIRONMENT", "production")
+	if _, err := connectors.ParseTLSSettings(`{"insecure_skip_verify": true}`); err != connectors.ErrInsecureTLSInProduction {
+		t.Errorf("Expected ErrInsecureTLSInProduction, got %v", err)
+	}
+	if result := callSOAP(server, settings); result.Status != "failed" {
+		t.Errorf("Expected insecure_skip_verify to be refused in production, got %s", result.Status)
+	}
+}
+
+// TestParseTLSSettingsValidation rejects malformed settings before they are saved
+func TestParseTLSSettingsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"bad json", `{`},
+		{"bad ca", `{"ca_bundle": "not a pem"}`},
+		{"cert without key", `{"client_cert": "-----BEGIN CERTIFICATE-----"}`},
+		{"bad version", `{"min_version": "1.0"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := connectors.ParseTLSSettings(tt.raw); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
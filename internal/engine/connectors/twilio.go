@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
+	"unicode/utf16"
 )
 
 // TwilioSMS handles Twilio SMS integrations
@@ -15,15 +17,101 @@ type TwilioSMS struct {
 	AccountSID string
 	AuthToken  string
 	FromNumber string
+	BaseURL    string // Defaults to https://api.twilio.com (overridden in tests)
 }
 
+// Twilio channels: plain SMS, or WhatsApp through Twilio's WhatsApp sender
+const (
+	TwilioChannelSMS      = "sms"
+	TwilioChannelWhatsApp = "whatsapp"
+)
+
+// whatsAppPrefix marks a WhatsApp address in Twilio's To and From fields
+const whatsAppPrefix = "whatsapp:"
+
 // TwilioConfig represents Twilio configuration
 type TwilioConfig struct {
-	To      string `json:"to"`       // Recipient phone number (e.g., "+15551234567")
-	Message string `json:"message"`  // SMS message body
+	To                 string   `json:"to"`                             // Recipient phone number(s) (e.g., "+15551234567" or "+15551234567, 07700 900123")
+	Recipients         []string `json:"recipients,omitempty"`           // Several recipients; To is ignored when set
+	Message            string   `json:"message"`                        // SMS message body
+	DefaultCountryCode string   `json:"default_country_code,omitempty"` // Applied to national numbers (e.g., "44")
+	Channel            string   `json:"channel,omitempty"`              // "sms" (default) or "whatsapp"; a "whatsapp:" recipient selects WhatsApp too
+	StatusCallback     string   `json:"status_callback,omitempty"`      // URL Twilio posts delivery status updates to
+}
+
+// PrepareTwilioRecipients resolves the channel and normalizes the recipients to E.164
+// A "whatsapp:" prefix on any recipient selects WhatsApp; mixing it with channel "sms"
+// is an error, as is a short code on WhatsApp
+func PrepareTwilioRecipients(config TwilioConfig) (channel string, recipients []string, err error) {
+	channel = config.Channel
+	switch channel {
+	case "", TwilioChannelSMS, TwilioChannelWhatsApp:
+	default:
+		return "", nil, fmt.Errorf("unknown channel %q (use %s or %s)", channel, TwilioChannelSMS, TwilioChannelWhatsApp)
+	}
+
+	values := config.Recipients
+	if len(values) == 0 {
+		values = []string{config.To}
+	}
+	var stripped []string
+	for _, value := range SplitRecipients(values) {
+		if number, ok := trimWhatsAppPrefix(value); ok {
+			if channel == TwilioChannelSMS {
+				return "", nil, fmt.Errorf("recipient %q is a WhatsApp address but the channel is %s", value, TwilioChannelSMS)
+			}
+			channel = TwilioChannelWhatsApp
+			value = number
+		}
+		stripped = append(stripped, value)
+	}
+	if channel == "" {
+		channel = TwilioChannelSMS
+	}
+
+	recipients, err = NormalizeRecipients(stripped, config.DefaultCountryCode)
+	if err != nil {
+		return "", nil, err
+	}
+	if channel == TwilioChannelWhatsApp {
+		for _, number := range recipients {
+			if !strings.HasPrefix(number, "+") {
+				return "", nil, fmt.Errorf("invalid phone number %q: WhatsApp needs a full E.164 number, not a short code", number)
+			}
+		}
+	}
+	return channel, recipients, nil
 }
 
-// ExecuteWithContext sends an SMS via Twilio
+// trimWhatsAppPrefix strips a case-insensitive "whatsapp:" prefix
+func trimWhatsAppPrefix(value string) (string, bool) {
+	if len(value) < len(whatsAppPrefix) || !strings.EqualFold(value[:len(whatsAppPrefix)], whatsAppPrefix) {
+		return value, false
+	}
+	return strings.TrimSpace(value[len(whatsAppPrefix):]), true
+}
+
+// sender normalizes the credential's from_number for a channel
+func (t *TwilioSMS) sender(channel string) (string, error) {
+	from, _ := trimWhatsAppPrefix(strings.TrimSpace(t.FromNumber))
+	if from == "" {
+		return "", fmt.Errorf("the credential has no from_number")
+	}
+	number, err := NormalizePhoneNumber(from, "")
+	if err != nil {
+		return "", err
+	}
+	if channel == TwilioChannelWhatsApp {
+		if !strings.HasPrefix(number, "+") {
+			return "", fmt.Errorf("invalid phone number %q: WhatsApp senders need a full E.164 number", from)
+		}
+		return whatsAppPrefix + number, nil
+	}
+	return number, nil
+}
+
+// ExecuteWithContext sends an SMS or WhatsApp message via Twilio
+// Recipients and the sender are checked as E.164 first; nothing is sent if any is invalid
 func (t *TwilioSMS) ExecuteWithContext(ctx context.Context, config TwilioConfig) Result {
 	start := time.Now()
 
@@ -34,19 +122,103 @@ func (t *TwilioSMS) ExecuteWithContext(ctx context.Context, config TwilioConfig)
 	default:
 	}
 
-	// Validate phone number format
-	if config.To == "" || config.Message == "" {
+	channel, recipients, err := PrepareTwilioRecipients(config)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Twilio recipient rejected: %v", err), start)
+	}
+	if len(recipients) == 0 || config.Message == "" {
 		return NewFailureResult("Twilio requires 'to' and 'message' fields", start)
 	}
+	from, err := t.sender(channel)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Twilio from_number rejected: %v", err), start)
+	}
+	message := twilioMessage{from: from, channel: channel, body: config.Message, statusCallback: config.StatusCallback}
 
+	if len(recipients) == 1 {
+		return t.send(ctx, recipients[0], message, start)
+	}
+
+	// Send to each recipient and aggregate the per-recipient results
+	segments, encoding := SMSSegments(config.Message)
+	results := make([]map[string]interface{}, 0, len(recipients))
+	sent := 0
+	for _, to := range recipients {
+		result := t.send(ctx, to, message, time.Now())
+		if result.Status == "cancelled" {
+			return result
+		}
+		entry := map[string]interface{}{
+			"to":       to,
+			"status":   result.Status,
+			"message":  result.Message,
+			"segments": segments,
+		}
+		if result.Status == "success" {
+			sent++
+			entry["sid"] = result.Data["sid"]
+			if price, ok := result.Data["price"]; ok {
+				entry["price"], entry["price_unit"] = price, result.Data["price_unit"]
+			}
+		}
+		results = append(results, entry)
+	}
+
+	data := map[string]interface{}{
+		"recipients": results,
+		"sent":       sent,
+		"failed":     len(recipients) - sent,
+		"message":    config.Message,
+		"channel":    channel,
+		"segments":   segments,
+		"encoding":   encoding,
+	}
+	summary := fmt.Sprintf("%s sent to %d/%d recipients via Twilio", channelLabel(channel), sent, len(recipients))
+	if sent < len(recipients) {
+		result := NewFailureResult(summary, start)
+		result.Data = data
+		return result
+	}
+	return NewSuccessResult(summary, data, start)
+}
+
+// twilioMessage is what is sent to each recipient
+type twilioMessage struct {
+	from           string // Normalized sender, "whatsapp:"-prefixed on WhatsApp
+	channel        string
+	body           string
+	statusCallback string
+}
+
+// channelLabel names a channel in result messages
+func channelLabel(channel string) string {
+	if channel == TwilioChannelWhatsApp {
+		return "WhatsApp message"
+	}
+	return "SMS"
+}
+
+// send delivers one message to an already-normalized number
+func (t *TwilioSMS) send(ctx context.Context, to string, message twilioMessage, start time.Time) Result {
 	// Prepare Twilio API request
-	apiURL := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", t.AccountSID)
+	baseURL := t.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.twilio.com"
+	}
+	apiURL := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(baseURL, "/"), t.AccountSID)
 
 	// Create form data
 	formData := url.Values{}
-	formData.Set("To", config.To)
-	formData.Set("From", t.FromNumber)
-	formData.Set("Body", config.Message)
+	if message.channel == TwilioChannelWhatsApp {
+		formData.Set("To", whatsAppPrefix+to)
+	} else {
+		formData.Set("To", to)
+	}
+	formData.Set("From", message.from)
+	formData.Set("Body", message.body)
+	if message.statusCallback != "" {
+		formData.Set("StatusCallback", message.statusCallback)
+	}
 
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBufferString(formData.Encode()))
@@ -58,10 +230,8 @@ func (t *TwilioSMS) ExecuteWithContext(ctx context.Context, config TwilioConfig)
 	req.SetBasicAuth(t.AccountSID, t.AuthToken)
 
 	// Execute request with timeout
-	client := &http.Client{
-		Timeout: 15 * time.Second, // Twilio can be slow
-	}
-	resp, err := client.Do(req)
+	client := HTTPClients().Client("twilio", 0)
+	resp, err := doCall(client, req)
 
 	// Check if context was cancelled during request
 	select {
@@ -71,26 +241,84 @@ func (t *TwilioSMS) ExecuteWithContext(ctx context.Context, config TwilioConfig)
 	}
 
 	if err != nil {
-		return NewFailureResult(fmt.Sprintf("Twilio API request failed: %v", err), start)
+		return NewRequestErrorResult("Twilio", err, fmt.Sprintf("Twilio API request failed: %v", err), start)
 	}
 	defer resp.Body.Close()
 
 	// Check response status
 	if resp.StatusCode >= 400 {
-		return NewFailureResult(fmt.Sprintf("Twilio returned error status: %d", resp.StatusCode), start)
+		return NewHTTPErrorResult("Twilio", resp.StatusCode, fmt.Sprintf("Twilio returned error status: %d", resp.StatusCode), start)
 	}
 
 	// Parse response
 	var twilioResp map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&twilioResp); err != nil {
-		return NewFailureResult(fmt.Sprintf("Failed to parse Twilio response: %v", err), start)
+		return NewInvalidResponseResult("Twilio", fmt.Sprintf("Failed to parse Twilio response: %v", err), start)
 	}
 
-	return NewSuccessResult("SMS sent successfully via Twilio", map[string]interface{}{
+	data := map[string]interface{}{
 		"status_code": resp.StatusCode,
-		"to":          config.To,
+		"to":          to,
+		"message":     message.body,
+		"channel":     message.channel,
 		"sid":         twilioResp["sid"],
 		"status":      twilioResp["status"],
-	}, start)
+	}
+	// Twilio only knows the price once the message is sent; it is null until then
+	if price, ok := twilioResp["price"].(string); ok && price != "" {
+		data["price"] = price
+		data["price_unit"] = twilioResp["price_unit"]
+	}
+	if message.channel == TwilioChannelSMS {
+		data["segments"], data["encoding"] = SMSSegments(message.body)
+	}
+
+	return NewSuccessResult(channelLabel(message.channel)+" sent successfully via Twilio", data, start)
 }
 
+// gsm7Basic is the GSM 03.38 default alphabet (one septet per character)
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞ\x1bÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extended characters need an escape septet, so they count as two
+const gsm7Extended = "^{}\\[~]|€\f"
+
+// SMSSegments calculates how many billable segments a message splits into
+// GSM-7 messages fit 160 septets (153 per part when concatenated); anything
+// outside the GSM alphabet forces UCS-2 with 70 code units (67 per part)
+func SMSSegments(message string) (segments int, encoding string) {
+	if message == "" {
+		return 0, "GSM-7"
+	}
+
+	septets := 0
+	isGSM := true
+	for _, r := range message {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extended, r):
+			septets += 2
+		default:
+			isGSM = false
+		}
+		if !isGSM {
+			break
+		}
+	}
+
+	if isGSM {
+		return segmentCount(septets, 160, 153), "GSM-7"
+	}
+
+	codeUnits := len(utf16.Encode([]rune(message)))
+	return segmentCount(codeUnits, 70, 67), "UCS-2"
+}
+
+// segmentCount splits units into single or concatenated SMS parts
+func segmentCount(units, single, perPart int) int {
+	if units <= single {
+		return 1
+	}
+	return (units + perPart - 1) / perPart
+}
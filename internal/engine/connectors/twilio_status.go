@@ -0,0 +1,81 @@
+package connectors
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"sort"
+)
+
+// TwilioStatus is a delivery status update Twilio posts to a message's StatusCallback
+type TwilioStatus struct {
+	MessageSID   string
+	Status       string // queued, sent, delivered, read, undelivered, failed, ...
+	To           string
+	ErrorCode    string // Twilio's numeric error code, e.g. "30003" (unreachable handset)
+	ErrorMessage string
+}
+
+// ParseTwilioStatus reads a status callback's form fields
+// Older accounts send SmsSid/SmsStatus alongside (or instead of) MessageSid/MessageStatus
+func ParseTwilioStatus(form url.Values) TwilioStatus {
+	status := TwilioStatus{
+		MessageSID:   form.Get("MessageSid"),
+		Status:       form.Get("MessageStatus"),
+		To:           form.Get("To"),
+		ErrorCode:    form.Get("ErrorCode"),
+		ErrorMessage: form.Get("ErrorMessage"),
+	}
+	if status.MessageSID == "" {
+		status.MessageSID = form.Get("SmsSid")
+	}
+	if status.Status == "" {
+		status.Status = form.Get("SmsStatus")
+	}
+	return status
+}
+
+// Final reports whether no further update will follow for the message
+func (s TwilioStatus) Final() bool {
+	switch s.Status {
+	case "delivered", "read", "undelivered", "failed", "canceled":
+		return true
+	}
+	return false
+}
+
+// Delivered reports whether the message reached the handset
+func (s TwilioStatus) Delivered() bool {
+	return s.Status == "delivered" || s.Status == "read"
+}
+
+// TwilioSignature computes the X-Twilio-Signature of a POST to callbackURL:
+// Base64(HMAC-SHA1(authToken, callbackURL followed by each form field's name and value,
+// sorted by name))
+func TwilioSignature(authToken, callbackURL string, form url.Values) string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(callbackURL))
+	for _, name := range names {
+		values := append([]string(nil), form[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			mac.Write([]byte(name + value))
+		}
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidTwilioSignature checks a callback's X-Twilio-Signature in constant time
+func ValidTwilioSignature(authToken, callbackURL string, form url.Values, signature string) bool {
+	if authToken == "" || signature == "" {
+		return false
+	}
+	return hmac.Equal([]byte(TwilioSignature(authToken, callbackURL, form)), []byte(signature))
+}
@@ -0,0 +1,247 @@
+package connectors_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestSMSSegments verifies GSM-7 vs UCS-2 segment boundaries
+func TestSMSSegments(t *testing.T) {
+	tests := []struct {
+		name             string
+		message          string
+		expectedSegments int
+		expectedEncoding string
+	}{
+		{"short GSM", "Order 42 shipped!", 1, "GSM-7"},
+		{"GSM single limit", strings.Repeat("a", 160), 1, "GSM-7"},
+		{"GSM concatenated", strings.Repeat("a", 161), 2, "GSM-7"},
+		{"GSM two full parts", strings.Repeat("a", 306), 2, "GSM-7"},
+		{"GSM three parts", strings.Repeat("a", 307), 3, "GSM-7"},
+		{"GSM extended chars count double", strings.Repeat("€", 80), 1, "GSM-7"},
+		{"GSM extended overflow", strings.Repeat("€", 81), 2, "GSM-7"},
+		{"GSM accented letters", "Café à Zürich", 1, "GSM-7"},
+		{"non-GSM accent forces UCS-2", "naïve", 1, "UCS-2"},
+		{"UCS-2 single limit", strings.Repeat("ж", 70), 1, "UCS-2"},
+		{"UCS-2 concatenated", strings.Repeat("ж", 71), 2, "UCS-2"},
+		{"UCS-2 from one emoji", strings.Repeat("a", 100) + "🚀", 2, "UCS-2"},
+		{"emoji uses surrogate pairs", strings.Repeat("🚀", 35), 1, "UCS-2"},
+		{"emoji surrogate overflow", strings.Repeat("🚀", 36), 2, "UCS-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, encoding := connectors.SMSSegments(tt.message)
+			if segments != tt.expectedSegments || encoding != tt.expectedEncoding {
+				t.Errorf("SMSSegments() = %d %s, want %d %s", segments, encoding, tt.expectedSegments, tt.expectedEncoding)
+			}
+		})
+	}
+}
+
+// TestNormalizePhoneNumber verifies E.164 normalization of international and national formats
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		countryCode string
+		expected    string
+		expectError bool
+	}{
+		{"already E.164", "+15551234567", "", "+15551234567", false},
+		{"E.164 with formatting", "+1 (555) 123-4567", "", "+15551234567", false},
+		{"UK international", "+44 7700 900123", "1", "+447700900123", false},
+		{"00 international prefix", "0044 7700 900123", "", "+447700900123", false},
+		{"011 prefix from North America", "011 49 30 1234567", "1", "+49301234567", false},
+		{"US national", "(555) 123-4567", "1", "+15551234567", false},
+		{"US national with leading 1", "1-555-123-4567", "+1", "+15551234567", false},
+		{"UK national drops trunk 0", "07700 900123", "44", "+447700900123", false},
+		{"German national", "030 1234567", "49", "+49301234567", false},
+		{"dotted Australian mobile", "0412.345.678", "61", "+61412345678", false},
+		{"short code passes through", "72345", "1", "72345", false},
+		{"short code without country code", "898211", "", "898211", false},
+		{"national without country code", "07700 900123", "", "", true},
+		{"US number with too few digits", "555-1234", "1", "", true},
+		{"vanity letters", "+1-555-WEATHER", "", "", true},
+		{"too long", "+1234567890123456", "", "", true},
+		{"too short", "+12345", "", "", true},
+		{"country code starting with 0", "+0447700900123", "", "", true},
+		{"invalid default country code", "07700 900123", "abc", "", true},
+		{"empty", "   ", "1", "", true},
+		{"only formatting", "+( )", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := connectors.NormalizePhoneNumber(tt.raw, tt.countryCode)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error for %q, got %q", tt.raw, got)
+				}
+				if !strings.Contains(err.Error(), tt.raw) && !strings.Contains(err.Error(), tt.countryCode) {
+					t.Errorf("Error should name the offending value: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("NormalizePhoneNumber(%q, %q) = %q, want %q", tt.raw, tt.countryCode, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestNormalizeRecipients verifies comma-separated, array and templated JSON lists
+func TestNormalizeRecipients(t *testing.T) {
+	got, err := connectors.NormalizeRecipients([]string{"+15551234567; 07700 900123", `["+1 555 123 4567", "+33 6 12 34 56 78"]`}, "44")
+	if err != nil {
+		t.Fatalf("NormalizeRecipients failed: %v", err)
+	}
+	expected := []string{"+15551234567", "+447700900123", "+33612345678"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected deduplicated %v, got %v", expected, got)
+	}
+}
+
+// TestTwilioMultipleRecipients sends to several numbers and aggregates per-recipient results
+func TestTwilioMultipleRecipients(t *testing.T) {
+	var sentTo []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		to := r.PostForm.Get("To")
+		sentTo = append(sentTo, to)
+		if to == "+447700900999" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"sid": "SM` + to[1:] + `", "status": "queued"}`))
+	}))
+	defer server.Close()
+
+	twilio := &connectors.TwilioSMS{AccountSID: "AC123", AuthToken: "token", FromNumber: "+15550000000", BaseURL: server.URL}
+
+	// An invalid recipient fails the step before anything is sent
+	result := twilio.ExecuteWithContext(context.Background(), connectors.TwilioConfig{
+		Recipients: []string{"+15551234567", "not-a-number"},
+		Message:    "Hello",
+	})
+	if result.Status != "failed" || !strings.Contains(result.Message, `"not-a-number"`) || len(sentTo) != 0 {
+		t.Fatalf("Expected a validation failure naming the value, got %q (sent %v)", result.Message, sentTo)
+	}
+
+	result = twilio.ExecuteWithContext(context.Background(), connectors.TwilioConfig{
+		To:                 "07700 900123, 07700 900999",
+		Message:            "Hello",
+		DefaultCountryCode: "44",
+	})
+	if result.Status != "failed" || result.Data["sent"] != 1 || result.Data["failed"] != 1 {
+		t.Fatalf("Expected a partial failure, got %s: %v", result.Status, result.Data)
+	}
+	recipients := result.Data["recipients"].([]map[string]interface{})
+	if recipients[0]["to"] != "+447700900123" || recipients[0]["status"] != "success" || recipients[0]["sid"] != "SM447700900123" {
+		t.Errorf("Unexpected first recipient result: %v", recipients[0])
+	}
+	if recipients[1]["status"] != "failed" {
+		t.Errorf("Unexpected second recipient result: %v", recipients[1])
+	}
+
+	// A single recipient keeps the flat result shape
+	result = twilio.ExecuteWithContext(context.Background(), connectors.TwilioConfig{To: "(555) 123-4567", Message: "Hello", DefaultCountryCode: "1"})
+	if result.Status != "success" || result.Data["to"] != "+15551234567" {
+		t.Errorf("Expected a normalized single send, got %s: %v", result.Status, result.Data)
+	}
+}
+
+// TestTwilioWhatsApp sends through the WhatsApp channel and checks the E.164 validation
+// that happens before Twilio is called
+func TestTwilioWhatsApp(t *testing.T) {
+	forms := make(chan url.Values, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		forms <- r.PostForm
+		w.Write([]byte(`{"sid": "SM123", "status": "queued", "price": "-0.00500", "price_unit": "USD"}`))
+	}))
+	defer server.Close()
+	twilio := &connectors.TwilioSMS{AccountSID: "AC123", AuthToken: "token", FromNumber: "+1 555 000 0000", BaseURL: server.URL}
+
+	// A "whatsapp:" recipient selects the channel
+	result := twilio.ExecuteWithContext(context.Background(), connectors.TwilioConfig{
+		To:             "whatsapp:+44 7700 900123",
+		Message:        "Your order shipped",
+		StatusCallback: "https://goflow.example.com/api/callbacks/twilio/wf_1",
+	})
+	if result.Status != "success" || result.Data["channel"] != connectors.TwilioChannelWhatsApp {
+		t.Fatalf("Expected a WhatsApp send, got %s: %s %v", result.Status, result.Message, result.Data)
+	}
+	form := <-forms
+	if form.Get("To") != "whatsapp:+447700900123" || form.Get("From") != "whatsapp:+15550000000" ||
+		form.Get("StatusCallback") != "https://goflow.example.com/api/callbacks/twilio/wf_1" {
+		t.Errorf("Unexpected request form %v", form)
+	}
+	if result.Data["sid"] != "SM123" || result.Data["price"] != "-0.00500" || result.Data["price_unit"] != "USD" {
+		t.Errorf("Expected the SID and price surfaced, got %v", result.Data)
+	}
+	if _, ok := result.Data["segments"]; ok {
+		t.Errorf("Expected no SMS segments for WhatsApp, got %v", result.Data)
+	}
+
+	for _, c := range []struct {
+		name   string
+		sender string
+		config connectors.TwilioConfig
+		want   string
+	}{
+		{"WhatsApp address on SMS", "+15550000000", connectors.TwilioConfig{To: "whatsapp:+15551234567", Channel: connectors.TwilioChannelSMS}, "WhatsApp address"},
+		{"short code on WhatsApp", "+15550000000", connectors.TwilioConfig{To: "12345", Channel: connectors.TwilioChannelWhatsApp}, "short code"},
+		{"unknown channel", "+15550000000", connectors.TwilioConfig{To: "+15551234567", Channel: "fax"}, "unknown channel"},
+		{"national sender", "07700 900123", connectors.TwilioConfig{To: "+15551234567"}, "from_number rejected"},
+		{"missing sender", "", connectors.TwilioConfig{To: "+15551234567"}, "no from_number"},
+	} {
+		c.config.Message = "Hello"
+		sender := &connectors.TwilioSMS{AccountSID: "AC123", AuthToken: "token", FromNumber: c.sender, BaseURL: server.URL}
+		result := sender.ExecuteWithContext(context.Background(), c.config)
+		if result.Status != "failed" || !strings.Contains(result.Message, c.want) {
+			t.Errorf("%s: expected a failure mentioning %q, got %s: %s", c.name, c.want, result.Status, result.Message)
+		}
+	}
+	select {
+	case form := <-forms:
+		t.Errorf("Expected nothing sent for invalid numbers, got %v", form)
+	default:
+	}
+}
+
+// TestTwilioSignature checks status callback signatures cover the URL and every field
+func TestTwilioSignature(t *testing.T) {
+	callbackURL := "https://goflow.example.com/api/callbacks/twilio/wf_1"
+	form := url.Values{"MessageSid": {"SM123"}, "MessageStatus": {"delivered"}, "To": {"+15551234567"}}
+	signature := connectors.TwilioSignature("secret", callbackURL, form)
+
+	if !connectors.ValidTwilioSignature("secret", callbackURL, form, signature) {
+		t.Fatal("Expected the signature to verify")
+	}
+	tampered := url.Values{"MessageSid": {"SM123"}, "MessageStatus": {"failed"}, "To": {"+15551234567"}}
+	for name, ok := range map[string]bool{
+		"other token":  connectors.ValidTwilioSignature("other", callbackURL, form, signature),
+		"other URL":    connectors.ValidTwilioSignature("secret", callbackURL+"?x=1", form, signature),
+		"other fields": connectors.ValidTwilioSignature("secret", callbackURL, tampered, signature),
+		"no signature": connectors.ValidTwilioSignature("secret", callbackURL, form, ""),
+	} {
+		if ok {
+			t.Errorf("%s: expected the signature refused", name)
+		}
+	}
+
+	status := connectors.ParseTwilioStatus(url.Values{"SmsSid": {"SM9"}, "SmsStatus": {"undelivered"}, "ErrorCode": {"30003"}})
+	if status.MessageSID != "SM9" || !status.Final() || status.Delivered() || status.ErrorCode != "30003" {
+		t.Errorf("Expected the legacy field names read, got %+v", status)
+	}
+}
@@ -0,0 +1,163 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// verifyTimeout bounds a credential check; the caller is waiting on it
+const verifyTimeout = 10 * time.Second
+
+// Verify checks a Slack webhook without posting anything: a live webhook rejects an
+// empty payload with 400, a revoked or unknown one answers 403, 404 or 410
+// A bot token is checked with auth.test instead
+func (s *SlackWebhook) Verify(ctx context.Context) Result {
+	start := time.Now()
+	if s.Token != "" {
+		return s.verifyToken(ctx, start)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.WebhookURL, bytes.NewBufferString("{}"))
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create Slack request: %v", err), start)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return verifyRequest(req, "Slack", func(status int) bool {
+		return status < 300 || status == http.StatusBadRequest
+	}, start)
+}
+
+// verifyToken asks auth.test about the bot token, which answers 200 either way and says
+// in the body whether it accepted it
+func (s *SlackWebhook) verifyToken(ctx context.Context, start time.Time) Result {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiURL("auth.test"), nil)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create Slack request: %v", err), start)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+
+	resp, err := HTTPClients().Client("verify", verifyTimeout).Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return NewCancelledResult(fmt.Sprintf("Context cancelled during Slack verification: %v", ctx.Err()))
+		}
+		return NewRequestErrorResult("Slack", err, fmt.Sprintf("Slack could not be reached: %v", err), start)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var answer struct {
+		OK bool `json:"ok"`
+	}
+	if resp.StatusCode >= 300 || json.Unmarshal(body, &answer) != nil || !answer.OK {
+		return NewHTTPErrorResult("Slack", resp.StatusCode, fmt.Sprintf("Slack rejected the credential: %s", body), start)
+	}
+	return NewSuccessResult("Slack accepted the credential", map[string]interface{}{
+		"status_code": resp.StatusCode,
+	}, start)
+}
+
+// Verify checks a Discord webhook with a GET, which returns the webhook's details
+// without posting a message
+func (d *DiscordWebhook) Verify(ctx context.Context) Result {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "GET", d.WebhookURL, nil)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create Discord request: %v", err), start)
+	}
+	return verifyRequest(req, "Discord", statusOK, start)
+}
+
+// Verify checks the API key with a weather lookup for one city
+func (w *OpenWeatherAPI) Verify(ctx context.Context) Result {
+	start := time.Now()
+	apiURL := fmt.Sprintf("%s/data/2.5/weather?q=London&appid=%s", w.baseURL(), url.QueryEscape(w.APIKey))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create weather request: %v", err), start)
+	}
+	return verifyRequest(req, "OpenWeather", statusOK, start)
+}
+
+// Verify checks the API key by asking for a single headline
+func (n *NewsAPI) Verify(ctx context.Context) Result {
+	start := time.Now()
+	apiURL := fmt.Sprintf("%s/v2/top-headlines?country=us&pageSize=1&apiKey=%s", n.baseURL(), url.QueryEscape(n.APIKey))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create News API request: %v", err), start)
+	}
+	return verifyRequest(req, "News API", statusOK, start)
+}
+
+// Verify checks the account SID and auth token by fetching the account
+// No message is sent, so the from number is not checked
+func (t *TwilioSMS) Verify(ctx context.Context) Result {
+	start := time.Now()
+	if t.AccountSID == "" || t.AuthToken == "" {
+		return NewFailureResult("Twilio requires account_sid and auth_token", start)
+	}
+	baseURL := t.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.twilio.com"
+	}
+	apiURL := fmt.Sprintf("%s/2010-04-01/Accounts/%s.json", strings.TrimRight(baseURL, "/"), url.PathEscape(t.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create Twilio request: %v", err), start)
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	return verifyRequest(req, "Twilio", statusOK, start)
+}
+
+// Verify checks the access token against the instance's REST API resource list
+func (s *SalesforceConnector) Verify(ctx context.Context) Result {
+	start := time.Now()
+	if s.InstanceURL == "" || s.AccessToken == "" {
+		return NewFailureResult("Salesforce requires instance_url and access_token", start)
+	}
+	apiVersion := s.APIVersion
+	if apiVersion == "" {
+		apiVersion = "v59.0"
+	}
+	apiURL := fmt.Sprintf("%s/services/data/%s/", strings.TrimRight(s.InstanceURL, "/"), apiVersion)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return NewFailureResult(fmt.Sprintf("Failed to create Salesforce request: %v", err), start)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.AccessToken)
+	return verifyRequest(req, "Salesforce", statusOK, start)
+}
+
+// statusOK accepts any 2xx answer
+func statusOK(status int) bool {
+	return status >= 200 && status < 300
+}
+
+// verifyRequest sends a credential check and reports whether the service accepted it
+// accepted decides which status codes mean the credential works
+func verifyRequest(req *http.Request, service string, accepted func(status int) bool, start time.Time) Result {
+	client := HTTPClients().Client("verify", verifyTimeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return NewCancelledResult(fmt.Sprintf("Context cancelled during %s verification: %v", service, req.Context().Err()))
+		}
+		return NewRequestErrorResult(service, err, fmt.Sprintf("%s could not be reached: %v", service, err), start)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if !accepted(resp.StatusCode) {
+		return NewHTTPErrorResult(service, resp.StatusCode, fmt.Sprintf("%s rejected the credential (status %d)", service, resp.StatusCode), start)
+	}
+	return NewSuccessResult(fmt.Sprintf("%s accepted the credential", service), map[string]interface{}{
+		"status_code": resp.StatusCode,
+	}, start)
+}
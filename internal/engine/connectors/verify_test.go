@@ -0,0 +1,72 @@
+package connectors_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// TestVerifyCredentials runs each connector's Verify against a fake service that accepts
+// one credential, and checks nothing is posted
+func TestVerifyCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		valid := false
+		switch r.URL.Path {
+		case "/slack/good":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "{}" {
+				t.Errorf("Expected an empty Slack payload, got %s", body)
+			}
+			w.WriteHeader(http.StatusBadRequest) // no_text: the webhook exists
+			return
+		case "/discord/good":
+			valid = r.Method == "GET"
+		case "/data/2.5/weather":
+			valid = r.URL.Query().Get("appid") == "good"
+		case "/v2/top-headlines":
+			valid = r.URL.Query().Get("apiKey") == "good" && r.URL.Query().Get("pageSize") == "1"
+		case "/2010-04-01/Accounts/AC1.json":
+			valid = r.Method == "GET" && user == "AC1" && pass == "good"
+		case "/services/data/v59.0/":
+			valid = r.Header.Get("Authorization") == "Bearer good"
+		}
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		good, bad interface {
+			Verify(context.Context) connectors.Result
+		}
+	}{
+		{"slack", &connectors.SlackWebhook{WebhookURL: srv.URL + "/slack/good"}, &connectors.SlackWebhook{WebhookURL: srv.URL + "/slack/revoked"}},
+		{"discord", &connectors.DiscordWebhook{WebhookURL: srv.URL + "/discord/good"}, &connectors.DiscordWebhook{WebhookURL: srv.URL + "/discord/revoked"}},
+		{"openweather", &connectors.OpenWeatherAPI{APIKey: "good", BaseURL: srv.URL}, &connectors.OpenWeatherAPI{APIKey: "bad", BaseURL: srv.URL}},
+		{"newsapi", &connectors.NewsAPI{APIKey: "good", BaseURL: srv.URL}, &connectors.NewsAPI{APIKey: "bad", BaseURL: srv.URL}},
+		{"twilio", &connectors.TwilioSMS{AccountSID: "AC1", AuthToken: "good", BaseURL: srv.URL}, &connectors.TwilioSMS{AccountSID: "AC1", AuthToken: "bad", BaseURL: srv.URL}},
+		{"salesforce", &connectors.SalesforceConnector{InstanceURL: srv.URL, AccessToken: "good"}, &connectors.SalesforceConnector{InstanceURL: srv.URL, AccessToken: "bad"}},
+	}
+	for _, tt := range tests {
+		if result := tt.good.Verify(ctx); result.Status != "success" {
+			t.Errorf("%s: expected the credential to be accepted, got %+v", tt.name, result)
+		}
+		if result := tt.bad.Verify(ctx); result.Status != "failed" || result.ErrorCode != connectors.ErrCodeAuthFailed {
+			t.Errorf("%s: expected the credential to be rejected, got %+v", tt.name, result)
+		}
+	}
+
+	srv.Close()
+	result := (&connectors.NewsAPI{APIKey: "good", BaseURL: srv.URL}).Verify(ctx)
+	if result.Status != "failed" || result.ErrorCode != connectors.ErrCodeUpstreamUnreachable {
+		t.Errorf("Expected an unreachable service to be reported, got %+v", result)
+	}
+}
@@ -0,0 +1,59 @@
+package connectors
+
+// LatestVersion follows the connector's current version (what workflows get by default)
+const LatestVersion = "latest"
+
+// defaultVersion is the only version of connectors that never declared any
+const defaultVersion = "v1"
+
+// ConnectorVersions are the internal versions of an action type's implementation
+// Versions newer than Current are only reached through a workflow pin or a tenant canary
+type ConnectorVersions struct {
+	Versions []string `json:"versions"` // Oldest first
+	Current  string   `json:"current"`  // What "latest" resolves to
+}
+
+// Supports reports whether v is one of the declared versions
+func (c ConnectorVersions) Supports(v string) bool {
+	for _, version := range c.Versions {
+		if version == v {
+			return true
+		}
+	}
+	return false
+}
+
+// actionVersions maps action types to their declared versions
+// Keep in sync with the version switches in the executor
+var actionVersions = map[string]ConnectorVersions{
+	"salesforce": {Versions: []string{"v1", "v2"}, Current: "v1"},
+}
+
+// RegisterVersions declares the versions of an action type's connector, oldest first
+// current is what workflows on "latest" run; newer versions are rolled out by canary
+func RegisterVersions(actionType, current string, versions ...string) {
+	actionVersions[actionType] = ConnectorVersions{Versions: versions, Current: current}
+}
+
+// VersionsFor returns the declared versions of an action type
+// Connectors that never declared any have a single version, v1
+func VersionsFor(actionType string) ConnectorVersions {
+	if versions, ok := actionVersions[actionType]; ok {
+		return versions
+	}
+	return ConnectorVersions{Versions: []string{defaultVersion}, Current: defaultVersion}
+}
+
+// salesforceAPIVersions are the Salesforce REST API versions each connector version calls
+var salesforceAPIVersions = map[string]string{
+	"v1": "v59.0",
+	"v2": "v60.0",
+}
+
+// SalesforceAPIVersion returns the REST API version a Salesforce connector version calls
+func SalesforceAPIVersion(connectorVersion string) string {
+	if apiVersion, ok := salesforceAPIVersions[connectorVersion]; ok {
+		return apiVersion
+	}
+	return salesforceAPIVersions[VersionsFor("salesforce").Current]
+}
@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// ErrCredentialEnvironment marks a credential from the other environment than its workflow's
+// Only returned when the tenant enforces environments; otherwise the mismatch is logged
+var ErrCredentialEnvironment = errors.New("credential environment mismatch")
+
+// CredentialEnvironmentError names both sides of a mismatch
+type CredentialEnvironmentError struct {
+	Workflow              string // Workflow name
+	WorkflowEnvironment   string
+	Service               string
+	CredentialEnvironment string
+}
+
+func (e *CredentialEnvironmentError) Error() string {
+	return fmt.Sprintf("workflow %q runs in %s but its %s credential is a %s credential",
+		e.Workflow, e.WorkflowEnvironment, e.Service, e.CredentialEnvironment)
+}
+
+func (e *CredentialEnvironmentError) Unwrap() error {
+	return ErrCredentialEnvironment
+}
+
+// WorkflowEnvironment returns the environment a workflow runs against (live unless it says test)
+func WorkflowEnvironment(config models.WorkflowConfig) string {
+	if config.Environment == models.EnvironmentTest {
+		return models.EnvironmentTest
+	}
+	return models.EnvironmentLive
+}
+
+// ValidateEnvironment checks a workflow or credential environment ("" means live)
+func ValidateEnvironment(environment string) error {
+	switch environment {
+	case "", models.EnvironmentLive, models.EnvironmentTest:
+		return nil
+	}
+	return fmt.Errorf("environment must be %q or %q", models.EnvironmentLive, models.EnvironmentTest)
+}
+
+// ValidateWorkflowEnvironment checks the environment a workflow config asks for
+func ValidateWorkflowEnvironment(configJSON string) error {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	return ValidateEnvironment(config.Environment)
+}
+
+// EnvironmentWarnings lists the checklist's environment mismatches as save-time warnings
+func EnvironmentWarnings(requirements *models.WorkflowRequirements) []string {
+	var warnings []string
+	for _, req := range requirements.Requirements {
+		if req.Status == "environment_mismatch" {
+			warnings = append(warnings, fmt.Sprintf("workflow runs in %s but %s is only connected with %s credentials",
+				requirements.Environment, req.Label, req.Environment))
+		}
+	}
+	return warnings
+}
+
+// PreferredCredentialEnvironment is the environment whose credential a run looks for first
+// Dry runs prefer test credentials, whatever the workflow's environment
+func PreferredCredentialEnvironment(workflowEnvironment string, dryRun bool) string {
+	if dryRun {
+		return models.EnvironmentTest
+	}
+	return workflowEnvironment
+}
+
+// CredentialEnvironmentAllowed decides whether a run may use a credential from credentialEnvironment
+// Returns whether the environments mismatch and whether the credential may still be used:
+// dry runs and tenants on the warn policy use it anyway
+func CredentialEnvironmentAllowed(workflowEnvironment, credentialEnvironment string, dryRun bool, policy string) (mismatch, allowed bool) {
+	if credentialEnvironment == "" {
+		credentialEnvironment = models.EnvironmentLive
+	}
+	if dryRun || credentialEnvironment == workflowEnvironment {
+		return false, true
+	}
+	return true, policy != models.CredentialPolicyEnforce
+}
+
+// ValidateCredentialPolicy checks a tenant's credential environment policy ("" means warn)
+func ValidateCredentialPolicy(policy string) error {
+	switch policy {
+	case "", models.CredentialPolicyWarn, models.CredentialPolicyEnforce:
+		return nil
+	}
+	return fmt.Errorf("credential_policy must be %q or %q", models.CredentialPolicyWarn, models.CredentialPolicyEnforce)
+}
+
+// credentialScopeKey carries the running workflow's name and environment to credential lookups
+type credentialScopeKey struct{}
+
+// credentialScope is what credential lookups need to know about the running workflow
+type credentialScope struct {
+	workflow    string
+	environment string
+}
+
+// withCredentialScope returns a context whose credential lookups honour the workflow's environment
+func withCredentialScope(ctx context.Context, workflow models.Workflow, config models.WorkflowConfig) context.Context {
+	return context.WithValue(ctx, credentialScopeKey{}, credentialScope{workflow: workflow.Name, environment: WorkflowEnvironment(config)})
+}
+
+// dryRunKey marks a context as a dry run's, whose lookups prefer test credentials
+type dryRunKey struct{}
+
+// withDryRun marks ctx as a dry run's
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// isDryRun reports whether ctx belongs to a dry run (sandboxed runs count as one)
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun || IsSandbox(ctx)
+}
+
+// credentialScopeFrom returns the running workflow's credential scope (live when unknown)
+func credentialScopeFrom(ctx context.Context) credentialScope {
+	if scope, ok := ctx.Value(credentialScopeKey{}).(credentialScope); ok {
+		return scope
+	}
+	return credentialScope{environment: models.EnvironmentLive}
+}
+
+// checkCredentialEnvironment applies the tenant's policy to the credential a lookup found
+func (e *Executor) checkCredentialEnvironment(ctx context.Context, cred *models.Credential, userID, tenantID string) error {
+	scope := credentialScopeFrom(ctx)
+	if mismatch, _ := CredentialEnvironmentAllowed(scope.environment, cred.Environment, isDryRun(ctx), ""); !mismatch {
+		return nil
+	}
+
+	policy := models.CredentialPolicyWarn
+	if settings, err := e.store.GetTenantSettings(tenantID); err == nil && settings.CredentialPolicy != "" {
+		policy = settings.CredentialPolicy
+	}
+	mismatchErr := &CredentialEnvironmentError{
+		Workflow:              scope.workflow,
+		WorkflowEnvironment:   scope.environment,
+		Service:               cred.ServiceName,
+		CredentialEnvironment: cred.Environment,
+	}
+	e.log.Warn("Credential environment mismatch", map[string]interface{}{
+		"user_id":                userID,
+		"tenant_id":              tenantID,
+		"workflow":               scope.workflow,
+		"workflow_environment":   scope.environment,
+		"service_name":           cred.ServiceName,
+		"credential_environment": cred.Environment,
+		"policy":                 policy,
+	})
+
+	if _, allowed := CredentialEnvironmentAllowed(scope.environment, cred.Environment, false, policy); !allowed {
+		return mismatchErr
+	}
+	return nil
+}
+
+// credentialErrorResult reports a credential lookup that failed: missing, or blocked by the environment policy
+func credentialErrorResult(service string, err error) connectors.Result {
+	var envErr *CredentialEnvironmentError
+	if errors.As(err, &envErr) {
+		return connectors.NewCredentialEnvironmentResult(service, envErr.WorkflowEnvironment, envErr.CredentialEnvironment, envErr.Error())
+	}
+	return connectors.NewCredentialMissingResult(service, fmt.Sprintf("%s not connected: %v", service, err))
+}
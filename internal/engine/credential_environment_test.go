@@ -0,0 +1,181 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestCredentialEnvironmentAllowed covers every workflow/credential environment pair,
+// in and out of dry runs, under both tenant policies
+func TestCredentialEnvironmentAllowed(t *testing.T) {
+	environments := []string{models.EnvironmentLive, models.EnvironmentTest}
+	for _, workflowEnv := range environments {
+		for _, credentialEnv := range environments {
+			for _, dryRun := range []bool{false, true} {
+				for _, policy := range []string{models.CredentialPolicyWarn, models.CredentialPolicyEnforce} {
+					wantMismatch := workflowEnv != credentialEnv && !dryRun
+					wantAllowed := !wantMismatch || policy == models.CredentialPolicyWarn
+
+					mismatch, allowed := engine.CredentialEnvironmentAllowed(workflowEnv, credentialEnv, dryRun, policy)
+					if mismatch != wantMismatch || allowed != wantAllowed {
+						t.Errorf("workflow %s, credential %s, dry run %t, policy %s: expected mismatch=%t allowed=%t, got %t %t",
+							workflowEnv, credentialEnv, dryRun, policy, wantMismatch, wantAllowed, mismatch, allowed)
+					}
+				}
+			}
+		}
+	}
+
+	// Credentials stored before environments existed count as live
+	if mismatch, _ := engine.CredentialEnvironmentAllowed(models.EnvironmentLive, "", false, models.CredentialPolicyEnforce); mismatch {
+		t.Error("Expected a credential without an environment to match a live workflow")
+	}
+}
+
+func TestPreferredCredentialEnvironment(t *testing.T) {
+	tests := []struct {
+		workflowEnv string
+		dryRun      bool
+		want        string
+	}{
+		{models.EnvironmentLive, false, models.EnvironmentLive},
+		{models.EnvironmentTest, false, models.EnvironmentTest},
+		{models.EnvironmentLive, true, models.EnvironmentTest},
+		{models.EnvironmentTest, true, models.EnvironmentTest},
+	}
+	for _, tt := range tests {
+		if got := engine.PreferredCredentialEnvironment(tt.workflowEnv, tt.dryRun); got != tt.want {
+			t.Errorf("workflow %s, dry run %t: expected %s, got %s", tt.workflowEnv, tt.dryRun, tt.want, got)
+		}
+	}
+}
+
+// countingServer is a fake Slack webhook counting the messages it receives
+func countingServer(t *testing.T) (*httptest.Server, *int32) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+// TestCredentialEnvironmentResolution runs Slack workflows against live and test webhooks
+// and checks which one each run posts to, and when the tenant's policy blocks the run
+func TestCredentialEnvironmentResolution(t *testing.T) {
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+
+	tests := []struct {
+		name        string
+		workflowEnv string
+		credentials []string // Environments the user has a Slack credential in
+		policy      string
+		dryRun      bool
+		want        string // Environment whose webhook receives the message; "" when the run is blocked
+	}{
+		{"live workflow picks the live credential", models.EnvironmentLive, []string{"live", "test"}, "enforce", false, "live"},
+		{"test workflow picks the test credential", models.EnvironmentTest, []string{"live", "test"}, "enforce", false, "test"},
+		{"dry run prefers the test credential", models.EnvironmentLive, []string{"live", "test"}, "enforce", true, "test"},
+		{"dry run falls back to the live credential", models.EnvironmentLive, []string{"live"}, "enforce", true, "live"},
+		{"live workflow with a test credential warns", models.EnvironmentLive, []string{"test"}, "warn", false, "test"},
+		{"live workflow with a test credential is blocked", models.EnvironmentLive, []string{"test"}, "enforce", false, ""},
+		{"test workflow with a live credential warns", models.EnvironmentTest, []string{"live"}, "warn", false, "live"},
+		{"test workflow with a live credential is blocked", models.EnvironmentTest, []string{"live"}, "enforce", false, ""},
+	}
+	for i, tt := range tests {
+		servers := map[string]*httptest.Server{}
+		hits := map[string]*int32{}
+		for _, env := range []string{"live", "test"} {
+			servers[env], hits[env] = countingServer(t)
+		}
+
+		user, _ := database.CreateUser(fmt.Sprintf("env%d@example.com", i), "hashed")
+		for _, env := range tt.credentials {
+			database.CreateCredentialInEnvironment(user.ID, "slack", servers[env].URL, env)
+		}
+		database.SaveTenantSettings(&models.TenantSettings{TenantID: models.DefaultTenantID(user.ID), CredentialPolicy: tt.policy})
+		workflow, _ := database.CreateWorkflow(user.ID, "Alerts", "webhook", "slack_message",
+			`{"slack_message": "hello", "environment": "`+tt.workflowEnv+`"}`)
+
+		var status, message string
+		if tt.dryRun {
+			result := executor.DryRun(*workflow, user.ID, "")
+			status, message = result.Status, result.Message
+			if tt.want == "" && result.ErrorCode != connectors.ErrCodeCredentialEnvironment {
+				t.Errorf("%s: expected error code %s, got %q", tt.name, connectors.ErrCodeCredentialEnvironment, result.ErrorCode)
+			}
+		} else {
+			executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+			execution, err := database.GetLatestExecution(workflow.ID)
+			if err != nil {
+				t.Fatalf("%s: expected an execution, got %v", tt.name, err)
+			}
+			status, message = execution.Status, execution.Message
+		}
+
+		for _, env := range []string{"live", "test"} {
+			want := int32(0)
+			if env == tt.want {
+				want = 1
+			}
+			if got := atomic.LoadInt32(hits[env]); got != want {
+				t.Errorf("%s: expected %d messages to the %s webhook, got %d", tt.name, want, env, got)
+			}
+		}
+		if tt.want == "" {
+			other := "test"
+			if tt.workflowEnv == models.EnvironmentTest {
+				other = "live"
+			}
+			if status != "failed" || !strings.Contains(message, "runs in "+tt.workflowEnv) || !strings.Contains(message, "is a "+other+" credential") {
+				t.Errorf("%s: expected a failure naming both environments, got %s: %q", tt.name, status, message)
+			}
+		}
+	}
+}
+
+// TestRequirementsEnvironment checks the checklist flags services connected only in the
+// other environment, and that the flag only blocks readiness when the tenant enforces it
+func TestRequirementsEnvironment(t *testing.T) {
+	database := dbtest.New(t)
+
+	for _, policy := range []string{models.CredentialPolicyWarn, models.CredentialPolicyEnforce} {
+		user, _ := database.CreateUser("checklist-"+policy+"@example.com", "hashed")
+		database.CreateCredentialInEnvironment(user.ID, "slack", "https://hooks.example.com/test", models.EnvironmentTest)
+		database.SaveTenantSettings(&models.TenantSettings{TenantID: models.DefaultTenantID(user.ID), CredentialPolicy: policy})
+
+		live, _ := database.CreateWorkflow(user.ID, "Live", "webhook", "slack_message", `{"slack_message": "hi"}`)
+		checklist, err := engine.CheckWorkflowRequirements(database, *live, user.ID)
+		if err != nil {
+			t.Fatalf("Failed to check requirements: %v", err)
+		}
+		entry := checklist.Requirements[0]
+		if checklist.Environment != models.EnvironmentLive || entry.Status != "environment_mismatch" || entry.Environment != models.EnvironmentTest {
+			t.Errorf("%s: expected a mismatch against the test credential, got %+v (%s)", policy, entry, checklist.Environment)
+		}
+		if wantReady := policy == models.CredentialPolicyWarn; checklist.Ready != wantReady {
+			t.Errorf("%s: expected ready=%t, got %t", policy, wantReady, checklist.Ready)
+		}
+		if warnings := engine.EnvironmentWarnings(checklist); len(warnings) != 1 || !strings.Contains(warnings[0], "runs in live") {
+			t.Errorf("%s: expected one save-time warning, got %q", policy, warnings)
+		}
+
+		test, _ := database.CreateWorkflow(user.ID, "Test", "webhook", "slack_message", `{"slack_message": "hi", "environment": "test"}`)
+		checklist, _ = engine.CheckWorkflowRequirements(database, *test, user.ID)
+		if !checklist.Ready || checklist.Requirements[0].Status != "connected" {
+			t.Errorf("%s: expected a test workflow to be ready with a test credential, got %+v", policy, checklist)
+		}
+	}
+}
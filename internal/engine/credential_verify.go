@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// VerifiableServices lists the services VerifyCredential can check
+var VerifiableServices = []string{"slack", "discord", "openweather", "newsapi", "twilio", "salesforce"}
+
+// ErrUnverifiableService is returned for services without a credential check
+var ErrUnverifiableService = fmt.Errorf("credential checks are supported for %s", strings.Join(VerifiableServices, ", "))
+
+// VerifyCredential checks a raw credential against its service without storing it
+// rawKey uses the same format as a saved credential: the webhook URL (or bot token) for Slack and Discord,
+// the API key for OpenWeather and News API, and JSON for Twilio and Salesforce
+// An error means the key could not be parsed; a rejected credential is a failed result
+func VerifyCredential(ctx context.Context, serviceName, rawKey string) (connectors.Result, error) {
+	switch serviceName {
+	case "slack", "discord":
+		if serviceName == "slack" && connectors.IsSlackBotToken(rawKey) {
+			return connectors.NewSlackConnector(rawKey).Verify(ctx), nil
+		}
+		// The server makes the request, so webhook URLs get the same check as http_request
+		if _, err := connectors.CheckHTTPURL(rawKey); err != nil {
+			return connectors.Result{}, fmt.Errorf("webhook URL: %v", err)
+		}
+		if serviceName == "slack" {
+			return (&connectors.SlackWebhook{WebhookURL: rawKey}).Verify(ctx), nil
+		}
+		return (&connectors.DiscordWebhook{WebhookURL: rawKey}).Verify(ctx), nil
+	case "openweather":
+		return (&connectors.OpenWeatherAPI{APIKey: rawKey}).Verify(ctx), nil
+	case "newsapi":
+		return (&connectors.NewsAPI{APIKey: rawKey}).Verify(ctx), nil
+	case "twilio":
+		var twilioConfig struct {
+			AccountSID string `json:"account_sid"`
+			AuthToken  string `json:"auth_token"`
+		}
+		if err := json.Unmarshal([]byte(rawKey), &twilioConfig); err != nil {
+			return connectors.Result{}, fmt.Errorf("Invalid Twilio credentials format: %v", err)
+		}
+		return (&connectors.TwilioSMS{AccountSID: twilioConfig.AccountSID, AuthToken: twilioConfig.AuthToken}).Verify(ctx), nil
+	case "salesforce":
+		var sfCreds map[string]string
+		if err := json.Unmarshal([]byte(rawKey), &sfCreds); err != nil {
+			return connectors.Result{}, fmt.Errorf("Invalid Salesforce credentials format: %v", err)
+		}
+		if _, err := connectors.CheckHTTPURL(sfCreds["instance_url"]); err != nil {
+			return connectors.Result{}, fmt.Errorf("instance_url: %v", err)
+		}
+		return (&connectors.SalesforceConnector{InstanceURL: sfCreds["instance_url"], AccessToken: sfCreds["access_token"]}).Verify(ctx), nil
+	default:
+		return connectors.Result{}, ErrUnverifiableService
+	}
+}
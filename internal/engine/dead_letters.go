@@ -0,0 +1,20 @@
+package engine
+
+// deadLetter records a job the worker pool dropped, so the run can be retried instead of
+// vanishing with a log line
+func (e *Executor) deadLetter(job WorkflowJob, reason string) {
+	letter, err := e.store.CreateDeadLetter(job.Workflow.ID, reason, job.Payload)
+	if err != nil {
+		e.log.Error("Failed to record dropped job", map[string]interface{}{
+			"workflow_id": job.Workflow.ID,
+			"reason":      reason,
+			"error":       err.Error(),
+		})
+		return
+	}
+	e.log.Warn("Dropped job dead-lettered", map[string]interface{}{
+		"workflow_id":    job.Workflow.ID,
+		"dead_letter_id": letter.ID,
+		"reason":         reason,
+	})
+}
@@ -0,0 +1,259 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// diffIgnoredFields vary between any two runs (or between sandbox and real sends)
+// and would drown the meaningful changes
+var diffIgnoredFields = map[string]bool{
+	"data.sandbox":     true,
+	"data.status_code": true,
+	"data.sid":         true,
+	"data.status":      true, // Provider delivery status (Twilio)
+	"data.timestamp":   true,
+	"data.duration":    true,
+}
+
+// maxWordDiffCells bounds the LCS table of the word diff (words before × words after)
+const maxWordDiffCells = 1000000
+
+// DryRunDiff runs a candidate workflow in sandbox mode with the trigger payload of a
+// stored execution, then diffs the candidate's step outputs against that execution
+func (e *Executor) DryRunDiff(workflow models.Workflow, baseline *models.Execution, userID, tenantID string) (*models.ExecutionDiff, error) {
+	ctx, cancel := withRunTimeout(WithSandbox(context.Background()), workflow, DryRunTimeout)
+	defer cancel()
+
+	result := e.executeWorkflowInternal(ctx, workflow, baseline.TriggerPayload, userID, tenantID)
+	codeFailure(&result, workflow.ActionType)
+	explainRunTimeout(ctx, &result)
+
+	return DiffExecution(baseline, result)
+}
+
+// DiffExecution compares a candidate result with a stored execution trace
+// Each step compares its status, its error (for non-successful steps) and its data fields
+func DiffExecution(baseline *models.Execution, candidate connectors.Result) (*models.ExecutionDiff, error) {
+	var baselineTrace map[string]interface{}
+	if baseline.ResultData != "" {
+		if err := json.Unmarshal([]byte(baseline.ResultData), &baselineTrace); err != nil {
+			return nil, fmt.Errorf("failed to parse stored execution trace: %w", err)
+		}
+	}
+
+	before := traceSteps(baselineTrace)
+	after := traceSteps(maskedTrace(candidate))
+
+	diff := &models.ExecutionDiff{
+		BaselineExecutionID: baseline.ID,
+		BaselineExecutedAt:  baseline.ExecutedAt,
+		CandidateStatus:     candidate.Status,
+		Steps:               []models.StepDiff{},
+	}
+
+	steps := len(before)
+	if len(after) > steps {
+		steps = len(after)
+	}
+	for i := 0; i < steps; i++ {
+		step := models.StepDiff{Step: i}
+		switch {
+		case i >= len(before):
+			step.Change = "added"
+			step.Fields = diffFields(nil, stepFields(after[i]))
+		case i >= len(after):
+			step.Change = "removed"
+			step.Fields = diffFields(stepFields(before[i]), nil)
+		default:
+			step.Fields = diffFields(stepFields(before[i]), stepFields(after[i]))
+			step.Change = "unchanged"
+			if len(step.Fields) > 0 {
+				step.Change = "changed"
+			}
+		}
+		if step.Change != "unchanged" {
+			diff.Changed = true
+		}
+		diff.Steps = append(diff.Steps, step)
+	}
+
+	return diff, nil
+}
+
+// traceSteps splits a trace into the primary step followed by its chain steps
+func traceSteps(trace map[string]interface{}) []map[string]interface{} {
+	if trace == nil {
+		return nil
+	}
+
+	primary := make(map[string]interface{}, len(trace))
+	for key, value := range trace {
+		primary[key] = value
+	}
+
+	var chain []interface{}
+	if data, ok := trace["data"].(map[string]interface{}); ok {
+		primaryData := make(map[string]interface{}, len(data))
+		for key, value := range data {
+			primaryData[key] = value
+		}
+		chain, _ = primaryData["chain_results"].([]interface{})
+		delete(primaryData, "chain_results")
+		delete(primaryData, "chain_count")
+		primary["data"] = primaryData
+	}
+
+	steps := []map[string]interface{}{primary}
+	for _, item := range chain {
+		if step, ok := item.(map[string]interface{}); ok {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// stepFields flattens the comparable parts of a step into dotted paths
+func stepFields(step map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	status, _ := step["status"].(string)
+	fields["status"] = status
+	if status != "success" {
+		fields["error"] = step["message"]
+	}
+	flattenInto(fields, "data", step["data"])
+
+	for path := range fields {
+		if diffIgnoredFields[path] {
+			delete(fields, path)
+		}
+	}
+	return fields
+}
+
+// flattenInto writes nested maps and arrays as "a.b.0.c" paths
+func flattenInto(fields map[string]interface{}, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			flattenInto(fields, prefix+"."+key, child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenInto(fields, fmt.Sprintf("%s.%d", prefix, i), child)
+		}
+	case nil:
+		// Absent and null are treated alike
+	default:
+		fields[prefix] = v
+	}
+}
+
+// diffFields compares two flattened steps, sorted by path
+func diffFields(before, after map[string]interface{}) []models.FieldDiff {
+	paths := make(map[string]bool)
+	for path := range before {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var fields []models.FieldDiff
+	for _, path := range sorted {
+		oldValue, hadOld := before[path]
+		newValue, hasNew := after[path]
+		switch {
+		case !hadOld:
+			fields = append(fields, models.FieldDiff{Path: path, Change: "added", After: newValue})
+		case !hasNew:
+			fields = append(fields, models.FieldDiff{Path: path, Change: "removed", Before: oldValue})
+		case !reflect.DeepEqual(oldValue, newValue):
+			field := models.FieldDiff{Path: path, Change: "changed", Before: oldValue, After: newValue}
+			oldText, oldIsText := oldValue.(string)
+			newText, newIsText := newValue.(string)
+			if oldIsText && newIsText {
+				field.TextDiff = wordDiff(oldText, newText)
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// wordDiff renders a word-level diff in git's --word-diff=plain style
+func wordDiff(before, after string) string {
+	a := strings.Fields(before)
+	b := strings.Fields(after)
+	if len(a)*len(b) > maxWordDiffCells {
+		return "[-" + before + "-]{+" + after + "+}"
+	}
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	var removed, added []string
+	flush := func() {
+		change := ""
+		if len(removed) > 0 {
+			change += "[-" + strings.Join(removed, " ") + "-]"
+			removed = nil
+		}
+		if len(added) > 0 {
+			change += "{+" + strings.Join(added, " ") + "+}"
+			added = nil
+		}
+		if change != "" {
+			out = append(out, change)
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			flush()
+			out = append(out, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	removed = append(removed, a[i:]...)
+	added = append(added, b[j:]...)
+	flush()
+
+	return strings.Join(out, " ")
+}
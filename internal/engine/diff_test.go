@@ -0,0 +1,150 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// slackStep builds a chain step result as the Slack connector reports it
+func slackStep(message string) connectors.Result {
+	return connectors.Result{
+		Status:  "success",
+		Message: "Slack message sent successfully",
+		Data:    map[string]interface{}{"status_code": 200, "message": message},
+	}
+}
+
+// chainResult builds a primary result with the given chain steps
+func chainResult(steps ...connectors.Result) connectors.Result {
+	return connectors.Result{
+		Status:  "success",
+		Message: "Mock response returned with status 200",
+		Data: map[string]interface{}{
+			"order_id":      "42",
+			"chain_results": steps,
+			"chain_count":   len(steps),
+		},
+	}
+}
+
+// storedExecution encodes a result the way the executor stores its trace
+func storedExecution(t *testing.T, result connectors.Result) *models.Execution {
+	t.Helper()
+	raw, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to encode trace: %v", err)
+	}
+	return &models.Execution{ID: "exec_1", WorkflowID: "wf_1", Status: result.Status, ResultData: string(raw)}
+}
+
+// TestDiffExecutionChainLengths compares chains that grew, shrank, or changed text
+func TestDiffExecutionChainLengths(t *testing.T) {
+	baseline := storedExecution(t, chainResult(slackStep("Order 41 shipped"), slackStep("Notify warehouse")))
+
+	tests := []struct {
+		name      string
+		candidate connectors.Result
+		changes   []string // Expected change per step (0 = primary)
+	}{
+		{"identical", chainResult(slackStep("Order 41 shipped"), slackStep("Notify warehouse")), []string{"unchanged", "unchanged", "unchanged"}},
+		{"longer chain", chainResult(slackStep("Order 41 shipped"), slackStep("Notify warehouse"), slackStep("Notify billing")), []string{"unchanged", "unchanged", "unchanged", "added"}},
+		{"shorter chain", chainResult(slackStep("Order 41 shipped")), []string{"unchanged", "unchanged", "removed"}},
+		{"no chain", connectors.Result{Status: "success", Data: map[string]interface{}{"order_id": "42"}}, []string{"unchanged", "removed", "removed"}},
+		{"changed text", chainResult(slackStep("Order 42 shipped"), slackStep("Notify warehouse")), []string{"unchanged", "changed", "unchanged"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := engine.DiffExecution(baseline, tt.candidate)
+			if err != nil {
+				t.Fatalf("DiffExecution failed: %v", err)
+			}
+			if len(diff.Steps) != len(tt.changes) {
+				t.Fatalf("Expected %d steps, got %d: %+v", len(tt.changes), len(diff.Steps), diff.Steps)
+			}
+			for i, want := range tt.changes {
+				if diff.Steps[i].Change != want {
+					t.Errorf("Step %d: expected %s, got %s (%+v)", i, want, diff.Steps[i].Change, diff.Steps[i].Fields)
+				}
+			}
+			if diff.Changed != (tt.name != "identical") {
+				t.Errorf("Expected Changed=%v", tt.name != "identical")
+			}
+		})
+	}
+
+	diff, _ := engine.DiffExecution(baseline, chainResult(slackStep("Order 42 shipped"), slackStep("Notify warehouse")))
+	field := diff.Steps[1].Fields[0]
+	if field.Path != "data.message" || field.TextDiff != "Order [-41-]{+42+} shipped" {
+		t.Errorf("Unexpected text diff: %+v", field)
+	}
+}
+
+// TestDryRunDiffReplaysStoredPayload runs an edited chain in sandbox mode against
+// the payload of the last execution without sending anything
+func TestDryRunDiffReplaysStoredPayload(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("diff@example.com", "hashed")
+	mockStore.CreateCredential(user.ID, "slack", "mock_webhook_url")
+
+	config := `{"testing_response_json": "{\"order_id\": \"{{order.id}}\", \"courier\": \"{{courier}}\"}"}`
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Shipping", "webhook", "testing", config)
+
+	baselineResult := connectors.Result{
+		Status: "success",
+		Data: map[string]interface{}{
+			"order_id":      "42",
+			"courier":       "UPS",
+			"chain_results": []connectors.Result{slackStep("Order 42 shipped")},
+		},
+	}
+	baseline := storedExecution(t, baselineResult)
+	baseline.WorkflowID = workflow.ID
+	baseline.TriggerPayload = `{"order": {"id": "42"}, "courier": "UPS", "api_key": "***REDACTED***"}`
+	mockStore.CreateExecution(baseline)
+
+	edited := *workflow
+	edited.ActionChain = `[
+		{"action_type": "slack_message", "use_data_from": "previous", "config": {"slack_message": "Order {{order_id}} shipped by {{courier}}"}},
+		{"action_type": "slack_message", "config": {"slack_message": "Sent with https://hooks.slack.com/services/T000/B000/XXXXXXXXXXXX"}}
+	]`
+
+	latest, err := mockStore.GetLatestExecution(workflow.ID)
+	if err != nil {
+		t.Fatalf("Expected stored execution: %v", err)
+	}
+	diff, err := executor.DryRunDiff(edited, latest, user.ID, "tenant_"+user.ID)
+	if err != nil {
+		t.Fatalf("DryRunDiff failed: %v", err)
+	}
+
+	if len(diff.Steps) != 3 {
+		t.Fatalf("Expected primary + 2 chain steps, got %+v", diff.Steps)
+	}
+	if diff.Steps[0].Change != "unchanged" {
+		t.Errorf("Expected primary step unchanged, got %+v", diff.Steps[0].Fields)
+	}
+	if diff.Steps[1].Change != "changed" || diff.Steps[1].Fields[0].TextDiff != "Order 42 shipped {+by UPS+}" {
+		t.Errorf("Expected rendered message diff, got %+v", diff.Steps[1])
+	}
+	if diff.Steps[2].Change != "added" {
+		t.Errorf("Expected added step, got %s", diff.Steps[2].Change)
+	}
+
+	out, _ := json.Marshal(diff)
+	if strings.Contains(string(out), "XXXXXXXXXXXX") {
+		t.Errorf("Expected secrets to be masked in diff: %s", out)
+	}
+	if len(mockStore.Logs) != 0 || len(mockStore.Executions) != 1 {
+		t.Errorf("Dry run diff must not record logs or executions")
+	}
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// discordEmbed converts a configured embed to Discord's wire format, rendering its text
+// fields against the trigger payload
+func (e *Executor) discordEmbed(embed models.DiscordEmbed, payload string) (connectors.DiscordEmbed, error) {
+	out := connectors.DiscordEmbed{
+		Title:       embed.Title,
+		Description: embed.Description,
+		URL:         embed.URL,
+		Color:       embed.Color,
+		Timestamp:   embed.Timestamp,
+	}
+	footer := embed.Footer
+	fields := []*string{&out.Title, &out.Description, &out.URL, &out.Timestamp, &footer}
+	for _, field := range embed.Fields {
+		out.Fields = append(out.Fields, connectors.DiscordEmbedField{Name: field.Name, Value: field.Value, Inline: field.Inline})
+	}
+	for i := range out.Fields {
+		fields = append(fields, &out.Fields[i].Name, &out.Fields[i].Value)
+	}
+
+	if payload != "" {
+		if err := e.renderTemplates(payload, fields...); err != nil {
+			return connectors.DiscordEmbed{}, err
+		}
+	}
+	if footer != "" {
+		out.Footer = &connectors.DiscordEmbedFooter{Text: footer}
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestDiscordEmbedTemplates renders the trigger payload into the content, username and embed
+func TestDiscordEmbedTemplates(t *testing.T) {
+	posted := make(chan map[string]interface{}, 1)
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		posted <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer discord.Close()
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("embeds@example.com", "hashed")
+	database.CreateCredential(user.ID, "discord", discord.URL)
+
+	workflow, err := database.CreateWorkflow(user.ID, "Orders", "webhook", "discord_post", `{
+		"discord_message": "New order {{order.id}}",
+		"discord_username": "{{shop}} Bot",
+		"discord_embed": {"title": "Order {{order.id}}", "color": 5793266, "footer": "{{shop}}", "timestamp": "{{order.placed_at}}",
+			"fields": [{"name": "Total", "value": "{{order.total | number_format:2}}", "inline": true}]}
+	}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow,
+		`{"shop": "Acme", "order": {"id": 42, "total": 1234.5, "placed_at": "2026-10-17T09:00:00Z"}}`)
+
+	if execution, err := database.GetLatestExecution(workflow.ID); err != nil || execution.Status != "success" {
+		t.Fatalf("Expected the post to succeed, got %+v, %v", execution, err)
+	}
+	body := <-posted
+	embed := body["embeds"].([]interface{})[0].(map[string]interface{})
+	field := embed["fields"].([]interface{})[0].(map[string]interface{})
+	if body["content"] != "New order 42" || body["username"] != "Acme Bot" || embed["title"] != "Order 42" ||
+		embed["timestamp"] != "2026-10-17T09:00:00Z" || embed["footer"].(map[string]interface{})["text"] != "Acme" || field["value"] != "1,234.50" {
+		t.Errorf("Expected the payload rendered into the message, got %v", body)
+	}
+}
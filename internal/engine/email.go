@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// executeEmailAction sends an email through the user's SMTP server, rendering the
+// recipients, subject and bodies against the trigger payload
+func (e *Executor) executeEmailAction(ctx context.Context, userID, tenantID string, config models.WorkflowConfig, triggerPayload string) connectors.Result {
+	select {
+	case <-ctx.Done():
+		return connectors.NewCancelledResult(ctx.Err().Error())
+	default:
+	}
+	start := time.Now()
+
+	cred, err := e.getCredential(ctx, userID, tenantID, credentialName(config, "smtp"))
+	if err != nil {
+		e.log.Error("SMTP credentials not found", map[string]interface{}{
+			"user_id":   userID,
+			"tenant_id": tenantID,
+			"error":     err.Error(),
+		})
+		return credentialErrorResult("SMTP", err)
+	}
+	smtpConnector, err := connectors.ParseSMTPCredential(cred.DecryptedKey)
+	if err != nil {
+		return connectors.NewFailureResult(fmt.Sprintf("Invalid SMTP credentials format: %v", err), start)
+	}
+
+	emailConfig := connectors.SMTPConfig{
+		To:       append([]string{}, config.EmailTo...),
+		Subject:  config.EmailSubject,
+		Body:     config.EmailBody,
+		HTMLBody: config.EmailHTMLBody,
+	}
+	if triggerPayload != "" {
+		fields := []*string{&emailConfig.Subject, &emailConfig.Body, &emailConfig.HTMLBody}
+		for i := range emailConfig.To {
+			fields = append(fields, &emailConfig.To[i])
+		}
+		if err := e.renderTemplates(triggerPayload, fields...); err != nil {
+			return connectors.NewFailureResult(fmt.Sprintf("Failed to render template: %v", err), start)
+		}
+	}
+
+	if IsSandbox(ctx) {
+		recipients, err := connectors.ParseEmailRecipients(emailConfig.To)
+		if err != nil {
+			return connectors.NewFailureResult(fmt.Sprintf("Email recipient rejected: %v", err), start)
+		}
+		return sandboxResult("Email not sent", map[string]interface{}{
+			"recipients": recipients,
+			"subject":    emailConfig.Subject,
+			"body":       emailConfig.Body,
+		})
+	}
+
+	return smtpConnector.ExecuteWithContext(ctx, emailConfig)
+}
@@ -3,14 +3,21 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/costs"
+	"github.com/alexmacdonald/simple-ipass/internal/crypto"
 	"github.com/alexmacdonald/simple-ipass/internal/db"
 	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
 	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/metrics"
 	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/notify"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
 	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/alexmacdonald/simple-ipass/internal/version"
 )
 
 // Executor handles workflow execution with structured logging
@@ -20,6 +27,22 @@ type Executor struct {
 	log            *logger.Logger
 	pool           *WorkerPool       // Bounded concurrency
 	templateEngine *utils.TemplateEngine // Dynamic field mapping
+	costs          *costs.Table          // Per-connector unit costs for spend estimates
+	limits         *serviceLimiter       // Per-action-type outbound rate limits
+	branches       *branchLimiter        // Slots shared by all parallel chain branches
+	versions       *versionStats         // Executions per connector version
+	notifications  *notificationThrottle // Per-workflow outbound notification windows
+	anomalies      *anomalyReports       // When each workflow's anomalies were last reported
+	notifier       *notify.Notifier      // Optional: anomaly alerts to tenant members
+	breakers       *CircuitBreakerManager // Per user and action type: stop calling a failing connector
+	metrics        *metrics.Collector     // Run counts, durations and queue length for /metrics
+	logStream      *LogBroadcaster        // New log entries for GET /api/logs/stream
+	salesforceRefreshes *userLocks        // One Salesforce token refresh per user at a time
+	runs           *runGuard             // Workflows with a run in flight (see allow_concurrent)
+	connectors     *connectors.Registry  // Action types and how to run them
+	clock          func() time.Time      // Stamps when runs start and the scheduler's due checks
+
+	maxTestingDelay int64 // Cap on the testing connector's delay in nanoseconds (read atomically)
 }
 
 // NewExecutor creates a new executor
@@ -28,28 +51,166 @@ func NewExecutor(store db.Store, log *logger.Logger) *Executor {
 	pool := NewWorkerPool(10, log)
 	pool.Start()
 
-	return &Executor{
+	return newExecutor(store, log, pool)
+}
+
+// NewStandbyExecutor creates an executor whose worker pool never starts, for a read-only
+// instance: it reports on workers and connectors but never runs a workflow
+func NewStandbyExecutor(store db.Store, log *logger.Logger) *Executor {
+	return newExecutor(store, log, NewWorkerPool(10, log))
+}
+
+// NewExecutorWithPool creates an executor running workflows on the given pool, which the
+// caller starts (tests use a small pool to fill its queue)
+func NewExecutorWithPool(store db.Store, log *logger.Logger, pool *WorkerPool) *Executor {
+	return newExecutor(store, log, pool)
+}
+
+func newExecutor(store db.Store, log *logger.Logger, pool *WorkerPool) *Executor {
+	e := &Executor{
 		store:          store,
 		log:            log,
 		pool:           pool,
 		templateEngine: utils.NewTemplateEngine(),
+		costs:          costs.DefaultTable(),
+		limits:         newServiceLimiter(),
+		branches:       newBranchLimiter(10),
+		versions:       newVersionStats(),
+		notifications:  newNotificationThrottle(),
+		anomalies:      newAnomalyReports(),
+		breakers:       NewCircuitBreakerManager(),
+		metrics:        metrics.NewCollector(),
+		logStream:      NewLogBroadcaster(),
+		salesforceRefreshes: newUserLocks(),
+		runs:           newRunGuard(),
+		clock:          time.Now,
+
+		maxTestingDelay: int64(DefaultMaxTestingDelay),
+	}
+	e.connectors = e.newConnectorRegistry()
+	pool.OnDrop(e.deadLetter)
+	e.metrics.WatchQueueLength(pool.QueueLength)
+	return e
+}
+
+// Metrics returns the collector served at /metrics
+func (e *Executor) Metrics() *metrics.Collector {
+	return e.metrics
+}
+
+// SetClock replaces the clock used to stamp runs and schedule the next one, which the
+// scheduler also checks due workflows against (tests use a fake clock)
+func (e *Executor) SetClock(clock func() time.Time) {
+	e.clock = clock
+}
+
+// SetCostTable replaces the unit costs used to estimate connector spend
+func (e *Executor) SetCostTable(table *costs.Table) {
+	e.costs = table
+}
+
+// ResizeWorkerPool changes the number of concurrent executions without a restart
+func (e *Executor) ResizeWorkerPool(workers int) {
+	e.pool.Resize(workers)
+	if workers >= 1 {
+		e.branches.resize(workers, 0)
 	}
 }
 
 // ExecuteWorkflow runs a workflow asynchronously via worker pool
 // PRODUCTION: Uses bounded concurrency instead of unbounded goroutines
-func (e *Executor) ExecuteWorkflow(workflow models.Workflow) {
+// payload is the trigger payload of this execution ("" for scheduled runs); it travels
+// with the job so concurrent triggers of one workflow never see each other's data
+func (e *Executor) ExecuteWorkflow(workflow models.Workflow, payload string) {
+	e.ExecuteWorkflowForRequest(workflow, payload, "")
+}
+
+// ExecuteWorkflowForRequest is ExecuteWorkflow for a run triggered by an API request:
+// requestID travels with the job, so the run's log entries carry it even though it
+// finishes after the response was sent
+func (e *Executor) ExecuteWorkflowForRequest(workflow models.Workflow, payload, requestID string) {
 	// Submit to worker pool instead of spawning goroutine directly
 	e.pool.Submit(WorkflowJob{
-		Workflow: workflow,
-		Executor: e,
+		Workflow:  workflow,
+		Payload:   payload,
+		Executor:  e,
+		RequestID: requestID,
+	})
+}
+
+// TryExecuteWorkflowForRequest is ExecuteWorkflowForRequest for a caller whose sender
+// retries: a run the pool can't queue is returned as an error instead of dead-lettered
+func (e *Executor) TryExecuteWorkflowForRequest(workflow models.Workflow, payload, requestID string) error {
+	var dropped error
+	e.pool.Submit(WorkflowJob{
+		Workflow:  workflow,
+		Payload:   payload,
+		Executor:  e,
+		RequestID: requestID,
+		dropped:   func(reason string) { dropped = errors.New(reason) }, // Called before Submit returns
 	})
+	return dropped
 }
 
 // ExecuteWorkflowWithContext runs a workflow with context awareness
 // PRODUCTION: Respects cancellation and timeouts
-func (e *Executor) ExecuteWorkflowWithContext(ctx context.Context, workflow models.Workflow) {
-	tenantID := "tenant_" + workflow.UserID
+func (e *Executor) ExecuteWorkflowWithContext(ctx context.Context, workflow models.Workflow, payload string) {
+	e.execute(ctx, workflow, payload)
+}
+
+// ExecuteWorkflowSync runs a triggered workflow on the worker pool and waits for its
+// result, for triggers that answer with it. Like any other run it takes a worker and
+// counts against its tenant's jobs in flight. The run is stopped when ctx (the triggering
+// request) ends, after wait, or at the workflow's own timeout if that is shorter;
+// timedOut reports it was stopped by one of those limits
+// requestID is the triggering request's X-Request-ID, recorded on the run's log entry
+func (e *Executor) ExecuteWorkflowSync(ctx context.Context, workflow models.Workflow, payload, requestID string, wait time.Duration) (result connectors.Result, timedOut bool) {
+	limit := workflowTimeout(workflow, wait)
+	if limit.limit > wait {
+		limit = runTimeout{limit: wait}
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, limit.limit)
+	defer cancel()
+	deadline, _ := waitCtx.Deadline()
+
+	started := make(chan struct{})
+	done := make(chan connectors.Result, 1)
+	e.pool.Submit(WorkflowJob{
+		Workflow:  workflow,
+		Payload:   payload,
+		Executor:  e,
+		RequestID: requestID,
+		Run: func(jobCtx context.Context) {
+			close(started)
+			runCtx, cancelRun := context.WithDeadline(context.WithValue(jobCtx, runTimeoutKey{}, limit), deadline)
+			defer cancelRun()
+			stop := context.AfterFunc(ctx, cancelRun) // The caller went away
+			defer stop()
+			done <- e.execute(runCtx, workflow, payload)
+		},
+		dropped: func(reason string) {
+			done <- connectors.Result{Status: "cancelled", Message: "Workflow not run: " + reason, ErrorCode: connectors.ErrCodeCancelled}
+		},
+	})
+
+	select {
+	case result = <-done:
+	case <-waitCtx.Done():
+		select {
+		case <-started:
+			result = <-done // Its context ended too, so it stops promptly
+		default:
+			result = connectors.Result{Status: "cancelled", Message: "Workflow still queued when the wait ran out", ErrorCode: connectors.ErrCodeCancelled}
+		}
+	}
+	// The run shares the wait's deadline and can report back the moment before waitCtx does
+	stoppedAtDeadline := ctx.Err() == nil && result.ErrorCode == connectors.ErrCodeCancelled && !time.Now().Before(deadline)
+	return result, errors.Is(waitCtx.Err(), context.DeadlineExceeded) || stoppedAtDeadline
+}
+
+// execute runs a workflow, records its log, trace and stats, and returns the result
+func (e *Executor) execute(ctx context.Context, workflow models.Workflow, payload string) connectors.Result {
+	tenantID := workflow.TenantID
 
 	// Check if context is already cancelled
 	select {
@@ -64,11 +225,21 @@ func (e *Executor) ExecuteWorkflowWithContext(ctx context.Context, workflow mode
 				"reason": ctx.Err().Error(),
 			},
 		)
-		return
+		return connectors.Result{Status: "cancelled", Message: "Workflow cancelled before execution", ErrorCode: connectors.ErrCodeCancelled}
 	default:
 	}
 
-	e.log.WorkflowLog(
+	// One run of a workflow at a time unless its config allows more
+	if !allowsConcurrentRuns(workflow) {
+		release, since, ok := e.runs.acquire(workflow, e.clock())
+		if !ok {
+			return e.skipOverlap(ctx, workflow, payload, tenantID, since)
+		}
+		defer release()
+	}
+
+	e.log.WorkflowLogContext(
+		ctx,
 		logger.LevelInfo,
 		"Executing workflow",
 		workflow.ID,
@@ -81,15 +252,23 @@ func (e *Executor) ExecuteWorkflowWithContext(ctx context.Context, workflow mode
 		},
 	)
 
-	// Update last executed time
-	e.store.UpdateWorkflowLastExecuted(workflow.ID, time.Now())
+	// Done before running, so a run outlasting the scheduler tick isn't started twice
+	e.advanceSchedule(ctx, workflow)
 
 	// Execute with context awareness
-	result := e.executeWorkflowInternal(ctx, workflow, workflow.UserID, tenantID)
-
-	// Only log if context wasn't cancelled
+	start := time.Now()
+	result := e.executeWorkflowInternal(ctx, workflow, payload, workflow.UserID, tenantID)
+	codeFailure(&result, workflow.ActionType)
+	duration := time.Since(start)
+	e.metrics.ObserveExecution(workflow.ActionType, result.Status, duration)
+
+	// Only log if context wasn't cancelled; a run stopped by its own timeout is recorded
+	done := ctx.Done()
+	if explainRunTimeout(ctx, &result) {
+		done = nil
+	}
 	select {
-	case <-ctx.Done():
+	case <-done:
 		e.log.WorkflowLog(
 			logger.LevelWarn,
 			"Workflow execution cancelled",
@@ -101,18 +280,153 @@ func (e *Executor) ExecuteWorkflowWithContext(ctx context.Context, workflow mode
 				"partial_result": result.Status,
 			},
 		)
-		return
+		// Spend and simulated delay already incurred still count against the tenant
+		e.recordUsage(workflow, tenantID, result)
+		return result
 	default:
 		// Log to database
-		e.store.CreateLog(workflow.ID, result.Status, result.Message)
+		durationMS := duration.Milliseconds()
+		e.createLog(workflow, &models.Log{
+			WorkflowID:  workflow.ID,
+			Status:      result.Status,
+			Message:     result.Message,
+			ErrorCode:   result.ErrorCode,
+			ErrorParams: result.ErrorParams,
+			RequestID:   logger.RequestIDFromContext(ctx),
+			DurationMS:  &durationMS,
+		})
+
+		// Keep a masked trace so later dry runs can be compared against this run
+		e.recordExecution(ctx, workflow, payload, tenantID, result, duration)
+
+		// Keep the list-view counters in step with the log
+		if err := e.store.RecordWorkflowExecution(workflow.ID, result.Status, duration, result.Message, time.Now()); err != nil {
+			e.log.WorkflowLog(
+				logger.LevelWarn,
+				"Failed to record workflow stats",
+				workflow.ID,
+				workflow.UserID,
+				tenantID,
+				map[string]interface{}{
+					"error": err.Error(),
+				},
+			)
+		} else if result.Status != models.StatusSuppressed {
+			e.checkAnomalies(workflow, tenantID, time.Now())
+			e.checkFailureAlert(workflow, tenantID, result, time.Now())
+		}
+
+		e.versions.record(workflow.ActionType, result.ConnectorVersion, result.Status)
+		e.recordUsage(workflow, tenantID, result)
+	}
+	return result
+}
+
+// advanceSchedule updates the last executed time and moves a scheduled workflow to its next slot
+// A manual run of a scheduled workflow leaves its schedule alone unless asked not to
+func (e *Executor) advanceSchedule(ctx context.Context, workflow models.Workflow) {
+	manual, isManual := manualRunFrom(ctx)
+	if !isManual || manual.countAsScheduled || workflow.TriggerType != "schedule" {
+		ranAt := e.clock()
+		e.store.UpdateWorkflowLastExecuted(workflow.ID, ranAt, workflow.NextRunAfter(&ranAt))
+	}
+}
+
+// recordExecution stores a run's masked trace, under the ID reserved for a manual run
+func (e *Executor) recordExecution(ctx context.Context, workflow models.Workflow, payload, tenantID string, result connectors.Result, duration time.Duration) {
+	execution := &models.Execution{
+		WorkflowID:     workflow.ID,
+		Status:         result.Status,
+		Message:        utils.Mask(result.Message),
+		TriggerSource:  workflow.TriggerType,
+		TriggerPayload: MaskPayload(payload),
+		ResultData:     encodeTrace(result),
+		DurationMS:     duration.Milliseconds(),
+		Version:        version.Version,
+		InstanceID:     version.InstanceID(),
+		CatchUp:        IsCatchUp(ctx),
+	}
+	if manual, isManual := manualRunFrom(ctx); isManual {
+		execution.ID = manual.executionID
+		execution.TriggerSource = TriggerManual
+	}
+	if err := e.store.CreateExecution(execution); err != nil {
+		e.log.WorkflowLog(
+			logger.LevelWarn,
+			"Failed to record execution trace",
+			workflow.ID,
+			workflow.UserID,
+			tenantID,
+			map[string]interface{}{
+				"error": err.Error(),
+			},
+		)
+	}
+}
+
+// recordUsage rolls estimated spend and simulated delay into the tenant's monthly usage
+func (e *Executor) recordUsage(workflow models.Workflow, tenantID string, result connectors.Result) {
+	if err := e.store.RecordWorkflowCost(tenantID, workflow.ID, costs.Month(time.Now()), workflowCost(result), workflowDelay(result)); err != nil {
+		e.log.WorkflowLog(
+			logger.LevelWarn,
+			"Failed to record workflow cost",
+			workflow.ID,
+			workflow.UserID,
+			tenantID,
+			map[string]interface{}{
+				"error": err.Error(),
+			},
+		)
+	}
+}
+
+// workflowCost sums the estimated cost of the primary action and every chained action
+func workflowCost(result connectors.Result) float64 {
+	total := result.Cost
+	if chainResults, ok := result.Data["chain_results"].([]connectors.Result); ok {
+		for _, chainResult := range chainResults {
+			total += chainResult.Cost
+		}
+	}
+	return total
+}
+
+// workflowDelay sums the simulated delay of the primary action and every chained action
+func workflowDelay(result connectors.Result) int64 {
+	total := result.DelayMS
+	if chainResults, ok := result.Data["chain_results"].([]connectors.Result); ok {
+		for _, chainResult := range chainResults {
+			total += chainResult.DelayMS
+		}
+	}
+	return total
+}
+
+// codeFailure gives a failed or cancelled result an error code when the connector didn't,
+// so user-facing surfaces never have to show the technical message
+func codeFailure(result *connectors.Result, actionType string) {
+	if result.ErrorCode != "" {
+		return
+	}
+	switch result.Status {
+	case "failed":
+		result.ErrorCode = connectors.ErrCodeActionFailed
+		result.ErrorParams = map[string]string{"action": actionType}
+	case "cancelled":
+		result.ErrorCode = connectors.ErrCodeCancelled
 	}
 }
 
 // DryRun executes a workflow synchronously without saving to database
 // PRODUCT FEATURE: Test integration before committing
 func (e *Executor) DryRun(workflow models.Workflow, userID, tenantID string) connectors.Result {
+	return e.DryRunWithPayload(workflow, "", userID, tenantID)
+}
+
+// DryRunWithPayload is DryRun with a trigger payload (e.g. a saved fixture) for template mapping
+func (e *Executor) DryRunWithPayload(workflow models.Workflow, payload, userID, tenantID string) connectors.Result {
 	// Use background context with timeout for dry runs
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := withRunTimeout(withDryRun(context.Background()), workflow, DryRunTimeout)
 	defer cancel()
 
 	e.log.WorkflowLog(
@@ -128,7 +442,11 @@ func (e *Executor) DryRun(workflow models.Workflow, userID, tenantID string) con
 	)
 
 	// Execute synchronously (blocking for immediate response)
-	result := e.executeWorkflowInternal(ctx, workflow, userID, tenantID)
+	start := time.Now()
+	result := e.executeWorkflowInternal(ctx, workflow, payload, userID, tenantID)
+	codeFailure(&result, workflow.ActionType)
+	explainRunTimeout(ctx, &result)
+	e.metrics.ObserveExecution(workflow.ActionType, result.Status, time.Since(start))
 
 	// Log result (but NOT to database - it's a test!)
 	logLevel := logger.LevelInfo
@@ -154,7 +472,7 @@ func (e *Executor) DryRun(workflow models.Workflow, userID, tenantID string) con
 
 // executeWorkflowInternal contains the core execution logic with context awareness
 // PRODUCTION: Respects context cancellation throughout execution
-func (e *Executor) executeWorkflowInternal(ctx context.Context, workflow models.Workflow, userID, tenantID string) connectors.Result {
+func (e *Executor) executeWorkflowInternal(ctx context.Context, workflow models.Workflow, payload, userID, tenantID string) connectors.Result {
 	start := time.Now()
 
 	// Check context before parsing
@@ -179,6 +497,7 @@ func (e *Executor) executeWorkflowInternal(ctx context.Context, workflow models.
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		}
 	}
+	ctx = withCredentialScope(ctx, workflow, config)
 
 	// Check context before executing action
 	select {
@@ -192,49 +511,45 @@ func (e *Executor) executeWorkflowInternal(ctx context.Context, workflow models.
 	default:
 	}
 
-	// Execute the action based on action type
-	var result connectors.Result
-
-	switch workflow.ActionType {
-	case "slack_message":
-		result = e.executeSlackAction(ctx, userID, tenantID, config, workflow.TriggerPayload)
-	case "discord_post":
-		result = e.executeDiscordAction(ctx, userID, tenantID, config, workflow.TriggerPayload)
-	case "twilio_sms":
-		result = e.executeTwilioAction(ctx, userID, tenantID, config, workflow.TriggerPayload)
-	case "news_fetch":
-		result = e.executeNewsAPIAction(ctx, userID, tenantID, config)
-	case "cat_fetch":
-		result = e.executeCatAPIAction(ctx, userID, tenantID, config)
-	case "fakestore_fetch":
-		result = e.executeFakeStoreAction(ctx, userID, tenantID, config)
-	case "weather_check":
-		result = e.executeWeatherAction(ctx, userID, tenantID, config)
-	case "soap_call":
-		result = e.executeSOAPAction(ctx, userID, tenantID, config)
-	case "swapi_fetch":
-		result = e.executeSWAPIAction(ctx, userID, tenantID, config)
-	case "salesforce":
-		result = e.executeSalesforceAction(ctx, userID, tenantID, config)
-	case "testing":
-		result = e.executeTestingAction(ctx, userID, tenantID, config, workflow.TriggerPayload)
-	default:
-		result = connectors.Result{
+	// Pick the connector version (workflow pin, then the tenant's canary, then current)
+	connectorVersion, err := e.connectorVersion(workflow, tenantID, config)
+	if err != nil {
+		return connectors.Result{
 			Status:    "failed",
-			Message:   fmt.Sprintf("Unknown action type: %s", workflow.ActionType),
+			Message:   err.Error(),
 			Duration:  time.Since(start).String(),
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		}
 	}
+	config.ConnectorVersion = connectorVersion
+
+	// Execute the action based on action type
+	var result connectors.Result
+
+	if suppressed := e.throttleNotification(ctx, workflow, config, payload, tenantID, time.Now()); suppressed != nil {
+		suppressed.Duration = time.Since(start).String()
+		return *suppressed
+	}
+
+	if throttled := e.throttle(ctx, workflow.ActionType); throttled != nil {
+		throttled.Duration = time.Since(start).String()
+		return *throttled
+	}
+
+	result = e.dispatchAction(ctx, workflow.ActionType, userID, tenantID, config, []byte(workflow.ConfigJSON), payload)
 
 	// Add total duration if not already set
 	if result.Duration == "" {
 		result.Duration = time.Since(start).String()
 	}
+	result.ConnectorVersion = connectorVersion
+
+	result.Cost = e.costs.Estimate(workflow.ActionType, result)
+	applyAssertions(&result, config.Assertions)
 
 	// Execute action chain if present
 	if workflow.ActionChain != "" {
-		chainResults := e.executeActionChain(ctx, workflow.ActionChain, userID, tenantID, result)
+		chainResults := e.executeActionChain(ctx, workflow.ActionChain, userID, tenantID, result, time.Duration(config.BudgetSeconds)*time.Second)
 		
 		// Append chain results to primary result
 		if result.Data == nil {
@@ -257,7 +572,9 @@ func (e *Executor) executeWorkflowInternal(ctx context.Context, workflow models.
 }
 
 // executeActionChain executes a sequence of chained actions
-func (e *Executor) executeActionChain(ctx context.Context, actionChainJSON, userID, tenantID string, previousResult connectors.Result) []connectors.Result {
+// With a budget, each step gets whatever is left of it (or its own timeout, if smaller),
+// and steps that can't start once the budget is spent are reported as not_started
+func (e *Executor) executeActionChain(ctx context.Context, actionChainJSON, userID, tenantID string, previousResult connectors.Result, budget time.Duration) []connectors.Result {
 	// Parse action chain
 	var chainedActions []models.ChainedAction
 	if err := json.Unmarshal([]byte(actionChainJSON), &chainedActions); err != nil {
@@ -270,8 +587,14 @@ func (e *Executor) executeActionChain(ctx context.Context, actionChainJSON, user
 
 	results := make([]connectors.Result, 0, len(chainedActions))
 	currentData := previousResult.Data
+	chainStart := time.Now()
 
 	for i, chainedAction := range chainedActions {
+		if budget > 0 && time.Since(chainStart) >= budget {
+			results = append(results, notStartedResult(i+1, chainedAction.ActionType, budget))
+			continue
+		}
+
 		// Check context before each chained action
 		select {
 		case <-ctx.Done():
@@ -284,6 +607,13 @@ func (e *Executor) executeActionChain(ctx context.Context, actionChainJSON, user
 		default:
 		}
 
+		if len(chainedAction.Parallel) > 0 {
+			result := e.executeParallelStep(ctx, i+1, chainedAction, userID, tenantID, currentData, chainStart, budget)
+			results = append(results, result)
+			currentData = mergeStepData(currentData, result.Data)
+			continue
+		}
+
 		e.log.Info("Executing chained action", map[string]interface{}{
 			"action_type": chainedAction.ActionType,
 			"chain_step":  i + 1,
@@ -292,74 +622,115 @@ func (e *Executor) executeActionChain(ctx context.Context, actionChainJSON, user
 			"tenant_id":   tenantID,
 		})
 
-		// Prepare config for chained action
-		config := models.WorkflowConfig{}
-		
-		// Copy config from chained action
-		configBytes, _ := json.Marshal(chainedAction.Config)
-		json.Unmarshal(configBytes, &config)
-
-		// If "use_data_from" is "previous", inject previous result data
-		if chainedAction.UseDataFrom == "previous" && currentData != nil {
-			// Format data for template engine or direct use
-			if dataJSON, err := json.Marshal(currentData); err == nil {
-				// Use as trigger payload for template mapping
-				result := e.executeChainedActionWithData(ctx, chainedAction.ActionType, userID, tenantID, config, string(dataJSON))
-				results = append(results, result)
-				if result.Data != nil {
-					currentData = result.Data
-				}
-				continue
-			}
-		}
-
-		// Execute normal chained action
-		result := e.executeChainedAction(ctx, chainedAction.ActionType, userID, tenantID, config)
+		result := e.runChainedAction(ctx, chainedAction, userID, tenantID, currentData, chainStart, budget)
 		results = append(results, result)
-		if result.Data != nil {
-			currentData = result.Data
-		}
+		currentData = mergeStepData(currentData, result.Data)
 	}
 
 	return results
 }
 
+// mergeStepData layers a step's data over the data before it, so later steps still see what
+// earlier ones fetched (a Slack step's delivery status doesn't hide the articles before it)
+func mergeStepData(previous, step map[string]interface{}) map[string]interface{} {
+	if step == nil {
+		return previous
+	}
+	merged := make(map[string]interface{}, len(previous)+len(step))
+	for key, value := range previous {
+		merged[key] = value
+	}
+	for key, value := range step {
+		merged[key] = value
+	}
+	return merged
+}
+
+// runChainedAction runs one chained action within its time limits and checks its output
+// With use_data_from "previous", the previous step's data is the action's template payload
+func (e *Executor) runChainedAction(ctx context.Context, chainedAction models.ChainedAction, userID, tenantID string, previousData map[string]interface{}, chainStart time.Time, budget time.Duration) connectors.Result {
+	// Prepare config for chained action
+	config := models.WorkflowConfig{}
+
+	// Copy config from chained action
+	configBytes, _ := json.Marshal(chainedAction.Config)
+	json.Unmarshal(configBytes, &config)
+
+	// If "use_data_from" is "previous", the previous data is the trigger payload for template mapping
+	payload := ""
+	if chainedAction.UseDataFrom == "previous" && previousData != nil {
+		if dataJSON, err := json.Marshal(previousData); err == nil {
+			payload = string(dataJSON)
+		}
+	}
+	run := func(stepCtx context.Context) connectors.Result {
+		return e.executeChainedAction(stepCtx, chainedAction.ActionType, userID, tenantID, config, configBytes, payload)
+	}
+
+	result := e.executeChainStep(ctx, chainedAction, chainStart, budget, run)
+	result.Cost = e.costs.Estimate(chainedAction.ActionType, result)
+	applyAssertions(&result, config.Assertions)
+	codeFailure(&result, chainedAction.ActionType)
+	return result
+}
+
 // executeChainedAction executes a single action in the chain
-func (e *Executor) executeChainedAction(ctx context.Context, actionType, userID, tenantID string, config models.WorkflowConfig) connectors.Result {
-	switch actionType {
-	case "slack_message":
-		return e.executeSlackAction(ctx, userID, tenantID, config, "")
-	case "discord_post":
-		return e.executeDiscordAction(ctx, userID, tenantID, config, "")
-	case "twilio_sms":
-		return e.executeTwilioAction(ctx, userID, tenantID, config, "")
-	default:
+// triggerPayload is the previous step's data with use_data_from "previous", otherwise empty
+func (e *Executor) executeChainedAction(ctx context.Context, actionType, userID, tenantID string, config models.WorkflowConfig, rawConfig []byte, triggerPayload string) connectors.Result {
+	if throttled := e.throttle(ctx, actionType); throttled != nil {
+		return *throttled
+	}
+	return e.dispatchAction(ctx, actionType, userID, tenantID, config, rawConfig, triggerPayload)
+}
+
+// dispatchAction runs one action's connector through its circuit breaker, for the
+// workflow's own action and chain steps alike
+// rawConfig is decoded by the connector, which fails the step if it rejects it
+func (e *Executor) dispatchAction(ctx context.Context, actionType, userID, tenantID string, config models.WorkflowConfig, rawConfig []byte, payload string) connectors.Result {
+	start := time.Now()
+	connector, ok := e.connectors.Lookup(actionType)
+	if !ok {
 		return connectors.Result{
 			Status:    "failed",
-			Message:   fmt.Sprintf("Unsupported chained action type: %s", actionType),
+			Message:   fmt.Sprintf("Unknown action type: %s", actionType),
+			Duration:  time.Since(start).String(),
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		}
 	}
+	options, err := e.connectors.Decode(actionType, rawConfig)
+	if err != nil {
+		return connectors.NewFailureResult(fmt.Sprintf("Invalid config: %v", err), start)
+	}
+
+	ctx = connectors.WithCallOptions(ctx, connectorCallOptions(config))
+	return e.guarded(ctx, userID, actionType, func() connectors.Result {
+		return connector.Execute(ctx, connectors.Call{
+			UserID:   userID,
+			TenantID: tenantID,
+			Config:   config,
+			Options:  options,
+			Payload:  payload,
+		})
+	})
 }
 
-// executeChainedActionWithData executes a chained action with data from previous action
-func (e *Executor) executeChainedActionWithData(ctx context.Context, actionType, userID, tenantID string, config models.WorkflowConfig, previousData string) connectors.Result {
-	switch actionType {
-	case "slack_message":
-		return e.executeSlackAction(ctx, userID, tenantID, config, previousData)
-	case "discord_post":
-		return e.executeDiscordAction(ctx, userID, tenantID, config, previousData)
-	case "twilio_sms":
-		return e.executeTwilioAction(ctx, userID, tenantID, config, previousData)
-	default:
-		return connectors.Result{
-			Status:    "failed",
-			Message:   fmt.Sprintf("Unsupported chained action type: %s", actionType),
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
+// renderTemplates renders each field against the trigger payload, in place
+// An unknown filter fails the step instead of sending a half-rendered message
+func (e *Executor) renderTemplates(payload string, fields ...*string) error {
+	for _, field := range fields {
+		rendered, err := e.templateEngine.RenderStrict(*field, payload)
+		if err != nil {
+			return err
 		}
+		*field = rendered
 	}
+	return nil
 }
 
+// templateErrorResult reports a template that couldn't be rendered
+func templateErrorResult(err error) connectors.Result {
+	return connectors.NewFailureResult(fmt.Sprintf("Failed to render template: %v", err), time.Now())
+}
 
 // executeSlackAction sends a message to Slack with context awareness and dynamic templates
 func (e *Executor) executeSlackAction(ctx context.Context, userID, tenantID string, config models.WorkflowConfig, triggerPayload string) connectors.Result {
@@ -375,36 +746,49 @@ func (e *Executor) executeSlackAction(ctx context.Context, userID, tenantID stri
 	}
 
 	// Get Slack credentials
-	cred, err := e.store.GetCredentialByUserAndService(userID, "slack")
+	cred, err := e.getCredential(ctx, userID, tenantID, credentialName(config, "slack"))
 	if err != nil {
 		e.log.Error("Slack credentials not found", map[string]interface{}{
 			"user_id":   userID,
 			"tenant_id": tenantID,
 			"error":     err.Error(),
 		})
-		return connectors.Result{
-			Status:    "failed",
-			Message:   fmt.Sprintf("Slack not connected: %v", err),
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-		}
+		return credentialErrorResult("Slack", err)
 	}
 
-	slack := &connectors.SlackWebhook{
-		WebhookURL: cred.DecryptedKey,
-	}
+	// A bot token (xoxb-...) posts with chat.postMessage, anything else is a webhook URL
+	slack := connectors.NewSlackConnector(cred.DecryptedKey)
 
-	message := config.SlackMessage
-	if message == "" {
-		message = "Hello from GoFlow! 🚀"
+	message := connectors.SlackMessage{
+		Channel:  config.SlackChannel,
+		Text:     config.SlackMessage,
+		Blocks:   config.SlackBlocks,
+		ThreadTS: config.SlackThreadTS,
+	}
+	if message.Text == "" && len(message.Blocks) == 0 {
+		message.Text = "Hello from GoFlow! 🚀"
 	}
 
 	// Apply dynamic template mapping if trigger payload exists
 	if triggerPayload != "" {
-		message = e.templateEngine.Render(message, triggerPayload)
+		if err := e.renderTemplates(triggerPayload, &message.Text, &message.ThreadTS); err != nil {
+			return templateErrorResult(err)
+		}
+		if message.Blocks, err = e.renderSlackBlocks(message.Blocks, triggerPayload); err != nil {
+			return templateErrorResult(err)
+		}
+	}
+
+	if IsSandbox(ctx) {
+		data := map[string]interface{}{"message": message.Text}
+		if len(message.Blocks) > 0 {
+			data["blocks"] = message.Blocks
+		}
+		return sandboxResult("Slack message not sent", data)
 	}
 
 	// Execute with context (connector should respect cancellation)
-	return slack.ExecuteWithContext(ctx, message)
+	return slack.Send(ctx, message)
 }
 
 // executeDiscordAction sends a message to Discord
@@ -419,30 +803,53 @@ func (e *Executor) executeDiscordAction(ctx context.Context, userID, tenantID st
 	default:
 	}
 
-	cred, err := e.store.GetCredentialByUserAndService(userID, "discord")
+	cred, err := e.getCredential(ctx, userID, tenantID, credentialName(config, "discord"))
 	if err != nil {
 		e.log.Error("Discord credentials not found", map[string]interface{}{
 			"user_id":   userID,
 			"tenant_id": tenantID,
 			"error":     err.Error(),
 		})
-		return connectors.Result{
-			Status:    "failed",
-			Message:   fmt.Sprintf("Discord not connected: %v", err),
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-		}
+		return credentialErrorResult("Discord", err)
 	}
 
 	discord := &connectors.DiscordWebhook{
 		WebhookURL: cred.DecryptedKey,
 	}
 
-	message := config.DiscordMessage
-	if message == "" {
-		message = "Hello from iPaaS! 🎮"
+	message := connectors.DiscordMessage{
+		Content:   config.DiscordMessage,
+		Username:  config.DiscordUsername,
+		AvatarURL: config.DiscordAvatarURL,
+	}
+	if message.Content == "" && config.DiscordEmbed == nil {
+		message.Content = "Hello from iPaaS! 🎮"
+	}
+
+	// Apply dynamic template mapping if trigger payload exists
+	if triggerPayload != "" {
+		if err := e.renderTemplates(triggerPayload, &message.Content, &message.Username); err != nil {
+			return templateErrorResult(err)
+		}
+	}
+	if config.DiscordEmbed != nil {
+		embed, err := e.discordEmbed(*config.DiscordEmbed, triggerPayload)
+		if err != nil {
+			return templateErrorResult(err)
+		}
+		message.Embeds = []connectors.DiscordEmbed{embed}
 	}
 
-	return discord.Execute(message)
+	if IsSandbox(ctx) {
+		data := map[string]interface{}{"message": message.Content}
+		if len(message.Embeds) > 0 {
+			data["embeds"] = message.Embeds
+		}
+		return sandboxResult("Discord message not sent", data)
+	}
+
+	// Execute with context (connector should respect cancellation)
+	return discord.Send(ctx, message)
 }
 
 // executeWeatherAction fetches weather data
@@ -457,18 +864,14 @@ func (e *Executor) executeWeatherAction(ctx context.Context, userID, tenantID st
 	default:
 	}
 
-	cred, err := e.store.GetCredentialByUserAndService(userID, "openweather")
+	cred, err := e.getCredential(ctx, userID, tenantID, "openweather")
 	if err != nil {
 		e.log.Error("OpenWeather credentials not found", map[string]interface{}{
 			"user_id":   userID,
 			"tenant_id": tenantID,
 			"error":     err.Error(),
 		})
-		return connectors.Result{
-			Status:    "failed",
-			Message:   fmt.Sprintf("OpenWeather not connected: %v", err),
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-		}
+		return credentialErrorResult("OpenWeather", err)
 	}
 
 	weather := &connectors.OpenWeatherAPI{
@@ -496,26 +899,18 @@ func (e *Executor) executeTwilioAction(ctx context.Context, userID, tenantID str
 	}
 
 	// Get Twilio credentials
-	cred, err := e.store.GetCredentialByUserAndService(userID, "twilio")
+	cred, err := e.getCredential(ctx, userID, tenantID, credentialName(config, "twilio"))
 	if err != nil {
 		e.log.Error("Twilio credentials not found", map[string]interface{}{
 			"user_id":   userID,
 			"tenant_id": tenantID,
 			"error":     err.Error(),
 		})
-		return connectors.Result{
-			Status:    "failed",
-			Message:   fmt.Sprintf("Twilio not connected: %v", err),
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-		}
+		return credentialErrorResult("Twilio", err)
 	}
 
 	// Parse Twilio credentials from JSON
-	var twilioConfig struct {
-		AccountSID string `json:"account_sid"`
-		AuthToken  string `json:"auth_token"`
-		FromNumber string `json:"from_number"`
-	}
+	var twilioConfig twilioCredential
 	if err := json.Unmarshal([]byte(cred.DecryptedKey), &twilioConfig); err != nil {
 		return connectors.Result{
 			Status:    "failed",
@@ -532,14 +927,46 @@ func (e *Executor) executeTwilioAction(ctx context.Context, userID, tenantID str
 
 	// Prepare SMS config
 	smsConfig := connectors.TwilioConfig{
-		To:      config.TwilioTo,
-		Message: config.TwilioMessage,
+		Recipients:         append([]string{}, config.TwilioTo...),
+		Message:            config.TwilioMessage,
+		DefaultCountryCode: config.TwilioDefaultCountryCode,
+		Channel:            config.TwilioChannel,
+		StatusCallback:     config.TwilioStatusCallback,
 	}
 
 	// Apply dynamic template mapping
 	if triggerPayload != "" {
-		smsConfig.Message = e.templateEngine.Render(smsConfig.Message, triggerPayload)
-		smsConfig.To = e.templateEngine.Render(smsConfig.To, triggerPayload)
+		fields := []*string{&smsConfig.Message}
+		for i := range smsConfig.Recipients {
+			fields = append(fields, &smsConfig.Recipients[i])
+		}
+		if err := e.renderTemplates(triggerPayload, fields...); err != nil {
+			return templateErrorResult(err)
+		}
+	}
+
+	if IsSandbox(ctx) {
+		channel, recipients, err := connectors.PrepareTwilioRecipients(smsConfig)
+		if err != nil {
+			return connectors.Result{
+				Status:    "failed",
+				Message:   fmt.Sprintf("Twilio recipient rejected: %v", err),
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+		}
+		segments, encoding := connectors.SMSSegments(smsConfig.Message)
+		data := map[string]interface{}{
+			"message":  smsConfig.Message,
+			"channel":  channel,
+			"segments": segments,
+			"encoding": encoding,
+		}
+		if len(recipients) == 1 {
+			data["to"] = recipients[0]
+		} else {
+			data["recipients"] = recipients
+		}
+		return sandboxResult("SMS not sent", data)
 	}
 
 	return twilio.ExecuteWithContext(ctx, smsConfig)
@@ -558,18 +985,14 @@ func (e *Executor) executeNewsAPIAction(ctx context.Context, userID, tenantID st
 	}
 
 	// Get News API credentials
-	cred, err := e.store.GetCredentialByUserAndService(userID, "newsapi")
+	cred, err := e.getCredential(ctx, userID, tenantID, "newsapi")
 	if err != nil {
 		e.log.Error("News API credentials not found", map[string]interface{}{
 			"user_id":   userID,
 			"tenant_id": tenantID,
 			"error":     err.Error(),
 		})
-		return connectors.Result{
-			Status:    "failed",
-			Message:   fmt.Sprintf("News API not connected: %v", err),
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-		}
+		return credentialErrorResult("News API", err)
 	}
 
 	newsAPI := &connectors.NewsAPI{
@@ -581,6 +1004,12 @@ func (e *Executor) executeNewsAPIAction(ctx context.Context, userID, tenantID st
 		Country:  config.NewsCountry,
 		Category: config.NewsCategory,
 		PageSize: config.NewsPageSize,
+		From:     config.NewsFrom,
+		To:       config.NewsTo,
+		Language: config.NewsLanguage,
+		Sources:  config.NewsSources,
+		SortBy:   config.NewsSortBy,
+		SearchIn: config.NewsSearchIn,
 	}
 
 	return newsAPI.ExecuteWithContext(ctx, newsConfig)
@@ -600,9 +1029,11 @@ func (e *Executor) executeCatAPIAction(ctx context.Context, userID, tenantID str
 
 	// Cat API key is optional, but we'll check for it
 	var apiKey string
-	cred, err := e.store.GetCredentialByUserAndService(userID, "catapi")
+	cred, err := e.getCredential(ctx, userID, tenantID, "catapi")
 	if err == nil {
 		apiKey = cred.DecryptedKey
+	} else if errors.Is(err, ErrCredentialEnvironment) {
+		return credentialErrorResult("Cat API", err)
 	}
 
 	catAPI := &connectors.CatAPI{
@@ -655,9 +1086,15 @@ func (e *Executor) executeSOAPAction(ctx context.Context, userID, tenantID strin
 	default:
 	}
 
+	tlsSettings, err := e.loadTLSSettings(ctx, userID, tenantID, config.TLSCredential)
+	if err != nil {
+		return connectors.NewFailureResult(err.Error(), time.Now())
+	}
+
 	soapConnector := &connectors.SOAPConnector{
 		SOAPEndpoint: config.SOAPEndpoint,
 		SOAPAction:   config.SOAPAction,
+		TLS:          tlsSettings,
 	}
 
 	soapConfig := connectors.SOAPConfig{
@@ -667,11 +1104,101 @@ func (e *Executor) executeSOAPAction(ctx context.Context, userID, tenantID strin
 		Namespace:  config.SOAPNamespace,
 		Parameters: config.SOAPParameters,
 		Headers:    config.SOAPHeaders,
+		Version:    config.SOAPVersion,
+	}
+
+	if config.SOAPWSSecurity {
+		cred, err := e.getCredential(ctx, userID, tenantID, credentialName(config, "soap"))
+		if err != nil {
+			e.log.Error("SOAP credentials not found", map[string]interface{}{
+				"user_id":   userID,
+				"tenant_id": tenantID,
+				"error":     err.Error(),
+			})
+			return credentialErrorResult("SOAP", err)
+		}
+		security, err := connectors.ParseSOAPCredential(cred.DecryptedKey)
+		if err != nil {
+			return connectors.NewFailureResult(fmt.Sprintf("Invalid SOAP credentials format: %v", err), time.Now())
+		}
+		soapConfig.Security = security
+	}
+
+	// SOAP methods may have side effects we can't detect, so never call them from a sandbox
+	if IsSandbox(ctx) {
+		return sandboxResult("SOAP call not performed", map[string]interface{}{
+			"method":     soapConfig.Method,
+			"parameters": soapConfig.Parameters,
+		})
 	}
 
 	return soapConnector.ExecuteWithContext(ctx, soapConfig)
 }
 
+// getCredential loads a decrypted credential for the tenant running the workflow, raising
+// a security alert when the row belongs to another tenant or its ciphertext belongs to
+// another row (e.g. copied there with DB write access)
+// The credential matching the workflow's environment wins; one from the other environment
+// is used only as far as the tenant's credential policy allows
+func (e *Executor) getCredential(ctx context.Context, userID, tenantID, serviceName string) (*models.Credential, error) {
+	if tenantID == "" {
+		tenantID = models.DefaultTenantID(userID)
+	}
+	environment := PreferredCredentialEnvironment(credentialScopeFrom(ctx).environment, isDryRun(ctx))
+	cred, err := e.store.GetCredentialInEnvironment(tenantID, userID, serviceName, environment)
+	switch {
+	case errors.Is(err, crypto.ErrTenantMismatch):
+		e.log.Error("SECURITY ALERT: credential requested on behalf of another tenant", map[string]interface{}{
+			"alert":        "credential_tenant_mismatch",
+			"user_id":      userID,
+			"tenant_id":    tenantID,
+			"service_name": serviceName,
+		})
+	case errors.Is(err, crypto.ErrContextMismatch):
+		e.log.Error("SECURITY ALERT: credential ciphertext does not belong to its row", map[string]interface{}{
+			"alert":        "credential_context_mismatch",
+			"user_id":      userID,
+			"tenant_id":    tenantID,
+			"service_name": serviceName,
+		})
+	case store.IsLocked(err), store.IsUnavailable(err):
+		// Not a missing credential: the action fails, but the user has nothing to reconnect
+		e.log.Error("Credential store unavailable", map[string]interface{}{
+			"user_id":      userID,
+			"tenant_id":    tenantID,
+			"service_name": serviceName,
+			"error":        err.Error(),
+		})
+	}
+	if err == nil {
+		if envErr := e.checkCredentialEnvironment(ctx, cred, userID, tenantID); envErr != nil {
+			return nil, envErr
+		}
+	}
+	return cred, err
+}
+
+// loadTLSSettings fetches the TLS settings credential referenced by a config
+// Returns nil settings when the config doesn't reference one
+func (e *Executor) loadTLSSettings(ctx context.Context, userID, tenantID, credentialName string) (*connectors.TLSSettings, error) {
+	if credentialName == "" {
+		return nil, nil
+	}
+
+	cred, err := e.getCredential(ctx, userID, tenantID, credentialName)
+	if errors.Is(err, ErrCredentialEnvironment) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("TLS credential %q not found", credentialName)
+	}
+
+	settings, err := connectors.ParseTLSSettings(cred.DecryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("TLS credential %q is invalid: %v", credentialName, err)
+	}
+	return settings, nil
+}
+
 // executeSWAPIAction fetches Star Wars data from SWAPI
 func (e *Executor) executeSWAPIAction(ctx context.Context, userID, tenantID string, config models.WorkflowConfig) connectors.Result {
 	select {
@@ -690,6 +1217,11 @@ func (e *Executor) executeSWAPIAction(ctx context.Context, userID, tenantID stri
 		Resource: config.SWAPIResource,
 		ID:       config.SWAPIID,
 		Search:   config.SWAPISearch,
+		ListOptions: connectors.ListOptions{
+			Page:   config.SWAPIPage,
+			Limit:  config.SWAPILimit,
+			Fields: config.SWAPIFields,
+		},
 	}
 
 	return swapiConnector.ExecuteWithContext(ctx, swapiConfig)
@@ -708,22 +1240,20 @@ func (e *Executor) executeSalesforceAction(ctx context.Context, userID, tenantID
 	}
 
 	// Get Salesforce credentials
-	cred, err := e.store.GetCredentialByUserAndService(userID, "salesforce")
+	cred, err := e.getCredential(ctx, userID, tenantID, "salesforce")
 	if err != nil {
 		e.log.Error("Salesforce credentials not found", map[string]interface{}{
 			"user_id":   userID,
 			"tenant_id": tenantID,
 			"error":     err.Error(),
 		})
-		return connectors.Result{
-			Status:    "failed",
-			Message:   fmt.Sprintf("Salesforce not connected: %v", err),
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-		}
+		return credentialErrorResult("Salesforce", err)
 	}
 
 	// DecryptedKey should contain JSON with instance_url and access_token
 	// Format: {"instance_url": "https://...", "access_token": "..."}
+	// Optionally with client_id, client_secret, username, password, security_token and
+	// login_url, so an expired access token is renewed automatically
 	var sfCreds map[string]string
 	if err := json.Unmarshal([]byte(cred.DecryptedKey), &sfCreds); err != nil {
 		e.log.Error("Failed to parse Salesforce credentials", map[string]interface{}{
@@ -741,6 +1271,7 @@ func (e *Executor) executeSalesforceAction(ctx context.Context, userID, tenantID
 	salesforceConnector := &connectors.SalesforceConnector{
 		InstanceURL: sfCreds["instance_url"],
 		AccessToken: sfCreds["access_token"],
+		APIVersion:  connectors.SalesforceAPIVersion(config.ConnectorVersion),
 	}
 
 	// Override with config if provided
@@ -757,8 +1288,45 @@ func (e *Executor) executeSalesforceAction(ctx context.Context, userID, tenantID
 		Data:        config.SalesforceData,
 		InstanceURL: instanceURL,
 		AccessToken: sfCreds["access_token"],
+		FlattenRecords: config.SalesforceFlattenRecords,
+		RecordLimit:    config.SalesforceRecordLimit,
+	}
+
+	// Reads are safe to run for real; writes only report what they would have sent
+	if IsSandbox(ctx) && salesforceConfig.Operation != "query" && salesforceConfig.Operation != "get" {
+		return sandboxResult("Salesforce "+salesforceConfig.Operation+" not performed", map[string]interface{}{
+			"operation": salesforceConfig.Operation,
+			"object":    salesforceConfig.Object,
+			"record_id": salesforceConfig.RecordID,
+			"data":      salesforceConfig.Data,
+		})
 	}
 
+	result := salesforceConnector.ExecuteWithContext(ctx, salesforceConfig)
+	if !connectors.SalesforceSessionExpired(result) {
+		return result
+	}
+	if _, ok := salesforceAuth(sfCreds); !ok {
+		return result
+	}
+
+	// The token expired: renew it and retry the operation once
+	refreshed, err := e.refreshSalesforceToken(ctx, userID, tenantID, sfCreds["access_token"])
+	if err != nil {
+		e.log.Error("Salesforce token refresh failed", map[string]interface{}{
+			"user_id":   userID,
+			"tenant_id": tenantID,
+			"error":     err.Error(),
+		})
+		result.Message += fmt.Sprintf(" (token refresh failed: %v)", err)
+		return result
+	}
+	salesforceConnector.AccessToken = refreshed["access_token"]
+	salesforceConfig.AccessToken = refreshed["access_token"]
+	if config.SalesforceInstanceURL == "" {
+		salesforceConnector.InstanceURL = refreshed["instance_url"]
+		salesforceConfig.InstanceURL = refreshed["instance_url"]
+	}
 	return salesforceConnector.ExecuteWithContext(ctx, salesforceConfig)
 }
 
@@ -785,7 +1353,9 @@ func (e *Executor) executeTestingAction(ctx context.Context, userID, tenantID st
 
 	// Apply template mapping if trigger payload exists
 	if triggerPayload != "" {
-		responseJSON = e.templateEngine.Render(responseJSON, triggerPayload)
+		if err := e.renderTemplates(triggerPayload, &responseJSON); err != nil {
+			return templateErrorResult(err)
+		}
 	}
 
 	// Parse the JSON to ensure it's valid
@@ -804,9 +1374,20 @@ func (e *Executor) executeTestingAction(ctx context.Context, userID, tenantID st
 		}
 	}
 
-	// Simulate delay if configured
-	if config.TestingDelay > 0 {
-		time.Sleep(time.Duration(config.TestingDelay) * time.Millisecond)
+	// Simulate delay if configured, within the cap and interruptible by cancellation
+	var delayed time.Duration
+	if config.TestingDelay != 0 {
+		if err := e.checkTestingDelay(config.TestingDelay); err != nil {
+			return connectors.NewFailureResult(err.Error(), start)
+		}
+		waited, err := sleepContext(ctx, time.Duration(config.TestingDelay)*time.Millisecond)
+		delayed = waited
+		if err != nil {
+			result := connectors.NewCancelledResult(fmt.Sprintf("Testing delay interrupted after %dms: %v", waited.Milliseconds(), err))
+			result.Duration = time.Since(start).String()
+			result.DelayMS = waited.Milliseconds()
+			return result
+		}
 	}
 
 	// Get status code (default 200)
@@ -815,6 +1396,13 @@ func (e *Executor) executeTestingAction(ctx context.Context, userID, tenantID st
 		statusCode = 200
 	}
 
+	// Error statuses fail like a real connector would, so failure handling can be rehearsed
+	if statusCode >= 400 {
+		result := connectors.NewHTTPErrorResult("Testing", statusCode, fmt.Sprintf("Mock response returned error status %d", statusCode), start)
+		result.DelayMS = delayed.Milliseconds()
+		return result
+	}
+
 	// Log successful execution
 	e.log.WorkflowLog(
 		logger.LevelInfo,
@@ -835,10 +1423,12 @@ func (e *Executor) executeTestingAction(ctx context.Context, userID, tenantID st
 		Data:      responseData,
 		Duration:  time.Since(start).String(),
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		DelayMS:   delayed.Milliseconds(),
 	}
 }
 
-// Shutdown gracefully stops the executor
+// Shutdown gracefully stops the executor: queued and running workflows finish and
+// write their logs unless ctx ends first (see WorkerPool.Shutdown)
 func (e *Executor) Shutdown(ctx context.Context) error {
 	return e.pool.Shutdown(ctx)
 }
@@ -2,6 +2,7 @@ package engine_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/alexmacdonald/simple-ipass/internal/engine"
 	"github.com/alexmacdonald/simple-ipass/internal/logger"
 	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/version"
 )
 
 // TestExecutorWithMockStore demonstrates dependency injection with MockStore
@@ -56,6 +58,84 @@ func TestExecutorWithMockStore(t *testing.T) {
 	}
 }
 
+// TestExecutionStampedWithBuild verifies execution records carry the version and instance ID
+func TestExecutionStampedWithBuild(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("stamp@example.com", "hashed_password")
+	workflow, err := mockStore.CreateWorkflow(user.ID, "Stamped", "webhook", "testing", `{"testing_response_json": "{\"ok\": true}"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+
+	execution, err := mockStore.GetLatestExecution(workflow.ID)
+	if err != nil {
+		t.Fatalf("Expected an execution record: %v", err)
+	}
+	if execution.Version != version.Version || execution.InstanceID != version.InstanceID() || execution.InstanceID == "" {
+		t.Errorf("Expected execution stamped with %s/%s, got %s/%s", version.Version, version.InstanceID(), execution.Version, execution.InstanceID)
+	}
+}
+
+// TestExecutionRecordsDuration checks a run's execution row carries its trigger source
+// and duration in milliseconds
+func TestExecutionRecordsDuration(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("duration@example.com", "hashed_password")
+	workflow, err := mockStore.CreateWorkflow(user.ID, "Slow", "schedule", "testing", `{"testing_delay": 60, "testing_response_json": "{\"ok\": true}"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+
+	executions, err := mockStore.ListExecutions(workflow.ID, models.PageRequest{Limit: 10})
+	if err != nil || len(executions) != 1 {
+		t.Fatalf("Expected one execution, got %v (%v)", executions, err)
+	}
+	execution := executions[0]
+	if execution.Status != "success" || execution.TriggerSource != "schedule" || execution.DurationMS < 60 {
+		t.Errorf("Expected a successful scheduled run of at least 60ms, got %s/%s in %dms", execution.Status, execution.TriggerSource, execution.DurationMS)
+	}
+	if !strings.Contains(execution.ResultData, `"ok":true`) {
+		t.Errorf("Expected the result data in the execution, got %s", execution.ResultData)
+	}
+}
+
+// TestTemplateFiltersInResult renders filters into the result and fails the run on an unknown one
+func TestTemplateFiltersInResult(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+	user, _ := mockStore.CreateUser("filters@example.com", "hashed_password")
+
+	tests := []struct {
+		name, response, wantStatus, wantText string
+	}{
+		{"filters", `{\"greeting\": \"Hi {{user.name | default:friend | upper}}\"}`, "success", `"greeting":"Hi FRIEND"`},
+		{"unknown filter", `{\"greeting\": \"Hi {{user.name | shout}}\"}`, "failed", `unknown filter \"shout\"`},
+	}
+	for _, tt := range tests {
+		workflow, err := mockStore.CreateWorkflow(user.ID, tt.name, "webhook", "testing", `{"testing_response_json": "`+tt.response+`"}`)
+		if err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+		executor.ExecuteWorkflowWithContext(context.Background(), *workflow, `{"user": {}}`)
+
+		execution, err := mockStore.GetLatestExecution(workflow.ID)
+		if err != nil {
+			t.Fatalf("%s: expected an execution record: %v", tt.name, err)
+		}
+		if execution.Status != tt.wantStatus || !strings.Contains(execution.Message+execution.ResultData, tt.wantText) {
+			t.Errorf("%s: expected %s with %s, got %s: %s %s", tt.name, tt.wantStatus, tt.wantText, execution.Status, execution.Message, execution.ResultData)
+		}
+	}
+}
+
 // TestContextCancellation proves executor respects context
 func TestContextCancellation(t *testing.T) {
 	mockStore := db.NewMockStore()
@@ -72,7 +152,7 @@ func TestContextCancellation(t *testing.T) {
 	cancel() // Cancel immediately
 
 	// Execute with cancelled context
-	executor.ExecuteWorkflowWithContext(ctx, *workflow)
+	executor.ExecuteWorkflowWithContext(ctx, *workflow, "")
 
 	// Give goroutine time to process cancellation
 	time.Sleep(100 * time.Millisecond)
@@ -105,7 +185,7 @@ func TestWorkerPoolBoundedConcurrency(t *testing.T) {
 			ConfigJSON:  `{"slack_message":"test"}`,
 			IsActive:    true,
 		}
-		executor.ExecuteWorkflow(*workflow)
+		executor.ExecuteWorkflow(*workflow, "")
 	}
 
 	// Give worker pool time to process
@@ -136,3 +216,42 @@ func BenchmarkMockStoreVsRealDB(b *testing.B) {
 	// })
 }
 
+
+// TestExecuteWorkflowSyncOnPool checks a synchronous run takes a worker and counts as its
+// tenant's job in flight, and is stopped as soon as the triggering request goes away
+func TestExecuteWorkflowSyncOnPool(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	pool := engine.NewWorkerPool(1, testLogger)
+	pool.Start()
+	defer pool.Shutdown(context.Background())
+	executor := engine.NewExecutorWithPool(mockStore, testLogger, pool)
+
+	user, _ := mockStore.CreateUser("sync@example.com", "hashed")
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Slow bridge", "webhook", "testing", `{"testing_delay": 5000}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	type outcome struct {
+		status   string
+		timedOut bool
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, timedOut := executor.ExecuteWorkflowSync(ctx, *workflow, "", "", 10*time.Second)
+		done <- outcome{result.Status, timedOut}
+	}()
+
+	waitFor(t, "the run to take a worker", func() bool {
+		return executor.TenantInFlight()[workflow.TenantID] == 1 && len(pool.Status().Workers) == 1 && pool.Status().Workers[0].WorkflowID == workflow.ID
+	})
+	cancel()
+	select {
+	case got := <-done:
+		if got.status != "cancelled" || got.timedOut {
+			t.Errorf("Expected the run cancelled with the request, not timed out, got %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the run to stop when the request went away")
+	}
+	waitFor(t, "the tenant's slot to be released", func() bool { return len(executor.TenantInFlight()) == 0 })
+}
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// DefaultAlertCooldown is how long a workflow that keeps failing waits before alerting again
+const DefaultAlertCooldown = time.Hour
+
+// Failure alert limits
+const (
+	alertTimeout         = 30 * time.Second // Sending one alert or all clear
+	maxAlertErrorLength  = 300              // Of the last error quoted in an alert
+	failureAlertLogsPath = "/dashboard/logs?workflow_id=%s"
+)
+
+// alertChannels are the credential services failure alerts can go through, with the
+// action that sends them
+var alertChannels = map[string]string{
+	"slack":   "slack_message",
+	"discord": "discord_post",
+	"smtp":    "email_send",
+	"twilio":  "twilio_sms",
+}
+
+// alertAction returns the action that sends alerts through a channel ("slack:ops" is a Slack credential)
+func alertAction(channel string) (string, bool) {
+	service := strings.SplitN(channel, ":", 2)[0]
+	actionType, ok := alertChannels[service]
+	return actionType, ok
+}
+
+// alertCooldown returns how long a failing workflow waits before alerting again
+func alertCooldown(config models.WorkflowConfig) time.Duration {
+	if config.AlertCooldownMinutes <= 0 {
+		return DefaultAlertCooldown
+	}
+	return time.Duration(config.AlertCooldownMinutes) * time.Minute
+}
+
+// ValidateFailureAlerts rejects alert settings the executor couldn't send
+// Malformed JSON is left for the executor to report
+func ValidateFailureAlerts(configJSON string) error {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	if config.AlertAfterFailures < 0 {
+		return fmt.Errorf("alert_after_failures can't be negative")
+	}
+	if config.AlertCooldownMinutes < 0 {
+		return fmt.Errorf("alert_cooldown_minutes can't be negative")
+	}
+	if config.AlertAfterFailures == 0 {
+		return nil
+	}
+	if config.AlertChannel == "" {
+		return fmt.Errorf("alert_channel is required with alert_after_failures")
+	}
+	actionType, ok := alertAction(config.AlertChannel)
+	if !ok {
+		return fmt.Errorf("alert_channel must be a slack, discord, smtp or twilio credential")
+	}
+	if (actionType == "email_send" || actionType == "twilio_sms") && len(config.AlertTo) == 0 {
+		return fmt.Errorf("alert_to is required for %s alerts", config.AlertChannel)
+	}
+	return nil
+}
+
+// checkFailureAlert runs once a run is recorded in the workflow's stats: the run that makes
+// alert_after_failures failures in a row sends an alert, and later failures only alert
+// again after the cooldown. The first success after an alert ends it, with an all clear
+// if the workflow asks for one
+func (e *Executor) checkFailureAlert(workflow models.Workflow, tenantID string, result connectors.Result, now time.Time) {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(workflow.ConfigJSON), &config); err != nil || config.AlertAfterFailures <= 0 {
+		return
+	}
+	if _, ok := alertAction(config.AlertChannel); !ok {
+		return
+	}
+	logsPath := fmt.Sprintf(failureAlertLogsPath, workflow.ID)
+
+	if result.Status == "success" {
+		recovered, err := e.store.ClearFailureAlert(workflow.ID)
+		if err != nil || !recovered || !config.AlertOnRecovery {
+			return
+		}
+		e.sendFailureAlert(workflow, tenantID, config, "recovery",
+			fmt.Sprintf("GoFlow: workflow %q recovered", workflow.Name),
+			fmt.Sprintf("GoFlow: workflow %q is succeeding again. Logs: %s", workflow.Name, logsPath))
+		return
+	}
+
+	failures, due, err := e.store.ClaimFailureAlert(workflow.ID, config.AlertAfterFailures, alertCooldown(config), now)
+	if err != nil || !due {
+		return
+	}
+	lastError := utils.Mask(result.Message)
+	if len(lastError) > maxAlertErrorLength {
+		lastError = lastError[:maxAlertErrorLength-3] + "..."
+	}
+	e.sendFailureAlert(workflow, tenantID, config, "failing",
+		fmt.Sprintf("GoFlow: workflow %q is failing", workflow.Name),
+		fmt.Sprintf("GoFlow: workflow %q failed %d times in a row. Last error: %s. Logs: %s", workflow.Name, failures, lastError, logsPath))
+}
+
+// sendFailureAlert sends an alert or all clear through the workflow's alert channel
+// The text is sent as is: message fields are templates, so braces quoted from an error are broken up
+func (e *Executor) sendFailureAlert(workflow models.Workflow, tenantID string, config models.WorkflowConfig, kind, subject, message string) {
+	actionType, _ := alertAction(config.AlertChannel)
+	message = strings.ReplaceAll(message, "{{", "{ {")
+	subject = strings.ReplaceAll(subject, "{{", "{ {")
+
+	alert := models.WorkflowConfig{Credential: config.AlertChannel, Environment: config.Environment}
+	switch actionType {
+	case "slack_message":
+		alert.SlackMessage = message
+		if len(config.AlertTo) > 0 {
+			alert.SlackChannel = config.AlertTo[0]
+		}
+	case "discord_post":
+		alert.DiscordMessage = message
+	case "email_send":
+		alert.EmailTo = config.AlertTo
+		alert.EmailSubject = subject
+		alert.EmailBody = message
+	case "twilio_sms":
+		alert.TwilioTo = config.AlertTo
+		alert.TwilioMessage = message
+	}
+	raw, _ := json.Marshal(alert)
+
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+	ctx = withCredentialScope(ctx, workflow, alert)
+	result := e.dispatchAction(ctx, actionType, workflow.UserID, tenantID, alert, raw, "")
+
+	level, logMessage := logger.LevelInfo, "Sent failure alert"
+	if result.Status != "success" {
+		level, logMessage = logger.LevelWarn, "Failed to send failure alert"
+	}
+	e.log.WorkflowLog(
+		level,
+		logMessage,
+		workflow.ID,
+		workflow.UserID,
+		tenantID,
+		map[string]interface{}{
+			"kind":    kind,
+			"channel": config.AlertChannel,
+			"status":  result.Status,
+			"message": result.Message,
+		},
+	)
+}
@@ -0,0 +1,118 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestFailureAlert fails a workflow three times in a row and checks exactly one alert goes to
+// the stubbed Slack channel, then that recovery sends the all clear and re-arms the alert
+func TestFailureAlert(t *testing.T) {
+	var mu sync.Mutex
+	var posted []map[string]interface{}
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		posted = append(posted, body)
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer xoxb-alerts" {
+			t.Errorf("Expected the alert channel's credential, got %q", r.Header.Get("Authorization"))
+		}
+		io.WriteString(w, `{"ok": true, "channel": "C-ALERTS", "ts": "1700000000.000100"}`)
+	}))
+	defer slack.Close()
+	routeConnectorHost(t, "slack.com", slack.URL)
+	alerts := func() []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]interface{}(nil), posted...)
+	}
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("alerts@example.com", "hashed")
+	database.CreateCredential(user.ID, "slack:ops", "xoxb-alerts")
+
+	const alerting = `"alert_after_failures": 3, "alert_channel": "slack:ops", "alert_to": "C-ALERTS", "alert_on_recovery": true`
+	workflow, err := database.CreateWorkflow(user.ID, "Nightly sync", "webhook", "testing", `{"testing_status_code": 500, `+alerting+`}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	run := func(times int) {
+		for i := 0; i < times; i++ {
+			executor.ExecuteWorkflowWithContext(context.Background(), *workflow, `{}`)
+		}
+	}
+
+	run(2)
+	if sent := alerts(); len(sent) != 0 {
+		t.Fatalf("Expected no alert before the third failure, got %v", sent)
+	}
+	run(1)
+	sent := alerts()
+	if len(sent) != 1 {
+		t.Fatalf("Expected one alert after three failures, got %d", len(sent))
+	}
+	text, _ := sent[0]["text"].(string)
+	for _, want := range []string{`"Nightly sync"`, "3 times in a row", "500", "/dashboard/logs?workflow_id=" + workflow.ID} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in the alert, got %q", want, text)
+		}
+	}
+	if sent[0]["channel"] != "C-ALERTS" {
+		t.Errorf("Expected the alert in alert_to's channel, got %v", sent[0]["channel"])
+	}
+
+	// Within the cooldown, further failures stay quiet (a fifth would open the circuit breaker)
+	run(1)
+	if sent := alerts(); len(sent) != 1 {
+		t.Fatalf("Expected no new alert within the cooldown, got %d", len(sent))
+	}
+
+	// The first success sends the all clear, once
+	workflow.ConfigJSON = `{"testing_status_code": 200, ` + alerting + `}`
+	run(2)
+	sent = alerts()
+	if len(sent) != 2 || !strings.Contains(sent[1]["text"].(string), "succeeding again") {
+		t.Fatalf("Expected one all clear after recovery, got %v", sent)
+	}
+
+	// Recovery re-arms the alert
+	workflow.ConfigJSON = `{"testing_status_code": 500, ` + alerting + `}`
+	run(3)
+	if sent := alerts(); len(sent) != 3 {
+		t.Errorf("Expected a new alert after failing three times again, got %d", len(sent))
+	}
+}
+
+// TestValidateFailureAlerts rejects alert settings that couldn't be sent
+func TestValidateFailureAlerts(t *testing.T) {
+	cases := map[string]string{
+		`{"alert_after_failures": 3}`:                                                           "alert_channel is required",
+		`{"alert_after_failures": 3, "alert_channel": "newsapi"}`:                               "alert_channel must be",
+		`{"alert_after_failures": 3, "alert_channel": "smtp"}`:                                  "alert_to is required",
+		`{"alert_after_failures": -1}`:                                                          "can't be negative",
+		`{"alert_after_failures": 3, "alert_channel": "discord", "alert_cooldown_minutes": -5}`: "can't be negative",
+	}
+	for config, want := range cases {
+		if err := engine.ValidateFailureAlerts(config); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", config, want, err)
+		}
+	}
+	for _, config := range []string{`{}`, `{"alert_after_failures": 2, "alert_channel": "slack:ops"}`, `{"alert_after_failures": 2, "alert_channel": "twilio", "alert_to": "+15550100"}`} {
+		if err := engine.ValidateFailureAlerts(config); err != nil {
+			t.Errorf("%s: expected valid, got %v", config, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// maxFallbacks bounds how many alternate targets one step may try
+const maxFallbacks = 5
+
+// notificationServices are the actions that may declare fallbacks, with the service
+// name used in their error codes. Testing is included so a fallback setup can be
+// rehearsed with simulated failures before it guards a real alert
+var notificationServices = map[string]string{
+	"slack_message": "Slack",
+	"discord_post":  "Discord",
+	"twilio_sms":    "Twilio",
+	"testing":       "Testing",
+}
+
+// credentialName returns the credential a config selects for a service
+func credentialName(config models.WorkflowConfig, service string) string {
+	if config.Credential != "" {
+		return config.Credential
+	}
+	return service
+}
+
+// executeNotification runs a notification action, then its fallbacks in order while
+// attempts fail definitively. The step succeeds as soon as one target accepts, and the
+// result's FallbackLevel records which target that was
+func (e *Executor) executeNotification(ctx context.Context, actionType, userID, tenantID string, config models.WorkflowConfig, triggerPayload string) connectors.Result {
+	result := e.attemptNotification(ctx, actionType, userID, tenantID, config, triggerPayload)
+
+	for i, fallback := range config.Fallbacks {
+		if ctx.Err() != nil || !shouldFallBack(result, config.FallbackOnTimeout) {
+			break
+		}
+		fallbackType, fallbackConfig := fallbackTarget(actionType, config, fallback)
+
+		e.log.Warn("Notification failed, trying fallback", map[string]interface{}{
+			"action_type":    actionType,
+			"fallback_level": i + 1,
+			"fallback_type":  fallbackType,
+			"error_code":     result.ErrorCode,
+			"user_id":        userID,
+			"tenant_id":      tenantID,
+		})
+
+		if throttled := e.throttle(ctx, fallbackType); throttled != nil {
+			throttled.FallbackLevel = i + 1
+			return *throttled
+		}
+		result = e.attemptNotification(ctx, fallbackType, userID, tenantID, fallbackConfig, triggerPayload)
+		result.FallbackLevel = i + 1
+	}
+	return result
+}
+
+// attemptNotification makes one delivery attempt, within the per-attempt time limit if set
+// An attempt that runs out of its own time reports upstream_timeout rather than a cancellation,
+// since the run itself goes on
+func (e *Executor) attemptNotification(ctx context.Context, actionType, userID, tenantID string, config models.WorkflowConfig, triggerPayload string) connectors.Result {
+	start := time.Now()
+	attemptCtx := ctx
+	if config.FallbackAttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(config.FallbackAttemptTimeout)*time.Millisecond)
+		defer cancel()
+	}
+
+	var result connectors.Result
+	switch actionType {
+	case "slack_message":
+		result = e.executeSlackAction(attemptCtx, userID, tenantID, config, triggerPayload)
+	case "discord_post":
+		result = e.executeDiscordAction(attemptCtx, userID, tenantID, config, triggerPayload)
+	case "twilio_sms":
+		result = e.executeTwilioAction(attemptCtx, userID, tenantID, config, triggerPayload)
+	case "testing":
+		result = e.executeTestingAction(attemptCtx, userID, tenantID, config, triggerPayload)
+	default:
+		return connectors.NewFailureResult(fmt.Sprintf("Unsupported notification action type: %s", actionType), start)
+	}
+
+	if result.Status == "cancelled" && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		timedOut := connectors.NewErrorResult(connectors.ErrCodeUpstreamTimeout,
+			map[string]string{"service": notificationServices[actionType]},
+			fmt.Sprintf("%s attempt timed out after %dms", notificationServices[actionType], config.FallbackAttemptTimeout), start)
+		timedOut.DelayMS = result.DelayMS
+		return timedOut
+	}
+	return result
+}
+
+// shouldFallBack reports whether the next target may be tried after an attempt
+// Only definitive failures qualify: after a timeout the target may have delivered
+// anyway, so falling back risks a duplicate notification unless explicitly allowed
+func shouldFallBack(result connectors.Result, onTimeout bool) bool {
+	if result.Status != "failed" {
+		return false
+	}
+	switch result.ErrorCode {
+	case connectors.ErrCodeUpstreamHTTP, connectors.ErrCodeAuthFailed, connectors.ErrCodeUpstreamUnreachable, connectors.ErrCodeCredentialMissing, connectors.ErrCodeRateLimited:
+		return true
+	case connectors.ErrCodeUpstreamTimeout:
+		return onTimeout
+	}
+	return false
+}
+
+// fallbackTarget builds the action type and config for one fallback
+// The fallback inherits the primary's per-attempt time limit but never its fallbacks
+func fallbackTarget(actionType string, primary models.WorkflowConfig, fallback models.Fallback) (string, models.WorkflowConfig) {
+	config := primary
+	if fallback.Config != nil {
+		config = models.WorkflowConfig{}
+		configBytes, _ := json.Marshal(fallback.Config)
+		json.Unmarshal(configBytes, &config)
+	} else if fallback.ActionType != "" && fallback.ActionType != actionType {
+		// The primary's credential belongs to another service
+		config.Credential = ""
+	}
+	if fallback.ActionType != "" {
+		actionType = fallback.ActionType
+	}
+	if fallback.Credential != "" {
+		config.Credential = fallback.Credential
+	}
+
+	config.Fallbacks = nil
+	config.FallbackAttemptTimeout = primary.FallbackAttemptTimeout
+	return actionType, config
+}
+
+// ValidateFallbacks checks the fallbacks of a workflow's primary action and of every
+// chained action, so misconfigured targets are rejected when the workflow is saved
+// Malformed JSON is left for the executor to report
+func (e *Executor) ValidateFallbacks(actionType, configJSON, actionChainJSON string) error {
+	if configJSON != "" {
+		var config models.WorkflowConfig
+		if err := json.Unmarshal([]byte(configJSON), &config); err == nil {
+			if err := e.checkFallbacks(actionType, config); err != nil {
+				return err
+			}
+		}
+	}
+
+	if actionChainJSON == "" {
+		return nil
+	}
+	var chain []models.ChainedAction
+	if err := json.Unmarshal([]byte(actionChainJSON), &chain); err != nil {
+		return nil
+	}
+	for _, step := range chainActions(chain) {
+		var config models.WorkflowConfig
+		configBytes, _ := json.Marshal(step.action.Config)
+		if err := json.Unmarshal(configBytes, &config); err != nil {
+			continue
+		}
+		if err := e.checkFallbacks(step.action.ActionType, config); err != nil {
+			return fmt.Errorf("%s: %w", step.label, err)
+		}
+	}
+	return nil
+}
+
+// checkFallbacks validates one step's fallback configuration
+func (e *Executor) checkFallbacks(actionType string, config models.WorkflowConfig) error {
+	if config.FallbackAttemptTimeout < 0 {
+		return fmt.Errorf("fallback_attempt_timeout must not be negative (got %dms)", config.FallbackAttemptTimeout)
+	}
+	if len(config.Fallbacks) == 0 {
+		return nil
+	}
+	if _, ok := notificationServices[actionType]; !ok {
+		return fmt.Errorf("fallbacks are only supported on slack_message, discord_post, twilio_sms and testing actions")
+	}
+	if len(config.Fallbacks) > maxFallbacks {
+		return fmt.Errorf("at most %d fallbacks are allowed (got %d)", maxFallbacks, len(config.Fallbacks))
+	}
+
+	for i, fallback := range config.Fallbacks {
+		if fallback.Credential == "" && fallback.ActionType == "" && fallback.Config == nil {
+			return fmt.Errorf("fallback %d must set credential, action_type or config", i+1)
+		}
+		fallbackType, fallbackConfig := fallbackTarget(actionType, config, fallback)
+		if _, ok := notificationServices[fallbackType]; !ok {
+			return fmt.Errorf("fallback %d: action_type must be slack_message, discord_post, twilio_sms or testing", i+1)
+		}
+		if fallbackType == "testing" {
+			if err := e.checkTestingDelay(fallbackConfig.TestingDelay); err != nil {
+				return fmt.Errorf("fallback %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
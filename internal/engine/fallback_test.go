@@ -0,0 +1,138 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestNotificationFallbacks simulates each failure layer with the testing connector
+func TestNotificationFallbacks(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("fallback@example.com", "hashed")
+	mockStore.CreateCredential(user.ID, "slack:backup", "https://hooks.slack.com/services/BACKUP")
+	tenantID := "tenant_" + user.ID
+
+	const backup = `{"action_type": "testing", "config": {"testing_response_json": "{\"target\": \"backup\"}"}}`
+	const slow = `{"action_type": "testing", "config": {"testing_delay": 300, "testing_response_json": "{\"target\": \"slow\"}"}}`
+
+	tests := []struct {
+		name       string
+		actionType string
+		config     string
+		status     string
+		level      int
+		code       string
+		target     string
+	}{
+		{
+			name:       "primary succeeds",
+			actionType: "testing",
+			config:     `{"testing_response_json": "{\"target\": \"primary\"}", "fallbacks": [` + backup + `]}`,
+			status:     "success", level: 0, target: "primary",
+		},
+		{
+			name:       "error status falls back",
+			actionType: "testing",
+			config:     `{"testing_status_code": 503, "fallbacks": [` + backup + `]}`,
+			status:     "success", level: 1, target: "backup",
+		},
+		{
+			name:       "missing credential falls back to another credential",
+			actionType: "slack_message",
+			config:     `{"credential": "slack:primary", "fallbacks": [{"credential": "slack:backup"}]}`,
+			// The mock store's webhook URL can't be dialled, which proves the backup credential was loaded
+			status: "failed", level: 1, code: connectors.ErrCodeUpstreamUnreachable,
+		},
+		{
+			name:       "every target fails",
+			actionType: "testing",
+			config:     `{"testing_status_code": 500, "fallbacks": [{"action_type": "testing", "config": {"testing_status_code": 502}}]}`,
+			status:     "failed", level: 1, code: connectors.ErrCodeUpstreamHTTP,
+		},
+		{
+			name:       "timeout does not fall back by default",
+			actionType: "testing",
+			config:     `{"testing_delay": 300, "fallback_attempt_timeout": 50, "fallbacks": [` + backup + `]}`,
+			status:     "failed", level: 0, code: connectors.ErrCodeUpstreamTimeout,
+		},
+		{
+			name:       "timeout falls back when allowed",
+			actionType: "testing",
+			config:     `{"testing_delay": 300, "fallback_attempt_timeout": 50, "fallback_on_timeout": true, "fallbacks": [` + backup + `]}`,
+			status:     "success", level: 1, target: "backup",
+		},
+		{
+			name:       "timed out fallback stops the sequence",
+			actionType: "testing",
+			config:     `{"testing_status_code": 503, "fallback_attempt_timeout": 50, "fallbacks": [` + slow + `, ` + backup + `]}`,
+			status:     "failed", level: 1, code: connectors.ErrCodeUpstreamTimeout,
+		},
+		{
+			name:       "non-retryable failure does not fall back",
+			actionType: "testing",
+			config:     `{"testing_response_json": "not json", "fallbacks": [` + backup + `]}`,
+			status:     "failed", level: 0, code: connectors.ErrCodeActionFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflow, err := mockStore.CreateWorkflow(user.ID, tt.name, "webhook", tt.actionType, tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create workflow: %v", err)
+			}
+			result := executor.DryRun(*workflow, user.ID, tenantID)
+
+			if result.Status != tt.status || result.FallbackLevel != tt.level {
+				t.Fatalf("Expected %s at level %d, got %s at level %d: %s", tt.status, tt.level, result.Status, result.FallbackLevel, result.Message)
+			}
+			if tt.code != "" && result.ErrorCode != tt.code {
+				t.Errorf("Expected error code %s, got %s", tt.code, result.ErrorCode)
+			}
+			if tt.target != "" && result.Data["target"] != tt.target {
+				t.Errorf("Expected the %s target to answer, got %v", tt.target, result.Data)
+			}
+		})
+	}
+}
+
+// TestValidateFallbacks rejects fallback configurations the executor can't honour
+func TestValidateFallbacks(t *testing.T) {
+	executor := engine.NewExecutor(db.NewMockStore(), logger.NewLogger("test"))
+
+	tests := []struct {
+		name       string
+		actionType string
+		config     string
+		chain      string
+		wantErr    string
+	}{
+		{"credential fallback", "slack_message", `{"fallbacks": [{"credential": "slack:backup"}]}`, "", ""},
+		{"other action", "slack_message", `{"fallbacks": [{"action_type": "discord_post"}]}`, "", ""},
+		{"not a notification", "news_fetch", `{"fallbacks": [{"action_type": "slack_message"}]}`, "", "only supported"},
+		{"empty fallback", "slack_message", `{"fallbacks": [{}]}`, "", "fallback 1 must set"},
+		{"unsupported target", "slack_message", `{"fallbacks": [{"action_type": "news_fetch"}]}`, "", "fallback 1: action_type"},
+		{"too many", "testing", `{"fallbacks": [{"credential": "a"}, {"credential": "b"}, {"credential": "c"}, {"credential": "d"}, {"credential": "e"}, {"credential": "f"}]}`, "", "at most 5"},
+		{"chained step", "slack_message", `{}`, `[{"action_type": "discord_post", "config": {"fallbacks": [{}]}}]`, "action_chain step 1: fallback 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executor.ValidateFallbacks(tt.actionType, tt.config, tt.chain)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// executeGitHubAction runs a GitHub operation with the user's personal access token,
+// rendering the issue title and body against the trigger payload
+func (e *Executor) executeGitHubAction(ctx context.Context, userID, tenantID string, config models.WorkflowConfig, triggerPayload string) connectors.Result {
+	select {
+	case <-ctx.Done():
+		return connectors.NewCancelledResult(ctx.Err().Error())
+	default:
+	}
+
+	cred, err := e.getCredential(ctx, userID, tenantID, "github")
+	if err != nil {
+		e.log.Error("GitHub credentials not found", map[string]interface{}{
+			"user_id":   userID,
+			"tenant_id": tenantID,
+			"error":     err.Error(),
+		})
+		return credentialErrorResult("GitHub", err)
+	}
+
+	githubConfig := connectors.GitHubConfig{
+		Operation:   config.GitHubOperation,
+		Owner:       config.GitHubOwner,
+		Repo:        config.GitHubRepo,
+		IssueNumber: config.GitHubIssueNumber,
+		Title:       config.GitHubTitle,
+		Body:        config.GitHubBody,
+		Labels:      config.GitHubLabels,
+	}
+	if triggerPayload != "" {
+		if err := e.renderTemplates(triggerPayload, &githubConfig.Title, &githubConfig.Body); err != nil {
+			return templateErrorResult(err)
+		}
+	}
+
+	// Reads are safe to run for real; writes only report what they would have sent
+	if IsSandbox(ctx) && connectors.GitHubOperationWrites(githubConfig.Operation) {
+		dryRun := (&connectors.GitHubConnector{}).DryRunGitHub(githubConfig)
+		return sandboxResult("GitHub "+githubConfig.Operation+" not performed", dryRun.Data)
+	}
+
+	github := &connectors.GitHubConnector{Token: cred.DecryptedKey}
+	return github.ExecuteWithContext(ctx, githubConfig)
+}
@@ -0,0 +1,63 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestChainGitHubIssueToSlack opens an issue from a webhook payload and posts its URL to
+// Slack in the next chain step
+func TestChainGitHubIssueToSlack(t *testing.T) {
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var issue map[string]string
+		json.NewDecoder(r.Body).Decode(&issue)
+		if r.URL.Path != "/repos/acme/api/issues" || issue["title"] != "Deploy 1.4.2 failed" || r.Header.Get("Authorization") != "Bearer ghp_test" {
+			t.Errorf("Unexpected GitHub request %s %v", r.URL.Path, issue)
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"number": 42, "html_url": "https://github.com/acme/api/issues/42"}`)
+	}))
+	defer github.Close()
+	routeConnectorHost(t, "api.github.com", github.URL)
+
+	messages := make(chan string, 1)
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		messages <- body.Text
+	}))
+	defer slack.Close()
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("github@example.com", "hashed")
+	database.CreateCredential(user.ID, "github", "ghp_test")
+	database.CreateCredential(user.ID, "slack", slack.URL)
+
+	workflow, err := database.CreateWorkflowWithChain(user.ID, "Deploy alerts", "webhook", "github",
+		`{"github_operation": "create_issue", "github_owner": "acme", "github_repo": "api", "github_title": "Deploy {{version}} failed"}`,
+		`[{"action_type": "slack_message", "config": {"slack_message": "Filed {{issue.html_url}}"}, "use_data_from": "previous"}]`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, `{"version": "1.4.2"}`)
+
+	execution, err := database.GetLatestExecution(workflow.ID)
+	if err != nil || execution.Status != "success" {
+		t.Fatalf("Expected the chain to succeed, got %+v, %v", execution, err)
+	}
+	if message := <-messages; message != "Filed https://github.com/acme/api/issues/42" {
+		t.Errorf("Expected the issue URL in the Slack message, got %q", message)
+	}
+}
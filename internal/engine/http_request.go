@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// httpMethods are the methods an http_request action may use; the bool marks methods
+// without side effects, which still run in a sandbox
+var httpMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPost:   false,
+	http.MethodPut:    false,
+	http.MethodPatch:  false,
+	http.MethodDelete: false,
+}
+
+// ValidateHTTPRequest rejects http_request configs the executor would refuse to run
+// Templated URLs are only checked once rendered, at execution time
+// Malformed JSON is left for the executor to report
+func ValidateHTTPRequest(actionType, configJSON string) error {
+	if actionType != "http_request" {
+		return nil
+	}
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+
+	if config.HTTPURL == "" {
+		return fmt.Errorf("http_url is required for http_request")
+	}
+	if !strings.Contains(config.HTTPURL, "{{") {
+		if _, err := connectors.CheckHTTPURL(config.HTTPURL); err != nil {
+			return fmt.Errorf("http_url: %v", err)
+		}
+	}
+	if _, ok := httpMethods[strings.ToUpper(config.HTTPMethod)]; !ok && config.HTTPMethod != "" {
+		return fmt.Errorf("http_method must be GET, HEAD, POST, PUT, PATCH or DELETE")
+	}
+	if config.HTTPTimeout < 0 || time.Duration(config.HTTPTimeout)*time.Second > connectors.MaxHTTPTimeout {
+		return fmt.Errorf("http_timeout must be between 0 and %d seconds", int(connectors.MaxHTTPTimeout.Seconds()))
+	}
+	return nil
+}
+
+// executeHTTPAction sends a generic HTTP request, rendering the URL and body against the
+// trigger payload
+func (e *Executor) executeHTTPAction(ctx context.Context, userID, tenantID string, config models.WorkflowConfig, triggerPayload string) connectors.Result {
+	select {
+	case <-ctx.Done():
+		return connectors.NewCancelledResult(ctx.Err().Error())
+	default:
+	}
+	start := time.Now()
+
+	tlsSettings, err := e.loadTLSSettings(ctx, userID, tenantID, config.TLSCredential)
+	if err != nil {
+		return connectors.NewFailureResult(err.Error(), start)
+	}
+
+	var auth *connectors.HTTPAuth
+	if config.HTTPAuthCredential != "" {
+		cred, err := e.getCredential(ctx, userID, tenantID, config.HTTPAuthCredential)
+		if err != nil {
+			return credentialErrorResult(config.HTTPAuthCredential, err)
+		}
+		if auth, err = connectors.ParseHTTPAuth(cred.DecryptedKey); err != nil {
+			return connectors.NewFailureResult(fmt.Sprintf("HTTP auth credential %q is invalid: %v", config.HTTPAuthCredential, err), start)
+		}
+	}
+
+	httpConfig := connectors.HTTPConfig{
+		Method:  strings.ToUpper(config.HTTPMethod),
+		URL:     config.HTTPURL,
+		Headers: config.HTTPHeaders,
+		Query:   config.HTTPQuery,
+		Body:    config.HTTPBody,
+		Auth:    auth,
+		Timeout: time.Duration(config.HTTPTimeout) * time.Second,
+	}
+	if triggerPayload != "" {
+		if err := e.renderTemplates(triggerPayload, &httpConfig.URL); err != nil {
+			return connectors.NewFailureResult(fmt.Sprintf("Failed to render template: %v", err), start)
+		}
+		switch body := config.HTTPBody.(type) {
+		case nil:
+		case string:
+			if err := e.renderTemplates(triggerPayload, &body); err != nil {
+				return connectors.NewFailureResult(fmt.Sprintf("Failed to render template: %v", err), start)
+			}
+			httpConfig.Body = body
+		default:
+			// Rendered as JSON text, so an object body keeps its Content-Type
+			encoded, err := json.Marshal(body)
+			if err != nil {
+				return connectors.NewFailureResult(fmt.Sprintf("Failed to encode http_body: %v", err), start)
+			}
+			rendered := string(encoded)
+			if err := e.renderTemplates(triggerPayload, &rendered); err != nil {
+				return connectors.NewFailureResult(fmt.Sprintf("Failed to render template: %v", err), start)
+			}
+			httpConfig.Body = json.RawMessage(rendered)
+		}
+	}
+
+	method := httpConfig.Method
+	if method == "" {
+		method = http.MethodGet
+		if httpConfig.Body != nil {
+			method = http.MethodPost
+		}
+	}
+	if IsSandbox(ctx) && !httpMethods[method] {
+		return sandboxResult("HTTP "+method+" not sent", map[string]interface{}{
+			"method": method,
+			"url":    httpConfig.URL,
+			"body":   httpConfig.Body,
+		})
+	}
+
+	connector := &connectors.HTTPRequestConnector{TLS: tlsSettings}
+	return connector.ExecuteWithContext(ctx, httpConfig)
+}
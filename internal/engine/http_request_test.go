@@ -0,0 +1,60 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestHTTPRequestRendersURL runs an http_request workflow whose URL comes from the trigger
+// payload and checks the rendered URL is still held to the SSRF check
+func TestHTTPRequestRendersURL(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("http@example.com", "hashed")
+	workflow, err := mockStore.CreateWorkflow(user.ID, "Callback", "webhook", "http_request", `{"http_url": "http://{{callback.host}}/latest/meta-data/"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, `{"callback": {"host": "169.254.169.254"}}`)
+
+	execution, err := mockStore.GetLatestExecution(workflow.ID)
+	if err != nil {
+		t.Fatalf("Expected an execution record: %v", err)
+	}
+	if execution.Status != "failed" || !strings.Contains(execution.Message, "169.254.169.254") || !strings.Contains(execution.Message, "refused") {
+		t.Errorf("Expected the rendered metadata URL to be refused, got %s: %s", execution.Status, execution.Message)
+	}
+}
+
+// TestValidateHTTPRequest checks http_request configs are rejected at save time
+func TestValidateHTTPRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "plain URL", config: `{"http_url": "https://api.example.com/orders", "http_method": "post"}`},
+		{name: "templated URL is checked when rendered", config: `{"http_url": "http://{{host}}/hook"}`},
+		{name: "missing URL", config: `{}`, wantErr: true},
+		{name: "metadata service", config: `{"http_url": "http://169.254.169.254/"}`, wantErr: true},
+		{name: "localhost", config: `{"http_url": "http://localhost:8080/api/admin/config"}`, wantErr: true},
+		{name: "other scheme", config: `{"http_url": "ftp://files.example.com/"}`, wantErr: true},
+		{name: "unknown method", config: `{"http_url": "https://api.example.com", "http_method": "TRACE"}`, wantErr: true},
+		{name: "timeout too long", config: `{"http_url": "https://api.example.com", "http_timeout": 600}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := engine.ValidateHTTPRequest("http_request", tt.config); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if err := engine.ValidateHTTPRequest("testing", `{}`); err != nil {
+		t.Errorf("Expected other action types to be ignored, got %v", err)
+	}
+}
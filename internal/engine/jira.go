@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// executeJiraAction runs a Jira operation with the user's Jira credential, rendering the
+// summary, description and issue key against the trigger payload
+func (e *Executor) executeJiraAction(ctx context.Context, userID, tenantID string, config models.WorkflowConfig, triggerPayload string) connectors.Result {
+	select {
+	case <-ctx.Done():
+		return connectors.NewCancelledResult(ctx.Err().Error())
+	default:
+	}
+
+	cred, err := e.getCredential(ctx, userID, tenantID, "jira")
+	if err != nil {
+		e.log.Error("Jira credentials not found", map[string]interface{}{
+			"user_id":   userID,
+			"tenant_id": tenantID,
+			"error":     err.Error(),
+		})
+		return credentialErrorResult("Jira", err)
+	}
+
+	jiraConfig := connectors.JiraConfig{
+		Operation:   config.JiraOperation,
+		ProjectKey:  config.JiraProjectKey,
+		IssueType:   config.JiraIssueType,
+		Summary:     config.JiraSummary,
+		Description: config.JiraDescription,
+		IssueKey:    config.JiraIssueKey,
+		Transition:  config.JiraTransition,
+		JQL:         config.JiraJQL,
+	}
+	if triggerPayload != "" {
+		if err := e.renderTemplates(triggerPayload, &jiraConfig.Summary, &jiraConfig.Description, &jiraConfig.IssueKey); err != nil {
+			return templateErrorResult(err)
+		}
+	}
+
+	// Searches are safe to run for real; writes only report what they would have sent
+	if IsSandbox(ctx) && connectors.JiraOperationWrites(jiraConfig.Operation) {
+		dryRun := (&connectors.JiraConnector{}).DryRunJira(jiraConfig)
+		return sandboxResult("Jira "+jiraConfig.Operation+" not performed", dryRun.Data)
+	}
+
+	jira, err := connectors.ParseJiraCredential(cred.DecryptedKey)
+	if err != nil {
+		return connectors.NewFailureResult(fmt.Sprintf("Invalid Jira credentials format: %v", err), time.Now())
+	}
+	return jira.ExecuteWithContext(ctx, jiraConfig)
+}
@@ -0,0 +1,67 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestChainJiraIssueToSlack files a Jira ticket from a webhook payload and posts its key
+// and browse URL to Slack in the next chain step
+func TestChainJiraIssueToSlack(t *testing.T) {
+	jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var issue struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		json.NewDecoder(r.Body).Decode(&issue)
+		if email, token, _ := r.BasicAuth(); r.URL.Path != "/rest/api/2/issue" || email != "ops@acme.com" || token != "jira-token" {
+			t.Errorf("Unexpected Jira request %s", r.URL.Path)
+		}
+		if issue.Fields["summary"] != "Checkout down in eu-west" || issue.Fields["description"] != "Alert: 500s above 5%" {
+			t.Errorf("Expected the rendered summary and description, got %v", issue.Fields)
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id": "10042", "key": "OPS-42"}`)
+	}))
+	defer jira.Close()
+
+	messages := make(chan string, 1)
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		messages <- body.Text
+	}))
+	defer slack.Close()
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("jira@example.com", "hashed")
+	database.CreateCredential(user.ID, "jira", `{"base_url": "`+jira.URL+`", "email": "ops@acme.com", "api_token": "jira-token"}`)
+	database.CreateCredential(user.ID, "slack", slack.URL)
+
+	workflow, err := database.CreateWorkflowWithChain(user.ID, "Incident tickets", "webhook", "jira",
+		`{"jira_operation": "create_issue", "jira_project_key": "OPS", "jira_summary": "{{service}} down in {{region}}", "jira_description": "Alert: {{alert}}"}`,
+		`[{"action_type": "slack_message", "config": {"slack_message": "Filed {{issue_key}}: {{url}}"}, "use_data_from": "previous"}]`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, `{"service": "Checkout", "region": "eu-west", "alert": "500s above 5%"}`)
+
+	execution, err := database.GetLatestExecution(workflow.ID)
+	if err != nil || execution.Status != "success" {
+		t.Fatalf("Expected the chain to succeed, got %+v, %v", execution, err)
+	}
+	if message := <-messages; message != "Filed OPS-42: "+jira.URL+"/browse/OPS-42" {
+		t.Errorf("Expected the issue key and URL in the Slack message, got %q", message)
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"sync"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// LogSubscriberBuffer is how many log entries a subscriber can fall behind by before
+// further entries are dropped for it
+const LogSubscriberBuffer = 64
+
+// LogBroadcaster fans new log entries out to the subscribers of the workflow owner
+// Publishing never blocks: a subscriber whose buffer is full misses the entry
+type LogBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*logSubscriber]struct{} // Keyed by user ID
+}
+
+// logSubscriber is one open stream
+type logSubscriber struct {
+	workflowID string // Empty = every workflow
+	entries    chan models.WorkflowLog
+}
+
+// NewLogBroadcaster creates a broadcaster with no subscribers
+func NewLogBroadcaster() *LogBroadcaster {
+	return &LogBroadcaster{subscribers: make(map[string]map[*logSubscriber]struct{})}
+}
+
+// Subscribe receives a user's new log entries, optionally only one workflow's
+// Call the returned function to unsubscribe; the channel is closed then
+func (b *LogBroadcaster) Subscribe(userID, workflowID string) (<-chan models.WorkflowLog, func()) {
+	sub := &logSubscriber{workflowID: workflowID, entries: make(chan models.WorkflowLog, LogSubscriberBuffer)}
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*logSubscriber]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.entries, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[userID], sub)
+			if len(b.subscribers[userID]) == 0 {
+				delete(b.subscribers, userID)
+			}
+			close(sub.entries)
+		})
+	}
+}
+
+// Publish sends an entry to its owner's subscribers without waiting for any of them
+func (b *LogBroadcaster) Publish(userID string, entry models.WorkflowLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[userID] {
+		if sub.workflowID != "" && sub.workflowID != entry.WorkflowID {
+			continue
+		}
+		select {
+		case sub.entries <- entry:
+		default: // Slow consumer; execution must not wait for it
+		}
+	}
+}
+
+// Subscribers is the number of open streams across all users
+func (b *LogBroadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	count := 0
+	for _, subs := range b.subscribers {
+		count += len(subs)
+	}
+	return count
+}
+
+// Logs returns the broadcaster the executor publishes each stored log entry to
+func (e *Executor) Logs() *LogBroadcaster {
+	return e.logStream
+}
+
+// createLog stores a run's log entry and publishes it to the owner's open streams
+func (e *Executor) createLog(workflow models.Workflow, entry *models.Log) {
+	if err := e.store.CreateLogEntry(entry); err != nil {
+		return
+	}
+	e.logStream.Publish(workflow.UserID, models.WorkflowLog{Log: *entry, WorkflowName: workflow.Name})
+}
@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// Overflow policies for runs past a workflow's max_notifications_per_hour
+const (
+	OverflowDrop   = "drop"   // Suppress the run; only the count is kept (the default)
+	OverflowDigest = "digest" // Suppress the run and send one summary through the same connector when the window frees
+	OverflowQueue  = "queue"  // Suppress the run now and run it again once the window frees
+)
+
+// Notification throttle limits
+const (
+	ThrottleWindow         = time.Hour // Sliding window max_notifications_per_hour counts over
+	maxQueuedNotifications = 100       // Queued runs kept per workflow; later ones are dropped
+)
+
+// throttledActions are the action types the per-workflow throttle applies to
+var throttledActions = map[string]bool{
+	"slack_message": true,
+	"discord_post":  true,
+	"twilio_sms":    true,
+	"testing":       true,
+}
+
+// throttledRun is a suppressed run waiting in a workflow's queue
+type throttledRun struct {
+	workflow models.Workflow
+	payload  string
+}
+
+// NotificationWindow counts a workflow's admitted sends over a sliding ThrottleWindow
+type NotificationWindow struct {
+	sent []time.Time // Admitted sends still inside the window, oldest first
+}
+
+// Admit counts a send at now if fewer than limit fall in the window ending at now
+// Otherwise it reports when the oldest send leaves the window and frees a slot
+func (w *NotificationWindow) Admit(limit int, now time.Time) (bool, time.Time) {
+	w.expire(now)
+	if len(w.sent) < limit {
+		w.sent = append(w.sent, now)
+		return true, time.Time{}
+	}
+	return false, w.sent[0].Add(ThrottleWindow)
+}
+
+// expire drops sends that have left the window ending at now
+// A send exactly one window old no longer counts
+func (w *NotificationWindow) expire(now time.Time) {
+	cutoff := now.Add(-ThrottleWindow)
+	kept := 0
+	for kept < len(w.sent) && !w.sent[kept].After(cutoff) {
+		kept++
+	}
+	w.sent = w.sent[kept:]
+}
+
+// throttleState is one workflow's sliding window and what it is holding back
+type throttleState struct {
+	NotificationWindow
+	workflow models.Workflow
+	config   models.WorkflowConfig
+	tenantID string
+
+	digested    int       // Runs suppressed since the last digest (digest policy)
+	digestFrom  time.Time // First of them
+	digestUntil time.Time // Last of them
+	queue       []throttledRun
+
+	flushAt time.Time   // When the window next frees a slot for held-back work (zero = nothing held)
+	timer   *time.Timer // Fires FlushThrottled at flushAt
+}
+
+// notificationThrottle tracks per-workflow sliding windows of outbound notifications
+type notificationThrottle struct {
+	mu        sync.Mutex
+	workflows map[string]*throttleState
+}
+
+func newNotificationThrottle() *notificationThrottle {
+	return &notificationThrottle{workflows: make(map[string]*throttleState)}
+}
+
+// throttleNotification applies the workflow's outbound notification limit
+// Returns the suppressed result to report instead of running the action, or nil to go ahead
+func (e *Executor) throttleNotification(ctx context.Context, workflow models.Workflow, config models.WorkflowConfig, payload, tenantID string, now time.Time) *connectors.Result {
+	if config.MaxNotificationsPerHour <= 0 || !throttledActions[workflow.ActionType] || IsSandbox(ctx) || isThrottleAdmitted(ctx) {
+		return nil
+	}
+
+	t := e.notifications
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.workflows[workflow.ID]
+	if !ok {
+		state = &throttleState{}
+		t.workflows[workflow.ID] = state
+	}
+	allowed, freesAt := state.Admit(config.MaxNotificationsPerHour, now)
+	if allowed {
+		return nil
+	}
+
+	policy := config.NotificationOverflow
+	if policy == "" {
+		policy = OverflowDrop
+	}
+	data := map[string]interface{}{
+		"limit":          config.MaxNotificationsPerHour,
+		"window":         ThrottleWindow.String(),
+		"window_start":   state.sent[0].UTC().Format(time.RFC3339),
+		"next_slot_at":   freesAt.UTC().Format(time.RFC3339),
+		"overflow":       policy,
+		"sent_in_window": len(state.sent),
+	}
+
+	switch policy {
+	case OverflowDigest:
+		if state.digested == 0 {
+			state.digestFrom = now
+		}
+		state.digested++
+		state.digestUntil = now
+		data["digest_pending"] = state.digested
+	case OverflowQueue:
+		if len(state.queue) >= maxQueuedNotifications {
+			data["queue_full"] = true
+			policy = OverflowDrop
+			break
+		}
+		state.queue = append(state.queue, throttledRun{workflow: workflow, payload: payload})
+		data["queued"] = len(state.queue)
+	}
+	if policy != OverflowDrop {
+		state.workflow, state.config, state.tenantID = workflow, config, tenantID
+		e.scheduleThrottleFlush(state, freesAt, now)
+	}
+
+	return &connectors.Result{
+		Status:    models.StatusSuppressed,
+		Message:   fmt.Sprintf("Suppressed: %d notifications per hour reached (next slot at %s, overflow: %s)", config.MaxNotificationsPerHour, freesAt.UTC().Format(time.RFC3339), policy),
+		Data:      data,
+		Timestamp: now.UTC().Format(time.RFC3339),
+	}
+}
+
+// scheduleThrottleFlush arranges for held-back work to be flushed at the given time
+// Callers hold the throttle's lock
+func (e *Executor) scheduleThrottleFlush(state *throttleState, at, now time.Time) {
+	if !state.flushAt.IsZero() && !at.Before(state.flushAt) {
+		return
+	}
+	state.flushAt = at
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	state.timer = time.AfterFunc(at.Sub(now), func() { e.FlushThrottled(time.Now()) })
+}
+
+// FlushThrottled sends the digests and releases the queued runs of every workflow
+// whose window has freed a slot by now, and returns how many digests and runs went out
+// Runs on a timer; exported so tests can flush at a chosen time
+func (e *Executor) FlushThrottled(now time.Time) int {
+	type digest struct {
+		workflow models.Workflow
+		config   models.WorkflowConfig
+		tenantID string
+		count    int
+		from, to time.Time
+	}
+	var digests []digest
+	var released []throttledRun
+
+	t := e.notifications
+	t.mu.Lock()
+	for _, state := range t.workflows {
+		if state.flushAt.IsZero() || now.Before(state.flushAt) {
+			continue
+		}
+		state.flushAt = time.Time{}
+		state.expire(now)
+
+		if state.digested > 0 {
+			digests = append(digests, digest{state.workflow, state.config, state.tenantID, state.digested, state.digestFrom, state.digestUntil})
+			state.digested = 0
+		}
+		// Queued runs take the freed slots, oldest first; they skip the throttle when they run
+		for len(state.queue) > 0 && len(state.sent) < state.config.MaxNotificationsPerHour {
+			state.sent = append(state.sent, now)
+			released = append(released, state.queue[0])
+			state.queue = state.queue[1:]
+		}
+		if len(state.queue) > 0 {
+			e.scheduleThrottleFlush(state, state.sent[0].Add(ThrottleWindow), now)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, d := range digests {
+		e.sendThrottleDigest(d.workflow, d.config, d.tenantID, d.count, d.from, d.to)
+	}
+	for _, run := range released {
+		run := run
+		e.pool.Submit(WorkflowJob{
+			Workflow: run.workflow,
+			Payload:  run.payload,
+			Executor: e,
+			Run: func(ctx context.Context) {
+				e.ExecuteWorkflowWithContext(withThrottleAdmitted(ctx), run.workflow, run.payload)
+			},
+		})
+	}
+	return len(digests) + len(released)
+}
+
+// sendThrottleDigest sends one summary of suppressed runs through the workflow's own connector
+// The digest doesn't count against the limit: a flood costs at most one extra message per window
+func (e *Executor) sendThrottleDigest(workflow models.Workflow, config models.WorkflowConfig, tenantID string, count int, from, to time.Time) {
+	message := fmt.Sprintf("%d notifications from %q were suppressed between %s and %s (limit %d per hour)",
+		count, workflow.Name, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339), config.MaxNotificationsPerHour)
+
+	switch workflow.ActionType {
+	case "slack_message":
+		config.SlackMessage = message
+	case "discord_post":
+		config.DiscordMessage = message
+	case "twilio_sms":
+		config.TwilioMessage = message
+	case "testing":
+		encoded, _ := json.Marshal(map[string]string{"message": message})
+		config.TestingResponseJSON = string(encoded)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), WorkflowTimeout)
+	defer cancel()
+	result := e.executeNotification(ctx, workflow.ActionType, workflow.UserID, tenantID, config, "")
+	codeFailure(&result, workflow.ActionType)
+
+	e.log.WorkflowLog(
+		logger.LevelInfo,
+		"Sent digest of suppressed notifications",
+		workflow.ID,
+		workflow.UserID,
+		tenantID,
+		map[string]interface{}{
+			"suppressed": count,
+			"status":     result.Status,
+		},
+	)
+	e.createLog(workflow, &models.Log{
+		WorkflowID:  workflow.ID,
+		Status:      result.Status,
+		Message:     "Digest: " + message,
+		ErrorCode:   result.ErrorCode,
+		ErrorParams: result.ErrorParams,
+	})
+}
+
+// throttleAdmittedKey marks a queued run whose slot was reserved when it was released
+type throttleAdmittedKey struct{}
+
+func withThrottleAdmitted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, throttleAdmittedKey{}, true)
+}
+
+func isThrottleAdmitted(ctx context.Context) bool {
+	admitted, _ := ctx.Value(throttleAdmittedKey{}).(bool)
+	return admitted
+}
+
+// ValidateNotificationThrottle rejects throttle settings the executor can't apply
+// Malformed JSON is left for the executor to report
+func ValidateNotificationThrottle(configJSON string) error {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	if config.MaxNotificationsPerHour < 0 {
+		return fmt.Errorf("max_notifications_per_hour can't be negative")
+	}
+	switch config.NotificationOverflow {
+	case "", OverflowDrop, OverflowDigest, OverflowQueue:
+	default:
+		return fmt.Errorf("notification_overflow must be %s, %s or %s", OverflowDrop, OverflowDigest, OverflowQueue)
+	}
+	return nil
+}
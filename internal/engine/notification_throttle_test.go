@@ -0,0 +1,158 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestNotificationWindowBoundaries checks the sliding window frees a slot exactly one
+// window after the oldest counted send, and not a moment before
+func TestNotificationWindowBoundaries(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	var window engine.NotificationWindow
+
+	for i, at := range []time.Duration{0, 10 * time.Minute, 20 * time.Minute} {
+		if ok, _ := window.Admit(3, start.Add(at)); !ok {
+			t.Fatalf("Expected send %d to be admitted", i+1)
+		}
+	}
+	ok, freesAt := window.Admit(3, start.Add(30*time.Minute))
+	if ok || !freesAt.Equal(start.Add(time.Hour)) {
+		t.Fatalf("Expected the 4th send to wait for %s, got ok=%t freesAt=%s", start.Add(time.Hour), ok, freesAt)
+	}
+	if ok, _ := window.Admit(3, start.Add(time.Hour-time.Nanosecond)); ok {
+		t.Error("Expected no slot until the oldest send is a full window old")
+	}
+	if ok, _ := window.Admit(3, start.Add(time.Hour)); !ok {
+		t.Error("Expected a slot exactly one window after the oldest send")
+	}
+
+	// The next slot comes from the next-oldest send, not a fixed hourly reset
+	ok, freesAt = window.Admit(3, start.Add(time.Hour+time.Minute))
+	if ok || !freesAt.Equal(start.Add(70*time.Minute)) {
+		t.Errorf("Expected the next slot at %s, got ok=%t freesAt=%s", start.Add(70*time.Minute), ok, freesAt)
+	}
+	if ok, _ := window.Admit(3, start.Add(3*time.Hour)); !ok {
+		t.Error("Expected an idle window to admit again")
+	}
+}
+
+// slackInbox records the messages posted to a fake Slack webhook
+type slackInbox struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (in *slackInbox) received() []string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return append([]string(nil), in.messages...)
+}
+
+// TestNotificationThrottleDigest floods a workflow past its limit and checks the
+// overflow is suppressed, counted in stats, and summarized once through Slack
+func TestNotificationThrottleDigest(t *testing.T) {
+	inbox := &slackInbox{}
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		inbox.mu.Lock()
+		inbox.messages = append(inbox.messages, body["text"])
+		inbox.mu.Unlock()
+	}))
+	defer slack.Close()
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("flood@example.com", "hashed")
+	database.CreateCredential(user.ID, "slack", slack.URL)
+	workflow, _ := database.CreateWorkflow(user.ID, "Orders", "webhook", "slack_message",
+		`{"slack_message": "Order {{id}}", "max_notifications_per_hour": 3, "notification_overflow": "digest"}`)
+
+	for i := 0; i < 10; i++ {
+		executor.ExecuteWorkflowWithContext(context.Background(), *workflow, `{"id": 1}`)
+	}
+
+	if messages := inbox.received(); len(messages) != 3 {
+		t.Fatalf("Expected 3 messages before the limit, got %d", len(messages))
+	}
+	execution, err := database.GetLatestExecution(workflow.ID)
+	if err != nil || execution.Status != models.StatusSuppressed {
+		t.Fatalf("Expected the latest execution to be suppressed, got %+v (%v)", execution, err)
+	}
+	if !strings.Contains(execution.ResultData, `"next_slot_at"`) || !strings.Contains(execution.ResultData, `"digest_pending":7`) {
+		t.Errorf("Expected window info in the suppressed trace, got %s", execution.ResultData)
+	}
+	stored, _ := database.GetWorkflowByID(workflow.ID)
+	if stored.Stats.SuppressedExecutions != 7 || stored.Stats.TotalExecutions != 3 || stored.Stats.ConsecutiveFailures != 0 {
+		t.Errorf("Expected 7 suppressed and 3 counted executions, got %+v", stored.Stats)
+	}
+
+	// Nothing is flushed while the window is still full
+	if flushed := executor.FlushThrottled(time.Now()); flushed != 0 {
+		t.Errorf("Expected nothing flushed before the window frees, got %d", flushed)
+	}
+	if flushed := executor.FlushThrottled(time.Now().Add(time.Hour)); flushed != 1 {
+		t.Fatalf("Expected one digest when the window frees, got %d", flushed)
+	}
+	messages := inbox.received()
+	if len(messages) != 4 || !strings.Contains(messages[3], `7 notifications from "Orders" were suppressed`) {
+		t.Errorf("Expected a digest of 7 suppressed notifications, got %q", messages)
+	}
+	if flushed := executor.FlushThrottled(time.Now().Add(2 * time.Hour)); flushed != 0 {
+		t.Errorf("Expected the digest to be sent only once, got %d more", flushed)
+	}
+}
+
+// TestNotificationThrottleQueueAndDrop checks queued runs go out once the window frees
+// while dropped runs never do
+func TestNotificationThrottleQueueAndDrop(t *testing.T) {
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("queue@example.com", "hashed")
+
+	queued, _ := database.CreateWorkflow(user.ID, "Queued", "webhook", "testing",
+		`{"max_notifications_per_hour": 1, "notification_overflow": "queue"}`)
+	dropped, _ := database.CreateWorkflow(user.ID, "Dropped", "webhook", "testing",
+		`{"max_notifications_per_hour": 1}`)
+	for _, workflow := range []*models.Workflow{queued, dropped} {
+		for i := 0; i < 3; i++ {
+			executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+		}
+	}
+
+	// One queued run per freed slot
+	if flushed := executor.FlushThrottled(time.Now().Add(time.Hour)); flushed != 1 {
+		t.Fatalf("Expected one queued run released, got %d", flushed)
+	}
+	statuses := func(workflowID string) map[string]int {
+		counts := make(map[string]int)
+		logs, _ := database.GetLogsByWorkflowID(workflowID)
+		for _, log := range logs {
+			counts[log.Status]++
+		}
+		return counts
+	}
+	waitFor(t, "the queued run", func() bool { return statuses(queued.ID)["success"] == 2 })
+
+	if counts := statuses(queued.ID); counts[models.StatusSuppressed] != 2 {
+		t.Errorf("Expected the queued workflow's overflow logged as suppressed, got %v", counts)
+	}
+	if counts := statuses(dropped.ID); counts["success"] != 1 || counts[models.StatusSuppressed] != 2 {
+		t.Errorf("Expected 1 sent and 2 dropped, got %v", counts)
+	}
+	if flushed := executor.FlushThrottled(time.Now().Add(time.Hour)); flushed != 0 {
+		t.Errorf("Expected the remaining queued run to wait for the next slot, got %d flushed", flushed)
+	}
+}
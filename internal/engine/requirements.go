@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// connectionsSetupURL is the frontend page where users add credentials
+const connectionsSetupURL = "/dashboard/connections?service=%s"
+
+// CheckWorkflowRequirements builds the credential checklist for a workflow
+// Inspects the primary action and every chained action, then compares the
+// needed services against the credentials the user has already saved.
+// A service connected only in the other environment than the workflow's is
+// flagged as a mismatch, which blocks readiness when the tenant enforces it
+func CheckWorkflowRequirements(store db.Store, workflow models.Workflow, userID string) (*models.WorkflowRequirements, error) {
+	actionTypes := []string{workflow.ActionType}
+	if workflow.ActionChain != "" {
+		var chain []models.ChainedAction
+		if err := json.Unmarshal([]byte(workflow.ActionChain), &chain); err != nil {
+			return nil, fmt.Errorf("failed to parse action chain: %w", err)
+		}
+		for _, action := range chain {
+			actionTypes = append(actionTypes, action.ActionType)
+		}
+	}
+
+	creds, err := store.GetCredentialsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch credentials: %w", err)
+	}
+	// Environments each service is connected in
+	connected := make(map[string]map[string]bool, len(creds))
+	for _, cred := range creds {
+		if connected[cred.ServiceName] == nil {
+			connected[cred.ServiceName] = make(map[string]bool)
+		}
+		environment := cred.Environment
+		if environment == "" {
+			environment = models.EnvironmentLive
+		}
+		connected[cred.ServiceName][environment] = true
+	}
+
+	var config models.WorkflowConfig
+	json.Unmarshal([]byte(workflow.ConfigJSON), &config)
+	environment := WorkflowEnvironment(config)
+
+	policy := models.CredentialPolicyWarn
+	if settings, err := store.GetTenantSettings(workflow.TenantID); err == nil && settings.CredentialPolicy != "" {
+		policy = settings.CredentialPolicy
+	}
+
+	checklist := &models.WorkflowRequirements{
+		WorkflowID:   workflow.ID,
+		Ready:        true,
+		Environment:  environment,
+		Requirements: []models.CredentialRequirementStatus{},
+	}
+
+	// Index by service so a service used by several steps appears once
+	index := make(map[string]int)
+	for _, actionType := range actionTypes {
+		for _, req := range connectors.CredentialRequirementsFor(actionType) {
+			if i, seen := index[req.Service]; seen {
+				entry := &checklist.Requirements[i]
+				entry.ActionTypes = appendUnique(entry.ActionTypes, actionType)
+				// Required by any step means required overall
+				if !req.Optional && entry.Optional {
+					entry.Optional = false
+					if entry.Status == "missing" || (entry.Status == "environment_mismatch" && policy == models.CredentialPolicyEnforce) {
+						checklist.Ready = false
+					}
+				}
+				continue
+			}
+
+			entry := models.CredentialRequirementStatus{
+				Service:     req.Service,
+				Label:       req.Label,
+				ActionTypes: []string{actionType},
+				Optional:    req.Optional,
+				Status:      "connected",
+				Environment: environment,
+			}
+			switch other := otherEnvironment(environment); {
+			case connected[req.Service][environment]:
+			case connected[req.Service][other]:
+				entry.Status = "environment_mismatch"
+				entry.Environment = other
+				entry.Hint = fmt.Sprintf("This workflow runs in %s, but %s is only connected with %s credentials", environment, req.Label, other)
+				entry.SetupURL = fmt.Sprintf(connectionsSetupURL, req.Service)
+				if !req.Optional && policy == models.CredentialPolicyEnforce {
+					checklist.Ready = false
+				}
+			default:
+				entry.Status = "missing"
+				entry.Environment = ""
+				entry.Hint = req.Hint
+				entry.SetupURL = fmt.Sprintf(connectionsSetupURL, req.Service)
+				if !req.Optional {
+					checklist.Ready = false
+				}
+			}
+
+			index[req.Service] = len(checklist.Requirements)
+			checklist.Requirements = append(checklist.Requirements, entry)
+		}
+	}
+
+	return checklist, nil
+}
+
+// otherEnvironment returns the environment that isn't environment
+func otherEnvironment(environment string) string {
+	if environment == models.EnvironmentTest {
+		return models.EnvironmentLive
+	}
+	return models.EnvironmentTest
+}
+
+// appendUnique appends value to list if it is not already present
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,54 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+)
+
+// TestCheckWorkflowRequirements builds the checklist for a chained workflow from the
+// connector registry: a missing credential blocks readiness with a hint and setup link,
+// an optional one doesn't, and connecting every required service makes it ready
+func TestCheckWorkflowRequirements(t *testing.T) {
+	database := dbtest.New(t)
+	user, _ := database.CreateUser("requirements@example.com", "hashed")
+	chain := `[{"action_type": "discord_post", "config": {}}, {"action_type": "cat_fetch", "config": {}}, {"action_type": "discord_post", "config": {}}]`
+	workflow, err := database.CreateWorkflowWithChain(user.ID, "Weather to Discord", "schedule", "weather_check", `{"interval": 60, "city": "London"}`, chain)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	database.CreateCredential(user.ID, "discord", "https://discord.com/api/webhooks/1/abc")
+
+	checklist, err := engine.CheckWorkflowRequirements(database, *workflow, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to check requirements: %v", err)
+	}
+	if checklist.Ready || checklist.WorkflowID != workflow.ID || len(checklist.Requirements) != 3 {
+		t.Fatalf("Expected an unready checklist of three services, got %+v", checklist)
+	}
+	statuses := map[string]string{}
+	for _, entry := range checklist.Requirements {
+		statuses[entry.Service] = entry.Status
+	}
+	if statuses["openweather"] != "missing" || statuses["discord"] != "connected" || statuses["catapi"] != "missing" {
+		t.Errorf("Expected openweather and catapi missing and discord connected, got %v", statuses)
+	}
+	weather := checklist.Requirements[0]
+	if weather.Service != "openweather" || weather.Hint == "" || weather.SetupURL != "/dashboard/connections?service=openweather" {
+		t.Errorf("Expected a hint and setup link for openweather, got %+v", weather)
+	}
+	if discord := checklist.Requirements[1]; len(discord.ActionTypes) != 1 || discord.SetupURL != "" {
+		t.Errorf("Expected discord listed once with no setup link, got %+v", discord)
+	}
+
+	// The optional Cat API key doesn't hold the workflow back
+	database.CreateCredential(user.ID, "openweather", "weather-key")
+	checklist, err = engine.CheckWorkflowRequirements(database, *workflow, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to check requirements: %v", err)
+	}
+	if !checklist.Ready || checklist.Requirements[0].Status != "connected" || !checklist.Requirements[2].Optional {
+		t.Errorf("Expected the workflow ready once its required services are connected, got %+v", checklist)
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// runGuard tracks which workflows have a run in flight, so a slow run isn't
+// overlapped by the next scheduled tick or a burst of webhooks.
+// The guard lives in memory, so it only covers runs on this instance.
+type runGuard struct {
+	mu      sync.Mutex
+	next    uint64
+	running map[string]runHold
+}
+
+// runHold is one in-flight run of a workflow
+type runHold struct {
+	token   uint64
+	since   time.Time
+	expires time.Time
+}
+
+func newRunGuard() *runGuard {
+	return &runGuard{running: make(map[string]runHold)}
+}
+
+// acquire marks a run of the workflow as in flight. If another run already is,
+// it returns false along with when that run started. A hold older than the
+// workflow's time limit is taken over, so a run the watchdog abandoned
+// doesn't block the workflow for good.
+func (g *runGuard) acquire(workflow models.Workflow, now time.Time) (release func(), since time.Time, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if hold, held := g.running[workflow.ID]; held && now.Before(hold.expires) {
+		return nil, hold.since, false
+	}
+
+	g.next++
+	hold := runHold{
+		token:   g.next,
+		since:   now,
+		expires: now.Add(workflowTimeout(workflow, WorkflowTimeout).limit),
+	}
+	g.running[workflow.ID] = hold
+
+	return func() { g.release(workflow.ID, hold.token) }, now, true
+}
+
+// release clears the hold, unless a later run has since taken it over
+func (g *runGuard) release(workflowID string, token uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if hold, held := g.running[workflowID]; held && hold.token == token {
+		delete(g.running, workflowID)
+	}
+}
+
+// allowsConcurrentRuns reports whether the workflow opted into overlapping runs
+func allowsConcurrentRuns(workflow models.Workflow) bool {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(workflow.ConfigJSON), &config); err != nil {
+		return false
+	}
+	return config.AllowConcurrent
+}
+
+// skipOverlap records a run skipped because the previous one is still going.
+// The schedule still moves on, so the scheduler doesn't retry on every tick.
+func (e *Executor) skipOverlap(ctx context.Context, workflow models.Workflow, payload, tenantID string, since time.Time) connectors.Result {
+	result := connectors.Result{
+		Status:  models.StatusSkippedOverlap,
+		Message: fmt.Sprintf("Skipped: the run started at %s is still in progress", since.UTC().Format(time.RFC3339)),
+		Data: map[string]interface{}{
+			"running_since": since.UTC().Format(time.RFC3339),
+		},
+	}
+
+	e.log.WorkflowLogContext(
+		ctx,
+		logger.LevelWarn,
+		"Workflow run skipped, previous run still in progress",
+		workflow.ID,
+		workflow.UserID,
+		tenantID,
+		map[string]interface{}{
+			"running_since": since.UTC().Format(time.RFC3339),
+		},
+	)
+
+	e.advanceSchedule(ctx, workflow)
+	e.metrics.ObserveExecution(workflow.ActionType, result.Status, 0)
+
+	e.createLog(workflow, &models.Log{
+		WorkflowID: workflow.ID,
+		Status:     result.Status,
+		Message:    result.Message,
+		RequestID:  logger.RequestIDFromContext(ctx),
+	})
+	e.recordExecution(ctx, workflow, payload, tenantID, result, 0)
+
+	return result
+}
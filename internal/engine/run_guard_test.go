@@ -0,0 +1,83 @@
+package engine_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// runTwice starts two synchronous runs of the workflow at the same time and returns both results
+func runTwice(executor *engine.Executor, workflow models.Workflow) []connectors.Result {
+	results := make([]connectors.Result, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = executor.ExecuteWorkflowSync(context.Background(), workflow, "", "", 10*time.Second)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// TestOverlappingRunSkipped runs a slow testing workflow twice at once and checks the second
+// run is skipped and recorded as such, unless the workflow sets allow_concurrent
+func TestOverlappingRunSkipped(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("overlap@example.com", "hashed")
+
+	t.Run("default", func(t *testing.T) {
+		workflow, err := mockStore.CreateWorkflow(user.ID, "Slow Sync", "webhook", "testing", `{"testing_delay": 300}`)
+		if err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+
+		statuses := map[string]int{}
+		for _, result := range runTwice(executor, *workflow) {
+			statuses[result.Status]++
+		}
+		if statuses["success"] != 1 || statuses[models.StatusSkippedOverlap] != 1 {
+			t.Fatalf("Expected one success and one skipped run, got %v", statuses)
+		}
+
+		executions, _ := mockStore.ListExecutions(workflow.ID, models.PageRequest{Limit: 10})
+		if len(executions) != 2 {
+			t.Fatalf("Expected both runs in the executions listing, got %d", len(executions))
+		}
+		logs, _ := mockStore.GetLogsByWorkflowID(workflow.ID)
+		skipped := 0
+		for _, log := range logs {
+			if log.Status == models.StatusSkippedOverlap {
+				skipped++
+			}
+		}
+		if skipped != 1 {
+			t.Errorf("Expected one skipped_overlap log, got %d of %d", skipped, len(logs))
+		}
+
+		// Once the first run finishes the workflow runs again
+		if result, _ := executor.ExecuteWorkflowSync(context.Background(), *workflow, "", "", 10*time.Second); result.Status != "success" {
+			t.Errorf("Expected a later run to go ahead, got %s: %s", result.Status, result.Message)
+		}
+	})
+
+	t.Run("allow_concurrent", func(t *testing.T) {
+		workflow, _ := mockStore.CreateWorkflow(user.ID, "Parallel", "webhook", "testing", `{"testing_delay": 300, "allow_concurrent": true}`)
+
+		for _, result := range runTwice(executor, *workflow) {
+			if result.Status != "success" {
+				t.Errorf("Expected both runs to succeed, got %s: %s", result.Status, result.Message)
+			}
+		}
+	})
+}
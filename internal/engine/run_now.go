@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/google/uuid"
+)
+
+// TriggerManual is the trigger source recorded for runs started with RunNow
+const TriggerManual = "manual"
+
+// manualRun describes a run started from the API rather than by its trigger
+type manualRun struct {
+	executionID      string // Reserved up front so the caller can follow the run
+	countAsScheduled bool   // Moves a scheduled workflow's next run as a scheduled run would
+}
+
+// manualRunKey marks a context whose execution was started with RunNow
+type manualRunKey struct{}
+
+// withManualRun returns a context whose execution is recorded as a manual run
+func withManualRun(ctx context.Context, run *manualRun) context.Context {
+	return context.WithValue(ctx, manualRunKey{}, run)
+}
+
+// manualRunFrom returns the manual run a context's execution belongs to, if any
+func manualRunFrom(ctx context.Context) (*manualRun, bool) {
+	run, ok := ctx.Value(manualRunKey{}).(*manualRun)
+	return run, ok
+}
+
+// RunNow queues a saved workflow to run with the payload, like its trigger would, and
+// returns the ID its execution trace will be recorded under
+// A scheduled workflow keeps its next run unless countAsScheduled is set
+func (e *Executor) RunNow(workflow models.Workflow, payload string, countAsScheduled bool) string {
+	run := &manualRun{executionID: uuid.New().String(), countAsScheduled: countAsScheduled}
+	e.pool.Submit(WorkflowJob{
+		Workflow: workflow,
+		Payload:  payload,
+		Executor: e,
+		Run: func(ctx context.Context) {
+			e.ExecuteWorkflowWithContext(withManualRun(ctx, run), workflow, payload)
+		},
+	})
+	return run.executionID
+}
+
+// RunNowSync is RunNow run in the caller's goroutine: it returns once the workflow has
+// finished, with its logs and trace recorded like any other run
+func (e *Executor) RunNowSync(workflow models.Workflow, payload string, countAsScheduled bool) (string, connectors.Result) {
+	run := &manualRun{executionID: uuid.New().String(), countAsScheduled: countAsScheduled}
+	ctx, cancel := withRunTimeout(withManualRun(context.Background(), run), workflow, WorkflowTimeout)
+	defer cancel()
+	return run.executionID, e.execute(ctx, workflow, payload)
+}
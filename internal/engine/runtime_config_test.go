@@ -0,0 +1,105 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// waitForLogCount polls until the workflow has n log entries
+func waitForLogCount(t *testing.T, database *db.Database, workflowID string, n int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		logs, err := database.GetLogsByWorkflowID(workflowID)
+		if err != nil {
+			t.Fatalf("Failed to fetch logs: %v", err)
+		}
+		if len(logs) >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d logs, have %d", n, len(logs))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestWorkerPoolResize grows the pool under load, then shrinks it with jobs in flight
+// Every job must still complete
+func TestWorkerPoolResize(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+
+	user, err := database.CreateUser("resize@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	workflow, err := database.CreateWorkflow(user.ID, "Slow", "webhook", "testing", `{"testing_delay": 200}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	pool := engine.NewWorkerPool(1, testLogger)
+	pool.Start()
+	pool.Resize(4)
+	if pool.WorkerCount() != 4 {
+		t.Fatalf("Expected 4 workers, got %d", pool.WorkerCount())
+	}
+
+	// 8 jobs of 200ms take 1.6s on one worker and ~400ms on four
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		pool.Submit(engine.WorkflowJob{Workflow: *workflow, Executor: executor})
+	}
+	waitForLogCount(t, database, workflow.ID, 8, 5*time.Second)
+	if elapsed := time.Since(start); elapsed > 1200*time.Millisecond {
+		t.Errorf("Expected resized pool to run jobs concurrently, took %s", elapsed)
+	}
+
+	// Shrinking lets in-flight jobs finish and leaves queued jobs for the remaining worker
+	for i := 0; i < 4; i++ {
+		pool.Submit(engine.WorkflowJob{Workflow: *workflow, Executor: executor})
+	}
+	pool.Resize(1)
+	if pool.WorkerCount() != 1 {
+		t.Fatalf("Expected 1 worker, got %d", pool.WorkerCount())
+	}
+	waitForLogCount(t, database, workflow.ID, 12, 5*time.Second)
+}
+
+// TestServiceLimitsThrottleOutboundCalls caps an action type at 2 calls/sec
+func TestServiceLimitsThrottleOutboundCalls(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+	executor.SetServiceLimits(map[string]float64{"testing": 2})
+
+	workflow := models.Workflow{ID: "wf_limited", ActionType: "testing", ConfigJSON: `{}`}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if result := executor.DryRun(workflow, "user", "tenant_user"); result.Status != "success" {
+			t.Fatalf("Run %d failed: %s", i, result.Message)
+		}
+	}
+	// The burst of 2 passes immediately; the third call waits ~500ms
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the third call to be throttled, took %s", elapsed)
+	}
+
+	// Removing the limit applies immediately
+	executor.SetServiceLimits(nil)
+	start = time.Now()
+	for i := 0; i < 5; i++ {
+		executor.DryRun(workflow, "user", "tenant_user")
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("Expected unthrottled calls after removing the limit, took %s", elapsed)
+	}
+}
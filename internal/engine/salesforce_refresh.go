@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// errSalesforceNoRefresh means the credential holds only an access token, so an expired
+// one can't be renewed without the user pasting a new one
+var errSalesforceNoRefresh = errors.New("salesforce credential has no client_id, client_secret, username and password to refresh with")
+
+// userLocks hands out one mutex per user, so work for the same user runs one at a time
+type userLocks struct {
+	locks sync.Map // userID -> *sync.Mutex
+}
+
+func newUserLocks() *userLocks {
+	return &userLocks{}
+}
+
+// lock blocks until the user's mutex is held and returns its unlock
+func (u *userLocks) lock(userID string) func() {
+	mu, _ := u.locks.LoadOrStore(userID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// salesforceAuth reads the optional OAuth username-password flow settings stored with
+// the salesforce credential; ok is false when they are incomplete
+func salesforceAuth(sfCreds map[string]string) (auth connectors.SalesforceAuthConfig, ok bool) {
+	auth = connectors.SalesforceAuthConfig{
+		ClientID:      sfCreds["client_id"],
+		ClientSecret:  sfCreds["client_secret"],
+		Username:      sfCreds["username"],
+		Password:      sfCreds["password"],
+		SecurityToken: sfCreds["security_token"],
+		LoginURL:      sfCreds["login_url"],
+	}
+	return auth, auth.ClientID != "" && auth.ClientSecret != "" && auth.Username != "" && auth.Password != ""
+}
+
+// refreshSalesforceToken replaces an expired access token using the credential's OAuth
+// settings and saves it, returning the updated credential fields
+// Refreshes are serialized per user: a caller that waited on another's refresh finds the
+// token already replaced and reuses it rather than calling the token endpoint again
+func (e *Executor) refreshSalesforceToken(ctx context.Context, userID, tenantID, expiredToken string) (map[string]string, error) {
+	unlock := e.salesforceRefreshes.lock(userID)
+	defer unlock()
+
+	cred, err := e.getCredential(ctx, userID, tenantID, "salesforce")
+	if err != nil {
+		return nil, err
+	}
+	var sfCreds map[string]string
+	if err := json.Unmarshal([]byte(cred.DecryptedKey), &sfCreds); err != nil {
+		return nil, fmt.Errorf("invalid Salesforce credentials format: %w", err)
+	}
+	if sfCreds["access_token"] != expiredToken {
+		return sfCreds, nil
+	}
+
+	auth, ok := salesforceAuth(sfCreds)
+	if !ok {
+		return nil, errSalesforceNoRefresh
+	}
+	token, err := (&connectors.SalesforceConnector{}).Authenticate(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("Salesforce token response has no access_token")
+	}
+	sfCreds["access_token"] = token.AccessToken
+	if token.InstanceURL != "" {
+		sfCreds["instance_url"] = token.InstanceURL
+	}
+
+	encoded, err := json.Marshal(sfCreds)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.store.UpdateCredential(cred.ID, string(encoded)); err != nil {
+		return nil, fmt.Errorf("failed to save refreshed Salesforce token: %w", err)
+	}
+	e.log.Info("Salesforce access token refreshed", map[string]interface{}{
+		"user_id":       userID,
+		"tenant_id":     tenantID,
+		"credential_id": cred.ID,
+	})
+	return sfCreds, nil
+}
@@ -0,0 +1,114 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// salesforceStub serves the token endpoint and a query endpoint that only accepts the
+// token it issued last, answering 401 INVALID_SESSION_ID to any other
+func salesforceStub(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var logins int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			r.ParseForm()
+			if r.PostForm.Get("client_id") != "cid" || r.PostForm.Get("password") != "pwtok" {
+				t.Errorf("Unexpected token request: %v", r.PostForm)
+			}
+			atomic.AddInt32(&logins, 1)
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "fresh", "instance_url": srv.URL})
+		case "/services/data/v59.0/query":
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				io.WriteString(w, `[{"message": "Session expired or invalid", "errorCode": "INVALID_SESSION_ID"}]`)
+				return
+			}
+			io.WriteString(w, `{"totalSize": 1, "done": true, "records": [{"Name": "Acme"}]}`)
+		default:
+			t.Errorf("Unexpected Salesforce request %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &logins
+}
+
+// TestSalesforceTokenRefresh renews an expired token, saves it and retries the query once
+func TestSalesforceTokenRefresh(t *testing.T) {
+	srv, logins := salesforceStub(t)
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("salesforce@example.com", "hashed")
+	credential, _ := json.Marshal(map[string]string{
+		"instance_url": srv.URL, "access_token": "expired",
+		"client_id": "cid", "client_secret": "secret", "username": "ops@acme.com",
+		"password": "pw", "security_token": "tok", "login_url": srv.URL,
+	})
+	database.CreateCredential(user.ID, "salesforce", string(credential))
+
+	workflow, _ := database.CreateWorkflow(user.ID, "Accounts", "webhook", "salesforce",
+		`{"salesforce_operation": "query", "salesforce_query": "SELECT Name FROM Account", "allow_concurrent": true}`)
+
+	// Several runs hit the expired token at once; only one of them logs in
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(logins); n != 1 {
+		t.Errorf("Expected concurrent refreshes to share one login, got %d", n)
+	}
+	logs, _ := database.GetLogsByWorkflowID(workflow.ID)
+	if len(logs) != 4 {
+		t.Fatalf("Expected 4 runs, got %d", len(logs))
+	}
+	for _, log := range logs {
+		if log.Status != "success" {
+			t.Errorf("Expected every run to succeed after the refresh, got %+v", log)
+		}
+	}
+
+	saved, err := database.GetCredentialByUserAndService(user.ID, "salesforce")
+	if err != nil || !strings.Contains(saved.DecryptedKey, `"access_token":"fresh"`) || !strings.Contains(saved.DecryptedKey, `"client_id":"cid"`) {
+		t.Errorf("Expected the refreshed token to be saved with the OAuth settings, got %v, %v", saved, err)
+	}
+}
+
+// TestSalesforceExpiredTokenWithoutRefresh keeps the 401 when the credential can't be renewed
+func TestSalesforceExpiredTokenWithoutRefresh(t *testing.T) {
+	srv, logins := salesforceStub(t)
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("salesforce@example.com", "hashed")
+	database.CreateCredential(user.ID, "salesforce", `{"instance_url": "`+srv.URL+`", "access_token": "expired"}`)
+
+	workflow, _ := database.CreateWorkflow(user.ID, "Accounts", "webhook", "salesforce",
+		`{"salesforce_operation": "query", "salesforce_query": "SELECT Name FROM Account"}`)
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, "")
+
+	execution, err := database.GetLatestExecution(workflow.ID)
+	if err != nil || execution.Status != "failed" || !strings.Contains(execution.Message, "INVALID_SESSION_ID") {
+		t.Errorf("Expected the expired session to fail the run, got %+v, %v", execution, err)
+	}
+	if atomic.LoadInt32(logins) != 0 {
+		t.Error("Expected no login without OAuth settings")
+	}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// sandboxKey marks a context whose execution must not cause side effects
+type sandboxKey struct{}
+
+// WithSandbox returns a context in which connectors that send or write
+// (Slack, Discord, Twilio, Salesforce writes, SOAP) render their output but don't call out
+func WithSandbox(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sandboxKey{}, true)
+}
+
+// IsSandbox reports whether execution is running in sandbox mode
+func IsSandbox(ctx context.Context) bool {
+	sandboxed, _ := ctx.Value(sandboxKey{}).(bool)
+	return sandboxed
+}
+
+// sandboxResult is reported by a side-effecting connector instead of calling out
+// data carries what would have been sent, so dry runs can still be compared
+func sandboxResult(message string, data map[string]interface{}) connectors.Result {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["sandbox"] = true
+	return connectors.Result{
+		Status:    "success",
+		Message:   "Sandbox: " + message,
+		Data:      data,
+		Duration:  "0s",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
@@ -1,12 +1,13 @@
 package engine
 
 import (
-	"encoding/json"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/alexmacdonald/simple-ipass/internal/db"
 	"github.com/alexmacdonald/simple-ipass/internal/logger"
-	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
 )
 
 // Scheduler handles scheduled workflow execution with tenant-aware rate limiting
@@ -15,8 +16,21 @@ type Scheduler struct {
 	store    db.Store // Interface, not concrete type!
 	executor *Executor
 	ticker   *time.Ticker
+	interval time.Duration
+	mu       sync.Mutex // Guards ticker and interval, which SetInterval changes at runtime
 	done     chan bool
 	log      *logger.Logger
+	// logAutoAckAge acknowledges log entries older than this on each tick (0 disables)
+	logAutoAckAge time.Duration
+	// catchUpJitter spreads runs missed during downtime over this long after startup
+	catchUpJitter time.Duration
+	stopPending   chan struct{} // Closed by Stop to abandon catch-up and jittered runs not yet started
+	stopOnce      sync.Once     // Stop may be called more than once (deferred and on shutdown)
+
+	// Fairness between tenants when many workflows are due on one tick; guarded by mu
+	jitter            bool            // Spread each tick's submissions over the interval
+	tenantConcurrency int             // Most jobs a tenant may have queued or running before its due workflows wait a tick (0 = no cap)
+	waiting           map[string]bool // Workflows whose jittered submission hasn't happened yet
 	// MULTI-TENANT: Future fields for rate limiting
 	// tenantRateLimits map[string]time.Duration
 }
@@ -28,21 +42,58 @@ func NewScheduler(store db.Store, executor *Executor, log *logger.Logger) *Sched
 		executor: executor,
 		done:     make(chan bool),
 		log:      log,
+
+		catchUpJitter: DefaultCatchUpJitter,
+		stopPending:   make(chan struct{}),
+		waiting:       make(map[string]bool),
 	}
 }
 
+// SetJitter spreads the workflows due on a tick over the tick interval instead of
+// submitting them all at once
+func (s *Scheduler) SetJitter(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jitter = enabled
+}
+
+// SetTenantConcurrency caps the jobs a tenant may have queued or running when the
+// scheduler submits its due workflows; the rest stay due and are retried next tick
+// Webhook and manual runs count towards the cap but are never held back (0 = no cap)
+func (s *Scheduler) SetTenantConcurrency(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenantConcurrency = limit
+}
+
+// SetLogAutoAckAge configures age-based auto-acknowledgment of log entries
+// Keeps the unacknowledged failures list limited to recent problems
+func (s *Scheduler) SetLogAutoAckAge(age time.Duration) {
+	s.logAutoAckAge = age
+}
+
 // Start begins the scheduler loop
+// Runs missed while the server was down are reconciled first (see Reconcile)
 func (s *Scheduler) Start(interval time.Duration) {
+	s.mu.Lock()
 	s.ticker = time.NewTicker(interval)
+	s.interval = interval
+	s.mu.Unlock()
 	s.log.Info("Scheduler started", map[string]interface{}{
-		"interval": interval.String(),
+		"interval":         interval.String(),
+		"log_auto_ack_age": s.logAutoAckAge.String(),
 	})
+	s.Reconcile(time.Now())
 
 	go func() {
 		for {
 			select {
 			case <-s.ticker.C:
-				s.checkAndExecute()
+				s.executor.metrics.SchedulerTick()
+				s.RunDue(s.executor.clock())
+				s.autoAcknowledgeLogs()
+				s.pruneWebhookEvents()
+				s.pruneRefreshTokens()
 			case <-s.done:
 				s.log.Info("Scheduler stopped", nil)
 				return
@@ -51,17 +102,54 @@ func (s *Scheduler) Start(interval time.Duration) {
 	}()
 }
 
-// Stop stops the scheduler
-func (s *Scheduler) Stop() {
+// SetInterval changes how often the scheduler checks for due workflows
+// Takes effect from the next tick; a check already running is not interrupted
+func (s *Scheduler) SetInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if interval <= 0 || interval == s.interval {
+		return
+	}
+	previous := s.interval
+	s.interval = interval
 	if s.ticker != nil {
-		s.ticker.Stop()
+		s.ticker.Reset(interval)
 	}
-	s.done <- true
+	s.log.Info("Scheduler interval changed", map[string]interface{}{
+		"previous_interval": previous.String(),
+		"interval":          interval.String(),
+	})
+}
+
+// Stop stops the scheduler; calls after the first do nothing
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		started := s.ticker != nil
+		if started {
+			s.ticker.Stop()
+		}
+		s.mu.Unlock()
+		close(s.stopPending)
+		if started {
+			s.done <- true // Only a started scheduler has a loop to receive it
+		}
+	})
 }
 
-// checkAndExecute checks for scheduled workflows that need to run
+// RunDue starts every scheduled workflow due at now and returns how many it started
+// One indexed query finds them (next_run_at <= now); the executor moves each to its
+// next slot when it runs, so a workflow that isn't due is never read
 // PRODUCTION: Uses panic recovery to prevent one bad workflow from crashing scheduler
-func (s *Scheduler) checkAndExecute() {
+// A tenant at its concurrency cap keeps its remaining due workflows for the next tick
+func (s *Scheduler) RunDue(now time.Time) int {
+	executedCount := 0
+	deferred := 0
+	s.mu.Lock()
+	jitter, limit, tickInterval := s.jitter, s.tenantConcurrency, s.interval
+	s.mu.Unlock()
+
 	// PRODUCTION FIX: Recover from panics to keep scheduler running
 	defer func() {
 		if r := recover(); r != nil {
@@ -71,17 +159,14 @@ func (s *Scheduler) checkAndExecute() {
 		}
 	}()
 
-	workflows, err := s.store.GetActiveScheduledWorkflows()
+	workflows, err := s.store.GetDueWorkflows(now)
 	if err != nil {
-		s.log.Error("Failed to fetch scheduled workflows", map[string]interface{}{
+		s.log.Error("Failed to fetch due workflows", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return
+		return 0
 	}
 
-	now := time.Now()
-	executedCount := 0
-
 	for _, workflow := range workflows {
 		// PRODUCTION FIX: Wrap each workflow execution in its own recovery
 		func() {
@@ -95,79 +180,158 @@ func (s *Scheduler) checkAndExecute() {
 				}
 			}()
 
+			s.mu.Lock()
+			waiting := s.waiting[workflow.ID]
+			s.mu.Unlock()
+			if waiting {
+				return // Still waiting for its jittered submission from an earlier tick
+			}
+
 			// PRODUCTION FIX: Re-check is_active before execution
 			// (user might have disabled it milliseconds ago)
-			currentWorkflow, err := s.store.GetWorkflowByID(workflow.ID)
+			currentWorkflow, err := s.store.GetActiveWorkflow(workflow.ID)
+			if store.IsNotFound(err) {
+				s.log.Debug("Workflow deleted or disabled before execution", map[string]interface{}{
+					"workflow_id": workflow.ID,
+				})
+				return
+			}
 			if err != nil {
-				s.log.Warn("Workflow not found during execution check", map[string]interface{}{
+				s.log.Warn("Failed to re-check workflow before execution", map[string]interface{}{
 					"workflow_id": workflow.ID,
 					"error":       err.Error(),
 				})
 				return
 			}
-
-			if !currentWorkflow.IsActive {
-				s.log.Debug("Workflow disabled before execution", map[string]interface{}{
-					"workflow_id": workflow.ID,
+			if currentWorkflow.PausedAt(now) {
+				s.log.Debug("Workflow paused before execution", map[string]interface{}{
+					"workflow_id":  workflow.ID,
+					"paused_until": currentWorkflow.PausedUntil,
 				})
 				return
 			}
 
-			// Parse config to get interval
-			var config models.WorkflowConfig
-			if err := json.Unmarshal([]byte(workflow.ConfigJSON), &config); err != nil {
-				s.log.Error("Failed to parse workflow config", map[string]interface{}{
+			// MULTI-TENANT: One tenant's backlog can't take every worker
+			if !s.executor.pool.reserveTenant(currentWorkflow.TenantID, limit) {
+				deferred++
+				s.log.Debug("Tenant at its concurrency cap, workflow deferred to the next tick", map[string]interface{}{
 					"workflow_id": workflow.ID,
-					"user_id":     workflow.UserID,
-					"error":       err.Error(),
+					"tenant_id":   workflow.TenantID,
+					"cap":         limit,
 				})
 				return
 			}
 
-		// Default interval is 10 minutes if not specified
-		interval := config.Interval
-		if interval <= 0 {
-			interval = 10
-		}
+			// MULTI-TENANT: Check tenant-specific rate limits
+			// if customInterval := s.getTenantRateLimit(workflow.TenantID); customInterval > 0 {
+			//     interval = customInterval
+			// }
 
-		// MULTI-TENANT: Check tenant-specific rate limits
-		// tenantID := "tenant_" + workflow.UserID // Phase 1
-		// if customInterval := s.getTenantRateLimit(tenantID); customInterval > 0 {
-		//     interval = customInterval
-		// }
-
-		// Check if enough time has passed since last execution
-		shouldExecute := false
-		if workflow.LastExecutedAt == nil {
-			shouldExecute = true
-		} else {
-			timeSinceLastExecution := now.Sub(*workflow.LastExecutedAt)
-			if timeSinceLastExecution >= time.Duration(interval)*time.Minute {
-				shouldExecute = true
-			}
-		}
-
-			if shouldExecute {
-				s.log.InfoWithContext(
-					"Triggering scheduled workflow",
-					workflow.UserID,
-					"tenant_"+workflow.UserID, // Phase 1: user is tenant
-					map[string]interface{}{
-						"workflow_id":   workflow.ID,
-						"workflow_name": workflow.Name,
-						"interval":      interval,
-					},
-				)
-				s.executor.ExecuteWorkflow(*currentWorkflow)
-				executedCount++
+			interval, _ := currentWorkflow.ScheduleInterval()
+			s.log.InfoWithContext(
+				"Triggering scheduled workflow",
+				workflow.UserID,
+				workflow.TenantID,
+				map[string]interface{}{
+					"workflow_id":   workflow.ID,
+					"workflow_name": workflow.Name,
+					"interval":      int(interval / time.Minute),
+					"due_at":        workflow.NextRunAt,
+				},
+			)
+			job := WorkflowJob{Workflow: *currentWorkflow, Executor: s.executor, reserved: true}
+			if jitter && tickInterval > 0 {
+				s.submitLater(job, time.Duration(rand.Int63n(int64(tickInterval))))
+			} else {
+				s.executor.pool.Submit(job)
 			}
+			executedCount++
 		}() // End of panic-recovery wrapper
 	}
 
-	if executedCount > 0 {
+	if executedCount > 0 || deferred > 0 {
 		s.log.Info("Scheduler tick completed", map[string]interface{}{
-			"total_workflows": len(workflows),
-			"executed":        executedCount,
+			"due_workflows": len(workflows),
+			"executed":      executedCount,
+			"deferred":      deferred,
+		})
+	}
+	return executedCount
+}
+
+// submitLater submits a scheduled job after delay, unless the scheduler stops first
+// Until then the workflow is skipped by later ticks, as it is still due
+func (s *Scheduler) submitLater(job WorkflowJob, delay time.Duration) {
+	s.mu.Lock()
+	s.waiting[job.Workflow.ID] = true
+	s.mu.Unlock()
+
+	go func() {
+		select {
+		case <-time.After(delay):
+		case <-s.stopPending:
+			s.executor.pool.releaseTenant(job.Workflow.TenantID)
+			return
+		}
+		s.mu.Lock()
+		delete(s.waiting, job.Workflow.ID)
+		s.mu.Unlock()
+		s.executor.pool.Submit(job)
+	}()
+}
+
+// autoAcknowledgeLogs acknowledges log entries older than the configured age
+func (s *Scheduler) autoAcknowledgeLogs() {
+	if s.logAutoAckAge <= 0 {
+		return
+	}
+
+	count, err := s.store.AutoAcknowledgeLogs(time.Now().Add(-s.logAutoAckAge))
+	if err != nil {
+		s.log.Error("Failed to auto-acknowledge logs", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if count > 0 {
+		s.log.Info("Auto-acknowledged old log entries", map[string]interface{}{
+			"count":   count,
+			"max_age": s.logAutoAckAge.String(),
+		})
+	}
+}
+
+// pruneWebhookEvents deletes expired webhook event IDs from the dedupe table
+func (s *Scheduler) pruneWebhookEvents() {
+	count, err := s.store.PruneWebhookEvents(time.Now())
+	if err != nil {
+		s.log.Error("Failed to prune webhook events", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if count > 0 {
+		s.log.Debug("Pruned expired webhook events", map[string]interface{}{
+			"count": count,
+		})
+	}
+}
+
+// pruneRefreshTokens deletes refresh tokens that were used, logged out or have expired
+func (s *Scheduler) pruneRefreshTokens() {
+	count, err := s.store.PruneRefreshTokens(time.Now())
+	if err != nil {
+		s.log.Error("Failed to prune refresh tokens", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if count > 0 {
+		s.log.Debug("Pruned closed refresh tokens", map[string]interface{}{
+			"count": count,
 		})
 	}
 }
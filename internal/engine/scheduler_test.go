@@ -0,0 +1,254 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestConcurrentLogWritesWhileSchedulerRuns proves workers writing logs never
+// see "database is locked" while the scheduler reads and updates workflows
+func TestConcurrentLogWritesWhileSchedulerRuns(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+
+	user, err := database.CreateUser("stress@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// Scheduled workflows give the scheduler something to read and update each tick
+	var workflowIDs []string
+	for i := 0; i < 5; i++ {
+		workflow, err := database.CreateWorkflow(user.ID, fmt.Sprintf("Scheduled %d", i), "schedule", "testing", `{"interval": 1}`)
+		if err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+		workflowIDs = append(workflowIDs, workflow.ID)
+	}
+
+	scheduler := engine.NewScheduler(database, executor, testLogger)
+	scheduler.Start(5 * time.Millisecond)
+	defer scheduler.Stop()
+
+	const writers = 20
+	const logsPerWriter = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*logsPerWriter)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for j := 0; j < logsPerWriter; j++ {
+				workflowID := workflowIDs[(writer+j)%len(workflowIDs)]
+				if err := database.CreateLog(workflowID, "success", fmt.Sprintf("writer %d log %d", writer, j)); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("CreateLog surfaced an error: %v", err)
+	}
+
+	t.Logf("Write retries after SQLITE_BUSY: %d", database.WriteRetries())
+}
+
+// recheckCountingStore counts the scheduler's per-workflow re-checks before a run
+type recheckCountingStore struct {
+	db.Store
+	mu       sync.Mutex
+	rechecks int
+}
+
+func (s *recheckCountingStore) GetActiveWorkflow(workflowID string) (*models.Workflow, error) {
+	s.mu.Lock()
+	s.rechecks++
+	s.mu.Unlock()
+	return s.Store.GetActiveWorkflow(workflowID)
+}
+
+// TestSchedulerRunsDueWorkflowsOnTheirSlots ticks a fake clock every minute for an hour and
+// checks a 15-minute workflow runs on every slot, without drifting by however late each
+// tick was, and is never read while it isn't due
+func TestSchedulerRunsDueWorkflowsOnTheirSlots(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	store := &recheckCountingStore{Store: database}
+
+	var mu sync.Mutex
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	clock := start
+	executor := engine.NewExecutor(store, testLogger)
+	executor.SetClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	})
+
+	user, _ := database.CreateUser("slots@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Quarter hourly", "schedule", "testing", `{"interval": 15}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	if workflow.NextRunAt == nil || !workflow.NextRunAt.Equal(workflow.CreatedAt) {
+		t.Errorf("Expected a new schedule to be due straight away, got %v", workflow.NextRunAt)
+	}
+	lastRun := start.Add(-15 * time.Minute)
+	database.UpdateWorkflowLastExecuted(workflow.ID, lastRun, &start)
+
+	scheduler := engine.NewScheduler(store, executor, testLogger)
+	var ran []int
+	for minute := 0; minute <= 60; minute++ {
+		tick := start.Add(time.Duration(minute)*time.Minute + 20*time.Second) // Ticks land 20s after each slot
+		mu.Lock()
+		clock = tick
+		mu.Unlock()
+
+		if scheduler.RunDue(tick) == 0 {
+			continue
+		}
+		ran = append(ran, minute)
+		waitFor(t, "the run to start", func() bool {
+			current, _ := database.GetWorkflowByID(workflow.ID)
+			return current.LastExecutedAt != nil && current.LastExecutedAt.Equal(tick)
+		})
+	}
+
+	if fmt.Sprint(ran) != "[0 15 30 45 60]" {
+		t.Errorf("Expected runs at minutes 0, 15, 30, 45 and 60, got %v", ran)
+	}
+	if store.rechecks != len(ran) {
+		t.Errorf("Expected the workflow read only when due (%d times), got %d", len(ran), store.rechecks)
+	}
+	current, _ := database.GetWorkflowByID(workflow.ID)
+	if want := start.Add(75 * time.Minute); current.NextRunAt == nil || !current.NextRunAt.Equal(want) {
+		t.Errorf("Expected the next run on the 75-minute slot %s, got %v", want, current.NextRunAt)
+	}
+
+	// A disabled workflow is no longer due
+	database.UpdateWorkflowActive(workflow.ID, false)
+	if started := scheduler.RunDue(start.Add(2 * time.Hour)); started != 0 {
+		t.Errorf("Expected a disabled workflow not to run, started %d", started)
+	}
+}
+
+// TestSchedulerTenantConcurrency makes 20 workflows due for one tenant and 2 for another
+// on the same tick: the busy tenant gets its cap of 3 and the other isn't starved; the
+// busy tenant's rest run on later ticks rather than being dropped
+func TestSchedulerTenantConcurrency(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	pool := engine.NewWorkerPool(2, testLogger) // Started once the first tick is checked
+	defer pool.Shutdown(context.Background())
+	executor := engine.NewExecutorWithPool(database, testLogger, pool)
+
+	busy, _ := database.CreateUser("busy@example.com", "hashed")
+	quiet, _ := database.CreateUser("quiet@example.com", "hashed")
+	var busyIDs, quietIDs []string
+	for i := 0; i < 20; i++ {
+		// Each run outlasts a tick, so the cap rather than timing decides what a tick submits
+		workflow, _ := database.CreateWorkflow(busy.ID, fmt.Sprintf("Busy %d", i), "schedule", "testing", `{"interval": 60, "testing_delay": 100}`)
+		busyIDs = append(busyIDs, workflow.ID)
+	}
+	for i := 0; i < 2; i++ {
+		workflow, _ := database.CreateWorkflow(quiet.ID, fmt.Sprintf("Quiet %d", i), "schedule", "testing", `{"interval": 60}`)
+		quietIDs = append(quietIDs, workflow.ID)
+	}
+	ran := func(ids []string) int {
+		count := 0
+		for _, id := range ids {
+			if workflow, _ := database.GetWorkflowByID(id); workflow.LastExecutedAt != nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	scheduler := engine.NewScheduler(database, executor, testLogger)
+	scheduler.SetTenantConcurrency(3)
+	now := time.Now()
+
+	if started := scheduler.RunDue(now); started != 5 {
+		t.Errorf("Expected 3 busy and 2 quiet workflows submitted, got %d", started)
+	}
+	inFlight := executor.TenantInFlight()
+	if inFlight[busy.TenantID] != 3 || inFlight[quiet.TenantID] != 2 {
+		t.Errorf("Expected 3 busy and 2 quiet jobs in flight, got %v", inFlight)
+	}
+
+	pool.Start()
+	waitFor(t, "the first tick's runs", func() bool { return len(executor.TenantInFlight()) == 0 })
+	if ran(quietIDs) != 2 || ran(busyIDs) != 3 {
+		t.Fatalf("Expected both quiet workflows and 3 busy ones run, got %d and %d", ran(quietIDs), ran(busyIDs))
+	}
+
+	// The deferred workflows are still due, 3 per tick
+	for tick := 0; tick < 10 && ran(busyIDs) < len(busyIDs); tick++ {
+		if started := scheduler.RunDue(now); started > 3 {
+			t.Errorf("Expected at most 3 busy workflows per tick, got %d", started)
+		}
+		waitFor(t, "the tick's runs", func() bool { return len(executor.TenantInFlight()) == 0 })
+	}
+	if ran(busyIDs) != len(busyIDs) {
+		t.Errorf("Expected every deferred workflow to run eventually, got %d of %d", ran(busyIDs), len(busyIDs))
+	}
+
+	// With jitter a submission waits up to one interval; later ticks don't submit it again
+	late, _ := database.CreateWorkflow(quiet.ID, "Jittered", "schedule", "testing", `{"interval": 60}`)
+	scheduler.SetJitter(true)
+	scheduler.SetInterval(200 * time.Millisecond)
+	if first, second := scheduler.RunDue(time.Now()), scheduler.RunDue(time.Now()); first != 1 || second != 0 {
+		t.Errorf("Expected the jittered workflow submitted once, got %d then %d", first, second)
+	}
+	waitFor(t, "the jittered run", func() bool { return ran([]string{late.ID}) == 1 })
+}
+
+// TestSchedulerSkipsPausedWorkflows pauses a due workflow for a second: the scheduler skips
+// it until the pause has passed, then runs it without anyone resuming it
+func TestSchedulerSkipsPausedWorkflows(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+
+	user, _ := database.CreateUser("paused@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Paused", "schedule", "testing", `{"interval": 60}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	now := time.Now()
+	until := now.Add(time.Second)
+	if err := database.SetWorkflowPausedUntil(workflow.ID, &until); err != nil {
+		t.Fatalf("Failed to pause workflow: %v", err)
+	}
+
+	scheduler := engine.NewScheduler(database, executor, testLogger)
+	if started := scheduler.RunDue(now); started != 0 {
+		t.Errorf("Expected a paused workflow not to run, started %d", started)
+	}
+	if current, _ := database.GetWorkflowByID(workflow.ID); current.LastExecutedAt != nil || !current.IsActive {
+		t.Errorf("Expected the paused workflow left active and unrun, got %+v", current)
+	}
+
+	later := now.Add(2 * time.Second)
+	if started := scheduler.RunDue(later); started != 1 {
+		t.Errorf("Expected the workflow to run once its pause passed, started %d", started)
+	}
+	waitFor(t, "the resumed run", func() bool {
+		current, _ := database.GetWorkflowByID(workflow.ID)
+		return current.LastExecutedAt != nil
+	})
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"golang.org/x/time/rate"
+)
+
+// serviceLimiter throttles outbound connector calls per action type
+// so a burst of runs can't exceed a provider's rate limit (e.g. Slack's 1 msg/sec)
+type serviceLimiter struct {
+	mu       sync.RWMutex
+	limiters map[string]*rate.Limiter
+}
+
+func newServiceLimiter() *serviceLimiter {
+	return &serviceLimiter{limiters: make(map[string]*rate.Limiter)}
+}
+
+// SetLimits replaces the per-action-type limits (calls/sec); action types not listed are unlimited
+// Existing limiters are adjusted in place so callers already waiting keep their place
+func (s *serviceLimiter) SetLimits(limits map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for actionType := range s.limiters {
+		if _, ok := limits[actionType]; !ok {
+			delete(s.limiters, actionType)
+		}
+	}
+	for actionType, perSecond := range limits {
+		burst := int(perSecond)
+		if burst < 1 {
+			burst = 1
+		}
+		if limiter, ok := s.limiters[actionType]; ok {
+			limiter.SetLimit(rate.Limit(perSecond))
+			limiter.SetBurst(burst)
+			continue
+		}
+		s.limiters[actionType] = rate.NewLimiter(rate.Limit(perSecond), burst)
+	}
+}
+
+// Wait blocks until the action type may call out, or ctx ends
+func (s *serviceLimiter) Wait(ctx context.Context, actionType string) error {
+	s.mu.RLock()
+	limiter, ok := s.limiters[actionType]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// SetServiceLimits applies per-action-type outbound limits (calls/sec)
+func (e *Executor) SetServiceLimits(limits map[string]float64) {
+	e.limits.SetLimits(limits)
+}
+
+// throttle waits for the action type's outbound limit
+// Returns a result to report instead of running the action if the wait is cut short
+func (e *Executor) throttle(ctx context.Context, actionType string) *connectors.Result {
+	if IsSandbox(ctx) {
+		return nil // Sandboxed runs don't call out
+	}
+	if err := e.limits.Wait(ctx, actionType); err != nil {
+		return &connectors.Result{
+			Status:    "cancelled",
+			Message:   fmt.Sprintf("Execution cancelled waiting for %s rate limit: %v", actionType, err),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+	return nil
+}
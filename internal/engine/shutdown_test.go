@@ -0,0 +1,101 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestExecutorShutdownDrainsJobs submits slow testing jobs to a small pool, shuts it down
+// while they're running and queued, and checks every one completes and writes its log
+func TestExecutorShutdownDrainsJobs(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	pool := engine.NewWorkerPool(2, testLogger)
+	pool.Start()
+	executor := engine.NewExecutorWithPool(database, testLogger, pool)
+
+	user, _ := database.CreateUser("shutdown@example.com", "hashed")
+	var workflowIDs []string
+	for i := 0; i < 6; i++ {
+		workflow, err := database.CreateWorkflow(user.ID, fmt.Sprintf("Slow %d", i), "webhook", "testing", `{"testing_delay": 150}`)
+		if err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+		workflowIDs = append(workflowIDs, workflow.ID)
+		executor.ExecuteWorkflow(*workflow, "")
+	}
+	time.Sleep(20 * time.Millisecond) // Two running, four queued
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := executor.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected the pool to drain, got %v", err)
+	}
+	for _, id := range workflowIDs {
+		if logs, _ := database.GetLogsByWorkflowID(id); len(logs) != 1 || logs[0].Status != "success" {
+			t.Errorf("Expected workflow %s to finish and log success, got %+v", id, logs)
+		}
+	}
+
+	// Later jobs are dead-lettered rather than lost, and a second shutdown is a no-op
+	late, _ := database.GetWorkflowByID(workflowIDs[0])
+	executor.ExecuteWorkflow(*late, "")
+	if letters, _ := database.ListDeadLetters(user.ID); len(letters) != 1 {
+		t.Errorf("Expected the job submitted after shutdown dead-lettered, got %d", len(letters))
+	}
+	if err := executor.Shutdown(ctx); err != nil {
+		t.Errorf("Expected a second shutdown to do nothing, got %v", err)
+	}
+}
+
+// TestExecutorShutdownTimeout cancels jobs still running when the shutdown budget runs out
+func TestExecutorShutdownTimeout(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	pool := engine.NewWorkerPool(1, testLogger)
+	pool.Start()
+	executor := engine.NewExecutorWithPool(database, testLogger, pool)
+
+	user, _ := database.CreateUser("timeout@example.com", "hashed")
+	workflow, _ := database.CreateWorkflow(user.ID, "Slower", "webhook", "testing", `{"testing_delay": 2000}`)
+	executor.ExecuteWorkflow(*workflow, "")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := executor.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the shutdown to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected shutdown to give up at its deadline, took %s", elapsed)
+	}
+}
+
+// TestSchedulerStopIsIdempotent stops a started scheduler twice and one never started
+func TestSchedulerStopIsIdempotent(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+
+	done := make(chan struct{})
+	go func() {
+		started := engine.NewScheduler(database, executor, testLogger)
+		started.Start(time.Hour)
+		started.Stop()
+		started.Stop()
+		engine.NewScheduler(database, executor, testLogger).Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected repeated Stop calls to return")
+	}
+}
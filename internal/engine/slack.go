@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// ValidateSlackMessage rejects slack_message configs whose blocks Slack would refuse
+// Malformed JSON is left for the executor to report
+func ValidateSlackMessage(actionType, configJSON string) error {
+	if actionType != "slack_message" {
+		return nil
+	}
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	if err := connectors.ValidateSlackBlocks(config.SlackBlocks); err != nil {
+		return fmt.Errorf("slack_blocks: %v", err)
+	}
+	return nil
+}
+
+// renderSlackBlocks renders the templates in every text field of a Block Kit array
+// Other fields (block IDs, image URLs) are passed through untouched
+func (e *Executor) renderSlackBlocks(blocks json.RawMessage, payload string) (json.RawMessage, error) {
+	if len(blocks) == 0 {
+		return blocks, nil
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(blocks, &parsed); err != nil {
+		return nil, fmt.Errorf("slack_blocks: %v", err)
+	}
+	rendered, err := mapSlackText(parsed, func(text string) (string, error) {
+		return e.templateEngine.RenderStrict(text, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rendered)
+}
+
+// slackBlockTexts lists the text fields of a Block Kit array, for linting
+func slackBlockTexts(blocks json.RawMessage) []string {
+	var parsed interface{}
+	if err := json.Unmarshal(blocks, &parsed); err != nil {
+		return nil
+	}
+	var texts []string
+	mapSlackText(parsed, func(text string) (string, error) {
+		texts = append(texts, text)
+		return text, nil
+	})
+	return texts
+}
+
+// mapSlackText applies fn to every string under a "text" key, at any depth: a section's
+// text object, its fields and context elements all keep theirs there
+func mapSlackText(value interface{}, fn func(string) (string, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if text, ok := child.(string); ok && key == "text" {
+				rendered, err := fn(text)
+				if err != nil {
+					return nil, err
+				}
+				v[key] = rendered
+				continue
+			}
+			mapped, err := mapSlackText(child, fn)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = mapped
+		}
+	case []interface{}:
+		for i, child := range v {
+			mapped, err := mapSlackText(child, fn)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = mapped
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,88 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestSlackBlocksInThread posts templated blocks with a bot token, then replies in the
+// message's thread from the next chain step
+func TestSlackBlocksInThread(t *testing.T) {
+	var mu sync.Mutex
+	var posted []map[string]interface{}
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		posted = append(posted, body)
+		mu.Unlock()
+		if r.URL.Path != "/api/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-bot" {
+			t.Errorf("Unexpected Slack request %s", r.URL.Path)
+		}
+		io.WriteString(w, `{"ok": true, "channel": "C42", "ts": "1700000000.000100"}`)
+	}))
+	defer slack.Close()
+	routeConnectorHost(t, "slack.com", slack.URL)
+
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+	user, _ := database.CreateUser("blocks@example.com", "hashed")
+	database.CreateCredential(user.ID, "slack", "xoxb-bot")
+
+	workflow, err := database.CreateWorkflowWithChain(user.ID, "Deploy blocks", "webhook", "slack_message",
+		`{"slack_channel": "C42", "slack_message": "Deploy {{version}}", "slack_blocks": [
+			{"type": "section", "block_id": "{{version}}", "text": {"type": "mrkdwn", "text": "*Deploy {{version}}* by {{user.name}}"}},
+			{"type": "context", "elements": [{"type": "mrkdwn", "text": "{{env | upper}}"}]}
+		]}`,
+		`[{"action_type": "slack_message", "config": {"slack_channel": "C42", "slack_message": "Rolled out", "slack_thread_ts": "{{ts}}"}, "use_data_from": "previous"}]`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *workflow, `{"version": "1.4.2", "env": "prod", "user": {"name": "Ada"}}`)
+
+	execution, err := database.GetLatestExecution(workflow.ID)
+	if err != nil || execution.Status != "success" {
+		t.Fatalf("Expected both messages sent, got %+v, %v", execution, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posted) != 2 {
+		t.Fatalf("Expected two messages, got %d", len(posted))
+	}
+	blocks, _ := json.Marshal(posted[0]["blocks"])
+	for _, want := range []string{`"text":"*Deploy 1.4.2* by Ada"`, `"text":"PROD"`, `"block_id":"{{version}}"`} {
+		if !strings.Contains(string(blocks), want) {
+			t.Errorf("Expected %s in the blocks, got %s", want, blocks)
+		}
+	}
+	if posted[0]["channel"] != "C42" || posted[0]["text"] != "Deploy 1.4.2" {
+		t.Errorf("Expected the rendered fallback text in C42, got %v", posted[0])
+	}
+	if posted[1]["thread_ts"] != "1700000000.000100" {
+		t.Errorf("Expected the reply in the first message's thread, got %v", posted[1])
+	}
+}
+
+// TestValidateSlackMessage rejects blocks Slack would refuse when the workflow is saved
+func TestValidateSlackMessage(t *testing.T) {
+	if err := engine.ValidateSlackMessage("slack_message", `{"slack_blocks": [{"text": "no type"}]}`); err == nil || !strings.Contains(err.Error(), "slack_blocks") {
+		t.Errorf("Expected a block without a type rejected, got %v", err)
+	}
+	if err := engine.ValidateSlackMessage("slack_message", `{"slack_blocks": [{"type": "divider"}]}`); err != nil {
+		t.Errorf("Expected valid blocks accepted, got %v", err)
+	}
+	if warnings := engine.TemplateWarnings(`{"slack_blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": "{{name | shout}}"}}]}`, ""); len(warnings) != 1 || !strings.HasPrefix(warnings[0], "slack_blocks:") {
+		t.Errorf("Expected the unknown filter in a block reported, got %v", warnings)
+	}
+}
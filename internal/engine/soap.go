@@ -0,0 +1,27 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// ValidateSOAPCall rejects soap_call configs asking for a SOAP version we can't send
+// Malformed JSON is left for the executor to report
+func ValidateSOAPCall(actionType, configJSON string) error {
+	if actionType != "soap_call" {
+		return nil
+	}
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	switch config.SOAPVersion {
+	case "", connectors.SOAP11, connectors.SOAP12:
+		return nil
+	default:
+		return fmt.Errorf("soap_version: must be %s or %s, got %q", connectors.SOAP11, connectors.SOAP12, config.SOAPVersion)
+	}
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// TemplateWarnings lints the config fields the executor renders against the trigger payload
+// (slack_message, slack_thread_ts, the text fields of slack_blocks, discord_message,
+// discord_username, the text fields of discord_embed, twilio_message, twilio_to,
+// testing_response_json, http_url, http_body)
+// with the parser Render uses
+// Without a payload only malformed references and unknown filters are found; with one,
+// undefined paths and type mismatches too
+// Unused payload fields are left out: one field never uses the whole payload
+func TemplateWarnings(configJSON, payload string) []string {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+
+	te := utils.NewTemplateEngine()
+	var warnings []string
+	warnings = append(warnings, lintField(te, "slack_message", config.SlackMessage, payload)...)
+	warnings = append(warnings, lintField(te, "slack_thread_ts", config.SlackThreadTS, payload)...)
+	for _, text := range slackBlockTexts(config.SlackBlocks) {
+		warnings = append(warnings, lintField(te, "slack_blocks", text, payload)...)
+	}
+	warnings = append(warnings, lintField(te, "discord_message", config.DiscordMessage, payload)...)
+	warnings = append(warnings, lintField(te, "discord_username", config.DiscordUsername, payload)...)
+	if embed := config.DiscordEmbed; embed != nil {
+		for _, text := range []string{embed.Title, embed.Description, embed.URL, embed.Footer, embed.Timestamp} {
+			warnings = append(warnings, lintField(te, "discord_embed", text, payload)...)
+		}
+		for _, field := range embed.Fields {
+			warnings = append(warnings, lintField(te, "discord_embed", field.Name, payload)...)
+			warnings = append(warnings, lintField(te, "discord_embed", field.Value, payload)...)
+		}
+	}
+	warnings = append(warnings, lintField(te, "twilio_message", config.TwilioMessage, payload)...)
+	warnings = append(warnings, lintField(te, "testing_response_json", config.TestingResponseJSON, payload)...)
+	warnings = append(warnings, lintField(te, "http_url", config.HTTPURL, payload)...)
+	if body, ok := config.HTTPBody.(string); ok {
+		warnings = append(warnings, lintField(te, "http_body", body, payload)...)
+	} else if config.HTTPBody != nil {
+		encoded, _ := json.Marshal(config.HTTPBody)
+		warnings = append(warnings, lintField(te, "http_body", string(encoded), payload)...)
+	}
+	for i, to := range config.TwilioTo {
+		warnings = append(warnings, lintField(te, fmt.Sprintf("twilio_to[%d]", i), to, payload)...)
+	}
+	return warnings
+}
+
+// lintField returns one template's error findings as warnings naming the field
+func lintField(te *utils.TemplateEngine, field, template, payload string) []string {
+	if template == "" {
+		return nil
+	}
+	findings, err := te.Lint(template, utils.LintOptions{Payload: payload})
+	if err != nil {
+		// A payload that isn't JSON renders nothing; lint the syntax alone
+		findings, _ = te.Lint(template, utils.LintOptions{})
+	}
+	var warnings []string
+	for _, finding := range findings {
+		if finding.Severity != utils.SeverityError {
+			continue
+		}
+		if finding.Path != "" {
+			warnings = append(warnings, fmt.Sprintf("%s: {{%s}}: %s", field, finding.Path, finding.Message))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", field, finding.Message))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// DefaultMaxTestingDelay caps the testing connector's simulated delay when nothing is configured
+const DefaultMaxTestingDelay = 10 * time.Second
+
+// SetMaxTestingDelay changes the longest delay a testing action may simulate
+func (e *Executor) SetMaxTestingDelay(max time.Duration) {
+	atomic.StoreInt64(&e.maxTestingDelay, int64(max))
+}
+
+// MaxTestingDelay returns the longest delay a testing action may simulate
+func (e *Executor) MaxTestingDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&e.maxTestingDelay))
+}
+
+// checkTestingDelay rejects a testing_delay (milliseconds) that is negative or over the cap
+func (e *Executor) checkTestingDelay(delayMS int) error {
+	if delayMS < 0 {
+		return fmt.Errorf("testing_delay must not be negative (got %dms)", delayMS)
+	}
+	if max := e.MaxTestingDelay(); time.Duration(delayMS)*time.Millisecond > max {
+		return fmt.Errorf("testing_delay of %dms exceeds the maximum of %dms", delayMS, max.Milliseconds())
+	}
+	return nil
+}
+
+// ValidateTestingDelays checks the testing_delay of a workflow's primary action and of
+// every chained testing action, so over-long delays are rejected when the workflow is saved
+// Malformed JSON is left for the executor to report
+func (e *Executor) ValidateTestingDelays(actionType, configJSON, actionChainJSON string) error {
+	if actionType == "testing" && configJSON != "" {
+		var config models.WorkflowConfig
+		if err := json.Unmarshal([]byte(configJSON), &config); err == nil {
+			if err := e.checkTestingDelay(config.TestingDelay); err != nil {
+				return err
+			}
+		}
+	}
+
+	if actionChainJSON == "" {
+		return nil
+	}
+	var chain []models.ChainedAction
+	if err := json.Unmarshal([]byte(actionChainJSON), &chain); err != nil {
+		return nil
+	}
+	for _, step := range chainActions(chain) {
+		if step.action.ActionType != "testing" {
+			continue
+		}
+		var config models.WorkflowConfig
+		configBytes, _ := json.Marshal(step.action.Config)
+		if err := json.Unmarshal(configBytes, &config); err != nil {
+			continue
+		}
+		if err := e.checkTestingDelay(config.TestingDelay); err != nil {
+			return fmt.Errorf("%s: %w", step.label, err)
+		}
+	}
+	return nil
+}
+
+// sleepContext waits for d or until ctx ends, returning how long it actually waited
+func sleepContext(ctx context.Context, d time.Duration) (time.Duration, error) {
+	start := time.Now()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	case <-timer.C:
+		return time.Since(start), nil
+	}
+}
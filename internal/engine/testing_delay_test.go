@@ -0,0 +1,97 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/costs"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestTestingDelayCancelledPromptly proves a cancelled context interrupts the delay
+// and that the time already spent is counted against the tenant's usage
+func TestTestingDelayCancelledPromptly(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+
+	user, _ := mockStore.CreateUser("delay@example.com", "hashed")
+	workflow, err := mockStore.CreateWorkflow(user.ID, "Slow Mock", "webhook", "testing", `{"testing_delay": 5000}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	executor.ExecuteWorkflowWithContext(ctx, *workflow, "")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected cancellation to interrupt the 5s delay, took %s", elapsed)
+	}
+
+	usage, _ := mockStore.GetWorkflowCosts("tenant_"+user.ID, costs.Month(time.Now()))
+	if len(usage) != 1 || usage[0].DelayMS < 40 || usage[0].DelayMS > 1000 {
+		t.Errorf("Expected the interrupted delay counted against usage, got %+v", usage)
+	}
+}
+
+// TestTestingDelayCapEnforced rejects delays over the cap for primary and chained testing actions
+func TestTestingDelayCapEnforced(t *testing.T) {
+	mockStore := db.NewMockStore()
+	executor := engine.NewExecutor(mockStore, logger.NewLogger("test"))
+	executor.SetMaxTestingDelay(100 * time.Millisecond)
+
+	user, _ := mockStore.CreateUser("cap@example.com", "hashed")
+	tenantID := "tenant_" + user.ID
+
+	t.Run("primary action", func(t *testing.T) {
+		workflow, _ := mockStore.CreateWorkflow(user.ID, "Over Cap", "webhook", "testing", `{"testing_delay": 60000}`)
+
+		start := time.Now()
+		result := executor.DryRun(*workflow, user.ID, tenantID)
+		if result.Status != "failed" || !strings.Contains(result.Message, "exceeds the maximum of 100ms") {
+			t.Errorf("Expected the delay rejected, got %s: %s", result.Status, result.Message)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected no delay to run, took %s", elapsed)
+		}
+	})
+
+	t.Run("chained action", func(t *testing.T) {
+		workflow, _ := mockStore.CreateWorkflow(user.ID, "Chain Over Cap", "webhook", "testing", `{"testing_delay": 20}`)
+		workflow.ActionChain = `[{"action_type": "testing", "config": {"testing_delay": 60000}, "use_data_from": "previous"},
+			{"action_type": "testing", "config": {"testing_delay": 60000}}]`
+
+		result := executor.DryRun(*workflow, user.ID, tenantID)
+		if result.Status != "success" || result.DelayMS < 20 {
+			t.Fatalf("Expected the primary delay within the cap to run, got %s (%dms): %s", result.Status, result.DelayMS, result.Message)
+		}
+		chainResults, ok := result.Data["chain_results"].([]connectors.Result)
+		if !ok || len(chainResults) != 2 {
+			t.Fatalf("Expected 2 chain results, got %v", result.Data["chain_results"])
+		}
+		for i, chainResult := range chainResults {
+			if chainResult.Status != "failed" || !strings.Contains(chainResult.Message, "exceeds the maximum") {
+				t.Errorf("Step %d: expected the delay rejected, got %s: %s", i+1, chainResult.Status, chainResult.Message)
+			}
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		if err := executor.ValidateTestingDelays("testing", `{"testing_delay": 100}`, ""); err != nil {
+			t.Errorf("Expected a delay at the cap to be accepted, got %v", err)
+		}
+		if err := executor.ValidateTestingDelays("testing", `{"testing_delay": 101}`, ""); err == nil {
+			t.Error("Expected a primary delay over the cap to be rejected")
+		}
+		err := executor.ValidateTestingDelays("slack_message", `{}`, `[{"action_type": "testing", "config": {"testing_delay": 60000}}]`)
+		if err == nil || !strings.Contains(err.Error(), "action_chain step 1") {
+			t.Errorf("Expected the chained delay rejected with its step, got %v", err)
+		}
+	})
+}
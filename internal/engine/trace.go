@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// maxTraceBytes caps the stored result trace so one chatty API can't bloat the database
+const maxTraceBytes = 64 * 1024
+
+// maskedTrace converts a result into masked generic JSON data
+// Round-tripping through JSON turns typed chain results into maps the masker can walk
+func maskedTrace(result connectors.Result) map[string]interface{} {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return map[string]interface{}{"status": result.Status, "message": utils.Mask(result.Message)}
+	}
+	var trace map[string]interface{}
+	if err := json.Unmarshal(raw, &trace); err != nil {
+		return map[string]interface{}{"status": result.Status, "message": utils.Mask(result.Message)}
+	}
+	return utils.MaskMap(trace)
+}
+
+// encodeTrace serializes a masked result trace for storage, truncating oversized traces
+func encodeTrace(result connectors.Result) string {
+	raw, err := json.Marshal(maskedTrace(result))
+	if err != nil {
+		return ""
+	}
+	if len(raw) > maxTraceBytes {
+		raw, _ = json.Marshal(map[string]interface{}{
+			"status":    result.Status,
+			"message":   utils.Mask(result.Message),
+			"truncated": true,
+		})
+	}
+	return string(raw)
+}
+
+// MaskPayload masks a trigger payload before storage
+// JSON bodies are masked key-by-key; anything else is pattern-masked as text
+func MaskPayload(payload string) string {
+	if payload == "" {
+		return ""
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		return utils.Mask(payload)
+	}
+	masked, err := json.Marshal(utils.MaskMap(data))
+	if err != nil {
+		return ""
+	}
+	return string(masked)
+}
+
+// MaskPayloadForTenant applies a tenant's masking policy on top of MaskPayload
+// Keys named in fields (case-insensitive, at any depth) are redacted; a payload that
+// isn't a JSON object can't be checked key by key and is withheld entirely
+func MaskPayloadForTenant(payload string, fields []string) string {
+	masked := MaskPayload(payload)
+	if masked == "" || len(fields) == 0 {
+		return masked
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(masked), &data); err != nil {
+		return "***REDACTED***"
+	}
+	redact := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		redact[strings.ToLower(field)] = true
+	}
+	encoded, err := json.Marshal(redactFields(data, redact))
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// redactFields replaces the values of the named keys throughout a decoded JSON value
+func redactFields(value interface{}, redact map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if redact[strings.ToLower(key)] {
+				v[key] = "***REDACTED***"
+				continue
+			}
+			v[key] = redactFields(nested, redact)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redactFields(nested, redact)
+		}
+	}
+	return value
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// Errors from RecordTwilioStatus
+var (
+	ErrTwilioCallbackDisabled = errors.New("no Twilio step of this workflow has a twilio_status_callback")
+	ErrTwilioSignature        = errors.New("invalid X-Twilio-Signature")
+)
+
+// twilioCredential is the JSON stored as a Twilio credential
+type twilioCredential struct {
+	AccountSID string `json:"account_sid"`
+	AuthToken  string `json:"auth_token"`
+	FromNumber string `json:"from_number"`
+}
+
+// ValidateTwilioMessage checks a Twilio step's channel and status callback URL
+func ValidateTwilioMessage(actionType, configJSON string) error {
+	if actionType != "twilio_sms" {
+		return nil
+	}
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return nil
+	}
+	switch config.TwilioChannel {
+	case "", connectors.TwilioChannelSMS, connectors.TwilioChannelWhatsApp:
+	default:
+		return fmt.Errorf("twilio_channel: must be %s or %s, got %q", connectors.TwilioChannelSMS, connectors.TwilioChannelWhatsApp, config.TwilioChannel)
+	}
+	if config.TwilioStatusCallback != "" {
+		if _, err := connectors.CheckHTTPURL(config.TwilioStatusCallback); err != nil {
+			return fmt.Errorf("twilio_status_callback: %v", err)
+		}
+	}
+	return nil
+}
+
+// twilioCallbackSteps returns the configs of a workflow's Twilio steps that ask for
+// status callbacks: the primary action and the chained steps
+func twilioCallbackSteps(workflow models.Workflow) []models.WorkflowConfig {
+	var steps []models.WorkflowConfig
+	add := func(actionType string, config models.WorkflowConfig) {
+		if actionType == "twilio_sms" && config.TwilioStatusCallback != "" {
+			steps = append(steps, config)
+		}
+	}
+
+	var primary models.WorkflowConfig
+	if json.Unmarshal([]byte(workflow.ConfigJSON), &primary) == nil {
+		add(workflow.ActionType, primary)
+	}
+	var chain []models.ChainedAction
+	if workflow.ActionChain != "" && json.Unmarshal([]byte(workflow.ActionChain), &chain) == nil {
+		for _, step := range chain {
+			encoded, _ := json.Marshal(step.Config)
+			var config models.WorkflowConfig
+			if json.Unmarshal(encoded, &config) == nil {
+				add(step.ActionType, config)
+			}
+		}
+	}
+	return steps
+}
+
+// RecordTwilioStatus checks a delivery status callback's signature against the workflow's
+// Twilio credential and stores final statuses (delivered, read, undelivered, failed,
+// canceled) as a log entry of the workflow. Interim statuses are accepted but not logged
+// Twilio signs the URL it was given, so each configured twilio_status_callback is tried
+func (e *Executor) RecordTwilioStatus(workflow models.Workflow, form url.Values, signature string) (connectors.TwilioStatus, error) {
+	status := connectors.ParseTwilioStatus(form)
+	steps := twilioCallbackSteps(workflow)
+	if len(steps) == 0 {
+		return status, ErrTwilioCallbackDisabled
+	}
+
+	verified := false
+	for _, config := range steps {
+		ctx := withCredentialScope(context.Background(), workflow, config)
+		cred, err := e.getCredential(ctx, workflow.UserID, workflow.TenantID, credentialName(config, "twilio"))
+		if err != nil {
+			return status, err
+		}
+		var twilio twilioCredential
+		if err := json.Unmarshal([]byte(cred.DecryptedKey), &twilio); err != nil {
+			return status, fmt.Errorf("Invalid Twilio credentials format: %v", err)
+		}
+		if connectors.ValidTwilioSignature(twilio.AuthToken, config.TwilioStatusCallback, form, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return status, ErrTwilioSignature
+	}
+	if !status.Final() {
+		return status, nil
+	}
+
+	entry := &models.Log{
+		WorkflowID: workflow.ID,
+		Status:     "success",
+		Message:    fmt.Sprintf("Twilio message %s to %s: %s", status.MessageSID, status.To, status.Status),
+	}
+	if !status.Delivered() {
+		entry.Status = "failed"
+		if status.ErrorCode != "" {
+			entry.Message += fmt.Sprintf(" (error %s", status.ErrorCode)
+			if status.ErrorMessage != "" {
+				entry.Message += ": " + status.ErrorMessage
+			}
+			entry.Message += ")"
+		}
+		entry.ErrorCode = connectors.ErrCodeDeliveryFailed
+		entry.ErrorParams = map[string]string{"service": "Twilio", "status": status.Status, "provider_code": status.ErrorCode}
+	}
+	e.createLog(workflow, entry)
+	return status, nil
+}
@@ -0,0 +1,22 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+)
+
+// TestValidateTwilioMessage rejects unknown channels and unusable status callback URLs
+func TestValidateTwilioMessage(t *testing.T) {
+	for config, want := range map[string]string{
+		`{"twilio_channel": "fax"}`:                                 "twilio_channel",
+		`{"twilio_status_callback": "ftp://goflow.example.com/cb"}`: "twilio_status_callback",
+		`{"twilio_channel": "whatsapp", "twilio_status_callback": "https://goflow.example.com/api/callbacks/twilio/wf_1"}`: "",
+	} {
+		err := engine.ValidateTwilioMessage("twilio_sms", config)
+		if (want == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), want)) {
+			t.Errorf("%s: expected %q, got %v", config, want, err)
+		}
+	}
+}
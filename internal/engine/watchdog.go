@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/alexmacdonald/simple-ipass/internal/version"
+)
+
+// Watchdog defaults: warn after a minute, and give up on a worker well after the
+// 5-minute job context should have stopped it (only code that ignores the context gets there)
+const (
+	DefaultWorkerSoftTimeout = 1 * time.Minute
+	DefaultWorkerHardTimeout = 6 * time.Minute
+)
+
+// StatusTimedOutHard is the execution status of a run the watchdog abandoned
+const StatusTimedOutHard = "timed_out_hard"
+
+// WorkerStatus describes what one worker is doing
+type WorkerStatus struct {
+	WorkerID     int        `json:"worker_id"`
+	State        string     `json:"state"` // 'idle', 'busy', or 'stuck' (past the soft threshold)
+	WorkflowID   string     `json:"workflow_id,omitempty"`
+	WorkflowName string     `json:"workflow_name,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	RunningMS    int64      `json:"running_ms,omitempty"`
+}
+
+// PoolStatus is a snapshot of the worker pool for operators
+type PoolStatus struct {
+	Workers       []WorkerStatus `json:"workers"`
+	QueueLength   int            `json:"queue_length"`
+	QueueCapacity int            `json:"queue_capacity"`
+	SoftTimeoutMS int64          `json:"soft_timeout_ms"`
+	HardTimeoutMS int64          `json:"hard_timeout_ms"`
+	Abandoned     int            `json:"abandoned"` // Workers replaced after a hard timeout since start
+
+	BranchSlots  int `json:"branch_slots"`  // Parallel branches allowed to run at once, across all workflows
+	BranchActive int `json:"branch_active"` // Branches running now
+	BranchQueued int `json:"branch_queued"` // Branches waiting for a slot
+}
+
+// stuckJob is a job the watchdog found over a threshold
+type stuckJob struct {
+	workerID int
+	job      WorkflowJob
+	running  time.Duration
+}
+
+// SetWatchdogThresholds changes when running jobs are reported (soft) and abandoned (hard)
+// Zero disables a threshold
+func (wp *WorkerPool) SetWatchdogThresholds(soft, hard time.Duration) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.softTimeout = soft
+	wp.hardTimeout = hard
+}
+
+// Status returns what every worker is doing right now
+func (wp *WorkerPool) Status() PoolStatus {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	now := time.Now()
+	status := PoolStatus{
+		Workers:       make([]WorkerStatus, 0, len(wp.workers)),
+		QueueLength:   len(wp.jobQueue),
+		QueueCapacity: cap(wp.jobQueue),
+		SoftTimeoutMS: wp.softTimeout.Milliseconds(),
+		HardTimeoutMS: wp.hardTimeout.Milliseconds(),
+		Abandoned:     wp.abandoned,
+	}
+	for _, slot := range wp.workers {
+		worker := WorkerStatus{WorkerID: slot.id, State: "idle"}
+		if slot.job != nil {
+			startedAt := slot.startedAt
+			running := now.Sub(startedAt)
+			worker.State = "busy"
+			if wp.softTimeout > 0 && running >= wp.softTimeout+slot.extraTime() {
+				worker.State = "stuck"
+			}
+			worker.WorkflowID = slot.job.Workflow.ID
+			worker.WorkflowName = slot.job.Workflow.Name
+			worker.StartedAt = &startedAt
+			worker.RunningMS = running.Milliseconds()
+		}
+		status.Workers = append(status.Workers, worker)
+	}
+	return status
+}
+
+// extraTime is how much longer than the default timeout the slot's job may run; the
+// watchdog thresholds are pushed back by it
+func (slot *workerSlot) extraTime() time.Duration {
+	if slot.limit > WorkflowTimeout {
+		return slot.limit - WorkflowTimeout
+	}
+	return 0
+}
+
+// watchdog periodically checks running jobs against the thresholds until the pool shuts down
+func (wp *WorkerPool) watchdog() {
+	for {
+		select {
+		case <-wp.ctx.Done():
+			return
+		case <-time.After(wp.watchdogInterval()):
+			wp.checkWorkers()
+		}
+	}
+}
+
+// watchdogInterval checks a few times per threshold, between 10ms and 5s
+func (wp *WorkerPool) watchdogInterval() time.Duration {
+	wp.mu.Lock()
+	threshold := wp.softTimeout
+	if threshold <= 0 || (wp.hardTimeout > 0 && wp.hardTimeout < threshold) {
+		threshold = wp.hardTimeout
+	}
+	wp.mu.Unlock()
+
+	interval := threshold / 4
+	if interval <= 0 || interval > 5*time.Second {
+		return 5 * time.Second
+	}
+	if interval < 10*time.Millisecond {
+		return 10 * time.Millisecond
+	}
+	return interval
+}
+
+// checkWorkers warns about jobs past the soft threshold and abandons those past the hard one
+// An abandoned worker's goroutine can't be stopped, so it is left to exit on its own
+// whenever its job returns, and a replacement keeps the pool at its configured size
+func (wp *WorkerPool) checkWorkers() {
+	var slow, hung []stuckJob
+
+	wp.mu.Lock()
+	now := time.Now()
+	var abandoned []*workerSlot
+	for _, slot := range wp.workers {
+		if slot.job == nil {
+			continue
+		}
+		running := now.Sub(slot.startedAt)
+		extra := slot.extraTime()
+		switch {
+		case wp.hardTimeout > 0 && running >= wp.hardTimeout+extra:
+			abandoned = append(abandoned, slot)
+			hung = append(hung, stuckJob{slot.id, *slot.job, running})
+		case wp.softTimeout > 0 && running >= wp.softTimeout+extra && !slot.warned:
+			slot.warned = true
+			slow = append(slow, stuckJob{slot.id, *slot.job, running})
+		}
+	}
+	for _, slot := range abandoned {
+		slot.abandoned = true
+		slot.cancelJob()
+		for i, worker := range wp.workers {
+			if worker == slot {
+				wp.workers = append(wp.workers[:i], wp.workers[i+1:]...)
+				break
+			}
+		}
+		slot.done.Do(wp.wg.Done)
+
+		// Once Shutdown is waiting on the workers, a replacement would race its wg.Wait
+		// and run after the pool has drained
+		if !wp.shuttingDown {
+			wp.startWorker()
+		}
+	}
+	wp.mu.Unlock()
+
+	for _, stuck := range slow {
+		wp.log.Warn("Workflow job exceeded soft time limit", map[string]interface{}{
+			"worker_id":   stuck.workerID,
+			"workflow_id": stuck.job.Workflow.ID,
+			"running_ms":  stuck.running.Milliseconds(),
+		})
+	}
+	for _, stuck := range hung {
+		wp.log.Error("Workflow job exceeded hard time limit, worker abandoned", map[string]interface{}{
+			"worker_id":   stuck.workerID,
+			"workflow_id": stuck.job.Workflow.ID,
+			"running_ms":  stuck.running.Milliseconds(),
+		})
+		if stuck.job.Executor != nil {
+			stuck.job.Executor.recordHardTimeout(stuck.job.Workflow, stuck.job.Payload, stuck.running)
+		}
+
+		// Counted once recorded, so a caller seeing the count also sees the record
+		wp.mu.Lock()
+		wp.abandoned++
+		wp.mu.Unlock()
+	}
+}
+
+// recordHardTimeout records a run the watchdog abandoned
+// The log entry is a coded failure so failure filters and alerts pick it up; the
+// execution trace keeps the distinct timed_out_hard status
+func (e *Executor) recordHardTimeout(workflow models.Workflow, payload string, running time.Duration) {
+	tenantID := workflow.TenantID
+	message := fmt.Sprintf("Execution abandoned by the watchdog after %s", running.Round(time.Millisecond))
+
+	e.log.WorkflowLog(
+		logger.LevelError,
+		"Workflow execution abandoned",
+		workflow.ID,
+		workflow.UserID,
+		tenantID,
+		map[string]interface{}{
+			"running_ms": running.Milliseconds(),
+		},
+	)
+
+	e.createLog(workflow, &models.Log{
+		WorkflowID:  workflow.ID,
+		Status:      "failed",
+		Message:     message,
+		ErrorCode:   connectors.ErrCodeTimedOutHard,
+		ErrorParams: map[string]string{"duration": running.Round(time.Second).String()},
+	})
+	if err := e.store.CreateExecution(&models.Execution{
+		WorkflowID:     workflow.ID,
+		Status:         StatusTimedOutHard,
+		Message:        utils.Mask(message),
+		TriggerSource:  workflow.TriggerType,
+		TriggerPayload: MaskPayload(payload),
+		DurationMS:     running.Milliseconds(),
+		Version:        version.Version,
+		InstanceID:     version.InstanceID(),
+	}); err != nil {
+		e.log.WorkflowLog(
+			logger.LevelWarn,
+			"Failed to record execution trace",
+			workflow.ID,
+			workflow.UserID,
+			tenantID,
+			map[string]interface{}{
+				"error": err.Error(),
+			},
+		)
+	}
+	e.store.RecordWorkflowExecution(workflow.ID, StatusTimedOutHard, running, message, time.Now())
+}
+
+// SetWatchdogThresholds changes when running jobs are reported (soft) and abandoned (hard)
+func (e *Executor) SetWatchdogThresholds(soft, hard time.Duration) {
+	e.pool.SetWatchdogThresholds(soft, hard)
+}
+
+// WorkerStatus returns what every worker in the pool is doing
+func (e *Executor) WorkerStatus() PoolStatus {
+	status := e.pool.Status()
+	status.BranchSlots, status.BranchActive, status.BranchQueued = e.branches.stats()
+	return status
+}
+
+// TenantInFlight returns how many jobs each tenant has queued or running in the pool
+func (e *Executor) TenantInFlight() map[string]int {
+	return e.pool.TenantInFlight()
+}
@@ -0,0 +1,122 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// waitFor polls until cond holds or the deadline passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestWatchdogReapsHungWorker runs a fake connector that ignores its context and checks
+// the watchdog reports it, abandons it, records the run and keeps the pool at full size
+func TestWatchdogReapsHungWorker(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(mockStore, testLogger)
+
+	user, _ := mockStore.CreateUser("watchdog@example.com", "hashed")
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Hangs", "webhook", "testing", `{}`)
+
+	pool := engine.NewWorkerPool(1, testLogger)
+	pool.SetWatchdogThresholds(50*time.Millisecond, 300*time.Millisecond)
+	pool.Start()
+
+	// The fake connector blocks until released, whatever happens to its context
+	release := make(chan struct{})
+	hungConnector := func(ctx context.Context) { <-release }
+	pool.Submit(engine.WorkflowJob{Workflow: *workflow, Executor: executor, Run: hungConnector})
+
+	waitFor(t, "the job to be reported stuck", func() bool {
+		status := pool.Status()
+		return len(status.Workers) == 1 && status.Workers[0].State == "stuck"
+	})
+	if worker := pool.Status().Workers[0]; worker.WorkflowID != workflow.ID || worker.StartedAt == nil {
+		t.Errorf("Expected the stuck worker to show its job, got %+v", worker)
+	}
+
+	waitFor(t, "the worker to be abandoned", func() bool { return pool.Status().Abandoned == 1 })
+	status := pool.Status()
+	if len(status.Workers) != 1 || status.Workers[0].State != "idle" {
+		t.Fatalf("Expected one idle replacement worker, got %+v", status.Workers)
+	}
+
+	execution, err := mockStore.GetLatestExecution(workflow.ID)
+	if err != nil || execution.Status != engine.StatusTimedOutHard {
+		t.Fatalf("Expected a timed_out_hard execution, got %+v (%v)", execution, err)
+	}
+	logs, _ := mockStore.GetLogsByWorkflowID(workflow.ID)
+	if len(logs) != 1 || logs[0].Status != "failed" || logs[0].ErrorCode != connectors.ErrCodeTimedOutHard {
+		t.Errorf("Expected a coded failure log, got %+v", logs)
+	}
+
+	// The replacement keeps serving jobs while the hung goroutine is still blocked
+	done := make(chan struct{})
+	pool.Submit(engine.WorkflowJob{Workflow: *workflow, Executor: executor, Run: func(ctx context.Context) { close(done) }})
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the replacement worker to run the next job")
+	}
+
+	// Once released, the abandoned goroutine exits without blocking shutdown or recording again
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+	if logs, _ := mockStore.GetLogsByWorkflowID(workflow.ID); len(logs) != 1 {
+		t.Errorf("Expected only the watchdog's log entry, got %d", len(logs))
+	}
+}
+
+// TestWatchdogDuringShutdown abandons a hung worker while Shutdown waits on it, letting
+// the shutdown finish without starting a replacement
+func TestWatchdogDuringShutdown(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(mockStore, testLogger)
+
+	user, _ := mockStore.CreateUser("draining@example.com", "hashed")
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Hangs on shutdown", "webhook", "testing", `{}`)
+
+	pool := engine.NewWorkerPool(1, testLogger)
+	pool.SetWatchdogThresholds(50*time.Millisecond, 300*time.Millisecond)
+	pool.Start()
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	pool.Submit(engine.WorkflowJob{Workflow: *workflow, Executor: executor, Run: func(ctx context.Context) {
+		close(started)
+		<-release
+	}})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected the abandoned worker to let shutdown finish, got %v", err)
+	}
+	// The watchdog counts the abandoned worker just after releasing it
+	waitFor(t, "the worker to be counted as abandoned", func() bool { return pool.Status().Abandoned == 1 })
+	if workers := pool.Status().Workers; len(workers) != 0 {
+		t.Errorf("Expected no replacement worker after shutdown, got %+v", workers)
+	}
+}
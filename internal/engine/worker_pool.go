@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -12,9 +13,19 @@ import (
 // WorkflowJob represents a workflow execution job
 type WorkflowJob struct {
 	Workflow models.Workflow
+	Payload  string // Trigger payload of this execution (JSON), used for template mapping
+	RequestID string // X-Request-ID of the API request that triggered it ("" for schedules)
 	Executor *Executor
+	Run      func(ctx context.Context) // Optional: runs instead of Executor.ExecuteWorkflowWithContext
+
+	reserved bool                // Its tenant's slot was taken with reserveTenant before Submit
+	dropped  func(reason string) // Called instead of the pool's OnDrop function if the job is dropped
 }
 
+// DefaultSubmitTimeout is how long Submit waits for room in a full queue before the job
+// is dropped (and dead-lettered)
+const DefaultSubmitTimeout = 5 * time.Second
+
 // WorkerPool manages a fixed number of workers to prevent resource exhaustion
 // PRODUCTION: Bounded concurrency instead of unlimited goroutines
 type WorkerPool struct {
@@ -24,6 +35,38 @@ type WorkerPool struct {
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
+
+	mu       sync.Mutex
+	workers  []*workerSlot // Running workers and their current jobs
+	nextID   int
+	started  bool // Resize only starts workers once Start has
+
+	softTimeout time.Duration // Jobs running longer are logged as stuck (0 = off)
+	hardTimeout time.Duration // Jobs running longer are abandoned and their worker replaced (0 = off)
+	abandoned   int           // Workers abandoned by the watchdog since start
+
+	submitTimeout time.Duration                         // How long Submit waits on a full queue
+	onDrop        func(job WorkflowJob, reason string) // Optional: called for each dropped job
+
+	tenantJobs map[string]int // Submitted jobs not yet finished, per tenant ID
+
+	shuttingDown bool           // Set by Shutdown; later submits are dropped
+	submits      sync.WaitGroup // Submits in progress, which Shutdown lets land before draining
+	draining     chan struct{}  // Closed by Shutdown: workers finish the queue, then exit
+}
+
+// workerSlot is one worker goroutine and the job it is running
+type workerSlot struct {
+	id   int
+	stop chan struct{} // Closed by Resize to retire the worker
+	done sync.Once     // Releases the pool's WaitGroup exactly once (worker exit or abandonment)
+
+	job       *WorkflowJob       // nil while idle
+	startedAt time.Time          // When the current job started
+	limit     time.Duration      // The current job's run timeout
+	cancelJob context.CancelFunc // Cancels the current job's context
+	warned    bool               // Soft threshold already reported for the current job
+	abandoned bool               // Given up on by the watchdog; exits once its job returns
 }
 
 // NewWorkerPool creates a new worker pool
@@ -35,9 +78,30 @@ func NewWorkerPool(workerCount int, log *logger.Logger) *WorkerPool {
 		log:         log,
 		ctx:         ctx,
 		cancel:      cancel,
+		softTimeout: DefaultWorkerSoftTimeout,
+		hardTimeout: DefaultWorkerHardTimeout,
+
+		submitTimeout: DefaultSubmitTimeout,
+		tenantJobs:    make(map[string]int),
+		draining:      make(chan struct{}),
 	}
 }
 
+// SetSubmitTimeout changes how long Submit waits on a full queue before dropping the job
+func (wp *WorkerPool) SetSubmitTimeout(timeout time.Duration) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.submitTimeout = timeout
+}
+
+// OnDrop registers a function called with every job Submit drops, so it isn't lost
+// (the executor records a dead letter)
+func (wp *WorkerPool) OnDrop(fn func(job WorkflowJob, reason string)) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.onDrop = fn
+}
+
 // Start spawns the worker goroutines
 func (wp *WorkerPool) Start() {
 	wp.log.Info("Starting worker pool", map[string]interface{}{
@@ -45,15 +109,68 @@ func (wp *WorkerPool) Start() {
 		"queue_size": cap(wp.jobQueue),
 	})
 
-	for i := 0; i < wp.workerCount; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i)
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.started = true
+	for len(wp.workers) < wp.workerCount {
+		wp.startWorker()
 	}
+	go wp.watchdog()
+}
+
+// startWorker launches one more worker; callers hold wp.mu
+func (wp *WorkerPool) startWorker() {
+	slot := &workerSlot{id: wp.nextID, stop: make(chan struct{})}
+	wp.workers = append(wp.workers, slot)
+	wp.wg.Add(1)
+	go wp.worker(slot)
+	wp.nextID++
+}
+
+// Resize changes the number of workers without dropping queued or in-flight jobs
+// Extra workers finish their current job before exiting; the queue capacity is fixed at creation
+func (wp *WorkerPool) Resize(workerCount int) {
+	if workerCount < 1 {
+		return
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if !wp.started {
+		wp.workerCount = workerCount
+		return
+	}
+
+	previous := len(wp.workers)
+	for len(wp.workers) < workerCount {
+		wp.startWorker()
+	}
+	for len(wp.workers) > workerCount {
+		last := len(wp.workers) - 1
+		close(wp.workers[last].stop)
+		wp.workers = wp.workers[:last]
+	}
+	wp.workerCount = workerCount
+
+	if previous != workerCount {
+		wp.log.Info("Worker pool resized", map[string]interface{}{
+			"previous_workers": previous,
+			"workers":          workerCount,
+		})
+	}
+}
+
+// WorkerCount returns the configured number of workers
+func (wp *WorkerPool) WorkerCount() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.workerCount
 }
 
 // worker is the individual worker goroutine
-func (wp *WorkerPool) worker(id int) {
-	defer wp.wg.Done()
+func (wp *WorkerPool) worker(slot *workerSlot) {
+	defer slot.done.Do(wp.wg.Done)
+	id := slot.id
 
 	wp.log.Debug("Worker started", map[string]interface{}{
 		"worker_id": id,
@@ -67,24 +184,55 @@ func (wp *WorkerPool) worker(id int) {
 			})
 			return
 
-		case job, ok := <-wp.jobQueue:
-			if !ok {
-				wp.log.Debug("Worker queue closed", map[string]interface{}{
+		case <-slot.stop:
+			wp.log.Debug("Worker removed by resize", map[string]interface{}{
+				"worker_id": id,
+			})
+			return
+
+		case <-wp.draining:
+			// Shutting down: finish whatever is still queued, then exit
+			select {
+			case job := <-wp.jobQueue:
+				if !wp.runJob(job, slot) {
+					return
+				}
+			default:
+				wp.log.Debug("Worker drained", map[string]interface{}{
 					"worker_id": id,
 				})
 				return
 			}
 
-			// Execute the job with timeout
-			wp.executeJob(job, id)
+		case job := <-wp.jobQueue:
+			if !wp.runJob(job, slot) {
+				return
+			}
 		}
 	}
 }
 
+// runJob executes a job and reports whether the worker should carry on
+func (wp *WorkerPool) runJob(job WorkflowJob, slot *workerSlot) bool {
+	// Execute the job with timeout
+	if abandoned := wp.executeJob(job, slot); abandoned {
+		// A replacement took this worker's place while the job hung
+		wp.log.Warn("Abandoned worker returned, exiting", map[string]interface{}{
+			"worker_id":   slot.id,
+			"workflow_id": job.Workflow.ID,
+		})
+		return false
+	}
+	return true
+}
+
 // executeJob runs a single workflow job with context awareness
-func (wp *WorkerPool) executeJob(job WorkflowJob, workerID int) {
+// Returns true if the watchdog abandoned the worker while the job ran
+func (wp *WorkerPool) executeJob(job WorkflowJob, slot *workerSlot) bool {
+	workerID := slot.id
+
 	// Create context with timeout for this job
-	ctx, cancel := context.WithTimeout(wp.ctx, 5*time.Minute)
+	ctx, cancel := withRunTimeout(logger.WithRequestID(wp.ctx, job.RequestID), job.Workflow, WorkflowTimeout)
 	defer cancel()
 
 	wp.log.Debug("Worker processing job", map[string]interface{}{
@@ -93,46 +241,103 @@ func (wp *WorkerPool) executeJob(job WorkflowJob, workerID int) {
 	})
 
 	start := time.Now()
+	wp.mu.Lock()
+	slot.job, slot.startedAt, slot.cancelJob, slot.warned = &job, start, cancel, false
+	slot.limit = workflowTimeout(job.Workflow, WorkflowTimeout).limit
+	wp.mu.Unlock()
 
 	// Execute with context awareness
-	job.Executor.ExecuteWorkflowWithContext(ctx, job.Workflow)
+	if job.Run != nil {
+		job.Run(ctx)
+	} else {
+		job.Executor.ExecuteWorkflowWithContext(ctx, job.Workflow, job.Payload)
+	}
+	wp.releaseTenant(job.Workflow.TenantID)
 
 	duration := time.Since(start)
-
-	wp.log.Debug("Worker completed job", map[string]interface{}{
-		"worker_id":   workerID,
-		"workflow_id": job.Workflow.ID,
-		"duration":    duration.String(),
-	})
+	wp.mu.Lock()
+	slot.job, slot.cancelJob = nil, nil
+	abandoned := slot.abandoned
+	wp.mu.Unlock()
+	if !abandoned {
+		wp.log.Debug("Worker completed job", map[string]interface{}{
+			"worker_id":   workerID,
+			"workflow_id": job.Workflow.ID,
+			"duration":    duration.String(),
+		})
+	}
+	return abandoned
 }
 
 // Submit adds a job to the queue
-// PRODUCTION: Non-blocking with queue full handling
+// PRODUCTION: Non-blocking with queue full handling; a job that finds no room within the
+// submit timeout is dropped and handed to the OnDrop function
+// Once Shutdown has started, jobs are dropped straight away
 func (wp *WorkerPool) Submit(job WorkflowJob) {
+	wp.mu.Lock()
+	timeout, onDrop, shuttingDown := wp.submitTimeout, wp.onDrop, wp.shuttingDown
+	if !shuttingDown {
+		wp.submits.Add(1)
+	}
+	if !job.reserved {
+		wp.tenantJobs[job.Workflow.TenantID]++
+	}
+	wp.mu.Unlock()
+
+	if shuttingDown {
+		wp.releaseTenant(job.Workflow.TenantID)
+		wp.log.Warn("Worker pool shutting down, job dropped", map[string]interface{}{
+			"workflow_id": job.Workflow.ID,
+		})
+		wp.drop(job, onDrop, "Worker pool shutting down")
+		return
+	}
+	defer wp.submits.Done()
+
 	select {
 	case wp.jobQueue <- job:
 		// Job submitted successfully
-	case <-time.After(5 * time.Second):
+	case <-time.After(timeout):
 		// Queue is full, log warning
 		wp.log.Warn("Worker queue full, job dropped", map[string]interface{}{
 			"workflow_id":  job.Workflow.ID,
 			"queue_length": len(wp.jobQueue),
 			"queue_cap":    cap(wp.jobQueue),
 		})
+		wp.releaseTenant(job.Workflow.TenantID)
+		wp.drop(job, onDrop, fmt.Sprintf("Worker queue full (%d jobs) for %s", cap(wp.jobQueue), timeout))
+	}
+}
+
+// drop hands a job Submit couldn't queue to whoever waits for it, or else to onDrop
+func (wp *WorkerPool) drop(job WorkflowJob, onDrop func(WorkflowJob, string), reason string) {
+	if job.dropped != nil {
+		job.dropped(reason)
+	} else if onDrop != nil {
+		onDrop(job, reason)
 	}
 }
 
 // Shutdown gracefully stops the worker pool
+// New jobs are refused; queued and in-flight jobs run to completion so their logs are
+// written. If ctx ends first, in-flight jobs are cancelled and ctx's error is returned
+// Safe to call more than once
 func (wp *WorkerPool) Shutdown(ctx context.Context) error {
+	wp.mu.Lock()
+	if wp.shuttingDown {
+		wp.mu.Unlock()
+		return nil
+	}
+	wp.shuttingDown = true
+	wp.mu.Unlock()
+
 	wp.log.Info("Shutting down worker pool", map[string]interface{}{
 		"pending_jobs": len(wp.jobQueue),
 	})
 
-	// Stop accepting new jobs
-	close(wp.jobQueue)
-
-	// Signal workers to stop
-	wp.cancel()
+	// Let submits already waiting for room land in the queue, then drain it
+	wp.submits.Wait()
+	close(wp.draining)
 
 	// Wait for workers to finish with timeout
 	done := make(chan struct{})
@@ -143,16 +348,55 @@ func (wp *WorkerPool) Shutdown(ctx context.Context) error {
 
 	select {
 	case <-done:
+		wp.cancel() // Stops the watchdog
 		wp.log.Info("Worker pool shutdown complete", nil)
 		return nil
 	case <-ctx.Done():
+		// Out of time: cancel in-flight jobs so they record their failure and return
+		wp.cancel()
 		wp.log.Warn("Worker pool shutdown timeout", map[string]interface{}{
-			"timeout": ctx.Err().Error(),
+			"timeout":        ctx.Err().Error(),
+			"abandoned_jobs": len(wp.jobQueue),
 		})
 		return ctx.Err()
 	}
 }
 
+// reserveTenant counts a job for a tenant that is about to be submitted, unless the tenant
+// already has limit jobs queued or running (limit <= 0 = no limit)
+func (wp *WorkerPool) reserveTenant(tenantID string, limit int) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if limit > 0 && wp.tenantJobs[tenantID] >= limit {
+		return false
+	}
+	wp.tenantJobs[tenantID]++
+	return true
+}
+
+// releaseTenant uncounts a tenant's job once it finishes, is dropped or is never submitted
+func (wp *WorkerPool) releaseTenant(tenantID string) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.tenantJobs[tenantID] <= 1 {
+		delete(wp.tenantJobs, tenantID)
+		return
+	}
+	wp.tenantJobs[tenantID]--
+}
+
+// TenantInFlight returns how many jobs each tenant has queued or running
+// Tenants with none are left out
+func (wp *WorkerPool) TenantInFlight() map[string]int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	counts := make(map[string]int, len(wp.tenantJobs))
+	for tenantID, count := range wp.tenantJobs {
+		counts[tenantID] = count
+	}
+	return counts
+}
+
 // QueueLength returns the current number of pending jobs
 func (wp *WorkerPool) QueueLength() int {
 	return len(wp.jobQueue)
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// Bounds on a workflow's timeout_seconds; values outside them are clamped
+const (
+	MinWorkflowTimeout = 5 * time.Second
+	MaxWorkflowTimeout = 600 * time.Second
+)
+
+// DryRunTimeout is how long a dry run may take when the workflow sets no timeout
+const DryRunTimeout = 30 * time.Second
+
+// runTimeoutKey carries the run's time limit in its context
+type runTimeoutKey struct{}
+
+// runTimeout is a run's time limit and where it came from
+type runTimeout struct {
+	limit      time.Duration
+	configured bool // From the workflow's timeout_seconds rather than the default
+}
+
+// workflowTimeout returns a workflow's time limit: its timeout_seconds clamped to
+// MinWorkflowTimeout-MaxWorkflowTimeout, or fallback when it sets none
+func workflowTimeout(workflow models.Workflow, fallback time.Duration) runTimeout {
+	var config models.WorkflowConfig
+	if err := json.Unmarshal([]byte(workflow.ConfigJSON), &config); err != nil || config.TimeoutSeconds <= 0 {
+		return runTimeout{limit: fallback}
+	}
+	limit := time.Duration(config.TimeoutSeconds) * time.Second
+	if limit < MinWorkflowTimeout {
+		limit = MinWorkflowTimeout
+	}
+	if limit > MaxWorkflowTimeout {
+		limit = MaxWorkflowTimeout
+	}
+	return runTimeout{limit: limit, configured: true}
+}
+
+// withRunTimeout limits a run to the workflow's time limit (or fallback)
+func withRunTimeout(parent context.Context, workflow models.Workflow, fallback time.Duration) (context.Context, context.CancelFunc) {
+	timeout := workflowTimeout(workflow, fallback)
+	ctx, cancel := context.WithTimeout(context.WithValue(parent, runTimeoutKey{}, timeout), timeout.limit)
+	return ctx, cancel
+}
+
+// explainRunTimeout says which timeout stopped a cancelled run, and reports whether it was
+// the run's own limit (rather than a shutdown or an outer caller)
+func explainRunTimeout(ctx context.Context, result *connectors.Result) bool {
+	timeout, ok := ctx.Value(runTimeoutKey{}).(runTimeout)
+	if !ok || result.Status != "cancelled" || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+	source := "default"
+	if timeout.configured {
+		source = "workflow-configured"
+	}
+	result.Message = fmt.Sprintf("Run stopped by the %s timeout of %s: %s", source, timeout.limit, result.Message)
+	return true
+}
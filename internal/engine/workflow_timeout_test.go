@@ -0,0 +1,53 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// TestWorkflowTimeout runs a testing action that outlasts the workflow's timeout_seconds
+// (clamped up to the 5 second minimum), queued and as a dry run, and checks both are
+// cancelled by the configured timeout
+func TestWorkflowTimeout(t *testing.T) {
+	database := dbtest.New(t)
+	executor := engine.NewExecutor(database, logger.NewLogger("test"))
+
+	user, _ := database.CreateUser("timeout@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Slow", "webhook", "testing", `{"timeout_seconds": 1, "testing_delay": 7000}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	start := time.Now()
+	executor.ExecuteWorkflow(*workflow, "")
+	result := executor.DryRun(*workflow, user.ID, "tenant_"+user.ID)
+	if elapsed := time.Since(start); elapsed < engine.MinWorkflowTimeout || elapsed > engine.MinWorkflowTimeout+time.Second {
+		t.Errorf("Expected the dry run to stop at the 5s minimum, took %s", elapsed)
+	}
+	if result.Status != "cancelled" || !strings.Contains(result.Message, "workflow-configured timeout of 5s") {
+		t.Errorf("Expected the dry run to be cancelled by the configured timeout, got %s: %s", result.Status, result.Message)
+	}
+
+	// The queued run started first, so it is recorded by now or shortly after
+	waitFor(t, "the queued run to be recorded", func() bool {
+		execution, err := database.GetLatestExecution(workflow.ID)
+		if err != nil {
+			return false
+		}
+		if execution.Status != "cancelled" || !strings.Contains(execution.Message, "workflow-configured timeout of 5s") {
+			t.Fatalf("Expected the queued run to be cancelled by the configured timeout, got %s: %s", execution.Status, execution.Message)
+		}
+		return true
+	})
+
+	// Without the field a dry run keeps the default limit
+	quick, _ := database.CreateWorkflow(user.ID, "Quick", "webhook", "testing", `{"testing_delay": 50}`)
+	if result := executor.DryRun(*quick, user.ID, "tenant_"+user.ID); result.Status != "success" {
+		t.Errorf("Expected a run within the default timeout to succeed, got %s: %s", result.Status, result.Message)
+	}
+}
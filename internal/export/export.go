@@ -0,0 +1,587 @@
+// Package export builds self-service tenant data exports (account portability).
+//
+// An export is a zip of NDJSON files: the tenant's workflows, credential metadata
+// (never values), fixtures, executions still within retention, monthly usage and
+// audit events, plus a manifest with the record counts. Jobs run in the background
+// and stream rows from the store straight into the zip writer, so memory stays flat
+// however much history a tenant has. The finished archive stays on disk until its
+// signed download link expires.
+package export
+
+import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/retention"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+// DefaultLinkTTL is how long a finished archive can be downloaded
+const DefaultLinkTTL = 24 * time.Hour
+
+// cleanupInterval is how often expired archives are deleted
+const cleanupInterval = 10 * time.Minute
+
+// Files in an export archive, in the order they are written
+const (
+	FileManifest    = "manifest.json"
+	FileWorkflows   = "workflows.ndjson"
+	FileCredentials = "credentials.ndjson"
+	FileFixtures    = "fixtures.ndjson"
+	FileExecutions  = "executions.ndjson"
+	FileUsage       = "usage.ndjson"
+	FileAuditEvents = "audit_events.ndjson"
+)
+
+var (
+	ErrInProgress  = errors.New("an export is already running for this tenant")
+	ErrNotFound    = errors.New("export not found")
+	ErrInvalidLink = errors.New("download link is invalid or expired")
+)
+
+// Job is one export request
+type Job struct {
+	ID          string           `json:"id"`
+	TenantID    string           `json:"tenant_id"`
+	RequestedBy string           `json:"requested_by"`
+	Status      string           `json:"status"` // pending, running, ready or failed
+	CreatedAt   time.Time        `json:"created_at"`
+	FinishedAt  *time.Time       `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"` // When the archive and its link go away
+	SizeBytes   int64            `json:"size_bytes,omitempty"`
+	Records     map[string]int64 `json:"records,omitempty"` // Records written, by file
+	Error       string           `json:"error,omitempty"`
+}
+
+// Manifest describes an archive; it is written to it as manifest.json
+type Manifest struct {
+	JobID       string                 `json:"job_id"`
+	TenantID    string                 `json:"tenant_id"`
+	RequestedBy string                 `json:"requested_by"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Retention   models.RetentionPolicy `json:"retention"` // Executions and payloads older than this were left out
+	Records     map[string]int64       `json:"records"`
+}
+
+// Workflow is the export format of a workflow: its definition with configs parsed
+// and masked, without stats or the deprecated trigger payload
+type Workflow struct {
+	ID             string                     `json:"id"`
+	Name           string                     `json:"name"`
+	TriggerType    string                     `json:"trigger_type"`
+	ActionType     string                     `json:"action_type"`
+	Config         map[string]interface{}     `json:"config"`
+	ActionChain    []models.ChainedAction     `json:"action_chain,omitempty"`
+	Parameters     []models.WorkflowParameter `json:"parameters,omitempty"`
+	IsActive       bool                       `json:"is_active"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	LastExecutedAt *time.Time                 `json:"last_executed_at,omitempty"`
+}
+
+// Credential is the metadata exported for a credential; the value never leaves the database
+type Credential struct {
+	ID          string    `json:"id"`
+	ServiceName string    `json:"service_name"`
+	TenantID    string    `json:"tenant_id,omitempty"`
+	KeyVersion  int       `json:"key_version"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store is the part of db.Store exports read from
+type Store interface {
+	GetWorkflowsByUserID(userID string) ([]models.Workflow, error)
+	GetCredentialsByUserID(userID string) ([]models.Credential, error)
+	ListWorkflowFixtures(workflowID string) ([]models.WorkflowFixture, error)
+	GetTenantSettings(tenantID string) (*models.TenantSettings, error)
+	EachExecution(userID string, since time.Time, fn func(*models.Execution) error) error
+	EachWorkflowCost(tenantID string, fn func(*models.WorkflowCost) error) error
+	EachAuditEvent(actorID string, fn func(*models.AuditEvent) error) error
+	RecordAuditEvent(event *models.AuditEvent) error
+}
+
+// Manager runs export jobs and serves their archives
+// Jobs are kept in memory; archives left over from a previous run are deleted on Start
+type Manager struct {
+	store Store
+	dir   string
+	key   []byte // Signs download links
+	log   *logger.Logger
+
+	mu        sync.Mutex
+	linkTTL   time.Duration
+	retention *retention.Worker // Holds the default log period (optional)
+	jobs      map[string]*Job
+	done      chan struct{}
+}
+
+// NewManager creates a manager writing archives to dir and signing links with key
+func NewManager(store Store, dir string, key []byte, log *logger.Logger) *Manager {
+	return &Manager{
+		store:   store,
+		dir:     dir,
+		key:     key,
+		log:     log,
+		linkTTL: DefaultLinkTTL,
+		jobs:    make(map[string]*Job),
+		done:    make(chan struct{}),
+	}
+}
+
+// SetLinkTTL changes how long archives finished from now on can be downloaded
+func (m *Manager) SetLinkTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.linkTTL = ttl
+}
+
+// SetRetention exports within the policies the worker applies, with its default log
+// period (LOG_RETENTION_DAYS); without one it is retention.DefaultLogsDays
+func (m *Manager) SetRetention(worker *retention.Worker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retention = worker
+}
+
+// defaultLogsDays is the default log period the retention worker applies
+func (m *Manager) defaultLogsDays() int {
+	m.mu.Lock()
+	worker := m.retention
+	m.mu.Unlock()
+	if worker == nil {
+		return retention.DefaultLogsDays
+	}
+	return worker.DefaultLogsDays()
+}
+
+// Start deletes leftover archives, then expired ones on a schedule until Stop is called
+func (m *Manager) Start() {
+	m.removeStale()
+	m.log.Info("Export manager started", map[string]interface{}{
+		"dir":      m.dir,
+		"link_ttl": m.currentLinkTTL().String(),
+	})
+
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.prune(time.Now())
+			case <-m.done:
+				m.log.Info("Export manager stopped", nil)
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the cleanup loop; running jobs finish
+func (m *Manager) Stop() {
+	close(m.done)
+}
+
+func (m *Manager) currentLinkTTL() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.linkTTL
+}
+
+// Request queues an export of the tenant's data on behalf of userID
+// Returns ErrInProgress while an earlier export of the tenant is still running
+func (m *Manager) Request(tenantID, userID string) (Job, error) {
+	m.mu.Lock()
+	for _, job := range m.jobs {
+		if job.TenantID == tenantID && (job.Status == StatusPending || job.Status == StatusRunning) {
+			m.mu.Unlock()
+			return Job{}, ErrInProgress
+		}
+	}
+	job := &Job{
+		ID:          uuid.New().String(),
+		TenantID:    tenantID,
+		RequestedBy: userID,
+		Status:      StatusPending,
+		CreatedAt:   time.Now(),
+	}
+	m.jobs[job.ID] = job
+	queued := *job
+	m.mu.Unlock()
+
+	go m.run(job)
+	return queued, nil
+}
+
+// Get returns a tenant's export job; other tenants' jobs are reported as ErrNotFound
+func (m *Manager) Get(tenantID, jobID string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[jobID]
+	if !ok || job.TenantID != tenantID {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// DownloadURL returns the signed link to a ready job's archive (empty for other jobs)
+func (m *Manager) DownloadURL(job Job) string {
+	if job.Status != StatusReady || job.ExpiresAt == nil {
+		return ""
+	}
+	expires := strconv.FormatInt(job.ExpiresAt.Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {m.sign(job.ID, expires)}}
+	return "/api/tenants/export/" + job.ID + "/download?" + query.Encode()
+}
+
+// Open checks a download link and opens the archive it points to
+// Any mismatch, including an expired link, is reported as ErrInvalidLink
+func (m *Manager) Open(jobID, expires, signature string, now time.Time) (*os.File, Job, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !now.Before(time.Unix(expiresAt, 0)) {
+		return nil, Job{}, ErrInvalidLink
+	}
+	if !hmac.Equal([]byte(signature), []byte(m.sign(jobID, expires))) {
+		return nil, Job{}, ErrInvalidLink
+	}
+
+	m.mu.Lock()
+	job, ok := m.jobs[jobID]
+	var copied Job
+	if ok {
+		copied = *job
+	}
+	m.mu.Unlock()
+	if !ok || copied.Status != StatusReady {
+		return nil, Job{}, ErrInvalidLink
+	}
+
+	file, err := os.Open(m.archivePath(jobID))
+	if err != nil {
+		return nil, Job{}, err
+	}
+	return file, copied, nil
+}
+
+// sign is the HMAC of a job ID and link expiry (unix seconds)
+func (m *Manager) sign(jobID, expires string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte("tenant-export\n" + jobID + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *Manager) archivePath(jobID string) string {
+	return filepath.Join(m.dir, jobID+".zip")
+}
+
+// run generates a job's archive and records the outcome in the audit trail
+func (m *Manager) run(job *Job) {
+	m.mu.Lock()
+	job.Status = StatusRunning
+	m.mu.Unlock()
+
+	size, records, err := m.generate(job)
+
+	m.mu.Lock()
+	finished := time.Now()
+	expires := finished.Add(m.linkTTL)
+	job.FinishedAt, job.ExpiresAt = &finished, &expires
+	job.Records = records
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusReady
+		job.SizeBytes = size
+	}
+	done := *job
+	m.mu.Unlock()
+
+	detail := fmt.Sprintf("tenant %s: export %s ready, %d workflows, %d credentials (metadata only), %d fixtures, %d executions, %d usage rows, %d audit events; link expires %s",
+		done.TenantID, done.ID, records[FileWorkflows], records[FileCredentials], records[FileFixtures], records[FileExecutions],
+		records[FileUsage], records[FileAuditEvents], expires.UTC().Format(time.RFC3339))
+	if err != nil {
+		detail = fmt.Sprintf("tenant %s: export %s failed: %v", done.TenantID, done.ID, err)
+		m.log.Error("Tenant export failed", map[string]interface{}{
+			"tenant_id": done.TenantID,
+			"job_id":    done.ID,
+			"error":     err.Error(),
+		})
+	} else {
+		m.log.Info("Tenant export ready", map[string]interface{}{
+			"tenant_id":  done.TenantID,
+			"job_id":     done.ID,
+			"size_bytes": size,
+			"records":    records,
+		})
+	}
+	m.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: done.RequestedBy,
+		Action:  models.AuditTenantExport,
+		Detail:  detail,
+	})
+}
+
+// generate writes a job's archive to a temporary file and moves it in place once complete
+func (m *Manager) generate(job *Job) (int64, map[string]int64, error) {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return 0, nil, fmt.Errorf("failed to create export dir: %w", err)
+	}
+	tmp := m.archivePath(job.ID) + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer os.Remove(tmp) // No-op once renamed
+
+	records, err := m.write(job, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, records, err
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, records, err
+	}
+	if err := os.Rename(tmp, m.archivePath(job.ID)); err != nil {
+		return 0, records, err
+	}
+	return info.Size(), records, nil
+}
+
+// write streams the tenant's data into a zip, one NDJSON file per kind of record
+// Tenants have a single user for now, whose ID keys the user-scoped tables
+func (m *Manager) write(job *Job, w io.Writer) (map[string]int64, error) {
+	settings, err := m.store.GetTenantSettings(job.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant settings: %w", err)
+	}
+	policy := retention.Effective(settings, m.defaultLogsDays())
+	now := time.Now()
+	executionsSince := now.AddDate(0, 0, -policy.ExecutionsDays)
+	payloadsSince := now.AddDate(0, 0, -policy.PayloadsDays)
+
+	zw := zip.NewWriter(w)
+	records := make(map[string]int64)
+
+	workflows, err := m.store.GetWorkflowsByUserID(job.RequestedBy)
+	if err != nil {
+		return records, fmt.Errorf("failed to read workflows: %w", err)
+	}
+	err = writeFile(zw, FileWorkflows, records, func(emit func(interface{}) error) error {
+		for _, workflow := range workflows {
+			if err := emit(exportWorkflow(workflow)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return records, err
+	}
+
+	err = writeFile(zw, FileCredentials, records, func(emit func(interface{}) error) error {
+		credentials, err := m.store.GetCredentialsByUserID(job.RequestedBy)
+		if err != nil {
+			return err
+		}
+		for _, cred := range credentials {
+			metadata := Credential{ID: cred.ID, ServiceName: cred.ServiceName, TenantID: cred.TenantID, KeyVersion: cred.KeyVersion, CreatedAt: cred.CreatedAt}
+			if err := emit(metadata); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return records, err
+	}
+
+	err = writeFile(zw, FileFixtures, records, func(emit func(interface{}) error) error {
+		for _, workflow := range workflows {
+			fixtures, err := m.store.ListWorkflowFixtures(workflow.ID)
+			if err != nil {
+				return err
+			}
+			for _, fixture := range fixtures {
+				if err := emit(fixture); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return records, err
+	}
+
+	err = writeFile(zw, FileExecutions, records, func(emit func(interface{}) error) error {
+		return m.store.EachExecution(job.RequestedBy, executionsSince, func(execution *models.Execution) error {
+			// The retention worker may not have cleared payloads past their period yet
+			if execution.ExecutedAt.Before(payloadsSince) {
+				execution.TriggerPayload, execution.ResultData = "", ""
+			}
+			return emit(execution)
+		})
+	})
+	if err != nil {
+		return records, err
+	}
+
+	err = writeFile(zw, FileUsage, records, func(emit func(interface{}) error) error {
+		return m.store.EachWorkflowCost(job.TenantID, func(cost *models.WorkflowCost) error {
+			return emit(cost)
+		})
+	})
+	if err != nil {
+		return records, err
+	}
+
+	err = writeFile(zw, FileAuditEvents, records, func(emit func(interface{}) error) error {
+		return m.store.EachAuditEvent(job.RequestedBy, func(event *models.AuditEvent) error {
+			return emit(event)
+		})
+	})
+	if err != nil {
+		return records, err
+	}
+
+	manifest, err := zw.Create(FileManifest)
+	if err != nil {
+		return records, err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(Manifest{
+		JobID:       job.ID,
+		TenantID:    job.TenantID,
+		RequestedBy: job.RequestedBy,
+		GeneratedAt: now,
+		Retention:   policy,
+		Records:     records,
+	})
+	if err != nil {
+		return records, err
+	}
+	return records, zw.Close()
+}
+
+// writeFile adds one NDJSON file to the archive, counting the records fill emits
+func writeFile(zw *zip.Writer, name string, records map[string]int64, fill func(emit func(interface{}) error) error) error {
+	file, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	records[name] = 0
+	err = fill(func(record interface{}) error {
+		records[name]++
+		return encoder.Encode(record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", strings.TrimSuffix(name, ".ndjson"), err)
+	}
+	return nil
+}
+
+// exportWorkflow converts a stored workflow to the export format
+// Configs are masked like logged payloads, in case a secret was pasted inline
+func exportWorkflow(workflow models.Workflow) Workflow {
+	exported := Workflow{
+		ID:             workflow.ID,
+		Name:           workflow.Name,
+		TriggerType:    workflow.TriggerType,
+		ActionType:     workflow.ActionType,
+		Config:         map[string]interface{}{},
+		IsActive:       workflow.IsActive,
+		CreatedAt:      workflow.CreatedAt,
+		LastExecutedAt: workflow.LastExecutedAt,
+	}
+	var config map[string]interface{}
+	if json.Unmarshal([]byte(workflow.ConfigJSON), &config) == nil && config != nil {
+		exported.Config = utils.MaskMap(config)
+	}
+	if workflow.ActionChain != "" && json.Unmarshal([]byte(workflow.ActionChain), &exported.ActionChain) == nil {
+		maskChain(exported.ActionChain)
+	}
+	if workflow.Parameters != "" {
+		json.Unmarshal([]byte(workflow.Parameters), &exported.Parameters)
+	}
+	return exported
+}
+
+// maskChain masks the config of every chained action, parallel branches included
+func maskChain(chain []models.ChainedAction) {
+	for i := range chain {
+		if chain[i].Config != nil {
+			chain[i].Config = utils.MaskMap(chain[i].Config)
+		}
+		maskChain(chain[i].Parallel)
+	}
+}
+
+// prune deletes archives whose link has expired, and forgets their jobs
+func (m *Manager) prune(now time.Time) {
+	m.mu.Lock()
+	var expired []string
+	for id, job := range m.jobs {
+		if job.ExpiresAt != nil && !now.Before(*job.ExpiresAt) {
+			expired = append(expired, id)
+			delete(m.jobs, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, id := range expired {
+		if err := os.Remove(m.archivePath(id)); err != nil && !os.IsNotExist(err) {
+			m.log.Warn("Failed to delete expired export", map[string]interface{}{
+				"job_id": id,
+				"error":  err.Error(),
+			})
+		}
+	}
+}
+
+// removeStale deletes archives written before this process started; their jobs are gone
+func (m *Manager) removeStale() {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, ".zip.tmp")) {
+			continue
+		}
+		os.Remove(filepath.Join(m.dir, name))
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/config"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// ConfigHandler exposes the runtime-tunable settings to administrators
+type ConfigHandler struct {
+	store  db.Store // Interface, not concrete type!
+	config *config.Manager
+}
+
+// NewConfigHandler creates a new runtime config handler
+func NewConfigHandler(store db.Store, manager *config.Manager) *ConfigHandler {
+	return &ConfigHandler{store: store, config: manager}
+}
+
+// GetConfig returns the effective settings with secrets masked
+func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.config.Masked())
+}
+
+// UpdateConfig applies a partial settings document without a restart
+// Body: any subset of the GET fields, e.g. {"worker_pool_size": 20, "scheduler_interval": "30s"}
+func (h *ConfigHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, utils.MaxRequestBodySize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.config.Apply(body, userID, config.SourceAPI); err != nil {
+		if errors.Is(err, config.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to apply config", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.config.Masked())
+}
+
+// GetConfigChanges returns the audit trail of settings changes, newest first
+func (h *ConfigHandler) GetConfigChanges(w http.ResponseWriter, r *http.Request) {
+	changes, err := h.store.ListConfigChanges(100)
+	if err != nil {
+		http.Error(w, "Failed to fetch config changes", http.StatusInternalServerError)
+		return
+	}
+	if changes == nil {
+		changes = []models.ConfigChange{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
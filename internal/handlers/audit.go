@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+)
+
+// AuditHandler serves a tenant's audit trail to its admins
+type AuditHandler struct {
+	store db.Store // Interface, not concrete type!
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(store db.Store) *AuditHandler {
+	return &AuditHandler{store: store}
+}
+
+// GetAuditEvents returns the caller's tenant audit events, newest first
+// Filters: ?resource_type=, ?since= and ?until= (RFC3339), ?limit= (default 100, at most 500)
+func (h *AuditHandler) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{ResourceType: query.Get("resource_type"), Limit: defaultAuditLimit}
+	for name, dest := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, name+" must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*dest = t
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	events, err := h.store.ListTenantAuditEvents(tenantID, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch audit events", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
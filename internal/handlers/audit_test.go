@@ -0,0 +1,94 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestAuditWorkflowLifecycle creates and deletes a workflow and reads both changes back
+// from the tenant's audit trail, along with a refused delete
+func TestAuditWorkflowLifecycle(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	admin, _ := database.CreateUser("admin@audit.com", "hashed")
+	member, _ := database.CreateUserInTenant(admin.TenantID, "member@audit.com", "hashed")
+	adminToken, memberToken := memberToken(t, admin), memberToken(t, member)
+	start := time.Now().Add(-time.Second)
+
+	var created handlers.WorkflowResponse
+	if status := call(t, "POST", srv.URL+"/api/workflows", adminToken, map[string]string{
+		"name": "Audited", "trigger_type": "webhook", "action_type": "testing",
+	}, &created); status != http.StatusCreated {
+		t.Fatalf("Expected the workflow created, got %d", status)
+	}
+	if status := call(t, "DELETE", srv.URL+"/api/workflows/"+created.ID, adminToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Expected the workflow deleted, got %d", status)
+	}
+
+	var events []models.AuditEvent
+	if status := call(t, "GET", srv.URL+"/api/audit?resource_type=workflow", adminToken, nil, &events); status != http.StatusOK {
+		t.Fatalf("Expected the audit trail, got %d", status)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected two audit events, got %d: %+v", len(events), events)
+	}
+	for i, want := range []string{models.AuditWorkflowDelete, models.AuditWorkflowCreate} {
+		e := events[i]
+		if e.Action != want || e.ActorID != admin.ID || e.TenantID != admin.TenantID ||
+			e.ResourceType != models.AuditResourceWorkflow || e.ResourceID != created.ID || e.SourceIP != "127.0.0.1" {
+			t.Errorf("Expected %s by the admin on the workflow, got %+v", want, e)
+		}
+	}
+	if !strings.Contains(events[1].Detail, `"name":"Audited"`) {
+		t.Errorf("Expected the created workflow summarised, got %s", events[1].Detail)
+	}
+
+	// A member deleting someone else's workflow is refused and recorded
+	other, _ := database.CreateWorkflow(admin.ID, "Admin's", "webhook", "testing", `{}`)
+	if status := call(t, "DELETE", srv.URL+"/api/workflows/"+other.ID, memberToken, nil, nil); status != http.StatusForbidden {
+		t.Fatalf("Expected the member forbidden, got %d", status)
+	}
+	events = nil
+	call(t, "GET", srv.URL+"/api/audit?resource_type=workflow&limit=1", adminToken, nil, &events)
+	if len(events) != 1 || events[0].Action != models.AuditAccessDenied || events[0].ActorID != member.ID ||
+		events[0].ResourceID != other.ID || events[0].StatusCode != http.StatusForbidden {
+		t.Errorf("Expected the refused delete recorded, got %+v", events)
+	}
+
+	// The time range narrows the trail
+	events = nil
+	call(t, "GET", srv.URL+"/api/audit?until="+start.UTC().Format(time.RFC3339), adminToken, nil, &events)
+	if events == nil || len(events) != 0 {
+		t.Errorf("Expected nothing before the test started, got %+v", events)
+	}
+
+	for _, c := range []struct {
+		name, token, query string
+		status             int
+	}{
+		{"member", memberToken, "", http.StatusForbidden},
+		{"bad since", adminToken, "?since=yesterday", http.StatusBadRequest},
+		{"limit too large", adminToken, "?limit=501", http.StatusBadRequest},
+	} {
+		if status := call(t, "GET", srv.URL+"/api/audit"+c.query, c.token, nil, nil); status != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, status)
+		}
+	}
+}
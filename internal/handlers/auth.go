@@ -1,14 +1,18 @@
 package handlers
 
 import (
-	"database/sql"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/audit"
 	"github.com/alexmacdonald/simple-ipass/internal/db"
 	"github.com/alexmacdonald/simple-ipass/internal/middleware"
 	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
 	"github.com/alexmacdonald/simple-ipass/internal/utils"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -18,11 +22,19 @@ import (
 // PRODUCTION: Uses Store interface for testability
 type AuthHandler struct {
 	store db.Store // Interface, not concrete type!
+	audit *audit.Recorder
+
+	resets       PasswordResetSender // Delivers forgot-password tokens (nil: stored only)
+	resetLimiter *emailLimiter       // Forgot-password requests per email
 }
 
 // NewAuthHandler creates a new auth handler
 func NewAuthHandler(store db.Store) *AuthHandler {
-	return &AuthHandler{store: store}
+	return &AuthHandler{
+		store:        store,
+		audit:        audit.NewRecorder(store),
+		resetLimiter: newEmailLimiter(forgotPasswordLimit, forgotPasswordWindow),
+	}
 }
 
 // Register handles user registration with strict JSON validation
@@ -31,157 +43,203 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	
 	// Use strict JSON decoding to prevent malformed requests
 	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
-		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		utils.WriteJSONErrorCode(w, utils.CodeInvalidBody, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Validate input using go-playground/validator
 	if err := utils.ValidateStruct(&req); err != nil {
-		utils.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		utils.WriteJSONErrorCode(w, utils.CodeValidationFailed, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Check if user already exists
 	_, err := h.store.GetUserByEmail(req.Email)
 	if err == nil {
-		utils.WriteJSONError(w, "User already exists", http.StatusConflict)
+		utils.WriteJSONErrorCode(w, utils.CodeUserExists, "User already exists", http.StatusConflict)
+		return
+	}
+	if !store.IsNotFound(err) {
+		SendError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to hash password")
 		return
 	}
 
 	// Create user
 	user, err := h.store.CreateUser(req.Email, string(hashedPassword))
-	if err != nil {
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+	if store.IsConflict(err) {
+		// Registered concurrently since the check above
+		utils.WriteJSONErrorCode(w, utils.CodeUserExists, "User already exists", http.StatusConflict)
 		return
 	}
-
-	// Generate JWT
-	token, err := generateJWT(user.ID)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
+	h.recordSignIn(r, models.AuditRegister, user, req.Email)
 
-	// Return response
-	response := models.AuthResponse{
-		Token: token,
-		User:  *user,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	h.respondWithSession(w, r, user)
 }
 
 // Login handles user login
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	// Validate input
 	if req.Email == "" || req.Password == "" {
-		http.Error(w, "Email and password are required", http.StatusBadRequest)
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Email and password are required")
 		return
 	}
 
 	// Get user by email
 	user, err := h.store.GetUserByEmail(req.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		if store.IsNotFound(err) {
+			h.recordSignIn(r, models.AuditLoginFailed, nil, req.Email)
+			SendErrorCode(w, http.StatusUnauthorized, utils.CodeInvalidCredentials, "Invalid credentials")
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		h.recordSignIn(r, models.AuditLoginFailed, user, req.Email)
+		SendErrorCode(w, http.StatusUnauthorized, utils.CodeInvalidCredentials, "Invalid credentials")
 		return
 	}
+	h.recordSignIn(r, models.AuditLogin, user, req.Email)
 
-	// Generate JWT
-	token, err := generateJWT(user.ID)
-	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
-		return
-	}
+	h.respondWithSession(w, r, user)
+}
 
-	// Return response
-	response := models.AuthResponse{
-		Token: token,
-		User:  *user,
+// recordSignIn audits a registration or sign-in attempt; user is nil for unknown emails
+func (h *AuthHandler) recordSignIn(r *http.Request, action string, user *models.User, email string) {
+	event := models.AuditEvent{Action: action, ResourceType: models.AuditResourceUser}
+	if user != nil {
+		event.ActorID, event.TenantID, event.ResourceID = user.ID, user.TenantID, user.ID
+	}
+	if action == models.AuditLoginFailed {
+		event.StatusCode = http.StatusUnauthorized
 	}
+	h.audit.Record(r, event, map[string]interface{}{"email": email})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// DevLoginEnabled reports whether /api/auth/dev-login may run
+// It takes both ENVIRONMENT=development and DEV_LOGIN_ENABLED=true, so a mistyped
+// or defaulted ENVIRONMENT alone never turns it on
+func DevLoginEnabled() bool {
+	return os.Getenv("ENVIRONMENT") == "development" && os.Getenv("DEV_LOGIN_ENABLED") == "true"
 }
 
-// DevLogin handles development mode auto-login
-// Creates a dev user if it doesn't exist, then logs in
+// DevLogin mints a short-lived token for any email, creating the user if needed
+// The guard is checked on every request as well as at startup
 // ONLY USE IN DEVELOPMENT - DO NOT ENABLE IN PRODUCTION
 func (h *AuthHandler) DevLogin(w http.ResponseWriter, r *http.Request) {
-	const (
-		devEmail    = "dev@goflow.local"
-		devPassword = "dev123"
-	)
+	if !DevLoginEnabled() {
+		SendError(w, http.StatusNotFound, "Not found")
+		return
+	}
 
-	// Try to get existing dev user
-	user, err := h.store.GetUserByEmail(devEmail)
-	
-	// If user doesn't exist, create it
+	var req models.DevLoginRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeInvalidBody, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeValidationFailed, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetUserByEmail(req.Email)
+	if err != nil && !store.IsNotFound(err) {
+		SendError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// A new user gets a password hash nothing matches, so they can only sign in this way
 	if err != nil {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(devPassword), bcrypt.DefaultCost)
-		if err != nil {
-			http.Error(w, "Failed to create dev user", http.StatusInternalServerError)
-			return
+		user, err = h.store.CreateUser(req.Email, unusablePasswordHash())
+		if store.IsConflict(err) {
+			// Created concurrently since the lookup above
+			user, err = h.store.GetUserByEmail(req.Email)
 		}
-
-		user, err = h.store.CreateUser(devEmail, string(hashedPassword))
 		if err != nil {
-			http.Error(w, "Failed to create dev user", http.StatusInternalServerError)
+			SendError(w, http.StatusInternalServerError, "Failed to create dev user")
 			return
 		}
 	}
+	h.recordSignIn(r, models.AuditDevLogin, user, req.Email)
 
-	// Generate JWT
-	token, err := generateJWT(user.ID)
+	token, err := generateDevJWT(user)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
-	// Return response
+	// Dev-login tokens come without a refresh token; sign in again once it expires
 	response := models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:     token,
+		ExpiresIn: int(DevLoginTokenTTL.Seconds()),
+		User:      *user,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// generateJWT creates a new JWT token for a user
-func generateJWT(userID string) (string, error) {
-	// TODO: MULTI-TENANT - Add tenant_id to claims
+// unusablePasswordHash returns a random value that isn't a bcrypt hash, so every password check fails
+func unusablePasswordHash() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return "!dev-login:" + hex.EncodeToString(buf)
+}
+
+// Token lifetimes: access tokens are short-lived and renewed with a refresh token
+// (POST /api/auth/refresh); a dev-login token has no refresh token and expires sooner still
+const (
+	AccessTokenTTL   = 15 * time.Minute
+	RefreshTokenTTL  = 30 * 24 * time.Hour
+	DevLoginTokenTTL = 10 * time.Minute
+)
+
+// generateJWT creates a new access token for a user, scoped to their tenant and role
+func generateJWT(user *models.User) (string, error) {
+	return signUserJWT(user, AccessTokenTTL, nil)
+}
+
+// generateDevJWT creates a short-lived token carrying a dev claim, which AuthMiddleware warns about
+func generateDevJWT(user *models.User) (string, error) {
+	return signUserJWT(user, DevLoginTokenTTL, jwt.MapClaims{"dev": true})
+}
+
+// signUserJWT signs a token for a user with the given lifetime and any extra claims
+// The token carries the user's token version, so a password change ends it
+func signUserJWT(user *models.User, ttl time.Duration, extra jwt.MapClaims) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
-		"iat":     time.Now().Unix(),
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"role":      user.Role,
+		"tv":        user.TokenVersion,
+		"exp":       time.Now().Add(ttl).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+	for key, value := range extra {
+		claims[key] = value
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(middleware.GetJWTSecret())
 }
-
@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// postAuth sends a JSON body to an auth handler and returns the recorder
+func postAuth(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// tokenClaims decodes an auth response and verifies its token
+func tokenClaims(t *testing.T, rec *httptest.ResponseRecorder) jwt.MapClaims {
+	t.Helper()
+	var response models.AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode auth response: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(response.Token, claims, func(*jwt.Token) (interface{}, error) {
+		return middleware.GetJWTSecret(), nil
+	}); err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	return claims
+}
+
+// TestDevLoginGuard only answers when both ENVIRONMENT=development and DEV_LOGIN_ENABLED=true
+func TestDevLoginGuard(t *testing.T) {
+	authHandler := handlers.NewAuthHandler(db.NewMockStore())
+
+	cases := []struct {
+		environment, enabled string
+		want                 int
+	}{
+		{"development", "true", http.StatusOK},
+		{"development", "", http.StatusNotFound},
+		{"development", "1", http.StatusNotFound},
+		{"production", "true", http.StatusNotFound},
+		{"Development", "true", http.StatusNotFound},
+		{"", "true", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		t.Setenv("ENVIRONMENT", c.environment)
+		t.Setenv("DEV_LOGIN_ENABLED", c.enabled)
+		if enabled := handlers.DevLoginEnabled(); enabled != (c.want == http.StatusOK) {
+			t.Errorf("ENVIRONMENT=%q DEV_LOGIN_ENABLED=%q: expected enabled=%v", c.environment, c.enabled, !enabled)
+		}
+		if rec := postAuth(authHandler.DevLogin, `{"email": "dev@goflow.local"}`); rec.Code != c.want {
+			t.Errorf("ENVIRONMENT=%q DEV_LOGIN_ENABLED=%q: expected %d, got %d", c.environment, c.enabled, c.want, rec.Code)
+		}
+	}
+
+	// Only the email is accepted
+	t.Setenv("ENVIRONMENT", "development")
+	t.Setenv("DEV_LOGIN_ENABLED", "true")
+	if rec := postAuth(authHandler.DevLogin, `{"email": "dev@goflow.local", "role": "admin"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected unknown fields to be rejected, got %d", rec.Code)
+	}
+	if rec := postAuth(authHandler.DevLogin, `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a missing email to be rejected, got %d", rec.Code)
+	}
+}
+
+// TestDevLoginToken mints a short-lived dev token for a new user who can't sign in with a password
+func TestDevLoginToken(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "development")
+	t.Setenv("DEV_LOGIN_ENABLED", "true")
+	mockStore := db.NewMockStore()
+	authHandler := handlers.NewAuthHandler(mockStore)
+
+	rec := postAuth(authHandler.DevLogin, `{"email": "new@example.com"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected dev login to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	devClaims := tokenClaims(t, rec)
+	if devClaims["dev"] != true {
+		t.Errorf("Expected a dev claim, got %v", devClaims)
+	}
+	if _, err := mockStore.GetUserByEmail("new@example.com"); err != nil {
+		t.Fatalf("Expected the user to be created: %v", err)
+	}
+
+	// The created user has no password that works
+	for _, password := range []string{"", "dev123", "!dev-login:"} {
+		body, _ := json.Marshal(models.LoginRequest{Email: "new@example.com", Password: password})
+		if rec := postAuth(authHandler.Login, string(body)); rec.Code != http.StatusUnauthorized && rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected password %q to be refused, got %d", password, rec.Code)
+		}
+	}
+
+	// A regular login is a short-lived access token (renewed with its refresh token) with no dev claim
+	if rec := postAuth(authHandler.Register, `{"email": "regular@example.com", "password": "secret123"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Failed to register: %d", rec.Code)
+	}
+	rec = postAuth(authHandler.Login, `{"email": "regular@example.com", "password": "secret123"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Failed to log in: %d", rec.Code)
+	}
+	loginClaims := tokenClaims(t, rec)
+	if _, ok := loginClaims["dev"]; ok {
+		t.Errorf("Expected no dev claim on a regular login, got %v", loginClaims)
+	}
+
+	devExp, _ := devClaims.GetExpirationTime()
+	loginExp, _ := loginClaims.GetExpirationTime()
+	if ttl := time.Until(devExp.Time); ttl > handlers.DevLoginTokenTTL || ttl < handlers.DevLoginTokenTTL-time.Minute {
+		t.Errorf("Expected the dev token to last %s, got %s", handlers.DevLoginTokenTTL, ttl)
+	}
+	if !devExp.Before(loginExp.Time) {
+		t.Errorf("Expected the dev token (%s) to expire before a regular one (%s)", devExp, loginExp)
+	}
+	if ttl := time.Until(loginExp.Time); ttl > handlers.AccessTokenTTL || ttl < handlers.AccessTokenTTL-time.Minute {
+		t.Errorf("Expected the access token to last %s, got %s", handlers.AccessTokenTTL, ttl)
+	}
+}
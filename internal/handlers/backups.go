@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/backup"
+)
+
+// BackupsHandler lists database snapshots and recent backup runs
+type BackupsHandler struct {
+	backups *backup.Manager
+}
+
+// NewBackupsHandler creates a new backups handler
+func NewBackupsHandler(backups *backup.Manager) *BackupsHandler {
+	return &BackupsHandler{backups: backups}
+}
+
+// BackupsResponse is the snapshot listing plus the outcome of recent runs
+type BackupsResponse struct {
+	Dir       string            `json:"dir"`
+	Snapshots []backup.Manifest `json:"snapshots"`   // Newest first
+	Runs      []backup.Outcome  `json:"recent_runs"` // Newest first, including failures
+}
+
+// ListBackups returns the verified snapshots on disk and the outcome of recent backup runs
+func (h *BackupsHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.backups.List()
+	if err != nil {
+		http.Error(w, "Failed to list backups", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BackupsResponse{
+		Dir:       h.backups.Dir(),
+		Snapshots: snapshots,
+		Runs:      h.backups.History(),
+	})
+}
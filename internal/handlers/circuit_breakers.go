@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// CircuitBreakersHandler lets administrators see and reset the executor's circuit breakers
+type CircuitBreakersHandler struct {
+	store    db.Store // Interface, not concrete type!
+	executor *engine.Executor
+}
+
+// NewCircuitBreakersHandler creates a new circuit breakers handler
+func NewCircuitBreakersHandler(store db.Store, executor *engine.Executor) *CircuitBreakersHandler {
+	return &CircuitBreakersHandler{store: store, executor: executor}
+}
+
+// GetCircuitBreakers lists every breaker's state, keyed by "<user ID>:<action type>"
+func (h *CircuitBreakersHandler) GetCircuitBreakers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.executor.CircuitBreakers().GetAllStates())
+}
+
+// ResetCircuitBreaker closes a breaker so the next call goes through, e.g. once the
+// provider is known to be back before the breaker's timeout runs out
+func (h *CircuitBreakersHandler) ResetCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	if !h.executor.CircuitBreakers().ResetBreaker(key) {
+		http.Error(w, "Circuit breaker not found", http.StatusNotFound)
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: adminID,
+		Action:  models.AuditCircuitBreakerReset,
+		Detail:  key,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+)
+
+// ListConnectors lists the action types workflows can use, with the config fields each reads
+func (h *WorkflowsHandler) ListConnectors(w http.ResponseWriter, r *http.Request) {
+	list := h.executor.Connectors().List()
+	infos := make([]connectors.ConnectorInfo, 0, len(list))
+	for _, c := range list {
+		infos = append(infos, c.Info())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
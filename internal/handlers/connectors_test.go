@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// echoConfig is the fake connector's own config
+type echoConfig struct {
+	Greeting string `json:"greeting"`
+}
+
+// echoConnector answers with its greeting and the payload it was given
+func echoConnector() connectors.Connector {
+	return connectors.Connector{
+		ActionType: "echo",
+		Label:      "Echo",
+		Chainable:  true,
+		Fields:     []connectors.ConfigField{{Name: "greeting", Required: true}},
+		DecodeConfig: func(raw []byte) (interface{}, error) {
+			var config echoConfig
+			if err := json.Unmarshal(raw, &config); err != nil {
+				return nil, err
+			}
+			if config.Greeting == "" {
+				return nil, errors.New("greeting is required for echo")
+			}
+			return config, nil
+		},
+		Execute: func(ctx context.Context, call connectors.Call) connectors.Result {
+			config := call.Options.(echoConfig)
+			return connectors.NewSuccessResult("Echoed", map[string]interface{}{
+				"greeting": config.Greeting,
+				"payload":  call.Payload,
+			}, time.Now())
+		},
+	}
+}
+
+// TestRegisteredConnector registers a fake connector, then lists, validates and runs it
+// through the API as the workflow's action and as a chain step
+func TestRegisteredConnector(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(mockStore, testLogger)
+	if err := executor.Connectors().Register(echoConnector()); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if err := executor.Connectors().Register(echoConnector()); err == nil {
+		t.Error("Expected a second registration of echo to fail")
+	}
+	srv := httptest.NewServer(server.NewRouter(server.Config{Store: mockStore, Executor: executor, Logger: testLogger}))
+	defer srv.Close()
+
+	user, _ := mockStore.CreateUser("registry@example.com", "hashed")
+	token := userToken(t, user.ID)
+
+	var listed []connectors.ConnectorInfo
+	if status := call(t, "GET", srv.URL+"/api/connectors", token, nil, &listed); status != http.StatusOK {
+		t.Fatalf("Failed to list connectors: %d", status)
+	}
+	byType := map[string]connectors.ConnectorInfo{}
+	for _, info := range listed {
+		byType[info.ActionType] = info
+	}
+	if echo := byType["echo"]; len(echo.ConfigFields) != 1 || !echo.ConfigFields[0].Required {
+		t.Errorf("Expected echo listed with its required greeting, got %+v", echo)
+	}
+	if httpRequest := byType["http_request"]; len(httpRequest.ConfigFields) == 0 || httpRequest.ConfigFields[0].Name != "http_url" || !httpRequest.ConfigFields[0].Required {
+		t.Errorf("Expected http_request listed with its required http_url, got %+v", httpRequest)
+	}
+
+	// The connector's DecodeConfig decides what can be saved, for the action and chain steps
+	if status := call(t, "POST", srv.URL+"/api/workflows", token, handlers.CreateWorkflowRequest{
+		Name: "Silent", TriggerType: "webhook", ActionType: "echo", ConfigJSON: `{}`,
+	}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected a config without greeting to be rejected, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/workflows", token, map[string]interface{}{
+		"name": "Silent chain", "trigger_type": "webhook", "action_type": "testing", "config_json": `{}`,
+		"action_chain": []map[string]interface{}{{"action_type": "echo", "config": map[string]interface{}{}}},
+	}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected a chain step without greeting to be rejected, got %d", status)
+	}
+
+	var created handlers.WorkflowResponse
+	if status := call(t, "POST", srv.URL+"/api/workflows", token, map[string]interface{}{
+		"name": "Echo", "trigger_type": "webhook", "action_type": "echo", "config_json": `{"greeting": "hello"}`,
+		"action_chain": []map[string]interface{}{{"action_type": "echo", "config": map[string]interface{}{"greeting": "again"}, "use_data_from": "previous"}},
+	}, &created); status != http.StatusCreated {
+		t.Fatalf("Expected the echo workflow to be created, got %d", status)
+	}
+
+	var response handlers.RunWorkflowResponse
+	if status := call(t, "POST", srv.URL+"/api/workflows/"+created.ID+"/run?sync=true", token, map[string]interface{}{"order": 7}, &response); status != http.StatusOK {
+		t.Fatalf("Expected the run to succeed, got %d: %+v", status, response)
+	}
+	if response.Result == nil || !response.Result.Success || response.Result.Data["greeting"] != "hello" {
+		t.Fatalf("Expected the echo connector's result, got %+v", response.Result)
+	}
+	steps, _ := response.Result.Data["chain_results"].([]interface{})
+	if len(steps) != 1 {
+		t.Fatalf("Expected one chain result, got %v", response.Result.Data["chain_results"])
+	}
+	step, _ := steps[0].(map[string]interface{})
+	data, _ := step["data"].(map[string]interface{})
+	if step["status"] != "success" || data["greeting"] != "again" {
+		t.Errorf("Expected the chained echo to run, got %v", step)
+	}
+
+	// Dry runs accept any registered action type
+	var dryRun handlers.DryRunResponse
+	if status := call(t, "POST", srv.URL+"/api/workflows/dry-run", token, handlers.DryRunRequest{ActionType: "echo", ConfigJSON: `{"greeting": "test"}`}, &dryRun); status != http.StatusOK || dryRun.Data["greeting"] != "test" {
+		t.Errorf("Expected the dry run to use the echo connector, got %d: %+v", status, dryRun)
+	}
+	if status := call(t, "POST", srv.URL+"/api/workflows/dry-run", token, handlers.DryRunRequest{ActionType: "unknown", ConfigJSON: `{}`}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown action type to be rejected, got %d", status)
+	}
+}
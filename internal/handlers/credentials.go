@@ -3,52 +3,83 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/audit"
 	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
 	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
 )
 
 // CredentialsHandler handles credential management HTTP requests
 // PRODUCTION: Uses Store interface for testability
 type CredentialsHandler struct {
 	store db.Store // Interface, not concrete type!
+	audit *audit.Recorder
 }
 
 // NewCredentialsHandler creates a new credentials handler
 func NewCredentialsHandler(store db.Store) *CredentialsHandler {
-	return &CredentialsHandler{store: store}
+	return &CredentialsHandler{store: store, audit: audit.NewRecorder(store)}
 }
 
 type CreateCredentialRequest struct {
 	ServiceName string `json:"service_name"`
 	APIKey      string `json:"api_key"`
+	Environment string `json:"environment,omitempty"` // 'live' (default) or 'test'
 }
 
 // CreateCredential saves encrypted API keys/webhooks
 func (h *CredentialsHandler) CreateCredential(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req CreateCredentialRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	if req.ServiceName == "" || req.APIKey == "" {
-		http.Error(w, "service_name and api_key are required", http.StatusBadRequest)
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "service_name and api_key are required")
 		return
 	}
 
+	if err := engine.ValidateEnvironment(req.Environment); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+	if req.Environment == "" {
+		req.Environment = models.EnvironmentLive
+	}
+
+	// TLS settings are used verbatim by connectors, so reject bad PEM or insecure settings up front
+	if strings.HasPrefix(req.ServiceName, connectors.TLSCredentialPrefix) {
+		if _, err := connectors.ParseTLSSettings(req.APIKey); err != nil {
+			SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Invalid TLS settings: "+err.Error())
+			return
+		}
+	}
+
 	// Create credential with encryption
-	cred, err := h.store.CreateCredential(userID, req.ServiceName, req.APIKey)
+	cred, err := h.store.CreateCredentialInEnvironment(userID, req.ServiceName, req.APIKey, req.Environment)
 	if err != nil {
-		http.Error(w, "Failed to save credential", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to save credential")
 		return
 	}
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditCredentialCreate,
+		ResourceType: models.AuditResourceCredential,
+		ResourceID:   cred.ID,
+	}, map[string]interface{}{"service_name": cred.ServiceName, "environment": cred.Environment})
 
 	// Don't return the encrypted key
 	cred.EncryptedKey = ""
@@ -58,18 +89,17 @@ func (h *CredentialsHandler) CreateCredential(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(cred)
 }
 
-// GetCredentials lists user's connections (without exposing keys)
+// GetCredentials lists the tenant's connections (without exposing keys), each with its environment
 func (h *CredentialsHandler) GetCredentials(w http.ResponseWriter, r *http.Request) {
-	// TODO: MULTI-TENANT - Filter by tenant_id instead of user_id
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	creds, err := h.store.GetCredentialsByUserID(userID)
+	creds, err := h.store.GetCredentialsByTenantID(tenantID)
 	if err != nil {
-		http.Error(w, "Failed to fetch credentials", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to fetch credentials")
 		return
 	}
 
@@ -82,3 +112,71 @@ func (h *CredentialsHandler) GetCredentials(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(creds)
 }
 
+// DeleteCredential removes one of the tenant's credentials
+// Workflows that use it fail with a missing credential until a new one is saved
+func (h *CredentialsHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	credentialID := mux.Vars(r)["id"]
+	if err := h.store.DeleteCredential(tenantID, credentialID); err != nil {
+		writeStoreErrorCode(w, err, utils.CodeCredentialNotFound, "Credential not found")
+		return
+	}
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditCredentialDelete,
+		ResourceType: models.AuditResourceCredential,
+		ResourceID:   credentialID,
+	}, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestCredentialRequest carries an unsaved credential to check
+type TestCredentialRequest struct {
+	ServiceName string `json:"service_name"`
+	APIKey      string `json:"api_key"`
+}
+
+// TestCredentialResponse reports whether the service accepted the credential
+type TestCredentialResponse struct {
+	Valid     bool   `json:"valid"`
+	Detail    string `json:"detail"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// TestCredential checks a credential against its service before it is saved
+// Nothing is stored; a rejected credential is still a 200 with valid=false
+func (h *CredentialsHandler) TestCredential(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetUserIDFromContext(r.Context()); !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req TestCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+	if req.ServiceName == "" || req.APIKey == "" {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "service_name and api_key are required")
+		return
+	}
+
+	start := time.Now()
+	result, err := engine.VerifyCredential(r.Context(), req.ServiceName, req.APIKey)
+	if err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TestCredentialResponse{
+		Valid:     result.Status == "success",
+		Detail:    result.Message,
+		LatencyMS: time.Since(start).Milliseconds(),
+	})
+}
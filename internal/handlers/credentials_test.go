@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestTestCredential checks credentials are parsed and refused before any request, and
+// that nothing is saved
+func TestTestCredential(t *testing.T) {
+	mockStore := db.NewMockStore()
+	srv := httptest.NewServer(server.NewRouter(server.Config{Store: mockStore, Logger: logger.NewLogger("test")}))
+	defer srv.Close()
+
+	user, _ := mockStore.CreateUser("creds@example.com", "hashed")
+	token := userToken(t, user.ID)
+	url := srv.URL + "/api/credentials/test"
+
+	for _, body := range []handlers.TestCredentialRequest{
+		{ServiceName: "carrier_pigeon", APIKey: "coo"},
+		{ServiceName: "twilio", APIKey: "AC1:token"},
+		{ServiceName: "slack", APIKey: "http://169.254.169.254/latest/meta-data/"},
+		{ServiceName: "salesforce", APIKey: `{"instance_url": "http://localhost:8080", "access_token": "t"}`},
+		{ServiceName: "newsapi"},
+	} {
+		if status := call(t, "POST", url, token, body, nil); status != http.StatusBadRequest {
+			t.Errorf("%s %q: expected 400, got %d", body.ServiceName, body.APIKey, status)
+		}
+	}
+
+	var resp handlers.TestCredentialResponse
+	status := call(t, "POST", url, token, handlers.TestCredentialRequest{ServiceName: "twilio", APIKey: `{"account_sid": "AC1"}`}, &resp)
+	if status != http.StatusOK || resp.Valid || !strings.Contains(resp.Detail, "auth_token") {
+		t.Errorf("Expected an incomplete Twilio credential to be invalid, got %d %+v", status, resp)
+	}
+
+	if creds, _ := mockStore.GetCredentialsByUserID(user.ID); len(creds) != 0 {
+		t.Errorf("Expected nothing to be saved, got %+v", creds)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// ListDeadLetters returns the runs of the user's workflows that the worker pool dropped,
+// newest first; retried ones have resolved_at set
+func (h *WorkflowsHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	letters, err := h.store.ListDeadLetters(userID)
+	if err != nil {
+		writeStoreError(w, err, "Dead letters not found")
+		return
+	}
+	if letters == nil {
+		letters = []models.DeadLetter{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(letters)
+}
+
+// RetryDeadLetter resubmits a dropped run with its original trigger payload and marks
+// the dead letter resolved; each dead letter can be retried once
+func (h *WorkflowsHandler) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	letter, err := h.store.GetDeadLetter(mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err, "Dead letter not found")
+		return
+	}
+	workflow, ok := h.ownedWorkflow(w, r, letter.WorkflowID)
+	if !ok {
+		return
+	}
+	if !workflow.IsActive {
+		SendError(w, http.StatusConflict, "Workflow is inactive")
+		return
+	}
+	if workflow.PausedAt(time.Now()) {
+		SendError(w, http.StatusConflict, "Workflow is paused")
+		return
+	}
+
+	// Resolve first: only one of two concurrent retries gets past this
+	now := time.Now()
+	if err := h.store.ResolveDeadLetter(letter.ID, now); err != nil {
+		writeStoreError(w, err, "Dead letter not found")
+		return
+	}
+	letter.ResolvedAt = &now
+	h.executor.ExecuteWorkflowForRequest(*workflow, letter.Payload, middleware.GetRequestIDFromContext(r.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(letter)
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestDeadLetters overflows a one-worker pool with slow runs, checks the dropped runs are
+// dead-lettered, and retries one with its original payload
+func TestDeadLetters(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	pool := engine.NewWorkerPool(1, testLogger) // One running job plus 10 queued
+	pool.SetSubmitTimeout(20 * time.Millisecond)
+	executor := engine.NewExecutorWithPool(database, testLogger, pool)
+	pool.Start()
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: executor,
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	owner, _ := database.CreateUser("owner@example.com", "hashed")
+	stranger, _ := database.CreateUser("stranger@example.com", "hashed")
+	token := userToken(t, owner.ID)
+	workflow, err := database.CreateWorkflow(owner.ID, "Slow", "webhook", "testing", `{"testing_delay": 100}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	for i := 1; i <= 13; i++ {
+		executor.ExecuteWorkflow(*workflow, fmt.Sprintf(`{"n": %d}`, i))
+	}
+
+	var letters []models.DeadLetter
+	if status := call(t, "GET", srv.URL+"/api/dead-letters", token, nil, &letters); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(letters) != 2 || letters[0].WorkflowID != workflow.ID || letters[0].ResolvedAt != nil || letters[0].Reason == "" {
+		t.Fatalf("Expected the 2 overflowing runs to be dead-lettered, got %+v", letters)
+	}
+	var others []models.DeadLetter
+	call(t, "GET", srv.URL+"/api/dead-letters", userToken(t, stranger.ID), nil, &others)
+	if len(others) != 0 {
+		t.Errorf("Expected no dead letters for another user, got %+v", others)
+	}
+
+	// Retry once the queue has drained, as an operator would
+	waitForExecutions := func(n int) []models.Execution {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			executions, _ := database.ListExecutions(workflow.ID, models.PageRequest{Limit: 20})
+			if len(executions) >= n {
+				return executions
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d executions, got %d", n, len(executions))
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitForExecutions(11)
+
+	retryURL := srv.URL + "/api/dead-letters/" + letters[0].ID + "/retry"
+	if status := call(t, "POST", retryURL, userToken(t, stranger.ID), nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected another user's retry to be rejected, got %d", status)
+	}
+	var retried models.DeadLetter
+	if status := call(t, "POST", retryURL, token, nil, &retried); status != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", status)
+	}
+	if retried.ResolvedAt == nil {
+		t.Errorf("Expected the dead letter to be resolved, got %+v", retried)
+	}
+	if status := call(t, "POST", retryURL, token, nil, nil); status != http.StatusConflict {
+		t.Errorf("Expected a second retry to be rejected, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/dead-letters/missing/retry", token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected a missing dead letter to be 404, got %d", status)
+	}
+
+	if executions := waitForExecutions(12); executions[0].TriggerPayload != `{"n":13}` {
+		t.Errorf("Expected the retry to run the newest dropped payload, got %s", executions[0].TriggerPayload)
+	}
+}
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// DomainChallengePrefix is prepended to a domain to name its verification TXT record
+const DomainChallengePrefix = "_goflow-challenge."
+
+// domainChallengeValue is the content the TXT record must carry
+const domainChallengeValue = "goflow-verification="
+
+// domainWebhookUpstream is where Kong sends webhook traffic for custom domains
+// (the backend as the Kong use-case templates reach it)
+const domainWebhookUpstream = "http://backend:8080"
+
+// TXTResolver looks up the TXT records of a name (net.Resolver.LookupTXT in production)
+type TXTResolver func(ctx context.Context, name string) ([]string, error)
+
+// domainLabel is one DNS label: letters, digits and inner hyphens
+var domainLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DomainsHandler handles tenant webhook domain HTTP requests
+// Each domain gets its own Kong service and host-matched route to the webhook endpoint
+type DomainsHandler struct {
+	store     db.Store
+	kong      *KongHandler
+	lookupTXT TXTResolver
+}
+
+// NewDomainsHandler creates a new domains handler that configures Kong through kong
+// A nil resolver uses the system's DNS
+func NewDomainsHandler(store db.Store, kong *KongHandler, lookupTXT TXTResolver) *DomainsHandler {
+	if lookupTXT == nil {
+		lookupTXT = net.DefaultResolver.LookupTXT
+	}
+	return &DomainsHandler{store: store, kong: kong, lookupTXT: lookupTXT}
+}
+
+// AddDomainRequest registers a custom webhook domain
+type AddDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// DomainResponse is a tenant domain with the DNS record that verifies it
+type DomainResponse struct {
+	models.TenantDomain
+	Challenge DomainChallenge `json:"challenge"`
+}
+
+// DomainChallenge is the TXT record a tenant publishes to prove it controls a domain
+type DomainChallenge struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// AddDomain registers a domain for the tenant and routes it to the webhook endpoint through Kong
+// Webhook URLs only switch to the domain once its TXT challenge is verified
+func (h *DomainsHandler) AddDomain(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can manage domains", http.StatusForbidden)
+		return
+	}
+
+	var req AddDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name, err := NormalizeDomain(req.Domain)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := newVerificationToken()
+	if err != nil {
+		http.Error(w, "Failed to generate verification token", http.StatusInternalServerError)
+		return
+	}
+
+	// Claim the domain before touching Kong, so a domain another tenant holds is
+	// rejected without creating anything
+	domain := &models.TenantDomain{
+		TenantID:          tenantID,
+		Domain:            name,
+		VerificationToken: token,
+		Status:            models.DomainPending,
+	}
+	if err := h.store.CreateTenantDomain(domain); err != nil {
+		writeStoreError(w, err, "Domain not found")
+		return
+	}
+
+	if err := h.provision(domain); err != nil {
+		h.store.DeleteTenantDomain(tenantID, domain.ID)
+		http.Error(w, fmt.Sprintf("Failed to configure Kong for %s: %v", name, err), http.StatusBadGateway)
+		return
+	}
+	if err := h.store.UpdateTenantDomain(domain); err != nil {
+		h.deprovision(domain)
+		h.store.DeleteTenantDomain(tenantID, domain.ID)
+		writeStoreError(w, err, "Domain not found")
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditDomainAdd,
+		Detail:  fmt.Sprintf("tenant %s: domain %s", tenantID, name),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(domainResponse(domain))
+}
+
+// ListDomains returns the tenant's domains with their verification state
+func (h *DomainsHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	domains, err := h.store.ListTenantDomains(tenantID)
+	if err != nil {
+		writeStoreError(w, err, "Domain not found")
+		return
+	}
+	responses := make([]DomainResponse, 0, len(domains))
+	for i := range domains {
+		responses = append(responses, domainResponse(&domains[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+// VerifyDomain checks the domain's TXT challenge now
+// A failed check answers 200 with the domain still pending and the reason in last_error
+func (h *DomainsHandler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can manage domains", http.StatusForbidden)
+		return
+	}
+
+	domain, err := h.store.GetTenantDomain(tenantID, mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err, "Domain not found")
+		return
+	}
+
+	if domain.Status != models.DomainVerified {
+		now := time.Now()
+		domain.CheckedAt = &now
+		domain.LastError = ""
+		if err := h.checkChallenge(r.Context(), domain); err != nil {
+			domain.LastError = err.Error()
+		} else {
+			domain.Status = models.DomainVerified
+			domain.VerifiedAt = &now
+		}
+		if err := h.store.UpdateTenantDomain(domain); err != nil {
+			writeStoreError(w, err, "Domain not found")
+			return
+		}
+		if domain.Status == models.DomainVerified {
+			h.store.RecordAuditEvent(&models.AuditEvent{
+				ActorID: userID,
+				Action:  models.AuditDomainVerify,
+				Detail:  fmt.Sprintf("tenant %s: domain %s", tenantID, domain.Domain),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domainResponse(domain))
+}
+
+// DeleteDomain removes the domain's Kong route and service, then the domain
+// If Kong can't be cleaned up the domain is kept, so deleting can be retried
+func (h *DomainsHandler) DeleteDomain(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can manage domains", http.StatusForbidden)
+		return
+	}
+
+	domain, err := h.store.GetTenantDomain(tenantID, mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err, "Domain not found")
+		return
+	}
+	if err := h.deprovision(domain); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to remove Kong configuration for %s: %v", domain.Domain, err), http.StatusBadGateway)
+		return
+	}
+	if err := h.store.DeleteTenantDomain(tenantID, domain.ID); err != nil {
+		writeStoreError(w, err, "Domain not found")
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditDomainRemove,
+		Detail:  fmt.Sprintf("tenant %s: domain %s", tenantID, domain.Domain),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// provision creates the domain's Kong service and a route matching its host,
+// recording their IDs on the domain; a half-made setup is removed again
+func (h *DomainsHandler) provision(domain *models.TenantDomain) error {
+	service, err := h.kong.callKongAdmin("POST", "/services", KongService{
+		Name: "domain-" + domain.ID,
+		URL:  domainWebhookUpstream,
+	})
+	if err != nil {
+		return err
+	}
+	serviceID, _ := service["id"].(string)
+	if serviceID == "" {
+		return errors.New("Kong returned a service without an id")
+	}
+	domain.KongServiceID = serviceID
+
+	// Only webhook deliveries are routed; the original Host reaches GoFlow so the
+	// webhook handler can tell which tenant's domain a request came in on
+	stripPath := false
+	route := KongRoute{
+		Name:         "domain-" + domain.ID,
+		Hosts:        []string{domain.Domain},
+		Paths:        []string{"/api/webhooks/"},
+		Methods:      []string{"POST"},
+		StripPath:    &stripPath,
+		PreserveHost: true,
+	}
+	route.Service.ID = serviceID
+	created, err := h.kong.callKongAdmin("POST", "/routes", route)
+	if err != nil {
+		h.deprovision(domain)
+		return err
+	}
+	domain.KongRouteID, _ = created["id"].(string)
+	return nil
+}
+
+// deprovision deletes the domain's Kong route and service; objects already gone are fine
+func (h *DomainsHandler) deprovision(domain *models.TenantDomain) error {
+	if domain.KongRouteID != "" {
+		if err := h.kong.deleteObject("/routes/" + domain.KongRouteID); err != nil {
+			return err
+		}
+	}
+	if domain.KongServiceID != "" {
+		if err := h.kong.deleteObject("/services/" + domain.KongServiceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkChallenge looks for the domain's verification token in its TXT records
+func (h *DomainsHandler) checkChallenge(ctx context.Context, domain *models.TenantDomain) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	name := DomainChallengePrefix + domain.Domain
+	records, err := h.lookupTXT(ctx, name)
+	if err != nil {
+		return fmt.Errorf("TXT lookup for %s failed: %v", name, err)
+	}
+	want := domainChallengeValue + domain.VerificationToken
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("no TXT record at %s contains %s", name, want)
+}
+
+// NormalizeDomain validates a hostname for webhook delivery and returns it lowercase
+// without a trailing dot; IP addresses, wildcards and single labels are rejected
+func NormalizeDomain(domain string) (string, error) {
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if name == "" {
+		return "", errors.New("domain is required")
+	}
+	if len(name) > 253 || net.ParseIP(name) != nil {
+		return "", fmt.Errorf("invalid domain %q", domain)
+	}
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("invalid domain %q: use a fully qualified hostname like hooks.example.com", domain)
+	}
+	for _, label := range labels {
+		if !domainLabel.MatchString(label) {
+			return "", fmt.Errorf("invalid domain %q", domain)
+		}
+	}
+	return name, nil
+}
+
+// newVerificationToken returns a random token for a domain's TXT challenge
+func newVerificationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// domainResponse adds the TXT record to publish to a domain
+func domainResponse(domain *models.TenantDomain) DomainResponse {
+	return DomainResponse{
+		TenantDomain: *domain,
+		Challenge: DomainChallenge{
+			Type:  "TXT",
+			Name:  DomainChallengePrefix + domain.Domain,
+			Value: domainChallengeValue + domain.VerificationToken,
+		},
+	}
+}
+
+// webhookBaseURL is where a tenant's webhooks are called: its oldest verified domain,
+// or the host this request reached the API on
+func webhookBaseURL(s db.Store, r *http.Request, tenantID string) string {
+	if domains, err := s.ListTenantDomains(tenantID); err == nil {
+		for _, domain := range domains {
+			if domain.Status == models.DomainVerified {
+				return "https://" + domain.Domain
+			}
+		}
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// foreignDomain reports whether a webhook request came in on a domain registered to
+// another tenant than the workflow's; a tenant's hostname only serves its own workflows
+func foreignDomain(s db.Store, r *http.Request, workflow *models.Workflow) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	domain, err := s.GetTenantDomainByName(strings.ToLower(host))
+	if err != nil {
+		return false // Not a tenant domain (or the lookup failed): the API's own host
+	}
+	return domain.TenantID != workflow.TenantID
+}
@@ -0,0 +1,222 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// fakeKong is a Kong Admin API keeping services and routes in memory
+// Like Kong, it refuses to delete a service that a route still points at
+type fakeKong struct {
+	mu         sync.Mutex
+	objects    map[string]map[string]interface{} // By path, e.g. /routes/route-1
+	failPath   string                            // Refuse POSTs to this collection
+	failDelete string                            // Answer 500 to DELETEs of this object
+	next       int
+}
+
+func (k *fakeKong) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	switch r.Method {
+	case http.MethodPost:
+		if r.URL.Path == k.failPath {
+			http.Error(w, `{"message": "schema violation"}`, http.StatusBadRequest)
+			return
+		}
+		var object map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&object)
+		k.next++
+		id := fmt.Sprintf("%s-%d", strings.TrimPrefix(r.URL.Path, "/"), k.next)
+		object["id"] = id
+		k.objects[r.URL.Path+"/"+id] = object
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(object)
+	case http.MethodDelete:
+		if _, ok := k.objects[r.URL.Path]; !ok {
+			http.Error(w, `{"message": "Not found"}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == k.failDelete {
+			http.Error(w, `{"message": "An unexpected error occurred"}`, http.StatusInternalServerError)
+			return
+		}
+		for path, object := range k.objects {
+			service, _ := object["service"].(map[string]interface{})
+			if strings.HasPrefix(path, "/routes/") && service != nil && "/services/"+fmt.Sprint(service["id"]) == r.URL.Path {
+				http.Error(w, `{"message": "an existing 'routes' entity references this 'services' entity"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		delete(k.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (k *fakeKong) count(prefix string) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	n := 0
+	for path := range k.objects {
+		if strings.HasPrefix(path, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// TestTenantDomains registers, verifies and deletes a custom webhook domain against a
+// fake Kong and DNS, and checks webhook URLs follow the verified domain
+func TestTenantDomains(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+
+	kong := &fakeKong{objects: map[string]map[string]interface{}{}}
+	kongServer := httptest.NewServer(kong)
+	defer kongServer.Close()
+
+	var dnsMu sync.Mutex
+	txt := map[string][]string{}
+	lookup := func(_ context.Context, name string) ([]string, error) {
+		dnsMu.Lock()
+		defer dnsMu.Unlock()
+		records, ok := txt[name]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		return records, nil
+	}
+
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:        database,
+		Executor:     engine.NewExecutor(database, testLogger),
+		Logger:       testLogger,
+		KongAdminURL: kongServer.URL,
+		DomainLookup: lookup,
+	}))
+	defer srv.Close()
+
+	owner, _ := database.CreateUser("owner@example.com", "hashed")
+	other, _ := database.CreateUser("other@example.com", "hashed")
+	ownerToken, otherToken := userToken(t, owner.ID), userToken(t, other.ID)
+	workflow, _ := database.CreateWorkflow(owner.ID, "Orders", "webhook", "testing", `{}`)
+	otherWorkflow, _ := database.CreateWorkflow(other.ID, "Theirs", "webhook", "testing", `{}`)
+
+	if status := call(t, "POST", srv.URL+"/api/tenants/domains", ownerToken, map[string]string{"domain": "not a domain"}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid domain to be rejected, got %d", status)
+	}
+
+	var domain handlers.DomainResponse
+	if status := call(t, "POST", srv.URL+"/api/tenants/domains", ownerToken, map[string]string{"domain": "Hooks.Customer.com."}, &domain); status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", status)
+	}
+	if domain.Domain != "hooks.customer.com" || domain.Status != models.DomainPending || domain.Challenge.Name != "_goflow-challenge.hooks.customer.com" {
+		t.Errorf("Unexpected domain: %+v", domain)
+	}
+	route := kong.objects["/routes/"+domain.KongRouteID]
+	if route == nil || route["hosts"].([]interface{})[0] != "hooks.customer.com" || route["preserve_host"] != true {
+		t.Errorf("Expected a Kong route matching the host, got %v", route)
+	}
+	if route["service"].(map[string]interface{})["id"] != domain.KongServiceID {
+		t.Errorf("Expected the route to point at the domain's service, got %v", route["service"])
+	}
+
+	// Another tenant can't take the domain, and nothing is created in Kong for it
+	if status := call(t, "POST", srv.URL+"/api/tenants/domains", otherToken, map[string]string{"domain": "hooks.customer.com"}, nil); status != http.StatusConflict {
+		t.Errorf("Expected a domain held by another tenant to be rejected, got %d", status)
+	}
+	if kong.count("/services/") != 1 || kong.count("/routes/") != 1 {
+		t.Errorf("Expected one service and route in Kong, got %v", kong.objects)
+	}
+
+	// Unverified: webhook URLs stay on the API host
+	var workflows []handlers.WorkflowResponse
+	call(t, "GET", srv.URL+"/api/workflows", ownerToken, nil, &workflows)
+	if len(workflows) != 1 || workflows[0].WebhookURL != srv.URL+"/api/webhooks/"+workflow.ID {
+		t.Errorf("Expected the API host in the webhook URL before verification, got %+v", workflows)
+	}
+
+	// Verification fails without the record, then with the wrong one
+	verifyURL := srv.URL + "/api/tenants/domains/" + domain.ID + "/verify"
+	var checked handlers.DomainResponse
+	call(t, "POST", verifyURL, ownerToken, nil, &checked)
+	if checked.Status != models.DomainPending || !strings.Contains(checked.LastError, "no such host") || checked.CheckedAt == nil {
+		t.Errorf("Expected a failed lookup to leave the domain pending, got %+v", checked)
+	}
+	dnsMu.Lock()
+	txt[domain.Challenge.Name] = []string{"goflow-verification=someone-else"}
+	dnsMu.Unlock()
+	call(t, "POST", verifyURL, ownerToken, nil, &checked)
+	if checked.Status != models.DomainPending || !strings.Contains(checked.LastError, "no TXT record") {
+		t.Errorf("Expected the wrong token to leave the domain pending, got %+v", checked)
+	}
+	if status := call(t, "POST", verifyURL, otherToken, nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected another tenant's domain to be invisible, got %d", status)
+	}
+
+	dnsMu.Lock()
+	txt[domain.Challenge.Name] = []string{"v=spf1 -all", domain.Challenge.Value}
+	dnsMu.Unlock()
+	checked = handlers.DomainResponse{}
+	call(t, "POST", verifyURL, ownerToken, nil, &checked)
+	if checked.Status != models.DomainVerified || checked.LastError != "" || checked.VerifiedAt == nil {
+		t.Errorf("Expected the domain to be verified, got %+v", checked)
+	}
+
+	call(t, "GET", srv.URL+"/api/workflows", ownerToken, nil, &workflows)
+	if len(workflows) != 1 || workflows[0].WebhookURL != "https://hooks.customer.com/api/webhooks/"+workflow.ID {
+		t.Errorf("Expected the verified domain in the webhook URL, got %+v", workflows)
+	}
+	call(t, "GET", srv.URL+"/api/workflows", otherToken, nil, &workflows)
+	if len(workflows) != 1 || strings.Contains(workflows[0].WebhookURL, "customer.com") {
+		t.Errorf("Expected other tenants' URLs unchanged, got %+v", workflows)
+	}
+
+	// The domain only serves its own tenant's workflows
+	req, _ := http.NewRequest("POST", srv.URL+"/api/webhooks/"+otherWorkflow.ID, strings.NewReader(`{}`))
+	req.Host = "hooks.customer.com"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Webhook request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected another tenant's workflow to be unreachable through the domain, got %d", resp.StatusCode)
+	}
+
+	// Deleting removes the Kong objects and frees the domain
+	if status := call(t, "DELETE", srv.URL+"/api/tenants/domains/"+domain.ID, ownerToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", status)
+	}
+	if kong.count("/") != 0 {
+		t.Errorf("Expected the Kong objects to be removed, got %v", kong.objects)
+	}
+	if status := call(t, "POST", srv.URL+"/api/tenants/domains", otherToken, map[string]string{"domain": "hooks.customer.com"}, nil); status != http.StatusCreated {
+		t.Errorf("Expected a deleted domain to be free again, got %d", status)
+	}
+
+	// A route Kong refuses leaves neither a service nor a claimed domain behind
+	kong.failPath = "/routes"
+	if status := call(t, "POST", srv.URL+"/api/tenants/domains", ownerToken, map[string]string{"domain": "in.example.org"}, nil); status != http.StatusBadGateway {
+		t.Errorf("Expected a Kong failure to be reported, got %d", status)
+	}
+	var remaining []handlers.DomainResponse
+	call(t, "GET", srv.URL+"/api/tenants/domains", ownerToken, nil, &remaining)
+	if len(remaining) != 0 || kong.count("/services/") != 1 {
+		t.Errorf("Expected the failed registration to be rolled back, got %v and %v", remaining, kong.objects)
+	}
+}
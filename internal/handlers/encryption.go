@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// EncryptionHandler lets administrators rotate the keys protecting credentials
+type EncryptionHandler struct {
+	store db.Store // Interface, not concrete type!
+}
+
+// NewEncryptionHandler creates a new encryption handler
+func NewEncryptionHandler(store db.Store) *EncryptionHandler {
+	return &EncryptionHandler{store: store}
+}
+
+// RotateKeyRequest selects what to rotate
+type RotateKeyRequest struct {
+	TenantID string `json:"tenant_id,omitempty"` // Rotate this tenant's data key; empty = re-wrap everything under the current master key
+}
+
+// RotateKeyResponse reports the outcome of a rotation
+type RotateKeyResponse struct {
+	TenantID    string `json:"tenant_id,omitempty"`
+	KeyVersion  int    `json:"key_version,omitempty"` // The tenant's new data key version
+	Reencrypted int    `json:"reencrypted"`
+	Skipped     int    `json:"skipped"` // Rows that could not be decrypted and were left as they were
+}
+
+// RotateKey rotates a single tenant's data key, or re-wraps all credentials after the
+// master key changed (ENCRYPTION_KEY set to the new key, ENCRYPTION_KEY_PREVIOUS to the old)
+// Safe to repeat: only rows not yet on the current keys are touched
+func (h *EncryptionHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RotateKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := RotateKeyResponse{TenantID: req.TenantID}
+	var err error
+	if req.TenantID != "" {
+		response.KeyVersion, response.Reencrypted, response.Skipped, err = h.store.RotateTenantKey(req.TenantID)
+	} else {
+		response.Reencrypted, response.Skipped, err = h.store.ReencryptCredentials()
+	}
+	if err != nil {
+		http.Error(w, "Failed to rotate encryption key", http.StatusInternalServerError)
+		return
+	}
+
+	detail := fmt.Sprintf("master key: %d re-encrypted, %d skipped", response.Reencrypted, response.Skipped)
+	if req.TenantID != "" {
+		detail = fmt.Sprintf("tenant %s to version %d: %d re-encrypted, %d skipped", req.TenantID, response.KeyVersion, response.Reencrypted, response.Skipped)
+	}
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: adminID,
+		Action:  models.AuditEncryptionRotate,
+		Detail:  detail,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// ListExecutions returns a page of a workflow's executions, newest first, each with its
+// status, trigger source and duration_ms
+// ?limit=N&cursor=... as for other lists; trigger payloads are never included here
+func (h *WorkflowsHandler) ListExecutions(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	params, err := parsePageParams(r, maxPageLimit)
+	if err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	page := params.PageRequest
+	page.Limit++
+	executions, err := h.store.ListExecutions(workflow.ID, page)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to fetch executions")
+		return
+	}
+
+	hasMore := len(executions) > params.Limit
+	if hasMore {
+		executions = executions[:params.Limit]
+	}
+	if executions == nil {
+		executions = []models.Execution{}
+	}
+	for i := range executions {
+		executions[i].TriggerPayload = ""
+	}
+
+	response := ListPage{Items: executions}
+	if hasMore {
+		last := executions[len(executions)-1]
+		response.NextCursor = EncodeCursor(last.ExecutedAt, last.ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetExecution returns a workflow execution's stored trace
+// The trigger payload is left out by default. With ?include=payload it is returned to a
+// tenant admin only, and never to an impersonation session. The tenant's masking policy
+// is applied to it, and every payload read is audited
+func (h *WorkflowsHandler) GetExecution(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	workflow, ok := h.ownedWorkflow(w, r, vars["id"])
+	if !ok {
+		return
+	}
+	execution, err := h.store.GetExecutionByID(vars["executionId"])
+	if err != nil {
+		writeStoreError(w, err, "Execution not found")
+		return
+	}
+	if execution.WorkflowID != workflow.ID {
+		SendError(w, http.StatusNotFound, "Execution not found")
+		return
+	}
+
+	includePayload := r.URL.Query().Get("include") == "payload"
+	if !includePayload {
+		execution.TriggerPayload = ""
+	} else {
+		if _, _, impersonated := middleware.GetImpersonationFromContext(r.Context()); impersonated || !tenantAdmin(r) {
+			SendError(w, http.StatusForbidden, "Only a tenant admin can read trigger payloads")
+			return
+		}
+		settings, err := h.store.GetTenantSettings(tenantID)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "Failed to fetch tenant settings")
+			return
+		}
+		execution.TriggerPayload = engine.MaskPayloadForTenant(execution.TriggerPayload, settings.MaskedFields)
+		h.store.RecordAuditEvent(&models.AuditEvent{
+			ActorID: userID,
+			Action:  models.AuditPayloadRead,
+			Detail:  fmt.Sprintf("execution %s of workflow %s", execution.ID, workflow.ID),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(execution)
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// maxFixturesPerWorkflow caps the sample payloads stored with one workflow
+const maxFixturesPerWorkflow = 20
+
+// fixtureNamePattern keeps names short and safe to use in URLs
+var fixtureNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// CreateFixtureRequest stores a sample payload under a name
+type CreateFixtureRequest struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"` // JSON object, as a webhook would deliver it
+}
+
+// CaptureFixtureRequest saves the payload of a real execution as a fixture
+type CaptureFixtureRequest struct {
+	Name        string `json:"name"`
+	ExecutionID string `json:"execution_id,omitempty"` // Default: the latest real execution
+}
+
+// ListFixtures returns the sample payloads stored with a workflow
+func (h *WorkflowsHandler) ListFixtures(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	fixtures, err := h.store.ListWorkflowFixtures(workflow.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to list fixtures")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fixtures)
+}
+
+// CreateFixture stores a named sample payload for a workflow
+func (h *WorkflowsHandler) CreateFixture(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req CreateFixtureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	h.saveFixture(w, workflow.ID, req.Name, string(req.Payload), "manual")
+}
+
+// CaptureFixture saves the (already masked) payload of a real execution as a fixture
+func (h *WorkflowsHandler) CaptureFixture(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req CaptureFixtureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	var execution *models.Execution
+	var err error
+	if req.ExecutionID != "" {
+		execution, err = h.store.GetExecutionByID(req.ExecutionID)
+		if err == nil && execution.WorkflowID != workflow.ID {
+			err = db.ErrNotFound
+		}
+	} else {
+		execution, err = h.store.GetLatestExecution(workflow.ID)
+	}
+	if err != nil {
+		writeStoreError(w, err, "Execution not found")
+		return
+	}
+	if execution.TriggerPayload == "" {
+		SendError(w, http.StatusUnprocessableEntity, "Execution has no trigger payload to capture")
+		return
+	}
+
+	h.saveFixture(w, workflow.ID, req.Name, execution.TriggerPayload, "execution")
+}
+
+// DeleteFixture removes a fixture by name
+func (h *WorkflowsHandler) DeleteFixture(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	workflow, ok := h.ownedWorkflow(w, r, vars["id"])
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteWorkflowFixture(workflow.ID, vars["name"]); err != nil {
+		SendError(w, http.StatusNotFound, "Fixture not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// saveFixture validates, masks and stores a fixture, then writes it as the response
+func (h *WorkflowsHandler) saveFixture(w http.ResponseWriter, workflowID, name, payload, source string) {
+	if !fixtureNamePattern.MatchString(name) {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Fixture name must be 1-64 letters, digits, '.', '_' or '-'")
+		return
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &object); err != nil || object == nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Fixture payload must be a JSON object")
+		return
+	}
+
+	fixture := &models.WorkflowFixture{
+		WorkflowID: workflowID,
+		Name:       name,
+		Payload:    json.RawMessage(engine.MaskPayload(payload)),
+		Source:     source,
+	}
+	if err := h.store.CreateWorkflowFixture(fixture, maxFixturesPerWorkflow); err != nil {
+		switch {
+		case errors.Is(err, db.ErrFixtureExists):
+			SendError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, db.ErrFixtureLimit):
+			SendError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			SendError(w, http.StatusInternalServerError, "Failed to save fixture")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(fixture)
+}
+
+// fixturePayload returns the stored payload of a named fixture, writing a 404 if it doesn't exist
+func (h *WorkflowsHandler) fixturePayload(w http.ResponseWriter, workflowID, name string) (string, bool) {
+	fixture, err := h.store.GetWorkflowFixture(workflowID, strings.TrimSpace(name))
+	if err != nil {
+		writeStoreError(w, err, "Fixture not found")
+		return "", false
+	}
+	return string(fixture.Payload), true
+}
+
+// ownedWorkflow loads a workflow and checks the caller's tenant owns it, writing the error response if not
+func (h *WorkflowsHandler) ownedWorkflow(w http.ResponseWriter, r *http.Request, workflowID string) (*models.Workflow, bool) {
+	workflow, err := h.store.GetWorkflowByID(workflowID)
+	if err != nil {
+		writeWorkflowError(w, err)
+		return nil, false
+	}
+
+	if !inTenant(r, workflow) {
+		SendError(w, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+	return workflow, true
+}
+
+// inTenant reports whether the workflow belongs to the caller's tenant, whose users all share it
+func inTenant(r *http.Request, workflow *models.Workflow) bool {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	return ok && workflow.TenantID == tenantID
+}
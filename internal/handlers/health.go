@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
 )
 
 // HealthHandler handles health check requests
@@ -91,23 +92,12 @@ func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
 
 // checkDatabase verifies database connectivity
 func (h *HealthHandler) checkDatabase() string {
-	// Try a simple query to verify database is accessible
-	// For production, you'd query a lightweight table
+	// Look up a user that never exists: "not found" means the database answered,
+	// any other kind (locked, unavailable, ...) means it can't serve requests
 	_, err := h.store.GetUserByID("health_check_dummy")
-	
-	// We expect "not found" error, which means DB is working
-	// Only "connection failed" type errors are problematic
-	if err != nil {
-		// Check if it's a connection error (not just "not found")
-		errMsg := err.Error()
-		if errMsg == "sql: database is closed" || 
-		   errMsg == "database is locked" {
-			return "error: " + errMsg
-		}
-		// "Not found" is acceptable - DB is working
-		return "ok"
+	if err != nil && !store.IsNotFound(err) {
+		return "error: " + err.Error()
 	}
-	
 	return "ok"
 }
 
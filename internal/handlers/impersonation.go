@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// impersonationTTL keeps support sessions short; start a new one (with a new reason) to continue
+const impersonationTTL = 30 * time.Minute
+
+// ImpersonationHandler lets administrators act as a user for support debugging
+type ImpersonationHandler struct {
+	store db.Store // Interface, not concrete type!
+}
+
+// NewImpersonationHandler creates a new impersonation handler
+func NewImpersonationHandler(store db.Store) *ImpersonationHandler {
+	return &ImpersonationHandler{store: store}
+}
+
+// ImpersonateRequest names the user to act as and why
+type ImpersonateRequest struct {
+	UserID string `json:"user_id,omitempty"`
+	Email  string `json:"email,omitempty"` // Alternative to user_id
+	Reason string `json:"reason"`          // Required, e.g. the support ticket
+}
+
+// ImpersonateResponse carries the token for the session
+type ImpersonateResponse struct {
+	Token   string                      `json:"token"`
+	Session models.ImpersonationSession `json:"session"`
+}
+
+// StartImpersonation issues a short-lived token that acts as the target user
+// The token carries an impersonator claim and only works while its session is active
+func (h *ImpersonationHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Reason) > 500 {
+		http.Error(w, "reason must be at most 500 characters", http.StatusBadRequest)
+		return
+	}
+
+	var target *models.User
+	var err error
+	switch {
+	case req.UserID != "":
+		target, err = h.store.GetUserByID(req.UserID)
+	case req.Email != "":
+		target, err = h.store.GetUserByEmail(req.Email)
+	default:
+		http.Error(w, "user_id or email is required", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err, "User not found")
+		return
+	}
+	if target.ID == adminID {
+		http.Error(w, "Cannot impersonate yourself", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	session := &models.ImpersonationSession{
+		AdminID:      adminID,
+		TargetUserID: target.ID,
+		Reason:       req.Reason,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(impersonationTTL),
+	}
+	if err := h.store.CreateImpersonationSession(session); err != nil {
+		http.Error(w, "Failed to start impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	// Audit before handing out the token so a session is never usable without a record
+	if err := h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID:        target.ID,
+		ImpersonatorID: adminID,
+		SessionID:      session.ID,
+		Action:         models.AuditImpersonationStart,
+		Detail:         req.Reason,
+	}); err != nil {
+		h.store.RevokeImpersonationSession(session.ID, adminID, time.Now())
+		http.Error(w, "Failed to audit impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := generateImpersonationJWT(session, target)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ImpersonateResponse{Token: token, Session: *session})
+}
+
+// ListImpersonations returns the sessions that are still active, newest first
+func (h *ImpersonationHandler) ListImpersonations(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.store.ListActiveImpersonationSessions(time.Now())
+	if err != nil {
+		http.Error(w, "Failed to list impersonation sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeImpersonation ends a session; its token is rejected from the next request on
+func (h *ImpersonationHandler) RevokeImpersonation(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := h.store.GetImpersonationSession(mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err, "Impersonation session not found")
+		return
+	}
+	if err := h.store.RevokeImpersonationSession(session.ID, adminID, time.Now()); err != nil {
+		if store.IsNotFound(err) {
+			http.Error(w, "Impersonation session already revoked", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke impersonation session", http.StatusInternalServerError)
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID:        session.TargetUserID,
+		ImpersonatorID: session.AdminID,
+		SessionID:      session.ID,
+		Action:         models.AuditImpersonationRevoke,
+		Detail:         "revoked by " + adminID,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAuditEvents returns the most recent audit events, newest first
+func (h *ImpersonationHandler) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.store.ListAuditEvents(100)
+	if err != nil {
+		http.Error(w, "Failed to fetch audit events", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// generateImpersonationJWT creates a token that acts as the target user, in their tenant and role, for one session
+func generateImpersonationJWT(session *models.ImpersonationSession, target *models.User) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id":      session.TargetUserID,
+		"tenant_id":    target.TenantID,
+		"role":         target.Role,
+		"impersonator": session.AdminID,
+		"sid":          session.ID,
+		"scope":        "impersonation",
+		"exp":          session.ExpiresAt.Unix(),
+		"iat":          session.CreatedAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(middleware.GetJWTSecret())
+}
@@ -0,0 +1,164 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// userToken signs a regular login token for a user as admin of the tenant created with them
+func userToken(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   userID,
+		"tenant_id": models.DefaultTenantID(userID),
+		"role":      models.RoleAdmin,
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}).SignedString(middleware.GetJWTSecret())
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+// call sends an authenticated JSON request and returns the status and decoded body
+func call(t *testing.T, method, url, token string, body interface{}, out interface{}) int {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req, _ := http.NewRequest(method, url, &payload)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+// TestImpersonationAuditAndRevocation follows a support session from start to revocation
+func TestImpersonationAuditAndRevocation(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	guard := middleware.NewImpersonationGuard(database, testLogger)
+
+	admin, _ := database.CreateUser("support@example.com", "hashed")
+	customer, _ := database.CreateUser("customer@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(customer.ID, "Customer flow", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:         database,
+		Executor:      engine.NewExecutor(database, testLogger),
+		Logger:        testLogger,
+		AdminEmails:   []string{"support@example.com"},
+		Impersonation: guard,
+	}))
+	defer srv.Close()
+	adminToken := userToken(t, admin.ID)
+
+	if status := call(t, "POST", srv.URL+"/api/admin/impersonate", adminToken, map[string]string{"user_id": customer.ID}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected a missing reason to be rejected, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/admin/impersonate", userToken(t, customer.ID),
+		map[string]string{"user_id": admin.ID, "reason": "curious"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected non-admins to be refused, got %d", status)
+	}
+
+	var started handlers.ImpersonateResponse
+	status := call(t, "POST", srv.URL+"/api/admin/impersonate", adminToken,
+		map[string]string{"email": "customer@example.com", "reason": "Ticket #4521: webhook not firing"}, &started)
+	if status != http.StatusCreated || started.Token == "" {
+		t.Fatalf("Expected a session token, got %d", status)
+	}
+	session := started.Session
+
+	// The token sees exactly what the customer sees
+	var workflows []models.Workflow
+	if status := call(t, "GET", srv.URL+"/api/workflows", started.Token, nil, &workflows); status != http.StatusOK {
+		t.Fatalf("Expected the impersonated request to succeed, got %d", status)
+	}
+	if len(workflows) != 1 || workflows[0].ID != workflow.ID {
+		t.Errorf("Expected the customer's workflows, got %+v", workflows)
+	}
+
+	// ...but never admin routes
+	if status := call(t, "GET", srv.URL+"/api/admin/impersonations", started.Token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected admin routes to refuse impersonation tokens, got %d", status)
+	}
+
+	// Both identities reach the audit trail
+	events, err := database.ListAuditEvents(100)
+	if err != nil {
+		t.Fatalf("ListAuditEvents failed: %v", err)
+	}
+	var sawStart, sawRequest bool
+	for _, event := range events {
+		if event.SessionID != session.ID {
+			continue
+		}
+		if event.ActorID != customer.ID || event.ImpersonatorID != admin.ID {
+			t.Errorf("Expected %s acting as %s, got %+v", admin.ID, customer.ID, event)
+		}
+		switch {
+		case event.Action == models.AuditImpersonationStart && event.Detail == "Ticket #4521: webhook not firing":
+			sawStart = true
+		case event.Action == models.AuditHTTPRequest && event.Path == "/api/workflows" && event.StatusCode == http.StatusOK:
+			sawRequest = true
+		}
+	}
+	if !sawStart || !sawRequest {
+		t.Errorf("Expected start and request audit events, got %+v", events)
+	}
+
+	// Read-only sessions can still dry run but not change anything
+	guard.SetReadOnly(true)
+	if status := call(t, "POST", srv.URL+"/api/workflows", started.Token,
+		map[string]string{"name": "Sneaky", "trigger_type": "webhook", "action_type": "testing"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a mutating request to be blocked, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/workflows/dry-run", started.Token,
+		map[string]string{"workflow_id": workflow.ID}, nil); status != http.StatusOK {
+		t.Errorf("Expected dry runs to stay allowed, got %d", status)
+	}
+	guard.SetReadOnly(false)
+
+	var active []models.ImpersonationSession
+	if call(t, "GET", srv.URL+"/api/admin/impersonations", adminToken, nil, &active); len(active) != 1 || active[0].ID != session.ID {
+		t.Fatalf("Expected the session to be listed, got %+v", active)
+	}
+
+	// Revocation takes effect on the very next request
+	if status := call(t, "DELETE", srv.URL+"/api/admin/impersonations/"+session.ID, adminToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Expected revocation to succeed, got %d", status)
+	}
+	if status := call(t, "GET", srv.URL+"/api/workflows", started.Token, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked token to be rejected, got %d", status)
+	}
+	if call(t, "GET", srv.URL+"/api/admin/impersonations", adminToken, nil, &active); len(active) != 0 {
+		t.Errorf("Expected no active sessions, got %+v", active)
+	}
+	if status := call(t, "DELETE", srv.URL+"/api/admin/impersonations/"+session.ID, adminToken, nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected a second revocation to 404, got %d", status)
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inviteTTL is how long an invite can be accepted; send a new one after that
+const inviteTTL = 7 * 24 * time.Hour
+
+// inviteScope marks invite tokens; they carry no user_id, so they never authenticate API calls
+const inviteScope = "invite"
+
+// errInvalidInvite means an invite token isn't one this server issued
+var errInvalidInvite = errors.New("invalid invite token")
+
+// InvitesHandler handles invitations into an existing tenant
+type InvitesHandler struct {
+	store db.Store // Interface, not concrete type!
+}
+
+// NewInvitesHandler creates a new invites handler
+func NewInvitesHandler(store db.Store) *InvitesHandler {
+	return &InvitesHandler{store: store}
+}
+
+// CreateInviteRequest names who to invite
+type CreateInviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// CreateInviteResponse carries the invite and its token, which is only ever returned here
+type CreateInviteResponse struct {
+	Invite models.TenantInvite `json:"invite"`
+	Token  string              `json:"token"`
+}
+
+// CreateInvite issues a signed, single-use invite into the admin's tenant
+// Hand the token to the invitee, who signs up with it at /api/auth/register-with-invite
+func (h *InvitesHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if _, _, impersonated := middleware.GetImpersonationFromContext(r.Context()); impersonated || !tenantAdmin(r) {
+		SendError(w, http.StatusForbidden, "Only a tenant admin can invite users")
+		return
+	}
+
+	var req CreateInviteRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, err.Error())
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if err := utils.ValidateStruct(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	_, err := h.store.GetUserByEmail(req.Email)
+	if err == nil {
+		SendErrorCode(w, http.StatusConflict, utils.CodeUserExists, "User already exists")
+		return
+	}
+	if !store.IsNotFound(err) {
+		writeStoreError(w, err, "User not found")
+		return
+	}
+
+	now := time.Now()
+	invite := &models.TenantInvite{
+		TenantID:  tenantID,
+		Email:     req.Email,
+		InvitedBy: userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(inviteTTL),
+	}
+	if err := h.store.CreateTenantInvite(invite); err != nil {
+		writeStoreError(w, err, "Tenant not found")
+		return
+	}
+
+	token, err := generateInviteJWT(invite)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditInviteCreate,
+		Detail:  fmt.Sprintf("tenant %s: invite %s for %s", tenantID, invite.ID, invite.Email),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateInviteResponse{Invite: *invite, Token: token})
+}
+
+// ListInvites returns the tenant's invites, newest first, with lapsed ones reported as expired
+func (h *InvitesHandler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invites, err := h.store.ListTenantInvites(tenantID)
+	if err != nil {
+		writeStoreError(w, err, "Invite not found")
+		return
+	}
+	now := time.Now()
+	for i := range invites {
+		invites[i].Status = invites[i].CurrentStatus(now)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invites)
+}
+
+// RevokeInvite withdraws a pending invite; its token is refused from then on
+// Answers 409 once the invite was accepted or already revoked
+func (h *InvitesHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if !tenantAdmin(r) {
+		SendError(w, http.StatusForbidden, "Only a tenant admin can revoke invites")
+		return
+	}
+
+	inviteID := mux.Vars(r)["id"]
+	if err := h.store.RevokeTenantInvite(tenantID, inviteID, time.Now()); err != nil {
+		writeStoreError(w, err, "Invite not found")
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditInviteRevoke,
+		Detail:  fmt.Sprintf("tenant %s: invite %s", tenantID, inviteID),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegisterWithInvite creates a user inside the inviting tenant, using the invite up
+// Expired and revoked invites answer 410; one that was already accepted answers 409
+func (h *AuthHandler) RegisterWithInvite(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterWithInviteRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, err.Error())
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	inviteID, err := parseInviteJWT(req.Token)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		SendErrorCode(w, http.StatusGone, utils.CodeInviteClosed, "Invite has expired")
+		return
+	}
+	if err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Invalid invite token")
+		return
+	}
+
+	invite, err := h.store.GetTenantInvite(inviteID)
+	if err != nil {
+		writeStoreError(w, err, "Invite not found")
+		return
+	}
+	now := time.Now()
+	switch invite.CurrentStatus(now) {
+	case models.InviteAccepted:
+		SendErrorCode(w, http.StatusConflict, utils.CodeInviteClosed, "Invite has already been accepted")
+		return
+	case models.InviteRevoked:
+		SendErrorCode(w, http.StatusGone, utils.CodeInviteClosed, "Invite has been revoked")
+		return
+	case models.InviteExpired:
+		SendErrorCode(w, http.StatusGone, utils.CodeInviteClosed, "Invite has expired")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to hash password")
+		return
+	}
+
+	user, err := h.store.AcceptTenantInvite(invite.ID, string(hashedPassword), now)
+	if errors.Is(err, db.ErrInviteClosed) {
+		// Accepted (or revoked) concurrently since the check above
+		SendErrorCode(w, http.StatusConflict, utils.CodeInviteClosed, "Invite has already been accepted")
+		return
+	}
+	if store.IsConflict(err) {
+		SendErrorCode(w, http.StatusConflict, utils.CodeUserExists, "User already exists")
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err, "Invite not found")
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: user.ID,
+		Action:  models.AuditInviteAccept,
+		Detail:  fmt.Sprintf("tenant %s: invite %s from %s", invite.TenantID, invite.ID, invite.InvitedBy),
+	})
+
+	response, err := newSession(h.store, r, user)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// generateInviteJWT creates the token an invitee signs up with, valid until the invite expires
+func generateInviteJWT(invite *models.TenantInvite) (string, error) {
+	claims := jwt.MapClaims{
+		"invite_id": invite.ID,
+		"scope":     inviteScope,
+		"exp":       invite.ExpiresAt.Unix(),
+		"iat":       invite.CreatedAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(middleware.GetJWTSecret())
+}
+
+// parseInviteJWT checks an invite token's signature and expiry and returns its invite ID
+// An expired token's error matches jwt.ErrTokenExpired
+func parseInviteJWT(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return middleware.GetJWTSecret(), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["scope"] != inviteScope {
+		return "", errInvalidInvite
+	}
+	inviteID, ok := claims["invite_id"].(string)
+	if !ok || inviteID == "" {
+		return "", errInvalidInvite
+	}
+	return inviteID, nil
+}
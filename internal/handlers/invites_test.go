@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newInviteServer serves the API over a fresh database with a tenant admin signed in
+func newInviteServer(t *testing.T) (*httptest.Server, *db.Database, *models.User, string) {
+	t.Helper()
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	t.Cleanup(srv.Close)
+
+	admin, err := database.CreateUser("admin@acme.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	return srv, database, admin, memberToken(t, admin)
+}
+
+// inviteToken signs an invite token as the server does, for any invite ID and expiry
+func inviteToken(t *testing.T, inviteID string, expiresAt time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"invite_id": inviteID,
+		"scope":     "invite",
+		"exp":       expiresAt.Unix(),
+	}).SignedString(middleware.GetJWTSecret())
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+// TestInviteAccept signs a teammate up into the admin's tenant, once
+func TestInviteAccept(t *testing.T) {
+	srv, _, admin, adminToken := newInviteServer(t)
+
+	var created handlers.CreateInviteResponse
+	if status := call(t, "POST", srv.URL+"/api/tenants/invites", adminToken, map[string]string{"email": "teammate@acme.com"}, &created); status != http.StatusCreated {
+		t.Fatalf("Expected the invite to be created, got %d", status)
+	}
+	if created.Token == "" || created.Invite.Status != models.InvitePending || created.Invite.TenantID != admin.TenantID {
+		t.Fatalf("Expected a pending invite into the admin's tenant with a token, got %+v", created)
+	}
+
+	// The invite token isn't a login token
+	if status := call(t, "GET", srv.URL+"/api/workflows", created.Token, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the invite token to be refused by the API, got %d", status)
+	}
+
+	accept := map[string]string{"token": created.Token, "password": "secret123"}
+	var registered models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/register-with-invite", "", accept, &registered); status != http.StatusOK {
+		t.Fatalf("Expected registration with the invite to succeed, got %d", status)
+	}
+	if registered.User.Email != "teammate@acme.com" || registered.User.TenantID != admin.TenantID {
+		t.Errorf("Expected the invited email in the admin's tenant, got %+v", registered.User)
+	}
+
+	// The new member sees the tenant's workflows but can't invite anyone
+	if status := call(t, "GET", srv.URL+"/api/workflows", registered.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("Expected the member's token to work, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/tenants/invites", registered.Token, map[string]string{"email": "other@acme.com"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected members to be forbidden from inviting, got %d", status)
+	}
+
+	if status := call(t, "POST", srv.URL+"/api/auth/register-with-invite", "", accept, nil); status != http.StatusConflict {
+		t.Errorf("Expected a second acceptance to conflict, got %d", status)
+	}
+
+	var invites []models.TenantInvite
+	call(t, "GET", srv.URL+"/api/tenants/invites", adminToken, nil, &invites)
+	if len(invites) != 1 || invites[0].Status != models.InviteAccepted || invites[0].AcceptedBy != registered.User.ID {
+		t.Errorf("Expected the invite to be listed as accepted, got %+v", invites)
+	}
+}
+
+// TestInviteExpiry refuses invites whose token or record has lapsed
+func TestInviteExpiry(t *testing.T) {
+	srv, database, admin, adminToken := newInviteServer(t)
+
+	invite := &models.TenantInvite{
+		TenantID:  admin.TenantID,
+		Email:     "late@acme.com",
+		InvitedBy: admin.ID,
+		CreatedAt: time.Now().Add(-8 * 24 * time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+	if err := database.CreateTenantInvite(invite); err != nil {
+		t.Fatalf("Failed to create invite: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired token", inviteToken(t, invite.ID, invite.ExpiresAt)},
+		{"expired invite", inviteToken(t, invite.ID, time.Now().Add(time.Hour))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]string{"token": tt.token, "password": "secret123"}
+			if status := call(t, "POST", srv.URL+"/api/auth/register-with-invite", "", body, nil); status != http.StatusGone {
+				t.Errorf("Expected 410, got %d", status)
+			}
+		})
+	}
+	if _, err := database.GetUserByEmail("late@acme.com"); err == nil {
+		t.Error("Expected no user to be created from an expired invite")
+	}
+
+	var invites []models.TenantInvite
+	call(t, "GET", srv.URL+"/api/tenants/invites", adminToken, nil, &invites)
+	if len(invites) != 1 || invites[0].Status != models.InviteExpired {
+		t.Errorf("Expected the invite to be listed as expired, got %+v", invites)
+	}
+}
+
+// TestInviteRevoke stops a revoked invite from being used
+func TestInviteRevoke(t *testing.T) {
+	srv, database, _, adminToken := newInviteServer(t)
+
+	var created handlers.CreateInviteResponse
+	call(t, "POST", srv.URL+"/api/tenants/invites", adminToken, map[string]string{"email": "revoked@acme.com"}, &created)
+	revoke := srv.URL + "/api/tenants/invites/" + created.Invite.ID
+
+	outsider, _ := database.CreateUser("outsider@example.com", "hashed")
+	if status := call(t, "DELETE", revoke, memberToken(t, outsider), nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected another tenant's admin not to find the invite, got %d", status)
+	}
+	if status := call(t, "DELETE", revoke, adminToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Expected the invite to be revoked, got %d", status)
+	}
+	if status := call(t, "DELETE", revoke, adminToken, nil, nil); status != http.StatusConflict {
+		t.Errorf("Expected revoking twice to conflict, got %d", status)
+	}
+
+	body := map[string]string{"token": created.Token, "password": "secret123"}
+	if status := call(t, "POST", srv.URL+"/api/auth/register-with-invite", "", body, nil); status != http.StatusGone {
+		t.Errorf("Expected a revoked invite to be refused with 410, got %d", status)
+	}
+
+	var invites []models.TenantInvite
+	call(t, "GET", srv.URL+"/api/tenants/invites", adminToken, nil, &invites)
+	if len(invites) != 1 || invites[0].Status != models.InviteRevoked || invites[0].RevokedAt == nil {
+		t.Errorf("Expected the invite to be listed as revoked, got %+v", invites)
+	}
+}
@@ -3,9 +3,15 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alexmacdonald/simple-ipass/internal/db"
 	"github.com/alexmacdonald/simple-ipass/internal/middleware"
@@ -14,10 +20,30 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// Kong Admin API client defaults
+const (
+	DefaultKongTimeout    = 10 * time.Second
+	DefaultKongRetries    = 3
+	DefaultKongRetryDelay = 500 * time.Millisecond // Doubles after each retry
+	maxKongRetryDelay     = 10 * time.Second       // Cap on the backoff and on a Retry-After
+	maxKongPages          = 1000                   // Guards against an offset that never ends
+)
+
+// KongAdminConfig configures the requests made to the Kong Admin API
+type KongAdminConfig struct {
+	Token       string        // Sent with every request when set: an RBAC token, or a Konnect access token
+	TokenHeader string        // "Kong-Admin-Token" (default), or "Authorization" to send "Bearer <token>"
+	Timeout     time.Duration // Per attempt (default DefaultKongTimeout)
+	MaxRetries  int           // Retries after 429, 502 and 503 responses (0 = DefaultKongRetries, negative = none)
+	RetryDelay  time.Duration // First backoff (default DefaultKongRetryDelay)
+}
+
 // KongHandler handles Kong Gateway integration
 type KongHandler struct {
 	store       db.Store
 	kongAdminURL string // Kong Admin API URL (default: http://kong:8001)
+	admin       KongAdminConfig
+	client      *http.Client
 }
 
 // NewKongHandler creates a new Kong handler
@@ -25,10 +51,31 @@ func NewKongHandler(store db.Store, kongAdminURL string) *KongHandler {
 	if kongAdminURL == "" {
 		kongAdminURL = "http://kong:8001" // Default in Docker
 	}
-	return &KongHandler{
+	h := &KongHandler{
 		store:       store,
 		kongAdminURL: kongAdminURL,
 	}
+	h.SetAdminConfig(KongAdminConfig{})
+	return h
+}
+
+// SetAdminConfig sets the Admin API token, timeout and retries, filling in the defaults
+// Not safe to call while requests are being served
+func (h *KongHandler) SetAdminConfig(cfg KongAdminConfig) {
+	if cfg.TokenHeader == "" {
+		cfg.TokenHeader = "Kong-Admin-Token"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultKongTimeout
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultKongRetries
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultKongRetryDelay
+	}
+	h.admin = cfg
+	h.client = &http.Client{Timeout: cfg.Timeout}
 }
 
 // KongService represents a Kong service
@@ -44,15 +91,28 @@ type KongService struct {
 
 // KongRoute represents a Kong route
 type KongRoute struct {
-	ID      string   `json:"id,omitempty"`
-	Name    string   `json:"name"`
-	Paths   []string `json:"paths"`
-	Methods []string `json:"methods,omitempty"`
-	Service struct {
+	ID           string   `json:"id,omitempty"`
+	Name         string   `json:"name"`
+	Hosts        []string `json:"hosts,omitempty"`
+	Paths        []string `json:"paths"`
+	Methods      []string `json:"methods,omitempty"`
+	StripPath    *bool    `json:"strip_path,omitempty"`
+	PreserveHost bool     `json:"preserve_host,omitempty"`
+	Service      struct {
 		ID string `json:"id"`
 	} `json:"service"`
 }
 
+// kongAPIError is an error response from the Kong Admin API
+type kongAPIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *kongAPIError) Error() string {
+	return fmt.Sprintf("Kong API error: %d - %s", e.StatusCode, e.Body)
+}
+
 // KongPlugin represents a Kong plugin
 type KongPlugin struct {
 	ID      string                 `json:"id,omitempty"`
@@ -65,7 +125,7 @@ type KongPlugin struct {
 
 // CreateKongService creates a Kong service that proxies to GoFlow
 func (h *KongHandler) CreateKongService(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
 	if !ok {
 		utils.WriteJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -85,11 +145,11 @@ func (h *KongHandler) CreateKongService(w http.ResponseWriter, r *http.Request)
 	// Verify workflow ownership
 	workflow, err := h.store.GetWorkflowByID(req.WorkflowID)
 	if err != nil {
-		utils.WriteJSONError(w, "Workflow not found", http.StatusNotFound)
+		utils.WriteJSONErrorCode(w, utils.CodeWorkflowNotFound, "Workflow not found", http.StatusNotFound)
 		return
 	}
 
-	if workflow.UserID != userID {
+	if !inTenant(r, workflow) {
 		utils.WriteJSONError(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -101,7 +161,7 @@ func (h *KongHandler) CreateKongService(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Call Kong Admin API
-	serviceResp, err := h.callKongAdmin("POST", "/services", kongService)
+	serviceResp, err := h.createFor(workflow, models.KongService, "/services", kongService, kongService.Name)
 	if err != nil {
 		utils.WriteJSONError(w, fmt.Sprintf("Failed to create Kong service: %v", err), http.StatusInternalServerError)
 		return
@@ -112,6 +172,12 @@ func (h *KongHandler) CreateKongService(w http.ResponseWriter, r *http.Request)
 
 // CreateKongRoute creates a route for a Kong service
 func (h *KongHandler) CreateKongRoute(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		utils.WriteJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		ServiceID string   `json:"service_id"`
 		Name      string   `json:"name"`
@@ -131,8 +197,8 @@ func (h *KongHandler) CreateKongRoute(w http.ResponseWriter, r *http.Request) {
 	}
 	kongRoute.Service.ID = req.ServiceID
 
-	// Call Kong Admin API
-	routeResp, err := h.callKongAdmin("POST", "/routes", kongRoute)
+	// Call Kong Admin API; a route on a workflow's service is deleted with the workflow
+	routeResp, err := h.createForService(tenantID, req.ServiceID, models.KongRoute, "/routes", kongRoute, kongRoute.Name)
 	if err != nil {
 		utils.WriteJSONError(w, fmt.Sprintf("Failed to create Kong route: %v", err), http.StatusInternalServerError)
 		return
@@ -143,6 +209,12 @@ func (h *KongHandler) CreateKongRoute(w http.ResponseWriter, r *http.Request) {
 
 // AddKongPlugin adds a plugin to a Kong service
 func (h *KongHandler) AddKongPlugin(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		utils.WriteJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		ServiceID  string                 `json:"service_id"`
 		PluginName string                 `json:"plugin_name"` // rate-limiting, key-auth, oauth2, etc.
@@ -160,8 +232,8 @@ func (h *KongHandler) AddKongPlugin(w http.ResponseWriter, r *http.Request) {
 	}
 	kongPlugin.Service.ID = req.ServiceID
 
-	// Call Kong Admin API
-	pluginResp, err := h.callKongAdmin("POST", "/plugins", kongPlugin)
+	// Call Kong Admin API; a plugin on a workflow's service is deleted with the workflow
+	pluginResp, err := h.createForService(tenantID, req.ServiceID, models.KongPlugin, "/plugins", kongPlugin, kongPlugin.Name)
 	if err != nil {
 		utils.WriteJSONError(w, fmt.Sprintf("Failed to add Kong plugin: %v", err), http.StatusInternalServerError)
 		return
@@ -170,15 +242,38 @@ func (h *KongHandler) AddKongPlugin(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, pluginResp, http.StatusCreated)
 }
 
-// ListKongServices lists all Kong services
+// ListKongServices lists all Kong services, following Kong's pages to the last
 func (h *KongHandler) ListKongServices(w http.ResponseWriter, r *http.Request) {
-	services, err := h.callKongAdmin("GET", "/services", nil)
+	services, err := h.listAll("/services")
 	if err != nil {
 		utils.WriteJSONError(w, fmt.Sprintf("Failed to list Kong services: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	utils.WriteJSON(w, services, http.StatusOK)
+	utils.WriteJSON(w, map[string]interface{}{"data": services, "next": nil}, http.StatusOK)
+}
+
+// listAll reads every page of a Kong collection, passing each page's offset to the next
+func (h *KongHandler) listAll(path string) ([]interface{}, error) {
+	all := []interface{}{}
+	seen := map[string]bool{}
+	next := path
+	for page := 0; page < maxKongPages; page++ {
+		resp, err := h.callKongAdmin("GET", next, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := resp["data"].([]interface{})
+		all = append(all, data...)
+
+		offset, _ := resp["offset"].(string)
+		if offset == "" || seen[offset] {
+			return all, nil
+		}
+		seen[offset] = true
+		next = path + "?offset=" + url.QueryEscape(offset)
+	}
+	return nil, fmt.Errorf("Kong listed more than %d pages of %s", maxKongPages, path)
 }
 
 // DeleteKongService deletes a Kong service
@@ -196,57 +291,257 @@ func (h *KongHandler) DeleteKongService(w http.ResponseWriter, r *http.Request)
 }
 
 // callKongAdmin makes a request to Kong Admin API
+// 429, 502 and 503 responses are retried with backoff, honouring Retry-After
 func (h *KongHandler) callKongAdmin(method, path string, body interface{}) (map[string]interface{}, error) {
-	var reqBody io.Reader
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
+		var err error
+		if jsonData, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp *http.Response
+	var responseBody []byte
+	delay := h.admin.RetryDelay
+	for attempt := 0; ; attempt++ {
+		var err error
+		resp, responseBody, err = h.doKongAdmin(method, path, jsonData)
 		if err != nil {
 			return nil, err
 		}
-		reqBody = bytes.NewBuffer(jsonData)
+		if !retryableKongStatus(resp.StatusCode) || attempt >= h.admin.MaxRetries {
+			break
+		}
+		time.Sleep(kongRetryAfter(resp.Header.Get("Retry-After"), delay))
+		delay = min(delay*2, maxKongRetryDelay)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, &kongAPIError{StatusCode: resp.StatusCode, Body: string(responseBody)}
+	}
+
+	// For DELETE requests, return empty response
+	if method == "DELETE" {
+		return map[string]interface{}{"success": true}, nil
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// doKongAdmin sends one attempt of an Admin API request and reads its response
+func (h *KongHandler) doKongAdmin(method, path string, jsonData []byte) (*http.Response, []byte, error) {
+	var reqBody io.Reader
+	if jsonData != nil {
+		reqBody = bytes.NewReader(jsonData)
 	}
 
 	req, err := http.NewRequest(method, h.kongAdminURL+path, reqBody)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if body != nil {
+	if jsonData != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	if h.admin.Token != "" {
+		if strings.EqualFold(h.admin.TokenHeader, "Authorization") {
+			req.Header.Set("Authorization", "Bearer "+h.admin.Token)
+		} else {
+			req.Header.Set(h.admin.TokenHeader, h.admin.Token)
+		}
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, responseBody, nil
+}
+
+// retryableKongStatus reports whether Kong may accept the same request later
+func retryableKongStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusBadGateway || status == http.StatusServiceUnavailable
+}
+
+// kongRetryAfter is how long to wait before a retry: the response's Retry-After seconds
+// when it sent any, otherwise the backoff, and never more than maxKongRetryDelay
+func kongRetryAfter(header string, backoff time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		backoff = time.Duration(seconds) * time.Second
+	}
+	return min(backoff, maxKongRetryDelay)
+}
+
+// kongPaths is where each kind of Kong object lives in the Admin API
+var kongPaths = map[string]string{
+	models.KongService: "/services/",
+	models.KongRoute:   "/routes/",
+	models.KongPlugin:  "/plugins/",
+}
+
+// kongTeardownOrder deletes plugins and routes before the services they belong to;
+// Kong refuses to delete a service that routes still point at
+var kongTeardownOrder = map[string]int{models.KongPlugin: 0, models.KongRoute: 1, models.KongService: 2}
+
+// createFor creates a Kong object for a workflow and records it, so deleting the workflow
+// deletes the object too; an object that can't be recorded is deleted again
+func (h *KongHandler) createFor(workflow *models.Workflow, kind, path string, body interface{}, name string) (map[string]interface{}, error) {
+	created, err := h.callKongAdmin("POST", path, body)
 	if err != nil {
 		return nil, err
 	}
+	kongID, _ := created["id"].(string)
+	if kongID == "" {
+		return nil, fmt.Errorf("Kong returned a %s without an id", kind)
+	}
+	if err := h.store.CreateKongResource(&models.KongResource{
+		WorkflowID: workflow.ID,
+		TenantID:   workflow.TenantID,
+		Kind:       kind,
+		KongID:     kongID,
+		Name:       name,
+	}); err != nil {
+		h.deleteObject(kongPaths[kind] + kongID)
+		return nil, fmt.Errorf("failed to record Kong %s: %v", kind, err)
+	}
+	return created, nil
+}
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("Kong API error: %d - %s", resp.StatusCode, string(responseBody))
+// createForService creates a route or plugin on a service; when the service was created
+// for one of the tenant's workflows, the new object is recorded against that workflow too
+func (h *KongHandler) createForService(tenantID, serviceID, kind, path string, body interface{}, name string) (map[string]interface{}, error) {
+	resources, err := h.store.ListKongResources(tenantID, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range resources {
+		if resource.Kind == models.KongService && resource.KongID == serviceID {
+			return h.createFor(&models.Workflow{ID: resource.WorkflowID, TenantID: tenantID}, kind, path, body, name)
+		}
 	}
+	return h.callKongAdmin("POST", path, body)
+}
 
-	// For DELETE requests, return empty response
-	if method == "DELETE" {
-		return map[string]interface{}{"success": true}, nil
+// deleteObject deletes a Kong object; one Kong no longer has counts as deleted
+func (h *KongHandler) deleteObject(path string) error {
+	_, err := h.callKongAdmin("DELETE", path, nil)
+	var apiErr *kongAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
 	}
+	return err
+}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(responseBody, &result); err != nil {
+// KongTeardown reports which of a workflow's Kong objects were deleted
+type KongTeardown struct {
+	Deleted []models.KongResource `json:"deleted"`
+	Failed  []KongTeardownFailure `json:"failed,omitempty"`
+}
+
+// KongTeardownFailure is a Kong object that couldn't be deleted; its record is kept
+type KongTeardownFailure struct {
+	models.KongResource
+	Error string `json:"error"`
+}
+
+// TeardownWorkflow deletes the Kong objects recorded for a workflow: plugins, then routes,
+// then services. A failure doesn't stop the others; the failed object stays recorded
+func (h *KongHandler) TeardownWorkflow(tenantID, workflowID string) (*KongTeardown, error) {
+	resources, err := h.store.ListKongResources(tenantID, workflowID)
+	if err != nil {
 		return nil, err
 	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		return kongTeardownOrder[resources[i].Kind] < kongTeardownOrder[resources[j].Kind]
+	})
 
-	return result, nil
+	teardown := &KongTeardown{Deleted: []models.KongResource{}}
+	for _, resource := range resources {
+		err := h.deleteObject(kongPaths[resource.Kind] + resource.KongID)
+		if err == nil {
+			err = h.store.DeleteKongResource(tenantID, resource.ID)
+		}
+		if err != nil {
+			teardown.Failed = append(teardown.Failed, KongTeardownFailure{KongResource: resource, Error: err.Error()})
+			continue
+		}
+		teardown.Deleted = append(teardown.Deleted, resource)
+	}
+	return teardown, nil
+}
+
+// ListWorkflowKong lists the Kong objects created for a workflow
+func (h *KongHandler) ListWorkflowKong(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := h.tenantWorkflow(w, r)
+	if !ok {
+		return
+	}
+
+	resources, err := h.store.ListKongResources(workflow.TenantID, workflow.ID)
+	if err != nil {
+		utils.WriteJSONError(w, "Failed to list Kong resources", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSON(w, resources, http.StatusOK)
+}
+
+// DeleteWorkflowKong deletes the Kong objects created for a workflow, keeping the workflow
+// Answers 502 with the same report when any object couldn't be deleted
+func (h *KongHandler) DeleteWorkflowKong(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := h.tenantWorkflow(w, r)
+	if !ok {
+		return
+	}
+
+	teardown, err := h.TeardownWorkflow(workflow.TenantID, workflow.ID)
+	if err != nil {
+		utils.WriteJSONError(w, "Failed to list Kong resources", http.StatusInternalServerError)
+		return
+	}
+	if len(teardown.Failed) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Failed to delete %d Kong object(s)", len(teardown.Failed)),
+			"data":    teardown,
+		})
+		return
+	}
+
+	utils.WriteJSON(w, teardown, http.StatusOK)
+}
+
+// tenantWorkflow loads the {id} workflow, answering 404 or 403 itself when it can't be used
+func (h *KongHandler) tenantWorkflow(w http.ResponseWriter, r *http.Request) (*models.Workflow, bool) {
+	workflow, err := h.store.GetWorkflowByID(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeWorkflowNotFound, "Workflow not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !inTenant(r, workflow) {
+		utils.WriteJSONError(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return workflow, true
 }
 
 // CreateUseCaseTemplate creates a Kong setup for common use cases
 func (h *KongHandler) CreateUseCaseTemplate(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
 	if !ok {
 		utils.WriteJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -265,11 +560,11 @@ func (h *KongHandler) CreateUseCaseTemplate(w http.ResponseWriter, r *http.Reque
 	// Verify workflow ownership
 	workflow, err := h.store.GetWorkflowByID(req.WorkflowID)
 	if err != nil {
-		utils.WriteJSONError(w, "Workflow not found", http.StatusNotFound)
+		utils.WriteJSONErrorCode(w, utils.CodeWorkflowNotFound, "Workflow not found", http.StatusNotFound)
 		return
 	}
 
-	if workflow.UserID != userID {
+	if !inTenant(r, workflow) {
 		utils.WriteJSONError(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -295,7 +590,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			Name: fmt.Sprintf("bridge-%s", workflow.ID),
 			URL:  fmt.Sprintf("http://backend:8080/api/webhooks/%s", workflow.ID),
 		}
-		serviceResp, err := h.callKongAdmin("POST", "/services", service)
+		serviceResp, err := h.createFor(workflow, models.KongService, "/services", service, service.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -310,7 +605,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			Name: fmt.Sprintf("webhook-%s", workflow.ID),
 			URL:  fmt.Sprintf("http://backend:8080/api/webhooks/%s", workflow.ID),
 		}
-		serviceResp, err := h.callKongAdmin("POST", "/services", service)
+		serviceResp, err := h.createFor(workflow, models.KongService, "/services", service, service.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -326,7 +621,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			},
 		}
 		plugin.Service.ID = serviceID
-		pluginResp, err := h.callKongAdmin("POST", "/plugins", plugin)
+		pluginResp, err := h.createFor(workflow, models.KongPlugin, "/plugins", plugin, plugin.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -338,7 +633,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			Name: fmt.Sprintf("aggregator-%s", workflow.ID),
 			URL:  fmt.Sprintf("http://backend:8080/api/webhooks/%s", workflow.ID),
 		}
-		serviceResp, err := h.callKongAdmin("POST", "/services", service)
+		serviceResp, err := h.createFor(workflow, models.KongService, "/services", service, service.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -356,7 +651,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			},
 		}
 		plugin.Service.ID = serviceID
-		pluginResp, err := h.callKongAdmin("POST", "/plugins", plugin)
+		pluginResp, err := h.createFor(workflow, models.KongPlugin, "/plugins", plugin, plugin.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -368,7 +663,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			Name: fmt.Sprintf("auth-%s", workflow.ID),
 			URL:  fmt.Sprintf("http://backend:8080/api/webhooks/%s", workflow.ID),
 		}
-		serviceResp, err := h.callKongAdmin("POST", "/services", service)
+		serviceResp, err := h.createFor(workflow, models.KongService, "/services", service, service.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -383,7 +678,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			},
 		}
 		plugin.Service.ID = serviceID
-		pluginResp, err := h.callKongAdmin("POST", "/plugins", plugin)
+		pluginResp, err := h.createFor(workflow, models.KongPlugin, "/plugins", plugin, plugin.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -395,7 +690,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			Name: fmt.Sprintf("usage-%s", workflow.ID),
 			URL:  fmt.Sprintf("http://backend:8080/api/webhooks/%s", workflow.ID),
 		}
-		serviceResp, err := h.callKongAdmin("POST", "/services", service)
+		serviceResp, err := h.createFor(workflow, models.KongService, "/services", service, service.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -414,7 +709,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			},
 		}
 		rateLimitPlugin.Service.ID = serviceID
-		rateLimitResp, err := h.callKongAdmin("POST", "/plugins", rateLimitPlugin)
+		rateLimitResp, err := h.createFor(workflow, models.KongPlugin, "/plugins", rateLimitPlugin, rateLimitPlugin.Name)
 		if err != nil {
 			return nil, err
 		}
@@ -428,7 +723,7 @@ func (h *KongHandler) setupUseCase(useCase string, workflow *models.Workflow) (m
 			},
 		}
 		sizeLimitPlugin.Service.ID = serviceID
-		sizeLimitResp, err := h.callKongAdmin("POST", "/plugins", sizeLimitPlugin)
+		sizeLimitResp, err := h.createFor(workflow, models.KongPlugin, "/plugins", sizeLimitPlugin, sizeLimitPlugin.Name)
 		if err != nil {
 			return nil, err
 		}
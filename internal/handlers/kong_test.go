@@ -0,0 +1,217 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestWorkflowKongTeardown records the Kong objects a use-case template creates for a
+// workflow, then deletes them on request and with the workflow, against a fake Kong
+func TestWorkflowKongTeardown(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+
+	kong := &fakeKong{objects: map[string]map[string]interface{}{}}
+	kongServer := httptest.NewServer(kong)
+	defer kongServer.Close()
+
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:        database,
+		Executor:     engine.NewExecutor(database, testLogger),
+		Logger:       testLogger,
+		KongAdminURL: kongServer.URL,
+	}))
+	defer srv.Close()
+
+	owner, _ := database.CreateUser("gateway@example.com", "hashed")
+	other, _ := database.CreateUser("elsewhere@example.com", "hashed")
+	ownerToken, otherToken := userToken(t, owner.ID), userToken(t, other.ID)
+	workflow, _ := database.CreateWorkflow(owner.ID, "Gateway", "webhook", "testing", `{}`)
+	kongURL := srv.URL + "/api/workflows/" + workflow.ID + "/kong"
+
+	// A template's service and plugin, and a route added to its service, are all recorded
+	var template struct {
+		Data struct {
+			Service map[string]interface{} `json:"service"`
+		} `json:"data"`
+	}
+	if status := call(t, "POST", srv.URL+"/api/kong/templates", ownerToken,
+		map[string]string{"workflow_id": workflow.ID, "use_case": "webhook_handler"}, &template); status != http.StatusCreated {
+		t.Fatalf("Expected the template created, got %d", status)
+	}
+	serviceID, _ := template.Data.Service["id"].(string)
+	if status := call(t, "POST", srv.URL+"/api/kong/routes", ownerToken,
+		map[string]interface{}{"service_id": serviceID, "name": "gateway", "paths": []string{"/gateway"}}, nil); status != http.StatusCreated {
+		t.Fatalf("Expected the route created, got %d", status)
+	}
+
+	var listed struct {
+		Data []models.KongResource `json:"data"`
+	}
+	if status := call(t, "GET", kongURL, ownerToken, nil, &listed); status != http.StatusOK || len(listed.Data) != 3 {
+		t.Fatalf("Expected a service, plugin and route recorded, got %d: %+v", status, listed.Data)
+	}
+	if status := call(t, "GET", kongURL, otherToken, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected another tenant refused, got %d", status)
+	}
+
+	// A plugin Kong won't delete is reported and kept; the route and service still go
+	var plugin models.KongResource
+	for _, resource := range listed.Data {
+		if resource.Kind == models.KongPlugin {
+			plugin = resource
+		}
+	}
+	kong.failDelete = "/plugins/" + plugin.KongID
+	var failed struct {
+		Data handlers.KongTeardown `json:"data"`
+	}
+	if status := call(t, "DELETE", kongURL, ownerToken, nil, &failed); status != http.StatusBadGateway {
+		t.Fatalf("Expected 502 with a failed delete, got %d", status)
+	}
+	if len(failed.Data.Deleted) != 2 || len(failed.Data.Failed) != 1 || failed.Data.Failed[0].KongID != plugin.KongID {
+		t.Errorf("Expected the route and service deleted and the plugin failed, got %+v", failed.Data)
+	}
+	if kong.count("/routes/") != 0 || kong.count("/services/") != 0 || kong.count("/plugins/") != 1 {
+		t.Errorf("Unexpected objects left in Kong: %v", kong.objects)
+	}
+	if call(t, "GET", kongURL, ownerToken, nil, &listed); len(listed.Data) != 1 || listed.Data[0].ID != plugin.ID {
+		t.Errorf("Expected only the plugin still recorded, got %+v", listed.Data)
+	}
+
+	// Deleting the workflow deletes what is left; objects Kong already lost are fine
+	kong.failDelete = ""
+	call(t, "POST", srv.URL+"/api/kong/templates", ownerToken, map[string]string{"workflow_id": workflow.ID, "use_case": "protocol_bridge"}, nil)
+	for path := range kong.objects {
+		if path != "/plugins/"+plugin.KongID {
+			delete(kong.objects, path)
+		}
+	}
+	if status := call(t, "DELETE", srv.URL+"/api/workflows/"+workflow.ID, ownerToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Expected the workflow deleted, got %d", status)
+	}
+	if len(kong.objects) != 0 {
+		t.Errorf("Expected the workflow's Kong objects deleted, got %v", kong.objects)
+	}
+	if resources, _ := database.ListKongResources(owner.TenantID, workflow.ID); len(resources) != 0 {
+		t.Errorf("Expected no records left, got %+v", resources)
+	}
+
+	// A Kong failure doesn't stop the workflow being deleted, but is reported
+	doomed, _ := database.CreateWorkflow(owner.ID, "Doomed", "webhook", "testing", `{}`)
+	call(t, "POST", srv.URL+"/api/kong/templates", ownerToken, map[string]string{"workflow_id": doomed.ID, "use_case": "protocol_bridge"}, nil)
+	for path := range kong.objects {
+		kong.failDelete = path
+	}
+	var deleted struct {
+		Deleted bool                  `json:"deleted"`
+		Kong    handlers.KongTeardown `json:"kong"`
+	}
+	if status := call(t, "DELETE", srv.URL+"/api/workflows/"+doomed.ID, ownerToken, nil, &deleted); status != http.StatusOK || !deleted.Deleted || len(deleted.Kong.Failed) != 1 {
+		t.Errorf("Expected the workflow deleted with the Kong failure reported, got %d: %+v", status, deleted)
+	}
+	if _, err := database.GetWorkflowByID(doomed.ID); err == nil {
+		t.Error("Expected the workflow deleted despite the Kong failure")
+	}
+}
+
+// TestKongAdminClient lists services from an Admin API that requires a token, answers
+// with two pages, and is briefly unavailable
+func TestKongAdminClient(t *testing.T) {
+	for _, c := range []struct {
+		header, sent, want string
+	}{
+		{"", "Kong-Admin-Token", "rbac-secret"},
+		{"Authorization", "Authorization", "Bearer rbac-secret"},
+	} {
+		var mu sync.Mutex
+		requests := 0
+		kongServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if r.Header.Get(c.sent) != c.want {
+				http.Error(w, `{"message": "Invalid credentials. Token or User credentials required"}`, http.StatusUnauthorized)
+				return
+			}
+			requests++
+			if requests == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, `{"message": "name resolution failed"}`, http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Query().Get("offset") {
+			case "":
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"data":   []map[string]string{{"id": "svc-1"}, {"id": "svc-2"}},
+					"offset": "page/2",
+					"next":   "/services?offset=page%2F2",
+				})
+			case "page/2":
+				json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]string{{"id": "svc-3"}}, "next": nil})
+			default:
+				http.Error(w, `{"message": "invalid offset"}`, http.StatusBadRequest)
+			}
+		}))
+		defer kongServer.Close()
+
+		database := dbtest.New(t)
+		testLogger := logger.NewLogger("test")
+		srv := httptest.NewServer(server.NewRouter(server.Config{
+			Store:        database,
+			Executor:     engine.NewExecutor(database, testLogger),
+			Logger:       testLogger,
+			KongAdminURL: kongServer.URL,
+			KongAdmin:    handlers.KongAdminConfig{Token: "rbac-secret", TokenHeader: c.header, RetryDelay: time.Millisecond},
+		}))
+		defer srv.Close()
+		admin, _ := database.CreateUser("kong-"+c.sent+"@example.com", "hashed")
+
+		var listed struct {
+			Data struct {
+				Data []map[string]string `json:"data"`
+			} `json:"data"`
+		}
+		if status := call(t, "GET", srv.URL+"/api/kong/services", userToken(t, admin.ID), nil, &listed); status != http.StatusOK {
+			t.Fatalf("%s: expected the services listed after a retry, got %d", c.sent, status)
+		}
+		if services := listed.Data.Data; len(services) != 3 || services[2]["id"] != "svc-3" {
+			t.Errorf("%s: expected both pages of services, got %v", c.sent, services)
+		}
+		if requests != 3 {
+			t.Errorf("%s: expected one retry and two pages, got %d requests", c.sent, requests)
+		}
+	}
+
+	// Without the token Kong's 401 is not retried and fails the request
+	unauthorized := 0
+	kongServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unauthorized++
+		http.Error(w, `{"message": "Unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer kongServer.Close()
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:        database,
+		Executor:     engine.NewExecutor(database, testLogger),
+		Logger:       testLogger,
+		KongAdminURL: kongServer.URL,
+	}))
+	defer srv.Close()
+	admin, _ := database.CreateUser("kong-anonymous@example.com", "hashed")
+	if status := call(t, "GET", srv.URL+"/api/kong/services", userToken(t, admin.ID), nil, nil); status != http.StatusInternalServerError || unauthorized != 1 {
+		t.Errorf("Expected one refused attempt, got %d after %d requests", status, unauthorized)
+	}
+}
@@ -2,68 +2,418 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/audit"
 	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
 	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/gorilla/mux"
 )
 
+// logStreamHeartbeat is how often an idle log stream sends a comment, so proxies keep it open
+const logStreamHeartbeat = 20 * time.Second
+
+// clearLogsBatchSize is how many entries each DELETE removes when clearing a workflow's logs
+const clearLogsBatchSize = 500
+
+// maxLogPageLimit caps ?limit= on log listings; larger values are rejected, not clamped
+const maxLogPageLimit = 1000
+
+// LogsPage is one page of logs with the filter's total, so the UI can show page counts
+type LogsPage struct {
+	Items      []models.WorkflowLog `json:"items"`
+	NextCursor string               `json:"next_cursor,omitempty"` // Empty on the last page
+	Total      int                  `json:"total"`                 // Logs matching the filter across all pages
+	HasMore    bool                 `json:"has_more"`
+}
+
 // LogsHandler handles log retrieval HTTP requests
 // PRODUCTION: Uses Store interface for testability
 type LogsHandler struct {
-	store db.Store // Interface, not concrete type!
+	store    db.Store // Interface, not concrete type!
+	messages *Messages
+	stream   *engine.LogBroadcaster // Enables GET /api/logs/stream when set
+	audit    *audit.Recorder
 }
 
 // NewLogsHandler creates a new logs handler
-func NewLogsHandler(store db.Store) *LogsHandler {
-	return &LogsHandler{store: store}
+func NewLogsHandler(store db.Store, messages *Messages) *LogsHandler {
+	return &LogsHandler{store: store, messages: messages, audit: audit.NewRecorder(store)}
 }
 
-// GetLogs retrieves logs for the user's workflows
+// SetLogStream sets where new log entries are published for GET /api/logs/stream
+func (h *LogsHandler) SetLogStream(stream *engine.LogBroadcaster) {
+	h.stream = stream
+}
+
+// GetLogs retrieves logs for the workflows of the caller's tenant
+// Filters: ?workflow_id=, ?status=, ?since= (RFC3339)
+// ?limit=N&cursor=... returns a LogsPage; ?offset=N is deprecated
 func (h *LogsHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
-	// TODO: MULTI-TENANT - Filter by tenant_id
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > maxLogPageLimit {
+		http.Error(w, fmt.Sprintf("limit must be at most %d", maxLogPageLimit), http.StatusBadRequest)
+		return
+	}
+	params, err := parsePageParams(r, maxLogPageLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Check if filtering by specific workflow
 	workflowID := r.URL.Query().Get("workflow_id")
 
+	filter := models.LogFilter{TenantID: tenantID, WorkflowID: workflowID}
+
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "since must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		filter.From = &since
+	}
+
+	// Status filter: 'success', 'failed', or 'unacknowledged' (failures nobody has triaged yet)
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+	case "unacknowledged":
+		filter.Status = "failed"
+		filter.Unacknowledged = true
+	case "success", "failed":
+		filter.Status = status
+	default:
+		http.Error(w, "Invalid status. Must be 'success', 'failed', or 'unacknowledged'", http.StatusBadRequest)
+		return
+	}
+
 	if workflowID != "" {
 		// Verify ownership of workflow
 		workflow, err := h.store.GetWorkflowByID(workflowID)
 		if err != nil {
-			http.Error(w, "Workflow not found", http.StatusNotFound)
+			writeWorkflowError(w, err)
 			return
 		}
 
-		if workflow.UserID != userID {
+		if !inTenant(r, workflow) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
+	}
+
+	if params.Paged || params.Legacy {
+		h.getLogsPage(w, r, userID, filter, params)
+		return
+	}
+
+	if status != "" || filter.From != nil {
+		logs, err := h.store.QueryLogs(userID, filter)
+		if err != nil {
+			http.Error(w, "Failed to fetch logs", http.StatusInternalServerError)
+			return
+		}
+		h.localizeWorkflowLogs(r, logs)
 
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(logs)
+		return
+	}
+
+	if workflowID != "" {
 		// Get logs for this workflow
 		logs, err := h.store.GetLogsByWorkflowID(workflowID)
 		if err != nil {
 			http.Error(w, "Failed to fetch logs", http.StatusInternalServerError)
 			return
 		}
+		lang, showDetail := h.messages.forRequest(r)
+		for i := range logs {
+			h.messages.localizeLog(&logs[i], lang, showDetail)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(logs)
 		return
 	}
 
-	// Get all logs for user's workflows
-	logs, err := h.store.GetLogsByUserID(userID)
+	// Get all logs for the tenant's workflows
+	logs, err := h.store.GetLogsByTenantID(tenantID)
 	if err != nil {
 		http.Error(w, "Failed to fetch logs", http.StatusInternalServerError)
 		return
 	}
+	h.localizeWorkflowLogs(r, logs)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(logs)
 }
 
+// StreamLogs pushes the caller's new log entries as Server-Sent Events until the client
+// disconnects: a "log" event per entry with the entry as JSON, and a comment every 20s
+// ?workflow_id= narrows the stream to one workflow of the tenant
+func (h *LogsHandler) StreamLogs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.stream == nil {
+		http.Error(w, "Log streaming is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Entries are published to the workflow's owner, who may be a teammate
+	ownerID := userID
+	workflowID := r.URL.Query().Get("workflow_id")
+	if workflowID != "" {
+		workflow, err := h.store.GetWorkflowByID(workflowID)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		if !inTenant(r, workflow) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		ownerID = workflow.UserID
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx buffering the stream
+
+	// The server's WriteTimeout would cut the stream; not every writer supports lifting it
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	if err := rc.Flush(); err != nil {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	entries, unsubscribe := h.stream.Subscribe(ownerID, workflowID)
+	defer unsubscribe()
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	lang, showDetail := h.messages.forRequest(r)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry := <-entries:
+			h.messages.localizeLog(&entry.Log, lang, showDetail)
+			data, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: log\ndata: %s\n\n", entry.ID, data); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// getLogsPage serves one page of logs for cursor (or deprecated offset) requests
+func (h *LogsHandler) getLogsPage(w http.ResponseWriter, r *http.Request, userID string, filter models.LogFilter, params pageParams) {
+	// Fetch one extra row to learn whether another page exists
+	filter.Limit = params.Limit + 1
+	filter.Cursor = params.Cursor
+	filter.Offset = params.Offset
+
+	logs, total, err := h.store.GetLogsFiltered(userID, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch logs", http.StatusInternalServerError)
+		return
+	}
+
+	hasMore := len(logs) > params.Limit
+	if hasMore {
+		logs = logs[:params.Limit]
+	}
+	if logs == nil {
+		logs = []models.WorkflowLog{}
+	}
+	h.localizeWorkflowLogs(r, logs)
+
+	w.Header().Set("Content-Type", "application/json")
+	if params.Legacy {
+		setOffsetDeprecation(w)
+		json.NewEncoder(w).Encode(logs)
+		return
+	}
+
+	response := LogsPage{Items: logs, Total: total, HasMore: hasMore}
+	if hasMore {
+		last := logs[len(logs)-1]
+		response.NextCursor = EncodeCursor(last.ExecutedAt, last.ID)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// localizeWorkflowLogs translates coded failure messages for the caller
+func (h *LogsHandler) localizeWorkflowLogs(r *http.Request, logs []models.WorkflowLog) {
+	lang, showDetail := h.messages.forRequest(r)
+	for i := range logs {
+		h.messages.localizeLog(&logs[i].Log, lang, showDetail)
+	}
+}
+
+// AcknowledgeLog marks a single log entry as triaged by the current user
+func (h *LogsHandler) AcknowledgeLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	logID := mux.Vars(r)["id"]
+
+	log, err := h.store.GetLogByID(logID)
+	if err != nil {
+		writeStoreError(w, err, "Log not found")
+		return
+	}
+
+	// Verify ownership through the log's workflow
+	workflow, err := h.store.GetWorkflowByID(log.WorkflowID)
+	if err != nil || !inTenant(r, workflow) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.store.AcknowledgeLog(logID, userID, time.Now()); err != nil {
+		http.Error(w, "Failed to acknowledge log", http.StatusInternalServerError)
+		return
+	}
+
+	log, err = h.store.GetLogByID(logID)
+	if err != nil {
+		http.Error(w, "Failed to fetch log", http.StatusInternalServerError)
+		return
+	}
+	lang, showDetail := h.messages.forRequest(r)
+	h.messages.localizeLog(log, lang, showDetail)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(log)
+}
+
+// AcknowledgeLogs bulk-acknowledges the tenant's log entries matching a filter
+// Body: {"workflow_id": "...", "status": "failed", "from": "RFC3339", "to": "RFC3339"} (all optional)
+func (h *LogsHandler) AcknowledgeLogs(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var filter models.LogFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	filter.TenantID = tenantID
+
+	if filter.Status != "" && filter.Status != "success" && filter.Status != "failed" {
+		http.Error(w, "Invalid status. Must be 'success' or 'failed'", http.StatusBadRequest)
+		return
+	}
+
+	if filter.WorkflowID != "" {
+		workflow, err := h.store.GetWorkflowByID(filter.WorkflowID)
+		if err != nil {
+			writeWorkflowError(w, err)
+			return
+		}
+		if !inTenant(r, workflow) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	count, err := h.store.AcknowledgeLogs(userID, filter, userID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to acknowledge logs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"acknowledged": count,
+	})
+}
+
+// ClearLogs deletes a workflow's whole log history (DELETE /api/logs?workflow_id=)
+// Members may only clear their own workflows; the tenant admin may clear any of the tenant's
+func (h *LogsHandler) ClearLogs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workflowID := r.URL.Query().Get("workflow_id")
+	if workflowID == "" {
+		http.Error(w, "workflow_id is required", http.StatusBadRequest)
+		return
+	}
+	workflow, err := h.store.GetWorkflowByID(workflowID)
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+	if !inTenant(r, workflow) {
+		h.audit.Denied(r, models.AuditLogsClear, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if workflow.UserID != userID && !tenantAdmin(r) {
+		h.audit.Denied(r, models.AuditLogsClear, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+		http.Error(w, "Only the workflow's creator or a tenant admin can clear its logs", http.StatusForbidden)
+		return
+	}
+
+	var deleted int64
+	for {
+		count, err := h.store.DeleteWorkflowLogs(workflowID, clearLogsBatchSize)
+		deleted += count
+		if err != nil {
+			http.Error(w, "Failed to clear logs", http.StatusInternalServerError)
+			return
+		}
+		if count < clearLogsBatchSize {
+			break
+		}
+	}
+
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditLogsClear,
+		ResourceType: models.AuditResourceWorkflow,
+		ResourceID:   workflow.ID,
+	}, map[string]interface{}{"deleted": deleted})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": deleted,
+	})
+}
@@ -0,0 +1,262 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestLogsFilteredPages pages through filtered logs and checks the total and has_more
+// flag, and that bad timestamps and oversized limits are rejected
+func TestLogsFilteredPages(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	user, _ := database.CreateUser("logs@example.com", "hashed")
+	token := userToken(t, user.ID)
+	orders, _ := database.CreateWorkflow(user.ID, "Orders", "webhook", "testing", `{}`)
+	refunds, _ := database.CreateWorkflow(user.ID, "Refunds", "webhook", "testing", `{}`)
+
+	// Orders: 6 failures then 6 successes an hour later; Refunds: 3 failures
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		status, at := "failed", start.Add(time.Duration(i)*time.Minute)
+		if i >= 6 {
+			status, at = "success", at.Add(time.Hour)
+		}
+		database.CreateLogEntry(&models.Log{WorkflowID: orders.ID, Status: status, Message: fmt.Sprint(i), ExecutedAt: at})
+	}
+	for i := 0; i < 3; i++ {
+		database.CreateLogEntry(&models.Log{WorkflowID: refunds.ID, Status: "failed", ExecutedAt: start})
+	}
+
+	var page handlers.LogsPage
+	if status := call(t, "GET", srv.URL+"/api/logs?workflow_id="+orders.ID+"&status=failed&limit=4", token, nil, &page); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(page.Items) != 4 || page.Total != 6 || !page.HasMore || page.NextCursor == "" {
+		t.Fatalf("Expected 4 of 6 failures with more to come, got %d items, total %d, has_more %v", len(page.Items), page.Total, page.HasMore)
+	}
+	for _, log := range page.Items {
+		if log.WorkflowID != orders.ID || log.Status != "failed" {
+			t.Errorf("Expected only Orders failures, got %+v", log)
+		}
+	}
+
+	cursor := page.NextCursor
+	page = handlers.LogsPage{}
+	call(t, "GET", srv.URL+"/api/logs?workflow_id="+orders.ID+"&status=failed&limit=4&cursor="+cursor, token, nil, &page)
+	if len(page.Items) != 2 || page.Total != 6 || page.HasMore || page.NextCursor != "" {
+		t.Errorf("Expected the last 2 failures on the second page, got %d items, total %d, has_more %v", len(page.Items), page.Total, page.HasMore)
+	}
+
+	// since is inclusive and applies across workflows
+	since := start.Add(time.Hour).Format(time.RFC3339)
+	page = handlers.LogsPage{}
+	call(t, "GET", srv.URL+"/api/logs?since="+since+"&limit=100", token, nil, &page)
+	if page.Total != 6 || len(page.Items) != 6 || page.HasMore {
+		t.Errorf("Expected the 6 later successes, got %d items, total %d", len(page.Items), page.Total)
+	}
+	var unpaged []models.WorkflowLog
+	call(t, "GET", srv.URL+"/api/logs?since="+since, token, nil, &unpaged)
+	if len(unpaged) != 6 {
+		t.Errorf("Expected since to filter unpaginated requests too, got %d logs", len(unpaged))
+	}
+
+	for name, query := range map[string]string{
+		"bad since":     "since=yesterday",
+		"limit too big": "limit=1001",
+		"zero limit":    "limit=0",
+	} {
+		if status := call(t, "GET", srv.URL+"/api/logs?"+query, token, nil, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+	page = handlers.LogsPage{}
+	if status := call(t, "GET", srv.URL+"/api/logs?limit=1000", token, nil, &page); status != http.StatusOK || page.Total != 15 || len(page.Items) != 15 {
+		t.Errorf("Expected the largest limit to return every log, got %d with %d of %d", status, len(page.Items), page.Total)
+	}
+}
+
+// flushRecorder is a ResponseRecorder the stream can flush while the test reads it
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	flushed chan struct{}
+}
+
+func (fr *flushRecorder) Write(b []byte) (int, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.ResponseRecorder.Write(b)
+}
+
+func (fr *flushRecorder) Flush() {
+	fr.mu.Lock()
+	fr.ResponseRecorder.Flush()
+	fr.mu.Unlock()
+	select {
+	case fr.flushed <- struct{}{}:
+	default:
+	}
+}
+
+func (fr *flushRecorder) body() string {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.ResponseRecorder.Body.String()
+}
+
+// TestStreamLogs streams one workflow's new log entries as SSE and unsubscribes when the
+// client goes away; a subscriber that stops reading never blocks publishing
+func TestStreamLogs(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+	router := server.NewRouter(server.Config{Store: database, Executor: executor, Logger: testLogger})
+
+	user, _ := database.CreateUser("stream@example.com", "hashed")
+	watched, _ := database.CreateWorkflow(user.ID, "Watched", "webhook", "testing", `{}`)
+	other, _ := database.CreateWorkflow(user.ID, "Other", "webhook", "testing", `{}`)
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/api/logs/stream?workflow_id="+watched.ID, nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+userToken(t, user.ID))
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{}, 1)}
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	waitFor := func(what string, ok func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !ok() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s; stream so far: %q", what, rec.body())
+			}
+			select {
+			case <-rec.flushed:
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	waitFor("the subscription", func() bool { return executor.Logs().Subscribers() == 1 })
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", rec.Header().Get("Content-Type"))
+	}
+
+	executor.ExecuteWorkflowWithContext(context.Background(), *other, `{}`)
+	executor.ExecuteWorkflowWithContext(context.Background(), *watched, `{}`)
+	waitFor("the watched workflow's entry", func() bool { return strings.Contains(rec.body(), "event: log") })
+
+	var data string
+	for _, line := range strings.Split(rec.body(), "\n") {
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	var entry models.WorkflowLog
+	if err := json.Unmarshal([]byte(data), &entry); err != nil || entry.WorkflowID != watched.ID || entry.WorkflowName != "Watched" || entry.Status != "success" {
+		t.Errorf("Expected the watched workflow's entry as JSON, got %q (%v)", data, err)
+	}
+	if strings.Count(rec.body(), "event: log") != 1 {
+		t.Errorf("Expected only the watched workflow's entry, got %q", rec.body())
+	}
+
+	disconnect()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the stream to end when the client disconnected")
+	}
+	if n := executor.Logs().Subscribers(); n != 0 {
+		t.Errorf("Expected the subscription cleaned up, got %d", n)
+	}
+
+	// Nobody reads this subscription: entries past its buffer are dropped, not waited for
+	entries, unsubscribe := executor.Logs().Subscribe(user.ID, "")
+	defer unsubscribe()
+	for i := 0; i < engine.LogSubscriberBuffer+10; i++ {
+		executor.Logs().Publish(user.ID, models.WorkflowLog{Log: models.Log{WorkflowID: watched.ID}})
+	}
+	if len(entries) != engine.LogSubscriberBuffer {
+		t.Errorf("Expected a full buffer of %d entries, got %d", engine.LogSubscriberBuffer, len(entries))
+	}
+
+	// Another tenant's workflow can't be watched
+	stranger, _ := database.CreateUser("stranger@example.com", "hashed")
+	forbidden := httptest.NewRequest("GET", "/api/logs/stream?workflow_id="+watched.ID, nil)
+	forbidden.Header.Set("Authorization", "Bearer "+userToken(t, stranger.ID))
+	denied := httptest.NewRecorder()
+	router.ServeHTTP(denied, forbidden)
+	if denied.Code != http.StatusForbidden {
+		t.Errorf("Expected another tenant's workflow to be forbidden, got %d", denied.Code)
+	}
+}
+
+// TestClearWorkflowLogs deletes one workflow's whole history across several batches and
+// leaves other workflows' logs alone; only the creator or a tenant admin may clear it
+func TestClearWorkflowLogs(t *testing.T) {
+	database := dbtest.New(t)
+	srv := httptest.NewServer(server.NewRouter(server.Config{Store: database, Logger: logger.NewLogger("test")}))
+	defer srv.Close()
+
+	owner, _ := database.CreateUser("noisy@example.com", "hashed")
+	member, _ := database.CreateUserInTenant(owner.TenantID, "member@example.com", "hashed")
+	stranger, _ := database.CreateUser("stranger@example.com", "hashed")
+	noisy, _ := database.CreateWorkflow(owner.ID, "Every minute", "schedule", "testing", `{}`)
+	quiet, _ := database.CreateWorkflow(owner.ID, "Nightly", "schedule", "testing", `{}`)
+
+	start := time.Now().AddDate(0, -3, 0)
+	for i := 0; i < 1203; i++ {
+		database.CreateLogEntry(&models.Log{WorkflowID: noisy.ID, Status: "success", ExecutedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+	for i := 0; i < 3; i++ {
+		database.CreateLogEntry(&models.Log{WorkflowID: quiet.ID, Status: "success", ExecutedAt: start})
+	}
+
+	url := srv.URL + "/api/logs?workflow_id=" + noisy.ID
+	if status := call(t, "DELETE", srv.URL+"/api/logs", userToken(t, owner.ID), nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without workflow_id, got %d", status)
+	}
+	if status := call(t, "DELETE", url, userToken(t, stranger.ID), nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for another tenant's workflow, got %d", status)
+	}
+	if status := call(t, "DELETE", url, memberToken(t, member), nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a member who didn't create the workflow, got %d", status)
+	}
+
+	var result struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if status := call(t, "DELETE", url, userToken(t, owner.ID), nil, &result); status != http.StatusOK || result.Deleted != 1203 {
+		t.Fatalf("Expected all 1203 entries deleted, got %d with %+v", status, result)
+	}
+	if logs, _ := database.GetLogsByWorkflowID(noisy.ID); len(logs) != 0 {
+		t.Errorf("Expected no logs left, got %d", len(logs))
+	}
+	if logs, _ := database.GetLogsByWorkflowID(quiet.ID); len(logs) != 3 {
+		t.Errorf("Expected the other workflow's 3 logs kept, got %d", len(logs))
+	}
+}
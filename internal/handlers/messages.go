@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// defaultLanguage is used when the caller accepts none of the catalogs, and for
+// codes a catalog hasn't translated yet
+const defaultLanguage = "en"
+
+// unknownErrorCode names the generic message shown for codes no catalog knows
+const unknownErrorCode = "unknown"
+
+// placeholderPattern matches {name} placeholders in message templates
+var placeholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// DefaultCatalogs holds the built-in message templates, by language then error code
+var DefaultCatalogs = map[string]map[string]string{
+	"en": {
+		unknownErrorCode:                        "Something went wrong while running this workflow.",
+		connectors.ErrCodeActionFailed:          "The {action} step failed. Check its configuration and try again.",
+		connectors.ErrCodeCancelled:             "The run was cancelled before it finished.",
+		connectors.ErrCodeCredentialMissing:     "{service} is not connected. Add your {service} credentials and try again.",
+		connectors.ErrCodeCredentialEnvironment: "This workflow runs in {workflow_environment}, but the only {service} credentials are for {credential_environment}. Add {workflow_environment} credentials for {service}.",
+		connectors.ErrCodeUpstreamHTTP:          "{service} rejected the request (HTTP {status}).",
+		connectors.ErrCodeAuthFailed:            "{service} rejected your credentials. Reconnect {service} and try again.",
+		connectors.ErrCodeUpstreamUnreachable:   "{service} could not be reached. Try again in a few minutes.",
+		connectors.ErrCodeUpstreamTimeout:       "{service} did not answer in time. Check whether the request went through before retrying.",
+		connectors.ErrCodeRateLimited:           "{service} is receiving too many requests. Try again in {retry_after} seconds.",
+		connectors.ErrCodeInvalidResponse:       "{service} sent a response that could not be read.",
+		connectors.ErrCodeAssertionFailed:       "{failed} of {total} output checks failed.",
+		connectors.ErrCodeCircuitOpen:           "The {action} step was skipped because it kept failing. It will be tried again in {retry_after} seconds.",
+		connectors.ErrCodeDeliveryFailed:        "{service} could not deliver the message ({status}). Check the recipient's number.",
+	},
+	"de": {
+		unknownErrorCode:                        "Beim Ausführen dieses Workflows ist ein Fehler aufgetreten.",
+		connectors.ErrCodeActionFailed:          "Der Schritt {action} ist fehlgeschlagen. Prüfen Sie die Konfiguration und versuchen Sie es erneut.",
+		connectors.ErrCodeCancelled:             "Die Ausführung wurde abgebrochen, bevor sie abgeschlossen war.",
+		connectors.ErrCodeCredentialMissing:     "{service} ist nicht verbunden. Hinterlegen Sie Ihre {service}-Zugangsdaten und versuchen Sie es erneut.",
+		connectors.ErrCodeCredentialEnvironment: "Dieser Workflow läuft in {workflow_environment}, die einzigen {service}-Zugangsdaten gelten aber für {credential_environment}. Hinterlegen Sie {service}-Zugangsdaten für {workflow_environment}.",
+		connectors.ErrCodeUpstreamHTTP:          "{service} hat die Anfrage abgelehnt (HTTP {status}).",
+		connectors.ErrCodeAuthFailed:            "{service} hat Ihre Zugangsdaten abgelehnt. Verbinden Sie {service} neu und versuchen Sie es erneut.",
+		connectors.ErrCodeUpstreamUnreachable:   "{service} ist nicht erreichbar. Versuchen Sie es in ein paar Minuten erneut.",
+		connectors.ErrCodeUpstreamTimeout:       "{service} hat nicht rechtzeitig geantwortet. Prüfen Sie vor einem neuen Versuch, ob die Anfrage angekommen ist.",
+		connectors.ErrCodeRateLimited:           "{service} erhält zu viele Anfragen. Versuchen Sie es in {retry_after} Sekunden erneut.",
+		connectors.ErrCodeInvalidResponse:       "Die Antwort von {service} konnte nicht gelesen werden.",
+		connectors.ErrCodeAssertionFailed:       "{failed} von {total} Ausgabeprüfungen sind fehlgeschlagen.",
+		connectors.ErrCodeCircuitOpen:           "Der Schritt {action} wurde übersprungen, weil er wiederholt fehlgeschlagen ist. In {retry_after} Sekunden wird er erneut versucht.",
+		connectors.ErrCodeDeliveryFailed:        "{service} konnte die Nachricht nicht zustellen ({status}). Prüfen Sie die Nummer des Empfängers.",
+	},
+}
+
+// Messages turns connector error codes into text in the caller's language
+// The untranslated connector message is only ever shown to administrators
+type Messages struct {
+	catalogs map[string]map[string]string
+	isAdmin  func(userID string) bool
+}
+
+// NewMessages creates a translator over the given catalogs; isAdmin may be nil
+func NewMessages(catalogs map[string]map[string]string, isAdmin func(userID string) bool) *Messages {
+	if isAdmin == nil {
+		isAdmin = func(string) bool { return false }
+	}
+	return &Messages{catalogs: catalogs, isAdmin: isAdmin}
+}
+
+// Language picks the best supported language from an Accept-Language header
+func (m *Messages) Language(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		lang, _, _ := strings.Cut(tag, "-")
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if _, ok := m.catalogs[lang]; ok && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return defaultLanguage
+	}
+
+	// Stable, so equally weighted languages keep the caller's order
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// Translate renders the message for an error code
+// Codes missing from the language fall back to English; unknown codes, and
+// templates whose placeholders the params don't fill, get the generic message
+func (m *Messages) Translate(lang, code string, params map[string]string) string {
+	template, ok := m.template(lang, code)
+	if !ok {
+		template, _ = m.template(lang, unknownErrorCode)
+		return template
+	}
+
+	complete := true
+	message := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := params[strings.Trim(placeholder, "{}")]
+		if !ok || value == "" {
+			complete = false
+		}
+		return value
+	})
+	if !complete {
+		template, _ = m.template(lang, unknownErrorCode)
+		return template
+	}
+	return message
+}
+
+// template looks a code up in the language's catalog, then in the default one
+func (m *Messages) template(lang, code string) (string, bool) {
+	if template, ok := m.catalogs[lang][code]; ok {
+		return template, true
+	}
+	template, ok := m.catalogs[defaultLanguage][code]
+	return template, ok
+}
+
+// forRequest returns the caller's language and whether they may see untranslated detail
+// An impersonating administrator keeps the detail while seeing the user's view
+func (m *Messages) forRequest(r *http.Request) (string, bool) {
+	lang := m.Language(r.Header.Get("Accept-Language"))
+	if _, _, impersonated := middleware.GetImpersonationFromContext(r.Context()); impersonated {
+		return lang, true
+	}
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	return lang, m.isAdmin(userID)
+}
+
+// localizeLog replaces a coded log message with its translation
+func (m *Messages) localizeLog(log *models.Log, lang string, showDetail bool) {
+	if log.ErrorCode == "" {
+		return
+	}
+	if showDetail {
+		log.Detail = log.Message
+	}
+	log.Message = m.Translate(lang, log.ErrorCode, log.ErrorParams)
+}
+
+// localizeResult replaces a coded result message with its translation
+func (m *Messages) localizeResult(result *connectors.Result, lang string, showDetail bool) {
+	if result.ErrorCode == "" {
+		return
+	}
+	if showDetail {
+		result.Detail = result.Message
+	}
+	result.Message = m.Translate(lang, result.ErrorCode, result.ErrorParams)
+}
@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestMessagesTranslate covers language fallback, unknown codes and missing params
+func TestMessagesTranslate(t *testing.T) {
+	messages := handlers.NewMessages(map[string]map[string]string{
+		"en": {
+			"unknown":                           "Something went wrong.",
+			connectors.ErrCodeUpstreamHTTP:      "{service} rejected the request (HTTP {status}).",
+			connectors.ErrCodeCredentialMissing: "{service} is not connected.",
+		},
+		"de": {
+			"unknown":                      "Ein Fehler ist aufgetreten.",
+			connectors.ErrCodeUpstreamHTTP: "{service} hat die Anfrage abgelehnt (HTTP {status}).",
+		},
+	}, nil)
+	params := map[string]string{"service": "Slack", "status": "404"}
+
+	tests := []struct {
+		name   string
+		lang   string
+		code   string
+		params map[string]string
+		want   string
+	}{
+		{"english", "en", connectors.ErrCodeUpstreamHTTP, params, "Slack rejected the request (HTTP 404)."},
+		{"german", "de", connectors.ErrCodeUpstreamHTTP, params, "Slack hat die Anfrage abgelehnt (HTTP 404)."},
+		{"missing translation falls back to English", "de", connectors.ErrCodeCredentialMissing, params, "Slack is not connected."},
+		{"unknown language", "fr", connectors.ErrCodeUpstreamHTTP, params, "Slack rejected the request (HTTP 404)."},
+		{"unknown code", "de", "quota_exceeded", params, "Ein Fehler ist aufgetreten."},
+		{"missing param", "en", connectors.ErrCodeUpstreamHTTP, map[string]string{"service": "Slack"}, "Something went wrong."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messages.Translate(tt.lang, tt.code, tt.params); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestMessagesLanguage picks the best supported language from Accept-Language
+func TestMessagesLanguage(t *testing.T) {
+	messages := handlers.NewMessages(handlers.DefaultCatalogs, nil)
+
+	tests := map[string]string{
+		"":                           "en",
+		"de":                         "de",
+		"de-CH":                      "de",
+		"fr-FR,fr;q=0.9":             "en",
+		"fr-FR,de;q=0.8,en;q=0.5":    "de",
+		"en;q=0.4,de;q=0.7":          "de",
+		"de;q=0, en":                 "en",
+		"DE-de;q=bogus, en-GB;q=0.1": "en",
+	}
+	for header, want := range tests {
+		if got := messages.Language(header); got != want {
+			t.Errorf("Language(%q): expected %q, got %q", header, want, got)
+		}
+	}
+}
+
+// TestLogsTranslatedWithAdminDetail checks the logs endpoint translates coded failures
+// and only returns the untranslated detail to administrators
+func TestLogsTranslatedWithAdminDetail(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+
+	admin, _ := database.CreateUser("support@example.com", "hashed")
+	customer, _ := database.CreateUser("customer@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(customer.ID, "Customer flow", "webhook", "slack_message", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	adminWorkflow, _ := database.CreateWorkflow(admin.ID, "Admin flow", "webhook", "slack_message", `{}`)
+
+	raw := "Slack API returned status 404: no_service"
+	for _, id := range []string{workflow.ID, adminWorkflow.ID} {
+		if err := database.CreateLogEntry(&models.Log{
+			WorkflowID:  id,
+			Status:      "failed",
+			Message:     raw,
+			ErrorCode:   connectors.ErrCodeUpstreamHTTP,
+			ErrorParams: map[string]string{"service": "Slack", "status": "404"},
+		}); err != nil {
+			t.Fatalf("CreateLogEntry failed: %v", err)
+		}
+	}
+
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:       database,
+		Executor:    engine.NewExecutor(database, testLogger),
+		Logger:      testLogger,
+		AdminEmails: []string{"support@example.com"},
+	}))
+	defer srv.Close()
+
+	fetch := func(token, language string) models.Log {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL+"/api/logs", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept-Language", language)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/logs failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var logs []models.WorkflowLog
+		if err := json.NewDecoder(resp.Body).Decode(&logs); err != nil || len(logs) != 1 {
+			t.Fatalf("Expected one log, got %+v (%v)", logs, err)
+		}
+		return logs[0].Log
+	}
+
+	log := fetch(userToken(t, customer.ID), "de-DE,de;q=0.9,en;q=0.8")
+	if log.Message != "Slack hat die Anfrage abgelehnt (HTTP 404)." {
+		t.Errorf("Expected a German message, got %q", log.Message)
+	}
+	if log.Detail != "" || log.ErrorCode != connectors.ErrCodeUpstreamHTTP {
+		t.Errorf("Expected the code but no detail for a regular user, got %+v", log)
+	}
+
+	log = fetch(userToken(t, admin.ID), "fr")
+	if log.Message != "Slack rejected the request (HTTP 404)." {
+		t.Errorf("Expected the English fallback, got %q", log.Message)
+	}
+	if log.Detail != raw {
+		t.Errorf("Expected admins to see the raw detail, got %q", log.Detail)
+	}
+}
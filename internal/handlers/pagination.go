@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// ListPage is the response envelope for cursor-paginated list endpoints
+type ListPage struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// pageParams are the pagination query parameters of a list request
+type pageParams struct {
+	models.PageRequest
+	Paged  bool // cursor or limit given: respond with a ListPage envelope
+	Legacy bool // offset given: respond with a bare array (deprecated)
+}
+
+// EncodeCursor builds an opaque cursor from the sort key of the last row on a page
+// The timestamp keeps its zone offset so it binds exactly as the stored value
+func EncodeCursor(t time.Time, id string) string {
+	raw := t.Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor
+func DecodeCursor(cursor string) (*models.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &models.Cursor{Time: t, ID: parts[1]}, nil
+}
+
+// parsePageParams reads cursor, limit and the deprecated offset from the query string
+// Requests without any of them keep the historical unpaginated behaviour; limits above
+// maxLimit are clamped
+func parsePageParams(r *http.Request, maxLimit int) (pageParams, error) {
+	query := r.URL.Query()
+	params := pageParams{PageRequest: models.PageRequest{Limit: defaultPageLimit}}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return params, errors.New("limit must be a positive integer")
+		}
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+		params.Limit = limit
+		params.Paged = true
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := DecodeCursor(raw)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = cursor
+		params.Paged = true
+	}
+
+	// DEPRECATED: offset pagination skips or repeats rows when new ones arrive between fetches
+	if raw := query.Get("offset"); raw != "" && params.Cursor == nil {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return params, errors.New("offset must be a non-negative integer")
+		}
+		params.Offset = offset
+		params.Paged = false
+		params.Legacy = true
+	}
+
+	return params, nil
+}
+
+// setOffsetDeprecation tells clients still using offset to switch to cursors
+func setOffsetDeprecation(w http.ResponseWriter) {
+	w.Header().Set("Deprecation", "true")
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordResetTTL is how long a reset token can be used
+const passwordResetTTL = 30 * time.Minute
+
+// Forgot-password requests allowed per email, so the endpoint can't flood an inbox
+const (
+	forgotPasswordLimit  = 3
+	forgotPasswordWindow = time.Hour
+)
+
+// PasswordResetSender delivers a reset token to the user it was issued for
+type PasswordResetSender interface {
+	SendPasswordReset(email, token string, expiresAt time.Time) error
+}
+
+// LogResetSender writes reset tokens to the log, for development without a mail setup
+// Outside ENVIRONMENT=development it logs only that a reset was requested: anyone who
+// can read the logs could otherwise take over the account
+type LogResetSender struct {
+	log *logger.Logger
+}
+
+// NewLogResetSender creates a sender that logs reset tokens
+func NewLogResetSender(log *logger.Logger) *LogResetSender {
+	return &LogResetSender{log: log}
+}
+
+// SendPasswordReset logs the token (development) or that no delivery is configured
+func (s *LogResetSender) SendPasswordReset(email, token string, expiresAt time.Time) error {
+	if os.Getenv("ENVIRONMENT") != "development" {
+		s.log.Warn("Password reset requested, but no delivery is configured", map[string]interface{}{
+			"email": email,
+		})
+		return nil
+	}
+	s.log.Info("Password reset token issued", map[string]interface{}{
+		"email":      email,
+		"token":      token,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// SetResetSender sets how forgot-password tokens reach users; without one they are only stored
+func (h *AuthHandler) SetResetSender(sender PasswordResetSender) {
+	h.resets = sender
+}
+
+// ChangePassword sets a new password for the signed-in user, who must give the current one
+// Tokens issued before the change stop working; the response carries a fresh one
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if _, _, impersonated := middleware.GetImpersonationFromContext(r.Context()); impersonated {
+		SendError(w, http.StatusForbidden, "Passwords can't be changed while impersonating")
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeInvalidBody, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeValidationFailed, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetUserByID(userID)
+	if err != nil {
+		writeStoreError(w, err, "User not found")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		SendErrorCode(w, http.StatusForbidden, utils.CodeInvalidCredentials, "Current password is incorrect")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to hash password")
+		return
+	}
+	user, err = h.store.UpdateUserPassword(userID, string(hashedPassword))
+	if err != nil {
+		writeStoreError(w, err, "User not found")
+		return
+	}
+	h.recordSignIn(r, models.AuditPasswordChange, user, user.Email)
+
+	h.respondWithSession(w, r, user)
+}
+
+// ForgotPassword issues a single-use reset token and hands it to the reset sender
+// It answers 202 whether or not the email has an account, so it can't be used to find accounts
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeInvalidBody, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeValidationFailed, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if !h.resetLimiter.allow(req.Email, now) {
+		w.Header().Set("Retry-After", "3600")
+		SendErrorCode(w, http.StatusTooManyRequests, utils.CodeRateLimited, "Too many password reset requests for this email")
+		return
+	}
+
+	user, err := h.store.GetUserByEmail(req.Email)
+	if err != nil && !store.IsNotFound(err) {
+		writeStoreError(w, err, "User not found")
+		return
+	}
+	if err == nil {
+		token, err := newOpaqueToken()
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+		reset := &models.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: hashOpaqueToken(token),
+			CreatedAt: now,
+			ExpiresAt: now.Add(passwordResetTTL),
+		}
+		if err := h.store.CreatePasswordReset(reset); err != nil {
+			writeStoreError(w, err, "User not found")
+			return
+		}
+		h.recordSignIn(r, models.AuditPasswordResetRequest, user, req.Email)
+
+		if h.resets != nil {
+			if err := h.resets.SendPasswordReset(user.Email, token, reset.ExpiresAt); err != nil {
+				SendError(w, http.StatusBadGateway, "Failed to send the password reset")
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "accepted",
+		"message": "If an account exists for this email, a password reset has been sent",
+	})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword, using the token up
+// A token that was already used or has expired answers 410; tokens issued before stop working
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeInvalidBody, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeValidationFailed, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to hash password")
+		return
+	}
+
+	user, err := h.store.ResetPassword(hashOpaqueToken(req.Token), string(hashedPassword), time.Now())
+	if errors.Is(err, db.ErrResetClosed) {
+		SendError(w, http.StatusGone, "Reset token was already used or has expired")
+		return
+	}
+	if store.IsNotFound(err) {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Invalid reset token")
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err, "Reset token not found")
+		return
+	}
+	h.recordSignIn(r, models.AuditPasswordReset, user, user.Email)
+
+	h.respondWithSession(w, r, user)
+}
+
+// newOpaqueToken returns a random, URL-safe token (password resets, refresh tokens)
+func newOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashOpaqueToken is what the store keeps and looks resets and refresh tokens up by
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// emailLimiter allows a few requests per email within a sliding window
+type emailLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	seen   map[string][]time.Time // Request times within the window, oldest first
+}
+
+func newEmailLimiter(limit int, window time.Duration) *emailLimiter {
+	return &emailLimiter{limit: limit, window: window, seen: make(map[string][]time.Time)}
+}
+
+// allow records a request for the email unless it already made limit requests in the window
+func (l *emailLimiter) allow(email string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := strings.ToLower(email)
+	recent := l.recent(key, now)
+	if len(recent) >= l.limit {
+		return false
+	}
+	l.seen[key] = append(recent, now)
+
+	// Drop emails with nothing left in the window, so the map doesn't grow for good
+	if len(l.seen) > 1000 {
+		for other := range l.seen {
+			l.recent(other, now)
+		}
+	}
+	return true
+}
+
+// recent prunes and returns the email's requests still within the window
+func (l *emailLimiter) recent(key string, now time.Time) []time.Time {
+	times := l.seen[key]
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= l.window {
+		i++
+	}
+	times = times[i:]
+	if len(times) == 0 {
+		delete(l.seen, key)
+	} else {
+		l.seen[key] = times
+	}
+	return times
+}
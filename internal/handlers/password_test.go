@@ -0,0 +1,182 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// captureResetSender keeps every reset token it is asked to deliver
+type captureResetSender struct {
+	mu     sync.Mutex
+	tokens map[string][]string // By email, oldest first
+}
+
+func (s *captureResetSender) SendPasswordReset(email, token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[email] = append(s.tokens[email], token)
+	return nil
+}
+
+func (s *captureResetSender) sent(email string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[email]
+}
+
+// newPasswordServer starts the API with reset tokens captured instead of logged
+func newPasswordServer(t *testing.T) (*httptest.Server, *captureResetSender) {
+	t.Helper()
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	sender := &captureResetSender{tokens: make(map[string][]string)}
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:          database,
+		Executor:       engine.NewExecutor(database, testLogger),
+		Logger:         testLogger,
+		PasswordResets: sender,
+	}))
+	t.Cleanup(srv.Close)
+	return srv, sender
+}
+
+// TestChangePassword requires the current password and ends tokens issued before the change
+func TestChangePassword(t *testing.T) {
+	srv, _ := newPasswordServer(t)
+
+	var registered models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/register", "", models.RegisterRequest{Email: "owner@example.com", Password: "first-pass"}, &registered); status != http.StatusOK {
+		t.Fatalf("Failed to register: %d", status)
+	}
+	oldToken := registered.Token
+	if status := call(t, "GET", srv.URL+"/api/workflows", oldToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("Expected the new token to work, got %d", status)
+	}
+
+	if status := call(t, "POST", srv.URL+"/api/auth/change-password", oldToken,
+		models.ChangePasswordRequest{CurrentPassword: "wrong-pass", NewPassword: "second-pass"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a wrong current password to be refused, got %d", status)
+	}
+
+	var changed models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/change-password", oldToken,
+		models.ChangePasswordRequest{CurrentPassword: "first-pass", NewPassword: "second-pass"}, &changed); status != http.StatusOK {
+		t.Fatalf("Expected the password change to succeed, got %d", status)
+	}
+
+	if status := call(t, "GET", srv.URL+"/api/workflows", oldToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the token from before the change to be refused, got %d", status)
+	}
+	if status := call(t, "GET", srv.URL+"/api/workflows", changed.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("Expected the token from the change to work, got %d", status)
+	}
+
+	if status := call(t, "POST", srv.URL+"/api/auth/login", "", models.LoginRequest{Email: "owner@example.com", Password: "first-pass"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the old password to be refused, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/auth/login", "", models.LoginRequest{Email: "owner@example.com", Password: "second-pass"}, nil); status != http.StatusOK {
+		t.Errorf("Expected the new password to work, got %d", status)
+	}
+}
+
+// TestPasswordReset follows a forgotten password from request to reset, then tries the token again
+func TestPasswordReset(t *testing.T) {
+	srv, sender := newPasswordServer(t)
+
+	var registered models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/register", "", models.RegisterRequest{Email: "forgetful@example.com", Password: "first-pass"}, &registered); status != http.StatusOK {
+		t.Fatalf("Failed to register: %d", status)
+	}
+
+	// Unknown emails get the same answer, and nothing is sent
+	if status := call(t, "POST", srv.URL+"/api/auth/forgot-password", "", models.ForgotPasswordRequest{Email: "nobody@example.com"}, nil); status != http.StatusAccepted {
+		t.Errorf("Expected 202 for an unknown email, got %d", status)
+	}
+	if sent := sender.sent("nobody@example.com"); len(sent) != 0 {
+		t.Errorf("Expected nothing sent for an unknown email, got %v", sent)
+	}
+
+	for i := 0; i < 2; i++ {
+		if status := call(t, "POST", srv.URL+"/api/auth/forgot-password", "", models.ForgotPasswordRequest{Email: "forgetful@example.com"}, nil); status != http.StatusAccepted {
+			t.Fatalf("Expected the reset request to be accepted, got %d", status)
+		}
+	}
+	tokens := sender.sent("forgetful@example.com")
+	if len(tokens) != 2 {
+		t.Fatalf("Expected 2 reset tokens sent, got %d", len(tokens))
+	}
+
+	if status := call(t, "POST", srv.URL+"/api/auth/reset-password", "",
+		models.ResetPasswordRequest{Token: "not-a-token", NewPassword: "second-pass"}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown token to be rejected, got %d", status)
+	}
+
+	var reset models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/reset-password", "",
+		models.ResetPasswordRequest{Token: tokens[0], NewPassword: "second-pass"}, &reset); status != http.StatusOK {
+		t.Fatalf("Expected the reset to succeed, got %d", status)
+	}
+
+	// The token is single-use, and the reset closes the user's other open one
+	for i, token := range tokens {
+		if status := call(t, "POST", srv.URL+"/api/auth/reset-password", "",
+			models.ResetPasswordRequest{Token: token, NewPassword: "third-pass"}, nil); status != http.StatusGone {
+			t.Errorf("Token %d: expected reuse to be refused with 410, got %d", i+1, status)
+		}
+	}
+
+	// Sessions from before the reset end; the new password works
+	if status := call(t, "GET", srv.URL+"/api/workflows", registered.Token, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the token from before the reset to be refused, got %d", status)
+	}
+	if status := call(t, "GET", srv.URL+"/api/workflows", reset.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("Expected the token from the reset to work, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/auth/login", "", models.LoginRequest{Email: "forgetful@example.com", Password: "second-pass"}, nil); status != http.StatusOK {
+		t.Errorf("Expected the new password to work, got %d", status)
+	}
+
+	// A third request is still allowed, a fourth within the hour is not (whatever the case)
+	if status := call(t, "POST", srv.URL+"/api/auth/forgot-password", "", models.ForgotPasswordRequest{Email: "forgetful@example.com"}, nil); status != http.StatusAccepted {
+		t.Errorf("Expected the third request to be accepted, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/auth/forgot-password", "", models.ForgotPasswordRequest{Email: "Forgetful@example.com"}, nil); status != http.StatusTooManyRequests {
+		t.Errorf("Expected the fourth request to be rate limited, got %d", status)
+	}
+}
+
+// TestPasswordResetExpired refuses a reset token past its 30 minutes
+func TestPasswordResetExpired(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{Store: database, Executor: engine.NewExecutor(database, testLogger), Logger: testLogger}))
+	defer srv.Close()
+
+	user, _ := database.CreateUser("late@example.com", "hashed")
+	sum := sha256.Sum256([]byte("expired-token"))
+	issued := time.Now().Add(-31 * time.Minute)
+	if err := database.CreatePasswordReset(&models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hex.EncodeToString(sum[:]),
+		CreatedAt: issued,
+		ExpiresAt: issued.Add(30 * time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to create reset: %v", err)
+	}
+
+	if status := call(t, "POST", srv.URL+"/api/auth/reset-password", "",
+		models.ResetPasswordRequest{Token: "expired-token", NewPassword: "second-pass"}, nil); status != http.StatusGone {
+		t.Errorf("Expected an expired token to be refused with 410, got %d", status)
+	}
+}
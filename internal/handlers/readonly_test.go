@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/backup"
+	"github.com/alexmacdonald/simple-ipass/internal/config"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/export"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestReadOnlyRole serves a primary's database from a read-only instance and checks
+// every mutating route answers 503 with a pointer to the primary while reads work
+func TestReadOnlyRole(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "primary.db")
+	primary, err := db.New(path)
+	if err != nil {
+		t.Fatalf("Failed to open primary database: %v", err)
+	}
+	defer primary.Close()
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("ops-pass"), bcrypt.MinCost)
+	user, _ := primary.CreateUser("ops@example.com", string(hashed))
+	workflow, _ := primary.CreateWorkflow(user.ID, "Dashboard flow", "webhook", "testing", `{}`)
+
+	replica, err := db.NewReadOnly(path)
+	if err != nil {
+		t.Fatalf("Failed to open database read-only: %v", err)
+	}
+	defer replica.Close()
+	if _, err := replica.CreateWorkflow(user.ID, "Nope", "webhook", "testing", `{}`); err == nil {
+		t.Error("Expected writes through a read-only database to fail")
+	}
+
+	testLogger := logger.NewLogger("test")
+	settings, _ := config.Load("")
+	router := server.NewRouter(server.Config{
+		Store:         replica,
+		Executor:      engine.NewStandbyExecutor(replica, testLogger),
+		Logger:        testLogger,
+		AdminEmails:   []string{"ops@example.com"},
+		RuntimeConfig: config.NewManager(settings, replica, testLogger),
+		Backups:       backup.NewManager(replica, t.TempDir(), testLogger),
+		Exports:       export.NewManager(replica, t.TempDir(), []byte("signing-key"), testLogger),
+		Role:          server.RoleReadOnly,
+		PrimaryURL:    "https://primary.example.com/",
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	token := userToken(t, user.ID)
+
+	// Every registered write, whatever its path variables
+	placeholder := regexp.MustCompile(`\{[^}]+\}`)
+	blocked := 0
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		template, _ := route.GetPathTemplate()
+		for _, method := range methods {
+			if method == http.MethodGet || template == "/api/templates/lint" || template == "/api/workflows/{id}/render-template" {
+				continue
+			}
+			path := placeholder.ReplaceAllString(template, "x")
+			req, _ := http.NewRequest(method, srv.URL+path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s failed: %v", method, path, err)
+			}
+			resp.Body.Close()
+			blocked++
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("%s %s: expected 503, got %d", method, template, resp.StatusCode)
+			}
+			if location := resp.Header.Get(server.PrimaryLocationHeader); location != "https://primary.example.com"+path {
+				t.Errorf("%s %s: expected a pointer to the primary, got %q", method, template, location)
+			}
+		}
+		return nil
+	})
+	if blocked < 30 {
+		t.Errorf("Expected every mutating route to be checked, only found %d", blocked)
+	}
+
+	// Webhook triggers go to the primary even without a token
+	resp, _ := http.Post(srv.URL+"/api/webhooks/"+workflow.ID, "application/json", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected webhook triggers to be refused, got %d", resp.StatusCode)
+	}
+
+	// Reads work
+	var workflows []map[string]interface{}
+	if status := call(t, "GET", srv.URL+"/api/workflows", token, nil, &workflows); status != http.StatusOK || len(workflows) != 1 {
+		t.Errorf("Expected the workflow list to be served, got %d with %d workflows", status, len(workflows))
+	}
+	if status := call(t, "GET", srv.URL+"/api/logs", token, nil, nil); status != http.StatusOK {
+		t.Errorf("Expected logs to be served, got %d", status)
+	}
+
+	// Logging in stores a refresh token, so even a correct password is sent to the primary,
+	// and the access token the primary issues works here
+	credentials := models.LoginRequest{Email: "ops@example.com", Password: "ops-pass"}
+	body, _ := json.Marshal(credentials)
+	resp, err = http.Post(srv.URL+"/api/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(server.PrimaryLocationHeader) != "https://primary.example.com/api/auth/login" {
+		t.Errorf("Expected login to be sent to the primary, got %d", resp.StatusCode)
+	}
+	primarySrv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    primary,
+		Executor: engine.NewExecutor(primary, testLogger),
+		Logger:   testLogger,
+	}))
+	defer primarySrv.Close()
+	var login models.AuthResponse
+	if status := call(t, "POST", primarySrv.URL+"/api/auth/login", "", credentials, &login); status != http.StatusOK || login.RefreshToken == "" {
+		t.Fatalf("Expected login on the primary to succeed, got %d", status)
+	}
+	if status := call(t, "GET", srv.URL+"/api/workflows", login.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("Expected the primary's access token to work here, got %d", status)
+	}
+	// Payload reads are audited, which only the primary can record
+	if status := call(t, "GET", srv.URL+"/api/workflows/"+workflow.ID+"/executions/x", token, nil, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected the audited trace endpoint to go to the primary, got %d", status)
+	}
+
+	for _, endpoint := range []string{"/health", "/api/version"} {
+		var body map[string]interface{}
+		if status := call(t, "GET", srv.URL+endpoint, "", nil, &body); status != http.StatusOK || body["role"] != server.RoleReadOnly {
+			t.Errorf("%s: expected role %q, got %d %v", endpoint, server.RoleReadOnly, status, body["role"])
+		}
+	}
+}
+
+// TestPrimaryRoleReported checks a default instance reports itself as the primary
+func TestPrimaryRoleReported(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body["role"] != server.RolePrimary {
+		t.Errorf("Expected role %q, got %q", server.RolePrimary, body["role"])
+	}
+}
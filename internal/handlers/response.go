@@ -3,6 +3,8 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
 )
 
 // JSONResponse is a standardized API response envelope
@@ -11,6 +13,7 @@ type JSONResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"` // Machine-readable error code (utils.Code*)
 	Meta    *MetaData   `json:"meta,omitempty"`
 }
 
@@ -34,14 +37,20 @@ func SendJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// SendError sends a standardized error response
+// SendError sends a standardized error response with the generic code for its status
 func SendError(w http.ResponseWriter, status int, message string) {
+	SendErrorCode(w, status, utils.ErrorCodeForStatus(status), message)
+}
+
+// SendErrorCode sends a standardized error response with a specific error code
+func SendErrorCode(w http.ResponseWriter, status int, code, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	
 	response := JSONResponse{
 		Success: false,
 		Error:   message,
+		Code:    code,
 	}
 	
 	json.NewEncoder(w).Encode(response)
@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestTenantRoles checks members build and run workflows but only admins delete
+// other members' workflows, delete credentials or manage Kong
+func TestTenantRoles(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:        database,
+		Executor:     engine.NewExecutor(database, testLogger),
+		Logger:       testLogger,
+		KongAdminURL: "http://127.0.0.1:1", // Nothing listens; role checks answer first
+	}))
+	defer srv.Close()
+
+	admin, _ := database.CreateUser("admin@acme.com", "hashed")
+	alice, _ := database.CreateUserInTenant(admin.TenantID, "alice@acme.com", "hashed")
+	bob, _ := database.CreateUserInTenant(admin.TenantID, "bob@acme.com", "hashed")
+	if admin.Role != models.RoleAdmin || alice.Role != models.RoleMember {
+		t.Fatalf("Expected the sign-up to be admin and added users members, got %q and %q", admin.Role, alice.Role)
+	}
+	adminToken, aliceToken, bobToken := memberToken(t, admin), memberToken(t, alice), memberToken(t, bob)
+
+	// Members create and run workflows
+	var created handlers.WorkflowResponse
+	if status := call(t, "POST", srv.URL+"/api/workflows", aliceToken, map[string]string{
+		"name": "Alice's", "trigger_type": "webhook", "action_type": "testing",
+	}, &created); status != http.StatusCreated {
+		t.Fatalf("Expected a member to create a workflow, got %d", status)
+	}
+	if status := call(t, "PUT", srv.URL+"/api/workflows/"+created.ID+"/toggle", aliceToken, nil, nil); status != http.StatusOK {
+		t.Errorf("Expected a member to toggle their workflow, got %d", status)
+	}
+
+	// Only the creator or the admin deletes it
+	if status := call(t, "DELETE", srv.URL+"/api/workflows/"+created.ID, bobToken, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected another member to be forbidden from deleting, got %d", status)
+	}
+	if status := call(t, "DELETE", srv.URL+"/api/workflows/"+created.ID, adminToken, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Expected the admin to delete a member's workflow, got %d", status)
+	}
+	own, _ := database.CreateWorkflow(bob.ID, "Bob's", "webhook", "testing", `{}`)
+	if status := call(t, "DELETE", srv.URL+"/api/workflows/"+own.ID, bobToken, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Expected a member to delete their own workflow, got %d", status)
+	}
+
+	// Credential deletion and Kong are admin-only
+	credential, _ := database.CreateCredential(alice.ID, "slack", "https://hooks.slack.com/services/T0/B0/X")
+	for _, c := range []struct {
+		method, path string
+	}{
+		{"DELETE", "/api/credentials/" + credential.ID},
+		{"GET", "/api/kong/services"},
+		{"POST", "/api/kong/services"},
+		{"DELETE", "/api/kong/services/svc"},
+	} {
+		if status := call(t, c.method, srv.URL+c.path, aliceToken, nil, nil); status != http.StatusForbidden {
+			t.Errorf("%s %s: expected a member to be forbidden, got %d", c.method, c.path, status)
+		}
+	}
+	if status := call(t, "DELETE", srv.URL+"/api/credentials/"+credential.ID, adminToken, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Expected the admin to delete the credential, got %d", status)
+	}
+	if status := call(t, "DELETE", srv.URL+"/api/credentials/"+credential.ID, adminToken, nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected a deleted credential to be gone, got %d", status)
+	}
+
+	// A token without a role claim acts as a member
+	if status := call(t, "GET", srv.URL+"/api/kong/services", userTokenWithoutRole(t, admin), nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a token without a role to be treated as a member, got %d", status)
+	}
+}
+
+// userTokenWithoutRole signs a login token as issued before roles existed
+func userTokenWithoutRole(t *testing.T, user *models.User) string {
+	t.Helper()
+	member := *user
+	member.Role = ""
+	return memberToken(t, &member)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// RunWorkflowResponse references the execution a run-now request started
+type RunWorkflowResponse struct {
+	ExecutionID string          `json:"execution_id"`
+	Status      string          `json:"status"`           // "queued", or the finished run's status with ?sync=true
+	URL         string          `json:"url"`              // The execution trace, found once the run has finished
+	Result      *DryRunResponse `json:"result,omitempty"` // ?sync=true only
+}
+
+// RunWorkflow runs a saved workflow now, with the request body (optional JSON) as its
+// trigger payload, and answers 202 with a reference to the execution
+// ?sync=true waits for the run and answers 200 with its result, like a dry run whose
+// logs and trace are kept. A scheduled workflow keeps its next run unless
+// ?count_as_scheduled=true
+func (h *WorkflowsHandler) RunWorkflow(w http.ResponseWriter, r *http.Request) {
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, utils.MaxRequestBodySize))
+	if err != nil {
+		SendErrorCode(w, http.StatusRequestEntityTooLarge, utils.CodeInvalidBody, "Request body too large")
+		return
+	}
+	payload := strings.TrimSpace(string(body))
+	if payload != "" && !json.Valid([]byte(payload)) {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Trigger payload must be JSON")
+		return
+	}
+
+	query := r.URL.Query()
+	countAsScheduled := query.Get("count_as_scheduled") == "true"
+	response := RunWorkflowResponse{Status: "queued"}
+	status := http.StatusAccepted
+	if query.Get("sync") == "true" {
+		executionID, result := h.executor.RunNowSync(*workflow, payload, countAsScheduled)
+		run := h.dryRunResponse(r, result, engine.TemplateWarnings(workflow.ConfigJSON, payload))
+		response.ExecutionID, response.Status, response.Result = executionID, result.Status, &run
+		status = http.StatusOK
+	} else {
+		response.ExecutionID = h.executor.RunNow(*workflow, payload, countAsScheduled)
+	}
+	response.URL = "/api/workflows/" + workflow.ID + "/executions/" + response.ExecutionID
+
+	w.Header().Set("Content-Type", "application/json")
+	if status == http.StatusAccepted {
+		w.Header().Set("Location", response.URL)
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
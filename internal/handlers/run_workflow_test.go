@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestRunWorkflowNow runs a saved scheduled workflow synchronously and asynchronously and
+// checks the runs are recorded as manual without moving the schedule unless asked to
+func TestRunWorkflowNow(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	pool := engine.NewWorkerPool(1, testLogger) // Started once the async run is queued
+	executor := engine.NewExecutorWithPool(mockStore, testLogger, pool)
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    mockStore,
+		Executor: executor,
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	user, _ := mockStore.CreateUser("runner@example.com", "hashed")
+	token := userToken(t, user.ID)
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Digest", "schedule", "testing",
+		`{"interval": 15, "testing_response_json": "{\"sent\": true}"}`)
+	lastRun := time.Now().Add(-5 * time.Minute)
+	nextRun := lastRun.Add(15 * time.Minute)
+	mockStore.UpdateWorkflowLastExecuted(workflow.ID, lastRun, &nextRun)
+	url := srv.URL + "/api/workflows/" + workflow.ID + "/run"
+
+	// Synchronous: the result comes back and the run is recorded, schedule untouched
+	var response handlers.RunWorkflowResponse
+	status := call(t, "POST", url+"?sync=true", token, map[string]interface{}{"order": 42}, &response)
+	if status != http.StatusOK || response.Status != "success" || response.Result == nil || !response.Result.Success {
+		t.Fatalf("Expected a successful synchronous run, got %d: %+v", status, response)
+	}
+	execution, err := mockStore.GetExecutionByID(response.ExecutionID)
+	if err != nil || execution.TriggerSource != engine.TriggerManual || !strings.Contains(execution.TriggerPayload, `"order":42`) {
+		t.Fatalf("Expected a manual execution with the payload, got %+v, %v", execution, err)
+	}
+	if response.URL != "/api/workflows/"+workflow.ID+"/executions/"+execution.ID {
+		t.Errorf("Expected the execution URL, got %q", response.URL)
+	}
+	if logs, _ := mockStore.GetLogsByWorkflowID(workflow.ID); len(logs) != 1 {
+		t.Errorf("Expected the run's log persisted, got %d", len(logs))
+	}
+	if stored, _ := mockStore.GetWorkflowByID(workflow.ID); !stored.LastExecutedAt.Equal(lastRun) || !stored.NextRunAt.Equal(nextRun) {
+		t.Errorf("Expected the schedule untouched, got last %v next %v", stored.LastExecutedAt, stored.NextRunAt)
+	}
+
+	// Counted as the scheduled run, the next one is an interval away
+	call(t, "POST", url+"?sync=true&count_as_scheduled=true", token, nil, nil)
+	if stored, _ := mockStore.GetWorkflowByID(workflow.ID); stored.NextRunAt.Sub(time.Now().Add(15*time.Minute)).Abs() > time.Minute {
+		t.Errorf("Expected the next run an interval from now, got %v", stored.NextRunAt)
+	}
+
+	// Asynchronous: queued with a reference, recorded once the pool runs it
+	response = handlers.RunWorkflowResponse{}
+	req, _ := http.NewRequest("POST", url, strings.NewReader(`{"order": 43}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Run request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") == "" {
+		t.Fatalf("Expected 202 with a Location, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	executionID := resp.Header.Get("Location")[strings.LastIndex(resp.Header.Get("Location"), "/")+1:]
+	if _, err := mockStore.GetExecutionByID(executionID); err == nil {
+		t.Fatal("Expected no execution before the pool ran the job")
+	}
+	pool.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := executor.Shutdown(ctx); err != nil {
+		t.Fatalf("Failed to drain the pool: %v", err)
+	}
+	if execution, err := mockStore.GetExecutionByID(executionID); err != nil || execution.TriggerSource != engine.TriggerManual ||
+		!strings.Contains(execution.TriggerPayload, `"order":43`) {
+		t.Errorf("Expected the queued run recorded under its reference, got %+v, %v", execution, err)
+	}
+
+	for _, c := range []struct {
+		name   string
+		url    string
+		token  string
+		body   string
+		status int
+	}{
+		{"payload that isn't JSON", url, token, "{order", http.StatusBadRequest},
+		{"another tenant's workflow", url, userToken(t, "someone-else"), "", http.StatusForbidden},
+		{"missing workflow", srv.URL + "/api/workflows/missing/run", token, "", http.StatusNotFound},
+	} {
+		req, _ := http.NewRequest("POST", c.url, strings.NewReader(c.body))
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", c.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/audit"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// maxUserAgentLength caps the device description kept with a refresh token
+const maxUserAgentLength = 256
+
+// Refresh exchanges a refresh token for a new access token and refresh token
+// Refresh tokens are single-use: the one presented is revoked, so replaying it (or one
+// that was logged out, has expired or predates a password change) answers 401
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeInvalidBody, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeValidationFailed, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	refreshToken, next, err := newRefreshToken(r, nil)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	previous, err := h.store.RotateRefreshToken(hashOpaqueToken(req.RefreshToken), next, time.Now())
+	if store.IsNotFound(err) || errors.Is(err, db.ErrRefreshTokenClosed) {
+		SendErrorCode(w, http.StatusUnauthorized, utils.CodeInvalidCredentials, "Invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err, "Refresh token not found")
+		return
+	}
+
+	user, err := h.store.GetUserByID(previous.UserID)
+	if err != nil {
+		writeStoreError(w, err, "User not found")
+		return
+	}
+	token, err := generateJWT(user)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+		User:         *user,
+	})
+}
+
+// Logout revokes the presented refresh token, or with all_sessions every one of its user's
+// Access tokens already issued stay valid until they expire, at most AccessTokenTTL later
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req models.LogoutRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeInvalidBody, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.WriteJSONErrorCode(w, utils.CodeValidationFailed, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.store.RevokeRefreshToken(hashOpaqueToken(req.RefreshToken), req.AllSessions, time.Now())
+	if store.IsNotFound(err) {
+		SendErrorCode(w, http.StatusUnauthorized, utils.CodeInvalidCredentials, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err, "Refresh token not found")
+		return
+	}
+
+	event := models.AuditEvent{Action: models.AuditLogout, ActorID: token.UserID, ResourceType: models.AuditResourceUser, ResourceID: token.UserID}
+	if user, err := h.store.GetUserByID(token.UserID); err == nil {
+		event.TenantID = user.TenantID
+	}
+	h.audit.Record(r, event, map[string]interface{}{"all_sessions": req.AllSessions})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithSession answers with a new session for the user
+func (h *AuthHandler) respondWithSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	response, err := newSession(h.store, r, user)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// newSession signs an access token for the user and stores a refresh token for the
+// device the request came from
+func newSession(s db.Store, r *http.Request, user *models.User) (*models.AuthResponse, error) {
+	token, err := generateJWT(user)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, stored, err := newRefreshToken(r, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CreateRefreshToken(stored); err != nil {
+		return nil, err
+	}
+	return &models.AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+		User:         *user,
+	}, nil
+}
+
+// newRefreshToken generates a refresh token and the row that stores its hash with the
+// requesting device; user is nil when the store fills it in from the token being rotated
+func newRefreshToken(r *http.Request, user *models.User) (string, *models.RefreshToken, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	stored := &models.RefreshToken{
+		TokenHash: hashOpaqueToken(token),
+		UserAgent: userAgent,
+		IPAddress: audit.SourceIP(r),
+		CreatedAt: now,
+		ExpiresAt: now.Add(RefreshTokenTTL),
+	}
+	if user != nil {
+		stored.UserID, stored.TokenVersion = user.ID, user.TokenVersion
+	}
+	return token, stored, nil
+}
@@ -0,0 +1,126 @@
+package handlers_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// TestRefreshRotation exchanges a refresh token for a new pair and checks the old one is
+// single-use, including when two refreshes race with it
+func TestRefreshRotation(t *testing.T) {
+	srv, _ := newPasswordServer(t)
+
+	var login models.AuthResponse
+	call(t, "POST", srv.URL+"/api/auth/register", "", models.RegisterRequest{Email: "rotate@example.com", Password: "first-pass"}, nil)
+	if status := call(t, "POST", srv.URL+"/api/auth/login", "", models.LoginRequest{Email: "rotate@example.com", Password: "first-pass"}, &login); status != http.StatusOK {
+		t.Fatalf("Failed to log in: %d", status)
+	}
+	if login.RefreshToken == "" || login.ExpiresIn != int(handlers.AccessTokenTTL.Seconds()) {
+		t.Fatalf("Expected a refresh token and a %s access token, got %+v", handlers.AccessTokenTTL, login)
+	}
+
+	var refreshed models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/refresh", "", models.RefreshRequest{RefreshToken: login.RefreshToken}, &refreshed); status != http.StatusOK {
+		t.Fatalf("Expected the refresh to succeed, got %d", status)
+	}
+	if refreshed.Token == "" || refreshed.RefreshToken == "" || refreshed.RefreshToken == login.RefreshToken || refreshed.User.Email != "rotate@example.com" {
+		t.Fatalf("Expected a new token pair, got %+v", refreshed)
+	}
+	if status := call(t, "GET", srv.URL+"/api/workflows", refreshed.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("Expected the refreshed access token to work, got %d", status)
+	}
+
+	// The first refresh token was used up
+	if status := call(t, "POST", srv.URL+"/api/auth/refresh", "", models.RefreshRequest{RefreshToken: login.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected a used refresh token to be refused, got %d", status)
+	}
+	for _, token := range []string{"", "not-a-token"} {
+		if status := call(t, "POST", srv.URL+"/api/auth/refresh", "", models.RefreshRequest{RefreshToken: token}, nil); status != http.StatusBadRequest && status != http.StatusUnauthorized {
+			t.Errorf("%q: expected the refresh to be refused, got %d", token, status)
+		}
+	}
+
+	// Of two refreshes racing with one token, exactly one wins
+	var wg sync.WaitGroup
+	statuses := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- call(t, "POST", srv.URL+"/api/auth/refresh", "", models.RefreshRequest{RefreshToken: refreshed.RefreshToken}, nil)
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	ok := 0
+	for status := range statuses {
+		if status == http.StatusOK {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Errorf("Expected one of two racing refreshes to succeed, %d did", ok)
+	}
+}
+
+// TestLogoutRevokesSessions refuses refresh tokens after logout, one device or all, and
+// after a password change
+func TestLogoutRevokesSessions(t *testing.T) {
+	srv, _ := newPasswordServer(t)
+
+	login := func() models.AuthResponse {
+		t.Helper()
+		var response models.AuthResponse
+		if status := call(t, "POST", srv.URL+"/api/auth/login", "", models.LoginRequest{Email: "devices@example.com", Password: "first-pass"}, &response); status != http.StatusOK {
+			t.Fatalf("Failed to log in: %d", status)
+		}
+		return response
+	}
+	refresh := func(token string) int {
+		t.Helper()
+		return call(t, "POST", srv.URL+"/api/auth/refresh", "", models.RefreshRequest{RefreshToken: token}, nil)
+	}
+	call(t, "POST", srv.URL+"/api/auth/register", "", models.RegisterRequest{Email: "devices@example.com", Password: "first-pass"}, nil)
+
+	// Logging out one device leaves the other signed in
+	laptop, phone := login(), login()
+	if status := call(t, "POST", srv.URL+"/api/auth/logout", "", models.LogoutRequest{RefreshToken: laptop.RefreshToken}, nil); status != http.StatusNoContent {
+		t.Fatalf("Expected logout to succeed, got %d", status)
+	}
+	if status := refresh(laptop.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked refresh token to be refused, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/auth/logout", "", models.LogoutRequest{RefreshToken: laptop.RefreshToken}, nil); status != http.StatusNoContent {
+		t.Errorf("Expected logging out twice to succeed, got %d", status)
+	}
+	if status := call(t, "POST", srv.URL+"/api/auth/logout", "", models.LogoutRequest{RefreshToken: "unknown"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown refresh token to be refused, got %d", status)
+	}
+	var renewed models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/refresh", "", models.RefreshRequest{RefreshToken: phone.RefreshToken}, &renewed); status != http.StatusOK {
+		t.Fatalf("Expected the other device to refresh, got %d", status)
+	}
+
+	// Logging out everywhere ends every session of the user
+	tablet := login()
+	if status := call(t, "POST", srv.URL+"/api/auth/logout", "", models.LogoutRequest{RefreshToken: tablet.RefreshToken, AllSessions: true}, nil); status != http.StatusNoContent {
+		t.Fatalf("Expected logout everywhere to succeed, got %d", status)
+	}
+	if status := refresh(renewed.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("Expected the phone's session ended, got %d", status)
+	}
+
+	// A password change ends the sessions opened with the old password
+	desktop := login()
+	if status := call(t, "POST", srv.URL+"/api/auth/change-password", desktop.Token,
+		models.ChangePasswordRequest{CurrentPassword: "first-pass", NewPassword: "second-pass"}, nil); status != http.StatusOK {
+		t.Fatalf("Expected the password change to succeed, got %d", status)
+	}
+	if status := refresh(desktop.RefreshToken); status != http.StatusUnauthorized {
+		t.Errorf("Expected a refresh token from before the password change to be refused, got %d", status)
+	}
+}
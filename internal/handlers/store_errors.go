@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// writeStoreError answers a failed store call by error kind: 404 with notFound when the
+// record doesn't exist, 409 on a conflict, 503 while the store is busy or down, 500 otherwise
+func writeStoreError(w http.ResponseWriter, err error, notFound string) {
+	writeStoreErrorCode(w, err, utils.CodeNotFound, notFound)
+}
+
+// writeStoreErrorCode is writeStoreError with a specific code for the 404
+func writeStoreErrorCode(w http.ResponseWriter, err error, notFoundCode, notFound string) {
+	switch {
+	case store.IsNotFound(err):
+		SendErrorCode(w, http.StatusNotFound, notFoundCode, notFound)
+	case store.IsConflict(err):
+		SendError(w, http.StatusConflict, err.Error())
+	case store.IsLocked(err), store.IsUnavailable(err):
+		w.Header().Set("Retry-After", "1")
+		SendError(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+	default:
+		SendError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// writeWorkflowError answers a failed workflow lookup
+func writeWorkflowError(w http.ResponseWriter, err error) {
+	writeStoreErrorCode(w, err, utils.CodeWorkflowNotFound, "Workflow not found")
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// TemplatesHandler handles template tooling HTTP requests
+type TemplatesHandler struct {
+	templateEngine *utils.TemplateEngine
+}
+
+// NewTemplatesHandler creates a new templates handler
+func NewTemplatesHandler() *TemplatesHandler {
+	return &TemplatesHandler{templateEngine: utils.NewTemplateEngine()}
+}
+
+// LintTemplateRequest is a template to check, optionally against a sample payload or a JSON Schema
+type LintTemplateRequest struct {
+	Template string          `json:"template"`
+	Payload  json.RawMessage `json:"payload,omitempty"` // Sample trigger payload; wins over the schema
+	Schema   json.RawMessage `json:"schema,omitempty"`  // JSON Schema of the trigger payload
+}
+
+// LintTemplateResponse lists a template's references and the problems found in it
+type LintTemplateResponse struct {
+	References []utils.TemplateRef `json:"references"`
+	Findings   []utils.LintFinding `json:"findings"`
+}
+
+// LintTemplate reports undefined references, type mismatches, unknown filters and unused
+// payload fields, with byte offsets for editor highlighting
+func (h *TemplatesHandler) LintTemplate(w http.ResponseWriter, r *http.Request) {
+	var req LintTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	opts := utils.LintOptions{Payload: string(req.Payload), Schema: req.Schema}
+	if opts.Payload == "null" {
+		opts.Payload = ""
+	}
+	findings, err := h.templateEngine.Lint(req.Template, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := LintTemplateResponse{
+		References: h.templateEngine.Parse(req.Template),
+		Findings:   findings,
+	}
+	if response.References == nil {
+		response.References = []utils.TemplateRef{}
+	}
+	if response.Findings == nil {
+		response.Findings = []utils.LintFinding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestTemplateLintEndpoint lints a template over HTTP and checks the save-time warnings
+// of a workflow use the same findings
+func TestTemplateLintEndpoint(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+	user, _ := database.CreateUser("templates@example.com", "hashed")
+	token := userToken(t, user.ID)
+
+	var lint handlers.LintTemplateResponse
+	status := call(t, "POST", srv.URL+"/api/templates/lint", token, map[string]interface{}{
+		"template": "Order {{order.id}} for {{customer.name|@keys}}",
+		"payload":  map[string]interface{}{"order": map[string]string{"id": "A-1"}, "customer": map[string]string{"name": "Ada"}, "debug": true},
+	}, &lint)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if len(lint.References) != 2 || lint.References[1].Offset != 23 || lint.References[1].Length != 23 {
+		t.Errorf("Expected both references with their positions, got %+v", lint.References)
+	}
+	var kinds []string
+	for _, f := range lint.Findings {
+		kinds = append(kinds, f.Kind)
+	}
+	if strings.Join(kinds, ",") != "type_mismatch,unused_field" {
+		t.Errorf("Expected a type mismatch and the unused debug field, got %+v", lint.Findings)
+	}
+
+	if status := call(t, "POST", srv.URL+"/api/templates/lint", token, map[string]interface{}{
+		"template": "{{a}}", "schema": map[string]interface{}{"properties": "nope"},
+	}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid schema to be rejected, got %d", status)
+	}
+
+	var created handlers.WorkflowResponse
+	call(t, "POST", srv.URL+"/api/workflows", token, map[string]string{
+		"name": "Greeter", "trigger_type": "webhook", "action_type": "slack_message",
+		"config_json": `{"slack_message": "Hi {{user.name|@upper}} {{user.id"}`,
+	}, &created)
+	if len(created.Warnings) != 2 || !strings.Contains(created.Warnings[0], "@upper") || !strings.Contains(created.Warnings[1], "unclosed") {
+		t.Errorf("Expected the malformed reference and unknown filter as warnings, got %v", created.Warnings)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/export"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// TenantExportHandler handles self-service tenant data exports
+type TenantExportHandler struct {
+	store   db.Store // Interface, not concrete type!
+	exports *export.Manager
+}
+
+// NewTenantExportHandler creates a new tenant export handler
+func NewTenantExportHandler(store db.Store, exports *export.Manager) *TenantExportHandler {
+	return &TenantExportHandler{store: store, exports: exports}
+}
+
+// ExportResponse is an export job plus its signed download link once the archive is ready
+type ExportResponse struct {
+	export.Job
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// RequestExport starts an export of everything the tenant has stored
+// Only the tenant's admin may request one; impersonating support staff can't
+func (h *TenantExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, _, impersonated := middleware.GetImpersonationFromContext(r.Context()); impersonated || !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can export the tenant's data", http.StatusForbidden)
+		return
+	}
+
+	job, err := h.exports.Request(tenantID, userID)
+	if errors.Is(err, export.ErrInProgress) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to start export", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/tenants/export/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(ExportResponse{Job: job})
+}
+
+// GetExport returns an export job's status, with the download link once it is ready
+func (h *TenantExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := h.exports.Get(tenantID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Export not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExportResponse{Job: job, DownloadURL: h.exports.DownloadURL(job)})
+}
+
+// DownloadExport streams an archive to whoever holds its signed link (no JWT needed)
+func (h *TenantExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	file, job, err := h.exports.Open(mux.Vars(r)["id"], query.Get("expires"), query.Get("signature"), time.Now())
+	if errors.Is(err, export.ErrInvalidLink) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to open export", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: job.RequestedBy,
+		Action:  models.AuditTenantExportDownload,
+		Method:  r.Method,
+		Path:    r.URL.Path, // Without the signature
+		Detail:  fmt.Sprintf("tenant %s: export %s downloaded from %s", job.TenantID, job.ID, r.RemoteAddr),
+	})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="goflow-export-%s.zip"`, job.ID))
+	w.Header().Set("Content-Length", fmt.Sprint(job.SizeBytes))
+	io.Copy(w, file)
+}
+
+// tenantAdmin reports whether the request's token carries the tenant admin role
+// The user whose sign-up created the tenant is its admin; users added to it are members
+func tenantAdmin(r *http.Request) bool {
+	role, _ := middleware.GetRoleFromContext(r.Context())
+	return role == models.RoleAdmin
+}
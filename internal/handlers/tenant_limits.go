@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// validTiers are the tiers a tenant can be moved to
+var validTiers = map[string]bool{
+	models.TierFree:       true,
+	models.TierPro:        true,
+	models.TierEnterprise: true,
+}
+
+// TenantLimitsHandler lets platform admins change a tenant's tier and API rate limits
+type TenantLimitsHandler struct {
+	store   db.Store // Interface, not concrete type!
+	limiter *middleware.RateLimiter
+}
+
+// NewTenantLimitsHandler creates a new tenant limits handler
+// limiter may be nil when rate limiting is off; the limits are stored either way
+func NewTenantLimitsHandler(store db.Store, limiter *middleware.RateLimiter) *TenantLimitsHandler {
+	return &TenantLimitsHandler{store: store, limiter: limiter}
+}
+
+// GetTenantLimits returns a tenant's tier and rate limit overrides
+func (h *TenantLimitsHandler) GetTenantLimits(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["id"]
+	if _, err := h.store.GetTenantByID(tenantID); err != nil {
+		writeStoreError(w, err, "Tenant not found")
+		return
+	}
+
+	limits, err := h.store.GetTenantLimits(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant limits", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+// UpdateTenantLimits changes a tenant's tier and rate limit overrides (0 = the tier's limit)
+// The change applies to the tenant's next request
+func (h *TenantLimitsHandler) UpdateTenantLimits(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantID := mux.Vars(r)["id"]
+	if _, err := h.store.GetTenantByID(tenantID); err != nil {
+		writeStoreError(w, err, "Tenant not found")
+		return
+	}
+
+	var limits models.TenantLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !validTiers[limits.Tier] {
+		http.Error(w, "tier must be 'free', 'pro' or 'enterprise'", http.StatusBadRequest)
+		return
+	}
+	if limits.RequestsPerSecond < 0 || limits.Burst < 0 {
+		http.Error(w, "requests_per_second and burst must not be negative", http.StatusBadRequest)
+		return
+	}
+	limits.TenantID = tenantID
+
+	if err := h.store.SetTenantLimits(&limits); err != nil {
+		http.Error(w, "Failed to save tenant limits", http.StatusInternalServerError)
+		return
+	}
+	if h.limiter != nil {
+		h.limiter.Invalidate(tenantID)
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID:  adminID,
+		TenantID: tenantID,
+		Action:   models.AuditTenantLimitsUpdate,
+		Detail:   fmt.Sprintf("tier %s, %g requests/s, burst %d", limits.Tier, limits.RequestsPerSecond, limits.Burst),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
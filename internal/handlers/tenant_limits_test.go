@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestRateLimitTiers sends the same burst of requests as a free and a pro tenant: only the
+// free tenant is limited, until an admin upgrades it
+func TestRateLimitTiers(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	limiter := middleware.NewRateLimiter(1, 1000000, 3)
+	limiter.UseTenantLimits(database, time.Hour) // Only invalidation can pick up a change in this test
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:       database,
+		Executor:    engine.NewExecutor(database, testLogger),
+		Logger:      testLogger,
+		RateLimiter: limiter,
+		AdminEmails: []string{"ops@example.com"},
+	}))
+	defer srv.Close()
+
+	free, _ := database.CreateUser("free@example.com", "hashed")
+	pro, _ := database.CreateUser("pro@example.com", "hashed")
+	ops, _ := database.CreateUser("ops@example.com", "hashed")
+	database.SetTenantLimits(&models.TenantLimits{TenantID: pro.TenantID, Tier: models.TierPro})
+	database.SetTenantLimits(&models.TenantLimits{TenantID: ops.TenantID, Tier: models.TierEnterprise})
+	freeToken, proToken, opsToken := memberToken(t, free), memberToken(t, pro), memberToken(t, ops)
+
+	burst := func(token string) (statuses []int, last *http.Response) {
+		for i := 0; i < 5; i++ {
+			req, _ := http.NewRequest("GET", srv.URL+"/api/workflows", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			statuses = append(statuses, resp.StatusCode)
+			last = resp
+		}
+		return statuses, last
+	}
+
+	statuses, last := burst(freeToken)
+	if statuses[2] != http.StatusOK || statuses[3] != http.StatusTooManyRequests || statuses[4] != http.StatusTooManyRequests {
+		t.Errorf("Expected the free tenant limited after its burst of 3, got %v", statuses)
+	}
+	if last.Header.Get("X-RateLimit-Limit") != "1" || last.Header.Get("X-RateLimit-Remaining") != "0" || last.Header.Get("Retry-After") != "1" {
+		t.Errorf("Expected the free tier's real limits in the headers, got %v", last.Header)
+	}
+
+	statuses, last = burst(proToken)
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Fatalf("Expected the pro tenant never limited, got %d on request %d", status, i+1)
+		}
+	}
+	if last.Header.Get("X-RateLimit-Limit") != "1000000" {
+		t.Errorf("Expected the pro tier's limit in the headers, got %q", last.Header.Get("X-RateLimit-Limit"))
+	}
+
+	// Upgrading the free tenant takes effect on its next request, despite the hour-long cache
+	limitsURL := srv.URL + "/api/admin/tenants/" + free.TenantID + "/limits"
+	var updated models.TenantLimits
+	if status := call(t, "PUT", limitsURL, opsToken, map[string]interface{}{"tier": "pro", "burst": 10}, &updated); status != http.StatusOK || updated.Tier != models.TierPro {
+		t.Fatalf("Expected the tenant upgraded, got %d: %+v", status, updated)
+	}
+	statuses, _ = burst(freeToken)
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Fatalf("Expected the upgraded tenant no longer limited, got %d on request %d", status, i+1)
+		}
+	}
+
+	for _, c := range []struct {
+		name, token, url string
+		body             map[string]interface{}
+		status           int
+	}{
+		{"unknown tier", opsToken, limitsURL, map[string]interface{}{"tier": "platinum"}, http.StatusBadRequest},
+		{"negative rate", opsToken, limitsURL, map[string]interface{}{"tier": "free", "requests_per_second": -1}, http.StatusBadRequest},
+		{"unknown tenant", opsToken, srv.URL + "/api/admin/tenants/tenant_missing/limits", map[string]interface{}{"tier": "pro"}, http.StatusNotFound},
+		{"not a platform admin", proToken, limitsURL, map[string]interface{}{"tier": "enterprise"}, http.StatusForbidden},
+	} {
+		if status := call(t, "PUT", c.url, c.token, c.body, nil); status != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, status)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/anomaly"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/notify"
+	"github.com/alexmacdonald/simple-ipass/internal/retention"
+)
+
+// TenantSettingsHandler handles a tenant's own settings
+type TenantSettingsHandler struct {
+	store     db.Store          // Interface, not concrete type!
+	retention *retention.Worker // Holds the default log period (optional)
+}
+
+// NewTenantSettingsHandler creates a new tenant settings handler
+func NewTenantSettingsHandler(store db.Store) *TenantSettingsHandler {
+	return &TenantSettingsHandler{store: store}
+}
+
+// SetRetention reports policies with the worker's default log period
+// (LOG_RETENTION_DAYS); without one it is retention.DefaultLogsDays
+func (h *TenantSettingsHandler) SetRetention(worker *retention.Worker) {
+	h.retention = worker
+}
+
+// defaultLogsDays is the default log period the retention worker applies
+func (h *TenantSettingsHandler) defaultLogsDays() int {
+	if h.retention == nil {
+		return retention.DefaultLogsDays
+	}
+	return h.retention.DefaultLogsDays()
+}
+
+// RetentionResponse is a tenant's retention policy and how it was derived
+type RetentionResponse struct {
+	TenantID  string                  `json:"tenant_id"`
+	Tier      string                  `json:"tier"`
+	Effective models.RetentionPolicy  `json:"effective"`           // What the retention worker applies
+	Defaults  models.RetentionPolicy  `json:"defaults"`            // The tier's defaults
+	Overrides *models.RetentionPolicy `json:"overrides,omitempty"` // The tenant's own settings (0 = tier default)
+	LastPurge *models.RetentionRun    `json:"last_purge,omitempty"`
+}
+
+// GetRetention returns the tenant's effective retention policy and last purge statistics
+func (h *TenantSettingsHandler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.retentionResponse(settings))
+}
+
+// UpdateRetention replaces the tenant's retention overrides
+// Fields left at 0 follow the tier default; the resulting policy must keep
+// payloads no longer than logs and executions
+func (h *TenantSettingsHandler) UpdateRetention(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var overrides models.RetentionPolicy
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+	settings.Retention = &overrides
+	if overrides == (models.RetentionPolicy{}) {
+		settings.Retention = nil
+	}
+	if err := retention.Validate(retention.Effective(settings, h.defaultLogsDays())); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.store.SaveTenantSettings(settings); err != nil {
+		http.Error(w, "Failed to save tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	effective := retention.Effective(settings, h.defaultLogsDays())
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditRetentionUpdate,
+		Detail: fmt.Sprintf("tenant %s: logs %dd, payloads %dd, executions %dd, fixtures %dd", tenantID,
+			effective.LogsDays, effective.PayloadsDays, effective.ExecutionsDays, effective.FixturesDays),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.retentionResponse(settings))
+}
+
+// retentionResponse describes a tenant's settings
+func (h *TenantSettingsHandler) retentionResponse(settings *models.TenantSettings) RetentionResponse {
+	logsDays := h.defaultLogsDays()
+	return RetentionResponse{
+		TenantID:  settings.TenantID,
+		Tier:      settings.Tier,
+		Effective: retention.Effective(settings, logsDays),
+		Defaults:  retention.TierDefaults(settings.Tier, logsDays),
+		Overrides: settings.Retention,
+		LastPurge: settings.LastPurge,
+	}
+}
+
+// NotificationSettings turns workflow change notifications on or off for the tenant
+type NotificationSettings struct {
+	Enabled      bool `json:"enabled"`
+	BatchSeconds int  `json:"batch_seconds,omitempty"` // 0 = one minute, at most an hour
+}
+
+// GetNotifications returns the tenant's workflow change notification settings
+func (h *TenantSettingsHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notificationsResponse(settings))
+}
+
+// UpdateNotifications turns notifications on or off and sets the batching window
+// Only the tenant's admin may; members' own preferences are kept
+func (h *TenantSettingsHandler) UpdateNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can change notification settings", http.StatusForbidden)
+		return
+	}
+
+	var req NotificationSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.BatchSeconds < 0 || time.Duration(req.BatchSeconds)*time.Second > notify.MaxBatchWindow {
+		http.Error(w, fmt.Sprintf("batch_seconds must be between 0 and %d", int(notify.MaxBatchWindow.Seconds())), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+	notifications := notificationsResponse(settings)
+	notifications.Enabled, notifications.BatchSeconds = req.Enabled, req.BatchSeconds
+	settings.Notifications = &notifications
+	if err := h.store.SaveTenantSettings(settings); err != nil {
+		http.Error(w, "Failed to save tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditNotificationsUpdate,
+		Detail:  fmt.Sprintf("tenant %s: enabled %t, batch %s", tenantID, req.Enabled, notify.BatchWindow(&notifications)),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// UpdateMyNotifications sets the caller's own channel or opts them out
+func (h *TenantSettingsHandler) UpdateMyNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.NotificationPreference
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	switch req.Channel {
+	case "", notify.ChannelSlack, notify.ChannelDiscord:
+	default:
+		http.Error(w, "channel must be slack or discord", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+	notifications := notificationsResponse(settings)
+	members := make(map[string]models.NotificationPreference, len(notifications.Members)+1)
+	for memberID, preference := range notifications.Members {
+		members[memberID] = preference
+	}
+	members[userID] = req
+	notifications.Members = members
+	settings.Notifications = &notifications
+	if err := h.store.SaveTenantSettings(settings); err != nil {
+		http.Error(w, "Failed to save tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// notificationsResponse returns a copy of the tenant's notification settings (off if never set)
+func notificationsResponse(settings *models.TenantSettings) models.ChangeNotifications {
+	if settings.Notifications == nil {
+		return models.ChangeNotifications{}
+	}
+	return *settings.Notifications
+}
+
+// GetCanaries returns the tenant's connector version canaries keyed by action type
+func (h *TenantSettingsHandler) GetCanaries(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(canariesResponse(settings))
+}
+
+// UpdateCanaries replaces the tenant's connector version canaries
+// Only the tenant's admin may; a canary at 0 percent (or an empty body) stops routing to it
+func (h *TenantSettingsHandler) UpdateCanaries(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can change connector canaries", http.StatusForbidden)
+		return
+	}
+
+	var req map[string]models.ConnectorCanary
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for actionType, canary := range req {
+		if err := engine.ValidateCanary(actionType, canary); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+	settings.Canaries = req
+	if err := h.store.SaveTenantSettings(settings); err != nil {
+		http.Error(w, "Failed to save tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditCanariesUpdate,
+		Detail:  fmt.Sprintf("tenant %s: %v", tenantID, req),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(canariesResponse(settings))
+}
+
+// canariesResponse returns the tenant's canaries (an empty object if none)
+func canariesResponse(settings *models.TenantSettings) map[string]models.ConnectorCanary {
+	if settings.Canaries == nil {
+		return map[string]models.ConnectorCanary{}
+	}
+	return settings.Canaries
+}
+
+// MaskingSettings is the tenant's payload masking policy
+type MaskingSettings struct {
+	MaskedFields []string `json:"masked_fields"` // Payload keys always redacted (case-insensitive, at any depth)
+}
+
+// GetMasking returns the payload keys the tenant always redacts
+func (h *TenantSettingsHandler) GetMasking(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maskingResponse(settings))
+}
+
+// UpdateMasking replaces the payload keys the tenant always redacts
+// Only the tenant's admin may; built-in secret masking applies regardless
+func (h *TenantSettingsHandler) UpdateMasking(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can change the masking policy", http.StatusForbidden)
+		return
+	}
+
+	var req MaskingSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	fields := make([]string, 0, len(req.MaskedFields))
+	for _, field := range req.MaskedFields {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+	settings.MaskedFields = fields
+	if err := h.store.SaveTenantSettings(settings); err != nil {
+		http.Error(w, "Failed to save tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditMaskingUpdate,
+		Detail:  fmt.Sprintf("tenant %s: %d masked fields", tenantID, len(fields)),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(maskingResponse(settings))
+}
+
+// maskingResponse describes the tenant's masking policy (an empty list if none)
+func maskingResponse(settings *models.TenantSettings) MaskingSettings {
+	if settings.MaskedFields == nil {
+		return MaskingSettings{MaskedFields: []string{}}
+	}
+	return MaskingSettings{MaskedFields: settings.MaskedFields}
+}
+
+// AnomalyResponse is the tenant's anomaly detection overrides and the thresholds in effect
+type AnomalyResponse struct {
+	models.AnomalySettings
+	Effective anomaly.Thresholds `json:"effective"`
+}
+
+// GetAnomaly returns when the tenant's workflows count as slower or flakier than usual
+func (h *TenantSettingsHandler) GetAnomaly(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(anomalyResponse(settings))
+}
+
+// UpdateAnomaly replaces the tenant's anomaly detection overrides
+// Only the tenant's admin may; zero values fall back to the defaults
+func (h *TenantSettingsHandler) UpdateAnomaly(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can change anomaly detection", http.StatusForbidden)
+		return
+	}
+
+	var req models.AnomalySettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := anomaly.Validate(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+	settings.Anomaly = &req
+	if err := h.store.SaveTenantSettings(settings); err != nil {
+		http.Error(w, "Failed to save tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	effective := anomaly.ThresholdsFor(&req)
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditAnomalyUpdate,
+		Detail: fmt.Sprintf("tenant %s: disabled %t, notify %t, duration x%.1f, failure rate %.2f",
+			tenantID, effective.Disabled, req.Notify, effective.DurationFactor, effective.FailureRate),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(anomalyResponse(settings))
+}
+
+// anomalyResponse describes the tenant's anomaly detection settings
+func anomalyResponse(settings *models.TenantSettings) AnomalyResponse {
+	resp := AnomalyResponse{Effective: anomaly.ThresholdsFor(settings.Anomaly)}
+	if settings.Anomaly != nil {
+		resp.AnomalySettings = *settings.Anomaly
+	}
+	return resp
+}
+
+// CredentialPolicyRequest sets what happens when a workflow uses a credential from the other environment
+type CredentialPolicyRequest struct {
+	CredentialPolicy string `json:"credential_policy"` // 'warn' or 'enforce'
+}
+
+// GetCredentialPolicy returns the tenant's credential environment policy
+func (h *TenantSettingsHandler) GetCredentialPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credentialPolicyResponse(settings))
+}
+
+// UpdateCredentialPolicy switches the tenant between warning about and blocking
+// credential environment mismatches; only the tenant's admin may
+func (h *TenantSettingsHandler) UpdateCredentialPolicy(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !tenantAdmin(r) {
+		http.Error(w, "Only a tenant admin can change the credential policy", http.StatusForbidden)
+		return
+	}
+
+	var req CredentialPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := engine.ValidateCredentialPolicy(req.CredentialPolicy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch tenant settings", http.StatusInternalServerError)
+		return
+	}
+	settings.CredentialPolicy = req.CredentialPolicy
+	if err := h.store.SaveTenantSettings(settings); err != nil {
+		http.Error(w, "Failed to save tenant settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := credentialPolicyResponse(settings)
+	h.store.RecordAuditEvent(&models.AuditEvent{
+		ActorID: userID,
+		Action:  models.AuditCredentialPolicy,
+		Detail:  fmt.Sprintf("tenant %s: credential environment policy %s", tenantID, response.CredentialPolicy),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// credentialPolicyResponse describes the tenant's policy, spelling out the default
+func credentialPolicyResponse(settings *models.TenantSettings) CredentialPolicyRequest {
+	policy := settings.CredentialPolicy
+	if policy == "" {
+		policy = models.CredentialPolicyWarn
+	}
+	return CredentialPolicyRequest{CredentialPolicy: policy}
+}
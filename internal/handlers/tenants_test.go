@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memberToken signs a login token for a user in their tenant, with their role
+func memberToken(t *testing.T, user *models.User) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"role":      user.Role,
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}).SignedString(middleware.GetJWTSecret())
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+// TestTenantScoping checks users of one tenant share workflows, credentials and logs,
+// and users of another tenant see none of them
+func TestTenantScoping(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	if _, err := database.CreateTenant("tenant_acme", "Acme"); err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	alice, _ := database.CreateUserInTenant("tenant_acme", "alice@acme.com", "hashed")
+	bob, _ := database.CreateUserInTenant("tenant_acme", "bob@acme.com", "hashed")
+
+	workflow, err := database.CreateWorkflow(alice.ID, "Alerts", "webhook", "testing", `{}`)
+	if err != nil || workflow.TenantID != "tenant_acme" {
+		t.Fatalf("Expected the workflow in alice's tenant, got %+v, %v", workflow, err)
+	}
+	database.CreateWorkflow(bob.ID, "Reports", "webhook", "testing", `{}`)
+	database.CreateCredential(alice.ID, "slack", "https://hooks.slack.com/services/T0/B0/X")
+	database.CreateLog(workflow.ID, "success", "Sent")
+
+	// A user who registers on their own gets a tenant of their own, carried in the token
+	var registered models.AuthResponse
+	if status := call(t, "POST", srv.URL+"/api/auth/register", "", map[string]string{"email": "eve@example.com", "password": "secret123"}, &registered); status != http.StatusOK {
+		t.Fatalf("Expected registration to succeed, got %d", status)
+	}
+	if registered.User.TenantID == "" || registered.User.TenantID == "tenant_acme" {
+		t.Fatalf("Expected eve in a tenant of her own, got %q", registered.User.TenantID)
+	}
+
+	for _, c := range []struct {
+		name                         string
+		token                        string
+		workflows, credentials, logs int
+	}{
+		{"bob", memberToken(t, bob), 2, 1, 1},
+		{"eve", registered.Token, 0, 0, 0},
+	} {
+		var workflows []handlers.WorkflowResponse
+		if status := call(t, "GET", srv.URL+"/api/workflows", c.token, nil, &workflows); status != http.StatusOK || len(workflows) != c.workflows {
+			t.Errorf("%s: expected %d workflows, got %d (status %d)", c.name, c.workflows, len(workflows), status)
+		}
+		var page struct {
+			Items []handlers.WorkflowResponse `json:"items"`
+		}
+		if status := call(t, "GET", srv.URL+"/api/workflows?limit=10", c.token, nil, &page); status != http.StatusOK || len(page.Items) != c.workflows {
+			t.Errorf("%s: expected %d paged workflows, got %d (status %d)", c.name, c.workflows, len(page.Items), status)
+		}
+		var credentials []models.Credential
+		if status := call(t, "GET", srv.URL+"/api/credentials", c.token, nil, &credentials); status != http.StatusOK || len(credentials) != c.credentials {
+			t.Errorf("%s: expected %d credentials, got %d (status %d)", c.name, c.credentials, len(credentials), status)
+		}
+		var logs []models.WorkflowLog
+		if status := call(t, "GET", srv.URL+"/api/logs", c.token, nil, &logs); status != http.StatusOK || len(logs) != c.logs {
+			t.Errorf("%s: expected %d logs, got %d (status %d)", c.name, c.logs, len(logs), status)
+		}
+	}
+
+	toggle := srv.URL + "/api/workflows/" + workflow.ID + "/toggle"
+	if status := call(t, "PUT", toggle, memberToken(t, bob), nil, nil); status != http.StatusOK {
+		t.Errorf("Expected bob to manage alice's workflow, got %d", status)
+	}
+	if status := call(t, "PUT", toggle, registered.Token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected another tenant's user to be forbidden, got %d", status)
+	}
+
+	// Tokens from before tenants carry no tenant_id and must sign in again
+	if status := call(t, "GET", srv.URL+"/api/workflows", userTokenWithoutTenant(t, bob.ID), nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected a token without tenant_id to be rejected, got %d", status)
+	}
+}
+
+// userTokenWithoutTenant signs a token as issued before tenants existed
+func userTokenWithoutTenant(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString(middleware.GetJWTSecret())
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
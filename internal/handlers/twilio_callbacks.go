@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/gorilla/mux"
+)
+
+// maxTwilioCallbackSize bounds a status callback's form body
+const maxTwilioCallbackSize = 64 * 1024
+
+// TwilioStatusCallback receives Twilio's delivery status updates for a workflow's messages
+// (set its twilio_status_callback to this endpoint's URL). The X-Twilio-Signature must
+// match the workflow's Twilio credential; final statuses are recorded in its logs
+func (h *WebhookHandler) TwilioStatusCallback(w http.ResponseWriter, r *http.Request) {
+	workflow, err := h.store.GetWorkflowByID(mux.Vars(r)["workflow_id"])
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTwilioCallbackSize)
+	if err := r.ParseForm(); err != nil {
+		SendError(w, http.StatusBadRequest, "Invalid form body")
+		return
+	}
+
+	status, err := h.executor.RecordTwilioStatus(*workflow, r.PostForm, r.Header.Get("X-Twilio-Signature"))
+	switch {
+	case errors.Is(err, engine.ErrTwilioCallbackDisabled):
+		SendError(w, http.StatusNotFound, "Status callbacks are not enabled for this workflow")
+		return
+	case errors.Is(err, engine.ErrTwilioSignature), store.IsNotFound(err):
+		// Without the workflow's Twilio credential the signature can't be checked either
+		SendError(w, http.StatusForbidden, "Invalid Twilio signature")
+		return
+	case err != nil:
+		h.log.Error("Failed to record Twilio status", map[string]interface{}{
+			"workflow_id": workflow.ID,
+			"error":       err.Error(),
+		})
+		writeStoreError(w, err, "Workflow not found")
+		return
+	}
+
+	h.log.Info("Twilio status received", map[string]interface{}{
+		"workflow_id": workflow.ID,
+		"message_sid": status.MessageSID,
+		"status":      status.Status,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestTwilioStatusCallback posts signed delivery updates and finds the final ones in the
+// workflow's logs
+func TestTwilioStatusCallback(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	const callbackURL = "https://goflow.example.com/api/callbacks/twilio/orders"
+	user, _ := database.CreateUser("sms@example.com", "hashed")
+	database.CreateCredential(user.ID, "twilio", `{"account_sid": "AC1", "auth_token": "secret", "from_number": "+15550000000"}`)
+	workflow, err := database.CreateWorkflow(user.ID, "Order texts", "webhook", "twilio_sms",
+		`{"twilio_to": "+15551234567", "twilio_message": "Shipped", "twilio_status_callback": "`+callbackURL+`"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	silent, _ := database.CreateWorkflow(user.ID, "No callbacks", "webhook", "twilio_sms", `{"twilio_to": "+15551234567", "twilio_message": "Hi"}`)
+
+	post := func(workflowID string, form url.Values, signature string) int {
+		req, _ := http.NewRequest("POST", srv.URL+"/api/callbacks/twilio/"+workflowID, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Twilio-Signature", signature)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Callback failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	signed := func(workflowID string, form url.Values) int {
+		return post(workflowID, form, connectors.TwilioSignature("secret", callbackURL, form))
+	}
+
+	sent := url.Values{"MessageSid": {"SM1"}, "MessageStatus": {"sent"}, "To": {"+15551234567"}}
+	delivered := url.Values{"MessageSid": {"SM1"}, "MessageStatus": {"delivered"}, "To": {"+15551234567"}}
+	undelivered := url.Values{"MessageSid": {"SM2"}, "MessageStatus": {"undelivered"}, "To": {"+15559999999"},
+		"ErrorCode": {"30003"}, "ErrorMessage": {"Unreachable destination handset"}}
+	for _, c := range []struct {
+		name   string
+		status int
+	}{
+		{"interim status", signed(workflow.ID, sent)},
+		{"delivered", signed(workflow.ID, delivered)},
+		{"undelivered", signed(workflow.ID, undelivered)},
+	} {
+		if c.status != http.StatusNoContent {
+			t.Errorf("%s: expected 204, got %d", c.name, c.status)
+		}
+	}
+
+	logs, _ := database.GetLogsByWorkflowID(workflow.ID)
+	if len(logs) != 2 {
+		t.Fatalf("Expected the two final statuses logged, got %+v", logs)
+	}
+	byStatus := map[string]string{}
+	for _, log := range logs {
+		byStatus[log.Status] = log.Message
+		if log.Status == "failed" && (log.ErrorCode != connectors.ErrCodeDeliveryFailed || log.ErrorParams["provider_code"] != "30003") {
+			t.Errorf("Expected the failure coded delivery_failed with Twilio's code, got %+v", log)
+		}
+	}
+	if !strings.Contains(byStatus["success"], "SM1") || !strings.Contains(byStatus["failed"], "Unreachable destination handset") {
+		t.Errorf("Unexpected log messages %v", byStatus)
+	}
+
+	for _, c := range []struct {
+		name, workflowID, signature string
+		status                      int
+	}{
+		{"bad signature", workflow.ID, "bm90IGEgc2lnbmF0dXJl", http.StatusForbidden},
+		{"signed for another URL", workflow.ID, connectors.TwilioSignature("secret", callbackURL+"/other", delivered), http.StatusForbidden},
+		{"callbacks not enabled", silent.ID, connectors.TwilioSignature("secret", callbackURL, delivered), http.StatusNotFound},
+		{"unknown workflow", "wf_missing", connectors.TwilioSignature("secret", callbackURL, delivered), http.StatusNotFound},
+	} {
+		if status := post(c.workflowID, delivered, c.signature); status != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, status)
+		}
+	}
+	if logs, _ := database.GetLogsByWorkflowID(workflow.ID); len(logs) != 2 {
+		t.Errorf("Expected refused callbacks not logged, got %d entries", len(logs))
+	}
+}
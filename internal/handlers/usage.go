@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/costs"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// UsageHandler handles usage and spend reporting HTTP requests
+// PRODUCTION: Uses Store interface for testability
+type UsageHandler struct {
+	store     db.Store // Interface, not concrete type!
+	costTable *costs.Table
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(store db.Store, costTable *costs.Table) *UsageHandler {
+	return &UsageHandler{store: store, costTable: costTable}
+}
+
+// CostReport is a tenant's estimated connector spend for one month
+type CostReport struct {
+	TenantID  string                `json:"tenant_id"`
+	Month     string                `json:"month"`
+	Currency  string                `json:"currency"`
+	TotalCost float64               `json:"total_cost"`
+	DelayMS   int64                 `json:"delay_ms"` // Total time spent in testing connector delays
+	Workflows []models.WorkflowCost `json:"workflows"`
+}
+
+// GetCosts returns the tenant's per-workflow spend for a month (?month=YYYY-MM, default: current)
+func (h *UsageHandler) GetCosts(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = costs.Month(time.Now())
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		http.Error(w, "Invalid month. Use YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	workflowCosts, err := h.store.GetWorkflowCosts(tenantID, month)
+	if err != nil {
+		http.Error(w, "Failed to fetch usage costs", http.StatusInternalServerError)
+		return
+	}
+
+	report := CostReport{
+		TenantID:  tenantID,
+		Month:     month,
+		Currency:  h.costTable.Currency,
+		Workflows: []models.WorkflowCost{},
+	}
+	for _, c := range workflowCosts {
+		report.TotalCost += c.Cost
+		report.DelayMS += c.DelayMS
+		report.Workflows = append(report.Workflows, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
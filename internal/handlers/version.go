@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/version"
+)
+
+// VersionHandler returns the build metadata, instance ID and role of the serving process
+// Public, like /health, so deploy checks can confirm which build is live and where
+func VersionHandler(role string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info := version.Get()
+		info.Role = role
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// Webhook authentication headers and limits
+const (
+	webhookSignatureHeader    = "X-Webhook-Signature"
+	webhookTokenHeader        = "X-Webhook-Token" // Alternative to Authorization: Bearer
+	webhookSignatureTolerance = 5 * time.Minute   // Older (or future) timestamps are replays
+	minWebhookSecretLength    = 16                // HMAC secrets and static tokens
+)
+
+// Reasons a webhook request failed authentication
+// Logged and captured for the workflow owner, never returned to the caller
+const (
+	webhookReasonIPNotAllowed      = "ip_not_allowed"
+	webhookReasonSignatureMissing  = "signature_missing"
+	webhookReasonSignatureStale    = "signature_stale"
+	webhookReasonSignatureMismatch = "signature_mismatch"
+	webhookReasonTokenMissing      = "token_missing"
+	webhookReasonTokenMismatch     = "token_mismatch"
+	webhookReasonBasicMissing      = "basic_missing"
+	webhookReasonBasicMismatch     = "basic_mismatch"
+)
+
+// webhookSecurityService is the credential service a workflow's webhook security is stored under
+func webhookSecurityService(workflowID string) string {
+	return "webhook_security:" + workflowID
+}
+
+// loadWebhookSecurity returns the webhook security of a workflow, or nil when none was ever set
+func loadWebhookSecurity(s db.Store, workflow *models.Workflow) (*models.WebhookSecurity, error) {
+	cred, err := s.GetCredentialForTenant(workflow.TenantID, workflow.UserID, webhookSecurityService(workflow.ID))
+	if store.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var security models.WebhookSecurity
+	if err := json.Unmarshal([]byte(cred.DecryptedKey), &security); err != nil {
+		return nil, fmt.Errorf("invalid webhook security: %w", err)
+	}
+	return &security, nil
+}
+
+// webhookAuthFailure is why a request failed authentication and which status it gets
+type webhookAuthFailure struct {
+	status  int    // 401 for missing or wrong credentials, 403 for a client IP outside the allowlist
+	reason  string // One of the webhookReason codes
+	verdict string // Capture verdict
+}
+
+// authenticateWebhook checks a request against every scheme the workflow requires
+// The IP allowlist is checked first so callers outside it learn nothing about the other schemes
+func authenticateWebhook(r *http.Request, body []byte, security *models.WebhookSecurity, now time.Time) *webhookAuthFailure {
+	if hasScheme(security.Schemes, models.WebhookAuthIPAllowlist) {
+		allowed, _ := parseIPNets(security.IPAllowlist)
+		proxies, _ := parseIPNets(security.TrustedProxies)
+		ip := webhookClientIP(r, proxies)
+		if ip == nil || !containsIP(allowed, ip) {
+			return &webhookAuthFailure{http.StatusForbidden, webhookReasonIPNotAllowed, models.WebhookVerdictRejectedAuth}
+		}
+	}
+
+	if hasScheme(security.Schemes, models.WebhookAuthHMAC) {
+		if reason := checkWebhookSignature(r.Header.Get(webhookSignatureHeader), body, security.HMACSecret, now); reason != "" {
+			return &webhookAuthFailure{http.StatusUnauthorized, reason, models.WebhookVerdictRejectedSignature}
+		}
+	}
+
+	if hasScheme(security.Schemes, models.WebhookAuthToken) {
+		// X-Webhook-Token frees the Authorization header for Basic auth when both are required
+		token := r.Header.Get(webhookTokenHeader)
+		if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); token == "" && found {
+			token = bearer
+		}
+		if token == "" {
+			return &webhookAuthFailure{http.StatusUnauthorized, webhookReasonTokenMissing, models.WebhookVerdictRejectedAuth}
+		}
+		if !secretEqual(token, security.Token) {
+			return &webhookAuthFailure{http.StatusUnauthorized, webhookReasonTokenMismatch, models.WebhookVerdictRejectedAuth}
+		}
+	}
+
+	if hasScheme(security.Schemes, models.WebhookAuthBasic) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return &webhookAuthFailure{http.StatusUnauthorized, webhookReasonBasicMissing, models.WebhookVerdictRejectedAuth}
+		}
+		// Compare both halves so the time taken doesn't reveal which one was wrong
+		usernameOK := secretEqual(username, security.BasicUsername)
+		passwordOK := secretEqual(password, security.BasicPassword)
+		if !usernameOK || !passwordOK {
+			return &webhookAuthFailure{http.StatusUnauthorized, webhookReasonBasicMismatch, models.WebhookVerdictRejectedAuth}
+		}
+	}
+
+	return nil
+}
+
+// checkWebhookSignature verifies a "t=<unix>,v1=<hex>" signature header
+// Returns the failure reason, or "" when the signature is valid
+func checkWebhookSignature(header string, body []byte, secret string, now time.Time) string {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value) // Several during a secret rollover
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return webhookReasonSignatureMissing
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return webhookReasonSignatureMismatch
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > webhookSignatureTolerance || age < -webhookSignatureTolerance {
+		return webhookReasonSignatureStale
+	}
+
+	expected := SignWebhook(secret, timestamp, body)
+	for _, signature := range signatures {
+		if secretEqual(signature, expected) {
+			return ""
+		}
+	}
+	return webhookReasonSignatureMismatch
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>", the v1 value of X-Webhook-Signature
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// secretEqual compares a presented secret with the stored one in constant time
+func secretEqual(presented, stored string) bool {
+	if stored == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(stored)) == 1
+}
+
+// webhookClientIP returns the real client IP of a request
+// X-Forwarded-For is only believed when the connection comes from a trusted proxy; it is then
+// read right to left, skipping trusted proxies, because the left-most entries are whatever the
+// client sent. Returns nil when the address can't be determined
+func webhookClientIP(r *http.Request, trustedProxies []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(trustedProxies, ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			return nil // A trusted proxy wouldn't have written this
+		}
+		ip = hop
+		if !containsIP(trustedProxies, hop) {
+			return hop
+		}
+	}
+	return ip // Every hop was a trusted proxy: the left-most one is the client
+}
+
+// parseIPNets parses IPs and CIDRs; a bare IP matches only itself
+func parseIPNets(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// containsIP reports whether ip is in any of nets
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasScheme reports whether scheme is among schemes
+func hasScheme(schemes []string, scheme string) bool {
+	for _, s := range schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWebhookSecurity checks that every required scheme is known and fully configured
+func validateWebhookSecurity(security *models.WebhookSecurity) error {
+	seen := make(map[string]bool)
+	for _, scheme := range security.Schemes {
+		if seen[scheme] {
+			return fmt.Errorf("scheme %q listed twice", scheme)
+		}
+		seen[scheme] = true
+
+		switch scheme {
+		case models.WebhookAuthHMAC:
+			if len(security.HMACSecret) < minWebhookSecretLength {
+				return fmt.Errorf("hmac_secret must be at least %d characters", minWebhookSecretLength)
+			}
+		case models.WebhookAuthToken:
+			if len(security.Token) < minWebhookSecretLength {
+				return fmt.Errorf("token must be at least %d characters", minWebhookSecretLength)
+			}
+		case models.WebhookAuthBasic:
+			if security.BasicUsername == "" || security.BasicPassword == "" {
+				return fmt.Errorf("basic requires basic_username and basic_password")
+			}
+			if strings.Contains(security.BasicUsername, ":") {
+				return fmt.Errorf("basic_username can't contain ':'")
+			}
+		case models.WebhookAuthIPAllowlist:
+			if len(security.IPAllowlist) == 0 {
+				return fmt.Errorf("ip_allowlist requires at least one IP or CIDR")
+			}
+		default:
+			return fmt.Errorf("unknown scheme %q (use hmac, token, basic or ip_allowlist)", scheme)
+		}
+	}
+
+	if _, err := parseIPNets(security.IPAllowlist); err != nil {
+		return fmt.Errorf("ip_allowlist: %w", err)
+	}
+	if _, err := parseIPNets(security.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted_proxies: %w", err)
+	}
+	return nil
+}
+
+// WebhookSecurityResponse reports which schemes a workflow's webhook requires, without its secrets
+type WebhookSecurityResponse struct {
+	Schemes          []string `json:"schemes"` // Empty when the webhook is open
+	SignatureHeader  string   `json:"signature_header,omitempty"`
+	HMACSecretSet    bool     `json:"hmac_secret_set"`
+	TokenSet         bool     `json:"token_set"`
+	BasicUsername    string   `json:"basic_username,omitempty"`
+	BasicPasswordSet bool     `json:"basic_password_set"`
+	IPAllowlist      []string `json:"ip_allowlist"`
+	TrustedProxies   []string `json:"trusted_proxies"`
+}
+
+// webhookSecurityResponse describes security (nil for an open webhook)
+func webhookSecurityResponse(security *models.WebhookSecurity) WebhookSecurityResponse {
+	if security == nil {
+		security = &models.WebhookSecurity{}
+	}
+	resp := WebhookSecurityResponse{
+		Schemes:          security.Schemes,
+		HMACSecretSet:    security.HMACSecret != "",
+		TokenSet:         security.Token != "",
+		BasicUsername:    security.BasicUsername,
+		BasicPasswordSet: security.BasicPassword != "",
+		IPAllowlist:      security.IPAllowlist,
+		TrustedProxies:   security.TrustedProxies,
+	}
+	if hasScheme(security.Schemes, models.WebhookAuthHMAC) {
+		resp.SignatureHeader = webhookSignatureHeader
+	}
+	if resp.Schemes == nil {
+		resp.Schemes = []string{}
+	}
+	if resp.IPAllowlist == nil {
+		resp.IPAllowlist = []string{}
+	}
+	if resp.TrustedProxies == nil {
+		resp.TrustedProxies = []string{}
+	}
+	return resp
+}
+
+// GetWebhookSecurity reports which authentication schemes a workflow's webhook requires
+func (h *WorkflowsHandler) GetWebhookSecurity(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	security, err := loadWebhookSecurity(h.store, workflow)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to load webhook security")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhookSecurityResponse(security))
+}
+
+// UpdateWebhookSecurity replaces the authentication schemes of a workflow's webhook
+// Secrets left out of the request keep their current values; an empty scheme list opens the webhook
+func (h *WorkflowsHandler) UpdateWebhookSecurity(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if workflow.TriggerType != "webhook" {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "This workflow does not support webhook triggers")
+		return
+	}
+
+	var req models.WebhookSecurity
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	current, err := loadWebhookSecurity(h.store, workflow)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to load webhook security")
+		return
+	}
+	if current != nil {
+		if req.HMACSecret == "" {
+			req.HMACSecret = current.HMACSecret
+		}
+		if req.Token == "" {
+			req.Token = current.Token
+		}
+		if req.BasicPassword == "" && req.BasicUsername == current.BasicUsername {
+			req.BasicPassword = current.BasicPassword
+		}
+	}
+
+	if err := validateWebhookSecurity(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	data, _ := json.Marshal(req)
+	if _, err := h.store.ReplaceCredential(workflow.UserID, webhookSecurityService(workflow.ID), string(data)); err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to save webhook security")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhookSecurityResponse(&req))
+}
@@ -0,0 +1,252 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// deliverWebhook posts body to a workflow's webhook as if it came from remoteAddr
+func deliverWebhook(router http.Handler, workflowID, remoteAddr, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/webhooks/"+workflowID, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestWebhookSecuritySchemesRequiredTogether configures several schemes and checks each is
+// enforced, that failures don't echo their reason and that secrets are never reported back
+func TestWebhookSecuritySchemesRequiredTogether(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	router := server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	user, _ := database.CreateUser("secure-hooks@example.com", "hashed")
+	token := userToken(t, user.ID)
+	workflow, err := database.CreateWorkflow(user.ID, "Signed events", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	securityURL := srv.URL + "/api/workflows/" + workflow.ID + "/webhook/security"
+
+	if status := call(t, "PUT", securityURL, token, map[string]interface{}{"schemes": []string{"hmac"}, "hmac_secret": "short"}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected a short HMAC secret to be rejected, got %d", status)
+	}
+	if status := call(t, "PUT", securityURL, token, map[string]interface{}{"schemes": []string{"mtls"}}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown scheme to be rejected, got %d", status)
+	}
+
+	var configured handlers.WebhookSecurityResponse
+	status := call(t, "PUT", securityURL, token, map[string]interface{}{
+		"schemes":        []string{"hmac", "token", "basic"},
+		"hmac_secret":    "whsec_0123456789abcdef",
+		"token":          "static-token-0123456789",
+		"basic_username": "stripe",
+		"basic_password": "hunter2-hunter2",
+	}, &configured)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the schemes to be saved, got %d", status)
+	}
+
+	var reported handlers.WebhookSecurityResponse
+	if status := call(t, "GET", securityURL, token, nil, &reported); status != http.StatusOK {
+		t.Fatalf("Expected the security settings, got %d", status)
+	}
+	if strings.Join(reported.Schemes, ",") != "hmac,token,basic" || !reported.HMACSecretSet || !reported.TokenSet || !reported.BasicPasswordSet {
+		t.Errorf("Expected all three schemes active with their secrets set, got %+v", reported)
+	}
+	if reported.SignatureHeader != "X-Webhook-Signature" {
+		t.Errorf("Expected the signature header to be reported, got %q", reported.SignatureHeader)
+	}
+
+	// Changing the username without a new password must not keep the old password
+	if status := call(t, "PUT", securityURL, token, map[string]interface{}{
+		"schemes": []string{"hmac", "token", "basic"}, "basic_username": "github",
+	}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected a new username without a password to be rejected, got %d", status)
+	}
+
+	body := `{"type": "invoice.paid"}`
+	sign := func(at time.Time) string {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		return "t=" + timestamp + ",v1=" + handlers.SignWebhook("whsec_0123456789abcdef", timestamp, []byte(body))
+	}
+	basic := func(username, password string) string {
+		r, _ := http.NewRequest("POST", "/", nil)
+		r.SetBasicAuth(username, password)
+		return r.Header.Get("Authorization")
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"no credentials", nil, http.StatusUnauthorized},
+		{"wrong signature", map[string]string{
+			"X-Webhook-Signature": "t=" + strconv.FormatInt(time.Now().Unix(), 10) + ",v1=deadbeef",
+			"X-Webhook-Token":     "static-token-0123456789",
+			"Authorization":       basic("stripe", "hunter2-hunter2"),
+		}, http.StatusUnauthorized},
+		{"stale signature", map[string]string{
+			"X-Webhook-Signature": sign(time.Now().Add(-time.Hour)),
+			"X-Webhook-Token":     "static-token-0123456789",
+			"Authorization":       basic("stripe", "hunter2-hunter2"),
+		}, http.StatusUnauthorized},
+		{"missing token", map[string]string{
+			"X-Webhook-Signature": sign(time.Now()),
+			"Authorization":       basic("stripe", "hunter2-hunter2"),
+		}, http.StatusUnauthorized},
+		{"wrong password", map[string]string{
+			"X-Webhook-Signature": sign(time.Now()),
+			"X-Webhook-Token":     "static-token-0123456789",
+			"Authorization":       basic("stripe", "hunter3"),
+		}, http.StatusUnauthorized},
+		{"all schemes pass", map[string]string{
+			"X-Webhook-Signature": sign(time.Now()),
+			"X-Webhook-Token":     "static-token-0123456789",
+			"Authorization":       basic("stripe", "hunter2-hunter2"),
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := deliverWebhook(router, workflow.ID, "198.51.100.7:4242", body, tt.headers)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if rec.Code == http.StatusUnauthorized && strings.TrimSpace(rec.Body.String()) != "Unauthorized" {
+			t.Errorf("%s: expected the reason not to be echoed, got %q", tt.name, rec.Body.String())
+		}
+	}
+}
+
+// TestWebhookIPAllowlistProxySpoofing checks X-Forwarded-For is only believed from trusted
+// proxies, and that client-supplied entries in front of the proxy's own are ignored
+func TestWebhookIPAllowlistProxySpoofing(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	router := server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	user, _ := database.CreateUser("allowlist@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Partner events", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	if status := call(t, "PUT", srv.URL+"/api/workflows/"+workflow.ID+"/webhook/security", userToken(t, user.ID), map[string]interface{}{
+		"schemes":         []string{"ip_allowlist"},
+		"ip_allowlist":    []string{"203.0.113.0/24", "2001:db8::1"},
+		"trusted_proxies": []string{"10.0.0.1", "10.0.1.0/24"},
+	}, nil); status != http.StatusOK {
+		t.Fatalf("Expected the allowlist to be saved, got %d", status)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{"allowed client connecting directly", "203.0.113.5:5000", "", http.StatusOK},
+		{"allowed IPv6 client", "[2001:db8::1]:5000", "", http.StatusOK},
+		{"outside client connecting directly", "198.51.100.7:5000", "", http.StatusForbidden},
+		{"outside client forging the header directly", "198.51.100.7:5000", "203.0.113.5", http.StatusForbidden},
+		{"allowed client behind a trusted proxy", "10.0.0.1:5000", "203.0.113.5", http.StatusOK},
+		{"allowed client behind two trusted proxies", "10.0.0.1:5000", "203.0.113.5, 10.0.1.7", http.StatusOK},
+		{"forged entry in front of the proxy's own", "10.0.0.1:5000", "203.0.113.5, 198.51.100.7", http.StatusForbidden},
+		{"allowed client behind an untrusted proxy", "10.0.0.2:5000", "203.0.113.5", http.StatusForbidden},
+		{"trusted proxy with a garbage header", "10.0.0.1:5000", "not-an-ip", http.StatusForbidden},
+		{"trusted proxy itself", "10.0.0.1:5000", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		headers := map[string]string{}
+		if tt.forwarded != "" {
+			headers["X-Forwarded-For"] = tt.forwarded
+		}
+		rec := deliverWebhook(router, workflow.ID, tt.remoteAddr, `{}`, headers)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if rec.Code == http.StatusForbidden && strings.TrimSpace(rec.Body.String()) != "Forbidden" {
+			t.Errorf("%s: expected the reason not to be echoed, got %q", tt.name, rec.Body.String())
+		}
+	}
+}
+
+// TestWebhookIgnoresIdempotencyKey checks webhook responses aren't cached by
+// X-Idempotency-Key: a refused delivery doesn't refuse the provider's authenticated retry,
+// and a synchronous run's result isn't replayed to a caller without credentials
+func TestWebhookIgnoresIdempotencyKey(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	router := server.NewRouter(server.Config{
+		Store:       database,
+		Executor:    engine.NewExecutor(database, testLogger),
+		Logger:      testLogger,
+		Idempotency: middleware.NewIdempotencyManager(time.Hour, 100),
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	user, _ := database.CreateUser("keyed-hooks@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Keyed events", "webhook", "testing", `{"testing_response_json": "{\"secret\": \"payroll\"}"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	if status := call(t, "PUT", srv.URL+"/api/workflows/"+workflow.ID+"/webhook/security", userToken(t, user.ID), map[string]interface{}{
+		"schemes": []string{"token"}, "token": "static-token-0123456789",
+	}, nil); status != http.StatusOK {
+		t.Fatalf("Expected the token scheme to be saved, got %d", status)
+	}
+	authenticated := map[string]string{"X-Webhook-Token": "static-token-0123456789", "X-Idempotency-Key": "retry-1"}
+
+	// A refused delivery isn't cached for the retry with the right token
+	if rec := deliverWebhook(router, workflow.ID, "198.51.100.7:4242", `{}`, map[string]string{"X-Idempotency-Key": "retry-1"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a delivery without the token to be refused, got %d", rec.Code)
+	}
+	if rec := deliverWebhook(router, workflow.ID, "198.51.100.7:4242", `{}`, authenticated); rec.Code != http.StatusOK || rec.Header().Get("X-Idempotency-Replay") != "" {
+		t.Errorf("Expected the authenticated retry to run, got %d %v", rec.Code, rec.Header())
+	}
+
+	// A synchronous result isn't replayed to a caller reusing the key without the token
+	req := httptest.NewRequest("POST", "/api/webhooks/"+workflow.ID+"?wait=true", strings.NewReader(`{}`))
+	for name, value := range map[string]string{"X-Webhook-Token": "static-token-0123456789", "X-Idempotency-Key": "sync-1"} {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "payroll") {
+		t.Fatalf("Expected the run's result, got %d: %s", rec.Code, rec.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/api/webhooks/"+workflow.ID+"?wait=true", strings.NewReader(`{}`))
+	req.Header.Set("X-Idempotency-Key", "sync-1")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), "payroll") {
+		t.Errorf("Expected a caller without the token to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
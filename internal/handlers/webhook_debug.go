@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// Webhook request capture limits
+const (
+	defaultDebugRequestLimit = 10             // Captured requests kept per workflow
+	maxDebugRequestLimit     = 50             // Largest limit a workflow may ask for
+	debugRequestsWindow      = 24 * time.Hour // Capture turns itself off after this
+	maxCapturedBodyBytes     = 64 * 1024      // Longer bodies are cut (and can't be replayed)
+)
+
+// sensitiveHeaderParts mark headers whose values are never stored
+var sensitiveHeaderParts = []string{"authorization", "cookie", "token", "secret", "signature", "password", "api-key", "apikey"}
+
+// DebugRequestsSettings turns webhook request capture on or off
+type DebugRequestsSettings struct {
+	DebugRequests bool `json:"debug_requests"`
+	Limit         int  `json:"limit,omitempty"` // Default 10, at most 50
+}
+
+// DebugRequestsResponse is a workflow's capture state and its captured requests, newest first
+type DebugRequestsResponse struct {
+	Enabled  bool                    `json:"enabled"`
+	Until    *time.Time              `json:"until,omitempty"`
+	Limit    int                     `json:"limit"`
+	Requests []models.WebhookRequest `json:"requests"`
+}
+
+// GetDebugRequests returns the raw webhook requests captured for a workflow
+func (h *WorkflowsHandler) GetDebugRequests(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	requests, err := h.store.ListWebhookRequests(workflow.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to list captured requests")
+		return
+	}
+	if requests == nil {
+		requests = []models.WebhookRequest{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debugRequestsResponse(workflow, requests))
+}
+
+// UpdateDebugRequests turns request capture on for the next 24 hours, or off
+// Requests captured earlier stay until they are replaced or purged by retention
+func (h *WorkflowsHandler) UpdateDebugRequests(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req DebugRequestsSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultDebugRequestLimit
+	}
+	if req.Limit < 1 || req.Limit > maxDebugRequestLimit {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "limit must be between 1 and 50")
+		return
+	}
+
+	var until *time.Time
+	if req.DebugRequests {
+		at := time.Now().Add(debugRequestsWindow)
+		until = &at
+	}
+	if err := h.store.SetWorkflowDebugRequests(workflow.ID, until, req.Limit); err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to update request capture")
+		return
+	}
+	workflow.DebugRequestsUntil, workflow.DebugRequestsLimit = until, req.Limit
+
+	requests, err := h.store.ListWebhookRequests(workflow.ID)
+	if err != nil || requests == nil {
+		requests = []models.WebhookRequest{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debugRequestsResponse(workflow, requests))
+}
+
+// ReplayDebugRequest dry-runs the workflow with a captured request's (masked) body
+func (h *WorkflowsHandler) ReplayDebugRequest(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	workflow, ok := h.ownedWorkflow(w, r, vars["id"])
+	if !ok {
+		return
+	}
+
+	request, err := h.store.GetWebhookRequest(workflow.ID, vars["requestId"])
+	if err != nil {
+		writeStoreError(w, err, "Captured request not found")
+		return
+	}
+	if request.BodyTruncated {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Captured body was truncated and can't be replayed")
+		return
+	}
+
+	result := h.executor.DryRunWithPayload(*workflow, request.Body, userID, tenantID)
+	h.writeDryRun(w, r, result, engine.TemplateWarnings(workflow.ConfigJSON, request.Body))
+}
+
+// debugRequestsResponse describes a workflow's capture state
+func debugRequestsResponse(workflow *models.Workflow, requests []models.WebhookRequest) DebugRequestsResponse {
+	limit := workflow.DebugRequestsLimit
+	if limit == 0 {
+		limit = defaultDebugRequestLimit
+	}
+	return DebugRequestsResponse{
+		Enabled:  workflow.DebugRequestsEnabled(time.Now()),
+		Until:    workflow.DebugRequestsUntil,
+		Limit:    limit,
+		Requests: requests,
+	}
+}
+
+// captureWebhookRequest stores a masked copy of an inbound request while the workflow has
+// capture enabled; the buffer keeps only the workflow's most recent requests
+func (h *WebhookHandler) captureWebhookRequest(workflow *models.Workflow, r *http.Request, body []byte, verdict, detail string) {
+	if !workflow.DebugRequestsEnabled(time.Now()) {
+		return
+	}
+
+	masked := engine.MaskPayload(string(body))
+	truncated := len(masked) > maxCapturedBodyBytes
+	if truncated {
+		masked = strings.ToValidUTF8(masked[:maxCapturedBodyBytes], "")
+	}
+
+	keep := workflow.DebugRequestsLimit
+	if keep == 0 {
+		keep = defaultDebugRequestLimit
+	}
+	err := h.store.CaptureWebhookRequest(&models.WebhookRequest{
+		WorkflowID:    workflow.ID,
+		Method:        r.Method,
+		Headers:       maskHeaders(r.Header),
+		Body:          masked,
+		BodyTruncated: truncated,
+		Verdict:       verdict,
+		Detail:        detail,
+	}, keep)
+	if err != nil {
+		h.log.Warn("Failed to capture webhook request", map[string]interface{}{
+			"workflow_id": workflow.ID,
+			"error":       err.Error(),
+		})
+	}
+}
+
+// maskHeaders flattens request headers, hiding credentials and signatures entirely
+// and pattern-masking everything else
+func maskHeaders(header http.Header) map[string]string {
+	masked := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.ToValidUTF8(strings.Join(values, ", "), "")
+		lower := strings.ToLower(name)
+		for _, part := range sensitiveHeaderParts {
+			if strings.Contains(lower, part) {
+				value = "***REDACTED***"
+				break
+			}
+		}
+		masked[name] = utils.Mask(value)
+	}
+	return masked
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// webhookPayloadsKept is how many recent payloads are kept per webhook workflow
+const webhookPayloadsKept = 5
+
+// RenderTemplateRequest renders a template against one of the workflow's recent payloads
+type RenderTemplateRequest struct {
+	Template     string `json:"template"`
+	PayloadIndex int    `json:"payload_index"` // 0 is the newest payload
+}
+
+// RenderTemplateResponse is the rendered output, or why it couldn't be rendered,
+// with the same findings as /api/templates/lint
+type RenderTemplateResponse struct {
+	Output   string              `json:"output"`
+	Error    string              `json:"error,omitempty"`
+	Findings []utils.LintFinding `json:"findings"`
+}
+
+// GetWebhookPayloads returns the last payloads that triggered a webhook workflow, newest first
+func (h *WorkflowsHandler) GetWebhookPayloads(w http.ResponseWriter, r *http.Request) {
+	payloads, ok := h.webhookPayloads(w, r)
+	if !ok {
+		return
+	}
+	if payloads == nil {
+		payloads = []models.WebhookPayload{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payloads)
+}
+
+// RenderTemplate renders a template against one of the workflow's recent payloads exactly
+// as a run would, so templates can be iterated on without triggering the workflow
+// A template the executor would fail on answers 200 with the error, like a dry run
+func (h *WorkflowsHandler) RenderTemplate(w http.ResponseWriter, r *http.Request) {
+	var req RenderTemplateRequest
+	if err := utils.DecodeJSONStrict(w, r, &req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Template) == "" {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "template is required")
+		return
+	}
+
+	payloads, ok := h.webhookPayloads(w, r)
+	if !ok {
+		return
+	}
+	if req.PayloadIndex < 0 || req.PayloadIndex >= len(payloads) {
+		SendError(w, http.StatusNotFound, "Payload not found")
+		return
+	}
+	payload := string(payloads[req.PayloadIndex].Payload)
+
+	te := utils.NewTemplateEngine()
+	var response RenderTemplateResponse
+	output, err := te.RenderStrict(req.Template, payload)
+	if err != nil {
+		response.Error = err.Error()
+	}
+	response.Output = output
+	response.Findings, _ = te.Lint(req.Template, utils.LintOptions{Payload: payload})
+	if response.Findings == nil {
+		response.Findings = []utils.LintFinding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// webhookPayloads loads a tenant workflow's recent payloads with the tenant's masking policy applied
+// Writes the error response and returns false when it can't
+func (h *WorkflowsHandler) webhookPayloads(w http.ResponseWriter, r *http.Request) ([]models.WebhookPayload, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return nil, false
+	}
+
+	payloads, err := h.store.ListWebhookPayloads(workflow.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to list payloads")
+		return nil, false
+	}
+	settings, err := h.store.GetTenantSettings(tenantID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to fetch tenant settings")
+		return nil, false
+	}
+	for i := range payloads {
+		payloads[i].Payload = json.RawMessage(engine.MaskPayloadForTenant(string(payloads[i].Payload), settings.MaskedFields))
+	}
+	return payloads, true
+}
+
+// saveWebhookPayload keeps a masked copy of a payload that triggered the workflow
+// Only JSON objects are kept, since templates render against nothing else
+func (h *WebhookHandler) saveWebhookPayload(workflow *models.Workflow, body []byte) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil || data == nil {
+		return
+	}
+	masked, err := json.Marshal(utils.MaskMap(data))
+	if err != nil {
+		return
+	}
+
+	err = h.store.SaveWebhookPayload(&models.WebhookPayload{
+		WorkflowID: workflow.ID,
+		Payload:    masked,
+	}, webhookPayloadsKept)
+	if err != nil {
+		h.log.Warn("Failed to save webhook payload", map[string]interface{}{
+			"workflow_id": workflow.ID,
+			"error":       err.Error(),
+		})
+	}
+}
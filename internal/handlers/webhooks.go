@@ -2,26 +2,64 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/alexmacdonald/simple-ipass/internal/db"
 	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
 	"github.com/gorilla/mux"
+	"github.com/tidwall/gjson"
 )
 
+// defaultWebhookDedupeTTL is how long provider event IDs are remembered
+// Stripe retries for up to 3 days, GitHub redeliveries are manual but usually same-day
+const defaultWebhookDedupeTTL = 72 * time.Hour
+
+// DefaultWebhookWaitTimeout is how long a synchronous webhook waits for its run,
+// inside the API server's 30 second write timeout
+const DefaultWebhookWaitTimeout = 25 * time.Second
+
+// WebhookResultResponse answers a synchronous webhook with the run's final result
+type WebhookResultResponse struct {
+	Status    string                 `json:"status"` // success, failed, cancelled or timed_out
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Duration  string                 `json:"duration"`
+	ErrorCode string                 `json:"error_code,omitempty"`
+}
+
 // WebhookHandler handles webhook-related HTTP requests  
 // PRODUCTION: Uses Store interface for testability
 type WebhookHandler struct {
-	store    db.Store // Interface, not concrete type!
-	executor *engine.Executor
+	store       db.Store // Interface, not concrete type!
+	executor    *engine.Executor
+	log         *logger.Logger
+	waitTimeout time.Duration
 }
 
 // NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(store db.Store, executor *engine.Executor) *WebhookHandler {
-	return &WebhookHandler{store: store, executor: executor}
+func NewWebhookHandler(store db.Store, executor *engine.Executor, log *logger.Logger) *WebhookHandler {
+	return &WebhookHandler{store: store, executor: executor, log: log, waitTimeout: DefaultWebhookWaitTimeout}
+}
+
+// SetWaitTimeout changes how long a synchronous webhook waits for its run
+func (h *WebhookHandler) SetWaitTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		h.waitTimeout = timeout
+	}
 }
 
 // TriggerWebhook handles incoming webhook requests
+// The workflow runs in the background and the caller gets an acknowledgement, unless
+// ?wait=true or the workflow's synchronous setting asks for the run's result instead
 func (h *WebhookHandler) TriggerWebhook(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	workflowID := vars["id"]
@@ -29,24 +67,149 @@ func (h *WebhookHandler) TriggerWebhook(w http.ResponseWriter, r *http.Request)
 	// Lookup the workflow
 	workflow, err := h.store.GetWorkflowByID(workflowID)
 	if err != nil {
-		http.Error(w, "Workflow not found", http.StatusNotFound)
+		writeWorkflowError(w, err)
+		return
+	}
+	if foreignDomain(h.store, r, workflow) {
+		writeWorkflowError(w, store.ErrNotFound)
+		return
+	}
+
+	// Read the body first so rejected requests can be captured for debugging too
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, utils.MaxRequestBodySize))
+	if err != nil {
+		h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictRejectedValidation, "Request body too large")
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
+	// Authenticate before anything else is revealed about the workflow
+	// Callers only see 401/403; the reason is logged and captured for the owner
+	security, err := loadWebhookSecurity(h.store, workflow)
+	if err != nil {
+		h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictError, "Failed to load webhook security")
+		http.Error(w, "Failed to load webhook security", http.StatusInternalServerError)
+		return
+	}
+	if security != nil {
+		if failure := authenticateWebhook(r, body, security, time.Now()); failure != nil {
+			h.captureWebhookRequest(workflow, r, body, failure.verdict, "Authentication failed: "+failure.reason)
+			h.log.WorkflowLog(
+				logger.LevelWarn,
+				"Webhook request rejected",
+				workflow.ID,
+				workflow.UserID,
+				workflow.TenantID,
+				map[string]interface{}{
+					"reason":      failure.reason,
+					"remote_addr": r.RemoteAddr,
+				},
+			)
+			http.Error(w, http.StatusText(failure.status), failure.status)
+			return
+		}
+	}
+
 	// Check if workflow is active
 	if !workflow.IsActive {
+		h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictRejectedValidation, "Workflow is not active")
 		http.Error(w, "Workflow is not active", http.StatusBadRequest)
 		return
 	}
+	if now := time.Now(); workflow.PausedAt(now) {
+		resume := workflow.PausedUntil.UTC().Format(time.RFC3339)
+		h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictRejectedValidation, "Workflow is paused until "+resume)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(workflow.PausedUntil.Sub(now).Seconds()))))
+		http.Error(w, "Workflow is paused until "+resume, http.StatusLocked)
+		return
+	}
 
 	// Check if trigger type is webhook
 	if workflow.TriggerType != "webhook" {
+		h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictRejectedValidation, "Workflow does not support webhook triggers")
 		http.Error(w, "This workflow does not support webhook triggers", http.StatusBadRequest)
 		return
 	}
 
-	// Execute the workflow asynchronously
-	h.executor.ExecuteWorkflow(*workflow)
+	// The inbound body drives template rendering and is kept (masked) in the execution trace
+	// It is passed with this execution only; the workflow itself is never modified
+	payload := string(body)
+
+	var config models.WorkflowConfig
+	json.Unmarshal([]byte(workflow.ConfigJSON), &config)
+	wait := config.Synchronous
+	if value := r.URL.Query().Get("wait"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictRejectedValidation, "Invalid wait parameter")
+			http.Error(w, "wait must be true or false", http.StatusBadRequest)
+			return
+		}
+		wait = parsed
+	}
+
+	// Provider redeliveries carry the same event ID: run the workflow only once per ID
+	var eventID string
+	if config.WebhookEventIDPath != "" || config.WebhookEventIDHeader != "" {
+		if eventID = webhookEventID(r, body, config); eventID != "" {
+			ttl := defaultWebhookDedupeTTL
+			if config.WebhookDedupeTTL > 0 {
+				ttl = time.Duration(config.WebhookDedupeTTL) * time.Hour
+			}
+
+			now := time.Now()
+			isNew, err := h.store.MarkWebhookEventSeen(workflow.ID, eventID, now, now.Add(ttl))
+			if err != nil {
+				h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictError, "Failed to record webhook event")
+				http.Error(w, "Failed to record webhook event", http.StatusInternalServerError)
+				return
+			}
+			if !isNew {
+				h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictDuplicate, "Event "+eventID+" already processed")
+				h.log.WorkflowLog(
+					logger.LevelDebug,
+					"Duplicate webhook event ignored",
+					workflow.ID,
+					workflow.UserID,
+					workflow.TenantID,
+					map[string]interface{}{
+						"event_id": eventID,
+					},
+				)
+
+				// 200 so the provider stops redelivering
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{
+					"status":   "duplicate_ignored",
+					"message":  "Event already processed",
+					"event_id": eventID,
+				})
+				return
+			}
+		}
+	}
+
+	if wait {
+		h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictExecuted, "")
+		h.saveWebhookPayload(workflow, body)
+		h.respondWithResult(w, r, workflow, payload)
+		return
+	}
+
+	// Execute the workflow asynchronously; a run the pool drops is dead-lettered, unless
+	// the event's ID was recorded: then the provider is asked to redeliver it instead
+	requestID := middleware.GetRequestIDFromContext(r.Context())
+	if eventID == "" {
+		h.executor.ExecuteWorkflowForRequest(*workflow, payload, requestID)
+	} else if err := h.executor.TryExecuteWorkflowForRequest(*workflow, payload, requestID); err != nil {
+		h.forgetWebhookEvent(workflow, eventID)
+		h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictError, "Workflow not queued: "+err.Error())
+		http.Error(w, "Workflow could not be queued, retry later", http.StatusServiceUnavailable)
+		return
+	}
+	h.captureWebhookRequest(workflow, r, body, models.WebhookVerdictExecuted, "")
+	h.saveWebhookPayload(workflow, body)
 
 	// Return immediate response
 	w.Header().Set("Content-Type", "application/json")
@@ -57,3 +220,58 @@ func (h *WebhookHandler) TriggerWebhook(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// forgetWebhookEvent drops a recorded event ID whose run was never queued, so that the
+// provider's redelivery isn't ignored as a duplicate
+func (h *WebhookHandler) forgetWebhookEvent(workflow *models.Workflow, eventID string) {
+	if err := h.store.ForgetWebhookEvent(workflow.ID, eventID); err != nil {
+		h.log.WorkflowLog(
+			logger.LevelError,
+			"Failed to forget webhook event; its redelivery will be ignored",
+			workflow.ID,
+			workflow.UserID,
+			workflow.TenantID,
+			map[string]interface{}{
+				"event_id": eventID,
+				"error":    err.Error(),
+			},
+		)
+	}
+}
+
+// respondWithResult runs the workflow in the request and answers with its result
+// A run still going when the wait timeout expires is stopped and answered with 504
+func (h *WebhookHandler) respondWithResult(w http.ResponseWriter, r *http.Request, workflow *models.Workflow, payload string) {
+	result, timedOut := h.executor.ExecuteWorkflowSync(r.Context(), *workflow, payload, middleware.GetRequestIDFromContext(r.Context()), h.waitTimeout)
+	response := WebhookResultResponse{
+		Status:    result.Status,
+		Message:   result.Message,
+		Data:      result.Data,
+		Duration:  result.Duration,
+		ErrorCode: result.ErrorCode,
+	}
+	status := http.StatusOK
+	if timedOut {
+		response.Status = "timed_out"
+		status = http.StatusGatewayTimeout
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// webhookEventID extracts the provider's event ID from the configured header or JSON path
+// The header wins when both are configured; returns "" if neither is present
+func webhookEventID(r *http.Request, body []byte, config models.WorkflowConfig) string {
+	if config.WebhookEventIDHeader != "" {
+		if id := r.Header.Get(config.WebhookEventIDHeader); id != "" {
+			return id
+		}
+	}
+	if config.WebhookEventIDPath != "" && gjson.ValidBytes(body) {
+		if id := gjson.GetBytes(body, config.WebhookEventIDPath); id.Exists() {
+			return id.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,476 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/gorilla/mux"
+)
+
+// TestWebhookDedupeConcurrentDeliveries races redeliveries of one event and
+// expects exactly one to trigger the workflow
+func TestWebhookDedupeConcurrentDeliveries(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+
+	user, err := database.CreateUser("hooks@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	workflow, err := database.CreateWorkflow(user.ID, "Stripe events", "webhook", "testing",
+		`{"webhook_event_id_path": "id", "webhook_event_id_header": "X-GitHub-Delivery"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/webhooks/{id}", handlers.NewWebhookHandler(database, executor, testLogger).TriggerWebhook).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	deliver := func(body string, header string) string {
+		req, _ := http.NewRequest("POST", server.URL+"/api/webhooks/"+workflow.ID, strings.NewReader(body))
+		if header != "" {
+			req.Header.Set("X-GitHub-Delivery", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("Delivery failed: %v", err)
+			return ""
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected 200, got %d", resp.StatusCode)
+		}
+		var out map[string]string
+		json.NewDecoder(resp.Body).Decode(&out)
+		return out["status"]
+	}
+
+	const deliveries = 20
+	statuses := make(chan string, deliveries)
+	var wg sync.WaitGroup
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- deliver(`{"id": "evt_1NqX", "type": "invoice.paid"}`, "")
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[string]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts["triggered"] != 1 || counts["duplicate_ignored"] != deliveries-1 {
+		t.Errorf("Expected 1 triggered and %d duplicate_ignored, got %v", deliveries-1, counts)
+	}
+
+	// A different event, and the header taking precedence over the body
+	if status := deliver(`{"id": "evt_2"}`, ""); status != "triggered" {
+		t.Errorf("Expected new event to trigger, got %q", status)
+	}
+	if status := deliver(`{"id": "evt_1NqX"}`, "delivery-abc"); status != "triggered" {
+		t.Errorf("Expected header event ID to be used, got %q", status)
+	}
+	if status := deliver(`{}`, "delivery-abc"); status != "duplicate_ignored" {
+		t.Errorf("Expected redelivered header ID to be ignored, got %q", status)
+	}
+}
+
+// TestWebhookEventForgottenWhenNotQueued answers 503 when the worker pool can't take the
+// run, and forgets the event ID so the provider's redelivery runs it
+func TestWebhookEventForgottenWhenNotQueued(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	user, _ := database.CreateUser("redeliver@example.com", "hashed")
+	workflow, _ := database.CreateWorkflow(user.ID, "Stripe events", "webhook", "testing", `{"webhook_event_id_path": "id"}`)
+
+	deliver := func(executor *engine.Executor) int {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/webhooks/{id}", handlers.NewWebhookHandler(database, executor, testLogger).TriggerWebhook).Methods("POST")
+		server := httptest.NewServer(router)
+		defer server.Close()
+		resp, err := http.Post(server.URL+"/api/webhooks/"+workflow.ID, "application/json", strings.NewReader(`{"id": "evt_retry"}`))
+		if err != nil {
+			t.Fatalf("Delivery failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	stopped := engine.NewWorkerPool(1, testLogger)
+	stopped.Shutdown(context.Background())
+	if status := deliver(engine.NewExecutorWithPool(database, testLogger, stopped)); status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 while the pool takes no jobs, got %d", status)
+	}
+	if letters, _ := database.ListDeadLetters(user.ID); len(letters) != 0 {
+		t.Errorf("Expected the provider to redeliver rather than a dead letter, got %d", len(letters))
+	}
+
+	executor := engine.NewExecutor(database, testLogger)
+	if status := deliver(executor); status != http.StatusOK {
+		t.Fatalf("Expected the redelivery to be accepted, got %d", status)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if logs, _ := database.GetLogsByWorkflowID(workflow.ID); len(logs) == 1 {
+			return
+		}
+	}
+	t.Error("Expected the redelivered event to run")
+}
+
+// TestWebhookEventExpiry verifies expired IDs are accepted again and pruned
+func TestWebhookEventExpiry(t *testing.T) {
+	database := dbtest.New(t)
+
+	user, err := database.CreateUser("expiry@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	workflow, err := database.CreateWorkflow(user.ID, "Expiring", "webhook", "testing", `{}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	now := time.Now()
+	if isNew, _ := database.MarkWebhookEventSeen(workflow.ID, "evt", now, now.Add(time.Hour)); !isNew {
+		t.Fatal("Expected first delivery to be new")
+	}
+	if isNew, _ := database.MarkWebhookEventSeen(workflow.ID, "evt", now.Add(time.Minute), now.Add(time.Hour)); isNew {
+		t.Error("Expected delivery within TTL to be a duplicate")
+	}
+	if isNew, _ := database.MarkWebhookEventSeen(workflow.ID, "evt", now.Add(2*time.Hour), now.Add(3*time.Hour)); !isNew {
+		t.Error("Expected delivery after TTL to be new again")
+	}
+
+	pruned, err := database.PruneWebhookEvents(now.Add(4 * time.Hour))
+	if err != nil {
+		t.Fatalf("PruneWebhookEvents failed: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected 1 pruned event, got %d", pruned)
+	}
+}
+
+// TestWebhookConcurrentTriggersKeepTheirPayloads races triggers of one workflow with
+// different payloads and expects every message to be rendered from its own payload
+func TestWebhookConcurrentTriggersKeepTheirPayloads(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+
+	const triggers = 20
+	messages := make(chan string, triggers)
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		messages <- body.Text
+	}))
+	defer slack.Close()
+
+	user, err := database.CreateUser("orders@example.com", "hashed")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := database.CreateCredential(user.ID, "slack", slack.URL); err != nil {
+		t.Fatalf("Failed to create credential: %v", err)
+	}
+	// allow_concurrent: without it, triggers arriving mid-run would be skipped
+	workflow, err := database.CreateWorkflow(user.ID, "New orders", "webhook", "slack_message",
+		`{"slack_message": "Order {{order.id}}", "allow_concurrent": true}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/webhooks/{id}", handlers.NewWebhookHandler(database, executor, testLogger).TriggerWebhook).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < triggers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"order": {"id": "%d"}}`, i)
+			resp, err := http.Post(server.URL+"/api/webhooks/"+workflow.ID, "application/json", strings.NewReader(body))
+			if err != nil {
+				t.Errorf("Trigger failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected 200, got %d", resp.StatusCode)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]int)
+	timeout := time.After(10 * time.Second)
+	for len(seen) < triggers {
+		select {
+		case text := <-messages:
+			seen[text]++
+		case <-timeout:
+			t.Fatalf("Expected %d messages, got %v", triggers, seen)
+		}
+	}
+	for i := 0; i < triggers; i++ {
+		if want := fmt.Sprintf("Order %d", i); seen[want] != 1 {
+			t.Errorf("Expected %q exactly once, got %v", want, seen)
+		}
+	}
+
+	// The stored workflow never carries a payload
+	stored, err := database.GetWorkflowByID(workflow.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload workflow: %v", err)
+	}
+	if stored.TriggerPayload != "" {
+		t.Errorf("Expected the workflow to be left untouched, got payload %q", stored.TriggerPayload)
+	}
+}
+
+// TestWebhookDebugRequestCapture enables request capture, checks verdicts, masking and
+// the ring buffer, replays a captured request and purges them through retention
+func TestWebhookDebugRequestCapture(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+
+	user, _ := database.CreateUser("debug@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Orders", "webhook", "testing", `{"webhook_event_id_path": "id"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+	token := userToken(t, user.ID)
+	debugURL := srv.URL + "/api/workflows/" + workflow.ID + "/debug/requests"
+
+	deliver := func(body string) {
+		req, _ := http.NewRequest("POST", srv.URL+"/api/webhooks/"+workflow.ID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Hub-Signature-256", "sha256=abcdef")
+		req.Header.Set("Authorization", "Bearer provider-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Delivery failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Nothing is captured until debugging is turned on
+	deliver(`{"id": "evt_0"}`)
+	var state handlers.DebugRequestsResponse
+	if status := call(t, "PUT", debugURL, token, map[string]interface{}{"debug_requests": true, "limit": 3}, &state); status != http.StatusOK {
+		t.Fatalf("Expected capture to be enabled, got %d", status)
+	}
+	if !state.Enabled || state.Until == nil || time.Until(*state.Until) > 24*time.Hour || len(state.Requests) != 0 {
+		t.Fatalf("Expected capture on for 24h with nothing captured yet, got %+v", state)
+	}
+
+	deliver(`{"id": "evt_1", "password": "hunter2-secret"}`)
+	deliver(`{"id": "evt_1", "password": "hunter2-secret"}`)
+	database.UpdateWorkflowActive(workflow.ID, false)
+	deliver(`{"id": "evt_2"}`)
+	database.UpdateWorkflowActive(workflow.ID, true)
+	deliver(`{"id": "evt_3", "order": {"id": 42}}`)
+
+	// The ring buffer keeps the newest 3
+	if status := call(t, "GET", debugURL, token, nil, &state); status != http.StatusOK {
+		t.Fatalf("Expected captured requests, got %d", status)
+	}
+	var verdicts []string
+	for _, request := range state.Requests {
+		verdicts = append(verdicts, request.Verdict)
+	}
+	want := []string{models.WebhookVerdictExecuted, models.WebhookVerdictRejectedValidation, models.WebhookVerdictDuplicate}
+	if fmt.Sprint(verdicts) != fmt.Sprint(want) {
+		t.Fatalf("Expected verdicts %v (newest first), got %v", want, verdicts)
+	}
+
+	duplicate := state.Requests[2]
+	if strings.Contains(duplicate.Body, "hunter2") {
+		t.Errorf("Expected the password to be masked, got %s", duplicate.Body)
+	}
+	for _, header := range []string{"Authorization", "X-Hub-Signature-256"} {
+		if duplicate.Headers[header] != "***REDACTED***" {
+			t.Errorf("Expected %s to be redacted, got %q", header, duplicate.Headers[header])
+		}
+	}
+	if duplicate.Headers["Content-Type"] != "application/json" || duplicate.Method != "POST" {
+		t.Errorf("Expected method and plain headers to be kept, got %s %v", duplicate.Method, duplicate.Headers)
+	}
+
+	// A captured request replays into a dry run
+	var dryRun handlers.DryRunResponse
+	status := call(t, "POST", debugURL+"/"+state.Requests[0].ID+"/replay", token, nil, &dryRun)
+	if status != http.StatusOK || !dryRun.Success {
+		t.Errorf("Expected the replay to succeed, got %d: %+v", status, dryRun)
+	}
+	if status := call(t, "POST", debugURL+"/"+state.Requests[0].ID+"/replay", userToken(t, "someone-else"), nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected other users to be refused, got %d", status)
+	}
+
+	// Captured requests are purged with payloads
+	removed, err := database.PurgeTenantData(models.DefaultTenantID(user.ID), models.RetentionDebugRequests, time.Now().Add(time.Minute), 100)
+	if err != nil || removed != 3 {
+		t.Errorf("Expected retention to purge 3 captured requests, got %d (%v)", removed, err)
+	}
+}
+
+// TestWebhookWaitForResult answers ?wait=true and synchronous workflows with the run's
+// result, acknowledges everything else, and stops runs that outlast the wait with 504
+func TestWebhookWaitForResult(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	handler := handlers.NewWebhookHandler(database, engine.NewExecutor(database, testLogger), testLogger)
+	handler.SetWaitTimeout(200 * time.Millisecond)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/webhooks/{id}", handler.TriggerWebhook).Methods("POST")
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	user, _ := database.CreateUser("bridge@example.com", "hashed")
+	bridge, _ := database.CreateWorkflow(user.ID, "Bridge", "webhook", "testing",
+		`{"testing_response_json": "{\"greeting\": \"Hello {{name}}\"}"}`)
+	synchronous, _ := database.CreateWorkflow(user.ID, "Always waits", "webhook", "testing",
+		`{"synchronous": true, "testing_response_json": "{\"greeting\": \"Hi {{name}}\"}"}`)
+	slow, _ := database.CreateWorkflow(user.ID, "Slow", "webhook", "testing", `{"testing_delay": 2000}`)
+
+	trigger := func(path string) (int, map[string]interface{}) {
+		resp, err := http.Post(srv.URL+"/api/webhooks/"+path, "application/json", strings.NewReader(`{"name": "Ada"}`))
+		if err != nil {
+			t.Fatalf("Trigger failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	// Asynchronous by default
+	if status, body := trigger(bridge.ID); status != http.StatusOK || body["status"] != "triggered" {
+		t.Errorf("Expected an acknowledgement, got %d: %v", status, body)
+	}
+
+	for _, path := range []string{bridge.ID + "?wait=true", synchronous.ID} {
+		status, body := trigger(path)
+		data, _ := body["data"].(map[string]interface{})
+		if status != http.StatusOK || body["status"] != "success" || body["duration"] == "" || data == nil {
+			t.Errorf("%s: expected the run's result, got %d: %v", path, status, body)
+			continue
+		}
+		if greeting := data["greeting"]; greeting != "Hello Ada" && greeting != "Hi Ada" {
+			t.Errorf("%s: expected the payload rendered into the result, got %v", path, data)
+		}
+	}
+	if status, body := trigger(synchronous.ID + "?wait=false"); status != http.StatusOK || body["status"] != "triggered" {
+		t.Errorf("Expected ?wait=false to override the workflow, got %d: %v", status, body)
+	}
+	if status, _ := trigger(bridge.ID + "?wait=maybe"); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid wait to be rejected, got %d", status)
+	}
+
+	// A run past the wait is stopped and recorded
+	start := time.Now()
+	if status, body := trigger(slow.ID + "?wait=true"); status != http.StatusGatewayTimeout || body["status"] != "timed_out" {
+		t.Errorf("Expected 504, got %d: %v", status, body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the run stopped at the wait timeout, took %v", elapsed)
+	}
+	logs, _ := database.GetLogsByWorkflowID(slow.ID)
+	if len(logs) != 1 || !strings.Contains(logs[0].Message, "timeout of 200ms") {
+		t.Errorf("Expected the stopped run logged, got %+v", logs)
+	}
+}
+
+// TestWebhookRequestIDReachesLog triggers a webhook with a known X-Request-ID and finds it
+// on the log entry of the run, which finishes after the response was sent
+func TestWebhookRequestIDReachesLog(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	user, _ := database.CreateUser("trace@example.com", "hashed")
+	workflow, err := database.CreateWorkflow(user.ID, "Traced", "webhook", "testing", `{"testing_response_json": "{\"ok\": true}"}`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	trigger := func(query, requestID string) string {
+		req, _ := http.NewRequest("POST", srv.URL+"/api/webhooks/"+workflow.ID+query, strings.NewReader(`{}`))
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Trigger failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		return resp.Header.Get("X-Request-ID")
+	}
+	logged := func(requestID string) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			logs, _ := database.GetLogsByWorkflowID(workflow.ID)
+			for _, log := range logs {
+				if log.RequestID == requestID {
+					return true
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	if echoed := trigger("", "req-webhook-42"); echoed != "req-webhook-42" {
+		t.Errorf("Expected the request ID echoed, got %q", echoed)
+	}
+	if !logged("req-webhook-42") {
+		t.Error("Expected the asynchronous run's log entry to carry the request ID")
+	}
+
+	// Without a usable header a UUID is generated, and synchronous runs carry it too
+	generated := trigger("?wait=true", "two words")
+	if generated == "" || generated == "two words" {
+		t.Fatalf("Expected a generated request ID, got %q", generated)
+	}
+	if !logged(generated) {
+		t.Errorf("Expected the synchronous run's log entry to carry %q", generated)
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+)
+
+// WorkersHandler shows operators what the execution workers are doing
+type WorkersHandler struct {
+	executor *engine.Executor
+}
+
+// NewWorkersHandler creates a new workers handler
+func NewWorkersHandler(executor *engine.Executor) *WorkersHandler {
+	return &WorkersHandler{executor: executor}
+}
+
+// GetWorkers lists each worker's current job and how long it has been running
+// Jobs past the watchdog's soft threshold are reported as 'stuck'
+func (h *WorkersHandler) GetWorkers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.executor.WorkerStatus())
+}
+
+// GetConnectorVersions lists each action type's connector versions with execution and
+// failure counts per version, to compare a canary against the current version
+func (h *WorkersHandler) GetConnectorVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.executor.ConnectorVersionStats())
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+// maxBulkWorkflows is how many workflows one bulk request may name
+const maxBulkWorkflows = 100
+
+// Bulk actions
+const (
+	BulkEnable  = "enable"
+	BulkDisable = "disable"
+	BulkDelete  = "delete"
+)
+
+// Per-workflow outcomes of a bulk request
+const (
+	BulkSuccess   = "success"
+	BulkNotFound  = "not_found"
+	BulkForbidden = "forbidden"
+)
+
+// BulkWorkflowRequest applies one action to several workflows
+type BulkWorkflowRequest struct {
+	Action      string   `json:"action"`       // enable, disable or delete
+	WorkflowIDs []string `json:"workflow_ids"` // At most 100; duplicates are ignored
+}
+
+// BulkWorkflowResult is one workflow's outcome
+type BulkWorkflowResult struct {
+	ID     string        `json:"id"`
+	Status string        `json:"status"` // success, not_found or forbidden
+	Error  string        `json:"error,omitempty"`
+	Kong   *KongTeardown `json:"kong,omitempty"` // Deletes only: the Kong objects that couldn't be deleted
+}
+
+// BulkWorkflowResponse lists each workflow's outcome, in request order
+type BulkWorkflowResponse struct {
+	Action    string               `json:"action"`
+	Succeeded int                  `json:"succeeded"`
+	Results   []BulkWorkflowResult `json:"results"`
+}
+
+// BulkWorkflows enables, disables or deletes several workflows of the tenant at once
+// Each ID is checked like the single-workflow endpoints (deletes need the creator or a
+// tenant admin); the allowed ones are changed in one transaction and the rest reported
+func (h *WorkflowsHandler) BulkWorkflows(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req BulkWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+	auditAction := models.AuditWorkflowToggle
+	switch req.Action {
+	case BulkEnable, BulkDisable:
+	case BulkDelete:
+		auditAction = models.AuditWorkflowDelete
+	default:
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "action must be enable, disable or delete")
+		return
+	}
+	workflowIDs := uniqueIDs(req.WorkflowIDs)
+	if len(workflowIDs) == 0 {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "workflow_ids is required")
+		return
+	}
+	if len(workflowIDs) > maxBulkWorkflows {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, fmt.Sprintf("At most %d workflow_ids per request", maxBulkWorkflows))
+		return
+	}
+
+	results := make([]BulkWorkflowResult, len(workflowIDs))
+	allowed := make(map[string]*models.Workflow)
+	var allowedIDs []string
+	for i, workflowID := range workflowIDs {
+		results[i] = BulkWorkflowResult{ID: workflowID}
+		workflow, err := h.store.GetWorkflowByID(workflowID)
+		if store.IsNotFound(err) {
+			results[i].Status = BulkNotFound
+			continue
+		}
+		if err != nil {
+			writeStoreError(w, err, "Workflow not found")
+			return
+		}
+		if !inTenant(r, workflow) {
+			h.audit.Denied(r, auditAction, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+			results[i].Status = BulkForbidden
+			continue
+		}
+		if req.Action == BulkDelete && workflow.UserID != userID && !tenantAdmin(r) {
+			h.audit.Denied(r, auditAction, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+			results[i].Status = BulkForbidden
+			results[i].Error = "Only the workflow's creator or a tenant admin can delete it"
+			continue
+		}
+		allowed[workflowID] = workflow
+		allowedIDs = append(allowedIDs, workflowID)
+	}
+
+	var missing []string
+	if len(allowedIDs) > 0 {
+		var err error
+		if req.Action == BulkDelete {
+			missing, err = h.store.DeleteWorkflows(allowedIDs)
+		} else {
+			missing, err = h.store.SetWorkflowsActive(allowedIDs, req.Action == BulkEnable)
+		}
+		if err != nil {
+			writeStoreError(w, err, "Workflow not found")
+			return
+		}
+	}
+	gone := make(map[string]bool, len(missing))
+	for _, workflowID := range missing {
+		gone[workflowID] = true
+	}
+
+	response := BulkWorkflowResponse{Action: req.Action, Results: results}
+	for i := range results {
+		workflow, ok := allowed[results[i].ID]
+		if !ok {
+			continue
+		}
+		if gone[workflow.ID] {
+			results[i].Status = BulkNotFound
+			continue
+		}
+		results[i].Status = BulkSuccess
+		response.Succeeded++
+
+		if req.Action == BulkDelete {
+			results[i].Kong = h.deleted(r, workflow, true)
+			continue
+		}
+		before := *workflow
+		workflow.IsActive = req.Action == BulkEnable
+		h.notifyChange(r, &before, workflow)
+		h.audit.Record(r, models.AuditEvent{
+			Action:       models.AuditWorkflowToggle,
+			ResourceType: models.AuditResourceWorkflow,
+			ResourceID:   workflow.ID,
+		}, map[string]interface{}{"is_active": workflow.IsActive, "bulk": true})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// deleted tears down a deleted workflow's Kong objects and records the deletion
+// Kong objects are deleted after the workflow, and a failure doesn't bring it back: it
+// returns the teardown when Kong refused some objects, which stay recorded in kong_resources
+func (h *WorkflowsHandler) deleted(r *http.Request, workflow *models.Workflow, bulk bool) *KongTeardown {
+	var teardown *KongTeardown
+	metadata := map[string]interface{}{"name": workflow.Name}
+	if bulk {
+		metadata["bulk"] = true
+	}
+	if h.kong != nil {
+		var err error
+		if teardown, err = h.kong.TeardownWorkflow(workflow.TenantID, workflow.ID); err != nil {
+			teardown = &KongTeardown{Failed: []KongTeardownFailure{{Error: err.Error()}}}
+		}
+		if len(teardown.Failed) > 0 {
+			metadata["kong_failed"] = len(teardown.Failed)
+		}
+	}
+	h.notifyChange(r, workflow, nil)
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditWorkflowDelete,
+		ResourceType: models.AuditResourceWorkflow,
+		ResourceID:   workflow.ID,
+	}, metadata)
+
+	if teardown == nil || len(teardown.Failed) == 0 {
+		return nil
+	}
+	return teardown
+}
+
+// uniqueIDs drops empty and repeated IDs, keeping the first occurrence's order
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestBulkWorkflows disables, then deletes, a mixed batch: the caller's own workflows, one
+// of another user's and an ID that doesn't exist
+func TestBulkWorkflows(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	owner, _ := database.CreateUser("bulk@example.com", "hashed")
+	other, _ := database.CreateUser("elsewhere@example.com", "hashed")
+	token := userToken(t, owner.ID)
+	first, _ := database.CreateWorkflow(owner.ID, "First", "webhook", "testing", `{}`)
+	second, _ := database.CreateWorkflow(owner.ID, "Second", "schedule", "testing", `{}`)
+	foreign, _ := database.CreateWorkflow(other.ID, "Not yours", "webhook", "testing", `{}`)
+	database.CreateLog(first.ID, "success", "ran")
+
+	batch := []string{first.ID, foreign.ID, "missing-workflow", second.ID, first.ID}
+	want := map[string]string{
+		first.ID:           handlers.BulkSuccess,
+		second.ID:          handlers.BulkSuccess,
+		foreign.ID:         handlers.BulkForbidden,
+		"missing-workflow": handlers.BulkNotFound,
+	}
+	check := func(response handlers.BulkWorkflowResponse) {
+		t.Helper()
+		if len(response.Results) != 4 || response.Succeeded != 2 {
+			t.Fatalf("Expected 4 results (duplicates dropped) with 2 successes, got %+v", response)
+		}
+		for _, result := range response.Results {
+			if result.Status != want[result.ID] {
+				t.Errorf("%s: expected %s, got %s", result.ID, want[result.ID], result.Status)
+			}
+		}
+	}
+
+	var disabled handlers.BulkWorkflowResponse
+	if status := call(t, "POST", srv.URL+"/api/workflows/bulk", token, handlers.BulkWorkflowRequest{Action: "disable", WorkflowIDs: batch}, &disabled); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	check(disabled)
+	for _, id := range []string{first.ID, second.ID} {
+		if workflow, _ := database.GetWorkflowByID(id); workflow.IsActive {
+			t.Errorf("Expected %s disabled", workflow.Name)
+		}
+	}
+	if workflow, _ := database.GetWorkflowByID(foreign.ID); !workflow.IsActive {
+		t.Error("Expected the other user's workflow left active")
+	}
+
+	var deleted handlers.BulkWorkflowResponse
+	if status := call(t, "POST", srv.URL+"/api/workflows/bulk", token, handlers.BulkWorkflowRequest{Action: "delete", WorkflowIDs: batch}, &deleted); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	check(deleted)
+	for _, id := range []string{first.ID, second.ID} {
+		if _, err := database.GetWorkflowByID(id); err == nil {
+			t.Errorf("Expected %s deleted", id)
+		}
+	}
+	if logs, _ := database.GetLogsByWorkflowID(first.ID); len(logs) != 0 {
+		t.Errorf("Expected the deleted workflow's logs gone, got %d", len(logs))
+	}
+	if _, err := database.GetWorkflowByID(foreign.ID); err != nil {
+		t.Errorf("Expected the other user's workflow kept, got %v", err)
+	}
+
+	// Bad requests are refused as a whole
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("workflow-%d", i)
+	}
+	for name, req := range map[string]handlers.BulkWorkflowRequest{
+		"unknown action": {Action: "archive", WorkflowIDs: []string{foreign.ID}},
+		"no IDs":         {Action: "enable"},
+		"too many IDs":   {Action: "enable", WorkflowIDs: tooMany},
+	} {
+		if status := call(t, "POST", srv.URL+"/api/workflows/bulk", token, req, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+}
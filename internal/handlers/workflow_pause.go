@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// maxPauseDuration is the longest a workflow can be paused for; toggle it off for longer
+const maxPauseDuration = 30 * 24 * time.Hour
+
+// PauseRequest pauses a workflow for a duration or until a time, or resumes it
+// Exactly one of the fields is set
+type PauseRequest struct {
+	Duration string     `json:"duration,omitempty"` // e.g. "2h30m"
+	Until    *time.Time `json:"until,omitempty"`    // RFC 3339
+	Resume   bool       `json:"resume,omitempty"`   // End the pause now
+}
+
+// PauseWorkflow pauses a workflow: the scheduler skips it and its webhook answers 423
+// until paused_until passes, when it resumes by itself
+func (h *WorkflowsHandler) PauseWorkflow(w http.ResponseWriter, r *http.Request) {
+	workflow, err := h.store.GetWorkflowByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+	if !inTenant(r, workflow) {
+		h.audit.Denied(r, models.AuditWorkflowPause, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+		SendError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req PauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+	until, err := pauseEnd(req, time.Now())
+	if err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	before := *workflow
+	if err := h.store.SetWorkflowPausedUntil(workflow.ID, until); err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+	workflow.PausedUntil = until
+
+	h.notifyChange(r, &before, workflow)
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditWorkflowPause,
+		ResourceType: models.AuditResourceWorkflow,
+		ResourceID:   workflow.ID,
+	}, map[string]interface{}{"paused_until": until})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(withWebhookURLs(h.store, r, []WorkflowResponse{workflowResponse(workflow)})[0])
+}
+
+// pauseEnd is when a pause request ends the pause; nil resumes the workflow
+func pauseEnd(req PauseRequest, now time.Time) (*time.Time, error) {
+	set := 0
+	for _, present := range []bool{req.Duration != "", req.Until != nil, req.Resume} {
+		if present {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("set exactly one of duration, until or resume")
+	}
+
+	var until time.Time
+	switch {
+	case req.Resume:
+		return nil, nil
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("duration must be positive, like \"90m\" or \"2h\"")
+		}
+		until = now.Add(d)
+	default:
+		until = *req.Until
+		if !until.After(now) {
+			return nil, fmt.Errorf("until must be in the future")
+		}
+	}
+	if until.Sub(now) > maxPauseDuration {
+		return nil, fmt.Errorf("a workflow can be paused for at most 30 days; disable it instead")
+	}
+	return &until, nil
+}
@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestPauseWorkflow pauses a webhook workflow, checks its webhook answers 423 with a
+// Retry-After until it is resumed, and that bad requests are refused
+func TestPauseWorkflow(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	owner, _ := database.CreateUser("pause@example.com", "hashed")
+	other, _ := database.CreateUser("other@example.com", "hashed")
+	token := userToken(t, owner.ID)
+	workflow, _ := database.CreateWorkflow(owner.ID, "Pausable", "webhook", "testing", `{}`)
+	pauseURL := srv.URL + "/api/workflows/" + workflow.ID + "/pause"
+
+	var paused handlers.WorkflowResponse
+	if status := call(t, "PUT", pauseURL, token, map[string]string{"duration": "1h"}, &paused); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if paused.PausedUntil == nil || time.Until(*paused.PausedUntil) < 59*time.Minute || !paused.IsActive {
+		t.Errorf("Expected the workflow paused for an hour and still active, got %+v", paused)
+	}
+
+	resp, err := http.Post(srv.URL+"/api/webhooks/"+workflow.ID, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Webhook failed: %v", err)
+	}
+	resp.Body.Close()
+	retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if resp.StatusCode != http.StatusLocked || retryAfter < 3590 || retryAfter > 3600 {
+		t.Errorf("Expected 423 with about an hour's Retry-After, got %d and %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	var workflows []handlers.WorkflowResponse
+	call(t, "GET", srv.URL+"/api/workflows", token, nil, &workflows)
+	if len(workflows) != 1 || workflows[0].PausedUntil == nil {
+		t.Errorf("Expected paused_until in the workflow list, got %+v", workflows)
+	}
+
+	var resumed handlers.WorkflowResponse
+	if status := call(t, "PUT", pauseURL, token, map[string]bool{"resume": true}, &resumed); status != http.StatusOK || resumed.PausedUntil != nil {
+		t.Errorf("Expected the workflow resumed, got %d: %+v", status, resumed)
+	}
+	if status := call(t, "POST", srv.URL+"/api/webhooks/"+workflow.ID, "", map[string]string{}, nil); status != http.StatusOK {
+		t.Errorf("Expected the resumed webhook accepted, got %d", status)
+	}
+
+	for _, c := range []struct {
+		name   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"nothing set", token, map[string]string{}, http.StatusBadRequest},
+		{"two fields set", token, map[string]interface{}{"duration": "1h", "resume": true}, http.StatusBadRequest},
+		{"negative duration", token, map[string]string{"duration": "-5m"}, http.StatusBadRequest},
+		{"until in the past", token, map[string]time.Time{"until": time.Now().Add(-time.Hour)}, http.StatusBadRequest},
+		{"longer than 30 days", token, map[string]string{"duration": "721h"}, http.StatusBadRequest},
+		{"another tenant", userToken(t, other.ID), map[string]string{"duration": "1h"}, http.StatusForbidden},
+	} {
+		if status := call(t, "PUT", pauseURL, c.token, c.body, nil); status != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, status)
+		}
+	}
+}
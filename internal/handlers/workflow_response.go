@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// WorkflowResponse is the API shape of a workflow
+// Listed field by field so nothing new on models.Workflow (like a stored trigger payload)
+// reaches list or detail responses without a decision; payloads are only readable
+// through the execution trace endpoint
+type WorkflowResponse struct {
+	ID                 string                       `json:"id"`
+	UserID             string                       `json:"user_id"`
+	TenantID           string                       `json:"tenant_id"`
+	Name               string                       `json:"name"`
+	TriggerType        string                       `json:"trigger_type"`
+	ActionType         string                       `json:"action_type"`
+	ConfigJSON         string                       `json:"config_json"`
+	ActionChain        string                       `json:"action_chain"`
+	Parameters         string                       `json:"parameters"`
+	ParsedChain        []models.ChainedAction       `json:"parsed_chain,omitempty"`
+	ParsedParameters   []models.WorkflowParameter   `json:"parsed_parameters,omitempty"`
+	IsActive           bool                         `json:"is_active"`
+	LastExecutedAt     *time.Time                   `json:"last_executed_at,omitempty"`
+	NextRunAt          *time.Time                   `json:"next_run_at,omitempty"`
+	CreatedAt          time.Time                    `json:"created_at"`
+	Stats              *models.WorkflowStats        `json:"stats,omitempty"`
+	DebugRequestsUntil *time.Time                   `json:"debug_requests_until,omitempty"`
+	DebugRequestsLimit int                          `json:"debug_requests_limit,omitempty"`
+	PausedUntil        *time.Time                   `json:"paused_until,omitempty"` // Set while a pause is still in force
+	WebhookURL         string                       `json:"webhook_url,omitempty"`  // Webhook triggers only; on the tenant's verified domain when it has one
+	Warnings           []string                     `json:"warnings,omitempty"`     // Save-time problems that don't stop the save (e.g. credential environment mismatches)
+	Requirements       *models.WorkflowRequirements `json:"requirements,omitempty"` // Credential checklist, on a just-saved workflow
+}
+
+// workflowResponse converts a workflow to its API shape
+// The stored action chain is also returned parsed, as parsed_chain
+func workflowResponse(workflow *models.Workflow) WorkflowResponse {
+	parsedChain := workflow.ParsedChain
+	if parsedChain == nil && workflow.ActionChain != "" {
+		json.Unmarshal([]byte(workflow.ActionChain), &parsedChain)
+	}
+	return WorkflowResponse{
+		ID:                 workflow.ID,
+		UserID:             workflow.UserID,
+		TenantID:           workflow.TenantID,
+		Name:               workflow.Name,
+		TriggerType:        workflow.TriggerType,
+		ActionType:         workflow.ActionType,
+		ConfigJSON:         workflow.ConfigJSON,
+		ActionChain:        workflow.ActionChain,
+		Parameters:         workflow.Parameters,
+		ParsedChain:        parsedChain,
+		ParsedParameters:   workflow.ParsedParameters,
+		IsActive:           workflow.IsActive,
+		LastExecutedAt:     workflow.LastExecutedAt,
+		NextRunAt:          workflow.NextRunAt,
+		CreatedAt:          workflow.CreatedAt,
+		Stats:              workflow.Stats,
+		DebugRequestsUntil: workflow.DebugRequestsUntil,
+		DebugRequestsLimit: workflow.DebugRequestsLimit,
+		PausedUntil:        pausedUntil(workflow, time.Now()),
+	}
+}
+
+// pausedUntil is the end of the workflow's pause, or nil once it has passed
+func pausedUntil(workflow *models.Workflow, now time.Time) *time.Time {
+	if !workflow.PausedAt(now) {
+		return nil
+	}
+	return workflow.PausedUntil
+}
+
+// workflowResponses converts a list of workflows (never nil, so it encodes as [])
+func workflowResponses(workflows []models.Workflow) []WorkflowResponse {
+	responses := make([]WorkflowResponse, 0, len(workflows))
+	for i := range workflows {
+		responses = append(responses, workflowResponse(&workflows[i]))
+	}
+	return responses
+}
+
+// withWebhookURLs fills in the URL each webhook-triggered workflow is called at
+func withWebhookURLs(s db.Store, r *http.Request, responses []WorkflowResponse) []WorkflowResponse {
+	bases := map[string]string{} // By tenant
+	for i := range responses {
+		if responses[i].TriggerType != "webhook" {
+			continue
+		}
+		tenantID := responses[i].TenantID
+		if _, ok := bases[tenantID]; !ok {
+			bases[tenantID] = webhookBaseURL(s, r, tenantID)
+		}
+		responses[i].WebhookURL = bases[tenantID] + "/api/webhooks/" + responses[i].ID
+	}
+	return responses
+}
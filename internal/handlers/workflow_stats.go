@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// Stats windows: ?window= takes days ("7d") or hours ("24h")
+const (
+	defaultStatsWindow = "7d"
+	maxStatsWindow     = 90 * 24 * time.Hour
+)
+
+// errStatsWindow rejects a window that isn't a number of days or hours
+var errStatsWindow = errors.New("window must be a number of days or hours, e.g. 7d or 24h")
+
+// parseStatsWindow reads a stats window such as "7d" or "24h", at most 90 days
+func parseStatsWindow(window string) (time.Duration, error) {
+	unit := time.Hour
+	switch {
+	case strings.HasSuffix(window, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(window, "h"):
+	default:
+		return 0, errStatsWindow
+	}
+	n, err := strconv.Atoi(window[:len(window)-1])
+	if err != nil || n < 1 {
+		return 0, errStatsWindow
+	}
+	if duration := time.Duration(n) * unit; duration <= maxStatsWindow {
+		return duration, nil
+	}
+	return 0, errors.New("window may be at most 90d")
+}
+
+// statsWindow reads ?window= (default 7d), answering 400 for one it can't use
+func statsWindow(w http.ResponseWriter, r *http.Request) (string, time.Time, bool) {
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = defaultStatsWindow
+	}
+	duration, err := parseStatsWindow(window)
+	if err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return "", time.Time{}, false
+	}
+	return window, time.Now().Add(-duration), true
+}
+
+// GetWorkflowStats returns a workflow's run counts, success rate, durations, failure
+// streak and daily histogram over ?window= (default 7d)
+func (h *WorkflowsHandler) GetWorkflowStats(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetTenantIDFromContext(r.Context()); !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, err := h.store.GetWorkflowByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+	if !inTenant(r, workflow) {
+		SendError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	window, since, ok := statsWindow(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.store.GetWorkflowRunStats(workflow.ID, since)
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+	stats.Window = window
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetStatsOverview rolls the run stats of every workflow the user can see up over ?window=
+func (h *WorkflowsHandler) GetStatsOverview(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	window, since, ok := statsWindow(w, r)
+	if !ok {
+		return
+	}
+	overview, err := h.store.GetRunStatsOverview(tenantID, since)
+	if err != nil {
+		writeStoreError(w, err, "Tenant not found")
+		return
+	}
+	overview.Window = window
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overview)
+}
@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestWorkflowStats reads a workflow's stats and the overview, within the caller's tenant only
+func TestWorkflowStats(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{Store: mockStore, Executor: engine.NewExecutor(mockStore, testLogger), Logger: testLogger}))
+	defer srv.Close()
+
+	user, _ := mockStore.CreateUser("stats@example.com", "hashed")
+	token := userToken(t, user.ID)
+	workflow, _ := mockStore.CreateWorkflow(user.ID, "Orders", "webhook", "testing", `{}`)
+
+	now := time.Now()
+	for i, status := range []string{"success", "success", "failed", "success", "failed", "failed"} {
+		duration := int64(10 * (i + 1))
+		mockStore.CreateLogEntry(&models.Log{WorkflowID: workflow.ID, Status: status, DurationMS: &duration, ExecutedAt: now.Add(time.Duration(i-6) * time.Hour)})
+	}
+	mockStore.CreateLogEntry(&models.Log{WorkflowID: workflow.ID, Status: "success", ExecutedAt: now.Add(-10 * 24 * time.Hour)})
+
+	var stats models.RunStats
+	if status := call(t, "GET", srv.URL+"/api/workflows/"+workflow.ID+"/stats", token, nil, &stats); status != http.StatusOK {
+		t.Fatalf("Expected the workflow's stats, got %d", status)
+	}
+	if stats.Window != "7d" || stats.TotalRuns != 6 || stats.Successes != 3 || stats.Failures != 3 || stats.SuccessRate != 0.5 {
+		t.Errorf("Expected the last 7 days' 6 runs, half failed, got %+v", stats)
+	}
+	if stats.FailureStreak != 2 || stats.AvgDurationMS == nil || *stats.AvgDurationMS != 35 || stats.MedianDurationMS == nil || *stats.MedianDurationMS != 35 {
+		t.Errorf("Expected a streak of 2 and 35ms durations, got %+v", stats)
+	}
+	if len(stats.Daily) < 7 || stats.LastExecutedAt == nil {
+		t.Errorf("Expected a daily histogram and the last run, got %+v", stats)
+	}
+
+	if status := call(t, "GET", srv.URL+"/api/workflows/"+workflow.ID+"/stats?window=30d", token, nil, &stats); status != http.StatusOK || stats.TotalRuns != 7 {
+		t.Errorf("Expected 7 runs over 30 days, got %d: %d", status, stats.TotalRuns)
+	}
+	for _, window := range []string{"0d", "7", "1w", "91d"} {
+		if status := call(t, "GET", srv.URL+"/api/workflows/"+workflow.ID+"/stats?window="+window, token, nil, nil); status != http.StatusBadRequest {
+			t.Errorf("window=%s: expected 400, got %d", window, status)
+		}
+	}
+
+	var overview models.RunStatsOverview
+	if status := call(t, "GET", srv.URL+"/api/stats/overview?window=24h", token, nil, &overview); status != http.StatusOK {
+		t.Fatalf("Expected the overview, got %d", status)
+	}
+	if overview.WorkflowCount != 1 || overview.TotalRuns != 6 || overview.FailingWorkflows != 1 || len(overview.Workflows) != 1 {
+		t.Errorf("Expected the one workflow's runs, got %+v", overview)
+	}
+
+	// Another tenant sees neither the workflow's stats nor its runs
+	outsider, _ := mockStore.CreateUser("outsider@example.com", "hashed")
+	outsiderToken := userToken(t, outsider.ID)
+	if status := call(t, "GET", srv.URL+"/api/workflows/"+workflow.ID+"/stats", outsiderToken, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected another tenant to be refused, got %d", status)
+	}
+	if status := call(t, "GET", srv.URL+"/api/stats/overview", outsiderToken, nil, &overview); status != http.StatusOK || overview.WorkflowCount != 0 || overview.TotalRuns != 0 {
+		t.Errorf("Expected an empty overview for another tenant, got %d: %+v", status, overview)
+	}
+}
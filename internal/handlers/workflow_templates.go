@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/templates"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// InstantiateTemplateRequest creates a workflow from a template
+type InstantiateTemplateRequest struct {
+	Name       string            `json:"name,omitempty"` // Default: the template's name
+	Parameters map[string]string `json:"parameters"`     // Values for the template's parameters
+}
+
+// ListWorkflowTemplates returns the catalog of ready-made workflows
+func (h *WorkflowsHandler) ListWorkflowTemplates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates.All())
+}
+
+// InstantiateTemplate creates a workflow from a template once the user has connected
+// every service it needs; otherwise it answers 422 listing the services to connect,
+// with the credential checklist of the workflow it would have created
+func (h *WorkflowsHandler) InstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tenantID, _ := middleware.GetTenantIDFromContext(r.Context())
+
+	template, ok := templates.Get(mux.Vars(r)["id"])
+	if !ok {
+		SendError(w, http.StatusNotFound, "Template not found")
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	configJSON, actionChainJSON, err := template.Instantiate(req.Parameters)
+	if err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+	name := req.Name
+	if name == "" {
+		name = template.Name
+	}
+
+	// The same check as GET /api/workflows/{id}/requirements, on the workflow before it is saved
+	draft := models.Workflow{
+		UserID:      userID,
+		TenantID:    tenantID,
+		Name:        name,
+		TriggerType: template.TriggerType,
+		ActionType:  template.ActionType,
+		ConfigJSON:  configJSON,
+		ActionChain: actionChainJSON,
+	}
+	requirements, err := engine.CheckWorkflowRequirements(h.store, draft, userID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to check credentials")
+		return
+	}
+	if !requirements.Ready {
+		missing := []string{}
+		for _, requirement := range requirements.Requirements {
+			if !requirement.Optional && requirement.Status != "connected" {
+				missing = append(missing, requirement.Service)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":          false,
+			"error":            fmt.Sprintf("Connect %s first", strings.Join(missing, ", ")),
+			"code":             utils.CodeCredentialMissing,
+			"missing_services": missing,
+			"requirements":     requirements,
+		})
+		return
+	}
+
+	h.create(w, r, userID, name, template.TriggerType, template.ActionType, configJSON, actionChainJSON)
+}
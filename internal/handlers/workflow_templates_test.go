@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/alexmacdonald/simple-ipass/internal/templates"
+)
+
+// TestInstantiateWorkflowTemplate lists the catalog, is refused with the services to connect
+// and the checklist while credentials are missing, then creates the workflow once they are connected
+func TestInstantiateWorkflowTemplate(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+	user, _ := database.CreateUser("catalog@example.com", "hashed")
+	token := userToken(t, user.ID)
+
+	var catalog []templates.Template
+	if status := call(t, "GET", srv.URL+"/api/workflow-templates", token, nil, &catalog); status != http.StatusOK || len(catalog) < 6 {
+		t.Fatalf("Expected the catalog, got %d with %d templates", status, len(catalog))
+	}
+
+	instantiateURL := srv.URL + "/api/workflow-templates/daily-weather-discord/instantiate"
+	body := map[string]interface{}{"name": "London weather", "parameters": map[string]string{"city": "London"}}
+	database.CreateCredential(user.ID, "discord", "https://discord.com/api/webhooks/1/abc")
+	var refused struct {
+		Error           string                       `json:"error"`
+		MissingServices []string                     `json:"missing_services"`
+		Requirements    *models.WorkflowRequirements `json:"requirements"`
+	}
+	if status := call(t, "POST", instantiateURL, token, body, &refused); status != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 without an OpenWeather credential, got %d", status)
+	}
+	if len(refused.MissingServices) != 1 || refused.MissingServices[0] != "openweather" || refused.Error != "Connect openweather first" {
+		t.Errorf("Expected only openweather listed as missing, got %+v", refused)
+	}
+	if checklist := refused.Requirements; checklist == nil || checklist.Ready || len(checklist.Requirements) != 2 || checklist.Requirements[1].Status != "connected" {
+		t.Errorf("Expected the checklist with openweather missing and discord connected, got %+v", checklist)
+	}
+	if workflows, _ := database.GetWorkflowsByUserID(user.ID); len(workflows) != 0 {
+		t.Errorf("Expected no workflow created, got %d", len(workflows))
+	}
+
+	database.CreateCredential(user.ID, "openweather", "weather-key")
+	var created handlers.WorkflowResponse
+	if status := call(t, "POST", instantiateURL, token, body, &created); status != http.StatusCreated {
+		t.Fatalf("Expected 201 once every service is connected, got %d", status)
+	}
+	if created.Requirements == nil || !created.Requirements.Ready {
+		t.Errorf("Expected a ready checklist in the response, got %+v", created.Requirements)
+	}
+	workflow, _ := database.GetWorkflowByID(created.ID)
+	var config models.WorkflowConfig
+	json.Unmarshal([]byte(workflow.ConfigJSON), &config)
+	if workflow.Name != "London weather" || workflow.TriggerType != "schedule" || workflow.ActionType != "weather_check" ||
+		config.City != "London" || config.Interval != 1440 || workflow.ActionChain == "" {
+		t.Errorf("Unexpected workflow %+v with config %s", workflow, workflow.ConfigJSON)
+	}
+
+	for _, c := range []struct {
+		name   string
+		url    string
+		body   interface{}
+		status int
+	}{
+		{"missing parameter", instantiateURL, map[string]interface{}{}, http.StatusBadRequest},
+		{"bad interval", instantiateURL, map[string]interface{}{"parameters": map[string]string{"city": "Paris", "interval": "daily"}}, http.StatusBadRequest},
+		{"unknown template", srv.URL + "/api/workflow-templates/nope/instantiate", body, http.StatusNotFound},
+	} {
+		if status := call(t, "POST", c.url, token, c.body, nil); status != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, status)
+		}
+	}
+}
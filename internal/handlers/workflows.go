@@ -2,12 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/audit"
 	"github.com/alexmacdonald/simple-ipass/internal/db"
 	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
 	"github.com/alexmacdonald/simple-ipass/internal/middleware"
 	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+	"github.com/alexmacdonald/simple-ipass/internal/notify"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
@@ -17,17 +23,40 @@ import (
 type WorkflowsHandler struct {
 	store    db.Store // Interface, not concrete type!
 	executor *engine.Executor
+	messages *Messages
+	notifier *notify.Notifier // Optional: workflow change notifications
+	audit    *audit.Recorder
+	kong     *KongHandler // Optional: deletes the Kong objects created for a deleted workflow
 }
 
 // NewWorkflowsHandler creates a new workflows handler
-func NewWorkflowsHandler(store db.Store, executor *engine.Executor) *WorkflowsHandler {
-	return &WorkflowsHandler{store: store, executor: executor}
+func NewWorkflowsHandler(store db.Store, executor *engine.Executor, messages *Messages) *WorkflowsHandler {
+	return &WorkflowsHandler{store: store, executor: executor, messages: messages, audit: audit.NewRecorder(store)}
+}
+
+// SetNotifier reports workflow changes to the tenant's members through notifier
+func (h *WorkflowsHandler) SetNotifier(notifier *notify.Notifier) {
+	h.notifier = notifier
+}
+
+// SetKong deletes the Kong objects recorded for a workflow when the workflow is deleted
+func (h *WorkflowsHandler) SetKong(kong *KongHandler) {
+	h.kong = kong
+}
+
+// notifyChange reports a change made by the request's user; delivery never affects the response
+func (h *WorkflowsHandler) notifyChange(r *http.Request, before, after *models.Workflow) {
+	userID, tenantID, ok := middleware.GetUserAndTenantFromContext(r.Context())
+	if !ok || h.notifier == nil {
+		return
+	}
+	h.notifier.Notify(notify.NewChange(tenantID, userID, before, after, time.Now()))
 }
 
 type CreateWorkflowRequest struct {
 	Name        string                  `json:"name"`
 	TriggerType string                  `json:"trigger_type"` // 'webhook', 'schedule'
-	ActionType  string                  `json:"action_type"`  // 'slack_message', 'discord_post', 'weather_check'
+	ActionType  string                  `json:"action_type"`  // A registered connector (see GET /api/connectors)
 	ConfigJSON  string                  `json:"config_json"`
 	ActionChain []models.ChainedAction  `json:"action_chain"` // Optional: additional actions to execute sequentially
 }
@@ -35,8 +64,10 @@ type CreateWorkflowRequest struct {
 
 // DryRunRequest represents a test execution request without saving
 type DryRunRequest struct {
-	ActionType string `json:"action_type"` // 'slack_message', 'discord_post', 'weather_check'
+	ActionType string `json:"action_type"` // A registered connector (see GET /api/connectors)
 	ConfigJSON string `json:"config_json"`
+	WorkflowID string `json:"workflow_id,omitempty"` // Optional: stored workflow supplying the action and config left empty
+	Fixture    string `json:"fixture,omitempty"`     // Optional: name of a workflow fixture used as the trigger payload
 }
 
 // DryRunResponse represents the result of a dry run
@@ -46,53 +77,29 @@ type DryRunResponse struct {
 	Duration  string                 `json:"duration"`
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Error     string                 `json:"error,omitempty"`
+	ErrorCode string                 `json:"error_code,omitempty"`
+	Detail    string                 `json:"detail,omitempty"` // Untranslated error, administrators only
 	Timestamp string                 `json:"timestamp"`
+	Warnings  []string               `json:"warnings,omitempty"` // Template problems found against the run's payload
 }
 
 // CreateWorkflow creates a new workflow
 func (h *WorkflowsHandler) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
-	// TODO: MULTI-TENANT - Filter by tenant_id
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req CreateWorkflowRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
 		return
 	}
 
 	// Validate input
 	if req.Name == "" || req.TriggerType == "" || req.ActionType == "" {
-		http.Error(w, "name, trigger_type, and action_type are required", http.StatusBadRequest)
-		return
-	}
-
-	// Validate trigger and action types
-	validTriggers := map[string]bool{"webhook": true, "schedule": true}
-	validActions := map[string]bool{
-		"slack_message":   true,
-		"discord_post":    true,
-		"twilio_sms":      true,
-		"weather_check":   true,
-		"news_fetch":      true,
-		"cat_fetch":       true,
-		"fakestore_fetch": true,
-		"soap_call":       true,
-		"swapi_fetch":     true,
-		"salesforce":      true,
-		"testing":         true, // NEW: Mock/testing endpoint
-	}
-
-	if !validTriggers[req.TriggerType] {
-		http.Error(w, "Invalid trigger_type. Must be 'webhook' or 'schedule'", http.StatusBadRequest)
-		return
-	}
-
-	if !validActions[req.ActionType] {
-		http.Error(w, "Invalid action_type", http.StatusBadRequest)
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "name, trigger_type, and action_type are required")
 		return
 	}
 
@@ -105,36 +112,209 @@ func (h *WorkflowsHandler) CreateWorkflow(w http.ResponseWriter, r *http.Request
 	if len(req.ActionChain) > 0 {
 		chainBytes, err := json.Marshal(req.ActionChain)
 		if err != nil {
-			http.Error(w, "Invalid action_chain format", http.StatusBadRequest)
+			SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Invalid action_chain format")
 			return
 		}
 		actionChainJSON = string(chainBytes)
 	}
 
+	h.create(w, r, userID, req.Name, req.TriggerType, req.ActionType, req.ConfigJSON, actionChainJSON)
+}
+
+// create validates and saves a new workflow, answering 201 with it
+func (h *WorkflowsHandler) create(w http.ResponseWriter, r *http.Request, userID, name, triggerType, actionType, configJSON, actionChainJSON string) {
+	if err := h.validateDefinition(triggerType, actionType, configJSON, actionChainJSON); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
 	// Create workflow with or without action chain
 	var workflow *models.Workflow
 	var err error
-	
 	if actionChainJSON != "" {
-		// Use CreateWorkflowWithChain method
-		if creator, ok := h.store.(*db.Database); ok {
-			workflow, err = creator.CreateWorkflowWithChain(userID, req.Name, req.TriggerType, req.ActionType, req.ConfigJSON, actionChainJSON)
-		} else {
-			// Fallback for mock store
-			workflow, err = h.store.CreateWorkflow(userID, req.Name, req.TriggerType, req.ActionType, req.ConfigJSON)
-		}
+		workflow, err = h.store.CreateWorkflowWithChain(userID, name, triggerType, actionType, configJSON, actionChainJSON)
 	} else {
-		workflow, err = h.store.CreateWorkflow(userID, req.Name, req.TriggerType, req.ActionType, req.ConfigJSON)
+		workflow, err = h.store.CreateWorkflow(userID, name, triggerType, actionType, configJSON)
 	}
-	
 	if err != nil {
-		http.Error(w, "Failed to create workflow", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to create workflow")
 		return
 	}
+	h.notifyChange(r, nil, workflow)
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditWorkflowCreate,
+		ResourceType: models.AuditResourceWorkflow,
+		ResourceID:   workflow.ID,
+	}, workflowAuditSummary(nil, workflow))
+
+	response := h.savedResponse(r, workflow)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(workflow)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateWorkflow changes a workflow in place, keeping its ID (and so its webhook URL)
+// Takes the fields of CreateWorkflowRequest; omitted fields keep their current value and
+// an empty action_chain removes the chain
+func (h *WorkflowsHandler) UpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	workflow, ok := h.ownedWorkflow(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req CreateWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	updated := *workflow
+	if req.Name != "" {
+		updated.Name = req.Name
+	}
+	if req.TriggerType != "" {
+		updated.TriggerType = req.TriggerType
+	}
+	if req.ActionType != "" {
+		updated.ActionType = req.ActionType
+	}
+	if req.ConfigJSON != "" {
+		updated.ConfigJSON = req.ConfigJSON
+	}
+	if req.ActionChain != nil {
+		updated.ActionChain = ""
+		if len(req.ActionChain) > 0 {
+			chainBytes, err := json.Marshal(req.ActionChain)
+			if err != nil {
+				SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Invalid action_chain format")
+				return
+			}
+			updated.ActionChain = string(chainBytes)
+		}
+	}
+
+	if err := h.validateDefinition(updated.TriggerType, updated.ActionType, updated.ConfigJSON, updated.ActionChain); err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	if err := h.store.UpdateWorkflow(&updated); err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+
+	// Read it back so the response carries the stored state, stats and history
+	saved, err := h.store.GetWorkflowByID(workflow.ID)
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+	h.notifyChange(r, workflow, saved)
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditWorkflowUpdate,
+		ResourceType: models.AuditResourceWorkflow,
+		ResourceID:   saved.ID,
+	}, workflowAuditSummary(workflow, saved))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.savedResponse(r, saved))
+}
+
+// workflowAuditSummary lists a workflow's definition for the audit trail: every field of a
+// new workflow, or only the fields an update changed. Config is kept as an object so
+// secrets in it are masked by key
+func workflowAuditSummary(before, after *models.Workflow) map[string]interface{} {
+	summary := map[string]interface{}{}
+	field := func(name, old, new string) {
+		if before == nil || old != new {
+			summary[name] = new
+		}
+	}
+	var old models.Workflow
+	if before != nil {
+		old = *before
+	}
+	field("name", old.Name, after.Name)
+	field("trigger_type", old.TriggerType, after.TriggerType)
+	field("action_type", old.ActionType, after.ActionType)
+	if before == nil || old.ConfigJSON != after.ConfigJSON {
+		var config map[string]interface{}
+		if json.Unmarshal([]byte(after.ConfigJSON), &config) == nil {
+			summary["config"] = config
+		}
+	}
+	if old.ActionChain != after.ActionChain {
+		var chain []interface{}
+		json.Unmarshal([]byte(after.ActionChain), &chain)
+		summary["action_chain"] = chain
+	}
+	return summary
+}
+
+// savedResponse describes a just-saved workflow, with its credential checklist
+// Missing credentials, environment mismatches and broken templates don't stop the save, but the user should know now
+func (h *WorkflowsHandler) savedResponse(r *http.Request, workflow *models.Workflow) WorkflowResponse {
+	response := workflowResponse(workflow)
+	if requirements, err := engine.CheckWorkflowRequirements(h.store, *workflow, workflow.UserID); err == nil {
+		response.Requirements = requirements
+		response.Warnings = engine.EnvironmentWarnings(requirements)
+	}
+	response.Warnings = append(response.Warnings, engine.TemplateWarnings(workflow.ConfigJSON, "")...)
+	return withWebhookURLs(h.store, r, []WorkflowResponse{response})[0]
+}
+
+// validTriggers are the trigger types a workflow may use; action types are the executor's connectors
+var validTriggers = map[string]bool{"webhook": true, "schedule": true}
+
+// validateDefinition checks a workflow definition before it is created or updated
+func (h *WorkflowsHandler) validateDefinition(triggerType, actionType, configJSON, actionChainJSON string) error {
+	if !validTriggers[triggerType] {
+		return errors.New("Invalid trigger_type. Must be 'webhook' or 'schedule'")
+	}
+	// The action's connector checks its config (required fields, connector-specific rules)
+	if err := h.executor.ValidateAction(actionType, configJSON); err != nil {
+		return err
+	}
+
+	// Reject simulated delays the executor would refuse to run
+	if err := h.executor.ValidateTestingDelays(actionType, configJSON, actionChainJSON); err != nil {
+		return err
+	}
+	if err := h.executor.ValidateFallbacks(actionType, configJSON, actionChainJSON); err != nil {
+		return err
+	}
+	if err := h.executor.ValidateActionChain(actionChainJSON); err != nil {
+		return err
+	}
+	if err := engine.ValidateParallelSteps(actionChainJSON); err != nil {
+		return err
+	}
+	if err := engine.ValidateConnectorVersion(actionType, configJSON); err != nil {
+		return err
+	}
+	if err := engine.ValidateCatchUp(configJSON); err != nil {
+		return err
+	}
+	if err := engine.ValidateConnectorOptions(configJSON); err != nil {
+		return err
+	}
+	if err := engine.ValidateNotificationThrottle(configJSON); err != nil {
+		return err
+	}
+	if err := engine.ValidateFailureAlerts(configJSON); err != nil {
+		return err
+	}
+	if err := engine.ValidateWorkflowEnvironment(configJSON); err != nil {
+		return err
+	}
+	return h.executor.ValidateChainTimeouts(configJSON, actionChainJSON)
 }
 
 // DryRunWorkflow tests a workflow configuration without saving it
@@ -142,7 +322,7 @@ func (h *WorkflowsHandler) CreateWorkflow(w http.ResponseWriter, r *http.Request
 func (h *WorkflowsHandler) DryRunWorkflow(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -150,34 +330,87 @@ func (h *WorkflowsHandler) DryRunWorkflow(w http.ResponseWriter, r *http.Request
 
 	var req DryRunRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
 		return
 	}
 
-	// Validate action type
-	validActions := map[string]bool{"slack_message": true, "discord_post": true, "weather_check": true}
-	if !validActions[req.ActionType] {
-		http.Error(w, "Invalid action_type", http.StatusBadRequest)
-		return
-	}
-
-	if req.ConfigJSON == "" {
-		req.ConfigJSON = "{}"
-	}
-
 	// Create a temporary workflow for dry run (not saved to database)
 	tempWorkflow := models.Workflow{
 		ID:          "dryrun_" + uuid.New().String(),
 		UserID:      userID,
+		TenantID:    tenantID,
 		Name:        "Dry Run Test",
 		TriggerType: "webhook",
-		ActionType:  req.ActionType,
-		ConfigJSON:  req.ConfigJSON,
 		IsActive:    true,
 	}
 
+	// A stored workflow supplies the action and config the request leaves empty
+	if req.WorkflowID != "" {
+		workflow, ok := h.ownedWorkflow(w, r, req.WorkflowID)
+		if !ok {
+			return
+		}
+		tempWorkflow.Name = workflow.Name
+		tempWorkflow.ActionType = workflow.ActionType
+		tempWorkflow.ConfigJSON = workflow.ConfigJSON
+	} else if req.Fixture != "" {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "fixture requires workflow_id")
+		return
+	}
+
+	// Validate action type
+	if req.ActionType != "" || req.WorkflowID == "" {
+		if _, ok := h.executor.Connectors().Lookup(req.ActionType); !ok {
+			SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Invalid action_type")
+			return
+		}
+		tempWorkflow.ActionType = req.ActionType
+	}
+
+	if req.ConfigJSON != "" {
+		tempWorkflow.ConfigJSON = req.ConfigJSON
+	}
+	if tempWorkflow.ConfigJSON == "" {
+		tempWorkflow.ConfigJSON = "{}"
+	}
+
+	var payload string
+	if req.Fixture != "" {
+		fixture, ok := h.fixturePayload(w, req.WorkflowID, req.Fixture)
+		if !ok {
+			return
+		}
+		payload = fixture
+	}
+
 	// Execute the workflow synchronously (blocking) for dry run
-	result := h.executor.DryRun(tempWorkflow, userID, tenantID)
+	result := h.executor.DryRunWithPayload(tempWorkflow, payload, userID, tenantID)
+	h.writeDryRun(w, r, result, engine.TemplateWarnings(tempWorkflow.ConfigJSON, payload))
+}
+
+// writeDryRun writes a dry run's result: 200 when it succeeded, 400 with the error otherwise
+func (h *WorkflowsHandler) writeDryRun(w http.ResponseWriter, r *http.Request, result connectors.Result, warnings []string) {
+	response := h.dryRunResponse(r, result, warnings)
+
+	w.Header().Set("Content-Type", "application/json")
+	if response.Success {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// dryRunResponse converts a run's result to its API shape, localized for the caller
+func (h *WorkflowsHandler) dryRunResponse(r *http.Request, result connectors.Result, warnings []string) DryRunResponse {
+	// Failures are shown in the caller's language, chained steps included
+	lang, showDetail := h.messages.forRequest(r)
+	h.messages.localizeResult(&result, lang, showDetail)
+	if chainResults, ok := result.Data["chain_results"].([]connectors.Result); ok {
+		for i := range chainResults {
+			h.messages.localizeResult(&chainResults[i], lang, showDetail)
+		}
+	}
 
 	// Build response
 	response := DryRunResponse{
@@ -185,46 +418,79 @@ func (h *WorkflowsHandler) DryRunWorkflow(w http.ResponseWriter, r *http.Request
 		Message:   result.Message,
 		Duration:  result.Duration,
 		Data:      result.Data,
+		ErrorCode: result.ErrorCode,
+		Detail:    result.Detail,
 		Timestamp: result.Timestamp,
+		Warnings:  warnings,
 	}
 
 	if result.Status != "success" {
 		response.Error = result.Message
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if response.Success {
-		w.WriteHeader(http.StatusOK)
-	} else {
-		w.WriteHeader(http.StatusBadRequest)
-	}
-	json.NewEncoder(w).Encode(response)
+	return response
 }
 
-// GetWorkflows retrieves all workflows for the user
+// GetWorkflows retrieves the workflows of every user in the caller's tenant
+// ?limit=N&cursor=... returns {"items": [...], "next_cursor": "..."}; ?offset=N is deprecated
 func (h *WorkflowsHandler) GetWorkflows(w http.ResponseWriter, r *http.Request) {
-	// TODO: MULTI-TENANT - Filter by tenant_id
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	tenantID, ok := middleware.GetTenantIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	params, err := parsePageParams(r, maxPageLimit)
+	if err != nil {
+		SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, err.Error())
+		return
+	}
+
+	if !params.Paged && !params.Legacy {
+		workflows, err := h.store.GetWorkflowsByTenantID(tenantID)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "Failed to fetch workflows")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(withWebhookURLs(h.store, r, workflowResponses(workflows)))
 		return
 	}
 
-	workflows, err := h.store.GetWorkflowsByUserID(userID)
+	// Fetch one extra row to learn whether another page exists
+	page := params.PageRequest
+	page.Limit++
+	workflows, err := h.store.ListTenantWorkflows(tenantID, page)
 	if err != nil {
-		http.Error(w, "Failed to fetch workflows", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to fetch workflows")
 		return
 	}
 
+	hasMore := len(workflows) > params.Limit
+	if hasMore {
+		workflows = workflows[:params.Limit]
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(workflows)
+	if params.Legacy {
+		setOffsetDeprecation(w)
+		json.NewEncoder(w).Encode(withWebhookURLs(h.store, r, workflowResponses(workflows)))
+		return
+	}
+
+	response := ListPage{Items: withWebhookURLs(h.store, r, workflowResponses(workflows))}
+	if hasMore {
+		last := workflows[len(workflows)-1]
+		response.NextCursor = EncodeCursor(last.CreatedAt, last.ID)
+	}
+	json.NewEncoder(w).Encode(response)
 }
 
 // ToggleWorkflow enables or disables a workflow
 func (h *WorkflowsHandler) ToggleWorkflow(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -234,32 +500,42 @@ func (h *WorkflowsHandler) ToggleWorkflow(w http.ResponseWriter, r *http.Request
 	// Verify ownership
 	workflow, err := h.store.GetWorkflowByID(workflowID)
 	if err != nil {
-		http.Error(w, "Workflow not found", http.StatusNotFound)
+		writeWorkflowError(w, err)
 		return
 	}
 
-	if workflow.UserID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	if !inTenant(r, workflow) {
+		h.audit.Denied(r, models.AuditWorkflowToggle, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+		SendError(w, http.StatusForbidden, "Forbidden")
 		return
 	}
 
 	// Toggle active status
+	before := *workflow
 	newStatus := !workflow.IsActive
 	if err := h.store.UpdateWorkflowActive(workflowID, newStatus); err != nil {
-		http.Error(w, "Failed to update workflow", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to update workflow")
 		return
 	}
 
 	workflow.IsActive = newStatus
+	h.notifyChange(r, &before, workflow)
+	h.audit.Record(r, models.AuditEvent{
+		Action:       models.AuditWorkflowToggle,
+		ResourceType: models.AuditResourceWorkflow,
+		ResourceID:   workflow.ID,
+	}, map[string]interface{}{"is_active": newStatus})
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(workflow)
+	json.NewEncoder(w).Encode(withWebhookURLs(h.store, r, []WorkflowResponse{workflowResponse(workflow)})[0])
 }
 
 // DeleteWorkflow deletes a workflow
+// Members may only delete their own workflows; the tenant admin may delete any of the tenant's
 func (h *WorkflowsHandler) DeleteWorkflow(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -269,19 +545,154 @@ func (h *WorkflowsHandler) DeleteWorkflow(w http.ResponseWriter, r *http.Request
 	// Verify ownership
 	workflow, err := h.store.GetWorkflowByID(workflowID)
 	if err != nil {
-		http.Error(w, "Workflow not found", http.StatusNotFound)
+		writeWorkflowError(w, err)
 		return
 	}
 
-	if workflow.UserID != userID {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	if !inTenant(r, workflow) {
+		h.audit.Denied(r, models.AuditWorkflowDelete, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+		SendError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+	if workflow.UserID != userID && !tenantAdmin(r) {
+		h.audit.Denied(r, models.AuditWorkflowDelete, models.AuditResourceWorkflow, workflow.ID, workflow.TenantID)
+		SendError(w, http.StatusForbidden, "Only the workflow's creator or a tenant admin can delete it")
 		return
 	}
 
 	if err := h.store.DeleteWorkflow(workflowID); err != nil {
-		http.Error(w, "Failed to delete workflow", http.StatusInternalServerError)
+		SendError(w, http.StatusInternalServerError, "Failed to delete workflow")
 		return
 	}
 
+	if teardown := h.deleted(r, workflow, false); teardown != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"deleted": true, "kong": teardown})
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetWorkflowRequirements returns the credential onboarding checklist for a workflow
+// Lists every credential service the primary action and chain need, and which are still missing
+func (h *WorkflowsHandler) GetWorkflowRequirements(w http.ResponseWriter, r *http.Request) {
+	_, ok := middleware.GetTenantIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	workflowID := vars["id"]
+
+	// Verify ownership
+	workflow, err := h.store.GetWorkflowByID(workflowID)
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+
+	if !inTenant(r, workflow) {
+		SendError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	requirements, err := engine.CheckWorkflowRequirements(h.store, *workflow, workflow.UserID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to check workflow requirements")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requirements)
+}
+
+// DryRunDiffRequest carries the edited (unsaved) parts of a workflow
+// Omitted fields fall back to the saved workflow
+type DryRunDiffRequest struct {
+	ActionType  string                 `json:"action_type"`
+	ConfigJSON  string                 `json:"config_json"`
+	ActionChain []models.ChainedAction `json:"action_chain"`
+	Fixture     string                 `json:"fixture,omitempty"` // Replay this fixture instead of the last execution's payload
+}
+
+// DryRunDiff runs an edited workflow in sandbox mode against the payload of its
+// most recent real execution (or a named fixture) and returns how the outputs would differ
+func (h *WorkflowsHandler) DryRunDiff(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		SendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tenantID, _ := middleware.GetTenantIDFromContext(r.Context())
+
+	vars := mux.Vars(r)
+	workflowID := vars["id"]
+
+	// Verify ownership
+	workflow, err := h.store.GetWorkflowByID(workflowID)
+	if err != nil {
+		writeWorkflowError(w, err)
+		return
+	}
+
+	if !inTenant(r, workflow) {
+		SendError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req DryRunDiffRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			SendErrorCode(w, http.StatusBadRequest, utils.CodeInvalidBody, "Invalid request body")
+			return
+		}
+	}
+
+	candidate := *workflow
+	if req.ActionType != "" {
+		candidate.ActionType = req.ActionType
+	}
+	if req.ConfigJSON != "" {
+		candidate.ConfigJSON = req.ConfigJSON
+	}
+	if req.ActionChain != nil {
+		chainJSON, err := json.Marshal(req.ActionChain)
+		if err != nil {
+			SendErrorCode(w, http.StatusBadRequest, utils.CodeValidationFailed, "Invalid action_chain")
+			return
+		}
+		candidate.ActionChain = string(chainJSON)
+	}
+
+	baseline, err := h.store.GetLatestExecution(workflowID)
+	if err != nil {
+		if req.Fixture == "" {
+			SendError(w, http.StatusNotFound, "No previous execution to compare against. Trigger the workflow once, then retry.")
+			return
+		}
+		// A fixture can be replayed before the first real run; every step shows as added
+		baseline = &models.Execution{WorkflowID: workflowID}
+	}
+
+	// Replay a fixture while still comparing against the last real run's outputs
+	if req.Fixture != "" {
+		payload, ok := h.fixturePayload(w, workflowID, req.Fixture)
+		if !ok {
+			return
+		}
+		replay := *baseline
+		replay.TriggerPayload = payload
+		baseline = &replay
+	}
+
+	diff, err := h.executor.DryRunDiff(candidate, baseline, userID, tenantID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "Failed to diff against previous execution")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
@@ -0,0 +1,238 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestUpdateWorkflow edits a workflow in place and checks the ID, untouched fields
+// and execution history survive
+func TestUpdateWorkflow(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	owner, _ := database.CreateUser("owner@example.com", "hashed")
+	stranger, _ := database.CreateUser("stranger@example.com", "hashed")
+	token := userToken(t, owner.ID)
+	workflow, err := database.CreateWorkflowWithChain(owner.ID, "Orders", "webhook", "testing",
+		`{"testing_response_json": "{\"ok\": true}"}`, `[{"action_type": "testing", "config": {}}]`)
+	if err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+	executedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	database.UpdateWorkflowLastExecuted(workflow.ID, executedAt, nil)
+	url := srv.URL + "/api/workflows/" + workflow.ID
+
+	if status := call(t, "PUT", url, userToken(t, stranger.ID), map[string]string{"name": "Mine now"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected another user's update to be rejected, got %d", status)
+	}
+	if status := call(t, "PUT", url, token, map[string]string{"action_type": "carrier_pigeon"}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid action type to be rejected, got %d", status)
+	}
+	if status := call(t, "PUT", url, token, map[string]string{"trigger_type": "email"}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid trigger type to be rejected, got %d", status)
+	}
+	if status := call(t, "PUT", srv.URL+"/api/workflows/missing", token, map[string]string{"name": "x"}, nil); status != http.StatusNotFound {
+		t.Errorf("Expected a missing workflow to be 404, got %d", status)
+	}
+
+	// Renaming leaves the config and chain alone
+	var updated handlers.WorkflowResponse
+	if status := call(t, "PUT", url, token, map[string]string{"name": "Orders v2"}, &updated); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if updated.ID != workflow.ID || updated.Name != "Orders v2" || updated.ConfigJSON != workflow.ConfigJSON || updated.ActionChain != workflow.ActionChain {
+		t.Errorf("Expected only the name to change, got %+v", updated)
+	}
+	if updated.LastExecutedAt == nil || !updated.LastExecutedAt.Equal(executedAt) {
+		t.Errorf("Expected last_executed_at %v to be kept, got %v", executedAt, updated.LastExecutedAt)
+	}
+	if !updated.IsActive || updated.WebhookURL != srv.URL+"/api/webhooks/"+workflow.ID {
+		t.Errorf("Expected the workflow to stay active at the same webhook URL, got %+v", updated)
+	}
+
+	// An empty chain removes it; a new config replaces the old one
+	updated = handlers.WorkflowResponse{}
+	call(t, "PUT", url, token, map[string]interface{}{"config_json": `{"testing_status_code": 201}`, "action_chain": []interface{}{}}, &updated)
+	if updated.ConfigJSON != `{"testing_status_code": 201}` || updated.ActionChain != "" || updated.Name != "Orders v2" {
+		t.Errorf("Expected a new config without a chain, got %+v", updated)
+	}
+
+	stored, _ := database.GetWorkflowByID(workflow.ID)
+	if stored.Name != "Orders v2" || stored.ActionChain != "" || stored.LastExecutedAt == nil {
+		t.Errorf("Expected the update to be stored, got %+v", stored)
+	}
+}
+
+// TestCreateWorkflowWithChain creates workflows with action chains through the API and
+// checks invalid chains are rejected before anything is saved
+func TestCreateWorkflowWithChain(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    mockStore,
+		Executor: engine.NewExecutor(mockStore, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	user, _ := mockStore.CreateUser("chain@example.com", "hashed")
+	token := userToken(t, user.ID)
+	url := srv.URL + "/api/workflows"
+
+	chain := []map[string]interface{}{
+		{"action_type": "slack_message", "config": map[string]interface{}{"slack_message": "Order received"}},
+		{"action_type": "discord_post", "config": map[string]interface{}{}, "use_data_from": "previous"},
+		{"action_type": "testing", "config": map[string]interface{}{}},
+	}
+	var created handlers.WorkflowResponse
+	status := call(t, "POST", url, token, map[string]interface{}{
+		"name": "Orders", "trigger_type": "webhook", "action_type": "testing", "action_chain": chain,
+	}, &created)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", status)
+	}
+	stored, _ := mockStore.GetWorkflowByID(created.ID)
+	if stored == nil || stored.ActionChain == "" {
+		t.Fatalf("Expected the chain to be stored, got %+v", stored)
+	}
+	// No credentials are connected yet, so the checklist lists both chained services as missing
+	if checklist := created.Requirements; checklist == nil || checklist.Ready || len(checklist.Requirements) != 2 ||
+		checklist.Requirements[0].Service != "slack" || checklist.Requirements[1].Status != "missing" {
+		t.Errorf("Expected slack and discord missing in the checklist, got %+v", checklist)
+	}
+
+	var workflows []handlers.WorkflowResponse
+	call(t, "GET", url, token, nil, &workflows)
+	if len(workflows) != 1 || len(workflows[0].ParsedChain) != 3 {
+		t.Fatalf("Expected the parsed chain in the list, got %+v", workflows)
+	}
+	if step := workflows[0].ParsedChain[1]; step.ActionType != "discord_post" || step.UseDataFrom != "previous" {
+		t.Errorf("Expected step 2 to post to Discord with the previous data, got %+v", step)
+	}
+
+	invalid := map[string][]map[string]interface{}{
+		"unsupported action in step 2": {chain[0], {"action_type": "carrier_pigeon", "config": map[string]interface{}{}}, chain[2]},
+		"unknown data source":          {chain[0], {"action_type": "testing", "use_data_from": "first"}},
+		"too many steps":               append(append(append(append([]map[string]interface{}{}, chain...), chain...), chain...), chain...),
+	}
+	for name, steps := range invalid {
+		status := call(t, "POST", url, token, map[string]interface{}{
+			"name": "Invalid " + name, "trigger_type": "webhook", "action_type": "testing", "action_chain": steps,
+		}, nil)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+	if len(mockStore.Workflows) != 1 {
+		t.Errorf("Expected invalid chains not to be saved, got %d workflows", len(mockStore.Workflows))
+	}
+}
+
+// TestCreateWorkflowIdempotent double-submits the create form with one X-Idempotency-Key:
+// the second gets the first response replayed and only one workflow is saved
+func TestCreateWorkflowIdempotent(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:       mockStore,
+		Executor:    engine.NewExecutor(mockStore, testLogger),
+		Logger:      testLogger,
+		Idempotency: middleware.NewIdempotencyManager(time.Hour, 100),
+	}))
+	defer srv.Close()
+
+	user, _ := mockStore.CreateUser("form@example.com", "hashed")
+	other, _ := mockStore.CreateUser("other@example.com", "hashed")
+
+	create := func(token, name, key string) (*http.Response, handlers.WorkflowResponse) {
+		body := strings.NewReader(`{"name": "` + name + `", "trigger_type": "webhook", "action_type": "testing"}`)
+		req, _ := http.NewRequest("POST", srv.URL+"/api/workflows", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Idempotency-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var created handlers.WorkflowResponse
+		json.NewDecoder(resp.Body).Decode(&created)
+		return resp, created
+	}
+
+	first, created := create(userToken(t, user.ID), "Orders", "form-submit-1")
+	second, replayed := create(userToken(t, user.ID), "Orders", "form-submit-1")
+	if first.StatusCode != http.StatusCreated || first.Header.Get("X-Idempotency-Replay") != "" {
+		t.Fatalf("Expected the first submit to create the workflow, got %d %v", first.StatusCode, first.Header)
+	}
+	if second.StatusCode != http.StatusCreated || second.Header.Get("X-Idempotency-Replay") != "true" || replayed.ID != created.ID {
+		t.Errorf("Expected the second submit to replay the first, got %d %v: %+v", second.StatusCode, second.Header, replayed)
+	}
+	if len(mockStore.Workflows) != 1 {
+		t.Fatalf("Expected one workflow, got %d", len(mockStore.Workflows))
+	}
+
+	// Keys are per user: another user's identical key is a new request
+	if resp, _ := create(userToken(t, other.ID), "Refunds", "form-submit-1"); resp.StatusCode != http.StatusCreated || resp.Header.Get("X-Idempotency-Replay") != "" || len(mockStore.Workflows) != 2 {
+		t.Errorf("Expected another user's key not to collide, got %d %v with %d workflows", resp.StatusCode, resp.Header, len(mockStore.Workflows))
+	}
+}
+
+// TestErrorCodes checks error responses carry the envelope with a machine-readable code
+func TestErrorCodes(t *testing.T) {
+	database := dbtest.New(t)
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    database,
+		Executor: engine.NewExecutor(database, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	user, _ := database.CreateUser("codes@example.com", "hashed")
+	token := userToken(t, user.ID)
+	workflow, _ := database.CreateWorkflow(user.ID, "Coded errors", "webhook", "testing", `{}`)
+	scheduled, _ := database.CreateWorkflow(user.ID, "Scheduled", "schedule", "testing", `{"interval": 60}`)
+
+	for _, c := range []struct {
+		name, method, path string
+		body               interface{}
+		status             int
+		code               string
+	}{
+		{"missing workflow", "DELETE", "/api/workflows/wf_missing", nil, http.StatusNotFound, "workflow_not_found"},
+		{"missing fields", "POST", "/api/workflows", map[string]interface{}{"name": "No trigger"}, http.StatusBadRequest, "validation_failed"},
+		{"malformed body", "POST", "/api/workflows", "not an object", http.StatusBadRequest, "invalid_body"},
+		{"wrong password", "POST", "/api/auth/login", map[string]interface{}{"email": "codes@example.com", "password": "wrong"}, http.StatusUnauthorized, "invalid_credentials"},
+		{"missing credential", "DELETE", "/api/credentials/cred_missing", nil, http.StatusNotFound, "credential_not_found"},
+		{"invalid invite", "POST", "/api/auth/register-with-invite", map[string]interface{}{"token": "not-a-token", "password": "invited-pass"}, http.StatusBadRequest, "validation_failed"},
+		{"malformed webhook security", "PUT", "/api/workflows/" + workflow.ID + "/webhook/security", "not an object", http.StatusBadRequest, "invalid_body"},
+		{"webhook security off a schedule", "PUT", "/api/workflows/" + scheduled.ID + "/webhook/security", map[string]interface{}{"schemes": []string{"token"}}, http.StatusBadRequest, "validation_failed"},
+		{"capture limit", "PUT", "/api/workflows/" + workflow.ID + "/debug/requests", map[string]interface{}{"debug_requests": true, "limit": 99}, http.StatusBadRequest, "validation_failed"},
+		{"missing execution", "GET", "/api/workflows/" + workflow.ID + "/executions/exec_missing", nil, http.StatusNotFound, "not_found"},
+	} {
+		var response handlers.JSONResponse
+		status := call(t, c.method, srv.URL+c.path, token, c.body, &response)
+		if status != c.status || response.Success || response.Code != c.code || response.Error == "" {
+			t.Errorf("%s: expected %d %s, got %d %+v", c.name, c.status, c.code, status, response)
+		}
+	}
+}
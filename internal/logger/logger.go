@@ -1,10 +1,13 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"os"
 	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/version"
 )
 
 // LogLevel represents the severity of a log entry
@@ -25,7 +28,10 @@ type LogEntry struct {
 	UserID     string                 `json:"user_id,omitempty"`
 	TenantID   string                 `json:"tenant_id,omitempty"`   // Multi-tenant ready!
 	WorkflowID string                 `json:"workflow_id,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"` // X-Request-ID of the API request behind the entry
 	Service    string                 `json:"service"`
+	Version    string                 `json:"version"`     // Build that wrote the entry (correlate with deploys)
+	InstanceID string                 `json:"instance_id"` // Host or INSTANCE_ID that wrote the entry
 	Meta       map[string]interface{} `json:"meta,omitempty"`
 }
 
@@ -76,6 +82,16 @@ func (l *Logger) WorkflowLog(level LogLevel, message, workflowID, userID, tenant
 	l.output(entry)
 }
 
+// WorkflowLogContext is WorkflowLog with the request ID carried by ctx, if any
+func (l *Logger) WorkflowLogContext(ctx context.Context, level LogLevel, message, workflowID, userID, tenantID string, meta map[string]interface{}) {
+	entry := l.buildEntry(level, message, meta)
+	entry.WorkflowID = workflowID
+	entry.UserID = userID
+	entry.TenantID = tenantID
+	entry.RequestID = RequestIDFromContext(ctx)
+	l.output(entry)
+}
+
 // log is the internal logging method
 func (l *Logger) log(level LogLevel, message string, meta map[string]interface{}) {
 	entry := l.buildEntry(level, message, meta)
@@ -85,11 +101,13 @@ func (l *Logger) log(level LogLevel, message string, meta map[string]interface{}
 // buildEntry constructs a log entry
 func (l *Logger) buildEntry(level LogLevel, message string, meta map[string]interface{}) LogEntry {
 	return LogEntry{
-		Timestamp: time.Now().UTC(),
-		Level:     level,
-		Message:   message,
-		Service:   l.service,
-		Meta:      meta,
+		Timestamp:  time.Now().UTC(),
+		Level:      level,
+		Message:    message,
+		Service:    l.service,
+		Version:    version.Version,
+		InstanceID: version.InstanceID(),
+		Meta:       meta,
 	}
 }
 
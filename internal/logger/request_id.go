@@ -0,0 +1,22 @@
+package logger
+
+import "context"
+
+// requestIDKey is the context key for the ID of the API request a piece of work belongs to
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the API request being served
+// It is set by middleware.RequestID and travels with the workflow runs the request
+// triggers, so their log entries can be correlated with it
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID set with WithRequestID, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Collector holds the executor and scheduler metrics exposed at /metrics
+type Collector struct {
+	*Registry
+	executed       *Counter
+	duration       *Histogram
+	schedulerTicks *Counter
+}
+
+// NewCollector registers the engine metrics on a fresh registry
+func NewCollector() *Collector {
+	registry := NewRegistry()
+	return &Collector{
+		Registry:       registry,
+		executed:       registry.NewCounter("workflows_executed_total", "Workflow runs by action type and final status, dry runs included.", "action_type", "status"),
+		duration:       registry.NewHistogram("workflow_duration_seconds", "Time from the start of a workflow run to its result.", DefaultDurationBuckets, "action_type"),
+		schedulerTicks: registry.NewCounter("scheduler_ticks_total", "Scheduler checks for due workflows."),
+	}
+}
+
+// ObserveExecution counts a finished run and records how long it took
+func (c *Collector) ObserveExecution(actionType, status string, duration time.Duration) {
+	c.executed.Inc(actionType, status)
+	c.duration.Observe(duration.Seconds(), actionType)
+}
+
+// SchedulerTick counts one scheduler check
+func (c *Collector) SchedulerTick() {
+	c.schedulerTicks.Inc()
+}
+
+// WatchQueueLength exposes worker_queue_length, read from queueLength on every scrape
+func (c *Collector) WatchQueueLength(queueLength func() int) {
+	c.NewGaugeFunc("worker_queue_length", "Jobs waiting for a worker.", func() float64 {
+		return float64(queueLength())
+	})
+}
+
+// WatchWriteRetries exposes db_write_retries_total, read from writeRetries on every scrape
+func (c *Collector) WatchWriteRetries(writeRetries func() uint64) {
+	c.NewCounterFunc("db_write_retries_total", "Database writes retried because the database was busy or locked.", func() float64 {
+		return float64(writeRetries())
+	})
+}
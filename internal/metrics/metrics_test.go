@@ -0,0 +1,103 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/metrics"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+)
+
+// TestScrapeAfterDryRuns runs two dry runs and checks /metrics counts them by action type
+// and status
+func TestScrapeAfterDryRuns(t *testing.T) {
+	mockStore := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(mockStore, testLogger)
+	srv := httptest.NewServer(server.NewRouter(server.Config{Store: mockStore, Executor: executor, Logger: testLogger}))
+	defer srv.Close()
+
+	user, _ := mockStore.CreateUser("metrics@example.com", "hashed")
+	tenantID := models.DefaultTenantID(user.ID)
+	if result := executor.DryRun(models.Workflow{UserID: user.ID, ActionType: "testing", ConfigJSON: `{}`}, user.ID, tenantID); result.Status != "success" {
+		t.Fatalf("Expected the testing dry run to succeed, got %+v", result)
+	}
+	// No Slack credential is saved, so this one fails
+	if result := executor.DryRun(models.Workflow{UserID: user.ID, ActionType: "slack_message", ConfigJSON: `{"slack_message": "hi"}`}, user.ID, tenantID); result.Status != "failed" {
+		t.Fatalf("Expected the Slack dry run to fail, got %+v", result)
+	}
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected the text exposition format, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	scrape := string(body)
+	for _, want := range []string{
+		"# TYPE workflows_executed_total counter",
+		`workflows_executed_total{action_type="testing",status="success"} 1`,
+		`workflows_executed_total{action_type="slack_message",status="failed"} 1`,
+		`workflow_duration_seconds_bucket{action_type="testing",le="+Inf"} 1`,
+		`workflow_duration_seconds_count{action_type="slack_message"} 1`,
+		"worker_queue_length 0",
+		"scheduler_ticks_total 0",
+	} {
+		if !strings.Contains(scrape, want) {
+			t.Errorf("Expected the scrape to contain %q, got:\n%s", want, scrape)
+		}
+	}
+}
+
+// TestHistogramBuckets checks bucket counts are cumulative
+func TestHistogramBuckets(t *testing.T) {
+	registry := metrics.NewRegistry()
+	histogram := registry.NewHistogram("run_seconds", "Run time.", []float64{0.1, 1}, "kind")
+	histogram.Observe((50 * time.Millisecond).Seconds(), "a")
+	histogram.Observe(0.5, "a")
+	histogram.Observe(5, "a")
+
+	var out strings.Builder
+	if err := registry.Write(&out); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	for _, want := range []string{
+		`run_seconds_bucket{kind="a",le="0.1"} 1`,
+		`run_seconds_bucket{kind="a",le="1"} 2`,
+		`run_seconds_bucket{kind="a",le="+Inf"} 3`,
+		`run_seconds_sum{kind="a"} 5.55`,
+		`run_seconds_count{kind="a"} 3`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestCounterFunc reads a counter kept elsewhere on every scrape
+func TestCounterFunc(t *testing.T) {
+	registry := metrics.NewRegistry()
+	retries := 0
+	registry.NewCounterFunc("retries_total", "Retried writes.", func() float64 { return float64(retries) })
+	retries = 3
+
+	var out strings.Builder
+	if err := registry.Write(&out); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if want := "# TYPE retries_total counter\nretries_total 3\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q, got:\n%s", want, out.String())
+	}
+}
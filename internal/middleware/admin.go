@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// RequireAdmin restricts routes to platform administrators
+// Must run after AuthMiddleware; isAdmin decides from the authenticated user ID
+// Impersonation tokens never reach admin routes, even when the target is an admin
+func RequireAdmin(log *logger.Logger, isAdmin func(userID string) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if impersonator, _, impersonated := GetImpersonationFromContext(r.Context()); impersonated {
+				log.Warn("Admin access denied to impersonation session", map[string]interface{}{
+					"user_id":         userID,
+					"impersonator_id": impersonator,
+					"path":            r.URL.Path,
+					"method":          r.Method,
+				})
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			if !isAdmin(userID) {
+				log.Warn("Admin access denied", map[string]interface{}{
+					"user_id": userID,
+					"path":    r.URL.Path,
+					"method":  r.Method,
+				})
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -16,8 +17,16 @@ type ContextKey string
 const (
 	// UserIDKey is the context key for user ID
 	UserIDKey ContextKey = "user_id"
-	// TenantIDKey is the context key for tenant ID (MULTI-TENANT READY!)
+	// TenantIDKey is the context key for tenant ID
 	TenantIDKey ContextKey = "tenant_id"
+	// RoleKey is the context key for the user's role within the tenant
+	RoleKey ContextKey = "role"
+	// ImpersonatorKey is the context key for the admin behind an impersonation token
+	ImpersonatorKey ContextKey = "impersonator"
+	// ImpersonationSessionKey is the context key for the impersonation session ID
+	ImpersonationSessionKey ContextKey = "impersonation_session"
+	// TokenVersionKey is the context key for the user's token version the token was issued at
+	TokenVersionKey ContextKey = "token_version"
 )
 
 var jwtSecret = []byte("ipaas-jwt-secret-change-in-production")
@@ -98,24 +107,69 @@ func AuthMiddleware(log *logger.Logger) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Extract tenant_id (optional for now, required in multi-tenant phase)
-			tenantID, _ := claims["tenant_id"].(string)
-			if tenantID == "" {
-				// MIGRATION PHASE: For backwards compatibility, derive tenant from user
-				// In Phase 1 (current): Each user is their own tenant
-				// In Phase 2 (multi-tenant): This would come from JWT
-				tenantID = "tenant_" + userID
+			// Extract tenant_id (required; tokens issued before tenants existed must sign in again)
+			tenantID, ok := claims["tenant_id"].(string)
+			if !ok || tenantID == "" {
+				log.Warn("Missing tenant_id in token", map[string]interface{}{
+					"path":    r.URL.Path,
+					"user_id": userID,
+				})
+				http.Error(w, "Invalid tenant_id in token", http.StatusUnauthorized)
+				return
+			}
+
+			// Extract role; tokens issued before roles existed act as members until the user signs in again
+			role, _ := claims["role"].(string)
+			if role != models.RoleAdmin {
+				role = models.RoleMember
+			}
+
+			// Token version the user was at when the token was issued; tokens from before versions existed are at 0
+			tokenVersion, _ := claims["tv"].(float64)
+
+			// Impersonation tokens carry the admin and the session that must stay active
+			impersonator, _ := claims["impersonator"].(string)
+			sessionID, _ := claims["sid"].(string)
+			if impersonator != "" && sessionID == "" {
+				log.Warn("Impersonation token without a session", map[string]interface{}{
+					"path":         r.URL.Path,
+					"user_id":      userID,
+					"impersonator": impersonator,
+				})
+				http.Error(w, "Invalid impersonation token", http.StatusUnauthorized)
+				return
+			}
+
+			// Dev-login tokens only come from development servers; flag any use of one
+			if dev, _ := claims["dev"].(bool); dev {
+				log.Warn("Request authenticated with a dev-login token", map[string]interface{}{
+					"path":      r.URL.Path,
+					"method":    r.Method,
+					"user_id":   userID,
+					"tenant_id": tenantID,
+				})
 			}
 
 			// Log successful authentication with context
-			log.InfoWithContext("Request authenticated", userID, tenantID, map[string]interface{}{
+			meta := map[string]interface{}{
 				"path":   r.URL.Path,
 				"method": r.Method,
-			})
+			}
+			if impersonator != "" {
+				meta["impersonator_id"] = impersonator
+				meta["impersonation_session"] = sessionID
+			}
+			log.InfoWithContext("Request authenticated", userID, tenantID, meta)
 
 			// Add both user_id and tenant_id to request context
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 			ctx = context.WithValue(ctx, TenantIDKey, tenantID)
+			ctx = context.WithValue(ctx, RoleKey, role)
+			ctx = context.WithValue(ctx, TokenVersionKey, int(tokenVersion))
+			if impersonator != "" {
+				ctx = context.WithValue(ctx, ImpersonatorKey, impersonator)
+				ctx = context.WithValue(ctx, ImpersonationSessionKey, sessionID)
+			}
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -128,12 +182,32 @@ func GetUserIDFromContext(ctx context.Context) (string, bool) {
 	return userID, ok
 }
 
-// GetTenantIDFromContext extracts tenant_id from request context (MULTI-TENANT READY!)
+// GetTenantIDFromContext extracts tenant_id from request context
 func GetTenantIDFromContext(ctx context.Context) (string, bool) {
 	tenantID, ok := ctx.Value(TenantIDKey).(string)
 	return tenantID, ok
 }
 
+// GetRoleFromContext extracts the user's tenant role from request context
+func GetRoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(RoleKey).(string)
+	return role, ok
+}
+
+// GetTokenVersionFromContext extracts the token version the request's token was issued at
+func GetTokenVersionFromContext(ctx context.Context) (int, bool) {
+	version, ok := ctx.Value(TokenVersionKey).(int)
+	return version, ok
+}
+
+// GetImpersonationFromContext returns the admin and session behind an impersonated request
+// ok is false for a user's own token
+func GetImpersonationFromContext(ctx context.Context) (impersonatorID, sessionID string, ok bool) {
+	impersonatorID, _ = ctx.Value(ImpersonatorKey).(string)
+	sessionID, _ = ctx.Value(ImpersonationSessionKey).(string)
+	return impersonatorID, sessionID, impersonatorID != ""
+}
+
 // GetUserAndTenantFromContext extracts both IDs (convenience method)
 func GetUserAndTenantFromContext(ctx context.Context) (userID, tenantID string, ok bool) {
 	userID, ok1 := GetUserIDFromContext(ctx)
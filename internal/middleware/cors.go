@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/rs/cors"
+)
+
+// CORS applies CORS options that can be replaced while the server is running
+type CORS struct {
+	mu      sync.RWMutex
+	handler *cors.Cors
+	debug   bool
+}
+
+// NewCORS creates the middleware with the initial options
+func NewCORS(options cors.Options, debug bool) *CORS {
+	c := &CORS{debug: debug}
+	c.SetOptions(options)
+	return c
+}
+
+// SetOptions swaps in new options; requests already in flight finish with the old ones
+func (c *CORS) SetOptions(options cors.Options) {
+	options.Debug = c.debug
+	handler := cors.New(options)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handler = handler
+}
+
+// Handler wraps next with the current CORS options
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		handler := c.handler
+		c.mu.RUnlock()
+		handler.ServeHTTP(w, r, next.ServeHTTP)
+	})
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"container/list"
 	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
@@ -8,6 +9,12 @@ import (
 	"time"
 )
 
+// DefaultIdempotencyTTL is how long a response is replayed for its key
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyMaxEntries caps the cached responses; the least recently used go first
+const DefaultIdempotencyMaxEntries = 10000
+
 // IdempotencyResult represents a cached result
 type IdempotencyResult struct {
 	StatusCode int
@@ -16,24 +23,41 @@ type IdempotencyResult struct {
 	Timestamp  time.Time
 }
 
+// idempotencyEntry is a key's cached result, or the request still producing it
+type idempotencyEntry struct {
+	key     string
+	result  *IdempotencyResult // Nil while the first request is in flight
+	done    chan struct{}      // Closed once result is set (or the request gave up)
+	element *list.Element
+}
+
 // IdempotencyManager manages idempotency keys to prevent duplicate operations
 // Solves the "double-click" problem in distributed systems
+// The cache is process-local: behind several API instances a retry may miss it
 type IdempotencyManager struct {
-	cache map[string]*IdempotencyResult
-	mu    sync.RWMutex
-	ttl   time.Duration // How long to cache results
+	cache      map[string]*idempotencyEntry
+	lru        *list.List // Front = most recently used
+	mu         sync.Mutex
+	ttl        time.Duration // How long to cache results
+	maxEntries int
 }
 
 // NewIdempotencyManager creates a new idempotency manager
-func NewIdempotencyManager(ttl time.Duration) *IdempotencyManager {
+// Results are kept for ttl, and at most maxEntries of them (<= 0 = DefaultIdempotencyMaxEntries)
+func NewIdempotencyManager(ttl time.Duration, maxEntries int) *IdempotencyManager {
+	if maxEntries <= 0 {
+		maxEntries = DefaultIdempotencyMaxEntries
+	}
 	im := &IdempotencyManager{
-		cache: make(map[string]*IdempotencyResult),
-		ttl:   ttl,
+		cache:      make(map[string]*idempotencyEntry),
+		lru:        list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
 	}
-	
+
 	// Start cleanup goroutine
 	go im.cleanup()
-	
+
 	return im
 }
 
@@ -41,43 +65,103 @@ func NewIdempotencyManager(ttl time.Duration) *IdempotencyManager {
 func (im *IdempotencyManager) cleanup() {
 	ticker := time.NewTicker(1 * time.Hour)
 	defer ticker.Stop()
-	
+
 	for range ticker.C {
 		im.mu.Lock()
 		now := time.Now()
-		for key, result := range im.cache {
-			if now.Sub(result.Timestamp) > im.ttl {
-				delete(im.cache, key)
+		for _, entry := range im.cache {
+			if entry.result != nil && now.Sub(entry.result.Timestamp) > im.ttl {
+				im.remove(entry)
 			}
 		}
 		im.mu.Unlock()
 	}
 }
 
+// remove drops an entry; callers hold im.mu
+func (im *IdempotencyManager) remove(entry *idempotencyEntry) {
+	im.lru.Remove(entry.element)
+	delete(im.cache, entry.key)
+}
+
 // Get retrieves a cached result
 func (im *IdempotencyManager) Get(key string) (*IdempotencyResult, bool) {
-	im.mu.RLock()
-	defer im.mu.RUnlock()
-	
-	result, exists := im.cache[key]
-	if !exists {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	entry, exists := im.cache[key]
+	if !exists || entry.result == nil {
 		return nil, false
 	}
-	
+
 	// Check if expired
-	if time.Since(result.Timestamp) > im.ttl {
+	if time.Since(entry.result.Timestamp) > im.ttl {
+		im.remove(entry)
 		return nil, false
 	}
-	
-	return result, true
+
+	im.lru.MoveToFront(entry.element)
+	return entry.result, true
 }
 
 // Set caches a result
 func (im *IdempotencyManager) Set(key string, result *IdempotencyResult) {
 	im.mu.Lock()
 	defer im.mu.Unlock()
-	
-	im.cache[key] = result
+
+	entry, exists := im.cache[key]
+	if !exists {
+		entry = im.add(key)
+	}
+	entry.result = result
+	im.finish(entry)
+}
+
+// add stores a new in-flight entry, evicting the least recently used past the cap
+// Callers hold im.mu
+func (im *IdempotencyManager) add(key string) *idempotencyEntry {
+	entry := &idempotencyEntry{key: key, done: make(chan struct{})}
+	entry.element = im.lru.PushFront(entry)
+	im.cache[key] = entry
+	for im.lru.Len() > im.maxEntries {
+		im.remove(im.lru.Back().Value.(*idempotencyEntry))
+	}
+	return entry
+}
+
+// finish wakes the requests waiting on an entry; callers hold im.mu
+func (im *IdempotencyManager) finish(entry *idempotencyEntry) {
+	select {
+	case <-entry.done:
+	default:
+		close(entry.done)
+	}
+}
+
+// begin claims a key for a request, or returns the entry another request already claimed
+func (im *IdempotencyManager) begin(key string) (entry *idempotencyEntry, claimed bool) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if entry, exists := im.cache[key]; exists {
+		if entry.result == nil || time.Since(entry.result.Timestamp) <= im.ttl {
+			im.lru.MoveToFront(entry.element)
+			return entry, false
+		}
+		im.remove(entry)
+	}
+	return im.add(key), true
+}
+
+// abandon releases a claimed key without a result, so a retry runs the request again
+func (im *IdempotencyManager) abandon(entry *idempotencyEntry) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if im.cache[entry.key] == entry {
+		im.remove(entry)
+	}
+	im.finish(entry)
 }
 
 // GenerateKey generates an idempotency key from request details
@@ -87,7 +171,22 @@ func (im *IdempotencyManager) GenerateKey(method, path, body string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
+// scopedKey is the cache key for a client's X-Idempotency-Key: the same key from another
+// user, or sent to another route, is a different request
+func scopedKey(r *http.Request, idempotencyKey string) string {
+	userID, _ := GetUserIDFromContext(r.Context()) // Empty on public routes such as webhooks
+	h := sha256.New()
+	for _, part := range []string{userID, r.Method, r.URL.Path, idempotencyKey} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // IdempotencyMiddleware provides idempotency for POST/PUT/PATCH requests
+// A repeated X-Idempotency-Key gets the first response again with X-Idempotency-Replay: true;
+// a repeat that arrives while the first is still running waits for it. 5xx responses are
+// not kept, so the client's retry runs the request again
 func (im *IdempotencyManager) IdempotencyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Only apply to mutating methods
@@ -103,42 +202,84 @@ func (im *IdempotencyManager) IdempotencyMiddleware(next http.Handler) http.Hand
 			next.ServeHTTP(w, r)
 			return
 		}
+		key := scopedKey(r, idempotencyKey)
 
 		// Check if we've seen this key before
-		if result, exists := im.Get(idempotencyKey); exists {
-			// Return cached result
-			for key, values := range result.Headers {
-				for _, value := range values {
-					w.Header().Add(key, value)
-				}
+		entry, claimed := im.begin(key)
+		for !claimed {
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
 			}
-			w.Header().Set("X-Idempotency-Replay", "true")
-			w.WriteHeader(result.StatusCode)
-			w.Write(result.Body)
-			return
+			if result, exists := im.Get(key); exists {
+				replay(w, result)
+				return
+			}
+			// The first request failed or was evicted; try to run it ourselves
+			entry, claimed = im.begin(key)
 		}
 
 		// Create a response recorder to capture the result
+		before := w.Header().Clone()
 		recorder := &ResponseRecorder{
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 			body:           []byte{},
 		}
 
-		// Process the request
+		// Process the request; a panic must not leave waiters blocked on the key
+		defer func() {
+			if p := recover(); p != nil {
+				im.abandon(entry)
+				panic(p)
+			}
+		}()
 		next.ServeHTTP(recorder, r)
+		if recorder.statusCode >= http.StatusInternalServerError {
+			im.abandon(entry)
+			return
+		}
 
-		// Cache the result
-		result := &IdempotencyResult{
+		// Cache the result, with only the headers the handler set
+		headers := http.Header{}
+		for name, values := range recorder.Header() {
+			if !equalValues(before[name], values) {
+				headers[name] = append([]string(nil), values...)
+			}
+		}
+		im.Set(key, &IdempotencyResult{
 			StatusCode: recorder.statusCode,
 			Body:       recorder.body,
-			Headers:    recorder.Header().Clone(),
+			Headers:    headers,
 			Timestamp:  time.Now(),
-		}
-		im.Set(idempotencyKey, result)
+		})
 	})
 }
 
+// replay writes a cached response
+func replay(w http.ResponseWriter, result *IdempotencyResult) {
+	for key, values := range result.Headers {
+		w.Header()[key] = values
+	}
+	w.Header().Set("X-Idempotency-Replay", "true")
+	w.WriteHeader(result.StatusCode)
+	w.Write(result.Body)
+}
+
+// equalValues reports whether two header value lists are the same
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // ResponseRecorder captures the response for caching
 type ResponseRecorder struct {
 	http.ResponseWriter
@@ -155,4 +296,3 @@ func (rr *ResponseRecorder) Write(b []byte) (int, error) {
 	rr.body = append(rr.body, b...)
 	return rr.ResponseWriter.Write(b)
 }
-
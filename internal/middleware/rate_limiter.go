@@ -1,66 +1,154 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/alexmacdonald/simple-ipass/internal/models"
 	"golang.org/x/time/rate"
 )
 
+// DefaultTenantLimitsTTL is how long a tenant's tier is trusted before it is looked up again
+const DefaultTenantLimitsTTL = 30 * time.Second
+
+// TenantLimitsStore is the part of db.Store the rate limiter reads tiers from
+type TenantLimitsStore interface {
+	GetTenantLimits(tenantID string) (*models.TenantLimits, error)
+}
+
+// tenantLimiter is one tenant's token bucket and the limits it was built from
+type tenantLimiter struct {
+	limiter   *rate.Limiter
+	limits    models.TenantLimits
+	fetchedAt time.Time // Zero forces a lookup on the next request
+}
+
 // RateLimiter manages rate limits per tenant
 // MULTI-TENANT: Different tiers get different limits
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
-	
+	tenants map[string]*tenantLimiter
+	mu      sync.Mutex
+
 	// Configuration
-	freeLimit  rate.Limit // requests per second (e.g., 5)
-	paidLimit  rate.Limit // requests per second (e.g., 50)
-	burstSize  int        // burst capacity
+	freeLimit rate.Limit // requests per second (e.g., 5)
+	paidLimit rate.Limit // requests per second for pro and enterprise (e.g., 50)
+	burstSize int        // burst capacity
+
+	store    TenantLimitsStore // Without one every tenant is on the free tier
+	cacheTTL time.Duration
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(freeLimit, paidLimit float64, burstSize int) *RateLimiter {
 	return &RateLimiter{
-		limiters:  make(map[string]*rate.Limiter),
+		tenants:   make(map[string]*tenantLimiter),
 		freeLimit: rate.Limit(freeLimit),
 		paidLimit: rate.Limit(paidLimit),
 		burstSize: burstSize,
 	}
 }
 
-// getLimiter returns or creates a rate limiter for a tenant
-func (rl *RateLimiter) getLimiter(tenantID string, isPaid bool) *rate.Limiter {
-	rl.mu.RLock()
-	limiter, exists := rl.limiters[tenantID]
-	rl.mu.RUnlock()
+// UseTenantLimits reads each tenant's tier and overrides from the store, caching them for ttl
+func (rl *RateLimiter) UseTenantLimits(store TenantLimitsStore, ttl time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.store = store
+	rl.cacheTTL = ttl
+	for _, entry := range rl.tenants {
+		entry.fetchedAt = time.Time{}
+	}
+}
+
+// Invalidate drops a tenant's cached limits, so a tier change applies to its next request
+func (rl *RateLimiter) Invalidate(tenantID string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if entry, ok := rl.tenants[tenantID]; ok {
+		entry.fetchedAt = time.Time{}
+	}
+}
 
-	if exists {
-		return limiter
+// getLimiter returns or creates a tenant's limiter, refreshing its limits once the cache expires
+func (rl *RateLimiter) getLimiter(tenantID string) (*rate.Limiter, rate.Limit) {
+	rl.mu.Lock()
+	entry, exists := rl.tenants[tenantID]
+	store := rl.store
+	stale := store != nil && (!exists || time.Since(entry.fetchedAt) >= rl.cacheTTL)
+	rl.mu.Unlock()
+
+	// Look the tier up without holding the lock; other tenants' requests go on meanwhile
+	var fetched *models.TenantLimits
+	if stale {
+		fetched, _ = store.GetTenantLimits(tenantID)
 	}
 
-	// Create new limiter
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	// Double-check after acquiring write lock
-	if limiter, exists := rl.limiters[tenantID]; exists {
-		return limiter
+	// Re-read after acquiring the lock: another request may have created it
+	entry, exists = rl.tenants[tenantID]
+	if !exists {
+		entry = &tenantLimiter{limits: models.TenantLimits{TenantID: tenantID, Tier: models.TierFree}}
+		rl.tenants[tenantID] = entry
 	}
+	if stale {
+		// A failed lookup keeps the previous limits until the next refresh
+		if fetched != nil {
+			entry.limits = *fetched
+		}
+		entry.fetchedAt = time.Now()
+	}
+
+	// A tier change starts a full bucket: an upgraded tenant shouldn't wait out the old rate
+	limit, burst := rl.limitsFor(entry.limits)
+	if entry.limiter == nil || entry.limiter.Limit() != limit || entry.limiter.Burst() != burst {
+		entry.limiter = rate.NewLimiter(limit, burst)
+	}
+	return entry.limiter, limit
+}
 
-	// Determine limit based on tier
+// limitsFor is the rate and burst for a tenant's tier and overrides
+// Callers hold rl.mu
+func (rl *RateLimiter) limitsFor(limits models.TenantLimits) (rate.Limit, int) {
 	limit := rl.freeLimit
-	if isPaid {
+	if limits.Tier == models.TierPro || limits.Tier == models.TierEnterprise {
 		limit = rl.paidLimit
 	}
+	if limits.RequestsPerSecond > 0 {
+		limit = rate.Limit(limits.RequestsPerSecond)
+	}
+	burst := rl.burstSize
+	if limits.Burst > 0 {
+		burst = limits.Burst
+	}
+	return limit, burst
+}
 
-	limiter = rate.NewLimiter(limit, rl.burstSize)
-	rl.limiters[tenantID] = limiter
-	return limiter
+// SetLimits changes the per-tier limits at runtime, including for tenants already seen
+func (rl *RateLimiter) SetLimits(freeLimit, paidLimit float64, burstSize int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.freeLimit = rate.Limit(freeLimit)
+	rl.paidLimit = rate.Limit(paidLimit)
+	rl.burstSize = burstSize
+
+	for _, entry := range rl.tenants {
+		if entry.limiter == nil {
+			continue
+		}
+		limit, burst := rl.limitsFor(entry.limits)
+		entry.limiter.SetLimit(limit)
+		entry.limiter.SetBurst(burst)
+	}
 }
 
 // RateLimitMiddleware enforces rate limits per tenant
+// X-RateLimit-Limit is the tenant's requests per second and X-RateLimit-Remaining the
+// requests it can still make right now (its bucket's whole tokens)
 func (rl *RateLimiter) RateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract tenant ID from context (set by AuthMiddleware)
@@ -71,29 +159,28 @@ func (rl *RateLimiter) RateLimitMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// TODO: MULTI-TENANT - Query tenant tier from database
-		// For now, assume all tenants are free tier
-		isPaid := false
+		// Get limiter for this tenant's tier
+		limiter, limit := rl.getLimiter(tenantID)
+		allowed := limiter.Allow()
+		tokens := limiter.Tokens()
 
-		// Get limiter for this tenant
-		limiter := rl.getLimiter(tenantID, isPaid)
+		w.Header().Set("X-RateLimit-Limit", strconv.FormatFloat(float64(limit), 'f', -1, 64))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
 
 		// Check if request is allowed
-		if !limiter.Allow() {
-			// Rate limit exceeded
+		if !allowed {
+			// Rate limit exceeded; wait until the bucket holds a whole token again
+			retryAfter := 1
+			if limit > 0 {
+				retryAfter = int(math.Max(1, math.Ceil((1-tokens)/float64(limit))))
+			}
 			w.Header().Set("Content-Type", "application/json")
-			w.Header().Set("X-RateLimit-Limit", "5")
-			w.Header().Set("X-RateLimit-Remaining", "0")
-			w.Header().Set("Retry-After", "1")
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 			w.WriteHeader(http.StatusTooManyRequests)
-			w.Write([]byte(`{"success":false,"error":"Rate limit exceeded. Please try again later."}`))
+			w.Write([]byte(`{"success":false,"error":"Rate limit exceeded. Please try again later.","code":"rate_limited"}`))
 			return
 		}
 
-		// Add rate limit headers
-		w.Header().Set("X-RateLimit-Limit", "5")
-		// Note: Getting remaining tokens requires additional logic
-
 		next.ServeHTTP(w, r)
 	})
 }
@@ -110,4 +197,3 @@ func (rl *RateLimiter) CleanupOldLimiters() {
 		}
 	}()
 }
-
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID that correlates a request with its log entries
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a client-supplied request ID
+const maxRequestIDLength = 128
+
+// RequestID reads X-Request-ID, or generates a UUID when it is missing or unusable,
+// stores it in the request context and echoes it in the response header
+// Register it before RequestLogger so every request line carries the ID
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// GetRequestIDFromContext returns the ID set by RequestID, or "" outside a request
+func GetRequestIDFromContext(ctx context.Context) string {
+	return logger.RequestIDFromContext(ctx)
+}
+
+// validRequestID accepts IDs of up to 128 printable ASCII characters without spaces,
+// so a client can't inject line breaks or other noise into the logs
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] <= ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -25,6 +25,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer (to flush streams)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // RequestLogger logs HTTP requests with status codes, execution time, and metadata
 // This provides observability for API performance and debugging
 func RequestLogger(log *logger.Logger) func(http.Handler) http.Handler {
@@ -68,6 +73,7 @@ func RequestLogger(log *logger.Logger) func(http.Handler) http.Handler {
 			"remote_addr": r.RemoteAddr,
 			"user_id":     userID,
 			"bytes_sent":  wrapped.written,
+			"request_id":  GetRequestIDFromContext(r.Context()),
 		}
 
 		switch level {
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+)
+
+// RequireRole restricts routes to users holding a tenant role (models.RoleAdmin, ...)
+// Must run after AuthMiddleware, which puts the token's role in the context
+func RequireRole(log *logger.Logger, role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if actual, _ := GetRoleFromContext(r.Context()); actual != role {
+				log.Warn("Role access denied", map[string]interface{}{
+					"user_id":       userID,
+					"role":          actual,
+					"required_role": role,
+					"path":          r.URL.Path,
+					"method":        r.Method,
+				})
+				http.Error(w, "Forbidden: requires the "+role+" role", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
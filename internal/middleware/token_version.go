@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/store"
+)
+
+// TokenVersionStore is the part of the store the token version guard needs
+type TokenVersionStore interface {
+	GetUserByID(id string) (*models.User, error)
+}
+
+// TokenVersionGuard refuses tokens issued before the user's last password change
+// Changing or resetting a password bumps the user's token version, which every
+// token carries, so sessions opened with the old password end at once
+type TokenVersionGuard struct {
+	store TokenVersionStore
+	log   *logger.Logger
+}
+
+// NewTokenVersionGuard creates a guard backed by the user store
+func NewTokenVersionGuard(store TokenVersionStore, log *logger.Logger) *TokenVersionGuard {
+	return &TokenVersionGuard{store: store, log: log}
+}
+
+// Middleware must run after AuthMiddleware; impersonation tokens are left to ImpersonationGuard,
+// which checks their session instead
+func (g *TokenVersionGuard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, impersonated := GetImpersonationFromContext(r.Context()); impersonated {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, _ := GetUserIDFromContext(r.Context())
+		version, _ := GetTokenVersionFromContext(r.Context())
+
+		// Users are never deleted, so a token for an unknown one has no version to check;
+		// the handlers find nothing of theirs
+		user, err := g.store.GetUserByID(userID)
+		if store.IsNotFound(err) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user.TokenVersion != version {
+			g.log.Warn("Rejected token issued before a password change", map[string]interface{}{
+				"user_id": userID,
+				"path":    r.URL.Path,
+			})
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
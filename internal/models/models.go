@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 // User represents a user in the system
 type User struct {
@@ -8,6 +12,23 @@ type User struct {
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"` // Never serialize password
 	CreatedAt    time.Time `json:"created_at"`
+	TenantID     string    `json:"tenant_id"` // The tenant whose workflows and credentials the user shares
+	Role         string    `json:"role"`      // RoleAdmin or RoleMember within the tenant
+	TokenVersion int       `json:"-"`         // Bumped by a password change; tokens carrying an older one are refused
+}
+
+// Tenant roles
+// The user whose sign-up created a tenant is its admin; users added to it are members
+const (
+	RoleAdmin  = "admin"  // Manages the tenant: settings, invites, Kong, and every member's workflows
+	RoleMember = "member" // Creates and runs workflows, and deletes only their own
+)
+
+// Tenant is an organization whose users share workflows, credentials and logs
+type Tenant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // Credential represents encrypted API keys/tokens for third-party services
@@ -18,12 +39,34 @@ type Credential struct {
 	EncryptedKey string    `json:"-"`            // Never expose in API
 	DecryptedKey string    `json:"api_key,omitempty"` // Only populated when needed
 	CreatedAt    time.Time `json:"created_at"`
+	TenantID     string    `json:"tenant_id,omitempty"` // Whose data key encrypts the value
+	KeyVersion   int       `json:"key_version"`         // Version of that key (0 = legacy master key)
+	Environment  string    `json:"environment"`         // 'live' or 'test' (e.g., a Salesforce sandbox token)
+}
+
+// Credential and workflow environments
+const (
+	EnvironmentLive = "live"
+	EnvironmentTest = "test"
+)
+
+// What happens when a workflow runs with a credential from the other environment
+const (
+	CredentialPolicyWarn    = "warn"    // Log a warning and run (default)
+	CredentialPolicyEnforce = "enforce" // Fail the step
+)
+
+// DefaultTenantID is the ID of the single-user tenant created for a user who signs up
+// on their own; users created before the tenants table were backfilled into it
+func DefaultTenantID(userID string) string {
+	return "tenant_" + userID
 }
 
 // Workflow represents an integration workflow
 type Workflow struct {
 	ID              string         `json:"id"`
 	UserID          string         `json:"user_id"`
+	TenantID        string         `json:"tenant_id"` // The creator's tenant; its users share the workflow
 	Name            string         `json:"name"`
 	TriggerType     string         `json:"trigger_type"`     // 'webhook', 'schedule'
 	ActionType      string         `json:"action_type"`      // Primary action: 'slack_message', 'discord_post', 'weather_check', etc.
@@ -32,10 +75,134 @@ type Workflow struct {
 	Parameters      string         `json:"parameters"`       // JSON array of runtime parameters
 	ParsedChain     []ChainedAction `json:"parsed_chain,omitempty"` // Parsed action chain (not stored in DB)
 	ParsedParameters []WorkflowParameter `json:"parsed_parameters,omitempty"` // Parsed parameters (not stored in DB)
-	TriggerPayload  string         `json:"trigger_payload,omitempty"` // JSON payload from webhook trigger for template mapping
+	// Deprecated: the trigger payload belongs to one execution and is passed to Executor.ExecuteWorkflow
+	// alongside the workflow. Never serialized: payloads are only readable through the execution trace endpoint
+	TriggerPayload  string         `json:"-"`
 	IsActive        bool           `json:"is_active"`
 	LastExecutedAt  *time.Time     `json:"last_executed_at,omitempty"`
+	NextRunAt       *time.Time     `json:"next_run_at,omitempty"` // When the scheduler runs it next (schedule triggers only)
 	CreatedAt       time.Time      `json:"created_at"`
+	Stats           *WorkflowStats `json:"stats,omitempty"` // Execution counters (not stored on the workflows table)
+	DebugRequestsUntil *time.Time  `json:"debug_requests_until,omitempty"` // Webhook requests are captured until then (see WebhookRequest)
+	DebugRequestsLimit int         `json:"debug_requests_limit,omitempty"` // How many captured requests are kept
+	PausedUntil     *time.Time     `json:"paused_until,omitempty"` // Treated as inactive until then, then resumes by itself
+}
+
+// PausedAt reports whether the workflow is paused at the given time
+func (w *Workflow) PausedAt(now time.Time) bool {
+	return w.PausedUntil != nil && now.Before(*w.PausedUntil)
+}
+
+// DebugRequestsEnabled reports whether inbound webhook requests are being captured at the given time
+func (w *Workflow) DebugRequestsEnabled(now time.Time) bool {
+	return w.DebugRequestsUntil != nil && now.Before(*w.DebugRequestsUntil)
+}
+
+// DefaultScheduleInterval is how often a schedule workflow without an interval runs
+const DefaultScheduleInterval = 10 * time.Minute
+
+// ScheduleInterval returns how often a schedule workflow runs (config interval, in minutes)
+func (w *Workflow) ScheduleInterval() (time.Duration, error) {
+	var config WorkflowConfig
+	if err := json.Unmarshal([]byte(w.ConfigJSON), &config); err != nil {
+		return 0, err
+	}
+	if config.Interval <= 0 {
+		return DefaultScheduleInterval, nil
+	}
+	return time.Duration(config.Interval) * time.Minute, nil
+}
+
+// NextRunAfter returns when a schedule workflow runs next if it last ran at ran (nil if
+// it never ran, which makes it due straight away). Nil for other triggers and for
+// configs that don't parse, which the scheduler never picks up
+// A run at or after its slot keeps the schedule on its slots, so a run the tick picked
+// up late doesn't push every later run back by the same amount
+func (w *Workflow) NextRunAfter(ran *time.Time) *time.Time {
+	if w.TriggerType != "schedule" {
+		return nil
+	}
+	interval, err := w.ScheduleInterval()
+	if err != nil {
+		return nil
+	}
+
+	var next time.Time
+	switch {
+	case ran == nil:
+		next = w.CreatedAt
+		if next.IsZero() {
+			next = time.Now()
+		}
+	case w.NextRunAt != nil && !ran.Before(*w.NextRunAt):
+		next = w.NextRunAt.Add((ran.Sub(*w.NextRunAt)/interval + 1) * interval)
+	default:
+		next = ran.Add(interval)
+	}
+	return &next
+}
+
+// WorkflowStats are lightweight execution counters for list-view sparklines
+// Maintained by the executor on every completed execution
+type WorkflowStats struct {
+	TotalExecutions     int64   `json:"total_executions"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	Successes24h        int     `json:"successes_24h"`
+	Successes7d         int     `json:"successes_7d"`
+	AvgDurationMS       float64 `json:"avg_duration_ms"`
+	LastError           string  `json:"last_error,omitempty"` // Masked and truncated
+	SuppressedExecutions int64  `json:"suppressed_executions"` // Held back by the workflow's outbound notification throttle
+}
+
+// WorkflowBaseline is the history anomaly checks compare a workflow's latest executions against
+type WorkflowBaseline struct {
+	RecentDurationMS   float64    `json:"recent_duration_ms"`   // Fast-moving average of recent executions
+	BaselineDurationMS float64    `json:"baseline_duration_ms"` // Slow-moving long-term average
+	TimedExecutions    int64      `json:"timed_executions"`
+	Hours              []StatHour `json:"hours"` // Hours with executions, oldest first
+}
+
+// RunStats summarize the logged runs of a workflow (or a tenant's workflows) within a window
+// Suppressed and skipped_overlap runs are counted by status but neither succeed nor fail
+type RunStats struct {
+	WorkflowID       string           `json:"workflow_id,omitempty"`
+	WorkflowName     string           `json:"workflow_name,omitempty"`
+	Window           string           `json:"window,omitempty"` // As requested, e.g. "7d"
+	Since            time.Time        `json:"since"`
+	TotalRuns        int64            `json:"total_runs"`
+	ByStatus         map[string]int64 `json:"by_status"`
+	Successes        int64            `json:"successes"`
+	Failures         int64            `json:"failures"`
+	SuccessRate      float64          `json:"success_rate"`       // Successes / (successes + failures), 0 without either
+	AvgDurationMS    *float64         `json:"avg_duration_ms"`    // Over runs with a logged duration; null without any
+	MedianDurationMS *float64         `json:"median_duration_ms"` // Same runs as the average
+	LastExecutedAt   *time.Time       `json:"last_executed_at"`   // Latest run, even before the window
+	FailureStreak    int64            `json:"failure_streak"`     // Failed runs since the latest success, even before the window
+	Daily            []StatDay        `json:"daily,omitempty"`    // Each UTC day of the window, oldest first
+}
+
+// StatDay is one UTC day of runs, for sparklines
+type StatDay struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Total     int64  `json:"total"`
+	Successes int64  `json:"successes"`
+	Failures  int64  `json:"failures"`
+}
+
+// RunStatsOverview rolls RunStats up across a tenant's workflows
+// The embedded totals have no workflow; their failure streak is the longest of any workflow
+type RunStatsOverview struct {
+	RunStats
+	WorkflowCount    int        `json:"workflow_count"`
+	FailingWorkflows int        `json:"failing_workflows"` // Workflows whose latest runs failed
+	Workflows        []RunStats `json:"workflows"`         // Each workflow's totals, without medians or daily counts
+}
+
+// StatHour is one hour of a workflow's execution outcomes
+type StatHour struct {
+	Hour      time.Time `json:"hour"` // Start of the hour, UTC
+	Successes int       `json:"successes"`
+	Failures  int       `json:"failures"`
 }
 
 // WorkflowParameter represents a runtime parameter for a workflow
@@ -52,8 +219,18 @@ type ChainedAction struct {
 	ActionType string                 `json:"action_type"` // 'slack_message', 'discord_post', 'twilio_sms', etc.
 	Config     map[string]interface{} `json:"config"`      // Action-specific configuration
 	UseDataFrom string                 `json:"use_data_from,omitempty"` // 'previous' to use data from previous action
+	TimeoutSeconds int                 `json:"timeout_seconds,omitempty"` // Limit for this step (0 = only the chain budget applies)
+	Parallel    []ChainedAction        `json:"parallel,omitempty"`      // Branches run at the same time instead of action_type
 }
 
+// StatusSuppressed is the status of an execution held back by its workflow's
+// outbound notification throttle (max_notifications_per_hour)
+const StatusSuppressed = "suppressed"
+
+// StatusSkippedOverlap is the status of a run skipped because the previous run of its
+// workflow was still in progress (and the workflow doesn't set allow_concurrent)
+const StatusSkippedOverlap = "skipped_overlap"
+
 // Log represents an execution log entry
 type Log struct {
 	ID         string    `json:"id"`
@@ -61,6 +238,264 @@ type Log struct {
 	Status     string    `json:"status"` // 'success', 'failed'
 	Message    string    `json:"message"`
 	ExecutedAt time.Time `json:"executed_at"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"` // User who triaged the entry
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	ErrorCode   string            `json:"error_code,omitempty"`   // Why the run failed, see connectors.ErrCode*
+	ErrorParams map[string]string `json:"error_params,omitempty"` // Values for the translated message
+	Detail      string            `json:"detail,omitempty"`       // Untranslated message, only returned to administrators
+	RequestID   string            `json:"request_id,omitempty"`   // X-Request-ID of the API request that triggered the run
+	DurationMS  *int64            `json:"duration_ms,omitempty"`  // How long the run took (nil for entries logged before durations were kept)
+}
+
+// Execution is the stored trace of one workflow run
+// Payload and result are masked before they are persisted
+type Execution struct {
+	ID             string    `json:"id"`
+	WorkflowID     string    `json:"workflow_id"`
+	Status         string    `json:"status"`
+	Message        string    `json:"message"`
+	TriggerSource  string    `json:"trigger_source"`            // 'webhook', 'schedule', 'manual' (run-now), 'dry-run'
+	TriggerPayload string    `json:"trigger_payload,omitempty"` // Masked inbound payload (JSON)
+	ResultData     string    `json:"result_data,omitempty"`     // Masked, truncated result trace (JSON)
+	DurationMS     int64     `json:"duration_ms"`
+	ExecutedAt     time.Time `json:"executed_at"`
+	Version        string    `json:"version,omitempty"`     // Build that ran the execution
+	InstanceID     string    `json:"instance_id,omitempty"` // Host or INSTANCE_ID that ran it
+	CatchUp        bool      `json:"catch_up,omitempty"`    // A scheduled run missed during downtime and run at startup
+}
+
+// WorkflowFixture is a named sample trigger payload stored with a workflow
+// Dry runs can select it by name instead of pasting the payload every time
+type WorkflowFixture struct {
+	ID         string          `json:"id"`
+	WorkflowID string          `json:"workflow_id"`
+	Name       string          `json:"name"`
+	Payload    json.RawMessage `json:"payload"` // Masked before storage
+	Source     string          `json:"source"`  // 'manual' or 'execution'
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Webhook request verdicts: what the webhook handler did with a captured request
+const (
+	WebhookVerdictExecuted           = "executed"
+	WebhookVerdictRejectedValidation = "rejected_validation" // Inactive workflow, wrong trigger type, oversized body
+	WebhookVerdictRejectedSignature  = "rejected_signature" // HMAC signature missing, wrong or stale
+	WebhookVerdictRejectedAuth       = "rejected_auth"      // Token, Basic auth or IP allowlist check failed
+	WebhookVerdictDuplicate          = "duplicate" // Event ID already seen
+	WebhookVerdictError              = "error"     // The request couldn't be processed
+)
+
+// Webhook authentication schemes; a workflow may require several at once
+const (
+	WebhookAuthHMAC        = "hmac"         // X-Webhook-Signature: t=<unix>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	WebhookAuthToken       = "token"        // Authorization: Bearer <token>, or X-Webhook-Token alongside Basic auth
+	WebhookAuthBasic       = "basic"        // HTTP Basic with a per-workflow username and password
+	WebhookAuthIPAllowlist = "ip_allowlist" // The client IP is in one of the allowed IPs/CIDRs
+)
+
+// WebhookSecurity is how a workflow's webhook authenticates its callers
+// Stored encrypted as a credential of the workflow's owner; no schemes means the webhook is open
+type WebhookSecurity struct {
+	Schemes        []string `json:"schemes"` // Every scheme listed must pass
+	HMACSecret     string   `json:"hmac_secret,omitempty"`
+	Token          string   `json:"token,omitempty"`
+	BasicUsername  string   `json:"basic_username,omitempty"`
+	BasicPassword  string   `json:"basic_password,omitempty"`
+	IPAllowlist    []string `json:"ip_allowlist,omitempty"`    // IPs or CIDRs
+	TrustedProxies []string `json:"trusted_proxies,omitempty"` // Proxies whose X-Forwarded-For entries are believed
+}
+
+// WebhookRequest is a raw inbound webhook request captured for debugging
+// Only the last few per workflow are kept, and only while capture is enabled
+type WebhookRequest struct {
+	ID            string            `json:"id"`
+	WorkflowID    string            `json:"workflow_id"`
+	ReceivedAt    time.Time         `json:"received_at"`
+	Method        string            `json:"method"`
+	Headers       map[string]string `json:"headers"`        // Sensitive values masked
+	Body          string            `json:"body"`           // Masked, at most 64KB
+	BodyTruncated bool              `json:"body_truncated"` // The body was cut, so it can't be replayed
+	Verdict       string            `json:"verdict"`
+	Detail        string            `json:"detail,omitempty"` // Why the request was rejected
+}
+
+// WebhookPayload is a JSON body that triggered a webhook workflow, kept so templates can
+// be tried against real data; only each workflow's most recent few are kept
+type WebhookPayload struct {
+	ID         string          `json:"id"`
+	WorkflowID string          `json:"workflow_id"`
+	Payload    json.RawMessage `json:"payload"` // Masked before storage
+	ReceivedAt time.Time       `json:"received_at"`
+}
+
+// DeadLetter is a workflow run the worker pool dropped because its queue stayed full
+// The payload is kept unmasked so a retry runs exactly what was triggered, and so is
+// never returned by the API
+type DeadLetter struct {
+	ID         string     `json:"id"`
+	WorkflowID string     `json:"workflow_id"`
+	Reason     string     `json:"reason"`
+	Payload    string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"` // Set once the run is resubmitted
+}
+
+// ImpersonationSession lets an administrator act as a user for support debugging
+// Tokens issued for it are only honoured while the session is active
+type ImpersonationSession struct {
+	ID           string     `json:"id"`
+	AdminID      string     `json:"admin_id"`
+	TargetUserID string     `json:"target_user_id"`
+	Reason       string     `json:"reason"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy    string     `json:"revoked_by,omitempty"`
+}
+
+// Active reports whether the session can still be used at the given time
+func (s *ImpersonationSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Audit actions
+const (
+	AuditImpersonationStart   = "impersonation.start"
+	AuditImpersonationRevoke  = "impersonation.revoke"
+	AuditHTTPRequest          = "http.request"
+	AuditEncryptionRotate     = "encryption.rotate"
+	AuditRetentionUpdate      = "retention.update"
+	AuditNotificationsUpdate  = "notifications.update"
+	AuditCanariesUpdate       = "canaries.update" // Connector version canaries changed
+	AuditMaskingUpdate        = "masking.update"
+	AuditAnomalyUpdate        = "anomaly.update"
+	AuditCredentialPolicy     = "credential_policy.update"
+	AuditPayloadRead          = "execution.payload.read" // A stored trigger payload was returned through the trace endpoint
+	AuditTenantExport         = "tenant.export"          // A data export archive was generated (or failed)
+	AuditTenantExportDownload = "tenant.export.download" // The archive was fetched through its signed link
+	AuditDomainAdd            = "domain.add"
+	AuditDomainVerify         = "domain.verify" // The domain's TXT challenge passed
+	AuditDomainRemove         = "domain.remove"
+	AuditInviteCreate         = "invite.create"
+	AuditInviteRevoke         = "invite.revoke"
+	AuditInviteAccept         = "invite.accept" // A user signed up into the tenant with the invite
+	AuditCircuitBreakerReset  = "circuit_breaker.reset"
+	AuditTenantLimitsUpdate   = "tenant.limits_update" // A platform admin changed a tenant's tier or rate limits
+	AuditWorkflowCreate       = "workflow.create"
+	AuditWorkflowUpdate       = "workflow.update"
+	AuditWorkflowToggle       = "workflow.toggle"
+	AuditWorkflowPause        = "workflow.pause" // Paused until a time, or resumed early
+	AuditWorkflowDelete       = "workflow.delete"
+	AuditLogsClear            = "logs.clear" // A workflow's log history was deleted
+	AuditCredentialCreate     = "credential.create"
+	AuditCredentialDelete     = "credential.delete"
+	AuditRegister             = "auth.register"
+	AuditLogin                = "auth.login"
+	AuditLoginFailed          = "auth.login_failed"
+	AuditDevLogin             = "auth.dev_login" // A token minted by /api/auth/dev-login (development only)
+	AuditPasswordChange       = "auth.password_change"
+	AuditPasswordResetRequest = "auth.password_reset_request" // A reset token was issued and handed to the delivery
+	AuditPasswordReset        = "auth.password_reset"         // A reset token was used to set a new password
+	AuditLogout               = "auth.logout"                 // A refresh token, or all of the user's, was revoked
+	AuditAccessDenied         = "access.denied" // A change refused because the caller may not make it; the detail names it
+)
+
+// Resource types of audit events
+const (
+	AuditResourceWorkflow   = "workflow"
+	AuditResourceCredential = "credential"
+	AuditResourceUser       = "user"
+)
+
+// AuditEvent is one entry in the audit trail
+// ImpersonatorID is set when an administrator performed the action as ActorID
+type AuditEvent struct {
+	ID             string    `json:"id"`
+	ActorID        string    `json:"actor_id"`
+	ImpersonatorID string    `json:"impersonator_id,omitempty"`
+	SessionID      string    `json:"session_id,omitempty"`
+	Action         string    `json:"action"`
+	Method         string    `json:"method,omitempty"`
+	Path           string    `json:"path,omitempty"`
+	StatusCode     int       `json:"status_code,omitempty"`
+	Detail         string    `json:"detail,omitempty"` // e.g. the reason given for an impersonation, or the masked change
+	TenantID       string    `json:"tenant_id,omitempty"`
+	ResourceType   string    `json:"resource_type,omitempty"` // AuditResourceWorkflow, AuditResourceCredential or AuditResourceUser
+	ResourceID     string    `json:"resource_id,omitempty"`
+	SourceIP       string    `json:"source_ip,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AuditFilter narrows a tenant's audit events
+type AuditFilter struct {
+	ResourceType string
+	Since        time.Time // Inclusive; zero = no lower bound
+	Until        time.Time // Exclusive; zero = no upper bound
+	Limit        int
+}
+
+// ExecutionDiff compares a candidate run against a stored execution
+type ExecutionDiff struct {
+	BaselineExecutionID string     `json:"baseline_execution_id"`
+	BaselineExecutedAt  time.Time  `json:"baseline_executed_at"`
+	CandidateStatus     string     `json:"candidate_status"`
+	Changed             bool       `json:"changed"`
+	Steps               []StepDiff `json:"steps"`
+}
+
+// StepDiff is the difference for one step (0 = primary action, 1..n = chain)
+type StepDiff struct {
+	Step   int         `json:"step"`
+	Change string      `json:"change"` // 'added', 'removed', 'changed', 'unchanged'
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// FieldDiff is a single changed field within a step's output
+type FieldDiff struct {
+	Path     string      `json:"path"`
+	Change   string      `json:"change"` // 'added', 'removed', 'changed'
+	Before   interface{} `json:"before,omitempty"`
+	After    interface{} `json:"after,omitempty"`
+	TextDiff string      `json:"text_diff,omitempty"` // Word diff for changed text: "Order [-41-]{+42+} shipped"
+}
+
+// ConfigChange is one audited update of the runtime settings
+type ConfigChange struct {
+	ID        string    `json:"id"`
+	Actor     string    `json:"actor"`   // User ID, or 'system' for SIGHUP reloads
+	Source    string    `json:"source"`  // 'api' or 'sighup'
+	Changes   string    `json:"changes"` // Masked JSON: {"setting": {"before": ..., "after": ...}}
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// AutoAcknowledgedBy marks entries acknowledged by the age-based sweep
+const AutoAcknowledgedBy = "system:auto"
+
+// LogFilter narrows log queries and bulk acknowledgment
+type LogFilter struct {
+	TenantID       string     `json:"-"` // Scope to the tenant's workflows instead of the user's
+	WorkflowID     string     `json:"workflow_id,omitempty"`
+	Status         string     `json:"status,omitempty"` // 'success', 'failed'
+	Unacknowledged bool       `json:"-"`                // Only entries nobody has acknowledged
+	From           *time.Time `json:"from,omitempty"`
+	To             *time.Time `json:"to,omitempty"`
+	Limit          int        `json:"-"`
+	Cursor         *Cursor    `json:"-"` // Return entries strictly after this one (keyset pagination)
+	Offset         int        `json:"-"` // Deprecated: use Cursor
+}
+
+// Cursor identifies the last row of a page for keyset pagination
+// Time is the row's sort timestamp (executed_at or created_at); ID breaks ties
+type Cursor struct {
+	Time time.Time
+	ID   string
+}
+
+// PageRequest describes which page of a list to return
+type PageRequest struct {
+	Limit  int
+	Cursor *Cursor // Return rows strictly after this one
+	Offset int     // Deprecated: use Cursor
 }
 
 // WorkflowWithDetails includes workflow name for log display
@@ -69,6 +504,203 @@ type WorkflowLog struct {
 	WorkflowName string `json:"workflow_name"`
 }
 
+// WorkflowCost is a workflow's estimated connector spend for one month
+type WorkflowCost struct {
+	WorkflowID   string  `json:"workflow_id"`
+	WorkflowName string  `json:"workflow_name,omitempty"` // Empty if the workflow was deleted
+	Month        string  `json:"month"`                   // 'YYYY-MM' (UTC)
+	Executions   int     `json:"executions"`
+	Cost         float64 `json:"cost"`
+	DelayMS      int64   `json:"delay_ms"` // Time spent in testing connector delays
+}
+
+// Tenant tiers; a tenant without settings is on the free tier
+const (
+	TierFree       = "free"
+	TierPro        = "pro"
+	TierEnterprise = "enterprise"
+)
+
+// Data classes a retention policy applies to
+const (
+	RetentionLogs          = "logs"           // Log entries
+	RetentionPayloads      = "payloads"       // Trigger payloads and result traces kept with executions
+	RetentionExecutions    = "executions"     // Execution records (status, duration, trace)
+	RetentionFixtures      = "fixtures"       // Saved dry-run fixtures
+	RetentionDebugRequests = "debug_requests" // Captured raw webhook requests (kept as long as payloads)
+)
+
+// RetentionPolicy is how many days each data class is kept
+// In stored overrides a zero field means "use the tier default"
+type RetentionPolicy struct {
+	LogsDays       int `json:"logs_days"`
+	PayloadsDays   int `json:"payloads_days"` // Payloads are cleared from executions that are kept longer
+	ExecutionsDays int `json:"executions_days"`
+	FixturesDays   int `json:"fixtures_days"`
+}
+
+// TenantSettings are per-tenant options stored in tenant_settings
+type TenantSettings struct {
+	TenantID         string                     `json:"tenant_id"`
+	Tier             string                     `json:"tier"`                // 'free', 'pro' or 'enterprise'
+	Retention        *RetentionPolicy           `json:"retention,omitempty"` // Overrides of the tier's retention defaults
+	Notifications    *ChangeNotifications       `json:"notifications,omitempty"`
+	Canaries         map[string]ConnectorCanary `json:"canaries,omitempty"`          // Keyed by action type
+	MaskedFields     []string                   `json:"masked_fields,omitempty"`     // Payload keys redacted for this tenant on top of the built-in secret masking
+	Anomaly          *AnomalySettings           `json:"anomaly,omitempty"`           // Overrides of the anomaly detection defaults
+	CredentialPolicy string                     `json:"credential_policy,omitempty"` // 'warn' (default) or 'enforce' for credential environment mismatches
+	LastPurge        *RetentionRun              `json:"last_purge,omitempty"`
+	UpdatedAt        time.Time                  `json:"updated_at"`
+}
+
+// TenantLimits is a tenant's tier and API rate limit overrides, kept in tenant_settings
+// Zero overrides follow the limits configured for the tier
+type TenantLimits struct {
+	TenantID          string  `json:"tenant_id"`
+	Tier              string  `json:"tier"`
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+}
+
+// AnomalySettings tunes when a workflow counts as slower or flakier than its history
+// Zero values fall back to the defaults in package anomaly
+type AnomalySettings struct {
+	Disabled       bool    `json:"disabled,omitempty"`
+	Notify         bool    `json:"notify,omitempty"`          // Also message the tenant's members (at most once per workflow per day)
+	DurationFactor float64 `json:"duration_factor,omitempty"` // Recent average duration vs. the long-term baseline
+	FailureRate    float64 `json:"failure_rate,omitempty"`    // Lowest hourly failure rate (0-1) worth alerting on
+	MinExecutions  int     `json:"min_executions,omitempty"`  // Executions in the hour before its failure rate counts
+	MinSamples     int     `json:"min_samples,omitempty"`     // Timed executions before the duration baseline is trusted
+}
+
+// ConnectorCanary routes a share of a tenant's executions of one action type to a newer connector version
+// Workflows are bucketed by ID, so a workflow stays on the same side while Percent is unchanged
+type ConnectorCanary struct {
+	Version string `json:"version"`
+	Percent int    `json:"percent"` // 0-100
+}
+
+// ChangeNotifications configures messages to a tenant's members when its workflows change
+type ChangeNotifications struct {
+	Enabled      bool                              `json:"enabled"`
+	BatchSeconds int                               `json:"batch_seconds,omitempty"` // Changes within this window share one message (default 60)
+	Members      map[string]NotificationPreference `json:"members,omitempty"`       // Keyed by user ID
+}
+
+// NotificationPreference is one member's delivery choice
+type NotificationPreference struct {
+	Channel string `json:"channel,omitempty"` // 'slack' (default) or 'discord', sent with the member's own credential
+	OptOut  bool   `json:"opt_out,omitempty"`
+}
+
+// Tenant domain verification states
+const (
+	DomainPending  = "pending" // Kong routes the host, but webhook URLs don't use it until the TXT challenge passes
+	DomainVerified = "verified"
+)
+
+// TenantDomain is a custom hostname a tenant receives webhooks on (e.g. hooks.customer.com)
+// A domain belongs to at most one tenant; Kong routes it to the webhook endpoint
+type TenantDomain struct {
+	ID                string     `json:"id"`
+	TenantID          string     `json:"tenant_id"`
+	Domain            string     `json:"domain"` // Lowercase, without a trailing dot
+	VerificationToken string     `json:"verification_token"`
+	Status            string     `json:"status"` // 'pending' or 'verified'
+	KongServiceID     string     `json:"kong_service_id,omitempty"`
+	KongRouteID       string     `json:"kong_route_id,omitempty"`
+	LastError         string     `json:"last_error,omitempty"` // Why the latest verification check failed
+	CheckedAt         *time.Time `json:"checked_at,omitempty"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// Kong object kinds, in the order they are torn down
+const (
+	KongPlugin  = "plugin"
+	KongRoute   = "route"
+	KongService = "service"
+)
+
+// KongResource is a Kong Gateway object created for a workflow, recorded so it can be
+// deleted with the workflow
+type KongResource struct {
+	ID         string    `json:"id"`
+	WorkflowID string    `json:"workflow_id"`
+	TenantID   string    `json:"tenant_id"`
+	Kind       string    `json:"kind"`    // 'service', 'route' or 'plugin'
+	KongID     string    `json:"kong_id"` // The object's ID in Kong
+	Name       string    `json:"name"`    // Service or route name, or the plugin's name
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Tenant invite states
+const (
+	InvitePending  = "pending"
+	InviteAccepted = "accepted"
+	InviteRevoked  = "revoked"
+	InviteExpired  = "expired" // Reported for pending invites past their expiry; never stored
+)
+
+// TenantInvite lets someone sign up into an existing tenant instead of a new one of their own
+// The invite token carries its ID; accepting it creates the user and uses it up
+type TenantInvite struct {
+	ID         string     `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	Email      string     `json:"email"`
+	InvitedBy  string     `json:"invited_by"`
+	Status     string     `json:"status"` // 'pending', 'accepted', 'revoked' or 'expired'
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy string     `json:"accepted_by,omitempty"` // ID of the user the invite created
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CurrentStatus is the invite's status at the given time, 'expired' once a pending invite lapses
+func (i *TenantInvite) CurrentStatus(now time.Time) string {
+	if i.Status == InvitePending && !now.Before(i.ExpiresAt) {
+		return InviteExpired
+	}
+	return i.Status
+}
+
+// PasswordReset is a single-use token for setting a new password without the old one
+// Only the token's SHA-256 hash is stored; the token itself goes to the user's delivery
+type PasswordReset struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// RefreshToken is an opaque, single-use token a client exchanges for a new access token
+// Only the token's SHA-256 hash is stored. Refreshing revokes it and issues the next one;
+// a password change (token version bump) or logout ends the session
+type RefreshToken struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	TokenHash    string     `json:"-"`
+	TokenVersion int        `json:"-"` // The user's token version when the session started
+	UserAgent    string     `json:"user_agent,omitempty"`
+	IPAddress    string     `json:"ip_address,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// RetentionRun is what one retention pass removed for a tenant
+type RetentionRun struct {
+	TenantID   string           `json:"tenant_id"`
+	Policy     RetentionPolicy  `json:"policy"` // Effective policy the pass applied
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	Removed    map[string]int64 `json:"removed"` // Rows deleted (payloads: cleared) by data class
+	Error      string           `json:"error,omitempty"`
+}
+
 // LoginRequest represents login credentials
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -81,10 +713,52 @@ type RegisterRequest struct {
 	Password string `json:"password" validate:"required,min=6,max=128"`
 }
 
+// DevLoginRequest asks /api/auth/dev-login for a token; the user is created if needed
+type DevLoginRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ChangePasswordRequest sets a new password for the signed-in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6,max=128"`
+}
+
+// ForgotPasswordRequest asks for a reset token for the account with this email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with a token from /api/auth/forgot-password
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6,max=128"`
+}
+
+// RegisterWithInviteRequest signs up into an existing tenant with an invite token
+// The email is the one the invite was issued for
+type RegisterWithInviteRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=128"`
+}
+
 // AuthResponse represents the JWT token response
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"` // Absent for dev-login tokens
+	ExpiresIn    int    `json:"expires_in"`              // Seconds until token expires
+	User         User   `json:"user"`
+}
+
+// RefreshRequest exchanges a refresh token for a new access and refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest revokes a refresh token, or with all_sessions every one of its user's
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	AllSessions  bool   `json:"all_sessions"`
 }
 
 // WorkflowConfig represents the configuration for different workflow types
@@ -92,24 +766,49 @@ type WorkflowConfig struct {
 	// For webhook triggers
 	WebhookURL string `json:"webhook_url,omitempty"`
 	
+	// Webhook event dedupe: where the provider puts its event ID (redeliveries reuse it)
+	WebhookEventIDPath   string `json:"webhook_event_id_path,omitempty"`   // JSON path in the body (e.g., "id" for Stripe)
+	WebhookEventIDHeader string `json:"webhook_event_id_header,omitempty"` // Header name (e.g., "X-GitHub-Delivery")
+	WebhookDedupeTTL     int    `json:"webhook_dedupe_ttl,omitempty"`      // Hours to remember event IDs (default: 72)
+
+	// Answer webhook calls with the run's result instead of an acknowledgement (as ?wait=true does)
+	Synchronous bool `json:"synchronous,omitempty"`
+	
 	// For schedule triggers
-	Interval int `json:"interval,omitempty"` // in minutes
+	Interval   int    `json:"interval,omitempty"`     // in minutes
+	CatchUp    string `json:"catch_up,omitempty"`     // Runs missed during downtime: "skip", "run_once" (default) or "backfill"
+	CatchUpMax int    `json:"catch_up_max,omitempty"` // Most missed runs a backfill executes (default 10, at most 100)
 	
 	// For Slack action (supports templates like "Hello {{user.name}}")
-	SlackMessage string `json:"slack_message,omitempty"`
+	SlackMessage  string          `json:"slack_message,omitempty"`   // With blocks, the notification text
+	SlackBlocks   json.RawMessage `json:"slack_blocks,omitempty"`    // Block Kit array (at most 50); text fields support templates
+	SlackChannel  string          `json:"slack_channel,omitempty"`   // Channel ID or name; required with a bot token (xoxb-...)
+	SlackThreadTS string          `json:"slack_thread_ts,omitempty"` // Reply in this thread (supports templates)
 	
 	// For Discord action (supports templates like "Order {{order.id}} placed!")
-	DiscordMessage string `json:"discord_message,omitempty"`
+	DiscordMessage   string        `json:"discord_message,omitempty"`
+	DiscordEmbed     *DiscordEmbed `json:"discord_embed,omitempty"`      // Rich embed; its text fields support templates
+	DiscordUsername  string        `json:"discord_username,omitempty"`   // Overrides the webhook's name (supports templates)
+	DiscordAvatarURL string        `json:"discord_avatar_url,omitempty"` // Overrides the webhook's avatar
 	
 	// For Twilio SMS action
-	TwilioTo      string `json:"twilio_to,omitempty"`      // Recipient phone number (supports templates like "{{user.phone}}")
-	TwilioMessage string `json:"twilio_message,omitempty"` // SMS message (supports templates)
+	TwilioTo                 StringList `json:"twilio_to,omitempty"`                   // Recipient phone number(s): a string (comma-separated) or array, supports templates like "{{user.phone}}"
+	TwilioMessage            string     `json:"twilio_message,omitempty"`              // SMS message (supports templates)
+	TwilioDefaultCountryCode string     `json:"twilio_default_country_code,omitempty"` // Country code for national numbers (e.g., "1", "44")
+	TwilioChannel            string     `json:"twilio_channel,omitempty"`              // "sms" (default) or "whatsapp"
+	TwilioStatusCallback     string     `json:"twilio_status_callback,omitempty"`      // Delivery status URL, normally <api>/api/callbacks/twilio/<workflow_id>
 	
 	// For News API action
 	NewsQuery    string `json:"news_query,omitempty"`     // Search query (e.g., "bitcoin")
 	NewsCountry  string `json:"news_country,omitempty"`   // Country code (e.g., "us")
 	NewsCategory string `json:"news_category,omitempty"`  // Category (e.g., "technology")
 	NewsPageSize int    `json:"news_page_size,omitempty"` // Number of articles (default: 10)
+	NewsFrom     string `json:"news_from,omitempty"`      // Oldest article, ISO 8601 (e.g., "2026-10-16")
+	NewsTo       string `json:"news_to,omitempty"`        // Newest article, ISO 8601
+	NewsLanguage string `json:"news_language,omitempty"`  // Two-letter language code (e.g., "en")
+	NewsSources  string `json:"news_sources,omitempty"`   // Comma-separated source IDs; not with country or category
+	NewsSortBy   string `json:"news_sort_by,omitempty"`   // relevancy, popularity or publishedAt
+	NewsSearchIn string `json:"news_search_in,omitempty"` // Comma-separated: title, description, content
 	
 	// For Cat API action
 	CatLimit     int    `json:"cat_limit,omitempty"`      // Number of cat images (default: 1)
@@ -132,11 +831,19 @@ type WorkflowConfig struct {
 	SOAPNamespace  string                 `json:"soap_namespace,omitempty"`  // XML namespace
 	SOAPParameters map[string]interface{} `json:"soap_parameters,omitempty"` // Method parameters
 	SOAPHeaders    map[string]string      `json:"soap_headers,omitempty"`    // Custom HTTP headers
+	SOAPVersion    string                 `json:"soap_version,omitempty"`    // "1.1" (default) or "1.2"
+	SOAPWSSecurity bool                   `json:"soap_ws_security,omitempty"` // Send a WS-Security UsernameToken from the "soap" credential
+	
+	// TLS settings credential (service name like "tls:partner-erp") for private CAs / mutual TLS
+	TLSCredential string `json:"tls_credential,omitempty"`
 	
 	// For SWAPI connector (Star Wars API)
 	SWAPIResource string `json:"swapi_resource,omitempty"` // films, people, planets, species, vehicles, starships
 	SWAPIID       string `json:"swapi_id,omitempty"`       // Resource ID (e.g., "1" for first film)
 	SWAPISearch   string `json:"swapi_search,omitempty"`   // Search query
+	SWAPIPage     int        `json:"swapi_page,omitempty"`   // 1-based page of a list or search
+	SWAPILimit    int        `json:"swapi_limit,omitempty"`  // Items per page (0 = all)
+	SWAPIFields   StringList `json:"swapi_fields,omitempty"` // Keep only these keys of each item
 	
 	// For Salesforce connector
 	SalesforceOperation  string                 `json:"salesforce_operation,omitempty"`   // query, create, get, update, delete
@@ -145,6 +852,42 @@ type WorkflowConfig struct {
 	SalesforceQuery      string                 `json:"salesforce_query,omitempty"`       // SOQL query
 	SalesforceData       map[string]interface{} `json:"salesforce_data,omitempty"`        // Data for create/update
 	SalesforceInstanceURL string                 `json:"salesforce_instance_url,omitempty"` // Override instance URL
+	SalesforceFlattenRecords bool               `json:"salesforce_flatten_records,omitempty"` // Strip attributes; a single query record becomes data
+	SalesforceRecordLimit int                    `json:"salesforce_record_limit,omitempty"` // Max query records kept (client-side)
+	
+	// For generic HTTP request action (templates apply to the URL and body)
+	HTTPMethod         string            `json:"http_method,omitempty"`          // GET (default; POST with a body), POST, PUT, PATCH, DELETE, HEAD
+	HTTPURL            string            `json:"http_url,omitempty"`             // http(s) URL; loopback and link-local hosts are refused
+	HTTPHeaders        map[string]string `json:"http_headers,omitempty"`         // Request headers
+	HTTPQuery          map[string]string `json:"http_query,omitempty"`           // Query string parameters
+	HTTPBody           interface{}       `json:"http_body,omitempty"`            // A string is sent as is; an object or array as JSON
+	HTTPAuthCredential string            `json:"http_auth_credential,omitempty"` // Credential holding {"token"} (bearer) or {"username", "password"} (basic)
+	HTTPTimeout        int               `json:"http_timeout,omitempty"`         // Seconds (default 30, at most 120)
+	
+	// For email action; the mail server and sender come from the "smtp" credential
+	EmailTo       StringList `json:"email_to,omitempty"`        // Recipient address(es): a string (comma-separated) or array, supports templates
+	EmailSubject  string     `json:"email_subject,omitempty"`   // Subject line (supports templates)
+	EmailBody     string     `json:"email_body,omitempty"`      // Plain text body (supports templates)
+	EmailHTMLBody string     `json:"email_html_body,omitempty"` // Optional HTML alternative to the plain text body (supports templates)
+	
+	// For GitHub connector (token from the "github" credential)
+	GitHubOperation   string     `json:"github_operation,omitempty"`    // create_issue, comment_issue, list_issues, get_repo
+	GitHubOwner       string     `json:"github_owner,omitempty"`        // User or organization
+	GitHubRepo        string     `json:"github_repo,omitempty"`         // Repository name
+	GitHubIssueNumber int        `json:"github_issue_number,omitempty"` // Issue to comment on
+	GitHubTitle       string     `json:"github_title,omitempty"`        // New issue title (supports templates)
+	GitHubBody        string     `json:"github_body,omitempty"`         // Issue or comment body (supports templates)
+	GitHubLabels      StringList `json:"github_labels,omitempty"`       // Labels for a new issue; filter for list_issues
+	
+	// For Jira connector (site and auth from the "jira" credential)
+	JiraOperation   string `json:"jira_operation,omitempty"`   // create_issue, add_comment, transition_issue, search
+	JiraProjectKey  string `json:"jira_project_key,omitempty"` // Project of a new issue (e.g., "OPS")
+	JiraIssueType   string `json:"jira_issue_type,omitempty"`  // Type of a new issue (default "Task")
+	JiraSummary     string `json:"jira_summary,omitempty"`     // New issue summary (supports templates)
+	JiraDescription string `json:"jira_description,omitempty"` // Issue description, or the comment for add_comment (supports templates)
+	JiraIssueKey    string `json:"jira_issue_key,omitempty"`   // Issue to comment on or transition (supports templates)
+	JiraTransition  string `json:"jira_transition,omitempty"`  // Transition name or ID
+	JiraJQL         string `json:"jira_jql,omitempty"`         // JQL query for search
 	
 	// For Testing/Mock Response action (NEW!)
 	TestingResponseJSON  string                 `json:"testing_response_json,omitempty"`  // Custom JSON response to return
@@ -152,7 +895,142 @@ type WorkflowConfig struct {
 	TestingDelay         int                    `json:"testing_delay,omitempty"`          // Delay in milliseconds before responding
 	TestingHeaders       map[string]string      `json:"testing_headers,omitempty"`        // Custom response headers
 	
+	// Response assertions evaluated against the action's result data (synthetic monitoring)
+	Assertions []Assertion `json:"assertions,omitempty"`
+	
+	// Credential service_name used instead of the action's default for Slack, Discord, Twilio and email (e.g., "slack:backup")
+	Credential string `json:"credential,omitempty"`
+	
+	// Fallback targets for notification actions, tried in order while attempts fail definitively
+	Fallbacks              []Fallback `json:"fallbacks,omitempty"`
+	FallbackAttemptTimeout int        `json:"fallback_attempt_timeout,omitempty"` // Per-attempt limit in milliseconds (0 = none)
+	FallbackOnTimeout      bool       `json:"fallback_on_timeout,omitempty"`      // Also fall back after a timeout (the slow target may still have delivered)
+	
+	// Outbound throttle for notification actions (anti-spam): runs past the limit in any sliding hour are suppressed
+	MaxNotificationsPerHour int    `json:"max_notifications_per_hour,omitempty"` // 0 = unlimited
+	NotificationOverflow    string `json:"notification_overflow,omitempty"`      // "drop" (default), "digest" (one summary when the window frees) or "queue" (send later)
+	
+	// Total time the action chain may take; steps that can't start within it are reported as not_started (0 = no budget)
+	BudgetSeconds int `json:"budget_seconds,omitempty"`
+	
+	// Time limit for the whole run, clamped to 5-600 seconds (0 = 5 minutes queued, 30 seconds for dry runs)
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+
+	// Let runs of this workflow overlap; by default a run requested while one is in flight is skipped
+	AllowConcurrent bool `json:"allow_concurrent,omitempty"`
+
+	// Failure alerts: a notification once the workflow fails this many times in a row (0 = no alerts)
+	AlertAfterFailures   int        `json:"alert_after_failures,omitempty"`
+	AlertChannel         string     `json:"alert_channel,omitempty"`          // Credential service_name to alert through: slack, discord, smtp or twilio (e.g., "slack:ops")
+	AlertTo              StringList `json:"alert_to,omitempty"`               // Email or phone recipients (required for smtp and twilio); the channel for a Slack bot token
+	AlertCooldownMinutes int        `json:"alert_cooldown_minutes,omitempty"` // While failures go on, alert again after this long (default 60)
+	AlertOnRecovery      bool       `json:"alert_on_recovery,omitempty"`      // Send an all clear on the first success after an alert
+	
+	// Outbound call overrides for the connector (Salesforce, SOAP, News API, OpenWeather, Fake Store, SWAPI)
+	ConnectorTimeoutSeconds int               `json:"connector_timeout_seconds,omitempty"` // Each request's timeout, 1-300 (0 = the connector's default)
+	ConnectorHeaders        map[string]string `json:"connector_headers,omitempty"`         // Extra request headers; the connector's own (e.g., Authorization) win
+	
+	// Connector implementation version to run (e.g., "v2"); "latest" or empty follows the tenant's canary and the connector's current version
+	ConnectorVersion string `json:"connector_version,omitempty"`
+	
+	// Environment the workflow runs against: "live" (default) or "test"; its credentials should match
+	Environment string `json:"environment,omitempty"`
+	
 	// General purpose field for custom data
 	CustomData map[string]interface{} `json:"custom_data,omitempty"`
 }
 
+// Fallback is an alternate target for a notification action
+// Credential alone retries the same action with another credential; ActionType and
+// Config replace the action (e.g., a Discord post when Slack is down)
+type Fallback struct {
+	Credential string                 `json:"credential,omitempty"`  // Credential service_name (e.g., "slack:backup")
+	ActionType string                 `json:"action_type,omitempty"` // Default: the primary action's type
+	Config     map[string]interface{} `json:"config,omitempty"`      // Default: the primary action's config
+}
+
+// DiscordEmbed is a rich Discord message block
+type DiscordEmbed struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	URL         string              `json:"url,omitempty"`       // Makes the title a link
+	Color       int                 `json:"color,omitempty"`     // Side bar color as an RGB integer (e.g., 5793266 for #5865F2)
+	Fields      []DiscordEmbedField `json:"fields,omitempty"`    // At most 25
+	Footer      string              `json:"footer,omitempty"`    // Footer text
+	Timestamp   string              `json:"timestamp,omitempty"` // RFC3339, shown in the footer (e.g., "{{order.created_at}}")
+}
+
+// DiscordEmbedField is a name/value pair in a Discord embed
+type DiscordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Assertion checks a value in an action's result data after it runs
+// Path uses the same dot syntax as templates (e.g., "data.status", "articles.0.title", "articles.#")
+type Assertion struct {
+	Path     string      `json:"path"`
+	Operator string      `json:"operator"`        // eq, ne, gt, lt, contains, exists
+	Value    interface{} `json:"value,omitempty"` // Expected value (exists accepts true/false, default true)
+}
+
+// AssertionResult reports whether a single assertion passed
+type AssertionResult struct {
+	Path     string      `json:"path"`
+	Operator string      `json:"operator"`
+	Expected interface{} `json:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+	Passed   bool        `json:"passed"`
+	Message  string      `json:"message"`
+}
+
+
+// CredentialRequirementStatus is one entry in a workflow's credential checklist
+type CredentialRequirementStatus struct {
+	Service     string   `json:"service"`      // Credential service_name (e.g., 'slack')
+	Label       string   `json:"label"`        // Human-readable service name
+	ActionTypes []string `json:"action_types"` // Workflow steps that use this service
+	Optional    bool     `json:"optional"`
+	Status      string   `json:"status"`                // 'connected', 'missing' or 'environment_mismatch'
+	Environment string   `json:"environment,omitempty"` // Environment of the credential a run would use
+	Hint        string   `json:"hint,omitempty"`        // What credential to provide
+	SetupURL    string   `json:"setup_url,omitempty"`   // Deep link to the connections page (missing and mismatched only)
+}
+
+// WorkflowRequirements is the credential onboarding checklist for a workflow
+type WorkflowRequirements struct {
+	WorkflowID   string                        `json:"workflow_id"`
+	Ready        bool                          `json:"ready"` // All required credentials are connected (in the workflow's environment, when the tenant enforces it)
+	Environment  string                        `json:"environment"`
+	Requirements []CredentialRequirementStatus `json:"requirements"`
+}
+
+// StringList is a config value written as a single string or an array of strings
+type StringList []string
+
+// UnmarshalJSON accepts "value" or ["value", ...]
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = nil
+		if single != "" {
+			*l = StringList{single}
+		}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("expected a string or an array of strings")
+	}
+	*l = list
+	return nil
+}
+
+// MarshalJSON writes a single value as a plain string so existing configs round-trip unchanged
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
@@ -0,0 +1,488 @@
+// Package notify tells a tenant's members when its workflows change.
+//
+// Mutating workflow operations report a Change. Changes are batched per tenant for
+// the tenant's window (a minute by default), so a burst of edits produces one message
+// per member. Each member gets the summary on their preferred channel (Slack or
+// Discord, through their own credential) unless they opted out. Delivery happens in
+// the background: a failed send is logged and never fails the request that made the change.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+const (
+	// DefaultBatchWindow is how long changes are collected before a message goes out
+	DefaultBatchWindow = time.Minute
+	// MaxBatchWindow bounds a tenant's batch_seconds
+	MaxBatchWindow = time.Hour
+
+	maxValueLength       = 120 // Longer field values are truncated in messages
+	maxChangesPerMessage = 25  // Further changes are summarized as a count
+	sendTimeout          = 10 * time.Second
+)
+
+// Channels a member can receive notifications on
+const (
+	ChannelSlack   = "slack"
+	ChannelDiscord = "discord"
+)
+
+// Change actions
+const (
+	ActionCreated  = "created"
+	ActionUpdated  = "updated"
+	ActionEnabled  = "enabled"
+	ActionDisabled = "disabled"
+	ActionDeleted  = "deleted"
+)
+
+// FieldChange is one field of a workflow that changed; values are masked and truncated
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Change is one mutating operation on a workflow
+type Change struct {
+	TenantID     string
+	ActorID      string
+	WorkflowID   string
+	WorkflowName string
+	Action       string
+	Fields       []FieldChange
+	At           time.Time
+}
+
+// NewChange describes the change from before to after
+// before is nil for a created workflow and after is nil for a deleted one
+func NewChange(tenantID, actorID string, before, after *models.Workflow, at time.Time) Change {
+	change := Change{TenantID: tenantID, ActorID: actorID, Action: ActionUpdated, Fields: Diff(before, after), At: at}
+	workflow := after
+	switch {
+	case before == nil:
+		change.Action = ActionCreated
+	case after == nil:
+		change.Action = ActionDeleted
+		workflow = before
+	case before.IsActive != after.IsActive && len(change.Fields) == 1:
+		change.Action = ActionDisabled
+		if after.IsActive {
+			change.Action = ActionEnabled
+		}
+	}
+	if workflow != nil {
+		change.WorkflowID, change.WorkflowName = workflow.ID, workflow.Name
+	}
+	return change
+}
+
+// Diff lists the fields that differ between two versions of a workflow
+// Config keys are compared one by one; secrets never appear, even when they changed
+func Diff(before, after *models.Workflow) []FieldChange {
+	var b, a models.Workflow
+	if before != nil {
+		b = *before
+	}
+	if after != nil {
+		a = *after
+	}
+
+	var changes []FieldChange
+	add := func(field string, beforeValue, afterValue interface{}, beforeRaw, afterRaw string) {
+		if beforeRaw == afterRaw {
+			return
+		}
+		change := FieldChange{Field: field, Before: display(beforeValue), After: display(afterValue)}
+		if change.Before == change.After {
+			change.Before, change.After = "", "(secret changed)"
+		}
+		changes = append(changes, change)
+	}
+	text := func(field, beforeValue, afterValue string) {
+		add(field, utils.Mask(beforeValue), utils.Mask(afterValue), beforeValue, afterValue)
+	}
+
+	text("name", b.Name, a.Name)
+	text("trigger_type", b.TriggerType, a.TriggerType)
+	text("action_type", b.ActionType, a.ActionType)
+	if before != nil && after != nil && b.IsActive != a.IsActive {
+		add("is_active", b.IsActive, a.IsActive, fmt.Sprint(b.IsActive), fmt.Sprint(a.IsActive))
+	}
+	if before != nil && after != nil {
+		beforePause, afterPause := pauseText(b.PausedUntil), pauseText(a.PausedUntil)
+		add("paused_until", beforePause, afterPause, beforePause, afterPause)
+	}
+
+	beforeConfig, beforeOK := configMap(b.ConfigJSON)
+	afterConfig, afterOK := configMap(a.ConfigJSON)
+	if beforeOK && afterOK {
+		maskedBefore, maskedAfter := utils.MaskMap(beforeConfig), utils.MaskMap(afterConfig)
+		for _, key := range configKeys(beforeConfig, afterConfig) {
+			beforeRaw, _ := json.Marshal(beforeConfig[key])
+			afterRaw, _ := json.Marshal(afterConfig[key])
+			add("config."+key, maskedBefore[key], maskedAfter[key], string(beforeRaw), string(afterRaw))
+		}
+	} else {
+		text("config", b.ConfigJSON, a.ConfigJSON)
+	}
+
+	text("action_chain", b.ActionChain, a.ActionChain)
+	text("parameters", b.Parameters, a.Parameters)
+	return changes
+}
+
+// pauseText is the end of a pause as shown in a change, empty when not paused
+func pauseText(until *time.Time) string {
+	if until == nil {
+		return ""
+	}
+	return until.UTC().Format(time.RFC3339)
+}
+
+// configMap parses a workflow config; an empty config is an empty map
+func configMap(configJSON string) (map[string]interface{}, bool) {
+	config := map[string]interface{}{}
+	if strings.TrimSpace(configJSON) == "" {
+		return config, true
+	}
+	return config, json.Unmarshal([]byte(configJSON), &config) == nil
+}
+
+// configKeys returns the keys of both configs, sorted
+func configKeys(before, after map[string]interface{}) []string {
+	seen := make(map[string]bool, len(before)+len(after))
+	var keys []string
+	for _, config := range []map[string]interface{}{before, after} {
+		for key := range config {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// display renders a (masked) value on one line, truncated to maxValueLength
+func display(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	default:
+		raw, _ := json.Marshal(v)
+		s = string(raw)
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxValueLength {
+		s = strings.ToValidUTF8(s[:maxValueLength], "") + "…"
+	}
+	return s
+}
+
+// Store is the part of db.Store the notifier needs
+type Store interface {
+	GetTenantSettings(tenantID string) (*models.TenantSettings, error)
+	GetUserByID(id string) (*models.User, error)
+	ListTenantUserIDs(tenantID string) ([]string, error)
+	GetCredentialForTenant(tenantID, userID, serviceName string) (*models.Credential, error)
+}
+
+// batch is a tenant's changes waiting for their window to end
+type batch struct {
+	due      time.Time
+	settings models.ChangeNotifications
+	changes  []Change
+}
+
+// Notifier batches workflow changes and delivers them to tenant members
+type Notifier struct {
+	store Store
+	log   *logger.Logger
+
+	mu      sync.Mutex
+	batches map[string]*batch // Keyed by tenant
+	done    chan struct{}
+}
+
+// NewNotifier creates a notifier over store
+func NewNotifier(store Store, log *logger.Logger) *Notifier {
+	return &Notifier{
+		store:   store,
+		log:     log,
+		batches: make(map[string]*batch),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start delivers batches as their windows end until Stop is called
+func (n *Notifier) Start() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				n.FlushDue(now)
+			case <-n.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background flush and delivers whatever is still pending
+func (n *Notifier) Stop() {
+	close(n.done)
+	n.FlushDue(time.Now().Add(MaxBatchWindow))
+}
+
+// Notify records a change for the tenant's next message
+// Never blocks on delivery; changes to tenants without notifications are dropped
+func (n *Notifier) Notify(change Change) {
+	if n == nil || (len(change.Fields) == 0 && change.Action == ActionUpdated) {
+		return
+	}
+	if change.At.IsZero() {
+		change.At = time.Now()
+	}
+
+	n.mu.Lock()
+	if pending, ok := n.batches[change.TenantID]; ok {
+		pending.changes = append(pending.changes, change)
+		n.mu.Unlock()
+		return
+	}
+	n.mu.Unlock()
+
+	// First change of a batch: the window and recipients come from the tenant's settings
+	settings, err := n.store.GetTenantSettings(change.TenantID)
+	if err != nil {
+		n.log.Warn("Failed to load notification settings", map[string]interface{}{
+			"tenant_id": change.TenantID,
+			"error":     err.Error(),
+		})
+		return
+	}
+	if settings.Notifications == nil || !settings.Notifications.Enabled {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if pending, ok := n.batches[change.TenantID]; ok {
+		pending.changes = append(pending.changes, change)
+		return
+	}
+	n.batches[change.TenantID] = &batch{
+		due:      change.At.Add(BatchWindow(settings.Notifications)),
+		settings: *settings.Notifications,
+		changes:  []Change{change},
+	}
+}
+
+// BatchWindow returns a tenant's batching window
+func BatchWindow(settings *models.ChangeNotifications) time.Duration {
+	window := time.Duration(settings.BatchSeconds) * time.Second
+	switch {
+	case window <= 0:
+		return DefaultBatchWindow
+	case window > MaxBatchWindow:
+		return MaxBatchWindow
+	}
+	return window
+}
+
+// FlushDue delivers every batch whose window ended by now
+// Returns how many messages were sent
+func (n *Notifier) FlushDue(now time.Time) int {
+	n.mu.Lock()
+	var due []*batch
+	var tenantIDs []string
+	for tenantID, pending := range n.batches {
+		if !now.Before(pending.due) {
+			due = append(due, pending)
+			tenantIDs = append(tenantIDs, tenantID)
+			delete(n.batches, tenantID)
+		}
+	}
+	n.mu.Unlock()
+
+	sent := 0
+	for i, pending := range due {
+		sent += n.deliver(tenantIDs[i], pending)
+	}
+	return sent
+}
+
+// members lists the tenant's users, logging and returning none if they can't be read
+func (n *Notifier) members(tenantID string) []string {
+	userIDs, err := n.store.ListTenantUserIDs(tenantID)
+	if err != nil {
+		n.log.Warn("Failed to list tenant members", map[string]interface{}{
+			"tenant_id": tenantID,
+			"error":     err.Error(),
+		})
+		return nil
+	}
+	return userIDs
+}
+
+// deliver sends a batch's summary to every member who hasn't opted out
+func (n *Notifier) deliver(tenantID string, pending *batch) int {
+	message := n.summary(pending.changes)
+
+	sent := 0
+	for _, memberID := range n.members(tenantID) {
+		preference := pending.settings.Members[memberID]
+		if preference.OptOut {
+			continue
+		}
+		channel := preference.Channel
+		if channel == "" {
+			channel = ChannelSlack
+		}
+
+		if err := n.send(tenantID, memberID, channel, message); err != nil {
+			n.log.Warn("Failed to deliver workflow change notification", map[string]interface{}{
+				"tenant_id": tenantID,
+				"user_id":   memberID,
+				"channel":   channel,
+				"changes":   len(pending.changes),
+				"error":     err.Error(),
+			})
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// Alert sends a message to the tenant's members right away, outside any batch
+// Members' channel choices and opt-outs from the change notification settings apply
+// Returns how many members it reached
+func (n *Notifier) Alert(tenantID, message string) int {
+	if n == nil {
+		return 0
+	}
+	settings, err := n.store.GetTenantSettings(tenantID)
+	if err != nil {
+		n.log.Warn("Failed to load notification settings", map[string]interface{}{
+			"tenant_id": tenantID,
+			"error":     err.Error(),
+		})
+		return 0
+	}
+	var members map[string]models.NotificationPreference
+	if settings.Notifications != nil {
+		members = settings.Notifications.Members
+	}
+
+	sent := 0
+	for _, memberID := range n.members(tenantID) {
+		preference := members[memberID]
+		if preference.OptOut {
+			continue
+		}
+		channel := preference.Channel
+		if channel == "" {
+			channel = ChannelSlack
+		}
+
+		if err := n.send(tenantID, memberID, channel, message); err != nil {
+			n.log.Warn("Failed to deliver alert", map[string]interface{}{
+				"tenant_id": tenantID,
+				"user_id":   memberID,
+				"channel":   channel,
+				"error":     err.Error(),
+			})
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// send posts a message with the member's own credential for the channel
+func (n *Notifier) send(tenantID, userID, channel, message string) error {
+	cred, err := n.store.GetCredentialForTenant(tenantID, userID, channel)
+	if err != nil {
+		return fmt.Errorf("no usable %s credential: %w", channel, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	var result connectors.Result
+	switch channel {
+	case ChannelSlack:
+		result = (&connectors.SlackWebhook{WebhookURL: cred.DecryptedKey}).ExecuteWithContext(ctx, message)
+	case ChannelDiscord:
+		result = (&connectors.DiscordWebhook{WebhookURL: cred.DecryptedKey}).ExecuteWithContext(ctx, message)
+	default:
+		return fmt.Errorf("unsupported channel %q", channel)
+	}
+	if result.Status != "success" {
+		return fmt.Errorf("%s", utils.Mask(result.Message))
+	}
+	return nil
+}
+
+// summary renders a batch as one message: who changed what, field by field, and when
+func (n *Notifier) summary(changes []Change) string {
+	var b strings.Builder
+	if len(changes) == 1 {
+		b.WriteString("GoFlow: 1 workflow change\n")
+	} else {
+		fmt.Fprintf(&b, "GoFlow: %d workflow changes\n", len(changes))
+	}
+
+	actors := make(map[string]string)
+	for i, change := range changes {
+		if i == maxChangesPerMessage {
+			fmt.Fprintf(&b, "…and %d more\n", len(changes)-i)
+			break
+		}
+		actor, ok := actors[change.ActorID]
+		if !ok {
+			actor = change.ActorID
+			if user, err := n.store.GetUserByID(change.ActorID); err == nil {
+				actor = user.Email
+			}
+			actors[change.ActorID] = actor
+		}
+
+		fmt.Fprintf(&b, "• %s %s %q at %s\n", actor, change.Action, change.WorkflowName, change.At.UTC().Format("2006-01-02 15:04 UTC"))
+		if change.Action == ActionCreated || change.Action == ActionDeleted {
+			continue
+		}
+		for _, field := range change.Fields {
+			fmt.Fprintf(&b, "    %s: %s → %s\n", field.Field, quoted(field.Before), quoted(field.After))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// quoted shows empty values explicitly
+func quoted(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,96 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// docsPage lists the operations of the document, grouped by tag, with a link to the JSON
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.Version}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; }
+td { padding: 0.2em 0.8em 0.2em 0; vertical-align: top; }
+code { font-size: 0.95em; }
+.method { font-weight: bold; width: 5em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}} <small>{{.Version}}</small></h1>
+<p>The full document is at <a href="{{.SpecURL}}">{{.SpecURL}}</a>. Operations marked 🔒 need an <code>Authorization: Bearer &lt;JWT&gt;</code> header.</p>
+{{range .Groups}}
+<h2>{{.Tag}}</h2>
+<table>
+{{range .Operations}}<tr><td class="method">{{.Method}}</td><td><code>{{.Path}}</code>{{if .Secured}} 🔒{{end}}</td><td>{{.Summary}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// docsOperation is one row of the docs page
+type docsOperation struct {
+	Method  string
+	Path    string
+	Summary string
+	Secured bool
+}
+
+// docsGroup is the operations of one tag
+type docsGroup struct {
+	Tag        string
+	Operations []docsOperation
+}
+
+// DocsHandler serves a plain HTML listing of the document's operations
+// specURL is where Handler serves the JSON
+func (s *Spec) DocsHandler(router *mux.Router, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc, err := s.document(router)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		groups := make(map[string]*docsGroup)
+		for path, item := range doc.Paths {
+			for method, op := range item {
+				group := groups[op.Tags[0]]
+				if group == nil {
+					group = &docsGroup{Tag: op.Tags[0]}
+					groups[op.Tags[0]] = group
+				}
+				group.Operations = append(group.Operations, docsOperation{
+					Method:  strings.ToUpper(method),
+					Path:    path,
+					Summary: op.Summary,
+					Secured: len(op.Security) > 0,
+				})
+			}
+		}
+		sorted := make([]*docsGroup, 0, len(groups))
+		for _, group := range groups {
+			sort.Slice(group.Operations, func(i, j int) bool {
+				a, b := group.Operations[i], group.Operations[j]
+				return a.Path < b.Path || (a.Path == b.Path && a.Method < b.Method)
+			})
+			sorted = append(sorted, group)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Tag < sorted[j].Tag })
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		docsPage.Execute(w, map[string]interface{}{
+			"Title":   doc.Info.Title,
+			"Version": doc.Info.Version,
+			"SpecURL": specURL,
+			"Groups":  sorted,
+		})
+	}
+}
@@ -0,0 +1,317 @@
+// Package openapi builds the OpenAPI 3.0 document of the API from its router.
+//
+// Paths and methods come from walking the registered routes, so a route can't be
+// missing from the document; routes describe their request and response bodies
+// with Spec.Describe next to their registration, and the schemas are derived from
+// those Go types. Routes registered on a subrouter (behind the auth middleware) are
+// marked as requiring a bearer JWT.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+// Version is the OpenAPI version of the documents built here
+const Version = "3.0.3"
+
+// Component names shared by every document
+const (
+	BearerScheme = "bearerAuth"
+	ErrorSchema  = "Error"
+)
+
+// Document is an OpenAPI 3.0 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations of one path, keyed by lower-case method
+type PathItem map[string]*Operation
+
+// Operation is a single API call
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security"` // Empty for public routes
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // "path" or "query"
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the JSON body of an operation
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one response status
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas and security schemes referenced by operations
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how the API authenticates
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Op describes the bodies of a registered route
+type Op struct {
+	Summary  string
+	Request  interface{} // A value of the JSON body's type, e.g. models.LoginRequest{}
+	Response interface{} // A value of the success body's type; nil when it has none
+	Status   int         // Success status (default 200)
+	Query    []Parameter // Query string parameters
+}
+
+// Spec collects route descriptions and builds the document from a router
+type Spec struct {
+	title   string
+	version string
+	ops     map[string]Op // By "METHOD path template"
+
+	once  sync.Once
+	doc   *Document
+	err   error
+	bytes []byte
+}
+
+// New creates an empty spec for an API
+func New(title, version string) *Spec {
+	return &Spec{title: title, version: version, ops: make(map[string]Op)}
+}
+
+// Describe records the bodies of the route registered for method and the full path
+// template (e.g. "/api/workflows/{id}"); Build fails when no such route exists
+func (s *Spec) Describe(method, path string, op Op) {
+	s.ops[strings.ToUpper(method)+" "+path] = op
+}
+
+// Build walks the router and describes every route with methods
+func (s *Spec) Build(router *mux.Router) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: s.title, Version: s.version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{ErrorSchema: errorSchema()},
+			SecuritySchemes: map[string]*SecurityScheme{
+				BearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	schemas := newSchemaBuilder(doc.Components.Schemas)
+	described := make(map[string]bool, len(s.ops))
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // Path prefixes of subrouters
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		path, names := pathParameters(template)
+		for _, method := range methods {
+			key := method + " " + template
+			op := s.ops[key]
+			described[key] = true
+			operation := s.operation(schemas, method, path, names, op)
+			if len(ancestors) > 0 {
+				operation.Security = []map[string][]string{{BearerScheme: {}}}
+			}
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(PathItem)
+			}
+			doc.Paths[path][strings.ToLower(method)] = operation
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for key := range s.ops {
+		if !described[key] {
+			stale = append(stale, key)
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		return nil, fmt.Errorf("described routes aren't registered: %s", strings.Join(stale, ", "))
+	}
+	return doc, nil
+}
+
+// operation describes one method of a route
+func (s *Spec) operation(schemas *schemaBuilder, method, path string, names []string, op Op) *Operation {
+	operation := &Operation{
+		OperationID: operationID(method, path),
+		Summary:     op.Summary,
+		Tags:        []string{tag(path)},
+		Responses: map[string]*Response{
+			"default": {
+				Description: "Error",
+				Content:     jsonContent(&Schema{Ref: schemaRef(ErrorSchema)}),
+			},
+		},
+		Security: []map[string][]string{},
+	}
+	for _, name := range names {
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	for _, param := range op.Query {
+		param.In = "query"
+		if param.Schema == nil {
+			param.Schema = &Schema{Type: "string"}
+		}
+		operation.Parameters = append(operation.Parameters, param)
+	}
+	if op.Request != nil {
+		operation.RequestBody = &RequestBody{Required: true, Content: jsonContent(schemas.schemaOf(op.Request))}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := &Response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		response.Content = jsonContent(schemas.schemaOf(op.Response))
+	}
+	operation.Responses[fmt.Sprint(status)] = response
+	return operation
+}
+
+// Handler serves the document as JSON
+func (s *Spec) Handler(router *mux.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := s.document(router); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.bytes)
+	}
+}
+
+// document builds the document on the first request, when every route is registered
+func (s *Spec) document(router *mux.Router) (*Document, error) {
+	s.once.Do(func() {
+		s.doc, s.err = s.Build(router)
+		if s.err == nil {
+			s.bytes, s.err = json.MarshalIndent(s.doc, "", "  ")
+		}
+	})
+	return s.doc, s.err
+}
+
+// errorSchema is the envelope every error is answered with (see handlers.SendErrorCode)
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"error":   {Type: "string"},
+			"code":    {Type: "string"},
+		},
+		Required: []string{"success", "error"},
+	}
+}
+
+// jsonContent is an application/json body with a schema
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// pathParameters turns a mux template into an OpenAPI path, dropping the patterns of
+// variables ("{key:[a-z]+}" becomes "{key}"), and lists the variables
+func pathParameters(template string) (string, []string) {
+	var path strings.Builder
+	var names []string
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			path.WriteString(template)
+			return path.String(), names
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			path.WriteString(template)
+			return path.String(), names
+		}
+		name := strings.SplitN(template[start+1:start+end], ":", 2)[0]
+		names = append(names, name)
+		path.WriteString(template[:start] + "{" + name + "}")
+		template = template[start+end+1:]
+	}
+}
+
+// operationID names an operation after its method and path: POST /api/workflows/{id}/run
+// is postWorkflowsIdRun
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(strings.TrimPrefix(path, "/api"), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return id.String()
+}
+
+// tag groups an operation by the first path segment after /api
+func tag(path string) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api"), "/"), "/")
+	if segments[0] == "" {
+		return "root"
+	}
+	return segments[0]
+}
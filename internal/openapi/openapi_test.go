@@ -0,0 +1,189 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/connectorgen"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/openapi"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/gorilla/mux"
+)
+
+// TestServedDocument fetches /api/openapi.json from the API router, validates it and
+// checks the workflow routes are described
+func TestServedDocument(t *testing.T) {
+	store := db.NewMockStore()
+	testLogger := logger.NewLogger("test")
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Store:    store,
+		Executor: engine.NewExecutor(store, testLogger),
+		Logger:   testLogger,
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to fetch the document: %v", err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 without a token, got %d: %s", resp.StatusCode, raw)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("Document isn't JSON: %v", err)
+	}
+	if err := doc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := connectorgen.ParseSpec(raw); err != nil {
+		t.Errorf("Expected the connector generator to read the document, got %v", err)
+	}
+
+	for path, methods := range map[string][]string{
+		"/api/workflows":               {"get", "post"},
+		"/api/workflows/{id}":          {"put", "delete"},
+		"/api/workflows/{id}/run":      {"post"},
+		"/api/workflows/{id}/stats":    {"get"},
+		"/api/workflows/dry-run":       {"post"},
+		"/api/workflows/bulk":          {"post"},
+		"/api/webhooks/{id}":           {"post"},
+		"/api/admin/workers":           {"get"},
+		"/api/workflows/{id}/kong":     {"get", "delete"},
+		"/api/tenants/invites/{id}":    {"delete"},
+		"/api/auth/change-password":    {"post"},
+		"/api/dead-letters/{id}/retry": {"post"},
+	} {
+		for _, method := range methods {
+			if doc.Paths[path][method] == nil {
+				t.Errorf("Expected %s %s in the document", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	create := doc.Paths["/api/workflows"]["post"]
+	if create.RequestBody == nil || create.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/CreateWorkflowRequest" {
+		t.Errorf("Expected POST /api/workflows to take a CreateWorkflowRequest, got %+v", create.RequestBody)
+	}
+	if create.Responses["201"] == nil || create.Responses["default"] == nil {
+		t.Errorf("Expected a 201 and the error envelope, got %v", create.Responses)
+	}
+	if len(create.Security) == 0 || len(doc.Paths["/api/auth/login"]["post"].Security) != 0 {
+		t.Error("Expected bearer auth on /api routes behind the middleware only")
+	}
+	if required := doc.Components.Schemas["LoginRequest"].Required; strings.Join(required, ",") != "email,password" {
+		t.Errorf("Expected email and password required from the validate tags, got %v", required)
+	}
+
+	// The docs page is public too and links the document
+	resp, err = http.Get(srv.URL + "/api/docs")
+	if err != nil {
+		t.Fatalf("Failed to fetch the docs page: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected an HTML page, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{"/api/openapi.json", "/api/workflows/{id}/run", "Create a workflow"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("Expected %q on the docs page", want)
+		}
+	}
+}
+
+type auditFields struct {
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by" validate:"required"`
+}
+
+type node struct {
+	auditFields
+	Name     string            `json:"name" validate:"required,max=20"`
+	Children []*node           `json:"children,omitempty"`
+	Parent   *node             `json:"parent"`
+	Labels   map[string]string `json:"labels"`
+	Limit    *int              `json:"limit"`
+	Raw      json.RawMessage   `json:"raw"`
+	Count    int64             `json:"count,string"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+// TestSchemaDerivation checks schemas follow encoding/json: tags, embedding, pointers
+// and recursion
+func TestSchemaDerivation(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/nodes/{id:[0-9]+}", func(http.ResponseWriter, *http.Request) {}).Methods("PUT")
+	spec := openapi.New("Test", "1.0")
+	spec.Describe("PUT", "/nodes/{id:[0-9]+}", openapi.Op{Request: node{}, Response: []node{}})
+
+	doc, err := spec.Build(router)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if err := doc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Paths["/nodes/{id}"]["put"]
+	if op == nil || op.Parameters[0].Name != "id" || len(op.Security) != 0 {
+		t.Fatalf("Expected a public PUT /nodes/{id}, got %+v", doc.Paths)
+	}
+	if items := op.Responses["200"].Content["application/json"].Schema.Items; items == nil || items.Ref != "#/components/schemas/node" {
+		t.Errorf("Expected an array of node references, got %+v", items)
+	}
+
+	schema := doc.Components.Schemas["node"]
+	want := map[string]openapi.Schema{
+		"created_at": {Type: "string", Format: "date-time"},
+		"created_by": {Type: "string"},
+		"name":       {Type: "string"},
+		"parent":     {Ref: "#/components/schemas/node"},
+		"limit":      {Type: "integer", Format: "int32", Nullable: true},
+		"raw":        {},
+		"count":      {Type: "string"},
+	}
+	for name, expected := range want {
+		got := schema.Properties[name]
+		if got == nil || got.Ref != expected.Ref || got.Type != expected.Type || got.Format != expected.Format || got.Nullable != expected.Nullable {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, got)
+		}
+	}
+	if children := schema.Properties["children"]; children == nil || children.Items.Ref != "#/components/schemas/node" {
+		t.Errorf("Expected children to reference node, got %+v", children)
+	}
+	if labels := schema.Properties["labels"]; labels == nil || labels.AdditionalProperties.Type != "string" {
+		t.Errorf("Expected labels as a string map, got %+v", labels)
+	}
+	for _, skipped := range []string{"Secret", "internal", "auditFields"} {
+		if schema.Properties[skipped] != nil {
+			t.Errorf("Expected %s left out", skipped)
+		}
+	}
+	if strings.Join(schema.Required, ",") != "name,created_by" {
+		t.Errorf("Expected name and created_by required, got %v", schema.Required)
+	}
+}
+
+// TestDescribedRouteMustExist fails the build when a description names no route
+func TestDescribedRouteMustExist(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/things", func(http.ResponseWriter, *http.Request) {}).Methods("GET")
+	spec := openapi.New("Test", "1.0")
+	spec.Describe("POST", "/things", openapi.Op{Request: node{}})
+
+	if _, err := spec.Build(router); err == nil || !strings.Contains(err.Error(), "POST /things") {
+		t.Errorf("Expected the stale description reported, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	openAPIVersion = regexp.MustCompile(`^3\.0\.\d+$`)
+	componentName  = regexp.MustCompile(`^[a-zA-Z0-9.\-_]+$`)
+	responseCode   = regexp.MustCompile(`^(default|[1-5](\d\d|XX))$`)
+	templateVar    = regexp.MustCompile(`{([^}/]+)}`)
+)
+
+// methods are the operations a path item may have
+var methods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// schemaTypes are the types an OpenAPI 3.0 schema may have
+var schemaTypes = map[string]bool{
+	"": true, "array": true, "boolean": true, "integer": true,
+	"number": true, "object": true, "string": true,
+}
+
+// Validate checks the document against the rules of the OpenAPI 3.0 schema that apply to
+// what Build emits: required fields, path templates and their parameters, response
+// codes, unique operation IDs and resolvable references
+func (d *Document) Validate() error {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if !openAPIVersion.MatchString(d.OpenAPI) {
+		fail("openapi %q isn't a 3.0 version", d.OpenAPI)
+	}
+	if d.Info.Title == "" || d.Info.Version == "" {
+		fail("info needs a title and a version")
+	}
+	for name := range d.Components.SecuritySchemes {
+		if !componentName.MatchString(name) {
+			fail("security scheme name %q is invalid", name)
+		}
+	}
+	for name, schema := range d.Components.Schemas {
+		if !componentName.MatchString(name) {
+			fail("schema name %q is invalid", name)
+		}
+		d.validateSchema("components.schemas."+name, schema, fail)
+	}
+
+	operationIDs := make(map[string]string)
+	for path, item := range d.Paths {
+		if !strings.HasPrefix(path, "/") {
+			fail("path %q doesn't start with /", path)
+		}
+		variables := make(map[string]bool)
+		for _, match := range templateVar.FindAllStringSubmatch(path, -1) {
+			variables[match[1]] = true
+		}
+		for method, op := range item {
+			where := strings.ToUpper(method) + " " + path
+			if !methods[method] {
+				fail("%s: unknown method", where)
+				continue
+			}
+			if op.OperationID == "" {
+				fail("%s: missing operationId", where)
+			} else if other, ok := operationIDs[op.OperationID]; ok {
+				fail("%s: operationId %q is also used by %s", where, op.OperationID, other)
+			} else {
+				operationIDs[op.OperationID] = where
+			}
+			d.validateOperation(where, op, variables, fail)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid OpenAPI document: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateOperation checks one operation's parameters, body, responses and security
+func (d *Document) validateOperation(where string, op *Operation, variables map[string]bool, fail func(string, ...interface{})) {
+	declared := make(map[string]bool)
+	for _, param := range op.Parameters {
+		key := param.In + " " + param.Name
+		if param.Name == "" || declared[key] {
+			fail("%s: parameter %q is unnamed or repeated", where, param.Name)
+		}
+		declared[key] = true
+		switch param.In {
+		case "path":
+			if !variables[param.Name] {
+				fail("%s: path parameter %q isn't in the path", where, param.Name)
+			}
+			if !param.Required {
+				fail("%s: path parameter %q must be required", where, param.Name)
+			}
+		case "query", "header", "cookie":
+		default:
+			fail("%s: parameter %q is in %q", where, param.Name, param.In)
+		}
+		if param.Schema == nil {
+			fail("%s: parameter %q has no schema", where, param.Name)
+		} else {
+			d.validateSchema(where+" parameter "+param.Name, param.Schema, fail)
+		}
+	}
+	for variable := range variables {
+		if !declared["path "+variable] {
+			fail("%s: path parameter %q isn't declared", where, variable)
+		}
+	}
+
+	if op.RequestBody != nil {
+		if len(op.RequestBody.Content) == 0 {
+			fail("%s: request body has no content", where)
+		}
+		for mediaType, content := range op.RequestBody.Content {
+			d.validateSchema(where+" request "+mediaType, content.Schema, fail)
+		}
+	}
+
+	if len(op.Responses) == 0 {
+		fail("%s: no responses", where)
+	}
+	for code, response := range op.Responses {
+		if !responseCode.MatchString(code) {
+			fail("%s: response code %q is invalid", where, code)
+		}
+		if response.Description == "" {
+			fail("%s: response %s has no description", where, code)
+		}
+		for mediaType, content := range response.Content {
+			d.validateSchema(where+" response "+code+" "+mediaType, content.Schema, fail)
+		}
+	}
+
+	for _, requirement := range op.Security {
+		for name := range requirement {
+			if d.Components.SecuritySchemes[name] == nil {
+				fail("%s: security scheme %q isn't defined", where, name)
+			}
+		}
+	}
+}
+
+// validateSchema checks that references resolve and that each schema is well formed
+func (d *Document) validateSchema(where string, schema *Schema, fail func(string, ...interface{})) {
+	if schema == nil {
+		fail("%s: missing schema", where)
+		return
+	}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		if name == schema.Ref || d.Components.Schemas[name] == nil {
+			fail("%s: unresolved reference %q", where, schema.Ref)
+		}
+		if schema.Type != "" || schema.Properties != nil || schema.Items != nil || schema.Nullable {
+			fail("%s: $ref has sibling keywords", where)
+		}
+		return
+	}
+	if !schemaTypes[schema.Type] {
+		fail("%s: unknown type %q", where, schema.Type)
+	}
+	if schema.Type == "array" && schema.Items == nil {
+		fail("%s: array without items", where)
+	}
+	for _, name := range schema.Required {
+		if schema.Properties[name] == nil {
+			fail("%s: required property %q isn't defined", where, name)
+		}
+	}
+	for name, property := range schema.Properties {
+		d.validateSchema(where+"."+name, property, fail)
+	}
+	if schema.Items != nil {
+		d.validateSchema(where+"[]", schema.Items, fail)
+	}
+	if schema.AdditionalProperties != nil {
+		d.validateSchema(where+"{}", schema.AdditionalProperties, fail)
+	}
+}
@@ -0,0 +1,345 @@
+// Package retention deletes tenant data once it is older than the tenant's policy allows.
+//
+// Every tenant has a retention policy: how many days logs, execution payloads,
+// executions and fixtures are kept. Defaults come from the tenant's tier (the log
+// period from LOG_RETENTION_DAYS, set on the worker) and can be overridden per tenant
+// in tenant_settings. The worker applies all policies in rounds, removing at most one batch per tenant and data class per round, so a
+// tenant with millions of expired rows can't hold up everyone else's purge.
+package retention
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// maxDays bounds any retention period (10 years)
+const maxDays = 3650
+
+// DataClasses lists the data classes in the order they are purged
+var DataClasses = []string{
+	models.RetentionPayloads,
+	models.RetentionLogs,
+	models.RetentionExecutions,
+	models.RetentionFixtures,
+	models.RetentionDebugRequests,
+}
+
+// tierDefaults are the retention periods each tier gets without overrides
+var tierDefaults = map[string]models.RetentionPolicy{
+	models.TierFree:       {LogsDays: 30, PayloadsDays: 7, ExecutionsDays: 30, FixturesDays: 90},
+	models.TierPro:        {LogsDays: 90, PayloadsDays: 30, ExecutionsDays: 90, FixturesDays: 365},
+	models.TierEnterprise: {LogsDays: 395, PayloadsDays: 90, ExecutionsDays: 395, FixturesDays: 730}, // 13 months
+}
+
+// DefaultLogsDays is how long logs are kept when LOG_RETENTION_DAYS isn't set
+const DefaultLogsDays = 30
+
+// TierDefaults returns the retention policy of a tier; unknown tiers get the free tier's
+// logsDays is the default log period (LOG_RETENTION_DAYS): the free tier keeps logs that
+// long and the paid tiers at least that long. Payloads never outlive their logs, so a
+// shorter period shortens the free tier's payloads too
+func TierDefaults(tier string, logsDays int) models.RetentionPolicy {
+	policy, ok := tierDefaults[tier]
+	if !ok {
+		tier, policy = models.TierFree, tierDefaults[models.TierFree]
+	}
+
+	if tier == models.TierFree || policy.LogsDays < logsDays {
+		policy.LogsDays = logsDays
+	}
+	if policy.PayloadsDays > policy.LogsDays {
+		policy.PayloadsDays = policy.LogsDays
+	}
+	return policy
+}
+
+// Effective returns the policy that applies to a tenant: the tier defaults for the
+// default log period with every non-zero override on top
+func Effective(settings *models.TenantSettings, logsDays int) models.RetentionPolicy {
+	policy := TierDefaults(settings.Tier, logsDays)
+	if overrides := settings.Retention; overrides != nil {
+		if overrides.LogsDays != 0 {
+			policy.LogsDays = overrides.LogsDays
+		}
+		if overrides.PayloadsDays != 0 {
+			policy.PayloadsDays = overrides.PayloadsDays
+		}
+		if overrides.ExecutionsDays != 0 {
+			policy.ExecutionsDays = overrides.ExecutionsDays
+		}
+		if overrides.FixturesDays != 0 {
+			policy.FixturesDays = overrides.FixturesDays
+		}
+	}
+	return policy
+}
+
+// Validate rejects policies outside the allowed range or that break the hierarchy:
+// payloads live on executions and are the most sensitive data, so they never
+// outlive the logs or executions they belong to
+func Validate(policy models.RetentionPolicy) error {
+	periods := []struct {
+		name string
+		days int
+	}{
+		{"logs_days", policy.LogsDays},
+		{"payloads_days", policy.PayloadsDays},
+		{"executions_days", policy.ExecutionsDays},
+		{"fixtures_days", policy.FixturesDays},
+	}
+	for _, period := range periods {
+		if period.days < 1 || period.days > maxDays {
+			return fmt.Errorf("%s must be between 1 and %d (got %d)", period.name, maxDays, period.days)
+		}
+	}
+	if policy.PayloadsDays > policy.LogsDays {
+		return fmt.Errorf("payloads_days (%d) must not exceed logs_days (%d)", policy.PayloadsDays, policy.LogsDays)
+	}
+	if policy.PayloadsDays > policy.ExecutionsDays {
+		return fmt.Errorf("payloads_days (%d) must not exceed executions_days (%d)", policy.PayloadsDays, policy.ExecutionsDays)
+	}
+	return nil
+}
+
+// Days returns the retention period of a data class
+func Days(policy models.RetentionPolicy, dataClass string) int {
+	switch dataClass {
+	case models.RetentionLogs:
+		return policy.LogsDays
+	case models.RetentionPayloads:
+		return policy.PayloadsDays
+	case models.RetentionExecutions:
+		return policy.ExecutionsDays
+	case models.RetentionFixtures:
+		return policy.FixturesDays
+	case models.RetentionDebugRequests:
+		return policy.PayloadsDays // Raw request bodies are payloads
+	}
+	return 0
+}
+
+// Store is the part of db.Store the worker needs
+type Store interface {
+	ListTenantIDs() ([]string, error)
+	GetTenantSettings(tenantID string) (*models.TenantSettings, error)
+	PurgeTenantData(tenantID, dataClass string, before time.Time, limit int) (int64, error)
+	RecordRetentionRun(run *models.RetentionRun) error
+}
+
+// Worker applies tenant retention policies on a schedule
+type Worker struct {
+	store Store
+	log   *logger.Logger
+
+	runMu sync.Mutex // One pass at a time
+
+	mu        sync.Mutex
+	interval  time.Duration // 0 disables scheduled passes
+	batchSize int
+	logsDays  int // Default log period without a tenant override
+	changed   chan struct{}
+	done      chan struct{}
+}
+
+// NewWorker creates a retention worker over store
+func NewWorker(store Store, log *logger.Logger) *Worker {
+	return &Worker{
+		store:     store,
+		log:       log,
+		batchSize: 500,
+		logsDays:  DefaultLogsDays,
+		changed:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// SetDefaultLogsDays changes how many days logs are kept without a tenant override
+// (LOG_RETENTION_DAYS); periods outside 1 to 3650 days are ignored
+func (w *Worker) SetDefaultLogsDays(days int) {
+	if days < 1 || days > maxDays {
+		return
+	}
+	w.mu.Lock()
+	w.logsDays = days
+	w.mu.Unlock()
+}
+
+// DefaultLogsDays returns how many days logs are kept without a tenant override
+func (w *Worker) DefaultLogsDays() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.logsDays
+}
+
+// SetSchedule changes how often policies are applied and the per-tenant batch size
+// An interval of 0 pauses scheduled passes; the change applies from the next wait
+func (w *Worker) SetSchedule(interval time.Duration, batchSize int) {
+	w.mu.Lock()
+	changed := interval != w.interval
+	w.interval = interval
+	if batchSize > 0 {
+		w.batchSize = batchSize
+	}
+	w.mu.Unlock()
+
+	if changed {
+		w.log.Info("Retention schedule changed", map[string]interface{}{
+			"interval":   interval.String(),
+			"batch_size": batchSize,
+		})
+		select {
+		case w.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start applies retention policies on the schedule until Stop is called
+func (w *Worker) Start() {
+	w.log.Info("Retention worker started", map[string]interface{}{
+		"interval": w.currentInterval().String(),
+	})
+
+	go func() {
+		for {
+			// A nil channel never fires, so a zero interval just waits for a change
+			var tick <-chan time.Time
+			var timer *time.Timer
+			if interval := w.currentInterval(); interval > 0 {
+				timer = time.NewTimer(interval)
+				tick = timer.C
+			}
+			select {
+			case <-tick:
+				w.Run(time.Now())
+			case <-w.changed:
+			case <-w.done:
+				w.log.Info("Retention worker stopped", nil)
+				return
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// Stop stops scheduled passes; a pass already running finishes
+func (w *Worker) Stop() {
+	close(w.done)
+}
+
+func (w *Worker) currentInterval() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.interval
+}
+
+// pending is one tenant's progress through a pass
+type pending struct {
+	run     models.RetentionRun
+	cutoffs map[string]time.Time
+	classes []string // Data classes that may still have expired rows
+}
+
+// Run applies every tenant's policy as of now and records what each tenant lost
+// Tenants are served round-robin, one batch per data class per round, until
+// nothing expired is left; a tenant whose purge fails is dropped from the pass
+func (w *Worker) Run(now time.Time) []models.RetentionRun {
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+
+	w.mu.Lock()
+	batchSize, logsDays := w.batchSize, w.logsDays
+	w.mu.Unlock()
+
+	tenantIDs, err := w.store.ListTenantIDs()
+	if err != nil {
+		w.log.Error("Failed to list tenants for retention", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil
+	}
+
+	var tenants []*pending
+	for _, tenantID := range tenantIDs {
+		p := &pending{run: models.RetentionRun{TenantID: tenantID, StartedAt: now, Removed: map[string]int64{}}}
+		tenants = append(tenants, p)
+
+		settings, err := w.store.GetTenantSettings(tenantID)
+		if err == nil {
+			p.run.Policy = Effective(settings, logsDays)
+			err = Validate(p.run.Policy)
+		}
+		if err != nil {
+			p.run.Error = err.Error()
+			continue
+		}
+		p.cutoffs = make(map[string]time.Time, len(DataClasses))
+		for _, dataClass := range DataClasses {
+			p.cutoffs[dataClass] = now.AddDate(0, 0, -Days(p.run.Policy, dataClass))
+		}
+		p.classes = append([]string(nil), DataClasses...)
+	}
+
+	for active := true; active; {
+		active = false
+		for _, p := range tenants {
+			remaining := p.classes[:0]
+			for _, dataClass := range p.classes {
+				removed, err := w.store.PurgeTenantData(p.run.TenantID, dataClass, p.cutoffs[dataClass], batchSize)
+				p.run.Removed[dataClass] += removed
+				if err != nil {
+					p.run.Error = fmt.Sprintf("%s: %v", dataClass, err)
+					remaining = nil
+					break
+				}
+				if removed >= int64(batchSize) {
+					remaining = append(remaining, dataClass)
+				}
+			}
+			p.classes = remaining
+			if len(p.classes) > 0 {
+				active = true
+			}
+		}
+	}
+
+	runs := make([]models.RetentionRun, 0, len(tenants))
+	for _, p := range tenants {
+		p.run.FinishedAt = time.Now()
+		if err := w.store.RecordRetentionRun(&p.run); err != nil {
+			w.log.Warn("Failed to record retention pass", map[string]interface{}{
+				"tenant_id": p.run.TenantID,
+				"error":     err.Error(),
+			})
+		}
+		w.logRun(p.run)
+		runs = append(runs, p.run)
+	}
+	return runs
+}
+
+// logRun reports a tenant's pass when it removed anything or failed
+func (w *Worker) logRun(run models.RetentionRun) {
+	var total int64
+	for _, removed := range run.Removed {
+		total += removed
+	}
+	if run.Error != "" {
+		w.log.Error("Retention pass failed for tenant", map[string]interface{}{
+			"tenant_id": run.TenantID,
+			"removed":   run.Removed,
+			"error":     run.Error,
+		})
+	} else if total > 0 {
+		w.log.Info("Retention pass removed expired data", map[string]interface{}{
+			"tenant_id": run.TenantID,
+			"removed":   run.Removed,
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package retention_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/retention"
+)
+
+// ages are the row ages, in days, written for every data class
+var ages = []int{1, 5, 10, 20, 40, 100, 200, 400}
+
+// TestRetentionPerTenantPolicies applies three tenants' policies in small batches
+// and checks exactly the rows inside each policy survive
+func TestRetentionPerTenantPolicies(t *testing.T) {
+	database := dbtest.New(t)
+	now := time.Now()
+
+	tenants := []struct {
+		email     string
+		tier      string
+		overrides *models.RetentionPolicy
+		logs      int // Rows expected to survive, by class
+		payloads  int
+		execs     int
+		fixtures  int
+	}{
+		// Free defaults: logs and executions 30d, payloads 7d, fixtures 90d
+		{"free@example.com", models.TierFree, nil, 4, 2, 4, 5},
+		// Pro (executions 90d, fixtures 365d) with longer logs and shorter payloads
+		{"pro@example.com", models.TierPro, &models.RetentionPolicy{LogsDays: 60, PayloadsDays: 14}, 5, 3, 5, 7},
+		// Enterprise defaults: logs and executions 13 months, payloads 90d, fixtures 730d
+		{"enterprise@example.com", models.TierEnterprise, nil, 7, 5, 7, 8},
+	}
+
+	tenantIDs := make([]string, len(tenants))
+	workflowIDs := make([]string, len(tenants))
+	executionIDs := make([][]string, len(tenants))
+	for i, tenant := range tenants {
+		user, err := database.CreateUser(tenant.email, "hashed")
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		tenantIDs[i] = models.DefaultTenantID(user.ID)
+		if err := database.SaveTenantSettings(&models.TenantSettings{
+			TenantID: tenantIDs[i], Tier: tenant.tier, Retention: tenant.overrides,
+		}); err != nil {
+			t.Fatalf("Failed to save tenant settings: %v", err)
+		}
+		workflow, err := database.CreateWorkflow(user.ID, "Orders", "webhook", "testing", `{}`)
+		if err != nil {
+			t.Fatalf("Failed to create workflow: %v", err)
+		}
+		workflowIDs[i] = workflow.ID
+
+		for _, age := range ages {
+			at := now.AddDate(0, 0, -age)
+			if err := database.CreateLogEntry(&models.Log{WorkflowID: workflow.ID, Status: "success", ExecutedAt: at}); err != nil {
+				t.Fatalf("Failed to create log: %v", err)
+			}
+			execution := &models.Execution{
+				WorkflowID: workflow.ID, Status: "success", TriggerSource: "webhook",
+				TriggerPayload: `{"order": 1}`, ResultData: `{"status": "success"}`, ExecutedAt: at,
+			}
+			if err := database.CreateExecution(execution); err != nil {
+				t.Fatalf("Failed to create execution: %v", err)
+			}
+			executionIDs[i] = append(executionIDs[i], execution.ID)
+			if err := database.CreateWorkflowFixture(&models.WorkflowFixture{
+				WorkflowID: workflow.ID, Name: fmt.Sprintf("day-%d", age), Payload: json.RawMessage(`{}`), Source: "manual", CreatedAt: at,
+			}, 100); err != nil {
+				t.Fatalf("Failed to create fixture: %v", err)
+			}
+		}
+	}
+
+	// A batch of 2 takes several rounds per tenant, interleaving the tenants
+	worker := retention.NewWorker(database, logger.NewLogger("test"))
+	worker.SetSchedule(0, 2)
+	runs := make(map[string]models.RetentionRun)
+	for _, run := range worker.Run(now) {
+		runs[run.TenantID] = run
+	}
+	if len(runs) != len(tenants) {
+		t.Fatalf("Expected %d tenant runs, got %d", len(tenants), len(runs))
+	}
+
+	for i, tenant := range tenants {
+		logs, err := database.GetLogsByWorkflowID(workflowIDs[i])
+		if err != nil {
+			t.Fatalf("Failed to read logs: %v", err)
+		}
+		fixtures, err := database.ListWorkflowFixtures(workflowIDs[i])
+		if err != nil {
+			t.Fatalf("Failed to read fixtures: %v", err)
+		}
+		var execs, payloads int
+		for j, id := range executionIDs[i] {
+			execution, err := database.GetExecutionByID(id)
+			if err != nil {
+				continue
+			}
+			execs++
+			if execution.TriggerPayload != "" {
+				payloads++
+			} else if execution.ResultData != "" {
+				t.Errorf("%s: execution aged %dd kept its result trace without its payload", tenant.email, ages[j])
+			}
+		}
+
+		got := [4]int{len(logs), payloads, execs, len(fixtures)}
+		want := [4]int{tenant.logs, tenant.payloads, tenant.execs, tenant.fixtures}
+		if got != want {
+			t.Errorf("%s: expected logs/payloads/executions/fixtures %v to survive, got %v", tenant.email, want, got)
+		}
+
+		run := runs[tenantIDs[i]]
+		if run.Error != "" {
+			t.Errorf("%s: unexpected retention error: %s", tenant.email, run.Error)
+		}
+		if removed := run.Removed[models.RetentionLogs]; removed != int64(len(ages)-tenant.logs) {
+			t.Errorf("%s: expected %d logs removed, got %d", tenant.email, len(ages)-tenant.logs, removed)
+		}
+	}
+
+	// The last purge is kept with the tenant's settings
+	settings, err := database.GetTenantSettings(tenantIDs[0])
+	if err != nil {
+		t.Fatalf("Failed to read tenant settings: %v", err)
+	}
+	if settings.LastPurge == nil || settings.LastPurge.Removed[models.RetentionFixtures] != 3 {
+		t.Errorf("Expected the last purge to record 3 fixtures removed, got %+v", settings.LastPurge)
+	}
+	if settings.Tier != models.TierFree {
+		t.Errorf("Expected recording a purge to keep the tier, got %q", settings.Tier)
+	}
+
+	// A second pass finds nothing left to remove
+	for _, run := range worker.Run(now) {
+		for dataClass, removed := range run.Removed {
+			if removed != 0 {
+				t.Errorf("%s: expected nothing left to purge, %d %s removed", run.TenantID, removed, dataClass)
+			}
+		}
+	}
+}
+
+// TestValidateRetentionPolicy enforces ranges and the payload hierarchy
+func TestValidateRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  models.RetentionPolicy
+		wantErr string
+	}{
+		{"tier default", retention.TierDefaults(models.TierFree, retention.DefaultLogsDays), ""},
+		{"payloads as long as logs", models.RetentionPolicy{LogsDays: 30, PayloadsDays: 30, ExecutionsDays: 30, FixturesDays: 1}, ""},
+		{"payloads outlive logs", models.RetentionPolicy{LogsDays: 7, PayloadsDays: 14, ExecutionsDays: 30, FixturesDays: 30}, "must not exceed logs_days"},
+		{"payloads outlive executions", models.RetentionPolicy{LogsDays: 30, PayloadsDays: 14, ExecutionsDays: 7, FixturesDays: 30}, "must not exceed executions_days"},
+		{"zero", models.RetentionPolicy{LogsDays: 30, PayloadsDays: 7, ExecutionsDays: 30}, "fixtures_days must be between"},
+		{"too long", models.RetentionPolicy{LogsDays: 5000, PayloadsDays: 7, ExecutionsDays: 30, FixturesDays: 30}, "logs_days must be between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := retention.Validate(tt.policy)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Overrides apply on top of the tier, so a short log override can break the hierarchy
+	settings := &models.TenantSettings{Tier: models.TierEnterprise, Retention: &models.RetentionPolicy{LogsDays: 30}}
+	if err := retention.Validate(retention.Effective(settings, retention.DefaultLogsDays)); err == nil {
+		t.Error("Expected 30 days of logs to conflict with the enterprise tier's 90 days of payloads")
+	}
+}
+
+// TestDefaultLogRetention sets LOG_RETENTION_DAYS' value on the worker: the free tier keeps
+// logs that long (and its payloads no longer), paid tiers at least that long, and
+// overrides still win
+func TestDefaultLogRetention(t *testing.T) {
+	if free := retention.TierDefaults(models.TierFree, 3); free.LogsDays != 3 || free.PayloadsDays != 3 || retention.Validate(free) != nil {
+		t.Errorf("Expected the free tier to keep logs and payloads 3 days, got %+v", free)
+	}
+	if pro := retention.TierDefaults(models.TierPro, 3); pro.LogsDays != 90 {
+		t.Errorf("Expected the pro tier to keep its 90 days of logs, got %+v", pro)
+	}
+
+	database := dbtest.New(t)
+	now := time.Now()
+	var workflowIDs []string
+	for _, override := range []*models.RetentionPolicy{nil, {LogsDays: 15}} {
+		user, _ := database.CreateUser(fmt.Sprintf("default-%d@example.com", len(workflowIDs)), "hashed")
+		database.SaveTenantSettings(&models.TenantSettings{TenantID: models.DefaultTenantID(user.ID), Tier: models.TierFree, Retention: override})
+		workflow, _ := database.CreateWorkflow(user.ID, "Noisy", "webhook", "testing", `{}`)
+		workflowIDs = append(workflowIDs, workflow.ID)
+		for _, age := range ages {
+			database.CreateLogEntry(&models.Log{WorkflowID: workflow.ID, Status: "success", ExecutedAt: now.AddDate(0, 0, -age)})
+		}
+	}
+
+	worker := retention.NewWorker(database, logger.NewLogger("test"))
+	worker.SetDefaultLogsDays(3)
+	worker.SetDefaultLogsDays(0) // Out of range, ignored
+	if days := worker.DefaultLogsDays(); days != 3 {
+		t.Fatalf("Expected a default of 3 days, got %d", days)
+	}
+	for _, run := range worker.Run(now) {
+		if run.Error != "" {
+			t.Errorf("%s: unexpected retention error: %s", run.TenantID, run.Error)
+		}
+	}
+	for i, want := range []int{1, 3} {
+		if logs, _ := database.GetLogsByWorkflowID(workflowIDs[i]); len(logs) != want {
+			t.Errorf("Tenant %d: expected %d logs to survive, got %d", i, want, len(logs))
+		}
+	}
+
+	if pro, enterprise := retention.TierDefaults(models.TierPro, 120), retention.TierDefaults(models.TierEnterprise, 120); pro.LogsDays != 120 || enterprise.LogsDays != 395 {
+		t.Errorf("Expected paid tiers to keep logs at least 120 days, got %d and %d", pro.LogsDays, enterprise.LogsDays)
+	}
+}
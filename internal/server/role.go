@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+// Instance roles (ROLE)
+// A read-only instance is a warm standby serving dashboards next to the primary:
+// it runs no scheduler or workers and sends every write to the primary
+const (
+	RolePrimary  = "primary"
+	RoleReadOnly = "readonly"
+)
+
+// PrimaryLocationHeader points clients of a read-only instance at the same URL on the primary
+const PrimaryLocationHeader = "X-Primary-Location"
+
+// readOnlyWrites are mutating routes a read-only instance still serves because they write nothing
+// Logging in isn't one: it stores a refresh token, so it goes to the primary too
+var readOnlyWrites = map[string]bool{
+	"/api/templates/lint":                 true,
+	"/api/workflows/{id}/render-template": true,
+}
+
+// readOnlyReads are GET routes a read-only instance sends to the primary because they
+// record audit events, which a read-only database can't store
+var readOnlyReads = map[string]bool{
+	"/api/workflows/{id}/executions/{executionId}": true,
+}
+
+// ValidateRole checks an instance role ("" means primary)
+func ValidateRole(role string) error {
+	switch role {
+	case "", RolePrimary, RoleReadOnly:
+		return nil
+	}
+	return fmt.Errorf("ROLE must be %q or %q", RolePrimary, RoleReadOnly)
+}
+
+// restrictToReads swaps the handler of every route a read-only instance can't serve
+// for one answering 503 with a pointer to the primary
+// Runs after registration, so routes added later are covered without being listed anywhere
+func restrictToReads(router *mux.Router, primaryURL string) error {
+	return router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // Path prefixes of subrouters
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if (mutating(method) && !readOnlyWrites[template]) || (!mutating(method) && readOnlyReads[template]) {
+				route.Handler(primaryOnly(primaryURL))
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// rejectImpersonation sends impersonated requests to the primary, where they are audited
+func rejectImpersonation(primaryURL string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, _, impersonated := middleware.GetImpersonationFromContext(r.Context()); impersonated {
+				primaryOnly(primaryURL).ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// primaryOnly answers requests only the primary can serve
+func primaryOnly(primaryURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if primaryURL != "" {
+			w.Header().Set(PrimaryLocationHeader, strings.TrimSuffix(primaryURL, "/")+r.URL.RequestURI())
+		}
+		http.Error(w, "This instance is read-only; send this request to the primary", http.StatusServiceUnavailable)
+	})
+}
+
+// mutating reports whether a method can change data
+func mutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,387 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/backup"
+	"github.com/alexmacdonald/simple-ipass/internal/config"
+	"github.com/alexmacdonald/simple-ipass/internal/costs"
+	"github.com/alexmacdonald/simple-ipass/internal/db"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/export"
+	"github.com/alexmacdonald/simple-ipass/internal/handlers"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/middleware"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/notify"
+	"github.com/alexmacdonald/simple-ipass/internal/openapi"
+	"github.com/alexmacdonald/simple-ipass/internal/retention"
+	"github.com/alexmacdonald/simple-ipass/internal/version"
+	"github.com/gorilla/mux"
+)
+
+// Config holds the dependencies the API routes are built from
+type Config struct {
+	Store        db.Store
+	Executor     *engine.Executor
+	Logger       *logger.Logger
+	CostTable    *costs.Table
+	KongAdminURL string
+	KongAdmin    handlers.KongAdminConfig // Admin API token, timeout and retries
+	DomainLookup handlers.TXTResolver     // Verifies tenant domains (default: system DNS)
+	DevLogin     bool                     // Expose /api/auth/dev-login (see handlers.DevLoginEnabled)
+
+	WebhookWaitTimeout time.Duration                // Longest a synchronous webhook waits for its run (default 25s)
+	PasswordResets     handlers.PasswordResetSender // Delivers forgot-password tokens (default: logged in development)
+
+	RuntimeConfig *config.Manager                // Enables /api/admin/config when set
+	RateLimiter   *middleware.RateLimiter        // Per-tenant API limits (optional)
+	Idempotency   *middleware.IdempotencyManager // Replays retried creates sent with X-Idempotency-Key (optional)
+	AdminEmails   []string                       // Users allowed on /api/admin routes
+
+	Impersonation *middleware.ImpersonationGuard // Enforces impersonation sessions (default: store-backed, read-write)
+	Backups       *backup.Manager                // Enables /api/admin/backups when set
+	Exports       *export.Manager                // Enables /api/tenants/export when set
+	Notifier      *notify.Notifier               // Workflow change notifications (optional)
+	Retention     *retention.Worker              // Default log period for tenant retention policies (default: 30 days)
+
+	Role       string // RolePrimary (default) or RoleReadOnly
+	PrimaryURL string // Where a read-only instance points writes (see PrimaryLocationHeader)
+}
+
+// NewRouter registers every API route
+// The API server and the in-process test harness share it so they can't drift apart
+// A read-only instance registers the same routes, then answers the ones that write with 503
+func NewRouter(cfg Config) *mux.Router {
+	if cfg.Role == "" {
+		cfg.Role = RolePrimary
+	}
+	router := mux.NewRouter()
+
+	// Every route lands in /api/openapi.json; routes describe their bodies next to their registration
+	spec := openapi.New("GoFlow API", version.Get().Version)
+
+	// Tag each request with an X-Request-ID, then log it (status codes, timing and that ID)
+	router.Use(middleware.RequestID)
+	router.Use(middleware.RequestLogger(cfg.Logger))
+
+	// Creates honour X-Idempotency-Key, so a double-submitted form creates one resource
+	// Webhook triggers don't: their cache key would have no user or credentials in it, and
+	// event-ID dedupe already covers a provider's redelivery
+	idempotent := func(h http.HandlerFunc) http.Handler { return h }
+	if cfg.Idempotency != nil {
+		idempotent = func(h http.HandlerFunc) http.Handler { return cfg.Idempotency.IdempotencyMiddleware(h) }
+	}
+
+	// Public routes
+	authHandler := handlers.NewAuthHandler(cfg.Store)
+	router.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST")
+	spec.Describe("POST", "/api/auth/register", openapi.Op{Summary: "Register as the admin of a new tenant", Request: models.RegisterRequest{}, Response: models.AuthResponse{}})
+	router.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST")
+	spec.Describe("POST", "/api/auth/login", openapi.Op{Summary: "Log in and get a JWT", Request: models.LoginRequest{}, Response: models.AuthResponse{}})
+	router.HandleFunc("/api/auth/refresh", authHandler.Refresh).Methods("POST")
+	spec.Describe("POST", "/api/auth/refresh", openapi.Op{Summary: "Exchange a refresh token for a new access and refresh token", Request: models.RefreshRequest{}, Response: models.AuthResponse{}})
+	router.HandleFunc("/api/auth/logout", authHandler.Logout).Methods("POST")
+	spec.Describe("POST", "/api/auth/logout", openapi.Op{Summary: "Revoke a refresh token, or all of its user's sessions", Request: models.LogoutRequest{}, Status: http.StatusNoContent})
+	router.HandleFunc("/api/auth/register-with-invite", authHandler.RegisterWithInvite).Methods("POST")
+	spec.Describe("POST", "/api/auth/register-with-invite", openapi.Op{Summary: "Register into a tenant with an invite", Request: models.RegisterWithInviteRequest{}, Response: models.AuthResponse{}})
+	resetSender := cfg.PasswordResets
+	if resetSender == nil {
+		resetSender = handlers.NewLogResetSender(cfg.Logger)
+	}
+	authHandler.SetResetSender(resetSender)
+	router.HandleFunc("/api/auth/forgot-password", authHandler.ForgotPassword).Methods("POST")
+	spec.Describe("POST", "/api/auth/forgot-password", openapi.Op{Summary: "Request a password reset token", Request: models.ForgotPasswordRequest{}, Response: map[string]string{}, Status: http.StatusAccepted})
+	router.HandleFunc("/api/auth/reset-password", authHandler.ResetPassword).Methods("POST")
+	spec.Describe("POST", "/api/auth/reset-password", openapi.Op{Summary: "Set a new password with a reset token", Request: models.ResetPasswordRequest{}, Response: models.AuthResponse{}})
+
+	// Dev mode endpoint (only enable in development); the handler re-checks the environment itself
+	if cfg.DevLogin {
+		router.HandleFunc("/api/auth/dev-login", authHandler.DevLogin).Methods("POST")
+		spec.Describe("POST", "/api/auth/dev-login", openapi.Op{Summary: "Get a token without a password (development only)", Request: models.DevLoginRequest{}, Response: models.AuthResponse{}})
+		cfg.Logger.Info("Dev mode enabled - /api/auth/dev-login endpoint available", nil)
+	}
+
+	// Webhook handler (public but workflow-specific)
+	webhookHandler := handlers.NewWebhookHandler(cfg.Store, cfg.Executor, cfg.Logger)
+	webhookHandler.SetWaitTimeout(cfg.WebhookWaitTimeout)
+	router.HandleFunc("/api/webhooks/{id}", webhookHandler.TriggerWebhook).Methods("POST")
+	router.HandleFunc("/api/callbacks/twilio/{workflow_id}", webhookHandler.TwilioStatusCallback).Methods("POST")
+
+	// Health check endpoint
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		info := version.Get()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":      "healthy",
+			"version":     info.Version,
+			"git_commit":  info.GitCommit,
+			"instance_id": info.InstanceID,
+			"role":        cfg.Role,
+		})
+	}).Methods("GET")
+
+	// Prometheus scrape target for run counts, durations and queue depth
+	if cfg.Executor != nil {
+		router.Handle("/metrics", cfg.Executor.Metrics()).Methods("GET")
+	}
+
+	// Build metadata (public, registered before the authenticated /api subrouter)
+	router.HandleFunc("/api/version", handlers.VersionHandler(cfg.Role)).Methods("GET")
+	spec.Describe("GET", "/api/version", openapi.Op{Summary: "Build metadata and role of this instance", Response: version.Info{}})
+
+	// API description (public, built from the routes registered here)
+	router.HandleFunc("/api/openapi.json", spec.Handler(router)).Methods("GET")
+	router.HandleFunc("/api/docs", spec.DocsHandler(router, "/api/openapi.json")).Methods("GET")
+
+	// Tenant exports: the signed link authorizes the download, so it is public too
+	var exportHandler *handlers.TenantExportHandler
+	if cfg.Exports != nil {
+		exportHandler = handlers.NewTenantExportHandler(cfg.Store, cfg.Exports)
+		router.HandleFunc("/api/tenants/export/{id}/download", exportHandler.DownloadExport).Methods("GET")
+	}
+
+	// Protected routes with tenant-aware middleware
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(middleware.AuthMiddleware(cfg.Logger)) // Now logs user_id AND tenant_id!
+	if cfg.RateLimiter != nil {
+		api.Use(cfg.RateLimiter.RateLimitMiddleware)
+	}
+	if cfg.Role == RoleReadOnly {
+		api.Use(rejectImpersonation(cfg.PrimaryURL))
+	}
+	impersonation := cfg.Impersonation
+	if impersonation == nil {
+		impersonation = middleware.NewImpersonationGuard(cfg.Store, cfg.Logger)
+	}
+	api.Use(impersonation.Middleware)
+	api.Use(middleware.NewTokenVersionGuard(cfg.Store, cfg.Logger).Middleware)
+
+	// Account routes
+	api.HandleFunc("/auth/change-password", authHandler.ChangePassword).Methods("POST")
+	spec.Describe("POST", "/api/auth/change-password", openapi.Op{Summary: "Change the signed-in user's password", Request: models.ChangePasswordRequest{}, Response: models.AuthResponse{}})
+
+	// Destructive tenant-wide routes are for the tenant admin
+	requireTenantAdmin := middleware.RequireRole(cfg.Logger, models.RoleAdmin)
+
+	// Credentials routes
+	credentialsHandler := handlers.NewCredentialsHandler(cfg.Store)
+	api.Handle("/credentials", idempotent(credentialsHandler.CreateCredential)).Methods("POST")
+	spec.Describe("POST", "/api/credentials", openapi.Op{Summary: "Save an encrypted credential", Request: handlers.CreateCredentialRequest{}, Response: models.Credential{}, Status: http.StatusCreated})
+	api.HandleFunc("/credentials", credentialsHandler.GetCredentials).Methods("GET")
+	spec.Describe("GET", "/api/credentials", openapi.Op{Summary: "List the tenant's credentials", Response: []models.Credential{}})
+	api.HandleFunc("/credentials/test", credentialsHandler.TestCredential).Methods("POST")
+	api.Handle("/credentials/{id}", requireTenantAdmin(http.HandlerFunc(credentialsHandler.DeleteCredential))).Methods("DELETE")
+
+	// Failure messages are translated per request; admins also see the raw detail
+	messages := handlers.NewMessages(handlers.DefaultCatalogs, adminCheck(cfg.Store, cfg.AdminEmails))
+
+	// Workflows routes
+	workflowsHandler := handlers.NewWorkflowsHandler(cfg.Store, cfg.Executor, messages)
+	workflowsHandler.SetNotifier(cfg.Notifier)
+	api.Handle("/workflows", idempotent(workflowsHandler.CreateWorkflow)).Methods("POST")
+	spec.Describe("POST", "/api/workflows", openapi.Op{Summary: "Create a workflow", Request: handlers.CreateWorkflowRequest{}, Response: handlers.WorkflowResponse{}, Status: http.StatusCreated})
+	api.HandleFunc("/workflows", workflowsHandler.GetWorkflows).Methods("GET")
+	spec.Describe("GET", "/api/workflows", openapi.Op{Summary: "List the tenant's workflows (a page of them with limit or cursor)", Response: []handlers.WorkflowResponse{}, Query: pageQuery})
+	api.HandleFunc("/workflows/dry-run", workflowsHandler.DryRunWorkflow).Methods("POST")
+	spec.Describe("POST", "/api/workflows/dry-run", openapi.Op{Summary: "Run an action without saving it", Request: handlers.DryRunRequest{}, Response: handlers.DryRunResponse{}})
+	api.HandleFunc("/workflows/bulk", workflowsHandler.BulkWorkflows).Methods("POST")
+	spec.Describe("POST", "/api/workflows/bulk", openapi.Op{Summary: "Enable, disable or delete several workflows", Request: handlers.BulkWorkflowRequest{}, Response: handlers.BulkWorkflowResponse{}})
+	api.HandleFunc("/workflow-templates", workflowsHandler.ListWorkflowTemplates).Methods("GET")
+	api.HandleFunc("/connectors", workflowsHandler.ListConnectors).Methods("GET")
+	spec.Describe("GET", "/api/connectors", openapi.Op{Summary: "List the registered connectors and their config fields", Response: []connectors.ConnectorInfo{}})
+	api.Handle("/workflow-templates/{id}/instantiate", idempotent(workflowsHandler.InstantiateTemplate)).Methods("POST")
+	api.HandleFunc("/workflows/{id}/toggle", workflowsHandler.ToggleWorkflow).Methods("PUT")
+	spec.Describe("PUT", "/api/workflows/{id}/toggle", openapi.Op{Summary: "Enable or disable a workflow", Response: handlers.WorkflowResponse{}})
+	api.HandleFunc("/workflows/{id}/requirements", workflowsHandler.GetWorkflowRequirements).Methods("GET")
+	spec.Describe("GET", "/api/workflows/{id}/requirements", openapi.Op{Summary: "Credential checklist of a workflow", Response: models.WorkflowRequirements{}})
+	api.HandleFunc("/workflows/{id}/stats", workflowsHandler.GetWorkflowStats).Methods("GET")
+	spec.Describe("GET", "/api/workflows/{id}/stats", openapi.Op{Summary: "Run statistics of a workflow", Response: models.RunStats{}, Query: statsQuery})
+	api.HandleFunc("/workflows/{id}/dry-run-diff", workflowsHandler.DryRunDiff).Methods("POST")
+	api.HandleFunc("/workflows/{id}/run", workflowsHandler.RunWorkflow).Methods("POST")
+	spec.Describe("POST", "/api/workflows/{id}/run", openapi.Op{Summary: "Run a saved workflow now", Response: handlers.RunWorkflowResponse{}, Status: http.StatusAccepted})
+	api.HandleFunc("/workflows/{id}/fixtures", workflowsHandler.ListFixtures).Methods("GET")
+	api.HandleFunc("/workflows/{id}/fixtures", workflowsHandler.CreateFixture).Methods("POST")
+	api.HandleFunc("/workflows/{id}/fixtures/capture", workflowsHandler.CaptureFixture).Methods("POST")
+	api.HandleFunc("/workflows/{id}/fixtures/{name}", workflowsHandler.DeleteFixture).Methods("DELETE")
+	api.HandleFunc("/workflows/{id}/executions", workflowsHandler.ListExecutions).Methods("GET")
+	api.HandleFunc("/workflows/{id}/executions/{executionId}", workflowsHandler.GetExecution).Methods("GET")
+	api.HandleFunc("/workflows/{id}/webhook/security", workflowsHandler.GetWebhookSecurity).Methods("GET")
+	api.HandleFunc("/workflows/{id}/webhook/security", workflowsHandler.UpdateWebhookSecurity).Methods("PUT")
+	api.HandleFunc("/workflows/{id}/debug/requests", workflowsHandler.GetDebugRequests).Methods("GET")
+	api.HandleFunc("/workflows/{id}/debug/requests", workflowsHandler.UpdateDebugRequests).Methods("PUT")
+	api.HandleFunc("/workflows/{id}/debug/requests/{requestId}/replay", workflowsHandler.ReplayDebugRequest).Methods("POST")
+	api.HandleFunc("/workflows/{id}/payloads", workflowsHandler.GetWebhookPayloads).Methods("GET")
+	api.HandleFunc("/workflows/{id}/render-template", workflowsHandler.RenderTemplate).Methods("POST")
+	api.HandleFunc("/workflows/{id}/pause", workflowsHandler.PauseWorkflow).Methods("PUT")
+	spec.Describe("PUT", "/api/workflows/{id}/pause", openapi.Op{Summary: "Pause or resume a workflow", Request: handlers.PauseRequest{}, Response: handlers.WorkflowResponse{}})
+	api.HandleFunc("/workflows/{id}", workflowsHandler.UpdateWorkflow).Methods("PUT")
+	spec.Describe("PUT", "/api/workflows/{id}", openapi.Op{Summary: "Update a workflow", Request: handlers.CreateWorkflowRequest{}, Response: handlers.WorkflowResponse{}})
+	api.HandleFunc("/workflows/{id}", workflowsHandler.DeleteWorkflow).Methods("DELETE")
+	spec.Describe("DELETE", "/api/workflows/{id}", openapi.Op{Summary: "Delete a workflow and its logs", Status: http.StatusNoContent})
+	api.HandleFunc("/stats/overview", workflowsHandler.GetStatsOverview).Methods("GET")
+	spec.Describe("GET", "/api/stats/overview", openapi.Op{Summary: "Run statistics across the tenant's workflows", Response: models.RunStatsOverview{}, Query: statsQuery})
+	api.HandleFunc("/dead-letters", workflowsHandler.ListDeadLetters).Methods("GET")
+	api.HandleFunc("/dead-letters/{id}/retry", workflowsHandler.RetryDeadLetter).Methods("POST")
+
+	// Template tooling
+	templatesHandler := handlers.NewTemplatesHandler()
+	api.HandleFunc("/templates/lint", templatesHandler.LintTemplate).Methods("POST")
+
+	// Logs routes
+	logsHandler := handlers.NewLogsHandler(cfg.Store, messages)
+	if cfg.Executor != nil {
+		logsHandler.SetLogStream(cfg.Executor.Logs())
+	}
+	api.HandleFunc("/logs", logsHandler.GetLogs).Methods("GET")
+	spec.Describe("GET", "/api/logs", openapi.Op{Summary: "List the tenant's execution logs (a page of them with limit or cursor)", Response: []models.WorkflowLog{}, Query: append([]openapi.Parameter{
+		{Name: "workflow_id"}, {Name: "status"}, {Name: "since", Description: "RFC 3339"},
+	}, pageQuery...)})
+	api.HandleFunc("/logs", logsHandler.ClearLogs).Methods("DELETE")
+	spec.Describe("DELETE", "/api/logs", openapi.Op{Summary: "Delete a workflow's log history", Response: map[string]int64{}, Query: []openapi.Parameter{
+		{Name: "workflow_id", Required: true},
+	}})
+	api.HandleFunc("/logs/stream", logsHandler.StreamLogs).Methods("GET")
+	api.HandleFunc("/logs/ack", logsHandler.AcknowledgeLogs).Methods("POST")
+	api.HandleFunc("/logs/{id}/ack", logsHandler.AcknowledgeLog).Methods("POST")
+
+	// Audit trail (tenant admins)
+	auditHandler := handlers.NewAuditHandler(cfg.Store)
+	api.Handle("/audit", requireTenantAdmin(http.HandlerFunc(auditHandler.GetAuditEvents))).Methods("GET")
+
+	// Usage routes
+	usageHandler := handlers.NewUsageHandler(cfg.Store, cfg.CostTable)
+	api.HandleFunc("/usage/costs", usageHandler.GetCosts).Methods("GET")
+
+	// Tenant settings routes
+	tenantSettingsHandler := handlers.NewTenantSettingsHandler(cfg.Store)
+	if cfg.Retention != nil {
+		tenantSettingsHandler.SetRetention(cfg.Retention)
+	}
+	api.HandleFunc("/tenants/settings/retention", tenantSettingsHandler.GetRetention).Methods("GET")
+	api.HandleFunc("/tenants/settings/retention", tenantSettingsHandler.UpdateRetention).Methods("PUT")
+	api.HandleFunc("/tenants/settings/notifications", tenantSettingsHandler.GetNotifications).Methods("GET")
+	api.HandleFunc("/tenants/settings/notifications", tenantSettingsHandler.UpdateNotifications).Methods("PUT")
+	api.HandleFunc("/tenants/settings/notifications/me", tenantSettingsHandler.UpdateMyNotifications).Methods("PUT")
+	api.HandleFunc("/tenants/settings/canaries", tenantSettingsHandler.GetCanaries).Methods("GET")
+	api.HandleFunc("/tenants/settings/canaries", tenantSettingsHandler.UpdateCanaries).Methods("PUT")
+	api.HandleFunc("/tenants/settings/masking", tenantSettingsHandler.GetMasking).Methods("GET")
+	api.HandleFunc("/tenants/settings/masking", tenantSettingsHandler.UpdateMasking).Methods("PUT")
+	api.HandleFunc("/tenants/settings/anomaly", tenantSettingsHandler.GetAnomaly).Methods("GET")
+	api.HandleFunc("/tenants/settings/anomaly", tenantSettingsHandler.UpdateAnomaly).Methods("PUT")
+	api.HandleFunc("/tenants/settings/credential-policy", tenantSettingsHandler.GetCredentialPolicy).Methods("GET")
+	api.HandleFunc("/tenants/settings/credential-policy", tenantSettingsHandler.UpdateCredentialPolicy).Methods("PUT")
+	if exportHandler != nil {
+		api.HandleFunc("/tenants/export", exportHandler.RequestExport).Methods("POST")
+		api.HandleFunc("/tenants/export/{id}", exportHandler.GetExport).Methods("GET")
+	}
+
+	// Kong Gateway integration routes (tenant admins only)
+	kongHandler := handlers.NewKongHandler(cfg.Store, cfg.KongAdminURL)
+	kongHandler.SetAdminConfig(cfg.KongAdmin)
+	kong := api.PathPrefix("/kong").Subrouter()
+	kong.Use(requireTenantAdmin)
+	kong.HandleFunc("/services", kongHandler.CreateKongService).Methods("POST")
+	kong.HandleFunc("/services", kongHandler.ListKongServices).Methods("GET")
+	kong.HandleFunc("/services/{id}", kongHandler.DeleteKongService).Methods("DELETE")
+	kong.HandleFunc("/routes", kongHandler.CreateKongRoute).Methods("POST")
+	kong.HandleFunc("/plugins", kongHandler.AddKongPlugin).Methods("POST")
+	kong.HandleFunc("/templates", kongHandler.CreateUseCaseTemplate).Methods("POST")
+	api.Handle("/workflows/{id}/kong", requireTenantAdmin(http.HandlerFunc(kongHandler.ListWorkflowKong))).Methods("GET")
+	api.Handle("/workflows/{id}/kong", requireTenantAdmin(http.HandlerFunc(kongHandler.DeleteWorkflowKong))).Methods("DELETE")
+	workflowsHandler.SetKong(kongHandler)
+
+	// Tenant webhook domains (Kong routes per hostname)
+	domainsHandler := handlers.NewDomainsHandler(cfg.Store, kongHandler, cfg.DomainLookup)
+	api.HandleFunc("/tenants/domains", domainsHandler.AddDomain).Methods("POST")
+	api.HandleFunc("/tenants/domains", domainsHandler.ListDomains).Methods("GET")
+	api.HandleFunc("/tenants/domains/{id}/verify", domainsHandler.VerifyDomain).Methods("POST")
+	api.HandleFunc("/tenants/domains/{id}", domainsHandler.DeleteDomain).Methods("DELETE")
+
+	// Tenant invites (admins add teammates to their tenant)
+	invitesHandler := handlers.NewInvitesHandler(cfg.Store)
+	api.HandleFunc("/tenants/invites", invitesHandler.CreateInvite).Methods("POST")
+	api.HandleFunc("/tenants/invites", invitesHandler.ListInvites).Methods("GET")
+	api.HandleFunc("/tenants/invites/{id}", invitesHandler.RevokeInvite).Methods("DELETE")
+
+	// Admin routes
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireAdmin(cfg.Logger, adminCheck(cfg.Store, cfg.AdminEmails)))
+
+	impersonationHandler := handlers.NewImpersonationHandler(cfg.Store)
+	admin.HandleFunc("/impersonate", impersonationHandler.StartImpersonation).Methods("POST")
+	admin.HandleFunc("/impersonations", impersonationHandler.ListImpersonations).Methods("GET")
+	admin.HandleFunc("/impersonations/{id}", impersonationHandler.RevokeImpersonation).Methods("DELETE")
+	admin.HandleFunc("/audit", impersonationHandler.GetAuditEvents).Methods("GET")
+
+	encryptionHandler := handlers.NewEncryptionHandler(cfg.Store)
+	admin.HandleFunc("/encryption/rotate", encryptionHandler.RotateKey).Methods("POST")
+
+	workersHandler := handlers.NewWorkersHandler(cfg.Executor)
+	admin.HandleFunc("/workers", workersHandler.GetWorkers).Methods("GET")
+	admin.HandleFunc("/connectors/versions", workersHandler.GetConnectorVersions).Methods("GET")
+
+	tenantLimitsHandler := handlers.NewTenantLimitsHandler(cfg.Store, cfg.RateLimiter)
+	admin.HandleFunc("/tenants/{id}/limits", tenantLimitsHandler.GetTenantLimits).Methods("GET")
+	admin.HandleFunc("/tenants/{id}/limits", tenantLimitsHandler.UpdateTenantLimits).Methods("PUT")
+
+	breakersHandler := handlers.NewCircuitBreakersHandler(cfg.Store, cfg.Executor)
+	admin.HandleFunc("/circuit-breakers", breakersHandler.GetCircuitBreakers).Methods("GET")
+	admin.HandleFunc("/circuit-breakers/{key}/reset", breakersHandler.ResetCircuitBreaker).Methods("POST")
+
+	if cfg.Backups != nil {
+		backupsHandler := handlers.NewBackupsHandler(cfg.Backups)
+		admin.HandleFunc("/backups", backupsHandler.ListBackups).Methods("GET")
+	}
+
+	if cfg.RuntimeConfig != nil {
+		configHandler := handlers.NewConfigHandler(cfg.Store, cfg.RuntimeConfig)
+		admin.HandleFunc("/config", configHandler.GetConfig).Methods("GET")
+		admin.HandleFunc("/config", configHandler.UpdateConfig).Methods("PUT")
+		admin.HandleFunc("/config/changes", configHandler.GetConfigChanges).Methods("GET")
+	}
+
+	if cfg.Role == RoleReadOnly {
+		if err := restrictToReads(router, cfg.PrimaryURL); err != nil {
+			cfg.Logger.Error("Failed to restrict routes to reads", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return router
+}
+
+// adminCheck reports whether a user's email is on the admin list
+func adminCheck(store db.Store, adminEmails []string) func(userID string) bool {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		admins[strings.ToLower(strings.TrimSpace(email))] = true
+	}
+	return func(userID string) bool {
+		if len(admins) == 0 {
+			return false
+		}
+		user, err := store.GetUserByID(userID)
+		if err != nil {
+			return false
+		}
+		return admins[strings.ToLower(user.Email)]
+	}
+}
+
+// Query parameters shared by the described list and stats routes
+var (
+	pageQuery = []openapi.Parameter{
+		{Name: "limit", Schema: &openapi.Schema{Type: "integer"}},
+		{Name: "cursor", Description: "next_cursor of the previous page"},
+	}
+	statsQuery = []openapi.Parameter{
+		{Name: "window", Description: "Days or hours, e.g. 7d (default) or 24h"},
+	}
+)
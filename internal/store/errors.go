@@ -0,0 +1,74 @@
+// Package store defines the error kinds every db.Store implementation reports
+//
+// Callers check kinds with errors.Is (or the Is* helpers) instead of comparing
+// driver errors or messages, so handlers and the executor behave the same against
+// SQLite and the mock store:
+//
+//	workflow, err := h.store.GetWorkflowByID(id)
+//	if store.IsNotFound(err) { ... 404 ... }
+package store
+
+import "errors"
+
+// Error kinds
+var (
+	ErrNotFound    = errors.New("not found")         // The record doesn't exist (or isn't visible to the caller)
+	ErrConflict    = errors.New("conflict")          // A uniqueness or limit constraint rejected the write
+	ErrLocked      = errors.New("store locked")      // The store stayed busy past its retries; safe to retry later
+	ErrUnavailable = errors.New("store unavailable") // The store is closed or its connection is gone
+)
+
+// Error tags an underlying error with its kind
+// errors.Is matches both the kind and the wrapped error (e.g. sql.ErrNoRows)
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Err.Error()
+}
+
+// Unwrap exposes both the kind and the underlying error to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Wrap tags err with a kind; nil stays nil
+func Wrap(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// IsNotFound reports whether err means the record doesn't exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict reports whether err means a write conflicted with existing data
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsLocked reports whether err means the store was too busy to answer
+func IsLocked(err error) bool {
+	return errors.Is(err, ErrLocked)
+}
+
+// IsUnavailable reports whether err means the store can't be reached at all
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// Classified reports whether err already carries one of the kinds
+func Classified(err error) bool {
+	return IsNotFound(err) || IsConflict(err) || IsLocked(err) || IsUnavailable(err)
+}
@@ -0,0 +1,300 @@
+// Package templates is the catalog of ready-made workflow definitions users can start from
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+)
+
+// Parameter types
+const (
+	ParamString  = "string"
+	ParamInteger = "integer"
+)
+
+// Parameter is a value the user supplies when instantiating a template
+// The template's config refers to it as "${name}"; a value that is only the placeholder
+// takes the parameter's type
+type Parameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // ParamString or ParamInteger
+	Description string `json:"description"`
+	Example     string `json:"example,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// Template is a ready-made workflow definition
+type Template struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	RequiredServices []string               `json:"required_services"` // Filled in from the connector registry by All and Get
+	TriggerType      string                 `json:"trigger_type"`
+	ActionType       string                 `json:"action_type"`
+	Config           map[string]interface{} `json:"config"`
+	ActionChain      []models.ChainedAction `json:"action_chain,omitempty"`
+	Parameters       []Parameter            `json:"parameters"`
+}
+
+// placeholderPattern matches a "${name}" placeholder
+var placeholderPattern = regexp.MustCompile(`\$\{([a-z_]+)\}`)
+
+// catalog is every template, in the order they are listed
+var catalog = []Template{
+	{
+		ID:          "webhook-slack-alert",
+		Name:        "Webhook → Slack alert",
+		Description: "Post a Slack message whenever your system calls the workflow's webhook URL.",
+		TriggerType: "webhook",
+		ActionType:  "slack_message",
+		Config: map[string]interface{}{
+			"slack_message": "${message}",
+		},
+		Parameters: []Parameter{
+			{Name: "message", Type: ParamString, Description: "Message to post; {{field}} inserts a field of the webhook payload", Example: "Deploy {{version}} finished: {{status}}", Default: "New event: {{event}}"},
+		},
+	},
+	{
+		ID:          "daily-weather-discord",
+		Name:        "Daily weather → Discord",
+		Description: "Check a city's weather once a day and post it to a Discord channel.",
+		TriggerType: "schedule",
+		ActionType:  "weather_check",
+		Config: map[string]interface{}{
+			"interval": "${interval}",
+			"city":     "${city}",
+		},
+		ActionChain: []models.ChainedAction{{
+			ActionType:  "discord_post",
+			Config:      map[string]interface{}{"discord_message": "Weather in {{city}}: {{description}}, {{temperature}}°C"},
+			UseDataFrom: "previous",
+		}},
+		Parameters: []Parameter{
+			{Name: "city", Type: ParamString, Description: "City to report on", Example: "London", Required: true},
+			{Name: "interval", Type: ParamInteger, Description: "Minutes between reports", Default: "1440"},
+		},
+	},
+	{
+		ID:          "salesforce-lead-sms",
+		Name:        "Salesforce lead created → SMS",
+		Description: "Text a sales rep when a Salesforce flow or outbound message posts a new lead to the webhook URL.",
+		TriggerType: "webhook",
+		ActionType:  "twilio_sms",
+		Config: map[string]interface{}{
+			"twilio_to":      "${phone}",
+			"twilio_message": "New lead: {{FirstName}} {{LastName}} at {{Company}}",
+		},
+		Parameters: []Parameter{
+			{Name: "phone", Type: ParamString, Description: "Phone number to text, in E.164 format", Example: "+15551234567", Required: true},
+		},
+	},
+	{
+		ID:          "news-digest-slack",
+		Name:        "News digest → Slack",
+		Description: "Fetch the top articles on a topic on a schedule and post the headline to Slack.",
+		TriggerType: "schedule",
+		ActionType:  "news_fetch",
+		Config: map[string]interface{}{
+			"interval":       "${interval}",
+			"news_query":     "${query}",
+			"news_page_size": 5,
+			"news_sort_by":   "popularity",
+		},
+		ActionChain: []models.ChainedAction{{
+			ActionType:  "slack_message",
+			Config:      map[string]interface{}{"slack_message": "Top story on ${query}: {{headlines.0.title}} ({{headlines.0.source}}) {{headlines.0.url}}"},
+			UseDataFrom: "previous",
+		}},
+		Parameters: []Parameter{
+			{Name: "query", Type: ParamString, Description: "Topic or company to follow", Example: "Acme Corp", Required: true},
+			{Name: "interval", Type: ParamInteger, Description: "Minutes between digests", Default: "1440"},
+		},
+	},
+	{
+		ID:          "webhook-github-issue",
+		Name:        "Webhook → GitHub issue",
+		Description: "Open a GitHub issue for each alert your monitoring posts to the webhook URL.",
+		TriggerType: "webhook",
+		ActionType:  "github",
+		Config: map[string]interface{}{
+			"github_operation": "create_issue",
+			"github_owner":     "${owner}",
+			"github_repo":      "${repo}",
+			"github_title":     "Alert: {{title}}",
+			"github_body":      "{{description}}",
+		},
+		Parameters: []Parameter{
+			{Name: "owner", Type: ParamString, Description: "User or organization owning the repository", Example: "acme", Required: true},
+			{Name: "repo", Type: ParamString, Description: "Repository to open issues in", Example: "backend", Required: true},
+		},
+	},
+	{
+		ID:          "webhook-email",
+		Name:        "Webhook → Email",
+		Description: "Email a team whenever the webhook URL is called, e.g. for form submissions.",
+		TriggerType: "webhook",
+		ActionType:  "email_send",
+		Config: map[string]interface{}{
+			"email_to":      "${to}",
+			"email_subject": "${subject}",
+			"email_body":    "{{message}}",
+		},
+		Parameters: []Parameter{
+			{Name: "to", Type: ParamString, Description: "Recipients, comma-separated", Example: "team@example.com", Required: true},
+			{Name: "subject", Type: ParamString, Description: "Subject line; {{field}} inserts a field of the webhook payload", Default: "New submission from {{name}}"},
+		},
+	},
+	{
+		ID:          "scheduled-health-check",
+		Name:        "Scheduled health check",
+		Description: "Call an HTTP endpoint on a schedule; the run fails, and shows in the logs, whenever it answers with an error.",
+		TriggerType: "schedule",
+		ActionType:  "http_request",
+		Config: map[string]interface{}{
+			"interval": "${interval}",
+			"http_url": "${url}",
+		},
+		Parameters: []Parameter{
+			{Name: "url", Type: ParamString, Description: "URL to check", Example: "https://api.example.com/health", Required: true},
+			{Name: "interval", Type: ParamInteger, Description: "Minutes between checks", Default: "5"},
+		},
+	},
+}
+
+// All returns every template
+func All() []Template {
+	all := make([]Template, len(catalog))
+	for i, template := range catalog {
+		all[i] = withServices(template)
+	}
+	return all
+}
+
+// Get returns a template by ID
+func Get(id string) (Template, bool) {
+	for _, template := range catalog {
+		if template.ID == id {
+			return withServices(template), true
+		}
+	}
+	return Template{}, false
+}
+
+// withServices lists the credential services the template's actions require, as the
+// connector registry has them, so the catalog can't drift from the connectors
+func withServices(t Template) Template {
+	actionTypes := []string{t.ActionType}
+	for _, step := range t.ActionChain {
+		actionTypes = append(actionTypes, step.ActionType)
+	}
+	t.RequiredServices = []string{}
+	seen := map[string]bool{}
+	for _, actionType := range actionTypes {
+		for _, requirement := range connectors.CredentialRequirementsFor(actionType) {
+			if !requirement.Optional && !seen[requirement.Service] {
+				seen[requirement.Service] = true
+				t.RequiredServices = append(t.RequiredServices, requirement.Service)
+			}
+		}
+	}
+	return t
+}
+
+// Instantiate fills the template's placeholders with values, falling back to each
+// parameter's default, and returns the workflow's config and action chain as JSON
+// Unknown parameters, missing required ones and non-integer values for integer ones are errors
+func (t Template) Instantiate(values map[string]string) (configJSON, actionChainJSON string, err error) {
+	params := make(map[string]Parameter, len(t.Parameters))
+	for _, param := range t.Parameters {
+		params[param.Name] = param
+	}
+	var unknown []string
+	for name := range values {
+		if _, ok := params[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", "", fmt.Errorf("unknown parameters: %s", strings.Join(unknown, ", "))
+	}
+
+	resolved := make(map[string]interface{}, len(t.Parameters))
+	for _, param := range t.Parameters {
+		value, ok := values[param.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			value = param.Default
+		}
+		if value == "" && param.Required {
+			return "", "", fmt.Errorf("parameter %s is required: %s", param.Name, param.Description)
+		}
+		if param.Type == ParamInteger {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return "", "", fmt.Errorf("parameter %s must be a whole number, got %q", param.Name, value)
+			}
+			resolved[param.Name] = n
+			continue
+		}
+		resolved[param.Name] = value
+	}
+
+	config, err := json.Marshal(substitute(t.Config, resolved))
+	if err != nil {
+		return "", "", err
+	}
+	if len(t.ActionChain) == 0 {
+		return string(config), "", nil
+	}
+	chain := make([]models.ChainedAction, len(t.ActionChain))
+	for i, step := range t.ActionChain {
+		step.Config = substitute(step.Config, resolved).(map[string]interface{})
+		chain[i] = step
+	}
+	chainJSON, err := json.Marshal(chain)
+	if err != nil {
+		return "", "", err
+	}
+	return string(config), string(chainJSON), nil
+}
+
+// substitute copies value with every "${name}" replaced; a string that is only a
+// placeholder becomes the parameter's value itself, keeping its type
+func substitute(value interface{}, params map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = substitute(item, params)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = substitute(item, params)
+		}
+		return out
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(v); match != nil && match[0] == v {
+			if param, ok := params[match[1]]; ok {
+				return param
+			}
+		}
+		return placeholderPattern.ReplaceAllStringFunc(v, func(placeholder string) string {
+			if param, ok := params[placeholder[2:len(placeholder)-1]]; ok {
+				return fmt.Sprint(param)
+			}
+			return placeholder
+		})
+	default:
+		return value
+	}
+}
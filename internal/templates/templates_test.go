@@ -0,0 +1,103 @@
+package templates_test
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/engine/connectors"
+	"github.com/alexmacdonald/simple-ipass/internal/models"
+	"github.com/alexmacdonald/simple-ipass/internal/templates"
+)
+
+// TestCatalog instantiates every template with its examples and checks each lists exactly
+// the credentials its actions require
+func TestCatalog(t *testing.T) {
+	all := templates.All()
+	if len(all) < 6 {
+		t.Fatalf("Expected at least 6 templates, got %d", len(all))
+	}
+	seen := map[string]bool{}
+	for _, template := range all {
+		if seen[template.ID] {
+			t.Errorf("Duplicate template ID %s", template.ID)
+		}
+		seen[template.ID] = true
+
+		values := map[string]string{}
+		for _, param := range template.Parameters {
+			if param.Example != "" {
+				values[param.Name] = param.Example
+			}
+		}
+		configJSON, chainJSON, err := template.Instantiate(values)
+		if err != nil {
+			t.Errorf("%s: failed to instantiate: %v", template.ID, err)
+			continue
+		}
+		if strings.Contains(configJSON+chainJSON, "${") {
+			t.Errorf("%s: placeholders left in %s %s", template.ID, configJSON, chainJSON)
+		}
+		var config models.WorkflowConfig
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			t.Errorf("%s: config doesn't fit WorkflowConfig: %v", template.ID, err)
+		}
+
+		actionTypes := []string{template.ActionType}
+		for _, step := range template.ActionChain {
+			actionTypes = append(actionTypes, step.ActionType)
+		}
+		var required []string
+		for _, actionType := range actionTypes {
+			for _, requirement := range connectors.CredentialRequirementsFor(actionType) {
+				if !requirement.Optional {
+					required = append(required, requirement.Service)
+				}
+			}
+		}
+		listed := append([]string(nil), template.RequiredServices...)
+		sort.Strings(required)
+		sort.Strings(listed)
+		if strings.Join(required, ",") != strings.Join(listed, ",") {
+			t.Errorf("%s: expected required services %v, got %v", template.ID, required, listed)
+		}
+	}
+}
+
+// TestInstantiate fills placeholders, keeping an integer parameter's type, and refuses
+// unknown, missing and malformed parameters
+func TestInstantiate(t *testing.T) {
+	template, ok := templates.Get("news-digest-slack")
+	if !ok {
+		t.Fatal("Expected the news digest template")
+	}
+	configJSON, chainJSON, err := template.Instantiate(map[string]string{"query": "Acme Corp", "interval": "60"})
+	if err != nil {
+		t.Fatalf("Failed to instantiate: %v", err)
+	}
+	var config models.WorkflowConfig
+	json.Unmarshal([]byte(configJSON), &config)
+	if config.NewsQuery != "Acme Corp" || config.Interval != 60 || config.NewsPageSize != 5 {
+		t.Errorf("Unexpected config %s", configJSON)
+	}
+	if !strings.Contains(chainJSON, "Top story on Acme Corp: {{headlines.0.title}}") {
+		t.Errorf("Expected the query in the chained message, got %s", chainJSON)
+	}
+
+	if configJSON, _, _ = template.Instantiate(map[string]string{"query": "Acme"}); !strings.Contains(configJSON, `"interval":1440`) {
+		t.Errorf("Expected the default interval, got %s", configJSON)
+	}
+	for _, c := range []struct {
+		values map[string]string
+		reason string
+	}{
+		{map[string]string{}, "query is required"},
+		{map[string]string{"query": "Acme", "interval": "daily"}, "whole number"},
+		{map[string]string{"query": "Acme", "channel": "#news"}, "unknown parameters: channel"},
+	} {
+		if _, _, err := template.Instantiate(c.values); err == nil || !strings.Contains(err.Error(), c.reason) {
+			t.Errorf("%v: expected %q, got %v", c.values, c.reason, err)
+		}
+	}
+}
@@ -88,13 +88,67 @@ func DecodeJSONStrict(w http.ResponseWriter, r *http.Request, dst interface{}) e
 	return nil
 }
 
-// WriteJSONError writes a JSON error response
+// Error codes in API error responses, so clients can tell errors apart without
+// matching messages. Connector failures use connectors.ErrCode* instead
+const (
+	CodeBadRequest         = "bad_request"          // Generic 400
+	CodeInvalidBody        = "invalid_body"         // The request body isn't valid JSON for the endpoint
+	CodeValidationFailed   = "validation_failed"    // A field is missing or has a value the endpoint refuses
+	CodeUnauthorized       = "unauthorized"         // No valid token
+	CodeInvalidCredentials = "invalid_credentials"  // Wrong email or password
+	CodeForbidden          = "forbidden"            // Authenticated, but not allowed
+	CodeNotFound           = "not_found"            // Generic 404
+	CodeWorkflowNotFound   = "workflow_not_found"   // No such workflow
+	CodeCredentialNotFound = "credential_not_found" // No such credential
+	CodeCredentialMissing  = "credential_missing"   // A service the request needs isn't connected
+	CodeConflict           = "conflict"             // Generic 409
+	CodeUserExists         = "user_exists"          // The email is already registered
+	CodeInviteClosed       = "invite_closed"        // The invite was accepted, revoked or has expired
+	CodeUnprocessable      = "unprocessable"        // Generic 422
+	CodeRateLimited        = "rate_limited"         // Too many requests; see Retry-After
+	CodeUnavailable        = "unavailable"          // The server is busy or read-only; retry later
+	CodeInternal           = "internal_error"       // Generic 5xx
+)
+
+// ErrorCodeForStatus is the generic error code for an HTTP status
+func ErrorCodeForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if statusCode >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// WriteJSONError writes a JSON error response with the generic code for its status
 func WriteJSONError(w http.ResponseWriter, message string, statusCode int) {
+	WriteJSONErrorCode(w, ErrorCodeForStatus(statusCode), message, statusCode)
+}
+
+// WriteJSONErrorCode writes a JSON error response with a specific error code
+func WriteJSONErrorCode(w http.ResponseWriter, code, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": false,
 		"error":   message,
+		"code":    code,
 	})
 }
 
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/tidwall/gjson"
@@ -21,22 +24,224 @@ func NewTemplateEngine() *TemplateEngine {
 	}
 }
 
+// TemplateRef is one {{path}} reference in a template
+// Offset and Length are in bytes and cover the braces, for editor highlighting
+type TemplateRef struct {
+	Path   string `json:"path"` // gjson path and any filters, trimmed of surrounding spaces
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// Parse finds the references Render substitutes, in order
+// Render and the linter both use it, so lint findings always match what runs
+func (te *TemplateEngine) Parse(template string) []TemplateRef {
+	var refs []TemplateRef
+	for _, loc := range te.templatePattern.FindAllStringSubmatchIndex(template, -1) {
+		refs = append(refs, TemplateRef{
+			Path:   strings.TrimSpace(template[loc[2]:loc[3]]),
+			Offset: loc[0],
+			Length: loc[1] - loc[0],
+		})
+	}
+	return refs
+}
+
 // Render replaces template variables with actual values from JSON data
+// Missing paths render empty; so does a reference RenderStrict would reject
 func (te *TemplateEngine) Render(template string, data string) string {
-	return te.templatePattern.ReplaceAllStringFunc(template, func(match string) string {
-		// Extract the path from {{path}}
-		path := strings.TrimSpace(match[2 : len(match)-2])
-		
-		// Use gjson to extract value from JSON
-		result := gjson.Get(data, path)
-		
-		if !result.Exists() {
-			// Path not found, keep original
-			return match
+	rendered, _ := te.render(template, data, false)
+	return rendered
+}
+
+// RenderStrict renders like Render but fails on the first reference with an unknown
+// filter or a bad filter argument, so a workflow step never sends half a message
+func (te *TemplateEngine) RenderStrict(template string, data string) (string, error) {
+	return te.render(template, data, true)
+}
+
+func (te *TemplateEngine) render(template, data string, strict bool) (string, error) {
+	var rendered strings.Builder
+	last := 0
+	for _, ref := range te.Parse(template) {
+		rendered.WriteString(template[last:ref.Offset])
+		last = ref.Offset + ref.Length
+
+		value, err := renderRef(ref.Path, data)
+		if err != nil {
+			if strict {
+				return "", fmt.Errorf("{{%s}}: %w", ref.Path, err)
+			}
+			continue
+		}
+		rendered.WriteString(value)
+	}
+	rendered.WriteString(template[last:])
+	return rendered.String(), nil
+}
+
+// renderRef evaluates one reference: its gjson path, then its filters left to right
+func renderRef(ref, data string) (string, error) {
+	expr, err := parseExpr(ref)
+	if err != nil {
+		return "", err
+	}
+
+	// Use gjson to extract value from JSON
+	result := gjson.Get(data, expr.path)
+	value, missing := result.String(), !result.Exists()
+	for _, filter := range expr.filters {
+		if value, err = filter.apply(value, missing); err != nil {
+			return "", err
+		}
+		missing = missing && value == ""
+	}
+	return value, nil
+}
+
+// ErrUnknownFilter is wrapped by errors for filters the engine doesn't know
+var ErrUnknownFilter = errors.New("unknown filter")
+
+// templateExpr is a reference split into its gjson path and the filters piped after it
+// gjson modifiers (|@reverse) are part of the path; the first plain name starts the filters
+type templateExpr struct {
+	path    string
+	filters []templateFilter
+}
+
+// hasDefault reports whether a default filter supplies a value when the path is missing
+func (e templateExpr) hasDefault() bool {
+	for _, filter := range e.filters {
+		if filter.name == "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFilter is one filter stage, like truncate:20 or default:"friend"
+type templateFilter struct {
+	name string
+	arg  string
+	n    int // Parsed argument of truncate and number_format
+}
+
+// parseExpr splits a reference into its path and filters, checking every filter up front
+// so an unknown one fails whether or not the payload has the field
+func parseExpr(ref string) (templateExpr, error) {
+	stages := splitPipes(ref)
+	split := len(stages)
+	for i := 1; i < len(stages); i++ {
+		if stage := strings.TrimSpace(stages[i]); stage != "" && isLetter(stage[0]) {
+			split = i
+			break
+		}
+	}
+
+	path := make([]string, split)
+	for i, stage := range stages[:split] {
+		path[i] = strings.TrimSpace(stage)
+		if name, ok := filterName(path[i]); ok && !gjson.ModifierExists(name, nil) {
+			return templateExpr{}, fmt.Errorf("%w %q", ErrUnknownFilter, "@"+name)
+		}
+	}
+	expr := templateExpr{path: strings.Join(path, "|")}
+	for _, stage := range stages[split:] {
+		filter, err := parseFilter(strings.TrimSpace(stage))
+		if err != nil {
+			return templateExpr{}, err
+		}
+		expr.filters = append(expr.filters, filter)
+	}
+	return expr, nil
+}
+
+// parseFilter reads one name[:argument] filter stage
+func parseFilter(stage string) (templateFilter, error) {
+	filter := templateFilter{name: stage}
+	if i := strings.Index(stage, ":"); i >= 0 {
+		filter.name, filter.arg = strings.TrimSpace(stage[:i]), strings.TrimSpace(stage[i+1:])
+	}
+
+	switch filter.name {
+	case "upper", "lower", "trim":
+		if filter.arg != "" {
+			return filter, fmt.Errorf("%s takes no argument", filter.name)
+		}
+	case "default":
+		if strings.HasPrefix(filter.arg, `"`) {
+			unquoted, err := strconv.Unquote(filter.arg)
+			if err != nil {
+				return filter, fmt.Errorf("default needs a quoted string, got %s", filter.arg)
+			}
+			filter.arg = unquoted
+		}
+	case "truncate", "number_format":
+		n, err := strconv.Atoi(filter.arg)
+		if err != nil || n < 0 || (filter.name == "number_format" && n > 10) {
+			return filter, fmt.Errorf("%s needs a whole number of characters or decimals, got %q", filter.name, filter.arg)
+		}
+		filter.n = n
+	default:
+		return filter, fmt.Errorf("%w %q", ErrUnknownFilter, filter.name)
+	}
+	return filter, nil
+}
+
+// apply runs the filter on a value; missing is true while no path or default has supplied one
+func (f templateFilter) apply(value string, missing bool) (string, error) {
+	switch f.name {
+	case "default":
+		if missing || value == "" {
+			return f.arg, nil
+		}
+	case "upper":
+		return strings.ToUpper(value), nil
+	case "lower":
+		return strings.ToLower(value), nil
+	case "trim":
+		return strings.TrimSpace(value), nil
+	case "truncate":
+		if runes := []rune(value); len(runes) > f.n {
+			return string(runes[:f.n]), nil
 		}
-		
-		return result.String()
-	})
+	case "number_format":
+		if value == "" {
+			return value, nil
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "", fmt.Errorf("number_format needs a number, got %q", value)
+		}
+		return formatNumber(number, f.n), nil
+	}
+	return value, nil
+}
+
+// formatNumber rounds to the given decimals and groups thousands with commas (1234.5 -> "1,234.50")
+func formatNumber(number float64, decimals int) string {
+	formatted := strconv.FormatFloat(number, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	whole, fraction := formatted, ""
+	if i := strings.Index(formatted, "."); i >= 0 {
+		whole, fraction = formatted[:i], formatted[i:]
+	}
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + fraction
+}
+
+// isLetter reports whether a byte is an ASCII letter
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
 }
 
 // RenderMap processes an entire config map with templates
@@ -72,15 +277,9 @@ func ExtractValue(data string, path string) string {
 // ValidateTemplate checks if a template string is valid
 func (te *TemplateEngine) ValidateTemplate(template string) []string {
 	var paths []string
-	
-	matches := te.templatePattern.FindAllStringSubmatch(template, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			path := strings.TrimSpace(match[1])
-			paths = append(paths, path)
-		}
+	for _, ref := range te.Parse(template) {
+		paths = append(paths, ref.Path)
 	}
-	
 	return paths
 }
 
@@ -89,4 +288,6 @@ func (te *TemplateEngine) ValidateTemplate(template string) []string {
 // data := `{"user": {"name": "Alex", "email": "alex@example.com"}}`
 // result := engine.Render(template, data)
 // Output: "Hello Alex, your email is alex@example.com"
-
+//
+// Filters are piped after the path and run left to right:
+// "Hi {{user.nickname | default:\"friend\" | upper}}" renders "Hi FRIEND" when nickname is missing
@@ -0,0 +1,139 @@
+package utils_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+const renderPayload = `{
+	"user": {"name": "Ada Lovelace", "nickname": "", "email": "ADA@Example.com", "bio": "  Poet of science  ", "address": {"city": "London", "geo": {"lat": 51.5}}},
+	"items": [{"title": "Notes on the Engine", "price": 1234.5}, {"title": "Sketch", "price": -0.126}],
+	"order": {"total": 1234567.891, "count": 3, "code": "12"},
+	"tags": ["math", "poetry"],
+	"active": true,
+	"missing_value": null
+}`
+
+func TestRenderPaths(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	tests := []struct {
+		name, template, want string
+	}{
+		{"top-level field", "{{active}}", "true"},
+		{"nested field", "Hi {{user.name}}", "Hi Ada Lovelace"},
+		{"deeply nested field", "{{user.address.geo.lat}}", "51.5"},
+		{"array element", "{{items.0.title}}", "Notes on the Engine"},
+		{"second array element", "{{items.1.title}}", "Sketch"},
+		{"scalar array element", "{{tags.1}}", "poetry"},
+		{"array length", "{{items.#}}", "2"},
+		{"field across elements", "{{items.#.title}}", `["Notes on the Engine","Sketch"]`},
+		{"gjson modifier", "{{tags|@reverse}}", `["poetry","math"]`},
+		{"spaces inside braces", "{{ user.name }}", "Ada Lovelace"},
+		{"missing field", "Hi {{user.title}}!", "Hi !"},
+		{"missing nested field", "{{user.address.zip.code}}", ""},
+		{"out of range element", "{{items.5.title}}", ""},
+		{"null renders empty", "[{{missing_value}}]", "[]"},
+		{"several references", "{{user.address.city}}: {{order.count}} items", "London: 3 items"},
+		{"no references", "plain text", "plain text"},
+		{"unclosed reference", "Hi {{user.name", "Hi {{user.name"},
+	}
+	for _, tt := range tests {
+		got, err := te.RenderStrict(tt.template, renderPayload)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRenderFilters(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	tests := []struct {
+		name, template, want string
+	}{
+		{"default on a missing field", `Hi {{user.title | default:"friend"}}`, "Hi friend"},
+		{"default on an empty field", `{{user.nickname | default:"friend"}}`, "friend"},
+		{"default on null", `{{missing_value | default:"n/a"}}`, "n/a"},
+		{"default ignored when present", `{{user.address.city | default:"Paris"}}`, "London"},
+		{"unquoted default", "{{user.title | default:friend}}", "friend"},
+		{"quoted default with a pipe", `{{user.title | default:"a|b"}}`, "a|b"},
+		{"default with escapes", `{{user.title | default:"say \"hi\""}}`, `say "hi"`},
+		{"upper", "{{user.name | upper}}", "ADA LOVELACE"},
+		{"lower", "{{user.email | lower}}", "ada@example.com"},
+		{"trim", "[{{user.bio | trim}}]", "[Poet of science]"},
+		{"truncate", "{{items.0.title | truncate:5}}", "Notes"},
+		{"truncate longer than the value", "{{items.1.title | truncate:50}}", "Sketch"},
+		{"truncate counts characters, not bytes", "{{name | truncate:3}}", "Zoë"},
+		{"truncate to nothing", "[{{user.name | truncate:0}}]", "[]"},
+		{"number_format", "{{order.total | number_format:2}}", "1,234,567.89"},
+		{"number_format without decimals", "{{order.total | number_format:0}}", "1,234,568"},
+		{"number_format on an array element", "{{items.0.price | number_format:2}}", "1,234.50"},
+		{"number_format on a negative number", "{{items.1.price | number_format:2}}", "-0.13"},
+		{"number_format on a numeric string", "{{order.code | number_format:1}}", "12.0"},
+		{"number_format on a missing field", "[{{order.tax | number_format:2}}]", "[]"},
+		{"no spaces around pipes", "{{user.name|upper}}", "ADA LOVELACE"},
+		{"chained filters", "{{user.bio | trim | upper | truncate:4}}", "POET"},
+		{"chain order matters", "{{user.bio | truncate:4 | trim | upper}}", "PO"},
+		{"default then filters", `{{user.title | default:"guest" | upper}}`, "GUEST"},
+		{"filters then default", `{{user.title | upper | default:"guest"}}`, "guest"},
+		{"default then number_format", `{{order.tax | default:"0" | number_format:2}}`, "0.00"},
+		{"gjson modifier then filter", "{{tags|@reverse|@this | upper}}", `["POETRY","MATH"]`},
+		{"filter on an array element in a sentence", "Top pick: {{items.0.title | lower | truncate:8}}!", "Top pick: notes on!"},
+	}
+	payload := strings.Replace(renderPayload, `"active": true`, `"active": true, "name": "Zoë Smith"`, 1)
+	for _, tt := range tests {
+		got, err := te.RenderStrict(tt.template, payload)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRenderFilterErrors(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	tests := []struct {
+		name, template string
+		unknown        bool
+		wantMessage    string
+	}{
+		{"unknown filter", "Hi {{user.name | shout}}", true, `{{user.name | shout}}: unknown filter "shout"`},
+		{"unknown filter on a missing field", "{{user.title | shout}}", true, `unknown filter "shout"`},
+		{"unknown filter after known ones", "{{user.name | upper | reverse}}", true, `unknown filter "reverse"`},
+		{"unknown gjson modifier", "{{user.name|@upper}}", true, `unknown filter "@upper"`},
+		{"modifier after a filter", "{{tags | upper | @reverse}}", true, `unknown filter "@reverse"`},
+		{"truncate without a length", "{{user.name | truncate}}", false, "truncate needs a whole number"},
+		{"negative truncate", "{{user.name | truncate:-1}}", false, "truncate needs a whole number"},
+		{"number_format with text", "{{order.total | number_format:two}}", false, "number_format needs a whole number"},
+		{"number_format on text", "{{user.name | number_format:2}}", false, `number_format needs a number, got "Ada Lovelace"`},
+		{"argument to upper", "{{user.name | upper:1}}", false, "upper takes no argument"},
+		{"unterminated default", `{{user.title | default:"friend}}`, false, "default needs a quoted string"},
+	}
+	for _, tt := range tests {
+		_, err := te.RenderStrict(tt.template, renderPayload)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if errors.Is(err, utils.ErrUnknownFilter) != tt.unknown {
+			t.Errorf("%s: expected unknown filter %v, got %v", tt.name, tt.unknown, err)
+		}
+		if !strings.Contains(err.Error(), tt.wantMessage) {
+			t.Errorf("%s: expected %q in the error, got %q", tt.name, tt.wantMessage, err)
+		}
+	}
+
+	// Render can't fail; the bad reference renders empty and the rest still renders
+	if got := te.Render("{{user.name | shout}} {{user.address.city}}", renderPayload); got != " London" {
+		t.Errorf("Expected the bad reference rendered empty, got %q", got)
+	}
+}
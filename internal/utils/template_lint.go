@@ -0,0 +1,434 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Lint finding kinds
+// Filters are gjson modifiers piped onto a path, like {{items|@reverse}}, and the
+// engine's own filters after them, like {{name | default:"friend" | upper}}
+const (
+	LintMalformed     = "malformed"      // A reference Render leaves as literal text, or a filter with a bad argument
+	LintUndefined     = "undefined"      // The path doesn't exist in the payload or schema
+	LintTypeMismatch  = "type_mismatch"  // A filter or count applied to a value of the wrong type
+	LintUnknownFilter = "unknown_filter" // A filter the engine doesn't know; the workflow step fails
+	LintUnusedField   = "unused_field"   // A top-level payload field no reference uses
+)
+
+// Lint severities
+const (
+	SeverityError = "error" // The reference renders wrong at runtime
+	SeverityInfo  = "info"
+)
+
+// LintFinding is one problem in a template
+// Offset and Length locate it in the template in bytes; Length is 0 for findings
+// about the payload rather than the template (unused fields)
+type LintFinding struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path,omitempty"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+// LintOptions is what references are checked against; both are optional
+// A sample payload wins over a schema, since it is exactly what Render would see
+type LintOptions struct {
+	Payload string          // Sample trigger payload (JSON)
+	Schema  json.RawMessage // JSON Schema of the payload (type, properties, items, additionalProperties)
+}
+
+// filterInputs is the value type each gjson modifier needs; "" means any
+// Modifiers given something else return nothing or an empty value
+var filterInputs = map[string]string{
+	"pretty":  "",
+	"ugly":    "",
+	"reverse": "",
+	"this":    "",
+	"valid":   "",
+	"tostr":   "",
+	"dig":     "",
+	"flatten": "array",
+	"join":    "array",
+	"keys":    "object",
+	"values":  "object",
+	"group":   "object",
+	"fromstr": "string",
+}
+
+// Lint reports problems in a template against a sample payload or schema
+// Returns an error only when the payload or schema itself isn't valid JSON
+func (te *TemplateEngine) Lint(template string, opts LintOptions) ([]LintFinding, error) {
+	if opts.Payload != "" && !gjson.Valid(opts.Payload) {
+		return nil, fmt.Errorf("payload is not valid JSON")
+	}
+	var schema *lintSchema
+	if len(opts.Schema) > 0 && opts.Payload == "" {
+		schema = &lintSchema{}
+		if err := json.Unmarshal(opts.Schema, schema); err != nil {
+			return nil, fmt.Errorf("schema is not valid JSON Schema: %v", err)
+		}
+	}
+
+	refs := te.Parse(template)
+	findings := malformedFindings(template, refs)
+	for _, ref := range refs {
+		findings = append(findings, lintRef(ref, opts.Payload, schema)...)
+	}
+	findings = append(findings, unusedFields(refs, opts.Payload, schema)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Length == 0 || findings[j].Length == 0 {
+			return findings[i].Length != 0 && findings[j].Length == 0
+		}
+		return findings[i].Offset < findings[j].Offset
+	})
+	return findings, nil
+}
+
+// malformedFindings reports braces Parse didn't turn into references
+func malformedFindings(template string, refs []TemplateRef) []LintFinding {
+	var findings []LintFinding
+	covered := func(offset int) bool {
+		for _, ref := range refs {
+			if offset >= ref.Offset && offset < ref.Offset+ref.Length {
+				return true
+			}
+		}
+		return false
+	}
+
+	for offset := 0; offset < len(template)-1; offset++ {
+		if template[offset] != '{' || template[offset+1] != '{' || covered(offset) {
+			continue
+		}
+		if strings.HasPrefix(template[offset:], "{{}}") {
+			findings = append(findings, LintFinding{Kind: LintMalformed, Severity: SeverityError,
+				Message: "empty reference is rendered as literal text", Offset: offset, Length: 4})
+			offset += 3
+			continue
+		}
+		findings = append(findings, LintFinding{Kind: LintMalformed, Severity: SeverityError,
+			Message: "unclosed reference is rendered as literal text", Offset: offset, Length: 2})
+		offset++
+	}
+
+	for _, ref := range refs {
+		if ref.Path == "" {
+			findings = append(findings, LintFinding{Kind: LintMalformed, Severity: SeverityError,
+				Message: "empty reference is rendered as literal text", Offset: ref.Offset, Length: ref.Length})
+		}
+	}
+	return findings
+}
+
+// lintRef checks one reference's filters and, given a payload or schema, its path
+func lintRef(ref TemplateRef, payload string, schema *lintSchema) []LintFinding {
+	if ref.Path == "" {
+		return nil
+	}
+	finding := func(kind, message string) LintFinding {
+		return LintFinding{Kind: kind, Severity: SeverityError, Message: message, Path: ref.Path, Offset: ref.Offset, Length: ref.Length}
+	}
+
+	expr, err := parseExpr(ref.Path)
+	if errors.Is(err, ErrUnknownFilter) {
+		return []LintFinding{finding(LintUnknownFilter, err.Error()+": the workflow step fails")}
+	}
+	if err != nil {
+		return []LintFinding{finding(LintMalformed, err.Error()+": the workflow step fails")}
+	}
+
+	stages := splitPipes(expr.path)
+	switch {
+	case payload != "":
+		return lintAgainstPayload(ref, expr, stages, payload, finding)
+	case schema != nil:
+		findings := lintAgainstSchema(stages, schema, finding)
+		if len(findings) == 1 && findings[0].Kind == LintUndefined && expr.hasDefault() {
+			return nil // The default stands in for the missing field
+		}
+		return findings
+	}
+	return nil
+}
+
+// lintAgainstPayload evaluates each stage of a reference exactly as Render would
+func lintAgainstPayload(ref TemplateRef, expr templateExpr, stages []string, payload string, finding func(kind, message string) LintFinding) []LintFinding {
+	for i, stage := range stages {
+		input := gjson.Result{Type: gjson.JSON, Raw: payload}
+		if i > 0 {
+			input = gjson.Get(payload, strings.Join(stages[:i], "|"))
+		}
+
+		if name, ok := filterName(stage); ok {
+			if want := filterInputs[name]; want != "" && input.Exists() && resultType(input) != want {
+				return []LintFinding{finding(LintTypeMismatch, fmt.Sprintf("@%s needs %s, got %s", name, article(want), article(resultType(input))))}
+			}
+			continue
+		}
+		if i == 0 {
+			if prefix, ok := countPrefix(stage); ok {
+				if counted := gjson.Get(payload, prefix); counted.Exists() && !counted.IsArray() {
+					return []LintFinding{finding(LintTypeMismatch, fmt.Sprintf("# counts array elements, but %s is %s", prefix, article(resultType(counted))))}
+				}
+			}
+		}
+	}
+
+	if !gjson.Get(payload, expr.path).Exists() && !expr.hasDefault() {
+		return []LintFinding{finding(LintUndefined, fmt.Sprintf("%s is not in the payload; the reference renders empty", expr.path))}
+	}
+	if _, err := renderRef(ref.Path, payload); err != nil {
+		return []LintFinding{finding(LintTypeMismatch, err.Error())}
+	}
+	return nil
+}
+
+// lintAgainstSchema resolves a reference's path through a schema
+// Paths the schema can't answer for (wildcards, queries) are given the benefit of the doubt
+func lintAgainstSchema(stages []string, schema *lintSchema, finding func(kind, message string) LintFinding) []LintFinding {
+	node, ok, missing := schema.resolve(stages[0])
+	if missing != "" {
+		return []LintFinding{finding(LintUndefined, fmt.Sprintf("%s is not in the schema", missing))}
+	}
+	if prefix, isCount := countPrefix(stages[0]); isCount && ok {
+		if t := node.jsonType(); t != "" && t != "array" {
+			return []LintFinding{finding(LintTypeMismatch, fmt.Sprintf("# counts array elements, but %s is %s", prefix, article(t)))}
+		}
+		node = &lintSchema{Type: "number"}
+	}
+	if !ok || len(stages) < 2 {
+		return nil
+	}
+	// Only the first filter's input type is known from the schema
+	if name, isFilter := filterName(stages[1]); isFilter {
+		if want := filterInputs[name]; want != "" && node.jsonType() != "" && node.jsonType() != want {
+			return []LintFinding{finding(LintTypeMismatch, fmt.Sprintf("@%s needs %s, got %s", name, article(want), article(node.jsonType())))}
+		}
+	}
+	return nil
+}
+
+// unusedFields lists top-level payload fields no reference reads
+func unusedFields(refs []TemplateRef, payload string, schema *lintSchema) []LintFinding {
+	var fields []string
+	switch {
+	case payload != "":
+		parsed := gjson.Parse(payload)
+		if !parsed.IsObject() {
+			return nil
+		}
+		parsed.ForEach(func(key, _ gjson.Result) bool {
+			fields = append(fields, key.String())
+			return true
+		})
+	case schema != nil:
+		for field := range schema.Properties {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	default:
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, ref := range refs {
+		first := firstSegment(splitPipes(ref.Path)[0])
+		if first == "" || strings.ContainsAny(first, "*?@#") {
+			return nil // Root references, filters on the root and wildcards may read any field
+		}
+		used[first] = true
+	}
+
+	var findings []LintFinding
+	for _, field := range fields {
+		if !used[field] {
+			findings = append(findings, LintFinding{Kind: LintUnusedField, Severity: SeverityInfo,
+				Message: fmt.Sprintf("payload field %s is not used", field), Path: field})
+		}
+	}
+	return findings
+}
+
+// splitPipes splits a gjson path on the pipes between its stages
+// Pipes inside queries, brackets or quotes belong to the stage
+func splitPipes(path string) []string {
+	var stages []string
+	depth, start := 0, 0
+	quoted := false
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case (c == ')' || c == ']' || c == '}') && depth > 0:
+			depth--
+		case c == '|' && depth == 0:
+			stages = append(stages, path[start:i])
+			start = i + 1
+		}
+	}
+	return append(stages, path[start:])
+}
+
+// filterName returns the modifier a stage applies, like "reverse" for "@reverse"
+func filterName(stage string) (string, bool) {
+	if !strings.HasPrefix(stage, "@") {
+		return "", false
+	}
+	name := stage[1:]
+	if i := strings.IndexAny(name, ":.|"); i >= 0 {
+		name = name[:i]
+	}
+	return name, true
+}
+
+// countPrefix returns the path whose elements a trailing # counts
+func countPrefix(stage string) (string, bool) {
+	if !strings.HasSuffix(stage, ".#") || strings.HasSuffix(stage, "\\.#") {
+		return "", false
+	}
+	return strings.TrimSuffix(stage, ".#"), true
+}
+
+// firstSegment returns the top-level key a path starts with
+func firstSegment(path string) string {
+	segments := splitPath(path)
+	if len(segments) == 0 {
+		return ""
+	}
+	return segments[0]
+}
+
+// splitPath splits a gjson path on unescaped dots, unescaping them
+func splitPath(path string) []string {
+	var segments []string
+	var current strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			current.WriteByte(path[i])
+		case path[i] == '.':
+			segments = append(segments, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	return append(segments, current.String())
+}
+
+// resultType names a gjson value's JSON type
+func resultType(r gjson.Result) string {
+	switch {
+	case r.IsArray():
+		return "array"
+	case r.IsObject():
+		return "object"
+	case r.Type == gjson.String:
+		return "string"
+	case r.Type == gjson.Number:
+		return "number"
+	case r.Type == gjson.True || r.Type == gjson.False:
+		return "boolean"
+	}
+	return "null"
+}
+
+// article prefixes a type name for messages ("an array", "a string")
+func article(typeName string) string {
+	if typeName == "" {
+		return "a value"
+	}
+	if strings.IndexAny(typeName[:1], "aeiou") == 0 {
+		return "an " + typeName
+	}
+	return "a " + typeName
+}
+
+// lintSchema is the part of JSON Schema the linter understands
+type lintSchema struct {
+	Type                 interface{}            `json:"type"` // A name or a list of names
+	Properties           map[string]*lintSchema `json:"properties"`
+	Items                *lintSchema            `json:"items"`
+	AdditionalProperties interface{}            `json:"additionalProperties"`
+}
+
+// jsonType is the schema's single type ("" when absent or a union); integer counts as number
+func (s *lintSchema) jsonType() string {
+	name, _ := s.Type.(string)
+	if name == "integer" {
+		return "number"
+	}
+	return name
+}
+
+// resolve walks a path through the schema
+// ok is false when the schema can't tell (wildcards, queries, untyped nodes);
+// missing names the first segment the schema rules out
+func (s *lintSchema) resolve(path string) (node *lintSchema, ok bool, missing string) {
+	node = s
+	segments := splitPath(path)
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "*?@!()=<>%") || segment == "" {
+			return node, false, ""
+		}
+		if segment == "#" && i == len(segments)-1 {
+			return node, true, ""
+		}
+		if node.Items != nil || node.jsonType() == "array" {
+			if _, err := strconv.Atoi(segment); err == nil {
+				if node.Items == nil {
+					return node, false, ""
+				}
+				node = node.Items
+				continue
+			}
+			if segment == "#" {
+				return node, false, "" // A query over the elements
+			}
+		}
+		child, found := node.Properties[segment]
+		switch {
+		case found && child != nil:
+			node = child
+		case found:
+			return node, false, ""
+		case node.closed():
+			return node, false, strings.Join(segments[:i+1], ".")
+		default:
+			return node, false, ""
+		}
+	}
+	return node, true, ""
+}
+
+// closed reports whether the schema rules out properties it doesn't list
+// Objects listing their properties are taken as complete unless they allow extra ones
+func (s *lintSchema) closed() bool {
+	if allowed, isBool := s.AdditionalProperties.(bool); isBool && allowed {
+		return false
+	}
+	if _, isSchema := s.AdditionalProperties.(map[string]interface{}); isSchema {
+		return false
+	}
+	if t := s.jsonType(); t != "" && t != "object" {
+		return true // Scalars and arrays have no named properties
+	}
+	return s.Properties != nil
+}
@@ -0,0 +1,248 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/utils"
+)
+
+const lintPayload = `{"user": {"name": "Ada", "tags": ["a", "b"], "age": 36}, "order": {"id": "A-1", "items": [{"sku": "x"}]}, "note": "{\"k\":1}", "debug": true}`
+
+const lintSchema = `{
+	"type": "object",
+	"properties": {
+		"user": {"type": "object", "properties": {"name": {"type": "string"}, "tags": {"type": "array", "items": {"type": "string"}}, "age": {"type": "integer"}}},
+		"order": {"type": "object", "properties": {"id": {"type": "string"}, "items": {"type": "array", "items": {"type": "object", "properties": {"sku": {"type": "string"}}}}}},
+		"extra": {"type": "object", "additionalProperties": true},
+		"debug": {"type": "boolean"}
+	}
+}`
+
+// kinds summarizes findings as "kind@offset" for comparison
+func kinds(findings []utils.LintFinding) []string {
+	var out []string
+	for _, f := range findings {
+		if f.Length == 0 {
+			out = append(out, f.Kind+":"+f.Path)
+			continue
+		}
+		out = append(out, f.Kind+"@"+itoa(f.Offset))
+	}
+	return out
+}
+
+func itoa(n int) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
+
+func TestLintAgainstPayload(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{"all defined", "{{user.name}} {{order.id}} {{note}} {{debug}}", nil},
+		{"undefined field", "Hi {{user.nickname}}", []string{"undefined@3"}},
+		{"count on an array", "{{user.tags.#}} {{order.id}} {{note}} {{debug}}", nil},
+		{"count on a string", "{{user.name.#}}", []string{"type_mismatch@0"}},
+		{"filter on the wrong type", "{{user.name|@keys}}", []string{"type_mismatch@0"}},
+		{"filter on the right type", "{{user|@keys}} {{order.items|@flatten}} {{note|@fromstr}} {{debug}}", nil},
+		{"numeric value through a string filter", "{{user.age|@fromstr}}", []string{"type_mismatch@0"}},
+		{"unknown filter", "{{user.name|@upper}}", []string{"unknown_filter@0"}},
+		{"unknown named filter", "{{user.name | shout}}", []string{"unknown_filter@0"}},
+		{"bad filter argument", "{{user.name | truncate:many}}", []string{"malformed@0"}},
+		{"default covers a missing field", `{{user.nickname | default:"friend"}}`, nil},
+		{"number_format on text", "{{user.name | number_format:2}}", []string{"type_mismatch@0"}},
+		{"filters on defined fields", "{{user.name | upper}} {{user.age | number_format:1}} {{order.id}} {{note}} {{debug}}", nil},
+		{"spaces inside braces", "{{ user.name }}", []string{"unused_field:order", "unused_field:note", "unused_field:debug"}},
+		{"escaped dot", `{{user\.name}}`, []string{"undefined@0", "unused_field:user", "unused_field:order", "unused_field:note", "unused_field:debug"}},
+	}
+	for _, tt := range tests {
+		findings, err := te.Lint(tt.template, utils.LintOptions{Payload: lintPayload})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		got := kinds(findings)
+		// Unused-field findings only matter where a test lists them
+		if !strings.Contains(strings.Join(tt.want, ","), "unused_field") {
+			got = withoutUnused(got)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func withoutUnused(found []string) []string {
+	var out []string
+	for _, f := range found {
+		if !strings.HasPrefix(f, "unused_field") {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// TestLintMatchesRender checks every reference reported undefined is exactly one Render leaves empty
+func TestLintMatchesRender(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	template := "{{user.name}} {{user.nickname}} {{order.items.0.sku}} {{order.items.5.sku}} {{user.tags|@reverse}} {{user.title | default:\"Dr\"}}"
+	findings, _ := te.Lint(template, utils.LintOptions{Payload: lintPayload})
+
+	undefined := 0
+	for _, f := range findings {
+		if f.Kind != utils.LintUndefined {
+			continue
+		}
+		undefined++
+		if rendered := te.Render(template[f.Offset:f.Offset+f.Length], lintPayload); rendered != "" {
+			t.Errorf("Lint reported %q undefined but Render substituted %q", f.Path, rendered)
+		}
+	}
+	if rendered := te.Render(template, lintPayload); undefined != 2 || rendered != `Ada  x  ["b","a"] Dr` {
+		t.Errorf("Expected two undefined references rendered empty, got %d findings and %q", undefined, rendered)
+	}
+}
+
+func TestLintAgainstSchema(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	tests := []struct {
+		name     string
+		template string
+		want     []string
+	}{
+		{"defined paths", "{{user.name}} {{order.items.0.sku}} {{extra.anything}} {{debug}}", nil},
+		{"undefined property", "{{user.email}}", []string{"undefined@0"}},
+		{"undefined nested property", "{{order.items.0.price}}", []string{"undefined@0"}},
+		{"property of a string", "{{user.name.first}}", []string{"undefined@0"}},
+		{"count on a string", "{{order.id.#}}", []string{"type_mismatch@0"}},
+		{"count on an array", "{{user.tags.#}}", nil},
+		{"filter on the wrong type", "{{user.tags|@keys}}", []string{"type_mismatch@0"}},
+		{"numeric field through a string filter", "{{user.age|@fromstr}}", []string{"type_mismatch@0"}},
+		{"wildcards are not second-guessed", "{{user.na*}} {{order.items.#.sku}}", nil},
+		{"default covers a missing property", `{{user.email | default:"none"}}`, nil},
+	}
+	for _, tt := range tests {
+		findings, err := te.Lint(tt.template, utils.LintOptions{Schema: json.RawMessage(lintSchema)})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if got := withoutUnused(kinds(findings)); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestLintUnusedFields(t *testing.T) {
+	te := utils.NewTemplateEngine()
+
+	findings, _ := te.Lint("{{user.name}}", utils.LintOptions{Payload: lintPayload})
+	if got := strings.Join(kinds(findings), ","); got != "unused_field:order,unused_field:note,unused_field:debug" {
+		t.Errorf("Expected the unused top-level fields in payload order, got %s", got)
+	}
+	for _, f := range findings {
+		if f.Severity != utils.SeverityInfo {
+			t.Errorf("Expected unused fields to be informational, got %+v", f)
+		}
+	}
+
+	findings, _ = te.Lint("{{user.name}} {{debug}}", utils.LintOptions{Schema: json.RawMessage(lintSchema)})
+	if got := strings.Join(kinds(findings), ","); got != "unused_field:extra,unused_field:order" {
+		t.Errorf("Expected the unused schema properties, got %s", got)
+	}
+
+	// A reference to the whole payload may read anything
+	if findings, _ := te.Lint("{{@this}}", utils.LintOptions{Payload: lintPayload}); len(findings) != 0 {
+		t.Errorf("Expected no unused fields when the whole payload is referenced, got %v", kinds(findings))
+	}
+}
+
+func TestLintMalformed(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		{"Hello {{name", []string{"malformed@6"}},
+		{"{{}} and {{ }}", []string{"malformed@0", "malformed@9"}},
+		{"{{a}} {{b", []string{"malformed@6"}},
+		{"{{a}} {{", []string{"malformed@6"}},
+		{"{{a}}}}", nil},
+		{"} }} {", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		findings, err := te.Lint(tt.template, utils.LintOptions{})
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", tt.template, err)
+		}
+		if got := kinds(findings); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: expected %v, got %v", tt.template, tt.want, got)
+		}
+	}
+}
+
+func TestLintRejectsBadInputs(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	if _, err := te.Lint("{{a}}", utils.LintOptions{Payload: `{"a": `}); err == nil {
+		t.Error("Expected an invalid payload to be rejected")
+	}
+	if _, err := te.Lint("{{a}}", utils.LintOptions{Schema: json.RawMessage(`{"properties": []}`)}); err == nil {
+		t.Error("Expected an invalid schema to be rejected")
+	}
+}
+
+// malformedTemplates are inputs the linter must survive
+var malformedTemplates = []string{
+	"{{", "}}", "{{{", "}}}", "{{}}", "{{ }}", "{{|}}", "{{||}}", "{{@}}", "{{@:}}", "{{.}}", "{{..}}",
+	"{{#}}", "{{.#}}", "{{a.#.#}}", `{{\}}`, `{{a\}}`, `{{"}}`, "{{(}}", "{{)}}", "{{a|@}}", "{{a|@join:}}",
+	"{{#(}}", "{{a.#(b==\"}}", "{{[}}", "{{{a}}}", "{{a}}{{", "\x00{{\x00}}", "{{é.ü}}", "{{a.-1}}",
+	"{{a.99999999999999999999}}", "{{!true}}", "{{@dig:}}", "{{user|@keys|@reverse|@flatten}}",
+	"{{a|default:}}", `{{a|default:"}}`, "{{a|truncate:-1}}", "{{a|number_format:}}", "{{a|upper|@reverse}}", "{{a| |upper}}",
+}
+
+func TestLintMalformedNeverPanics(t *testing.T) {
+	te := utils.NewTemplateEngine()
+	options := []utils.LintOptions{
+		{},
+		{Payload: lintPayload},
+		{Payload: `"just a string"`},
+		{Payload: `[1, 2]`},
+		{Schema: json.RawMessage(lintSchema)},
+		{Schema: json.RawMessage(`{"type": ["string", "null"], "items": {}}`)},
+		{Schema: json.RawMessage(`{"properties": {"a": null}}`)},
+	}
+	for _, template := range malformedTemplates {
+		for _, opts := range options {
+			findings, _ := te.Lint(template, opts)
+			for _, f := range findings {
+				if f.Offset < 0 || f.Offset+f.Length > len(template) {
+					t.Errorf("%q: finding out of range: %+v", template, f)
+				}
+			}
+		}
+	}
+}
+
+func FuzzLint(f *testing.F) {
+	for _, template := range malformedTemplates {
+		f.Add(template, lintPayload)
+	}
+	te := utils.NewTemplateEngine()
+	f.Fuzz(func(t *testing.T, template, payload string) {
+		findings, err := te.Lint(template, utils.LintOptions{Payload: payload})
+		if err != nil {
+			return
+		}
+		for _, finding := range findings {
+			if finding.Offset < 0 || finding.Offset+finding.Length > len(template) {
+				t.Errorf("%q: finding out of range: %+v", template, finding)
+			}
+		}
+		te.Lint(template, utils.LintOptions{Schema: json.RawMessage(lintSchema)})
+	})
+}
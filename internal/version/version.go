@@ -0,0 +1,63 @@
+// Package version holds build metadata injected at link time:
+//
+//	go build -ldflags "-X github.com/alexmacdonald/simple-ipass/internal/version.Version=v0.8.0 \
+//	  -X github.com/alexmacdonald/simple-ipass/internal/version.GitCommit=$(git rev-parse --short HEAD) \
+//	  -X github.com/alexmacdonald/simple-ipass/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Every structured log entry and execution record is stamped with the version
+// and instance ID so incidents can be traced back to a deploy and a host.
+package version
+
+import (
+	"os"
+	"runtime"
+	"sync"
+)
+
+// Set with -ldflags "-X ..." (see package doc); defaults identify local builds
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info is the build and instance metadata served by /api/version
+type Info struct {
+	Version    string `json:"version"`
+	GitCommit  string `json:"git_commit"`
+	BuildDate  string `json:"build_date"`
+	GoVersion  string `json:"go_version"`
+	InstanceID string `json:"instance_id"`
+	Role       string `json:"role,omitempty"` // 'primary' or 'readonly', filled in by the API server
+}
+
+// Get returns the metadata of the running binary
+func Get() Info {
+	return Info{
+		Version:    Version,
+		GitCommit:  GitCommit,
+		BuildDate:  BuildDate,
+		GoVersion:  runtime.Version(),
+		InstanceID: InstanceID(),
+	}
+}
+
+var (
+	instanceOnce sync.Once
+	instanceID   string
+)
+
+// InstanceID identifies this process: INSTANCE_ID if set, otherwise the hostname
+// Resolved once, so every log entry of a process carries the same value
+func InstanceID() string {
+	instanceOnce.Do(func() {
+		instanceID = os.Getenv("INSTANCE_ID")
+		if instanceID == "" {
+			instanceID, _ = os.Hostname()
+		}
+		if instanceID == "" {
+			instanceID = "unknown"
+		}
+	})
+	return instanceID
+}
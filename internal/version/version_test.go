@@ -0,0 +1,30 @@
+package version_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/alexmacdonald/simple-ipass/internal/version"
+)
+
+// TestGet checks the defaults and that INSTANCE_ID overrides the hostname
+func TestGet(t *testing.T) {
+	t.Setenv("INSTANCE_ID", "worker-7")
+
+	info := version.Get()
+	if info.Version != "dev" || info.GitCommit != "unknown" || info.BuildDate != "unknown" {
+		t.Errorf("Expected local build defaults, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+	if info.InstanceID != "worker-7" {
+		t.Errorf("Expected instance ID from INSTANCE_ID, got %q", info.InstanceID)
+	}
+
+	// Resolved once per process
+	t.Setenv("INSTANCE_ID", "worker-8")
+	if id := version.InstanceID(); id != "worker-7" {
+		t.Errorf("Expected a stable instance ID, got %q", id)
+	}
+}
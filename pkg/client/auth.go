@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Register creates an account and authenticates the client with its token
+func (c *Client) Register(ctx context.Context, email, password string) (*AuthResponse, error) {
+	return c.authenticate(ctx, "/api/auth/register", email, password)
+}
+
+// Login authenticates the client with the account's token
+func (c *Client) Login(ctx context.Context, email, password string) (*AuthResponse, error) {
+	return c.authenticate(ctx, "/api/auth/login", email, password)
+}
+
+func (c *Client) authenticate(ctx context.Context, path, email, password string) (*AuthResponse, error) {
+	body := map[string]string{"email": email, "password": password}
+	var out AuthResponse
+	if err := c.call(ctx, http.MethodPost, path, nil, body, &out); err != nil {
+		return nil, err
+	}
+	c.SetToken(out.Token)
+	return &out, nil
+}
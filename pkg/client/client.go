@@ -0,0 +1,188 @@
+// Package client is a typed Go client for the GoFlow API.
+//
+//	c := client.New("https://goflow.example.com")
+//	if _, err := c.Login(ctx, "me@example.com", "secret"); err != nil {
+//		return err
+//	}
+//	workflows, err := c.ListWorkflows(ctx)
+//
+// Every call takes a context. Non-2xx responses are returned as *APIError,
+// which matches the sentinel errors with errors.Is:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKeyHeader is the header Kong's key-auth plugin reads API keys from
+const APIKeyHeader = "apikey"
+
+// Client calls the GoFlow API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests (timeouts, transport, TLS)
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with a JWT from Login or Register
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithAPIKey authenticates requests through a Kong route protected by key-auth
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// New creates a client for the API at baseURL (e.g. "http://localhost:8080")
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the JWT the client currently sends
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the JWT sent with each request
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// newRequest builds a request with the client's credentials; body is JSON-encoded unless nil
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("goflow: failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(APIKeyHeader, c.apiKey)
+	}
+	return req, nil
+}
+
+// do sends a request and decodes a 2xx JSON response into out (if non-nil)
+func (c *Client) do(req *http.Request, out interface{}) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, parseAPIError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return resp, nil
+	}
+	if err := decodeBody(resp.Body, out); err != nil {
+		return resp, fmt.Errorf("goflow: failed to decode response: %w", err)
+	}
+	return resp, nil
+}
+
+// call is the common path for JSON endpoints
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(req, out)
+	return err
+}
+
+// decodeBody decodes a response, unwrapping the {"success", "data", "error", "meta"} envelope if present
+// Only objects made up entirely of envelope keys are unwrapped, so payloads that merely
+// contain a "success" field (like dry-run results) decode as they are
+func decodeBody(body io.Reader, out interface{}) error {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if data, ok := envelopeData(raw); ok {
+		raw = data
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// envelopeData returns the data member of a JSONResponse envelope
+func envelopeData(raw []byte) (json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, false
+	}
+	if _, ok := fields["success"]; !ok {
+		return nil, false
+	}
+	for key := range fields {
+		switch key {
+		case "success", "data", "error", "meta":
+		default:
+			return nil, false
+		}
+	}
+	data, ok := fields["data"]
+	return data, ok
+}
@@ -0,0 +1,348 @@
+package client_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexmacdonald/simple-ipass/internal/db/dbtest"
+	"github.com/alexmacdonald/simple-ipass/internal/engine"
+	"github.com/alexmacdonald/simple-ipass/internal/logger"
+	"github.com/alexmacdonald/simple-ipass/internal/server"
+	"github.com/alexmacdonald/simple-ipass/internal/version"
+	"github.com/alexmacdonald/simple-ipass/pkg/client"
+)
+
+// newTestServer serves the real API router over a temp SQLite database
+// Credentials are encrypted, so the test pins a valid 32-byte key
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+
+	database := dbtest.New(t)
+
+	testLogger := logger.NewLogger("test")
+	executor := engine.NewExecutor(database, testLogger)
+	router := server.NewRouter(server.Config{
+		Store:    database,
+		Executor: executor,
+		Logger:   testLogger,
+	})
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		srv.Close()
+	})
+	return srv
+}
+
+// waitForLogs polls until the workflow has at least n log entries
+func waitForLogs(t *testing.T, c *client.Client, workflowID string, n int) []client.Log {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		logs, err := c.ListLogs(context.Background(), client.LogQuery{WorkflowID: workflowID})
+		if err != nil {
+			t.Fatalf("ListLogs failed: %v", err)
+		}
+		if len(logs) >= n {
+			return logs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d logs, have %d", n, len(logs))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestClientWorkflowLifecycle drives auth, credentials, workflows, webhooks and logs end to end
+func TestClientWorkflowLifecycle(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	c := client.New(srv.URL, client.WithHTTPClient(srv.Client()))
+
+	auth, err := c.Register(ctx, "sdk@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if auth.Token == "" || c.Token() != auth.Token || auth.User.Email != "sdk@example.com" {
+		t.Fatalf("Expected client to be authenticated, got %+v", auth)
+	}
+
+	cred, err := c.CreateCredential(ctx, "slack", "https://hooks.slack.com/services/T000/B000/XXXX")
+	if err != nil {
+		t.Fatalf("CreateCredential failed: %v", err)
+	}
+	creds, err := c.ListCredentials(ctx)
+	if err != nil || len(creds) != 1 || creds[0].ID != cred.ID {
+		t.Fatalf("Expected the created credential, got %+v (%v)", creds, err)
+	}
+
+	var workflows []*client.Workflow
+	for _, name := range []string{"First", "Second", "Third"} {
+		workflow, err := c.CreateWorkflow(ctx, client.CreateWorkflowRequest{
+			Name:        name,
+			TriggerType: "webhook",
+			ActionType:  "testing",
+			ConfigJSON:  `{"testing_response_json": "{\"order_id\": \"{{order.id}}\"}"}`,
+		})
+		if err != nil {
+			t.Fatalf("CreateWorkflow failed: %v", err)
+		}
+		workflows = append(workflows, workflow)
+	}
+
+	// Walk every page; together they must cover each workflow exactly once
+	seen := make(map[string]bool)
+	page := client.PageOptions{Limit: 2}
+	for pages := 0; ; pages++ {
+		result, err := c.ListWorkflowsPage(ctx, page)
+		if err != nil {
+			t.Fatalf("ListWorkflowsPage failed: %v", err)
+		}
+		for _, workflow := range result.Items {
+			if seen[workflow.ID] {
+				t.Errorf("Workflow %s returned twice", workflow.ID)
+			}
+			seen[workflow.ID] = true
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		if pages > 3 {
+			t.Fatal("Pagination did not terminate")
+		}
+		page.Cursor = result.NextCursor
+	}
+	if len(seen) != len(workflows) {
+		t.Errorf("Expected %d workflows across pages, got %d", len(workflows), len(seen))
+	}
+
+	target := workflows[0]
+	requirements, err := c.WorkflowRequirements(ctx, target.ID)
+	if err != nil || requirements.WorkflowID != target.ID {
+		t.Fatalf("WorkflowRequirements failed: %+v (%v)", requirements, err)
+	}
+
+	resp, err := c.TriggerWebhook(ctx, target.ID, []byte(`{"order": {"id": "42"}}`), client.WebhookOptions{SigningSecret: "whsec_test"})
+	if err != nil || resp.Status != "triggered" {
+		t.Fatalf("TriggerWebhook failed: %+v (%v)", resp, err)
+	}
+	logs := waitForLogs(t, c, target.ID, 1)
+	if logs[0].Status != "success" {
+		t.Errorf("Expected successful execution, got %+v", logs[0])
+	}
+
+	logPage, err := c.ListLogsPage(ctx, client.LogQuery{Status: "success"}, client.PageOptions{Limit: 10})
+	if err != nil || len(logPage.Items) != 1 || logPage.NextCursor != "" {
+		t.Fatalf("Expected one log page, got %+v (%v)", logPage, err)
+	}
+	acknowledged, err := c.AcknowledgeLogs(ctx, client.AcknowledgeLogsRequest{WorkflowID: target.ID})
+	if err != nil || acknowledged != 1 {
+		t.Errorf("Expected 1 acknowledged log, got %d (%v)", acknowledged, err)
+	}
+
+	diff, err := c.DryRunDiff(ctx, target.ID, client.DryRunDiffRequest{
+		ConfigJSON: `{"testing_response_json": "{\"order_id\": \"{{order.id}}\", \"carrier\": \"UPS\"}"}`,
+	})
+	if err != nil {
+		t.Fatalf("DryRunDiff failed: %v", err)
+	}
+	if !diff.Changed || len(diff.Steps) != 1 || diff.Steps[0].Fields[0].Path != "data.carrier" {
+		t.Errorf("Expected an added carrier field, got %+v", diff)
+	}
+
+	toggled, err := c.ToggleWorkflow(ctx, target.ID)
+	if err != nil || toggled.IsActive {
+		t.Errorf("Expected workflow to be deactivated, got %+v (%v)", toggled, err)
+	}
+	if err := c.DeleteWorkflow(ctx, target.ID); err != nil {
+		t.Fatalf("DeleteWorkflow failed: %v", err)
+	}
+	if _, err := c.ToggleWorkflow(ctx, target.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+// TestClientFixtures stores, replays, captures and deletes workflow fixtures
+func TestClientFixtures(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	c := client.New(srv.URL, client.WithHTTPClient(srv.Client()))
+	if _, err := c.Register(ctx, "fixtures@example.com", "password123"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	workflow, err := c.CreateWorkflow(ctx, client.CreateWorkflowRequest{
+		Name:        "Orders",
+		TriggerType: "webhook",
+		ActionType:  "testing",
+		ConfigJSON:  `{"testing_response_json": "{\"order_id\": \"{{order.id}}\"}"}`,
+	})
+	if err != nil {
+		t.Fatalf("CreateWorkflow failed: %v", err)
+	}
+
+	fixture, err := c.CreateFixture(ctx, workflow.ID, "small-order", json.RawMessage(`{"order": {"id": "7"}, "api_key": "sk_live_123"}`))
+	if err != nil {
+		t.Fatalf("CreateFixture failed: %v", err)
+	}
+	if fixture.Source != "manual" || strings.Contains(string(fixture.Payload), "sk_live_123") {
+		t.Errorf("Expected a masked manual fixture, got %s (%s)", fixture.Payload, fixture.Source)
+	}
+	if _, err := c.CreateFixture(ctx, workflow.ID, "small-order", json.RawMessage(`{}`)); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("Expected ErrConflict for a duplicate name, got %v", err)
+	}
+	if _, err := c.CreateFixture(ctx, workflow.ID, "bad name!", json.RawMessage(`{}`)); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("Expected ErrBadRequest for an invalid name, got %v", err)
+	}
+	if _, err := c.CreateFixture(ctx, workflow.ID, "list", json.RawMessage(`[1, 2]`)); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("Expected ErrBadRequest for a non-object payload, got %v", err)
+	}
+
+	// Fixtures replay through dry runs without pasting the payload
+	result, err := c.DryRun(ctx, client.DryRunRequest{WorkflowID: workflow.ID, Fixture: "small-order"})
+	if err != nil || result.Data["order_id"] != "7" {
+		t.Fatalf("Expected the fixture to drive the dry run, got %+v (%v)", result, err)
+	}
+	if _, err := c.DryRun(ctx, client.DryRunRequest{WorkflowID: workflow.ID, Fixture: "missing"}); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown fixture, got %v", err)
+	}
+	diff, err := c.DryRunDiff(ctx, workflow.ID, client.DryRunDiffRequest{Fixture: "small-order"})
+	if err != nil || len(diff.Steps) != 1 || diff.Steps[0].Change != "added" {
+		t.Fatalf("Expected a fixture replay before the first run, got %+v (%v)", diff, err)
+	}
+
+	// Capture the payload of a real execution
+	if _, err := c.CaptureFixture(ctx, workflow.ID, "last-real", ""); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound before any execution, got %v", err)
+	}
+	if _, err := c.TriggerWebhook(ctx, workflow.ID, []byte(`{"order": {"id": "42"}, "password": "hunter2"}`), client.WebhookOptions{}); err != nil {
+		t.Fatalf("TriggerWebhook failed: %v", err)
+	}
+	waitForLogs(t, c, workflow.ID, 1)
+	var captured *client.Fixture
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		// The execution trace is written just after the log entry
+		if captured, err = c.CaptureFixture(ctx, workflow.ID, "last-real", ""); err == nil || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err != nil || captured.Source != "execution" || !strings.Contains(string(captured.Payload), `"42"`) || strings.Contains(string(captured.Payload), "hunter2") {
+		t.Fatalf("Expected a masked captured payload, got %+v (%v)", captured, err)
+	}
+
+	fixtures, err := c.ListFixtures(ctx, workflow.ID)
+	if err != nil || len(fixtures) != 2 || fixtures[0].Name != "last-real" || fixtures[1].Name != "small-order" {
+		t.Fatalf("Expected two fixtures sorted by name, got %+v (%v)", fixtures, err)
+	}
+	if err := c.DeleteFixture(ctx, workflow.ID, "small-order"); err != nil {
+		t.Fatalf("DeleteFixture failed: %v", err)
+	}
+	if err := c.DeleteFixture(ctx, workflow.ID, "small-order"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a deleted fixture, got %v", err)
+	}
+
+	// The per-workflow cap counts every stored fixture
+	for i := 1; ; i++ {
+		_, err := c.CreateFixture(ctx, workflow.ID, fmt.Sprintf("order-%d", i), json.RawMessage(`{"order": {"id": "1"}}`))
+		if err != nil {
+			if !errors.Is(err, client.ErrBadRequest) || i != 20 {
+				t.Errorf("Expected the 21st fixture to be rejected, got %v at %d", err, i)
+			}
+			break
+		}
+	}
+
+	other := client.New(srv.URL, client.WithHTTPClient(srv.Client()))
+	if _, err := other.Register(ctx, "intruder@example.com", "password123"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if _, err := other.ListFixtures(ctx, workflow.ID); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden for another user's workflow, got %v", err)
+	}
+}
+
+// TestClientErrors maps envelope and plain-text error responses onto typed errors
+func TestClientErrors(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	anonymous := client.New(srv.URL)
+	if _, err := anonymous.ListWorkflows(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized without a token, got %v", err)
+	}
+	if info, err := anonymous.Version(ctx); err != nil || info.Version != version.Version || info.InstanceID == "" {
+		t.Errorf("Expected public version info, got %+v (%v)", info, err)
+	}
+
+	c := client.New(srv.URL)
+	if _, err := c.Register(ctx, "dup@example.com", "password123"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Envelope error body: {"success": false, "error": "User already exists", "code": "user_exists"}
+	_, err := client.New(srv.URL).Register(ctx, "dup@example.com", "password123")
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("Expected conflict APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusConflict || apiErr.Message != "User already exists" || apiErr.Code != "user_exists" {
+		t.Errorf("Unexpected APIError: %+v", apiErr)
+	}
+
+	if _, err := client.New(srv.URL).Login(ctx, "dup@example.com", "wrong-password"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized for bad credentials, got %v", err)
+	} else if errors.As(err, &apiErr) && (apiErr.Message != "Invalid credentials" || apiErr.Code != "invalid_credentials") {
+		t.Errorf("Expected the invalid_credentials code, got %+v", apiErr)
+	}
+
+	if _, err := c.DryRun(ctx, client.DryRunRequest{ActionType: "not_a_connector"}); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("Expected ErrBadRequest for unknown action, got %v", err)
+	}
+	if _, err := c.DryRunDiff(ctx, "missing", client.DryRunDiffRequest{}); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown workflow, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.ListWorkflows(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context cancellation to be honoured, got %v", err)
+	}
+}
+
+// TestWebhookSignature round-trips the signing helpers
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"id": "evt_1"}`)
+	now := time.Unix(1700000000, 0)
+	header := client.SignWebhookPayload("secret", now, body)
+
+	if err := client.VerifyWebhookSignature("secret", header, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if err := client.VerifyWebhookSignature("other", header, body, 5*time.Minute, now); !errors.Is(err, client.ErrSignatureMismatch) {
+		t.Errorf("Expected mismatch for wrong secret, got %v", err)
+	}
+	if err := client.VerifyWebhookSignature("secret", header, []byte(`{"id": "evt_2"}`), 0, now); !errors.Is(err, client.ErrSignatureMismatch) {
+		t.Errorf("Expected mismatch for tampered body, got %v", err)
+	}
+	if err := client.VerifyWebhookSignature("secret", header, body, 5*time.Minute, now.Add(time.Hour)); !errors.Is(err, client.ErrSignatureExpired) {
+		t.Errorf("Expected expired signature, got %v", err)
+	}
+	if err := client.VerifyWebhookSignature("secret", "garbage", body, 0, now); !errors.Is(err, client.ErrSignatureMissing) {
+		t.Errorf("Expected malformed signature error, got %v", err)
+	}
+
+	// A rotated secret verifies when both signatures are sent
+	rotated := header + ",v1=" + client.SignWebhookPayload("new-secret", now, body)[len("t=1700000000,v1="):]
+	if err := client.VerifyWebhookSignature("new-secret", rotated, body, 0, now); err != nil {
+		t.Errorf("Expected rotated signature to verify, got %v", err)
+	}
+}
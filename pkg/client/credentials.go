@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateCredential stores an API key or webhook URL for a service
+func (c *Client) CreateCredential(ctx context.Context, serviceName, apiKey string) (*Credential, error) {
+	body := map[string]string{"service_name": serviceName, "api_key": apiKey}
+	var out Credential
+	if err := c.call(ctx, http.MethodPost, "/api/credentials", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCredentials returns the user's connections without their keys
+func (c *Client) ListCredentials(ctx context.Context) ([]Credential, error) {
+	var out []Credential
+	if err := c.call(ctx, http.MethodGet, "/api/credentials", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by *APIError through errors.Is
+var (
+	ErrBadRequest   = errors.New("goflow: bad request")
+	ErrUnauthorized = errors.New("goflow: unauthorized")
+	ErrForbidden    = errors.New("goflow: forbidden")
+	ErrNotFound     = errors.New("goflow: not found")
+	ErrConflict     = errors.New("goflow: conflict")
+	ErrRateLimited  = errors.New("goflow: rate limited")
+	ErrServer       = errors.New("goflow: server error")
+)
+
+// maxErrorBodySize bounds how much of an error response is read
+const maxErrorBodySize = 64 * 1024
+
+// APIError is a non-2xx response from the API
+type APIError struct {
+	StatusCode int
+	Message    string // Server message, from the envelope's "error" or the plain-text body
+	Code       string // Machine-readable code from the envelope's "code", e.g. "workflow_not_found"; empty for plain-text bodies
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("goflow: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("goflow: %d %s", e.StatusCode, e.Message)
+}
+
+// Is maps the status code onto the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// parseAPIError reads an error response
+// Handlers answer with either the JSON envelope ({"success": false, "error": "...", "code": "..."})
+// or a plain-text http.Error body; both end up in Message
+func parseAPIError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var envelope struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(raw, &envelope) == nil && envelope.Error != "" {
+		apiErr.Message = envelope.Error
+		apiErr.Code = envelope.Code
+	} else {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+	return apiErr
+}
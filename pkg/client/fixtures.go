@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// CreateFixture stores a named sample payload (a JSON object) with a workflow
+func (c *Client) CreateFixture(ctx context.Context, workflowID, name string, payload json.RawMessage) (*Fixture, error) {
+	body := map[string]interface{}{"name": name, "payload": payload}
+	var out Fixture
+	if err := c.call(ctx, http.MethodPost, fixturesPath(workflowID), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CaptureFixture saves the trigger payload of an execution as a fixture
+// An empty executionID captures the workflow's latest real execution
+func (c *Client) CaptureFixture(ctx context.Context, workflowID, name, executionID string) (*Fixture, error) {
+	body := map[string]string{"name": name}
+	if executionID != "" {
+		body["execution_id"] = executionID
+	}
+	var out Fixture
+	if err := c.call(ctx, http.MethodPost, fixturesPath(workflowID)+"/capture", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListFixtures returns a workflow's fixtures sorted by name
+func (c *Client) ListFixtures(ctx context.Context, workflowID string) ([]Fixture, error) {
+	var out []Fixture
+	if err := c.call(ctx, http.MethodGet, fixturesPath(workflowID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteFixture removes a fixture by name
+func (c *Client) DeleteFixture(ctx context.Context, workflowID, name string) error {
+	return c.call(ctx, http.MethodDelete, fixturesPath(workflowID)+"/"+url.PathEscape(name), nil, nil, nil)
+}
+
+func fixturesPath(workflowID string) string {
+	return "/api/workflows/" + url.PathEscape(workflowID) + "/fixtures"
+}
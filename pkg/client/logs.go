@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ListLogs returns all log entries matching the query
+func (c *Client) ListLogs(ctx context.Context, q LogQuery) ([]Log, error) {
+	var out []Log
+	if err := c.call(ctx, http.MethodGet, "/api/logs", q.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLogsPage returns one page of log entries, newest first
+func (c *Client) ListLogsPage(ctx context.Context, q LogQuery, page PageOptions) (*LogPage, error) {
+	query := page.query()
+	for key, values := range q.values() {
+		query[key] = values
+	}
+	var out LogPage
+	if err := c.call(ctx, http.MethodGet, "/api/logs", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AcknowledgeLog marks one log entry as triaged
+func (c *Client) AcknowledgeLog(ctx context.Context, logID string) (*Log, error) {
+	var out Log
+	if err := c.call(ctx, http.MethodPost, "/api/logs/"+url.PathEscape(logID)+"/ack", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AcknowledgeLogs marks every matching log entry as triaged and returns how many changed
+func (c *Client) AcknowledgeLogs(ctx context.Context, req AcknowledgeLogsRequest) (int64, error) {
+	var out struct {
+		Acknowledged int64 `json:"acknowledged"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/api/logs/ack", nil, req, &out); err != nil {
+		return 0, err
+	}
+	return out.Acknowledged, nil
+}
+
+func (q LogQuery) values() url.Values {
+	query := url.Values{}
+	if q.WorkflowID != "" {
+		query.Set("workflow_id", q.WorkflowID)
+	}
+	if q.Status != "" {
+		query.Set("status", q.Status)
+	}
+	if q.Since != nil {
+		query.Set("since", q.Since.Format(time.RFC3339))
+	}
+	return query
+}
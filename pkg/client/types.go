@@ -0,0 +1,223 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// User is an account returned by the auth endpoints
+type User struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuthResponse is returned by Register and Login
+type AuthResponse struct {
+	Token string `json:"token"`
+	User  User   `json:"user"`
+}
+
+// Workflow is an integration workflow
+type Workflow struct {
+	ID             string         `json:"id"`
+	UserID         string         `json:"user_id"`
+	Name           string         `json:"name"`
+	TriggerType    string         `json:"trigger_type"` // 'webhook', 'schedule'
+	ActionType     string         `json:"action_type"`
+	ConfigJSON     string         `json:"config_json"`
+	ActionChain    string         `json:"action_chain"` // JSON array of ChainedAction
+	Parameters     string         `json:"parameters"`
+	IsActive       bool           `json:"is_active"`
+	LastExecutedAt *time.Time     `json:"last_executed_at,omitempty"`
+	NextRunAt      *time.Time     `json:"next_run_at,omitempty"` // Schedule triggers only
+	CreatedAt      time.Time      `json:"created_at"`
+	Stats          *WorkflowStats `json:"stats,omitempty"`
+	WebhookURL     string         `json:"webhook_url,omitempty"` // Webhook triggers only
+}
+
+// WorkflowStats are a workflow's execution counters
+type WorkflowStats struct {
+	TotalExecutions     int64   `json:"total_executions"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	Successes24h        int     `json:"successes_24h"`
+	Successes7d         int     `json:"successes_7d"`
+	AvgDurationMS       float64 `json:"avg_duration_ms"`
+	LastError           string  `json:"last_error,omitempty"`
+}
+
+// ChainedAction is an additional step executed after the primary action
+type ChainedAction struct {
+	ActionType  string                 `json:"action_type"`
+	Config      map[string]interface{} `json:"config"`
+	UseDataFrom string                 `json:"use_data_from,omitempty"` // 'previous' to use data from previous action
+}
+
+// CreateWorkflowRequest describes a new workflow
+type CreateWorkflowRequest struct {
+	Name        string          `json:"name"`
+	TriggerType string          `json:"trigger_type"`
+	ActionType  string          `json:"action_type"`
+	ConfigJSON  string          `json:"config_json"`
+	ActionChain []ChainedAction `json:"action_chain,omitempty"`
+}
+
+// UpdateWorkflowRequest changes a workflow in place; empty fields keep their value
+type UpdateWorkflowRequest struct {
+	Name        string           `json:"name,omitempty"`
+	TriggerType string           `json:"trigger_type,omitempty"`
+	ActionType  string           `json:"action_type,omitempty"`
+	ConfigJSON  string           `json:"config_json,omitempty"`
+	ActionChain *[]ChainedAction `json:"action_chain,omitempty"` // Nil keeps the chain, an empty slice removes it
+}
+
+// DryRunRequest is an action to test without saving a workflow
+type DryRunRequest struct {
+	ActionType string `json:"action_type"`
+	ConfigJSON string `json:"config_json"`
+	WorkflowID string `json:"workflow_id,omitempty"` // Stored workflow supplying an empty action or config
+	Fixture    string `json:"fixture,omitempty"`     // Workflow fixture used as the trigger payload (needs WorkflowID)
+}
+
+// DryRunResult is the outcome of a dry run
+type DryRunResult struct {
+	Success   bool                   `json:"success"`
+	Message   string                 `json:"message"`
+	Duration  string                 `json:"duration"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Timestamp string                 `json:"timestamp"`
+}
+
+// DryRunDiffRequest overrides parts of a stored workflow for a sandboxed replay
+// Empty fields keep the stored workflow's values
+type DryRunDiffRequest struct {
+	ActionType  string          `json:"action_type,omitempty"`
+	ConfigJSON  string          `json:"config_json,omitempty"`
+	ActionChain []ChainedAction `json:"action_chain,omitempty"`
+	Fixture     string          `json:"fixture,omitempty"` // Replay this fixture instead of the last payload
+}
+
+// ExecutionDiff compares a sandboxed replay with the workflow's last execution
+type ExecutionDiff struct {
+	BaselineExecutionID string     `json:"baseline_execution_id"`
+	BaselineExecutedAt  time.Time  `json:"baseline_executed_at"`
+	CandidateStatus     string     `json:"candidate_status"`
+	Changed             bool       `json:"changed"`
+	Steps               []StepDiff `json:"steps"`
+}
+
+// StepDiff is the difference for one step (0 = primary action, 1..n = chain)
+type StepDiff struct {
+	Step   int         `json:"step"`
+	Change string      `json:"change"` // 'added', 'removed', 'changed', 'unchanged'
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// FieldDiff is a single changed field within a step's output
+type FieldDiff struct {
+	Path     string      `json:"path"`
+	Change   string      `json:"change"`
+	Before   interface{} `json:"before,omitempty"`
+	After    interface{} `json:"after,omitempty"`
+	TextDiff string      `json:"text_diff,omitempty"`
+}
+
+// WorkflowRequirements is the credential checklist for a workflow
+type WorkflowRequirements struct {
+	WorkflowID   string                        `json:"workflow_id"`
+	Ready        bool                          `json:"ready"`
+	Requirements []CredentialRequirementStatus `json:"requirements"`
+}
+
+// CredentialRequirementStatus is one entry in a workflow's credential checklist
+type CredentialRequirementStatus struct {
+	Service     string   `json:"service"`
+	Label       string   `json:"label"`
+	ActionTypes []string `json:"action_types"`
+	Optional    bool     `json:"optional"`
+	Status      string   `json:"status"` // 'connected' or 'missing'
+	Hint        string   `json:"hint,omitempty"`
+	SetupURL    string   `json:"setup_url,omitempty"`
+}
+
+// Credential is a stored connection; the key itself is never returned
+type Credential struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	ServiceName string    `json:"service_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Log is an execution log entry
+type Log struct {
+	ID             string     `json:"id"`
+	WorkflowID     string     `json:"workflow_id"`
+	WorkflowName   string     `json:"workflow_name,omitempty"`
+	Status         string     `json:"status"` // 'success', 'failed'
+	Message        string     `json:"message"`
+	ExecutedAt     time.Time  `json:"executed_at"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// LogQuery filters log listings
+type LogQuery struct {
+	WorkflowID string
+	Status     string     // 'success', 'failed', or 'unacknowledged'
+	Since      *time.Time // Only entries executed at or after this time
+}
+
+// AcknowledgeLogsRequest selects log entries to acknowledge in bulk (all fields optional)
+type AcknowledgeLogsRequest struct {
+	WorkflowID string     `json:"workflow_id,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+}
+
+// PageOptions selects one page of a cursor-paginated listing
+type PageOptions struct {
+	Limit  int    // Server default (50) if zero; capped at 200 (logs: at most 1000)
+	Cursor string // NextCursor of the previous page; empty for the first page
+}
+
+// WorkflowPage is one page of workflows
+type WorkflowPage struct {
+	Items      []Workflow `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// LogPage is one page of log entries
+type LogPage struct {
+	Items      []Log  `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+	Total      int    `json:"total"`                 // Entries matching the query across all pages
+	HasMore    bool   `json:"has_more"`
+}
+
+// WebhookResponse is the acknowledgement of a webhook delivery
+type WebhookResponse struct {
+	Status  string `json:"status"` // 'triggered' or 'duplicate_ignored'
+	Message string `json:"message"`
+	EventID string `json:"event_id,omitempty"`
+}
+
+// VersionInfo is the build and instance metadata of the API server
+type VersionInfo struct {
+	Version    string `json:"version"`
+	GitCommit  string `json:"git_commit"`
+	BuildDate  string `json:"build_date"`
+	GoVersion  string `json:"go_version"`
+	InstanceID string `json:"instance_id"`
+}
+
+// Fixture is a named sample trigger payload stored with a workflow
+type Fixture struct {
+	ID         string          `json:"id"`
+	WorkflowID string          `json:"workflow_id"`
+	Name       string          `json:"name"`
+	Payload    json.RawMessage `json:"payload"` // Masked by the server
+	Source     string          `json:"source"`  // 'manual' or 'execution'
+	CreatedAt  time.Time       `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Version returns the server's build metadata (no authentication required)
+func (c *Client) Version(ctx context.Context) (*VersionInfo, error) {
+	var out VersionInfo
+	if err := c.call(ctx, http.MethodGet, "/api/version", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
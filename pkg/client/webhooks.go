@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the HMAC signature of a webhook delivery
+// Format: "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+const SignatureHeader = "X-GoFlow-Signature"
+
+// Signature verification errors
+var (
+	ErrSignatureMissing  = errors.New("goflow: webhook signature missing or malformed")
+	ErrSignatureMismatch = errors.New("goflow: webhook signature mismatch")
+	ErrSignatureExpired  = errors.New("goflow: webhook signature timestamp outside tolerance")
+)
+
+// WebhookOptions customise a webhook delivery
+type WebhookOptions struct {
+	Header        http.Header // Extra headers, e.g. an event ID header used for dedupe
+	SigningSecret string      // Signs the body with SignatureHeader when set
+	ContentType   string      // Defaults to application/json
+}
+
+// TriggerWebhook delivers body to a workflow's webhook trigger
+// Webhooks are public, so no token or API key is required
+func (c *Client) TriggerWebhook(ctx context.Context, workflowID string, body []byte, opts WebhookOptions) (*WebhookResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/webhooks/"+url.PathEscape(workflowID), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range opts.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if opts.SigningSecret != "" {
+		req.Header.Set(SignatureHeader, SignWebhookPayload(opts.SigningSecret, time.Now(), body))
+	}
+
+	var out WebhookResponse
+	if _, err := c.do(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SignWebhookPayload returns the SignatureHeader value for body signed at timestamp
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + webhookMAC(secret, t, body)
+}
+
+// VerifyWebhookSignature checks a SignatureHeader value against body
+// tolerance bounds the age (and clock skew) of the timestamp; zero disables the check
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrSignatureMissing
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureMissing
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	// Several v1 values allow a secret to be rotated without dropping deliveries
+	expected := webhookMAC(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+func webhookMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
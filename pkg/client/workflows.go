@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateWorkflow saves a new workflow
+func (c *Client) CreateWorkflow(ctx context.Context, req CreateWorkflowRequest) (*Workflow, error) {
+	var out Workflow
+	if err := c.call(ctx, http.MethodPost, "/api/workflows", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWorkflows returns all of the user's workflows
+func (c *Client) ListWorkflows(ctx context.Context) ([]Workflow, error) {
+	var out []Workflow
+	if err := c.call(ctx, http.MethodGet, "/api/workflows", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListWorkflowsPage returns one page of workflows, newest first
+func (c *Client) ListWorkflowsPage(ctx context.Context, page PageOptions) (*WorkflowPage, error) {
+	var out WorkflowPage
+	if err := c.call(ctx, http.MethodGet, "/api/workflows", page.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ToggleWorkflow flips a workflow between active and inactive
+func (c *Client) ToggleWorkflow(ctx context.Context, workflowID string) (*Workflow, error) {
+	var out Workflow
+	if err := c.call(ctx, http.MethodPut, "/api/workflows/"+url.PathEscape(workflowID)+"/toggle", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateWorkflow changes a workflow's definition, keeping its ID and webhook URL
+func (c *Client) UpdateWorkflow(ctx context.Context, workflowID string, req UpdateWorkflowRequest) (*Workflow, error) {
+	var out Workflow
+	if err := c.call(ctx, http.MethodPut, "/api/workflows/"+url.PathEscape(workflowID), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWorkflow removes a workflow
+func (c *Client) DeleteWorkflow(ctx context.Context, workflowID string) error {
+	return c.call(ctx, http.MethodDelete, "/api/workflows/"+url.PathEscape(workflowID), nil, nil, nil)
+}
+
+// DryRun executes an action once without saving a workflow
+// A failing action is reported as an *APIError (400) carrying the connector's message
+func (c *Client) DryRun(ctx context.Context, req DryRunRequest) (*DryRunResult, error) {
+	var out DryRunResult
+	if err := c.call(ctx, http.MethodPost, "/api/workflows/dry-run", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DryRunDiff replays the workflow's last trigger payload in sandbox mode with the given
+// overrides and diffs the outputs against the last execution
+func (c *Client) DryRunDiff(ctx context.Context, workflowID string, req DryRunDiffRequest) (*ExecutionDiff, error) {
+	var out ExecutionDiff
+	if err := c.call(ctx, http.MethodPost, "/api/workflows/"+url.PathEscape(workflowID)+"/dry-run-diff", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// WorkflowRequirements returns the credential checklist for a workflow
+func (c *Client) WorkflowRequirements(ctx context.Context, workflowID string) (*WorkflowRequirements, error) {
+	var out WorkflowRequirements
+	if err := c.call(ctx, http.MethodGet, "/api/workflows/"+url.PathEscape(workflowID)+"/requirements", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// query encodes the page options; limit is always sent so the server answers with a page envelope
+func (p PageOptions) query() url.Values {
+	query := url.Values{}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	query.Set("limit", strconv.Itoa(limit))
+	if p.Cursor != "" {
+		query.Set("cursor", p.Cursor)
+	}
+	return query
+}
@@ -60,14 +60,20 @@ func testTenantUserCreation(t *testing.T, config E2ETestConfig) {
 	}
 	defer database.Close()
 
-	// STEP 1: Create a tenant (simulating Phase 2 multi-tenant)
+	// STEP 1: Create a tenant
 	tenantID := "tenant_acme_corp_001"
 	tenantName := "Acme Corporation"
 	t.Logf("   Creating tenant: %s (%s)", tenantName, tenantID)
 
-	// TODO: Once tenant table exists, use:
-	// err = database.CreateTenant(tenantID, tenantName)
-	// For now, we simulate by creating a user with derived tenant
+	tenant, err := database.CreateTenant(tenantID, tenantName)
+	if err != nil {
+		t.Fatalf("❌ Failed to create tenant: %v", err)
+	}
+
+	savedTenant, err := database.GetTenantByID(tenant.ID)
+	if err != nil || savedTenant.Name != tenantName {
+		t.Fatalf("❌ Verification FAILED: Tenant not found in database: %v", err)
+	}
 
 	// STEP 2: Create a user for this tenant
 	userEmail := "admin@acme.com"
@@ -79,7 +85,7 @@ func testTenantUserCreation(t *testing.T, config E2ETestConfig) {
 		t.Fatalf("❌ Failed to hash password: %v", err)
 	}
 
-	user, err := database.CreateUser(userEmail, string(hashedPassword))
+	user, err := database.CreateUserInTenant(tenantID, userEmail, string(hashedPassword))
 	if err != nil {
 		t.Fatalf("❌ Failed to create user: %v", err)
 	}
@@ -99,6 +105,10 @@ func testTenantUserCreation(t *testing.T, config E2ETestConfig) {
 		t.Fatalf("❌ Verification FAILED: Email mismatch. Expected %s, got %s", userEmail, savedUser.Email)
 	}
 
+	if savedUser.TenantID != tenantID {
+		t.Fatalf("❌ Verification FAILED: Tenant mismatch. Expected %s, got %s", tenantID, savedUser.TenantID)
+	}
+
 	t.Logf("   ✅ Verification PASSED: User %s (ID: %s) successfully created", userEmail, user.ID)
 
 	// STEP 4: Test authentication flow